@@ -0,0 +1,110 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+	"med-pulse-bot/internal/services"
+)
+
+// EmployeeActivator switches one tenant's employees on and off after an admin confirms
+type EmployeeActivator interface {
+	Find(ctx context.Context, code string) (*models.Employee, error)
+	HandleCallback(ctx context.Context, data string, actorID int64, actorName string) (string, error)
+}
+
+var (
+	activatorsMu sync.RWMutex
+	activators   = make(map[string]EmployeeActivator) // tenant ID → activator
+)
+
+// SetEmployeeActivation enables /activate and /deactivate for the tenant's admin chats
+func SetEmployeeActivation(tenantID string, a EmployeeActivator) {
+	activatorsMu.Lock()
+	activators[tenantID] = a
+	activatorsMu.Unlock()
+	HandleCallbacks(tenantID, services.ActivationCallbackPrefix, func(ctx context.Context, chatID int64, data string) (string, error) {
+		if !isSiteAdmin(chatID) {
+			return "", errors.New("employee activation is for admin chats only")
+		}
+		activatorsMu.RLock()
+		a := activators[tenantID]
+		activatorsMu.RUnlock()
+		if a == nil {
+			return "", errors.New("employee activation is not enabled")
+		}
+		user := callbackUser(ctx)
+		if user == nil {
+			return "", errors.New("unknown user")
+		}
+		by := periodLockActor(user)
+		return a.HandleCallback(ctx, data, by.UserID, by.Name)
+	})
+}
+
+// handleActivation asks the admin to confirm switching the employee with the code given
+// as the argument on (/activate) or off (/deactivate)
+func handleActivation(s *site, message *tgbotapi.Message, msg *tgbotapi.MessageConfig, active bool) {
+	if !isSiteAdmin(message.Chat.ID) {
+		msg.Text = "❌ คำสั่งนี้สำหรับผู้ดูแลระบบเท่านั้น"
+		return
+	}
+	activatorsMu.RLock()
+	a := activators[s.id]
+	activatorsMu.RUnlock()
+	if a == nil {
+		msg.Text = "❌ การเปิด/ปิดใช้งานพนักงานไม่ได้เปิดใช้งาน"
+		return
+	}
+	code := strings.TrimSpace(message.CommandArguments())
+	if code == "" {
+		msg.Text = fmt.Sprintf("Usage: `/%s <รหัสพนักงาน>`", message.Command())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(pollContext(), 10*time.Second)
+	defer cancel()
+	emp, err := a.Find(ctx, code)
+	if errors.Is(err, repository.ErrEmployeeNotFound) {
+		msg.Text = fmt.Sprintf("❌ ไม่พบพนักงานรหัส %s", markdown.Escape(code))
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to look up employee %s: %v", code, err)
+		msg.Text = unavailableMessage
+		return
+	}
+	name := fmt.Sprintf("%s (%s)", markdown.Escape(emp.Name), markdown.Escape(emp.EmployeeCode))
+	switch {
+	case active && emp.IsActive:
+		msg.Text = fmt.Sprintf("ℹ️ %s ใช้งานอยู่แล้ว", name)
+		return
+	case !active && !emp.IsActive:
+		msg.Text = fmt.Sprintf("ℹ️ %s ถูกปิดใช้งานแล้ว", name)
+		return
+	}
+
+	confirm := "✅ ยืนยันเปิดใช้งาน"
+	msg.Text = fmt.Sprintf("เปิดใช้งาน %s อีกครั้ง?\nแท็กของพนักงานจะลงเวลาได้ทันที", name)
+	if !active {
+		confirm = "🚫 ยืนยันปิดใช้งาน"
+		msg.Text = fmt.Sprintf("ปิดใช้งาน %s?\nแท็กของพนักงานจะไม่ลงเวลาอีกตั้งแต่ตอนนี้", name)
+	}
+	if emp.Department != "" {
+		msg.Text += "\nแผนก: " + markdown.Escape(emp.Department)
+	}
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(confirm, services.ActivationData(emp.EmployeeCode, active)),
+		tgbotapi.NewInlineKeyboardButtonData("❌ ยกเลิก", services.ActivationCallbackPrefix+":cancel"),
+	))
+}
@@ -0,0 +1,52 @@
+package bot
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+	"med-pulse-bot/internal/services"
+	"med-pulse-bot/internal/tenant"
+)
+
+func TestActivationPrompt(t *testing.T) {
+	const adminChatID = 42
+	useSingleSite(t, http.NotFoundHandler(), adminChatID)
+	tg := newFakeTelegram(t)
+	store := memory.NewStore(clock.NewFake(time.Date(2026, 3, 2, 9, 0, 0, 0, location)))
+	store.AddEmployee(models.Employee{ID: "e1", Name: "Som_chai", EmployeeCode: "N001", IsActive: true})
+	store.AddEmployee(models.Employee{ID: "e2", Name: "Malee", EmployeeCode: "N002"})
+	SetEmployeeActivation(tenant.DefaultID, services.NewEmployeeActivations(store.Employees(), store.AuditLog()))
+	t.Cleanup(func() { SetEmployeeActivation(tenant.DefaultID, nil) })
+
+	tests := []struct {
+		name       string
+		chatID     int64
+		command    string
+		want       string
+		wantButton string
+	}{
+		{"employee chat", 1001, "/deactivate N001", "ผู้ดูแลระบบเท่านั้น", ""},
+		{"no code", adminChatID, "/deactivate", "Usage", ""},
+		{"unknown code", adminChatID, "/deactivate N999", "ไม่พบพนักงานรหัส N999", ""},
+		{"deactivate", adminChatID, "/deactivate N001", `ปิดใช้งาน Som\_chai (N001)?`, "act:off:N001"},
+		{"already active", adminChatID, "/activate N001", "ใช้งานอยู่แล้ว", ""},
+		{"reactivate", adminChatID, "/activate N002", "เปิดใช้งาน Malee (N002) อีกครั้ง?", "act:on:N002"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handleUpdate(commandUpdate(tt.chatID, tt.command))
+			call := tg.last(t, "sendMessage")
+			if got := call.params.Get("text"); !strings.Contains(got, tt.want) {
+				t.Errorf("%s = %q, want it to contain %q", tt.command, got, tt.want)
+			}
+			if got := call.params.Get("reply_markup"); !strings.Contains(got, tt.wantButton) || (tt.wantButton == "") != (got == "") {
+				t.Errorf("%s buttons = %q, want %q", tt.command, got, tt.wantButton)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package bot
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSeveralAdminChats(t *testing.T) {
+	const manager, hrGroup = 42, -1001234567890
+	useSingleSite(t, http.NotFoundHandler(), 0)
+	adminChatIDs = []int64{manager, hrGroup}
+	tg := newFakeTelegram(t)
+
+	SendNotification("⚠️ สาย: Somchai")
+	var sentTo []string
+	tg.mu.Lock()
+	for _, c := range tg.calls {
+		if c.method == "sendMessage" {
+			sentTo = append(sentTo, c.params.Get("chat_id"))
+		}
+	}
+	tg.mu.Unlock()
+	if strings.Join(sentTo, ",") != "42,-1001234567890" {
+		t.Errorf("notification sent to %v, want both admin chats", sentTo)
+	}
+
+	tests := []struct {
+		name   string
+		chatID int64
+		admin  bool
+	}{
+		{"manager", manager, true},
+		{"HR group", hrGroup, true},
+		{"employee", 1001, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handleUpdate(commandUpdate(tt.chatID, "/whoisin"))
+			refused := strings.Contains(tg.last(t, "sendMessage").params.Get("text"), "ผู้ดูแลระบบเท่านั้น")
+			if refused == tt.admin {
+				t.Errorf("admin command from %d refused = %v, want %v", tt.chatID, refused, !tt.admin)
+			}
+		})
+	}
+}
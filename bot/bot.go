@@ -1,55 +1,39 @@
 package bot
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
-	"strconv"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/macaddr"
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+	"med-pulse-bot/internal/services"
 )
 
 var (
 	bot          *tgbotapi.BotAPI
-	targetChatID int64
-	pbURL        string
-	pbToken      string
-	httpClient   = &http.Client{Timeout: 10 * time.Second}
-	userStates   = make(map[int64]*RegistrationState)
+	adminChatIDs []int64 // AUTHORIZED_CHAT_IDS
+	location     = time.Local
 )
 
-type RegistrationState struct {
-	Step         int
-	MacAddress   string
-	Name         string
-	EmployeeCode string
-	Department   string
-}
-
-// SetPocketBaseURL sets the PocketBase REST API URL
-func SetPocketBaseURL(url string) {
-	pbURL = strings.TrimRight(url, "/")
+// SetLocation sets the timezone of the days and times shown by /today and /history,
+// e.g. the configured TIMEZONE
+func SetLocation(loc *time.Location) {
+	location = loc
 }
 
-// SetPocketBaseToken sets the PocketBase auth token
-func SetPocketBaseToken(token string) {
-	pbToken = token
-}
-
-// addAuthHeader adds authorization header if token exists
-func addAuthHeader(req *http.Request) {
-	if pbToken != "" {
-		req.Header.Set("Authorization", pbToken)
-	}
-}
-
-// Init initializes the Telegram Bot
-func Init(token string, authorizedChatIDStr string) error {
+// Init initializes the Telegram Bot; notifications for the admins go to every chat in
+// adminChats (AUTHORIZED_CHAT_IDS)
+func Init(token string, adminChats []int64) error {
 	var err error
 	bot, err = tgbotapi.NewBotAPI(token)
 	if err != nil {
@@ -59,353 +43,723 @@ func Init(token string, authorizedChatIDStr string) error {
 	bot.Debug = false
 	log.Printf("Authorized on account %s", bot.Self.UserName)
 
-	if authorizedChatIDStr != "" {
-		id, err := strconv.ParseInt(authorizedChatIDStr, 10, 64)
-		if err == nil {
-			targetChatID = id
-		}
-	}
-
+	adminChatIDs = adminChats
 	return nil
 }
 
-// StartPolling starts the update loop
-func StartPolling() {
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
+// Initialized reports whether Init connected to Telegram
+func Initialized() bool {
+	return bot != nil
+}
+
+// pollTimeout is how long one getUpdates long poll may wait
+const pollTimeout = 30
 
-	updates := bot.GetUpdatesChan(u)
+var (
+	pollCtxMu sync.RWMutex
+	pollCtx   = context.Background() // done once polling stops
+)
+
+// pollContext is the context of the PocketBase calls made for an update, so shutdown
+// abandons them instead of waiting for their timeout
+func pollContext() context.Context {
+	pollCtxMu.RLock()
+	defer pollCtxMu.RUnlock()
+	return pollCtx
+}
 
+// StartPolling handles Telegram updates until ctx is done. The updates seen so far are
+// confirmed on the way out so whichever instance polls next does not handle them again.
+func StartPolling(ctx context.Context) {
+	pollCtxMu.Lock()
+	pollCtx = ctx
+	pollCtxMu.Unlock()
 	go func() {
-		for update := range updates {
-			if update.CallbackQuery != nil {
-				handleCallback(update.CallbackQuery)
+		u := tgbotapi.NewUpdate(0)
+		u.Timeout = pollTimeout
+		for ctx.Err() == nil {
+			updates, err := bot.GetUpdates(u)
+			if err != nil {
+				log.Printf("Failed to get updates, retrying in 3 seconds: %v", err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(3 * time.Second):
+				}
 				continue
 			}
-
-			if update.Message == nil || !update.Message.IsCommand() {
-				continue
+			for _, update := range updates {
+				if update.UpdateID >= u.Offset {
+					u.Offset = update.UpdateID + 1
+				}
+				handleUpdate(update)
 			}
+			expireRegistrations(time.Now())
+		}
 
-			msg := tgbotapi.NewMessage(update.Message.Chat.ID, "")
-			msg.ParseMode = "Markdown"
-
-			switch update.Message.Command() {
-			case "start":
-				msg.Text = "🏢 *ระบบบันทึกเวลาเข้างาน*\n\n" +
-					"*คำสั่ง:*\n" +
-					"/register_employee - ลงทะเบียน\n" +
-					"/myinfo - ข้อมูลฉัน\n" +
-					"/today - เวลาวันนี้\n" +
-					"/history - ประวัติ\n" +
-					"/scanners - สถานะ Scanner"
-
-			case "getid":
-				msg.Text = fmt.Sprintf("Chat ID: `%d`", update.Message.Chat.ID)
-
-			case "scanners":
-				scanners, err := getActiveScanners()
-				if err != nil {
-					msg.Text = fmt.Sprintf("Error: %v", err)
-				} else if len(scanners) == 0 {
-					msg.Text = "No scanners found"
-				} else {
-					msg.Text = "📡 *Scanners:*\n" + strings.Join(scanners, "\n")
-				}
+		if u.Offset > 0 {
+			u.Timeout, u.Limit = 0, 1
+			if _, err := bot.GetUpdates(u); err != nil {
+				log.Printf("Failed to confirm handled updates: %v", err)
+			}
+		}
+		log.Println("Telegram polling stopped")
+	}()
+}
 
-			case "register_employee":
-				handleRegisterEmployee(update.Message, &msg)
+// handleUpdate answers one command or button press
+func handleUpdate(update tgbotapi.Update) {
+	if update.CallbackQuery != nil {
+		handleCallback(update.CallbackQuery)
+		return
+	}
 
-			case "myinfo":
-				handleMyInfo(update.Message.Chat.ID, &msg)
+	if update.Message == nil {
+		return
+	}
+	rememberChatType(update.Message.Chat)
+	if !update.Message.IsCommand() {
+		if !handleTypedTime(update.Message) && !handleDeviceAssignmentAnswer(update.Message) {
+			handleRegistrationAnswer(update.Message)
+		}
+		return
+	}
+	// A new command abandons a time the chat was asked to type, an employee code it was
+	// asked for and a registration in progress
+	cancelTypedTime(update.Message.Chat.ID)
+	cancelDeviceAssignment(update.Message.Chat.ID)
+	cancelRegistration(update.Message.Chat.ID)
 
-			case "today":
-				handleToday(update.Message.Chat.ID, &msg)
+	msg := tgbotapi.NewMessage(update.Message.Chat.ID, "")
+	msg.ParseMode = "Markdown"
 
-			case "history":
-				handleHistory(update.Message, &msg)
+	command := update.Message.Command()
+	s, siteErr := siteFor(update.Message.Chat.ID)
+	if siteErr != nil && siteCommands[command] {
+		msg.Text = "❌ " + siteErr.Error()
+		command = ""
+	}
+	if readOnly() && writesData(command, update.Message.CommandArguments()) {
+		msg.Text = maintenanceMessage
+		command = ""
+	}
 
-			default:
-				msg.Text = "ไม่รู้จำคำสั่ง ใช้ /start"
-			}
+	switch command {
+	case "":
+		// site resolution failed or writes are suspended, msg already explains why
 
-			if _, err := bot.Send(msg); err != nil {
-				log.Printf("Bot send error: %v", err)
-			}
+	case "start":
+		lang := chatLanguage(s, update.Message.Chat.ID)
+		msg.Text = tr(lang, "start.title") + tr(lang, "start.commands")
+		if tenants != nil {
+			msg.Text += tr(lang, "start.site")
+		}
+		if isAdminChat(update.Message.Chat.ID) {
+			msg.Text += tr(lang, "start.readonly")
+		}
+		if isSiteAdmin(update.Message.Chat.ID) {
+			msg.Text += tr(lang, "start.admin")
 		}
-	}()
-}
 
-func handleCallback(query *tgbotapi.CallbackQuery) {
-	// Simplified callback handler
-	callback := tgbotapi.NewCallback(query.ID, "OK")
-	bot.Request(callback)
-}
+	case "cancel":
+		cancelDevicePairing(s, update.Message.Chat.ID)
+		msg.Text = tr(chatLanguage(s, update.Message.Chat.ID), "cancelled")
 
-func handleRegisterEmployee(message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
-	args := strings.Fields(message.CommandArguments())
-	if len(args) < 4 {
-		msg.Text = "Usage: `/register_employee <MAC> <Name> <Code> <Dept>`"
-		return
+	case "getid":
+		msg.Text = fmt.Sprintf("Chat ID: `%d`", update.Message.Chat.ID)
+
+	case "site":
+		handleSite(update.Message, &msg)
+
+	case "readonly":
+		handleReadOnly(update.Message, &msg)
+
+	case "scanners":
+		handleScanners(s, &msg)
+
+	case "pair_scanner":
+		handlePairScanner(s, update.Message, &msg)
+
+	case "scanner_profile":
+		handleScannerProfile(s, update.Message, &msg)
+
+	case "scanner_token":
+		handleScannerToken(s, update.Message, &msg)
+
+	case "rename_scanner":
+		handleRenameScanner(s, update.Message, &msg)
+
+	case "queues":
+		handleQueues(s, update.Message, &msg)
+
+	case "whoisin":
+		handleWhoIsIn(s, update.Message, &msg)
+
+	case "lock_period":
+		handlePeriodLock(s, models.PeriodActionLock, update.Message, &msg)
+
+	case "unlock_period":
+		handlePeriodLock(s, models.PeriodActionUnlock, update.Message, &msg)
+
+	case "register_employee":
+		handleRegisterEmployee(s, update.Message, &msg)
+
+	case "pair":
+		handlePair(s, update.Message, &msg)
+
+	case "register_guest":
+		handleRegisterGuest(s, update.Message, &msg)
+
+	case "myinfo":
+		handleMyInfo(s, update.Message.Chat.ID, &msg)
+
+	case "add_device":
+		handleAddDevice(s, update.Message, &msg)
+
+	case "remove_device":
+		handleRemoveDevice(s, update.Message, &msg)
+
+	case "today":
+		handleToday(s, update.Message.Chat.ID, &msg)
+
+	case "checkin":
+		handleSelfCheckIn(s, update.Message, &msg)
+
+	case "checkout":
+		handleSelfCheckOut(s, update.Message, &msg)
+
+	case "history":
+		handleHistory(s, update.Message, &msg)
+
+	case "monthly":
+		handleMonthly(s, update.Message, &msg)
+
+	case "export":
+		handleExport(s, update.Message, &msg)
+
+	case "employees":
+		handleEmployees(s, update.Message, &msg)
+
+	case "pending_devices":
+		handlePendingDevices(s, update.Message, &msg)
+
+	case "activate":
+		handleActivation(s, update.Message, &msg, true)
+
+	case "deactivate":
+		handleActivation(s, update.Message, &msg, false)
+
+	case "notifications":
+		handleNotifications(s, update.Message, &msg)
+
+	case "language":
+		handleLanguage(s, update.Message, &msg)
+
+	case "privacy":
+		handlePrivacy(s, update.Message, &msg)
+
+	case "set_schedule":
+		handleSetSchedule(s, update.Message, &msg)
+
+	case "manual_checkin":
+		handleManualCheckIn(s, update.Message, &msg)
+
+	case "late_approval":
+		handleLateApproval(s, update.Message, &msg)
+
+	default:
+		msg.Text = tr(chatLanguage(s, update.Message.Chat.ID), "unknown_command")
 	}
 
-	err := registerEmployee(args[0], message.Chat.ID, args[1], args[2], strings.Join(args[3:], " "))
-	if err != nil {
-		msg.Text = fmt.Sprintf("❌ Error: %v", err)
-	} else {
-		msg.Text = fmt.Sprintf("✅ Registered!\nName: %s\nCode: %s", args[1], args[2])
+	if siteCommands[command] {
+		msg.Text = withBanner(s, msg.Text)
+	}
+
+	if err := send(msg); err != nil {
+		log.Printf("Bot send error: %v", err)
 	}
 }
 
-func handleMyInfo(chatID int64, msg *tgbotapi.MessageConfig) {
-	emp, err := getEmployeeByChat(chatID)
-	if err != nil {
-		msg.Text = "❌ Not registered. Use /register_employee"
-		return
+// errNotRegistered means the chat has no active employee record
+var errNotRegistered = errors.New("not registered")
+
+// unavailableMessage answers data commands that failed with nothing cached to fall back on
+const unavailableMessage = "❌ ไม่สามารถดึงข้อมูลได้ในขณะนี้ กรุณาลองใหม่ภายหลัง"
+
+// siteCommands are the commands that read or write a tenant's data
+var siteCommands = map[string]bool{
+	"scanners":          true,
+	"register_employee": true,
+	"pair":              true,
+	"register_guest":    true,
+	"myinfo":            true,
+	"add_device":        true,
+	"remove_device":     true,
+	"today":             true,
+	"checkin":           true,
+	"checkout":          true,
+	"history":           true,
+	"monthly":           true,
+	"notifications":     true,
+	"language":          true,
+	"privacy":           true,
+	"pair_scanner":      true,
+	"scanner_profile":   true,
+	"scanner_token":     true,
+	"rename_scanner":    true,
+	"queues":            true,
+	"whoisin":           true,
+	"export":            true,
+	"employees":         true,
+	"pending_devices":   true,
+	"activate":          true,
+	"deactivate":        true,
+	"lock_period":       true,
+	"unlock_period":     true,
+	"set_schedule":      true,
+	"manual_checkin":    true,
+	"late_approval":     true,
+}
+
+// writesData reports whether a command writes to PocketBase
+func writesData(command, args string) bool {
+	switch command {
+	case "register_employee", "pair", "register_guest", "set_schedule", "manual_checkin", "late_approval", "scanner_token",
+		"rename_scanner", "activate", "deactivate", "add_device", "remove_device", "checkin", "checkout":
+		return true
+	case "notifications", "language", "privacy", "lock_period", "unlock_period", "scanner_profile":
+		return strings.TrimSpace(args) != ""
 	}
-	msg.Text = fmt.Sprintf("👤 *Info*\nName: %s\nCode: %s\nDept: %s\nMAC: %s",
-		emp.Name, emp.EmployeeCode, emp.Department, emp.MacAddress)
+	return false
 }
 
-func handleToday(chatID int64, msg *tgbotapi.MessageConfig) {
-	att, err := getTodayAttendance(chatID)
-	if err != nil || att == nil {
-		msg.Text = "No check-in today"
+// isAdminChat reports whether chatID is one of the global admin chats (AUTHORIZED_CHAT_IDS),
+// a user or a group whose members all count as admins
+func isAdminChat(chatID int64) bool {
+	return chatID != 0 && slices.Contains(adminChatIDs, chatID)
+}
+
+// handleReadOnly shows or switches maintenance read-only mode; admin chat only
+func handleReadOnly(message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	if !isAdminChat(message.Chat.ID) || systemStatus == nil {
+		msg.Text = "❌ คำสั่งนี้สำหรับผู้ดูแลระบบเท่านั้น"
 		return
 	}
-	msg.Text = fmt.Sprintf("📊 *Today*\nIn: %s\nStatus: %s",
-		att.CheckInTime.Format("15:04"), att.Status)
-}
 
-func handleHistory(message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
-	history, err := getAttendanceHistory(message.Chat.ID, 7)
-	if err != nil || len(history) == 0 {
-		msg.Text = "No history found"
+	switch strings.ToLower(strings.TrimSpace(message.CommandArguments())) {
+	case "":
+	case "on":
+		if systemStatus.SetReadOnly(true) {
+			log.Printf("🛠️ Read-only mode switched on from an admin chat")
+		}
+	case "off":
+		if systemStatus.SetReadOnly(false) {
+			log.Printf("🛠️ Read-only mode switched off from an admin chat")
+		}
+	default:
+		msg.Text = "Usage: `/readonly on|off`"
 		return
 	}
-	text := "📅 *History*\n\n"
-	for _, h := range history {
-		text += fmt.Sprintf("%s: %s\n", h.CreatedDate.Format("02/01"), h.Status)
+
+	if systemStatus.ReadOnly() {
+		msg.Text = fmt.Sprintf("🛠️ โหมดอ่านอย่างเดียว: *เปิด* ตั้งแต่ %s\nการตรวจจับจะถูกเก็บไว้ในคิวจนกว่าจะปิดโหมด",
+			systemStatus.ReadOnlySince().Format("02/01 15:04"))
+	} else {
+		msg.Text = "✅ โหมดอ่านอย่างเดียว: *ปิด*"
 	}
-	msg.Text = text
 }
 
-// REST API Functions
+// handleSite binds an employee chat to a tenant by join code
+func handleSite(message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	if tenants == nil {
+		msg.Text = "ระบบนี้มีสาขาเดียว ไม่ต้องเลือกสาขา"
+		return
+	}
 
-func getActiveScanners() ([]string, error) {
-	if pbURL == "" {
-		return nil, fmt.Errorf("PocketBase URL not set")
+	code := strings.TrimSpace(message.CommandArguments())
+	if code == "" {
+		if s, err := siteFor(message.Chat.ID); err == nil {
+			msg.Text = fmt.Sprintf("🏥 สาขาปัจจุบัน: `%s`", s.id)
+		} else {
+			msg.Text = "Usage: `/site <รหัสเข้าร่วม>`"
+		}
+		return
 	}
 
-	url := fmt.Sprintf("%s/api/collections/scanners/records?sort=-last_seen", pbURL)
-	req, _ := http.NewRequest("GET", url, nil)
-	addAuthHeader(req)
-	resp, err := httpClient.Do(req)
+	t, err := bindChat(message.Chat.ID, code)
 	if err != nil {
-		return nil, err
+		msg.Text = fmt.Sprintf("❌ %v", err)
+		return
 	}
-	defer resp.Body.Close()
+	name := t.Name
+	if name == "" {
+		name = t.ID
+	}
+	log.Printf("🏥 A chat was bound to tenant %s", t.ID)
+	msg.Text = fmt.Sprintf("✅ เลือกสาขา *%s* แล้ว", name)
+}
 
-	var result struct {
-		Items []struct {
-			ScannerMac string `json:"scanner_mac"`
-			LastSeen   string `json:"last_seen"`
-		} `json:"items"`
+// handleRegisterEmployee registers the chat's employee from one line, or starts the
+// step-by-step conversation when no arguments are given
+func handleRegisterEmployee(s *site, message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	if !message.Chat.IsPrivate() {
+		msg.Text = privateChatOnlyMessage
+		return
+	}
+	lang := chatLanguage(s, message.Chat.ID)
+	args := strings.Fields(message.CommandArguments())
+	if len(args) == 0 {
+		startRegistration(message.Chat.ID, msg, lang)
+		return
+	}
+	if len(args) < 4 {
+		msg.Text = tr(lang, "registration.usage")
+		return
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+	mac, err := macaddr.Normalize(args[0])
+	if err != nil {
+		msg.Text = invalidMACMessage(args[0], lang)
+		return
 	}
 
-	var scanners []string
-	for _, item := range result.Items {
-		scanners = append(scanners, fmt.Sprintf("- `%s` (%s)", item.ScannerMac, item.LastSeen))
+	err = registerEmployee(s, mac, message.Chat.ID, args[1], args[2], strings.Join(args[3:], " "), "")
+	if errors.Is(err, repository.ErrEmployeeExists) {
+		msg.Text = tr(lang, "mac_exists")
+	} else if errors.Is(err, ErrNotPrivateChat) {
+		msg.Text = privateChatOnlyMessage
+	} else if err != nil {
+		msg.Text = tr(lang, "error", err)
+	} else {
+		msg.Text = tr(lang, "registration.registered", args[1], args[2], tr(lang, "privacy.question"))
+		msg.ReplyMarkup = consentButtons(lang)
 	}
-	return scanners, nil
 }
 
-func registerEmployee(mac string, chatID int64, name, code, dept string) error {
-	if pbURL == "" {
-		return fmt.Errorf("PocketBase URL not set")
+// invalidMACMessage explains in lang which MAC notations are accepted
+func invalidMACMessage(input, lang string) string {
+	formats := make([]string, len(macaddr.AcceptedFormats))
+	for i, f := range macaddr.AcceptedFormats {
+		formats[i] = "`" + f + "`"
 	}
+	return tr(lang, "invalid_mac", strings.ReplaceAll(input, "`", ""), strings.Join(formats, "\n"))
+}
 
-	url := fmt.Sprintf("%s/api/collections/employees/records", pbURL)
-	data := map[string]interface{}{
-		"mac_address":      strings.ToUpper(mac),
-		"telegram_chat_id": chatID,
-		"name":             name,
-		"employee_code":    code,
-		"department":       dept,
-		"is_active":        true,
+func handleMyInfo(s *site, chatID int64, msg *tgbotapi.MessageConfig) {
+	emp, err := getEmployeeByChat(s, chatID)
+	stale := ""
+	if err != nil && !errors.Is(err, errNotRegistered) {
+		if cached, ok := cachedEmployeeFor(s, chatID); ok {
+			emp, err = &cached.employee, nil
+			stale = "\n\n" + staleNote(cached.fetchedAt)
+		}
+	}
+	if errors.Is(err, errNotRegistered) {
+		msg.Text = tr(defaultLanguage, "not_registered")
+		return
 	}
-
-	jsonData, _ := json.Marshal(data)
-	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	req.Header.Set("Content-Type", "application/json")
-	addAuthHeader(req)
-	resp, err := httpClient.Do(req)
 	if err != nil {
-		return err
+		msg.Text = tr(chatLanguage(s, chatID), "unavailable")
+		return
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("%s: %s", resp.Status, string(body))
+	lang := employeeLanguage(emp)
+	late, devices := "", ""
+	if stale == "" {
+		late = approvedLateLine(s, emp.ID, lang)
+		devices = devicesLines(s, emp.ID, lang)
 	}
-	return nil
+	msg.Text = tr(lang, "myinfo",
+		markdown.Escape(emp.Name), markdown.Escape(emp.EmployeeCode), markdown.Escape(emp.Department), markdown.Escape(emp.MacAddress)) + devices + gracePeriodLine(s, emp, lang) + late + stale
 }
 
-func getEmployeeByChat(chatID int64) (*Employee, error) {
-	if pbURL == "" {
-		return nil, fmt.Errorf("PocketBase URL not set")
-	}
+var (
+	gracePeriodsMu sync.RWMutex
+	gracePeriods   = make(map[string]time.Duration) // tenant ID → GRACE_PERIOD_MINUTES
+)
 
-	filter := fmt.Sprintf("telegram_chat_id=%d&&is_active=true", chatID)
-	url := fmt.Sprintf("%s/api/collections/employees/records?filter=%s&limit=1", pbURL, filter)
+// SetGracePeriod sets the grace period of the tenant's employees without their own
+func SetGracePeriod(tenantID string, d time.Duration) {
+	gracePeriodsMu.Lock()
+	gracePeriods[tenantID] = d
+	gracePeriodsMu.Unlock()
+}
 
-	req, _ := http.NewRequest("GET", url, nil)
-	addAuthHeader(req)
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+// gracePeriodLine is the /myinfo line with the employee's effective grace period and the
+// time from which a check-in counts as late
+func gracePeriodLine(s *site, emp *models.Employee, lang string) string {
+	gracePeriodsMu.RLock()
+	site, ok := gracePeriods[s.id]
+	gracePeriodsMu.RUnlock()
+	if !ok {
+		site = services.DefaultGracePeriod
+	}
+	grace := services.GracePeriod(emp, site)
+	line := tr(lang, "myinfo.grace", int(grace.Minutes()))
+	if start, err := time.Parse("15:04:05", emp.WorkStartTime); err == nil {
+		line += tr(lang, "myinfo.late_from", start.Add(grace).Format("15:04"))
+	}
+	return line
+}
 
-	var result struct {
-		Items []Employee `json:"items"`
+func handleToday(s *site, chatID int64, msg *tgbotapi.MessageConfig) {
+	att, err := getTodayAttendance(s, chatID)
+	lang := chatLanguage(s, chatID)
+	if err != nil && !errors.Is(err, errNotRegistered) {
+		msg.Text = tr(lang, "unavailable")
+		return
 	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+	if att == nil {
+		msg.Text = tr(lang, "today.none")
+		return
 	}
+	msg.Text = todayText(att, time.Now(), lang)
+}
+
+// todayText renders the day's attendance for /today in lang; until the employee checks
+// out the hours worked run up to now
+func todayText(att *models.Attendance, now time.Time, lang string) string {
+	in := att.CheckInTime.In(location)
+	out := tr(lang, "today.not_out")
+	end := now
+	if !att.CheckOutTime.IsZero() {
+		out = att.CheckOutTime.In(location).Format("15:04")
+		end = att.CheckOutTime
+	}
+	text := tr(lang, "today", in.Format("15:04"), out, formatWorked(end.Sub(in)))
+	if att.ScannerMac != "" {
+		text += tr(lang, "today.scanner", att.ScannerMac)
+	}
+	return text + tr(lang, "today.status") + statusLabel(att.Status, lang)
+}
 
-	if len(result.Items) == 0 {
-		return nil, fmt.Errorf("not found")
+// formatWorked renders hours worked as "7h 45m"; a check-out corrected to before the
+// check-in counts as no time rather than a negative one
+func formatWorked(d time.Duration) string {
+	if d < 0 {
+		d = 0
 	}
+	return fmt.Sprintf("%dh %02dm", int(d.Hours()), int(d.Minutes())%60)
+}
 
-	return &result.Items[0], nil
+// statusLabel renders an attendance status for /today and /history in lang; check-ins
+// on a weekend or holiday stand out from on-time ones
+func statusLabel(status, lang string) string {
+	if status == models.AttendanceStatusOffDay {
+		return tr(lang, "status.offday")
+	}
+	return status
 }
 
-func getTodayAttendance(chatID int64) (*Attendance, error) {
-	emp, err := getEmployeeByChat(chatID)
+// handleNotifications shows and edits per-employee preferences
+func handleNotifications(s *site, message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	emp, err := getEmployeeByChat(s, message.Chat.ID)
 	if err != nil {
-		return nil, err
+		msg.Text = tr(defaultLanguage, "not_registered")
+		return
 	}
+	lang := employeeLanguage(emp)
 
-	today := time.Now().Format("2006-01-02")
-	filter := fmt.Sprintf("employee_id=%s&&created_date='%s'", emp.ID, today)
-	url := fmt.Sprintf("%s/api/collections/attendance/records?filter=%s&sort=-check_in_time&limit=1", pbURL, filter)
-
-	req, _ := http.NewRequest("GET", url, nil)
-	addAuthHeader(req)
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return nil, err
+	args := strings.Fields(strings.ToLower(message.CommandArguments()))
+	if len(args) == 0 {
+		msg.Text = notificationSettingsText(emp, lang)
+		return
 	}
-	defer resp.Body.Close()
 
-	var result struct {
-		Items []Attendance `json:"items"`
+	// Check-in messages and quiet hours are the employee's own notification settings
+	if len(args) == 1 {
+		args = append([]string{string(models.NotificationCheckIn)}, args...)
+	}
+	if len(args) == 2 && (args[0] == string(models.NotificationCheckIn) || args[0] == "quiet") {
+		prefs := notificationPrefs(emp)
+		switch {
+		case args[0] == "quiet":
+			quiet, err := normalizeQuietHours(args[1])
+			if err != nil {
+				msg.Text = tr(lang, "settings.quiet_invalid")
+				return
+			}
+			prefs.QuietHours = quiet
+		case args[1] == "on" || args[1] == "off":
+			prefs.CheckIn = args[1] == "on"
+		default:
+			msg.Text = notificationsUsage
+			return
+		}
+		if err := saveNotificationPrefs(s, emp, prefs); err != nil {
+			log.Printf("❌ Saving notification settings of %s failed: %v", emp.Name, err)
+			msg.Text = tr(lang, "error", err)
+			return
+		}
+		msg.Text = tr(lang, "saved") + "\n\n" + notificationSettingsText(emp, lang)
+		return
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+	if len(args) != 2 || (args[1] != "on" && args[1] != "off") {
+		msg.Text = notificationsUsage
+		return
 	}
+	on := args[1] == "on"
 
-	if len(result.Items) == 0 {
-		return nil, nil
+	if args[0] == "board" {
+		emp.ShowOnBoard = on
+		if err := updateSettings(s, emp); err != nil {
+			msg.Text = tr(lang, "error", err)
+			return
+		}
+		msg.Text = tr(lang, "saved") + "\n\n" + notificationSettingsText(emp, lang)
+		return
 	}
 
-	return &result.Items[0], nil
+	category, ok := notificationCategory(args[0])
+	if !ok {
+		msg.Text = notificationsUsage
+		return
+	}
+	emp.MutedNotifications = setMuted(emp.MutedNotifications, category, !on)
+	if err := updateSettings(s, emp); err != nil {
+		msg.Text = tr(lang, "error", err)
+		return
+	}
+	msg.Text = tr(lang, "saved") + "\n\n" + notificationSettingsText(emp, lang)
 }
 
-func getAttendanceHistory(chatID int64, days int) ([]Attendance, error) {
-	emp, err := getEmployeeByChat(chatID)
-	if err != nil {
-		return nil, err
+// notificationsUsage lists the settings /notifications can change
+var notificationsUsage = func() string {
+	names := []string{"board"}
+	for _, c := range models.NotificationCategories {
+		names = append(names, string(c))
+	}
+	return "Usage: `/notifications on|off`, `/notifications <" + strings.Join(names, "|") + "> on|off` " +
+		"or `/notifications quiet <HH:MM-HH:MM|off>`"
+}()
+
+// notificationCategory looks up a mutable notification category by name
+func notificationCategory(name string) (models.NotificationCategory, bool) {
+	for _, c := range models.NotificationCategories {
+		if string(c) == name {
+			return c, true
+		}
 	}
+	return "", false
+}
 
-	startDate := time.Now().AddDate(0, 0, -days).Format("2006-01-02")
-	filter := fmt.Sprintf("employee_id=%s&&created_date>='%s'", emp.ID, startDate)
-	url := fmt.Sprintf("%s/api/collections/attendance/records?filter=%s&sort=-created_date", pbURL, filter)
+// setMuted returns the muted categories with category added or removed
+func setMuted(muted []string, category models.NotificationCategory, mute bool) []string {
+	out := []string{}
+	for _, c := range muted {
+		if c != string(category) {
+			out = append(out, c)
+		}
+	}
+	if mute {
+		out = append(out, string(category))
+	}
+	return out
+}
 
-	req, _ := http.NewRequest("GET", url, nil)
-	addAuthHeader(req)
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return nil, err
+// notificationSettingsText renders the current preferences of an employee in lang
+func notificationSettingsText(emp *models.Employee, lang string) string {
+	onOff := func(on bool) string {
+		if on {
+			return tr(lang, "settings.on")
+		}
+		return tr(lang, "settings.off")
 	}
-	defer resp.Body.Close()
+	board := onOff(emp.ShowOnBoard)
+	displayName := emp.DisplayName
+	if displayName == "" {
+		if fields := strings.Fields(emp.Name); len(fields) > 0 {
+			displayName = fields[0]
+		}
+	}
+	text := tr(lang, "settings", board, markdown.Escape(displayName))
+	for _, c := range models.NotificationCategories {
+		text += fmt.Sprintf("%s (`%s`): *%s*\n", tr(lang, "category."+string(c)), c, onOff(!emp.NotificationMuted(c)))
+	}
+	quiet := tr(lang, "settings.quiet_unset")
+	if emp.QuietHours != "" {
+		quiet = "`" + emp.QuietHours + "`"
+	}
+	text += tr(lang, "settings.quiet", quiet)
+	return text + "\n" + strings.Replace(notificationsUsage, "Usage:", tr(lang, "settings.change"), 1)
+}
+
+// Repository Functions
 
-	var result struct {
-		Items []Attendance `json:"items"`
+// registerEmployee creates the chat's employee record; an empty workStart leaves the
+// default start time. Only private chats can be registered.
+func registerEmployee(s *site, mac string, chatID int64, name, code, dept, workStart string) error {
+	if err := CheckPersonalChat(chatID); err != nil {
+		return err
 	}
+	emp := &models.Employee{
+		MacAddress:     mac,
+		TelegramChatID: chatID,
+		Name:           name,
+		EmployeeCode:   code,
+		Department:     dept,
+		WorkStartTime:  workStart,
+		IsActive:       true,
+		ShowOnBoard:    true,
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+		// Nothing beyond check-ins is tracked until the employee answers the consent question
+		PresenceOptOut: true,
+	}
+	if err := s.employees.Register(pollContext(), emp); err != nil {
+		return err
 	}
+	invalidateEmployee(s.id, mac)
+	forgetLanguage(chatID)
+	return nil
+}
 
-	return result.Items, nil
+// updateSettings saves the settings the employee changed on emp
+func updateSettings(s *site, emp *models.Employee) error {
+	return s.employees.UpdateSettings(pollContext(), emp)
 }
 
-// UpdateScannerActivity updates scanner via REST API
-func UpdateScannerActivity(scannerMac string) {
-	if pbURL == "" {
-		return
+func getEmployeeByChat(s *site, chatID int64) (*models.Employee, error) {
+	emp, err := s.employees.GetByChatID(pollContext(), chatID)
+	if errors.Is(err, repository.ErrEmployeeNotFound) {
+		return nil, errNotRegistered
 	}
-
-	// Try to find existing
-	filter := fmt.Sprintf("scanner_mac='%s'", scannerMac)
-	findURL := fmt.Sprintf("%s/api/collections/scanners/records?filter=%s&limit=1", pbURL, filter)
-
-	req, _ := http.NewRequest("GET", findURL, nil)
-	addAuthHeader(req)
-	resp, err := httpClient.Do(req)
 	if err != nil {
-		return
+		return nil, fmt.Errorf("failed to get employee: %w", err)
 	}
 
-	var findResult struct {
-		Items []struct {
-			ID string `json:"id"`
-		} `json:"items"`
-	}
-	json.NewDecoder(resp.Body).Decode(&findResult)
-	resp.Body.Close()
+	cacheEmployee(s, chatID, emp)
+	return emp, nil
+}
 
-	data := map[string]interface{}{
-		"scanner_mac": scannerMac,
-		"last_seen":   time.Now().Format(time.RFC3339),
+// getTodayAttendance returns the latest check-in of the chat's employee on the day of
+// their current shift, or nil. GetTodayByEmployee would go by the calendar day, which
+// misses a night shift's check-in after midnight.
+func getTodayAttendance(s *site, chatID int64) (*models.Attendance, error) {
+	emp, err := getEmployeeByChat(s, chatID)
+	if err != nil {
+		return nil, err
 	}
-	jsonData, _ := json.Marshal(data)
 
-	if len(findResult.Items) > 0 {
-		// Update
-		updateURL := fmt.Sprintf("%s/api/collections/scanners/records/%s", pbURL, findResult.Items[0].ID)
-		req, _ := http.NewRequest("PATCH", updateURL, bytes.NewBuffer(jsonData))
-		req.Header.Set("Content-Type", "application/json")
-		addAuthHeader(req)
-		httpClient.Do(req)
-	} else {
-		// Create
-		createURL := fmt.Sprintf("%s/api/collections/scanners/records", pbURL)
-		req, _ := http.NewRequest("POST", createURL, bytes.NewBuffer(jsonData))
-		req.Header.Set("Content-Type", "application/json")
-		addAuthHeader(req)
-		httpClient.Do(req)
+	// A night shift's attendance is on the day its shift started
+	day := emp.ShiftDay(time.Now().In(location))
+	records, _, err := s.attendance.ListHistory(pollContext(), emp.ID, day, day, repository.Page{Number: 1, Size: 1})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attendance: %w", err)
 	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	return &records[0], nil
 }
 
-// SendNotification sends message to admin
+// SendNotification sends message to every admin chat
 func SendNotification(message string) {
-	if bot == nil || targetChatID == 0 {
+	if bot == nil {
 		return
 	}
-	msg := tgbotapi.NewMessage(targetChatID, message)
-	msg.ParseMode = "Markdown"
-	if _, err := bot.Send(msg); err != nil {
-		log.Printf("Failed to send: %v", err)
+	for _, chatID := range adminChatIDs {
+		msg := tgbotapi.NewMessage(chatID, message)
+		msg.ParseMode = "Markdown"
+		enqueue(msg)
 	}
 }
 
@@ -416,28 +770,5 @@ func SendPersonalNotification(chatID int64, message string) {
 	}
 	msg := tgbotapi.NewMessage(chatID, message)
 	msg.ParseMode = "Markdown"
-	if _, err := bot.Send(msg); err != nil {
-		log.Printf("Failed to send to %d: %v", chatID, err)
-	}
-}
-
-// Types
-type Employee struct {
-	ID             string `json:"id"`
-	MacAddress     string `json:"mac_address"`
-	TelegramChatID int64  `json:"telegram_chat_id"`
-	Name           string `json:"name"`
-	EmployeeCode   string `json:"employee_code"`
-	Department     string `json:"department"`
-	WorkStartTime  string `json:"work_start_time"`
-	IsActive       bool   `json:"is_active"`
-}
-
-type Attendance struct {
-	ID          string    `json:"id"`
-	EmployeeID  string    `json:"employee_id"`
-	CheckInTime time.Time `json:"check_in_time"`
-	ScannerMac  string    `json:"scanner_mac"`
-	Status      string    `json:"status"`
-	CreatedDate time.Time `json:"created_date"`
+	enqueue(msg)
 }
@@ -0,0 +1,134 @@
+package bot
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/models"
+)
+
+// CallbackHandler answers an inline button press from chatID and returns the text
+// that replaces the prompt
+type CallbackHandler func(ctx context.Context, chatID int64, data string) (string, error)
+
+var (
+	callbacksMu sync.RWMutex
+	callbacks   = make(map[string]CallbackHandler) // tenant ID + "|" + data prefix → handler
+)
+
+// HandleCallbacks routes button presses whose data starts with prefix + ":" from chats
+// of the given tenant to h
+func HandleCallbacks(tenantID, prefix string, h CallbackHandler) {
+	callbacksMu.Lock()
+	defer callbacksMu.Unlock()
+	callbacks[tenantID+"|"+prefix] = h
+}
+
+// handleCallback dispatches a button press to the handler registered for the chat's tenant
+func handleCallback(query *tgbotapi.CallbackQuery) {
+	if query.Message == nil {
+		bot.Request(tgbotapi.NewCallback(query.ID, ""))
+		return
+	}
+	chatID := query.Message.Chat.ID
+	rememberChatType(query.Message.Chat)
+
+	// Leave the buttons in place so they can be pressed again after maintenance
+	if readOnly() {
+		bot.Request(tgbotapi.NewCallbackWithAlert(query.ID, maintenanceMessage))
+		return
+	}
+
+	answer := "OK"
+	reply, err := dispatchCallback(chatID, query.Message.MessageID, query.From, query.Data)
+	if err != nil {
+		// The data may carry a MAC address, so only its prefix is logged
+		prefix, _, _ := strings.Cut(query.Data, ":")
+		log.Printf("❌ Callback %q failed: %v", prefix, err)
+		answer = "❌ ไม่สามารถดำเนินการได้"
+		reply = ""
+	}
+	if _, err := bot.Request(tgbotapi.NewCallback(query.ID, answer)); err != nil {
+		log.Printf("Failed to answer callback: %v", err)
+	}
+	if reply == "" {
+		return
+	}
+
+	// Replace the prompt so its buttons cannot be pressed twice
+	edit := tgbotapi.NewEditMessageText(chatID, query.Message.MessageID, reply)
+	edit.ParseMode = "Markdown"
+	if _, err := bot.Send(edit); err != nil {
+		log.Printf("Failed to edit message: %v", err)
+	}
+}
+
+// callbackUserKey is the context key of the user who pressed the button
+type callbackUserKey struct{}
+
+// callbackUser returns the user who pressed the button, nil if unknown
+func callbackUser(ctx context.Context) *tgbotapi.User {
+	user, _ := ctx.Value(callbackUserKey{}).(*tgbotapi.User)
+	return user
+}
+
+// dispatchCallback finds the handler for the chat's tenant and the data prefix. Time
+// picker, consent, registration, history and employee list buttons are handled for every tenant.
+func dispatchCallback(chatID int64, messageID int, from *tgbotapi.User, data string) (string, error) {
+	s, err := siteFor(chatID)
+	if err != nil {
+		return "", err
+	}
+	prefix, _, _ := strings.Cut(data, ":")
+
+	ctx, cancel := context.WithTimeout(context.WithValue(pollContext(), callbackUserKey{}, from), 10*time.Second)
+	defer cancel()
+	switch prefix {
+	case timePickerPrefix:
+		return handleTimePicker(ctx, s, chatID, messageID, data)
+	case privacyPrefix:
+		return handlePrivacyCallback(ctx, s, chatID, data)
+	case registrationPrefix:
+		return handleRegistrationCallback(ctx, s, chatID, data)
+	case historyPrefix:
+		return handleHistoryCallback(s, chatID, messageID, data)
+	case employeesPrefix:
+		return handleEmployeesCallback(ctx, s, chatID, messageID, data)
+	}
+
+	callbacksMu.RLock()
+	h, ok := callbacks[s.id+"|"+prefix]
+	callbacksMu.RUnlock()
+	if !ok {
+		return "", nil
+	}
+	return h(ctx, chatID, data)
+}
+
+// SendPrompt sends every admin chat (AUTHORIZED_CHAT_IDS) a message with one inline button
+// per row; the first admin to press a button answers for all
+func SendPrompt(message string, buttons []models.PromptButton) {
+	for _, chatID := range adminChatIDs {
+		SendPersonalPrompt(chatID, message, buttons)
+	}
+}
+
+// SendPersonalPrompt sends a user a message with one inline button per row
+func SendPersonalPrompt(chatID int64, message string, buttons []models.PromptButton) {
+	if bot == nil {
+		return
+	}
+	rows := make([][]tgbotapi.InlineKeyboardButton, len(buttons))
+	for i, b := range buttons {
+		rows[i] = tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(b.Label, b.Data))
+	}
+	msg := tgbotapi.NewMessage(chatID, message)
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	enqueue(msg)
+}
@@ -0,0 +1,68 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// ErrNotPrivateChat is returned for an employee chat ID that is a group or channel;
+// personal notifications sent there would reach everyone in it
+var ErrNotPrivateChat = errors.New("not a private chat")
+
+var (
+	chatTypesMu sync.Mutex
+	chatTypes   = make(map[int64]string) // chat → Telegram chat type
+)
+
+// rememberChatType caches the type of a chat the bot has heard from
+func rememberChatType(chat *tgbotapi.Chat) {
+	if chat == nil || chat.Type == "" {
+		return
+	}
+	chatTypesMu.Lock()
+	chatTypes[chat.ID] = chat.Type
+	chatTypesMu.Unlock()
+}
+
+// ChatType returns the Telegram type of a chat ("private", "group", "supergroup" or
+// "channel"), asking Telegram with getChat the first time
+func ChatType(chatID int64) (string, error) {
+	chatTypesMu.Lock()
+	typ, ok := chatTypes[chatID]
+	chatTypesMu.Unlock()
+	if ok {
+		return typ, nil
+	}
+	if bot == nil {
+		return "", errors.New("telegram bot is not initialized")
+	}
+
+	chat, err := bot.GetChat(tgbotapi.ChatInfoConfig{ChatConfig: tgbotapi.ChatConfig{ChatID: chatID}})
+	if err != nil {
+		return "", fmt.Errorf("failed to look up chat %d: %w", chatID, err)
+	}
+	rememberChatType(&chat)
+	return chat.Type, nil
+}
+
+// CheckPersonalChat returns ErrNotPrivateChat unless chatID is a private chat with a user
+func CheckPersonalChat(chatID int64) error {
+	// Users have positive IDs, so a negative one is a group or channel without asking
+	if chatID < 0 {
+		return fmt.Errorf("chat %d: %w", chatID, ErrNotPrivateChat)
+	}
+	typ, err := ChatType(chatID)
+	if err != nil {
+		return err
+	}
+	if typ != "private" {
+		return fmt.Errorf("chat %d is a %s: %w", chatID, typ, ErrNotPrivateChat)
+	}
+	return nil
+}
+
+// privateChatOnlyMessage answers registration attempts from a group or channel
+const privateChatOnlyMessage = "❌ ลงทะเบียนได้เฉพาะในแชทส่วนตัวกับบอท เพื่อไม่ให้การแจ้งเตือนส่วนตัวถูกส่งเข้ากลุ่ม"
@@ -0,0 +1,75 @@
+package bot
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestCheckPersonalChat(t *testing.T) {
+	tg := newFakeTelegram(t)
+	tg.chatTypes = map[string]string{"2002": "supergroup"}
+	chatTypes = make(map[int64]string) // earlier tests' updates are remembered
+	t.Cleanup(func() { chatTypes = make(map[int64]string) })
+
+	tests := []struct {
+		name        string
+		chatID      int64
+		wantErr     bool
+		wantGetChat int // getChat calls so far
+	}{
+		{"group ID refused without asking", -100123, true, 0},
+		{"private chat", 1001, false, 1},
+		{"cached", 1001, false, 1},
+		{"positive ID of a group", 2002, true, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckPersonalChat(tt.chatID)
+			if got := errors.Is(err, ErrNotPrivateChat); got != tt.wantErr {
+				t.Errorf("CheckPersonalChat(%d) = %v, want ErrNotPrivateChat %v", tt.chatID, err, tt.wantErr)
+			}
+			if got := tg.count("getChat"); got != tt.wantGetChat {
+				t.Errorf("getChat calls = %d, want %d", got, tt.wantGetChat)
+			}
+		})
+	}
+}
+
+func TestRegisterFromGroupRefused(t *testing.T) {
+	const groupID = -100123
+	writes := 0
+	useSingleSite(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writes++
+		http.NotFound(w, r)
+	}), 0)
+	tg := newFakeTelegram(t)
+	t.Cleanup(func() { chatTypes = make(map[int64]string) })
+
+	for _, command := range []string{"/register_employee", "/register_employee aa:bb:cc:dd:ee:01 Somchai E001 ER"} {
+		t.Run(command, func(t *testing.T) {
+			handleUpdate(commandUpdate(groupID, command))
+			if got := tg.last(t, "sendMessage").params.Get("text"); got != privateChatOnlyMessage {
+				t.Errorf("reply = %q, want %q", got, privateChatOnlyMessage)
+			}
+		})
+	}
+	if writes != 0 {
+		t.Errorf("PocketBase was called %d times", writes)
+	}
+}
+
+func TestNotifierDropsGroupChats(t *testing.T) {
+	tg := newFakeTelegram(t)
+	n := NewNotifier()
+
+	n.SendPersonalNotification(-100123, "ลืมเช็คเอาท์")
+	n.SendPersonalPrompt(-100123, "ลืมเช็คเอาท์", nil)
+	if got := tg.count("sendMessage"); got != 0 {
+		t.Errorf("sent %d messages to a group chat", got)
+	}
+	n.SendPersonalNotification(1001, "ลืมเช็คเอาท์")
+	if got := tg.count("sendMessage"); got != 1 {
+		t.Errorf("sent %d messages to a private chat, want 1", got)
+	}
+}
@@ -0,0 +1,29 @@
+package bot
+
+import (
+	"context"
+	"errors"
+)
+
+// DepartmentAcceptor applies department proposals accepted from the admin digest
+type DepartmentAcceptor interface {
+	Accept(ctx context.Context, data string, actorID int64, actorName string) (string, error)
+}
+
+// departmentCallbackPrefix routes the Accept buttons of the department digest
+const departmentCallbackPrefix = "dept"
+
+// SetDepartmentInference lets the tenant's admin chats accept department proposals
+func SetDepartmentInference(tenantID string, a DepartmentAcceptor) {
+	HandleCallbacks(tenantID, departmentCallbackPrefix, func(ctx context.Context, chatID int64, data string) (string, error) {
+		if !isSiteAdmin(chatID) {
+			return "", errors.New("department proposals are for admin chats only")
+		}
+		user := callbackUser(ctx)
+		if user == nil {
+			return "", errors.New("unknown user")
+		}
+		by := periodLockActor(user)
+		return a.Accept(ctx, data, by.UserID, by.Name)
+	})
+}
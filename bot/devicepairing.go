@@ -0,0 +1,132 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/logging"
+	"med-pulse-bot/internal/macaddr"
+	"med-pulse-bot/internal/repository"
+	"med-pulse-bot/internal/services"
+)
+
+// DevicePairer finds the MAC of a chat's new tag for /pair
+type DevicePairer interface {
+	Start(chatID int64) time.Time
+	Confirm(chatID int64, mac string) error
+	Cancel(chatID int64)
+}
+
+var (
+	devicePairersMu sync.RWMutex
+	devicePairers   = make(map[string]DevicePairer) // tenant ID → pairer
+)
+
+// SetDevicePairing enables /pair for the tenant's employees
+func SetDevicePairing(tenantID string, p DevicePairer) {
+	devicePairersMu.Lock()
+	devicePairers[tenantID] = p
+	devicePairersMu.Unlock()
+
+	HandleCallbacks(tenantID, services.DevicePairingCallbackPrefix, func(ctx context.Context, chatID int64, data string) (string, error) {
+		s, err := siteFor(chatID)
+		if err != nil {
+			return "", err
+		}
+		return handleDevicePairingCallback(s, p, chatID, data)
+	})
+}
+
+// devicePairerFor returns the tenant's pairer, if /pair is enabled
+func devicePairerFor(s *site) (DevicePairer, bool) {
+	devicePairersMu.RLock()
+	defer devicePairersMu.RUnlock()
+	p, ok := devicePairers[s.id]
+	return p, ok
+}
+
+// handlePair listens for the chat's new tag; the strongest device no employee owns is
+// proposed once the window closes
+func handlePair(s *site, message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	if !message.Chat.IsPrivate() {
+		msg.Text = privateChatOnlyMessage
+		return
+	}
+	p, ok := devicePairerFor(s)
+	if !ok {
+		msg.Text = "❌ การจับคู่อุปกรณ์ไม่ได้เปิดใช้งาน"
+		return
+	}
+	closes := p.Start(message.Chat.ID)
+	msg.Text = fmt.Sprintf("📲 *จับคู่อุปกรณ์*\n\nถือแท็กหรือโทรศัพท์ไว้ใกล้ Scanner จนถึง `%s`\n"+
+		"ระบบจะเสนออุปกรณ์ที่สัญญาณแรงที่สุดให้ยืนยัน ยกเลิกได้ด้วย /cancel", closes.In(location).Format("15:04:05"))
+}
+
+// cancelDevicePairing drops the chat's pairing in progress, if any
+func cancelDevicePairing(s *site, chatID int64) {
+	if s == nil {
+		return
+	}
+	if p, ok := devicePairerFor(s); ok {
+		p.Cancel(chatID)
+	}
+}
+
+// handleDevicePairingCallback saves the confirmed MAC as the employee's device, or starts
+// /register_employee with it for a chat not registered yet; Retry listens again
+func handleDevicePairingCallback(s *site, p DevicePairer, chatID int64, data string) (string, error) {
+	action, arg, _ := strings.Cut(strings.TrimPrefix(data, services.DevicePairingCallbackPrefix+":"), ":")
+	switch action {
+	case "retry":
+		p.Cancel(chatID)
+		closes := p.Start(chatID)
+		return fmt.Sprintf("📲 ฟังอีกครั้ง ถือแท็กไว้ใกล้ Scanner จนถึง `%s`", closes.In(location).Format("15:04:05")), nil
+	case "ok":
+	default:
+		return "", fmt.Errorf("invalid device pairing callback %q", data)
+	}
+
+	mac, err := macaddr.Normalize(arg)
+	if err != nil {
+		return "", fmt.Errorf("invalid device pairing callback %q", data)
+	}
+	if err := p.Confirm(chatID, mac); err != nil {
+		return "⌛ การจับคู่หมดเวลา เริ่มใหม่ด้วย /pair", nil
+	}
+
+	emp, err := getEmployeeByChat(s, chatID)
+	if errors.Is(err, errNotRegistered) {
+		userStatesMu.Lock()
+		state := &RegistrationState{Step: stepName, MacAddress: mac, Lang: th, UpdatedAt: time.Now()}
+		userStates[chatID] = state
+		userStatesMu.Unlock()
+		return fmt.Sprintf("📝 *ลงทะเบียนพนักงาน*\nอุปกรณ์: `%s`\nตอบทีละข้อ ยกเลิกได้ด้วย /cancel\n\n%s", mac, state.question()), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	owner, err := s.employees.GetOwnerByMac(pollContext(), mac)
+	if err == nil && owner.ID != emp.ID {
+		return "❌ This MAC address is already registered", nil
+	}
+	if err != nil && !errors.Is(err, repository.ErrEmployeeNotFound) {
+		return "", err
+	}
+	previous := emp.MacAddress
+	emp.MacAddress = mac
+	if err := updateSettings(s, emp); err != nil {
+		return "", fmt.Errorf("failed to save the paired device: %w", err)
+	}
+	invalidateEmployee(s.id, previous)
+	invalidateEmployee(s.id, mac)
+	log.Printf("📲 %s paired device %s (was %s)", emp.Name, logging.MaskMAC(mac), logging.MaskMAC(previous))
+	return fmt.Sprintf("✅ จับคู่อุปกรณ์ `%s` แล้ว\nการตรวจจับอุปกรณ์นี้จะบันทึกเวลาเข้างานของคุณ", mac), nil
+}
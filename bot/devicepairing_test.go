@@ -0,0 +1,138 @@
+package bot
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/services"
+	"med-pulse-bot/internal/tenant"
+)
+
+// fakePairer proposes one MAC to every chat
+type fakePairer struct {
+	proposed string
+	started  []int64
+}
+
+func (f *fakePairer) Start(chatID int64) time.Time {
+	f.started = append(f.started, chatID)
+	return time.Date(2026, 3, 2, 9, 1, 0, 0, location)
+}
+
+func (f *fakePairer) Confirm(chatID int64, mac string) error {
+	if mac != f.proposed {
+		return services.ErrNoPairingProposal
+	}
+	return nil
+}
+
+func (f *fakePairer) Cancel(chatID int64) {}
+
+func TestDevicePairing(t *testing.T) {
+	const (
+		employeeChat = 1001
+		newChat      = 1002
+	)
+	var mu sync.Mutex
+	var patched string
+	useSingleSite(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		filter := r.URL.Query().Get("filter")
+		switch {
+		case r.URL.Path == "/api/collections/employees/records" && strings.Contains(filter, fmt.Sprint(employeeChat)):
+			fmt.Fprintf(w, `{"items":[{"id":"emp1","name":"Somchai","mac_address":"aa:bb:cc:dd:ee:01","telegram_chat_id":%d,"is_active":true}]}`, employeeChat)
+		case r.URL.Path == "/api/collections/employees/records" && strings.Contains(filter, "aa:bb:cc:dd:ee:09"):
+			w.Write([]byte(`{"items":[{"id":"emp2","mac_address":"aa:bb:cc:dd:ee:09"}]}`))
+		case r.URL.Path == "/api/collections/employees/records":
+			w.Write([]byte(`{"items":[]}`))
+		case r.URL.Path == "/api/collections/employees/records/emp1" && r.Method == http.MethodPatch:
+			body, _ := io.ReadAll(r.Body)
+			patched = string(body)
+			w.Write(body)
+		default:
+			http.NotFound(w, r)
+		}
+	}), 0)
+	tg := newFakeTelegram(t)
+	pairer := &fakePairer{proposed: "aa:bb:cc:dd:ee:0a"}
+	SetDevicePairing(tenant.DefaultID, pairer)
+	t.Cleanup(func() {
+		devicePairersMu.Lock()
+		delete(devicePairers, tenant.DefaultID)
+		devicePairersMu.Unlock()
+	})
+
+	t.Run("pair starts listening", func(t *testing.T) {
+		handleUpdate(commandUpdate(employeeChat, "/pair"))
+		if got := tg.last(t, "sendMessage").params.Get("text"); !strings.Contains(got, "`09:01:00`") {
+			t.Errorf("/pair = %q, want the window's close time", got)
+		}
+		if len(pairer.started) != 1 || pairer.started[0] != employeeChat {
+			t.Errorf("started = %v, want chat %d", pairer.started, employeeChat)
+		}
+	})
+
+	t.Run("group chats cannot pair", func(t *testing.T) {
+		handleUpdate(commandUpdate(-100, "/pair"))
+		if got := tg.last(t, "sendMessage").params.Get("text"); got != privateChatOnlyMessage {
+			t.Errorf("/pair in a group = %q, want %q", got, privateChatOnlyMessage)
+		}
+	})
+
+	tests := []struct {
+		name        string
+		chatID      int64
+		data        string
+		want        string
+		wantPatched string
+	}{
+		{"retry", employeeChat, "dp:retry", "ฟังอีกครั้ง", ""},
+		{"expired proposal", employeeChat, "dp:ok:aa:bb:cc:dd:ee:0b", "หมดเวลา", ""},
+		{"registered employee", employeeChat, "dp:ok:AA:BB:CC:DD:EE:0A", "จับคู่อุปกรณ์ `aa:bb:cc:dd:ee:0a` แล้ว", `"mac_address":"aa:bb:cc:dd:ee:0a"`},
+		{"new employee registers", newChat, "dp:ok:aa:bb:cc:dd:ee:0a", "(2/5)", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			patched = ""
+			mu.Unlock()
+			reply, err := dispatchCallback(tt.chatID, 7, nil, tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(reply, tt.want) {
+				t.Errorf("%s replied %q, want it to contain %q", tt.data, reply, tt.want)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if !strings.Contains(patched, tt.wantPatched) || (tt.wantPatched == "" && patched != "") {
+				t.Errorf("PATCH = %q, want %q", patched, tt.wantPatched)
+			}
+		})
+	}
+
+	t.Run("device owned by someone else", func(t *testing.T) {
+		pairer.proposed = "aa:bb:cc:dd:ee:09"
+		reply, err := dispatchCallback(employeeChat, 7, nil, "dp:ok:aa:bb:cc:dd:ee:09")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(reply, "already registered") {
+			t.Errorf("reply = %q, want the MAC refused", reply)
+		}
+	})
+
+	userStatesMu.Lock()
+	state := userStates[newChat]
+	userStatesMu.Unlock()
+	if state == nil || state.MacAddress != "aa:bb:cc:dd:ee:0a" {
+		t.Errorf("registration state = %+v, want it to carry the paired MAC", state)
+	}
+	cancelRegistration(newChat)
+}
@@ -0,0 +1,135 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/logging"
+	"med-pulse-bot/internal/macaddr"
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// handleAddDevice registers another device of the chat's employee; a detection of any of
+// their devices checks them in
+func handleAddDevice(s *site, message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	lang := chatLanguage(s, message.Chat.ID)
+	args := strings.Fields(message.CommandArguments())
+	if len(args) < 2 {
+		msg.Text = tr(lang, "devices.add_usage")
+		return
+	}
+	mac, err := macaddr.Normalize(args[0])
+	if err != nil {
+		msg.Text = invalidMACMessage(args[0], lang)
+		return
+	}
+	emp, ok := deviceOwner(s, message.Chat.ID, msg, lang)
+	if !ok {
+		return
+	}
+	if strings.EqualFold(emp.MacAddress, mac) {
+		msg.Text = tr(lang, "devices.primary")
+		return
+	}
+
+	ctx := pollContext()
+	_, err = s.employees.GetOwnerByMac(ctx, mac)
+	if err == nil {
+		msg.Text = tr(lang, "mac_exists")
+		return
+	}
+	if !errors.Is(err, repository.ErrEmployeeNotFound) {
+		msg.Text = tr(lang, "unavailable")
+		return
+	}
+
+	label := strings.Join(args[1:], " ")
+	err = s.devices.AddDevice(ctx, &models.EmployeeDevice{EmployeeID: emp.ID, MacAddress: mac, Label: label})
+	if errors.Is(err, repository.ErrDeviceExists) {
+		msg.Text = tr(lang, "mac_exists")
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Adding device %s of %s failed: %v", logging.MaskMAC(mac), emp.Name, err)
+		msg.Text = tr(lang, "error", err)
+		return
+	}
+	invalidateEmployee(s.id, mac)
+	log.Printf("📱 Device %s (%s) added for %s", logging.MaskMAC(mac), label, emp.Name)
+	msg.Text = tr(lang, "devices.added", mac, markdown.Escape(label))
+}
+
+// handleRemoveDevice removes one of the chat's employee's extra devices
+func handleRemoveDevice(s *site, message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	lang := chatLanguage(s, message.Chat.ID)
+	args := strings.Fields(message.CommandArguments())
+	if len(args) != 1 {
+		msg.Text = tr(lang, "devices.remove_usage")
+		return
+	}
+	mac, err := macaddr.Normalize(args[0])
+	if err != nil {
+		msg.Text = invalidMACMessage(args[0], lang)
+		return
+	}
+	emp, ok := deviceOwner(s, message.Chat.ID, msg, lang)
+	if !ok {
+		return
+	}
+
+	err = s.devices.RemoveDevice(pollContext(), emp.ID, mac)
+	if errors.Is(err, repository.ErrDeviceNotFound) {
+		msg.Text = tr(lang, "devices.not_found", mac)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Removing device %s of %s failed: %v", logging.MaskMAC(mac), emp.Name, err)
+		msg.Text = tr(lang, "error", err)
+		return
+	}
+	invalidateEmployee(s.id, mac)
+	log.Printf("📱 Device %s removed for %s", logging.MaskMAC(mac), emp.Name)
+	msg.Text = tr(lang, "devices.removed", mac)
+}
+
+// deviceOwner returns the chat's employee, or explains in msg why there is none
+func deviceOwner(s *site, chatID int64, msg *tgbotapi.MessageConfig, lang string) (*models.Employee, bool) {
+	emp, err := getEmployeeByChat(s, chatID)
+	if errors.Is(err, errNotRegistered) {
+		msg.Text = tr(lang, "not_registered")
+		return nil, false
+	}
+	if err != nil {
+		msg.Text = tr(lang, "unavailable")
+		return nil, false
+	}
+	return emp, true
+}
+
+// devicesLines lists the employee's extra devices for /myinfo in lang, empty if they have
+// none or they cannot be read
+func devicesLines(s *site, employeeID, lang string) string {
+	devices, err := s.devices.ListDevices(pollContext(), employeeID)
+	if err != nil {
+		log.Printf("Warning: failed to read devices of %s: %v", employeeID, err)
+		return ""
+	}
+	if len(devices) == 0 {
+		return ""
+	}
+	lines := []string{tr(lang, "devices.title")}
+	for _, d := range devices {
+		line := fmt.Sprintf("• `%s` %s", d.MacAddress, markdown.Escape(d.Label))
+		if d.IsPrimary {
+			line += " ⭐"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
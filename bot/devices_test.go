@@ -0,0 +1,136 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/sqlite"
+	"med-pulse-bot/internal/tenant"
+)
+
+// device is an employee_devices record of the PocketBase fake
+type device struct {
+	ID         string `json:"id"`
+	EmployeeID string `json:"employee_id"`
+	MacAddress string `json:"mac_address"`
+	Label      string `json:"label"`
+}
+
+func TestDeviceCommands(t *testing.T) {
+	const chatID = 1001
+	var mu sync.Mutex
+	devices := map[string]device{} // ID → device
+	useSingleSite(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		filter := r.URL.Query().Get("filter")
+		switch {
+		case r.URL.Path == "/api/collections/employees/records" && strings.Contains(filter, "telegram_chat_id"):
+			fmt.Fprintf(w, `{"items":[{"id":"emp1","name":"Somchai","mac_address":"aa:bb:cc:dd:ee:01","telegram_chat_id":%d,"is_active":true}]}`, chatID)
+		case r.URL.Path == "/api/collections/employees/records":
+			if strings.Contains(filter, "aa:bb:cc:dd:ee:09") {
+				w.Write([]byte(`{"items":[{"id":"emp2","mac_address":"aa:bb:cc:dd:ee:09"}]}`))
+				return
+			}
+			w.Write([]byte(`{"items":[]}`))
+		case r.URL.Path == "/api/collections/employee_devices/records" && r.Method == http.MethodPost:
+			var d device
+			body, _ := io.ReadAll(r.Body)
+			json.Unmarshal(body, &d)
+			d.ID = fmt.Sprintf("dev%d", len(devices)+1)
+			devices[d.ID] = d
+			json.NewEncoder(w).Encode(d)
+		case r.URL.Path == "/api/collections/employee_devices/records":
+			items := []device{}
+			for _, d := range devices {
+				if !strings.Contains(filter, "mac_address=") || strings.Contains(filter, d.MacAddress) {
+					items = append(items, d)
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+		case strings.HasPrefix(r.URL.Path, "/api/collections/employee_devices/records/") && r.Method == http.MethodDelete:
+			delete(devices, strings.TrimPrefix(r.URL.Path, "/api/collections/employee_devices/records/"))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}), 0)
+	tg := newFakeTelegram(t)
+
+	steps := []struct {
+		command     string
+		wantReply   string
+		wantDevices int
+	}{
+		{"/add_device AA:BB:CC:DD:EE:02", "Usage", 0},
+		{"/add_device not-a-mac AirPods", "ไม่ถูกต้อง", 0},
+		{"/add_device AA:BB:CC:DD:EE:01 iTag", "อุปกรณ์หลัก", 0},
+		{"/add_device AA:BB:CC:DD:EE:09 iTag", "ลงทะเบียนไว้แล้ว", 0},
+		{"/add_device AA-BB-CC-DD-EE-02 AirPods Pro", "เพิ่มอุปกรณ์ `aa:bb:cc:dd:ee:02`", 1},
+		{"/myinfo", "• `aa:bb:cc:dd:ee:02` AirPods Pro", 1},
+		{"/remove_device aa:bb:cc:dd:ee:03", "ไม่พบอุปกรณ์", 1},
+		{"/remove_device AA:BB:CC:DD:EE:02", "ลบอุปกรณ์", 0},
+	}
+	for _, st := range steps {
+		handleUpdate(commandUpdate(chatID, st.command))
+		if got := tg.last(t, "sendMessage").params.Get("text"); !strings.Contains(got, st.wantReply) {
+			t.Errorf("%s replied %q, want it to contain %q", st.command, got, st.wantReply)
+		}
+		mu.Lock()
+		n := len(devices)
+		mu.Unlock()
+		if n != st.wantDevices {
+			t.Errorf("after %s devices = %d, want %d", st.command, n, st.wantDevices)
+		}
+	}
+}
+
+func TestDeviceCommandsWithoutPocketBase(t *testing.T) {
+	const chatID = 1001
+	ctx := context.Background()
+	db, err := sqlite.Open(ctx, filepath.Join(t.TempDir(), "med-pulse.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	emp := &models.Employee{Name: "Somchai", MacAddress: "aa:bb:cc:dd:ee:01", TelegramChatID: chatID, IsActive: true}
+	if err := db.Employees().Create(ctx, emp); err != nil {
+		t.Fatal(err)
+	}
+	adminChatIDs, tenants = nil, nil
+	SetRepositories(tenant.DefaultID, Repositories{Employees: db.Employees(), Attendance: db.Attendance(),
+		Devices: db.Devices(), Consents: db.PrivacyAudit(), Scanners: db.Scanners()})
+	t.Cleanup(func() { SetRepositories(tenant.DefaultID, Repositories{}) })
+	tg := newFakeTelegram(t)
+	answerIn(t, th)
+
+	steps := []struct {
+		command   string
+		wantReply string
+	}{
+		{"/add_device AA:BB:CC:DD:EE:02 AirPods", "เพิ่มอุปกรณ์ `aa:bb:cc:dd:ee:02`"},
+		{"/myinfo", "• `aa:bb:cc:dd:ee:02` AirPods"},
+		{"/remove_device AA:BB:CC:DD:EE:02", "ลบอุปกรณ์"},
+		{"/privacy off", "*ไม่ยินยอม*"},
+		{"/scanners", "No scanners found"},
+		{"/language en", "Language set to"},
+		{"/add_device AA:BB:CC:DD:EE:01 iTag", "❌ This MAC is already your main device"},
+		{"/add_device AA:BB:CC:DD:EE:03 AirPods", "✅ Device `aa:bb:cc:dd:ee:03` (AirPods) added"},
+		{"/remove_device AA:BB:CC:DD:EE:04", "❌ You have no device `aa:bb:cc:dd:ee:04`"},
+		{"/remove_device not-a-mac", "❌ MAC address `not-a-mac` is invalid"},
+	}
+	for _, st := range steps {
+		handleUpdate(commandUpdate(chatID, st.command))
+		if got := tg.last(t, "sendMessage").params.Get("text"); !strings.Contains(got, st.wantReply) {
+			t.Errorf("%s replied %q, want it to contain %q", st.command, got, st.wantReply)
+		}
+	}
+}
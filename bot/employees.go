@@ -0,0 +1,154 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// employeesPrefix routes the /employees page buttons; the data is
+// "em:<page>:<search>"
+const employeesPrefix = "em"
+
+const employeesPageSize = 15
+
+// employeesMaxQuery keeps the search short enough for Telegram's 64-byte button data
+const employeesMaxQuery = 48
+
+// EmployeeLister searches one tenant's active employees a page at a time
+type EmployeeLister interface {
+	List(ctx context.Context, query string, page repository.Page) ([]models.Employee, int, error)
+}
+
+var (
+	employeeListersMu sync.RWMutex
+	employeeListers   = make(map[string]EmployeeLister) // tenant ID → lister
+)
+
+// SetEmployeeList enables /employees for the tenant's admin chats
+func SetEmployeeList(tenantID string, l EmployeeLister) {
+	employeeListersMu.Lock()
+	employeeListers[tenantID] = l
+	employeeListersMu.Unlock()
+}
+
+// handleEmployees lists the active employees, or those whose name or code contains the
+// argument
+func handleEmployees(s *site, message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	if !isSiteAdmin(message.Chat.ID) {
+		msg.Text = "❌ คำสั่งนี้สำหรับผู้ดูแลระบบเท่านั้น"
+		return
+	}
+	query := strings.TrimSpace(message.CommandArguments())
+	if len(query) > employeesMaxQuery {
+		msg.Text = "❌ คำค้นหายาวเกินไป"
+		return
+	}
+	ctx, cancel := context.WithTimeout(pollContext(), 10*time.Second)
+	defer cancel()
+	text, markup, err := employeesPage(ctx, s, query, 1)
+	if err != nil {
+		log.Printf("Failed to list employees: %v", err)
+		msg.Text = unavailableMessage
+		return
+	}
+	msg.Text = text
+	if markup != nil {
+		msg.ReplyMarkup = *markup
+	}
+}
+
+// handleEmployeesCallback turns the page of an /employees message
+func handleEmployeesCallback(ctx context.Context, s *site, chatID int64, messageID int, data string) (string, error) {
+	if !isSiteAdmin(chatID) {
+		return "", errors.New("employee list is for admin chats only")
+	}
+	parts := strings.SplitN(data, ":", 3)
+	if len(parts) != 3 {
+		return "", fmt.Errorf("malformed employees data %q", data)
+	}
+	page, err := strconv.Atoi(parts[1])
+	if err != nil || page < 1 {
+		return "", fmt.Errorf("malformed employees data %q", data)
+	}
+	text, markup, err := employeesPage(ctx, s, parts[2], page)
+	if err != nil {
+		return "", err
+	}
+	if markup == nil {
+		return text, nil
+	}
+	return "", editPrompt(chatID, messageID, text, *markup)
+}
+
+// employeesPage renders one page of the list, with Previous/Next buttons when there
+// are other pages (nil markup otherwise)
+func employeesPage(ctx context.Context, s *site, query string, page int) (string, *tgbotapi.InlineKeyboardMarkup, error) {
+	employeeListersMu.RLock()
+	l := employeeListers[s.id]
+	employeeListersMu.RUnlock()
+	if l == nil {
+		return "❌ การดูรายชื่อพนักงานไม่ได้เปิดใช้งาน", nil, nil
+	}
+
+	employees, total, err := l.List(ctx, query, repository.Page{Number: page, Size: employeesPageSize})
+	if err != nil {
+		return "", nil, err
+	}
+
+	text := "👥 *พนักงาน*"
+	if query != "" {
+		text += fmt.Sprintf(" ที่ค้นหา \"%s\"", markdown.Escape(query))
+	}
+	text += "\n\n"
+	if total == 0 {
+		return text + "ไม่พบพนักงาน", nil, nil
+	}
+	for _, e := range employees {
+		text += employeeLine(&e) + "\n"
+	}
+	if total <= employeesPageSize {
+		return text + fmt.Sprintf("\nทั้งหมด %d คน", total), nil, nil
+	}
+	pages := (total + employeesPageSize - 1) / employeesPageSize
+	text += fmt.Sprintf("\nหน้า %d/%d (%d คน)", page, pages, total)
+
+	data := func(page int) string {
+		return fmt.Sprintf("%s:%d:%s", employeesPrefix, page, query)
+	}
+	var row []tgbotapi.InlineKeyboardButton
+	if page > 1 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("◀️ ก่อนหน้า", data(page-1)))
+	}
+	if page < pages {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("ถัดไป ▶️", data(page+1)))
+	}
+	markup := tgbotapi.NewInlineKeyboardMarkup(row)
+	return text, &markup, nil
+}
+
+// employeeLine renders one employee of /employees: name, code, department and tag MAC
+func employeeLine(e *models.Employee) string {
+	line := "• " + markdown.Escape(e.Name)
+	if e.EmployeeCode != "" {
+		line += " (" + markdown.Escape(e.EmployeeCode) + ")"
+	}
+	if e.Department != "" {
+		line += " – " + markdown.Escape(e.Department)
+	}
+	if e.MacAddress != "" {
+		line += fmt.Sprintf(" `%s`", e.MacAddress)
+	}
+	return line
+}
@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+	"med-pulse-bot/internal/tenant"
+)
+
+func TestEmployees(t *testing.T) {
+	const adminChatID = 42
+	useSingleSite(t, http.NotFoundHandler(), adminChatID)
+	tg := newFakeTelegram(t)
+	store := memory.NewStore(clock.NewFake(time.Date(2026, 3, 2, 9, 0, 0, 0, location)))
+	for i := 1; i <= 20; i++ {
+		store.AddEmployee(models.Employee{ID: fmt.Sprintf("e%d", i), Name: fmt.Sprintf("Staff %02d", i),
+			EmployeeCode: fmt.Sprintf("N%03d", i), MacAddress: fmt.Sprintf("AA:BB:CC:DD:EE:%02d", i), IsActive: true})
+	}
+	store.AddEmployee(models.Employee{ID: "s1", Name: "Som_chai *", EmployeeCode: "E_01", Department: "ICU", IsActive: true})
+	SetEmployeeList(tenant.DefaultID, store.Employees())
+	t.Cleanup(func() { SetEmployeeList(tenant.DefaultID, nil) })
+
+	tests := []struct {
+		name        string
+		chatID      int64
+		command     string
+		want        []string
+		wantButtons bool
+	}{
+		{"employee chat", 1001, "/employees", []string{"ผู้ดูแลระบบเท่านั้น"}, false},
+		{"all", adminChatID, "/employees", []string{"Staff 01 (N001) `AA:BB:CC:DD:EE:01`", "หน้า 1/2 (21 คน)"}, true},
+		{"search escapes markdown", adminChatID, "/employees som", []string{`Som\_chai \* (E\_01) – ICU`, "ทั้งหมด 1 คน"}, false},
+		{"search by code", adminChatID, "/employees n020", []string{"Staff 20", "ทั้งหมด 1 คน"}, false},
+		{"no match", adminChatID, "/employees nobody", []string{"ไม่พบพนักงาน"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handleUpdate(commandUpdate(tt.chatID, tt.command))
+			call := tg.last(t, "sendMessage")
+			for _, want := range tt.want {
+				if got := call.params.Get("text"); !strings.Contains(got, want) {
+					t.Errorf("%s = %q, want it to contain %q", tt.command, got, want)
+				}
+			}
+			if got := call.params.Get("reply_markup") != ""; got != tt.wantButtons {
+				t.Errorf("%s has buttons = %v, want %v", tt.command, got, tt.wantButtons)
+			}
+		})
+	}
+
+	t.Run("next page", func(t *testing.T) {
+		if _, err := dispatchCallback(adminChatID, 7, nil, "em:2:"); err != nil {
+			t.Fatal(err)
+		}
+		if got := tg.last(t, "editMessageText").params.Get("text"); !strings.Contains(got, "Staff 20") || !strings.Contains(got, "หน้า 2/2") {
+			t.Errorf("page 2 = %q, want the last employees", got)
+		}
+	})
+
+	t.Run("employee chat cannot page", func(t *testing.T) {
+		if _, err := dispatchCallback(1001, 7, nil, "em:2:"); err == nil {
+			t.Error("paging from an employee chat succeeded, want an error")
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/models"
+)
+
+// AttendanceExporter writes one tenant's check-ins of a month as CSV
+type AttendanceExporter interface {
+	WriteCSV(ctx context.Context, w io.Writer, period string) (int, error)
+}
+
+var (
+	exportersMu sync.RWMutex
+	exporters   = make(map[string]AttendanceExporter) // tenant ID → exporter
+)
+
+// SetAttendanceExport enables /export for the tenant's admin chats
+func SetAttendanceExport(tenantID string, e AttendanceExporter) {
+	exportersMu.Lock()
+	exporters[tenantID] = e
+	exportersMu.Unlock()
+}
+
+// handleExport uploads a month's attendance as a CSV document, this month without an argument
+func handleExport(s *site, message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	if !isSiteAdmin(message.Chat.ID) {
+		msg.Text = "❌ คำสั่งนี้สำหรับผู้ดูแลระบบเท่านั้น"
+		return
+	}
+	period := strings.TrimSpace(message.CommandArguments())
+	if period == "" {
+		period = time.Now().In(location).Format(models.PeriodLayout)
+	}
+	if _, err := time.Parse(models.PeriodLayout, period); err != nil {
+		msg.Text = "Usage: `/export [YYYY-MM]` เช่น `/export 2026-01`"
+		return
+	}
+	exportersMu.RLock()
+	e := exporters[s.id]
+	exportersMu.RUnlock()
+	if e == nil {
+		msg.Text = "❌ การส่งออกข้อมูลไม่ได้เปิดใช้งาน"
+		return
+	}
+
+	// Every page of the month is read, so allow longer than a single query
+	ctx, cancel := context.WithTimeout(pollContext(), time.Minute)
+	defer cancel()
+	var b bytes.Buffer
+	n, err := e.WriteCSV(ctx, &b, period)
+	if err != nil {
+		log.Printf("❌ Export of %s failed: %v", period, err)
+		msg.Text = unavailableMessage
+		return
+	}
+
+	doc := tgbotapi.NewDocument(message.Chat.ID, tgbotapi.FileBytes{Name: fmt.Sprintf("attendance-%s.csv", period), Bytes: b.Bytes()})
+	doc.Caption = fmt.Sprintf("📄 การเข้างาน %s", period)
+	if _, err := bot.Send(doc); err != nil {
+		log.Printf("❌ Uploading the export of %s failed: %v", period, err)
+		msg.Text = fmt.Sprintf("❌ Error: %v", err)
+		return
+	}
+	log.Printf("📄 Exported %d check-ins of %s", n, period)
+	msg.Text = fmt.Sprintf("✅ ส่งออก %s แล้ว: %d รายการ", period, n)
+}
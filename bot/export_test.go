@@ -0,0 +1,61 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"med-pulse-bot/internal/tenant"
+)
+
+// fakeExporter writes one CSV row per export and remembers the period asked for
+type fakeExporter struct {
+	period string
+}
+
+func (f *fakeExporter) WriteCSV(ctx context.Context, w io.Writer, period string) (int, error) {
+	f.period = period
+	fmt.Fprintf(w, "date,employee_code\n%s-05,E001\n", period)
+	return 1, nil
+}
+
+func TestExport(t *testing.T) {
+	const adminChatID = 42
+	useSingleSite(t, http.NotFoundHandler(), adminChatID)
+	tg := newFakeTelegram(t)
+	exporter := &fakeExporter{}
+	SetAttendanceExport(tenant.DefaultID, exporter)
+	t.Cleanup(func() { SetAttendanceExport(tenant.DefaultID, nil) })
+
+	tests := []struct {
+		name       string
+		chatID     int64
+		command    string
+		wantReply  string
+		wantPeriod string
+	}{
+		{"employee chat", 1001, "/export 2026-01", "ผู้ดูแลระบบเท่านั้น", ""},
+		{"invalid month", adminChatID, "/export 2026-13", "Usage", ""},
+		{"month", adminChatID, "/export 2026-01", "ส่งออก 2026-01 แล้ว: 1 รายการ", "2026-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter.period = ""
+			handleUpdate(commandUpdate(tt.chatID, tt.command))
+			if got := tg.last(t, "sendMessage").params.Get("text"); !strings.Contains(got, tt.wantReply) {
+				t.Errorf("%s replied %q, want it to contain %q", tt.command, got, tt.wantReply)
+			}
+			if exporter.period != tt.wantPeriod {
+				t.Errorf("exported period %q, want %q", exporter.period, tt.wantPeriod)
+			}
+			if tt.wantPeriod != "" {
+				if got := tg.last(t, "sendDocument").params.Get("caption"); !strings.Contains(got, tt.wantPeriod) {
+					t.Errorf("document caption = %q, want the month", got)
+				}
+			}
+		})
+	}
+}
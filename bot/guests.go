@@ -0,0 +1,94 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/logging"
+	"med-pulse-bot/internal/macaddr"
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/services"
+)
+
+// GuestRegistrar lends tags to guests of one tenant until a date
+type GuestRegistrar interface {
+	Register(ctx context.Context, mac, name string, until time.Time, actorID int64, actorName string) (*models.Employee, error)
+}
+
+var (
+	guestRegistrarsMu sync.RWMutex
+	guestRegistrars   = make(map[string]GuestRegistrar) // tenant ID → registrar
+)
+
+// SetGuests enables /register_guest for the tenant's admin chats
+func SetGuests(tenantID string, g GuestRegistrar) {
+	guestRegistrarsMu.Lock()
+	guestRegistrars[tenantID] = g
+	guestRegistrarsMu.Unlock()
+}
+
+// guestUsage explains /register_guest
+const guestUsage = "Usage: `/register_guest <MAC> <ชื่อ> <YYYY-MM-DD>`\n" +
+	"เช่น `/register_guest AA:BB:CC:DD:EE:FF ช่างแอร์ สมศักดิ์ 2026-03-06`\n" +
+	"แท็กใช้ได้ถึงสิ้นวันที่กำหนด แล้วปิดใช้งานอัตโนมัติ"
+
+// handleRegisterGuest lends a tag with `/register_guest <MAC> <name> <until>`; the name
+// may have spaces
+func handleRegisterGuest(s *site, message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	if !isSiteAdmin(message.Chat.ID) {
+		msg.Text = "❌ คำสั่งนี้สำหรับผู้ดูแลระบบเท่านั้น"
+		return
+	}
+	guestRegistrarsMu.RLock()
+	g, ok := guestRegistrars[s.id]
+	guestRegistrarsMu.RUnlock()
+	if !ok {
+		msg.Text = "❌ การลงทะเบียนผู้มาติดต่อไม่ได้เปิดใช้งาน"
+		return
+	}
+
+	args := strings.Fields(message.CommandArguments())
+	if len(args) < 3 {
+		msg.Text = guestUsage
+		return
+	}
+	mac, err := macaddr.Normalize(args[0])
+	if err != nil {
+		msg.Text = fmt.Sprintf("❌ MAC ไม่ถูกต้อง: %v", err)
+		return
+	}
+	until, err := time.ParseInLocation("2006-01-02", args[len(args)-1], location)
+	if err != nil {
+		msg.Text = "❌ วันที่ต้องเป็น YYYY-MM-DD\n\n" + guestUsage
+		return
+	}
+	name := strings.Join(args[1:len(args)-1], " ")
+
+	var by models.PeriodLockActor
+	if message.From != nil {
+		by = periodLockActor(message.From)
+	}
+	ctx, cancel := context.WithTimeout(pollContext(), 10*time.Second)
+	defer cancel()
+	guest, err := g.Register(ctx, mac, name, until, by.UserID, by.Name)
+	switch {
+	case errors.Is(err, services.ErrGuestUntilPast), errors.Is(err, services.ErrTagInUse):
+		msg.Text = fmt.Sprintf("❌ %v", err)
+		return
+	case err != nil:
+		log.Printf("❌ Guest registration of %s failed: %v", logging.MaskMAC(mac), err)
+		msg.Text = fmt.Sprintf("❌ Error: %v", err)
+		return
+	}
+	invalidateEmployee(s.id, mac)
+	msg.Text = fmt.Sprintf("🏷️ *ลงทะเบียนผู้มาติดต่อแล้ว*\n👤 %s\n📱 `%s`\n📅 ใช้ได้ถึง %s\n\nไม่คิดมาสาย และแยกจากรายงานพนักงาน",
+		markdown.Escape(guest.Name), mac, until.Format("02/01/2006"))
+}
@@ -0,0 +1,184 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// historyPrefix routes the /history page buttons; the data is
+// "hs:<first day>:<last day>:<offset>" with days as YYYYMMDD
+const historyPrefix = "hs"
+
+const (
+	historyPageSize    = 10
+	historyDefaultDays = 7
+	historyMaxDays     = 366
+)
+
+// handleHistory shows the first page of the chat's employee's attendance in a range of days
+func handleHistory(s *site, message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	lang := chatLanguage(s, message.Chat.ID)
+	first, last, err := parseHistoryRange(strings.Fields(message.CommandArguments()), time.Now().In(location), lang)
+	if err != nil {
+		msg.Text = fmt.Sprintf("❌ %v\n\n%s", err, tr(lang, "history.usage"))
+		return
+	}
+	text, markup, err := historyPage(s, message.Chat.ID, first, last, 0, lang)
+	if errors.Is(err, errNotRegistered) {
+		msg.Text = tr(lang, "history.none")
+		return
+	}
+	if err != nil {
+		msg.Text = tr(lang, "unavailable")
+		return
+	}
+	msg.Text = text
+	if markup != nil {
+		msg.ReplyMarkup = *markup
+	}
+}
+
+// handleHistoryCallback turns the page of a /history message
+func handleHistoryCallback(s *site, chatID int64, messageID int, data string) (string, error) {
+	parts := strings.Split(data, ":")
+	if len(parts) != 4 {
+		return "", fmt.Errorf("malformed history data %q", data)
+	}
+	first, err1 := time.ParseInLocation("20060102", parts[1], location)
+	last, err2 := time.ParseInLocation("20060102", parts[2], location)
+	offset, err3 := strconv.Atoi(parts[3])
+	if err1 != nil || err2 != nil || err3 != nil || offset < 0 {
+		return "", fmt.Errorf("malformed history data %q", data)
+	}
+	text, markup, err := historyPage(s, chatID, first, last, offset, chatLanguage(s, chatID))
+	if err != nil {
+		return "", err
+	}
+	if markup == nil {
+		return text, nil
+	}
+	return "", editPrompt(chatID, messageID, text, *markup)
+}
+
+// parseHistoryRange reads the days /history covers: the last N days (7 without
+// arguments), a month (2026-01) or two dates, both included. Its errors are in lang.
+func parseHistoryRange(args []string, now time.Time, lang string) (first, last time.Time, err error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	switch len(args) {
+	case 0:
+		return today.AddDate(0, 0, 1-historyDefaultDays), today, nil
+	case 1:
+		if days, err := strconv.Atoi(args[0]); err == nil {
+			if days < 1 || days > historyMaxDays {
+				return first, last, errors.New(tr(lang, "history.days", historyMaxDays))
+			}
+			return today.AddDate(0, 0, 1-days), today, nil
+		}
+		month, err := time.ParseInLocation("2006-01", args[0], location)
+		if err != nil {
+			return first, last, errors.New(tr(lang, "history.format", args[0]))
+		}
+		return month, month.AddDate(0, 1, -1), nil
+	case 2:
+		first, err = time.ParseInLocation("2006-01-02", args[0], location)
+		if err != nil {
+			return first, last, errors.New(tr(lang, "history.date", args[0]))
+		}
+		last, err = time.ParseInLocation("2006-01-02", args[1], location)
+		if err != nil {
+			return first, last, errors.New(tr(lang, "history.date", args[1]))
+		}
+		if last.Before(first) {
+			return first, last, errors.New(tr(lang, "history.order"))
+		}
+		if last.Sub(first) >= historyMaxDays*24*time.Hour {
+			return first, last, errors.New(tr(lang, "history.too_long", historyMaxDays))
+		}
+		return first, last, nil
+	}
+	return first, last, errors.New(tr(lang, "history.invalid"))
+}
+
+// historyPage renders the page of the range starting at offset in lang, with
+// Previous/Next buttons when there are other pages (nil markup otherwise)
+func historyPage(s *site, chatID int64, first, last time.Time, offset int, lang string) (string, *tgbotapi.InlineKeyboardMarkup, error) {
+	records, total, err := getAttendanceHistory(s, chatID, first, last, historyPageSize, offset)
+	if err != nil {
+		return "", nil, err
+	}
+	text := tr(lang, "history.title", first.Format("02/01/2006"), last.Format("02/01/2006"))
+	if total == 0 {
+		return text + tr(lang, "history.none"), nil, nil
+	}
+	for _, h := range records {
+		text += historyLine(h, lang) + "\n"
+	}
+	if total <= historyPageSize {
+		return text, nil, nil
+	}
+	text += tr(lang, "history.page", offset/historyPageSize+1, (total+historyPageSize-1)/historyPageSize, total)
+
+	data := func(offset int) string {
+		return fmt.Sprintf("%s:%s:%s:%d", historyPrefix, first.Format("20060102"), last.Format("20060102"), offset)
+	}
+	var row []tgbotapi.InlineKeyboardButton
+	if offset > 0 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(tr(lang, "history.previous"), data(max(offset-historyPageSize, 0))))
+	}
+	if offset+historyPageSize < total {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(tr(lang, "history.next"), data(offset+historyPageSize)))
+	}
+	markup := tgbotapi.NewInlineKeyboardMarkup(row)
+	return text, &markup, nil
+}
+
+// historyLine renders one day of /history: check-in, check-out and an on-time/late marker
+func historyLine(h models.Attendance, lang string) string {
+	out := "-"
+	if !h.CheckOutTime.IsZero() {
+		out = h.CheckOutTime.In(location).Format("15:04")
+	}
+	in := "-"
+	if !h.CheckInTime.IsZero() {
+		in = h.CheckInTime.In(location).Format("15:04")
+	}
+	return tr(lang, "history.line", h.CreatedDate.Format("02/01"), in, out, historyMarker(h.Status, lang))
+}
+
+// historyMarker marks a /history day as on time or late
+func historyMarker(status, lang string) string {
+	switch status {
+	case models.AttendanceStatusOnTime:
+		return "✅"
+	case models.AttendanceStatusOnTimeApproved:
+		return tr(lang, "history.approved")
+	case models.AttendanceStatusLate:
+		return tr(lang, "history.late")
+	}
+	return statusLabel(status, lang)
+}
+
+// getAttendanceHistory returns up to limit of the employee's attendance records from the
+// days first to last, newest first and skipping the first offset, with the number of
+// records in the whole range
+func getAttendanceHistory(s *site, chatID int64, first, last time.Time, limit, offset int) ([]models.Attendance, int, error) {
+	emp, err := getEmployeeByChat(s, chatID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page := repository.Page{Number: offset/limit + 1, Size: limit}
+	records, total, err := s.attendance.ListHistory(pollContext(), emp.ID, first, last, page)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get attendance history: %w", err)
+	}
+	return records, total, nil
+}
@@ -0,0 +1,142 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseHistoryRange(t *testing.T) {
+	now := time.Date(2026, 3, 15, 10, 0, 0, 0, location)
+	tests := []struct {
+		args        string
+		first, last string
+		wantErr     bool
+	}{
+		{"", "2026-03-09", "2026-03-15", false},
+		{"30", "2026-02-14", "2026-03-15", false},
+		{"2026-02", "2026-02-01", "2026-02-28", false},
+		{"2026-01-01 2026-01-31", "2026-01-01", "2026-01-31", false},
+		{"0", "", "", true},
+		{"367", "", "", true},
+		{"2026-13", "", "", true},
+		{"2026-01-31 2026-01-01", "", "", true},
+		{"2025-01-01 2026-03-01", "", "", true},
+		{"2026-01-01 2026-01-02 2026-01-03", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			first, last, err := parseHistoryRange(strings.Fields(tt.args), now, th)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseHistoryRange(%q) = %s – %s, want an error", tt.args, first.Format("2006-01-02"), last.Format("2006-01-02"))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := first.Format("2006-01-02") + " " + last.Format("2006-01-02"); got != tt.first+" "+tt.last {
+				t.Errorf("parseHistoryRange(%q) = %s, want %s %s", tt.args, got, tt.first, tt.last)
+			}
+		})
+	}
+}
+
+func TestHistoryPages(t *testing.T) {
+	const chatID = 1001
+	useSingleSite(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/collections/employees/records":
+			fmt.Fprintf(w, `{"items":[{"id":"emp1","name":"Somchai","telegram_chat_id":%d,"is_active":true}]}`, chatID)
+		case "/api/collections/attendance/records":
+			// 23 days of January, newest first
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			perPage, _ := strconv.Atoi(r.URL.Query().Get("perPage"))
+			var items []map[string]string
+			for i := (page - 1) * perPage; i < min(page*perPage, 23); i++ {
+				status := "ontime"
+				if i%2 == 1 {
+					status = "late"
+				}
+				items = append(items, map[string]string{
+					"created_date":  fmt.Sprintf("2026-01-%02d 00:00:00.000Z", 23-i),
+					"check_in_time": fmt.Sprintf("2026-01-%02d 01:15:00.000Z", 23-i),
+					"status":        status,
+				})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"totalItems": 23, "items": items})
+		default:
+			http.NotFound(w, r)
+		}
+	}), 0)
+	first, last := time.Date(2026, 1, 1, 0, 0, 0, 0, location), time.Date(2026, 1, 31, 0, 0, 0, 0, location)
+
+	tests := []struct {
+		offset      int
+		wantLines   []string
+		wantButtons []string
+	}{
+		{0, []string{"23/01: เข้า", "ออก - ✅", "22/01: เข้า", "⏰ สาย", "หน้า 1/3 (23 รายการ)"}, []string{"hs:20260101:20260131:10"}},
+		{10, []string{"13/01: เข้า", "หน้า 2/3"}, []string{"hs:20260101:20260131:0", "hs:20260101:20260131:20"}},
+		{20, []string{"03/01: เข้า", "01/01: เข้า", "หน้า 3/3"}, []string{"hs:20260101:20260131:10"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("offset %d", tt.offset), func(t *testing.T) {
+			text, markup, err := historyPage(defaultSite(), chatID, first, last, tt.offset, th)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.wantLines {
+				if !strings.Contains(text, want) {
+					t.Errorf("page = %q, want it to contain %q", text, want)
+				}
+			}
+			if markup == nil {
+				t.Fatal("page has no buttons")
+			}
+			var got []string
+			for _, b := range markup.InlineKeyboard[0] {
+				got = append(got, *b.CallbackData)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.wantButtons) {
+				t.Errorf("buttons = %v, want %v", got, tt.wantButtons)
+			}
+		})
+	}
+}
+
+func TestHistoryInEnglish(t *testing.T) {
+	const chatID = 1001
+	useSingleSite(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/collections/employees/records":
+			fmt.Fprintf(w, `{"items":[{"id":"emp1","name":"Somchai","telegram_chat_id":%d,"is_active":true,"language":"en"}]}`, chatID)
+		case "/api/collections/attendance/records":
+			w.Write([]byte(`{"totalItems":1,"items":[{"created_date":"2026-01-05 00:00:00.000Z","check_in_time":"2026-01-05 01:15:00.000Z","status":"late"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}), 0)
+	tg := newFakeTelegram(t)
+	answerIn(t, th)
+
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"/history 2026-13", "❌ Invalid format: `2026-13`"},
+		{"/history 0", "The number of days must be 1-366"},
+		{"/history 2026-01", "05/01: in " + time.Date(2026, 1, 5, 1, 15, 0, 0, time.UTC).In(location).Format("15:04") + " out - ⏰ late"},
+	}
+	for _, tt := range tests {
+		handleUpdate(commandUpdate(chatID, tt.command))
+		if got := tg.last(t, "sendMessage").params.Get("text"); !strings.Contains(got, tt.want) {
+			t.Errorf("%s = %q, want it to contain %q", tt.command, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,720 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/models"
+)
+
+const (
+	th = models.LanguageThai
+	en = models.LanguageEnglish
+)
+
+// catalog holds the bot replies by message ID. Every message has Thai, which is also
+// sent when a message has no translation in the chat's language.
+var catalog = map[string]map[string]string{
+	"start.title": {
+		th: "🏢 *ระบบบันทึกเวลาเข้างาน*\n\n*คำสั่ง:*\n",
+		en: "🏢 *Attendance*\n\n*Commands:*\n",
+	},
+	"start.commands": {
+		th: "/register_employee - ลงทะเบียน\n" +
+			"/pair - จับคู่แท็กโดยถือไว้ใกล้ Scanner\n" +
+			"/myinfo - ข้อมูลฉัน\n" +
+			"/add_device - เพิ่มอุปกรณ์ (เช่น AirPods)\n" +
+			"/remove_device - ลบอุปกรณ์\n" +
+			"/today - เวลาวันนี้\n" +
+			"/checkin - ลงเวลาเข้างานเอง (เมื่อแท็กใช้ไม่ได้)\n" +
+			"/checkout - ลงเวลาออกงานเอง\n" +
+			"/history [วัน|YYYY-MM] - ประวัติ\n" +
+			"/monthly [YYYY-MM] - สรุปการเข้างานรายเดือน\n" +
+			"/late_approval - ขอเข้างานสายล่วงหน้า\n" +
+			"/set_schedule - ตั้งเวลาเริ่ม/เลิกงาน\n" +
+			"/notifications - การตั้งค่า\n" +
+			"/language - ภาษา (th/en)\n" +
+			"/privacy - ความเป็นส่วนตัว\n" +
+			"/scanners - สถานะ Scanner",
+		en: "/register_employee - register\n" +
+			"/pair - pair your tag by holding it near a scanner\n" +
+			"/myinfo - my details\n" +
+			"/add_device - add a device (e.g. AirPods)\n" +
+			"/remove_device - remove a device\n" +
+			"/today - today's times\n" +
+			"/checkin - check in yourself (when the tag does not work)\n" +
+			"/checkout - check out yourself\n" +
+			"/history [days|YYYY-MM] - history\n" +
+			"/monthly [YYYY-MM] - monthly attendance summary\n" +
+			"/late_approval - ask in advance to come in late\n" +
+			"/set_schedule - set your start/end times\n" +
+			"/notifications - settings\n" +
+			"/language - language (th/en)\n" +
+			"/privacy - privacy\n" +
+			"/scanners - scanner status",
+	},
+	"start.site": {
+		th: "\n/site - เลือกสาขา",
+		en: "\n/site - choose your site",
+	},
+	"start.readonly": {
+		th: "\n/readonly - โหมดปรับปรุงระบบ",
+		en: "\n/readonly - maintenance mode",
+	},
+	"start.admin": {
+		th: "\n/pair_scanner - จับคู่ Scanner ใหม่" +
+			"\n/register_guest - ลงทะเบียนแท็กผู้มาติดต่อ" +
+			"\n/scanner_profile - โปรไฟล์การตั้งค่า Scanner" +
+			"\n/scanner_token - ออก/ยกเลิก token ของ Scanner" +
+			"\n/rename_scanner - ตั้งชื่อ Scanner" +
+			"\n/queues - สถานะคิวในเครื่อง" +
+			"\n/whoisin - ใครอยู่ในสำนักงานตอนนี้" +
+			"\n/employees [ชื่อ|รหัส] - รายชื่อพนักงาน" +
+			"\n/pending_devices - อุปกรณ์ใหม่ที่รอมอบให้พนักงาน" +
+			"\n/deactivate <รหัส> - ปิดใช้งานพนักงานที่ลาออก" +
+			"\n/activate <รหัส> - เปิดใช้งานพนักงานอีกครั้ง" +
+			"\n/lock_period - ล็อกงวดหลังปิดเงินเดือน" +
+			"\n/unlock_period - ปลดล็อกงวด" +
+			"\n/manual_checkin - บันทึกเข้างานแทนพนักงาน" +
+			"\n/export - ส่งออกการเข้างานรายเดือน (CSV)",
+		en: "\n/pair_scanner - pair a new scanner" +
+			"\n/register_guest - register a visitor tag" +
+			"\n/scanner_profile - scanner settings profiles" +
+			"\n/scanner_token - issue/revoke a scanner token" +
+			"\n/rename_scanner - name a scanner" +
+			"\n/queues - local queue status" +
+			"\n/whoisin - who is in the office now" +
+			"\n/employees [name|code] - employee list" +
+			"\n/pending_devices - new devices waiting for an employee" +
+			"\n/deactivate <code> - deactivate an employee who left" +
+			"\n/activate <code> - reactivate an employee" +
+			"\n/lock_period - lock a period after payroll" +
+			"\n/unlock_period - unlock a period" +
+			"\n/manual_checkin - record a check-in for an employee" +
+			"\n/export - export monthly attendance (CSV)",
+	},
+	"cancelled": {
+		th: "❌ ยกเลิกแล้ว",
+		en: "❌ Cancelled",
+	},
+	"unknown_command": {
+		th: "ไม่รู้จำคำสั่ง ใช้ /start",
+		en: "Unknown command, see /start",
+	},
+	"not_registered": {
+		th: "❌ ยังไม่ได้ลงทะเบียน ใช้ /register_employee",
+		en: "❌ Not registered. Use /register_employee",
+	},
+	"unavailable": {
+		th: unavailableMessage,
+		en: "❌ The data cannot be fetched right now, please try again later",
+	},
+	"error": {
+		th: "❌ Error: %v",
+	},
+	"saved": {
+		th: "✅ บันทึกแล้ว",
+		en: "✅ Saved",
+	},
+	"myinfo": {
+		th: "👤 *Info*\nName: %s\nCode: %s\nDept: %s\nMAC: %s",
+		en: "👤 *My details*\nName: %s\nCode: %s\nDept: %s\nMAC: %s",
+	},
+	"myinfo.grace": {
+		th: "\nGrace: %d นาที",
+		en: "\nGrace: %d min",
+	},
+	"myinfo.late_from": {
+		th: " (สายตั้งแต่ `%s`)",
+		en: " (late from `%s`)",
+	},
+	"today": {
+		th: "📊 *Today*\nIn: %s\nOut: %s\nHours: %s",
+	},
+	"today.none": {
+		th: "No check-in today",
+	},
+	"today.not_out": {
+		th: "ยังไม่ออกงาน",
+		en: "not yet",
+	},
+	"today.scanner": {
+		th: "\nScanner: `%s`",
+	},
+	"today.status": {
+		th: "\nStatus: ",
+	},
+	"status.offday": {
+		th: "🏖️ offday (วันหยุด)",
+		en: "🏖️ offday",
+	},
+	"settings": {
+		th: "🔔 *การตั้งค่า*\nแสดงบนบอร์ดหน้าเคาน์เตอร์: *%s*\nชื่อที่แสดง: %s\n",
+		en: "🔔 *Settings*\nShown on the front desk board: *%s*\nDisplay name: %s\n",
+	},
+	"settings.on": {
+		th: "เปิด",
+		en: "on",
+	},
+	"settings.off": {
+		th: "ปิด",
+		en: "off",
+	},
+	"settings.quiet": {
+		th: "ช่วงเวลาห้ามรบกวน (`quiet`): %s\n",
+		en: "Quiet hours (`quiet`): %s\n",
+	},
+	"settings.quiet_unset": {
+		th: "ไม่ได้ตั้ง",
+		en: "not set",
+	},
+	"settings.quiet_invalid": {
+		th: "❌ ช่วงเวลาไม่ถูกต้อง ใช้รูปแบบ `22:00-07:00` หรือ `off`",
+		en: "❌ Invalid time range, use `22:00-07:00` or `off`",
+	},
+	"settings.change": {
+		th: "เปลี่ยน:",
+		en: "Change:",
+	},
+	"category.checkin": {
+		th: "แจ้งเตือนเข้างาน",
+		en: "Check-in messages",
+	},
+	"category.checkout": {
+		th: "แจ้งเตือนออกงาน",
+		en: "Check-out messages",
+	},
+	"category.checkout_reminder": {
+		th: "เตือนลืมบันทึกออกงาน",
+		en: "Forgotten check-out reminder",
+	},
+	"language": {
+		th: "🌐 ภาษา: *%s*\nเปลี่ยน: `/language th|en`",
+		en: "🌐 Language: *%s*\nChange: `/language th|en`",
+	},
+	"language.saved": {
+		th: "✅ ตั้งภาษาเป็น *%s* แล้ว",
+		en: "✅ Language set to *%s*",
+	},
+	"language.usage": {
+		th: "Usage: `/language th|en`",
+	},
+	"language.th": {
+		th: "ไทย",
+		en: "Thai",
+	},
+	"language.en": {
+		th: "อังกฤษ",
+		en: "English",
+	},
+	"admin_only": {
+		th: "❌ คำสั่งนี้สำหรับผู้ดูแลระบบเท่านั้น",
+		en: "❌ This command is for admins only",
+	},
+	"invalid_mac": {
+		th: "❌ MAC address `%s` ไม่ถูกต้อง\nต้องมีเลขฐานสิบหก 12 หลัก รองรับรูปแบบ:\n%s",
+		en: "❌ MAC address `%s` is invalid\nIt needs 12 hexadecimal digits, written as one of:\n%s",
+	},
+	"mac_exists": {
+		th: "❌ MAC address นี้ลงทะเบียนไว้แล้ว",
+		en: "❌ This MAC address is already registered",
+	},
+	"period_locked": {
+		th: "🔒 งวดนี้ถูกล็อกแล้ว กรุณาติดต่อผู้ดูแลระบบ",
+		en: "🔒 This period is locked, please contact an admin",
+	},
+	"timepicker.too_long": {
+		th: "❌ ข้อมูลยาวเกินไป",
+		en: "❌ The input is too long",
+	},
+	"timepicker.hour": {
+		th: "🕐 เลือกชั่วโมง",
+		en: "🕐 Choose the hour",
+	},
+	"timepicker.minute": {
+		th: "🕐 เลือกนาที (%02d:..)",
+		en: "🕐 Choose the minute (%02d:..)",
+	},
+	"timepicker.type": {
+		th: "⌨️ พิมพ์เวลา เช่น `08:30` หรือ `0830`",
+		en: "⌨️ Type the time, e.g. `08:30` or `0830`",
+	},
+	"timepicker.invalid": {
+		th: "❌ รูปแบบเวลาไม่ถูกต้อง พิมพ์ใหม่ เช่น `08:30` หรือ `0830`",
+		en: "❌ Invalid time, type it again, e.g. `08:30` or `0830`",
+	},
+	"timepicker.type_button": {
+		th: "⌨️ พิมพ์เอง",
+		en: "⌨️ Type it",
+	},
+	"timepicker.cancel_button": {
+		th: "❌ ยกเลิก",
+		en: "❌ Cancel",
+	},
+	"timepicker.hours_button": {
+		th: "« ชั่วโมง",
+		en: "« Hours",
+	},
+	"schedule.usage": {
+		th: "Usage: `/set_schedule [start|end]`",
+	},
+	"schedule.start": {
+		th: "เวลาเริ่มงาน",
+		en: "work start time",
+	},
+	"schedule.end": {
+		th: "เวลาเลิกงาน",
+		en: "work end time",
+	},
+	"schedule.title": {
+		th: "🗓️ *ตั้ง%s*",
+		en: "🗓️ *Set your %s*",
+	},
+	"schedule.saved": {
+		th: "✅ ตั้ง%sเป็น `%02d:%02d` แล้ว",
+		en: "✅ Your %s is set to `%02d:%02d`",
+	},
+	"schedule.failed": {
+		th: "❌ ไม่สามารถดำเนินการได้",
+		en: "❌ This cannot be done",
+	},
+	"manual_checkin.disabled": {
+		th: "❌ การบันทึกเข้างานแทนไม่ได้เปิดใช้งาน",
+		en: "❌ Manual check-ins are not enabled",
+	},
+	"manual_checkin.usage": {
+		th: "Usage: `/manual_checkin <Code>`",
+	},
+	"manual_checkin.title": {
+		th: "✍️ *บันทึกเข้างานแทน* `%s`",
+		en: "✍️ *Check in* `%s`",
+	},
+	"manual_checkin.done": {
+		th: "✅ *บันทึกเข้างานแทนแล้ว*\nพนักงาน: %s (%s)\nเวลา: `%s`\nสถานะ: %s",
+		en: "✅ *Check-in recorded*\nEmployee: %s (%s)\nTime: `%s`\nStatus: %s",
+	},
+	"self_entry.disabled": {
+		th: "❌ การบันทึกเวลาด้วยตนเองไม่ได้เปิดใช้งาน",
+		en: "❌ Checking in yourself is not enabled",
+	},
+	"self_entry.checkin": {
+		th: "✅ *บันทึกเวลาเข้างานแล้ว*\n\n🕐 เวลา: `%s`\n⏰ สถานะ: *%s*\n\nแจ้งผู้ดูแลระบบแล้ว",
+		en: "✅ *Check-in recorded*\n\n🕐 Time: `%s`\n⏰ Status: *%s*\n\nThe admins have been told",
+	},
+	"self_entry.checkout": {
+		th: "👋 *บันทึกเวลาออกงานแล้ว*\n\n🕐 เข้างาน: `%s`\n🕐 ออกงาน: `%s`\n\nแจ้งผู้ดูแลระบบแล้ว",
+		en: "👋 *Check-out recorded*\n\n🕐 In: `%s`\n🕐 Out: `%s`\n\nThe admins have been told",
+	},
+	"self_entry.already_in": {
+		th: "❌ วันนี้คุณลงเวลาเข้างานแล้ว",
+		en: "❌ You have already checked in today",
+	},
+	"self_entry.not_in": {
+		th: "❌ วันนี้คุณยังไม่ได้ลงเวลาเข้างาน ใช้ /checkin",
+		en: "❌ You have not checked in today, use /checkin",
+	},
+	"history.usage": {
+		th: "Usage: `/history [days]`, `/history 2026-01` or `/history 2026-01-01 2026-01-31`",
+	},
+	"history.days": {
+		th: "จำนวนวันต้องอยู่ระหว่าง 1-%d",
+		en: "The number of days must be 1-%d",
+	},
+	"history.format": {
+		th: "รูปแบบไม่ถูกต้อง: `%s`",
+		en: "Invalid format: `%s`",
+	},
+	"history.date": {
+		th: "วันที่ไม่ถูกต้อง: `%s`",
+		en: "Invalid date: `%s`",
+	},
+	"history.order": {
+		th: "วันสิ้นสุดต้องไม่อยู่ก่อนวันเริ่มต้น",
+		en: "The last day must not be before the first",
+	},
+	"history.too_long": {
+		th: "ช่วงวันที่ต้องไม่เกิน %d วัน",
+		en: "The range must not be longer than %d days",
+	},
+	"history.invalid": {
+		th: "รูปแบบไม่ถูกต้อง",
+		en: "Invalid format",
+	},
+	"history.title": {
+		th: "📅 *History* %s – %s\n\n",
+	},
+	"history.none": {
+		th: "No history found",
+	},
+	"history.line": {
+		th: "%s: เข้า %s ออก %s %s",
+		en: "%s: in %s out %s %s",
+	},
+	"history.approved": {
+		th: "✅ (อนุมัติล่วงหน้า)",
+		en: "✅ (approved in advance)",
+	},
+	"history.late": {
+		th: "⏰ สาย",
+		en: "⏰ late",
+	},
+	"history.page": {
+		th: "\nหน้า %d/%d (%d รายการ)",
+		en: "\nPage %d/%d (%d records)",
+	},
+	"history.previous": {
+		th: "◀️ ก่อนหน้า",
+		en: "◀️ Previous",
+	},
+	"history.next": {
+		th: "ถัดไป ▶️",
+		en: "Next ▶️",
+	},
+	"monthly.usage": {
+		th: "Usage: `/monthly [YYYY-MM]` เช่น `/monthly 2026-01`",
+		en: "Usage: `/monthly [YYYY-MM]`, e.g. `/monthly 2026-01`",
+	},
+	"monthly.disabled": {
+		th: "❌ สรุปรายเดือนไม่ได้เปิดใช้งาน",
+		en: "❌ Monthly summaries are not enabled",
+	},
+	"monthly.title": {
+		th: "📆 *สรุปการเข้างาน %s %d*",
+		en: "📆 *Attendance in %s %d*",
+	},
+	"monthly.working_days": {
+		th: "วันทำงาน: %d วัน",
+		en: "Working days: %d",
+	},
+	"monthly.present": {
+		th: "มาทำงาน: %d วัน",
+		en: "Days present: %d",
+	},
+	"monthly.late": {
+		th: "มาสาย: %d วัน (รวม %d นาที)",
+		en: "Days late: %d (%d minutes in all)",
+	},
+	"monthly.average": {
+		th: "เวลาเข้างานเฉลี่ย: %02d:%02d น.",
+		en: "Average check-in: %02d:%02d",
+	},
+	"monthly.offdays": {
+		th: "🏖️ มาทำงานวันหยุด: %d วัน",
+		en: "🏖️ Days off worked: %d",
+	},
+	"devices.add_usage": {
+		th: "Usage: `/add_device <MAC> <label>`\nเช่น `/add_device AA:BB:CC:DD:EE:FF AirPods`",
+		en: "Usage: `/add_device <MAC> <label>`\ne.g. `/add_device AA:BB:CC:DD:EE:FF AirPods`",
+	},
+	"devices.remove_usage": {
+		th: "Usage: `/remove_device <MAC>`",
+	},
+	"devices.primary": {
+		th: "❌ MAC นี้เป็นอุปกรณ์หลักของคุณอยู่แล้ว",
+		en: "❌ This MAC is already your main device",
+	},
+	"devices.added": {
+		th: "✅ เพิ่มอุปกรณ์ `%s` (%s) แล้ว",
+		en: "✅ Device `%s` (%s) added",
+	},
+	"devices.not_found": {
+		th: "❌ ไม่พบอุปกรณ์ `%s` ของคุณ",
+		en: "❌ You have no device `%s`",
+	},
+	"devices.removed": {
+		th: "🗑️ ลบอุปกรณ์ `%s` แล้ว",
+		en: "🗑️ Device `%s` removed",
+	},
+	"devices.title": {
+		th: "\n📱 *Devices:*",
+	},
+	"privacy": {
+		th: "🔒 *ความเป็นส่วนตัว*\nการติดตามการอยู่ในพื้นที่: *%s*\n\n" +
+			"เมื่อไม่ยินยอม ระบบจะบันทึกเฉพาะเวลาเข้างานและออกงาน ไม่เก็บประวัติการตรวจพบอุปกรณ์\n\n" +
+			"เปลี่ยน: `/privacy on|off`",
+		en: "🔒 *Privacy*\nPresence tracking: *%s*\n\n" +
+			"Without consent only your check-in and check-out times are recorded, not when your devices were detected\n\n" +
+			"Change: `/privacy on|off`",
+	},
+	"privacy.question": {
+		th: "🔒 *ยินยอมให้ติดตามการอยู่ในพื้นที่หรือไม่?*\n" +
+			"ถ้ายินยอม ระบบจะเก็บประวัติการตรวจพบอุปกรณ์ระหว่างวัน\n" +
+			"ถ้าไม่ยินยอม ระบบจะบันทึกเฉพาะเวลาเข้างานและออกงาน",
+		en: "🔒 *Do you agree to presence tracking?*\n" +
+			"If you agree, the times your devices are detected during the day are kept\n" +
+			"If not, only your check-in and check-out times are recorded",
+	},
+	"privacy.consent": {
+		th: "ยินยอม",
+		en: "agreed",
+	},
+	"privacy.no_consent": {
+		th: "ไม่ยินยอม",
+		en: "not agreed",
+	},
+	"privacy.agree": {
+		th: "✅ ยินยอม",
+		en: "✅ I agree",
+	},
+	"privacy.disagree": {
+		th: "🚫 ไม่ยินยอม",
+		en: "🚫 I do not agree",
+	},
+	"privacy.usage": {
+		th: "Usage: `/privacy on|off`",
+	},
+	"registration.usage": {
+		th: "Usage: `/register_employee <MAC> <Name> <Code> <Dept>`\nหรือพิมพ์ /register_employee เพื่อลงทะเบียนทีละขั้นตอน",
+		en: "Usage: `/register_employee <MAC> <Name> <Code> <Dept>`\nor send /register_employee to register step by step",
+	},
+	"registration.registered": {
+		th: "✅ Registered!\nName: %s\nCode: %s\n\n%s",
+	},
+	"registration.intro": {
+		th: "📝 *ลงทะเบียนพนักงาน*\nตอบทีละข้อ ยกเลิกได้ด้วย /cancel\n\n",
+		en: "📝 *Employee registration*\nAnswer one question at a time, /cancel stops\n\n",
+	},
+	"registration.mac": {
+		th: "ส่ง MAC address ของอุปกรณ์ เช่น `AA:BB:CC:DD:EE:FF`",
+		en: "Send the MAC address of your device, e.g. `AA:BB:CC:DD:EE:FF`",
+	},
+	"registration.name": {
+		th: "ส่งชื่อ-นามสกุล",
+		en: "Send your full name",
+	},
+	"registration.code": {
+		th: "ส่งรหัสพนักงาน",
+		en: "Send your employee code",
+	},
+	"registration.department": {
+		th: "ส่งชื่อแผนก",
+		en: "Send your department",
+	},
+	"registration.work_start": {
+		th: "ส่งเวลาเริ่มงาน เช่น `08:00:00` หรือ `-` เพื่อใช้เวลาตั้งต้น",
+		en: "Send your work start time, e.g. `08:00:00`, or `-` for the default",
+	},
+	"registration.empty": {
+		th: "❌ กรุณาพิมพ์คำตอบ\n\n",
+		en: "❌ Please type an answer\n\n",
+	},
+	"registration.code_space": {
+		th: "❌ รหัสพนักงานต้องไม่มีช่องว่าง",
+		en: "❌ The employee code must not contain spaces",
+	},
+	"registration.invalid_start": {
+		th: "❌ รูปแบบเวลาไม่ถูกต้อง ใช้ `HH:MM:SS` เช่น `08:00:00`",
+		en: "❌ Invalid time, use `HH:MM:SS`, e.g. `08:00:00`",
+	},
+	"registration.press_button": {
+		th: "กรุณากดปุ่มยืนยันหรือยกเลิกด้านบน",
+		en: "Please press Confirm or Cancel above",
+	},
+	"registration.default_start": {
+		th: "ตามค่าตั้งต้น",
+		en: "the default",
+	},
+	"registration.check": {
+		th: "📝 *ตรวจสอบข้อมูล*\nMAC: `%s`\nชื่อ: %s\nรหัส: %s\nแผนก: %s\nเวลาเริ่มงาน: %s\n\nยืนยันการลงทะเบียน?",
+		en: "📝 *Check your details*\nMAC: `%s`\nName: %s\nCode: %s\nDept: %s\nWork start: %s\n\nRegister?",
+	},
+	"registration.confirm": {
+		th: "✅ ยืนยัน",
+		en: "✅ Confirm",
+	},
+	"registration.expired": {
+		th: "⌛ การลงทะเบียนหมดเวลา เริ่มใหม่ด้วย /register_employee",
+		en: "⌛ The registration timed out, start again with /register_employee",
+	},
+	"registration.cancelled": {
+		th: "❌ ยกเลิกการลงทะเบียนแล้ว",
+		en: "❌ Registration cancelled",
+	},
+	"registration.exists": {
+		th: "❌ MAC address หรือรหัสพนักงานนี้ลงทะเบียนไว้แล้ว เริ่มใหม่ด้วย /register_employee",
+		en: "❌ This MAC address or employee code is already registered, start again with /register_employee",
+	},
+	"registration.done": {
+		th: "✅ ลงทะเบียนแล้ว\nชื่อ: %s\nรหัส: %s",
+		en: "✅ Registered\nName: %s\nCode: %s",
+	},
+	"late.disabled": {
+		th: "❌ การขอเข้างานสายล่วงหน้าไม่ได้เปิดใช้งาน",
+		en: "❌ Late arrival requests are not enabled",
+	},
+	"late.usage": {
+		th: "Usage: `/late_approval <YYYY-MM-DD> <HH:MM> <เหตุผล>`\n" +
+			"เช่น `/late_approval 2026-03-05 10:30 ไปพบแพทย์`",
+		en: "Usage: `/late_approval <YYYY-MM-DD> <HH:MM> <reason>`\n" +
+			"e.g. `/late_approval 2026-03-05 10:30 doctor's appointment`",
+	},
+	"late.requested": {
+		th: "🕘 ส่งคำขอเข้างานภายใน `%s` วันที่ %s แล้ว รอผู้ดูแลอนุมัติ",
+		en: "🕘 Asked to arrive by `%s` on %s, waiting for an admin",
+	},
+	"late.today": {
+		th: "\n🕘 วันนี้อนุมัติให้เข้างานภายใน `%s` (%s)",
+		en: "\n🕘 Approved to arrive by `%s` today (%s)",
+	},
+	"whoisin.disabled": {
+		th: "❌ การดูผู้อยู่ในสำนักงานไม่ได้เปิดใช้งาน",
+		en: "❌ Presence reports are not enabled",
+	},
+	"whoisin.nobody": {
+		th: "🏢 ไม่พบใครในสำนักงานใน %d นาทีที่ผ่านมา",
+		en: "🏢 Nobody was in the office in the last %d minutes",
+	},
+	"whoisin.title": {
+		th: "🏢 *อยู่ในสำนักงาน* (%d คน, %d นาทีล่าสุด)",
+		en: "🏢 *In the office* (%d people, last %d minutes)",
+	},
+	"whoisin.more": {
+		th: "… และอีก %d คน",
+		en: "… and %d more",
+	},
+	"pairing.disabled": {
+		th: "❌ การจับคู่ Scanner ไม่ได้เปิดใช้งาน",
+		en: "❌ Scanner pairing is not enabled",
+	},
+	"pairing.zones": {
+		th: "📡 *จับคู่ Scanner ใหม่*\n\nเลือกโซนที่ติดตั้ง หรือพิมพ์ `/pair_scanner <โซน>` สำหรับโซนใหม่",
+		en: "📡 *Pair a new scanner*\n\nChoose the zone it is installed in, or send `/pair_scanner <zone>` for a new zone",
+	},
+	"pairing.failed": {
+		th: "❌ ไม่สามารถสร้างรหัสจับคู่ได้: %v",
+		en: "❌ The pairing code could not be created: %v",
+	},
+	"pairing.code": {
+		th: "📡 *รหัสจับคู่ Scanner:* `%s`\n\n" +
+			"โซน: *%s*\nหมดอายุ: `%s`\n\n" +
+			"ใส่รหัสนี้เป็น `pairing_code` ใน heartbeat แรกของ Scanner " +
+			"ระบบจะแจ้งอีกครั้งเมื่อได้รับการตรวจจับครั้งแรก",
+		en: "📡 *Scanner pairing code:* `%s`\n\n" +
+			"Zone: *%s*\nExpires: `%s`\n\n" +
+			"Set this code as `pairing_code` in the scanner's first heartbeat; " +
+			"you will be told again when its first detection arrives",
+	},
+}
+
+// tr returns the message id in lang, formatted with args, in Thai if lang has none
+func tr(lang, id string, args ...any) string {
+	texts, ok := catalog[id]
+	if !ok {
+		log.Printf("⚠️ Message %q is not in the catalog", id)
+		return id
+	}
+	text, ok := texts[lang]
+	if !ok {
+		text = texts[th]
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
+
+var (
+	// defaultLanguage answers the admin chats and chats without an employee
+	defaultLanguage = th
+
+	chatLanguagesMu sync.Mutex
+	chatLanguages   = make(map[int64]string) // chat → language, once looked up
+)
+
+// SetDefaultLanguage sets the language of the admin chats and of chats with no employee
+// (ADMIN_LANGUAGE)
+func SetDefaultLanguage(lang string) error {
+	if !models.ValidLanguage(lang) {
+		return fmt.Errorf("unsupported language %q, want th or en", lang)
+	}
+	defaultLanguage = lang
+	return nil
+}
+
+// chatLanguage returns the language to answer the chat in: its employee's choice, or
+// the default when the chat has no employee or the lookup fails. It is looked up once
+// per chat.
+func chatLanguage(s *site, chatID int64) string {
+	chatLanguagesMu.Lock()
+	lang, ok := chatLanguages[chatID]
+	chatLanguagesMu.Unlock()
+	if ok {
+		return lang
+	}
+	if s == nil {
+		return defaultLanguage
+	}
+	emp, err := getEmployeeByChat(s, chatID)
+	switch {
+	case errors.Is(err, errNotRegistered):
+		rememberLanguage(chatID, defaultLanguage)
+		return defaultLanguage
+	case err != nil:
+		return defaultLanguage
+	}
+	lang = employeeLanguage(emp)
+	rememberLanguage(chatID, lang)
+	return lang
+}
+
+// rememberLanguage caches the language of the chat
+func rememberLanguage(chatID int64, lang string) {
+	chatLanguagesMu.Lock()
+	chatLanguages[chatID] = lang
+	chatLanguagesMu.Unlock()
+}
+
+// forgetLanguage drops the cached language, e.g. once the chat registers an employee
+func forgetLanguage(chatID int64) {
+	chatLanguagesMu.Lock()
+	delete(chatLanguages, chatID)
+	chatLanguagesMu.Unlock()
+}
+
+// employeeLanguage returns the employee's language, Thai if unset
+func employeeLanguage(e *models.Employee) string {
+	if !models.ValidLanguage(e.Language) {
+		return th
+	}
+	return e.Language
+}
+
+// languageName is the name of lang in the language in
+func languageName(in, lang string) string {
+	return tr(in, "language."+lang) + " (`" + lang + "`)"
+}
+
+// handleLanguage shows or switches the language of the chat's employee
+func handleLanguage(s *site, message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	emp, err := getEmployeeByChat(s, message.Chat.ID)
+	if err != nil {
+		msg.Text = tr(defaultLanguage, "not_registered")
+		return
+	}
+
+	current := employeeLanguage(emp)
+	lang := strings.ToLower(strings.TrimSpace(message.CommandArguments()))
+	if lang == "" {
+		msg.Text = tr(current, "language", languageName(current, current))
+		return
+	}
+	if !models.ValidLanguage(lang) {
+		msg.Text = tr(current, "language.usage")
+		return
+	}
+	emp.Language = lang
+	if err := updateSettings(s, emp); err != nil {
+		log.Printf("❌ Saving the language of %s failed: %v", emp.Name, err)
+		msg.Text = tr(current, "error", err)
+		return
+	}
+	// Check-in messages pick up the language without waiting for the employee cache
+	invalidateEmployee(s.id, emp.MacAddress)
+	rememberLanguage(message.Chat.ID, lang)
+	msg.Text = tr(lang, "language.saved", languageName(lang, lang))
+}
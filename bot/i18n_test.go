@@ -0,0 +1,107 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCatalog(t *testing.T) {
+	for id, texts := range catalog {
+		if texts[th] == "" {
+			t.Errorf("%s has no Thai text", id)
+		}
+		for lang, text := range texts {
+			if strings.Count(text, "%") != strings.Count(texts[th], "%") {
+				t.Errorf("%s in %s takes other arguments than in Thai", id, lang)
+			}
+		}
+	}
+	if got := tr(en, "today.none"); got != catalog["today.none"][th] {
+		t.Errorf("untranslated message = %q, want the Thai one", got)
+	}
+}
+
+// answerIn answers admin chats and chats without an employee in lang during the test,
+// forgetting the chat languages cached by earlier tests
+func answerIn(t *testing.T, lang string) {
+	t.Helper()
+	chatLanguages = make(map[int64]string)
+	defaultLanguage = lang
+	t.Cleanup(func() {
+		chatLanguages = make(map[int64]string)
+		defaultLanguage = th
+	})
+}
+
+func TestLanguageCommand(t *testing.T) {
+	const chatID, adminChat = 1001, 42
+	var mu sync.Mutex
+	language := ""
+	// PocketBase has one employee, whose language the bot changes
+	useSingleSite(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodPatch && r.URL.Path == "/api/collections/employees/records/e1":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			language = body["language"]
+			w.Write([]byte(`{"id":"e1"}`))
+		case r.URL.Path == "/api/collections/employees/records" && strings.Contains(r.URL.Query().Get("filter"), "1001"):
+			json.NewEncoder(w).Encode(map[string]interface{}{"items": []map[string]interface{}{{
+				"id": "e1", "name": "Somchai", "telegram_chat_id": chatID, "is_active": true, "language": language,
+			}}})
+		case r.URL.Path == "/api/collections/employees/records":
+			w.Write([]byte(`{"items":[]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}), adminChat)
+	tg := newFakeTelegram(t)
+	answerIn(t, th)
+
+	steps := []struct {
+		chatID  int64
+		command string
+		want    string
+	}{
+		{chatID, "/language", "ภาษา: *ไทย (`th`)*"},
+		{chatID, "/start", "*คำสั่ง:*"},
+		{chatID, "/language fr", "Usage: `/language th|en`"},
+		{chatID, "/language en", "Language set to *English (`en`)*"},
+		{chatID, "/start", "*Commands:*\n/register_employee - register"},
+		{chatID, "/myinfo", "👤 *My details*\nName: Somchai"},
+		{chatID, "/notifications", "Check-in messages (`checkin`): *on*"},
+		{chatID, "/cancel", "❌ Cancelled"},
+		{chatID, "/language th", "ตั้งภาษาเป็น *ไทย (`th`)* แล้ว"},
+		{chatID, "/notifications", "แจ้งเตือนเข้างาน (`checkin`): *เปิด*"},
+		{adminChat, "/start", "*คำสั่ง:*"},
+	}
+	for _, st := range steps {
+		t.Run(st.command, func(t *testing.T) {
+			handleUpdate(commandUpdate(st.chatID, st.command))
+			if got := tg.last(t, "sendMessage").params.Get("text"); !strings.Contains(got, st.want) {
+				t.Errorf("%s = %q, want it to contain %q", st.command, got, st.want)
+			}
+		})
+	}
+
+	// Admin chats without an employee are answered in ADMIN_LANGUAGE
+	if err := SetDefaultLanguage("fr"); err == nil {
+		t.Error("SetDefaultLanguage(fr) = nil, want an error")
+	}
+	if err := SetDefaultLanguage(en); err != nil {
+		t.Fatal(err)
+	}
+	chatLanguages = make(map[int64]string)
+	handleUpdate(commandUpdate(adminChat, "/start"))
+	if got := tg.last(t, "sendMessage").params.Get("text"); !strings.Contains(got, "/whoisin - who is in the office now") {
+		t.Errorf("admin /start = %q, want it in English", got)
+	}
+	if language != th {
+		t.Errorf("stored language %q, want th", language)
+	}
+}
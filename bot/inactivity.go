@@ -0,0 +1,29 @@
+package bot
+
+import (
+	"context"
+	"errors"
+)
+
+// InactivityDecider applies the Deactivate and Keep buttons of the inactivity digest
+type InactivityDecider interface {
+	HandleCallback(ctx context.Context, data string, actorID int64, actorName string) (string, error)
+}
+
+// inactivityCallbackPrefix routes the buttons of the inactivity digest
+const inactivityCallbackPrefix = "idle"
+
+// SetInactivityPolicy lets the tenant's admin chats answer the inactivity digest
+func SetInactivityPolicy(tenantID string, d InactivityDecider) {
+	HandleCallbacks(tenantID, inactivityCallbackPrefix, func(ctx context.Context, chatID int64, data string) (string, error) {
+		if !isSiteAdmin(chatID) {
+			return "", errors.New("the inactivity digest is for admin chats only")
+		}
+		user := callbackUser(ctx)
+		if user == nil {
+			return "", errors.New("unknown user")
+		}
+		by := periodLockActor(user)
+		return d.HandleCallback(ctx, data, by.UserID, by.Name)
+	})
+}
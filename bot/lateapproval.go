@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/models"
+)
+
+// LateApprover records employees' requests to arrive late on one day and the admin
+// decisions on them
+type LateApprover interface {
+	Request(ctx context.Context, employeeID, date, expected, reason string) (*models.LateApproval, error)
+	Decide(ctx context.Context, data string, actorID int64, actorName string) (string, error)
+	ApprovedFor(ctx context.Context, employeeID string, day time.Time) (*models.LateApproval, error)
+}
+
+// lateApprovalCallbackPrefix routes the approve and reject buttons of late arrival requests
+const lateApprovalCallbackPrefix = "late"
+
+var (
+	lateApproversMu sync.RWMutex
+	lateApprovers   = make(map[string]LateApprover) // tenant ID → approver
+)
+
+// SetLateApprovals enables /late_approval for the tenant's employees and lets its admin
+// chats decide the requests
+func SetLateApprovals(tenantID string, a LateApprover) {
+	lateApproversMu.Lock()
+	lateApprovers[tenantID] = a
+	lateApproversMu.Unlock()
+
+	HandleCallbacks(tenantID, lateApprovalCallbackPrefix, func(ctx context.Context, chatID int64, data string) (string, error) {
+		if !isSiteAdmin(chatID) {
+			return "", errors.New("late arrival requests are for admin chats only")
+		}
+		user := callbackUser(ctx)
+		if user == nil {
+			return "", errors.New("unknown user")
+		}
+		by := periodLockActor(user)
+		return a.Decide(ctx, data, by.UserID, by.Name)
+	})
+}
+
+// lateApprover returns the site's approver, or nil when not enabled
+func lateApprover(s *site) LateApprover {
+	lateApproversMu.RLock()
+	defer lateApproversMu.RUnlock()
+	return lateApprovers[s.id]
+}
+
+// handleLateApproval asks the admin chat to let the employee arrive late on one day
+func handleLateApproval(s *site, message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	lang := chatLanguage(s, message.Chat.ID)
+	a := lateApprover(s)
+	if a == nil {
+		msg.Text = tr(lang, "late.disabled")
+		return
+	}
+	args := strings.Fields(message.CommandArguments())
+	if len(args) < 3 {
+		msg.Text = tr(lang, "late.usage")
+		return
+	}
+
+	emp, err := getEmployeeByChat(s, message.Chat.ID)
+	if errors.Is(err, errNotRegistered) {
+		msg.Text = tr(lang, "not_registered")
+		return
+	}
+	if err != nil {
+		msg.Text = tr(lang, "unavailable")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(pollContext(), 10*time.Second)
+	defer cancel()
+	approval, err := a.Request(ctx, emp.ID, args[0], args[1], strings.Join(args[2:], " "))
+	if err != nil {
+		log.Printf("❌ Late arrival request of %s failed: %v", emp.Name, err)
+		msg.Text = fmt.Sprintf("❌ %v", err)
+		return
+	}
+	msg.Text = tr(lang, "late.requested", approval.ExpectedTime, approval.Date)
+}
+
+// approvedLateLine is the /myinfo line of today's approved late arrival in lang, empty
+// if none
+func approvedLateLine(s *site, employeeID, lang string) string {
+	a := lateApprover(s)
+	if a == nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(pollContext(), 10*time.Second)
+	defer cancel()
+	approval, err := a.ApprovedFor(ctx, employeeID, time.Now())
+	if err != nil {
+		log.Printf("Warning: failed to read late approvals of %s: %v", employeeID, err)
+		return ""
+	}
+	if approval == nil {
+		return ""
+	}
+	return tr(lang, "late.today", approval.ExpectedTime, markdown.Escape(approval.Reason))
+}
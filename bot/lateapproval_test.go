@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/tenant"
+)
+
+// fakeLateApprover records requests and approves the one given for every day
+type fakeLateApprover struct {
+	requests []string
+	approved *models.LateApproval
+}
+
+func (f *fakeLateApprover) Request(ctx context.Context, employeeID, date, expected, reason string) (*models.LateApproval, error) {
+	f.requests = append(f.requests, strings.Join([]string{employeeID, date, expected, reason}, "|"))
+	return &models.LateApproval{EmployeeID: employeeID, Date: date, ExpectedTime: expected, Reason: reason}, nil
+}
+
+func (f *fakeLateApprover) Decide(ctx context.Context, data string, actorID int64, actorName string) (string, error) {
+	return "decided", nil
+}
+
+func (f *fakeLateApprover) ApprovedFor(ctx context.Context, employeeID string, day time.Time) (*models.LateApproval, error) {
+	return f.approved, nil
+}
+
+func TestLateApprovalCommand(t *testing.T) {
+	const chatID = 1001
+	useSingleSite(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/collections/employees/records" {
+			fmt.Fprintf(w, `{"items":[{"id":"emp1","name":"Somchai","telegram_chat_id":%d,"work_start_time":"08:30:00","grace_period_minutes":10,"is_active":true}]}`, chatID)
+			return
+		}
+		http.NotFound(w, r)
+	}), 0)
+	tg := newFakeTelegram(t)
+	approver := &fakeLateApprover{}
+	SetLateApprovals(tenant.DefaultID, approver)
+	t.Cleanup(func() {
+		lateApproversMu.Lock()
+		delete(lateApprovers, tenant.DefaultID)
+		lateApproversMu.Unlock()
+	})
+
+	tests := []struct {
+		name         string
+		command      string
+		approved     *models.LateApproval
+		wantReply    string
+		wantRequests int
+	}{
+		{"usage", "/late_approval 2026-03-05", nil, "Usage", 0},
+		{"request", "/late_approval 2026-03-05 10:30 ไปพบแพทย์ ที่ รพ.", nil, "รอผู้ดูแลอนุมัติ", 1},
+		{"myinfo without approval", "/myinfo", nil, "Name: Somchai", 1},
+		{"myinfo shows the grace period", "/myinfo", nil, "Grace: 10 นาที (สายตั้งแต่ `08:40`)", 1},
+		{"myinfo shows today's approval", "/myinfo", &models.LateApproval{ExpectedTime: "10:30", Reason: "ไปพบแพทย์"}, "เข้างานภายใน `10:30`", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			approver.approved = tt.approved
+			handleUpdate(commandUpdate(chatID, tt.command))
+			if got := tg.last(t, "sendMessage").params.Get("text"); !strings.Contains(got, tt.wantReply) {
+				t.Errorf("reply = %q, want it to contain %q", got, tt.wantReply)
+			}
+			if len(approver.requests) != tt.wantRequests {
+				t.Errorf("requests = %q, want %d", approver.requests, tt.wantRequests)
+			}
+		})
+	}
+	if want := "emp1|2026-03-05|10:30|ไปพบแพทย์ ที่ รพ."; approver.requests[0] != want {
+		t.Errorf("request = %q, want %q", approver.requests[0], want)
+	}
+}
+
+func TestLateApprovalInEnglish(t *testing.T) {
+	const chatID = 1001
+	useSingleSite(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/collections/employees/records" {
+			fmt.Fprintf(w, `{"items":[{"id":"emp1","name":"Somchai","telegram_chat_id":%d,"is_active":true,"language":"en"}]}`, chatID)
+			return
+		}
+		http.NotFound(w, r)
+	}), 0)
+	tg := newFakeTelegram(t)
+	answerIn(t, th)
+	approver := &fakeLateApprover{}
+	SetLateApprovals(tenant.DefaultID, approver)
+	t.Cleanup(func() {
+		lateApproversMu.Lock()
+		delete(lateApprovers, tenant.DefaultID)
+		lateApproversMu.Unlock()
+	})
+
+	tests := []struct {
+		command  string
+		approved *models.LateApproval
+		want     string
+	}{
+		{"/late_approval 2026-03-05", nil, "<reason>"},
+		{"/late_approval 2026-03-05 10:30 dentist", nil, "🕘 Asked to arrive by `10:30` on 2026-03-05, waiting for an admin"},
+		{"/myinfo", &models.LateApproval{ExpectedTime: "10:30", Reason: "dentist"}, "🕘 Approved to arrive by `10:30` today (dentist)"},
+	}
+	for _, tt := range tests {
+		approver.approved = tt.approved
+		handleUpdate(commandUpdate(chatID, tt.command))
+		if got := tg.last(t, "sendMessage").params.Get("text"); !strings.Contains(got, tt.want) {
+			t.Errorf("%s = %q, want it to contain %q", tt.command, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+	"med-pulse-bot/internal/services"
+)
+
+// ManualCheckInRecorder records check-ins admins enter for employees of one tenant, and
+// the check-ins and check-outs employees enter themselves
+type ManualCheckInRecorder interface {
+	Find(ctx context.Context, employeeCode string) (*models.Employee, error)
+	CheckIn(ctx context.Context, employeeCode string, hour, minute int) (*models.Employee, *models.Attendance, error)
+	SelfCheckIn(ctx context.Context, chatID int64) (*models.Employee, *models.Attendance, error)
+	SelfCheckOut(ctx context.Context, chatID int64) (*models.Employee, *models.Attendance, error)
+}
+
+// manualCheckInFlow is the time picker flow of /manual_checkin; its arg is the employee code
+const manualCheckInFlow = "mci"
+
+var (
+	manualCheckInsMu sync.RWMutex
+	manualCheckIns   = make(map[string]ManualCheckInRecorder) // tenant ID → recorder
+)
+
+// SetManualCheckIns enables /manual_checkin for the tenant's admin chats
+func SetManualCheckIns(tenantID string, r ManualCheckInRecorder) {
+	manualCheckInsMu.Lock()
+	manualCheckIns[tenantID] = r
+	manualCheckInsMu.Unlock()
+}
+
+// manualCheckInRecorder returns the site's recorder, or nil when not enabled
+func manualCheckInRecorder(s *site) ManualCheckInRecorder {
+	manualCheckInsMu.RLock()
+	defer manualCheckInsMu.RUnlock()
+	return manualCheckIns[s.id]
+}
+
+// handleManualCheckIn checks the employee code and asks the admin for the check-in time
+func handleManualCheckIn(s *site, message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	lang := chatLanguage(s, message.Chat.ID)
+	if !isSiteAdmin(message.Chat.ID) {
+		msg.Text = tr(lang, "admin_only")
+		return
+	}
+	r := manualCheckInRecorder(s)
+	if r == nil {
+		msg.Text = tr(lang, "manual_checkin.disabled")
+		return
+	}
+	code := strings.TrimSpace(message.CommandArguments())
+	if code == "" {
+		msg.Text = tr(lang, "manual_checkin.usage")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(pollContext(), 10*time.Second)
+	defer cancel()
+	if _, err := r.Find(ctx, code); err != nil {
+		msg.Text = fmt.Sprintf("❌ %v", err)
+		return
+	}
+	startTimePicker(msg, manualCheckInFlow, code, lang)
+}
+
+func manualCheckInTitle(lang, code string) string {
+	return tr(lang, "manual_checkin.title", code)
+}
+
+// manualCheckInDone records the check-in at the chosen time
+func manualCheckInDone(ctx context.Context, s *site, chatID int64, code string, hour, minute int) string {
+	lang := chatLanguage(s, chatID)
+	if !isSiteAdmin(chatID) {
+		return tr(lang, "admin_only")
+	}
+	r := manualCheckInRecorder(s)
+	if r == nil {
+		return tr(lang, "manual_checkin.disabled")
+	}
+
+	emp, att, err := r.CheckIn(ctx, code, hour, minute)
+	if err != nil {
+		log.Printf("❌ Manual check-in of %s at %02d:%02d failed: %v", code, hour, minute, err)
+		return fmt.Sprintf("❌ %v", err)
+	}
+	return tr(lang, "manual_checkin.done",
+		markdown.Escape(emp.Name), markdown.Code(emp.EmployeeCode), att.CheckInTime.Format("15:04"), statusLabel(att.Status, lang))
+}
+
+// handleSelfCheckIn records a check-in now for the chat's employee, whose tag was not
+// detected; the admins are told
+func handleSelfCheckIn(s *site, message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	lang := chatLanguage(s, message.Chat.ID)
+	r := manualCheckInRecorder(s)
+	if r == nil {
+		msg.Text = tr(lang, "self_entry.disabled")
+		return
+	}
+	ctx, cancel := context.WithTimeout(pollContext(), 10*time.Second)
+	defer cancel()
+	_, att, err := r.SelfCheckIn(ctx, message.Chat.ID)
+	if err != nil {
+		msg.Text = selfEntryError(err, "check-in", lang)
+		return
+	}
+	msg.Text = tr(lang, "self_entry.checkin", att.CheckInTime.In(location).Format("15:04"), statusLabel(att.Status, lang))
+}
+
+// handleSelfCheckOut records now as the chat's employee's check-out; the admins are told
+func handleSelfCheckOut(s *site, message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	lang := chatLanguage(s, message.Chat.ID)
+	r := manualCheckInRecorder(s)
+	if r == nil {
+		msg.Text = tr(lang, "self_entry.disabled")
+		return
+	}
+	ctx, cancel := context.WithTimeout(pollContext(), 10*time.Second)
+	defer cancel()
+	_, att, err := r.SelfCheckOut(ctx, message.Chat.ID)
+	if err != nil {
+		msg.Text = selfEntryError(err, "check-out", lang)
+		return
+	}
+	msg.Text = tr(lang, "self_entry.checkout",
+		att.CheckInTime.In(location).Format("15:04"), att.CheckOutTime.In(location).Format("15:04"))
+}
+
+// selfEntryError explains in lang why /checkin or /checkout was not recorded
+func selfEntryError(err error, what, lang string) string {
+	switch {
+	case errors.Is(err, services.ErrChatNotEmployee):
+		return tr(lang, "not_registered")
+	case errors.Is(err, services.ErrAlreadyCheckedIn):
+		return tr(lang, "self_entry.already_in")
+	case errors.Is(err, services.ErrNotCheckedIn):
+		return tr(lang, "self_entry.not_in")
+	case errors.Is(err, repository.ErrPeriodLocked):
+		return tr(lang, "period_locked")
+	}
+	log.Printf("❌ Self %s failed: %v", what, err)
+	return tr(lang, "unavailable")
+}
@@ -0,0 +1,86 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/logging"
+	"med-pulse-bot/internal/markdown"
+)
+
+func TestMyInfoEscapesNames(t *testing.T) {
+	const chatID = 1001
+	useSingleSite(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/collections/employees/records" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"items":[{"id":"emp1","name":"*Som_chai* [night%[1]ss]","employee_code":"N_01",`+
+			`"department":"ICU*2","mac_address":"aa:bb:cc:dd:ee:01","telegram_chat_id":%[2]d,"is_active":true}]}`, "`", chatID)
+	}), 0)
+	tg := newFakeTelegram(t)
+
+	handleUpdate(commandUpdate(chatID, "/myinfo"))
+	text := tg.last(t, "sendMessage").params.Get("text")
+	if err := markdown.Check(text); err != nil {
+		t.Errorf("/myinfo = %q does not parse: %v", text, err)
+	}
+	if !strings.Contains(text, `Name: \*Som\_chai\* \[night`) {
+		t.Errorf("/myinfo = %q, want the name escaped", text)
+	}
+}
+
+func TestRejectedMessageIsLogged(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/getMe") {
+			w.Write([]byte(`{"ok":true,"result":{"id":1,"is_bot":true,"first_name":"Test"}}`))
+			return
+		}
+		w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: can't parse entities"}`))
+	}))
+	t.Cleanup(srv.Close)
+	api, err := tgbotapi.NewBotAPIWithAPIEndpoint("test-token", srv.URL+"/bot%s/%s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot = api
+	t.Cleanup(func() { bot = nil })
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	if err := logging.Setup(&logs, "debug", "text"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		slog.SetDefault(defaultLogger)
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	})
+
+	SendPersonalNotification(1001, "👋 คุณSom_chai")
+	var info, debug []string
+	for _, line := range strings.Split(logs.String(), "\n") {
+		switch {
+		case strings.Contains(line, "level=INFO"):
+			info = append(info, line)
+		case strings.Contains(line, "level=DEBUG"):
+			debug = append(debug, line)
+		}
+	}
+	// The reason is logged at info; the chat and the text, both personal, only at debug
+	if got := strings.Join(info, "\n"); !strings.Contains(got, "Telegram rejected a message (can't find end of the entity") ||
+		strings.Contains(got, "1001") || strings.Contains(got, "Som_chai") {
+		t.Errorf("info log = %q, want the reason without the chat or the text", got)
+	}
+	if got := strings.Join(debug, "\n"); !strings.Contains(got, "chat_id=1001") || !strings.Contains(got, "Som_chai") {
+		t.Errorf("debug log = %q, want the chat and the text", got)
+	}
+}
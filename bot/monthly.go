@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/services"
+)
+
+// MonthlyReporter totals an employee's attendance of one tenant for a month
+type MonthlyReporter interface {
+	Monthly(ctx context.Context, emp *models.Employee, month time.Time) (*services.MonthlySummary, error)
+}
+
+var (
+	monthlyReportersMu sync.RWMutex
+	monthlyReporters   = make(map[string]MonthlyReporter) // tenant ID → reporter
+)
+
+// SetMonthlyReport enables /monthly for the tenant's employees
+func SetMonthlyReport(tenantID string, r MonthlyReporter) {
+	monthlyReportersMu.Lock()
+	monthlyReporters[tenantID] = r
+	monthlyReportersMu.Unlock()
+}
+
+// thaiMonths are the month names of /monthly, January first
+var thaiMonths = [...]string{"มกราคม", "กุมภาพันธ์", "มีนาคม", "เมษายน", "พฤษภาคม", "มิถุนายน",
+	"กรกฎาคม", "สิงหาคม", "กันยายน", "ตุลาคม", "พฤศจิกายน", "ธันวาคม"}
+
+// monthName names the month in lang
+func monthName(m time.Month, lang string) string {
+	if lang == th {
+		return thaiMonths[m-1]
+	}
+	return m.String()
+}
+
+// handleMonthly shows the chat's employee's attendance totals for a month, this month
+// without an argument
+func handleMonthly(s *site, message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	lang := chatLanguage(s, message.Chat.ID)
+	month := time.Now().In(location)
+	if arg := strings.TrimSpace(message.CommandArguments()); arg != "" {
+		parsed, err := time.ParseInLocation("2006-01", arg, location)
+		if err != nil {
+			msg.Text = tr(lang, "monthly.usage")
+			return
+		}
+		month = parsed
+	}
+
+	monthlyReportersMu.RLock()
+	r := monthlyReporters[s.id]
+	monthlyReportersMu.RUnlock()
+	if r == nil {
+		msg.Text = tr(lang, "monthly.disabled")
+		return
+	}
+	emp, err := getEmployeeByChat(s, message.Chat.ID)
+	if errors.Is(err, errNotRegistered) {
+		msg.Text = tr(lang, "not_registered")
+		return
+	}
+	if err != nil {
+		msg.Text = tr(lang, "unavailable")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(pollContext(), 10*time.Second)
+	defer cancel()
+	summary, err := r.Monthly(ctx, &models.Employee{ID: emp.ID, WorkStartTime: emp.WorkStartTime, WorkEndTime: emp.WorkEndTime, GracePeriod: emp.GracePeriod}, month)
+	if err != nil {
+		log.Printf("Failed to build the monthly summary of %s: %v", emp.Name, err)
+		msg.Text = tr(lang, "unavailable")
+		return
+	}
+	msg.Text = monthlyText(summary, lang)
+}
+
+// monthlyText renders a month's totals in lang
+func monthlyText(m *services.MonthlySummary, lang string) string {
+	lines := []string{
+		tr(lang, "monthly.title", monthName(m.Month.Month(), lang), m.Month.Year()),
+		tr(lang, "monthly.working_days", m.WorkingDays),
+		tr(lang, "monthly.present", m.DaysPresent),
+		tr(lang, "monthly.late", m.DaysLate, m.LateMinutes),
+	}
+	if m.DaysPresent > 0 {
+		avg := m.AverageCheckIn % (24 * time.Hour)
+		lines = append(lines, tr(lang, "monthly.average", int(avg.Hours()), int(avg.Minutes())%60))
+	}
+	if m.OffDays > 0 {
+		lines = append(lines, tr(lang, "monthly.offdays", m.OffDays))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/services"
+	"med-pulse-bot/internal/tenant"
+)
+
+// fakeMonthly returns a fixed summary for the requested month
+type fakeMonthly services.MonthlySummary
+
+func (f fakeMonthly) Monthly(ctx context.Context, emp *models.Employee, month time.Time) (*services.MonthlySummary, error) {
+	summary := services.MonthlySummary(f)
+	summary.Month = time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, location)
+	return &summary, nil
+}
+
+func TestMonthly(t *testing.T) {
+	const chatID = 1001
+	useSingleSite(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"items":[{"id":"emp1","name":"Somchai","telegram_chat_id":%d,"is_active":true}]}`, chatID)
+	}), 0)
+	tg := newFakeTelegram(t)
+	t.Cleanup(func() { SetMonthlyReport(tenant.DefaultID, nil) })
+	SetMonthlyReport(tenant.DefaultID, fakeMonthly{WorkingDays: 20, DaysPresent: 18, DaysLate: 2, LateMinutes: 27, OffDays: 1,
+		AverageCheckIn: 24*time.Hour + 5*time.Minute})
+
+	tests := []struct {
+		command string
+		want    []string
+		notWant string
+	}{
+		{"/monthly 2026-13", []string{"Usage"}, "สรุป"},
+		{"/monthly 2026-01", []string{"สรุปการเข้างาน มกราคม 2026", "วันทำงาน: 20 วัน", "มาทำงาน: 18 วัน",
+			"มาสาย: 2 วัน (รวม 27 นาที)", "เวลาเข้างานเฉลี่ย: 00:05 น.", "มาทำงานวันหยุด: 1 วัน"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			handleUpdate(commandUpdate(chatID, tt.command))
+			got := tg.last(t, "sendMessage").params.Get("text")
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("%s = %q, want it to contain %q", tt.command, got, want)
+				}
+			}
+			if tt.notWant != "" && strings.Contains(got, tt.notWant) {
+				t.Errorf("%s = %q, want no %q", tt.command, got, tt.notWant)
+			}
+		})
+	}
+}
+
+func TestMonthlyInEnglish(t *testing.T) {
+	const chatID = 1001
+	useSingleSite(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"items":[{"id":"emp1","name":"Somchai","telegram_chat_id":%d,"is_active":true,"language":"en"}]}`, chatID)
+	}), 0)
+	tg := newFakeTelegram(t)
+	answerIn(t, th)
+	t.Cleanup(func() { SetMonthlyReport(tenant.DefaultID, nil) })
+	SetMonthlyReport(tenant.DefaultID, fakeMonthly{WorkingDays: 20, DaysPresent: 18, DaysLate: 2, LateMinutes: 27,
+		AverageCheckIn: 8*time.Hour + 5*time.Minute})
+
+	handleUpdate(commandUpdate(chatID, "/monthly 2026-01"))
+	got := tg.last(t, "sendMessage").params.Get("text")
+	for _, want := range []string{"Attendance in January 2026", "Working days: 20", "Days present: 18",
+		"Days late: 2 (27 minutes in all)", "Average check-in: 08:05"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("/monthly = %q, want it to contain %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/models"
+)
+
+// NotificationPrefsUpdater saves the notification settings employees change with /notifications
+type NotificationPrefsUpdater interface {
+	UpdateNotificationPrefs(ctx context.Context, employeeID string, prefs models.NotificationPrefs) error
+}
+
+var (
+	prefsUpdatersMu sync.RWMutex
+	prefsUpdaters   = make(map[string]NotificationPrefsUpdater) // tenant ID → updater
+)
+
+// SetNotificationPrefs enables `/notifications on|off` and `/notifications quiet` for the
+// tenant's employees
+func SetNotificationPrefs(tenantID string, u NotificationPrefsUpdater) {
+	prefsUpdatersMu.Lock()
+	prefsUpdaters[tenantID] = u
+	prefsUpdatersMu.Unlock()
+}
+
+// notificationPrefs returns the settings the employee can change themselves
+func notificationPrefs(e *models.Employee) models.NotificationPrefs {
+	return models.NotificationPrefs{
+		CheckIn:    !e.NotificationMuted(models.NotificationCheckIn),
+		QuietHours: e.QuietHours,
+	}
+}
+
+// saveNotificationPrefs writes the employee's settings and applies them to emp
+func saveNotificationPrefs(s *site, emp *models.Employee, prefs models.NotificationPrefs) error {
+	prefsUpdatersMu.RLock()
+	u := prefsUpdaters[s.id]
+	prefsUpdatersMu.RUnlock()
+	if u == nil {
+		return errors.New("notification settings are not enabled")
+	}
+
+	ctx, cancel := context.WithTimeout(pollContext(), 10*time.Second)
+	defer cancel()
+	if err := u.UpdateNotificationPrefs(ctx, emp.ID, prefs); err != nil {
+		return err
+	}
+	emp.CheckInOptOut = !prefs.CheckIn
+	emp.QuietHours = prefs.QuietHours
+	return nil
+}
+
+// normalizeQuietHours checks "HH:MM-HH:MM" and writes it with two-digit hours; "off"
+// clears the quiet hours
+func normalizeQuietHours(arg string) (string, error) {
+	if arg == "off" {
+		return "", nil
+	}
+	start, end, err := models.ParseQuietHours(arg)
+	if err != nil {
+		return "", err
+	}
+	if start == end {
+		return "", fmt.Errorf("quiet hours %q start and end at the same time", arg)
+	}
+	hm := func(d time.Duration) string { return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60) }
+	return hm(start) + "-" + hm(end), nil
+}
@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+	"med-pulse-bot/internal/tenant"
+)
+
+func TestNotificationPrefs(t *testing.T) {
+	const chatID = 1001
+	store := memory.NewStore(clock.NewFake(time.Date(2026, 3, 2, 9, 0, 0, 0, location)))
+	store.AddEmployee(models.Employee{ID: "e1", Name: "Somchai", TelegramChatID: chatID, IsActive: true})
+	// PocketBase serves the employee as the store has it
+	useSingleSite(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/collections/employees/records" {
+			http.NotFound(w, r)
+			return
+		}
+		emps, _ := store.Employees().ListActive(r.Context())
+		e := emps[0]
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": []map[string]interface{}{{
+			"id": e.ID, "name": e.Name, "telegram_chat_id": e.TelegramChatID, "is_active": true,
+			"notify_checkin": !e.CheckInOptOut, "notify_quiet_hours": e.QuietHours,
+		}}})
+	}), 0)
+	tg := newFakeTelegram(t)
+	SetNotificationPrefs(tenant.DefaultID, store.Employees())
+	t.Cleanup(func() { SetNotificationPrefs(tenant.DefaultID, nil) })
+
+	steps := []struct {
+		command    string
+		want       string
+		wantOptOut bool
+		wantQuiet  string
+	}{
+		{"/notifications", "แจ้งเตือนเข้างาน (`checkin`): *เปิด*", false, ""},
+		{"/notifications off", "แจ้งเตือนเข้างาน (`checkin`): *ปิด*", true, ""},
+		{"/notifications checkin on", "แจ้งเตือนเข้างาน (`checkin`): *เปิด*", false, ""},
+		{"/notifications quiet 22:00-7:00", "ช่วงเวลาห้ามรบกวน (`quiet`): `22:00-07:00`", false, "22:00-07:00"},
+		{"/notifications quiet 25:00-07:00", "ช่วงเวลาไม่ถูกต้อง", false, "22:00-07:00"},
+		{"/notifications quiet 07:00-07:00", "ช่วงเวลาไม่ถูกต้อง", false, "22:00-07:00"},
+		{"/notifications quiet off", "ช่วงเวลาห้ามรบกวน (`quiet`): ไม่ได้ตั้ง", false, ""},
+		{"/notifications maybe", "Usage", false, ""},
+	}
+	for _, st := range steps {
+		t.Run(st.command, func(t *testing.T) {
+			handleUpdate(commandUpdate(chatID, st.command))
+			if got := tg.last(t, "sendMessage").params.Get("text"); !strings.Contains(got, st.want) {
+				t.Errorf("%s = %q, want it to contain %q", st.command, got, st.want)
+			}
+			emps, _ := store.Employees().ListActive(t.Context())
+			if emps[0].CheckInOptOut != st.wantOptOut || emps[0].QuietHours != st.wantQuiet {
+				t.Errorf("stored opt-out %v, quiet hours %q; want %v, %q", emps[0].CheckInOptOut, emps[0].QuietHours, st.wantOptOut, st.wantQuiet)
+			}
+		})
+	}
+}
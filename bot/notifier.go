@@ -1,26 +1,89 @@
 // Package bot provides a wrapper for the Telegram bot to implement BotNotifier interface
 package bot
 
+import (
+	"log"
+
+	"med-pulse-bot/internal/models"
+)
+
 // Notifier wraps the package-level bot functions to implement services.BotNotifier interface
-type Notifier struct{}
+type Notifier struct {
+	tenantScoped bool
+	adminChatID  int64
+}
 
 // NewNotifier creates a new bot notifier
 func NewNotifier() *Notifier {
 	return &Notifier{}
 }
 
+// NewTenantNotifier creates a notifier whose admin messages go to a tenant's admin chat.
+// They are dropped when the tenant has none rather than leaking to another admin chat.
+func NewTenantNotifier(adminChatID int64) *Notifier {
+	return &Notifier{tenantScoped: true, adminChatID: adminChatID}
+}
+
 // SendNotification sends a notification to the admin chat
 func (n *Notifier) SendNotification(message string) {
-	SendNotification(message)
+	if !n.tenantScoped {
+		SendNotification(message)
+		return
+	}
+	if n.adminChatID == 0 {
+		log.Printf("🔕 Dropped admin notification: tenant has no admin_chat_id")
+		return
+	}
+	SendPersonalNotification(n.adminChatID, message)
 }
 
 // SendPersonalNotification sends a notification to a specific user
 func (n *Notifier) SendPersonalNotification(chatID int64, message string) {
+	if groupChat(chatID) {
+		return
+	}
 	SendPersonalNotification(chatID, message)
 }
 
-// Ensure Notifier implements the BotNotifier interface
+// SendPersonalPrompt sends a user a message with inline reply buttons
+func (n *Notifier) SendPersonalPrompt(chatID int64, message string, buttons []models.PromptButton) {
+	if groupChat(chatID) {
+		return
+	}
+	SendPersonalPrompt(chatID, message, buttons)
+}
+
+// groupChat drops personal messages addressed to a group or channel, which employee
+// records registered before chat IDs were checked may hold, and those to records
+// without a chat, such as guest tags
+func groupChat(chatID int64) bool {
+	if chatID == 0 {
+		return true
+	}
+	if chatID > 0 {
+		return false
+	}
+	log.Printf("🔕 Dropped personal notification to a group chat; run audit-chat-ids")
+	return true
+}
+
+// SendPrompt sends the admin chat a message with inline reply buttons
+func (n *Notifier) SendPrompt(message string, buttons []models.PromptButton) {
+	if !n.tenantScoped {
+		SendPrompt(message, buttons)
+		return
+	}
+	if n.adminChatID == 0 {
+		log.Printf("🔕 Dropped admin prompt: tenant has no admin_chat_id")
+		return
+	}
+	SendPersonalPrompt(n.adminChatID, message, buttons)
+}
+
+// Ensure Notifier implements the PromptNotifier and AdminPromptNotifier interfaces
 var _ interface {
 	SendNotification(message string)
 	SendPersonalNotification(chatID int64, message string)
+	SendPersonalPrompt(chatID int64, message string, buttons []models.PromptButton)
+	SendPrompt(message string, buttons []models.PromptButton)
 } = (*Notifier)(nil)
@@ -0,0 +1,204 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+const (
+	// outboxSize is how many notifications may wait for delivery; beyond it they are
+	// dead-lettered rather than blocking the caller
+	outboxSize = 256
+	// maxSendAttempts is how often one notification is tried before it is dead-lettered
+	maxSendAttempts = 5
+	// deadLetterTimeout bounds recording a dead letter in PocketBase
+	deadLetterTimeout = 3 * time.Second
+)
+
+// retryDelay is the wait before the second attempt, doubled before each next one.
+// Telegram's retry_after replaces it on 429.
+var retryDelay = time.Second
+
+// sleep waits d or until ctx is done; tests replace it to skip the waits
+var sleep = func(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// errOutboxFull dead-letters a notification that found the outbox full
+var errOutboxFull = errors.New("outbox full")
+
+// outbox delivers queued notifications one at a time, so a 429 holds back the rest too
+type outbox struct {
+	queue  chan tgbotapi.MessageConfig
+	ctx    context.Context // cancelled when the flush runs out of time
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+var (
+	outboxMu     sync.RWMutex
+	activeOutbox *outbox // nil sends synchronously, e.g. in CLI commands
+
+	deadLettersMu sync.RWMutex
+	deadLetters   repository.NotificationFailureLog
+)
+
+// SetDeadLetterLog keeps notifications that were never delivered in l as well as in the log
+func SetDeadLetterLog(l repository.NotificationFailureLog) {
+	deadLettersMu.Lock()
+	deadLetters = l
+	deadLettersMu.Unlock()
+}
+
+// StartOutbox makes notifications non-blocking: they are queued and delivered, with
+// retries, in the background until FlushOutbox
+func StartOutbox() {
+	ctx, cancel := context.WithCancel(context.Background())
+	o := &outbox{queue: make(chan tgbotapi.MessageConfig, outboxSize), ctx: ctx, cancel: cancel, done: make(chan struct{})}
+	go o.run()
+	outboxMu.Lock()
+	activeOutbox = o
+	outboxMu.Unlock()
+}
+
+// FlushOutbox stops queueing and waits for the queued notifications to be delivered.
+// Those still waiting when ctx is done are dead-lettered. Later notifications are sent
+// synchronously.
+func FlushOutbox(ctx context.Context) error {
+	outboxMu.Lock()
+	o := activeOutbox
+	activeOutbox = nil
+	if o != nil {
+		close(o.queue)
+	}
+	outboxMu.Unlock()
+	if o == nil {
+		return nil
+	}
+
+	select {
+	case <-o.done:
+		return nil
+	case <-ctx.Done():
+		o.cancel()
+		<-o.done
+		return fmt.Errorf("notifications left undelivered: %w", ctx.Err())
+	}
+}
+
+// enqueue hands msg to the outbox, or delivers it right away when there is none
+func enqueue(msg tgbotapi.MessageConfig) {
+	outboxMu.RLock()
+	o := activeOutbox
+	queued := false
+	if o != nil {
+		select {
+		case o.queue <- msg:
+			queued = true
+		default:
+		}
+	}
+	outboxMu.RUnlock()
+
+	switch {
+	case queued:
+	case o != nil:
+		deadLetter(msg, 0, errOutboxFull)
+	default:
+		deliverWithRetry(context.Background(), msg)
+	}
+}
+
+func (o *outbox) run() {
+	defer close(o.done)
+	for msg := range o.queue {
+		if err := o.ctx.Err(); err != nil {
+			deadLetter(msg, 0, fmt.Errorf("shutting down: %w", err))
+			continue
+		}
+		deliverWithRetry(o.ctx, msg)
+	}
+}
+
+// deliverWithRetry sends msg until Telegram accepts it, the error is final or the
+// attempts run out, and dead-letters it in the last two cases
+func deliverWithRetry(ctx context.Context, msg tgbotapi.MessageConfig) {
+	for attempt := 1; ; attempt++ {
+		err := send(msg)
+		if err == nil {
+			return
+		}
+		wait, retry := retryWait(err, attempt)
+		if !retry || attempt == maxSendAttempts {
+			deadLetter(msg, attempt, err)
+			return
+		}
+		log.Printf("⏳ Sending failed (attempt %d/%d), retrying in %s: %v", attempt, maxSendAttempts, wait, err)
+		if serr := sleep(ctx, wait); serr != nil {
+			deadLetter(msg, attempt, fmt.Errorf("%w; shutting down: %w", err, serr))
+			return
+		}
+	}
+}
+
+// retryWait is how long to wait before the attempt after the given one, and whether
+// to try again at all. Telegram's 429 answers say how long to wait; other rejections
+// (400 for bad Markdown, 403 for a bot blocked by the user) are final. Network errors
+// and 5xx answers back off exponentially.
+func retryWait(err error, attempt int) (time.Duration, bool) {
+	backoff := retryDelay << (attempt - 1)
+	var tgErr *tgbotapi.Error
+	if !errors.As(err, &tgErr) {
+		return backoff, true
+	}
+	switch {
+	case tgErr.RetryAfter > 0:
+		return time.Duration(tgErr.RetryAfter) * time.Second, true
+	case tgErr.Code == http.StatusTooManyRequests || tgErr.Code >= 500:
+		return backoff, true
+	}
+	return 0, false
+}
+
+// deadLetter gives up on msg: it is logged, counted for the notification_failures
+// alert and kept in the dead letter log if one is set
+func deadLetter(msg tgbotapi.MessageConfig, attempts int, err error) {
+	// The chat and the text are personal, so they are kept in the dead letter log and only
+	// logged at debug
+	log.Printf("💀 Gave up sending after %d attempts: %v", attempts, err)
+	slog.Debug("Undelivered message", "chat_id", msg.ChatID, "text", msg.Text)
+	notificationFailed(err)
+
+	deadLettersMu.RLock()
+	l := deadLetters
+	deadLettersMu.RUnlock()
+	if l == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), deadLetterTimeout)
+	defer cancel()
+	failure := &models.NotificationFailure{
+		ChatID: msg.ChatID, Text: msg.Text, Error: err.Error(), Attempts: attempts, FailedAt: time.Now(),
+	}
+	if rerr := l.Record(ctx, failure); rerr != nil {
+		log.Printf("Failed to record the undelivered message: %v", rerr)
+	}
+}
@@ -0,0 +1,166 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+// scriptedTelegram answers sendMessage with the scripted replies in turn, then with
+// success. release, when set, holds every answer until it is closed.
+type scriptedTelegram struct {
+	mu      sync.Mutex
+	replies []string
+	sent    []string // texts of the sendMessage calls, failed ones included
+	release chan struct{}
+}
+
+func newScriptedTelegram(t *testing.T, replies ...string) *scriptedTelegram {
+	t.Helper()
+	f := &scriptedTelegram{replies: replies}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/getMe") {
+			w.Write([]byte(`{"ok":true,"result":{"id":1,"is_bot":true,"first_name":"Test"}}`))
+			return
+		}
+		if f.release != nil {
+			<-f.release
+		}
+		r.ParseForm()
+		f.mu.Lock()
+		f.sent = append(f.sent, r.PostForm.Get("text"))
+		reply := `{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":1,"type":"private"}}}`
+		if len(f.replies) > 0 {
+			reply, f.replies = f.replies[0], f.replies[1:]
+		}
+		f.mu.Unlock()
+		w.Write([]byte(reply))
+	}))
+	t.Cleanup(srv.Close)
+	api, err := tgbotapi.NewBotAPIWithAPIEndpoint("test-token", srv.URL+"/bot%s/%s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot = api
+	t.Cleanup(func() { bot = nil })
+	return f
+}
+
+func (f *scriptedTelegram) texts() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.sent...)
+}
+
+// recordWaits replaces the retry waits with a record of them
+func recordWaits(t *testing.T) *[]time.Duration {
+	var waits []time.Duration
+	saved := sleep
+	sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return ctx.Err()
+	}
+	t.Cleanup(func() { sleep = saved })
+	return &waits
+}
+
+const (
+	tooManyRequests = `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 7","parameters":{"retry_after":7}}`
+	serverError     = `{"ok":false,"error_code":502,"description":"Bad Gateway"}`
+	blocked         = `{"ok":false,"error_code":403,"description":"Forbidden: bot was blocked by the user"}`
+)
+
+func TestDeliverWithRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		replies      []string
+		wantSends    int
+		wantWaits    []time.Duration
+		wantFailures int
+	}{
+		{"delivered", nil, 1, nil, 0},
+		{"429 waits retry_after", []string{tooManyRequests}, 2, []time.Duration{7 * time.Second}, 0},
+		{"5xx backs off", []string{serverError, serverError}, 3, []time.Duration{time.Second, 2 * time.Second}, 0},
+		{"blocked is final", []string{blocked}, 1, nil, 1},
+		{"attempts run out", []string{serverError, serverError, serverError, serverError, serverError, serverError}, maxSendAttempts,
+			[]time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tg := newScriptedTelegram(t, tt.replies...)
+			waits := recordWaits(t)
+			store := memory.NewStore(clock.Real{})
+			SetDeadLetterLog(store.NotificationFailureLog())
+			t.Cleanup(func() { SetDeadLetterLog(nil) })
+
+			SendPersonalNotification(1001, "✅ เข้างานแล้ว")
+			if got := len(tg.texts()); got != tt.wantSends {
+				t.Errorf("sent %d times, want %d", got, tt.wantSends)
+			}
+			if fmt.Sprint(*waits) != fmt.Sprint(tt.wantWaits) {
+				t.Errorf("waited %v, want %v", *waits, tt.wantWaits)
+			}
+			failures := store.NotificationFailures()
+			if len(failures) != tt.wantFailures {
+				t.Fatalf("dead-lettered %d messages, want %d", len(failures), tt.wantFailures)
+			}
+			if tt.wantFailures > 0 {
+				if f := failures[0]; f.ChatID != 1001 || f.Text != "✅ เข้างานแล้ว" || f.Attempts != len(tg.texts()) || f.Error == "" {
+					t.Errorf("dead letter = %+v", f)
+				}
+			}
+		})
+	}
+}
+
+func TestOutboxFlushesOnShutdown(t *testing.T) {
+	tg := newScriptedTelegram(t)
+	tg.release = make(chan struct{})
+	StartOutbox()
+	t.Cleanup(func() { FlushOutbox(context.Background()) })
+
+	start := time.Now()
+	for i := 1; i <= 3; i++ {
+		SendPersonalNotification(1001, fmt.Sprintf("ข้อความ %d", i))
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("queueing took %s, want it not to wait for Telegram", d)
+	}
+	close(tg.release)
+
+	if err := FlushOutbox(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(tg.texts(), ","); got != "ข้อความ 1,ข้อความ 2,ข้อความ 3" {
+		t.Errorf("delivered %q, want every queued message in order", got)
+	}
+}
+
+func TestOutboxFlushTimeoutDeadLetters(t *testing.T) {
+	newScriptedTelegram(t, serverError, serverError, serverError, serverError, serverError)
+	store := memory.NewStore(clock.Real{})
+	SetDeadLetterLog(store.NotificationFailureLog())
+	t.Cleanup(func() { SetDeadLetterLog(nil) })
+	StartOutbox()
+
+	SendPersonalNotification(1001, "ข้อความ 1")
+	SendPersonalNotification(1001, "ข้อความ 2")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := FlushOutbox(ctx); err == nil {
+		t.Error("FlushOutbox() = nil, want the messages left undelivered reported")
+	}
+	if got := len(store.NotificationFailures()); got != 2 {
+		t.Errorf("dead-lettered %d messages, want both", got)
+	}
+}
@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// ScannerPairer issues pairing codes for new scanners of one tenant
+type ScannerPairer interface {
+	NewCode(zone string, chatID int64) (string, time.Time, error)
+	Zones(ctx context.Context) ([]string, error)
+}
+
+// pairCallbackPrefix routes zone button presses of the /pair_scanner dialog
+const pairCallbackPrefix = "pair"
+
+var (
+	pairersMu sync.RWMutex
+	pairers   = make(map[string]ScannerPairer) // tenant ID → pairer
+)
+
+// SetScannerPairing enables /pair_scanner for the tenant's admin chats
+func SetScannerPairing(tenantID string, p ScannerPairer) {
+	pairersMu.Lock()
+	pairers[tenantID] = p
+	pairersMu.Unlock()
+
+	HandleCallbacks(tenantID, pairCallbackPrefix, func(ctx context.Context, chatID int64, data string) (string, error) {
+		if !isSiteAdmin(chatID) {
+			return "", errors.New("scanner pairing is for admin chats only")
+		}
+		return pairingCodeText(p, strings.TrimPrefix(data, pairCallbackPrefix+":"), chatID, chatLanguage(nil, chatID)), nil
+	})
+}
+
+// isSiteAdmin reports whether chatID is the global admin chat or a tenant's admin chat
+func isSiteAdmin(chatID int64) bool {
+	if isAdminChat(chatID) {
+		return true
+	}
+	if tenants == nil {
+		return false
+	}
+	_, ok := tenants.ByAdminChat(chatID)
+	return ok
+}
+
+// handlePairScanner issues a pairing code for the zone given as argument, or offers the
+// known zones as buttons
+func handlePairScanner(s *site, message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	lang := chatLanguage(s, message.Chat.ID)
+	if !isSiteAdmin(message.Chat.ID) {
+		msg.Text = tr(lang, "admin_only")
+		return
+	}
+	pairersMu.RLock()
+	p, ok := pairers[s.id]
+	pairersMu.RUnlock()
+	if !ok {
+		msg.Text = tr(lang, "pairing.disabled")
+		return
+	}
+
+	if zone := strings.TrimSpace(message.CommandArguments()); zone != "" {
+		msg.Text = pairingCodeText(p, zone, message.Chat.ID, lang)
+		return
+	}
+
+	msg.Text = tr(lang, "pairing.zones")
+	ctx, cancel := context.WithTimeout(pollContext(), 10*time.Second)
+	defer cancel()
+	zones, err := p.Zones(ctx)
+	if err != nil {
+		log.Printf("Failed to list scanner zones: %v", err)
+		return
+	}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, zone := range zones {
+		data := pairCallbackPrefix + ":" + zone
+		if len(data) > 64 {
+			continue
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(zone, data)))
+	}
+	if len(rows) > 0 {
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	}
+}
+
+// pairingCodeText issues a code for the zone and explains in lang how to use it
+func pairingCodeText(p ScannerPairer, zone string, chatID int64, lang string) string {
+	code, expires, err := p.NewCode(zone, chatID)
+	if err != nil {
+		return tr(lang, "pairing.failed", err)
+	}
+	return tr(lang, "pairing.code", code, zone, expires.Format("15:04"))
+}
@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/tenant"
+)
+
+// fakeScannerPairer issues the code PAIR-<zone> in the zones it knows
+type fakeScannerPairer struct {
+	zones []string
+}
+
+func (f fakeScannerPairer) NewCode(zone string, chatID int64) (string, time.Time, error) {
+	return "PAIR-" + zone, time.Date(2026, 2, 2, 9, 45, 0, 0, time.Local), nil
+}
+
+func (f fakeScannerPairer) Zones(ctx context.Context) ([]string, error) {
+	return f.zones, nil
+}
+
+func TestPairScanner(t *testing.T) {
+	const adminChatID = 42
+	useSingleSite(t, http.NotFoundHandler(), adminChatID)
+	tg := newFakeTelegram(t)
+	t.Cleanup(func() {
+		pairersMu.Lock()
+		delete(pairers, tenant.DefaultID)
+		pairersMu.Unlock()
+	})
+
+	tests := []struct {
+		name    string
+		lang    string
+		chatID  int64
+		command string
+		want    string
+	}{
+		{"disabled", th, adminChatID, "/pair_scanner", "การจับคู่ Scanner ไม่ได้เปิดใช้งาน"},
+		{"employee chat", th, 1001, "/pair_scanner Lobby", "ผู้ดูแลระบบเท่านั้น"},
+		{"zones", th, adminChatID, "/pair_scanner", "เลือกโซนที่ติดตั้ง"},
+		{"code", th, adminChatID, "/pair_scanner Lobby", "`PAIR-Lobby`\n\nโซน: *Lobby*\nหมดอายุ: `09:45`"},
+		{"zones in English", en, adminChatID, "/pair_scanner", "📡 *Pair a new scanner*"},
+		{"code in English", en, adminChatID, "/pair_scanner Lobby", "`PAIR-Lobby`\n\nZone: *Lobby*\nExpires: `09:45`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answerIn(t, tt.lang)
+			if tt.name != "disabled" {
+				SetScannerPairing(tenant.DefaultID, fakeScannerPairer{zones: []string{"Lobby", "Ward 3"}})
+			}
+			handleUpdate(commandUpdate(tt.chatID, tt.command))
+			if got := tg.last(t, "sendMessage").params.Get("text"); !strings.Contains(got, tt.want) {
+				t.Errorf("%s = %q, want it to contain %q", tt.command, got, tt.want)
+			}
+		})
+	}
+
+	// The zone buttons answer in the admin chat's language too
+	answerIn(t, en)
+	handleUpdate(commandUpdate(adminChatID, "/pair_scanner"))
+	handleUpdate(pressUpdate(adminChatID, tg.last(t, "sendMessage").button(t, "Ward 3")))
+	if got := tg.last(t, "editMessageText").params.Get("text"); !strings.Contains(got, "Zone: *Ward 3*") {
+		t.Errorf("zone button = %q, want the code in English", got)
+	}
+}
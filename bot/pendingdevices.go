@@ -0,0 +1,209 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/logging"
+	"med-pulse-bot/internal/macaddr"
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+	"med-pulse-bot/internal/services"
+)
+
+// PendingDeviceReviewer lists one tenant's unknown devices for admins to assign or ignore
+type PendingDeviceReviewer interface {
+	List(ctx context.Context) ([]models.DeviceSighting, error)
+	Assign(ctx context.Context, mac, code string) (*models.Employee, error)
+	Ignore(ctx context.Context, mac string) error
+}
+
+// deviceAssignmentTimeout is how long an admin has to send the employee code
+const deviceAssignmentTimeout = 10 * time.Minute
+
+// deviceAssignment is a device an admin chose to assign, waiting for the employee code
+type deviceAssignment struct {
+	mac     string
+	expires time.Time
+}
+
+var (
+	reviewersMu sync.RWMutex
+	reviewers   = make(map[string]PendingDeviceReviewer) // tenant ID → reviewer
+
+	assigningMu sync.Mutex
+	assigning   = make(map[int64]deviceAssignment) // admin chat → device waiting for a code
+)
+
+// SetPendingDevices enables /pending_devices for the tenant's admin chats
+func SetPendingDevices(tenantID string, r PendingDeviceReviewer) {
+	reviewersMu.Lock()
+	reviewers[tenantID] = r
+	reviewersMu.Unlock()
+	HandleCallbacks(tenantID, services.PendingDeviceCallbackPrefix, func(ctx context.Context, chatID int64, data string) (string, error) {
+		if !isSiteAdmin(chatID) {
+			return "", errors.New("pending devices are for admin chats only")
+		}
+		return handlePendingDeviceCallback(ctx, r, chatID, data)
+	})
+}
+
+// reviewerFor returns the tenant's reviewer, if /pending_devices is enabled
+func reviewerFor(s *site) (PendingDeviceReviewer, bool) {
+	reviewersMu.RLock()
+	defer reviewersMu.RUnlock()
+	r, ok := reviewers[s.id]
+	return r, ok
+}
+
+// handlePendingDevices lists the devices heard often that no employee owns, each with
+// buttons to assign it to an employee or ignore it
+func handlePendingDevices(s *site, message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	if !isSiteAdmin(message.Chat.ID) {
+		msg.Text = "❌ คำสั่งนี้สำหรับผู้ดูแลระบบเท่านั้น"
+		return
+	}
+	r, ok := reviewerFor(s)
+	if !ok {
+		msg.Text = "❌ การบันทึกอุปกรณ์ที่ไม่รู้จักไม่ได้เปิดใช้งาน (UNKNOWN_DEVICE_CAPTURE)"
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(pollContext(), 10*time.Second)
+	defer cancel()
+	devices, err := r.List(ctx)
+	if err != nil {
+		log.Printf("❌ Listing pending devices failed: %v", err)
+		msg.Text = unavailableMessage
+		return
+	}
+	if len(devices) == 0 {
+		msg.Text = "✅ ไม่มีอุปกรณ์ใหม่ที่รอตรวจสอบ"
+		return
+	}
+
+	lines := []string{fmt.Sprintf("📡 *อุปกรณ์ใหม่ที่รอตรวจสอบ* (%d)\n", len(devices))}
+	rows := make([][]tgbotapi.InlineKeyboardButton, len(devices))
+	for i, d := range devices {
+		lines = append(lines, fmt.Sprintf("%d. `%s`\n    แรงสุด %d dBm · พบ %d ครั้ง · ล่าสุด %s",
+			i+1, d.MacAddress, d.RSSI, d.Count, d.SeenAt.In(location).Format("02/01 15:04")))
+		rows[i] = tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("👤 %d. มอบให้พนักงาน", i+1), pendingDeviceData("assign", d.MacAddress)),
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🙈 %d. ไม่สนใจ", i+1), pendingDeviceData("ignore", d.MacAddress)),
+		)
+	}
+	msg.Text = strings.Join(lines, "\n")
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// pendingDeviceData is the callback data of a /pending_devices button
+func pendingDeviceData(action, mac string) string {
+	return services.PendingDeviceCallbackPrefix + ":" + action + ":" + mac
+}
+
+// handlePendingDeviceCallback asks for the employee code of a device to assign, or
+// ignores the device
+func handlePendingDeviceCallback(ctx context.Context, r PendingDeviceReviewer, chatID int64, data string) (string, error) {
+	action, arg, _ := strings.Cut(strings.TrimPrefix(data, services.PendingDeviceCallbackPrefix+":"), ":")
+	mac, err := macaddr.Normalize(arg)
+	if err != nil {
+		return "", fmt.Errorf("invalid pending device callback %q", data)
+	}
+
+	switch action {
+	case "assign":
+		assigningMu.Lock()
+		assigning[chatID] = deviceAssignment{mac: mac, expires: time.Now().Add(deviceAssignmentTimeout)}
+		assigningMu.Unlock()
+		return fmt.Sprintf("👤 ส่งรหัสพนักงานที่จะได้รับอุปกรณ์ `%s`\nยกเลิกได้ด้วย /cancel", mac), nil
+	case "ignore":
+		err := r.Ignore(ctx, mac)
+		if errors.Is(err, repository.ErrDeviceNotFound) {
+			return fmt.Sprintf("❌ ไม่พบอุปกรณ์ `%s`", mac), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("🙈 จะไม่แสดง `%s` อีก\nดูรายการที่เหลือด้วย /pending_devices", mac), nil
+	}
+	return "", fmt.Errorf("invalid pending device callback %q", data)
+}
+
+// handleDeviceAssignmentAnswer takes a plain message from an admin chat that chose a
+// device to assign as the employee code; it reports false when the chat is not assigning
+func handleDeviceAssignmentAnswer(message *tgbotapi.Message) bool {
+	chatID := message.Chat.ID
+	assigningMu.Lock()
+	pending, ok := assigning[chatID]
+	assigningMu.Unlock()
+	if !ok {
+		return false
+	}
+
+	msg := tgbotapi.NewMessage(chatID, "")
+	msg.ParseMode = "Markdown"
+	code := strings.TrimSpace(message.Text)
+	s, siteErr := siteFor(chatID)
+	var r PendingDeviceReviewer
+	if siteErr == nil {
+		r, ok = reviewerFor(s)
+	}
+	switch {
+	case time.Now().After(pending.expires):
+		cancelDeviceAssignment(chatID)
+		msg.Text = "⌛ หมดเวลา เริ่มใหม่ด้วย /pending_devices"
+	case code == "" || strings.ContainsAny(code, " \t"):
+		msg.Text = "❌ ส่งรหัสพนักงานหนึ่งรหัส หรือ /cancel"
+	case siteErr != nil:
+		msg.Text = "❌ " + siteErr.Error()
+	case !ok:
+		cancelDeviceAssignment(chatID)
+		msg.Text = "❌ การบันทึกอุปกรณ์ที่ไม่รู้จักไม่ได้เปิดใช้งาน (UNKNOWN_DEVICE_CAPTURE)"
+	case readOnly():
+		msg.Text = maintenanceMessage
+	default:
+		msg.Text = assignPendingDevice(s, r, chatID, pending.mac, code)
+	}
+	if err := send(msg); err != nil {
+		log.Printf("Bot send error: %v", err)
+	}
+	return true
+}
+
+// assignPendingDevice gives the device to the employee with the code and returns the reply;
+// an unknown code leaves the chat waiting for another one
+func assignPendingDevice(s *site, r PendingDeviceReviewer, chatID int64, mac, code string) string {
+	ctx, cancel := context.WithTimeout(pollContext(), 10*time.Second)
+	defer cancel()
+	emp, err := r.Assign(ctx, mac, code)
+	if errors.Is(err, repository.ErrEmployeeNotFound) {
+		return fmt.Sprintf("❌ ไม่พบพนักงานรหัส %s ส่งรหัสใหม่ หรือ /cancel", markdown.Escape(code))
+	}
+	cancelDeviceAssignment(chatID)
+	if errors.Is(err, services.ErrDeviceAssigned) {
+		return "❌ This MAC address is already registered"
+	}
+	if err != nil {
+		log.Printf("❌ Assigning device %s to %s failed: %v", logging.MaskMAC(mac), code, err)
+		return fmt.Sprintf("❌ Error: %v", err)
+	}
+	// The MAC was cached as unknown; the employee's next detection should check them in
+	invalidateEmployee(s.id, mac)
+	return fmt.Sprintf("✅ มอบอุปกรณ์ `%s` ให้ %s (%s) แล้ว\nดูรายการที่เหลือด้วย /pending_devices",
+		mac, markdown.Escape(emp.Name), markdown.Escape(emp.EmployeeCode))
+}
+
+// cancelDeviceAssignment stops waiting for an employee code from the chat
+func cancelDeviceAssignment(chatID int64) {
+	assigningMu.Lock()
+	delete(assigning, chatID)
+	assigningMu.Unlock()
+}
@@ -0,0 +1,135 @@
+package bot
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+	"med-pulse-bot/internal/tenant"
+)
+
+// fakeReviewer lists fixed devices and records what admins decide
+type fakeReviewer struct {
+	devices  []models.DeviceSighting
+	assigned map[string]string // MAC → employee code
+	ignored  []string
+}
+
+func (f *fakeReviewer) List(ctx context.Context) ([]models.DeviceSighting, error) {
+	return f.devices, nil
+}
+
+func (f *fakeReviewer) Assign(ctx context.Context, mac, code string) (*models.Employee, error) {
+	if code != "N002" {
+		return nil, repository.ErrEmployeeNotFound
+	}
+	f.assigned[mac] = code
+	return &models.Employee{ID: "e2", Name: "Suda", EmployeeCode: code}, nil
+}
+
+func (f *fakeReviewer) Ignore(ctx context.Context, mac string) error {
+	f.ignored = append(f.ignored, mac)
+	return nil
+}
+
+func TestPendingDevices(t *testing.T) {
+	const adminChatID = 42
+	useSingleSite(t, http.NotFoundHandler(), adminChatID)
+	tg := newFakeTelegram(t)
+	reviewer := &fakeReviewer{
+		devices: []models.DeviceSighting{
+			{MacAddress: "aa:bb:cc:dd:ee:0b", RSSI: -50, Count: 10, SeenAt: time.Date(2026, 3, 2, 8, 30, 0, 0, location)},
+			{MacAddress: "aa:bb:cc:dd:ee:0d", RSSI: -58, Count: 2, SeenAt: time.Date(2026, 3, 1, 17, 5, 0, 0, location)},
+		},
+		assigned: make(map[string]string),
+	}
+	SetPendingDevices(tenant.DefaultID, reviewer)
+	t.Cleanup(func() {
+		reviewersMu.Lock()
+		delete(reviewers, tenant.DefaultID)
+		reviewersMu.Unlock()
+		cancelDeviceAssignment(adminChatID)
+	})
+
+	t.Run("employee chats cannot review", func(t *testing.T) {
+		handleUpdate(commandUpdate(1001, "/pending_devices"))
+		if got := tg.last(t, "sendMessage").params.Get("text"); !strings.Contains(got, "ผู้ดูแลระบบเท่านั้น") {
+			t.Errorf("/pending_devices from an employee = %q, want it refused", got)
+		}
+		if _, err := dispatchCallback(1001, 7, nil, "pd:ignore:aa:bb:cc:dd:ee:0b"); err == nil {
+			t.Error("ignoring from an employee chat succeeded, want an error")
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		handleUpdate(commandUpdate(adminChatID, "/pending_devices"))
+		call := tg.last(t, "sendMessage")
+		want := "📡 *อุปกรณ์ใหม่ที่รอตรวจสอบ* (2)\n\n" +
+			"1. `aa:bb:cc:dd:ee:0b`\n    แรงสุด -50 dBm · พบ 10 ครั้ง · ล่าสุด 02/03 08:30\n" +
+			"2. `aa:bb:cc:dd:ee:0d`\n    แรงสุด -58 dBm · พบ 2 ครั้ง · ล่าสุด 01/03 17:05"
+		if got := call.params.Get("text"); got != want {
+			t.Errorf("/pending_devices = %q, want %q", got, want)
+		}
+		for _, data := range []string{"pd:assign:aa:bb:cc:dd:ee:0b", "pd:ignore:aa:bb:cc:dd:ee:0d"} {
+			if !strings.Contains(call.params.Get("reply_markup"), data) {
+				t.Errorf("buttons = %s, want %q", call.params.Get("reply_markup"), data)
+			}
+		}
+	})
+
+	t.Run("ignore", func(t *testing.T) {
+		reply, err := dispatchCallback(adminChatID, 7, nil, "pd:ignore:aa:bb:cc:dd:ee:0d")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(reply, "จะไม่แสดง") || len(reviewer.ignored) != 1 || reviewer.ignored[0] != "aa:bb:cc:dd:ee:0d" {
+			t.Errorf("ignore replied %q and ignored %v", reply, reviewer.ignored)
+		}
+	})
+
+	t.Run("assign asks for the employee code", func(t *testing.T) {
+		reply, err := dispatchCallback(adminChatID, 7, nil, "pd:assign:aa:bb:cc:dd:ee:0b")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(reply, "ส่งรหัสพนักงาน") {
+			t.Errorf("assign replied %q, want the code asked for", reply)
+		}
+
+		steps := []struct {
+			text string
+			want string
+		}{
+			{"N999", "ไม่พบพนักงานรหัส N999"},
+			{"N002", "มอบอุปกรณ์ `aa:bb:cc:dd:ee:0b` ให้ Suda (N002) แล้ว"},
+		}
+		for _, st := range steps {
+			handleUpdate(textUpdate(adminChatID, st.text))
+			if got := tg.last(t, "sendMessage").params.Get("text"); !strings.Contains(got, st.want) {
+				t.Errorf("%s replied %q, want it to contain %q", st.text, got, st.want)
+			}
+		}
+		if reviewer.assigned["aa:bb:cc:dd:ee:0b"] != "N002" {
+			t.Errorf("assigned = %v, want the device given to N002", reviewer.assigned)
+		}
+
+		// The conversation is over, so the next message is not taken as a code
+		if handleDeviceAssignmentAnswer(textUpdate(adminChatID, "N002").Message) {
+			t.Error("a message after the assignment was taken as another code")
+		}
+	})
+
+	t.Run("a command abandons the assignment", func(t *testing.T) {
+		if _, err := dispatchCallback(adminChatID, 7, nil, "pd:assign:aa:bb:cc:dd:ee:0b"); err != nil {
+			t.Fatal(err)
+		}
+		handleUpdate(commandUpdate(adminChatID, "/cancel"))
+		if handleDeviceAssignmentAnswer(textUpdate(adminChatID, "N002").Message) {
+			t.Error("the code was taken after /cancel")
+		}
+	})
+}
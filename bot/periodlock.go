@@ -0,0 +1,145 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/models"
+)
+
+// PeriodLocker locks and unlocks payroll periods of one tenant with two-admin approval
+type PeriodLocker interface {
+	Request(ctx context.Context, action, period string, by models.PeriodLockActor) (*models.PeriodLockRequest, error)
+	Approve(ctx context.Context, id string, by models.PeriodLockActor) (*models.LockedPeriod, error)
+	Cancel(id string, by models.PeriodLockActor) (*models.PeriodLockRequest, error)
+	Locked(ctx context.Context) ([]models.LockedPeriod, error)
+}
+
+// periodLockCallbackPrefix routes the approve and cancel buttons of lock requests
+const periodLockCallbackPrefix = "plock"
+
+var (
+	periodLockersMu sync.RWMutex
+	periodLockers   = make(map[string]PeriodLocker) // tenant ID → locker
+)
+
+// SetPeriodLocking enables /lock_period and /unlock_period for the tenant's admin chats
+func SetPeriodLocking(tenantID string, l PeriodLocker) {
+	periodLockersMu.Lock()
+	periodLockers[tenantID] = l
+	periodLockersMu.Unlock()
+
+	HandleCallbacks(tenantID, periodLockCallbackPrefix, func(ctx context.Context, chatID int64, data string) (string, error) {
+		if !isSiteAdmin(chatID) {
+			return "", errors.New("period locking is for admin chats only")
+		}
+		user := callbackUser(ctx)
+		if user == nil {
+			return "", errors.New("unknown user")
+		}
+		by := periodLockActor(user)
+
+		parts := strings.Split(data, ":")
+		if len(parts) != 3 {
+			return "", fmt.Errorf("malformed period lock data %q", data)
+		}
+		switch parts[1] {
+		case "approve":
+			lock, err := l.Approve(ctx, parts[2], by)
+			if err != nil {
+				return "", err
+			}
+			if lock.Locked {
+				return fmt.Sprintf("🔒 *ล็อกงวด %s แล้ว*\nอนุมัติโดย: %s", lock.Period, markdown.Escape(by.Name)), nil
+			}
+			return fmt.Sprintf("🔓 *ปลดล็อกงวด %s แล้ว*\nอนุมัติโดย: %s", lock.Period, markdown.Escape(by.Name)), nil
+		case "cancel":
+			req, err := l.Cancel(parts[2], by)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("❌ ยกเลิกคำขอ%sงวด %s แล้ว", periodActionText(req.Action), req.Period), nil
+		}
+		return "", fmt.Errorf("unknown period lock action %q", parts[1])
+	})
+}
+
+// handlePeriodLock asks a second admin to approve locking or unlocking the period given
+// as argument; without one it lists the locked periods
+func handlePeriodLock(s *site, action string, message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	if !isSiteAdmin(message.Chat.ID) || message.From == nil {
+		msg.Text = "❌ คำสั่งนี้สำหรับผู้ดูแลระบบเท่านั้น"
+		return
+	}
+	periodLockersMu.RLock()
+	l, ok := periodLockers[s.id]
+	periodLockersMu.RUnlock()
+	if !ok {
+		msg.Text = "❌ การล็อกงวดไม่ได้เปิดใช้งาน"
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(pollContext(), 10*time.Second)
+	defer cancel()
+
+	period := strings.TrimSpace(message.CommandArguments())
+	if period == "" {
+		msg.Text = lockedPeriodsText(ctx, l, action)
+		return
+	}
+
+	req, err := l.Request(ctx, action, period, periodLockActor(message.From))
+	if err != nil {
+		msg.Text = fmt.Sprintf("❌ ไม่สามารถ%sงวดได้: %v", periodActionText(action), err)
+		return
+	}
+	msg.Text = fmt.Sprintf("🔐 *คำขอ%sงวด %s*\n\nขอโดย: %s\n\n"+
+		"ต้องให้ผู้ดูแลอีกคนกดอนุมัติภายใน `%s`",
+		periodActionText(action), req.Period, req.Requester.Name, req.Expires.Format("02/01 15:04"))
+	data := periodLockCallbackPrefix + ":%s:" + req.ID
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("✅ อนุมัติ", fmt.Sprintf(data, "approve")),
+		tgbotapi.NewInlineKeyboardButtonData("❌ ยกเลิก", fmt.Sprintf(data, "cancel")),
+	))
+}
+
+// lockedPeriodsText explains the command and lists the currently locked periods
+func lockedPeriodsText(ctx context.Context, l PeriodLocker, action string) string {
+	text := fmt.Sprintf("🔐 ใช้ `/%s_period YYYY-MM` เช่น `/%s_period 2026-01`", action, action)
+	locked, err := l.Locked(ctx)
+	if err != nil {
+		return text + "\n\n" + unavailableMessage
+	}
+	if len(locked) == 0 {
+		return text + "\n\nยังไม่มีงวดที่ล็อก"
+	}
+	text += "\n\n*งวดที่ล็อกแล้ว:*"
+	for _, lock := range locked {
+		text += fmt.Sprintf("\n🔒 %s (ล็อกเมื่อ %s)", lock.Period, lock.LockedAt.Format("02/01/2006"))
+	}
+	return text
+}
+
+// periodLockActor identifies the Telegram user for the audit history
+func periodLockActor(user *tgbotapi.User) models.PeriodLockActor {
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if name == "" {
+		name = user.UserName
+	}
+	return models.PeriodLockActor{UserID: user.ID, Name: name}
+}
+
+// periodActionText is the Thai verb of a lock action
+func periodActionText(action string) string {
+	if action == models.PeriodActionUnlock {
+		return "ปลดล็อก"
+	}
+	return "ล็อก"
+}
@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/models"
+)
+
+// privacyPrefix routes the presence tracking consent buttons; the data is "pv:yes" or "pv:no"
+const privacyPrefix = "pv"
+
+// consentButtons offers an explicit yes or no to the "privacy.question" message
+func consentButtons(lang string) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(tr(lang, "privacy.agree"), privacyPrefix+":yes"),
+		tgbotapi.NewInlineKeyboardButtonData(tr(lang, "privacy.disagree"), privacyPrefix+":no"),
+	))
+}
+
+// handlePrivacy shows or changes the employee's presence tracking consent
+func handlePrivacy(s *site, message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	emp, err := getEmployeeByChat(s, message.Chat.ID)
+	if err != nil {
+		msg.Text = tr(defaultLanguage, "not_registered")
+		return
+	}
+	lang := employeeLanguage(emp)
+
+	switch strings.ToLower(strings.TrimSpace(message.CommandArguments())) {
+	case "":
+		msg.Text = privacyText(emp, lang)
+		msg.ReplyMarkup = consentButtons(lang)
+	case "on", "off":
+		consent := strings.EqualFold(strings.TrimSpace(message.CommandArguments()), "on")
+		if err := setPresenceConsent(s, emp, consent, message.Chat.ID); err != nil {
+			msg.Text = tr(lang, "error", err)
+			return
+		}
+		msg.Text = tr(lang, "saved") + "\n\n" + privacyText(emp, lang)
+	default:
+		msg.Text = tr(lang, "privacy.usage")
+	}
+}
+
+// handlePrivacyCallback records the answer to the consent buttons
+func handlePrivacyCallback(ctx context.Context, s *site, chatID int64, data string) (string, error) {
+	var consent bool
+	switch data {
+	case privacyPrefix + ":yes":
+		consent = true
+	case privacyPrefix + ":no":
+	default:
+		return "", fmt.Errorf("malformed privacy data %q", data)
+	}
+	emp, err := getEmployeeByChat(s, chatID)
+	if err != nil {
+		return "", err
+	}
+	by := chatID
+	if user := callbackUser(ctx); user != nil {
+		by = user.ID
+	}
+	if err := setPresenceConsent(s, emp, consent, by); err != nil {
+		return "", err
+	}
+	lang := employeeLanguage(emp)
+	return tr(lang, "saved") + "\n\n" + privacyText(emp, lang), nil
+}
+
+// privacyText renders the employee's consent in lang
+func privacyText(emp *models.Employee, lang string) string {
+	state := tr(lang, "privacy.consent")
+	if emp.PresenceOptOut {
+		state = tr(lang, "privacy.no_consent")
+	}
+	return tr(lang, "privacy", state)
+}
+
+// setPresenceConsent audits and stores a consent change made by the chat or user `by`.
+// The audit record is written first, so no change goes unrecorded.
+func setPresenceConsent(s *site, emp *models.Employee, consent bool, by int64) error {
+	if err := auditConsent(s, emp.ID, consent, by); err != nil {
+		return fmt.Errorf("failed to audit consent change: %w", err)
+	}
+	emp.PresenceOptOut = !consent
+	if err := updateSettings(s, emp); err != nil {
+		return err
+	}
+	log.Printf("🔒 %s set presence tracking consent to %v", emp.Name, consent)
+	return nil
+}
+
+// auditConsent records the privacy_audit entry of a consent change
+func auditConsent(s *site, employeeID string, consent bool, by int64) error {
+	return s.consents.RecordConsent(pollContext(), employeeID, consent, by, time.Now())
+}
@@ -0,0 +1,99 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPrivacyConsentIsAudited(t *testing.T) {
+	const chatID = 1001
+	var mu sync.Mutex
+	var writes []string // "audit <consent>" or "employee <consent>", in order
+	auditFails := false
+	useSingleSite(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var data map[string]interface{}
+		json.Unmarshal(body, &data)
+
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/collections/employees/records":
+			fmt.Fprintf(w, `{"items":[{"id":"emp1","name":"Somchai","telegram_chat_id":%d,"is_active":true}]}`, chatID)
+		case r.Method == http.MethodPost && r.URL.Path == "/api/collections/privacy_audit/records":
+			if auditFails {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"status":404,"message":"Missing collection context.","data":{}}`))
+				return
+			}
+			writes = append(writes, fmt.Sprintf("audit %v by %v", data["consent"], data["changed_by"]))
+			w.Write([]byte(`{"id":"pva1"}`))
+		case r.Method == http.MethodPatch && r.URL.Path == "/api/collections/employees/records/emp1":
+			writes = append(writes, fmt.Sprintf("employee %v", data["presence_tracking_consent"]))
+			w.Write([]byte(`{"id":"emp1"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}), 0)
+	tg := newFakeTelegram(t)
+
+	handleUpdate(commandUpdate(chatID, "/privacy"))
+	shown := tg.last(t, "sendMessage")
+	if !strings.Contains(shown.params.Get("text"), "*ยินยอม*") {
+		t.Errorf("/privacy = %q, want consent shown for a record from before the migration", shown.params.Get("text"))
+	}
+
+	handleUpdate(commandUpdate(chatID, "/privacy off"))
+	if text := tg.last(t, "sendMessage").params.Get("text"); !strings.Contains(text, "*ไม่ยินยอม*") {
+		t.Errorf("/privacy off = %q", text)
+	}
+	handleUpdate(pressUpdate(chatID, shown.button(t, "✅ ยินยอม")))
+
+	mu.Lock()
+	auditFails = true
+	mu.Unlock()
+	handleUpdate(commandUpdate(chatID, "/privacy off"))
+	if text := tg.last(t, "sendMessage").params.Get("text"); !strings.Contains(text, "audit") {
+		t.Errorf("reply when the audit fails = %q, want the failure", text)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := "audit false by 1001,employee false,audit true by 1001,employee true"
+	if got := strings.Join(writes, ","); got != want {
+		t.Errorf("writes = %s, want %s", got, want)
+	}
+}
+
+func TestPrivacyInEnglish(t *testing.T) {
+	const chatID = 1001
+	useSingleSite(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/collections/employees/records":
+			fmt.Fprintf(w, `{"items":[{"id":"emp1","name":"Somchai","telegram_chat_id":%d,"is_active":true,"language":"en"}]}`, chatID)
+		case r.Method == http.MethodPost && r.URL.Path == "/api/collections/privacy_audit/records":
+			w.Write([]byte(`{"id":"pva1"}`))
+		case r.Method == http.MethodPatch && r.URL.Path == "/api/collections/employees/records/emp1":
+			w.Write([]byte(`{"id":"emp1"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}), 0)
+	tg := newFakeTelegram(t)
+	answerIn(t, th)
+
+	handleUpdate(commandUpdate(chatID, "/privacy"))
+	shown := tg.last(t, "sendMessage")
+	if text := shown.params.Get("text"); !strings.Contains(text, "Presence tracking: *agreed*") {
+		t.Errorf("/privacy = %q, want it in English", text)
+	}
+	handleUpdate(pressUpdate(chatID, shown.button(t, "🚫 I do not agree")))
+	if text := tg.last(t, "editMessageText").params.Get("text"); !strings.HasPrefix(text, "✅ Saved") || !strings.Contains(text, "*not agreed*") {
+		t.Errorf("answer to the buttons = %q", text)
+	}
+}
@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/models"
+)
+
+func TestQueryFilters(t *testing.T) {
+	const chatID = 1001
+	var mu sync.Mutex
+	queries := map[string]string{} // raw query by collection path
+	pocketBase := useSingleSite(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		queries[r.Method+" "+r.URL.Path] = r.URL.RawQuery
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/api/collections/employees/records":
+			fmt.Fprintf(w, `{"items":[{"id":"o'brien","name":"O'Brien","telegram_chat_id":%d,"is_active":true}]}`, chatID)
+		case "/api/collections/attendance/records":
+			w.Write([]byte(`{"items":[{"id":"att1","employee_id":"o'brien","status":"on_time"}]}`))
+		default:
+			w.Write([]byte(`{"items":[]}`))
+		}
+	}), 0)
+	s, err := siteFor(chatID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := func(t *testing.T, path string) string {
+		t.Helper()
+		mu.Lock()
+		defer mu.Unlock()
+		q, ok := queries[path]
+		if !ok {
+			t.Fatalf("no request to %s", path)
+		}
+		return q
+	}
+
+	today := time.Now().Format("2006-01-02")
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	first, last := time.Date(2026, 1, 1, 0, 0, 0, 0, location), time.Date(2026, 1, 31, 0, 0, 0, 0, location)
+	employeeQuery := fmt.Sprintf("filter=telegram_chat_id%%3D%d+%%26%%26+is_active%%3Dtrue&page=1&perPage=1", chatID)
+	tests := []struct {
+		name string
+		call func() error
+		path string
+		want string
+	}{
+		{"employee by chat", func() error {
+			_, err := getEmployeeByChat(s, chatID)
+			return err
+		}, "GET /api/collections/employees/records", employeeQuery},
+		{"today's attendance", func() error {
+			rec, err := getTodayAttendance(s, chatID)
+			if err == nil && rec == nil {
+				err = fmt.Errorf("no attendance found")
+			}
+			return err
+		}, "GET /api/collections/attendance/records",
+			"filter=employee_id%3D%27o%5C%27brien%27+%26%26+created_date%3E%3D%27" + today + "+00%3A00%3A00%27+%26%26+created_date%3C%27" +
+				tomorrow + "+00%3A00%3A00%27&page=1&perPage=1&sort=-created_date%2C-check_in_time"},
+		{"attendance history", func() error {
+			records, _, err := getAttendanceHistory(s, chatID, first, last, 10, 10)
+			if err == nil && len(records) != 1 {
+				err = fmt.Errorf("got %d records, want 1", len(records))
+			}
+			return err
+		}, "GET /api/collections/attendance/records",
+			"filter=employee_id%3D%27o%5C%27brien%27+%26%26+created_date%3E%3D%272026-01-01+00%3A00%3A00%27+%26%26+created_date%3C%272026-02-01+00%3A00%3A00%27" +
+				"&page=2&perPage=10&sort=-created_date%2C-check_in_time"},
+		{"scanner activity", func() error {
+			return pocketBase.Scanners().UpdateActivity(context.Background(), "AA-BB-CC-DD-EE-01", models.ScannerHealth{})
+		}, "GET /api/collections/scanners/records",
+			"filter=scanner_mac%3D%27aa%3Abb%3Acc%3Add%3Aee%3A01%27+%7C%7C+scanner_mac%3D%27AA%3ABB%3ACC%3ADD%3AEE%3A01%27+%7C%7C+" +
+				"scanner_mac%3D%27aa-bb-cc-dd-ee-01%27+%7C%7C+scanner_mac%3D%27AA-BB-CC-DD-EE-01%27+%7C%7C+" +
+				"scanner_mac%3D%27aabbccddee01%27+%7C%7C+scanner_mac%3D%27AABBCCDDEE01%27&page=1&perPage=1&sort=-last_seen"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); err != nil {
+				t.Fatal(err)
+			}
+			if got := query(t, tt.path); got != tt.want {
+				t.Errorf("query = %s\nwant    %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/services"
+)
+
+// QueueReporter reports the local queues of one tenant
+type QueueReporter interface {
+	Stats(ctx context.Context) ([]services.QueueStats, error)
+}
+
+var (
+	queueReportersMu sync.RWMutex
+	queueReporters   = make(map[string]QueueReporter) // tenant ID → reporter
+)
+
+// SetQueueWatchdog enables /queues for the tenant's admin chats
+func SetQueueWatchdog(tenantID string, r QueueReporter) {
+	queueReportersMu.Lock()
+	queueReporters[tenantID] = r
+	queueReportersMu.Unlock()
+}
+
+// handleQueues shows the size, oldest entry and drain rate of the tenant's local queues
+func handleQueues(s *site, message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	if !isSiteAdmin(message.Chat.ID) {
+		msg.Text = "❌ คำสั่งนี้สำหรับผู้ดูแลระบบเท่านั้น"
+		return
+	}
+	queueReportersMu.RLock()
+	r, ok := queueReporters[s.id]
+	queueReportersMu.RUnlock()
+	if !ok {
+		msg.Text = "❌ ไม่มีคิวในเครื่องที่เปิดใช้งาน"
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(pollContext(), 10*time.Second)
+	defer cancel()
+	all, err := r.Stats(ctx)
+	if err != nil {
+		log.Printf("Failed to read queue stats: %v", err)
+		msg.Text = unavailableMessage
+		return
+	}
+
+	now := time.Now()
+	lines := []string{"📥 *คิวในเครื่อง*"}
+	for _, q := range all {
+		line := fmt.Sprintf("• `%s`: %d รายการ", q.Name, q.Len)
+		if !q.Oldest.IsZero() {
+			line += fmt.Sprintf(", เก่าสุด %s", now.Sub(q.Oldest).Round(time.Second))
+		}
+		line += fmt.Sprintf(", ส่งออกแล้ว %d รายการใน 1 ชั่วโมง", q.DrainedLastHour)
+		lines = append(lines, line)
+	}
+	msg.Text = strings.Join(lines, "\n")
+}
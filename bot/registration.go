@@ -0,0 +1,261 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/logging"
+	"med-pulse-bot/internal/macaddr"
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/repository"
+)
+
+// registrationPrefix routes the confirmation buttons of /register_employee; the data is
+// "reg:yes" or "reg:no"
+const registrationPrefix = "reg"
+
+// registrationTimeout is how long a registration waits for the next answer
+const registrationTimeout = 10 * time.Minute
+
+// Steps of the /register_employee conversation, in order
+const (
+	stepMAC = iota
+	stepName
+	stepCode
+	stepDepartment
+	stepWorkStart
+	stepConfirm
+)
+
+// registrationQuestions are the message IDs of the questions asked for each step before
+// the confirmation
+var registrationQuestions = [...]string{
+	stepMAC:        "registration.mac",
+	stepName:       "registration.name",
+	stepCode:       "registration.code",
+	stepDepartment: "registration.department",
+	stepWorkStart:  "registration.work_start",
+}
+
+// RegistrationState is a chat's /register_employee conversation in progress
+type RegistrationState struct {
+	Step          int
+	MacAddress    string
+	Name          string
+	EmployeeCode  string
+	Department    string
+	WorkStartTime string // HH:MM:SS, empty for the default
+	Lang          string // language the questions are asked in
+	UpdatedAt     time.Time
+}
+
+var (
+	userStatesMu sync.Mutex
+	userStates   = make(map[int64]*RegistrationState) // chat → registration in progress
+)
+
+// EmployeeCache is a tenant's cache of employee lookups by MAC
+type EmployeeCache interface {
+	Invalidate(mac string)
+}
+
+var (
+	employeeCachesMu sync.RWMutex
+	employeeCaches   = make(map[string]EmployeeCache) // tenant ID → cache
+)
+
+// SetEmployeeCache makes registrations in the tenant drop the registered MAC from cache,
+// so a tag cached as unknown checks in right away
+func SetEmployeeCache(tenantID string, c EmployeeCache) {
+	employeeCachesMu.Lock()
+	employeeCaches[tenantID] = c
+	employeeCachesMu.Unlock()
+}
+
+// invalidateEmployee drops the MAC from the tenant's employee cache, if it has one
+func invalidateEmployee(tenantID, mac string) {
+	employeeCachesMu.RLock()
+	c, ok := employeeCaches[tenantID]
+	employeeCachesMu.RUnlock()
+	if ok {
+		c.Invalidate(mac)
+	}
+}
+
+// startRegistration starts the conversation for the chat in lang, replacing any in progress
+func startRegistration(chatID int64, msg *tgbotapi.MessageConfig, lang string) {
+	state := &RegistrationState{Step: stepMAC, Lang: lang, UpdatedAt: time.Now()}
+	userStatesMu.Lock()
+	userStates[chatID] = state
+	userStatesMu.Unlock()
+	msg.Text = tr(lang, "registration.intro") + state.question()
+}
+
+// handleRegistrationAnswer takes a plain message as the answer to the chat's current
+// registration step and asks the next question; chats not registering are ignored
+func handleRegistrationAnswer(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	userStatesMu.Lock()
+	state, ok := userStates[chatID]
+	if !ok {
+		userStatesMu.Unlock()
+		return
+	}
+	msg := tgbotapi.NewMessage(chatID, "")
+	msg.ParseMode = "Markdown"
+	if time.Since(state.UpdatedAt) > registrationTimeout {
+		delete(userStates, chatID)
+		msg.Text = tr(state.Lang, "registration.expired")
+	} else if errText := state.answer(strings.TrimSpace(message.Text)); errText != "" {
+		msg.Text = errText
+	} else if state.Step == stepConfirm {
+		msg.Text = state.confirmation()
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(state.Lang, "registration.confirm"), registrationPrefix+":yes"),
+			tgbotapi.NewInlineKeyboardButtonData(tr(state.Lang, "timepicker.cancel_button"), registrationPrefix+":no"),
+		))
+	} else {
+		msg.Text = state.question()
+	}
+	userStatesMu.Unlock()
+
+	if err := send(msg); err != nil {
+		log.Printf("Bot send error: %v", err)
+	}
+}
+
+// answer validates the answer to the current step and moves to the next one. It returns
+// the text explaining why an answer was refused, empty when it was taken.
+func (r *RegistrationState) answer(text string) string {
+	if text == "" {
+		return tr(r.Lang, "registration.empty") + r.question()
+	}
+	switch r.Step {
+	case stepMAC:
+		mac, err := macaddr.Normalize(text)
+		if err != nil {
+			return invalidMACMessage(text, r.Lang)
+		}
+		r.MacAddress = mac
+	case stepName:
+		r.Name = text
+	case stepCode:
+		if strings.ContainsAny(text, " \t") {
+			return tr(r.Lang, "registration.code_space")
+		}
+		r.EmployeeCode = text
+	case stepDepartment:
+		r.Department = text
+	case stepWorkStart:
+		start, ok := parseWorkStart(text)
+		if !ok {
+			return tr(r.Lang, "registration.invalid_start")
+		}
+		r.WorkStartTime = start
+	default:
+		return tr(r.Lang, "registration.press_button")
+	}
+	r.Step++
+	r.UpdatedAt = time.Now()
+	return ""
+}
+
+// confirmation shows the answers before anything is saved
+func (r *RegistrationState) confirmation() string {
+	start := r.WorkStartTime
+	if start == "" {
+		start = tr(r.Lang, "registration.default_start")
+	}
+	return tr(r.Lang, "registration.check",
+		r.MacAddress, markdown.Escape(r.Name), markdown.Escape(r.EmployeeCode), markdown.Escape(r.Department), start)
+}
+
+// question numbers the question of the current step
+func (r *RegistrationState) question() string {
+	return fmt.Sprintf("(%d/%d) %s", r.Step+1, stepConfirm, tr(r.Lang, registrationQuestions[r.Step]))
+}
+
+// parseWorkStart reads an HH:MM:SS start time, also taking HH:MM as typed on phones;
+// "-" keeps the default and returns ""
+func parseWorkStart(text string) (string, bool) {
+	if text == "-" {
+		return "", true
+	}
+	if t, err := time.Parse("15:04:05", text); err == nil {
+		return t.Format("15:04:05"), true
+	}
+	if hour, minute, ok := parseTypedTime(text); ok {
+		return fmt.Sprintf("%02d:%02d:00", hour, minute), true
+	}
+	return "", false
+}
+
+// handleRegistrationCallback saves or drops the confirmed registration of the chat
+func handleRegistrationCallback(ctx context.Context, s *site, chatID int64, data string) (string, error) {
+	userStatesMu.Lock()
+	state, ok := userStates[chatID]
+	if ok && (state.Step != stepConfirm || time.Since(state.UpdatedAt) > registrationTimeout) {
+		ok = false
+	}
+	delete(userStates, chatID)
+	userStatesMu.Unlock()
+
+	lang := chatLanguage(s, chatID)
+	if state != nil {
+		lang = state.Lang
+	}
+	if data != registrationPrefix+":yes" {
+		return tr(lang, "registration.cancelled"), nil
+	}
+	if !ok {
+		return tr(lang, "registration.expired"), nil
+	}
+
+	err := registerEmployee(s, state.MacAddress, chatID, state.Name, state.EmployeeCode, state.Department, state.WorkStartTime)
+	if errors.Is(err, repository.ErrEmployeeExists) {
+		return tr(lang, "registration.exists"), nil
+	}
+	if errors.Is(err, ErrNotPrivateChat) {
+		return privateChatOnlyMessage, nil
+	}
+	if err != nil {
+		userStatesMu.Lock()
+		userStates[chatID] = state // let the button be pressed again
+		userStatesMu.Unlock()
+		return "", err
+	}
+	log.Printf("📝 Registered %s (%s)", state.EmployeeCode, logging.MaskMAC(state.MacAddress))
+
+	consent := tgbotapi.NewMessage(chatID, tr(lang, "privacy.question"))
+	consent.ParseMode = "Markdown"
+	consent.ReplyMarkup = consentButtons(lang)
+	if err := send(consent); err != nil {
+		log.Printf("Bot send error: %v", err)
+	}
+	return tr(lang, "registration.done", markdown.Escape(state.Name), markdown.Escape(state.EmployeeCode)), nil
+}
+
+// cancelRegistration drops the chat's registration in progress
+func cancelRegistration(chatID int64) {
+	userStatesMu.Lock()
+	delete(userStates, chatID)
+	userStatesMu.Unlock()
+}
+
+// expireRegistrations drops registrations that have waited longer than registrationTimeout
+func expireRegistrations(now time.Time) {
+	userStatesMu.Lock()
+	defer userStatesMu.Unlock()
+	for chatID, state := range userStates {
+		if now.Sub(state.UpdatedAt) > registrationTimeout {
+			delete(userStates, chatID)
+		}
+	}
+}
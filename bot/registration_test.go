@@ -0,0 +1,234 @@
+package bot
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/tenant"
+)
+
+func TestRegistrationConversation(t *testing.T) {
+	const chatID = 1001
+	var mu sync.Mutex
+	var created []map[string]interface{}
+	useSingleSite(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/collections/employees/records" {
+			http.NotFound(w, r)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var data map[string]interface{}
+		json.Unmarshal(body, &data)
+		mu.Lock()
+		created = append(created, data)
+		mu.Unlock()
+		w.Write([]byte(`{"id":"emp1"}`))
+	}), 0)
+	tg := newFakeTelegram(t)
+	t.Cleanup(func() { cancelRegistration(chatID) })
+	reply := func() string { return tg.last(t, "sendMessage").params.Get("text") }
+
+	handleUpdate(commandUpdate(chatID, "/register_employee"))
+	if !strings.Contains(reply(), "(1/5)") {
+		t.Fatalf("start = %q, want the first question", reply())
+	}
+
+	steps := []struct {
+		answer string
+		want   string
+	}{
+		{"not-a-mac", "ไม่ถูกต้อง"},
+		{"aa-bb-cc-dd-ee-01", "(2/5)"},
+		{"Somchai Jaidee", "(3/5)"},
+		{"E 001", "ต้องไม่มีช่องว่าง"},
+		{"E001", "(4/5)"},
+		{"Emergency", "(5/5)"},
+		{"25:00:00", "รูปแบบเวลาไม่ถูกต้อง"},
+		{"08:30", "ตรวจสอบข้อมูล"},
+	}
+	for _, step := range steps {
+		handleUpdate(textUpdate(chatID, step.answer))
+		if !strings.Contains(reply(), step.want) {
+			t.Fatalf("answer %q got %q, want %q", step.answer, reply(), step.want)
+		}
+	}
+
+	confirm := tg.last(t, "sendMessage")
+	if text := confirm.params.Get("text"); !strings.Contains(text, "aa:bb:cc:dd:ee:01") || !strings.Contains(text, "08:30:00") {
+		t.Errorf("confirmation = %q, want the normalized MAC and start time", text)
+	}
+	handleUpdate(textUpdate(chatID, "yes"))
+	if !strings.Contains(reply(), "กดปุ่มยืนยัน") {
+		t.Errorf("typed answer to the confirmation got %q, want a pointer to the buttons", reply())
+	}
+	mu.Lock()
+	if len(created) != 0 {
+		t.Fatalf("registered before confirmation: %v", created)
+	}
+	mu.Unlock()
+
+	handleUpdate(pressUpdate(chatID, confirm.button(t, "✅ ยืนยัน")))
+	if text := tg.last(t, "editMessageText").params.Get("text"); !strings.Contains(text, "ลงทะเบียนแล้ว") {
+		t.Errorf("confirmed = %q", text)
+	}
+	if text := reply(); !strings.Contains(text, "ยินยอมให้ติดตาม") {
+		t.Errorf("after registering got %q, want the consent question", text)
+	}
+	mu.Lock()
+	if len(created) != 1 || created[0]["mac_address"] != "aa:bb:cc:dd:ee:01" || created[0]["name"] != "Somchai Jaidee" ||
+		created[0]["employee_code"] != "E001" || created[0]["department"] != "Emergency" || created[0]["work_start_time"] != "08:30:00" {
+		t.Errorf("created = %v", created)
+	}
+	mu.Unlock()
+
+	// The conversation is over, so a second press and further messages do nothing
+	handleUpdate(pressUpdate(chatID, confirm.button(t, "✅ ยืนยัน")))
+	if text := tg.last(t, "editMessageText").params.Get("text"); !strings.Contains(text, "หมดเวลา") {
+		t.Errorf("second press = %q, want the expired answer", text)
+	}
+	sent := len(tg.calls)
+	handleUpdate(textUpdate(chatID, "hello"))
+	if len(tg.calls) != sent {
+		t.Errorf("a message outside the conversation was answered")
+	}
+	mu.Lock()
+	if len(created) != 1 {
+		t.Errorf("created %d employees, want 1", len(created))
+	}
+	mu.Unlock()
+}
+
+func TestRegistrationEnds(t *testing.T) {
+	const chatID = 1002
+	useSingleSite(t, http.NotFoundHandler(), 0)
+	tg := newFakeTelegram(t)
+	t.Cleanup(func() { cancelRegistration(chatID) })
+	reply := func() string { return tg.last(t, "sendMessage").params.Get("text") }
+	registering := func() bool {
+		userStatesMu.Lock()
+		defer userStatesMu.Unlock()
+		_, ok := userStates[chatID]
+		return ok
+	}
+
+	t.Run("cancel", func(t *testing.T) {
+		handleUpdate(commandUpdate(chatID, "/register_employee"))
+		handleUpdate(commandUpdate(chatID, "/cancel"))
+		if registering() || !strings.Contains(reply(), "ยกเลิกแล้ว") {
+			t.Errorf("registering = %v after /cancel, reply %q", registering(), reply())
+		}
+	})
+
+	t.Run("stale answer", func(t *testing.T) {
+		handleUpdate(commandUpdate(chatID, "/register_employee"))
+		userStatesMu.Lock()
+		userStates[chatID].UpdatedAt = time.Now().Add(-registrationTimeout - time.Second)
+		userStatesMu.Unlock()
+		handleUpdate(textUpdate(chatID, "aa:bb:cc:dd:ee:01"))
+		if registering() || !strings.Contains(reply(), "หมดเวลา") {
+			t.Errorf("registering = %v after the timeout, reply %q", registering(), reply())
+		}
+	})
+
+	t.Run("swept while idle", func(t *testing.T) {
+		handleUpdate(commandUpdate(chatID, "/register_employee"))
+		expireRegistrations(time.Now().Add(registrationTimeout - time.Second))
+		if !registering() {
+			t.Fatal("expired before the timeout")
+		}
+		expireRegistrations(time.Now().Add(registrationTimeout + time.Second))
+		if registering() {
+			t.Error("still registering after the timeout")
+		}
+	})
+}
+
+func TestParseWorkStart(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		ok    bool
+	}{
+		{"08:00:00", "08:00:00", true},
+		{"8:30", "08:30:00", true},
+		{"0830", "08:30:00", true},
+		{"-", "", true},
+		{"24:00:00", "", false},
+		{"08:30:61", "", false},
+		{"morning", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := parseWorkStart(tt.input)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("parseWorkStart(%q) = %q, %v; want %q, %v", tt.input, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+// recordingCache records invalidated MACs
+type recordingCache struct {
+	mu          sync.Mutex
+	invalidated []string
+}
+
+func (c *recordingCache) Invalidate(mac string) {
+	c.mu.Lock()
+	c.invalidated = append(c.invalidated, mac)
+	c.mu.Unlock()
+}
+
+func TestRegistrationInvalidatesEmployeeCache(t *testing.T) {
+	const chatID = 1001
+	useSingleSite(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"emp1"}`))
+	}), 0)
+	newFakeTelegram(t)
+	cache := &recordingCache{}
+	SetEmployeeCache(tenant.DefaultID, cache)
+	t.Cleanup(func() {
+		employeeCachesMu.Lock()
+		delete(employeeCaches, tenant.DefaultID)
+		employeeCachesMu.Unlock()
+	})
+
+	handleUpdate(commandUpdate(chatID, "/register_employee AA-BB-CC-DD-EE-01 Somchai E001 ER"))
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if len(cache.invalidated) != 1 || cache.invalidated[0] != "aa:bb:cc:dd:ee:01" {
+		t.Errorf("invalidated %v, want [aa:bb:cc:dd:ee:01]", cache.invalidated)
+	}
+}
+
+func TestRegistrationInEnglish(t *testing.T) {
+	const chatID = 1001
+	useSingleSite(t, http.NotFoundHandler(), 0)
+	tg := newFakeTelegram(t)
+	answerIn(t, en)
+	t.Cleanup(func() { cancelRegistration(chatID) })
+	reply := func() string { return tg.last(t, "sendMessage").params.Get("text") }
+
+	handleUpdate(commandUpdate(chatID, "/register_employee"))
+	if !strings.Contains(reply(), "*Employee registration*") || !strings.Contains(reply(), "(1/5) Send the MAC address") {
+		t.Fatalf("start = %q, want the first question in English", reply())
+	}
+	handleUpdate(textUpdate(chatID, "not-a-mac"))
+	if !strings.Contains(reply(), "❌ MAC address `not-a-mac` is invalid") {
+		t.Errorf("invalid MAC got %q", reply())
+	}
+	handleUpdate(textUpdate(chatID, "aa-bb-cc-dd-ee-01"))
+	if !strings.Contains(reply(), "(2/5) Send your full name") {
+		t.Errorf("valid MAC got %q", reply())
+	}
+	handleUpdate(commandUpdate(chatID, "/register_employee aa-bb-cc-dd-ee-01"))
+	if !strings.Contains(reply(), "or send /register_employee to register step by step") {
+		t.Errorf("one-line registration without the name got %q", reply())
+	}
+}
@@ -0,0 +1,41 @@
+package bot
+
+import (
+	"sync"
+
+	"med-pulse-bot/internal/repository"
+)
+
+// Repositories is where a tenant keeps the records the employees' own commands read and
+// change: /register_employee, /myinfo, /today, /history, /add_device, /remove_device,
+// /privacy and the settings commands, and the scanners /scanners lists
+type Repositories struct {
+	Employees  repository.EmployeeAccounts
+	Attendance repository.AttendanceHistory
+	Devices    repository.EmployeeDevices
+	Consents   repository.ConsentLog
+	Scanners   repository.ScannerRegistry
+
+	// Backend is the URL of the PocketBase holding them, whose outages the replies warn
+	// about; empty with SQLite
+	Backend string
+}
+
+var (
+	repositoriesMu sync.RWMutex
+	repositories   = make(map[string]Repositories) // tenant ID → repositories
+)
+
+// SetRepositories sets the repositories of the tenant's employee commands
+func SetRepositories(tenantID string, r Repositories) {
+	repositoriesMu.Lock()
+	repositories[tenantID] = r
+	repositoriesMu.Unlock()
+}
+
+// repositoriesFor returns the tenant's repositories
+func repositoriesFor(tenantID string) Repositories {
+	repositoriesMu.RLock()
+	defer repositoriesMu.RUnlock()
+	return repositories[tenantID]
+}
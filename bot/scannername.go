@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/macaddr"
+	"med-pulse-bot/internal/markdown"
+)
+
+// maxScannerNameLength matches the scanners.name field of migration 029
+const maxScannerNameLength = 64
+
+// ScannerRenamer stores the friendly names of one tenant's scanners
+type ScannerRenamer interface {
+	Rename(ctx context.Context, scannerMac, name string) error
+}
+
+var (
+	scannerNamesMu sync.RWMutex
+	scannerNames   = make(map[string]ScannerRenamer) // tenant ID → renamer
+)
+
+// SetScannerNames enables /rename_scanner for the tenant's admin chats
+func SetScannerNames(tenantID string, r ScannerRenamer) {
+	scannerNamesMu.Lock()
+	scannerNames[tenantID] = r
+	scannerNamesMu.Unlock()
+}
+
+// handleRenameScanner names a scanner with `/rename_scanner <MAC> <name>`; notifications
+// and /scanners show the name instead of the MAC
+func handleRenameScanner(s *site, message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	if !isSiteAdmin(message.Chat.ID) {
+		msg.Text = "❌ คำสั่งนี้สำหรับผู้ดูแลระบบเท่านั้น"
+		return
+	}
+	scannerNamesMu.RLock()
+	renamer, ok := scannerNames[s.id]
+	scannerNamesMu.RUnlock()
+	if !ok {
+		msg.Text = "❌ การตั้งชื่อ Scanner ไม่ได้เปิดใช้งาน"
+		return
+	}
+
+	args := strings.Fields(message.CommandArguments())
+	if len(args) < 2 {
+		msg.Text = "Usage: `/rename_scanner <MAC> <ชื่อ>`\nเช่น `/rename_scanner AA:BB:CC:DD:EE:FF ประตูหน้า ชั้น 1`"
+		return
+	}
+	mac, err := macaddr.NormalizeScannerID(args[0], true)
+	if err != nil {
+		msg.Text = fmt.Sprintf("❌ Scanner ไม่ถูกต้อง: %v", err)
+		return
+	}
+	name := strings.Join(args[1:], " ")
+	if utf8.RuneCountInString(name) > maxScannerNameLength {
+		msg.Text = fmt.Sprintf("❌ ชื่อยาวเกิน %d ตัวอักษร", maxScannerNameLength)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(pollContext(), 10*time.Second)
+	defer cancel()
+	if err := renamer.Rename(ctx, mac, name); err != nil {
+		log.Printf("Failed to rename scanner %s: %v", mac, err)
+		msg.Text = fmt.Sprintf("❌ Error: %v", err)
+		return
+	}
+	log.Printf("🏷️ Scanner %s renamed to %q", mac, name)
+	msg.Text = fmt.Sprintf("✅ ตั้งชื่อ Scanner `%s` เป็น %s แล้ว", mac, markdown.Bold(name))
+}
@@ -0,0 +1,127 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/macaddr"
+	"med-pulse-bot/internal/services"
+)
+
+// ScannerProfiler assigns configuration profiles to the scanners of one tenant
+type ScannerProfiler interface {
+	Profiles() []string
+	List(ctx context.Context) ([]services.ScannerConfig, error)
+	SetProfile(ctx context.Context, scannerMac, profile string) (services.ScannerConfig, error)
+}
+
+var (
+	scannerProfilersMu sync.RWMutex
+	scannerProfilers   = make(map[string]ScannerProfiler) // tenant ID → profiler
+)
+
+// SetScannerProfiles enables /scanner_profile for the tenant's admin chats
+func SetScannerProfiles(tenantID string, p ScannerProfiler) {
+	scannerProfilersMu.Lock()
+	scannerProfilers[tenantID] = p
+	scannerProfilersMu.Unlock()
+}
+
+// handleScannerProfile assigns a profile with `/scanner_profile <MAC> <profile>` (or
+// `default` to drop it); without arguments it lists the scanners' effective configs
+func handleScannerProfile(s *site, message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	if !isSiteAdmin(message.Chat.ID) {
+		msg.Text = "❌ คำสั่งนี้สำหรับผู้ดูแลระบบเท่านั้น"
+		return
+	}
+	scannerProfilersMu.RLock()
+	p, ok := scannerProfilers[s.id]
+	scannerProfilersMu.RUnlock()
+	if !ok {
+		msg.Text = "❌ โปรไฟล์ Scanner ไม่ได้เปิดใช้งาน"
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(pollContext(), 10*time.Second)
+	defer cancel()
+
+	args := strings.Fields(message.CommandArguments())
+	if len(args) == 0 {
+		msg.Text = scannerProfilesText(ctx, p)
+		return
+	}
+	if len(args) != 2 {
+		msg.Text = "Usage: `/scanner_profile <MAC> <profile|default>`"
+		return
+	}
+
+	mac, err := macaddr.NormalizeScannerID(args[0], true)
+	if err != nil {
+		msg.Text = fmt.Sprintf("❌ Scanner ไม่ถูกต้อง: %v", err)
+		return
+	}
+	profile := args[1]
+	if profile == "default" {
+		profile = ""
+	}
+	cfg, err := p.SetProfile(ctx, mac, profile)
+	if errors.Is(err, services.ErrUnknownScannerProfile) {
+		msg.Text = fmt.Sprintf("❌ ไม่มีโปรไฟล์ `%s`\nโปรไฟล์ที่มี: %s", args[1], profileNames(p))
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to set profile of scanner %s: %v", mac, err)
+		msg.Text = unavailableMessage
+		return
+	}
+	log.Printf("📡 Scanner %s assigned profile %q", mac, profile)
+	msg.Text = "✅ *ตั้งโปรไฟล์ Scanner แล้ว*\n" + scannerConfigLine(cfg)
+}
+
+// scannerProfilesText lists the defined profiles and every scanner's effective config
+func scannerProfilesText(ctx context.Context, p ScannerProfiler) string {
+	text := "📡 *โปรไฟล์ Scanner:* " + profileNames(p) +
+		"\nใช้ `/scanner_profile <MAC> <profile|default>` เพื่อกำหนด"
+	configs, err := p.List(ctx)
+	if err != nil {
+		log.Printf("Failed to list scanner configs: %v", err)
+		return text + "\n\n" + unavailableMessage
+	}
+	var lines []string
+	for _, cfg := range configs {
+		lines = append(lines, scannerConfigLine(cfg))
+	}
+	if len(lines) > 0 {
+		text += "\n\n" + strings.Join(lines, "\n")
+	}
+	return text
+}
+
+// profileNames lists the profile names for a message, or says there are none
+func profileNames(p ScannerProfiler) string {
+	names := p.Profiles()
+	if len(names) == 0 {
+		return "(ไม่มี)"
+	}
+	return "`" + strings.Join(names, "`, `") + "`"
+}
+
+// scannerConfigLine describes one scanner's effective configuration
+func scannerConfigLine(cfg services.ScannerConfig) string {
+	profile := cfg.Profile
+	if profile == "" {
+		profile = "default"
+	}
+	line := fmt.Sprintf("• `%s` %s: RSSI ≥ %d", cfg.ScannerMac, profile, cfg.RSSIThreshold)
+	if cfg.Zone != "" {
+		line += ", โซน " + cfg.Zone
+	}
+	return line
+}
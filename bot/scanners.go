@@ -0,0 +1,131 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/macaddr"
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/models"
+)
+
+// scannerOfflineAfter is how long a scanner may stay silent before /scanners shows it offline
+var scannerOfflineAfter = 10 * time.Minute
+
+// SetScannerOfflineAfter sets the silence after which /scanners shows a scanner offline,
+// e.g. the configured SCANNER_OFFLINE_AFTER
+func SetScannerOfflineAfter(d time.Duration) {
+	if d > 0 {
+		scannerOfflineAfter = d
+	}
+}
+
+// handleScanners lists scanners, falling back to the last known list when they cannot be read
+func handleScanners(s *site, msg *tgbotapi.MessageConfig) {
+	scanners, err := getActiveScanners(s)
+	stale := ""
+	if err != nil {
+		cached, ok := cachedScannersFor(s)
+		if !ok {
+			msg.Text = fmt.Sprintf("Error: %v", err)
+			return
+		}
+		scanners, stale = cached.lines, "\n\n"+staleNote(cached.fetchedAt)
+	}
+	if len(scanners) == 0 {
+		msg.Text = "No scanners found" + stale
+		return
+	}
+	msg.Text = "📡 *Scanners:*\n" + strings.Join(scanners, "\n") + stale
+}
+
+func getActiveScanners(s *site) ([]string, error) {
+	records, err := s.scanners.List(pollContext())
+	if err != nil {
+		return nil, err
+	}
+	scanners := scannerLines(records, time.Now(), scannerOfflineAfter)
+	cacheScanners(s, scanners)
+	return scanners, nil
+}
+
+// scannerLines renders one line per scanner, offline ones first, each group most recently
+// seen first. A scanner is offline once it has been silent for longer than offlineAfter,
+// or if it was never seen.
+func scannerLines(records []models.Scanner, now time.Time, offlineAfter time.Duration) []string {
+	// Records left under another spelling of a seen ID are the same scanner; show it once
+	var scanners []models.Scanner
+	listed := make(map[string]bool)
+	for _, item := range records {
+		mac, err := macaddr.NormalizeScannerID(item.ScannerMac, true)
+		if err != nil {
+			mac = item.ScannerMac
+		}
+		if listed[mac] {
+			continue
+		}
+		listed[mac] = true
+		item.ScannerMac = mac
+		scanners = append(scanners, item)
+	}
+	offline := func(sc models.Scanner) bool {
+		return sc.LastSeen.IsZero() || now.Sub(sc.LastSeen) > offlineAfter
+	}
+	sort.SliceStable(scanners, func(i, j int) bool {
+		if offline(scanners[i]) != offline(scanners[j]) {
+			return offline(scanners[i])
+		}
+		return scanners[i].LastSeen.After(scanners[j].LastSeen)
+	})
+
+	lines := make([]string, 0, len(scanners))
+	for _, sc := range scanners {
+		line := "🟢 "
+		if offline(sc) {
+			line = "🔴 "
+		}
+		if sc.Name != "" || sc.Location != "" {
+			line += markdown.Bold(sc.Label()) + " "
+		}
+		line += fmt.Sprintf("`%s` %s", sc.ScannerMac, seenAgo(sc.LastSeen, now))
+		if sc.Health.FirmwareVersion != "" {
+			line += fmt.Sprintf(" fw `%s`", sc.Health.FirmwareVersion)
+		}
+		if sc.Health.UptimeSeconds > 0 {
+			line += " up " + formatUptime(sc.Health.UptimeSeconds)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// seenAgo describes how long ago a scanner was last seen, e.g. "seen 5m ago"
+func seenAgo(lastSeen, now time.Time) string {
+	if lastSeen.IsZero() {
+		return "never seen"
+	}
+	age := now.Sub(lastSeen)
+	if age < time.Minute {
+		// Also a last_seen slightly ahead of the bot's clock
+		return "seen just now"
+	}
+	return "seen " + formatUptime(int64(age/time.Second)) + " ago"
+}
+
+// formatUptime renders a scanner's reported uptime in its two largest units, e.g. "3d 4h"
+func formatUptime(seconds int64) string {
+	d := time.Duration(seconds) * time.Second
+	days, hours, minutes := int(d.Hours())/24, int(d.Hours())%24, int(d.Minutes())%60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
@@ -0,0 +1,106 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+	"med-pulse-bot/internal/tenant"
+)
+
+func TestScannersShowHealth(t *testing.T) {
+	// last_seen in PocketBase's format, minutes before now
+	ago := func(minutes int) string {
+		return time.Now().UTC().Add(-time.Duration(minutes) * time.Minute).Format("2006-01-02 15:04:05.000Z")
+	}
+	useSingleSite(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/collections/scanners/records" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"items":[
+			{"scanner_mac":"aa:bb:cc:00:00:01","last_seen":%q,"firmware_version":"1.4.2","uptime_seconds":273600},
+			{"scanner_mac":"AA-BB-CC-00-00-01","last_seen":%q},
+			{"scanner_mac":"aa:bb:cc:00:00:02","last_seen":%q,"uptime_seconds":2700},
+			{"scanner_mac":"aa:bb:cc:00:00:03","last_seen":%q},
+			{"scanner_mac":"aa:bb:cc:00:00:04","name":"ประตู_หน้า","location":"ชั้น 1","last_seen":%q},
+			{"scanner_mac":"aa:bb:cc:00:00:05","last_seen":""}]}`, ago(0), ago(1440), ago(3), ago(125), ago(1500))
+	}), 0)
+	tg := newFakeTelegram(t)
+
+	handleUpdate(commandUpdate(1001, "/scanners"))
+	got := tg.last(t, "sendMessage").params.Get("text")
+	want := "📡 *Scanners:*\n" +
+		"🔴 `aa:bb:cc:00:00:03` seen 2h 5m ago\n" +
+		"🔴 *ประตู_หน้า (ชั้น 1)* `aa:bb:cc:00:00:04` seen 1d 1h ago\n" +
+		"🔴 `aa:bb:cc:00:00:05` never seen\n" +
+		"🟢 `aa:bb:cc:00:00:01` seen just now fw `1.4.2` up 3d 4h\n" +
+		"🟢 `aa:bb:cc:00:00:02` seen 3m ago up 45m"
+	if got != want {
+		t.Errorf("/scanners = %q, want %q", got, want)
+	}
+}
+
+func TestScannerLinesThreshold(t *testing.T) {
+	now := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		silent time.Duration
+		want   string
+	}{
+		{"just seen", 0, "🟢 `aa:bb:cc:00:00:01` seen just now"},
+		{"clock skew", -30 * time.Second, "🟢 `aa:bb:cc:00:00:01` seen just now"},
+		{"at the threshold", 10 * time.Minute, "🟢 `aa:bb:cc:00:00:01` seen 10m ago"},
+		{"past the threshold", 10*time.Minute + time.Second, "🔴 `aa:bb:cc:00:00:01` seen 10m ago"},
+		{"hours", 2*time.Hour + 59*time.Second, "🔴 `aa:bb:cc:00:00:01` seen 2h 0m ago"},
+		{"days", 50 * time.Hour, "🔴 `aa:bb:cc:00:00:01` seen 2d 2h ago"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := []models.Scanner{{ScannerMac: "AA:BB:CC:00:00:01", LastSeen: now.Add(-tt.silent)}}
+			if got := scannerLines(records, now, 10*time.Minute); len(got) != 1 || got[0] != tt.want {
+				t.Errorf("scannerLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenameScanner(t *testing.T) {
+	const adminChatID = 42
+	useSingleSite(t, http.NotFoundHandler(), adminChatID)
+	tg := newFakeTelegram(t)
+	store := memory.NewStore(clock.Real{})
+	store.AddScanner(models.Scanner{ScannerMac: "aa:bb:cc:00:00:01"})
+	SetScannerNames(tenant.DefaultID, store.ScannerRecords())
+	t.Cleanup(func() { SetScannerNames(tenant.DefaultID, nil) })
+
+	tests := []struct {
+		name    string
+		chatID  int64
+		command string
+		want    string
+	}{
+		{"employee chat", 1001, "/rename_scanner aa:bb:cc:00:00:01 Lobby", "ผู้ดูแลระบบเท่านั้น"},
+		{"no name", adminChatID, "/rename_scanner aa:bb:cc:00:00:01", "Usage"},
+		{"too long", adminChatID, "/rename_scanner aa:bb:cc:00:00:01 " + strings.Repeat("ก", 65), "ยาวเกิน 64"},
+		{"rename", adminChatID, "/rename_scanner AA-BB-CC-00-00-01 ประตู_หน้า ชั้น 1", "✅ ตั้งชื่อ Scanner `aa:bb:cc:00:00:01` เป็น *ประตู_หน้า ชั้น 1* แล้ว"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handleUpdate(commandUpdate(tt.chatID, tt.command))
+			if got := tg.last(t, "sendMessage").params.Get("text"); !strings.Contains(got, tt.want) {
+				t.Errorf("%s = %q, want it to contain %q", tt.command, got, tt.want)
+			}
+		})
+	}
+	sc, err := store.ScannerRecords().GetByMac(context.Background(), "aa:bb:cc:00:00:01")
+	if err != nil || sc.Name != "ประตู_หน้า ชั้น 1" {
+		t.Errorf("scanner = %+v, %v, want it named", sc, err)
+	}
+}
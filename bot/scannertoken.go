@@ -0,0 +1,88 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/macaddr"
+)
+
+// ScannerTokenIssuer stores the device tokens the scanners of one tenant authenticate with
+type ScannerTokenIssuer interface {
+	SetToken(ctx context.Context, scannerMac, token string) error
+}
+
+var (
+	scannerTokensMu sync.RWMutex
+	scannerTokens   = make(map[string]ScannerTokenIssuer) // tenant ID → issuer
+)
+
+// SetScannerTokens enables /scanner_token for the tenant's admin chats
+func SetScannerTokens(tenantID string, i ScannerTokenIssuer) {
+	scannerTokensMu.Lock()
+	scannerTokens[tenantID] = i
+	scannerTokensMu.Unlock()
+}
+
+// handleScannerToken issues a new device token with `/scanner_token <MAC>`, replacing the
+// scanner's old one, or revokes it with `/scanner_token <MAC> revoke`
+func handleScannerToken(s *site, message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	if !isSiteAdmin(message.Chat.ID) {
+		msg.Text = "❌ คำสั่งนี้สำหรับผู้ดูแลระบบเท่านั้น"
+		return
+	}
+	scannerTokensMu.RLock()
+	issuer, ok := scannerTokens[s.id]
+	scannerTokensMu.RUnlock()
+	if !ok {
+		msg.Text = "❌ Token ของ Scanner ไม่ได้เปิดใช้งาน"
+		return
+	}
+
+	args := strings.Fields(message.CommandArguments())
+	if len(args) == 0 || len(args) > 2 || (len(args) == 2 && args[1] != "revoke") {
+		msg.Text = "Usage: `/scanner_token <MAC>` ออก token ใหม่\n`/scanner_token <MAC> revoke` ยกเลิก token"
+		return
+	}
+	mac, err := macaddr.NormalizeScannerID(args[0], true)
+	if err != nil {
+		msg.Text = fmt.Sprintf("❌ Scanner ไม่ถูกต้อง: %v", err)
+		return
+	}
+
+	token := ""
+	if len(args) == 1 {
+		buf := make([]byte, 24)
+		if _, err := rand.Read(buf); err != nil {
+			log.Printf("Failed to generate a scanner token: %v", err)
+			msg.Text = unavailableMessage
+			return
+		}
+		token = base64.RawURLEncoding.EncodeToString(buf)
+	}
+
+	ctx, cancel := context.WithTimeout(pollContext(), 10*time.Second)
+	defer cancel()
+	if err := issuer.SetToken(ctx, mac, token); err != nil {
+		log.Printf("Failed to set the token of scanner %s: %v", mac, err)
+		msg.Text = fmt.Sprintf("❌ Error: %v", err)
+		return
+	}
+	if token == "" {
+		log.Printf("🔑 Scanner %s token revoked", mac)
+		msg.Text = fmt.Sprintf("🔑 ยกเลิก token ของ Scanner `%s` แล้ว\nมีผลภายใน 5 นาที", mac)
+		return
+	}
+	log.Printf("🔑 Scanner %s issued a new token", mac)
+	msg.Text = fmt.Sprintf("🔑 *Token ใหม่ของ Scanner* `%s`\n`%s`\n\n"+
+		"ตั้งใน firmware เป็น `Authorization: Bearer <token>`\n"+
+		"token เดิมใช้ไม่ได้ภายใน 5 นาที และจะแสดงครั้งนี้ครั้งเดียว", mac, token)
+}
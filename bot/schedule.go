@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/models"
+)
+
+// scheduleFlow is the time picker flow of /set_schedule; its arg is the field to set
+const scheduleFlow = "sched"
+
+// scheduleFields maps the /set_schedule argument to the employee field, the message ID
+// of its label and how it is set
+var scheduleFields = map[string]struct {
+	field, label string
+	set          func(e *models.Employee, value string)
+}{
+	"start": {"work_start_time", "schedule.start", func(e *models.Employee, v string) { e.WorkStartTime = v }},
+	"end":   {"work_end_time", "schedule.end", func(e *models.Employee, v string) { e.WorkEndTime = v }},
+}
+
+// handleSetSchedule lets an employee pick their own work start (or, with "end", end) time
+func handleSetSchedule(s *site, message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	lang := chatLanguage(s, message.Chat.ID)
+	which := strings.ToLower(strings.TrimSpace(message.CommandArguments()))
+	if which == "" {
+		which = "start"
+	}
+	if _, ok := scheduleFields[which]; !ok {
+		msg.Text = tr(lang, "schedule.usage")
+		return
+	}
+
+	if _, err := getEmployeeByChat(s, message.Chat.ID); errors.Is(err, errNotRegistered) {
+		msg.Text = tr(lang, "not_registered")
+		return
+	} else if err != nil {
+		msg.Text = tr(lang, "unavailable")
+		return
+	}
+	startTimePicker(msg, scheduleFlow, which, lang)
+}
+
+func scheduleTitle(lang, which string) string {
+	return tr(lang, "schedule.title", tr(lang, scheduleFields[which].label))
+}
+
+// scheduleDone saves the chosen time on the chat's employee record
+func scheduleDone(ctx context.Context, s *site, chatID int64, which string, hour, minute int) string {
+	lang := chatLanguage(s, chatID)
+	f, ok := scheduleFields[which]
+	if !ok {
+		return tr(lang, "schedule.failed")
+	}
+	emp, err := getEmployeeByChat(s, chatID)
+	if errors.Is(err, errNotRegistered) {
+		return tr(lang, "not_registered")
+	}
+	if err != nil {
+		return tr(lang, "unavailable")
+	}
+
+	value := fmt.Sprintf("%02d:%02d:00", hour, minute)
+	f.set(emp, value)
+	if err := updateSettings(s, emp); err != nil {
+		log.Printf("❌ Failed to set %s of %s: %v", f.field, emp.Name, err)
+		return tr(lang, "unavailable")
+	}
+	log.Printf("🗓️ %s set %s to %s", emp.Name, f.field, value)
+	return tr(lang, "schedule.saved", tr(lang, f.label), hour, minute)
+}
@@ -0,0 +1,209 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/markdown"
+)
+
+// telegramMessageLimit is the most UTF-16 code units Telegram accepts in one message
+const telegramMessageLimit = 4096
+
+// maxMessageParts is the most parts a long message is sent in; longer text is sent as a file
+const maxMessageParts = 5
+
+// partHeaderReserve leaves room for the "📄 2/3" header of each part
+const partHeaderReserve = 16
+
+// send delivers a message, splitting text over Telegram's limit into numbered parts or,
+// beyond maxMessageParts, a text file. Reply buttons go with the last part.
+func send(msg tgbotapi.MessageConfig) error {
+	if textLength(msg.Text) <= telegramMessageLimit {
+		return deliver(msg)
+	}
+
+	parts := splitMessage(msg.Text, telegramMessageLimit-partHeaderReserve)
+	if len(parts) > maxMessageParts {
+		doc := tgbotapi.NewDocument(msg.ChatID, tgbotapi.FileBytes{Name: "message.txt", Bytes: []byte(msg.Text)})
+		doc.Caption = fmt.Sprintf("📄 ข้อความยาวเกินไป (%d ส่วน) จึงส่งเป็นไฟล์แทน", len(parts))
+		doc.ReplyMarkup = msg.ReplyMarkup
+		_, err := bot.Send(doc)
+		return err
+	}
+
+	markup := msg.ReplyMarkup
+	for i, part := range parts {
+		msg.Text = fmt.Sprintf("📄 %d/%d\n%s", i+1, len(parts), part)
+		msg.ReplyMarkup = nil
+		if i == len(parts)-1 {
+			msg.ReplyMarkup = markup
+		}
+		if err := deliver(msg); err != nil {
+			return fmt.Errorf("part %d/%d: %w", i+1, len(parts), err)
+		}
+	}
+	return nil
+}
+
+// splitMessage splits Markdown text into parts of at most limit UTF-16 code units. It
+// cuts at line ends where it can and never inside a bold, italic, link or inline code
+// span; a code block cut in two is closed and reopened so both parts render.
+func splitMessage(text string, limit int) []string {
+	safe, fences := markdownCuts(text)
+
+	var parts []string
+	reopen := ""
+	for start := 0; start < len(text); {
+		if textLength(reopen+text[start:]) <= limit {
+			parts = append(parts, reopen+text[start:])
+			break
+		}
+
+		// Room for the reopened fence and, in case the cut lands inside a block, its closing
+		budget := limit - textLength(reopen) - textLength("\n```")
+		end := start
+		for n := 0; end < len(text); {
+			r, size := utf8.DecodeRuneInString(text[end:])
+			n += utf16.RuneLen(r)
+			if n > budget {
+				break
+			}
+			end += size
+		}
+		if end == start {
+			_, size := utf8.DecodeRuneInString(text[start:])
+			end += size
+		}
+		cut := bestCut(text, safe, start, end)
+
+		part := reopen + text[start:cut]
+		reopen = ""
+		if fences[cut] != "" {
+			part = strings.TrimRight(part, "\n") + "\n```"
+			reopen = fences[cut] + "\n"
+		}
+		parts = append(parts, part)
+		start = cut
+	}
+	return parts
+}
+
+// bestCut picks where a part starting at start and fitting up to end is cut: the last
+// safe line end, else the last safe space, else any line end, else the last safe
+// character boundary, else end itself
+func bestCut(text string, safe []bool, start, end int) int {
+	if end >= len(text) {
+		return len(text)
+	}
+	candidates := []func(i int) bool{
+		func(i int) bool { return safe[i] && text[i-1] == '\n' },
+		func(i int) bool { return safe[i] && text[i-1] == ' ' },
+		func(i int) bool { return text[i-1] == '\n' },
+		func(i int) bool { return safe[i] && utf8.RuneStart(text[i]) },
+	}
+	for _, ok := range candidates {
+		for i := end; i > start; i-- {
+			if ok(i) {
+				return i
+			}
+		}
+	}
+	for end > start+1 && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	return end
+}
+
+// markdownCuts reports, for every byte offset of text, whether a cut there keeps
+// Telegram Markdown entities whole and, if the offset is inside a code block, the
+// block's opening fence line
+func markdownCuts(text string) (safe []bool, fences []string) {
+	safe = make([]bool, len(text)+1)
+	fences = make([]string, len(text)+1)
+
+	var open byte // the marker of the entity being read, 0 outside entities
+	fence := ""
+	lineStart := true
+	for i := 0; i < len(text); i++ {
+		safe[i] = open == 0
+		fences[i] = fence
+		c := text[i]
+
+		if lineStart && open == 0 && strings.HasPrefix(text[i:], "```") {
+			end := strings.IndexByte(text[i:], '\n')
+			if end < 0 {
+				end = len(text) - i
+			}
+			if fence == "" {
+				fence = text[i : i+end]
+			} else {
+				fence = ""
+			}
+			// Never cut within the fence line itself
+			for j := i + 1; j <= i+end && j < len(text); j++ {
+				safe[j] = false
+			}
+			i += end
+			if i < len(text) {
+				lineStart = true
+			}
+			continue
+		}
+		lineStart = c == '\n'
+		if fence != "" {
+			continue
+		}
+
+		switch {
+		case open == 0 && c == '\\' && i+1 < len(text):
+			i++
+			safe[i] = false
+			fences[i] = fence
+		case open == 0 && (c == '*' || c == '_' || c == '`' || c == '['):
+			open = c
+		case open == '[' && c == ']':
+			open = 0
+			if i+1 < len(text) && text[i+1] == '(' {
+				open = '('
+			}
+		case open == '(' && c == ')':
+			open = 0
+		case open == c:
+			open = 0
+		}
+	}
+	safe[len(text)] = open == 0
+	fences[len(text)] = fence
+	return safe, fences
+}
+
+// textLength counts UTF-16 code units, the way Telegram measures message length
+func textLength(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// deliver sends one message. When Telegram rejects it, the text is logged with the first
+// entity that does not parse, which is usually an unescaped name.
+func deliver(msg tgbotapi.MessageConfig) error {
+	_, err := bot.Send(msg)
+	if err != nil && msg.ParseMode != "" {
+		reason := "all entities close"
+		if perr := markdown.Check(msg.Text); perr != nil {
+			reason = perr.Error()
+		}
+		log.Printf("❌ Telegram rejected a message (%s)", reason)
+		slog.Debug("Rejected message", "chat_id", msg.ChatID, "text", msg.Text)
+	}
+	return err
+}
@@ -0,0 +1,174 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// thaiLines is n lines of Thai attendance report text
+func thaiLines(n int) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "%d. นายสมชาย ใจดี เข้างาน `08:%02d:00` สถานะ *ตรงเวลา*\n", i, i%60)
+	}
+	return b.String()
+}
+
+func TestSplitMessage(t *testing.T) {
+	codeBlock := "สรุปรายงาน\n```text\n" + strings.Repeat("บรรทัดในโค้ด 0123456789\n", 30) + "```\nจบรายงาน"
+
+	tests := []struct {
+		name      string
+		text      string
+		limit     int
+		wantParts int
+		check     func(t *testing.T, parts []string)
+	}{
+		{
+			name:      "fits",
+			text:      "✅ *เข้างานตรงเวลา*",
+			limit:     100,
+			wantParts: 1,
+		},
+		{
+			name:      "thai lines cut at line ends",
+			text:      thaiLines(40),
+			limit:     1000,
+			wantParts: 3,
+			check: func(t *testing.T, parts []string) {
+				for i, p := range parts[:len(parts)-1] {
+					if !strings.HasSuffix(p, "\n") {
+						t.Errorf("part %d does not end at a line end: %q", i, p[len(p)-20:])
+					}
+				}
+				if strings.Join(parts, "") != thaiLines(40) {
+					t.Error("parts do not add up to the text")
+				}
+			},
+		},
+		{
+			name:      "code block closed and reopened",
+			text:      codeBlock,
+			limit:     300,
+			wantParts: 3,
+			check: func(t *testing.T, parts []string) {
+				for i, p := range parts {
+					if n := strings.Count(p, "```"); n%2 != 0 {
+						t.Errorf("part %d has %d fences: %q", i, n, p)
+					}
+				}
+				if !strings.HasPrefix(parts[1], "```text\n") {
+					t.Errorf("part 1 does not reopen the block: %q", parts[1][:20])
+				}
+			},
+		},
+		{
+			name:      "bold run across lines stays whole",
+			text:      strings.Repeat("ก", 50) + "\n*ตัวหนา\nหลายบรรทัด*\n" + strings.Repeat("ข", 50),
+			limit:     70,
+			wantParts: 2,
+			check: func(t *testing.T, parts []string) {
+				for i, p := range parts {
+					if strings.Count(p, "*")%2 != 0 {
+						t.Errorf("part %d splits the bold run: %q", i, p)
+					}
+				}
+			},
+		},
+		{
+			name:      "long line without spaces",
+			text:      strings.Repeat("ทดสอบ", 100),
+			limit:     120,
+			wantParts: 5,
+			check: func(t *testing.T, parts []string) {
+				if strings.Join(parts, "") != strings.Repeat("ทดสอบ", 100) {
+					t.Error("parts do not add up to the text")
+				}
+			},
+		},
+		{
+			name:      "inline code kept whole at spaces",
+			text:      strings.Repeat("คำ ", 20) + "`aa:bb:cc:dd:ee:01 aa:bb:cc:dd:ee:02`" + strings.Repeat(" คำ", 20),
+			limit:     80,
+			wantParts: 3,
+			check: func(t *testing.T, parts []string) {
+				for i, p := range parts {
+					if strings.Count(p, "`")%2 != 0 {
+						t.Errorf("part %d splits the code span: %q", i, p)
+					}
+				}
+			},
+		},
+		{
+			name:      "emoji count twice",
+			text:      strings.Repeat("😀", 60),
+			limit:     50,
+			wantParts: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := splitMessage(tt.text, tt.limit)
+			if len(parts) != tt.wantParts {
+				t.Fatalf("parts = %d, want %d: %q", len(parts), tt.wantParts, parts)
+			}
+			for i, p := range parts {
+				if n := textLength(p); n > tt.limit {
+					t.Errorf("part %d is %d units, over %d", i, n, tt.limit)
+				}
+				if !utf8.ValidString(p) {
+					t.Errorf("part %d is not valid UTF-8", i)
+				}
+			}
+			if tt.check != nil {
+				tt.check(t, parts)
+			}
+		})
+	}
+}
+
+func TestSendSplitsLongMessages(t *testing.T) {
+	tests := []struct {
+		name      string
+		lines     int
+		wantTexts int
+		wantFile  bool
+	}{
+		{"short", 10, 1, false},
+		{"numbered parts", 100, 2, false},
+		{"file beyond five parts", 400, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tg := newFakeTelegram(t)
+			msg := tgbotapi.NewMessage(7, thaiLines(tt.lines))
+			msg.ParseMode = "Markdown"
+			if err := send(msg); err != nil {
+				t.Fatal(err)
+			}
+
+			var texts []string
+			files := 0
+			for _, c := range tg.calls {
+				switch c.method {
+				case "sendMessage":
+					texts = append(texts, c.params.Get("text"))
+				case "sendDocument":
+					files++
+				}
+			}
+			if len(texts) != tt.wantTexts || (files == 1) != tt.wantFile {
+				t.Fatalf("sent %d messages and %d files, want %d and file %v", len(texts), files, tt.wantTexts, tt.wantFile)
+			}
+			if len(texts) > 1 && !strings.HasPrefix(texts[1], fmt.Sprintf("📄 2/%d\n", len(texts))) {
+				t.Errorf("second part starts %q, want its number", texts[1][:20])
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package bot
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/metrics"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/status"
+)
+
+// degradedBanner is prepended to data command responses while PocketBase is down
+const degradedBanner = "⚠️ ระบบฐานข้อมูลขัดข้อง ข้อมูลอาจไม่เป็นปัจจุบัน"
+
+// readOnlyBanner is prepended to data command responses during maintenance
+const readOnlyBanner = "🛠️ ระบบอยู่ระหว่างปรับปรุง ดูข้อมูลได้อย่างเดียว"
+
+// maintenanceMessage answers commands that would write during maintenance
+const maintenanceMessage = "🛠️ ระบบอยู่ระหว่างปรับปรุง ยังไม่สามารถบันทึกข้อมูลได้ กรุณาลองใหม่ภายหลัง"
+
+var (
+	systemStatus *status.SystemStatus // nil disables the banner
+
+	cacheMu       sync.Mutex
+	employeeCache = make(map[string]cachedEmployee) // keyed by cacheKey
+	scannerCache  = make(map[string]cachedScanners) // keyed by site ID
+)
+
+type cachedEmployee struct {
+	employee  models.Employee
+	fetchedAt time.Time
+}
+
+type cachedScanners struct {
+	lines     []string
+	fetchedAt time.Time
+}
+
+// SetSystemStatus enables the degraded-mode and read-only banners, cache fallbacks and /readonly
+func SetSystemStatus(s *status.SystemStatus) {
+	systemStatus = s
+}
+
+// notificationFailed counts a notification Telegram refused, for the notification_failures alert
+func notificationFailed(err error) {
+	metrics.NotificationFailures.Inc()
+	if systemStatus != nil {
+		systemStatus.RecordNotificationFailure(err)
+	}
+}
+
+// degraded reports whether the site's PocketBase is currently marked down
+func degraded(s *site) bool {
+	return systemStatus != nil && s != nil && !systemStatus.Backend(s.url).Healthy
+}
+
+// readOnly reports whether writes are suspended for maintenance
+func readOnly() bool {
+	return systemStatus != nil && systemStatus.ReadOnly()
+}
+
+// withBanner prefixes text with the read-only and degraded-mode banners that apply
+func withBanner(s *site, text string) string {
+	if degraded(s) {
+		text = degradedBanner + "\n\n" + text
+	}
+	if readOnly() {
+		text = readOnlyBanner + "\n\n" + text
+	}
+	return text
+}
+
+// staleNote marks data served from cache
+func staleNote(fetchedAt time.Time) string {
+	return fmt.Sprintf("🕒 _ข้อมูลล่าสุดเมื่อ %s_", fetchedAt.Format("02/01 15:04"))
+}
+
+func cacheKey(s *site, chatID int64) string {
+	return fmt.Sprintf("%s/%d", s.id, chatID)
+}
+
+func cacheEmployee(s *site, chatID int64, emp *models.Employee) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	employeeCache[cacheKey(s, chatID)] = cachedEmployee{employee: *emp, fetchedAt: time.Now()}
+}
+
+func cachedEmployeeFor(s *site, chatID int64) (cachedEmployee, bool) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	c, ok := employeeCache[cacheKey(s, chatID)]
+	return c, ok
+}
+
+func cacheScanners(s *site, lines []string) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	scannerCache[s.id] = cachedScanners{lines: lines, fetchedAt: time.Now()}
+}
+
+func cachedScannersFor(s *site) (cachedScanners, bool) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	c, ok := scannerCache[s.id]
+	return c, ok
+}
@@ -0,0 +1,125 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"med-pulse-bot/internal/repository"
+	"med-pulse-bot/internal/tenant"
+)
+
+// site is the tenant a chat's commands run against, with its repositories
+type site struct {
+	id  string
+	url string // the PocketBase holding its records; empty with SQLite
+
+	employees  repository.EmployeeAccounts
+	attendance repository.AttendanceHistory
+	devices    repository.EmployeeDevices
+	consents   repository.ConsentLog
+	scanners   repository.ScannerRegistry
+}
+
+var (
+	tenants      *tenant.Registry // nil in single-site mode
+	bindingsMu   sync.Mutex
+	bindings     = make(map[int64]string) // employee chat → tenant ID
+	bindingsPath string
+)
+
+// SetTenants switches the bot to multi-tenant mode. Chat bindings made with /site
+// are kept in bindingsFile.
+func SetTenants(registry *tenant.Registry, bindingsFile string) error {
+	tenants = registry
+	bindingsPath = bindingsFile
+
+	data, err := os.ReadFile(bindingsFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	bindingsMu.Lock()
+	defer bindingsMu.Unlock()
+	if err := json.Unmarshal(data, &bindings); err != nil {
+		return fmt.Errorf("failed to parse chat bindings %s: %w", bindingsFile, err)
+	}
+	for chatID, id := range bindings {
+		if _, ok := registry.Get(id); !ok {
+			log.Printf("⚠️ Dropping a chat binding to removed tenant %q", id)
+			delete(bindings, chatID)
+		}
+	}
+	return nil
+}
+
+// siteFor resolves the tenant of a chat: its admin chat, then its /site binding.
+// In single-site mode every chat uses the default tenant's repositories.
+func siteFor(chatID int64) (*site, error) {
+	if tenants == nil {
+		return checkRepositories(defaultSite())
+	}
+
+	t, ok := tenants.ByAdminChat(chatID)
+	if !ok {
+		bindingsMu.Lock()
+		id := bindings[chatID]
+		bindingsMu.Unlock()
+		t, ok = tenants.Get(id)
+	}
+	if !ok {
+		return nil, fmt.Errorf("ยังไม่ได้เลือกสาขา ใช้ /site <รหัสเข้าร่วม>")
+	}
+	return checkRepositories(withRepositories(&site{id: t.ID}))
+}
+
+// withRepositories gives the site the repositories SetRepositories set for its tenant
+func withRepositories(s *site) *site {
+	r := repositoriesFor(s.id)
+	s.url = r.Backend
+	s.employees, s.attendance, s.devices, s.consents, s.scanners = r.Employees, r.Attendance, r.Devices, r.Consents, r.Scanners
+	return s
+}
+
+// checkRepositories fails for a site whose tenant has no repositories yet
+func checkRepositories(s *site) (*site, error) {
+	if s.employees == nil || s.attendance == nil || s.devices == nil || s.consents == nil || s.scanners == nil {
+		return nil, fmt.Errorf("repositories of tenant %q not set", s.id)
+	}
+	return s, nil
+}
+
+// defaultSite is the only tenant of single-site mode
+func defaultSite() *site {
+	return withRepositories(&site{id: tenant.DefaultID})
+}
+
+// bindChat binds an employee chat to the tenant owning a join code
+func bindChat(chatID int64, code string) (*tenant.Tenant, error) {
+	t, ok := tenants.ByJoinCode(code)
+	if !ok {
+		return nil, fmt.Errorf("รหัสเข้าร่วมไม่ถูกต้อง")
+	}
+
+	bindingsMu.Lock()
+	defer bindingsMu.Unlock()
+	bindings[chatID] = t.ID
+	data, err := json.MarshalIndent(bindings, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(bindingsPath), 0o755); err != nil {
+		return nil, err
+	}
+	tmp := bindingsPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return nil, err
+	}
+	return t, os.Rename(tmp, bindingsPath)
+}
@@ -0,0 +1,227 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// timePickerPrefix routes the buttons of every time picker; the data is
+// "tp:<flow>:<step>:<arg>" with step h (hours), mHH (minutes of hour HH), tHHMM (chosen),
+// kb (type it instead) or x (cancel)
+const timePickerPrefix = "tp"
+
+// maxTimeFlowArg keeps the longest picker callback data within Telegram's 64 bytes
+const maxTimeFlowArg = 40
+
+// timeFlow is a dialog step that asks for a time of day with the picker
+type timeFlow struct {
+	// title heads the picker message in the language given
+	title func(lang, arg string) string
+	// done handles the chosen time and returns the text that replaces the picker
+	done func(ctx context.Context, s *site, chatID int64, arg string, hour, minute int) string
+}
+
+// timeFlows are the dialogs using the picker, by the flow name in callback data
+var timeFlows = map[string]timeFlow{
+	scheduleFlow:      {title: scheduleTitle, done: scheduleDone},
+	manualCheckInFlow: {title: manualCheckInTitle, done: manualCheckInDone},
+}
+
+// typedTime is a picker whose user chose to type the time instead
+type typedTime struct {
+	flow string
+	arg  string
+}
+
+var (
+	typingMu sync.Mutex
+	typing   = make(map[int64]typedTime) // chat → picker waiting for a typed time
+)
+
+// startTimePicker turns msg into a picker for the flow showing the hour grid in lang
+func startTimePicker(msg *tgbotapi.MessageConfig, flow, arg, lang string) {
+	if len(arg) > maxTimeFlowArg {
+		msg.Text = tr(lang, "timepicker.too_long")
+		return
+	}
+	f := timeFlows[flow]
+	msg.Text = f.title(lang, arg) + "\n\n" + tr(lang, "timepicker.hour")
+	msg.ReplyMarkup = hourGrid(flow, arg, lang)
+}
+
+// handleTimePicker moves a picker to its next step. Intermediate steps edit the buttons
+// in place and return ""; the chosen time returns the flow's result.
+func handleTimePicker(ctx context.Context, s *site, chatID int64, messageID int, data string) (string, error) {
+	parts := strings.SplitN(data, ":", 4)
+	if len(parts) != 4 {
+		return "", fmt.Errorf("malformed time picker data %q", data)
+	}
+	name, step, arg := parts[1], parts[2], parts[3]
+	f, ok := timeFlows[name]
+	if !ok {
+		return "", fmt.Errorf("unknown time picker flow %q", name)
+	}
+	lang := chatLanguage(s, chatID)
+
+	switch {
+	case step == "h":
+		return "", editPrompt(chatID, messageID, f.title(lang, arg)+"\n\n"+tr(lang, "timepicker.hour"), hourGrid(name, arg, lang))
+	case step == "kb":
+		typingMu.Lock()
+		typing[chatID] = typedTime{flow: name, arg: arg}
+		typingMu.Unlock()
+		return f.title(lang, arg) + "\n\n" + tr(lang, "timepicker.type"), nil
+	case step == "x":
+		return tr(lang, "cancelled"), nil
+	case strings.HasPrefix(step, "m"):
+		hour, err := strconv.Atoi(step[1:])
+		if err != nil || hour < 0 || hour > 23 {
+			return "", fmt.Errorf("invalid hour in %q", data)
+		}
+		return "", editPrompt(chatID, messageID, f.title(lang, arg)+"\n\n"+tr(lang, "timepicker.minute", hour), minuteGrid(name, arg, hour, lang))
+	case strings.HasPrefix(step, "t") && len(step) == 5:
+		hour, minute, ok := parseTypedTime(step[1:])
+		if !ok {
+			return "", fmt.Errorf("invalid time in %q", data)
+		}
+		return f.done(ctx, s, chatID, arg, hour, minute), nil
+	}
+	return "", fmt.Errorf("unknown time picker step %q", step)
+}
+
+// handleTypedTime answers a plain message from a chat whose picker waits for a typed
+// time; it reports false when the chat is not typing a time
+func handleTypedTime(message *tgbotapi.Message) bool {
+	chatID := message.Chat.ID
+	typingMu.Lock()
+	pending, ok := typing[chatID]
+	typingMu.Unlock()
+	if !ok {
+		return false
+	}
+
+	msg := tgbotapi.NewMessage(chatID, "")
+	msg.ParseMode = "Markdown"
+	hour, minute, valid := parseTypedTime(message.Text)
+	s, err := siteFor(chatID)
+	switch {
+	case !valid:
+		msg.Text = tr(chatLanguage(s, chatID), "timepicker.invalid")
+	case err != nil:
+		msg.Text = "❌ " + err.Error()
+	case readOnly():
+		msg.Text = maintenanceMessage
+	default:
+		cancelTypedTime(chatID)
+		ctx, cancel := context.WithTimeout(pollContext(), 10*time.Second)
+		msg.Text = timeFlows[pending.flow].done(ctx, s, chatID, pending.arg, hour, minute)
+		cancel()
+	}
+	if err := send(msg); err != nil {
+		log.Printf("Bot send error: %v", err)
+	}
+	return true
+}
+
+// cancelTypedTime stops waiting for a typed time from the chat
+func cancelTypedTime(chatID int64) {
+	typingMu.Lock()
+	delete(typing, chatID)
+	typingMu.Unlock()
+}
+
+// parseTypedTime reads a time of day as typed on phone keyboards: "8:30", "08.30",
+// "0830", "8" or "๐๘.๓๐ น.", in Thai or Arabic digits
+func parseTypedTime(input string) (hour, minute int, ok bool) {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(input) {
+		switch {
+		case r >= '๐' && r <= '๙':
+			b.WriteRune('0' + r - '๐')
+		case r >= '０' && r <= '９':
+			b.WriteRune('0' + r - '０')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	text := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(strings.TrimSuffix(b.String(), ".")), "น"))
+
+	var h, m string
+	if i := strings.IndexAny(text, ":.： "); i >= 0 {
+		h, m = text[:i], strings.TrimLeft(text[i:], ":.： ")
+	} else if len(text) > 2 {
+		h, m = text[:len(text)-2], text[len(text)-2:]
+	} else {
+		h, m = text, "0"
+	}
+	if h == "" || m == "" || len(h) > 2 || len(m) > 2 {
+		return 0, 0, false
+	}
+
+	hour, err1 := strconv.Atoi(h)
+	minute, err2 := strconv.Atoi(m)
+	if err1 != nil || err2 != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, 0, false
+	}
+	return hour, minute, true
+}
+
+// hourGrid offers the 24 hours, six per row
+func hourGrid(flow, arg, lang string) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for start := 0; start < 24; start += 6 {
+		var row []tgbotapi.InlineKeyboardButton
+		for hour := start; hour < start+6; hour++ {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("%02d", hour), pickerData(flow, fmt.Sprintf("m%02d", hour), arg)))
+		}
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(tr(lang, "timepicker.type_button"), pickerData(flow, "kb", arg)),
+		tgbotapi.NewInlineKeyboardButtonData(tr(lang, "timepicker.cancel_button"), pickerData(flow, "x", arg)),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// minuteGrid offers the hour's minutes in 5-minute steps, four per row
+func minuteGrid(flow, arg string, hour int, lang string) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for start := 0; start < 60; start += 20 {
+		var row []tgbotapi.InlineKeyboardButton
+		for minute := start; minute < start+20; minute += 5 {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("%02d:%02d", hour, minute), pickerData(flow, fmt.Sprintf("t%02d%02d", hour, minute), arg)))
+		}
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(tr(lang, "timepicker.hours_button"), pickerData(flow, "h", arg)),
+		tgbotapi.NewInlineKeyboardButtonData(tr(lang, "timepicker.type_button"), pickerData(flow, "kb", arg)),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// pickerData builds the callback data of a picker button
+func pickerData(flow, step, arg string) string {
+	return timePickerPrefix + ":" + flow + ":" + step + ":" + arg
+}
+
+// editPrompt replaces the text and buttons of a picker or other message with buttons
+func editPrompt(chatID int64, messageID int, text string, markup tgbotapi.InlineKeyboardMarkup) error {
+	if messageID == 0 {
+		return errors.New("prompt message is unknown")
+	}
+	edit := tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, text, markup)
+	edit.ParseMode = "Markdown"
+	_, err := bot.Send(edit)
+	return err
+}
@@ -0,0 +1,429 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+	"med-pulse-bot/internal/services"
+	"med-pulse-bot/internal/tenant"
+)
+
+// telegramCall is one Bot API request received by the fake Telegram server
+type telegramCall struct {
+	method string
+	params url.Values
+}
+
+// fakeTelegram is a Bot API server that records requests and answers them with
+// minimal successful results
+type fakeTelegram struct {
+	mu        sync.Mutex
+	calls     []telegramCall
+	nextID    int
+	chatTypes map[string]string // chat ID → type answered by getChat, default private
+}
+
+// newFakeTelegram starts the fake server and points the package bot at it
+func newFakeTelegram(t *testing.T) *fakeTelegram {
+	t.Helper()
+	f := &fakeTelegram{nextID: 100}
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(srv.Close)
+
+	api, err := tgbotapi.NewBotAPIWithAPIEndpoint("test-token", srv.URL+"/bot%s/%s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot = api
+	t.Cleanup(func() { bot = nil })
+	return f
+}
+
+func (f *fakeTelegram) serve(w http.ResponseWriter, r *http.Request) {
+	r.ParseMultipartForm(1 << 20) // documents are uploaded as multipart forms
+	method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+
+	f.mu.Lock()
+	f.calls = append(f.calls, telegramCall{method: method, params: r.PostForm})
+	f.nextID++
+	id := f.nextID
+	f.mu.Unlock()
+
+	var result string
+	switch method {
+	case "getMe":
+		result = `{"id":1,"is_bot":true,"first_name":"Test","username":"test_bot"}`
+	case "sendMessage", "editMessageText", "sendDocument":
+		result = fmt.Sprintf(`{"message_id":%d,"date":0,"chat":{"id":%s,"type":"private"}}`, id, r.PostForm.Get("chat_id"))
+	case "getChat":
+		f.mu.Lock()
+		chatType := f.chatTypes[r.PostForm.Get("chat_id")]
+		f.mu.Unlock()
+		if chatType == "" {
+			chatType = "private"
+		}
+		result = fmt.Sprintf(`{"id":%s,"type":%q}`, r.PostForm.Get("chat_id"), chatType)
+	default:
+		result = "true"
+	}
+	fmt.Fprintf(w, `{"ok":true,"result":%s}`, result)
+}
+
+// last returns the most recent call of the method
+func (f *fakeTelegram) last(t *testing.T, method string) telegramCall {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := len(f.calls) - 1; i >= 0; i-- {
+		if f.calls[i].method == method {
+			return f.calls[i]
+		}
+	}
+	t.Fatalf("no %s call", method)
+	return telegramCall{}
+}
+
+// count returns how many times the method was called
+func (f *fakeTelegram) count(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, c := range f.calls {
+		if c.method == method {
+			n++
+		}
+	}
+	return n
+}
+
+// button returns the callback data of the button labelled text in the call's keyboard
+func (c telegramCall) button(t *testing.T, text string) string {
+	t.Helper()
+	var markup tgbotapi.InlineKeyboardMarkup
+	if err := json.Unmarshal([]byte(c.params.Get("reply_markup")), &markup); err != nil {
+		t.Fatalf("%s has no inline keyboard: %v", c.method, err)
+	}
+	for _, row := range markup.InlineKeyboard {
+		for _, b := range row {
+			if b.Text == text && b.CallbackData != nil {
+				return *b.CallbackData
+			}
+		}
+	}
+	t.Fatalf("%s has no %q button", c.method, text)
+	return ""
+}
+
+// commandUpdate is a /command message from chatID
+func commandUpdate(chatID int64, text string) tgbotapi.Update {
+	command, _, _ := strings.Cut(text, " ")
+	return tgbotapi.Update{Message: &tgbotapi.Message{
+		MessageID: 1,
+		Chat:      testChat(chatID),
+		Text:      text,
+		Entities:  []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(command)}},
+	}}
+}
+
+// textUpdate is a plain message from chatID
+func textUpdate(chatID int64, text string) tgbotapi.Update {
+	return tgbotapi.Update{Message: &tgbotapi.Message{MessageID: 2, Chat: testChat(chatID), Text: text}}
+}
+
+// testChat is a private chat for a positive ID and a group for a negative one, as on Telegram
+func testChat(chatID int64) *tgbotapi.Chat {
+	if chatID < 0 {
+		return &tgbotapi.Chat{ID: chatID, Type: "group"}
+	}
+	return &tgbotapi.Chat{ID: chatID, Type: "private"}
+}
+
+// pressUpdate is a button press on the picker message in chatID
+func pressUpdate(chatID int64, data string) tgbotapi.Update {
+	return tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:      "q",
+		From:    &tgbotapi.User{ID: chatID, FirstName: "Tester"},
+		Message: &tgbotapi.Message{MessageID: 200, Chat: testChat(chatID)},
+		Data:    data,
+	}}
+}
+
+// useSingleSite points the bot at a PocketBase fake and restores the globals afterwards;
+// it returns the fake's site for calls the bot does not make itself
+func useSingleSite(t *testing.T, pocketBase http.Handler, adminChatID int64) repository.Site {
+	t.Helper()
+	srv := httptest.NewServer(pocketBase)
+	t.Cleanup(srv.Close)
+	adminChatIDs, tenants = nil, nil
+	if adminChatID != 0 {
+		adminChatIDs = []int64{adminChatID}
+	}
+	site := repository.Site{URL: srv.URL}
+	SetRepositories(tenant.DefaultID, Repositories{Employees: site.Employees(), Attendance: site.Attendance(),
+		Devices: site.Devices(), Consents: site.PrivacyAudit(), Scanners: site.Scanners(), Backend: srv.URL})
+	t.Cleanup(func() {
+		adminChatIDs = nil
+		SetRepositories(tenant.DefaultID, Repositories{})
+	})
+	return site
+}
+
+func TestSetScheduleWithTimePicker(t *testing.T) {
+	const chatID = 1001
+	var mu sync.Mutex
+	var patched map[string]interface{}
+	useSingleSite(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/collections/employees/records":
+			fmt.Fprintf(w, `{"items":[{"id":"emp1","name":"Somchai","telegram_chat_id":%d,"is_active":true}]}`, chatID)
+		case r.Method == http.MethodPatch && r.URL.Path == "/api/collections/employees/records/emp1":
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			json.Unmarshal(body, &patched)
+			mu.Unlock()
+			w.Write([]byte(`{"id":"emp1"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}), 0)
+	tg := newFakeTelegram(t)
+
+	handleUpdate(commandUpdate(chatID, "/set_schedule"))
+	picker := tg.last(t, "sendMessage")
+	if !strings.Contains(picker.params.Get("text"), "ตั้งเวลาเริ่มงาน") {
+		t.Fatalf("picker text = %q", picker.params.Get("text"))
+	}
+
+	handleUpdate(pressUpdate(chatID, picker.button(t, "08")))
+	minutes := tg.last(t, "editMessageText")
+	if minutes.params.Get("message_id") != "200" {
+		t.Errorf("minute grid edited message %s, want the picker", minutes.params.Get("message_id"))
+	}
+
+	handleUpdate(pressUpdate(chatID, minutes.button(t, "08:30")))
+	done := tg.last(t, "editMessageText")
+	if !strings.Contains(done.params.Get("text"), "08:30") || done.params.Get("reply_markup") != "" {
+		t.Errorf("final edit = %v, want the chosen time without buttons", done.params)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if patched["work_start_time"] != "08:30:00" {
+		t.Errorf("PATCH body = %v, want work_start_time 08:30:00", patched)
+	}
+}
+
+// fakeRecorder records manual check-ins of employee E001
+type fakeRecorder struct {
+	mu      sync.Mutex
+	checkIn []string
+}
+
+func (r *fakeRecorder) Find(ctx context.Context, code string) (*models.Employee, error) {
+	if code != "E001" {
+		return nil, fmt.Errorf("no active employee with this code")
+	}
+	return &models.Employee{ID: "emp1", Name: "Somchai", EmployeeCode: code}, nil
+}
+
+func (r *fakeRecorder) CheckIn(ctx context.Context, code string, hour, minute int) (*models.Employee, *models.Attendance, error) {
+	emp, err := r.Find(ctx, code)
+	if err != nil {
+		return nil, nil, err
+	}
+	r.mu.Lock()
+	r.checkIn = append(r.checkIn, fmt.Sprintf("%s %02d:%02d", code, hour, minute))
+	r.mu.Unlock()
+	at := time.Date(2026, 2, 2, hour, minute, 0, 0, time.Local)
+	return emp, &models.Attendance{EmployeeID: emp.ID, CheckInTime: at, Status: "late"}, nil
+}
+
+func (r *fakeRecorder) SelfCheckIn(ctx context.Context, chatID int64) (*models.Employee, *models.Attendance, error) {
+	if chatID != 1001 {
+		return nil, nil, services.ErrChatNotEmployee
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.checkIn) > 0 {
+		return nil, nil, services.ErrAlreadyCheckedIn
+	}
+	r.checkIn = append(r.checkIn, "self 08:20")
+	at := time.Date(2026, 2, 2, 8, 20, 0, 0, time.Local)
+	return &models.Employee{ID: "emp1", Name: "Somchai"}, &models.Attendance{EmployeeID: "emp1", CheckInTime: at, Status: "late"}, nil
+}
+
+func (r *fakeRecorder) SelfCheckOut(ctx context.Context, chatID int64) (*models.Employee, *models.Attendance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.checkIn) == 0 {
+		return nil, nil, services.ErrNotCheckedIn
+	}
+	in, out := time.Date(2026, 2, 2, 8, 20, 0, 0, time.Local), time.Date(2026, 2, 2, 17, 30, 0, 0, time.Local)
+	return &models.Employee{ID: "emp1", Name: "Somchai"}, &models.Attendance{EmployeeID: "emp1", CheckInTime: in, CheckOutTime: out}, nil
+}
+
+func TestSelfCheckInCommands(t *testing.T) {
+	useSingleSite(t, http.NotFoundHandler(), 42)
+	tg := newFakeTelegram(t)
+	SetManualCheckIns(tenant.DefaultID, &fakeRecorder{})
+	t.Cleanup(func() { SetManualCheckIns(tenant.DefaultID, nil) })
+
+	steps := []struct {
+		chatID  int64
+		command string
+		want    string
+	}{
+		{1001, "/checkout", "ยังไม่ได้ลงเวลาเข้างาน"},
+		{2002, "/checkin", "ยังไม่ได้ลงทะเบียน"},
+		{1001, "/checkin", "บันทึกเวลาเข้างานแล้ว"},
+		{1001, "/checkin", "ลงเวลาเข้างานแล้ว"},
+		{1001, "/checkout", "ออกงาน: `17:30`"},
+	}
+	for _, st := range steps {
+		handleUpdate(commandUpdate(st.chatID, st.command))
+		if got := tg.last(t, "sendMessage").params.Get("text"); !strings.Contains(got, st.want) {
+			t.Errorf("%s from %d replied %q, want it to contain %q", st.command, st.chatID, got, st.want)
+		}
+	}
+}
+
+func TestManualCheckInTypedTime(t *testing.T) {
+	const adminChatID = 42
+	useSingleSite(t, http.NotFoundHandler(), adminChatID)
+	tg := newFakeTelegram(t)
+	recorder := &fakeRecorder{}
+	SetManualCheckIns(tenant.DefaultID, recorder)
+	t.Cleanup(func() { SetManualCheckIns(tenant.DefaultID, nil) })
+
+	handleUpdate(commandUpdate(1001, "/manual_checkin E001"))
+	if text := tg.last(t, "sendMessage").params.Get("text"); !strings.Contains(text, "ผู้ดูแลระบบเท่านั้น") {
+		t.Errorf("employee chat got %q, want admin-only refusal", text)
+	}
+
+	handleUpdate(commandUpdate(adminChatID, "/manual_checkin E001"))
+	picker := tg.last(t, "sendMessage")
+	handleUpdate(pressUpdate(adminChatID, picker.button(t, "⌨️ พิมพ์เอง")))
+	if text := tg.last(t, "editMessageText").params.Get("text"); !strings.Contains(text, "พิมพ์เวลา") {
+		t.Fatalf("type-it prompt = %q", text)
+	}
+
+	handleUpdate(textUpdate(adminChatID, "แปดโมง"))
+	if text := tg.last(t, "sendMessage").params.Get("text"); !strings.Contains(text, "รูปแบบเวลาไม่ถูกต้อง") {
+		t.Errorf("invalid time reply = %q", text)
+	}
+
+	handleUpdate(textUpdate(adminChatID, "๐๘.๑๕ น."))
+	if text := tg.last(t, "sendMessage").params.Get("text"); !strings.Contains(text, "บันทึกเข้างานแทนแล้ว") {
+		t.Errorf("check-in reply = %q", text)
+	}
+
+	// The picker is done, so further messages are no longer read as times
+	handleUpdate(textUpdate(adminChatID, "09:00"))
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if strings.Join(recorder.checkIn, ",") != "E001 08:15" {
+		t.Errorf("check-ins = %v, want E001 08:15", recorder.checkIn)
+	}
+}
+
+func TestManualCheckInInEnglish(t *testing.T) {
+	const adminChatID = 42
+	useSingleSite(t, http.NotFoundHandler(), adminChatID)
+	tg := newFakeTelegram(t)
+	answerIn(t, en)
+	SetManualCheckIns(tenant.DefaultID, &fakeRecorder{})
+	t.Cleanup(func() { SetManualCheckIns(tenant.DefaultID, nil) })
+
+	handleUpdate(commandUpdate(adminChatID, "/manual_checkin E001"))
+	picker := tg.last(t, "sendMessage")
+	if text := picker.params.Get("text"); text != "✍️ *Check in* `E001`\n\n🕐 Choose the hour" {
+		t.Errorf("picker = %q", text)
+	}
+	handleUpdate(pressUpdate(adminChatID, picker.button(t, "⌨️ Type it")))
+	handleUpdate(textUpdate(adminChatID, "8:15"))
+	if text := tg.last(t, "sendMessage").params.Get("text"); !strings.Contains(text, "✅ *Check-in recorded*\nEmployee: Somchai") {
+		t.Errorf("check-in reply = %q", text)
+	}
+	handleUpdate(commandUpdate(1001, "/checkout"))
+	if text := tg.last(t, "sendMessage").params.Get("text"); !strings.Contains(text, "👋 *Check-out recorded*") {
+		t.Errorf("/checkout = %q", text)
+	}
+}
+
+func TestSetScheduleInEnglish(t *testing.T) {
+	const chatID = 1001
+	useSingleSite(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/collections/employees/records":
+			fmt.Fprintf(w, `{"items":[{"id":"emp1","name":"Somchai","telegram_chat_id":%d,"is_active":true,"language":"en"}]}`, chatID)
+		case r.Method == http.MethodPatch && r.URL.Path == "/api/collections/employees/records/emp1":
+			w.Write([]byte(`{"id":"emp1"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}), 0)
+	tg := newFakeTelegram(t)
+	answerIn(t, th)
+
+	handleUpdate(commandUpdate(chatID, "/set_schedule end"))
+	picker := tg.last(t, "sendMessage")
+	if text := picker.params.Get("text"); !strings.Contains(text, "🗓️ *Set your work end time*") {
+		t.Fatalf("picker text = %q", text)
+	}
+	handleUpdate(pressUpdate(chatID, picker.button(t, "17")))
+	minutes := tg.last(t, "editMessageText")
+	if text := minutes.params.Get("text"); !strings.Contains(text, "Choose the minute (17:..)") {
+		t.Errorf("minute grid = %q", text)
+	}
+	handleUpdate(pressUpdate(chatID, minutes.button(t, "17:30")))
+	if text := tg.last(t, "editMessageText").params.Get("text"); text != "✅ Your work end time is set to `17:30`" {
+		t.Errorf("final edit = %q", text)
+	}
+}
+
+func TestParseTypedTime(t *testing.T) {
+	tests := []struct {
+		input        string
+		hour, minute int
+		ok           bool
+	}{
+		{"08:30", 8, 30, true},
+		{"8.30", 8, 30, true},
+		{"0830", 8, 30, true},
+		{"830", 8, 30, true},
+		{"17", 17, 0, true},
+		{" 8 30 ", 8, 30, true},
+		{"๐๘:๓๐", 8, 30, true},
+		{"๑๗.๐๐ น.", 17, 0, true},
+		{"08：30", 8, 30, true},
+		{"24:00", 0, 0, false},
+		{"08:60", 0, 0, false},
+		{"8:30:00", 0, 0, false},
+		{"", 0, 0, false},
+		{"แปดโมง", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			hour, minute, ok := parseTypedTime(tt.input)
+			if ok != tt.ok || hour != tt.hour || minute != tt.minute {
+				t.Errorf("parseTypedTime(%q) = %d, %d, %v; want %d, %d, %v",
+					tt.input, hour, minute, ok, tt.hour, tt.minute, tt.ok)
+			}
+		})
+	}
+}
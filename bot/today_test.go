@@ -0,0 +1,39 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/models"
+)
+
+func TestTodayText(t *testing.T) {
+	in := time.Date(2026, 3, 2, 8, 15, 0, 0, location)
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, 3, 2, hour, minute, 0, 0, location)
+	}
+	tests := []struct {
+		name string
+		att  models.Attendance
+		now  time.Time
+		want []string
+	}{
+		{"midday, not checked out", models.Attendance{CheckInTime: in, ScannerMac: "aa:bb:cc:00:00:01", Status: "on_time"},
+			in.Add(4*time.Hour + 5*time.Minute), []string{"In: 08:15", "Out: ยังไม่ออกงาน", "Hours: 4h 05m", "Scanner: `aa:bb:cc:00:00:01`", "Status: on_time"}},
+		{"checked out", models.Attendance{CheckInTime: in, CheckOutTime: at(17, 0), Status: "late"},
+			in.Add(12 * time.Hour), []string{"Out: 17:00", "Hours: 8h 45m"}},
+		{"check-out corrected before check-in", models.Attendance{CheckInTime: in, CheckOutTime: at(7, 30), Status: "on_time"},
+			in.Add(time.Hour), []string{"Out: 07:30", "Hours: 0h 00m"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := todayText(&tt.att, tt.now, th)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("todayText() = %q, want it to contain %q", got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/services"
+)
+
+// whoIsInLimit is how many people /whoisin lists before summarizing the rest
+const whoIsInLimit = 30
+
+// PresenceReporter tells who of one tenant was detected recently
+type PresenceReporter interface {
+	WhoIsIn(ctx context.Context, window time.Duration) ([]services.PresentEmployee, error)
+}
+
+var (
+	presenceReportersMu sync.RWMutex
+	presenceReporters   = make(map[string]PresenceReporter) // tenant ID → reporter
+)
+
+// SetPresence enables /whoisin for the tenant's admin chats
+func SetPresence(tenantID string, r PresenceReporter) {
+	presenceReportersMu.Lock()
+	presenceReporters[tenantID] = r
+	presenceReportersMu.Unlock()
+}
+
+// handleWhoIsIn lists the employees detected in the last minutes with the scanner that
+// heard them best
+func handleWhoIsIn(s *site, message *tgbotapi.Message, msg *tgbotapi.MessageConfig) {
+	lang := chatLanguage(s, message.Chat.ID)
+	if !isSiteAdmin(message.Chat.ID) {
+		msg.Text = tr(lang, "admin_only")
+		return
+	}
+	presenceReportersMu.RLock()
+	r := presenceReporters[s.id]
+	presenceReportersMu.RUnlock()
+	if r == nil {
+		msg.Text = tr(lang, "whoisin.disabled")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(pollContext(), 10*time.Second)
+	defer cancel()
+	present, err := r.WhoIsIn(ctx, services.DefaultPresenceWindow)
+	if err != nil {
+		log.Printf("Failed to read presence: %v", err)
+		msg.Text = tr(lang, "unavailable")
+		return
+	}
+	minutes := int(services.DefaultPresenceWindow / time.Minute)
+	if len(present) == 0 {
+		msg.Text = tr(lang, "whoisin.nobody", minutes)
+		return
+	}
+
+	lines := []string{tr(lang, "whoisin.title", len(present), minutes)}
+	for i, p := range present {
+		if i == whoIsInLimit {
+			lines = append(lines, tr(lang, "whoisin.more", len(present)-whoIsInLimit))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("• %s %s %s (%d dBm)",
+			markdown.Escape(p.Employee.Name), p.LastSeen.In(location).Format("15:04"), markdown.Code(p.ScannerMac), p.RSSI))
+	}
+	msg.Text = strings.Join(lines, "\n")
+}
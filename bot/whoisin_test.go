@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/services"
+	"med-pulse-bot/internal/tenant"
+)
+
+// fakePresence reports a fixed list of present employees
+type fakePresence []services.PresentEmployee
+
+func (f fakePresence) WhoIsIn(ctx context.Context, window time.Duration) ([]services.PresentEmployee, error) {
+	return f, nil
+}
+
+func TestWhoIsIn(t *testing.T) {
+	const adminChatID = 42
+	useSingleSite(t, http.NotFoundHandler(), adminChatID)
+	tg := newFakeTelegram(t)
+	t.Cleanup(func() { SetPresence(tenant.DefaultID, nil) })
+
+	seen := time.Date(2026, 2, 2, 9, 15, 0, 0, time.Local)
+	crowd := make(fakePresence, 35)
+	for i := range crowd {
+		crowd[i] = services.PresentEmployee{Employee: models.Employee{Name: fmt.Sprintf("Staff_%02d", i+1)}, LastSeen: seen, ScannerMac: "aa:bb:cc:00:00:01", RSSI: -61}
+	}
+
+	freeform := fakePresence{{Employee: models.Employee{Name: "Somchai"}, LastSeen: seen, ScannerMac: "esp32_`lobby`*", RSSI: -70}}
+
+	tests := []struct {
+		name     string
+		chatID   int64
+		presence fakePresence
+		want     []string
+		notWant  string
+	}{
+		{"employee chat", 1001, crowd[:1], []string{"ผู้ดูแลระบบเท่านั้น"}, "Staff"},
+		{"nobody", adminChatID, fakePresence{}, []string{"ไม่พบใครในสำนักงานใน 15 นาที"}, ""},
+		{"a few", adminChatID, crowd[:2], []string{"(2 คน", "• Staff\\_01 09:15 `aa:bb:cc:00:00:01` (-61 dBm)", "Staff\\_02"}, "และอีก"},
+		{"truncated", adminChatID, crowd, []string{"(35 คน", "Staff\\_30", "… และอีก 5 คน"}, "Staff\\_31"},
+		{"free-form scanner ID", adminChatID, freeform, []string{"`esp32_'lobby'*`"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetPresence(tenant.DefaultID, tt.presence)
+			handleUpdate(commandUpdate(tt.chatID, "/whoisin"))
+			got := tg.last(t, "sendMessage").params.Get("text")
+			if err := markdown.Check(got); err != nil {
+				t.Errorf("/whoisin = %q does not parse: %v", got, err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("/whoisin = %q, want it to contain %q", got, want)
+				}
+			}
+			if tt.notWant != "" && strings.Contains(got, tt.notWant) {
+				t.Errorf("/whoisin = %q, want no %q", got, tt.notWant)
+			}
+		})
+	}
+}
+
+func TestWhoIsInInEnglish(t *testing.T) {
+	const adminChatID = 42
+	useSingleSite(t, http.NotFoundHandler(), adminChatID)
+	tg := newFakeTelegram(t)
+	answerIn(t, en)
+	t.Cleanup(func() { SetPresence(tenant.DefaultID, nil) })
+	seen := time.Date(2026, 2, 2, 9, 15, 0, 0, time.Local)
+
+	tests := []struct {
+		chatID   int64
+		presence fakePresence
+		want     string
+	}{
+		{1001, fakePresence{}, "❌ This command is for admins only"},
+		{adminChatID, fakePresence{}, "🏢 Nobody was in the office in the last 15 minutes"},
+		{adminChatID, fakePresence{{Employee: models.Employee{Name: "Somchai"}, LastSeen: seen, ScannerMac: "aa:bb:cc:00:00:01", RSSI: -61}},
+			"🏢 *In the office* (1 people, last 15 minutes)"},
+	}
+	for _, tt := range tests {
+		SetPresence(tenant.DefaultID, tt.presence)
+		handleUpdate(commandUpdate(tt.chatID, "/whoisin"))
+		if got := tg.last(t, "sendMessage").params.Get("text"); !strings.Contains(got, tt.want) {
+			t.Errorf("/whoisin from %d = %q, want it to contain %q", tt.chatID, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,433 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"med-pulse-bot/bot"
+	"med-pulse-bot/config"
+	"med-pulse-bot/internal/repository"
+	"med-pulse-bot/internal/services"
+)
+
+// runCommand executes a CLI subcommand instead of starting the server and returns the exit code
+func runCommand(cfg *config.Config, name string, args []string) int {
+	initPocketBaseAuth(cfg)
+	switch name {
+	case "doctor":
+		return runDoctor(cfg)
+	case "baselines":
+		return runBaselines(cfg, args)
+	case "import-attendance":
+		return runImportAttendance(cfg, args)
+	case "normalize-scanners":
+		return runNormalizeScanners(cfg, args)
+	case "export-attendance":
+		return runExportAttendance(cfg, args)
+	case "audit-chat-ids":
+		return runAuditChatIDs(cfg)
+	case "repair-employee-ids":
+		return runRepairEmployeeIDs(cfg, args)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+		fmt.Fprintln(os.Stderr, "Usage: app [command]")
+		fmt.Fprintln(os.Stderr, "\nCommands:")
+		fmt.Fprintln(os.Stderr, "  doctor                   Check PocketBase connectivity and schema capabilities")
+		fmt.Fprintln(os.Stderr, "  baselines rebuild [days] Recompute check-in time baselines from attendance history")
+		fmt.Fprintln(os.Stderr, "  import-attendance <csv>  Import history exported from the legacy fingerprint system")
+		fmt.Fprintln(os.Stderr, "  normalize-scanners       Rewrite scanner records to canonical MAC addresses")
+		fmt.Fprintln(os.Stderr, "  export-attendance <month> Export a month's check-ins as CSV for payroll")
+		fmt.Fprintln(os.Stderr, "  audit-chat-ids           List employees whose chat ID is a group or channel")
+		fmt.Fprintln(os.Stderr, "  repair-employee-ids      Relink check-ins and detections stored without their employee")
+		return 2
+	}
+}
+
+// runDoctor checks PocketBase connectivity and prints the live schema capability set
+func runDoctor(cfg *config.Config) int {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	fmt.Println("🩺 MedPulseBot Doctor")
+	fmt.Println("====================")
+	fmt.Printf("📍 PocketBase URL: %s\n", cfg.PocketBaseURL)
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(cfg.PocketBaseURL, "/")+"/api/health", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Printf("❌ PocketBase unreachable: %v\n", err)
+		return 1
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		fmt.Printf("❌ PocketBase health check failed: %s\n", resp.Status)
+		return 1
+	}
+	fmt.Println("✅ PocketBase is running")
+
+	if cfg.PocketBaseToken == "" && (cfg.PocketBaseAdminEmail == "" || cfg.PocketBaseAdminPassword == "") {
+		fmt.Println("❌ Neither POCKETBASE_TOKEN nor POCKETBASE_ADMIN_EMAIL/PASSWORD set - schema cannot be inspected")
+		return 1
+	}
+
+	caps := repository.NewSchemaCapabilities(cfg.PocketBaseURL, cfg.PocketBaseToken)
+	if err := caps.Refresh(ctx); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+
+	latest := repository.SchemaVersions[len(repository.SchemaVersions)-1]
+	fmt.Printf("\n🧬 Schema version: %d (code expects up to %d: %s)\n", caps.Version(), latest.Version, latest.Name)
+	for _, v := range repository.SchemaVersions {
+		mark := "✅"
+		if caps.Version() < v.Version {
+			mark = "⏳"
+		}
+		fmt.Printf("   %s v%d %s\n", mark, v.Version, v.Name)
+	}
+
+	if missing := caps.Missing(); len(missing) > 0 {
+		fmt.Println("\n⚠️  Fields missing from the live schema (optional fields are skipped on write):")
+		for _, m := range missing {
+			fmt.Printf("   • %s\n", m)
+		}
+		fmt.Println("\n   Run: go run scripts/migrate/main.go")
+	} else {
+		fmt.Println("\n✅ Live schema has every field this build knows about")
+	}
+
+	return 0
+}
+
+// runBaselines recomputes every employee's check-in baseline from recent attendance history
+func runBaselines(cfg *config.Config, args []string) int {
+	if len(args) == 0 || args[0] != "rebuild" {
+		fmt.Fprintln(os.Stderr, "Usage: app baselines rebuild [days]")
+		return 2
+	}
+
+	if cfg.ReadOnly {
+		fmt.Println("❌ READ_ONLY is set; baselines cannot be rebuilt during maintenance")
+		return 1
+	}
+
+	// Enough calendar days to cover the baseline window of working days plus leave
+	days := services.BaselineWindowDays * 3
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			fmt.Fprintf(os.Stderr, "Invalid number of days %q\n", args[1])
+			return 2
+		}
+		days = n
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	since := time.Now().AddDate(0, 0, -days)
+	history, err := repository.NewPocketBaseRESTAttendanceRepository(cfg.PocketBaseURL).ListSince(ctx, since)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	fmt.Printf("📚 Loaded %d attendance records since %s\n", len(history), since.Format("2006-01-02"))
+
+	baselines, err := services.NewCheckInBaselines(
+		repository.NewPocketBaseRESTBaselineRepository(cfg.PocketBaseURL), cfg.AnomalyMADThreshold)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+
+	updated, err := baselines.Rebuild(ctx, history)
+	if err != nil {
+		fmt.Printf("❌ %v (%d baselines updated before the failure)\n", err, updated)
+		return 1
+	}
+	fmt.Printf("✅ Rebuilt %d check-in baselines\n", updated)
+	return 0
+}
+
+// runImportAttendance imports a legacy fingerprint export (code, date, in, out) as attendance
+// with source=import. Rows with unknown employee codes go to a review file instead of
+// aborting the import.
+func runImportAttendance(cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("import-attendance", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "report what would be imported without writing")
+	unknownOut := flags.String("unknown-out", "", "file for rows with unknown employee codes (default <csv>.unknown.csv)")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: app import-attendance [--dry-run] [--unknown-out file] <export.csv>")
+		return 2
+	}
+	path := flags.Arg(0)
+	if *unknownOut == "" {
+		*unknownOut = strings.TrimSuffix(path, filepath.Ext(path)) + ".unknown.csv"
+	}
+
+	if cfg.ReadOnly && !*dryRun {
+		fmt.Println("❌ READ_ONLY is set; only --dry-run is allowed during maintenance")
+		return 1
+	}
+
+	loc, err := cfg.Location()
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 2
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	rows, err := services.ParseLegacyCSV(f)
+	f.Close()
+	if err != nil {
+		fmt.Printf("❌ %s: %v\n", path, err)
+		return 1
+	}
+	fmt.Printf("📥 Read %d rows from %s (times in %s)\n", len(rows), path, loc)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	// Imported records must be distinguishable, so refuse to write without the source field
+	caps := repository.NewSchemaCapabilities(cfg.PocketBaseURL, cfg.PocketBaseToken)
+	if err := caps.Refresh(ctx); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	if !*dryRun && !caps.Has("attendance", "source") {
+		fmt.Println("❌ attendance.source is missing from the live schema; run migrations first")
+		return 1
+	}
+	repository.SetSchemaCapabilities(caps)
+
+	importer := services.NewLegacyImporter(
+		repository.NewPocketBaseRESTEmployeeRepository(cfg.PocketBaseURL),
+		repository.NewPocketBaseRESTAttendanceRepository(cfg.PocketBaseURL),
+		loc,
+	)
+	importer.SetGracePeriod(time.Duration(cfg.GracePeriodMinutes) * time.Minute)
+	report, err := importer.Import(ctx, rows, *dryRun)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+
+	fmt.Println()
+	report.Write(os.Stdout)
+
+	if unknown := report.Unknown(); len(unknown) > 0 {
+		if err := writeUnknownRows(*unknownOut, unknown); err != nil {
+			fmt.Printf("❌ Failed to write unknown employee codes: %v\n", err)
+			return 1
+		}
+		fmt.Printf("📝 %d rows with unknown employee codes written to %s for review\n", len(unknown), *unknownOut)
+	}
+	if *dryRun {
+		fmt.Println("🧪 Dry run - nothing was written")
+	}
+	if report.Count(services.ImportFailed) > 0 || report.Count(services.ImportLocked) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runNormalizeScanners rewrites scanner records stored under another spelling of their
+// MAC to the canonical form and reports records that turn out to be the same scanner
+func runNormalizeScanners(cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("normalize-scanners", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "report what would change without writing")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, "Usage: app normalize-scanners [--dry-run]")
+		return 2
+	}
+
+	if cfg.ReadOnly && !*dryRun {
+		fmt.Println("❌ READ_ONLY is set; only --dry-run is allowed during maintenance")
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	report, err := services.NormalizeScannerIDs(ctx,
+		repository.NewPocketBaseRESTScannerRepository(cfg.PocketBaseURL), cfg.FreeformScannerIDs, *dryRun)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+
+	report.Write(os.Stdout)
+	if *dryRun {
+		fmt.Println("🧪 Dry run - nothing was written")
+	}
+	if report.Count(services.ScannerIDCollision) > 0 || report.Count(services.ScannerIDInvalid) > 0 ||
+		report.Count(services.ScannerIDFailed) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runRepairEmployeeIDs links the attendance and detection records left without an
+// employee_id by the old number field back to their employee
+func runRepairEmployeeIDs(cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("repair-employee-ids", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "report what would be relinked without writing")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, "Usage: app repair-employee-ids [--dry-run]")
+		return 2
+	}
+
+	if cfg.ReadOnly && !*dryRun {
+		fmt.Println("❌ READ_ONLY is set; only --dry-run is allowed during maintenance")
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	site := repository.DefaultSite(cfg.PocketBaseURL)
+	repair := services.NewEmployeeIDRepair(site.Attendance(), site.Detections(), site.Employees())
+	repair.SetDevices(site.Devices())
+	report, err := repair.Run(ctx, *dryRun)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+
+	report.Write(os.Stdout)
+	if *dryRun {
+		fmt.Println("🧪 Dry run - nothing was written")
+	}
+	if report.Count(services.RelinkFailed) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runExportAttendance writes the check-ins of a month as CSV, naming employees as they
+// were when each check-in was recorded
+func runExportAttendance(cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("export-attendance", flag.ContinueOnError)
+	out := flags.String("out", "", "file to write (default stdout)")
+	includeGuests := flags.Bool("include-guests", false, "also export check-ins of guest tags")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: app export-attendance [--out file] [--include-guests] <YYYY-MM>")
+		return 2
+	}
+
+	loc, err := cfg.Location()
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	export := services.NewAttendanceExport(
+		repository.NewPocketBaseRESTEmployeeRepository(cfg.PocketBaseURL),
+		repository.NewPocketBaseRESTAttendanceRepository(cfg.PocketBaseURL),
+		repository.DefaultSite(cfg.PocketBaseURL).PeriodLocks(),
+		loc,
+	)
+	export.SetIncludeGuests(*includeGuests)
+	if *out == "" {
+		if _, err := export.WriteCSV(ctx, os.Stdout, flags.Arg(0)); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	n, err := export.WriteCSV(ctx, f, flags.Arg(0))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	fmt.Printf("📤 Exported %d check-ins for %s to %s\n", n, flags.Arg(0), *out)
+	return 0
+}
+
+// runAuditChatIDs lists active employees whose telegram_chat_id is not a private chat,
+// so their personal notifications would go to a whole group
+func runAuditChatIDs(cfg *config.Config) int {
+	if err := bot.Init(cfg.TelegramBotToken, nil); err != nil {
+		fmt.Printf("❌ Telegram: %v\n", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	employees, err := repository.NewPocketBaseRESTEmployeeRepository(cfg.PocketBaseURL).ListActive(ctx)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+
+	checked, found, failed := 0, 0, 0
+	for _, emp := range employees {
+		if emp.TelegramChatID == 0 {
+			continue
+		}
+		checked++
+		err := bot.CheckPersonalChat(emp.TelegramChatID)
+		switch {
+		case errors.Is(err, bot.ErrNotPrivateChat):
+			found++
+			fmt.Printf("⚠️  %s (%s, id %s): %v\n", emp.Name, emp.EmployeeCode, emp.ID, err)
+		case err != nil:
+			failed++
+			fmt.Printf("❌ %s (%s, id %s): %v\n", emp.Name, emp.EmployeeCode, emp.ID, err)
+		}
+	}
+
+	fmt.Printf("\n🔍 Checked %d chat IDs: %d group or channel, %d could not be checked\n", checked, found, failed)
+	if found > 0 {
+		fmt.Println("   Clear telegram_chat_id of these employees and have them register from a private chat")
+	}
+	if found > 0 || failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// writeUnknownRows saves rows with unknown employee codes as CSV for manual review
+func writeUnknownRows(path string, rows []services.LegacyRow) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	w.Write([]string{"line", "employee_code", "date", "in", "out"})
+	for _, row := range rows {
+		w.Write([]string{strconv.Itoa(row.Line), row.EmployeeCode, row.Date, row.In, row.Out})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
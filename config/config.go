@@ -1,8 +1,14 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,9 +18,163 @@ type Config struct {
 	PocketBaseURL   string // PocketBase server URL (e.g., http://192.168.100.100:8090)
 	PocketBaseToken string // Auth token for API access
 
+	// Admin credentials; when set the token is obtained and refreshed automatically
+	PocketBaseAdminEmail    string
+	PocketBaseAdminPassword string
+
 	// Telegram Bot
-	TelegramBotToken string
-	AuthorizedChatID string
+	Mode              string // telegram or headless; headless, or no TelegramBotToken, runs without the bot
+	TelegramBotToken  string
+	AuthorizedChatIDs string // Comma-separated admin chats, users or groups (AUTHORIZED_CHAT_IDS, or the older AUTHORIZED_CHAT_ID)
+	AdminLanguage     string // th or en: bot replies in the admin chats and the alerts sent there
+
+	// Notification channels
+	Notifiers        string // Comma-separated channels notifications go to: telegram, webhook
+	NotifyWebhookURL string // Where the webhook channel POSTs notifications as JSON
+
+	// Logging
+	LogLevel      string // debug, info, warn or error; debug adds PocketBase lookups and every detection
+	LogFormat     string // text or json (for container log shippers)
+	LogHTTPBodies bool   // Log PocketBase response bodies (personal data) at debug
+
+	// Detection payload compatibility
+	PayloadProfiles      string // Extra field-mapping profiles: "name:src=dst,...;name2:..."
+	PayloadProfileKeys   string // API key routing: "apikey:profile,..."
+	DetectRateLimit      int    // Detections per minute per scanner; 0 disables the limit
+	DetectMaxBodyBytes   int    // Largest accepted detection request body
+	DetectWorkers        int    // Workers processing detections after a 202 answer; 0 processes them before answering
+	DetectQueueSize      int    // Detections waiting for a worker before scanners get 503
+	FreeformScannerIDs   bool   // Accept scanner_mac values that are not MAC addresses
+	RequireScannerTokens bool   // Reject detections without a per-scanner device token
+
+	// Local state
+	DataDir    string // Directory for local snapshots (smoothing window, ...)
+	LocalStore string // Backend of the detection queue and smoothing snapshot: file, memory or pocketbase
+	Timezone   string // IANA zone of attendance days, late checks and times without one (e.g. legacy imports)
+
+	// Storage of employees, attendance, detections and scanners
+	StorageBackend string // pocketbase or sqlite
+	SQLitePath     string // SQLite database file; empty uses med-pulse.db in DataDir
+
+	// Check-in distance
+	RSSIThreshold int // Weakest signal (dBm) accepted for a check-in
+
+	// Scanner configuration
+	ScannerProfilesFile string // YAML file with scanner defaults and named profiles; empty uses RSSI_THRESHOLD only
+
+	// Detection smoothing
+	SmoothingMinDetections int           // Detections required within SmoothingWindow before check-in; 1 disables smoothing
+	SmoothingWindow        time.Duration // Sliding window for SmoothingMinDetections
+	RSSISmoothingAlpha     float64       // Weight of the newest reading in the RSSI moving average; 0 disables it
+	RSSISmoothingIdle      time.Duration // A device's average is forgotten after this long without a reading
+
+	GracePeriodMinutes int // Minutes after work start a check-in is still on time, unless the employee has their own
+
+	WorkDays string // Working weekdays, e.g. "Mon,Tue,Wed,Thu,Fri"; empty makes every day a working day
+
+	// Check-in window
+	CheckInWindowStart string // HH:MM from which detections may check in; empty with CheckInWindowEnd allows any time
+	CheckInWindowEnd   string // HH:MM until which detections may check in
+
+	// Repeated detections
+	RecentDetectionTTL time.Duration // Repeats of a settled detection within this are dropped; 0 disables
+
+	// Presence
+	PresenceLogInterval time.Duration // A detection of each checked-in employee is saved this often for /whoisin; 0 disables
+
+	// Unknown devices
+	UnknownDeviceCapture        bool          // Record strong detections of devices no employee owns in the devices collection
+	UnknownDeviceMinRSSI        int           // Only devices heard stronger than this (dBm) are recorded
+	UnknownDeviceSampleInterval time.Duration // Each unknown device is written at most this often
+
+	// Privacy
+	WhitelistOnly bool // Drop detections of devices that are neither an employee's nor whitelisted before logging or storing them
+
+	// PocketBase write failures
+	PocketBaseRetryAttempts int           // Calls made on connection errors and 5xx responses before giving up; 1 does not retry
+	PocketBaseTimeout       time.Duration // Longest wait for one PocketBase request, on top of the caller's own deadline
+
+	// Employee lookups
+	EmployeeCacheTTL     time.Duration // How long a MAC's employee is cached; 0 disables the cache
+	EmployeeCacheMissTTL time.Duration // How long a MAC no employee owns is cached; 0 looks it up every time
+
+	// Scheduled jobs
+	EndOfDayTime     string // HH:MM at which the end-of-day job runs
+	AutoCheckOutTime string // HH:MM at which open records are checked out at their last detection; empty disables
+
+	// Left-behind tag detection
+	StationaryTagEveningStart  string // HH:MM after which continuous detections count as a left-behind tag
+	StationaryTagConfirmations int    // Consecutive moving detections required next morning
+
+	// Admin daily summary
+	DailySummaryEnabled bool    // Send the admin chat a summary at the end-of-day time
+	MorningReportTime   string  // HH:MM at which the admin chat gets who is on time, late or not seen; "off" disables
+	AnomalyMADThreshold float64 // MADs from the usual check-in time before it is noted as unusual
+
+	// Department inference
+	DepartmentZones           string        // Scanner zone to department table "zone:department,..."; empty disables proposals
+	DepartmentInferenceWindow time.Duration // Trailing window of detections a proposal is based on
+
+	// Inactive employee policy
+	InactivityPolicyEnabled  bool // Ask the admin weekly about employees not seen for a while
+	InactivityFlagDays       int  // Days without detections or check-ins before an employee is listed
+	InactivityDeactivateDays int  // Days after which a listed employee nobody answered for is deactivated
+
+	// Notification wording
+	TemplatesDir string // Directory of .tmpl files replacing the built-in check-in/check-out messages; empty keeps them all
+
+	// Check-out tracking
+	CheckOutTrackingEnabled bool   // Record check-outs from detections of checked-in employees
+	CheckOutAfter           string // HH:MM from which a detection counts as leaving
+
+	// Forgotten check-out reminder
+	CheckOutReminderEnabled bool          // Remind employees without a check-out after their scheduled end
+	WorkEndTime             string        // HH:MM:SS end of day for employees without their own work_end_time; empty skips them
+	CheckOutReminderDelay   time.Duration // Time after the scheduled end before the reminder is sent
+	CheckOutReminderSnooze  time.Duration // How long "still working" postpones the reminder
+	ExitScannerMACs         string        // Comma-separated scanners at the exits; a detection there counts as leaving
+
+	// Scanner pairing
+	PairingCodeTTL      time.Duration // How long a /pair_scanner code stays valid
+	DevicePairingWindow time.Duration // How long /pair listens for an employee's new tag
+
+	// Public status board
+	PublicBoardCIDRs     string // Comma-separated networks allowed to view /public/board; empty disables it
+	PublicBoardRateLimit int    // Requests per minute per client IP
+
+	// Dashboard API
+	DashboardAPIToken string // Bearer token of /api/attendance and /api/employees; empty disables them
+	AllowedOrigins    string // Comma-separated origins browsers may call the dashboard API from; "*" allows any
+
+	// Multi-tenant mode
+	TenantsFile string // YAML file listing tenants; empty keeps single-site mode
+
+	// Pipeline self-test
+	SelfTestInterval time.Duration // How often a synthetic detection is pushed through the pipeline; 0 disables it
+	SelfTestDeadline time.Duration // How long the synthetic detection record may take to appear
+	SelfTestMAC      string        // Reserved device MAC of the synthetic employee; empty uses the built-in one
+
+	// Built-in alerts, served at /api/alerts and sent to the admin chat
+	AlertInterval             time.Duration // How often alerts are evaluated for admin chat messages; 0 disables the messages
+	ScannerOfflineAfter       time.Duration // Silence after which a scanner counts as offline, in alerts and /scanners
+	AlertQueueDepth           int           // Queued detections at which the queue depth alert fires
+	AlertNotificationFailures int           // Failed Telegram sends within AlertNotificationWindow that fire an alert
+	AlertNotificationWindow   time.Duration // Window for AlertNotificationFailures
+
+	// Local queue watchdog; AlertQueueDepth is the high water mark
+	QueueHardCap int // Queued entries above which presence detections are dropped, oldest first; 0 never drops
+
+	// Warm standby
+	LeaderElection bool          // Compete for a lease so only one instance runs schedulers, queue drains and Telegram polling
+	InstanceID     string        // Name of this instance in the lease; empty uses the hostname
+	LeaderLeaseTTL time.Duration // How long the leader's lease lasts without renewal
+
+	// Maintenance
+	ReadOnly bool // Start with PocketBase writes suspended; toggled at runtime with /readonly
+
+	// Resilience testing
+	EnableFaultInjection bool   // Exposes /debug/faults; never set in production
+	FaultInjectionToken  string // Bearer token of /debug/faults; empty keeps it unexposed
 }
 
 func LoadConfig() (*Config, error) {
@@ -32,16 +192,374 @@ func LoadConfig() (*Config, error) {
 		log.Printf("godotenv.Load() error: %v", err)
 	}
 
-	// Get PocketBase URL (required)
-	pbURL := os.Getenv("POCKETBASE_URL")
-	if pbURL == "" {
-		pbURL = "http://192.168.100.100:8090" // Default external server
+	cfg := fromEnv(os.Getenv)
+	if _, err := cfg.Location(); err != nil {
+		return nil, err
+	}
+	if _, err := cfg.AdminChatIDs(); err != nil {
+		return nil, err
+	}
+	if cfg.GracePeriodMinutes < 0 {
+		return nil, fmt.Errorf("invalid GRACE_PERIOD_MINUTES %d: want 0 or more", cfg.GracePeriodMinutes)
+	}
+	if err := cfg.CheckStorage(); err != nil {
+		return nil, err
+	}
+	if err := cfg.CheckMode(); err != nil {
+		return nil, err
+	}
+	if _, err := cfg.NotifierChannels(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Run modes of MODE
+const (
+	ModeTelegram = "telegram"
+	ModeHeadless = "headless" // detections and attendance only, no Telegram bot or Telegram notifications
+)
+
+// CheckMode validates MODE
+func (c *Config) CheckMode() error {
+	if c.Mode != ModeTelegram && c.Mode != ModeHeadless {
+		return fmt.Errorf("invalid MODE %q: want telegram or headless", c.Mode)
+	}
+	return nil
+}
+
+// Headless reports whether the server runs without Telegram: MODE=headless, or no
+// TELEGRAM_BOT_TOKEN to connect with
+func (c *Config) Headless() bool {
+	return c.Mode == ModeHeadless || c.TelegramBotToken == ""
+}
+
+// Notification channels of NOTIFIERS
+const (
+	NotifierTelegram = "telegram"
+	NotifierWebhook  = "webhook" // JSON POSTs to NOTIFY_WEBHOOK_URL
+)
+
+// NotifierChannels returns the channels listed in NOTIFIERS, each once
+func (c *Config) NotifierChannels() ([]string, error) {
+	var channels []string
+	for _, part := range strings.Split(c.Notifiers, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		switch {
+		case part == "" || slices.Contains(channels, part):
+			continue
+		case part == NotifierWebhook && c.NotifyWebhookURL == "":
+			return nil, fmt.Errorf("NOTIFIERS includes webhook but NOTIFY_WEBHOOK_URL is not set")
+		case part != NotifierTelegram && part != NotifierWebhook:
+			return nil, fmt.Errorf("invalid notifier %q in NOTIFIERS: want telegram or webhook", part)
+		}
+		channels = append(channels, part)
+	}
+	return channels, nil
+}
+
+// Storage backends of STORAGE_BACKEND
+const (
+	StoragePocketBase = "pocketbase"
+	StorageSQLite     = "sqlite" // one local file, for a single site without a PocketBase server
+)
+
+// CheckStorage validates STORAGE_BACKEND and the settings that need PocketBase, which a
+// SQLite deployment cannot use
+func (c *Config) CheckStorage() error {
+	switch c.StorageBackend {
+	case StoragePocketBase:
+		return nil
+	case StorageSQLite:
+	default:
+		return fmt.Errorf("invalid STORAGE_BACKEND %q: want pocketbase or sqlite", c.StorageBackend)
+	}
+	switch {
+	case c.TenantsFile != "":
+		return fmt.Errorf("STORAGE_BACKEND=sqlite serves a single site; unset TENANTS_FILE")
+	case c.LeaderElection:
+		return fmt.Errorf("STORAGE_BACKEND=sqlite cannot be shared by instances; unset LEADER_ELECTION")
+	case c.LocalStore == "pocketbase":
+		return fmt.Errorf("STORAGE_BACKEND=sqlite has no PocketBase to keep the queue in; set LOCAL_STORE=file")
+	}
+	return nil
+}
+
+// SQLiteFile returns the SQLite database file of STORAGE_BACKEND=sqlite
+func (c *Config) SQLiteFile() string {
+	if c.SQLitePath != "" {
+		return c.SQLitePath
+	}
+	return filepath.Join(c.DataDir, "med-pulse.db")
+}
+
+// WithOverrides returns a copy of the configuration re-read with overrides taking
+// precedence over the environment, keyed by environment variable name
+func (c *Config) WithOverrides(overrides map[string]string) *Config {
+	if len(overrides) == 0 {
+		copied := *c
+		return &copied
+	}
+	return fromEnv(func(key string) string {
+		if val, ok := overrides[key]; ok {
+			return val
+		}
+		return os.Getenv(key)
+	})
+}
+
+// Location returns the configured timezone, the system zone when Timezone is empty
+func (c *Config) Location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TIMEZONE %q: %w", c.Timezone, err)
+	}
+	return loc, nil
+}
+
+// AdminChatIDs returns the chats listed in AUTHORIZED_CHAT_IDS; group chats have negative IDs
+func (c *Config) AdminChatIDs() ([]int64, error) {
+	var ids []int64
+	for _, part := range strings.Split(c.AuthorizedChatIDs, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("invalid chat ID %q in AUTHORIZED_CHAT_IDS: want a number like 123456789 or -1001234567890", part)
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
 	}
+	return ids, nil
+}
 
+// ExitScanners returns the scanner MACs listed in EXIT_SCANNER_MACS
+func (c *Config) ExitScanners() []string {
+	var macs []string
+	for _, mac := range strings.Split(c.ExitScannerMACs, ",") {
+		if mac = strings.TrimSpace(mac); mac != "" {
+			macs = append(macs, mac)
+		}
+	}
+	return macs
+}
+
+// fromEnv builds the configuration from an environment lookup
+func fromEnv(get envSource) *Config {
 	return &Config{
-		PocketBaseURL:    pbURL,
-		PocketBaseToken:  os.Getenv("POCKETBASE_TOKEN"),
-		TelegramBotToken: os.Getenv("TELEGRAM_BOT_TOKEN"),
-		AuthorizedChatID: os.Getenv("AUTHORIZED_CHAT_ID"),
-	}, nil
+		PocketBaseURL:     get.getEnv("POCKETBASE_URL", "http://192.168.100.100:8090"), // Default external server
+		PocketBaseToken:   get("POCKETBASE_TOKEN"),
+		Mode:              get.getEnv("MODE", ModeTelegram),
+		TelegramBotToken:  get("TELEGRAM_BOT_TOKEN"),
+		AuthorizedChatIDs: get.getEnv("AUTHORIZED_CHAT_IDS", get("AUTHORIZED_CHAT_ID")),
+		AdminLanguage:     get.getEnv("ADMIN_LANGUAGE", "th"),
+		Notifiers:         get.getEnv("NOTIFIERS", NotifierTelegram),
+		NotifyWebhookURL:  get("NOTIFY_WEBHOOK_URL"),
+
+		LogLevel:      get.getEnv("LOG_LEVEL", "info"),
+		LogFormat:     get.getEnv("LOG_FORMAT", "text"),
+		LogHTTPBodies: get.getEnvBool("LOG_HTTP_BODIES", false),
+
+		PocketBaseAdminEmail:    get("POCKETBASE_ADMIN_EMAIL"),
+		PocketBaseAdminPassword: get("POCKETBASE_ADMIN_PASSWORD"),
+
+		PayloadProfiles:      get("PAYLOAD_PROFILES"),
+		PayloadProfileKeys:   get("PAYLOAD_PROFILE_KEYS"),
+		DetectRateLimit:      get.getEnvInt("DETECT_RATE_LIMIT", 0),
+		DetectMaxBodyBytes:   get.getEnvInt("DETECT_MAX_BODY_BYTES", 64<<10),
+		DetectWorkers:        get.getEnvInt("DETECT_WORKERS", 0),
+		DetectQueueSize:      get.getEnvInt("DETECT_QUEUE_SIZE", 1000),
+		FreeformScannerIDs:   get.getEnvBool("ALLOW_FREEFORM_SCANNER_IDS", false),
+		RequireScannerTokens: get.getEnvBool("REQUIRE_SCANNER_TOKENS", false),
+
+		DataDir:    get.getEnv("DATA_DIR", "data"),
+		LocalStore: get.getEnv("LOCAL_STORE", "file"),
+		Timezone:   get.getEnv("TIMEZONE", "Asia/Bangkok"),
+
+		StorageBackend: get.getEnv("STORAGE_BACKEND", StoragePocketBase),
+		SQLitePath:     get("SQLITE_PATH"),
+
+		RSSIThreshold: get.getEnvRSSI("RSSI_THRESHOLD", -70),
+
+		ScannerProfilesFile: get("SCANNER_PROFILES_FILE"),
+
+		SmoothingMinDetections: get.getEnvInt("SMOOTHING_MIN_DETECTIONS", 1),
+		SmoothingWindow:        get.getEnvDuration("SMOOTHING_WINDOW", 2*time.Minute),
+		RSSISmoothingAlpha:     get.getEnvFloat("RSSI_SMOOTHING_ALPHA", 0),
+		RSSISmoothingIdle:      get.getEnvDuration("RSSI_SMOOTHING_IDLE", 5*time.Minute),
+
+		GracePeriodMinutes: get.getEnvInt("GRACE_PERIOD_MINUTES", 5),
+
+		WorkDays: get("WORK_DAYS"),
+
+		CheckInWindowStart: get("CHECKIN_WINDOW_START"),
+		CheckInWindowEnd:   get("CHECKIN_WINDOW_END"),
+
+		RecentDetectionTTL: get.getEnvDuration("RECENT_DETECTION_TTL", 60*time.Second),
+
+		PresenceLogInterval: get.getEnvDuration("PRESENCE_LOG_INTERVAL", 5*time.Minute),
+
+		UnknownDeviceCapture:        get.getEnvBool("UNKNOWN_DEVICE_CAPTURE", false),
+		UnknownDeviceMinRSSI:        get.getEnvRSSI("UNKNOWN_DEVICE_MIN_RSSI", -60),
+		UnknownDeviceSampleInterval: get.getEnvDuration("UNKNOWN_DEVICE_SAMPLE_INTERVAL", 5*time.Minute),
+
+		WhitelistOnly: get.getEnvBool("WHITELIST_ONLY", false),
+
+		PocketBaseRetryAttempts: get.getEnvInt("POCKETBASE_RETRY_ATTEMPTS", 3),
+		PocketBaseTimeout:       get.getEnvDuration("POCKETBASE_TIMEOUT", 5*time.Second),
+
+		EmployeeCacheTTL:     get.getEnvDuration("EMPLOYEE_CACHE_TTL", 5*time.Minute),
+		EmployeeCacheMissTTL: get.getEnvDuration("EMPLOYEE_CACHE_MISS_TTL", time.Minute),
+
+		EndOfDayTime:     get.getEnv("END_OF_DAY_TIME", "23:30"),
+		AutoCheckOutTime: get("AUTO_CHECKOUT_TIME"),
+
+		StationaryTagEveningStart:  get.getEnv("STATIONARY_TAG_EVENING_START", "20:00"),
+		StationaryTagConfirmations: get.getEnvInt("STATIONARY_TAG_CONFIRMATIONS", 3),
+
+		DailySummaryEnabled: get.getEnvBool("DAILY_SUMMARY_ENABLED", true),
+		MorningReportTime:   get.getEnv("MORNING_REPORT_TIME", "10:00"),
+		AnomalyMADThreshold: get.getEnvFloat("ANOMALY_MAD_THRESHOLD", 3),
+
+		DepartmentZones:           get.getEnv("DEPARTMENT_ZONES", ""),
+		DepartmentInferenceWindow: get.getEnvDuration("DEPARTMENT_INFERENCE_WINDOW", 14*24*time.Hour),
+
+		InactivityPolicyEnabled:  get.getEnvBool("INACTIVITY_POLICY_ENABLED", false),
+		InactivityFlagDays:       get.getEnvInt("INACTIVITY_FLAG_DAYS", 30),
+		InactivityDeactivateDays: get.getEnvInt("INACTIVITY_DEACTIVATE_DAYS", 90),
+
+		TemplatesDir: get("TEMPLATES_DIR"),
+
+		CheckOutTrackingEnabled: get.getEnvBool("CHECKOUT_TRACKING_ENABLED", true),
+		CheckOutAfter:           get.getEnv("CHECKOUT_AFTER", "16:00"),
+
+		CheckOutReminderEnabled: get.getEnvBool("CHECKOUT_REMINDER_ENABLED", true),
+		WorkEndTime:             get("WORK_END_TIME"),
+		CheckOutReminderDelay:   get.getEnvDuration("CHECKOUT_REMINDER_DELAY", 30*time.Minute),
+		CheckOutReminderSnooze:  get.getEnvDuration("CHECKOUT_REMINDER_SNOOZE", 2*time.Hour),
+		ExitScannerMACs:         get("EXIT_SCANNER_MACS"),
+
+		PairingCodeTTL:      get.getEnvDuration("PAIRING_CODE_TTL", 10*time.Minute),
+		DevicePairingWindow: get.getEnvDuration("DEVICE_PAIRING_WINDOW", 60*time.Second),
+
+		PublicBoardCIDRs:     get("PUBLIC_BOARD_CIDRS"),
+		PublicBoardRateLimit: get.getEnvInt("PUBLIC_BOARD_RATE_LIMIT", 12),
+
+		DashboardAPIToken: get("DASHBOARD_API_TOKEN"),
+		AllowedOrigins:    get("ALLOWED_ORIGINS"),
+
+		TenantsFile: get("TENANTS_FILE"),
+
+		SelfTestInterval: get.getEnvDuration("SELF_TEST_INTERVAL", 0),
+		SelfTestDeadline: get.getEnvDuration("SELF_TEST_DEADLINE", 30*time.Second),
+		SelfTestMAC:      get("SELF_TEST_MAC"),
+
+		AlertInterval:             get.getEnvDuration("ALERT_INTERVAL", time.Minute),
+		ScannerOfflineAfter:       get.getEnvDuration("SCANNER_OFFLINE_AFTER", 10*time.Minute),
+		AlertQueueDepth:           get.getEnvInt("ALERT_QUEUE_DEPTH", 100),
+		AlertNotificationFailures: get.getEnvInt("ALERT_NOTIFICATION_FAILURES", 5),
+		AlertNotificationWindow:   get.getEnvDuration("ALERT_NOTIFICATION_WINDOW", 15*time.Minute),
+
+		QueueHardCap: get.getEnvInt("QUEUE_HARD_CAP", 20000),
+
+		LeaderElection: get.getEnvBool("LEADER_ELECTION", false),
+		InstanceID:     get("INSTANCE_ID"),
+		LeaderLeaseTTL: get.getEnvDuration("LEADER_LEASE_TTL", 30*time.Second),
+
+		ReadOnly: get.getEnvBool("READ_ONLY", false),
+
+		EnableFaultInjection: get.getEnvBool("ENABLE_FAULT_INJECTION", false),
+		FaultInjectionToken:  get("FAULT_INJECTION_TOKEN"),
+	}
+}
+
+// envSource looks up an environment variable, returning "" when unset
+type envSource func(string) string
+
+// getEnv reads an environment variable, falling back to def when unset
+func (get envSource) getEnv(key, def string) string {
+	if val := get(key); val != "" {
+		return val
+	}
+	return def
+}
+
+// getEnvInt reads an integer environment variable, falling back to def when unset or invalid
+func (get envSource) getEnvInt(key string, def int) int {
+	val := get(key)
+	if val == "" {
+		return def
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(val))
+	if err != nil {
+		log.Printf("Invalid %s=%q, using default %d", key, val, def)
+		return def
+	}
+	return n
+}
+
+// getEnvRSSI reads a signal strength in dBm, falling back to def when unset or invalid.
+// RSSI is always negative, so a value written without its sign ("70") is taken as -70.
+func (get envSource) getEnvRSSI(key string, def int) int {
+	val := get(key)
+	if val == "" {
+		return def
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(val))
+	if err != nil || n == 0 || n < -127 || n > 127 {
+		log.Printf("Invalid %s=%q, using default %d", key, val, def)
+		return def
+	}
+	if n > 0 {
+		log.Printf("%s=%q has no sign, using %d", key, val, -n)
+		n = -n
+	}
+	return n
+}
+
+// getEnvFloat reads a float environment variable, falling back to def when unset or invalid
+func (get envSource) getEnvFloat(key string, def float64) float64 {
+	val := get(key)
+	if val == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+	if err != nil {
+		log.Printf("Invalid %s=%q, using default %v", key, val, def)
+		return def
+	}
+	return f
+}
+
+// getEnvDuration reads a duration environment variable (e.g. "90s", "2m"), falling back to def when unset or invalid
+func (get envSource) getEnvDuration(key string, def time.Duration) time.Duration {
+	val := get(key)
+	if val == "" {
+		return def
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(val))
+	if err != nil {
+		log.Printf("Invalid %s=%q, using default %s", key, val, def)
+		return def
+	}
+	return d
+}
+
+// getEnvBool reads a boolean environment variable, falling back to def when unset or invalid
+func (get envSource) getEnvBool(key string, def bool) bool {
+	val := get(key)
+	if val == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		log.Printf("Invalid %s=%q, using default %v", key, val, def)
+		return def
+	}
+	return b
 }
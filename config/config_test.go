@@ -0,0 +1,193 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestRSSIThreshold(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  int
+	}{
+		{"unset", "", -70},
+		{"negative", "-75", -75},
+		{"without sign", "75", -75},
+		{"spaces", " -80 ", -80},
+		{"not a number", "far", -70},
+		{"zero", "0", -70},
+		{"out of range", "-200", -70},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := fromEnv(func(key string) string {
+				if key == "RSSI_THRESHOLD" {
+					return tt.value
+				}
+				return ""
+			})
+			if cfg.RSSIThreshold != tt.want {
+				t.Errorf("RSSIThreshold = %d, want %d", cfg.RSSIThreshold, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocation(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"unset", "", "Asia/Bangkok", false},
+		{"IANA zone", "Europe/London", "Europe/London", false},
+		{"UTC", "UTC", "UTC", false},
+		{"unknown zone", "Asia/Atlantis", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := fromEnv(func(key string) string {
+				if key == "TIMEZONE" {
+					return tt.value
+				}
+				return ""
+			})
+			loc, err := cfg.Location()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Location() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && loc.String() != tt.want {
+				t.Errorf("Location() = %s, want %s", loc, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdminChatIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		ids     string
+		legacy  string
+		want    []int64
+		wantErr bool
+	}{
+		{"unset", "", "", nil, false},
+		{"older single chat", "", "123", []int64{123}, false},
+		{"list wins over the older setting", "123, -1001234567890", "999", []int64{123, -1001234567890}, false},
+		{"duplicates and empty entries", "123,,123,", "", []int64{123}, false},
+		{"not a number", "123,hr-group", "", nil, true},
+		{"zero", "0", "", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := fromEnv(func(key string) string {
+				switch key {
+				case "AUTHORIZED_CHAT_IDS":
+					return tt.ids
+				case "AUTHORIZED_CHAT_ID":
+					return tt.legacy
+				}
+				return ""
+			})
+			got, err := cfg.AdminChatIDs()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AdminChatIDs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("AdminChatIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckStorage(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		wantFile string
+		wantErr  bool
+	}{
+		{"unset", nil, "data/med-pulse.db", false},
+		{"sqlite in the data directory", map[string]string{"STORAGE_BACKEND": "sqlite", "DATA_DIR": "/var/lib/med-pulse"}, "/var/lib/med-pulse/med-pulse.db", false},
+		{"sqlite file", map[string]string{"STORAGE_BACKEND": "sqlite", "SQLITE_PATH": "/srv/attendance.db"}, "/srv/attendance.db", false},
+		{"unknown backend", map[string]string{"STORAGE_BACKEND": "postgres"}, "", true},
+		{"sqlite with tenants", map[string]string{"STORAGE_BACKEND": "sqlite", "TENANTS_FILE": "tenants.yaml"}, "", true},
+		{"sqlite with leader election", map[string]string{"STORAGE_BACKEND": "sqlite", "LEADER_ELECTION": "true"}, "", true},
+		{"sqlite with the queue in PocketBase", map[string]string{"STORAGE_BACKEND": "sqlite", "LOCAL_STORE": "pocketbase"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := fromEnv(func(key string) string { return tt.env[key] })
+			err := cfg.CheckStorage()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckStorage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && cfg.SQLiteFile() != tt.wantFile {
+				t.Errorf("SQLiteFile() = %s, want %s", cfg.SQLiteFile(), tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestMode(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          map[string]string
+		wantHeadless bool
+		wantErr      bool
+	}{
+		{"bot token", map[string]string{"TELEGRAM_BOT_TOKEN": "123:abc"}, false, false},
+		{"no bot token", nil, true, false},
+		{"headless with a bot token", map[string]string{"MODE": "headless", "TELEGRAM_BOT_TOKEN": "123:abc"}, true, false},
+		{"unknown mode", map[string]string{"MODE": "quiet"}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := fromEnv(func(key string) string { return tt.env[key] })
+			err := cfg.CheckMode()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckMode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && cfg.Headless() != tt.wantHeadless {
+				t.Errorf("Headless() = %v, want %v", cfg.Headless(), tt.wantHeadless)
+			}
+		})
+	}
+}
+
+func TestNotifierChannels(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    []string
+		wantErr bool
+	}{
+		{"default", nil, []string{"telegram"}, false},
+		{"telegram and webhook", map[string]string{"NOTIFIERS": "telegram, Webhook,telegram", "NOTIFY_WEBHOOK_URL": "http://hooks.local/x"},
+			[]string{"telegram", "webhook"}, false},
+		{"none", map[string]string{"NOTIFIERS": " "}, nil, false},
+		{"webhook without a URL", map[string]string{"NOTIFIERS": "webhook"}, nil, true},
+		{"unknown channel", map[string]string{"NOTIFIERS": "telegram,sms"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := fromEnv(func(key string) string { return tt.env[key] })
+			got, err := cfg.NotifierChannels()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NotifierChannels() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("NotifierChannels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
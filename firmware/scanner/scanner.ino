@@ -96,6 +96,7 @@ const char* getDeviceType(BLEAdvertisedDevice& dev) {
 void setup() {
   Serial.begin(115200);
   Serial.println("🚀 เริ่มต้น BLE Scanner...");
+  pinMode(ledPin, OUTPUT);
 
   // เชื่อมต่อ WiFi
   Serial.printf("📡 กำลังเชื่อมต่อ WiFi: %s\n", ssid);
@@ -160,6 +161,17 @@ void loop() {
     Serial.printf("📡 ส่งข้อมูล: %s | RSSI: %d | Type: %s (HTTP %d)\n",
                   mac, rssi, deviceType, httpCode);
 
+    // Backend ตอบ {"result":..,"matched":..,"checked_in":..}: กระพริบไฟเมื่อเช็คอินสำเร็จ
+    if (httpCode == 200 && http.getString().indexOf("\"checked_in\":true") >= 0) {
+      Serial.printf("✅ เช็คอินสำเร็จ: %s\n", mac);
+      for (int blink = 0; blink < 3; blink++) {
+        digitalWrite(ledPin, HIGH);
+        delay(100);
+        digitalWrite(ledPin, LOW);
+        delay(100);
+      }
+    }
+
     http.end();
   }
 
@@ -6,6 +6,8 @@ require (
 	github.com/go-telegram-bot-api/telegram-bot-api/v5 v5.5.1
 	github.com/joho/godotenv v1.5.1
 	github.com/pocketbase/pocketbase v0.36.1
+	gopkg.in/yaml.v3 v3.0.1
+	modernc.org/sqlite v1.44.2
 )
 
 require (
@@ -37,5 +39,4 @@ require (
 	modernc.org/libc v1.67.6 // indirect
 	modernc.org/mathutil v1.7.1 // indirect
 	modernc.org/memory v1.11.0 // indirect
-	modernc.org/sqlite v1.44.2 // indirect
 )
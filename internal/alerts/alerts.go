@@ -0,0 +1,168 @@
+// Package alerts evaluates the built-in alert conditions from in-memory state. The same
+// evaluation is served at /api/alerts for external monitors and sent to the admin chat.
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/markdown"
+)
+
+// Severity tells a monitor how urgently a firing alert should be handled
+type Severity string
+
+const (
+	SeverityCritical Severity = "critical"
+	SeverityWarning  Severity = "warning"
+)
+
+// Result is one evaluation of a rule's condition
+type Result struct {
+	Firing  bool
+	Since   time.Time              // when the condition began; zero uses the first evaluation that saw it
+	Context map[string]interface{} // details for whoever is paged
+}
+
+// Rule is a built-in alert condition
+type Rule struct {
+	Name      string
+	Severity  Severity
+	Summary   string // Thai description used in admin chat messages
+	Threshold string // human-readable firing threshold
+	Evaluate  func(now time.Time) Result
+}
+
+// Alert is the current state of a rule, as served by /api/alerts
+type Alert struct {
+	Name      string                 `json:"name"`
+	Severity  Severity               `json:"severity"`
+	Threshold string                 `json:"threshold"`
+	Firing    bool                   `json:"firing"`
+	Since     *time.Time             `json:"firing_since,omitempty"`
+	Context   map[string]interface{} `json:"context,omitempty"`
+}
+
+// AdminNotifier sends alert messages to the admin chat
+type AdminNotifier interface {
+	SendNotification(message string)
+}
+
+// Evaluator evaluates a fixed set of rules and remembers when each started firing
+type Evaluator struct {
+	rules []Rule
+	clock clock.Clock
+
+	mu       sync.Mutex
+	since    map[string]time.Time // rule name → first evaluation that saw it firing
+	notified map[string]bool      // rule name → firing state last sent to the admin chat
+}
+
+// NewEvaluator creates an evaluator for the rules, listed in the given order
+func NewEvaluator(rules ...Rule) *Evaluator {
+	return &Evaluator{
+		rules:    rules,
+		clock:    clock.Real{},
+		since:    make(map[string]time.Time),
+		notified: make(map[string]bool),
+	}
+}
+
+// SetClock replaces the time source, used by tests
+func (e *Evaluator) SetClock(c clock.Clock) {
+	e.clock = c
+}
+
+// Evaluate returns the current state of every rule, firing or not
+func (e *Evaluator) Evaluate() []Alert {
+	now := e.clock.Now()
+	alerts := make([]Alert, 0, len(e.rules))
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, rule := range e.rules {
+		res := rule.Evaluate(now)
+		alert := Alert{
+			Name:      rule.Name,
+			Severity:  rule.Severity,
+			Threshold: rule.Threshold,
+			Firing:    res.Firing,
+			Context:   res.Context,
+		}
+		if !res.Firing {
+			delete(e.since, rule.Name)
+			alerts = append(alerts, alert)
+			continue
+		}
+		since, ok := e.since[rule.Name]
+		if !ok {
+			since = now
+			e.since[rule.Name] = since
+		}
+		if !res.Since.IsZero() {
+			since = res.Since
+		}
+		alert.Since = &since
+		alerts = append(alerts, alert)
+	}
+	return alerts
+}
+
+// Firing reports whether any alert of the given severity is firing
+func Firing(alerts []Alert, severity Severity) bool {
+	for _, a := range alerts {
+		if a.Firing && a.Severity == severity {
+			return true
+		}
+	}
+	return false
+}
+
+// Start evaluates the rules every interval until ctx is done, telling the admin chat
+// when an alert starts firing and when it resolves
+func (e *Evaluator) Start(ctx context.Context, interval time.Duration, notifier AdminNotifier) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				e.Notify(notifier)
+			}
+		}
+	}()
+}
+
+// Notify evaluates the rules once and sends a message for every alert that changed
+// state since the last call
+func (e *Evaluator) Notify(notifier AdminNotifier) {
+	summaries := make(map[string]string, len(e.rules))
+	for _, rule := range e.rules {
+		summaries[rule.Name] = rule.Summary
+	}
+
+	for _, a := range e.Evaluate() {
+		e.mu.Lock()
+		changed := e.notified[a.Name] != a.Firing
+		e.notified[a.Name] = a.Firing
+		e.mu.Unlock()
+		if !changed {
+			continue
+		}
+
+		if a.Firing {
+			log.Printf("🚨 Alert %s firing (%s): %v", a.Name, a.Severity, a.Context)
+			notifier.SendNotification(fmt.Sprintf("🚨 %s\n\n%s\nเกณฑ์: %s\nตั้งแต่: `%s`",
+				markdown.Bold("แจ้งเตือน: "+a.Name), markdown.Escape(summaries[a.Name]), markdown.Code(a.Threshold), a.Since.Format("02/01 15:04")))
+			continue
+		}
+		log.Printf("✅ Alert %s resolved", a.Name)
+		notifier.SendNotification(fmt.Sprintf("✅ %s\n\n%s", markdown.Bold("แจ้งเตือนหายแล้ว: "+a.Name), markdown.Escape(summaries[a.Name])))
+	}
+}
@@ -0,0 +1,143 @@
+package alerts
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/status"
+)
+
+type fakeQueue int
+
+func (q fakeQueue) Len() int { return int(q) }
+
+type recordingNotifier struct {
+	messages []string
+}
+
+func (n *recordingNotifier) SendNotification(message string) {
+	n.messages = append(n.messages, message)
+}
+
+func TestRules(t *testing.T) {
+	start := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	errDown := errors.New("connection refused")
+
+	tests := []struct {
+		name       string
+		setup      func(s *status.SystemStatus, clk *clock.Fake)
+		queue      fakeQueue
+		wantFiring map[string]bool
+	}{
+		{
+			name:       "nothing wrong",
+			setup:      func(s *status.SystemStatus, clk *clock.Fake) { s.ScannerSeen("aa:bb:cc:dd:ee:01") },
+			wantFiring: map[string]bool{},
+		},
+		{
+			name: "backend down",
+			setup: func(s *status.SystemStatus, clk *clock.Fake) {
+				for i := 0; i < 3; i++ {
+					s.Record("pb:8090", errDown)
+				}
+			},
+			wantFiring: map[string]bool{PocketBaseDown: true},
+		},
+		{
+			name: "silent scanner",
+			setup: func(s *status.SystemStatus, clk *clock.Fake) {
+				s.ScannerSeen("aa:bb:cc:dd:ee:01")
+				clk.Advance(11 * time.Minute)
+			},
+			wantFiring: map[string]bool{ScannerOffline: true},
+		},
+		{
+			name:       "queue at threshold",
+			setup:      func(s *status.SystemStatus, clk *clock.Fake) {},
+			queue:      100,
+			wantFiring: map[string]bool{DetectionQueueDepth: true},
+		},
+		{
+			name: "failed sends",
+			setup: func(s *status.SystemStatus, clk *clock.Fake) {
+				for i := 0; i < 5; i++ {
+					s.RecordNotificationFailure(errors.New("chat not found"))
+				}
+			},
+			wantFiring: map[string]bool{NotificationFailures: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(start)
+			s := status.NewSystemStatus(3)
+			s.SetClock(clk)
+			tt.setup(s, clk)
+
+			e := NewEvaluator(
+				PocketBaseDownRule(s),
+				ScannerOfflineRule(s, 10*time.Minute),
+				DetectionQueueDepthRule(map[string]QueueLen{"default": tt.queue}, 100),
+				NotificationFailuresRule(s, 5, 15*time.Minute),
+			)
+			e.SetClock(clk)
+
+			got := e.Evaluate()
+			if len(got) != 4 {
+				t.Fatalf("got %d alerts, want all 4 listed", len(got))
+			}
+			for _, a := range got {
+				if a.Firing != tt.wantFiring[a.Name] {
+					t.Errorf("%s firing = %v, want %v", a.Name, a.Firing, tt.wantFiring[a.Name])
+				}
+				if a.Threshold == "" {
+					t.Errorf("%s has no threshold", a.Name)
+				}
+				if a.Firing != (a.Since != nil) {
+					t.Errorf("%s firing_since = %v with firing = %v", a.Name, a.Since, a.Firing)
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluatorSinceAndNotify(t *testing.T) {
+	start := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	depth := fakeQueue(0)
+	rule := Rule{
+		Name:      "queue",
+		Severity:  SeverityWarning,
+		Summary:   "คิวยาว",
+		Threshold: ">= 1",
+		Evaluate:  func(now time.Time) Result { return Result{Firing: depth > 0} },
+	}
+	e := NewEvaluator(rule)
+	e.SetClock(clk)
+	notifier := &recordingNotifier{}
+
+	e.Notify(notifier)
+	depth = 3
+	e.Notify(notifier)
+	clk.Advance(time.Minute)
+	e.Notify(notifier)
+
+	got := e.Evaluate()
+	if got[0].Since == nil || !got[0].Since.Equal(start) {
+		t.Errorf("firing_since = %v, want the first evaluation that saw it firing (%s)", got[0].Since, start)
+	}
+	if len(notifier.messages) != 1 || !strings.Contains(notifier.messages[0], "แจ้งเตือน: queue") {
+		t.Fatalf("messages = %q, want one firing alert", notifier.messages)
+	}
+
+	depth = 0
+	e.Notify(notifier)
+	e.Notify(notifier)
+	if len(notifier.messages) != 2 || !strings.Contains(notifier.messages[1], "หายแล้ว") {
+		t.Errorf("messages = %q, want a single resolved notice", notifier.messages)
+	}
+}
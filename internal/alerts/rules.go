@@ -0,0 +1,128 @@
+package alerts
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"med-pulse-bot/internal/status"
+)
+
+// Names of the built-in alerts
+const (
+	PocketBaseDown       = "pocketbase_down"
+	ScannerOffline       = "scanner_offline"
+	DetectionQueueDepth  = "detection_queue_depth"
+	NotificationFailures = "notification_failures"
+)
+
+// QueueLen is a queue whose depth is watched, e.g. a services.DetectionQueue
+type QueueLen interface {
+	Len() int
+}
+
+// PocketBaseDownRule fires while a PocketBase backend is marked down, i.e. while its
+// calls are being failed fast
+func PocketBaseDownRule(s *status.SystemStatus) Rule {
+	return Rule{
+		Name:      PocketBaseDown,
+		Severity:  SeverityCritical,
+		Summary:   "PocketBase ติดต่อไม่ได้ ระบบบันทึกเวลาเข้างานไม่ได้",
+		Threshold: fmt.Sprintf("%d consecutive failed calls", s.FailureThreshold()),
+		Evaluate: func(now time.Time) Result {
+			var res Result
+			var down []map[string]interface{}
+			for _, b := range s.Backends() {
+				if b.Healthy {
+					continue
+				}
+				down = append(down, map[string]interface{}{"host": b.Host, "reason": b.Reason})
+				if !res.Firing || b.Since.Before(res.Since) {
+					res.Since = b.Since
+				}
+				res.Firing = true
+			}
+			if res.Firing {
+				res.Context = map[string]interface{}{"backends": down}
+			}
+			return res
+		},
+	}
+}
+
+// ScannerOfflineRule fires while a scanner heard from since startup has been silent for
+// longer than after
+func ScannerOfflineRule(s *status.SystemStatus, after time.Duration) Rule {
+	return Rule{
+		Name:      ScannerOffline,
+		Severity:  SeverityWarning,
+		Summary:   "Scanner ไม่ส่งข้อมูลเข้ามา",
+		Threshold: fmt.Sprintf("no detection or heartbeat for %s", after),
+		Evaluate: func(now time.Time) Result {
+			var res Result
+			var offline []map[string]interface{}
+			for _, sc := range s.Scanners() {
+				if now.Sub(sc.LastSeen) <= after {
+					continue
+				}
+				offline = append(offline, map[string]interface{}{"scanner_mac": sc.Mac, "last_seen": sc.LastSeen})
+				if since := sc.LastSeen.Add(after); !res.Firing || since.Before(res.Since) {
+					res.Since = since
+				}
+				res.Firing = true
+			}
+			if res.Firing {
+				res.Context = map[string]interface{}{"scanners": offline}
+			}
+			return res
+		},
+	}
+}
+
+// DetectionQueueDepthRule fires while the detections held back in read-only mode reach
+// threshold in any of the named queues
+func DetectionQueueDepthRule(queues map[string]QueueLen, threshold int) Rule {
+	names := make([]string, 0, len(queues))
+	for name := range queues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return Rule{
+		Name:      DetectionQueueDepth,
+		Severity:  SeverityWarning,
+		Summary:   "มีการตรวจจับค้างอยู่ในคิวจำนวนมาก",
+		Threshold: fmt.Sprintf(">= %d queued detections", threshold),
+		Evaluate: func(now time.Time) Result {
+			var res Result
+			depths := make(map[string]interface{}, len(names))
+			for _, name := range names {
+				n := queues[name].Len()
+				depths[name] = n
+				if n >= threshold {
+					res.Firing = true
+				}
+			}
+			res.Context = map[string]interface{}{"depth": depths}
+			return res
+		},
+	}
+}
+
+// NotificationFailuresRule fires while at least threshold Telegram sends failed within window
+func NotificationFailuresRule(s *status.SystemStatus, threshold int, window time.Duration) Rule {
+	return Rule{
+		Name:      NotificationFailures,
+		Severity:  SeverityWarning,
+		Summary:   "ส่งข้อความ Telegram ไม่สำเร็จหลายครั้ง",
+		Threshold: fmt.Sprintf(">= %d failed sends in %s", threshold, window),
+		Evaluate: func(now time.Time) Result {
+			n, lastErr := s.NotificationFailures(window)
+			res := Result{Firing: n >= threshold, Context: map[string]interface{}{"failures": n}}
+			if lastErr != "" {
+				res.Context["last_error"] = lastErr
+			}
+			return res
+		},
+	}
+}
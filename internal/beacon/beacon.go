@@ -0,0 +1,72 @@
+// Package beacon normalizes the identities BLE beacons advertise, so that what a scanner
+// reports matches what was registered for an employee however either was typed
+package beacon
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalidUUID is returned when an iBeacon UUID is not 32 hex digits
+	ErrInvalidUUID = errors.New("iBeacon UUID must contain exactly 32 hex digits")
+	// ErrInvalidMajorMinor is returned when an iBeacon major or minor does not fit in 16 bits
+	ErrInvalidMajorMinor = errors.New("iBeacon major and minor must be 0-65535")
+	// ErrInvalidNamespace is returned when an Eddystone-UID namespace is not 20 hex digits
+	ErrInvalidNamespace = errors.New("Eddystone namespace ID must contain exactly 20 hex digits")
+	// ErrInvalidInstance is returned when an Eddystone-UID instance is not 12 hex digits
+	ErrInvalidInstance = errors.New("Eddystone instance ID must contain exactly 12 hex digits")
+)
+
+// NormalizeUUID converts an iBeacon proximity UUID into the canonical lower-case
+// 8-4-4-4-12 form; dashes and surrounding whitespace in the input are ignored
+func NormalizeUUID(input string) (string, error) {
+	digits, ok := hexDigits(input, 32)
+	if !ok {
+		return "", ErrInvalidUUID
+	}
+	return digits[0:8] + "-" + digits[8:12] + "-" + digits[12:16] + "-" + digits[16:20] + "-" + digits[20:32], nil
+}
+
+// IBeaconID is the identity of an iBeacon as stored in employees.ibeacon_id:
+// "<uuid>:<major>:<minor>" with the UUID normalized
+func IBeaconID(uuid string, major, minor int) (string, error) {
+	id, err := NormalizeUUID(uuid)
+	if err != nil {
+		return "", err
+	}
+	if major < 0 || major > 0xFFFF || minor < 0 || minor > 0xFFFF {
+		return "", ErrInvalidMajorMinor
+	}
+	return fmt.Sprintf("%s:%d:%d", id, major, minor), nil
+}
+
+// EddystoneID is the identity of an Eddystone-UID beacon as stored in
+// employees.eddystone_id: "<namespace>:<instance>", both lower-case hex without dashes
+func EddystoneID(namespace, instance string) (string, error) {
+	ns, ok := hexDigits(strings.TrimPrefix(strings.TrimSpace(namespace), "0x"), 20)
+	if !ok {
+		return "", ErrInvalidNamespace
+	}
+	inst, ok := hexDigits(strings.TrimPrefix(strings.TrimSpace(instance), "0x"), 12)
+	if !ok {
+		return "", ErrInvalidInstance
+	}
+	return ns + ":" + inst, nil
+}
+
+// hexDigits returns the lower-cased hex digits of input without dashes, and whether there
+// are exactly n of them and nothing else
+func hexDigits(input string, n int) (string, bool) {
+	digits := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(input), "-", ""))
+	if len(digits) != n {
+		return "", false
+	}
+	for _, r := range digits {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f') {
+			return "", false
+		}
+	}
+	return digits, true
+}
@@ -0,0 +1,63 @@
+package beacon
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIBeaconID(t *testing.T) {
+	const want = "fda50693-a4e2-4fb1-afcf-c6eb07647825:1:42"
+
+	tests := []struct {
+		name         string
+		uuid         string
+		major, minor int
+		want         string
+		wantErr      error
+	}{
+		{"canonical", "fda50693-a4e2-4fb1-afcf-c6eb07647825", 1, 42, want, nil},
+		{"upper case", "FDA50693-A4E2-4FB1-AFCF-C6EB07647825", 1, 42, want, nil},
+		{"no dashes", "FDA50693A4E24FB1AFCFC6EB07647825", 1, 42, want, nil},
+		{"surrounding whitespace", " fda50693-a4e2-4fb1-afcf-c6eb07647825\n", 1, 42, want, nil},
+		{"largest major and minor", "fda50693-a4e2-4fb1-afcf-c6eb07647825", 65535, 0, "fda50693-a4e2-4fb1-afcf-c6eb07647825:65535:0", nil},
+		{"too short", "fda50693-a4e2-4fb1-afcf-c6eb0764782", 1, 42, "", ErrInvalidUUID},
+		{"not hex", "gda50693-a4e2-4fb1-afcf-c6eb07647825", 1, 42, "", ErrInvalidUUID},
+		{"empty", "", 1, 42, "", ErrInvalidUUID},
+		{"major too large", "fda50693-a4e2-4fb1-afcf-c6eb07647825", 65536, 42, "", ErrInvalidMajorMinor},
+		{"negative minor", "fda50693-a4e2-4fb1-afcf-c6eb07647825", 1, -1, "", ErrInvalidMajorMinor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IBeaconID(tt.uuid, tt.major, tt.minor)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("IBeaconID(%q, %d, %d) = %q, %v, want %q, %v", tt.uuid, tt.major, tt.minor, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEddystoneID(t *testing.T) {
+	const want = "edd1ebeac04e5defa017:0123456789ab"
+
+	tests := []struct {
+		name                string
+		namespace, instance string
+		want                string
+		wantErr             error
+	}{
+		{"canonical", "edd1ebeac04e5defa017", "0123456789ab", want, nil},
+		{"upper case", "EDD1EBEAC04E5DEFA017", "0123456789AB", want, nil},
+		{"0x prefix", "0xEDD1EBEAC04E5DEFA017", "0x0123456789AB", want, nil},
+		{"short namespace", "edd1ebeac04e5defa0", "0123456789ab", "", ErrInvalidNamespace},
+		{"instance not hex", "edd1ebeac04e5defa017", "0123456789az", "", ErrInvalidInstance},
+		{"no instance", "edd1ebeac04e5defa017", "", "", ErrInvalidInstance},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EddystoneID(tt.namespace, tt.instance)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("EddystoneID(%q, %q) = %q, %v, want %q, %v", tt.namespace, tt.instance, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
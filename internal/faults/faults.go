@@ -0,0 +1,147 @@
+// Package faults provides opt-in fault injection for resilience testing.
+//
+// Nothing in this package is active unless ENABLE_FAULT_INJECTION=true is set:
+// main only wraps the PocketBase transport and the notifier, and only registers
+// the /debug/faults endpoint, when the flag is on.
+package faults
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Components that can have faults injected
+const (
+	ComponentPocketBase = "pocketbase"
+	ComponentNotifier   = "notifier"
+)
+
+var (
+	// ErrInjected is returned when a request fails because of an injected error rate
+	ErrInjected = errors.New("injected fault")
+	// ErrOutage is returned while a component is in an injected full outage
+	ErrOutage = errors.New("injected outage")
+)
+
+// Fault describes the misbehaviour injected into a single component
+type Fault struct {
+	Component string        `json:"component"`
+	Latency   time.Duration `json:"-"`
+	LatencyMS int64         `json:"latency_ms"`
+	ErrorRate float64       `json:"error_rate"` // 0.0 - 1.0
+	Outage    bool          `json:"outage"`
+	ExpiresAt time.Time     `json:"expires_at"`
+}
+
+// Injector holds the active faults per component
+type Injector struct {
+	mu     sync.Mutex
+	faults map[string]Fault
+	now    func() time.Time
+	rand   func() float64
+}
+
+// NewInjector creates an injector with no active faults
+func NewInjector() *Injector {
+	return &Injector{
+		faults: make(map[string]Fault),
+		now:    time.Now,
+		rand:   rand.Float64,
+	}
+}
+
+// Set activates a fault for a component for the given duration, replacing any existing one
+func (i *Injector) Set(f Fault, duration time.Duration) error {
+	if f.Component != ComponentPocketBase && f.Component != ComponentNotifier {
+		return fmt.Errorf("unknown component %q", f.Component)
+	}
+	if f.ErrorRate < 0 || f.ErrorRate > 1 {
+		return fmt.Errorf("error_rate must be between 0 and 1, got %v", f.ErrorRate)
+	}
+	if f.Latency < 0 {
+		return fmt.Errorf("latency must not be negative")
+	}
+	if duration <= 0 {
+		return fmt.Errorf("duration must be positive")
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	f.LatencyMS = f.Latency.Milliseconds()
+	f.ExpiresAt = i.now().Add(duration)
+	i.faults[f.Component] = f
+	return nil
+}
+
+// Clear removes the fault for a component
+func (i *Injector) Clear(component string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.faults, component)
+}
+
+// Active returns all non-expired faults sorted by component
+func (i *Injector) Active() []Fault {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	now := i.now()
+	active := make([]Fault, 0, len(i.faults))
+	for name, f := range i.faults {
+		if !now.Before(f.ExpiresAt) {
+			delete(i.faults, name)
+			continue
+		}
+		active = append(active, f)
+	}
+	sort.Slice(active, func(a, b int) bool { return active[a].Component < active[b].Component })
+	return active
+}
+
+// Apply enforces the active fault for a component: it sleeps for the injected
+// latency (honouring ctx) and then returns ErrOutage or ErrInjected if the call should fail
+func (i *Injector) Apply(ctx context.Context, component string) error {
+	f, ok := i.lookup(component)
+	if !ok {
+		return nil
+	}
+
+	if f.Latency > 0 {
+		timer := time.NewTimer(f.Latency)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if f.Outage {
+		return ErrOutage
+	}
+	if f.ErrorRate > 0 && i.rand() < f.ErrorRate {
+		return ErrInjected
+	}
+	return nil
+}
+
+func (i *Injector) lookup(component string) (Fault, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	f, ok := i.faults[component]
+	if !ok {
+		return Fault{}, false
+	}
+	if !i.now().Before(f.ExpiresAt) {
+		delete(i.faults, component)
+		return Fault{}, false
+	}
+	return f, true
+}
@@ -0,0 +1,194 @@
+package faults
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/repository"
+)
+
+func newTestInjector(now *time.Time) *Injector {
+	i := NewInjector()
+	i.now = func() time.Time { return *now }
+	i.rand = func() float64 { return 0.5 }
+	return i
+}
+
+func TestInjectorSet(t *testing.T) {
+	tests := []struct {
+		name     string
+		fault    Fault
+		duration time.Duration
+		wantErr  bool
+	}{
+		{"Valid outage", Fault{Component: ComponentPocketBase, Outage: true}, time.Minute, false},
+		{"Valid error rate", Fault{Component: ComponentNotifier, ErrorRate: 0.3}, time.Minute, false},
+		{"Unknown component", Fault{Component: "telegram"}, time.Minute, true},
+		{"Error rate above 1", Fault{Component: ComponentPocketBase, ErrorRate: 1.5}, time.Minute, true},
+		{"Negative latency", Fault{Component: ComponentPocketBase, Latency: -time.Second}, time.Minute, true},
+		{"Zero duration", Fault{Component: ComponentPocketBase, Outage: true}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewInjector().Set(tt.fault, tt.duration)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Set() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInjectorApply(t *testing.T) {
+	now := time.Date(2026, 2, 1, 8, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		fault   *Fault
+		wantErr error
+	}{
+		{"No fault", nil, nil},
+		{"Outage", &Fault{Component: ComponentPocketBase, Outage: true}, ErrOutage},
+		{"Error rate hit", &Fault{Component: ComponentPocketBase, ErrorRate: 0.9}, ErrInjected},
+		{"Error rate miss", &Fault{Component: ComponentPocketBase, ErrorRate: 0.1}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := newTestInjector(&now)
+			if tt.fault != nil {
+				if err := i.Set(*tt.fault, time.Minute); err != nil {
+					t.Fatalf("Set() error = %v", err)
+				}
+			}
+
+			err := i.Apply(context.Background(), ComponentPocketBase)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Apply() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInjectorExpiry(t *testing.T) {
+	now := time.Date(2026, 2, 1, 8, 0, 0, 0, time.UTC)
+	i := newTestInjector(&now)
+
+	if err := i.Set(Fault{Component: ComponentPocketBase, Outage: true}, 30*time.Second); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got := len(i.Active()); got != 1 {
+		t.Fatalf("Active() = %d faults, want 1", got)
+	}
+
+	now = now.Add(30 * time.Second)
+	if err := i.Apply(context.Background(), ComponentPocketBase); err != nil {
+		t.Errorf("Apply() after expiry error = %v, want nil", err)
+	}
+	if got := len(i.Active()); got != 0 {
+		t.Errorf("Active() after expiry = %d faults, want 0", got)
+	}
+}
+
+func TestInjectorLatencyHonoursContext(t *testing.T) {
+	i := NewInjector()
+	if err := i.Set(Fault{Component: ComponentPocketBase, Latency: time.Hour}, time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := i.Apply(ctx, ComponentPocketBase)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Apply() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Apply() took %v, want prompt return on cancellation", elapsed)
+	}
+}
+
+// TestRepositoryUnderInjectedFaults verifies that injected PocketBase faults surface
+// as errors from the real REST repositories and that they recover once cleared
+func TestRepositoryUnderInjectedFaults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"items":[{"id":"emp1","name":"Somchai","mac_address":"11:22:33:44:55:66","is_active":true}]}`))
+	}))
+	defer server.Close()
+
+	injector := NewInjector()
+	repository.SetTransport(NewTransport(http.DefaultTransport, injector, ComponentPocketBase))
+	defer repository.SetTransport(http.DefaultTransport)
+	repo := repository.NewPocketBaseRESTEmployeeRepository(server.URL)
+
+	tests := []struct {
+		name    string
+		fault   *Fault
+		wantErr bool
+	}{
+		{"Healthy", nil, false},
+		{"Full outage", &Fault{Component: ComponentPocketBase, Outage: true}, true},
+		{"Every request fails with 503", &Fault{Component: ComponentPocketBase, ErrorRate: 1}, true},
+		{"Latency only", &Fault{Component: ComponentPocketBase, Latency: time.Millisecond}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			injector.Clear(ComponentPocketBase)
+			if tt.fault != nil {
+				if err := injector.Set(*tt.fault, time.Minute); err != nil {
+					t.Fatalf("Set() error = %v", err)
+				}
+			}
+
+			emp, err := repo.GetByMacAddress(context.Background(), "11:22:33:44:55:66")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetByMacAddress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && emp.ID != "emp1" {
+				t.Errorf("GetByMacAddress() ID = %v, want emp1", emp.ID)
+			}
+		})
+	}
+}
+
+type recordingNotifier struct {
+	admin    []string
+	personal []string
+}
+
+func (r *recordingNotifier) SendNotification(message string) {
+	r.admin = append(r.admin, message)
+}
+
+func (r *recordingNotifier) SendPersonalNotification(chatID int64, message string) {
+	r.personal = append(r.personal, message)
+}
+
+func TestNotifierUnderInjectedOutage(t *testing.T) {
+	injector := NewInjector()
+	next := &recordingNotifier{}
+	n := NewNotifier(next, injector)
+
+	n.SendPersonalNotification(1, "before")
+	if err := injector.Set(Fault{Component: ComponentNotifier, Outage: true}, time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	n.SendPersonalNotification(1, "during")
+	n.SendNotification("during")
+	injector.Clear(ComponentNotifier)
+	n.SendNotification("after")
+
+	if len(next.personal) != 1 || next.personal[0] != "before" {
+		t.Errorf("personal = %v, want [before]", next.personal)
+	}
+	if len(next.admin) != 1 || next.admin[0] != "after" {
+		t.Errorf("admin = %v, want [after]", next.admin)
+	}
+}
@@ -0,0 +1,81 @@
+package faults
+
+import (
+	"context"
+	"log"
+
+	"med-pulse-bot/internal/models"
+)
+
+// notifier mirrors services.BotNotifier without importing the services package
+type notifier interface {
+	SendNotification(message string)
+	SendPersonalNotification(chatID int64, message string)
+}
+
+// prompter mirrors services.PromptNotifier
+type prompter interface {
+	SendPersonalPrompt(chatID int64, message string, buttons []models.PromptButton)
+}
+
+// adminPrompter mirrors services.AdminPromptNotifier
+type adminPrompter interface {
+	SendPrompt(message string, buttons []models.PromptButton)
+}
+
+// Notifier wraps a bot notifier and drops messages while a notifier fault is active
+type Notifier struct {
+	next     notifier
+	injector *Injector
+}
+
+// NewNotifier wraps next with fault injection for the notifier component
+func NewNotifier(next notifier, injector *Injector) *Notifier {
+	return &Notifier{next: next, injector: injector}
+}
+
+// SendNotification sends an admin notification unless a fault is injected
+func (n *Notifier) SendNotification(message string) {
+	if err := n.injector.Apply(context.Background(), ComponentNotifier); err != nil {
+		log.Printf("💥 Dropped admin notification: %v", err)
+		return
+	}
+	n.next.SendNotification(message)
+}
+
+// SendPersonalNotification sends a personal notification unless a fault is injected
+func (n *Notifier) SendPersonalNotification(chatID int64, message string) {
+	if err := n.injector.Apply(context.Background(), ComponentNotifier); err != nil {
+		log.Printf("💥 Dropped personal notification: %v", err)
+		return
+	}
+	n.next.SendPersonalNotification(chatID, message)
+}
+
+// SendPersonalPrompt sends a personal prompt unless a fault is injected; without
+// prompt support in the wrapped notifier it is sent as a plain personal notification
+func (n *Notifier) SendPersonalPrompt(chatID int64, message string, buttons []models.PromptButton) {
+	if err := n.injector.Apply(context.Background(), ComponentNotifier); err != nil {
+		log.Printf("💥 Dropped personal prompt: %v", err)
+		return
+	}
+	if p, ok := n.next.(prompter); ok {
+		p.SendPersonalPrompt(chatID, message, buttons)
+		return
+	}
+	n.next.SendPersonalNotification(chatID, message)
+}
+
+// SendPrompt sends an admin prompt unless a fault is injected; without prompt support in
+// the wrapped notifier it is sent as a plain admin notification
+func (n *Notifier) SendPrompt(message string, buttons []models.PromptButton) {
+	if err := n.injector.Apply(context.Background(), ComponentNotifier); err != nil {
+		log.Printf("💥 Dropped admin prompt: %v", err)
+		return
+	}
+	if p, ok := n.next.(adminPrompter); ok {
+		p.SendPrompt(message, buttons)
+		return
+	}
+	n.next.SendNotification(message)
+}
@@ -0,0 +1,48 @@
+package faults
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Transport is an http.RoundTripper that injects faults before forwarding requests.
+// An injected outage surfaces as a transport error (like a refused connection) while
+// an injected error rate surfaces as a synthetic 503 response from the server.
+type Transport struct {
+	next      http.RoundTripper
+	injector  *Injector
+	component string
+}
+
+// NewTransport wraps next with fault injection for the given component
+func NewTransport(next http.RoundTripper, injector *Injector, component string) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{next: next, injector: injector, component: component}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.injector.Apply(req.Context(), t.component); err != nil {
+		if errors.Is(err, ErrInjected) {
+			body := `{"code":503,"message":"Injected fault.","data":{}}`
+			return &http.Response{
+				Status:        fmt.Sprintf("%d %s", http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable)),
+				StatusCode:    http.StatusServiceUnavailable,
+				Proto:         "HTTP/1.1",
+				ProtoMajor:    1,
+				ProtoMinor:    1,
+				Header:        http.Header{"Content-Type": []string{"application/json"}},
+				Body:          io.NopCloser(strings.NewReader(body)),
+				ContentLength: int64(len(body)),
+				Request:       req,
+			}, nil
+		}
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL.Path, err)
+	}
+	return t.next.RoundTrip(req)
+}
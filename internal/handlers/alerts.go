@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+
+	"med-pulse-bot/internal/alerts"
+)
+
+// AlertsHandler serves the state of the built-in alerts for external monitors
+type AlertsHandler struct {
+	evaluator *alerts.Evaluator
+}
+
+// NewAlertsHandler creates a handler serving the evaluator's alerts
+func NewAlertsHandler(evaluator *alerts.Evaluator) *AlertsHandler {
+	return &AlertsHandler{evaluator: evaluator}
+}
+
+// alertsResponse is the JSON body returned by /api/alerts
+type alertsResponse struct {
+	Status string         `json:"status"` // "ok", "warning" or "critical"
+	Alerts []alerts.Alert `json:"alerts"`
+}
+
+// HandleAlerts lists every alert, firing or not. It answers 503 while a critical alert
+// fires so a plain HTTP check can page on it. Only in-memory state is read.
+func (h *AlertsHandler) HandleAlerts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	list := h.evaluator.Evaluate()
+	resp := alertsResponse{Status: "ok", Alerts: list}
+	code := http.StatusOK
+	switch {
+	case alerts.Firing(list, alerts.SeverityCritical):
+		resp.Status = "critical"
+		code = http.StatusServiceUnavailable
+	case alerts.Firing(list, alerts.SeverityWarning):
+		resp.Status = "warning"
+	}
+	writeJSON(w, code, resp)
+}
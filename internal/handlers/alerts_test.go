@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/alerts"
+	"med-pulse-bot/internal/status"
+)
+
+type fixedQueue int
+
+func (q fixedQueue) Len() int { return int(q) }
+
+func TestAlertsHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		backendErr error
+		queue      fixedQueue
+		wantCode   int
+		wantStatus string
+	}{
+		{"all clear", http.MethodGet, nil, 0, http.StatusOK, "ok"},
+		{"warning stays 200", http.MethodGet, nil, 50, http.StatusOK, "warning"},
+		{"critical answers 503", http.MethodGet, errors.New("connection refused"), 0, http.StatusServiceUnavailable, "critical"},
+		{"wrong method", http.MethodPost, nil, 0, http.StatusMethodNotAllowed, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := status.NewSystemStatus(1)
+			if tt.backendErr != nil {
+				s.Record("pb:8090", tt.backendErr)
+			}
+			h := NewAlertsHandler(alerts.NewEvaluator(
+				alerts.PocketBaseDownRule(s),
+				alerts.ScannerOfflineRule(s, 10*time.Minute),
+				alerts.DetectionQueueDepthRule(map[string]alerts.QueueLen{"default": tt.queue}, 50),
+				alerts.NotificationFailuresRule(s, 5, 15*time.Minute),
+			))
+
+			rec := httptest.NewRecorder()
+			h.HandleAlerts(rec, httptest.NewRequest(tt.method, "/api/alerts", nil))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if tt.wantStatus == "" {
+				return
+			}
+
+			var resp alertsResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Status != tt.wantStatus || len(resp.Alerts) != 4 {
+				t.Errorf("status = %q with %d alerts, want %q with all 4", resp.Status, len(resp.Alerts), tt.wantStatus)
+			}
+		})
+	}
+}
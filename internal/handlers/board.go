@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"med-pulse-bot/internal/services"
+)
+
+//go:embed templates/board.html
+var boardTemplates embed.FS
+
+var boardPage = template.Must(template.ParseFS(boardTemplates, "templates/board.html"))
+
+// BoardHandler serves the public status board to allowlisted networks only
+type BoardHandler struct {
+	board   services.BoardProvider
+	allowed []*net.IPNet
+	limiter *rateLimiter
+}
+
+// NewBoardHandler creates a board handler. cidrs is a comma-separated allowlist of
+// networks or single IPs; perMinute is the request limit per client IP.
+func NewBoardHandler(board services.BoardProvider, cidrs string, perMinute int) (*BoardHandler, error) {
+	allowed, err := parseAllowlist(cidrs)
+	if err != nil {
+		return nil, err
+	}
+	if len(allowed) == 0 {
+		return nil, fmt.Errorf("public board allowlist is empty")
+	}
+	if perMinute < 1 {
+		return nil, fmt.Errorf("public board rate limit must be at least 1 request per minute")
+	}
+
+	return &BoardHandler{
+		board:   board,
+		allowed: allowed,
+		limiter: newRateLimiter(perMinute, time.Minute),
+	}, nil
+}
+
+// parseAllowlist parses "10.0.0.0/24,192.168.1.20" into networks; bare IPs become single-host networks
+func parseAllowlist(spec string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if !strings.Contains(part, "/") {
+			ip := net.ParseIP(part)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid allowlist entry %q", part)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowlist entry %q: %w", part, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// boardGroup is one department section of the HTML board
+type boardGroup struct {
+	Department string
+	Entries    []services.BoardEntry
+}
+
+// HandleBoard returns the board as JSON, or as an HTML page for browsers
+func (h *BoardHandler) HandleBoard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Only the direct peer address counts; forwarded headers are trivially spoofed on a guest network
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !h.isAllowed(ip) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	if !h.limiter.Allow(ip.String()) {
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, "Too many requests", http.StatusTooManyRequests)
+		return
+	}
+
+	entries, err := h.board.Board(r.Context())
+	if err != nil {
+		log.Printf("Error building public board: %v", err)
+		http.Error(w, "Board unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	if !strings.Contains(r.Header.Get("Accept"), "text/html") {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"employees": entries})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := boardPage.Execute(w, groupByDepartment(entries)); err != nil {
+		log.Printf("Error rendering public board: %v", err)
+	}
+}
+
+func (h *BoardHandler) isAllowed(ip net.IP) bool {
+	for _, n := range h.allowed {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// groupByDepartment splits entries (already sorted by department) into sections
+func groupByDepartment(entries []services.BoardEntry) []boardGroup {
+	var groups []boardGroup
+	for _, e := range entries {
+		if len(groups) == 0 || groups[len(groups)-1].Department != e.Department {
+			groups = append(groups, boardGroup{Department: e.Department})
+		}
+		last := &groups[len(groups)-1]
+		last.Entries = append(last.Entries, e)
+	}
+	return groups
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"med-pulse-bot/internal/services"
+)
+
+type stubBoard struct {
+	entries []services.BoardEntry
+}
+
+func (s *stubBoard) Board(ctx context.Context) ([]services.BoardEntry, error) {
+	return s.entries, nil
+}
+
+func newTestBoardHandler(t *testing.T, perMinute int) *BoardHandler {
+	t.Helper()
+	h, err := NewBoardHandler(&stubBoard{entries: []services.BoardEntry{
+		{DisplayName: "Somchai", Department: "ER", Present: true},
+		{DisplayName: "<b>Suda</b>", Department: "ER"},
+	}}, "192.168.50.0/24, 10.0.0.7", perMinute)
+	if err != nil {
+		t.Fatalf("NewBoardHandler() error = %v", err)
+	}
+	return h
+}
+
+func TestNewBoardHandler_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		cidrs     string
+		perMinute int
+	}{
+		{"empty allowlist", " , ", 10},
+		{"bad cidr", "192.168.50.0/99", 10},
+		{"bad ip", "not-an-ip", 10},
+		{"zero rate limit", "10.0.0.0/8", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewBoardHandler(&stubBoard{}, tt.cidrs, tt.perMinute); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestBoardHandler_Access(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		remoteAddr string
+		forwarded  string
+		wantStatus int
+	}{
+		{"allowed network", http.MethodGet, "192.168.50.12:5123", "", http.StatusOK},
+		{"allowed single ip", http.MethodGet, "10.0.0.7:5123", "", http.StatusOK},
+		{"outside allowlist", http.MethodGet, "10.0.0.8:5123", "", http.StatusForbidden},
+		{"forwarded header ignored", http.MethodGet, "10.0.0.8:5123", "192.168.50.12", http.StatusForbidden},
+		{"wrong method", http.MethodPost, "192.168.50.12:5123", "", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestBoardHandler(t, 10)
+			req := httptest.NewRequest(tt.method, "/public/board", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			rr := httptest.NewRecorder()
+			h.HandleBoard(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestBoardHandler_RateLimit(t *testing.T) {
+	h := newTestBoardHandler(t, 2)
+
+	codes := make([]int, 0, 4)
+	for _, addr := range []string{"192.168.50.12:1", "192.168.50.12:2", "192.168.50.12:3", "192.168.50.13:1"} {
+		req := httptest.NewRequest(http.MethodGet, "/public/board", nil)
+		req.RemoteAddr = addr
+		rr := httptest.NewRecorder()
+		h.HandleBoard(rr, req)
+		codes = append(codes, rr.Code)
+	}
+
+	want := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests, http.StatusOK}
+	for i := range want {
+		if codes[i] != want[i] {
+			t.Errorf("request %d status = %d, want %d", i, codes[i], want[i])
+		}
+	}
+}
+
+func TestBoardHandler_Formats(t *testing.T) {
+	h := newTestBoardHandler(t, 10)
+
+	t.Run("json", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/public/board", nil)
+		req.RemoteAddr = "192.168.50.12:5123"
+		rr := httptest.NewRecorder()
+		h.HandleBoard(rr, req)
+
+		var body struct {
+			Employees []map[string]interface{} `json:"employees"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if len(body.Employees) != 2 {
+			t.Fatalf("employees = %d, want 2", len(body.Employees))
+		}
+		for key := range body.Employees[0] {
+			if key != "display_name" && key != "department" && key != "present" {
+				t.Errorf("unexpected field %q exposed on public board", key)
+			}
+		}
+	})
+
+	t.Run("html", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/public/board", nil)
+		req.RemoteAddr = "192.168.50.12:5123"
+		req.Header.Set("Accept", "text/html,application/xhtml+xml")
+		rr := httptest.NewRecorder()
+		h.HandleBoard(rr, req)
+
+		body := rr.Body.String()
+		if !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/html") {
+			t.Errorf("Content-Type = %q, want text/html", rr.Header().Get("Content-Type"))
+		}
+		if !strings.Contains(body, `class="present"`) || !strings.Contains(body, "Somchai") {
+			t.Error("expected present entry for Somchai")
+		}
+		if strings.Contains(body, "<b>Suda</b>") {
+			t.Error("display names must be HTML-escaped")
+		}
+	})
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+const (
+	dashboardPageSize    = 50
+	dashboardMaxPageSize = 200
+	dashboardMaxRange    = 366 // days of one employee's attendance per request
+)
+
+// dashboardSite is the data one dashboard token reads
+type dashboardSite struct {
+	token      []byte
+	employees  repository.EmployeeBrowser
+	attendance repository.AttendanceBrowser
+}
+
+// DashboardHandler serves the read-only attendance API for dashboards. Each site has its
+// own bearer token (DASHBOARD_API_TOKEN), which selects whose data is read.
+type DashboardHandler struct {
+	sites   []dashboardSite
+	origins map[string]bool // allowed CORS origins; "*" allows any
+	loc     *time.Location
+}
+
+// NewDashboardHandler creates the handler; allowedOrigins is a comma-separated list of
+// origins browsers may call the API from (ALLOWED_ORIGINS)
+func NewDashboardHandler(allowedOrigins string, loc *time.Location) *DashboardHandler {
+	origins := make(map[string]bool)
+	for _, origin := range strings.Split(allowedOrigins, ",") {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			origins[origin] = true
+		}
+	}
+	return &DashboardHandler{origins: origins, loc: loc}
+}
+
+// AddSite lets requests with the token read the site's employees and attendance; sites
+// without a token have no API access
+func (h *DashboardHandler) AddSite(token string, employees repository.EmployeeBrowser, attendance repository.AttendanceBrowser) {
+	if token == "" {
+		return
+	}
+	h.sites = append(h.sites, dashboardSite{token: []byte(token), employees: employees, attendance: attendance})
+}
+
+// Enabled reports whether any site has a token
+func (h *DashboardHandler) Enabled() bool {
+	return len(h.sites) > 0
+}
+
+// dashboardPage is the JSON shape of every list
+type dashboardPage struct {
+	Items   interface{} `json:"items"`
+	Page    int         `json:"page"`
+	PerPage int         `json:"per_page"`
+	Total   int         `json:"total"`
+}
+
+type dashboardAttendance struct {
+	ID           string     `json:"id"`
+	EmployeeID   string     `json:"employee_id"`
+	EmployeeName string     `json:"employee_name,omitempty"`
+	EmployeeCode string     `json:"employee_code,omitempty"`
+	Department   string     `json:"department,omitempty"`
+	Date         string     `json:"date"`
+	CheckIn      time.Time  `json:"check_in"`
+	CheckOut     *time.Time `json:"check_out"`
+	Status       string     `json:"status"`
+	Source       string     `json:"source,omitempty"`
+	ScannerMac   string     `json:"scanner_mac,omitempty"`
+}
+
+// dashboardEmployee leaves out tag MACs and chat IDs, which a dashboard has no use for
+type dashboardEmployee struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	EmployeeCode  string `json:"employee_code,omitempty"`
+	Department    string `json:"department,omitempty"`
+	WorkStartTime string `json:"work_start_time,omitempty"`
+	WorkEndTime   string `json:"work_end_time,omitempty"`
+}
+
+// HandleAttendance lists a day's attendance (?date=YYYY-MM-DD, today by default) or one
+// employee's (?employee_id=X&from=YYYY-MM-DD&to=YYYY-MM-DD, both days included)
+func (h *DashboardHandler) HandleAttendance(w http.ResponseWriter, r *http.Request) {
+	site, ok := h.begin(w, r)
+	if !ok {
+		return
+	}
+	page, ok := dashboardPaging(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	var records []models.Attendance
+	var total int
+	var err error
+	if employeeID := strings.TrimSpace(q.Get("employee_id")); employeeID != "" {
+		from, okFrom := h.parseDate(q.Get("from"))
+		to, okTo := h.parseDate(q.Get("to"))
+		if !okFrom || !okTo || q.Get("from") == "" || q.Get("to") == "" {
+			http.Error(w, "from and to must be dates as YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		if to.Before(from) || to.Sub(from) >= dashboardMaxRange*24*time.Hour {
+			http.Error(w, "from must not be after to, and the range at most 366 days", http.StatusBadRequest)
+			return
+		}
+		records, total, err = site.attendance.PageByEmployeeAndRange(r.Context(), employeeID, from, to.AddDate(0, 0, 1), page)
+	} else {
+		date, ok := h.parseDate(q.Get("date"))
+		if !ok {
+			http.Error(w, "date must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		records, total, err = site.attendance.PageByDate(r.Context(), date, page)
+	}
+	if err != nil {
+		log.Printf("❌ Dashboard attendance query failed: %v", err)
+		http.Error(w, "Attendance unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	items := make([]dashboardAttendance, 0, len(records))
+	for _, a := range records {
+		item := dashboardAttendance{
+			ID:           a.ID,
+			EmployeeID:   a.EmployeeID,
+			EmployeeName: a.EmployeeName,
+			EmployeeCode: a.EmployeeCode,
+			Department:   a.Department,
+			Date:         a.CreatedDate.In(h.loc).Format("2006-01-02"),
+			CheckIn:      a.CheckInTime.In(h.loc),
+			Status:       a.Status,
+			Source:       a.Source,
+			ScannerMac:   a.ScannerMac,
+		}
+		if !a.CheckOutTime.IsZero() {
+			checkOut := a.CheckOutTime.In(h.loc)
+			item.CheckOut = &checkOut
+		}
+		items = append(items, item)
+	}
+	writeJSON(w, http.StatusOK, dashboardPage{Items: items, Page: page.Number, PerPage: page.Size, Total: total})
+}
+
+// HandleEmployees lists the active employees by name
+func (h *DashboardHandler) HandleEmployees(w http.ResponseWriter, r *http.Request) {
+	site, ok := h.begin(w, r)
+	if !ok {
+		return
+	}
+	page, ok := dashboardPaging(w, r)
+	if !ok {
+		return
+	}
+
+	employees, total, err := site.employees.PageActive(r.Context(), page)
+	if err != nil {
+		log.Printf("❌ Dashboard employee query failed: %v", err)
+		http.Error(w, "Employees unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	items := make([]dashboardEmployee, 0, len(employees))
+	for _, e := range employees {
+		items = append(items, dashboardEmployee{
+			ID:            e.ID,
+			Name:          e.Name,
+			EmployeeCode:  e.EmployeeCode,
+			Department:    e.Department,
+			WorkStartTime: e.WorkStartTime,
+			WorkEndTime:   e.WorkEndTime,
+		})
+	}
+	writeJSON(w, http.StatusOK, dashboardPage{Items: items, Page: page.Number, PerPage: page.Size, Total: total})
+}
+
+// begin sets the CORS headers, answers preflight requests and authenticates the rest. It
+// returns the site of the request's token; requests that fail are answered here.
+func (h *DashboardHandler) begin(w http.ResponseWriter, r *http.Request) (*dashboardSite, bool) {
+	if origin := r.Header.Get("Origin"); origin != "" && (h.origins["*"] || h.origins[origin]) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+	}
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+		return nil, false
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+
+	if token := bearerToken(r); token != "" {
+		for i := range h.sites {
+			if subtle.ConstantTimeCompare([]byte(token), h.sites[i].token) == 1 {
+				return &h.sites[i], true
+			}
+		}
+	}
+	log.Printf("🚫 Rejected dashboard request to %s from %s: missing or unknown token", r.URL.Path, r.RemoteAddr)
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	return nil, false
+}
+
+// bearerToken returns the token of the request's "Authorization: Bearer" header
+func bearerToken(r *http.Request) string {
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return strings.TrimSpace(token)
+}
+
+// dashboardPaging reads ?page= and ?per_page=, answering invalid values with 400
+func dashboardPaging(w http.ResponseWriter, r *http.Request) (repository.Page, bool) {
+	page := repository.Page{Number: 1, Size: dashboardPageSize}
+	for _, p := range []struct {
+		name  string
+		value *int
+		max   int
+	}{
+		{"page", &page.Number, 0},
+		{"per_page", &page.Size, dashboardMaxPageSize},
+	} {
+		raw := r.URL.Query().Get(p.name)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || (p.max > 0 && n > p.max) {
+			http.Error(w, "invalid "+p.name, http.StatusBadRequest)
+			return page, false
+		}
+		*p.value = n
+	}
+	return page, true
+}
+
+// parseDate parses a YYYY-MM-DD day in the site's time zone; empty means today
+func (h *DashboardHandler) parseDate(value string) (time.Time, bool) {
+	if value == "" {
+		now := time.Now().In(h.loc)
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, h.loc), true
+	}
+	day, err := time.ParseInLocation("2006-01-02", value, h.loc)
+	return day, err == nil
+}
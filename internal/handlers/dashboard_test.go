@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+func TestDashboardAPI(t *testing.T) {
+	loc := time.FixedZone("ICT", 7*60*60)
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, loc)
+	store := memory.NewStore(clock.NewFake(day.Add(12 * time.Hour)))
+	for i, name := range []string{"Somsri", "Somchai", "Malee"} {
+		store.AddEmployee(models.Employee{ID: fmt.Sprintf("e%d", i+1), Name: name, MacAddress: "11:22:33:44:55:66", IsActive: true})
+	}
+	store.AddEmployee(models.Employee{ID: "e9", Name: "Probe", IsActive: true, IsSynthetic: true})
+	for i := 0; i < 3; i++ {
+		for _, id := range []string{"e1", "e2"} {
+			a := models.Attendance{EmployeeID: id, CheckInTime: day.AddDate(0, 0, i).Add(8 * time.Hour),
+				CreatedDate: day.AddDate(0, 0, i), Status: models.AttendanceStatusOnTime}
+			if err := store.AttendanceRecords().Create(context.Background(), &a); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	h := NewDashboardHandler("https://dash.example.com", loc)
+	h.AddSite("secret", store.Employees(), store.AttendanceRecords())
+	h.AddSite("", store.Employees(), store.AttendanceRecords())
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		token      string
+		handle     http.HandlerFunc
+		wantStatus int
+		wantTotal  int
+		wantItems  int
+	}{
+		{"no token", http.MethodGet, "/api/employees", "", h.HandleEmployees, http.StatusUnauthorized, 0, 0},
+		{"wrong token", http.MethodGet, "/api/employees", "guess", h.HandleEmployees, http.StatusUnauthorized, 0, 0},
+		{"post", http.MethodPost, "/api/employees", "secret", h.HandleEmployees, http.StatusMethodNotAllowed, 0, 0},
+		{"preflight", http.MethodOptions, "/api/employees", "", h.HandleEmployees, http.StatusNoContent, 0, 0},
+		{"employees", http.MethodGet, "/api/employees", "secret", h.HandleEmployees, http.StatusOK, 3, 3},
+		{"employees paged", http.MethodGet, "/api/employees?page=2&per_page=2", "secret", h.HandleEmployees, http.StatusOK, 3, 1},
+		{"per_page too large", http.MethodGet, "/api/employees?per_page=500", "secret", h.HandleEmployees, http.StatusBadRequest, 0, 0},
+		{"day", http.MethodGet, "/api/attendance?date=2026-03-03", "secret", h.HandleAttendance, http.StatusOK, 2, 2},
+		{"invalid day", http.MethodGet, "/api/attendance?date=03/03/2026", "secret", h.HandleAttendance, http.StatusBadRequest, 0, 0},
+		{"employee range", http.MethodGet, "/api/attendance?employee_id=e1&from=2026-03-02&to=2026-03-03", "secret", h.HandleAttendance, http.StatusOK, 2, 2},
+		{"employee range paged", http.MethodGet, "/api/attendance?employee_id=e1&from=2026-03-01&to=2026-03-31&per_page=2&page=2", "secret", h.HandleAttendance, http.StatusOK, 3, 1},
+		{"range without to", http.MethodGet, "/api/attendance?employee_id=e1&from=2026-03-01", "secret", h.HandleAttendance, http.StatusBadRequest, 0, 0},
+		{"range too long", http.MethodGet, "/api/attendance?employee_id=e1&from=2025-01-01&to=2026-03-31", "secret", h.HandleAttendance, http.StatusBadRequest, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			req.Header.Set("Origin", "https://dash.example.com")
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			tt.handle(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://dash.example.com" {
+				t.Errorf("Access-Control-Allow-Origin = %q, want the allowed origin", got)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var body struct {
+				Items []map[string]interface{} `json:"items"`
+				Total int                      `json:"total"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Total != tt.wantTotal || len(body.Items) != tt.wantItems {
+				t.Errorf("got %d of %d items, want %d of %d", len(body.Items), body.Total, tt.wantItems, tt.wantTotal)
+			}
+			for _, item := range body.Items {
+				if _, ok := item["mac_address"]; ok {
+					t.Errorf("item %v exposes the tag MAC", item)
+				}
+			}
+		})
+	}
+}
+
+func TestDashboardCORS(t *testing.T) {
+	h := NewDashboardHandler("https://dash.example.com", time.UTC)
+	req := httptest.NewRequest(http.MethodOptions, "/api/attendance", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	rec := httptest.NewRecorder()
+	h.HandleAttendance(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Access-Control-Allow-Origin = %q for an unlisted origin, want none", got)
+	}
+}
@@ -2,58 +2,267 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"mime"
 	"net/http"
+	"strings"
+	"time"
 
+	"med-pulse-bot/internal/beacon"
+	"med-pulse-bot/internal/logging"
+	"med-pulse-bot/internal/macaddr"
 	"med-pulse-bot/internal/models"
 	"med-pulse-bot/internal/services"
 )
 
 // DetectionHandler handles BLE device detection requests
 type DetectionHandler struct {
-	service services.AttendanceProcessor
+	service  services.AttendanceProcessor
+	profiles *PayloadProfiles
+	limiter  *rateLimiter // nil when detections are not rate limited
+	limit    int
+	tracker  ScannerTracker             // nil when scanner activity is not tracked
+	workers  *services.DetectionWorkers // nil when detections are processed before answering
+	auth     *ScannerAuth               // nil when scanners are not authenticated by device token
+	freeform bool                       // accept scanner IDs that are not MAC addresses
+	maxBody  int64                      // largest accepted request body in bytes
 }
 
-// NewDetectionHandler creates a new detection handler
+// DefaultMaxBodyBytes is the largest detection body accepted unless configured; a real
+// detection is a few hundred bytes
+const DefaultMaxBodyBytes = 64 << 10
+
+// ScannerTracker notes that a scanner was heard from, for the scanner_offline alert
+type ScannerTracker interface {
+	ScannerSeen(scannerMac string)
+}
+
+// NewDetectionHandler creates a new detection handler with the built-in payload profiles
 func NewDetectionHandler(service services.AttendanceProcessor) *DetectionHandler {
-	return &DetectionHandler{service: service}
+	profiles, _ := NewPayloadProfiles("", "")
+	return &DetectionHandler{service: service, profiles: profiles, maxBody: DefaultMaxBodyBytes}
+}
+
+// SetMaxBodyBytes caps the request body; larger bodies get 413. 0 or less keeps the default.
+func (h *DetectionHandler) SetMaxBodyBytes(n int64) {
+	if n <= 0 {
+		n = DefaultMaxBodyBytes
+	}
+	h.maxBody = n
+}
+
+// SetPayloadProfiles replaces the payload compatibility profiles
+func (h *DetectionHandler) SetPayloadProfiles(profiles *PayloadProfiles) {
+	h.profiles = profiles
+}
+
+// SetRateLimit limits each scanner to perMinute detections; 0 disables the limit
+func (h *DetectionHandler) SetRateLimit(perMinute int) {
+	if perMinute <= 0 {
+		h.limiter, h.limit = nil, 0
+		return
+	}
+	h.limiter, h.limit = newRateLimiter(perMinute, time.Minute), perMinute
+}
+
+// SetScannerTracker reports every valid detection's scanner to the tracker
+func (h *DetectionHandler) SetScannerTracker(t ScannerTracker) {
+	h.tracker = t
+}
+
+// SetScannerAuth authenticates scanners by device token; an authenticated scanner's ID
+// replaces the scanner_mac its payload claims
+func (h *DetectionHandler) SetScannerAuth(a *ScannerAuth) {
+	h.auth = a
 }
 
-// HandleDetect processes BLE scanner detection requests
+// SetWorkers answers each valid detection with 202 once it is queued for the workers,
+// or 503 when their queue is full, instead of processing it before answering
+func (h *DetectionHandler) SetWorkers(w *services.DetectionWorkers) {
+	h.workers = w
+}
+
+// SetFreeformScannerIDs accepts scanner_mac values that are not MAC addresses
+func (h *DetectionHandler) SetFreeformScannerIDs(allow bool) {
+	h.freeform = allow
+}
+
+// busyRetryAfter is the Retry-After, in seconds, of a detection refused with a full queue
+const busyRetryAfter = "5"
+
+// HandleDetect processes a detection and answers with a machine-readable result the
+// firmware can react to, e.g. {"result":"accepted","matched":true,"checked_in":true}.
+// Unknown devices still get 200.
 func (h *DetectionHandler) HandleDetect(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	res, ok := h.detect(w, r)
+	if !ok {
 		return
 	}
 
+	code := http.StatusOK
+	switch res.Result {
+	case services.ResultQueued:
+		code = http.StatusAccepted
+	case services.ResultBusy:
+		w.Header().Set("Retry-After", busyRetryAfter)
+		code = http.StatusServiceUnavailable
+	case services.ResultRateLimited:
+		code = http.StatusTooManyRequests
+	case services.ResultError:
+		code = http.StatusInternalServerError
+	}
+	writeJSON(w, code, res)
+}
+
+// HandleDetectV2 answers like HandleDetect; it is kept for firmware built against
+// /api/v2/detect
+func (h *DetectionHandler) HandleDetectV2(w http.ResponseWriter, r *http.Request) {
+	h.HandleDetect(w, r)
+}
+
+// requestError is the JSON body of a rejected detection request
+type requestError struct {
+	Error string `json:"error"`
+	Code  string `json:"code"` // machine-readable reason, e.g. body_too_large
+}
+
+// rejectRequest answers a detection request that cannot be processed
+func rejectRequest(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, requestError{Error: message, Code: code})
+}
+
+// detect validates and processes a detection request. Invalid requests are answered
+// here with a JSON error and reported as not ok.
+func (h *DetectionHandler) detect(w http.ResponseWriter, r *http.Request) (services.DetectionResult, bool) {
+	if r.Method != http.MethodPost {
+		rejectRequest(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return services.DetectionResult{}, false
+	}
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		rejectRequest(w, http.StatusUnsupportedMediaType, "unsupported_media_type", "Content-Type must be application/json")
+		return services.DetectionResult{}, false
+	}
+
+	if h.auth != nil {
+		var ok bool
+		if r, ok = h.auth.Authenticate(w, r); !ok {
+			return services.DetectionResult{}, false
+		}
+	}
+
+	profile, err := h.profiles.Resolve(r)
+	if err != nil {
+		rejectRequest(w, http.StatusBadRequest, "unknown_profile", err.Error())
+		return services.DetectionResult{}, false
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.maxBody))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		rejectRequest(w, http.StatusRequestEntityTooLarge, "body_too_large", fmt.Sprintf("Request body over %d bytes", tooLarge.Limit))
+		return services.DetectionResult{}, false
+	}
+	if err != nil {
+		rejectRequest(w, http.StatusBadRequest, "invalid_body", "Invalid request body")
+		return services.DetectionResult{}, false
+	}
+
 	var req models.DetectionRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
+	if err := profile.Decode(body, &req); err != nil {
+		code := "invalid_body"
+		var unknown *UnknownFieldError
+		if errors.As(err, &unknown) {
+			code = "unknown_field"
+		}
+		rejectRequest(w, http.StatusBadRequest, code, "Invalid request body: "+err.Error())
+		return services.DetectionResult{}, false
 	}
 
-	// Log detection with target device info
-	if req.IsTargetDevice {
-		log.Printf("🎯 [TARGET DEVICE] Scanner: %s | Device: %s | MAC: %s | RSSI: %d | Type: %s",
-			req.ScannerMac, req.DeviceName, req.MacAddress, req.RSSI, req.DeviceType)
-	} else {
-		log.Printf("[Scanner: %s] Detected MAC: %s, RSSI: %d, Type: %s, iTag03: %v",
-			req.ScannerMac, req.MacAddress, req.RSSI, req.DeviceType, req.IsITag03)
+	mac, err := macaddr.Normalize(req.MacAddress)
+	if err != nil {
+		rejectRequest(w, http.StatusBadRequest, "invalid_mac_address", "Invalid mac_address: "+err.Error())
+		return services.DetectionResult{}, false
+	}
+	req.MacAddress = mac
+	if req.IBeaconUUID != "" {
+		if _, err := beacon.IBeaconID(req.IBeaconUUID, req.Major, req.Minor); err != nil {
+			rejectRequest(w, http.StatusBadRequest, "invalid_ibeacon", "Invalid iBeacon: "+err.Error())
+			return services.DetectionResult{}, false
+		}
+		req.IBeaconUUID, _ = beacon.NormalizeUUID(req.IBeaconUUID)
+	}
+	if req.NamespaceID != "" || req.InstanceID != "" {
+		id, err := beacon.EddystoneID(req.NamespaceID, req.InstanceID)
+		if err != nil {
+			rejectRequest(w, http.StatusBadRequest, "invalid_eddystone", "Invalid Eddystone-UID: "+err.Error())
+			return services.DetectionResult{}, false
+		}
+		req.NamespaceID, req.InstanceID, _ = strings.Cut(id, ":")
+	}
+	if authenticated, ok := AuthenticatedScanner(r.Context()); ok {
+		if claimed, err := macaddr.NormalizeScannerID(req.ScannerMac, true); req.ScannerMac != "" && (err != nil || claimed != authenticated) {
+			log.Printf("⚠️  Scanner %s sent a detection claiming scanner_mac %q; using the token's scanner", authenticated, req.ScannerMac)
+		}
+		req.ScannerMac = authenticated
+	}
+	scanner, err := macaddr.NormalizeScannerID(req.ScannerMac, h.freeform)
+	if err != nil {
+		rejectRequest(w, http.StatusBadRequest, "invalid_scanner_mac", "Invalid scanner_mac: "+err.Error())
+		return services.DetectionResult{}, false
+	}
+	req.ScannerMac = scanner
+	if h.tracker != nil {
+		h.tracker.ScannerSeen(req.ScannerMac)
 	}
 
-	if req.IsITag03 {
-		log.Printf("🏷️  iTag03 detected from scanner %s: MAC=%s, RSSI=%d",
-			req.ScannerMac, req.MacAddress, req.RSSI)
+	// Under WHITELIST_ONLY other devices are dropped before anything names them
+	if filter, ok := h.service.(services.DetectionFilter); ok && !filter.Admit(r.Context(), &req) {
+		return services.DetectionResult{Result: services.ResultUnknownDevice, Stage: "whitelist"}, true
 	}
 
-	// Process detection with request context
-	ctx := r.Context()
-	if err := h.service.ProcessDetection(ctx, &req); err != nil {
-		log.Printf("Error processing detection: %v", err)
-		// Don't return error to client - detection is async
+	// Every record about this detection carries its scanner and device
+	logger := logging.From(r.Context()).With("scanner_mac", req.ScannerMac, "mac", req.MacAddress)
+	if req.IBeaconUUID != "" {
+		logger = logger.With("ibeacon_uuid", req.IBeaconUUID, "major", req.Major, "minor", req.Minor)
 	}
+	if req.NamespaceID != "" {
+		logger = logger.With("namespace_id", req.NamespaceID, "instance_id", req.InstanceID)
+	}
+
+	if h.limiter != nil && !h.limiter.Allow(req.ScannerMac) {
+		logger.Warn("🚦 Scanner exceeded the detection rate limit", "limit_per_minute", h.limit)
+		limit := h.limit
+		return services.DetectionResult{Result: services.ResultRateLimited, Threshold: &limit}, true
+	}
+
+	logger.Debug("Detection received", "rssi", req.RSSI, "device_type", req.DeviceType,
+		"device_name", req.DeviceName, "itag03", req.IsITag03, "target", req.IsTargetDevice)
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("OK"))
+	if h.workers != nil {
+		if !h.workers.Enqueue(r.Context(), &req) {
+			logger.Debug("🚧 Detection queue full, asking the scanner to retry")
+			return services.DetectionResult{Result: services.ResultBusy}, true
+		}
+		return services.DetectionResult{Result: services.ResultQueued}, true
+	}
+
+	// Process detection with request context; the pipeline adds the same attributes
+	ctx := r.Context()
+	res := services.DetectionResult{Result: services.ResultAccepted}
+	if processor, ok := h.service.(services.DetectionResultProcessor); ok {
+		res, err = processor.DetectWithResult(ctx, &req)
+	} else {
+		err = h.service.ProcessDetection(ctx, &req)
+	}
+	if err != nil {
+		logger.Error("Error processing detection", "error", err)
+		res = services.DetectionResult{Result: services.ResultError, Stage: res.Stage}
+	}
+	if res.Matched {
+		logger.Debug("🎯 Detection matched", "identity", res.Identity, "result", res.Result)
+	}
+	return res, true
 }
@@ -4,11 +4,21 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"log"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"med-pulse-bot/config"
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/localstore"
+	"med-pulse-bot/internal/logging"
 	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
 	"med-pulse-bot/internal/services"
 )
 
@@ -35,6 +45,7 @@ func TestHandleDetect(t *testing.T) {
 		body           interface{}
 		wantStatusCode int
 		wantCalled     bool
+		wantMac        string
 	}{
 		{
 			name:   "Valid detection request",
@@ -49,6 +60,29 @@ func TestHandleDetect(t *testing.T) {
 			wantStatusCode: http.StatusOK,
 			wantCalled:     true,
 		},
+		{
+			name:   "Phone-style MAC is normalized",
+			method: http.MethodPost,
+			body: models.DetectionRequest{
+				ScannerMac: "AA:BB:CC:DD:EE:FF",
+				MacAddress: "11 22 33 44 55 6A",
+				RSSI:       -50,
+			},
+			wantStatusCode: http.StatusOK,
+			wantCalled:     true,
+			wantMac:        "11:22:33:44:55:6a",
+		},
+		{
+			name:   "Invalid MAC",
+			method: http.MethodPost,
+			body: models.DetectionRequest{
+				ScannerMac: "AA:BB:CC:DD:EE:FF",
+				MacAddress: "11:22:33:44:55",
+				RSSI:       -50,
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantCalled:     false,
+		},
 		{
 			name:           "Invalid method - GET",
 			method:         http.MethodGet,
@@ -87,7 +121,7 @@ func TestHandleDetect(t *testing.T) {
 
 			// Create request
 			req := httptest.NewRequest(tt.method, "/api/detect", bytes.NewReader(bodyBytes))
-			if tt.body != nil && tt.body != "invalid json" {
+			if tt.body != nil {
 				req.Header.Set("Content-Type", "application/json")
 			}
 
@@ -108,7 +142,11 @@ func TestHandleDetect(t *testing.T) {
 			}
 
 			// Verify request was passed correctly
-			if tt.wantCalled && mockService.lastRequest != nil {
+			if tt.wantMac != "" && mockService.lastRequest != nil {
+				if mockService.lastRequest.MacAddress != tt.wantMac {
+					t.Errorf("MacAddress = %v, want %v", mockService.lastRequest.MacAddress, tt.wantMac)
+				}
+			} else if tt.wantCalled && mockService.lastRequest != nil {
 				if req, ok := tt.body.(models.DetectionRequest); ok {
 					if mockService.lastRequest.MacAddress != req.MacAddress {
 						t.Errorf("MacAddress = %v, want %v", mockService.lastRequest.MacAddress, req.MacAddress)
@@ -118,3 +156,258 @@ func TestHandleDetect(t *testing.T) {
 		})
 	}
 }
+
+// sentNotifier counts the personal messages a site with Telegram sends
+type sentNotifier struct{ personal int }
+
+func (n *sentNotifier) SendNotification(message string)                       {}
+func (n *sentNotifier) SendPersonalNotification(chatID int64, message string) { n.personal++ }
+
+// staticGate is a write gate fixed in one mode
+type staticGate bool
+
+func (g staticGate) ReadOnly() bool { return bool(g) }
+
+func TestHandleDetectResults(t *testing.T) {
+	const employeeMAC = "11:22:33:44:55:66"
+	tests := []struct {
+		name          string
+		mac           string
+		rssi          int
+		checkedIn     bool
+		readOnly      bool
+		rateLimit     int
+		wantCode      int
+		wantResult    string
+		wantMatched   bool
+		wantCheckedIn bool
+		wantThreshold *int
+	}{
+		{name: "accepted", mac: employeeMAC, rssi: -50, wantCode: http.StatusOK, wantResult: services.ResultAccepted, wantMatched: true, wantCheckedIn: true},
+		{name: "too far", mac: employeeMAC, rssi: -80, wantCode: http.StatusOK, wantResult: services.ResultTooFar, wantMatched: true, wantThreshold: intPtr(services.DefaultRSSIThreshold)},
+		{name: "unknown device", mac: "66:55:44:33:22:11", rssi: -50, wantCode: http.StatusOK, wantResult: services.ResultUnknownDevice},
+		{name: "duplicate", mac: employeeMAC, rssi: -50, checkedIn: true, wantCode: http.StatusOK, wantResult: services.ResultDuplicate, wantMatched: true},
+		{name: "paused", mac: employeeMAC, rssi: -50, readOnly: true, wantCode: http.StatusOK, wantResult: services.ResultPaused},
+		{name: "rate limited", mac: employeeMAC, rssi: -80, rateLimit: 1, wantCode: http.StatusTooManyRequests, wantResult: services.ResultRateLimited, wantThreshold: intPtr(1)},
+	}
+
+	// Headless sites record the same attendance and give scanners the same answers
+	for _, mode := range []string{config.ModeTelegram, config.ModeHeadless} {
+		for _, tt := range tests {
+			t.Run(mode+"/"+tt.name, func(t *testing.T) {
+				clk := clock.NewFake(time.Date(2026, 2, 2, 7, 55, 0, 0, time.Local))
+				store := memory.NewStore(clk)
+				store.AddEmployee(models.Employee{Name: "Somchai Jaidee", TelegramChatID: 987654321, MacAddress: employeeMAC, IsActive: true})
+				sent := &sentNotifier{}
+				var notifier services.BotNotifier = sent
+				if mode == config.ModeHeadless {
+					notifier = services.NopNotifier{}
+				}
+				service := services.NewAttendanceService(store.Employees(), store.AttendanceRecords(),
+					store.DetectionRecords(), store.ScannerRecords(), notifier)
+				service.SetClock(clk)
+				service.SetReadOnlyQueue(staticGate(tt.readOnly), services.NewDetectionQueue(localstore.NewMemoryStore()))
+				handler := NewDetectionHandler(service)
+				handler.SetRateLimit(tt.rateLimit)
+
+				send := func(mac string, rssi int) *httptest.ResponseRecorder {
+					body, _ := json.Marshal(models.DetectionRequest{ScannerMac: "AA:BB:CC:DD:EE:FF", MacAddress: mac, RSSI: rssi})
+					req := httptest.NewRequest(http.MethodPost, "/api/detect", bytes.NewReader(body))
+					req.Header.Set("Content-Type", "application/json")
+					rec := httptest.NewRecorder()
+					handler.HandleDetect(rec, req)
+					return rec
+				}
+				if tt.checkedIn || tt.rateLimit > 0 {
+					send(employeeMAC, -50)
+				}
+
+				rec := send(tt.mac, tt.rssi)
+				if rec.Code != tt.wantCode {
+					t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+				}
+				var res services.DetectionResult
+				if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+					t.Fatalf("decode %q: %v", rec.Body.String(), err)
+				}
+				if res.Result != tt.wantResult {
+					t.Errorf("result = %q (stage %q), want %q", res.Result, res.Stage, tt.wantResult)
+				}
+				if res.Matched != tt.wantMatched || res.CheckedIn != tt.wantCheckedIn {
+					t.Errorf("matched, checked_in = %v, %v, want %v, %v", res.Matched, res.CheckedIn, tt.wantMatched, tt.wantCheckedIn)
+				}
+				if (res.Threshold == nil) != (tt.wantThreshold == nil) || (res.Threshold != nil && *res.Threshold != *tt.wantThreshold) {
+					t.Errorf("threshold = %v, want %v", res.Threshold, tt.wantThreshold)
+				}
+				if body := rec.Body.String(); strings.Contains(body, "Somchai") || strings.Contains(body, "987654321") {
+					t.Errorf("response leaks personal data: %s", body)
+				}
+				// Only the check-in sends a message, to the employee over Telegram
+				checkedIn := tt.wantCheckedIn || tt.checkedIn || tt.rateLimit > 0
+				if wantSent := mode == config.ModeTelegram && checkedIn; (sent.personal > 0) != wantSent {
+					t.Errorf("personal messages sent = %d, want any = %v", sent.personal, wantSent)
+				}
+			})
+		}
+	}
+}
+
+func intPtr(n int) *int { return &n }
+
+func TestHandleDetectRejectsMalformedRequests(t *testing.T) {
+	const valid = `{"scanner_mac":"AA:BB:CC:DD:EE:FF","mac_address":"11:22:33:44:55:66","rssi":-50}`
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantCode    string // empty when the request is accepted
+	}{
+		{"valid", "application/json", valid, http.StatusOK, ""},
+		{"charset parameter", "application/json; charset=utf-8", valid, http.StatusOK, ""},
+		{"missing content type", "", valid, http.StatusUnsupportedMediaType, "unsupported_media_type"},
+		{"form content type", "application/x-www-form-urlencoded", valid, http.StatusUnsupportedMediaType, "unsupported_media_type"},
+		{"oversized body", "application/json", `{"scanner_mac":"` + strings.Repeat("A", 300) + `"}`, http.StatusRequestEntityTooLarge, "body_too_large"},
+		{"extra field", "application/json", `{"scanner_mac":"AA:BB:CC:DD:EE:FF","mac_address":"11:22:33:44:55:66","rssi":-50,"timestamp":1}`, http.StatusBadRequest, "unknown_field"},
+		{"malformed JSON", "application/json", `{"scanner_mac":`, http.StatusBadRequest, "invalid_body"},
+		{"iBeacon", "application/json", `{"scanner_mac":"AA:BB:CC:DD:EE:FF","mac_address":"11:22:33:44:55:66","rssi":-50,"ibeacon_uuid":"FDA50693A4E24FB1AFCFC6EB07647825","major":1,"minor":42}`, http.StatusOK, ""},
+		{"invalid iBeacon UUID", "application/json", `{"scanner_mac":"AA:BB:CC:DD:EE:FF","mac_address":"11:22:33:44:55:66","rssi":-50,"ibeacon_uuid":"not-a-uuid"}`, http.StatusBadRequest, "invalid_ibeacon"},
+		{"iBeacon major out of range", "application/json", `{"scanner_mac":"AA:BB:CC:DD:EE:FF","mac_address":"11:22:33:44:55:66","rssi":-50,"ibeacon_uuid":"FDA50693A4E24FB1AFCFC6EB07647825","major":70000}`, http.StatusBadRequest, "invalid_ibeacon"},
+		{"Eddystone-UID", "application/json", `{"scanner_mac":"AA:BB:CC:DD:EE:FF","mac_address":"11:22:33:44:55:66","rssi":-50,"namespace_id":"0xEDD1EBEAC04E5DEFA017","instance_id":"0123456789AB"}`, http.StatusOK, ""},
+		{"Eddystone-UID without instance", "application/json", `{"scanner_mac":"AA:BB:CC:DD:EE:FF","mac_address":"11:22:33:44:55:66","rssi":-50,"namespace_id":"edd1ebeac04e5defa017"}`, http.StatusBadRequest, "invalid_eddystone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &mockAttendanceService{}
+			handler := NewDetectionHandler(service)
+			handler.SetMaxBodyBytes(256)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/detect", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			handler.HandleDetect(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if service.processDetectionCalled != (tt.wantCode == "") {
+				t.Errorf("ProcessDetection called = %v", service.processDetectionCalled)
+			}
+			if tt.wantCode == "" {
+				if uuid := service.lastRequest.IBeaconUUID; uuid != "" && uuid != "fda50693-a4e2-4fb1-afcf-c6eb07647825" {
+					t.Errorf("ibeacon_uuid = %q, want it normalized", uuid)
+				}
+				if ns := service.lastRequest.NamespaceID; ns != "" && ns != "edd1ebeac04e5defa017" {
+					t.Errorf("namespace_id = %q, want it normalized", ns)
+				}
+				return
+			}
+			var got requestError
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode %q: %v", rec.Body.String(), err)
+			}
+			if got.Code != tt.wantCode || got.Error == "" {
+				t.Errorf("error = %+v, want code %q", got, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestHandleDetectWhitelistOnly(t *testing.T) {
+	const (
+		employeeMAC = "11:22:33:44:55:66"
+		strangerMAC = "66:55:44:33:22:11"
+	)
+	defaultLogger := slog.Default()
+	t.Cleanup(func() {
+		slog.SetDefault(defaultLogger)
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	})
+	var logs bytes.Buffer
+	if err := logging.Setup(&logs, "debug", "text"); err != nil {
+		t.Fatal(err)
+	}
+
+	clk := clock.NewFake(time.Date(2026, 2, 2, 7, 55, 0, 0, time.Local))
+	store := memory.NewStore(clk)
+	store.AddEmployee(models.Employee{Name: "Somchai Jaidee", TelegramChatID: 987654321, MacAddress: employeeMAC, IsActive: true})
+	service := services.NewAttendanceService(store.Employees(), store.AttendanceRecords(),
+		store.DetectionRecords(), store.ScannerRecords(), services.NopNotifier{})
+	service.SetClock(clk)
+	service.SetUnknownDeviceCapture(services.NewUnknownDeviceCapture(store.DeviceSightingLog(), -60, time.Minute))
+	service.SetWhitelist(services.NewDeviceWhitelist(store.Employees(), store.DeviceSightingLog(), time.Minute))
+	handler := NewDetectionHandler(service)
+
+	for _, mac := range []string{strangerMAC, employeeMAC} {
+		body, _ := json.Marshal(models.DetectionRequest{ScannerMac: "AA:BB:CC:DD:EE:FF", MacAddress: mac, RSSI: -50})
+		req := httptest.NewRequest(http.MethodPost, "/api/detect", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.HandleDetect(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: status = %d, want %d", mac, rec.Code, http.StatusOK)
+		}
+	}
+
+	if n := len(store.Detections()); n != 1 {
+		t.Errorf("detection records = %d, want the employee's only", n)
+	}
+	if sightings := store.DeviceSightings(); len(sightings) != 0 {
+		t.Errorf("devices = %+v, want the stranger not recorded", sightings)
+	}
+	if strings.Contains(logs.String(), strangerMAC) {
+		t.Errorf("logs name the dropped device:\n%s", logs.String())
+	}
+	if !strings.Contains(logs.String(), employeeMAC) {
+		t.Errorf("logs = %q, want the employee's detection logged", logs.String())
+	}
+}
+
+func TestHandleDetectWithWorkers(t *testing.T) {
+	service := &mockAttendanceService{}
+	// Not started, so the one queued detection keeps the queue full
+	workers := services.NewDetectionWorkers(service, 1, 1, "handler-workers-test")
+	handler := NewDetectionHandler(service)
+	handler.SetWorkers(workers)
+
+	tests := []struct {
+		name       string
+		wantStatus int
+		wantResult string
+		retryAfter string
+	}{
+		{"queued", http.StatusAccepted, services.ResultQueued, ""},
+		{"queue full", http.StatusServiceUnavailable, services.ResultBusy, busyRetryAfter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(models.DetectionRequest{ScannerMac: "AA:BB:CC:DD:EE:FF", MacAddress: "11:22:33:44:55:66", RSSI: -50})
+			req := httptest.NewRequest(http.MethodPost, "/api/detect", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			handler.HandleDetect(rec, req)
+
+			var res services.DetectionResult
+			json.Unmarshal(rec.Body.Bytes(), &res)
+			if rec.Code != tt.wantStatus || res.Result != tt.wantResult || rec.Header().Get("Retry-After") != tt.retryAfter {
+				t.Errorf("status %d, result %q, Retry-After %q; want %d, %q, %q",
+					rec.Code, res.Result, rec.Header().Get("Retry-After"), tt.wantStatus, tt.wantResult, tt.retryAfter)
+			}
+		})
+	}
+	if service.processDetectionCalled {
+		t.Error("the detection was processed before answering")
+	}
+
+	// The queued detection is processed once the workers run and drained on Stop
+	workers.Start(context.Background())
+	if err := workers.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !service.processDetectionCalled || service.lastRequest.MacAddress != "11:22:33:44:55:66" {
+		t.Errorf("queued detection not processed: %+v", service.lastRequest)
+	}
+}
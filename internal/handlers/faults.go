@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"med-pulse-bot/internal/faults"
+)
+
+// FaultHandler exposes the fault injector over HTTP; only registered when
+// ENABLE_FAULT_INJECTION=true, and only answering requests with FAULT_INJECTION_TOKEN
+type FaultHandler struct {
+	injector *faults.Injector
+	token    []byte
+}
+
+// NewFaultHandler creates a new fault injection debug handler accepting requests with the
+// bearer token; without a token every request is refused
+func NewFaultHandler(injector *faults.Injector, token string) *FaultHandler {
+	return &FaultHandler{injector: injector, token: []byte(token)}
+}
+
+// authorize answers requests without the handler's bearer token (FAULT_INJECTION_TOKEN, never the
+// read-only dashboard token) with 401
+func (h *FaultHandler) authorize(w http.ResponseWriter, r *http.Request) bool {
+	token := bearerToken(r)
+	if len(h.token) > 0 && token != "" && subtle.ConstantTimeCompare([]byte(token), h.token) == 1 {
+		return true
+	}
+	log.Printf("🚫 Rejected fault injection request from %s: missing or unknown token", r.RemoteAddr)
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	return false
+}
+
+// injectFaultRequest is the body accepted by POST /debug/faults
+type injectFaultRequest struct {
+	Component       string  `json:"component"`
+	LatencyMS       int64   `json:"latency_ms"`
+	ErrorRate       float64 `json:"error_rate"`
+	Outage          bool    `json:"outage"`
+	DurationSeconds int64   `json:"duration_seconds"`
+}
+
+// HandleFaults lists (GET), injects (POST) or clears (DELETE ?component=) faults
+func (h *FaultHandler) HandleFaults(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, h.injector.Active())
+
+	case http.MethodPost:
+		var req injectFaultRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		fault := faults.Fault{
+			Component: req.Component,
+			Latency:   time.Duration(req.LatencyMS) * time.Millisecond,
+			ErrorRate: req.ErrorRate,
+			Outage:    req.Outage,
+		}
+		if err := h.injector.Set(fault, time.Duration(req.DurationSeconds)*time.Second); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		log.Printf("💥 Fault injected: component=%s latency=%dms error_rate=%.2f outage=%v duration=%ds",
+			req.Component, req.LatencyMS, req.ErrorRate, req.Outage, req.DurationSeconds)
+		writeJSON(w, http.StatusOK, h.injector.Active())
+
+	case http.MethodDelete:
+		component := r.URL.Query().Get("component")
+		h.injector.Clear(component)
+		log.Printf("💥 Fault cleared: component=%s", component)
+		writeJSON(w, http.StatusOK, h.injector.Active())
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
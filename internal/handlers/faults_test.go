@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/faults"
+	"med-pulse-bot/internal/lifecycle"
+	"med-pulse-bot/internal/status"
+)
+
+func TestHandleFaults(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		body           string
+		token          string
+		wantStatusCode int
+		wantActive     int
+	}{
+		{
+			name:           "Inject PocketBase outage",
+			method:         http.MethodPost,
+			url:            "/debug/faults",
+			body:           `{"component":"pocketbase","outage":true,"duration_seconds":60}`,
+			wantStatusCode: http.StatusOK,
+			wantActive:     1,
+		},
+		{
+			name:           "Unknown component",
+			method:         http.MethodPost,
+			url:            "/debug/faults",
+			body:           `{"component":"mqtt","outage":true,"duration_seconds":60}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantActive:     0,
+		},
+		{
+			name:           "Missing duration",
+			method:         http.MethodPost,
+			url:            "/debug/faults",
+			body:           `{"component":"notifier","error_rate":0.5}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantActive:     0,
+		},
+		{
+			name:           "Wrong token",
+			method:         http.MethodPost,
+			url:            "/debug/faults",
+			body:           `{"component":"pocketbase","outage":true,"duration_seconds":60}`,
+			token:          "guess",
+			wantStatusCode: http.StatusUnauthorized,
+			wantActive:     0,
+		},
+		{
+			name:           "Invalid method",
+			method:         http.MethodPut,
+			url:            "/debug/faults",
+			wantStatusCode: http.StatusMethodNotAllowed,
+			wantActive:     0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			injector := faults.NewInjector()
+			handler := NewFaultHandler(injector, "secret")
+
+			req := httptest.NewRequest(tt.method, tt.url, bytes.NewReader([]byte(tt.body)))
+			token := tt.token
+			if token == "" {
+				token = "secret"
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+			rr := httptest.NewRecorder()
+			handler.HandleFaults(rr, req)
+
+			if rr.Code != tt.wantStatusCode {
+				t.Errorf("HandleFaults() status = %v, want %v", rr.Code, tt.wantStatusCode)
+			}
+			if got := len(injector.Active()); got != tt.wantActive {
+				t.Errorf("active faults = %d, want %d", got, tt.wantActive)
+			}
+		})
+	}
+}
+
+func TestHandleReadyShowsInjectedFaults(t *testing.T) {
+	tests := []struct {
+		name       string
+		injector   *faults.Injector
+		inject     bool
+		wantStatus string
+		wantBlock  bool
+	}{
+		{"Fault injection disabled", nil, false, "ready", false},
+		{"Enabled without faults", faults.NewInjector(), false, "ready", true},
+		{"Enabled with active fault", faults.NewInjector(), true, "ready_with_injected_faults", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.inject {
+				tt.injector.Set(faults.Fault{Component: faults.ComponentNotifier, Outage: true}, time.Minute)
+			}
+
+			rr := httptest.NewRecorder()
+			NewHealthHandler(tt.injector).HandleReady(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			var body readyResponse
+			if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+				t.Fatalf("Failed to decode body: %v", err)
+			}
+			if body.Status != tt.wantStatus {
+				t.Errorf("status = %v, want %v", body.Status, tt.wantStatus)
+			}
+			if (body.FaultInjection != nil) != tt.wantBlock {
+				t.Errorf("fault_injection present = %v, want %v", body.FaultInjection != nil, tt.wantBlock)
+			}
+		})
+	}
+}
+
+func TestHandleReadyReportsDegradedPocketBase(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   int
+		readOnly   bool
+		wantCode   int
+		wantStatus string
+	}{
+		{"Healthy backend", 0, false, http.StatusOK, "ready"},
+		{"Below threshold", 1, false, http.StatusOK, "ready"},
+		{"Backend down", 2, false, http.StatusServiceUnavailable, "degraded"},
+		{"Read-only stays ready", 0, true, http.StatusOK, "read_only"},
+		{"Down while read-only", 2, true, http.StatusServiceUnavailable, "degraded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.NewSystemStatus(2)
+			st.Record("pb:8090", nil)
+			for i := 0; i < tt.failures; i++ {
+				st.Record("pb:8090", errors.New("connection refused"))
+			}
+			st.SetReadOnly(tt.readOnly)
+
+			h := NewHealthHandler(nil)
+			h.SetSystemStatus(st)
+			rr := httptest.NewRecorder()
+			h.HandleReady(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			var body readyResponse
+			if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+				t.Fatalf("Failed to decode body: %v", err)
+			}
+			if rr.Code != tt.wantCode || body.Status != tt.wantStatus {
+				t.Errorf("got %d %q, want %d %q", rr.Code, body.Status, tt.wantCode, tt.wantStatus)
+			}
+			if len(body.PocketBase) != 1 {
+				t.Errorf("pocketbase = %+v, want one backend", body.PocketBase)
+			}
+			if body.ReadOnly == nil || body.ReadOnly.Enabled != tt.readOnly {
+				t.Errorf("read_only = %+v, want enabled=%v", body.ReadOnly, tt.readOnly)
+			}
+		})
+	}
+}
+
+func TestHandleReadyReportsComponents(t *testing.T) {
+	tests := []struct {
+		name       string
+		start      bool
+		stop       bool
+		wantCode   int
+		wantStatus string
+		wantState  lifecycle.State
+	}{
+		{"Starting up", false, false, http.StatusServiceUnavailable, "components_not_running", lifecycle.StateRegistered},
+		{"Running", true, false, http.StatusOK, "ready", lifecycle.StateRunning},
+		{"Shutting down", true, true, http.StatusServiceUnavailable, "components_not_running", lifecycle.StateStopped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := lifecycle.NewManager()
+			m.Register(lifecycle.Component{Name: "schedulers"})
+			if tt.start {
+				m.Start(context.Background())
+			}
+			if tt.stop {
+				m.Stop(context.Background())
+			}
+
+			h := NewHealthHandler(nil)
+			h.SetLifecycle(m)
+			rr := httptest.NewRecorder()
+			h.HandleReady(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			var body readyResponse
+			if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+				t.Fatalf("Failed to decode body: %v", err)
+			}
+			if rr.Code != tt.wantCode || body.Status != tt.wantStatus {
+				t.Errorf("got %d %q, want %d %q", rr.Code, body.Status, tt.wantCode, tt.wantStatus)
+			}
+			if len(body.Components) != 1 || body.Components[0].State != tt.wantState {
+				t.Errorf("components = %+v, want schedulers %s", body.Components, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestHandleReadyChecksDependencies(t *testing.T) {
+	var pings int
+	healthy := true
+	pb := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pings++
+		if r.URL.Path != "/api/health" || !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"code":200}`))
+	}))
+	defer pb.Close()
+
+	now := time.Date(2026, 2, 2, 8, 0, 0, 0, time.Local)
+	telegram := true
+	h := NewHealthHandler(nil)
+	h.SetPocketBaseProbe([]string{pb.URL}, nil)
+	h.probe.now = func() time.Time { return now }
+	h.SetTelegram(func() bool { return telegram })
+
+	ready := func() (int, readyResponse) {
+		rr := httptest.NewRecorder()
+		h.HandleReady(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var body readyResponse
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode body: %v", err)
+		}
+		return rr.Code, body
+	}
+
+	tests := []struct {
+		name       string
+		advance    time.Duration
+		pbHealthy  bool
+		telegram   bool
+		wantCode   int
+		wantStatus string
+		wantPings  int
+	}{
+		{"all healthy", 0, true, true, http.StatusOK, "ready", 1},
+		{"cached while fresh", 5 * time.Second, false, true, http.StatusOK, "ready", 1},
+		{"probed again after the cache", 5 * time.Second, false, true, http.StatusServiceUnavailable, "dependency_down", 2},
+		{"bot not initialized", probeCacheTTL, true, false, http.StatusServiceUnavailable, "dependency_down", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.advance)
+			healthy, telegram = tt.pbHealthy, tt.telegram
+			code, body := ready()
+			if code != tt.wantCode || body.Status != tt.wantStatus {
+				t.Errorf("got %d %q, want %d %q", code, body.Status, tt.wantCode, tt.wantStatus)
+			}
+			if pings != tt.wantPings {
+				t.Errorf("pings = %d, want %d", pings, tt.wantPings)
+			}
+			if len(body.Dependencies) != 2 || body.Dependencies[0].Name != "pocketbase" || body.Dependencies[1].Name != "telegram" {
+				t.Errorf("dependencies = %+v, want pocketbase and telegram", body.Dependencies)
+			}
+		})
+	}
+
+	t.Run("liveness ignores dependencies", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		h.HandleLive(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		if rr.Code != http.StatusOK {
+			t.Errorf("HandleLive() = %d, want 200", rr.Code)
+		}
+	})
+}
@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/faults"
+	"med-pulse-bot/internal/leader"
+	"med-pulse-bot/internal/lifecycle"
+	"med-pulse-bot/internal/status"
+)
+
+// HealthHandler serves liveness and readiness probes
+type HealthHandler struct {
+	faults *faults.Injector     // nil unless fault injection is enabled
+	status *status.SystemStatus // nil skips the PocketBase check
+	leader LeaderStatus         // nil when leader election is disabled
+	comps  Lifecycle            // nil skips the component check
+
+	probe    *dependencyProbe // nil skips the active PocketBase check
+	telegram func() bool      // nil skips the Telegram bot check
+}
+
+// LeaderStatus reports which instance runs the singleton components
+type LeaderStatus interface {
+	Status() leader.Status
+}
+
+// Lifecycle reports the state of the app's long-running components
+type Lifecycle interface {
+	Status() []lifecycle.ComponentStatus
+	Running() bool
+}
+
+// NewHealthHandler creates a new health handler; injector may be nil
+func NewHealthHandler(injector *faults.Injector) *HealthHandler {
+	return &HealthHandler{faults: injector}
+}
+
+// SetSystemStatus makes readiness fail while a PocketBase backend is down
+func (h *HealthHandler) SetSystemStatus(s *status.SystemStatus) {
+	h.status = s
+}
+
+// SetElector reports leadership on /readyz; followers stay ready since they serve HTTP
+func (h *HealthHandler) SetElector(l LeaderStatus) {
+	h.leader = l
+}
+
+// SetLifecycle makes readiness fail unless every component is running
+func (h *HealthHandler) SetLifecycle(l Lifecycle) {
+	h.comps = l
+}
+
+// SetPocketBaseProbe makes readiness ping each server's /api/health, caching the result
+// for a few seconds so frequent probes do not hammer PocketBase
+func (h *HealthHandler) SetPocketBaseProbe(baseURLs []string, rt http.RoundTripper) {
+	h.probe = newDependencyProbe(baseURLs, rt)
+}
+
+// SetTelegram makes readiness fail unless the Telegram bot was initialized
+func (h *HealthHandler) SetTelegram(initialized func() bool) {
+	h.telegram = initialized
+}
+
+// HandleLive is the liveness probe: cheap, and always OK while the process serves HTTP
+func (h *HealthHandler) HandleLive(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "alive"})
+}
+
+// Dependency is the result of checking one external dependency for /readyz
+type Dependency struct {
+	Name      string    `json:"name"`
+	Target    string    `json:"target,omitempty"`
+	Healthy   bool      `json:"healthy"`
+	LatencyMS float64   `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// probeTimeout bounds one /api/health ping; probeCacheTTL is how long its result is reused
+const (
+	probeTimeout  = 2 * time.Second
+	probeCacheTTL = 10 * time.Second
+)
+
+// dependencyProbe pings PocketBase servers and caches the results
+type dependencyProbe struct {
+	client   *http.Client
+	baseURLs []string
+	now      func() time.Time
+
+	mu      sync.Mutex
+	checked time.Time
+	results []Dependency
+}
+
+func newDependencyProbe(baseURLs []string, rt http.RoundTripper) *dependencyProbe {
+	return &dependencyProbe{
+		client:   &http.Client{Timeout: probeTimeout, Transport: rt},
+		baseURLs: baseURLs,
+		now:      time.Now,
+	}
+}
+
+// check returns the cached results while fresh, or pings every server. Concurrent probes
+// wait for one ping instead of each sending their own.
+func (p *dependencyProbe) check(ctx context.Context) []Dependency {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.results != nil && p.now().Sub(p.checked) < probeCacheTTL {
+		return p.results
+	}
+	results := make([]Dependency, 0, len(p.baseURLs))
+	for _, base := range p.baseURLs {
+		results = append(results, p.ping(ctx, base))
+	}
+	p.checked, p.results = p.now(), results
+	return results
+}
+
+// ping calls one server's /api/health
+func (p *dependencyProbe) ping(ctx context.Context, baseURL string) Dependency {
+	d := Dependency{Name: "pocketbase", Target: baseURL}
+	start := p.now()
+	err := func() error {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), probeTimeout)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(baseURL, "/")+"/api/health", nil)
+		if err != nil {
+			return err
+		}
+		resp, err := p.client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("HTTP %d", resp.StatusCode)
+		}
+		return nil
+	}()
+	d.CheckedAt = p.now()
+	d.LatencyMS = float64(d.CheckedAt.Sub(start).Microseconds()) / 1000
+	if err != nil {
+		d.Error = err.Error()
+	} else {
+		d.Healthy = true
+	}
+	return d
+}
+
+// readyResponse is the JSON body returned by /readyz
+type readyResponse struct {
+	Status         string              `json:"status"`
+	ReadOnly       *readOnlyInfo       `json:"read_only,omitempty"`
+	PocketBase     []status.Backend    `json:"pocketbase,omitempty"`
+	FaultInjection *faultInjectionInfo `json:"fault_injection,omitempty"`
+	Leader         *leader.Status      `json:"leader,omitempty"`
+	Dependencies   []Dependency        `json:"dependencies,omitempty"`
+
+	Components []lifecycle.ComponentStatus `json:"components,omitempty"`
+}
+
+type readOnlyInfo struct {
+	Enabled bool       `json:"enabled"`
+	Since   *time.Time `json:"since,omitempty"` // last switch; nil if never switched
+}
+
+type faultInjectionInfo struct {
+	Enabled bool           `json:"enabled"`
+	Active  []faults.Fault `json:"active"`
+}
+
+// HandleReady reports readiness, including any injected faults so they are never silent.
+// It fails with 503 while a PocketBase backend is down, a dependency check fails or a
+// component is not running; read-only mode is reported but stays ready since reads and
+// detection intake keep working.
+func (h *HealthHandler) HandleReady(w http.ResponseWriter, r *http.Request) {
+	resp := readyResponse{Status: "ready"}
+	code := http.StatusOK
+
+	if h.faults != nil {
+		active := h.faults.Active()
+		resp.FaultInjection = &faultInjectionInfo{Enabled: true, Active: active}
+		if len(active) > 0 {
+			resp.Status = "ready_with_injected_faults"
+		}
+	}
+
+	if h.status != nil {
+		resp.ReadOnly = &readOnlyInfo{Enabled: h.status.ReadOnly()}
+		if since := h.status.ReadOnlySince(); !since.IsZero() {
+			resp.ReadOnly.Since = &since
+		}
+		if resp.ReadOnly.Enabled {
+			resp.Status = "read_only"
+		}
+		resp.PocketBase = h.status.Backends()
+		if h.status.Degraded() {
+			resp.Status = "degraded"
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	if h.leader != nil {
+		s := h.leader.Status()
+		resp.Leader = &s
+	}
+
+	// Active checks: PocketBase answering now, and the bot that sends every notification
+	if h.probe != nil {
+		resp.Dependencies = append(resp.Dependencies, h.probe.check(r.Context())...)
+	}
+	if h.telegram != nil {
+		d := Dependency{Name: "telegram", Healthy: h.telegram(), CheckedAt: time.Now()}
+		if !d.Healthy {
+			d.Error = "bot not initialized"
+		}
+		resp.Dependencies = append(resp.Dependencies, d)
+	}
+	for _, d := range resp.Dependencies {
+		if !d.Healthy {
+			resp.Status = "dependency_down"
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	// Not ready while starting up, shutting down or after a component failed
+	if h.comps != nil {
+		resp.Components = h.comps.Status()
+		if !h.comps.Running() {
+			resp.Status = "components_not_running"
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	writeJSON(w, code, resp)
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"med-pulse-bot/internal/macaddr"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+	"med-pulse-bot/internal/services"
+)
+
+// HeartbeatRequest is the status a scanner posts periodically. A new scanner adds the
+// pairing code shown in the bot until it has been paired.
+type HeartbeatRequest struct {
+	ScannerMac      string `json:"scanner_mac"`
+	PairingCode     string `json:"pairing_code,omitempty"`
+	FirmwareVersion string `json:"firmware_version,omitempty"`
+	UptimeSeconds   int64  `json:"uptime_seconds,omitempty"`
+	FreeHeap        int64  `json:"free_heap,omitempty"` // bytes
+}
+
+// heartbeatResponse tells the scanner whether the heartbeat (and pairing) succeeded
+type heartbeatResponse struct {
+	Status string `json:"status,omitempty"` // "ok" or "paired"
+	Zone   string `json:"zone,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// HeartbeatHandler records scanner heartbeats and redeems pairing codes
+type HeartbeatHandler struct {
+	scanners repository.ScannerRepository
+	pairing  *services.ScannerPairing // nil when pairing is not available
+	gate     services.WriteGate       // nil means always writable
+	tracker  ScannerTracker           // nil when scanner activity is not tracked
+	freeform bool                     // accept scanner IDs that are not MAC addresses
+}
+
+// NewHeartbeatHandler creates a heartbeat handler; pairing may be nil
+func NewHeartbeatHandler(scanners repository.ScannerRepository, pairing *services.ScannerPairing) *HeartbeatHandler {
+	return &HeartbeatHandler{scanners: scanners, pairing: pairing}
+}
+
+// SetWriteGate skips writes while the gate is read-only
+func (h *HeartbeatHandler) SetWriteGate(gate services.WriteGate) {
+	h.gate = gate
+}
+
+// SetScannerTracker reports every valid heartbeat's scanner to the tracker
+func (h *HeartbeatHandler) SetScannerTracker(t ScannerTracker) {
+	h.tracker = t
+}
+
+// SetFreeformScannerIDs accepts scanner_mac values that are not MAC addresses
+func (h *HeartbeatHandler) SetFreeformScannerIDs(allow bool) {
+	h.freeform = allow
+}
+
+// HandleHeartbeat updates the scanner's last seen time, or pairs it when a code is sent
+func (h *HeartbeatHandler) HandleHeartbeat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req HeartbeatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	scanner, err := macaddr.NormalizeScannerID(req.ScannerMac, h.freeform)
+	if err != nil {
+		http.Error(w, "Invalid scanner_mac: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.ScannerMac = scanner
+	if req.UptimeSeconds < 0 || req.FreeHeap < 0 {
+		http.Error(w, "Invalid heartbeat: uptime_seconds and free_heap cannot be negative", http.StatusBadRequest)
+		return
+	}
+	if h.tracker != nil {
+		h.tracker.ScannerSeen(req.ScannerMac)
+	}
+	readOnly := h.gate != nil && h.gate.ReadOnly()
+
+	if req.PairingCode == "" {
+		if !readOnly {
+			if err := h.scanners.UpdateActivity(r.Context(), req.ScannerMac, models.ScannerHealth{
+				FirmwareVersion: req.FirmwareVersion,
+				UptimeSeconds:   req.UptimeSeconds,
+				FreeHeap:        req.FreeHeap,
+			}); err != nil {
+				log.Printf("❌ Failed to record heartbeat of %s: %v", req.ScannerMac, err)
+				writeJSON(w, http.StatusInternalServerError, heartbeatResponse{Error: services.ResultError})
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, heartbeatResponse{Status: "ok"})
+		return
+	}
+
+	if readOnly {
+		writeJSON(w, http.StatusServiceUnavailable, heartbeatResponse{Error: "read_only"})
+		return
+	}
+	if h.pairing == nil {
+		writeJSON(w, http.StatusForbidden, heartbeatResponse{Error: services.ErrPairingCodeInvalid.Error()})
+		return
+	}
+
+	zone, err := h.pairing.Redeem(r.Context(), req.PairingCode, req.ScannerMac)
+	switch {
+	case errors.Is(err, services.ErrPairingCodeInvalid), errors.Is(err, services.ErrPairingCodeExpired),
+		errors.Is(err, services.ErrPairingCodeUsed):
+		writeJSON(w, http.StatusForbidden, heartbeatResponse{Error: err.Error()})
+	case err != nil:
+		log.Printf("❌ Failed to pair scanner %s: %v", req.ScannerMac, err)
+		writeJSON(w, http.StatusInternalServerError, heartbeatResponse{Error: services.ResultError})
+	default:
+		writeJSON(w, http.StatusOK, heartbeatResponse{Status: "paired", Zone: zone})
+	}
+}
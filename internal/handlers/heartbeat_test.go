@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+	"med-pulse-bot/internal/services"
+)
+
+func TestHandleHeartbeat(t *testing.T) {
+	store := memory.NewStore(nil)
+	pairing, err := services.NewScannerPairing(store.ScannerRecords(), services.NopNotifier{}, 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _, err := pairing.NewCode("ทางเข้า", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gate := new(staticGate)
+	handler := NewHeartbeatHandler(store.ScannerRecords(), pairing)
+	handler.SetWriteGate(gate)
+
+	tests := []struct {
+		name       string
+		body       string
+		readOnly   bool
+		wantStatus int
+		wantBody   string
+	}{
+		{"plain heartbeat", `{"scanner_mac":"AA:BB:CC:00:00:01"}`, false, http.StatusOK, `"status":"ok"`},
+		{"health fields", `{"scanner_mac":"AA:BB:CC:00:00:01","firmware_version":"1.4.2","uptime_seconds":3600,"free_heap":81920}`, false, http.StatusOK, `"status":"ok"`},
+		{"negative uptime", `{"scanner_mac":"AA:BB:CC:00:00:01","uptime_seconds":-1}`, false, http.StatusBadRequest, "cannot be negative"},
+		{"same scanner in another notation", `{"scanner_mac":"aabb.cc00.0001"}`, false, http.StatusOK, `"status":"ok"`},
+		{"invalid mac", `{"scanner_mac":"nope"}`, false, http.StatusBadRequest, "Invalid scanner_mac"},
+		{"free-form ID", `{"scanner_mac":"esp32-lobby"}`, false, http.StatusBadRequest, "Invalid scanner_mac"},
+		{"pairing paused in read-only mode", `{"scanner_mac":"AA:BB:CC:00:00:10","pairing_code":"` + code + `"}`, true, http.StatusServiceUnavailable, `"read_only"`},
+		{"pairing", `{"scanner_mac":"AA:BB:CC:00:00:10","pairing_code":"` + code + `"}`, false, http.StatusOK, `"zone":"ทางเข้า"`},
+		{"reused code", `{"scanner_mac":"AA:BB:CC:00:00:11","pairing_code":"` + code + `"}`, false, http.StatusForbidden, "pairing_code_used"},
+		{"unknown code", `{"scanner_mac":"AA:BB:CC:00:00:11","pairing_code":"999999x"}`, false, http.StatusForbidden, "pairing_code_invalid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*gate = staticGate(tt.readOnly)
+			rec := httptest.NewRecorder()
+			handler.HandleHeartbeat(rec, httptest.NewRequest(http.MethodPost, "/api/scanner/heartbeat", strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("got %d %s, want %d containing %s", rec.Code, rec.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+
+	scanners := store.Scanners()
+	if n := len(scanners); n != 2 {
+		t.Fatalf("got %d scanner records, want 2", n)
+	}
+	// A later heartbeat without health fields keeps the reported ones
+	want := models.ScannerHealth{FirmwareVersion: "1.4.2", UptimeSeconds: 3600, FreeHeap: 81920}
+	for _, sc := range scanners {
+		if sc.ScannerMac == "aa:bb:cc:00:00:01" && sc.Health != want {
+			t.Errorf("health = %+v, want %+v", sc.Health, want)
+		}
+	}
+}
+
+func TestHandleHeartbeatFreeformScannerIDs(t *testing.T) {
+	store := memory.NewStore(nil)
+	handler := NewHeartbeatHandler(store.ScannerRecords(), nil)
+	handler.SetFreeformScannerIDs(true)
+
+	for _, body := range []string{`{"scanner_mac":"ESP32-Lobby"}`, `{"scanner_mac":"esp32-lobby"}`} {
+		rec := httptest.NewRecorder()
+		handler.HandleHeartbeat(rec, httptest.NewRequest(http.MethodPost, "/api/scanner/heartbeat", strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: got %d %s, want 200", body, rec.Code, rec.Body.String())
+		}
+	}
+
+	scanners := store.Scanners()
+	if len(scanners) != 1 || scanners[0].ScannerMac != "esp32-lobby" {
+		t.Errorf("got scanners %+v, want one esp32-lobby", scanners)
+	}
+}
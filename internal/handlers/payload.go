@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"med-pulse-bot/internal/models"
+)
+
+// Built-in payload profile names
+const (
+	DefaultPayloadProfile = "default"
+	LegacyPayloadProfile  = "legacy"
+)
+
+// PayloadProfile maps incoming JSON keys onto models.DetectionRequest JSON keys.
+// An empty mapping means the payload already uses our field names.
+type PayloadProfile struct {
+	Name   string
+	Fields map[string]string // incoming key -> DetectionRequest json key
+}
+
+// PayloadProfiles holds the known profiles and the API key -> profile routing
+type PayloadProfiles struct {
+	profiles map[string]PayloadProfile
+	byAPIKey map[string]string
+}
+
+// NewPayloadProfiles builds the profile set from the built-ins plus the configured specs.
+//
+// profileSpec format: "name:src=dst,src=dst;name2:src=dst"
+// keySpec format:     "apikey:profile,apikey2:profile"
+//
+// Every destination key and every API key route is validated so a typo fails at startup.
+func NewPayloadProfiles(profileSpec, keySpec string) (*PayloadProfiles, error) {
+	p := &PayloadProfiles{
+		profiles: map[string]PayloadProfile{
+			DefaultPayloadProfile: {Name: DefaultPayloadProfile},
+			LegacyPayloadProfile: {Name: LegacyPayloadProfile, Fields: map[string]string{
+				"mac":     "mac_address",
+				"rssi":    "rssi",
+				"scanner": "scanner_mac",
+			}},
+		},
+		byAPIKey: make(map[string]string),
+	}
+
+	for _, entry := range splitNonEmpty(profileSpec, ";") {
+		name, mappings, ok := strings.Cut(entry, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid payload profile %q: want name:src=dst,...", entry)
+		}
+		if name == DefaultPayloadProfile {
+			return nil, fmt.Errorf("payload profile %q cannot be redefined", name)
+		}
+
+		profile := PayloadProfile{Name: name, Fields: make(map[string]string)}
+		for _, m := range splitNonEmpty(mappings, ",") {
+			src, dst, ok := strings.Cut(m, "=")
+			src, dst = strings.TrimSpace(src), strings.TrimSpace(dst)
+			if !ok || src == "" || dst == "" {
+				return nil, fmt.Errorf("invalid mapping %q in payload profile %q", m, name)
+			}
+			if !detectionFields[dst] {
+				return nil, fmt.Errorf("payload profile %q maps %q to unknown field %q (known: %s)",
+					name, src, dst, strings.Join(knownDetectionFields(), ", "))
+			}
+			profile.Fields[src] = dst
+		}
+		p.profiles[name] = profile
+	}
+
+	for _, entry := range splitNonEmpty(keySpec, ",") {
+		key, name, ok := strings.Cut(entry, ":")
+		key, name = strings.TrimSpace(key), strings.TrimSpace(name)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid payload profile key route %q: want apikey:profile", entry)
+		}
+		if _, exists := p.profiles[name]; !exists {
+			return nil, fmt.Errorf("API key route points at unknown payload profile %q", name)
+		}
+		p.byAPIKey[key] = name
+	}
+
+	return p, nil
+}
+
+// Resolve picks the profile for a request: ?profile= wins, then the X-API-Key route, then default
+func (p *PayloadProfiles) Resolve(r *http.Request) (PayloadProfile, error) {
+	name := r.URL.Query().Get("profile")
+	if name == "" {
+		name = p.byAPIKey[r.Header.Get("X-API-Key")]
+	}
+	if name == "" {
+		name = DefaultPayloadProfile
+	}
+
+	profile, ok := p.profiles[name]
+	if !ok {
+		return PayloadProfile{}, fmt.Errorf("unknown payload profile %q", name)
+	}
+	return profile, nil
+}
+
+// Decode decodes body into a DetectionRequest, renaming keys according to the profile.
+// Keys that are not DetectionRequest fields after renaming are rejected.
+func (profile PayloadProfile) Decode(body []byte, req *models.DetectionRequest) error {
+	if len(profile.Fields) == 0 {
+		return decodeStrict(body, req)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return err
+	}
+
+	mapped := make(map[string]json.RawMessage, len(raw))
+	for key, value := range raw {
+		if dst, ok := profile.Fields[key]; ok {
+			mapped[dst] = value
+		} else if _, taken := mapped[key]; !taken {
+			mapped[key] = value
+		}
+	}
+
+	remapped, err := json.Marshal(mapped)
+	if err != nil {
+		return err
+	}
+	return decodeStrict(remapped, req)
+}
+
+// UnknownFieldError is returned by Decode for a key that is no DetectionRequest field
+type UnknownFieldError struct {
+	Field string
+}
+
+func (e *UnknownFieldError) Error() string {
+	return fmt.Sprintf("json: unknown field %q", e.Field)
+}
+
+// decodeStrict decodes one JSON value, failing on unknown fields with an
+// *UnknownFieldError. The keys are checked here rather than by reading the text of the
+// decoder's DisallowUnknownFields error, which encoding/json does not promise to keep.
+func decodeStrict(body []byte, req *models.DetectionRequest) error {
+	var raw map[string]json.RawMessage
+	if json.Unmarshal(body, &raw) == nil {
+		keys := make([]string, 0, len(raw))
+		for key := range raw {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			// encoding/json matches field names case-insensitively
+			if !detectionFields[strings.ToLower(key)] {
+				return &UnknownFieldError{Field: key}
+			}
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	return dec.Decode(req)
+}
+
+// detectionFields is the set of JSON keys accepted by models.DetectionRequest
+var detectionFields = func() map[string]bool {
+	fields := make(map[string]bool)
+	t := reflect.TypeOf(models.DetectionRequest{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}()
+
+func knownDetectionFields() []string {
+	names := make([]string, 0, len(detectionFields))
+	for name := range detectionFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func splitNonEmpty(s, sep string) []string {
+	var out []string
+	for _, part := range strings.Split(s, sep) {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"med-pulse-bot/internal/models"
+)
+
+func TestNewPayloadProfiles(t *testing.T) {
+	tests := []struct {
+		name        string
+		profileSpec string
+		keySpec     string
+		wantErr     bool
+	}{
+		{"Built-ins only", "", "", false},
+		{"Custom profile", "acme:addr=mac_address,sig=rssi,id=scanner_mac", "", false},
+		{"Several profiles", "a:m=mac_address;b:s=scanner_mac", "key1:a,key2:b", false},
+		{"Key routed to built-in legacy", "", "key1:legacy", false},
+		{"Unknown destination field", "acme:addr=mac", "", true},
+		{"Missing profile name", ":addr=mac_address", "", true},
+		{"Malformed mapping", "acme:addr", "", true},
+		{"Redefining default", "default:mac=mac_address", "", true},
+		{"Key routed to unknown profile", "", "key1:nope", true},
+		{"Key route without key", "", ":legacy", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPayloadProfiles(tt.profileSpec, tt.keySpec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewPayloadProfiles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPayloadProfileDecodeUnknownField(t *testing.T) {
+	acme := PayloadProfile{Name: "acme", Fields: map[string]string{"addr": "mac_address"}}
+	tests := []struct {
+		name      string
+		profile   PayloadProfile
+		body      string
+		wantField string // empty: no unknown field
+	}{
+		{"known fields", PayloadProfile{}, `{"mac_address":"aa:bb:cc:dd:ee:01","rssi":-50}`, ""},
+		{"field names ignore case", PayloadProfile{}, `{"MAC_Address":"aa:bb:cc:dd:ee:01"}`, ""},
+		{"unknown field", PayloadProfile{}, `{"mac_address":"aa:bb:cc:dd:ee:01","timestamp":1}`, "timestamp"},
+		{"first of several unknown fields", PayloadProfile{}, `{"zone":1,"battery":2}`, "battery"},
+		{"renamed field", acme, `{"addr":"aa:bb:cc:dd:ee:01"}`, ""},
+		{"unknown field after renaming", acme, `{"addr":"aa:bb:cc:dd:ee:01","sig":-50}`, "sig"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req models.DetectionRequest
+			err := tt.profile.Decode([]byte(tt.body), &req)
+			var unknown *UnknownFieldError
+			if !errors.As(err, &unknown) {
+				if tt.wantField != "" || err != nil {
+					t.Fatalf("Decode() error = %v, want unknown field %q", err, tt.wantField)
+				}
+				return
+			}
+			if unknown.Field != tt.wantField {
+				t.Errorf("unknown field = %q, want %q", unknown.Field, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestHandleDetectPayloadProfiles(t *testing.T) {
+	legacyBody := `{"mac":"AA-BB-CC-DD-EE-01","rssi":-61,"scanner":"11:22:33:44:55:66"}`
+	defaultBody := `{"mac_address":"AA:BB:CC:DD:EE:01","rssi":-61,"scanner_mac":"11:22:33:44:55:66"}`
+
+	tests := []struct {
+		name           string
+		url            string
+		apiKey         string
+		body           string
+		wantStatusCode int
+		wantCalled     bool
+	}{
+		{"Default profile unchanged", "/api/detect", "", defaultBody, http.StatusOK, true},
+		{"Legacy payload via query parameter", "/api/detect?profile=legacy", "", legacyBody, http.StatusOK, true},
+		{"Legacy payload via API key", "/api/detect", "legacy-key", legacyBody, http.StatusOK, true},
+		{"Custom profile via query parameter", "/api/detect?profile=acme", "", `{"addr":"aabb.ccdd.ee01","sig":-61,"id":"11:22:33:44:55:66"}`, http.StatusOK, true},
+		{"Legacy payload without profile fails validation", "/api/detect", "", legacyBody, http.StatusBadRequest, false},
+		{"Unknown profile", "/api/detect?profile=nope", "", legacyBody, http.StatusBadRequest, false},
+		{"Malformed JSON with profile", "/api/detect?profile=legacy", "", `{"mac":`, http.StatusBadRequest, false},
+	}
+
+	profiles, err := NewPayloadProfiles("acme:addr=mac_address,sig=rssi,id=scanner_mac", "legacy-key:legacy")
+	if err != nil {
+		t.Fatalf("NewPayloadProfiles() error = %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockAttendanceService{}
+			handler := NewDetectionHandler(mockService)
+			handler.SetPayloadProfiles(profiles)
+
+			req := httptest.NewRequest(http.MethodPost, tt.url, bytes.NewReader([]byte(tt.body)))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.apiKey != "" {
+				req.Header.Set("X-API-Key", tt.apiKey)
+			}
+			rr := httptest.NewRecorder()
+			handler.HandleDetect(rr, req)
+
+			if rr.Code != tt.wantStatusCode {
+				t.Fatalf("HandleDetect() status = %v, want %v (%s)", rr.Code, tt.wantStatusCode, rr.Body.String())
+			}
+			if mockService.processDetectionCalled != tt.wantCalled {
+				t.Fatalf("ProcessDetection called = %v, want %v", mockService.processDetectionCalled, tt.wantCalled)
+			}
+			if !tt.wantCalled {
+				return
+			}
+
+			got := mockService.lastRequest
+			if got.MacAddress != "aa:bb:cc:dd:ee:01" || got.RSSI != -61 || got.ScannerMac != "11:22:33:44:55:66" {
+				t.Errorf("mapped request = %+v", got)
+			}
+		})
+	}
+}
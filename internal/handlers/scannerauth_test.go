@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+// failingTokens is a token lookup whose PocketBase is unreachable
+type failingTokens struct{}
+
+func (failingTokens) GetByToken(ctx context.Context, token string) (*models.Scanner, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestHandleDetectScannerTokens(t *testing.T) {
+	store := memory.NewStore(nil)
+	scanners := store.ScannerRecords()
+	if err := scanners.SetToken(context.Background(), "AA:BB:CC:DD:EE:01", "token-lobby"); err != nil {
+		t.Fatal(err)
+	}
+	const body = `{"scanner_mac":"AA:BB:CC:DD:EE:99","mac_address":"11:22:33:44:55:66","rssi":-50}`
+
+	tests := []struct {
+		name        string
+		tokens      ScannerTokens
+		required    bool
+		auth        string
+		wantStatus  int
+		wantScanner string // scanner the service saw; empty when not called
+	}{
+		{"no token trusts the payload", scanners, false, "", http.StatusOK, "aa:bb:cc:dd:ee:99"},
+		{"no token when required", scanners, true, "", http.StatusUnauthorized, ""},
+		{"unknown token", scanners, false, "Bearer stolen", http.StatusUnauthorized, ""},
+		{"token wins over the payload", scanners, true, "Bearer token-lobby", http.StatusOK, "aa:bb:cc:dd:ee:01"},
+		{"lookup failure", failingTokens{}, false, "Bearer token-lobby", http.StatusServiceUnavailable, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &mockAttendanceService{}
+			h := NewDetectionHandler(service)
+			h.SetScannerAuth(NewScannerAuth(tt.tokens, tt.required))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/detect", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			h.HandleDetect(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			got := ""
+			if service.lastRequest != nil {
+				got = service.lastRequest.ScannerMac
+			}
+			if got != tt.wantScanner {
+				t.Errorf("scanner = %q, want %q", got, tt.wantScanner)
+			}
+		})
+	}
+}
+
+func TestScannerAuthRevocation(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore(nil)
+	scanners := store.ScannerRecords()
+	if err := scanners.SetToken(ctx, "AA:BB:CC:DD:EE:01", "token-lobby"); err != nil {
+		t.Fatal(err)
+	}
+	auth := NewScannerAuth(scanners, true)
+	now := time.Date(2026, 2, 2, 8, 0, 0, 0, time.Local)
+	auth.now = func() time.Time { return now }
+
+	authenticate := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/api/detect", nil)
+		req.Header.Set("Authorization", "Bearer token-lobby")
+		w := httptest.NewRecorder()
+		if _, ok := auth.Authenticate(w, req); ok {
+			return http.StatusOK
+		}
+		return w.Code
+	}
+
+	if got := authenticate(); got != http.StatusOK {
+		t.Fatalf("status = %d before revoking", got)
+	}
+	if err := scanners.SetToken(ctx, "AA:BB:CC:DD:EE:01", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := authenticate(); got != http.StatusOK {
+		t.Errorf("status = %d while the lookup is cached, want %d", got, http.StatusOK)
+	}
+	now = now.Add(scannerTokenTTL)
+	if got := authenticate(); got != http.StatusUnauthorized {
+		t.Errorf("status = %d after the cache expired, want %d", got, http.StatusUnauthorized)
+	}
+}
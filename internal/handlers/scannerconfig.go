@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"med-pulse-bot/internal/macaddr"
+	"med-pulse-bot/internal/services"
+)
+
+// ScannerConfigHandler serves scanners their effective configuration: defaults, then
+// their profile, then their own overrides
+type ScannerConfigHandler struct {
+	configs  *services.ScannerConfigs
+	freeform bool // accept scanner IDs that are not MAC addresses
+}
+
+// NewScannerConfigHandler creates a handler serving configs
+func NewScannerConfigHandler(configs *services.ScannerConfigs) *ScannerConfigHandler {
+	return &ScannerConfigHandler{configs: configs}
+}
+
+// SetFreeformScannerIDs accepts scanner_mac values that are not MAC addresses
+func (h *ScannerConfigHandler) SetFreeformScannerIDs(allow bool) {
+	h.freeform = allow
+}
+
+// HandleScannerConfig answers GET ?scanner_mac= with the scanner's effective configuration
+func (h *ScannerConfigHandler) HandleScannerConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	scanner, err := macaddr.NormalizeScannerID(r.URL.Query().Get("scanner_mac"), h.freeform)
+	if err != nil {
+		http.Error(w, "Invalid scanner_mac: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cfg, err := h.configs.Config(r.Context(), scanner)
+	if err != nil {
+		log.Printf("❌ Failed to resolve config of scanner %s: %v", scanner, err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": services.ResultError})
+		return
+	}
+	writeJSON(w, http.StatusOK, cfg)
+}
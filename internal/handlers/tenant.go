@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"med-pulse-bot/internal/metrics"
+	"med-pulse-bot/internal/tenant"
+)
+
+// TenantRouter sends each detection to the handler of the tenant owning the
+// request's X-API-Key. Requests without a known key are rejected, so a scanner
+// can never write into another tenant's data.
+type TenantRouter struct {
+	registry   *tenant.Registry
+	handlers   map[string]*DetectionHandler
+	heartbeats map[string]*HeartbeatHandler
+	configs    map[string]*ScannerConfigHandler
+}
+
+// NewTenantRouter creates a router over per-tenant detection handlers keyed by tenant ID
+func NewTenantRouter(registry *tenant.Registry, handlers map[string]*DetectionHandler) *TenantRouter {
+	return &TenantRouter{registry: registry, handlers: handlers}
+}
+
+// HandleDetect resolves the tenant and delegates to its detection handler
+func (t *TenantRouter) HandleDetect(w http.ResponseWriter, r *http.Request) {
+	if h, r := t.route(w, r); h != nil {
+		h.HandleDetect(w, r)
+	}
+}
+
+// SetHeartbeatHandlers sets the per-tenant scanner heartbeat handlers keyed by tenant ID
+func (t *TenantRouter) SetHeartbeatHandlers(heartbeats map[string]*HeartbeatHandler) {
+	t.heartbeats = heartbeats
+}
+
+// HandleHeartbeat resolves the tenant and delegates to its heartbeat handler, so a
+// pairing code only works with the API key of the tenant it was issued for
+func (t *TenantRouter) HandleHeartbeat(w http.ResponseWriter, r *http.Request) {
+	tn, ok := t.tenant(w, r)
+	if !ok {
+		return
+	}
+	h, ok := t.heartbeats[tn.ID]
+	if !ok {
+		metrics.RejectedRequests.Inc("tenant_not_started")
+		http.Error(w, "Tenant unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	h.HandleHeartbeat(w, r.WithContext(tenant.WithTenant(r.Context(), tn)))
+}
+
+// SetScannerConfigHandlers sets the per-tenant scanner config handlers keyed by tenant ID
+func (t *TenantRouter) SetScannerConfigHandlers(configs map[string]*ScannerConfigHandler) {
+	t.configs = configs
+}
+
+// HandleScannerConfig resolves the tenant and delegates to its scanner config handler
+func (t *TenantRouter) HandleScannerConfig(w http.ResponseWriter, r *http.Request) {
+	tn, ok := t.tenant(w, r)
+	if !ok {
+		return
+	}
+	h, ok := t.configs[tn.ID]
+	if !ok {
+		metrics.RejectedRequests.Inc("tenant_not_started")
+		http.Error(w, "Tenant unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	h.HandleScannerConfig(w, r.WithContext(tenant.WithTenant(r.Context(), tn)))
+}
+
+// HandleDetectV2 resolves the tenant and delegates to its v2 detection handler
+func (t *TenantRouter) HandleDetectV2(w http.ResponseWriter, r *http.Request) {
+	if h, r := t.route(w, r); h != nil {
+		h.HandleDetectV2(w, r)
+	}
+}
+
+// route finds the tenant's handler and attaches the tenant to the request context.
+// Requests that cannot be routed are answered here and get a nil handler.
+func (t *TenantRouter) route(w http.ResponseWriter, r *http.Request) (*DetectionHandler, *http.Request) {
+	tn, ok := t.tenant(w, r)
+	if !ok {
+		return nil, r
+	}
+	h, ok := t.handlers[tn.ID]
+	if !ok {
+		metrics.RejectedRequests.Inc("tenant_not_started")
+		http.Error(w, "Tenant unavailable", http.StatusServiceUnavailable)
+		return nil, r
+	}
+	return h, r.WithContext(tenant.WithTenant(r.Context(), tn))
+}
+
+// tenant finds the tenant owning the request's API key; unknown keys are answered here
+func (t *TenantRouter) tenant(w http.ResponseWriter, r *http.Request) (*tenant.Tenant, bool) {
+	tn, ok := t.registry.ByAPIKey(r.Header.Get("X-API-Key"))
+	if !ok {
+		metrics.RejectedRequests.Inc("unknown_api_key")
+		log.Printf("🚫 Rejected request to %s from %s: unknown API key", r.URL.Path, r.RemoteAddr)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	}
+	return tn, ok
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+	"med-pulse-bot/internal/services"
+	"med-pulse-bot/internal/tenant"
+)
+
+// tenantRecordingService remembers which tenant each detection was processed for
+type tenantRecordingService struct {
+	tenants []string
+}
+
+func (s *tenantRecordingService) ProcessDetection(ctx context.Context, req *models.DetectionRequest) error {
+	s.tenants = append(s.tenants, tenant.IDFromContext(ctx))
+	return nil
+}
+
+func TestTenantRouter(t *testing.T) {
+	registry, err := tenant.NewRegistry([]*tenant.Tenant{
+		{ID: "clinic-a", APIKeys: []string{"key-a"}, CollectionPrefix: "a_"},
+		{ID: "clinic-b", APIKeys: []string{"key-b"}, CollectionPrefix: "b_"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serviceA, serviceB := &tenantRecordingService{}, &tenantRecordingService{}
+	router := NewTenantRouter(registry, map[string]*DetectionHandler{
+		"clinic-a": NewDetectionHandler(serviceA),
+		"clinic-b": NewDetectionHandler(serviceB),
+	})
+
+	tests := []struct {
+		name       string
+		apiKey     string
+		wantStatus int
+		wantA      int
+		wantB      int
+	}{
+		{"tenant a", "key-a", http.StatusOK, 1, 0},
+		{"tenant b", "key-b", http.StatusOK, 1, 1},
+		{"unknown key", "key-c", http.StatusUnauthorized, 1, 1},
+		{"missing key", "", http.StatusUnauthorized, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := []byte(`{"scanner_mac":"AA:BB:CC:DD:EE:FF","mac_address":"11:22:33:44:55:66","rssi":-50}`)
+			req := httptest.NewRequest(http.MethodPost, "/api/detect", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.apiKey != "" {
+				req.Header.Set("X-API-Key", tt.apiKey)
+			}
+			rec := httptest.NewRecorder()
+			router.HandleDetect(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if len(serviceA.tenants) != tt.wantA || len(serviceB.tenants) != tt.wantB {
+				t.Errorf("detections a=%d b=%d, want a=%d b=%d",
+					len(serviceA.tenants), len(serviceB.tenants), tt.wantA, tt.wantB)
+			}
+		})
+	}
+
+	for _, got := range serviceA.tenants {
+		if got != "clinic-a" {
+			t.Errorf("tenant a service saw context tenant %q", got)
+		}
+	}
+}
+
+func TestTenantRouterHeartbeat(t *testing.T) {
+	registry, err := tenant.NewRegistry([]*tenant.Tenant{
+		{ID: "clinic-a", APIKeys: []string{"key-a"}, CollectionPrefix: "a_"},
+		{ID: "clinic-b", APIKeys: []string{"key-b"}, CollectionPrefix: "b_"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	heartbeats := make(map[string]*HeartbeatHandler)
+	var codeA string
+	for _, id := range []string{"clinic-a", "clinic-b"} {
+		store := memory.NewStore(nil)
+		pairing, err := services.NewScannerPairing(store.ScannerRecords(), services.NopNotifier{}, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id == "clinic-a" {
+			codeA, _, _ = pairing.NewCode("lobby", 1)
+		}
+		heartbeats[id] = NewHeartbeatHandler(store.ScannerRecords(), pairing)
+	}
+	router := NewTenantRouter(registry, nil)
+	router.SetHeartbeatHandlers(heartbeats)
+
+	tests := []struct {
+		name       string
+		apiKey     string
+		wantStatus int
+	}{
+		{"code of another tenant", "key-b", http.StatusForbidden},
+		{"unknown key", "key-c", http.StatusUnauthorized},
+		{"own tenant", "key-a", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"scanner_mac":"AA:BB:CC:DD:EE:FF","pairing_code":"` + codeA + `"}`
+			req := httptest.NewRequest(http.MethodPost, "/api/scanner/heartbeat", strings.NewReader(body))
+			req.Header.Set("X-API-Key", tt.apiKey)
+			rec := httptest.NewRecorder()
+			router.HandleHeartbeat(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+// Package leader elects one of several app instances sharing a PocketBase to run the
+// singleton components (schedulers, queue drains, Telegram polling). Leadership is a
+// lease with a TTL: the leader renews it, and a follower takes a new term once it expires.
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// LeaseName is the lease all instances of this app compete for
+const LeaseName = "med-pulse-bot"
+
+// Status is the elector's view of leadership, as reported by /readyz
+type Status struct {
+	Instance  string     `json:"instance"`
+	Leader    bool       `json:"leader"`
+	Holder    string     `json:"holder,omitempty"` // current leader as last seen; empty if unknown
+	Term      int        `json:"term,omitempty"`
+	Since     *time.Time `json:"since,omitempty"` // when this instance last gained or lost leadership
+	ExpiresAt *time.Time `json:"lease_expires_at,omitempty"`
+}
+
+// singleton is a component started with a context that ends when leadership is lost
+// or its own context is done
+type singleton struct {
+	ctx   context.Context
+	name  string
+	start func(ctx context.Context)
+}
+
+// Elector competes for the lease and runs singletons while this instance leads
+type Elector struct {
+	leases   repository.LeaseRepository
+	instance string
+	ttl      time.Duration
+	clock    clock.Clock
+
+	tickMu sync.Mutex // serializes Tick and Resign
+
+	mu         sync.Mutex
+	root       context.Context // set by Start
+	singletons []singleton
+	lease      *models.Lease   // the term held by this instance; nil when following
+	renewed    time.Time       // last successful take or renewal of lease
+	leaderCtx  context.Context // the singletons' context; nil when following
+	cancel     context.CancelFunc
+	seen       *models.Lease // latest term read from the repository
+	since      time.Time
+}
+
+// NewElector creates an elector for the instance; the lease expires ttl after its last renewal
+func NewElector(leases repository.LeaseRepository, instance string, ttl time.Duration) (*Elector, error) {
+	if instance == "" {
+		return nil, errors.New("instance ID must not be empty")
+	}
+	if ttl <= 0 {
+		return nil, errors.New("lease TTL must be positive")
+	}
+	return &Elector{leases: leases, instance: instance, ttl: ttl, clock: clock.Real{}}, nil
+}
+
+// SetClock replaces the time source, used by tests
+func (e *Elector) SetClock(c clock.Clock) {
+	e.clock = c
+}
+
+// Run registers a singleton component. start must not block: it launches background
+// work that stops when its context is done, which is when leadership is lost or ctx is
+// done. Without an elector start runs right away with ctx.
+func (e *Elector) Run(ctx context.Context, name string, start func(ctx context.Context)) {
+	if e == nil {
+		start(ctx)
+		return
+	}
+	s := singleton{ctx: ctx, name: name, start: start}
+	e.mu.Lock()
+	e.singletons = append(e.singletons, s)
+	leaderCtx := e.leaderCtx
+	e.mu.Unlock()
+	if leaderCtx != nil {
+		s.run(leaderCtx)
+	}
+}
+
+// run starts the singleton with a context ending with leaderCtx or its own, unless its
+// own is already done
+func (s singleton) run(leaderCtx context.Context) {
+	if s.ctx.Err() != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(leaderCtx)
+	stop := context.AfterFunc(s.ctx, cancel)
+	context.AfterFunc(ctx, func() { stop() })
+	s.start(ctx)
+}
+
+// IsLeader reports whether this instance runs the singletons; true without an elector
+func (e *Elector) IsLeader() bool {
+	if e == nil {
+		return true
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.cancel != nil
+}
+
+// Status returns the current leadership as seen by this instance
+func (e *Elector) Status() Status {
+	if e == nil {
+		return Status{Leader: true}
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	s := Status{Instance: e.instance, Leader: e.cancel != nil}
+	if !e.since.IsZero() {
+		since := e.since
+		s.Since = &since
+	}
+	if e.seen != nil {
+		expires := e.seen.ExpiresAt
+		s.Holder, s.Term, s.ExpiresAt = e.seen.Holder, e.seen.Term, &expires
+	}
+	return s
+}
+
+// Start ticks every third of the TTL until ctx is done
+func (e *Elector) Start(ctx context.Context) {
+	e.mu.Lock()
+	e.root = ctx
+	e.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(e.ttl / 3)
+		defer ticker.Stop()
+		e.tickLogged(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				e.tickLogged(ctx)
+			}
+		}
+	}()
+}
+
+func (e *Elector) tickLogged(ctx context.Context) {
+	if err := e.Tick(ctx); err != nil && ctx.Err() == nil {
+		log.Printf("⚠️  Leader election: %v", err)
+	}
+}
+
+// Tick renews the lease while leading, or takes a new term once the leader's has expired.
+// A leader that cannot renew steps down when its own lease runs out.
+func (e *Elector) Tick(ctx context.Context) error {
+	e.tickMu.Lock()
+	defer e.tickMu.Unlock()
+
+	now := e.clock.Now()
+	e.mu.Lock()
+	held, renewedAt := e.lease, e.renewed
+	e.mu.Unlock()
+
+	latest, err := e.leases.Latest(ctx, LeaseName)
+	if err != nil {
+		if held != nil && now.After(renewedAt.Add(e.ttl)) {
+			e.stepDown(now, "lease could not be renewed")
+		}
+		return err
+	}
+	e.mu.Lock()
+	e.seen = latest
+	e.mu.Unlock()
+
+	if held != nil {
+		if latest == nil || latest.Term != held.Term {
+			e.stepDown(now, "another instance took a newer term")
+			return nil
+		}
+		renewed := *held
+		renewed.ExpiresAt = now.Add(e.ttl)
+		if err := e.leases.Renew(ctx, &renewed); err != nil {
+			if now.After(renewedAt.Add(e.ttl)) {
+				e.stepDown(now, "lease could not be renewed")
+			}
+			return err
+		}
+		e.mu.Lock()
+		e.lease, e.seen, e.renewed = &renewed, &renewed, now
+		e.mu.Unlock()
+		return nil
+	}
+
+	if latest != nil && latest.Holder != e.instance && now.Before(latest.ExpiresAt) {
+		return nil // following a live leader
+	}
+
+	term := 1
+	if latest != nil {
+		term = latest.Term + 1
+	}
+	lease := &models.Lease{Name: LeaseName, Term: term, Holder: e.instance, ExpiresAt: now.Add(e.ttl)}
+	if err := e.leases.Create(ctx, lease); err != nil {
+		if errors.Is(err, repository.ErrLeaseTaken) {
+			return nil // another follower won this term
+		}
+		return err
+	}
+	e.becomeLeader(now, lease, latest)
+	return nil
+}
+
+// Resign steps down and expires the lease right away, so a follower takes over
+// without waiting for it to run out
+func (e *Elector) Resign(ctx context.Context) {
+	if e == nil {
+		return
+	}
+	e.tickMu.Lock()
+	defer e.tickMu.Unlock()
+
+	e.mu.Lock()
+	held := e.lease
+	e.mu.Unlock()
+	if held == nil {
+		return
+	}
+
+	now := e.clock.Now()
+	released := *held
+	released.ExpiresAt = now
+	if err := e.leases.Renew(ctx, &released); err != nil {
+		log.Printf("⚠️  Failed to release leader lease: %v", err)
+	}
+	e.stepDown(now, "shutting down")
+}
+
+// becomeLeader records the new term and starts the singletons
+func (e *Elector) becomeLeader(now time.Time, lease, previous *models.Lease) {
+	from := "no previous leader"
+	if previous != nil {
+		from = fmt.Sprintf("after %s's term %d", previous.Holder, previous.Term)
+	}
+	log.Printf("👑 Instance %s is now the leader (term %d, %s)", e.instance, lease.Term, from)
+
+	e.mu.Lock()
+	e.lease, e.seen, e.renewed, e.since = lease, lease, now, now
+	e.leaderCtx, e.cancel = context.WithCancel(e.rootContext())
+	leaderCtx, singletons := e.leaderCtx, append([]singleton(nil), e.singletons...)
+	e.mu.Unlock()
+
+	for _, s := range singletons {
+		if s.ctx.Err() != nil {
+			continue
+		}
+		log.Printf("👑 Starting %s", s.name)
+		s.run(leaderCtx)
+	}
+}
+
+// stepDown stops the singletons
+func (e *Elector) stepDown(now time.Time, reason string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.lease == nil {
+		return
+	}
+	log.Printf("🔻 Instance %s is no longer the leader (term %d): %s", e.instance, e.lease.Term, reason)
+	e.cancel()
+	e.lease, e.leaderCtx, e.cancel, e.since = nil, nil, nil, now
+}
+
+// rootContext is the context leadership contexts derive from; callers hold mu
+func (e *Elector) rootContext() context.Context {
+	if e.root != nil {
+		return e.root
+	}
+	return context.Background()
+}
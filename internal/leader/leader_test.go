@@ -0,0 +1,185 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+// flakyLeases fails every call while down, like an unreachable PocketBase
+type flakyLeases struct {
+	repository.LeaseRepository
+	down bool
+}
+
+func (f *flakyLeases) Latest(ctx context.Context, name string) (*models.Lease, error) {
+	if f.down {
+		return nil, errors.New("connection refused")
+	}
+	return f.LeaseRepository.Latest(ctx, name)
+}
+
+// instance is an elector with a singleton that records whether it is running
+type instance struct {
+	elector *Elector
+	running context.Context
+}
+
+func (i *instance) active() bool {
+	return i.running != nil && i.running.Err() == nil
+}
+
+func newInstance(t *testing.T, leases repository.LeaseRepository, clk clock.Clock, id string) *instance {
+	t.Helper()
+	e, err := NewElector(leases, id, 30*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetClock(clk)
+	i := &instance{elector: e}
+	e.Run(context.Background(), "scheduler", func(ctx context.Context) { i.running = ctx })
+	return i
+}
+
+func TestElector(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+
+	tick := func(t *testing.T, instances ...*instance) {
+		t.Helper()
+		for _, i := range instances {
+			if err := i.elector.Tick(ctx); err != nil {
+				t.Fatalf("%s: %v", i.elector.instance, err)
+			}
+		}
+	}
+	wantLeader := func(t *testing.T, leader *instance, others ...*instance) {
+		t.Helper()
+		if !leader.elector.IsLeader() || !leader.active() {
+			t.Errorf("%s: leader=%v singleton running=%v, want both", leader.elector.instance, leader.elector.IsLeader(), leader.active())
+		}
+		for _, o := range others {
+			if o.elector.IsLeader() || o.active() {
+				t.Errorf("%s: leader=%v singleton running=%v, want a follower", o.elector.instance, o.elector.IsLeader(), o.active())
+			}
+		}
+	}
+
+	t.Run("follower takes over when the lease expires", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		store := memory.NewStore(clk)
+		a, b := newInstance(t, store.Leases(), clk, "a"), newInstance(t, store.Leases(), clk, "b")
+
+		tick(t, a, b)
+		wantLeader(t, a, b)
+
+		// a renews on time, so b keeps following
+		for n := 0; n < 3; n++ {
+			clk.Advance(10 * time.Second)
+			tick(t, a, b)
+		}
+		wantLeader(t, a, b)
+		if st := b.elector.Status(); st.Holder != "a" || st.Term != 1 {
+			t.Errorf("follower status = %+v, want holder a in term 1", st)
+		}
+
+		// a hangs; b waits out the TTL and takes term 2
+		clk.Advance(20 * time.Second)
+		tick(t, b)
+		wantLeader(t, a, b)
+		clk.Advance(11 * time.Second)
+		tick(t, b)
+		if !b.elector.IsLeader() || !b.active() {
+			t.Fatal("b did not take over after the lease expired")
+		}
+
+		// a wakes up, sees the newer term and stops its singletons
+		tick(t, a)
+		wantLeader(t, b, a)
+		if st := a.elector.Status(); st.Holder != "b" || st.Term != 2 || st.Since == nil {
+			t.Errorf("demoted status = %+v, want holder b in term 2", st)
+		}
+	})
+
+	t.Run("resigning hands over without waiting for the TTL", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		store := memory.NewStore(clk)
+		a, b := newInstance(t, store.Leases(), clk, "a"), newInstance(t, store.Leases(), clk, "b")
+
+		tick(t, a, b)
+		a.elector.Resign(ctx)
+		if a.elector.IsLeader() || a.active() {
+			t.Error("a still leads after resigning")
+		}
+		clk.Advance(time.Second)
+		tick(t, b)
+		wantLeader(t, b, a)
+	})
+
+	t.Run("leader that cannot reach the lease steps down after the TTL", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		leases := &flakyLeases{LeaseRepository: memory.NewStore(clk).Leases()}
+		a := newInstance(t, leases, clk, "a")
+		tick(t, a)
+
+		leases.down = true
+		clk.Advance(20 * time.Second)
+		if err := a.elector.Tick(ctx); err == nil {
+			t.Fatal("expected an error while the lease is unreachable")
+		}
+		if !a.elector.IsLeader() {
+			t.Error("stepped down before the lease expired")
+		}
+		clk.Advance(11 * time.Second)
+		a.elector.Tick(ctx)
+		if a.elector.IsLeader() || a.active() {
+			t.Error("still leading after the lease expired without renewal")
+		}
+	})
+
+	t.Run("singleton stops with its own context", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		a := newInstance(t, memory.NewStore(clk).Leases(), clk, "a")
+		own, stop := context.WithCancel(ctx)
+		var polling context.Context
+		a.elector.Run(own, "polling", func(ctx context.Context) { polling = ctx })
+
+		tick(t, a)
+		if polling == nil || polling.Err() != nil {
+			t.Fatal("singleton not running after taking the lease")
+		}
+		stop()
+		select {
+		case <-polling.Done():
+		case <-time.After(time.Second):
+			t.Error("singleton still running after its own context ended")
+		}
+		if !a.elector.IsLeader() || !a.active() {
+			t.Error("stopping one singleton affected leadership or the others")
+		}
+
+		// Not restarted in a later term
+		polling = nil
+		a.elector.Resign(ctx)
+		clk.Advance(time.Second)
+		tick(t, a)
+		if polling != nil {
+			t.Error("stopped singleton restarted in the next term")
+		}
+	})
+
+	t.Run("nil elector runs everything", func(t *testing.T) {
+		var e *Elector
+		ran := false
+		e.Run(ctx, "scheduler", func(context.Context) { ran = true })
+		if !ran || !e.IsLeader() {
+			t.Error("a nil elector should run singletons right away and lead")
+		}
+	})
+}
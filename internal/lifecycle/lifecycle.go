@@ -0,0 +1,260 @@
+// Package lifecycle starts the app's long-running components in dependency order and
+// stops them in reverse, each within its own timeout, so nothing is torn down while a
+// component that depends on it is still running.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultStopTimeout bounds a component's Stop when it sets no timeout of its own
+const DefaultStopTimeout = 5 * time.Second
+
+// State is where a component is in its lifecycle
+type State string
+
+const (
+	StateRegistered  State = "registered"
+	StateStarting    State = "starting"
+	StateRunning     State = "running"
+	StateFailed      State = "failed" // Start returned an error
+	StateStopping    State = "stopping"
+	StateStopped     State = "stopped"
+	StateStopTimeout State = "stop_timeout" // Stop did not return in time and was abandoned
+)
+
+// Component is one long-running part of the app
+type Component struct {
+	Name      string
+	DependsOn []string // started before and stopped after this component
+
+	// Start must not block: it launches background work and returns. Nil for
+	// components that only need a Stop.
+	Start func(ctx context.Context) error
+	// Stop shuts the component down, returning by the time ctx is done. Nil for
+	// components whose work simply ends.
+	Stop        func(ctx context.Context) error
+	StopTimeout time.Duration // 0 uses DefaultStopTimeout
+}
+
+// ComponentStatus is a component's state as reported on /readyz
+type ComponentStatus struct {
+	Name  string `json:"name"`
+	State State  `json:"state"`
+	Error string `json:"error,omitempty"`
+}
+
+// component is a registered Component and its state
+type component struct {
+	Component
+	state State
+	err   error
+}
+
+// Manager owns the app's components
+type Manager struct {
+	mu         sync.Mutex
+	components []*component // in registration order
+	byName     map[string]*component
+	started    []*component // in start order
+}
+
+// NewManager creates an empty manager
+func NewManager() *Manager {
+	return &Manager{byName: make(map[string]*component)}
+}
+
+// Register adds a component; dependencies may be registered later, before Start
+func (m *Manager) Register(c Component) error {
+	if c.Name == "" {
+		return errors.New("component name must not be empty")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.byName[c.Name]; ok {
+		return fmt.Errorf("component %s registered twice", c.Name)
+	}
+	comp := &component{Component: c, state: StateRegistered}
+	m.components = append(m.components, comp)
+	m.byName[c.Name] = comp
+	return nil
+}
+
+// Start starts every component after its dependencies, in registration order otherwise.
+// If one fails to start, those already started are stopped again.
+func (m *Manager) Start(ctx context.Context) error {
+	order, err := m.order()
+	if err != nil {
+		return err
+	}
+
+	for _, c := range order {
+		m.setState(c, StateStarting, nil)
+		if c.Start != nil {
+			if err := c.Start(ctx); err != nil {
+				m.setState(c, StateFailed, err)
+				log.Printf("❌ Component %s failed to start: %v", c.Name, err)
+				m.Stop(context.WithoutCancel(ctx))
+				return fmt.Errorf("failed to start %s: %w", c.Name, err)
+			}
+		}
+		m.mu.Lock()
+		m.started = append(m.started, c)
+		m.mu.Unlock()
+		m.setState(c, StateRunning, nil)
+	}
+	log.Printf("▶️  Started %d components", len(order))
+	return nil
+}
+
+// Stop stops the started components in reverse start order. A component whose Stop
+// outlives its timeout is abandoned so the rest still stop.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	started := m.started
+	m.started = nil
+	m.mu.Unlock()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		if err := m.stop(ctx, started[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// stop runs one component's Stop within its timeout
+func (m *Manager) stop(ctx context.Context, c *component) error {
+	m.setState(c, StateStopping, nil)
+	if c.Stop == nil {
+		m.setState(c, StateStopped, nil)
+		return nil
+	}
+
+	timeout := c.StopTimeout
+	if timeout <= 0 {
+		timeout = DefaultStopTimeout
+	}
+	stopCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- c.Stop(stopCtx) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			m.setState(c, StateStopped, err)
+			log.Printf("⚠️  Component %s stopped with an error: %v", c.Name, err)
+			return fmt.Errorf("failed to stop %s: %w", c.Name, err)
+		}
+		m.setState(c, StateStopped, nil)
+		return nil
+	case <-stopCtx.Done():
+		m.setState(c, StateStopTimeout, stopCtx.Err())
+		log.Printf("⏱️  Component %s did not stop within %s, moving on", c.Name, timeout)
+		return fmt.Errorf("stopping %s: %w", c.Name, stopCtx.Err())
+	}
+}
+
+// Status returns every component's state in registration order
+func (m *Manager) Status() []ComponentStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	statuses := make([]ComponentStatus, len(m.components))
+	for i, c := range m.components {
+		statuses[i] = ComponentStatus{Name: c.Name, State: c.state}
+		if c.err != nil {
+			statuses[i].Error = c.err.Error()
+		}
+	}
+	return statuses
+}
+
+// Running reports whether every component is running
+func (m *Manager) Running() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range m.components {
+		if c.state != StateRunning {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Manager) setState(c *component, state State, err error) {
+	m.mu.Lock()
+	c.state, c.err = state, err
+	m.mu.Unlock()
+}
+
+// order sorts the components so each comes after its dependencies, rejecting unknown
+// dependencies and cycles
+func (m *Manager) order() ([]*component, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	mark := make(map[*component]int, len(m.components))
+	var order []*component
+	var visit func(c *component, path []string) error
+	visit = func(c *component, path []string) error {
+		switch mark[c] {
+		case visiting:
+			return fmt.Errorf("dependency cycle: %v", append(path, c.Name))
+		case done:
+			return nil
+		}
+		mark[c] = visiting
+		for _, name := range c.DependsOn {
+			dep, ok := m.byName[name]
+			if !ok {
+				return fmt.Errorf("component %s depends on unknown component %s", c.Name, name)
+			}
+			if err := visit(dep, append(path, c.Name)); err != nil {
+				return err
+			}
+		}
+		mark[c] = done
+		order = append(order, c)
+		return nil
+	}
+
+	for _, c := range m.components {
+		if err := visit(c, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Background is a component for work in the elector.Run style: start launches
+// goroutines that run until its context is done. Stop cancels that context.
+func Background(name string, dependsOn []string, start func(ctx context.Context)) Component {
+	var cancel context.CancelFunc
+	return Component{
+		Name:      name,
+		DependsOn: dependsOn,
+		Start: func(ctx context.Context) error {
+			var runCtx context.Context
+			// The component outlives the context it was started with
+			runCtx, cancel = context.WithCancel(context.WithoutCancel(ctx))
+			start(runCtx)
+			return nil
+		},
+		Stop: func(context.Context) error {
+			cancel()
+			return nil
+		},
+	}
+}
@@ -0,0 +1,178 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder notes the order components start and stop in
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	r.events = append(r.events, event)
+	r.mu.Unlock()
+}
+
+func (r *recorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.events, " ")
+}
+
+// component records its start and stop in r
+func (r *recorder) component(name string, deps ...string) Component {
+	return Component{
+		Name:      name,
+		DependsOn: deps,
+		Start:     func(context.Context) error { r.add("+" + name); return nil },
+		Stop:      func(context.Context) error { r.add("-" + name); return nil },
+	}
+}
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("dependency order", func(t *testing.T) {
+		r := &recorder{}
+		m := NewManager()
+		m.Register(r.component("http_server", "schedulers", "telegram_bot"))
+		m.Register(r.component("telegram_bot", "schedulers"))
+		m.Register(r.component("schedulers", "leader_election"))
+		m.Register(r.component("leader_election"))
+		m.Register(r.component("probe"))
+
+		if err := m.Start(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if !m.Running() {
+			t.Errorf("status = %+v, want all running", m.Status())
+		}
+		if err := m.Stop(ctx); err != nil {
+			t.Fatal(err)
+		}
+		want := "+leader_election +schedulers +telegram_bot +http_server +probe " +
+			"-probe -http_server -telegram_bot -schedulers -leader_election"
+		if got := r.String(); got != want {
+			t.Errorf("events = %s\nwant     %s", got, want)
+		}
+		for _, s := range m.Status() {
+			if s.State != StateStopped {
+				t.Errorf("%s is %s after Stop", s.Name, s.State)
+			}
+		}
+	})
+
+	t.Run("invalid registrations", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			components []Component
+			wantErr    string
+		}{
+			{"unknown dependency", []Component{{Name: "a", DependsOn: []string{"missing"}}}, "unknown component missing"},
+			{"cycle", []Component{{Name: "a", DependsOn: []string{"b"}}, {Name: "b", DependsOn: []string{"a"}}}, "dependency cycle"},
+			{"self dependency", []Component{{Name: "a", DependsOn: []string{"a"}}}, "dependency cycle"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				m := NewManager()
+				for _, c := range tt.components {
+					m.Register(c)
+				}
+				if err := m.Start(ctx); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Start() error = %v, want %q", err, tt.wantErr)
+				}
+			})
+		}
+
+		m := NewManager()
+		m.Register(Component{Name: "a"})
+		if err := m.Register(Component{Name: "a"}); err == nil {
+			t.Error("registered the same name twice")
+		}
+		if err := m.Register(Component{}); err == nil {
+			t.Error("registered a component without a name")
+		}
+	})
+
+	t.Run("failed start stops what was started", func(t *testing.T) {
+		r := &recorder{}
+		m := NewManager()
+		m.Register(r.component("store"))
+		m.Register(Component{Name: "http_server", DependsOn: []string{"store"}, Start: func(context.Context) error {
+			return errors.New("address already in use")
+		}})
+		m.Register(r.component("bot", "http_server"))
+
+		if err := m.Start(ctx); err == nil || !strings.Contains(err.Error(), "http_server") {
+			t.Fatalf("Start() error = %v", err)
+		}
+		if got := r.String(); got != "+store -store" {
+			t.Errorf("events = %s, want the store started and stopped again", got)
+		}
+		states := map[string]State{}
+		for _, s := range m.Status() {
+			states[s.Name] = s.State
+		}
+		if states["http_server"] != StateFailed || states["bot"] != StateRegistered || states["store"] != StateStopped {
+			t.Errorf("states = %v", states)
+		}
+	})
+
+	t.Run("hanging stop is abandoned", func(t *testing.T) {
+		r := &recorder{}
+		m := NewManager()
+		m.Register(r.component("store"))
+		release := make(chan struct{})
+		defer close(release)
+		m.Register(Component{
+			Name:        "queue",
+			DependsOn:   []string{"store"},
+			Stop:        func(context.Context) error { <-release; return nil }, // ignores ctx
+			StopTimeout: 20 * time.Millisecond,
+		})
+		m.Register(r.component("http_server", "queue"))
+		m.Start(ctx)
+
+		begin := time.Now()
+		err := m.Stop(ctx)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Stop() error = %v, want the queue's timeout", err)
+		}
+		if elapsed := time.Since(begin); elapsed > time.Second {
+			t.Errorf("Stop() took %s", elapsed)
+		}
+		if got := r.String(); got != "+store +http_server -http_server -store" {
+			t.Errorf("events = %s, want the others stopped in order", got)
+		}
+		for _, s := range m.Status() {
+			if s.Name == "queue" && (s.State != StateStopTimeout || s.Error == "") {
+				t.Errorf("queue status = %+v, want stop_timeout", s)
+			}
+		}
+	})
+
+	t.Run("background component", func(t *testing.T) {
+		startCtx, cancelStart := context.WithCancel(ctx)
+		var running context.Context
+		m := NewManager()
+		m.Register(Background("poller", nil, func(ctx context.Context) { running = ctx }))
+		m.Start(startCtx)
+
+		cancelStart()
+		if running.Err() != nil {
+			t.Fatal("background work ended with the start context")
+		}
+		m.Stop(ctx)
+		if running.Err() == nil {
+			t.Error("background work still running after Stop")
+		}
+	})
+}
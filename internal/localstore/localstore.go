@@ -0,0 +1,27 @@
+// Package localstore keeps the bot's own state (detection queue, smoothing snapshot)
+// outside the attendance data, in a backend chosen by LOCAL_STORE
+package localstore
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound is returned by Get for a key without a value
+var ErrNotFound = errors.New("local store key not found")
+
+// LocalStore holds values replaced as a whole (snapshots) and append-only record lists
+// (queues). A key holds either a value or a list, never both. Values and records are
+// text, usually JSON; records must not contain newlines.
+type LocalStore interface {
+	// Get returns the value under key, or ErrNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Put replaces the value under key
+	Put(ctx context.Context, key string, value []byte) error
+	// Append adds records to the end of the list under key
+	Append(ctx context.Context, key string, records ...[]byte) error
+	// Records returns the list under key, oldest first; a missing list is empty
+	Records(ctx context.Context, key string) ([][]byte, error)
+	// Delete removes the value or list under key; a missing key is not an error
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,41 @@
+package localstore_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"med-pulse-bot/internal/localstore"
+	"med-pulse-bot/internal/localstore/localstoretest"
+)
+
+func TestFileStore(t *testing.T) {
+	localstoretest.Run(t, func(t *testing.T) localstore.LocalStore {
+		return localstore.NewFileStore(filepath.Join(t.TempDir(), "data"))
+	})
+
+	t.Run("existing files are read", func(t *testing.T) {
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, "detection_queue.jsonl"), []byte("{\"n\":1}\n\n{\"n\":2}\n"), 0o644)
+		records, err := localstore.NewFileStore(dir).Records(context.Background(), "detection_queue.jsonl")
+		if err != nil || len(records) != 2 {
+			t.Errorf("Records() = %q, %v, want the two lines", records, err)
+		}
+	})
+
+	t.Run("keys stay inside the directory", func(t *testing.T) {
+		s := localstore.NewFileStore(t.TempDir())
+		for _, key := range []string{"", "..", "../escape.json", "sub/key.json"} {
+			if err := s.Put(context.Background(), key, []byte(`{}`)); err == nil {
+				t.Errorf("Put(%q) succeeded", key)
+			}
+		}
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	localstoretest.Run(t, func(t *testing.T) localstore.LocalStore {
+		return localstore.NewMemoryStore()
+	})
+}
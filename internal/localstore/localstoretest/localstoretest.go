@@ -0,0 +1,139 @@
+// Package localstoretest is the conformance suite every LocalStore backend must pass
+package localstoretest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"med-pulse-bot/internal/localstore"
+)
+
+// Run checks the LocalStore contract against stores made by newStore, one per subtest
+func Run(t *testing.T, newStore func(t *testing.T) localstore.LocalStore) {
+	ctx := context.Background()
+
+	t.Run("missing value", func(t *testing.T) {
+		s := newStore(t)
+		if _, err := s.Get(ctx, "snapshot.json"); !errors.Is(err, localstore.ErrNotFound) {
+			t.Errorf("Get() error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("put replaces the value", func(t *testing.T) {
+		s := newStore(t)
+		for _, value := range []string{`{"version":1}`, `{"version":2,"devices":{}}`} {
+			if err := s.Put(ctx, "snapshot.json", []byte(value)); err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+			got, err := s.Get(ctx, "snapshot.json")
+			if err != nil || string(got) != value {
+				t.Errorf("Get() = %q, %v, want %q", got, err, value)
+			}
+		}
+	})
+
+	t.Run("delete value", func(t *testing.T) {
+		s := newStore(t)
+		s.Put(ctx, "snapshot.json", []byte(`{}`))
+		if err := s.Delete(ctx, "snapshot.json"); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		if _, err := s.Get(ctx, "snapshot.json"); !errors.Is(err, localstore.ErrNotFound) {
+			t.Errorf("Get() after Delete error = %v, want ErrNotFound", err)
+		}
+		if err := s.Delete(ctx, "snapshot.json"); err != nil {
+			t.Errorf("Delete() of a missing key error = %v", err)
+		}
+	})
+
+	t.Run("missing list is empty", func(t *testing.T) {
+		s := newStore(t)
+		if records, err := s.Records(ctx, "queue.jsonl"); err != nil || len(records) != 0 {
+			t.Errorf("Records() = %q, %v, want empty", records, err)
+		}
+	})
+
+	t.Run("records kept in order", func(t *testing.T) {
+		s := newStore(t)
+		if err := s.Append(ctx, "queue.jsonl", []byte(`{"n":1}`), []byte(`{"n":2}`)); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+		if err := s.Append(ctx, "queue.jsonl", []byte(`{"n":3,"name":"สมชาย"}`)); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+		records, err := s.Records(ctx, "queue.jsonl")
+		if err != nil {
+			t.Fatalf("Records() error = %v", err)
+		}
+		if got := fmt.Sprintf("%s", records); got != `[{"n":1} {"n":2} {"n":3,"name":"สมชาย"}]` {
+			t.Errorf("Records() = %s", got)
+		}
+	})
+
+	t.Run("delete list", func(t *testing.T) {
+		s := newStore(t)
+		s.Append(ctx, "queue.jsonl", []byte(`{"n":1}`), []byte(`{"n":2}`))
+		if err := s.Delete(ctx, "queue.jsonl"); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		if records, err := s.Records(ctx, "queue.jsonl"); err != nil || len(records) != 0 {
+			t.Errorf("Records() after Delete = %q, %v", records, err)
+		}
+		s.Append(ctx, "queue.jsonl", []byte(`{"n":3}`))
+		if records, _ := s.Records(ctx, "queue.jsonl"); len(records) != 1 {
+			t.Errorf("Records() after a new Append = %q, want one record", records)
+		}
+	})
+
+	t.Run("keys are independent", func(t *testing.T) {
+		s := newStore(t)
+		s.Put(ctx, "a.json", []byte(`"a"`))
+		s.Put(ctx, "b.json", []byte(`"b"`))
+		s.Append(ctx, "a.jsonl", []byte(`1`))
+		s.Append(ctx, "b.jsonl", []byte(`2`))
+		s.Delete(ctx, "a.json")
+		s.Delete(ctx, "a.jsonl")
+
+		if got, err := s.Get(ctx, "b.json"); err != nil || string(got) != `"b"` {
+			t.Errorf("Get(b.json) = %q, %v", got, err)
+		}
+		if records, _ := s.Records(ctx, "b.jsonl"); len(records) != 1 || string(records[0]) != `2` {
+			t.Errorf("Records(b.jsonl) = %q", records)
+		}
+	})
+
+	t.Run("returned bytes are copies", func(t *testing.T) {
+		s := newStore(t)
+		value := []byte(`"kept"`)
+		s.Put(ctx, "snapshot.json", value)
+		value[1] = 'X'
+		got, _ := s.Get(ctx, "snapshot.json")
+		got[1] = 'Y'
+		if again, _ := s.Get(ctx, "snapshot.json"); string(again) != `"kept"` {
+			t.Errorf("Get() = %q after the caller changed its bytes", again)
+		}
+	})
+
+	t.Run("concurrent appends", func(t *testing.T) {
+		s := newStore(t)
+		var wg sync.WaitGroup
+		for w := 0; w < 5; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < 4; i++ {
+					if err := s.Append(ctx, "queue.jsonl", []byte(fmt.Sprintf(`{"w":%d,"i":%d}`, w, i))); err != nil {
+						t.Errorf("Append() error = %v", err)
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+		if records, err := s.Records(ctx, "queue.jsonl"); err != nil || len(records) != 20 {
+			t.Errorf("Records() = %d records, %v, want 20", len(records), err)
+		}
+	})
+}
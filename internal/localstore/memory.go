@@ -0,0 +1,63 @@
+package localstore
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryStore keeps everything in process memory. Its contents are lost on restart:
+// queued detections are dropped and smoothing starts empty.
+type MemoryStore struct {
+	mu     sync.Mutex
+	values map[string][]byte
+	lists  map[string][][]byte
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{values: make(map[string][]byte), lists: make(map[string][][]byte)}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.values[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), value...), nil
+}
+
+func (s *MemoryStore) Put(ctx context.Context, key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = append([]byte(nil), value...)
+	return nil
+}
+
+func (s *MemoryStore) Append(ctx context.Context, key string, records ...[]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rec := range records {
+		s.lists[key] = append(s.lists[key], append([]byte(nil), rec...))
+	}
+	return nil
+}
+
+func (s *MemoryStore) Records(ctx context.Context, key string) ([][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var records [][]byte
+	for _, rec := range s.lists[key] {
+		records = append(records, append([]byte(nil), rec...))
+	}
+	return records, nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	delete(s.lists, key)
+	return nil
+}
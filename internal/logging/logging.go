@@ -0,0 +1,71 @@
+// Package logging configures the process-wide structured logger and carries per-request
+// attributes (scanner MAC, device MAC) through a context, so one check-in's lifecycle can
+// be followed across the handler, the pipeline and the repositories
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Setup makes a handler of the given format ("text" or "json") writing to w at the given
+// level the default logger. Calls to the log package are routed through it at info level.
+// Records above debug have device MACs masked and chat IDs dropped (see MaskMAC).
+func Setup(w io.Writer, level, format string) error {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	var newHandler func(io.Writer, *slog.HandlerOptions) slog.Handler
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "text":
+		newHandler = func(w io.Writer, opts *slog.HandlerOptions) slog.Handler { return slog.NewTextHandler(w, opts) }
+	case "json":
+		newHandler = func(w io.Writer, opts *slog.HandlerOptions) slog.Handler { return slog.NewJSONHandler(w, opts) }
+	default:
+		return fmt.Errorf("invalid LOG_FORMAT %q: want text or json", format)
+	}
+	h := redactingHandler{
+		full:     newHandler(w, &slog.HandlerOptions{Level: lvl}),
+		redacted: newHandler(w, &slog.HandlerOptions{Level: lvl, ReplaceAttr: redactAttr}),
+	}
+	slog.SetDefault(slog.New(h))
+	return nil
+}
+
+// ParseLevel reads a level name (debug, info, warn or error); empty is info
+func ParseLevel(s string) (slog.Level, error) {
+	var lvl slog.Level
+	if strings.TrimSpace(s) == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := lvl.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return 0, fmt.Errorf("invalid LOG_LEVEL %q: want debug, info, warn or error", s)
+	}
+	return lvl, nil
+}
+
+type loggerKey struct{}
+
+// With returns a context whose logger adds the attributes (slog key-value pairs) to
+// every record
+func With(ctx context.Context, args ...any) context.Context {
+	return context.WithValue(ctx, loggerKey{}, From(ctx).With(args...))
+}
+
+// Discard returns a context whose logger drops every record, for lookups that must leave
+// no trace of the device they are about
+func Discard(ctx context.Context) context.Context {
+	return context.WithValue(ctx, loggerKey{}, slog.New(slog.DiscardHandler))
+}
+
+// From returns the context's logger, the default logger when none was attached
+func From(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
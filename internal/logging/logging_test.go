@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    slog.Level
+		wantErr bool
+	}{
+		{"", slog.LevelInfo, false},
+		{"debug", slog.LevelDebug, false},
+		{"WARN", slog.LevelWarn, false},
+		{" error ", slog.LevelError, false},
+		{"verbose", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseLevel(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevel(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("ParseLevel(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetup(t *testing.T) {
+	defaultLogger := slog.Default()
+	defer slog.SetDefault(defaultLogger)
+
+	if err := Setup(&bytes.Buffer{}, "info", "xml"); err == nil {
+		t.Error("Setup() with an unknown format succeeded")
+	}
+
+	var buf bytes.Buffer
+	if err := Setup(&buf, "info", "json"); err != nil {
+		t.Fatal(err)
+	}
+	ctx := With(context.Background(), "scanner_mac", "aa:bb:cc:dd:ee:ff")
+	ctx = With(ctx, "mac", "11:22:33:44:55:66")
+	From(ctx).Debug("hidden below the level")
+	From(ctx).Info("checked in", "status", "late")
+	log.Printf("from the log package")
+
+	var records []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var rec map[string]any
+		if err := json.Unmarshal(line, &rec); err != nil {
+			t.Fatalf("not JSON: %s", line)
+		}
+		records = append(records, rec)
+	}
+	if len(records) != 2 {
+		t.Fatalf("records = %v, want the info record and the log package line", records)
+	}
+	if r := records[0]; r["msg"] != "checked in" || r["scanner_mac"] != "aa:bb:cc:dd:ee:ff" || r["mac"] != "11:22:**:**:**:66" || r["status"] != "late" {
+		t.Errorf("record = %v, want the context attributes", r)
+	}
+	if r := records[1]; r["msg"] != "from the log package" || r["level"] != "INFO" {
+		t.Errorf("record = %v, want the log line at info", r)
+	}
+}
@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// macKeys are the attributes holding a device's MAC address, masked above debug. Scanner
+// MACs are infrastructure and stay readable.
+var macKeys = map[string]bool{"mac": true, "lookup_mac": true, "mac_address": true}
+
+// chatKeys are the attributes holding a Telegram chat ID, dropped above debug
+var chatKeys = map[string]bool{"chat_id": true, "telegram_chat_id": true}
+
+// MaskMAC keeps the first two and the last octet of a MAC address, enough to tell devices
+// apart in the logs without naming one: "AA:BB:CC:DD:EE:FF" becomes "AA:BB:**:**:**:FF".
+// A value that is not a MAC address is masked whole.
+func MaskMAC(mac string) string {
+	sep := ":"
+	if !strings.Contains(mac, sep) {
+		sep = "-"
+	}
+	parts := strings.Split(mac, sep)
+	if len(parts) != 6 {
+		if mac == "" {
+			return ""
+		}
+		return "**"
+	}
+	for i := 2; i < 5; i++ {
+		parts[i] = "**"
+	}
+	return strings.Join(parts, sep)
+}
+
+// redactAttr masks device MACs and drops chat IDs; it is the ReplaceAttr of the handler
+// writing records above debug
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	switch {
+	case macKeys[a.Key] && a.Value.Kind() == slog.KindString:
+		return slog.String(a.Key, MaskMAC(a.Value.String()))
+	case chatKeys[a.Key]:
+		return slog.Attr{}
+	}
+	return a
+}
+
+// redactingHandler writes debug records in full and the others through a handler that
+// redacts personal data, so LOG_LEVEL=debug is the only way to see it
+type redactingHandler struct {
+	full, redacted slog.Handler
+}
+
+func (h redactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.full.Enabled(ctx, level)
+}
+
+func (h redactingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level <= slog.LevelDebug {
+		return h.full.Handle(ctx, r)
+	}
+	return h.redacted.Handle(ctx, r)
+}
+
+func (h redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return redactingHandler{full: h.full.WithAttrs(attrs), redacted: h.redacted.WithAttrs(attrs)}
+}
+
+func (h redactingHandler) WithGroup(name string) slog.Handler {
+	return redactingHandler{full: h.full.WithGroup(name), redacted: h.redacted.WithGroup(name)}
+}
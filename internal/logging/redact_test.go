@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestMaskMAC(t *testing.T) {
+	tests := []struct {
+		mac  string
+		want string
+	}{
+		{"AA:BB:CC:DD:EE:FF", "AA:BB:**:**:**:FF"},
+		{"aa:bb:cc:dd:ee:01", "aa:bb:**:**:**:01"},
+		{"aa-bb-cc-dd-ee-01", "aa-bb-**-**-**-01"},
+		{"tag-42", "**"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mac, func(t *testing.T) {
+			if got := MaskMAC(tt.mac); got != tt.want {
+				t.Errorf("MaskMAC(%q) = %q, want %q", tt.mac, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetupRedacts(t *testing.T) {
+	defaultLogger := slog.Default()
+	defer slog.SetDefault(defaultLogger)
+
+	tests := []struct {
+		name  string
+		level slog.Level
+		want  []string
+		hide  []string
+	}{
+		{"info", slog.LevelInfo,
+			[]string{"mac=aa:bb:**:**:**:01", "lookup_mac=aa:bb:**:**:**:01", "scanner_mac=11:22:33:44:55:66", "device.mac_address=aa:bb:**:**:**:01"},
+			[]string{"cc:dd:ee", "chat_id", "424242"}},
+		{"warn", slog.LevelWarn,
+			[]string{"mac=aa:bb:**:**:**:01"},
+			[]string{"cc:dd:ee", "chat_id"}},
+		{"debug", slog.LevelDebug,
+			[]string{"mac=aa:bb:cc:dd:ee:01", "lookup_mac=aa:bb:cc:dd:ee:01", "chat_id=424242", "device.mac_address=aa:bb:cc:dd:ee:01"},
+			nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Setup(&buf, "debug", "text"); err != nil {
+				t.Fatal(err)
+			}
+			ctx := With(context.Background(), "scanner_mac", "11:22:33:44:55:66", "mac", "aa:bb:cc:dd:ee:01")
+			From(ctx).Log(ctx, tt.level, "lookup",
+				"lookup_mac", "aa:bb:cc:dd:ee:01", "chat_id", int64(424242),
+				slog.Group("device", "mac_address", "aa:bb:cc:dd:ee:01"))
+
+			got := buf.String()
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("record %q, want %q in it", got, w)
+				}
+			}
+			for _, h := range tt.hide {
+				if strings.Contains(got, h) {
+					t.Errorf("record %q, want no %q in it", got, h)
+				}
+			}
+		})
+	}
+}
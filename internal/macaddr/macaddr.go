@@ -0,0 +1,133 @@
+// Package macaddr normalizes and validates MAC addresses entered by users and scanners
+package macaddr
+
+import (
+	"errors"
+	"strings"
+)
+
+// AcceptedFormats lists example inputs accepted by Normalize, for user-facing error messages
+var AcceptedFormats = []string{
+	"AA:BB:CC:DD:EE:FF",
+	"AA-BB-CC-DD-EE-FF",
+	"AA BB CC DD EE FF",
+	"aabb.ccdd.eeff",
+	"AABBCCDDEEFF",
+}
+
+var (
+	// ErrInvalidLength is returned when the input does not contain exactly 12 hex digits
+	ErrInvalidLength = errors.New("MAC address must contain exactly 12 hex digits")
+	// ErrInvalidCharacter is returned when the input contains something other than hex digits and separators
+	ErrInvalidCharacter = errors.New("MAC address contains an invalid character")
+	// ErrInvalidScannerID is returned for a free-form scanner ID that is empty, too long or
+	// contains characters other than letters, digits, '-', '_', '.' and ':'
+	ErrInvalidScannerID = errors.New("scanner ID must be 1-64 letters, digits, '-', '_', '.' or ':'")
+)
+
+// maxScannerIDLength bounds free-form scanner IDs
+const maxScannerIDLength = 64
+
+// Normalize converts a MAC address in any supported notation into the canonical
+// lower-case colon-separated form (aa:bb:cc:dd:ee:ff).
+//
+// Colons, ASCII and unicode dashes, dots, spaces and other whitespace are treated as
+// separators and may be mixed freely; whatever remains must be exactly 12 hex digits.
+func Normalize(input string) (string, error) {
+	digits := make([]byte, 0, 12)
+
+	for _, r := range strings.TrimSpace(input) {
+		switch {
+		case isSeparator(r):
+			continue
+		case r >= '0' && r <= '9', r >= 'a' && r <= 'f':
+			digits = append(digits, byte(r))
+		case r >= 'A' && r <= 'F':
+			digits = append(digits, byte(r-'A'+'a'))
+		default:
+			return "", ErrInvalidCharacter
+		}
+		if len(digits) > 12 {
+			return "", ErrInvalidLength
+		}
+	}
+
+	if len(digits) != 12 {
+		return "", ErrInvalidLength
+	}
+
+	var b strings.Builder
+	b.Grow(17)
+	for i := 0; i < 12; i += 2 {
+		if i > 0 {
+			b.WriteByte(':')
+		}
+		b.Write(digits[i : i+2])
+	}
+	return b.String(), nil
+}
+
+// IsValid reports whether input normalizes to a MAC address
+func IsValid(input string) bool {
+	_, err := Normalize(input)
+	return err == nil
+}
+
+// NormalizeScannerID returns the canonical form of a scanner identifier. MAC addresses
+// are normalized as by Normalize. Anything else is rejected unless allowFreeform is set,
+// in which case it is lower-cased so that differently typed IDs of one scanner match.
+func NormalizeScannerID(input string, allowFreeform bool) (string, error) {
+	mac, err := Normalize(input)
+	if err == nil || !allowFreeform {
+		return mac, err
+	}
+
+	id := strings.ToLower(strings.TrimSpace(input))
+	if id == "" || len(id) > maxScannerIDLength {
+		return "", ErrInvalidScannerID
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_', r == '.', r == ':':
+		default:
+			return "", ErrInvalidScannerID
+		}
+	}
+	return id, nil
+}
+
+// Spellings returns the notations a canonical ID may have been stored in before
+// scanner IDs were normalized, starting with the canonical form itself
+func Spellings(canonical string) []string {
+	out := []string{canonical, strings.ToUpper(canonical)}
+	if !IsValid(canonical) {
+		return out
+	}
+	for _, sep := range []string{"-", ""} {
+		v := strings.ReplaceAll(canonical, ":", sep)
+		out = append(out, v, strings.ToUpper(v))
+	}
+	return out
+}
+
+// isSeparator reports whether r may appear between hex digits
+func isSeparator(r rune) bool {
+	switch r {
+	case ':', '-', '.', '_', ' ', '\t', '\n', '\r',
+		'\u00a0', // no-break space
+		'\u2007', // figure space
+		'\u202f', // narrow no-break space
+		'\u200b', // zero-width space
+		'\u2010', // hyphen
+		'\u2011', // non-breaking hyphen
+		'\u2012', // figure dash
+		'\u2013', // en dash
+		'\u2014', // em dash
+		'\u2015', // horizontal bar
+		'\u2212', // minus sign
+		'\uff1a', // fullwidth colon
+		'\uff0d': // fullwidth hyphen-minus
+		return true
+	}
+	return false
+}
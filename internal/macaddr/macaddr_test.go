@@ -0,0 +1,156 @@
+package macaddr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	const want = "aa:bb:cc:dd:ee:ff"
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr error
+	}{
+		// Accepted notations
+		{"Colon upper case", "AA:BB:CC:DD:EE:FF", want, nil},
+		{"Colon lower case", "aa:bb:cc:dd:ee:ff", want, nil},
+		{"Colon mixed case", "Aa:bB:cC:Dd:eE:Ff", want, nil},
+		{"Hyphen", "AA-BB-CC-DD-EE-FF", want, nil},
+		{"Spaces from phone screenshot", "AA BB CC DD EE FF", want, nil},
+		{"Double spaces", "AA  BB  CC  DD  EE  FF", want, nil},
+		{"Tabs", "AA\tBB\tCC\tDD\tEE\tFF", want, nil},
+		{"En dash", "AA–BB–CC–DD–EE–FF", want, nil},
+		{"Em dash", "AA—BB—CC—DD—EE—FF", want, nil},
+		{"Unicode hyphen", "AA‐BB‐CC‐DD‐EE‐FF", want, nil},
+		{"Non-breaking hyphen", "AA‑BB‑CC‑DD‑EE‑FF", want, nil},
+		{"Minus sign", "AA−BB−CC−DD−EE−FF", want, nil},
+		{"Fullwidth colon", "AA：BB：CC：DD：EE：FF", want, nil},
+		{"No-break spaces", "AA\u00a0BB\u00a0CC\u00a0DD\u00a0EE\u00a0FF", want, nil},
+		{"Zero-width spaces", "AA\u200bBB:CC:DD:EE:FF", want, nil},
+		{"Cisco dotted", "aabb.ccdd.eeff", want, nil},
+		{"Cisco dotted upper", "AABB.CCDD.EEFF", want, nil},
+		{"Bare hex", "AABBCCDDEEFF", want, nil},
+		{"Mixed separators", "AA:BB-CC DD.EE–FF", want, nil},
+		{"Surrounding whitespace", "  AA:BB:CC:DD:EE:FF \n", want, nil},
+		{"Underscores", "AA_BB_CC_DD_EE_FF", want, nil},
+		{"Numeric digits", "01:23:45:67:89:0A", "01:23:45:67:89:0a", nil},
+		{"Unusual grouping", "A:AB:BC:CD:DE:EF:F", want, nil},
+
+		// Invalid lengths
+		{"Empty", "", "", ErrInvalidLength},
+		{"Only separators", ":::--..", "", ErrInvalidLength},
+		{"Too short by one digit", "AA:BB:CC:DD:EE:F", "", ErrInvalidLength},
+		{"Five octets", "AA:BB:CC:DD:EE", "", ErrInvalidLength},
+		{"Too long by one digit", "AA:BB:CC:DD:EE:FF:0", "", ErrInvalidLength},
+		{"Seven octets", "AA:BB:CC:DD:EE:FF:00", "", ErrInvalidLength},
+		{"EUI-64", "AABBCCDDEEFF0011", "", ErrInvalidLength},
+		{"Short Cisco", "aabb.ccdd.eef", "", ErrInvalidLength},
+
+		// Non-hex characters
+		{"Letter G", "AA:BB:CC:DD:EE:FG", "", ErrInvalidCharacter},
+		{"Letter O instead of zero", "AA:BB:CC:DD:EE:O0", "", ErrInvalidCharacter},
+		{"Scanner name", "esp32-lobby", "", ErrInvalidCharacter},
+		{"Comma separator", "AA,BB,CC,DD,EE,FF", "", ErrInvalidCharacter},
+		{"Slash separator", "AA/BB/CC/DD/EE/FF", "", ErrInvalidCharacter},
+		{"Thai digits", "๑๒:BB:CC:DD:EE:FF", "", ErrInvalidCharacter},
+		{"Fullwidth letters", "ＡＡ:BB:CC:DD:EE:FF", "", ErrInvalidCharacter},
+		{"Hex prefix", "0xAABBCCDDEEFF", "", ErrInvalidCharacter},
+		{"Quote injection", "AA:BB:CC:DD:EE:FF' || 1=1", "", ErrInvalidCharacter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Normalize(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeIsIdempotent(t *testing.T) {
+	inputs := []string{"AA BB CC DD EE FF", "aabb.ccdd.eeff", "AA–BB–CC–DD–EE–FF"}
+	for _, input := range inputs {
+		first, err := Normalize(input)
+		if err != nil {
+			t.Fatalf("Normalize(%q) error = %v", input, err)
+		}
+		second, err := Normalize(first)
+		if err != nil || second != first {
+			t.Errorf("Normalize(%q) = %q, %v; want %q", first, second, err, first)
+		}
+	}
+}
+
+func TestAcceptedFormatsAreValid(t *testing.T) {
+	for _, f := range AcceptedFormats {
+		if !IsValid(f) {
+			t.Errorf("AcceptedFormats entry %q does not normalize", f)
+		}
+	}
+}
+
+func TestNormalizeScannerID(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		freeform bool
+		want     string
+		wantErr  error
+	}{
+		{"MAC", "AA-BB-CC-DD-EE-FF", false, "aa:bb:cc:dd:ee:ff", nil},
+		{"Bare MAC", "aabbccddeeff", false, "aa:bb:cc:dd:ee:ff", nil},
+		{"MAC with free-form allowed", "AA:BB:CC:DD:EE:FF", true, "aa:bb:cc:dd:ee:ff", nil},
+		{"Name rejected", "esp32-lobby", false, "", ErrInvalidCharacter},
+		{"Name allowed", " ESP32-Lobby ", true, "esp32-lobby", nil},
+		{"Empty", "", true, "", ErrInvalidScannerID},
+		{"Quote", "lobby'||1=1", true, "", ErrInvalidScannerID},
+		{"Too long", strings.Repeat("a", 65), true, "", ErrInvalidScannerID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeScannerID(tt.input, tt.freeform)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NormalizeScannerID(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeScannerID(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpellings(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want []string
+	}{
+		{"MAC", "aa:bb:cc:dd:ee:ff", []string{
+			"aa:bb:cc:dd:ee:ff", "AA:BB:CC:DD:EE:FF", "aa-bb-cc-dd-ee-ff", "AA-BB-CC-DD-EE-FF", "aabbccddeeff", "AABBCCDDEEFF",
+		}},
+		{"Free-form", "esp32-lobby", []string{"esp32-lobby", "ESP32-LOBBY"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Spellings(tt.id)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Spellings(%q) = %v, want %v", tt.id, got, tt.want)
+			}
+			for _, v := range got {
+				if n, err := NormalizeScannerID(v, true); err != nil || n != tt.id {
+					t.Errorf("spelling %q normalizes to %q, %v", v, n, err)
+				}
+			}
+		})
+	}
+}
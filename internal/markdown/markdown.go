@@ -0,0 +1,81 @@
+// Package markdown formats text for Telegram's legacy Markdown parse mode, which every bot
+// message is sent in. Names, departments, MACs and other values from PocketBase or from
+// users go through it, so a "_" or "*" in them cannot make Telegram reject the message.
+package markdown
+
+import (
+	"fmt"
+	"strings"
+)
+
+// reserved are the characters that start an entity
+const reserved = "_*`["
+
+var escaper = strings.NewReplacer(`_`, `\_`, `*`, `\*`, "`", "\\`", `[`, `\[`)
+
+// Escape makes s literal outside an entity
+func Escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// Code returns s as inline code. A backtick cannot be escaped inside one, so it becomes
+// an apostrophe; an empty s is a lone space.
+func Code(s string) string {
+	s = strings.ReplaceAll(s, "`", "'")
+	if s == "" {
+		s = " "
+	}
+	return "`" + s + "`"
+}
+
+// Bold returns s in bold. Escapes do not work inside an entity, so the bold is closed
+// around each "*" in s, which is escaped in between.
+func Bold(s string) string {
+	parts := strings.Split(s, "*")
+	for i, p := range parts {
+		if p != "" {
+			parts[i] = "*" + p + "*"
+		}
+	}
+	return strings.Join(parts, `\*`)
+}
+
+// Check reports the first entity Telegram would fail to parse in text, such as a "*"
+// that is never closed
+func Check(text string) error {
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c == '\\' && i+1 < len(text) && strings.IndexByte(reserved, text[i+1]) >= 0 {
+			i++
+			continue
+		}
+		switch {
+		case strings.HasPrefix(text[i:], "```"):
+			end := strings.Index(text[i+3:], "```")
+			if end < 0 {
+				return fmt.Errorf("can't find end of the code block starting at byte offset %d", i)
+			}
+			i += 3 + end + 2
+		case c == '*' || c == '_' || c == '`':
+			end := strings.IndexByte(text[i+1:], c)
+			if end < 0 {
+				return fmt.Errorf("can't find end of the entity starting at byte offset %d", i)
+			}
+			i += 1 + end
+		case c == '[':
+			end := strings.IndexByte(text[i+1:], ']')
+			if end < 0 {
+				return fmt.Errorf("can't find end of the link starting at byte offset %d", i)
+			}
+			i += 1 + end
+			if strings.HasPrefix(text[i+1:], "(") {
+				close := strings.IndexByte(text[i+1:], ')')
+				if close < 0 {
+					return fmt.Errorf("can't find end of the link URL starting at byte offset %d", i+1)
+				}
+				i += close + 1
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+// hostile has every reserved character, in the places that break naive formatting
+const hostile = "*Som_chai* [night`s] O'Neil\\ 2*2_"
+
+func TestFormatters(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"escape", "before " + Escape(hostile) + " after", "before \\*Som\\_chai\\* \\[night\\`s] O'Neil\\ 2\\*2\\_ after"},
+		{"code", "MAC " + Code(hostile), "MAC `*Som_chai* [night's] O'Neil\\ 2*2_`"},
+		{"empty code", Code(""), "` `"},
+		{"bold", Bold("คุณ" + hostile + "!"), "*คุณ*\\**Som_chai*\\** [night`s] O'Neil\\ 2*\\**2_!*"},
+		{"bold starting with a star", Bold("*x"), "\\**x*"},
+		{"all in one message", "*หัวข้อ*\n" + Escape(hostile) + " " + Code(hostile) + " " + Bold(hostile), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Check(tt.text); err != nil {
+				t.Errorf("%q does not parse: %v", tt.text, err)
+			}
+			if tt.want != "" && tt.text != tt.want {
+				t.Errorf("got %q, want %q", tt.text, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		text    string
+		wantErr string
+	}{
+		{"plain", ""},
+		{"*bold* _italic_ `code` [link](http://x) ```\npre\n```", ""},
+		{`escaped \* \_ \[ \` + "`", ""},
+		{"*Som_chai", "entity starting at byte offset 0"},
+		{"คุณ Som_chai", "entity starting"},
+		{"`unclosed", "entity starting"},
+		{"[no end", "link starting"},
+		{"```\nnever closed", "code block"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			err := Check(tt.text)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Check() error = %v, want none", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Check() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
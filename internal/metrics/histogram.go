@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Histogram counts observations in buckets, partitioned by label values
+type Histogram struct {
+	name    string
+	help    string
+	buckets []float64 // upper bounds, ascending
+	labels  []string
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries // keyed by joined label values
+}
+
+type histogramSeries struct {
+	counts []uint64 // per bucket, the last one above every bound
+	sum    float64
+}
+
+// NewHistogram registers a histogram with the given ascending bucket bounds and label names
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{name: name, help: help, buckets: buckets, labels: labels, series: make(map[string]*histogramSeries)}
+	register(h)
+	return h
+}
+
+// Observe records one value for the given label values
+func (h *Histogram) Observe(v float64, values ...string) {
+	if len(values) != len(h.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", h.name, len(h.labels), len(values)))
+	}
+	k := strings.Join(values, "\xff")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[k]
+	if !ok {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets)+1)}
+		h.series[k] = s
+	}
+	s.counts[sort.SearchFloat64s(h.buckets, v)]++
+	s.sum += v
+}
+
+// Buckets returns the upper bounds of the buckets
+func (h *Histogram) Buckets() []float64 {
+	return h.buckets
+}
+
+// Counts returns the number of observations in each bucket for the given label values,
+// not cumulative; the extra last element counts those above every bound
+func (h *Histogram) Counts(values ...string) []uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	counts := make([]uint64, len(h.buckets)+1)
+	if s, ok := h.series[strings.Join(values, "\xff")]; ok {
+		copy(counts, s.counts)
+	}
+	return counts
+}
+
+// write appends the histogram in exposition format, series sorted for stable output
+func (h *Histogram) write(b *strings.Builder) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	keys := make([]string, 0, len(h.series))
+	for k := range h.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := h.series[k]
+		var cumulative uint64
+		for i, count := range s.counts {
+			cumulative += count
+			le := "+Inf"
+			if i < len(h.buckets) {
+				le = strconv.FormatFloat(h.buckets[i], 'g', -1, 64)
+			}
+			fmt.Fprintf(b, "%s_bucket%s %d\n", h.name, labelSet(h.labels, k, fmt.Sprintf("le=%q", le)), cumulative)
+		}
+		fmt.Fprintf(b, "%s_sum%s %g\n", h.name, labelSet(h.labels, k), s.sum)
+		fmt.Fprintf(b, "%s_count%s %d\n", h.name, labelSet(h.labels, k), cumulative)
+	}
+}
@@ -0,0 +1,228 @@
+// Package metrics keeps in-process counters and serves them in the Prometheus
+// text exposition format
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Counter is a monotonically increasing counter partitioned by label values
+type Counter struct {
+	name   string
+	help   string
+	kind   string // exposition type, "counter" or "gauge"
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]float64 // keyed by joined label values
+}
+
+// collector is a metric that can write itself in exposition format
+type collector interface {
+	write(b *strings.Builder)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []collector
+)
+
+// register adds a metric to the ones served by Handler
+func register(c collector) {
+	registryMu.Lock()
+	registry = append(registry, c)
+	registryMu.Unlock()
+}
+
+// NewCounter registers a counter with the given label names
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{name: name, help: help, kind: "counter", labels: labels, values: make(map[string]float64)}
+	register(c)
+	return c
+}
+
+// Inc adds one for the given label values, which must match the label names
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds delta for the given label values
+func (c *Counter) Add(delta float64, values ...string) {
+	if len(values) != len(c.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", c.name, len(c.labels), len(values)))
+	}
+	c.mu.Lock()
+	c.values[strings.Join(values, "\xff")] += delta
+	c.mu.Unlock()
+}
+
+// Gauge is a value that can go up and down, partitioned by label values. It also
+// remembers the highest value of each series until TakePeak is called.
+type Gauge struct {
+	*Counter
+	peaks map[string]float64 // guarded by Counter.mu
+}
+
+// NewGauge registers a gauge with the given label names
+func NewGauge(name, help string, labels ...string) *Gauge {
+	c := NewCounter(name, help, labels...)
+	c.kind = "gauge"
+	return &Gauge{Counter: c, peaks: make(map[string]float64)}
+}
+
+// Set replaces the value for the given label values
+func (g *Gauge) Set(v float64, values ...string) {
+	g.update(values, func(float64) float64 { return v })
+}
+
+// Add moves the value for the given label values by delta, which may be negative
+func (g *Gauge) Add(delta float64, values ...string) {
+	g.update(values, func(v float64) float64 { return v + delta })
+}
+
+// TakePeak returns the highest value of a series since the previous call and starts
+// tracking again from its current value
+func (g *Gauge) TakePeak(values ...string) float64 {
+	k := strings.Join(values, "\xff")
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	peak, ok := g.peaks[k]
+	if !ok {
+		peak = g.values[k]
+	}
+	g.peaks[k] = g.values[k]
+	return peak
+}
+
+func (g *Gauge) update(values []string, next func(float64) float64) {
+	if len(values) != len(g.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", g.name, len(g.labels), len(values)))
+	}
+	k := strings.Join(values, "\xff")
+	g.mu.Lock()
+	v := next(g.values[k])
+	g.values[k] = v
+	if peak, ok := g.peaks[k]; !ok || v > peak {
+		g.peaks[k] = v
+	}
+	g.mu.Unlock()
+}
+
+// Value returns the current count for the given label values
+func (c *Counter) Value(values ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[strings.Join(values, "\xff")]
+}
+
+// Total sums the series whose leading label values are the given ones, e.g. every
+// stage of one tenant; with no values it sums every series
+func (c *Counter) Total(values ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	total := 0.0
+	for k, v := range c.values {
+		if matchesPrefix(k, values) {
+			total += v
+		}
+	}
+	return total
+}
+
+// matchesPrefix reports whether the joined label values in key start with values
+func matchesPrefix(key string, values []string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	parts := strings.Split(key, "\xff")
+	if len(parts) < len(values) {
+		return false
+	}
+	for i, v := range values {
+		if parts[i] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// write appends the counter in exposition format, series sorted for stable output
+func (c *Counter) write(b *strings.Builder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", c.name, c.help, c.name, c.kind)
+	keys := make([]string, 0, len(c.values))
+	for k := range c.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(b, "%s%s %g\n", c.name, labelSet(c.labels, k), c.values[k])
+	}
+}
+
+// labelSet renders joined label values as {name="value",...}, plus any extra pairs;
+// a series without labels renders as nothing
+func labelSet(labels []string, key string, extra ...string) string {
+	var pairs []string
+	if len(labels) > 0 {
+		for i, v := range strings.Split(key, "\xff") {
+			pairs = append(pairs, fmt.Sprintf("%s=%q", labels[i], v))
+		}
+	}
+	pairs = append(pairs, extra...)
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Handler serves every registered counter and gauge
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var b strings.Builder
+		registryMu.Lock()
+		for _, c := range registry {
+			c.write(&b)
+		}
+		registryMu.Unlock()
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write([]byte(b.String()))
+	})
+}
+
+// Counters shared across the application
+var (
+	Detections = NewCounter("medpulse_detections_total",
+		"Detections received, by tenant and the pipeline stage that finished them", "tenant", "stage")
+	CheckIns = NewCounter("medpulse_checkins_total",
+		"Check-ins recorded, by tenant and status", "tenant", "status")
+	RejectedRequests = NewCounter("medpulse_rejected_requests_total",
+		"Detection requests rejected before reaching a tenant", "reason")
+	SelfTestHealthy = NewGauge("medpulse_selftest_healthy",
+		"1 if the last pipeline self-test found its detection record in time, 0 if not", "tenant")
+	SelfTestFailures = NewCounter("medpulse_selftest_failures_total",
+		"Pipeline self-test runs that failed, by tenant", "tenant")
+	DetectionLatency = NewHistogram("medpulse_detection_latency_seconds",
+		"Time the detection pipeline took, by tenant", LatencyBuckets, "tenant")
+	DetectionQueueLength = NewGauge("medpulse_detection_queue_length",
+		"Detections waiting in the read-only mode queue, by tenant", "tenant")
+	QueueOverHighWater = NewGauge("medpulse_queue_over_high_water",
+		"1 while a local queue is at or above its high water mark, by tenant and queue", "tenant", "queue")
+	DetectionsDropped = NewCounter("medpulse_detections_dropped_total",
+		"Detections refused with 503 because the processing queue was full, by tenant", "tenant")
+	QueueDropped = NewCounter("medpulse_queue_dropped_total",
+		"Entries dropped from a local queue over its hard cap, by tenant and queue", "tenant", "queue")
+	PocketBaseRequests = NewCounter("medpulse_pocketbase_requests_total",
+		"Requests to PocketBase, by outcome (ok or error)", "outcome")
+	NotificationFailures = NewCounter("medpulse_notification_failures_total",
+		"Telegram messages that could not be sent")
+)
+
+// LatencyBuckets are the bucket bounds, in seconds, of DetectionLatency
+var LatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
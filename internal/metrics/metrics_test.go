@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	c := NewCounter("test_events_total", "Test events", "tenant", "kind")
+	c.Inc("clinic-a", "x")
+	c.Inc("clinic-a", "x")
+	c.Add(3, "clinic-b", `q"uote`)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"help", "# HELP test_events_total Test events\n# TYPE test_events_total counter\n"},
+		{"first tenant", `test_events_total{tenant="clinic-a",kind="x"} 2` + "\n"},
+		{"escaped label", `test_events_total{tenant="clinic-b",kind="q\"uote"} 3` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(body, tt.want) {
+				t.Errorf("output missing %q:\n%s", tt.want, body)
+			}
+		})
+	}
+
+	if got := c.Value("clinic-a", "x"); got != 2 {
+		t.Errorf("Value = %v, want 2", got)
+	}
+}
+
+func TestGauge(t *testing.T) {
+	g := NewGauge("test_healthy", "Test health", "tenant")
+	g.Set(1, "clinic-a")
+	g.Set(0, "clinic-a")
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, want := range []string{"# TYPE test_healthy gauge\n", `test_healthy{tenant="clinic-a"} 0` + "\n"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("output missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestTotal(t *testing.T) {
+	c := NewCounter("test_stages_total", "Test stages", "tenant", "stage")
+	c.Add(2, "clinic-a", "dedupe")
+	c.Add(3, "clinic-a", "notification")
+	c.Add(5, "clinic-b", "dedupe")
+
+	tests := []struct {
+		values []string
+		want   float64
+	}{
+		{nil, 10},
+		{[]string{"clinic-a"}, 5},
+		{[]string{"clinic-b", "dedupe"}, 5},
+		{[]string{"clinic-c"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.values, "/"), func(t *testing.T) {
+			if got := c.Total(tt.values...); got != tt.want {
+				t.Errorf("Total(%q) = %v, want %v", tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGaugePeak(t *testing.T) {
+	g := NewGauge("test_queue_length", "Test queue", "tenant")
+	if got := g.TakePeak("clinic-a"); got != 0 {
+		t.Errorf("TakePeak() of an unset series = %v, want 0", got)
+	}
+	g.Add(1, "clinic-a")
+	g.Add(2, "clinic-a")
+	g.Set(1, "clinic-a")
+	if got := g.TakePeak("clinic-a"); got != 3 {
+		t.Errorf("TakePeak() = %v, want 3", got)
+	}
+	if got := g.TakePeak("clinic-a"); got != 1 {
+		t.Errorf("TakePeak() after taking = %v, want the current value 1", got)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram("test_latency_seconds", "Test latency", []float64{0.1, 1}, "tenant")
+	h.Observe(0.05, "clinic-a")
+	h.Observe(0.1, "clinic-a")
+	h.Observe(0.5, "clinic-a")
+	h.Observe(7, "clinic-a")
+	unlabelled := NewCounter("test_failures_total", "Test failures")
+	unlabelled.Inc()
+
+	if got := fmt.Sprint(h.Counts("clinic-a")); got != "[2 1 1]" {
+		t.Errorf("Counts() = %s, want [2 1 1]", got)
+	}
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE test_latency_seconds histogram\n",
+		`test_latency_seconds_bucket{tenant="clinic-a",le="0.1"} 2` + "\n",
+		`test_latency_seconds_bucket{tenant="clinic-a",le="1"} 3` + "\n",
+		`test_latency_seconds_bucket{tenant="clinic-a",le="+Inf"} 4` + "\n",
+		`test_latency_seconds_sum{tenant="clinic-a"} 7.65` + "\n",
+		`test_latency_seconds_count{tenant="clinic-a"} 4` + "\n",
+		"test_failures_total 1\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("output missing %q:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package models
+
+import "slices"
+
+// Languages of the bot replies and notifications
+const (
+	LanguageThai    = "th"
+	LanguageEnglish = "en"
+)
+
+// Languages lists the supported languages; Thai is the default and the fallback for
+// anything not translated
+var Languages = []string{LanguageThai, LanguageEnglish}
+
+// ValidLanguage reports whether lang is one of Languages
+func ValidLanguage(lang string) bool {
+	return slices.Contains(Languages, lang)
+}
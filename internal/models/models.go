@@ -14,6 +14,16 @@ type DetectionRequest struct {
 	IsITag03       bool   `json:"itag03"`
 	IsTargetDevice bool   `json:"target_device"` // True if MAC or UUID matches target list
 	DeviceName     string `json:"device_name"`   // Custom name for target device (e.g., "MSL AirPods Pro")
+
+	// iBeacon identity of the advertisement, for phones whose MAC is randomized; the UUID
+	// is empty when the scanner did not parse one
+	IBeaconUUID string `json:"ibeacon_uuid"`
+	Major       int    `json:"major"`
+	Minor       int    `json:"minor"`
+
+	// Eddystone-UID identity of the advertisement; empty when the scanner did not parse one
+	NamespaceID string `json:"namespace_id"`
+	InstanceID  string `json:"instance_id"`
 }
 
 // Employee represents an employee in the system
@@ -21,19 +31,304 @@ type Employee struct {
 	ID             string
 	TelegramChatID int64
 	Name           string
+	EmployeeCode   string
 	MacAddress     string
+	IBeaconID      string // "<uuid>:<major>:<minor>" of an iBeacon the employee carries; empty if none
+	EddystoneID    string // "<namespace>:<instance>" of an Eddystone-UID tag the employee carries; empty if none
 	WorkStartTime  string
+	WorkEndTime    string // HH:MM:SS; empty when the employee has no end-of-day schedule, before WorkStartTime for a night shift
+	GracePeriod    int    // Minutes after WorkStartTime still on time; 0 uses the site's grace period
 	IsActive       bool
+	Department     string
+	DisplayName    string // Name shown on the public board; empty means first name
+	ShowOnBoard    bool   // Opt-out flag for the public board
+	IsSynthetic    bool   // Reserved self-test employee: never notified, excluded from reports
+	PresenceOptOut bool   // Declined presence tracking: only check-ins and check-outs are kept
+	Language       string // LanguageThai or LanguageEnglish for bot replies and notifications; empty is Thai
+
+	MutedNotifications []string  // NotificationCategory values the employee opted out of
+	CheckInOptOut      bool      // Turned personal check-in messages off; admin late alerts are sent regardless
+	QuietHours         string    // "HH:MM-HH:MM" in which no personal check-in message is sent; empty for none
+	LeaveUntil         time.Time // Last day of approved long leave; zero when not on leave
+
+	IsGuest    bool      // Time-boxed visitor or contractor tag: never late, reported apart from staff
+	GuestUntil time.Time // Last day a guest's tag is valid; zero for employees
+}
+
+// GuestExpired reports whether a guest's tag is no longer valid on the day; always false
+// for employees
+func (e *Employee) GuestExpired(day time.Time) bool {
+	if !e.IsGuest || e.GuestUntil.IsZero() {
+		return false
+	}
+	y, m, d := day.In(e.GuestUntil.Location()).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, e.GuestUntil.Location()).After(e.GuestUntil)
+}
+
+// OnLeave reports whether the employee's approved leave covers the day
+func (e *Employee) OnLeave(day time.Time) bool {
+	if e.LeaveUntil.IsZero() {
+		return false
+	}
+	y, m, d := day.In(e.LeaveUntil.Location()).Date()
+	return !time.Date(y, m, d, 0, 0, 0, 0, e.LeaveUntil.Location()).After(e.LeaveUntil)
+}
+
+// ShiftDay returns midnight of the calendar day on which the shift containing at started.
+// For a night shift (WorkEndTime before WorkStartTime, e.g. 22:00-06:00) times before the
+// middle of the off-shift gap belong to the shift of the previous evening, so a check-in at
+// 01:30 counts for the day before; for other schedules it is at's own day.
+func (e *Employee) ShiftDay(at time.Time) time.Time {
+	day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+	start, errStart := time.Parse("15:04:05", e.WorkStartTime)
+	end, errEnd := time.Parse("15:04:05", e.WorkEndTime)
+	if errStart != nil || errEnd != nil || !end.Before(start) {
+		return day
+	}
+	// time.Parse puts a time of day on January 1st of year 0
+	midnight := time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)
+	cutoff := end.Sub(midnight) + start.Sub(end)/2
+	if at.Sub(day) < cutoff {
+		return day.AddDate(0, 0, -1)
+	}
+	return day
+}
+
+// Overnight reports whether the employee's shift ends on the day after it starts
+func (e *Employee) Overnight() bool {
+	start, errStart := time.Parse("15:04:05", e.WorkStartTime)
+	end, errEnd := time.Parse("15:04:05", e.WorkEndTime)
+	return errStart == nil && errEnd == nil && end.Before(start)
+}
+
+// NotificationMuted reports whether the employee opted out of a notification category
+func (e *Employee) NotificationMuted(category NotificationCategory) bool {
+	if category == NotificationCheckIn && e.CheckInOptOut {
+		return true
+	}
+	for _, c := range e.MutedNotifications {
+		if c == string(category) {
+			return true
+		}
+	}
+	return false
 }
 
+// InQuietHours reports whether at falls in the employee's quiet hours. Quiet hours that
+// end before they start, e.g. 22:00-07:00, run past midnight.
+func (e *Employee) InQuietHours(at time.Time) bool {
+	start, end, err := ParseQuietHours(e.QuietHours)
+	if err != nil || start == end {
+		return false
+	}
+	now := time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute
+	if start < end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+// Attendance sources
+const (
+	AttendanceSourceScanner  = "scanner"  // BLE detection
+	AttendanceSourceImport   = "import"   // legacy fingerprint system import
+	AttendanceSourceSelfTest = "selftest" // synthetic self-test check-in, never reported
+	AttendanceSourceManual   = "manual"   // entered by an admin with /manual_checkin or the employee with /checkin
+)
+
+// Check-out sources
+const (
+	CheckOutSourceScanner      = "scanner"
+	CheckOutSourceImport       = "import"
+	CheckOutSourceSelfReported = "self_reported" // from the forgotten check-out reminder
+	CheckOutSourceAuto         = "auto"          // the day's last detection, taken by the auto check-out job
+	CheckOutSourceManual       = "manual"        // entered by the employee with /checkout
+)
+
 // Attendance represents an attendance record
 type Attendance struct {
-	ID          string
-	EmployeeID  string
-	CheckInTime time.Time
-	ScannerMac  string
-	Status      string
-	CreatedDate time.Time
+	ID             string
+	EmployeeID     string
+	CheckInTime    time.Time
+	CheckOutTime   time.Time // zero when not checked out
+	CheckOutSource string    // CheckOutSource*
+	NeedsReview    bool      // self-reported data an admin should confirm
+	ScannerMac     string
+	Status         string
+	CreatedDate    time.Time
+	Source         string // AttendanceSource*; empty for records from before the source field
+
+	// The employee as they were at check-in, so renaming them does not change past
+	// reports; empty for records from before the snapshot fields
+	EmployeeName string
+	EmployeeCode string
+	Department   string
+}
+
+// SnapshotEmployee copies the employee's name, code and department onto the record
+func (a *Attendance) SnapshotEmployee(e *Employee) {
+	a.EmployeeName = e.Name
+	a.EmployeeCode = e.EmployeeCode
+	a.Department = e.Department
+}
+
+// Attendance statuses
+const (
+	AttendanceStatusOnTime         = "ontime"
+	AttendanceStatusLate           = "late"
+	AttendanceStatusOnTimeApproved = "ontime_approved" // late, but by the time approved in advance
+	AttendanceStatusGuest          = "guest"           // check-in of a guest tag, never on time or late
+	AttendanceStatusOffDay         = "offday"          // check-in on a weekend or holiday, never late
+)
+
+// EmployeeDevice is an extra tag or phone of an employee, besides Employee.MacAddress;
+// a detection of any of them counts for the employee
+type EmployeeDevice struct {
+	ID         string
+	EmployeeID string
+	MacAddress string
+	Label      string // e.g. "iTag" or "AirPods"
+	IsPrimary  bool
+}
+
+// Holiday is a day on which nobody is expected at work
+type Holiday struct {
+	ID   string
+	Date string // YYYY-MM-DD
+	Name string
+}
+
+// LateApproval lets an employee arrive by ExpectedTime on one day, e.g. after a hospital
+// appointment, without being counted late
+type LateApproval struct {
+	ID           string
+	EmployeeID   string
+	Date         string // YYYY-MM-DD, the only day it applies to
+	ExpectedTime string // HH:MM
+	Reason       string
+	Status       string // LateApproval*
+	RequestedAt  time.Time
+	DecidedBy    int64 // Telegram user of the deciding admin; 0 until decided or when expired
+	DeciderName  string
+	DecidedAt    time.Time
+}
+
+// Late approval statuses
+const (
+	LateApprovalPending  = "pending"
+	LateApprovalApproved = "approved"
+	LateApprovalRejected = "rejected"
+	LateApprovalExpired  = "expired" // its day passed before an admin decided
+)
+
+// CheckInBaseline is an employee's rolling check-in time baseline
+type CheckInBaseline struct {
+	ID         string
+	EmployeeID string
+	Samples    []BaselineSample // Oldest first, one per working day
+	Median     float64          // Minutes after midnight
+	MAD        float64          // Median absolute deviation in minutes
+}
+
+// BaselineSample is one working day's check-in time
+type BaselineSample struct {
+	Date    string `json:"date"`    // YYYY-MM-DD
+	Minutes int    `json:"minutes"` // Minutes after midnight
+}
+
+// PeriodLayout is how a payroll period (one calendar month) is written, e.g. "2026-01"
+const PeriodLayout = "2006-01"
+
+// LockedPeriod is a payroll period whose attendance records may no longer change
+type LockedPeriod struct {
+	ID       string
+	Period   string // PeriodLayout
+	Locked   bool
+	LockedAt time.Time         // zero while unlocked
+	History  []PeriodLockEvent // every approved lock and unlock, oldest first
+}
+
+// PeriodLockEvent is one approved lock or unlock, kept for auditing
+type PeriodLockEvent struct {
+	Action        string    `json:"action"` // PeriodActionLock or PeriodActionUnlock
+	RequestedBy   int64     `json:"requested_by"`
+	RequesterName string    `json:"requester_name,omitempty"`
+	ApprovedBy    int64     `json:"approved_by"`
+	ApproverName  string    `json:"approver_name,omitempty"`
+	At            time.Time `json:"at"`
+}
+
+// Period lock actions
+const (
+	PeriodActionLock   = "lock"
+	PeriodActionUnlock = "unlock"
+)
+
+// PeriodLockActor is the Telegram user asking for or approving a lock change
+type PeriodLockActor struct {
+	UserID int64
+	Name   string
+}
+
+// PeriodLockRequest is a lock or unlock waiting for a second admin's approval
+type PeriodLockRequest struct {
+	ID        string
+	Action    string // PeriodActionLock or PeriodActionUnlock
+	Period    string // PeriodLayout
+	Requester PeriodLockActor
+	Expires   time.Time
+}
+
+// Lease is one term of the leadership lease instances compete for. A new term can only
+// be created once, so two instances taking over at the same time cannot both win.
+type Lease struct {
+	ID        string
+	Name      string
+	Term      int
+	Holder    string // instance ID
+	ExpiresAt time.Time
+}
+
+// AuditEntry is one administrative change kept in the audit log
+type AuditEntry struct {
+	ID        string
+	Action    string // e.g. AuditDepartmentAccepted
+	TargetID  string // the changed record, e.g. an employee ID
+	ActorID   int64  // Telegram user; 0 for changes made by the system
+	ActorName string
+	Details   string
+	At        time.Time
+}
+
+// Audit log actions
+const (
+	AuditDepartmentAccepted  = "department_accepted"
+	AuditEmployeeDeactivated = "employee_deactivated" // by an admin, or by the system after the inactivity threshold
+	AuditEmployeeKept        = "employee_kept_active" // an admin kept an employee flagged as inactive
+	AuditEmployeeReactivated = "employee_reactivated" // an admin switched a deactivated employee back on
+	AuditGuestRegistered     = "guest_registered"     // an admin lent a guest tag until a date
+)
+
+// NotificationFailure is a bot message Telegram never accepted, kept after the last
+// delivery attempt
+type NotificationFailure struct {
+	ID       string
+	ChatID   int64
+	Text     string
+	Error    string
+	Attempts int
+	FailedAt time.Time
+}
+
+// DeviceSighting is a strong detection of a device no employee owns, kept in the devices
+// collection so it can be paired later
+type DeviceSighting struct {
+	MacAddress  string
+	RSSI        int // strongest
+	SeenAt      time.Time
+	Count       int  // detections since the last sighting written
+	Reviewed    bool // an admin assigned or ignored the device
+	Whitelisted bool // assigned to an employee by an admin
 }
 
 // EmployeeDetection represents a detection record for an employee
@@ -47,6 +342,11 @@ type EmployeeDetection struct {
 	IsITag03       bool
 	IsTargetDevice bool   // True if matched target MAC/UUID
 	DeviceName     string // Custom name for target device
+	IBeaconUUID    string // empty unless the scanner reported an iBeacon
+	Major          int
+	Minor          int
+	NamespaceID    string // empty unless the scanner reported an Eddystone-UID
+	InstanceID     string
 	DetectedAt     time.Time
 }
 
@@ -55,4 +355,42 @@ type Scanner struct {
 	ID         string
 	ScannerMac string
 	LastSeen   time.Time
+	Name       string    // friendly name shown to employees, e.g. "ประตูหน้า"; empty shows the MAC
+	Location   string    // where it is, e.g. "อาคาร A ชั้น 1", shown after the name
+	Zone       string    // where the scanner is installed, chosen when pairing
+	PairedAt   time.Time // zero for scanners that were never paired from the bot
+	Profile    string    // name of the configuration profile it follows; empty uses the defaults
+	Overrides  ScannerSettings
+	Health     ScannerHealth // as of the last heartbeat that reported it
+}
+
+// Label is how the scanner is shown to employees: its name and location, or its MAC
+// when it has neither
+func (sc *Scanner) Label() string {
+	switch {
+	case sc.Name != "" && sc.Location != "":
+		return sc.Name + " (" + sc.Location + ")"
+	case sc.Name != "":
+		return sc.Name
+	case sc.Location != "":
+		return sc.Location
+	}
+	return "Scanner " + sc.ScannerMac
+}
+
+// ScannerHealth is what a scanner reports about itself in its heartbeat; zero fields were
+// not reported
+type ScannerHealth struct {
+	FirmwareVersion string
+	UptimeSeconds   int64
+	FreeHeap        int64 // bytes
+}
+
+// ScannerSettings are the tunable settings of a scanner at one level of its configuration
+// (defaults, profile, per-scanner overrides). Nil fields are inherited from the level below.
+type ScannerSettings struct {
+	RSSIThreshold         *int    `json:"rssi_threshold,omitempty" yaml:"rssi_threshold"`
+	Zone                  *string `json:"zone,omitempty" yaml:"zone"`
+	ReportIntervalSeconds *int    `json:"report_interval_seconds,omitempty" yaml:"report_interval_seconds"`
+	MinFirmware           *string `json:"min_firmware,omitempty" yaml:"min_firmware"`
 }
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// NotificationCategory groups personal notifications so employees can opt out per kind
+type NotificationCategory string
+
+// Notification categories
+const (
+	NotificationCheckIn          NotificationCategory = "checkin"
+	NotificationCheckOut         NotificationCategory = "checkout"
+	NotificationCheckOutReminder NotificationCategory = "checkout_reminder"
+)
+
+// NotificationCategories lists the categories employees can mute, in display order; the
+// bot labels them in each language
+var NotificationCategories = []NotificationCategory{
+	NotificationCheckIn,
+	NotificationCheckOut,
+	NotificationCheckOutReminder,
+}
+
+// NotificationPrefs are the notification settings an employee changes themselves
+type NotificationPrefs struct {
+	CheckIn    bool   // personal check-in messages
+	QuietHours string // "HH:MM-HH:MM", or empty for none
+}
+
+// ParseQuietHours parses "HH:MM-HH:MM" into the times of day it starts and ends
+func ParseQuietHours(s string) (start, end time.Duration, err error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("quiet hours %q are not HH:MM-HH:MM", s)
+	}
+	midnight := time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)
+	parse := func(v string) (time.Duration, error) {
+		t, err := time.Parse("15:04", strings.TrimSpace(v))
+		if err != nil {
+			return 0, fmt.Errorf("quiet hours %q are not HH:MM-HH:MM", s)
+		}
+		return t.Sub(midnight), nil
+	}
+	if start, err = parse(from); err != nil {
+		return 0, 0, err
+	}
+	if end, err = parse(to); err != nil {
+		return 0, 0, err
+	}
+	return start, end, nil
+}
+
+// PromptButton is an inline reply button attached to a personal notification
+type PromptButton struct {
+	Label string
+	Data  string // callback data, at most 64 bytes
+}
@@ -0,0 +1,268 @@
+// Package pbclient is the one place that talks to the PocketBase records API: it owns
+// the timeout, the auth header, filter encoding, paging and error parsing.
+package pbclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"med-pulse-bot/internal/logging"
+	"med-pulse-bot/internal/metrics"
+)
+
+// DefaultTimeout bounds every request to PocketBase unless SetTimeout changes it
+const DefaultTimeout = 5 * time.Second
+
+// pageSize is the number of records requested per page when listing every record
+const pageSize = 200
+
+// logBodies logs every successful response body at debug (LOG_HTTP_BODIES)
+var logBodies atomic.Bool
+
+// timeout bounds each request, on top of the caller's context (POCKETBASE_TIMEOUT)
+var timeout atomic.Int64
+
+// SetTimeout bounds every later request to PocketBase; 0 or less restores DefaultTimeout
+func SetTimeout(d time.Duration) {
+	if d <= 0 {
+		d = DefaultTimeout
+	}
+	timeout.Store(int64(d))
+}
+
+// Timeout is the bound on each request to PocketBase
+func Timeout() time.Duration {
+	if d := time.Duration(timeout.Load()); d > 0 {
+		return d
+	}
+	return DefaultTimeout
+}
+
+// SetLogBodies turns the logging of response bodies on or off. The bodies hold employee
+// names, chat IDs and MAC addresses, so they are never logged by default.
+func SetLogBodies(on bool) {
+	logBodies.Store(on)
+}
+
+// NewHTTPClient is the HTTP client used to talk to PocketBase; a nil transport uses
+// http.DefaultTransport. Requests are bounded by their context, see Timeout.
+func NewHTTPClient(transport http.RoundTripper) *http.Client {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &http.Client{Transport: transport}
+}
+
+// Client calls the records API of one PocketBase server. Collection names are given
+// without the site's prefix.
+type Client struct {
+	baseURL string
+	token   string
+	prefix  string // prepended to every collection name, e.g. "clinic_a_"
+	http    *http.Client
+}
+
+// New creates a client for the PocketBase at baseURL. token may be empty, e.g. when
+// the transport authorizes requests itself.
+func New(baseURL, token, prefix string, transport http.RoundTripper) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		prefix:  prefix,
+		http:    NewHTTPClient(transport),
+	}
+}
+
+// Collection is the full name of a collection on this server
+func (c *Client) Collection(name string) string {
+	return c.prefix + name
+}
+
+// Quote makes value a single-quoted string literal for a filter, escaping quotes and
+// backslashes so a value like "O'Brien" cannot end the literal early
+func Quote(value string) string {
+	return "'" + filterEscaper.Replace(value) + "'"
+}
+
+var filterEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// List decodes the records matching filter into out, a pointer to a slice. A limit of
+// 0 reads every page; otherwise only the first limit records are read. filter and sort
+// may be empty.
+func (c *Client) List(ctx context.Context, collection, filter, sort string, limit int, out any) error {
+	query := url.Values{}
+	if filter != "" {
+		query.Set("filter", filter)
+	}
+	if sort != "" {
+		query.Set("sort", sort)
+	}
+
+	perPage := limit
+	if limit <= 0 {
+		perPage = pageSize
+	}
+	query.Set("perPage", strconv.Itoa(perPage))
+
+	var items []json.RawMessage
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+		var result struct {
+			TotalPages int               `json:"totalPages"`
+			Items      []json.RawMessage `json:"items"`
+		}
+		if err := c.do(ctx, http.MethodGet, c.recordsPath(collection, "")+"?"+query.Encode(), collection, "list", nil, &result); err != nil {
+			return err
+		}
+		items = append(items, result.Items...)
+		if limit > 0 || page >= result.TotalPages || len(result.Items) == 0 {
+			break
+		}
+	}
+
+	all, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(all, out)
+}
+
+// ListPage decodes one page of perPage records matching filter into out, a pointer to a
+// slice, and returns the number of matching records on all pages. Pages start at 1.
+func (c *Client) ListPage(ctx context.Context, collection, filter, sort string, page, perPage int, out any) (int, error) {
+	query := url.Values{}
+	if filter != "" {
+		query.Set("filter", filter)
+	}
+	if sort != "" {
+		query.Set("sort", sort)
+	}
+	query.Set("page", strconv.Itoa(page))
+	query.Set("perPage", strconv.Itoa(perPage))
+
+	var result struct {
+		TotalItems int             `json:"totalItems"`
+		Items      json.RawMessage `json:"items"`
+	}
+	if err := c.do(ctx, http.MethodGet, c.recordsPath(collection, "")+"?"+query.Encode(), collection, "list", nil, &result); err != nil {
+		return 0, err
+	}
+	if len(result.Items) == 0 {
+		return result.TotalItems, nil
+	}
+	return result.TotalItems, json.Unmarshal(result.Items, out)
+}
+
+// GetOne decodes the record with the given ID into out
+func (c *Client) GetOne(ctx context.Context, collection, id string, out any) error {
+	return c.do(ctx, http.MethodGet, c.recordsPath(collection, id), collection, "get", nil, out)
+}
+
+// Create creates a record from data and decodes the stored record into out, which may be nil
+func (c *Client) Create(ctx context.Context, collection string, data, out any) error {
+	return c.do(ctx, http.MethodPost, c.recordsPath(collection, ""), collection, "create", data, out)
+}
+
+// Update writes the fields in data to the record with the given ID and decodes the
+// stored record into out, which may be nil
+func (c *Client) Update(ctx context.Context, collection, id string, data, out any) error {
+	return c.do(ctx, http.MethodPatch, c.recordsPath(collection, id), collection, "update", data, out)
+}
+
+// Delete removes the record with the given ID; a record that is already gone is not an error
+func (c *Client) Delete(ctx context.Context, collection, id string) error {
+	err := c.do(ctx, http.MethodDelete, c.recordsPath(collection, id), collection, "delete", nil, nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// Schema decodes the definition of a collection into out. It needs a superuser token.
+func (c *Client) Schema(ctx context.Context, collection string, out any) error {
+	path := "/api/collections/" + url.PathEscape(c.Collection(collection))
+	return c.do(ctx, http.MethodGet, path, collection, "schema", nil, out)
+}
+
+// recordsPath is the path of a collection's records, or of one record when id is set
+func (c *Client) recordsPath(collection, id string) string {
+	path := "/api/collections/" + url.PathEscape(c.Collection(collection)) + "/records"
+	if id != "" {
+		path += "/" + url.PathEscape(id)
+	}
+	return path
+}
+
+// do sends one request with data as its JSON body and decodes a successful response into
+// out. Failures become an *APIError; a 404 is left to the caller to report. The call is
+// abandoned when ctx is done or after Timeout, whichever comes first.
+func (c *Client) do(ctx context.Context, method, path, collection, operation string, data, out any) error {
+	ctx, cancel := context.WithTimeout(ctx, Timeout())
+	defer cancel()
+	var body io.Reader
+	if data != nil {
+		jsonData, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(jsonData)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		metrics.PocketBaseRequests.Inc("error")
+		var apiErr *APIError
+		if errors.Is(ctx.Err(), context.Canceled) || errors.As(err, &apiErr) {
+			// The caller gave up, or the transport got PocketBase's answer (e.g. to a login)
+			return err
+		}
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if resp.StatusCode == http.StatusNotFound {
+			metrics.PocketBaseRequests.Inc("ok") // an answer, not a failure
+			return readAPIError(resp, c.Collection(collection), operation)
+		}
+		metrics.PocketBaseRequests.Inc("error")
+		return ReadAPIError(resp, c.Collection(collection), operation)
+	}
+	metrics.PocketBaseRequests.Inc("ok")
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	var respBody io.Reader = resp.Body
+	if logBodies.Load() {
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read %s %s response: %w", c.Collection(collection), operation, err)
+		}
+		logging.From(ctx).Debug("PocketBase response", "collection", c.Collection(collection), "operation", operation, "body", string(data))
+		respBody = bytes.NewReader(data)
+	}
+	if err := json.NewDecoder(respBody).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s %s response: %w", c.Collection(collection), operation, err)
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package pbclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRecords serves the records of clinic_a_employees in pages of two
+func fakeRecords(t *testing.T) *httptest.Server {
+	t.Helper()
+	names := []string{"a", "b", "c", "d", "e"}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"status":401,"message":"The request requires valid record authorization token.","data":{}}`))
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/collections/clinic_a_employees/records":
+			if got := r.URL.Query().Get("filter"); got != "is_active=true && name!='x&y'" {
+				t.Errorf("filter = %q", got)
+			}
+			perPage, _ := strconv.Atoi(r.URL.Query().Get("perPage"))
+			perPage = min(perPage, 2)
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			start := min((page-1)*perPage, len(names))
+			var items []map[string]string
+			for _, n := range names[start:min(start+perPage, len(names))] {
+				items = append(items, map[string]string{"name": n})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"page": page, "totalPages": (len(names) + perPage - 1) / perPage, "totalItems": len(names), "items": items,
+			})
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":400,"message":"Failed to create record.","data":{"mac_address":{"code":"validation_not_unique","message":"Value must be unique."}}}`))
+		case r.Method == http.MethodPatch:
+			w.Write([]byte(`{"id":"emp1","name":"renamed"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	srv := fakeRecords(t)
+	c := New(srv.URL+"/", "tok", "clinic_a_", nil)
+	const filter = "is_active=true && name!='x&y'"
+
+	t.Run("list", func(t *testing.T) {
+		tests := []struct {
+			limit int
+			want  string
+		}{
+			{0, "[a b c d e]"},
+			{1, "[a]"},
+			{2, "[a b]"},
+		}
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("limit %d", tt.limit), func(t *testing.T) {
+				var records []struct{ Name string }
+				if err := c.List(ctx, "employees", filter, "", tt.limit, &records); err != nil {
+					t.Fatal(err)
+				}
+				var names []string
+				for _, r := range records {
+					names = append(names, r.Name)
+				}
+				if got := fmt.Sprint(names); got != tt.want {
+					t.Errorf("names = %s, want %s", got, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("list page", func(t *testing.T) {
+		tests := []struct {
+			page int
+			want string
+		}{
+			{1, "[a b]"},
+			{3, "[e]"},
+			{4, "[]"},
+		}
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("page %d", tt.page), func(t *testing.T) {
+				var records []struct{ Name string }
+				total, err := c.ListPage(ctx, "employees", filter, "", tt.page, 2, &records)
+				if err != nil {
+					t.Fatal(err)
+				}
+				names := []string{}
+				for _, r := range records {
+					names = append(names, r.Name)
+				}
+				if got := fmt.Sprint(names); got != tt.want || total != 5 {
+					t.Errorf("names = %s of %d, want %s of 5", got, total, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("update decodes the record", func(t *testing.T) {
+		var rec struct{ ID, Name string }
+		if err := c.Update(ctx, "employees", "emp1", map[string]string{"name": "renamed"}, &rec); err != nil {
+			t.Fatal(err)
+		}
+		if rec.ID != "emp1" || rec.Name != "renamed" {
+			t.Errorf("record = %+v", rec)
+		}
+	})
+
+	t.Run("rejections are structured", func(t *testing.T) {
+		err := c.Create(ctx, "employees", map[string]string{"mac_address": "aa"}, nil)
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.Collection != "clinic_a_employees" || apiErr.Operation != "create" {
+			t.Fatalf("err = %#v, want an APIError for clinic_a_employees create", err)
+		}
+		if !errors.Is(err, ErrUniqueViolation) {
+			t.Errorf("errors.Is(err, ErrUniqueViolation) = false for %v", err)
+		}
+
+		if err := c.GetOne(ctx, "employees", "gone", &struct{}{}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("GetOne() error = %v, want ErrNotFound", err)
+		}
+		if err := New(srv.URL, "", "clinic_a_", nil).GetOne(ctx, "employees", "emp1", &struct{}{}); !errors.Is(err, ErrUnauthorized) || err.(*APIError).Status != http.StatusUnauthorized {
+			t.Errorf("GetOne() without a token error = %v, want 401", err)
+		}
+	})
+
+	t.Run("an unreachable server is unavailable", func(t *testing.T) {
+		down := httptest.NewServer(http.NotFoundHandler())
+		down.Close()
+		if err := New(down.URL, "tok", "", nil).GetOne(ctx, "employees", "emp1", &struct{}{}); !errors.Is(err, ErrUnavailable) {
+			t.Errorf("GetOne() error = %v, want ErrUnavailable", err)
+		}
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		if err := New(down.URL, "tok", "", nil).GetOne(cancelled, "employees", "emp1", &struct{}{}); errors.Is(err, ErrUnavailable) {
+			t.Errorf("GetOne() with a cancelled context error = %v, want it not blamed on PocketBase", err)
+		}
+	})
+
+	t.Run("bodies are logged only when asked", func(t *testing.T) {
+		defaultLogger := slog.Default()
+		defer slog.SetDefault(defaultLogger)
+		defer SetLogBodies(false)
+		var logs bytes.Buffer
+		slog.SetDefault(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+		for _, on := range []bool{false, true} {
+			logs.Reset()
+			SetLogBodies(on)
+			var rec struct{ ID, Name string }
+			if err := c.Update(ctx, "employees", "emp1", map[string]string{"name": "renamed"}, &rec); err != nil {
+				t.Fatal(err)
+			}
+			if logged := strings.Contains(logs.String(), "renamed"); logged != on || rec.Name != "renamed" {
+				t.Errorf("with bodies logged = %v: logs %q, record %+v", on, logs.String(), rec)
+			}
+		}
+	})
+
+	t.Run("deleting a missing record succeeds", func(t *testing.T) {
+		if err := c.Delete(ctx, "employees", "gone"); err != nil {
+			t.Errorf("Delete() error = %v", err)
+		}
+	})
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"emp1", `'emp1'`},
+		{"", `''`},
+		{"O'Brien", `'O\'Brien'`},
+		{`C:\scans`, `'C:\\scans'`},
+		{`x\' || 1=1`, `'x\\\' || 1=1'`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := Quote(tt.value); got != tt.want {
+				t.Errorf("Quote(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientAbandonsSlowRequests(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+	c := New(srv.URL, "tok", "", nil)
+
+	tests := []struct {
+		name    string
+		timeout time.Duration // 0 keeps DefaultTimeout
+		cancel  time.Duration // cancel the caller's context after this; 0 never does
+		want    error
+	}{
+		{"caller cancels", 0, 20 * time.Millisecond, context.Canceled},
+		{"caller's deadline", 0, 0, ErrUnavailable},
+		{"per-call timeout", 20 * time.Millisecond, 0, ErrUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetTimeout(tt.timeout)
+			defer SetTimeout(0)
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			if tt.timeout > 0 || tt.cancel > 0 {
+				ctx, cancel = context.WithCancel(context.Background())
+			}
+			defer cancel()
+			if tt.cancel > 0 {
+				time.AfterFunc(tt.cancel, cancel)
+			}
+
+			start := time.Now()
+			err := c.GetOne(ctx, "employees", "emp1", &struct{}{})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("GetOne() error = %v, want %v", err, tt.want)
+			}
+			if took := time.Since(start); took > time.Second {
+				t.Errorf("GetOne() returned after %s, want it abandoned at once", took)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package pbclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Categories of PocketBase rejections, matched with errors.Is on an *APIError
+var (
+	ErrValidationFailed = errors.New("validation failed")
+	ErrUniqueViolation  = errors.New("unique violation")
+	ErrMissingField     = errors.New("missing field")
+	ErrNotFound         = errors.New("not found") // the record or the collection does not exist
+)
+
+// Kinds of failed requests, matched with errors.Is on the error of any call: every
+// *APIError is one of them, and a request that got no answer is ErrUnavailable
+var (
+	ErrBadRequest   = errors.New("bad request")            // 400: the message says what PocketBase rejected
+	ErrUnauthorized = errors.New("unauthorized")           // 401 or 403: the token is missing, invalid or lacks the rule
+	ErrUnavailable  = errors.New("pocketbase unavailable") // 5xx, or no answer at all
+)
+
+// maxErrorBody bounds how much of an error response is read
+const maxErrorBody = 16 << 10
+
+// maxErrorMessage bounds the text kept from a message or a body that is not JSON
+const maxErrorMessage = 200
+
+// FieldError is PocketBase's verdict on one field of a rejected record
+type FieldError struct {
+	Field   string // dotted path for nested fields
+	Code    string // e.g. validation_required
+	Message string
+}
+
+// APIError is a PocketBase error response: the top-level status and message plus the
+// per-field errors, sorted by field
+type APIError struct {
+	Collection string
+	Operation  string
+	Status     int
+	Message    string
+	Fields     []FieldError
+}
+
+// Error summarises the field errors, or the message when there are none, so the text
+// stays short enough to show to an admin
+func (e *APIError) Error() string {
+	if len(e.Fields) == 0 {
+		return fmt.Sprintf("%d %s", e.Status, e.Message)
+	}
+	parts := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		parts[i] = f.Field + ": " + f.Code
+	}
+	return fmt.Sprintf("%d %s", e.Status, strings.Join(parts, ", "))
+}
+
+// Unwrap returns the kind of the failure by status (ErrBadRequest, ErrUnauthorized,
+// ErrNotFound or ErrUnavailable) and the category sentinel of a rejected record
+func (e *APIError) Unwrap() []error {
+	var errs []error
+	switch {
+	case e.Status == http.StatusNotFound:
+		return []error{ErrNotFound}
+	case e.Status == http.StatusUnauthorized || e.Status == http.StatusForbidden:
+		errs = append(errs, ErrUnauthorized)
+	case e.Status >= 500:
+		errs = append(errs, ErrUnavailable)
+	case e.Status >= 400:
+		errs = append(errs, ErrBadRequest)
+	}
+	switch e.Category() {
+	case "unique_violation":
+		errs = append(errs, ErrUniqueViolation)
+	case "missing_field":
+		errs = append(errs, ErrMissingField)
+	case "validation_failed":
+		errs = append(errs, ErrValidationFailed)
+	}
+	return errs
+}
+
+// Category names the kind of rejection: unique_violation, missing_field,
+// validation_failed, or "" for anything else (auth, not found, server errors)
+func (e *APIError) Category() string {
+	category := ""
+	for _, f := range e.Fields {
+		switch {
+		case f.Code == "validation_not_unique":
+			return "unique_violation"
+		case f.Code == "validation_required":
+			category = "missing_field"
+		case category == "":
+			category = "validation_failed"
+		}
+	}
+	if category == "" && e.Status == http.StatusBadRequest {
+		category = "validation_failed"
+	}
+	return category
+}
+
+// ReadAPIError reads a failed response from PocketBase and logs one line per field error
+func ReadAPIError(resp *http.Response, collection, operation string) *APIError {
+	e := readAPIError(resp, collection, operation)
+	e.log()
+	return e
+}
+
+// readAPIError reads a failed response without logging it
+func readAPIError(resp *http.Response, collection, operation string) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	e := parseAPIError(resp.StatusCode, body)
+	e.Collection, e.Operation = collection, operation
+	return e
+}
+
+// parseAPIError decodes both the older {"code": ...} and the newer {"status": ...} error
+// bodies; other bodies, e.g. a proxy's HTML page, become a truncated message
+func parseAPIError(status int, body []byte) *APIError {
+	e := &APIError{Status: status}
+	var payload struct {
+		Message string                     `json:"message"`
+		Data    map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		e.Message = truncate(strings.TrimSpace(string(body)))
+		return e
+	}
+	e.Message = truncate(payload.Message)
+	e.Fields = fieldErrors("", payload.Data)
+	sort.Slice(e.Fields, func(i, j int) bool { return e.Fields[i].Field < e.Fields[j].Field })
+	return e
+}
+
+// fieldErrors flattens the data object; fields of JSON and multi-value fields nest
+func fieldErrors(parent string, data map[string]json.RawMessage) []FieldError {
+	var fields []FieldError
+	for name, raw := range data {
+		if parent != "" {
+			name = parent + "." + name
+		}
+		var f struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(raw, &f) == nil && f.Code != "" {
+			fields = append(fields, FieldError{Field: name, Code: f.Code, Message: truncate(f.Message)})
+			continue
+		}
+		var nested map[string]json.RawMessage
+		if json.Unmarshal(raw, &nested) == nil {
+			fields = append(fields, fieldErrors(name, nested)...)
+		}
+	}
+	return fields
+}
+
+// log writes the error as key=value fields so failure causes can be counted
+func (e *APIError) log() {
+	prefix := fmt.Sprintf("⚠️ PocketBase error collection=%s operation=%s status=%d category=%s",
+		e.Collection, e.Operation, e.Status, orNone(e.Category()))
+	if len(e.Fields) == 0 {
+		log.Printf("%s message=%q", prefix, e.Message)
+		return
+	}
+	for _, f := range e.Fields {
+		log.Printf("%s field=%s code=%s", prefix, f.Field, f.Code)
+	}
+}
+
+func truncate(s string) string {
+	if len(s) <= maxErrorMessage {
+		return s
+	}
+	cut := maxErrorMessage
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
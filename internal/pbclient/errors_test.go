@@ -0,0 +1,120 @@
+package pbclient
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseAPIError(t *testing.T) {
+	// Bodies as returned by PocketBase; 0.22 reports "code", 0.23 and later "status"
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		category string
+		sentinel error
+		kind     error // the sentinel of the status
+		text     string
+	}{
+		{
+			name:     "unique violation",
+			status:   400,
+			body:     `{"code":400,"message":"Failed to create record.","data":{"mac_address":{"code":"validation_not_unique","message":"Value must be unique."}}}`,
+			category: "unique_violation",
+			sentinel: ErrUniqueViolation,
+			kind:     ErrBadRequest,
+			text:     "400 mac_address: validation_not_unique",
+		},
+		{
+			name:     "missing fields",
+			status:   400,
+			body:     `{"status":400,"message":"Failed to create record.","data":{"name":{"code":"validation_required","message":"Cannot be blank."},"employee_code":{"code":"validation_required","message":"Cannot be blank."}}}`,
+			category: "missing_field",
+			sentinel: ErrMissingField,
+			kind:     ErrBadRequest,
+			text:     "400 employee_code: validation_required, name: validation_required",
+		},
+		{
+			name:     "invalid value",
+			status:   400,
+			body:     `{"status":400,"message":"Failed to update record.","data":{"scanner_mac":{"code":"validation_invalid_format","message":"Invalid value format."},"status":{"code":"validation_invalid_value","message":"Invalid value late2."}}}`,
+			category: "validation_failed",
+			sentinel: ErrValidationFailed,
+			kind:     ErrBadRequest,
+			text:     "400 scanner_mac: validation_invalid_format, status: validation_invalid_value",
+		},
+		{
+			name:     "unique wins over other fields",
+			status:   400,
+			body:     `{"status":400,"message":"Failed to create record.","data":{"holder":{"code":"validation_required","message":"Cannot be blank."},"name":{"code":"validation_not_unique","message":"Value must be unique."}}}`,
+			category: "unique_violation",
+			sentinel: ErrUniqueViolation,
+			kind:     ErrBadRequest,
+			text:     "400 holder: validation_required, name: validation_not_unique",
+		},
+		{
+			name:     "nested field",
+			status:   400,
+			body:     `{"status":400,"message":"Failed to create record.","data":{"history":{"0":{"code":"validation_invalid_value","message":"Invalid value."}}}}`,
+			category: "validation_failed",
+			sentinel: ErrValidationFailed,
+			kind:     ErrBadRequest,
+			text:     "400 history.0: validation_invalid_value",
+		},
+		{
+			name:     "not found",
+			status:   404,
+			body:     `{"code":404,"message":"The requested resource wasn't found.","data":{}}`,
+			sentinel: ErrNotFound,
+			kind:     ErrNotFound,
+			text:     "404 The requested resource wasn't found.",
+		},
+		{
+			name:   "forbidden",
+			status: 403,
+			kind:   ErrUnauthorized,
+			body:   `{"status":403,"message":"Only superusers can perform this action.","data":{}}`,
+			text:   "403 Only superusers can perform this action.",
+		},
+		{
+			name:   "proxy page",
+			status: 502,
+			kind:   ErrUnavailable,
+			body:   "<html><body><h1>502 Bad Gateway</h1></body></html>\n",
+			text:   "502 <html><body><h1>502 Bad Gateway</h1></body></html>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parseAPIError(tt.status, []byte(tt.body))
+			if got := err.Category(); got != tt.category {
+				t.Errorf("Category() = %q, want %q", got, tt.category)
+			}
+			if got := err.Error(); got != tt.text {
+				t.Errorf("Error() = %q, want %q", got, tt.text)
+			}
+			for _, sentinel := range []error{ErrUniqueViolation, ErrMissingField, ErrValidationFailed, ErrNotFound} {
+				if got := errors.Is(err, sentinel); got != (sentinel == tt.sentinel) {
+					t.Errorf("errors.Is(err, %v) = %v", sentinel, got)
+				}
+			}
+			for _, kind := range []error{ErrBadRequest, ErrUnauthorized, ErrUnavailable} {
+				if got := errors.Is(err, kind); got != (kind == tt.kind) {
+					t.Errorf("errors.Is(err, %v) = %v", kind, got)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAPIErrorBoundsMessage(t *testing.T) {
+	err := parseAPIError(500, []byte(strings.Repeat("ข", 500)))
+	if len(err.Message) > maxErrorMessage+len("…") {
+		t.Errorf("message is %d bytes, want at most %d", len(err.Message), maxErrorMessage)
+	}
+	if !strings.HasSuffix(err.Message, "ข…") {
+		t.Errorf("message was not cut on a rune boundary: %q", err.Message[len(err.Message)-8:])
+	}
+}
@@ -0,0 +1,238 @@
+package pbtest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// matcher reports whether a record matches a filter
+type matcher func(Record) bool
+
+// parseFilter compiles the part of PocketBase's filter syntax the repositories use:
+// comparisons with = != > >= < <= ~ !~ of a field against a quoted string, number, bool
+// or null, joined with && and || and grouped with parentheses. Anything else is an error,
+// which the server answers with PocketBase's 400.
+func parseFilter(filter string) (matcher, error) {
+	if strings.TrimSpace(filter) == "" {
+		return func(Record) bool { return true }, nil
+	}
+	tokens, err := tokenize(filter)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	m, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in filter %q", p.tokens[p.pos].text, filter)
+	}
+	return m, nil
+}
+
+// token is one word of a filter; quoted strings are unquoted
+type token struct {
+	text   string
+	quoted bool
+}
+
+var operators = []string{"&&", "||", "!=", ">=", "<=", "!~", "=", ">", "<", "~", "(", ")"}
+
+func tokenize(filter string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(filter); {
+		c := filter[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '\'' || c == '"':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(filter) && filter[j] != c; j++ {
+				if filter[j] == '\\' && j+1 < len(filter) {
+					j++
+				}
+				b.WriteByte(filter[j])
+			}
+			if j == len(filter) {
+				return nil, fmt.Errorf("unterminated string in filter %q", filter)
+			}
+			tokens = append(tokens, token{text: b.String(), quoted: true})
+			i = j + 1
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(filter[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op != "" {
+				tokens = append(tokens, token{text: op})
+				i += len(op)
+				continue
+			}
+			j := i
+			for j < len(filter) && !strings.ContainsRune(" \t\n'\"()=!<>~&|", rune(filter[j])) {
+				j++
+			}
+			if j == i {
+				return nil, fmt.Errorf("unexpected %q in filter %q", c, filter)
+			}
+			tokens = append(tokens, token{text: filter[i:j]})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// next returns the next unquoted token if it is one of want
+func (p *parser) next(want ...string) (string, bool) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].quoted {
+		return "", false
+	}
+	for _, w := range want {
+		if p.tokens[p.pos].text == w {
+			p.pos++
+			return w, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) or() (matcher, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.next("||"); !ok {
+			return left, nil
+		}
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(rec Record) bool { return l(rec) || right(rec) }
+	}
+}
+
+func (p *parser) and() (matcher, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.next("&&"); !ok {
+			return left, nil
+		}
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(rec Record) bool { return l(rec) && right(rec) }
+	}
+}
+
+func (p *parser) term() (matcher, error) {
+	if _, ok := p.next("("); ok {
+		m, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.next(")"); !ok {
+			return nil, fmt.Errorf("missing )")
+		}
+		return m, nil
+	}
+
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("incomplete filter")
+	}
+	field := p.tokens[p.pos]
+	if field.quoted {
+		return nil, fmt.Errorf("expected a field, got %q", field.text)
+	}
+	p.pos++
+	op, ok := p.next("=", "!=", ">", ">=", "<", "<=", "~", "!~")
+	if !ok || p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("expected a comparison after %s", field.text)
+	}
+	value := p.tokens[p.pos]
+	p.pos++
+	literal := value.text
+	if !value.quoted {
+		switch {
+		case literal == "null":
+			literal = ""
+		case literal == "true" || literal == "false":
+		default:
+			if _, err := strconv.ParseFloat(literal, 64); err != nil {
+				return nil, fmt.Errorf("unsupported value %s", literal)
+			}
+		}
+	}
+
+	name := field.text
+	return func(rec Record) bool {
+		c := compare(rec[name], literal)
+		switch op {
+		case "=":
+			return c == 0
+		case "!=":
+			return c != 0
+		case ">":
+			return c > 0
+		case ">=":
+			return c >= 0
+		case "<":
+			return c < 0
+		case "<=":
+			return c <= 0
+		case "~":
+			return strings.Contains(strings.ToLower(text(rec[name])), strings.ToLower(literal))
+		default: // !~
+			return !strings.Contains(strings.ToLower(text(rec[name])), strings.ToLower(literal))
+		}
+	}, nil
+}
+
+// compare orders a field value against a literal: as numbers when the field holds one,
+// else as text. A missing field compares as "".
+func compare(value any, literal string) int {
+	a, isNumber := value.(float64)
+	b, err := strconv.ParseFloat(literal, 64)
+	if isNumber && err == nil {
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(text(value), literal)
+}
+
+// text is a field value as a filter literal spells it
+func text(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
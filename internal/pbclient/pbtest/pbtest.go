@@ -0,0 +1,374 @@
+// Package pbtest is a fake PocketBase serving the records API from memory, for tests of the
+// code talking to PocketBase over REST
+package pbtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Record is one stored record, its fields as decoded from JSON
+type Record map[string]any
+
+// Request is one request received by the server
+type Request struct {
+	Method     string
+	Collection string
+	ID         string // the record of a get, update or delete
+	Filter     string
+	Sort       string
+	Body       Record // the JSON body of a create or update
+}
+
+// response is a canned answer replacing the records of a collection
+type response struct {
+	status int
+	body   string
+}
+
+// Server answers /api/collections/<name>/records like PocketBase does: list with filter,
+// sort and paging, get, create, update and delete. Collections exist once seeded;
+// requests to any other collection get PocketBase's 404, as before their migration.
+type Server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	collections map[string][]Record
+	unique      map[string][][]string // collection → field sets that must not repeat
+	responses   map[string]response
+	requests    []Request
+	lastID      int
+}
+
+// NewServer starts a server that is closed when the test ends
+func NewServer(t testing.TB) *Server {
+	s := &Server{
+		collections: make(map[string][]Record),
+		unique:      make(map[string][][]string),
+		responses:   make(map[string]response),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Seed creates the collection if needed and stores the records in it. Records without an
+// id get one; values are stored as they come back from JSON, so ints become float64.
+func (s *Server) Seed(collection string, records ...Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := s.collections[collection]
+	for _, rec := range records {
+		data, err := json.Marshal(rec)
+		if err != nil {
+			panic(fmt.Sprintf("pbtest: seeding %s: %v", collection, err))
+		}
+		rec = nil
+		json.Unmarshal(data, &rec)
+		rec = normalize(rec)
+		if rec["id"] == nil {
+			rec["id"] = s.newID()
+		}
+		stored = append(stored, rec)
+	}
+	if stored == nil {
+		stored = []Record{}
+	}
+	s.collections[collection] = stored
+}
+
+// Unique refuses creates and updates repeating the fields of another record of the
+// collection, like a unique index over them
+func (s *Server) Unique(collection string, fields ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unique[collection] = append(s.unique[collection], fields)
+}
+
+// Respond answers every following request to the collection with status and body
+// instead of serving its records, e.g. 401, 503 or a truncated JSON body
+func (s *Server) Respond(collection string, status int, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[collection] = response{status: status, body: body}
+}
+
+// Records returns a copy of the records stored in the collection
+func (s *Server) Records(collection string) []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var records []Record
+	for _, rec := range s.collections[collection] {
+		records = append(records, copyRecord(rec))
+	}
+	return records
+}
+
+// Requests returns the requests received for the collection, oldest first
+func (s *Server) Requests(collection string) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var requests []Request
+	for _, req := range s.requests {
+		if req.Collection == collection {
+			requests = append(requests, req)
+		}
+	}
+	return requests
+}
+
+// Filters returns the filters of the list requests received for the collection
+func (s *Server) Filters(collection string) []string {
+	var filters []string
+	for _, req := range s.Requests(collection) {
+		if req.Method == http.MethodGet && req.ID == "" {
+			filters = append(filters, req.Filter)
+		}
+	}
+	return filters
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rest, ok := strings.CutPrefix(r.URL.Path, "/api/collections/")
+	collection, rest, _ := strings.Cut(rest, "/")
+	records, id, _ := strings.Cut(rest, "/")
+	if !ok || records != "records" {
+		writeError(w, http.StatusNotFound, "The requested resource wasn't found.", nil)
+		return
+	}
+
+	req := Request{
+		Method:     r.Method,
+		Collection: collection,
+		ID:         id,
+		Filter:     r.URL.Query().Get("filter"),
+		Sort:       r.URL.Query().Get("sort"),
+	}
+	if r.Method == http.MethodPost || r.Method == http.MethodPatch {
+		if err := json.NewDecoder(r.Body).Decode(&req.Body); err != nil {
+			s.requests = append(s.requests, req)
+			writeError(w, http.StatusBadRequest, "Failed to load the submitted data due to invalid formatting.", nil)
+			return
+		}
+	}
+	s.requests = append(s.requests, req)
+
+	if resp, ok := s.responses[collection]; ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(resp.status)
+		w.Write([]byte(resp.body))
+		return
+	}
+	if _, ok := s.collections[collection]; !ok {
+		writeError(w, http.StatusNotFound, "Missing collection context.", nil)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		s.list(w, r, req)
+	case r.Method == http.MethodGet:
+		if rec := s.find(collection, id); rec != nil {
+			writeJSON(w, http.StatusOK, rec)
+		} else {
+			writeError(w, http.StatusNotFound, "The requested resource wasn't found.", nil)
+		}
+	case r.Method == http.MethodPost && id == "":
+		s.create(w, req)
+	case r.Method == http.MethodPatch && id != "":
+		s.update(w, req)
+	case r.Method == http.MethodDelete && id != "":
+		s.delete(w, req)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed", nil)
+	}
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request, req Request) {
+	match, err := parseFilter(req.Filter)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Something went wrong while processing your request. Invalid filter parameters.", nil)
+		return
+	}
+	items := []Record{}
+	for _, rec := range s.collections[req.Collection] {
+		if match(rec) {
+			items = append(items, rec)
+		}
+	}
+	sortRecords(items, req.Sort)
+
+	page, perPage := queryInt(r, "page", 1), queryInt(r, "perPage", 30)
+	total := len(items)
+	from := min((page-1)*perPage, total)
+	to := min(from+perPage, total)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"page":       page,
+		"perPage":    perPage,
+		"totalItems": total,
+		"totalPages": int(math.Ceil(float64(total) / float64(perPage))),
+		"items":      items[from:to],
+	})
+}
+
+func (s *Server) create(w http.ResponseWriter, req Request) {
+	rec := normalize(req.Body)
+	if rec["id"] == nil || rec["id"] == "" {
+		rec["id"] = s.newID()
+	}
+	if s.find(req.Collection, fmt.Sprint(rec["id"])) != nil {
+		writeError(w, http.StatusBadRequest, "Failed to create record.", []string{"id"})
+		return
+	}
+	if fields := s.violates(req.Collection, rec); fields != nil {
+		writeError(w, http.StatusBadRequest, "Failed to create record.", fields)
+		return
+	}
+	s.collections[req.Collection] = append(s.collections[req.Collection], rec)
+	writeJSON(w, http.StatusOK, rec)
+}
+
+func (s *Server) update(w http.ResponseWriter, req Request) {
+	rec := s.find(req.Collection, req.ID)
+	if rec == nil {
+		writeError(w, http.StatusNotFound, "The requested resource wasn't found.", nil)
+		return
+	}
+	updated := copyRecord(rec)
+	for field, value := range normalize(req.Body) {
+		updated[field] = value
+	}
+	if fields := s.violates(req.Collection, updated); fields != nil {
+		writeError(w, http.StatusBadRequest, "Failed to update record.", fields)
+		return
+	}
+	for field, value := range updated {
+		rec[field] = value
+	}
+	writeJSON(w, http.StatusOK, rec)
+}
+
+func (s *Server) delete(w http.ResponseWriter, req Request) {
+	stored := s.collections[req.Collection]
+	for i, rec := range stored {
+		if rec["id"] == req.ID {
+			s.collections[req.Collection] = append(stored[:i:i], stored[i+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "The requested resource wasn't found.", nil)
+}
+
+// find returns the stored record with the given ID, or nil
+func (s *Server) find(collection, id string) Record {
+	for _, rec := range s.collections[collection] {
+		if rec["id"] == id {
+			return rec
+		}
+	}
+	return nil
+}
+
+// violates returns the fields of the unique index rec would break, or nil
+func (s *Server) violates(collection string, rec Record) []string {
+	for _, fields := range s.unique[collection] {
+		for _, other := range s.collections[collection] {
+			if other["id"] == rec["id"] {
+				continue
+			}
+			same := true
+			for _, f := range fields {
+				same = same && text(other[f]) == text(rec[f])
+			}
+			if same {
+				return fields
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Server) newID() string {
+	s.lastID++
+	return fmt.Sprintf("pbtest%09d", s.lastID) // 15 characters like PocketBase's
+}
+
+// normalize copies rec with its dates stored the way PocketBase stores date fields,
+// "2026-02-01 00:00:00.000Z", so that filters compare them as they would
+func normalize(rec Record) Record {
+	out := make(Record, len(rec))
+	for field, value := range rec {
+		if s, ok := value.(string); ok {
+			for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+				if t, err := time.Parse(layout, s); err == nil {
+					value = t.UTC().Format("2006-01-02 15:04:05.000Z")
+					break
+				}
+			}
+		}
+		out[field] = value
+	}
+	return out
+}
+
+func copyRecord(rec Record) Record {
+	out := make(Record, len(rec))
+	for field, value := range rec {
+		out[field] = value
+	}
+	return out
+}
+
+// sortRecords orders records by PocketBase's sort parameter, e.g. "-last_seen,name"
+func sortRecords(records []Record, by string) {
+	if by == "" {
+		return
+	}
+	fields := strings.Split(by, ",")
+	sort.SliceStable(records, func(i, j int) bool {
+		for _, field := range fields {
+			desc := strings.HasPrefix(field, "-")
+			field = strings.TrimPrefix(field, "-")
+			c := compare(records[i][field], text(records[j][field]))
+			if c != 0 {
+				return (c < 0) != desc
+			}
+		}
+		return false
+	})
+}
+
+func queryInt(r *http.Request, name string, def int) int {
+	if n, err := strconv.Atoi(r.URL.Query().Get(name)); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes PocketBase's error body; fields are reported as not unique
+func writeError(w http.ResponseWriter, status int, message string, fields []string) {
+	data := map[string]any{}
+	for _, f := range fields {
+		data[f] = map[string]string{"code": "validation_not_unique", "message": "Value must be unique."}
+	}
+	writeJSON(w, status, map[string]any{"status": status, "message": message, "data": data})
+}
@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"med-pulse-bot/internal/pbclient"
+)
+
+// superusersCollection is the auth collection of PocketBase admins (>= 0.23)
+const superusersCollection = "_superusers"
+
+// AuthManager supplies the token for one PocketBase server. Given admin credentials it
+// authenticates itself, caches the token and re-authenticates when PocketBase rejects it;
+// with only a static token it behaves as before.
+type AuthManager struct {
+	baseURL  string
+	initial  string // POCKETBASE_TOKEN, used until the first re-authentication
+	email    string
+	password string
+	client   *http.Client
+
+	mu    sync.Mutex
+	token string
+}
+
+// defaultAuth is the manager of the default PocketBase, shared by every site it serves
+var defaultAuth *AuthManager
+
+// SetDefaultAuth makes repositories created afterwards use a for the sites it serves
+func SetDefaultAuth(a *AuthManager) {
+	defaultAuth = a
+}
+
+// NewAuthManager creates the manager of the PocketBase at baseURL. email and password
+// may be empty, in which case the token is never refreshed.
+func NewAuthManager(baseURL, token, email, password string) *AuthManager {
+	return &AuthManager{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		initial:  token,
+		email:    email,
+		password: password,
+		client:   pbclient.NewHTTPClient(transport),
+		token:    token,
+	}
+}
+
+// Serves reports whether a site at baseURL with the given token uses this manager's
+// credentials: the same server and the token it was created with. Safe on nil.
+func (a *AuthManager) Serves(baseURL, token string) bool {
+	return a != nil && strings.TrimRight(baseURL, "/") == a.baseURL && token == a.initial
+}
+
+// Token returns the cached token, authenticating first if there is none yet
+func (a *AuthManager) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token == "" && a.canRefresh() {
+		if err := a.authenticate(ctx); err != nil {
+			return "", err
+		}
+	}
+	return a.token, nil
+}
+
+// canRefresh reports whether the manager has credentials to get a new token
+func (a *AuthManager) canRefresh() bool {
+	return a.email != "" && a.password != ""
+}
+
+// refresh replaces a token PocketBase rejected. Requests that failed together share one
+// re-authentication: if the token already changed, the new one is returned as is.
+func (a *AuthManager) refresh(ctx context.Context, rejected string) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != rejected {
+		return a.token, nil
+	}
+	if err := a.authenticate(ctx); err != nil {
+		return "", err
+	}
+	return a.token, nil
+}
+
+// authenticate gets a new token with the admin credentials. Callers hold mu.
+func (a *AuthManager) authenticate(ctx context.Context) error {
+	body, _ := json.Marshal(map[string]string{"identity": a.email, "password": a.password})
+	apiURL := fmt.Sprintf("%s/api/collections/%s/auth-with-password", a.baseURL, url.PathEscape(superusersCollection))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to authenticate with PocketBase: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to authenticate with PocketBase: %w", pbclient.ReadAPIError(resp, superusersCollection, "auth"))
+	}
+
+	var result struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to read PocketBase auth response: %w", err)
+	}
+	if result.Token == "" {
+		return fmt.Errorf("PocketBase auth response has no token")
+	}
+	a.token = result.Token
+	log.Printf("🔑 Authenticated with PocketBase as %s", a.email)
+	return nil
+}
+
+// Transport wraps next so every request carries the current token, and a request
+// rejected with 401 or 403 is retried once with a fresh one
+func (a *AuthManager) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &authTransport{auth: a, next: next}
+}
+
+// authTransport is the RoundTripper returned by AuthManager.Transport
+type authTransport struct {
+	auth *AuthManager
+	next http.RoundTripper
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.auth.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.next.RoundTrip(withToken(req, token))
+	if err != nil || (resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden) {
+		return resp, err
+	}
+	// A body that cannot be replayed cannot be retried
+	if !t.auth.canRefresh() || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		return resp, nil
+	}
+
+	fresh, err := t.auth.refresh(req.Context(), token)
+	if err != nil {
+		log.Printf("⚠️  PocketBase rejected the token and re-authentication failed: %v", err)
+		return resp, nil
+	}
+	retry := withToken(req, fresh)
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	resp.Body.Close()
+	log.Printf("🔑 PocketBase rejected the token (%d), retrying %s %s with a fresh one", resp.StatusCode, req.Method, req.URL.Path)
+	return t.next.RoundTrip(retry)
+}
+
+// withToken returns a copy of req authorized with token; RoundTrippers must not change
+// the request they are given
+func withToken(req *http.Request, token string) *http.Request {
+	r := req.Clone(req.Context())
+	if token != "" {
+		r.Header.Set("Authorization", token)
+	}
+	return r
+}
+
+// siteTransport is the transport of a site: it goes through the default auth manager
+// when that manager serves the site
+func siteTransport(baseURL, token string) http.RoundTripper {
+	if defaultAuth.Serves(baseURL, token) {
+		return defaultAuth.Transport(transport)
+	}
+	return transport
+}
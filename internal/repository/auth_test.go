@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"med-pulse-bot/internal/models"
+)
+
+// fakeAuthServer issues a new token per admin login and only accepts the latest one
+type fakeAuthServer struct {
+	mu     sync.Mutex
+	valid  string
+	logins int
+	bodies []string // bodies of accepted record writes
+}
+
+func (f *fakeAuthServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.URL.Path == "/api/collections/_superusers/auth-with-password" {
+		var creds struct{ Identity, Password string }
+		json.NewDecoder(r.Body).Decode(&creds)
+		if creds.Identity != "admin@example.com" || creds.Password != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":400,"message":"Failed to authenticate.","data":{}}`))
+			return
+		}
+		f.logins++
+		f.valid = fmt.Sprintf("tok-%d", f.logins)
+		json.NewEncoder(w).Encode(map[string]string{"token": f.valid})
+		return
+	}
+
+	if r.Header.Get("Authorization") != f.valid {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"status":401,"message":"The request requires valid record authorization token.","data":{}}`))
+		return
+	}
+	if r.Method == http.MethodPost {
+		body, _ := io.ReadAll(r.Body)
+		f.bodies = append(f.bodies, string(body))
+	}
+	w.Write([]byte(`{"id":"rec1","items":[],"totalPages":1}`))
+}
+
+func TestAuthManager(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		token      string
+		email      string
+		password   string
+		expire     bool // the server forgets every token first
+		wantOK     bool
+		wantLogins int
+	}{
+		{"credentials without a token", "", "admin@example.com", "secret", false, true, 1},
+		{"expired token is refreshed", "stale", "admin@example.com", "secret", true, true, 1},
+		{"static token without credentials", "stale", "", "", true, false, 0},
+		{"wrong password keeps the rejection", "stale", "admin@example.com", "wrong", true, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeAuthServer{valid: "stale"}
+			if tt.expire {
+				fake.valid = "nobody"
+			}
+			server := httptest.NewServer(fake)
+			defer server.Close()
+
+			SetDefaultAuth(NewAuthManager(server.URL, tt.token, tt.email, tt.password))
+			defer SetDefaultAuth(nil)
+
+			err := Site{URL: server.URL, Token: tt.token}.Attendance().Create(ctx, &models.Attendance{EmployeeID: "e1"})
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("Create() error = %v, want success %v", err, tt.wantOK)
+			}
+			if fake.logins != tt.wantLogins {
+				t.Errorf("logins = %d, want %d", fake.logins, tt.wantLogins)
+			}
+			if tt.wantOK && (len(fake.bodies) != 1 || fake.bodies[0] == "") {
+				t.Errorf("accepted bodies = %q, want the replayed record", fake.bodies)
+			}
+		})
+	}
+
+	t.Run("concurrent rejections share one login", func(t *testing.T) {
+		fake := &fakeAuthServer{valid: "nobody"}
+		server := httptest.NewServer(fake)
+		defer server.Close()
+		SetDefaultAuth(NewAuthManager(server.URL, "stale", "admin@example.com", "secret"))
+		defer SetDefaultAuth(nil)
+
+		repo := Site{URL: server.URL, Token: "stale"}.Employees()
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := repo.ListActive(ctx); err != nil {
+					t.Errorf("ListActive() error = %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		if fake.logins != 1 {
+			t.Errorf("logins = %d, want 1", fake.logins)
+		}
+	})
+
+	t.Run("sites with their own token are left alone", func(t *testing.T) {
+		fake := &fakeAuthServer{valid: "tenant-token"}
+		server := httptest.NewServer(fake)
+		defer server.Close()
+		SetDefaultAuth(NewAuthManager(server.URL, "root", "admin@example.com", "secret"))
+		defer SetDefaultAuth(nil)
+
+		if _, err := (Site{URL: server.URL, Token: "tenant-token"}).Employees().ListActive(ctx); err != nil {
+			t.Fatalf("ListActive() error = %v", err)
+		}
+		if fake.logins != 0 {
+			t.Errorf("logins = %d, want 0", fake.logins)
+		}
+	})
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/models"
+)
+
+// filterRecorder is a PocketBase stand-in that records the list filters and created
+// attendance it receives
+type filterRecorder struct {
+	mu      sync.Mutex
+	filters []string
+	created map[string]interface{}
+}
+
+func (f *filterRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Method == http.MethodPost {
+		json.NewDecoder(r.Body).Decode(&f.created)
+		w.Write([]byte(`{"id":"att1"}`))
+		return
+	}
+	if r.URL.Path == "/api/collections/attendance/records" || r.URL.Path == "/api/collections/employees/records" {
+		f.filters = append(f.filters, r.URL.Query().Get("filter"))
+	}
+	w.Write([]byte(`{"items":[]}`))
+}
+
+func (f *filterRecorder) last() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.filters) == 0 {
+		return ""
+	}
+	return f.filters[len(f.filters)-1]
+}
+
+func TestCreatedDateFilters(t *testing.T) {
+	bangkok := time.FixedZone("Asia/Bangkok", 7*60*60)
+	SetLocation(bangkok)
+	defer SetLocation(time.Local)
+
+	rec := &filterRecorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	site := Site{URL: srv.URL}
+	ctx := context.Background()
+
+	// 23:30 UTC is already the next morning in Bangkok
+	at := time.Date(2026, 2, 1, 23, 30, 0, 0, time.UTC)
+	const feb2 = "created_date>='2026-02-02 00:00:00' && created_date<'2026-02-03 00:00:00'"
+
+	t.Run("one day is a range in the configured timezone", func(t *testing.T) {
+		if _, err := site.Attendance().ListByDate(ctx, at); err != nil {
+			t.Fatal(err)
+		}
+		if got := rec.last(); got != feb2 {
+			t.Errorf("filter = %q, want %q", got, feb2)
+		}
+	})
+
+	t.Run("checked in on a day never compares the bare date", func(t *testing.T) {
+		today := time.Now().In(bangkok)
+		if _, err := site.Employees().IsCheckedInOn(ctx, "e1", today); err != nil {
+			t.Fatal(err)
+		}
+		want := "employee_id='e1' && " + DayFilter("created_date", today)
+		if got := rec.last(); got != want || strings.Contains(got, "created_date='") {
+			t.Errorf("filter = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("month boundaries", func(t *testing.T) {
+		from := time.Date(2026, 2, 1, 0, 0, 0, 0, bangkok)
+		if _, err := site.Attendance().ListBetween(ctx, from, from.AddDate(0, 1, 0)); err != nil {
+			t.Fatal(err)
+		}
+		want := "created_date>='2026-02-01 00:00:00' && created_date<'2026-03-01 00:00:00'"
+		if got := rec.last(); got != want {
+			t.Errorf("filter = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("employee search quotes the query", func(t *testing.T) {
+		if _, _, err := site.Employees().List(ctx, " o'neil ", Page{Number: 1, Size: 10}); err != nil {
+			t.Fatal(err)
+		}
+		if got := rec.last(); !strings.HasSuffix(got, ` && (name~'o\'neil' || employee_code~'o\'neil')`) {
+			t.Errorf("filter = %q, want the query quoted", got)
+		}
+	})
+
+	t.Run("created date is written in the configured timezone", func(t *testing.T) {
+		if err := site.Attendance().Create(ctx, &models.Attendance{EmployeeID: "e1", CheckInTime: at, CreatedDate: at}); err != nil {
+			t.Fatal(err)
+		}
+		if got := rec.created["created_date"]; got != "2026-02-02" {
+			t.Errorf("created_date = %v, want 2026-02-02", got)
+		}
+	})
+}
+
+func TestCreatedDateReadInNegativeOffsetZone(t *testing.T) {
+	newYork := time.FixedZone("America/New_York", -5*60*60)
+	SetLocation(newYork)
+	defer SetLocation(time.Local)
+
+	// PocketBase returns the bare date written by Create as its UTC midnight
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"items":[{"id":"a1","employee_id":"e1","check_in_time":"2026-02-02 13:05:00.000Z",` +
+			`"created_date":"2026-02-02 00:00:00.000Z"}]}`))
+	}))
+	defer srv.Close()
+
+	day := time.Date(2026, 2, 2, 8, 5, 0, 0, newYork)
+	records, err := Site{URL: srv.URL}.Attendance().ListByDate(context.Background(), day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1", len(records))
+	}
+	want := time.Date(2026, 2, 2, 0, 0, 0, 0, newYork)
+	if got := records[0].CreatedDate; !got.Equal(want) || got.Location() != newYork {
+		t.Errorf("created_date = %v, want %v", got, want)
+	}
+}
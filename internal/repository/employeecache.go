@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/beacon"
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+)
+
+// CachableEmployees is what CachedEmployeeRepository wraps
+type CachableEmployees interface {
+	EmployeeRepository
+	EmployeeDirectory
+	EmployeeActivation
+}
+
+// employeeCacheEntry is one MAC lookup; employee is nil for a MAC no employee owns
+type employeeCacheEntry struct {
+	employee *models.Employee
+	expires  time.Time
+}
+
+// CachedEmployeeRepository keeps the result of GetByMacAddress for ttl, and remembers
+// MACs no employee owns for missTTL, so the tags and passing phones every scanner
+// reports do not each cost a PocketBase lookup. Updates and deactivations made through
+// it drop the employee's entries; anything else that changes employees must call
+// Invalidate.
+type CachedEmployeeRepository struct {
+	next    CachableEmployees
+	devices DeviceRepository // nil until SetDevices
+	ttl     time.Duration
+	missTTL time.Duration
+	clock   clock.Clock
+
+	mu        sync.Mutex
+	entries   map[string]employeeCacheEntry // lower-case MAC, or devicePrefix and MAC → lookup
+	lastSweep time.Time
+}
+
+// NewCachedEmployeeRepository caches lookups of next; a missTTL of 0 does not cache
+// unknown MACs
+func NewCachedEmployeeRepository(next CachableEmployees, ttl, missTTL time.Duration) *CachedEmployeeRepository {
+	return &CachedEmployeeRepository{
+		next:    next,
+		ttl:     ttl,
+		missTTL: missTTL,
+		clock:   clock.Real{},
+		entries: make(map[string]employeeCacheEntry),
+	}
+}
+
+// SetClock replaces the time source, used by tests
+func (r *CachedEmployeeRepository) SetClock(c clock.Clock) {
+	r.clock = c
+}
+
+// Prefixes keeping the lookups of GetEmployeeByDeviceMac, GetByBeacon and GetByEddystone
+// apart from GetByMacAddress
+const (
+	devicePrefix    = "device:"
+	ibeaconPrefix   = "ibeacon:"
+	eddystonePrefix = "eddystone:"
+)
+
+// SetDevices makes GetEmployeeByDeviceMac cache lookups of devices
+func (r *CachedEmployeeRepository) SetDevices(devices DeviceRepository) {
+	r.devices = devices
+}
+
+// GetByMacAddress returns the cached lookup of the MAC, asking next when it has expired.
+// Errors other than ErrEmployeeNotFound are not cached.
+func (r *CachedEmployeeRepository) GetByMacAddress(ctx context.Context, macAddress string) (*models.Employee, error) {
+	return r.lookup(ctx, strings.ToLower(macAddress), func() (*models.Employee, error) {
+		return r.next.GetByMacAddress(ctx, macAddress)
+	})
+}
+
+// GetEmployeeByDeviceMac returns the cached lookup of the device, like GetByMacAddress;
+// without SetDevices no device is known
+func (r *CachedEmployeeRepository) GetEmployeeByDeviceMac(ctx context.Context, macAddress string) (*models.Employee, error) {
+	if r.devices == nil {
+		return nil, ErrEmployeeNotFound
+	}
+	return r.lookup(ctx, devicePrefix+strings.ToLower(macAddress), func() (*models.Employee, error) {
+		return r.devices.GetEmployeeByDeviceMac(ctx, macAddress)
+	})
+}
+
+// GetByBeacon returns the cached lookup of the iBeacon, like GetByMacAddress
+func (r *CachedEmployeeRepository) GetByBeacon(ctx context.Context, uuid string, major, minor int) (*models.Employee, error) {
+	id, err := beacon.IBeaconID(uuid, major, minor)
+	if err != nil {
+		return nil, ErrEmployeeNotFound
+	}
+	return r.lookup(ctx, ibeaconPrefix+id, func() (*models.Employee, error) {
+		return r.next.GetByBeacon(ctx, uuid, major, minor)
+	})
+}
+
+// GetByEddystone returns the cached lookup of the Eddystone-UID tag, like GetByMacAddress
+func (r *CachedEmployeeRepository) GetByEddystone(ctx context.Context, namespace, instance string) (*models.Employee, error) {
+	id, err := beacon.EddystoneID(namespace, instance)
+	if err != nil {
+		return nil, ErrEmployeeNotFound
+	}
+	return r.lookup(ctx, eddystonePrefix+id, func() (*models.Employee, error) {
+		return r.next.GetByEddystone(ctx, namespace, instance)
+	})
+}
+
+// lookup returns the cached entry under key, calling fetch when it has expired
+func (r *CachedEmployeeRepository) lookup(ctx context.Context, key string, fetch func() (*models.Employee, error)) (*models.Employee, error) {
+	now := r.clock.Now()
+
+	r.mu.Lock()
+	entry, ok := r.entries[key]
+	r.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		if entry.employee == nil {
+			return nil, ErrEmployeeNotFound
+		}
+		emp := *entry.employee
+		return &emp, nil
+	}
+
+	emp, err := fetch()
+	switch {
+	case err == nil:
+		cached := *emp
+		r.store(key, employeeCacheEntry{employee: &cached, expires: now.Add(r.ttl)})
+	case errors.Is(err, ErrEmployeeNotFound) && r.missTTL > 0:
+		r.store(key, employeeCacheEntry{expires: now.Add(r.missTTL)})
+	}
+	return emp, err
+}
+
+// store saves an entry, now and then dropping expired ones so MACs seen once do not pile up
+func (r *CachedEmployeeRepository) store(key string, entry employeeCacheEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[key] = entry
+
+	now := r.clock.Now()
+	if now.Sub(r.lastSweep) < r.missTTL {
+		return
+	}
+	for k, e := range r.entries {
+		if !now.Before(e.expires) {
+			delete(r.entries, k)
+		}
+	}
+	r.lastSweep = now
+}
+
+// Invalidate drops the cached lookups of a MAC, e.g. after an employee registers with it
+// or adds it as a device
+func (r *CachedEmployeeRepository) Invalidate(macAddress string) {
+	key := strings.ToLower(macAddress)
+	r.mu.Lock()
+	delete(r.entries, key)
+	delete(r.entries, devicePrefix+key)
+	r.mu.Unlock()
+}
+
+// invalidateEmployee drops every cached lookup that found the employee
+func (r *CachedEmployeeRepository) invalidateEmployee(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for k, e := range r.entries {
+		if e.employee != nil && e.employee.ID == id {
+			delete(r.entries, k)
+		}
+	}
+}
+
+// Len returns the number of cached lookups, found or not
+func (r *CachedEmployeeRepository) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries)
+}
+
+// IsCheckedInOn is not cached
+func (r *CachedEmployeeRepository) IsCheckedInOn(ctx context.Context, employeeID string, day time.Time) (bool, error) {
+	return r.next.IsCheckedInOn(ctx, employeeID, day)
+}
+
+// Update writes the employee and drops its cached lookups, under the old MAC and the new
+func (r *CachedEmployeeRepository) Update(ctx context.Context, employee *models.Employee) error {
+	err := r.next.Update(ctx, employee)
+	r.invalidateEmployee(employee.ID)
+	r.Invalidate(employee.MacAddress)
+	return err
+}
+
+// UpdateNotificationPrefs writes the employee's settings and drops its cached lookups
+func (r *CachedEmployeeRepository) UpdateNotificationPrefs(ctx context.Context, employeeID string, prefs models.NotificationPrefs) error {
+	err := r.next.UpdateNotificationPrefs(ctx, employeeID, prefs)
+	r.invalidateEmployee(employeeID)
+	return err
+}
+
+// ListActive is not cached
+func (r *CachedEmployeeRepository) ListActive(ctx context.Context) ([]models.Employee, error) {
+	return r.next.ListActive(ctx)
+}
+
+// GetByCode is not cached
+func (r *CachedEmployeeRepository) GetByCode(ctx context.Context, code string) (*models.Employee, error) {
+	return r.next.GetByCode(ctx, code)
+}
+
+// SetActive switches the employee on or off and drops its cached lookups. The MAC of a
+// reactivated employee is cached as unknown, so every unknown MAC is dropped then.
+func (r *CachedEmployeeRepository) SetActive(ctx context.Context, id string, active bool) error {
+	err := r.next.SetActive(ctx, id, active)
+	r.invalidateEmployee(id)
+	if active {
+		r.mu.Lock()
+		for k, e := range r.entries {
+			if e.employee == nil {
+				delete(r.entries, k)
+			}
+		}
+		r.mu.Unlock()
+	}
+	return err
+}
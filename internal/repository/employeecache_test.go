@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+)
+
+// fakeEmployees is an employee source counting MAC lookups
+type fakeEmployees struct {
+	employees map[string]*models.Employee // ID → employee
+	lookups   int
+	fail      bool
+}
+
+func (f *fakeEmployees) GetByMacAddress(ctx context.Context, mac string) (*models.Employee, error) {
+	f.lookups++
+	if f.fail {
+		return nil, errors.New("pocketbase unavailable")
+	}
+	for _, e := range f.employees {
+		if e.IsActive && strings.EqualFold(e.MacAddress, mac) {
+			emp := *e
+			return &emp, nil
+		}
+	}
+	return nil, ErrEmployeeNotFound
+}
+
+func (f *fakeEmployees) GetByBeacon(ctx context.Context, uuid string, major, minor int) (*models.Employee, error) {
+	f.lookups++
+	return nil, ErrEmployeeNotFound
+}
+
+func (f *fakeEmployees) GetByEddystone(ctx context.Context, namespace, instance string) (*models.Employee, error) {
+	f.lookups++
+	return nil, ErrEmployeeNotFound
+}
+
+func (f *fakeEmployees) IsCheckedInOn(ctx context.Context, id string, day time.Time) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeEmployees) Update(ctx context.Context, e *models.Employee) error {
+	emp := *e
+	f.employees[e.ID] = &emp
+	return nil
+}
+
+func (f *fakeEmployees) ListActive(ctx context.Context) ([]models.Employee, error) {
+	return nil, nil
+}
+
+func (f *fakeEmployees) GetByCode(ctx context.Context, code string) (*models.Employee, error) {
+	return nil, ErrEmployeeNotFound
+}
+
+func (f *fakeEmployees) UpdateNotificationPrefs(ctx context.Context, id string, prefs models.NotificationPrefs) error {
+	f.employees[id].CheckInOptOut = !prefs.CheckIn
+	return nil
+}
+
+func (f *fakeEmployees) SetActive(ctx context.Context, id string, active bool) error {
+	f.employees[id].IsActive = active
+	return nil
+}
+
+func TestCachedEmployeeRepository(t *testing.T) {
+	ctx := context.Background()
+	const known, unknown = "aa:bb:cc:dd:ee:01", "aa:bb:cc:dd:ee:99"
+	source := &fakeEmployees{employees: map[string]*models.Employee{
+		"e1": {ID: "e1", Name: "Somchai", MacAddress: known, IsActive: true},
+	}}
+	clk := clock.NewFake(time.Date(2026, 2, 2, 8, 0, 0, 0, time.UTC))
+	cache := NewCachedEmployeeRepository(source, 5*time.Minute, time.Minute)
+	cache.SetClock(clk)
+
+	steps := []struct {
+		name        string
+		do          func()
+		mac         string
+		wantName    string // empty for not found
+		wantLookups int    // total so far
+	}{
+		{"first lookup", nil, known, "Somchai", 1},
+		{"cached, any spelling", nil, "AA:BB:CC:DD:EE:01", "Somchai", 1},
+		{"unknown", nil, unknown, "", 2},
+		{"unknown is cached", func() { clk.Advance(30 * time.Second) }, unknown, "", 2},
+		{"unknown expires sooner", func() { clk.Advance(30 * time.Second) }, unknown, "", 3},
+		{"registered MAC is invalidated", func() {
+			source.employees["e2"] = &models.Employee{ID: "e2", Name: "Malee", MacAddress: unknown, IsActive: true}
+			cache.Invalidate(unknown)
+		}, unknown, "Malee", 4},
+		{"known expires", func() { clk.Advance(5 * time.Minute) }, known, "Somchai", 5},
+		{"update drops the entry", func() {
+			cache.Update(ctx, &models.Employee{ID: "e1", Name: "Somchai J.", MacAddress: known, IsActive: true})
+		}, known, "Somchai J.", 6},
+		{"notification settings drop the entry", func() {
+			cache.UpdateNotificationPrefs(ctx, "e1", models.NotificationPrefs{CheckIn: false})
+		}, known, "Somchai J.", 7},
+		{"deactivation drops the entry", func() { cache.SetActive(ctx, "e1", false) }, known, "", 8},
+		{"reactivation drops unknown entries", func() { cache.SetActive(ctx, "e1", true) }, known, "Somchai J.", 9},
+	}
+	for _, st := range steps {
+		t.Run(st.name, func(t *testing.T) {
+			if st.do != nil {
+				st.do()
+			}
+			emp, err := cache.GetByMacAddress(ctx, st.mac)
+			switch {
+			case st.wantName == "" && !errors.Is(err, ErrEmployeeNotFound):
+				t.Errorf("GetByMacAddress() = %v, %v, want ErrEmployeeNotFound", emp, err)
+			case st.wantName != "" && (err != nil || emp.Name != st.wantName):
+				t.Errorf("GetByMacAddress() = %v, %v, want %s", emp, err, st.wantName)
+			}
+			if source.lookups != st.wantLookups {
+				t.Errorf("lookups = %d, want %d", source.lookups, st.wantLookups)
+			}
+		})
+	}
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		source.fail = true
+		cache.Invalidate(known)
+		if _, err := cache.GetByMacAddress(ctx, known); err == nil {
+			t.Fatal("GetByMacAddress() succeeded while PocketBase is down")
+		}
+		source.fail = false
+		if emp, err := cache.GetByMacAddress(ctx, known); err != nil || emp.Name != "Somchai J." {
+			t.Errorf("GetByMacAddress() after recovery = %v, %v", emp, err)
+		}
+	})
+
+	t.Run("callers cannot change the cached employee", func(t *testing.T) {
+		emp, _ := cache.GetByMacAddress(ctx, known)
+		emp.Name = "changed"
+		if again, _ := cache.GetByMacAddress(ctx, known); again.Name != "Somchai J." {
+			t.Errorf("cached name = %q", again.Name)
+		}
+	})
+}
+
+// fakeDevices resolves device MACs to employees of a fakeEmployees, counting lookups
+type fakeDevices struct {
+	owners    map[string]string // MAC → employee ID
+	employees *fakeEmployees
+	lookups   int
+}
+
+func (f *fakeDevices) GetEmployeeByDeviceMac(ctx context.Context, mac string) (*models.Employee, error) {
+	f.lookups++
+	if e, ok := f.employees.employees[f.owners[strings.ToLower(mac)]]; ok && e.IsActive {
+		emp := *e
+		return &emp, nil
+	}
+	return nil, ErrEmployeeNotFound
+}
+
+func TestCachedEmployeeRepository_Devices(t *testing.T) {
+	ctx := context.Background()
+	const primary, airpods = "aa:bb:cc:dd:ee:01", "aa:bb:cc:dd:ee:02"
+	source := &fakeEmployees{employees: map[string]*models.Employee{
+		"e1": {ID: "e1", Name: "Somchai", MacAddress: primary, IsActive: true},
+	}}
+	devices := &fakeDevices{owners: map[string]string{}, employees: source}
+	cache := NewCachedEmployeeRepository(source, 5*time.Minute, time.Minute)
+	cache.SetClock(clock.NewFake(time.Date(2026, 2, 2, 8, 0, 0, 0, time.UTC)))
+
+	if _, err := cache.GetEmployeeByDeviceMac(ctx, airpods); !errors.Is(err, ErrEmployeeNotFound) {
+		t.Fatalf("GetEmployeeByDeviceMac() without devices = %v, want ErrEmployeeNotFound", err)
+	}
+	cache.SetDevices(devices)
+
+	if _, err := cache.GetEmployeeByDeviceMac(ctx, airpods); !errors.Is(err, ErrEmployeeNotFound) {
+		t.Fatalf("GetEmployeeByDeviceMac() of an unknown device = %v, want ErrEmployeeNotFound", err)
+	}
+	devices.owners[airpods] = "e1"
+	if _, err := cache.GetEmployeeByDeviceMac(ctx, airpods); !errors.Is(err, ErrEmployeeNotFound) || devices.lookups != 1 {
+		t.Fatalf("GetEmployeeByDeviceMac() = %v after %d lookups, want the cached miss", err, devices.lookups)
+	}
+
+	// Adding the device invalidates its MAC, and the device lookup is cached apart from the MAC lookup
+	cache.Invalidate(airpods)
+	if emp, err := cache.GetEmployeeByDeviceMac(ctx, airpods); err != nil || emp.Name != "Somchai" {
+		t.Fatalf("GetEmployeeByDeviceMac() = %v, %v, want Somchai", emp, err)
+	}
+	if _, err := cache.GetByMacAddress(ctx, airpods); !errors.Is(err, ErrEmployeeNotFound) {
+		t.Errorf("GetByMacAddress() of the device = %v, want ErrEmployeeNotFound", err)
+	}
+	if devices.lookups != 2 || source.lookups != 1 {
+		t.Errorf("lookups = %d devices, %d employees, want 2 and 1", devices.lookups, source.lookups)
+	}
+
+	cache.SetActive(ctx, "e1", false)
+	if _, err := cache.GetEmployeeByDeviceMac(ctx, airpods); !errors.Is(err, ErrEmployeeNotFound) {
+		t.Errorf("GetEmployeeByDeviceMac() after deactivation = %v, want ErrEmployeeNotFound", err)
+	}
+}
@@ -3,31 +3,356 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
+
 	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/pbclient"
+)
+
+// Kinds of repository failures, matched with errors.Is. ErrNotFound and ErrAlreadyExists
+// are answers; the others are failures a caller must not mistake for "no such record".
+var (
+	// ErrNotFound means the record does not exist; the ErrXNotFound errors below are ErrNotFound
+	ErrNotFound = pbclient.ErrNotFound
+	// ErrAlreadyExists means a record that may exist only once is already stored, so the
+	// write has nothing left to do
+	ErrAlreadyExists = errors.New("already exists")
+	// ErrUnauthorized means PocketBase refused the token (401 or 403)
+	ErrUnauthorized = pbclient.ErrUnauthorized
+	// ErrUnavailable means PocketBase could not be reached, timed out or answered 5xx, and
+	// for writes kept doing so on every attempt. Callers may queue the work instead of
+	// dropping it.
+	ErrUnavailable = pbclient.ErrUnavailable
+	// ErrBadRequest means PocketBase rejected the request (400); the error carries its message
+	ErrBadRequest = pbclient.ErrBadRequest
 )
 
+// ErrEmployeeNotFound is returned by GetByMacAddress when no active employee owns the MAC
+var ErrEmployeeNotFound = fmt.Errorf("employee %w", ErrNotFound)
+
+// ErrEmployeeExists is returned by Register when the MAC is already registered
+var ErrEmployeeExists = fmt.Errorf("employee %w", ErrAlreadyExists)
+
+// ErrScannerNotFound is returned by GetByToken when no scanner holds the token
+var ErrScannerNotFound = fmt.Errorf("scanner %w", ErrNotFound)
+
+// ErrDeviceNotFound is returned by Review when the devices collection has no record of the
+// MAC, and by RemoveDevice when the employee has no such device
+var ErrDeviceNotFound = fmt.Errorf("device %w", ErrNotFound)
+
+// ErrDeviceExists is returned by AddDevice when the MAC is already an employee's device
+var ErrDeviceExists = fmt.Errorf("device %w", ErrAlreadyExists)
+
+// ErrAttendanceExists is returned by Create for a check-in of an employee already checked
+// in that day
+var ErrAttendanceExists = fmt.Errorf("attendance of the day %w", ErrAlreadyExists)
+
 // EmployeeRepository defines the interface for employee data access
 type EmployeeRepository interface {
 	// GetByMacAddress retrieves an employee by their MAC address
 	GetByMacAddress(ctx context.Context, macAddress string) (*models.Employee, error)
-	// IsCheckedInToday checks if employee already checked in today
-	IsCheckedInToday(ctx context.Context, employeeID string) (bool, error)
+	// GetByBeacon retrieves the active employee carrying the iBeacon, or ErrEmployeeNotFound
+	GetByBeacon(ctx context.Context, uuid string, major, minor int) (*models.Employee, error)
+	// GetByEddystone retrieves the active employee carrying the Eddystone-UID tag, or
+	// ErrEmployeeNotFound
+	GetByEddystone(ctx context.Context, namespace, instance string) (*models.Employee, error)
+	// IsCheckedInOn checks if the employee already has a check-in for the day (created_date),
+	// e.g. the day their current shift started
+	IsCheckedInOn(ctx context.Context, employeeID string, day time.Time) (bool, error)
+	// Update writes the employee's profile: name, code, department, display name and schedule
+	Update(ctx context.Context, employee *models.Employee) error
+	// UpdateNotificationPrefs writes the notification settings the employee chose
+	UpdateNotificationPrefs(ctx context.Context, employeeID string, prefs models.NotificationPrefs) error
+}
+
+// DeviceRepository resolves employees through their registered devices
+type DeviceRepository interface {
+	// GetEmployeeByDeviceMac returns the active employee owning the device with the MAC,
+	// or ErrEmployeeNotFound
+	GetEmployeeByDeviceMac(ctx context.Context, macAddress string) (*models.Employee, error)
+}
+
+// DeviceSightingRepository records devices no employee owns (the devices collection)
+type DeviceSightingRepository interface {
+	// UpsertSighting creates or updates the device's record with the sighting's RSSI and
+	// time, adding its count to the device's detection count
+	UpsertSighting(ctx context.Context, sighting models.DeviceSighting) error
+	// ListPending returns the devices seen since the time that no admin has reviewed yet
+	ListPending(ctx context.Context, since time.Time) ([]models.DeviceSighting, error)
+	// Review records an admin's decision on the device: whitelisted once assigned to an
+	// employee, not whitelisted when ignored. Either way it stops being pending.
+	Review(ctx context.Context, mac string, whitelisted bool) error
+	// ListWhitelisted returns the lower-case MACs of the devices admins whitelisted
+	ListWhitelisted(ctx context.Context) ([]string, error)
+}
+
+// DeviceAssignment gives employees the devices admins approve
+type DeviceAssignment interface {
+	// AssignDevice makes the MAC the employee's: their mac_address when they have none,
+	// an extra employee_devices record otherwise
+	AssignDevice(ctx context.Context, employee *models.Employee, mac string) error
+}
+
+// EmployeeDevices is the extra devices employees manage themselves with /add_device and
+// /remove_device
+type EmployeeDevices interface {
+	// ListDevices returns the employee's extra devices by MAC
+	ListDevices(ctx context.Context, employeeID string) ([]models.EmployeeDevice, error)
+	// AddDevice creates the device, or returns ErrDeviceExists when the MAC is taken
+	AddDevice(ctx context.Context, device *models.EmployeeDevice) error
+	// RemoveDevice deletes the employee's device with the MAC, or returns ErrDeviceNotFound
+	RemoveDevice(ctx context.Context, employeeID, mac string) error
+}
+
+// ConsentLog keeps the audit trail of presence tracking consent changes (privacy_audit)
+type ConsentLog interface {
+	// RecordConsent records that the chat or user `by` set the employee's consent at the time
+	RecordConsent(ctx context.Context, employeeID string, consent bool, by int64, at time.Time) error
+}
+
+// EmployeeDirectory lists employees for views that cover the whole staff
+type EmployeeDirectory interface {
+	// ListActive returns every active employee
+	ListActive(ctx context.Context) ([]models.Employee, error)
+}
+
+// EmployeeActivation switches employees on and off
+type EmployeeActivation interface {
+	// GetByCode returns the employee with the code, active or not, or ErrEmployeeNotFound
+	GetByCode(ctx context.Context, code string) (*models.Employee, error)
+	// SetActive sets the employee's is_active flag
+	SetActive(ctx context.Context, id string, active bool) error
+}
+
+// GuestRegistry registers time-boxed guest tags
+type GuestRegistry interface {
+	// CreateGuest creates an active guest record for MacAddress, valid through GuestUntil
+	CreateGuest(ctx context.Context, guest *models.Employee) error
+}
+
+// EmployeeAccounts is the chat's own employee record, for the bot's self-service commands
+type EmployeeAccounts interface {
+	// GetByChatID returns the active employee registered from the Telegram chat, or
+	// ErrEmployeeNotFound
+	GetByChatID(ctx context.Context, chatID int64) (*models.Employee, error)
+	// GetOwnerByMac returns the employee whose mac_address is the MAC, active or not, or
+	// ErrEmployeeNotFound
+	GetOwnerByMac(ctx context.Context, macAddress string) (*models.Employee, error)
+	// Register creates the employee a chat registered: identity, department, start time and
+	// the settings below. It returns ErrEmployeeExists when the MAC is taken.
+	Register(ctx context.Context, employee *models.Employee) error
+	// UpdateSettings writes what employees change themselves: their MAC, schedule, board
+	// visibility, muted notifications, presence tracking consent and language
+	UpdateSettings(ctx context.Context, employee *models.Employee) error
 }
 
 // AttendanceRepository defines the interface for attendance data access
 type AttendanceRepository interface {
-	// Create records a new attendance check-in
+	// Create records a new attendance check-in, or returns ErrAttendanceExists when the
+	// employee already has a record for its created_date
 	Create(ctx context.Context, attendance *models.Attendance) error
 }
 
+// AttendanceLog reads back recorded check-ins
+type AttendanceLog interface {
+	// ListByDate returns all attendance records created on the given day
+	ListByDate(ctx context.Context, date time.Time) ([]models.Attendance, error)
+	// ListSince returns all attendance records created on or after the given day, oldest first
+	ListSince(ctx context.Context, since time.Time) ([]models.Attendance, error)
+	// ListBetween returns the attendance records created on or after from and before to, oldest first
+	ListBetween(ctx context.Context, from, to time.Time) ([]models.Attendance, error)
+	// ListOpenForDate returns the attendance records created on the given day that have no
+	// check-out yet
+	ListOpenForDate(ctx context.Context, date time.Time) ([]models.Attendance, error)
+	// ListByEmployeeAndRange returns the employee's attendance records created on or after
+	// from and before to, oldest first
+	ListByEmployeeAndRange(ctx context.Context, employeeID string, from, to time.Time) ([]models.Attendance, error)
+}
+
+// Page selects one page of a listing; pages start at 1
+type Page struct {
+	Number int
+	Size   int
+}
+
+// AttendanceBrowser lists attendance records a page at a time, for the dashboard API
+type AttendanceBrowser interface {
+	// PageByDate returns a page of the records created on the given day, by check-in time,
+	// and the number of records on all pages
+	PageByDate(ctx context.Context, date time.Time, page Page) ([]models.Attendance, int, error)
+	// PageByEmployeeAndRange returns a page of the employee's records created on or after
+	// from and before to, oldest first, and the number of records on all pages
+	PageByEmployeeAndRange(ctx context.Context, employeeID string, from, to time.Time, page Page) ([]models.Attendance, int, error)
+}
+
+// EmployeeBrowser lists employees a page at a time, for the dashboard API and /employees
+type EmployeeBrowser interface {
+	// PageActive returns a page of the active employees by name, without the self-test
+	// employee, and the number of employees on all pages
+	PageActive(ctx context.Context, page Page) ([]models.Employee, int, error)
+	// List is PageActive narrowed to employees whose name or code contains query, ignoring
+	// case; an empty query matches everyone
+	List(ctx context.Context, query string, page Page) ([]models.Employee, int, error)
+}
+
+// AttendanceHistory reads back one employee's check-ins, for /today and /history
+type AttendanceHistory interface {
+	// ListHistory returns a page of the employee's records created on the days first to
+	// last, newest first, and the number of records on all pages
+	ListHistory(ctx context.Context, employeeID string, first, last time.Time, page Page) ([]models.Attendance, int, error)
+}
+
+// AttendanceToday finds an employee's check-in of the day
+type AttendanceToday interface {
+	// GetTodayByEmployee returns the employee's attendance record of today, or nil if
+	// they have not checked in
+	GetTodayByEmployee(ctx context.Context, employeeID string) (*models.Attendance, error)
+	// GetByEmployeeOn returns the employee's attendance record created on the day, or nil
+	// if they have not checked in that day
+	GetByEmployeeOn(ctx context.Context, employeeID string, day time.Time) (*models.Attendance, error)
+}
+
+// AttendanceUpdater changes recorded check-ins
+type AttendanceUpdater interface {
+	// Get returns one attendance record by ID
+	Get(ctx context.Context, id string) (*models.Attendance, error)
+	// UpdateCheckOut writes the check-out time, source and review flag of a record
+	UpdateCheckOut(ctx context.Context, attendance *models.Attendance) error
+}
+
+// BaselineRepository stores per-employee check-in time baselines
+type BaselineRepository interface {
+	// Get returns the employee's baseline, or nil if none has been recorded yet
+	Get(ctx context.Context, employeeID string) (*models.CheckInBaseline, error)
+	// Save creates or updates the baseline
+	Save(ctx context.Context, baseline *models.CheckInBaseline) error
+}
+
+// PeriodLockRepository stores which payroll periods are locked
+type PeriodLockRepository interface {
+	// Get returns the period's record, or nil if it was never locked
+	Get(ctx context.Context, period string) (*models.LockedPeriod, error)
+	// Save creates or updates the period's record
+	Save(ctx context.Context, lock *models.LockedPeriod) error
+	// ListLocked returns the currently locked periods, oldest first
+	ListLocked(ctx context.Context) ([]models.LockedPeriod, error)
+}
+
+// LeaseRepository stores the leadership lease of instances sharing one PocketBase
+type LeaseRepository interface {
+	// Latest returns the lease term with the highest number, or nil if none was ever taken
+	Latest(ctx context.Context, name string) (*models.Lease, error)
+	// Create takes a new term; ErrLeaseTaken means another instance created it first
+	Create(ctx context.Context, lease *models.Lease) error
+	// Renew moves the expiry of a term the caller holds
+	Renew(ctx context.Context, lease *models.Lease) error
+}
+
 // EmployeeDetectionRepository defines the interface for employee detection data access
 type EmployeeDetectionRepository interface {
 	// Create saves a new employee detection record
 	Create(ctx context.Context, detection *models.EmployeeDetection) error
 }
 
+// DetectionLog reads back recorded detections
+type DetectionLog interface {
+	// ListByEmployeeSince returns the employee's detections at or after since, oldest first
+	ListByEmployeeSince(ctx context.Context, employeeID string, since time.Time) ([]models.EmployeeDetection, error)
+	// ListRecent returns every employee's detections at or after since, oldest first
+	ListRecent(ctx context.Context, since time.Time) ([]models.EmployeeDetection, error)
+	// GetLastForEmployeeOnDate returns the employee's last detection on the given day, or nil
+	GetLastForEmployeeOnDate(ctx context.Context, employeeID string, date time.Time) (*models.EmployeeDetection, error)
+}
+
+// UnlinkedAttendance finds the check-ins that lost their employee_id while it was a number
+// field (migration 033) and links them to their employee again
+type UnlinkedAttendance interface {
+	// ListUnlinked returns the attendance records without an employee_id, oldest first
+	ListUnlinked(ctx context.Context) ([]models.Attendance, error)
+	// Relink sets the employee_id of one record
+	Relink(ctx context.Context, id, employeeID string) error
+}
+
+// UnlinkedDetections finds the detections that lost their employee_id while it was a
+// number field (migration 033) and links them to their employee again
+type UnlinkedDetections interface {
+	// ListUnlinked returns the detections without an employee_id, oldest first
+	ListUnlinked(ctx context.Context) ([]models.EmployeeDetection, error)
+	// Relink sets the employee_id of one record
+	Relink(ctx context.Context, id, employeeID string) error
+}
+
+// LateApprovalRepository stores late arrival requests and the admins' decisions
+type LateApprovalRepository interface {
+	// Create stores a new request
+	Create(ctx context.Context, approval *models.LateApproval) error
+	// Get returns one request by ID, or nil if there is none
+	Get(ctx context.Context, id string) (*models.LateApproval, error)
+	// Update writes the status and decision of a request
+	Update(ctx context.Context, approval *models.LateApproval) error
+	// ListByEmployeeDate returns the employee's requests for the day (YYYY-MM-DD)
+	ListByEmployeeDate(ctx context.Context, employeeID, date string) ([]models.LateApproval, error)
+	// ListPending returns every request waiting for a decision, oldest day first
+	ListPending(ctx context.Context) ([]models.LateApproval, error)
+}
+
+// HolidayRepository reads the holidays of the work calendar
+type HolidayRepository interface {
+	// GetByDate returns the holiday on the day (YYYY-MM-DD), or nil if there is none
+	GetByDate(ctx context.Context, date string) (*models.Holiday, error)
+}
+
+// AuditLog records administrative changes
+type AuditLog interface {
+	// Record appends an entry
+	Record(ctx context.Context, entry *models.AuditEntry) error
+}
+
+// NotificationFailureLog keeps the bot messages that could not be delivered
+type NotificationFailureLog interface {
+	// Record appends a failed message
+	Record(ctx context.Context, failure *models.NotificationFailure) error
+}
+
+// SelfTestRepository manages the synthetic self-test employee and its records
+type SelfTestRepository interface {
+	// EnsureSyntheticEmployee returns the synthetic employee with the MAC, creating it if
+	// needed; it fails if a real employee already owns the MAC
+	EnsureSyntheticEmployee(ctx context.Context, macAddress string) (*models.Employee, error)
+	// CountDetectionsSince counts the employee's detection records at or after since
+	CountDetectionsSince(ctx context.Context, employeeID string, since time.Time) (int, error)
+	// DeleteRecordsBefore deletes the employee's attendance and detection records from
+	// before the given time and returns how many were deleted
+	DeleteRecordsBefore(ctx context.Context, employeeID string, before time.Time) (int, error)
+}
+
 // ScannerRepository defines the interface for scanner data access
 type ScannerRepository interface {
-	// UpdateActivity updates the last seen timestamp for a scanner
-	UpdateActivity(ctx context.Context, scannerMac string) error
+	// UpdateActivity upserts the scanner's last seen time and the health fields it reported;
+	// fields left zero keep their stored value
+	UpdateActivity(ctx context.Context, scannerMac string, health models.ScannerHealth) error
+	// GetByToken returns the scanner holding the device token, or ErrScannerNotFound
+	GetByToken(ctx context.Context, token string) (*models.Scanner, error)
+	// GetByMac returns the scanner stored under any spelling of the ID, or ErrScannerNotFound
+	GetByMac(ctx context.Context, scannerMac string) (*models.Scanner, error)
+}
+
+// ScannerRegistry provisions scanners
+type ScannerRegistry interface {
+	// Pair creates or updates the scanner record with its zone and pairing time
+	Pair(ctx context.Context, scannerMac, zone string, at time.Time) error
+	// List returns every scanner record
+	List(ctx context.Context) ([]models.Scanner, error)
+	// SetMac rewrites the scanner_mac of the record with the given ID
+	SetMac(ctx context.Context, id, scannerMac string) error
+	// SetProfile creates or updates the scanner record with the configuration profile it
+	// follows; an empty profile returns it to the defaults
+	SetProfile(ctx context.Context, scannerMac, profile string) error
+	// SetToken creates or updates the scanner record with the device token it
+	// authenticates with; an empty token revokes it
+	SetToken(ctx context.Context, scannerMac, token string) error
+	// Rename creates or updates the scanner record with the friendly name employees see
+	Rename(ctx context.Context, scannerMac, name string) error
 }
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"med-pulse-bot/internal/localstore"
+	"med-pulse-bot/internal/localstore/localstoretest"
+)
+
+// fakeLocalQueue serves the local_queue collection the way PocketBase does, with small
+// pages so listing has to follow them
+type fakeLocalQueue struct {
+	mu      sync.Mutex
+	records map[string]localQueueRecord
+	nextID  int
+}
+
+var keyFilter = regexp.MustCompile(`^key='(.*)'$`)
+
+func (f *fakeLocalQueue) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	const base = "/api/collections/clinic_a_local_queue/records"
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, base), "/")
+	if !strings.HasPrefix(r.URL.Path, base) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":404,"message":"Missing collection context.","data":{}}`))
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		match := keyFilter.FindStringSubmatch(r.URL.Query().Get("filter"))
+		if match == nil || r.URL.Query().Get("sort") != "seq" {
+			http.Error(w, "unexpected query "+r.URL.RawQuery, http.StatusBadRequest)
+			return
+		}
+		var items []localQueueRecord
+		for _, rec := range f.records {
+			if rec.Key == match[1] {
+				items = append(items, rec)
+			}
+		}
+		sort.Slice(items, func(i, j int) bool { return items[i].Seq < items[j].Seq })
+
+		const perPage = 3
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		totalPages := (len(items) + perPage - 1) / perPage
+		start := min((page-1)*perPage, len(items))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"page": page, "totalPages": totalPages, "items": items[start:min(start+perPage, len(items))],
+		})
+	case r.Method == http.MethodPost && id == "":
+		var rec localQueueRecord
+		json.NewDecoder(r.Body).Decode(&rec)
+		f.nextID++
+		rec.ID = fmt.Sprintf("lq%d", f.nextID)
+		f.records[rec.ID] = rec
+		json.NewEncoder(w).Encode(rec)
+	case r.Method == http.MethodPatch:
+		if _, ok := f.records[id]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		var rec localQueueRecord
+		json.NewDecoder(r.Body).Decode(&rec)
+		rec.ID = id
+		f.records[id] = rec
+		json.NewEncoder(w).Encode(rec)
+	case r.Method == http.MethodDelete:
+		if _, ok := f.records[id]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		delete(f.records, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "unexpected request", http.StatusMethodNotAllowed)
+	}
+}
+
+func TestPocketBaseLocalStore(t *testing.T) {
+	localstoretest.Run(t, func(t *testing.T) localstore.LocalStore {
+		server := httptest.NewServer(&fakeLocalQueue{records: make(map[string]localQueueRecord)})
+		t.Cleanup(server.Close)
+		return Site{URL: server.URL, Prefix: "clinic_a_"}.LocalStore()
+	})
+
+	t.Run("collection not migrated", func(t *testing.T) {
+		server := httptest.NewServer(&fakeLocalQueue{records: make(map[string]localQueueRecord)})
+		defer server.Close()
+		store := Site{URL: server.URL}.LocalStore()
+		if err := store.Append(t.Context(), "queue.jsonl", []byte(`{}`)); err == nil {
+			t.Error("Append() succeeded without the local_queue collection")
+		}
+	})
+}
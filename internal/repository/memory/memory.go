@@ -0,0 +1,1373 @@
+// Package memory provides in-memory implementations of the repository interfaces.
+// They mimic the PocketBase REST repositories closely enough for scenario tests and
+// local development: MAC lookups are case-insensitive and "today" follows the clock.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/beacon"
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/macaddr"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// Store holds all records shared by the in-memory repositories
+type Store struct {
+	mu         sync.Mutex
+	clock      clock.Clock
+	nextID     int
+	employees  []models.Employee
+	attendance []models.Attendance
+	detections []models.EmployeeDetection
+	scanners   map[string]*models.Scanner
+	tokens     map[string]string // token hash → scanner ID
+	baselines  map[string]models.CheckInBaseline
+	periods    map[string]models.LockedPeriod // period → lock record
+	leases     []models.Lease
+	audit      []models.AuditEntry
+	failures   []models.NotificationFailure
+	late       []models.LateApproval
+	holidays   []models.Holiday
+	devices    []models.EmployeeDevice
+	sightings  map[string]models.DeviceSighting // MAC → sighting with the total count
+}
+
+// NewStore creates an empty store; clk decides what "today" means
+func NewStore(clk clock.Clock) *Store {
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	return &Store{
+		clock:     clk,
+		scanners:  make(map[string]*models.Scanner),
+		tokens:    make(map[string]string),
+		baselines: make(map[string]models.CheckInBaseline),
+		periods:   make(map[string]models.LockedPeriod),
+		sightings: make(map[string]models.DeviceSighting),
+	}
+}
+
+func (s *Store) newID(prefix string) string {
+	s.nextID++
+	return fmt.Sprintf("%s%d", prefix, s.nextID)
+}
+
+// AddEmployee seeds an employee record, assigning an ID if empty
+func (s *Store) AddEmployee(emp models.Employee) models.Employee {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if emp.ID == "" {
+		emp.ID = s.newID("emp")
+	}
+	s.employees = append(s.employees, emp)
+	return emp
+}
+
+// AddScanner seeds a scanner record as given, without normalizing its ID
+func (s *Store) AddScanner(sc models.Scanner) models.Scanner {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sc.ID == "" {
+		sc.ID = s.newID("scn")
+	}
+	s.scanners[sc.ScannerMac] = &sc
+	return sc
+}
+
+// AddAttendance seeds an attendance record as given, even a second one of the employee's
+// day as older data may hold, assigning an ID if empty
+func (s *Store) AddAttendance(a models.Attendance) models.Attendance {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a.ID == "" {
+		a.ID = s.newID("att")
+	}
+	s.attendance = append(s.attendance, a)
+	return a
+}
+
+// Attendance returns a copy of all attendance records in creation order
+func (s *Store) Attendance() []models.Attendance {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.Attendance(nil), s.attendance...)
+}
+
+// Detections returns a copy of all detection records in creation order
+func (s *Store) Detections() []models.EmployeeDetection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.EmployeeDetection(nil), s.detections...)
+}
+
+// AuditEntries returns a copy of the audit log in recording order
+func (s *Store) AuditEntries() []models.AuditEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.AuditEntry(nil), s.audit...)
+}
+
+// Scanners returns a copy of all scanner records
+func (s *Store) Scanners() []models.Scanner {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]models.Scanner, 0, len(s.scanners))
+	for _, sc := range s.scanners {
+		out = append(out, *sc)
+	}
+	return out
+}
+
+// EmployeeRepository implements repository.EmployeeRepository
+type EmployeeRepository struct{ store *Store }
+
+// AttendanceRepository implements repository.AttendanceRepository
+type AttendanceRepository struct{ store *Store }
+
+// DetectionRepository implements repository.EmployeeDetectionRepository
+type DetectionRepository struct{ store *Store }
+
+// ScannerRepository implements repository.ScannerRepository
+type ScannerRepository struct{ store *Store }
+
+// BaselineRepository implements repository.BaselineRepository
+type BaselineRepository struct{ store *Store }
+
+// SelfTestRepository implements repository.SelfTestRepository
+type SelfTestRepository struct{ store *Store }
+
+// NotificationFailures returns a copy of the undelivered bot messages in recording order
+func (s *Store) NotificationFailures() []models.NotificationFailure {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.NotificationFailure(nil), s.failures...)
+}
+
+// DeviceSightings returns a copy of the unknown device records, ordered by MAC, each
+// with its total detection count
+func (s *Store) DeviceSightings() []models.DeviceSighting {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]models.DeviceSighting, 0, len(s.sightings))
+	for _, sighting := range s.sightings {
+		out = append(out, sighting)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].MacAddress < out[j].MacAddress })
+	return out
+}
+
+// PeriodLockRepository implements repository.PeriodLockRepository
+type PeriodLockRepository struct{ store *Store }
+
+// LeaseRepository implements repository.LeaseRepository
+type LeaseRepository struct{ store *Store }
+
+// AuditLogRepository implements repository.AuditLog
+type AuditLogRepository struct{ store *Store }
+
+// NotificationFailureRepository implements repository.NotificationFailureLog
+type NotificationFailureRepository struct{ store *Store }
+
+// LateApprovalRepository implements repository.LateApprovalRepository
+type LateApprovalRepository struct{ store *Store }
+
+// HolidayRepository implements repository.HolidayRepository
+type HolidayRepository struct{ store *Store }
+
+// DeviceRepository implements repository.DeviceRepository
+type DeviceRepository struct{ store *Store }
+
+// DeviceSightingRepository implements repository.DeviceSightingRepository
+type DeviceSightingRepository struct{ store *Store }
+
+// Employees returns the employee repository view of the store
+func (s *Store) Employees() *EmployeeRepository { return &EmployeeRepository{store: s} }
+
+// AttendanceRecords returns the attendance repository view of the store
+func (s *Store) AttendanceRecords() *AttendanceRepository { return &AttendanceRepository{store: s} }
+
+// DetectionRecords returns the detection repository view of the store
+func (s *Store) DetectionRecords() *DetectionRepository { return &DetectionRepository{store: s} }
+
+// ScannerRecords returns the scanner repository view of the store
+func (s *Store) ScannerRecords() *ScannerRepository { return &ScannerRepository{store: s} }
+
+// Baselines returns the check-in baseline repository view of the store
+func (s *Store) Baselines() *BaselineRepository { return &BaselineRepository{store: s} }
+
+// SelfTest returns the self-test repository view of the store
+func (s *Store) SelfTest() *SelfTestRepository { return &SelfTestRepository{store: s} }
+
+// PeriodLocks returns the locked payroll period repository view of the store
+func (s *Store) PeriodLocks() *PeriodLockRepository { return &PeriodLockRepository{store: s} }
+
+// Leases returns the instance lease repository view of the store
+func (s *Store) Leases() *LeaseRepository { return &LeaseRepository{store: s} }
+
+// AuditLog returns the audit log view of the store
+func (s *Store) AuditLog() *AuditLogRepository { return &AuditLogRepository{store: s} }
+
+// NotificationFailureLog returns the undelivered bot message view of the store
+func (s *Store) NotificationFailureLog() *NotificationFailureRepository {
+	return &NotificationFailureRepository{store: s}
+}
+
+// Devices returns the employee device repository view of the store
+func (s *Store) Devices() *DeviceRepository { return &DeviceRepository{store: s} }
+
+// DeviceSightingLog returns the unknown device repository view of the store
+func (s *Store) DeviceSightingLog() *DeviceSightingRepository {
+	return &DeviceSightingRepository{store: s}
+}
+
+// Holidays returns the holiday repository view of the store
+func (s *Store) Holidays() *HolidayRepository { return &HolidayRepository{store: s} }
+
+// LateApprovals returns the late arrival approval repository view of the store
+func (s *Store) LateApprovals() *LateApprovalRepository { return &LateApprovalRepository{store: s} }
+
+// GetByMacAddress returns the active employee with the MAC (case-insensitive)
+func (r *EmployeeRepository) GetByMacAddress(ctx context.Context, macAddress string) (*models.Employee, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, emp := range r.store.employees {
+		if emp.IsActive && strings.EqualFold(emp.MacAddress, macAddress) {
+			e := emp
+			return &e, nil
+		}
+	}
+	return nil, repository.ErrEmployeeNotFound
+}
+
+// GetByBeacon returns the active employee whose ibeacon_id matches the iBeacon
+func (r *EmployeeRepository) GetByBeacon(ctx context.Context, uuid string, major, minor int) (*models.Employee, error) {
+	id, err := beacon.IBeaconID(uuid, major, minor)
+	if err != nil {
+		return nil, repository.ErrEmployeeNotFound
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, emp := range r.store.employees {
+		if emp.IsActive && emp.IBeaconID == id {
+			e := emp
+			return &e, nil
+		}
+	}
+	return nil, repository.ErrEmployeeNotFound
+}
+
+// GetByEddystone returns the active employee whose eddystone_id matches the tag
+func (r *EmployeeRepository) GetByEddystone(ctx context.Context, namespace, instance string) (*models.Employee, error) {
+	id, err := beacon.EddystoneID(namespace, instance)
+	if err != nil {
+		return nil, repository.ErrEmployeeNotFound
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, emp := range r.store.employees {
+		if emp.IsActive && emp.EddystoneID == id {
+			e := emp
+			return &e, nil
+		}
+	}
+	return nil, repository.ErrEmployeeNotFound
+}
+
+// IsCheckedInOn reports whether an attendance record exists for the day's date
+func (r *EmployeeRepository) IsCheckedInOn(ctx context.Context, employeeID string, day time.Time) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, a := range r.store.attendance {
+		// created_date is a day of the service's timezone, so the day is taken in it too
+		if a.EmployeeID == employeeID && a.CreatedDate.Format("2006-01-02") == day.In(a.CreatedDate.Location()).Format("2006-01-02") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ListActive returns every active non-synthetic employee in insertion order
+func (r *EmployeeRepository) ListActive(ctx context.Context) ([]models.Employee, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var out []models.Employee
+	for _, emp := range r.store.employees {
+		if emp.IsActive && !emp.IsSynthetic {
+			out = append(out, emp)
+		}
+	}
+	return out, nil
+}
+
+// PageActive returns a page of the active employees by name, and how many there are
+func (r *EmployeeRepository) PageActive(ctx context.Context, page repository.Page) ([]models.Employee, int, error) {
+	return r.List(ctx, "", page)
+}
+
+// List returns a page of the active employees whose name or code contains query, by
+// name, and how many there are
+func (r *EmployeeRepository) List(ctx context.Context, query string, page repository.Page) ([]models.Employee, int, error) {
+	active, _ := r.ListActive(ctx)
+	query = strings.ToLower(strings.TrimSpace(query))
+	var matches []models.Employee
+	for _, emp := range active {
+		if strings.Contains(strings.ToLower(emp.Name), query) || strings.Contains(strings.ToLower(emp.EmployeeCode), query) {
+			matches = append(matches, emp)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Name < matches[j].Name })
+	return pageOf(matches, page), len(matches), nil
+}
+
+// Update writes the employee's profile fields
+func (r *EmployeeRepository) Update(ctx context.Context, employee *models.Employee) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i := range r.store.employees {
+		emp := &r.store.employees[i]
+		if emp.ID != employee.ID {
+			continue
+		}
+		emp.Name = employee.Name
+		emp.EmployeeCode = employee.EmployeeCode
+		emp.Department = employee.Department
+		emp.DisplayName = employee.DisplayName
+		emp.WorkStartTime = employee.WorkStartTime
+		emp.WorkEndTime = employee.WorkEndTime
+		return nil
+	}
+	return fmt.Errorf("employee %s: %w", employee.ID, repository.ErrNotFound)
+}
+
+// CreateGuest adds an active guest record
+func (r *EmployeeRepository) CreateGuest(ctx context.Context, guest *models.Employee) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	guest.ID = r.store.newID("emp")
+	guest.IsActive, guest.IsGuest = true, true
+	r.store.employees = append(r.store.employees, *guest)
+	return nil
+}
+
+// GetByChatID returns the active employee registered from the chat
+func (r *EmployeeRepository) GetByChatID(ctx context.Context, chatID int64) (*models.Employee, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, emp := range r.store.employees {
+		if emp.IsActive && emp.TelegramChatID == chatID {
+			e := emp
+			return &e, nil
+		}
+	}
+	return nil, repository.ErrEmployeeNotFound
+}
+
+// GetOwnerByMac returns the employee with the MAC (case-insensitive), active or not
+func (r *EmployeeRepository) GetOwnerByMac(ctx context.Context, macAddress string) (*models.Employee, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, emp := range r.store.employees {
+		if strings.EqualFold(emp.MacAddress, macAddress) {
+			e := emp
+			return &e, nil
+		}
+	}
+	return nil, repository.ErrEmployeeNotFound
+}
+
+// Register stores the employee unless the MAC is taken
+func (r *EmployeeRepository) Register(ctx context.Context, employee *models.Employee) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, emp := range r.store.employees {
+		if strings.EqualFold(emp.MacAddress, employee.MacAddress) {
+			return repository.ErrEmployeeExists
+		}
+	}
+	employee.ID = r.store.newID("emp")
+	employee.MacAddress = strings.ToLower(employee.MacAddress)
+	r.store.employees = append(r.store.employees, *employee)
+	return nil
+}
+
+// UpdateSettings sets the fields employees change themselves
+func (r *EmployeeRepository) UpdateSettings(ctx context.Context, employee *models.Employee) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i := range r.store.employees {
+		if e := &r.store.employees[i]; e.ID == employee.ID {
+			e.MacAddress = strings.ToLower(employee.MacAddress)
+			e.WorkStartTime, e.WorkEndTime = employee.WorkStartTime, employee.WorkEndTime
+			e.ShowOnBoard = employee.ShowOnBoard
+			e.MutedNotifications = append([]string(nil), employee.MutedNotifications...)
+			e.PresenceOptOut = employee.PresenceOptOut
+			e.Language = employee.Language
+			return nil
+		}
+	}
+	return repository.ErrEmployeeNotFound
+}
+
+// GetByCode returns the employee with the code, active or not; an active one wins
+func (r *EmployeeRepository) GetByCode(ctx context.Context, code string) (*models.Employee, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var found *models.Employee
+	for i := range r.store.employees {
+		if emp := r.store.employees[i]; emp.EmployeeCode == code && (found == nil || emp.IsActive) {
+			found = &emp
+		}
+	}
+	if found == nil {
+		return nil, repository.ErrEmployeeNotFound
+	}
+	return found, nil
+}
+
+// UpdateNotificationPrefs sets the employee's notification settings
+func (r *EmployeeRepository) UpdateNotificationPrefs(ctx context.Context, employeeID string, prefs models.NotificationPrefs) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i := range r.store.employees {
+		if r.store.employees[i].ID == employeeID {
+			r.store.employees[i].CheckInOptOut = !prefs.CheckIn
+			r.store.employees[i].QuietHours = prefs.QuietHours
+			return nil
+		}
+	}
+	return fmt.Errorf("employee %s: %w", employeeID, repository.ErrNotFound)
+}
+
+// SetActive sets the employee's is_active flag
+func (r *EmployeeRepository) SetActive(ctx context.Context, id string, active bool) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i := range r.store.employees {
+		if r.store.employees[i].ID == id {
+			r.store.employees[i].IsActive = active
+			return nil
+		}
+	}
+	return fmt.Errorf("employee %s: %w", id, repository.ErrNotFound)
+}
+
+// ListByDate returns attendance records created on the given day
+func (r *AttendanceRepository) ListByDate(ctx context.Context, date time.Time) ([]models.Attendance, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	day := date.Format("2006-01-02")
+	var out []models.Attendance
+	for _, a := range r.store.attendance {
+		if a.CreatedDate.Format("2006-01-02") == day {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+// ListOpenForDate returns the attendance records created on the given day without a check-out
+func (r *AttendanceRepository) ListOpenForDate(ctx context.Context, date time.Time) ([]models.Attendance, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	day := date.Format("2006-01-02")
+	var out []models.Attendance
+	for _, a := range r.store.attendance {
+		if a.CreatedDate.Format("2006-01-02") == day && a.CheckOutTime.IsZero() {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+// ListSince returns attendance records created on or after the given day, oldest first
+func (r *AttendanceRepository) ListSince(ctx context.Context, since time.Time) ([]models.Attendance, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	from := since.Format("2006-01-02")
+	var out []models.Attendance
+	for _, a := range r.store.attendance {
+		if a.CreatedDate.Format("2006-01-02") >= from {
+			out = append(out, a)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CheckInTime.Before(out[j].CheckInTime) })
+	return out, nil
+}
+
+// ListBetween returns attendance records created on or after from and before to, oldest first
+func (r *AttendanceRepository) ListBetween(ctx context.Context, from, to time.Time) ([]models.Attendance, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	start, end := from.Format("2006-01-02"), to.Format("2006-01-02")
+	var out []models.Attendance
+	for _, a := range r.store.attendance {
+		if day := a.CreatedDate.Format("2006-01-02"); day >= start && day < end {
+			out = append(out, a)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CheckInTime.Before(out[j].CheckInTime) })
+	return out, nil
+}
+
+// ListByEmployeeAndRange returns the employee's attendance records created on or after from
+// and before to, oldest first
+func (r *AttendanceRepository) ListByEmployeeAndRange(ctx context.Context, employeeID string, from, to time.Time) ([]models.Attendance, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	start, end := from.Format("2006-01-02"), to.Format("2006-01-02")
+	var out []models.Attendance
+	for _, a := range r.store.attendance {
+		if day := a.CreatedDate.Format("2006-01-02"); a.EmployeeID == employeeID && day >= start && day < end {
+			out = append(out, a)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CheckInTime.Before(out[j].CheckInTime) })
+	return out, nil
+}
+
+// PageByDate returns a page of the records created on the given day, and how many there are
+func (r *AttendanceRepository) PageByDate(ctx context.Context, date time.Time, page repository.Page) ([]models.Attendance, int, error) {
+	records, _ := r.ListByDate(ctx, date)
+	sort.SliceStable(records, func(i, j int) bool { return records[i].CheckInTime.Before(records[j].CheckInTime) })
+	return pageOf(records, page), len(records), nil
+}
+
+// PageByEmployeeAndRange returns a page of the employee's records created on or after from
+// and before to, and how many there are
+func (r *AttendanceRepository) PageByEmployeeAndRange(ctx context.Context, employeeID string, from, to time.Time, page repository.Page) ([]models.Attendance, int, error) {
+	records, _ := r.ListByEmployeeAndRange(ctx, employeeID, from, to)
+	return pageOf(records, page), len(records), nil
+}
+
+// ListHistory returns a page of the employee's records created on the days first to last,
+// newest first, and how many there are
+func (r *AttendanceRepository) ListHistory(ctx context.Context, employeeID string, first, last time.Time, page repository.Page) ([]models.Attendance, int, error) {
+	records, _ := r.ListByEmployeeAndRange(ctx, employeeID, first, last.AddDate(0, 0, 1))
+	slices.Reverse(records)
+	return pageOf(records, page), len(records), nil
+}
+
+// pageOf returns the items on the page, none past the last one
+func pageOf[T any](items []T, page repository.Page) []T {
+	start := min((page.Number-1)*page.Size, len(items))
+	return items[start:min(start+page.Size, len(items))]
+}
+
+// Create stores an attendance record unless its period is locked or the employee already
+// has one for the day
+func (r *AttendanceRepository) Create(ctx context.Context, attendance *models.Attendance) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.store.checkWritable(*attendance); err != nil {
+		return err
+	}
+	for _, a := range r.store.attendance {
+		if a.EmployeeID == attendance.EmployeeID && a.CreatedDate.Format("2006-01-02") == attendance.CreatedDate.In(a.CreatedDate.Location()).Format("2006-01-02") {
+			return repository.ErrAttendanceExists
+		}
+	}
+
+	attendance.ID = r.store.newID("att")
+	r.store.attendance = append(r.store.attendance, *attendance)
+	return nil
+}
+
+// GetTodayByEmployee returns the employee's first attendance record of the store clock's
+// day, or nil
+func (r *AttendanceRepository) GetTodayByEmployee(ctx context.Context, employeeID string) (*models.Attendance, error) {
+	return r.GetByEmployeeOn(ctx, employeeID, r.store.clock.Now())
+}
+
+// GetByEmployeeOn returns the employee's first attendance record created on the day, or nil
+func (r *AttendanceRepository) GetByEmployeeOn(ctx context.Context, employeeID string, day time.Time) (*models.Attendance, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, a := range r.store.attendance {
+		if a.EmployeeID == employeeID && a.CreatedDate.Format("2006-01-02") == day.In(a.CreatedDate.Location()).Format("2006-01-02") {
+			return &a, nil
+		}
+	}
+	return nil, nil
+}
+
+// Get returns one attendance record by ID
+func (r *AttendanceRepository) Get(ctx context.Context, id string) (*models.Attendance, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, a := range r.store.attendance {
+		if a.ID == id {
+			return &a, nil
+		}
+	}
+	return nil, fmt.Errorf("attendance %s: %w", id, repository.ErrNotFound)
+}
+
+// UpdateCheckOut writes the check-out time, source and review flag of a record unless
+// its period is locked
+func (r *AttendanceRepository) UpdateCheckOut(ctx context.Context, attendance *models.Attendance) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i := range r.store.attendance {
+		if r.store.attendance[i].ID == attendance.ID {
+			if err := r.store.checkWritable(r.store.attendance[i]); err != nil {
+				return err
+			}
+			r.store.attendance[i].CheckOutTime = attendance.CheckOutTime
+			r.store.attendance[i].CheckOutSource = attendance.CheckOutSource
+			r.store.attendance[i].NeedsReview = attendance.NeedsReview
+			return nil
+		}
+	}
+	return fmt.Errorf("attendance %s: %w", attendance.ID, repository.ErrNotFound)
+}
+
+// ListUnlinked returns the attendance records without an employee_id in creation order
+func (r *AttendanceRepository) ListUnlinked(ctx context.Context) ([]models.Attendance, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var out []models.Attendance
+	for _, a := range r.store.attendance {
+		if a.EmployeeID == "" {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+// Relink sets the employee_id of one attendance record
+func (r *AttendanceRepository) Relink(ctx context.Context, id, employeeID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i := range r.store.attendance {
+		if r.store.attendance[i].ID == id {
+			if err := r.store.checkWritable(r.store.attendance[i]); err != nil {
+				return err
+			}
+			r.store.attendance[i].EmployeeID = employeeID
+			return nil
+		}
+	}
+	return fmt.Errorf("attendance %s: %w", id, repository.ErrNotFound)
+}
+
+// Create stores a detection record
+func (r *DetectionRepository) Create(ctx context.Context, detection *models.EmployeeDetection) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	detection.ID = r.store.newID("det")
+	r.store.detections = append(r.store.detections, *detection)
+	return nil
+}
+
+// ListByEmployeeSince returns the employee's detections at or after since, oldest first
+func (r *DetectionRepository) ListByEmployeeSince(ctx context.Context, employeeID string, since time.Time) ([]models.EmployeeDetection, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var out []models.EmployeeDetection
+	for _, det := range r.store.detections {
+		if det.EmployeeID == employeeID && !det.DetectedAt.Before(since) {
+			out = append(out, det)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].DetectedAt.Before(out[j].DetectedAt) })
+	return out, nil
+}
+
+// GetLastForEmployeeOnDate returns the employee's last detection on the given day, or nil
+func (r *DetectionRepository) GetLastForEmployeeOnDate(ctx context.Context, employeeID string, date time.Time) (*models.EmployeeDetection, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	day := date.Format("2006-01-02")
+	var last *models.EmployeeDetection
+	for i, det := range r.store.detections {
+		if det.EmployeeID == employeeID && det.DetectedAt.Format("2006-01-02") == day && (last == nil || det.DetectedAt.After(last.DetectedAt)) {
+			last = &r.store.detections[i]
+		}
+	}
+	if last == nil {
+		return nil, nil
+	}
+	out := *last
+	return &out, nil
+}
+
+// ListUnlinked returns the detections without an employee_id, oldest first
+func (r *DetectionRepository) ListUnlinked(ctx context.Context) ([]models.EmployeeDetection, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var out []models.EmployeeDetection
+	for _, det := range r.store.detections {
+		if det.EmployeeID == "" {
+			out = append(out, det)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].DetectedAt.Before(out[j].DetectedAt) })
+	return out, nil
+}
+
+// Relink sets the employee_id of one detection
+func (r *DetectionRepository) Relink(ctx context.Context, id, employeeID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i := range r.store.detections {
+		if r.store.detections[i].ID == id {
+			if err := r.store.checkWritable(models.Attendance{CheckInTime: r.store.detections[i].DetectedAt}); err != nil {
+				return err
+			}
+			r.store.detections[i].EmployeeID = employeeID
+			return nil
+		}
+	}
+	return fmt.Errorf("detection %s: %w", id, repository.ErrNotFound)
+}
+
+// ListRecent returns every employee's detections at or after since, oldest first
+func (r *DetectionRepository) ListRecent(ctx context.Context, since time.Time) ([]models.EmployeeDetection, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var out []models.EmployeeDetection
+	for _, det := range r.store.detections {
+		if !det.DetectedAt.Before(since) {
+			out = append(out, det)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].DetectedAt.Before(out[j].DetectedAt) })
+	return out, nil
+}
+
+// UpdateActivity upserts the scanner's last seen time and reported health
+func (r *ScannerRepository) UpdateActivity(ctx context.Context, scannerMac string, health models.ScannerHealth) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	sc, err := r.lookup(scannerMac)
+	if err != nil {
+		return err
+	}
+	sc.LastSeen = r.store.clock.Now()
+	if health.FirmwareVersion != "" {
+		sc.Health.FirmwareVersion = health.FirmwareVersion
+	}
+	if health.UptimeSeconds != 0 {
+		sc.Health.UptimeSeconds = health.UptimeSeconds
+	}
+	if health.FreeHeap != 0 {
+		sc.Health.FreeHeap = health.FreeHeap
+	}
+	return nil
+}
+
+// Pair creates or updates the scanner record with its zone and pairing time
+func (r *ScannerRepository) Pair(ctx context.Context, scannerMac, zone string, at time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	sc, err := r.lookup(scannerMac)
+	if err != nil {
+		return err
+	}
+	sc.LastSeen, sc.Zone, sc.PairedAt = at, zone, at
+	return nil
+}
+
+// SetProfile creates or updates the scanner record with its configuration profile
+func (r *ScannerRepository) SetProfile(ctx context.Context, scannerMac, profile string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	sc, err := r.lookup(scannerMac)
+	if err != nil {
+		return err
+	}
+	sc.Profile = profile
+	return nil
+}
+
+// GetByToken returns the scanner holding the device token
+func (r *ScannerRepository) GetByToken(ctx context.Context, token string) (*models.Scanner, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	id, ok := r.store.tokens[repository.HashScannerToken(token)]
+	if !ok || token == "" {
+		return nil, repository.ErrScannerNotFound
+	}
+	for _, sc := range r.store.scanners {
+		if sc.ID == id {
+			found := *sc
+			return &found, nil
+		}
+	}
+	return nil, repository.ErrScannerNotFound
+}
+
+// GetByMac returns the scanner stored under any spelling of the ID
+func (r *ScannerRepository) GetByMac(ctx context.Context, scannerMac string) (*models.Scanner, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	mac, err := macaddr.NormalizeScannerID(scannerMac, true)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scanner_mac %q: %w", scannerMac, err)
+	}
+	for _, v := range macaddr.Spellings(mac) {
+		if sc, ok := r.store.scanners[v]; ok {
+			found := *sc
+			return &found, nil
+		}
+	}
+	return nil, repository.ErrScannerNotFound
+}
+
+// Rename creates or updates the scanner record with its friendly name
+func (r *ScannerRepository) Rename(ctx context.Context, scannerMac, name string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	sc, err := r.lookup(scannerMac)
+	if err != nil {
+		return err
+	}
+	sc.Name = name
+	return nil
+}
+
+// SetToken creates or updates the scanner record with its device token; an empty token
+// revokes it
+func (r *ScannerRepository) SetToken(ctx context.Context, scannerMac, token string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	sc, err := r.lookup(scannerMac)
+	if err != nil {
+		return err
+	}
+	for hash, id := range r.store.tokens {
+		if id == sc.ID {
+			delete(r.store.tokens, hash)
+		}
+	}
+	if token != "" {
+		r.store.tokens[repository.HashScannerToken(token)] = sc.ID
+	}
+	return nil
+}
+
+// SetMac rewrites the scanner_mac of the record with the given ID
+func (r *ScannerRepository) SetMac(ctx context.Context, id, scannerMac string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for key, sc := range r.store.scanners {
+		if sc.ID != id {
+			continue
+		}
+		if other, ok := r.store.scanners[scannerMac]; ok && other != sc {
+			return fmt.Errorf("scanner %s already exists", scannerMac)
+		}
+		delete(r.store.scanners, key)
+		sc.ScannerMac = scannerMac
+		r.store.scanners[scannerMac] = sc
+		return nil
+	}
+	return fmt.Errorf("scanner %s: %w", id, repository.ErrNotFound)
+}
+
+// lookup returns the record stored under any spelling of the scanner ID, rewriting it
+// to the canonical one, or creates it; callers hold the store lock
+func (r *ScannerRepository) lookup(scannerMac string) (*models.Scanner, error) {
+	mac, err := macaddr.NormalizeScannerID(scannerMac, true)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scanner_mac %q: %w", scannerMac, err)
+	}
+	for _, v := range macaddr.Spellings(mac) {
+		if sc, ok := r.store.scanners[v]; ok {
+			delete(r.store.scanners, v)
+			sc.ScannerMac = mac
+			r.store.scanners[mac] = sc
+			return sc, nil
+		}
+	}
+	sc := &models.Scanner{ID: r.store.newID("scn"), ScannerMac: mac}
+	r.store.scanners[mac] = sc
+	return sc, nil
+}
+
+// List returns every scanner record, most recently seen first
+func (r *ScannerRepository) List(ctx context.Context) ([]models.Scanner, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	out := make([]models.Scanner, 0, len(r.store.scanners))
+	for _, sc := range r.store.scanners {
+		out = append(out, *sc)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LastSeen.After(out[j].LastSeen) })
+	return out, nil
+}
+
+// Get returns the employee's baseline, or nil if none exists
+func (r *BaselineRepository) Get(ctx context.Context, employeeID string) (*models.CheckInBaseline, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	b, ok := r.store.baselines[employeeID]
+	if !ok {
+		return nil, nil
+	}
+	b.Samples = append([]models.BaselineSample(nil), b.Samples...)
+	return &b, nil
+}
+
+// Save creates or replaces the employee's baseline
+func (r *BaselineRepository) Save(ctx context.Context, baseline *models.CheckInBaseline) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if baseline.ID == "" {
+		baseline.ID = r.store.newID("bsl")
+	}
+	b := *baseline
+	b.Samples = append([]models.BaselineSample(nil), baseline.Samples...)
+	r.store.baselines[baseline.EmployeeID] = b
+	return nil
+}
+
+// EnsureSyntheticEmployee returns the synthetic employee with the MAC, creating it if needed
+func (r *SelfTestRepository) EnsureSyntheticEmployee(ctx context.Context, macAddress string) (*models.Employee, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, emp := range r.store.employees {
+		if strings.EqualFold(emp.MacAddress, macAddress) {
+			if !emp.IsSynthetic {
+				return nil, fmt.Errorf("self-test MAC %s belongs to employee %s", macAddress, emp.ID)
+			}
+			e := emp
+			return &e, nil
+		}
+	}
+	emp := models.Employee{
+		ID:            r.store.newID("emp"),
+		Name:          "Self-test (synthetic)",
+		EmployeeCode:  "SELFTEST",
+		MacAddress:    strings.ToLower(macAddress),
+		WorkStartTime: "23:59:59",
+		IsActive:      true,
+		IsSynthetic:   true,
+	}
+	r.store.employees = append(r.store.employees, emp)
+	return &emp, nil
+}
+
+// CountDetectionsSince counts the employee's detection records at or after since
+func (r *SelfTestRepository) CountDetectionsSince(ctx context.Context, employeeID string, since time.Time) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	n := 0
+	for _, d := range r.store.detections {
+		if d.EmployeeID == employeeID && !d.DetectedAt.Before(since) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// DeleteRecordsBefore deletes the employee's attendance and detection records from before the given time
+func (r *SelfTestRepository) DeleteRecordsBefore(ctx context.Context, employeeID string, before time.Time) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	deleted := 0
+	attendance := r.store.attendance[:0]
+	for _, a := range r.store.attendance {
+		if a.EmployeeID == employeeID && a.CheckInTime.Before(before) {
+			deleted++
+			continue
+		}
+		attendance = append(attendance, a)
+	}
+	r.store.attendance = attendance
+
+	detections := r.store.detections[:0]
+	for _, d := range r.store.detections {
+		if d.EmployeeID == employeeID && d.DetectedAt.Before(before) {
+			deleted++
+			continue
+		}
+		detections = append(detections, d)
+	}
+	r.store.detections = detections
+	return deleted, nil
+}
+
+// Ensure the in-memory repositories implement the interfaces
+var (
+	_ repository.EmployeeRepository          = (*EmployeeRepository)(nil)
+	_ repository.EmployeeDirectory           = (*EmployeeRepository)(nil)
+	_ repository.AttendanceRepository        = (*AttendanceRepository)(nil)
+	_ repository.AttendanceLog               = (*AttendanceRepository)(nil)
+	_ repository.AttendanceUpdater           = (*AttendanceRepository)(nil)
+	_ repository.EmployeeDetectionRepository = (*DetectionRepository)(nil)
+	_ repository.DetectionLog                = (*DetectionRepository)(nil)
+	_ repository.ScannerRepository           = (*ScannerRepository)(nil)
+	_ repository.ScannerRegistry             = (*ScannerRepository)(nil)
+	_ repository.BaselineRepository          = (*BaselineRepository)(nil)
+	_ repository.SelfTestRepository          = (*SelfTestRepository)(nil)
+	_ repository.AuditLog                    = (*AuditLogRepository)(nil)
+	_ repository.NotificationFailureLog      = (*NotificationFailureRepository)(nil)
+)
+
+// checkWritable refuses records of a locked period; callers hold mu
+func (s *Store) checkWritable(attendance models.Attendance) error {
+	day := attendance.CreatedDate
+	if day.IsZero() {
+		day = attendance.CheckInTime
+	}
+	period := day.Format(models.PeriodLayout)
+	if lock, ok := s.periods[period]; ok && lock.Locked && !day.IsZero() {
+		return fmt.Errorf("%w: %s", repository.ErrPeriodLocked, period)
+	}
+	return nil
+}
+
+// Get returns the period's record, or nil if it was never locked
+func (r *PeriodLockRepository) Get(ctx context.Context, period string) (*models.LockedPeriod, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	lock, ok := r.store.periods[period]
+	if !ok {
+		return nil, nil
+	}
+	lock.History = append([]models.PeriodLockEvent(nil), lock.History...)
+	return &lock, nil
+}
+
+// Save creates or replaces the period's record
+func (r *PeriodLockRepository) Save(ctx context.Context, lock *models.LockedPeriod) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if lock.ID == "" {
+		lock.ID = r.store.newID("lck")
+	}
+	l := *lock
+	l.History = append([]models.PeriodLockEvent(nil), lock.History...)
+	r.store.periods[lock.Period] = l
+	return nil
+}
+
+// ListLocked returns the currently locked periods, oldest first
+func (r *PeriodLockRepository) ListLocked(ctx context.Context) ([]models.LockedPeriod, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var out []models.LockedPeriod
+	for _, lock := range r.store.periods {
+		if lock.Locked {
+			out = append(out, lock)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Period < out[j].Period })
+	return out, nil
+}
+
+// Latest returns the lease term with the highest number, or nil if none was ever taken
+func (r *LeaseRepository) Latest(ctx context.Context, name string) (*models.Lease, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var latest *models.Lease
+	for i := range r.store.leases {
+		if l := r.store.leases[i]; l.Name == name && (latest == nil || l.Term > latest.Term) {
+			latest = &l
+		}
+	}
+	return latest, nil
+}
+
+// Create takes a new term unless it already exists, like the unique index in PocketBase
+func (r *LeaseRepository) Create(ctx context.Context, lease *models.Lease) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, l := range r.store.leases {
+		if l.Name == lease.Name && l.Term == lease.Term {
+			return repository.ErrLeaseTaken
+		}
+	}
+	lease.ID = r.store.newID("lease")
+	r.store.leases = append(r.store.leases, *lease)
+	return nil
+}
+
+// Renew moves the expiry of a lease term
+func (r *LeaseRepository) Renew(ctx context.Context, lease *models.Lease) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i := range r.store.leases {
+		if r.store.leases[i].ID == lease.ID {
+			r.store.leases[i].ExpiresAt = lease.ExpiresAt
+			return nil
+		}
+	}
+	return fmt.Errorf("lease %s: %w", lease.ID, repository.ErrNotFound)
+}
+
+// Record appends an entry to the audit log
+func (r *AuditLogRepository) Record(ctx context.Context, entry *models.AuditEntry) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	entry.ID = r.store.newID("aud")
+	r.store.audit = append(r.store.audit, *entry)
+	return nil
+}
+
+// Record appends an undelivered bot message
+func (r *NotificationFailureRepository) Record(ctx context.Context, failure *models.NotificationFailure) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	failure.ID = r.store.newID("ntf")
+	r.store.failures = append(r.store.failures, *failure)
+	return nil
+}
+
+// UpsertSighting records the sighting, adding its count to the device's and keeping its
+// strongest RSSI and review
+func (r *DeviceSightingRepository) UpsertSighting(ctx context.Context, sighting models.DeviceSighting) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	sighting.MacAddress = strings.ToLower(sighting.MacAddress)
+	if old, ok := r.store.sightings[sighting.MacAddress]; ok {
+		sighting.Count += old.Count
+		sighting.RSSI = max(sighting.RSSI, old.RSSI)
+		sighting.Reviewed, sighting.Whitelisted = old.Reviewed, old.Whitelisted
+	}
+	r.store.sightings[sighting.MacAddress] = sighting
+	return nil
+}
+
+// ListPending returns the unreviewed devices seen since the time, most often seen first
+func (r *DeviceSightingRepository) ListPending(ctx context.Context, since time.Time) ([]models.DeviceSighting, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var out []models.DeviceSighting
+	for _, s := range r.store.sightings {
+		if !s.Reviewed && !s.SeenAt.Before(since) {
+			out = append(out, s)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].MacAddress < out[j].MacAddress
+	})
+	return out, nil
+}
+
+// Review marks the device reviewed and whitelisted or not
+func (r *DeviceSightingRepository) Review(ctx context.Context, mac string, whitelisted bool) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	s, ok := r.store.sightings[strings.ToLower(mac)]
+	if !ok {
+		return repository.ErrDeviceNotFound
+	}
+	s.Reviewed, s.Whitelisted = true, whitelisted
+	r.store.sightings[s.MacAddress] = s
+	return nil
+}
+
+// Create stores a new late arrival request
+func (r *LateApprovalRepository) Create(ctx context.Context, approval *models.LateApproval) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	approval.ID = r.store.newID("late")
+	r.store.late = append(r.store.late, *approval)
+	return nil
+}
+
+// Get returns one request by ID, or nil if there is none
+func (r *LateApprovalRepository) Get(ctx context.Context, id string) (*models.LateApproval, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, a := range r.store.late {
+		if a.ID == id {
+			return &a, nil
+		}
+	}
+	return nil, nil
+}
+
+// Update writes the status and decision of a request
+func (r *LateApprovalRepository) Update(ctx context.Context, approval *models.LateApproval) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i := range r.store.late {
+		if r.store.late[i].ID == approval.ID {
+			a := &r.store.late[i]
+			a.Status, a.DecidedBy, a.DeciderName, a.DecidedAt = approval.Status, approval.DecidedBy, approval.DeciderName, approval.DecidedAt
+			return nil
+		}
+	}
+	return fmt.Errorf("late approval %s: %w", approval.ID, repository.ErrNotFound)
+}
+
+// ListByEmployeeDate returns the employee's requests for the day
+func (r *LateApprovalRepository) ListByEmployeeDate(ctx context.Context, employeeID, date string) ([]models.LateApproval, error) {
+	return r.list(func(a models.LateApproval) bool { return a.EmployeeID == employeeID && a.Date == date }), nil
+}
+
+// ListPending returns every request waiting for a decision, oldest day first
+func (r *LateApprovalRepository) ListPending(ctx context.Context) ([]models.LateApproval, error) {
+	return r.list(func(a models.LateApproval) bool { return a.Status == models.LateApprovalPending }), nil
+}
+
+func (r *LateApprovalRepository) list(match func(models.LateApproval) bool) []models.LateApproval {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var out []models.LateApproval
+	for _, a := range r.store.late {
+		if match(a) {
+			out = append(out, a)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Date < out[j].Date })
+	return out
+}
+
+// AddHoliday seeds a holiday on the day (YYYY-MM-DD)
+func (s *Store) AddHoliday(date, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.holidays = append(s.holidays, models.Holiday{ID: s.newID("hol"), Date: date, Name: name})
+}
+
+// GetByDate returns the holiday on the day, or nil if there is none
+func (r *HolidayRepository) GetByDate(ctx context.Context, date string) (*models.Holiday, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, h := range r.store.holidays {
+		if h.Date == date {
+			return &h, nil
+		}
+	}
+	return nil, nil
+}
+
+// AddDevice seeds an extra device of an employee
+func (s *Store) AddDevice(dev models.EmployeeDevice) models.EmployeeDevice {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if dev.ID == "" {
+		dev.ID = s.newID("dev")
+	}
+	s.devices = append(s.devices, dev)
+	return dev
+}
+
+// ListWhitelisted returns the MACs of the whitelisted devices, in order
+func (r *DeviceSightingRepository) ListWhitelisted(ctx context.Context) ([]string, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var macs []string
+	for mac, s := range r.store.sightings {
+		if s.Whitelisted {
+			macs = append(macs, mac)
+		}
+	}
+	sort.Strings(macs)
+	return macs, nil
+}
+
+// AssignDevice sets the employee's MAC when it is empty and adds an extra device otherwise
+func (r *DeviceRepository) AssignDevice(ctx context.Context, employee *models.Employee, mac string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	mac = strings.ToLower(mac)
+	if employee.MacAddress != "" {
+		r.store.devices = append(r.store.devices, models.EmployeeDevice{ID: r.store.newID("dev"), EmployeeID: employee.ID, MacAddress: mac})
+		return nil
+	}
+	for i := range r.store.employees {
+		if r.store.employees[i].ID == employee.ID {
+			r.store.employees[i].MacAddress = mac
+			return nil
+		}
+	}
+	return repository.ErrEmployeeNotFound
+}
+
+// GetEmployeeByDeviceMac returns the active employee owning the device (case-insensitive)
+func (r *DeviceRepository) GetEmployeeByDeviceMac(ctx context.Context, macAddress string) (*models.Employee, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, dev := range r.store.devices {
+		if !strings.EqualFold(dev.MacAddress, macAddress) {
+			continue
+		}
+		for _, emp := range r.store.employees {
+			if emp.ID == dev.EmployeeID && emp.IsActive {
+				e := emp
+				return &e, nil
+			}
+		}
+	}
+	return nil, repository.ErrEmployeeNotFound
+}
@@ -2,220 +2,800 @@
 package repository
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"net/url"
-	"os"
 	"strings"
+	"sync"
 	"time"
 
+	"med-pulse-bot/internal/beacon"
+	"med-pulse-bot/internal/localstore"
+	"med-pulse-bot/internal/logging"
+	"med-pulse-bot/internal/macaddr"
 	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/pbclient"
 )
 
+// transport is the HTTP transport shared by all PocketBase REST repositories
+var transport http.RoundTripper = http.DefaultTransport
+
+// SetTransport overrides the HTTP transport used by repositories created afterwards
+func SetTransport(rt http.RoundTripper) {
+	transport = rt
+}
+
+// location is the timezone whose calendar days created_date holds
+var location = time.Local
+
+// SetLocation sets the timezone of the calendar days written to and queried from
+// created_date, e.g. the configured TIMEZONE
+func SetLocation(loc *time.Location) {
+	location = loc
+}
+
+// Location is the timezone set with SetLocation, for other backends keeping calendar days
+func Location() *time.Location {
+	return location
+}
+
+// dayStart formats the start of t's calendar day in location for comparison with a
+// PocketBase date field
+func dayStart(t time.Time) string {
+	return t.In(location).Format("2006-01-02") + " 00:00:00"
+}
+
+// DayFilter matches a date field on t's calendar day in the configured timezone. PocketBase
+// stores date fields with a time ("2026-02-01 00:00:00.000Z"), so an equality filter on
+// the bare date never matches; a half-open range does.
+func DayFilter(field string, t time.Time) string {
+	return DaysFilter(field, t, t)
+}
+
+// DaysFilter matches a date field on the calendar days from first to last, both included
+func DaysFilter(field string, first, last time.Time) string {
+	next := last.In(location).AddDate(0, 0, 1)
+	return fmt.Sprintf("%s>='%s' && %s<'%s'", field, dayStart(first), field, dayStart(next))
+}
+
+// employeeRecord is an employees record as returned by the PocketBase API
+type employeeRecord struct {
+	ID             string `json:"id"`
+	MacAddress     string `json:"mac_address"`
+	IBeaconID      string `json:"ibeacon_id"`
+	EddystoneID    string `json:"eddystone_id"`
+	TelegramChatID int64  `json:"telegram_chat_id"`
+	Name           string `json:"name"`
+	EmployeeCode   string `json:"employee_code"`
+	WorkStartTime  string `json:"work_start_time"`
+	WorkEndTime    string `json:"work_end_time"`
+	GracePeriod    int    `json:"grace_period_minutes"`
+	IsActive       bool   `json:"is_active"`
+	Department     string `json:"department"`
+	DisplayName    string `json:"display_name"`
+	ShowOnBoard    *bool  `json:"show_on_board"` // absent before the public board migration
+	IsSynthetic    bool   `json:"is_synthetic"`
+	Language       string `json:"language"` // absent before the language migration
+
+	MutedNotifications      []string `json:"muted_notifications"`
+	NotifyCheckIn           *bool    `json:"notify_checkin"` // absent before the notification preference migration
+	NotifyQuietHours        string   `json:"notify_quiet_hours"`
+	PresenceTrackingConsent *bool    `json:"presence_tracking_consent"` // absent before the consent migration
+	LeaveUntil              string   `json:"leave_until"`
+	IsGuest                 bool     `json:"is_guest"`
+	GuestUntil              string   `json:"guest_until"`
+}
+
+func (rec employeeRecord) toModel() models.Employee {
+	return models.Employee{
+		ID:             rec.ID,
+		TelegramChatID: rec.TelegramChatID,
+		Name:           rec.Name,
+		EmployeeCode:   rec.EmployeeCode,
+		MacAddress:     rec.MacAddress,
+		IBeaconID:      rec.IBeaconID,
+		EddystoneID:    rec.EddystoneID,
+		WorkStartTime:  rec.WorkStartTime,
+		WorkEndTime:    rec.WorkEndTime,
+		GracePeriod:    rec.GracePeriod,
+		IsActive:       rec.IsActive,
+		Department:     rec.Department,
+		DisplayName:    rec.DisplayName,
+		ShowOnBoard:    rec.ShowOnBoard == nil || *rec.ShowOnBoard,
+		IsSynthetic:    rec.IsSynthetic,
+		PresenceOptOut: rec.PresenceTrackingConsent != nil && !*rec.PresenceTrackingConsent,
+		Language:       rec.Language,
+
+		MutedNotifications: rec.MutedNotifications,
+		CheckInOptOut:      rec.NotifyCheckIn != nil && !*rec.NotifyCheckIn,
+		QuietHours:         rec.NotifyQuietHours,
+		LeaveUntil:         parseRecordDay(rec.LeaveUntil),
+
+		IsGuest:    rec.IsGuest,
+		GuestUntil: parseRecordDay(rec.GuestUntil),
+	}
+}
+
+// parseRecordTime parses a PocketBase date field into the configured timezone, returning
+// the zero time if empty or invalid. Date-only values are taken as midnight there.
+func parseRecordTime(value string) time.Time {
+	for _, layout := range []string{"2006-01-02 15:04:05.000Z", time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.In(location)
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, location); err == nil {
+		return t
+	}
+	return time.Time{}
+}
+
+// parseRecordDay parses a PocketBase date field holding a calendar day, such as
+// created_date, into midnight of that day in the configured timezone. The day is written
+// as a bare date and read back as its UTC midnight ("2026-02-01 00:00:00.000Z"), so only
+// the date is kept: converting that instant would move every day west of UTC back one.
+func parseRecordDay(value string) time.Time {
+	if len(value) < len("2006-01-02") {
+		return time.Time{}
+	}
+	t, err := time.ParseInLocation("2006-01-02", value[:len("2006-01-02")], location)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
 // PocketBaseRESTEmployeeRepository implements EmployeeRepository
 type PocketBaseRESTEmployeeRepository struct {
-	baseURL    string
-	authToken  string
-	httpClient *http.Client
+	client *pbclient.Client
 }
 
 // NewPocketBaseRESTEmployeeRepository creates repository
 func NewPocketBaseRESTEmployeeRepository(baseURL string) *PocketBaseRESTEmployeeRepository {
-	return &PocketBaseRESTEmployeeRepository{
-		baseURL:    strings.TrimRight(baseURL, "/"),
-		authToken:  os.Getenv("POCKETBASE_TOKEN"),
-		httpClient: &http.Client{Timeout: 10 * time.Second},
+	return DefaultSite(baseURL).Employees()
+}
+
+func (r *PocketBaseRESTEmployeeRepository) GetByMacAddress(ctx context.Context, macAddress string) (*models.Employee, error) {
+	filter := fmt.Sprintf("mac_address=%s && is_active=true", pbclient.Quote(strings.ToLower(macAddress)))
+	logging.From(ctx).Debug("🔍 Looking up employee by MAC", "lookup_mac", macAddress)
+
+	var records []employeeRecord
+	if err := r.client.List(ctx, "employees", filter, "", 1, &records); err != nil {
+		logging.From(ctx).Error("❌ Error looking up employee", "lookup_mac", macAddress, "error", err)
+		return nil, fmt.Errorf("failed to get employee: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, ErrEmployeeNotFound
 	}
+
+	emp := records[0].toModel()
+	return &emp, nil
 }
 
-func (r *PocketBaseRESTEmployeeRepository) addAuthHeader(req *http.Request) {
-	if r.authToken != "" {
-		req.Header.Set("Authorization", r.authToken)
+// GetByBeacon looks the employee up by employees.ibeacon_id; without migration 023 nobody
+// carries an iBeacon
+func (r *PocketBaseRESTEmployeeRepository) GetByBeacon(ctx context.Context, uuid string, major, minor int) (*models.Employee, error) {
+	id, err := beacon.IBeaconID(uuid, major, minor)
+	if err != nil || (schema != nil && !schema.Has("employees", "ibeacon_id")) {
+		return nil, ErrEmployeeNotFound
 	}
+	logging.From(ctx).Debug("🔍 Looking up employee by iBeacon", "ibeacon", id)
+	return r.getBy(ctx, "ibeacon_id", id)
 }
 
-func (r *PocketBaseRESTEmployeeRepository) GetByMacAddress(ctx context.Context, macAddress string) (*models.Employee, error) {
-	filter := fmt.Sprintf("mac_address='%s' && is_active=true", strings.ToLower(macAddress))
-	encodedFilter := url.QueryEscape(filter)
-	apiURL := fmt.Sprintf("%s/api/collections/employees/records?filter=%s&limit=1", r.baseURL, encodedFilter)
+// GetByEddystone looks the employee up by employees.eddystone_id; without migration 024
+// nobody carries an Eddystone tag
+func (r *PocketBaseRESTEmployeeRepository) GetByEddystone(ctx context.Context, namespace, instance string) (*models.Employee, error) {
+	id, err := beacon.EddystoneID(namespace, instance)
+	if err != nil || (schema != nil && !schema.Has("employees", "eddystone_id")) {
+		return nil, ErrEmployeeNotFound
+	}
+	logging.From(ctx).Debug("🔍 Looking up employee by Eddystone", "eddystone", id)
+	return r.getBy(ctx, "eddystone_id", id)
+}
+
+// getBy returns the active employee whose field holds the beacon identity
+func (r *PocketBaseRESTEmployeeRepository) getBy(ctx context.Context, field, id string) (*models.Employee, error) {
+	var records []employeeRecord
+	if err := r.client.List(ctx, "employees", fmt.Sprintf("%s=%s && is_active=true", field, pbclient.Quote(id)), "", 1, &records); err != nil {
+		return nil, fmt.Errorf("failed to get employee by %s: %w", field, err)
+	}
+	if len(records) == 0 {
+		return nil, ErrEmployeeNotFound
+	}
+	emp := records[0].toModel()
+	return &emp, nil
+}
+
+// ListActive returns every active employee except the synthetic self-test employee
+func (r *PocketBaseRESTEmployeeRepository) ListActive(ctx context.Context) ([]models.Employee, error) {
+	var records []employeeRecord
+	if err := r.client.List(ctx, "employees", "is_active=true", "", 0, &records); err != nil {
+		return nil, fmt.Errorf("failed to list employees: %w", err)
+	}
+	var employees []models.Employee
+	for _, rec := range records {
+		if !rec.IsSynthetic {
+			employees = append(employees, rec.toModel())
+		}
+	}
+	return employees, nil
+}
 
-	log.Printf("🔍 Looking up employee by MAC: %s", macAddress)
-	log.Printf("🔍 API URL: %s", apiURL)
+// PageActive returns a page of the active employees by name, and how many there are
+func (r *PocketBaseRESTEmployeeRepository) PageActive(ctx context.Context, page Page) ([]models.Employee, int, error) {
+	return r.List(ctx, "", page)
+}
 
-	req, _ := http.NewRequest("GET", apiURL, nil)
-	r.addAuthHeader(req)
-	resp, err := r.httpClient.Do(req)
+// List returns a page of the active employees whose name or code contains query, by
+// name, and how many there are
+func (r *PocketBaseRESTEmployeeRepository) List(ctx context.Context, query string, page Page) ([]models.Employee, int, error) {
+	filter := "is_active=true"
+	if schema == nil || schema.Has("employees", "is_synthetic") {
+		filter += " && is_synthetic!=true"
+	}
+	if query = strings.TrimSpace(query); query != "" {
+		// ~ matches a substring, ignoring case
+		filter += fmt.Sprintf(" && (name~%s || employee_code~%s)", pbclient.Quote(query), pbclient.Quote(query))
+	}
+	var records []employeeRecord
+	total, err := r.client.ListPage(ctx, "employees", filter, "name", page.Number, page.Size, &records)
 	if err != nil {
-		log.Printf("❌ HTTP error looking up employee: %v", err)
-		return nil, err
+		return nil, 0, fmt.Errorf("failed to list employees: %w", err)
+	}
+	employees := make([]models.Employee, 0, len(records))
+	for _, rec := range records {
+		employees = append(employees, rec.toModel())
+	}
+	return employees, total, nil
+}
+
+// Update writes the employee's profile fields; other fields are left as they are
+func (r *PocketBaseRESTEmployeeRepository) Update(ctx context.Context, employee *models.Employee) error {
+	data := map[string]interface{}{
+		"name":            employee.Name,
+		"employee_code":   employee.EmployeeCode,
+		"department":      employee.Department,
+		"display_name":    employee.DisplayName,
+		"work_start_time": employee.WorkStartTime,
+		"work_end_time":   employee.WorkEndTime,
+	}
+	schema.filterOptional("employees", data)
+
+	if err := r.client.Update(ctx, "employees", employee.ID, data, nil); err != nil {
+		return fmt.Errorf("failed to update employee: %w", err)
 	}
-	defer resp.Body.Close()
+	return nil
+}
 
-	body, _ := io.ReadAll(resp.Body)
-	log.Printf("🔍 API Response Status: %d", resp.StatusCode)
-	log.Printf("🔍 API Response Body: %s", string(body))
+// UpdateNotificationPrefs writes notify_checkin and notify_quiet_hours; it fails without
+// migration 027, since the settings would otherwise be silently dropped
+func (r *PocketBaseRESTEmployeeRepository) UpdateNotificationPrefs(ctx context.Context, employeeID string, prefs models.NotificationPrefs) error {
+	if schema != nil && (!schema.Has("employees", "notify_checkin") || !schema.Has("employees", "notify_quiet_hours")) {
+		return fmt.Errorf("employees.notify_checkin is missing; run migration 027 before changing notification settings")
+	}
+	data := map[string]interface{}{
+		"notify_checkin":     prefs.CheckIn,
+		"notify_quiet_hours": prefs.QuietHours,
+	}
+	if err := r.client.Update(ctx, "employees", employeeID, data, nil); err != nil {
+		return fmt.Errorf("failed to update notification settings: %w", err)
+	}
+	return nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get employee: %s", resp.Status)
+// GetByCode looks the employee up by employee_code, active or not; an active employee wins
+// over inactive ones with the same code
+func (r *PocketBaseRESTEmployeeRepository) GetByCode(ctx context.Context, code string) (*models.Employee, error) {
+	var records []employeeRecord
+	if err := r.client.List(ctx, "employees", "employee_code="+pbclient.Quote(code), "-is_active", 1, &records); err != nil {
+		return nil, fmt.Errorf("failed to get employee by code: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, ErrEmployeeNotFound
 	}
+	emp := records[0].toModel()
+	return &emp, nil
+}
 
-	// Re-create reader for JSON decoding
-	resp.Body = io.NopCloser(strings.NewReader(string(body)))
+// SetActive sets the employee's is_active flag; inactive employees no longer check in
+func (r *PocketBaseRESTEmployeeRepository) SetActive(ctx context.Context, id string, active bool) error {
+	if err := r.client.Update(ctx, "employees", id, map[string]interface{}{"is_active": active}, nil); err != nil {
+		return fmt.Errorf("failed to set employee %s active=%v: %w", id, active, err)
+	}
+	return nil
+}
 
-	var result struct {
-		Items []struct {
-			ID             string `json:"id"`
-			MacAddress     string `json:"mac_address"`
-			TelegramChatID int64  `json:"telegram_chat_id"`
-			Name           string `json:"name"`
-			WorkStartTime  string `json:"work_start_time"`
-			IsActive       bool   `json:"is_active"`
-		} `json:"items"`
+// CreateGuest creates an active guest record for the tag; it fails without migration 018,
+// since the guest would otherwise be stored as an employee
+func (r *PocketBaseRESTEmployeeRepository) CreateGuest(ctx context.Context, guest *models.Employee) error {
+	if schema != nil && (!schema.Has("employees", "is_guest") || !schema.Has("employees", "guest_until")) {
+		return fmt.Errorf("employees.is_guest is missing; run migration 018 before registering guests")
 	}
+	data := map[string]interface{}{
+		"mac_address":      strings.ToLower(guest.MacAddress),
+		"telegram_chat_id": 0,
+		"name":             guest.Name,
+		"is_active":        true,
+		"is_guest":         true,
+		"guest_until":      guest.GuestUntil.Format("2006-01-02"),
+		"show_on_board":    false,
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+		"presence_tracking_consent": false,
 	}
+	schema.filterOptional("employees", data)
 
-	if len(result.Items) == 0 {
-		return nil, fmt.Errorf("employee not found")
+	var rec employeeRecord
+	if err := r.client.Create(ctx, "employees", data, &rec); err != nil {
+		return fmt.Errorf("failed to create guest: %w", err)
 	}
+	guest.ID = rec.ID
+	guest.IsActive, guest.IsGuest = true, true
+	return nil
+}
 
-	item := result.Items[0]
-	return &models.Employee{
-		ID:             item.ID,
-		TelegramChatID: item.TelegramChatID,
-		Name:           item.Name,
-		MacAddress:     item.MacAddress,
-		WorkStartTime:  item.WorkStartTime,
-		IsActive:       item.IsActive,
-	}, nil
+// GetByChatID returns the active employee registered from the Telegram chat
+func (r *PocketBaseRESTEmployeeRepository) GetByChatID(ctx context.Context, chatID int64) (*models.Employee, error) {
+	return r.first(ctx, fmt.Sprintf("telegram_chat_id=%d && is_active=true", chatID))
+}
+
+// GetOwnerByMac returns the employee whose mac_address is the MAC, active or not
+func (r *PocketBaseRESTEmployeeRepository) GetOwnerByMac(ctx context.Context, macAddress string) (*models.Employee, error) {
+	return r.first(ctx, "mac_address="+pbclient.Quote(strings.ToLower(macAddress)))
+}
+
+// first returns the first employee matching filter, or ErrEmployeeNotFound
+func (r *PocketBaseRESTEmployeeRepository) first(ctx context.Context, filter string) (*models.Employee, error) {
+	var records []employeeRecord
+	if err := r.client.List(ctx, "employees", filter, "", 1, &records); err != nil {
+		return nil, fmt.Errorf("failed to get employee: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, ErrEmployeeNotFound
+	}
+	emp := records[0].toModel()
+	return &emp, nil
 }
 
-func (r *PocketBaseRESTEmployeeRepository) IsCheckedInToday(ctx context.Context, employeeID string) (bool, error) {
-	today := time.Now().Format("2006-01-02")
-	filter := fmt.Sprintf("employee_id='%s' && created_date='%s'", employeeID, today)
-	encodedFilter := url.QueryEscape(filter)
-	apiURL := fmt.Sprintf("%s/api/collections/attendance/records?filter=%s&limit=1", r.baseURL, encodedFilter)
+// Register creates the employee a chat registered; an empty WorkStartTime leaves the
+// collection's default start time
+func (r *PocketBaseRESTEmployeeRepository) Register(ctx context.Context, employee *models.Employee) error {
+	data := map[string]interface{}{
+		"mac_address":      strings.ToLower(employee.MacAddress),
+		"telegram_chat_id": employee.TelegramChatID,
+		"name":             employee.Name,
+		"employee_code":    employee.EmployeeCode,
+		"department":       employee.Department,
+		"is_active":        employee.IsActive,
+		"show_on_board":    employee.ShowOnBoard,
+		"notify_checkin":   !employee.CheckInOptOut,
 
-	log.Printf("🔍 Checking attendance for employee ID %s on %s", employeeID, today)
-	log.Printf("🔍 Attendance API URL: %s", apiURL)
+		"presence_tracking_consent": !employee.PresenceOptOut,
+	}
+	if employee.WorkStartTime != "" {
+		data["work_start_time"] = employee.WorkStartTime
+	}
+	schema.filterOptional("employees", data)
 
-	req, _ := http.NewRequest("GET", apiURL, nil)
-	r.addAuthHeader(req)
-	resp, err := r.httpClient.Do(req)
+	var rec employeeRecord
+	err := r.client.Create(ctx, "employees", data, &rec)
+	if errors.Is(err, pbclient.ErrUniqueViolation) {
+		return ErrEmployeeExists
+	}
 	if err != nil {
-		log.Printf("❌ HTTP error checking attendance: %v", err)
-		return false, err
+		return fmt.Errorf("failed to register employee: %w", err)
+	}
+	employee.ID = rec.ID
+	return nil
+}
+
+// UpdateSettings writes the fields employees change themselves; other fields are left as
+// they are
+func (r *PocketBaseRESTEmployeeRepository) UpdateSettings(ctx context.Context, employee *models.Employee) error {
+	muted := employee.MutedNotifications
+	if muted == nil {
+		muted = []string{}
 	}
-	defer resp.Body.Close()
+	data := map[string]interface{}{
+		"mac_address":         strings.ToLower(employee.MacAddress),
+		"work_start_time":     employee.WorkStartTime,
+		"work_end_time":       employee.WorkEndTime,
+		"show_on_board":       employee.ShowOnBoard,
+		"muted_notifications": muted,
+		"language":            employee.Language,
 
-	body, _ := io.ReadAll(resp.Body)
-	log.Printf("🔍 Attendance Response Status: %d", resp.StatusCode)
-	log.Printf("🔍 Attendance Response Body: %s", string(body))
+		"presence_tracking_consent": !employee.PresenceOptOut,
+	}
+	schema.filterOptional("employees", data)
 
-	var result struct {
-		Items []interface{} `json:"items"`
+	err := r.client.Update(ctx, "employees", employee.ID, data, nil)
+	if errors.Is(err, pbclient.ErrUniqueViolation) {
+		return ErrEmployeeExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update employee settings: %w", err)
 	}
+	return nil
+}
+
+func (r *PocketBaseRESTEmployeeRepository) IsCheckedInOn(ctx context.Context, employeeID string, day time.Time) (bool, error) {
+	filter := fmt.Sprintf("employee_id=%s && %s", pbclient.Quote(employeeID), DayFilter("created_date", day))
+	logging.From(ctx).Debug("🔍 Checking attendance", "employee_id", employeeID, "day", day.In(location).Format("2006-01-02"))
 
-	if err := json.NewDecoder(strings.NewReader(string(body))).Decode(&result); err != nil {
-		log.Printf("❌ JSON decode error: %v", err)
-		return false, err
+	var records []struct {
+		ID string `json:"id"`
+	}
+	if err := r.client.List(ctx, "attendance", filter, "", 1, &records); err != nil {
+		logging.From(ctx).Error("❌ Error checking attendance", "employee_id", employeeID, "error", err)
+		return false, fmt.Errorf("failed to check attendance: %w", err)
 	}
 
-	isCheckedIn := len(result.Items) > 0
-	log.Printf("✅ Employee ID %s checked in today: %v", employeeID, isCheckedIn)
+	isCheckedIn := len(records) > 0
+	logging.From(ctx).Debug("Checked attendance", "employee_id", employeeID, "checked_in", isCheckedIn)
 	return isCheckedIn, nil
 }
 
+// attendanceRecord is an attendance record as returned by the PocketBase API
+type attendanceRecord struct {
+	ID             string `json:"id"`
+	EmployeeID     string `json:"employee_id"`
+	CheckInTime    string `json:"check_in_time"`
+	CheckOutTime   string `json:"check_out_time"`
+	CheckOutSource string `json:"check_out_source"`
+	NeedsReview    bool   `json:"needs_review"`
+	ScannerMac     string `json:"scanner_mac"`
+	Status         string `json:"status"`
+	CreatedDate    string `json:"created_date"`
+	Source         string `json:"source"`
+	EmployeeName   string `json:"employee_name"`
+	EmployeeCode   string `json:"employee_code"`
+	Department     string `json:"department"`
+}
+
+func (rec attendanceRecord) toModel() models.Attendance {
+	return models.Attendance{
+		ID:             rec.ID,
+		EmployeeID:     rec.EmployeeID,
+		CheckInTime:    parseRecordTime(rec.CheckInTime),
+		CheckOutTime:   parseRecordTime(rec.CheckOutTime),
+		CheckOutSource: rec.CheckOutSource,
+		NeedsReview:    rec.NeedsReview,
+		ScannerMac:     rec.ScannerMac,
+		Status:         rec.Status,
+		CreatedDate:    parseRecordDay(rec.CreatedDate),
+		Source:         rec.Source,
+		EmployeeName:   rec.EmployeeName,
+		EmployeeCode:   rec.EmployeeCode,
+		Department:     rec.Department,
+	}
+}
+
 // PocketBaseRESTAttendanceRepository implements AttendanceRepository
 type PocketBaseRESTAttendanceRepository struct {
-	baseURL    string
-	authToken  string
-	httpClient *http.Client
+	client *pbclient.Client
 }
 
 func NewPocketBaseRESTAttendanceRepository(baseURL string) *PocketBaseRESTAttendanceRepository {
-	return &PocketBaseRESTAttendanceRepository{
-		baseURL:    strings.TrimRight(baseURL, "/"),
-		authToken:  os.Getenv("POCKETBASE_TOKEN"),
-		httpClient: &http.Client{Timeout: 10 * time.Second},
-	}
+	return DefaultSite(baseURL).Attendance()
 }
 
-func (r *PocketBaseRESTAttendanceRepository) addAuthHeader(req *http.Request) {
-	if r.authToken != "" {
-		req.Header.Set("Authorization", r.authToken)
-	}
+// periodLocks reads the locked periods of the same site
+func (r *PocketBaseRESTAttendanceRepository) periodLocks() *PocketBaseRESTPeriodLockRepository {
+	return &PocketBaseRESTPeriodLockRepository{client: r.client}
 }
 
+// Create records a check-in; records in a locked period are refused with ErrPeriodLocked,
+// and a second record of the employee's day with ErrAttendanceExists. The day is checked
+// again right before the write, for other instances checking in the same employee, and the
+// unique index on employee_id and created_date catches the ones writing in between.
 func (r *PocketBaseRESTAttendanceRepository) Create(ctx context.Context, attendance *models.Attendance) error {
-	url := fmt.Sprintf("%s/api/collections/attendance/records", r.baseURL)
+	if err := r.periodLocks().CheckWritable(ctx, attendanceDay(attendance)); err != nil {
+		return err
+	}
+	existing, err := r.existingID(ctx, attendance)
+	if err != nil {
+		return err
+	}
+	if existing != "" {
+		if existing == attendance.ID {
+			return nil // stored by an earlier attempt whose response was lost
+		}
+		return ErrAttendanceExists
+	}
 
 	data := map[string]interface{}{
 		"employee_id":   attendance.EmployeeID,
 		"check_in_time": attendance.CheckInTime.Format(time.RFC3339),
 		"scanner_mac":   attendance.ScannerMac,
 		"status":        attendance.Status,
-		"created_date":  attendance.CreatedDate.Format("2006-01-02"),
+		"created_date":  attendance.CreatedDate.In(location).Format("2006-01-02"),
+		"source":        attendance.Source,
+		"employee_name": attendance.EmployeeName,
+		"employee_code": attendance.EmployeeCode,
+		"department":    attendance.Department,
+	}
+	if attendance.ID != "" {
+		data["id"] = attendance.ID // chosen up front so a retried create cannot store it twice
+	}
+	if !attendance.CheckOutTime.IsZero() {
+		data["check_out_time"] = attendance.CheckOutTime.Format(time.RFC3339)
+		data["check_out_source"] = attendance.CheckOutSource
+		data["needs_review"] = attendance.NeedsReview
+	}
+	schema.filterOptional("attendance", data)
+
+	var created attendanceRecord
+	if err := r.client.Create(ctx, "attendance", data, &created); isDayTaken(err) {
+		return ErrAttendanceExists
+	} else if err != nil {
+		return fmt.Errorf("failed to create attendance: %w", err)
+	}
+	attendance.ID = created.ID
+	return nil
+}
+
+// isDayTaken reports whether a create was refused by the unique index of the employee's day
+func isDayTaken(err error) bool {
+	var apiErr *pbclient.APIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, pbclient.ErrUniqueViolation) {
+		return false
+	}
+	for _, f := range apiErr.Fields {
+		if f.Code == "validation_not_unique" && (f.Field == "employee_id" || f.Field == "created_date") {
+			return true
+		}
+	}
+	return false
+}
+
+// existingID returns the ID of the employee's attendance record of the same day, if any
+func (r *PocketBaseRESTAttendanceRepository) existingID(ctx context.Context, attendance *models.Attendance) (string, error) {
+	filter := fmt.Sprintf("employee_id=%s && %s", pbclient.Quote(attendance.EmployeeID), DayFilter("created_date", attendance.CreatedDate))
+	var records []struct {
+		ID string `json:"id"`
+	}
+	if err := r.client.List(ctx, "attendance", filter, "", 1, &records); err != nil {
+		return "", fmt.Errorf("failed to check for an existing attendance record: %w", err)
+	}
+	if len(records) == 0 {
+		return "", nil
 	}
+	return records[0].ID, nil
+}
+
+// ListByDate returns all attendance records created on the given day
+func (r *PocketBaseRESTAttendanceRepository) ListByDate(ctx context.Context, date time.Time) ([]models.Attendance, error) {
+	return r.list(ctx, DayFilter("created_date", date))
+}
+
+// ListSince returns all attendance records created on or after the given day, oldest first
+func (r *PocketBaseRESTAttendanceRepository) ListSince(ctx context.Context, since time.Time) ([]models.Attendance, error) {
+	return r.list(ctx, fmt.Sprintf("created_date>='%s'", dayStart(since)))
+}
+
+// ListBetween returns the attendance records created on or after from and before to, oldest first
+func (r *PocketBaseRESTAttendanceRepository) ListBetween(ctx context.Context, from, to time.Time) ([]models.Attendance, error) {
+	return r.list(ctx, fmt.Sprintf("created_date>='%s' && created_date<'%s'", dayStart(from), dayStart(to)))
+}
+
+// ListOpenForDate returns the attendance records created on the given day without a check-out
+func (r *PocketBaseRESTAttendanceRepository) ListOpenForDate(ctx context.Context, date time.Time) ([]models.Attendance, error) {
+	return r.list(ctx, DayFilter("created_date", date)+" && check_out_time=''")
+}
+
+// ListByEmployeeAndRange returns the employee's attendance records created on or after from
+// and before to, oldest first
+func (r *PocketBaseRESTAttendanceRepository) ListByEmployeeAndRange(ctx context.Context, employeeID string, from, to time.Time) ([]models.Attendance, error) {
+	return r.list(ctx, fmt.Sprintf("employee_id=%s && created_date>='%s' && created_date<'%s'", pbclient.Quote(employeeID), dayStart(from), dayStart(to)))
+}
+
+// PageByDate returns a page of the records created on the given day, and how many there are
+func (r *PocketBaseRESTAttendanceRepository) PageByDate(ctx context.Context, date time.Time, page Page) ([]models.Attendance, int, error) {
+	return r.page(ctx, DayFilter("created_date", date), page)
+}
+
+// PageByEmployeeAndRange returns a page of the employee's records created on or after from
+// and before to, and how many there are
+func (r *PocketBaseRESTAttendanceRepository) PageByEmployeeAndRange(ctx context.Context, employeeID string, from, to time.Time, page Page) ([]models.Attendance, int, error) {
+	return r.page(ctx, fmt.Sprintf("employee_id=%s && created_date>='%s' && created_date<'%s'", pbclient.Quote(employeeID), dayStart(from), dayStart(to)), page)
+}
+
+// ListHistory returns a page of the employee's records created on the days first to last,
+// newest first
+func (r *PocketBaseRESTAttendanceRepository) ListHistory(ctx context.Context, employeeID string, first, last time.Time, page Page) ([]models.Attendance, int, error) {
+	filter := fmt.Sprintf("employee_id=%s && %s", pbclient.Quote(employeeID), DaysFilter("created_date", first, last))
+	return r.pageSorted(ctx, filter, "-created_date,-check_in_time", page)
+}
+
+func (r *PocketBaseRESTAttendanceRepository) page(ctx context.Context, filter string, page Page) ([]models.Attendance, int, error) {
+	return r.pageSorted(ctx, filter, "created_date,check_in_time", page)
+}
+
+func (r *PocketBaseRESTAttendanceRepository) pageSorted(ctx context.Context, filter, sort string, page Page) ([]models.Attendance, int, error) {
+	var records []attendanceRecord
+	total, err := r.client.ListPage(ctx, "attendance", filter, sort, page.Number, page.Size, &records)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list attendance: %w", err)
+	}
+	attendance := make([]models.Attendance, 0, len(records))
+	for _, rec := range records {
+		attendance = append(attendance, rec.toModel())
+	}
+	return attendance, total, nil
+}
 
-	jsonData, _ := json.Marshal(data)
-	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	req.Header.Set("Content-Type", "application/json")
-	r.addAuthHeader(req)
+func (r *PocketBaseRESTAttendanceRepository) list(ctx context.Context, filter string) ([]models.Attendance, error) {
+	var records []attendanceRecord
+	if err := r.client.List(ctx, "attendance", filter, "created_date,check_in_time", 0, &records); err != nil {
+		return nil, fmt.Errorf("failed to list attendance: %w", err)
+	}
+	var attendance []models.Attendance
+	for _, rec := range records {
+		attendance = append(attendance, rec.toModel())
+	}
+	return attendance, nil
+}
+
+// ListUnlinked returns the attendance records without an employee_id, oldest first
+func (r *PocketBaseRESTAttendanceRepository) ListUnlinked(ctx context.Context) ([]models.Attendance, error) {
+	return r.list(ctx, "employee_id=''")
+}
 
-	resp, err := r.httpClient.Do(req)
+// Relink sets the employee_id of one record; records in a locked period are refused with
+// ErrPeriodLocked
+func (r *PocketBaseRESTAttendanceRepository) Relink(ctx context.Context, id, employeeID string) error {
+	attendance, err := r.Get(ctx, id)
 	if err != nil {
+		return fmt.Errorf("failed to relink attendance %s: %w", id, err)
+	}
+	if err := r.periodLocks().CheckWritable(ctx, attendanceDay(attendance)); err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	if err := r.client.Update(ctx, "attendance", id, map[string]interface{}{"employee_id": employeeID}, nil); err != nil {
+		return fmt.Errorf("failed to relink attendance %s: %w", id, err)
+	}
+	return nil
+}
+
+// GetTodayByEmployee returns the employee's first attendance record of today, or nil
+func (r *PocketBaseRESTAttendanceRepository) GetTodayByEmployee(ctx context.Context, employeeID string) (*models.Attendance, error) {
+	return r.GetByEmployeeOn(ctx, employeeID, time.Now())
+}
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to create attendance: %s - %s", resp.Status, string(body))
+// GetByEmployeeOn returns the employee's first attendance record created on the day, or nil
+func (r *PocketBaseRESTAttendanceRepository) GetByEmployeeOn(ctx context.Context, employeeID string, day time.Time) (*models.Attendance, error) {
+	records, err := r.list(ctx, fmt.Sprintf("employee_id=%s && %s", pbclient.Quote(employeeID), DayFilter("created_date", day)))
+	if err != nil || len(records) == 0 {
+		return nil, err
 	}
+	return &records[0], nil
+}
 
-	var result struct {
-		ID string `json:"id"`
+// Get returns one attendance record by ID
+func (r *PocketBaseRESTAttendanceRepository) Get(ctx context.Context, id string) (*models.Attendance, error) {
+	var rec attendanceRecord
+	if err := r.client.GetOne(ctx, "attendance", id, &rec); err != nil {
+		return nil, fmt.Errorf("failed to get attendance: %w", err)
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	attendance := rec.toModel()
+	return &attendance, nil
+}
+
+// UpdateCheckOut writes the check-out time, source and review flag of a record. Records
+// in a locked period are refused with ErrPeriodLocked.
+func (r *PocketBaseRESTAttendanceRepository) UpdateCheckOut(ctx context.Context, attendance *models.Attendance) error {
+	if err := r.periodLocks().CheckWritable(ctx, attendanceDay(attendance)); err != nil {
 		return err
 	}
+	data := map[string]interface{}{
+		"check_out_time":   attendance.CheckOutTime.Format(time.RFC3339),
+		"check_out_source": attendance.CheckOutSource,
+		"needs_review":     attendance.NeedsReview,
+	}
+	schema.filterOptional("attendance", data)
 
-	attendance.ID = result.ID
+	if err := r.client.Update(ctx, "attendance", attendance.ID, data, nil); err != nil {
+		return fmt.Errorf("failed to update attendance: %w", err)
+	}
 	return nil
 }
 
 // PocketBaseRESTDetectionRepository implements EmployeeDetectionRepository
 type PocketBaseRESTDetectionRepository struct {
-	baseURL    string
-	authToken  string
-	httpClient *http.Client
+	client *pbclient.Client
 }
 
 func NewPocketBaseRESTDetectionRepository(baseURL string) *PocketBaseRESTDetectionRepository {
-	return &PocketBaseRESTDetectionRepository{
-		baseURL:    strings.TrimRight(baseURL, "/"),
-		authToken:  os.Getenv("POCKETBASE_TOKEN"),
-		httpClient: &http.Client{Timeout: 10 * time.Second},
+	return DefaultSite(baseURL).Detections()
+}
+
+// detectionRecord is an employee_detections record as returned by the PocketBase API
+type detectionRecord struct {
+	ID         string `json:"id"`
+	EmployeeID string `json:"employee_id"`
+	MacAddress string `json:"mac_address"`
+	ScannerMac string `json:"scanner_mac"`
+	RSSI       int    `json:"rssi"`
+	DeviceType string `json:"device_type"`
+	DetectedAt string `json:"detected_at"`
+}
+
+func (rec detectionRecord) toModel() models.EmployeeDetection {
+	return models.EmployeeDetection{
+		ID:         rec.ID,
+		EmployeeID: rec.EmployeeID,
+		MacAddress: rec.MacAddress,
+		ScannerMac: rec.ScannerMac,
+		RSSI:       rec.RSSI,
+		DeviceType: rec.DeviceType,
+		DetectedAt: parseRecordTime(rec.DetectedAt),
+	}
+}
+
+// ListByEmployeeSince returns the employee's detections at or after since, oldest first
+func (r *PocketBaseRESTDetectionRepository) ListByEmployeeSince(ctx context.Context, employeeID string, since time.Time) ([]models.EmployeeDetection, error) {
+	return r.list(ctx, fmt.Sprintf("employee_id=%s && detected_at>='%s'", pbclient.Quote(employeeID), recordFilterTime(since)))
+}
+
+// ListRecent returns every employee's detections at or after since, oldest first
+func (r *PocketBaseRESTDetectionRepository) ListRecent(ctx context.Context, since time.Time) ([]models.EmployeeDetection, error) {
+	return r.list(ctx, fmt.Sprintf("detected_at>='%s'", recordFilterTime(since)))
+}
+
+// GetLastForEmployeeOnDate returns the employee's last detection on the given day, or nil
+func (r *PocketBaseRESTDetectionRepository) GetLastForEmployeeOnDate(ctx context.Context, employeeID string, date time.Time) (*models.EmployeeDetection, error) {
+	filter := fmt.Sprintf("employee_id=%s && %s", pbclient.Quote(employeeID), DayFilter("detected_at", date))
+	var records []detectionRecord
+	if err := r.client.List(ctx, "employee_detections", filter, "-detected_at", 1, &records); err != nil {
+		return nil, fmt.Errorf("failed to get last detection: %w", err)
 	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	detection := records[0].toModel()
+	return &detection, nil
+}
+
+// ListUnlinked returns the detections without an employee_id, oldest first
+func (r *PocketBaseRESTDetectionRepository) ListUnlinked(ctx context.Context) ([]models.EmployeeDetection, error) {
+	return r.list(ctx, "employee_id=''")
 }
 
-func (r *PocketBaseRESTDetectionRepository) addAuthHeader(req *http.Request) {
-	if r.authToken != "" {
-		req.Header.Set("Authorization", r.authToken)
+// Relink sets the employee_id of one record; detections in a locked period are refused
+// with ErrPeriodLocked
+func (r *PocketBaseRESTDetectionRepository) Relink(ctx context.Context, id, employeeID string) error {
+	var rec detectionRecord
+	if err := r.client.GetOne(ctx, "employee_detections", id, &rec); err != nil {
+		return fmt.Errorf("failed to relink detection %s: %w", id, err)
+	}
+	locks := &PocketBaseRESTPeriodLockRepository{client: r.client}
+	if err := locks.CheckWritable(ctx, rec.toModel().DetectedAt); err != nil {
+		return err
+	}
+	if err := r.client.Update(ctx, "employee_detections", id, map[string]interface{}{"employee_id": employeeID}, nil); err != nil {
+		return fmt.Errorf("failed to relink detection %s: %w", id, err)
 	}
+	return nil
 }
 
-func (r *PocketBaseRESTDetectionRepository) Create(ctx context.Context, detection *models.EmployeeDetection) error {
-	url := fmt.Sprintf("%s/api/collections/employee_detections/records", r.baseURL)
+// list returns the detections matching filter, oldest first
+func (r *PocketBaseRESTDetectionRepository) list(ctx context.Context, filter string) ([]models.EmployeeDetection, error) {
+	var records []detectionRecord
+	if err := r.client.List(ctx, "employee_detections", filter, "detected_at", 0, &records); err != nil {
+		return nil, fmt.Errorf("failed to list detections: %w", err)
+	}
+	var detections []models.EmployeeDetection
+	for _, rec := range records {
+		detections = append(detections, rec.toModel())
+	}
+	return detections, nil
+}
 
+func (r *PocketBaseRESTDetectionRepository) Create(ctx context.Context, detection *models.EmployeeDetection) error {
 	data := map[string]interface{}{
 		"employee_id":      detection.EmployeeID,
 		"mac_address":      strings.ToLower(detection.MacAddress),
@@ -227,100 +807,1230 @@ func (r *PocketBaseRESTDetectionRepository) Create(ctx context.Context, detectio
 		"device_name":      detection.DeviceName,
 		"detected_at":      detection.DetectedAt.Format(time.RFC3339),
 	}
-
-	jsonData, _ := json.Marshal(data)
-	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	req.Header.Set("Content-Type", "application/json")
-	r.addAuthHeader(req)
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return err
+	if detection.IBeaconUUID != "" {
+		data["ibeacon_uuid"] = detection.IBeaconUUID
+		data["major"] = detection.Major
+		data["minor"] = detection.Minor
+	}
+	if detection.NamespaceID != "" {
+		data["namespace_id"] = detection.NamespaceID
+		data["instance_id"] = detection.InstanceID
+	}
+	if detection.ID != "" {
+		data["id"] = detection.ID
 	}
-	defer resp.Body.Close()
+	schema.filterOptional("employee_detections", data)
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to create detection: %s - %s", resp.Status, string(body))
+	if err := r.client.Create(ctx, "employee_detections", data, nil); err != nil {
+		return fmt.Errorf("failed to create detection: %w", err)
 	}
 
-	log.Printf("💾 Saved detection for employee ID %s: MAC=%s, RSSI=%d, Type=%s",
-		detection.EmployeeID, detection.MacAddress, detection.RSSI, detection.DeviceType)
+	logging.From(ctx).Debug("💾 Saved detection record", "employee_id", detection.EmployeeID, "rssi", detection.RSSI)
 
 	return nil
 }
 
 // PocketBaseRESTScannerRepository implements ScannerRepository
 type PocketBaseRESTScannerRepository struct {
-	baseURL    string
-	authToken  string
-	httpClient *http.Client
+	client *pbclient.Client
 }
 
 func NewPocketBaseRESTScannerRepository(baseURL string) *PocketBaseRESTScannerRepository {
-	return &PocketBaseRESTScannerRepository{
-		baseURL:    strings.TrimRight(baseURL, "/"),
-		authToken:  os.Getenv("POCKETBASE_TOKEN"),
-		httpClient: &http.Client{Timeout: 10 * time.Second},
-	}
+	return DefaultSite(baseURL).Scanners()
 }
 
-func (r *PocketBaseRESTScannerRepository) addAuthHeader(req *http.Request) {
-	if r.authToken != "" {
-		req.Header.Set("Authorization", r.authToken)
+// UpdateActivity upserts the scanner's last seen time and reported health. A record stored
+// under another spelling of the ID is rewritten to the canonical one.
+func (r *PocketBaseRESTScannerRepository) UpdateActivity(ctx context.Context, scannerMac string, health models.ScannerHealth) error {
+	mac, err := macaddr.NormalizeScannerID(scannerMac, true)
+	if err != nil {
+		return fmt.Errorf("invalid scanner_mac %q: %w", scannerMac, err)
+	}
+	existing, err := r.find(ctx, mac)
+	if err != nil {
+		return fmt.Errorf("failed to look up scanner: %w", err)
+	}
+
+	data := map[string]interface{}{
+		"last_seen": time.Now().Format(time.RFC3339),
+	}
+	if health.FirmwareVersion != "" {
+		data["firmware_version"] = health.FirmwareVersion
+	}
+	if health.UptimeSeconds != 0 {
+		data["uptime_seconds"] = health.UptimeSeconds
+	}
+	if health.FreeHeap != 0 {
+		data["free_heap"] = health.FreeHeap
+	}
+	schema.filterOptional("scanners", data)
+	data["scanner_mac"] = mac
+
+	id := ""
+	if existing != nil {
+		id = existing.ID
 	}
+	if err := r.save(ctx, id, data); err != nil {
+		return fmt.Errorf("failed to update scanner: %w", err)
+	}
+	return nil
 }
 
-func (r *PocketBaseRESTScannerRepository) UpdateActivity(ctx context.Context, scannerMac string) error {
-	filter := fmt.Sprintf("scanner_mac='%s'", scannerMac)
-	findURL := fmt.Sprintf("%s/api/collections/scanners/records?filter=%s&limit=1", r.baseURL, filter)
+// find returns the most recently seen record stored under any spelling of the
+// canonical scanner ID, or nil
+func (r *PocketBaseRESTScannerRepository) find(ctx context.Context, mac string) (*scannerRecord, error) {
+	var clauses []string
+	for _, v := range macaddr.Spellings(mac) {
+		clauses = append(clauses, fmt.Sprintf("scanner_mac='%s'", v))
+	}
 
-	req, _ := http.NewRequest("GET", findURL, nil)
-	r.addAuthHeader(req)
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return err
+	var records []scannerRecord
+	if err := r.client.List(ctx, "scanners", strings.Join(clauses, " || "), "-last_seen", 1, &records); err != nil {
+		return nil, err
 	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	return &records[0], nil
+}
 
-	var findResult struct {
-		Items []struct {
-			ID string `json:"id"`
-		} `json:"items"`
+// save creates a scanner record, or updates the one with the given ID
+func (r *PocketBaseRESTScannerRepository) save(ctx context.Context, id string, data map[string]interface{}) error {
+	if id == "" {
+		return r.client.Create(ctx, "scanners", data, nil)
 	}
+	return r.client.Update(ctx, "scanners", id, data, nil)
+}
 
-	json.NewDecoder(resp.Body).Decode(&findResult)
-	resp.Body.Close()
+// scannerRecord is a scanners record as returned by the PocketBase API
+type scannerRecord struct {
+	ID         string `json:"id"`
+	ScannerMac string `json:"scanner_mac"`
+	LastSeen   string `json:"last_seen"`
+	Name       string `json:"name"`
+	Location   string `json:"location"`
+	Zone       string `json:"zone"`
+	PairedAt   string `json:"paired_at"`
+	Profile    string `json:"profile"`
 
-	data := map[string]interface{}{
-		"scanner_mac": scannerMac,
-		"last_seen":   time.Now().Format(time.RFC3339),
+	Overrides *models.ScannerSettings `json:"overrides"` // null until overrides are set
+
+	FirmwareVersion string `json:"firmware_version"`
+	UptimeSeconds   int64  `json:"uptime_seconds"`
+	FreeHeap        int64  `json:"free_heap"`
+}
+
+func (rec scannerRecord) toModel() models.Scanner {
+	sc := models.Scanner{
+		ID:         rec.ID,
+		ScannerMac: rec.ScannerMac,
+		LastSeen:   parseRecordTime(rec.LastSeen),
+		Name:       rec.Name,
+		Location:   rec.Location,
+		Zone:       rec.Zone,
+		PairedAt:   parseRecordTime(rec.PairedAt),
+		Profile:    rec.Profile,
+		Health: models.ScannerHealth{
+			FirmwareVersion: rec.FirmwareVersion,
+			UptimeSeconds:   rec.UptimeSeconds,
+			FreeHeap:        rec.FreeHeap,
+		},
+	}
+	if rec.Overrides != nil {
+		sc.Overrides = *rec.Overrides
 	}
+	return sc
+}
 
-	jsonData, _ := json.Marshal(data)
+// Pair creates or updates the scanner record with its zone and pairing time. A record
+// pre-provisioned under another spelling of the ID is updated rather than duplicated.
+func (r *PocketBaseRESTScannerRepository) Pair(ctx context.Context, scannerMac, zone string, at time.Time) error {
+	mac, err := macaddr.NormalizeScannerID(scannerMac, true)
+	if err != nil {
+		return fmt.Errorf("invalid scanner_mac %q: %w", scannerMac, err)
+	}
+	existing, err := r.find(ctx, mac)
+	if err != nil {
+		return fmt.Errorf("failed to look up scanner: %w", err)
+	}
 
-	if len(findResult.Items) > 0 {
-		updateURL := fmt.Sprintf("%s/api/collections/scanners/records/%s", r.baseURL, findResult.Items[0].ID)
-		req, _ := http.NewRequest("PATCH", updateURL, bytes.NewBuffer(jsonData))
-		req.Header.Set("Content-Type", "application/json")
-		r.addAuthHeader(req)
-		resp, err = r.httpClient.Do(req)
-	} else {
-		createURL := fmt.Sprintf("%s/api/collections/scanners/records", r.baseURL)
-		req, _ := http.NewRequest("POST", createURL, bytes.NewBuffer(jsonData))
-		req.Header.Set("Content-Type", "application/json")
-		r.addAuthHeader(req)
-		resp, err = r.httpClient.Do(req)
+	data := map[string]interface{}{
+		"last_seen": at.Format(time.RFC3339),
+		"zone":      zone,
+		"paired_at": at.Format(time.RFC3339),
+	}
+	schema.filterOptional("scanners", data)
+	data["scanner_mac"] = mac
+
+	id := ""
+	if existing != nil {
+		id = existing.ID
+	}
+	if err := r.save(ctx, id, data); err != nil {
+		return fmt.Errorf("failed to pair scanner: %w", err)
 	}
+	return nil
+}
 
+// SetProfile creates or updates the scanner record with its configuration profile
+func (r *PocketBaseRESTScannerRepository) SetProfile(ctx context.Context, scannerMac, profile string) error {
+	mac, err := macaddr.NormalizeScannerID(scannerMac, true)
 	if err != nil {
-		return err
+		return fmt.Errorf("invalid scanner_mac %q: %w", scannerMac, err)
+	}
+	// Without the field the profile would be silently dropped
+	if schema != nil && !schema.Has("scanners", "profile") {
+		return fmt.Errorf("scanners.profile is missing; run migration 017 before assigning scanner profiles")
 	}
-	defer resp.Body.Close()
+	existing, err := r.find(ctx, mac)
+	if err != nil {
+		return fmt.Errorf("failed to look up scanner: %w", err)
+	}
+
+	data := map[string]interface{}{"scanner_mac": mac, "profile": profile}
+	id := ""
+	if existing != nil {
+		id = existing.ID
+	}
+	if err := r.save(ctx, id, data); err != nil {
+		return fmt.Errorf("failed to set scanner profile: %w", err)
+	}
+	return nil
+}
+
+// HashScannerToken is what the scanners' token field holds for a device token, so a
+// leaked database backup does not leak working tokens
+func HashScannerToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to update scanner: %s - %s", resp.Status, string(body))
+// GetByToken returns the scanner whose token field holds the token's hash
+func (r *PocketBaseRESTScannerRepository) GetByToken(ctx context.Context, token string) (*models.Scanner, error) {
+	if token == "" {
+		return nil, ErrScannerNotFound
+	}
+	var records []scannerRecord
+	filter := fmt.Sprintf("token='%s'", HashScannerToken(token))
+	if err := r.client.List(ctx, "scanners", filter, "", 1, &records); err != nil {
+		return nil, fmt.Errorf("failed to look up scanner token: %w", err)
 	}
+	if len(records) == 0 {
+		return nil, ErrScannerNotFound
+	}
+	sc := records[0].toModel()
+	return &sc, nil
+}
 
+// GetByMac returns the most recently seen scanner stored under any spelling of the ID
+func (r *PocketBaseRESTScannerRepository) GetByMac(ctx context.Context, scannerMac string) (*models.Scanner, error) {
+	mac, err := macaddr.NormalizeScannerID(scannerMac, true)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scanner_mac %q: %w", scannerMac, err)
+	}
+	rec, err := r.find(ctx, mac)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up scanner: %w", err)
+	}
+	if rec == nil {
+		return nil, ErrScannerNotFound
+	}
+	sc := rec.toModel()
+	return &sc, nil
+}
+
+// Rename creates or updates the scanner record with its friendly name
+func (r *PocketBaseRESTScannerRepository) Rename(ctx context.Context, scannerMac, name string) error {
+	mac, err := macaddr.NormalizeScannerID(scannerMac, true)
+	if err != nil {
+		return fmt.Errorf("invalid scanner_mac %q: %w", scannerMac, err)
+	}
+	// Without the field the name would be silently dropped
+	if schema != nil && !schema.Has("scanners", "name") {
+		return fmt.Errorf("scanners.name is missing; run migration 029 before naming scanners")
+	}
+	existing, err := r.find(ctx, mac)
+	if err != nil {
+		return fmt.Errorf("failed to look up scanner: %w", err)
+	}
+
+	data := map[string]interface{}{"scanner_mac": mac, "name": name}
+	id := ""
+	if existing != nil {
+		id = existing.ID
+	}
+	if err := r.save(ctx, id, data); err != nil {
+		return fmt.Errorf("failed to rename scanner: %w", err)
+	}
+	return nil
+}
+
+// SetToken creates or updates the scanner record with the hash of its device token
+func (r *PocketBaseRESTScannerRepository) SetToken(ctx context.Context, scannerMac, token string) error {
+	mac, err := macaddr.NormalizeScannerID(scannerMac, true)
+	if err != nil {
+		return fmt.Errorf("invalid scanner_mac %q: %w", scannerMac, err)
+	}
+	// Without the field the token would be silently dropped and never authenticate
+	if schema != nil && !schema.Has("scanners", "token") {
+		return fmt.Errorf("scanners.token is missing; run migration 019 before issuing scanner tokens")
+	}
+	existing, err := r.find(ctx, mac)
+	if err != nil {
+		return fmt.Errorf("failed to look up scanner: %w", err)
+	}
+
+	data := map[string]interface{}{"scanner_mac": mac, "token": ""}
+	if token != "" {
+		data["token"] = HashScannerToken(token)
+	}
+	id := ""
+	if existing != nil {
+		id = existing.ID
+	}
+	if err := r.save(ctx, id, data); err != nil {
+		return fmt.Errorf("failed to set scanner token: %w", err)
+	}
+	return nil
+}
+
+// SetMac rewrites the scanner_mac of the record with the given ID
+func (r *PocketBaseRESTScannerRepository) SetMac(ctx context.Context, id, scannerMac string) error {
+	if err := r.save(ctx, id, map[string]interface{}{"scanner_mac": scannerMac}); err != nil {
+		return fmt.Errorf("failed to update scanner %s: %w", id, err)
+	}
+	return nil
+}
+
+// List returns every scanner record
+func (r *PocketBaseRESTScannerRepository) List(ctx context.Context) ([]models.Scanner, error) {
+	var records []scannerRecord
+	if err := r.client.List(ctx, "scanners", "", "-last_seen", 0, &records); err != nil {
+		return nil, fmt.Errorf("failed to list scanners: %w", err)
+	}
+	var scanners []models.Scanner
+	for _, rec := range records {
+		scanners = append(scanners, rec.toModel())
+	}
+	return scanners, nil
+}
+
+// PocketBaseRESTBaselineRepository implements BaselineRepository
+type PocketBaseRESTBaselineRepository struct {
+	client *pbclient.Client
+}
+
+func NewPocketBaseRESTBaselineRepository(baseURL string) *PocketBaseRESTBaselineRepository {
+	return DefaultSite(baseURL).Baselines()
+}
+
+// baselineRecord is a checkin_baselines record as stored in PocketBase
+type baselineRecord struct {
+	ID            string                  `json:"id,omitempty"`
+	EmployeeID    string                  `json:"employee_id"`
+	Samples       []models.BaselineSample `json:"samples"`
+	MedianMinutes float64                 `json:"median_minutes"`
+	MADMinutes    float64                 `json:"mad_minutes"`
+}
+
+func (r *PocketBaseRESTBaselineRepository) Get(ctx context.Context, employeeID string) (*models.CheckInBaseline, error) {
+	var records []baselineRecord
+	if err := r.client.List(ctx, "checkin_baselines", "employee_id="+pbclient.Quote(employeeID), "", 1, &records); err != nil {
+		return nil, fmt.Errorf("failed to get baseline: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	rec := records[0]
+	return &models.CheckInBaseline{
+		ID:         rec.ID,
+		EmployeeID: rec.EmployeeID,
+		Samples:    rec.Samples,
+		Median:     rec.MedianMinutes,
+		MAD:        rec.MADMinutes,
+	}, nil
+}
+
+func (r *PocketBaseRESTBaselineRepository) Save(ctx context.Context, baseline *models.CheckInBaseline) error {
+	data := baselineRecord{
+		EmployeeID:    baseline.EmployeeID,
+		Samples:       baseline.Samples,
+		MedianMinutes: baseline.Median,
+		MADMinutes:    baseline.MAD,
+	}
+
+	var saved baselineRecord
+	var err error
+	if baseline.ID == "" {
+		err = r.client.Create(ctx, "checkin_baselines", data, &saved)
+	} else {
+		err = r.client.Update(ctx, "checkin_baselines", baseline.ID, data, &saved)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save baseline: %w", err)
+	}
+	baseline.ID = saved.ID
+	return nil
+}
+
+// PocketBaseRESTSelfTestRepository implements SelfTestRepository
+type PocketBaseRESTSelfTestRepository struct {
+	client *pbclient.Client
+}
+
+// recordFilterTime formats a time for comparison with PocketBase date fields
+func recordFilterTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05.000Z")
+}
+
+// EnsureSyntheticEmployee returns the synthetic employee with the MAC, creating it if needed
+func (r *PocketBaseRESTSelfTestRepository) EnsureSyntheticEmployee(ctx context.Context, macAddress string) (*models.Employee, error) {
+	mac := strings.ToLower(macAddress)
+	var found []employeeRecord
+	if err := r.client.List(ctx, "employees", "mac_address="+pbclient.Quote(mac), "", 0, &found); err != nil {
+		return nil, fmt.Errorf("failed to look up self-test employee: %w", err)
+	}
+	if len(found) > 0 {
+		if !found[0].IsSynthetic {
+			return nil, fmt.Errorf("self-test MAC %s belongs to employee %s", mac, found[0].ID)
+		}
+		emp := found[0].toModel()
+		return &emp, nil
+	}
+
+	// Without the flag the synthetic employee would show up as a real one
+	if schema != nil && !schema.Has("employees", "is_synthetic") {
+		return nil, fmt.Errorf("employees.is_synthetic is missing; run migration 007 before enabling the self-test")
+	}
+	data := map[string]interface{}{
+		"mac_address":      mac,
+		"telegram_chat_id": 0,
+		"name":             "Self-test (synthetic)",
+		"employee_code":    "SELFTEST",
+		"work_start_time":  "23:59:59",
+		"is_active":        true,
+		"is_synthetic":     true,
+		"show_on_board":    false,
+	}
+	schema.filterOptional("employees", data)
+
+	var rec employeeRecord
+	if err := r.client.Create(ctx, "employees", data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to create self-test employee: %w", err)
+	}
+	log.Printf("🧪 Created synthetic self-test employee %s (MAC %s)", rec.ID, mac)
+	emp := rec.toModel()
+	emp.IsSynthetic = true
+	return &emp, nil
+}
+
+// CountDetectionsSince counts the employee's detection records at or after since
+func (r *PocketBaseRESTSelfTestRepository) CountDetectionsSince(ctx context.Context, employeeID string, since time.Time) (int, error) {
+	ids, err := r.listIDs(ctx, "employee_detections",
+		fmt.Sprintf("employee_id=%s && detected_at>='%s'", pbclient.Quote(employeeID), recordFilterTime(since)))
+	return len(ids), err
+}
+
+// DeleteRecordsBefore deletes the employee's attendance and detection records from before the given time
+func (r *PocketBaseRESTSelfTestRepository) DeleteRecordsBefore(ctx context.Context, employeeID string, before time.Time) (int, error) {
+	deleted := 0
+	for collection, field := range map[string]string{"attendance": "check_in_time", "employee_detections": "detected_at"} {
+		ids, err := r.listIDs(ctx, collection,
+			fmt.Sprintf("employee_id=%s && %s<'%s'", pbclient.Quote(employeeID), field, recordFilterTime(before)))
+		if err != nil {
+			return deleted, err
+		}
+		for _, id := range ids {
+			if err := r.client.Delete(ctx, collection, id); err != nil {
+				return deleted, fmt.Errorf("failed to delete %s record: %w", collection, err)
+			}
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// listIDs returns the IDs of the records matching filter
+func (r *PocketBaseRESTSelfTestRepository) listIDs(ctx context.Context, collection, filter string) ([]string, error) {
+	var records []struct {
+		ID string `json:"id"`
+	}
+	if err := r.client.List(ctx, collection, filter, "", 0, &records); err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", collection, err)
+	}
+	var ids []string
+	for _, rec := range records {
+		ids = append(ids, rec.ID)
+	}
+	return ids, nil
+}
+
+// ErrPeriodLocked is returned for writes into an attendance period locked after payroll
+var ErrPeriodLocked = errors.New("attendance period is locked")
+
+// attendanceDay is the work day an attendance record belongs to
+func attendanceDay(attendance *models.Attendance) time.Time {
+	if !attendance.CreatedDate.IsZero() {
+		return attendance.CreatedDate
+	}
+	return attendance.CheckInTime
+}
+
+// PocketBaseRESTPeriodLockRepository implements PeriodLockRepository
+type PocketBaseRESTPeriodLockRepository struct {
+	client *pbclient.Client
+}
+
+// lockedPeriodRecord is a locked_periods record as stored in PocketBase
+type lockedPeriodRecord struct {
+	ID       string                   `json:"id,omitempty"`
+	Period   string                   `json:"period"`
+	Locked   bool                     `json:"locked"`
+	LockedAt string                   `json:"locked_at"`
+	History  []models.PeriodLockEvent `json:"history"`
+}
+
+func (rec lockedPeriodRecord) toModel() models.LockedPeriod {
+	return models.LockedPeriod{
+		ID:       rec.ID,
+		Period:   rec.Period,
+		Locked:   rec.Locked,
+		LockedAt: parseRecordTime(rec.LockedAt),
+		History:  rec.History,
+	}
+}
+
+// Get returns the period's record, or nil if it was never locked
+func (r *PocketBaseRESTPeriodLockRepository) Get(ctx context.Context, period string) (*models.LockedPeriod, error) {
+	var records []lockedPeriodRecord
+	err := r.client.List(ctx, "locked_periods", fmt.Sprintf("period='%s'", period), "", 1, &records)
+	// The collection only exists after migration 009
+	if errors.Is(err, pbclient.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get locked period: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	lock := records[0].toModel()
+	return &lock, nil
+}
+
+// Save creates or updates the period's record
+func (r *PocketBaseRESTPeriodLockRepository) Save(ctx context.Context, lock *models.LockedPeriod) error {
+	rec := lockedPeriodRecord{Period: lock.Period, Locked: lock.Locked, History: lock.History}
+	if !lock.LockedAt.IsZero() {
+		rec.LockedAt = lock.LockedAt.Format(time.RFC3339)
+	}
+
+	var saved lockedPeriodRecord
+	var err error
+	if lock.ID == "" {
+		err = r.client.Create(ctx, "locked_periods", rec, &saved)
+	} else {
+		err = r.client.Update(ctx, "locked_periods", lock.ID, rec, &saved)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save locked period: %w", err)
+	}
+	lock.ID = saved.ID
+	return nil
+}
+
+// ListLocked returns the currently locked periods, oldest first
+func (r *PocketBaseRESTPeriodLockRepository) ListLocked(ctx context.Context) ([]models.LockedPeriod, error) {
+	var records []lockedPeriodRecord
+	if err := r.client.List(ctx, "locked_periods", "locked=true", "period", 0, &records); err != nil {
+		return nil, fmt.Errorf("failed to list locked periods: %w", err)
+	}
+	var locks []models.LockedPeriod
+	for _, rec := range records {
+		locks = append(locks, rec.toModel())
+	}
+	return locks, nil
+}
+
+// CheckWritable returns ErrPeriodLocked when the day falls into a locked period. Without
+// migration 009 nothing is locked.
+func (r *PocketBaseRESTPeriodLockRepository) CheckWritable(ctx context.Context, day time.Time) error {
+	if day.IsZero() || (schema != nil && !schema.Has("locked_periods", "locked")) {
+		return nil
+	}
+	period := day.Format(models.PeriodLayout)
+	lock, err := r.Get(ctx, period)
+	if err != nil {
+		return fmt.Errorf("failed to check period lock: %w", err)
+	}
+	if lock != nil && lock.Locked {
+		return fmt.Errorf("%w: %s", ErrPeriodLocked, period)
+	}
+	return nil
+}
+
+// ErrLeaseTaken means another instance created the same lease term first
+var ErrLeaseTaken = errors.New("lease term already taken")
+
+// PocketBaseRESTLeaseRepository implements LeaseRepository
+type PocketBaseRESTLeaseRepository struct {
+	client *pbclient.Client
+}
+
+// leaseRecord is an instance_lease record as stored in PocketBase
+type leaseRecord struct {
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name"`
+	Term      int    `json:"term"`
+	Holder    string `json:"holder"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// Latest returns the lease term with the highest number, or nil if none was ever taken
+func (r *PocketBaseRESTLeaseRepository) Latest(ctx context.Context, name string) (*models.Lease, error) {
+	var records []leaseRecord
+	if err := r.client.List(ctx, "instance_lease", fmt.Sprintf("name='%s'", name), "-term", 1, &records); err != nil {
+		return nil, fmt.Errorf("failed to get lease: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	rec := records[0]
+	return &models.Lease{
+		ID:        rec.ID,
+		Name:      rec.Name,
+		Term:      rec.Term,
+		Holder:    rec.Holder,
+		ExpiresAt: parseRecordTime(rec.ExpiresAt),
+	}, nil
+}
+
+// Create takes a new term. The unique (name, term) index makes this a compare-and-swap:
+// only the first instance to create a term gets it.
+func (r *PocketBaseRESTLeaseRepository) Create(ctx context.Context, lease *models.Lease) error {
+	data := leaseRecord{
+		Name:      lease.Name,
+		Term:      lease.Term,
+		Holder:    lease.Holder,
+		ExpiresAt: lease.ExpiresAt.UTC().Format(time.RFC3339Nano),
+	}
+
+	var created leaseRecord
+	err := r.client.Create(ctx, "instance_lease", data, &created)
+	if errors.Is(err, pbclient.ErrUniqueViolation) {
+		return ErrLeaseTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create lease: %w", err)
+	}
+	lease.ID = created.ID
+	return nil
+}
+
+// Renew moves the expiry of a term the caller holds
+func (r *PocketBaseRESTLeaseRepository) Renew(ctx context.Context, lease *models.Lease) error {
+	data := map[string]interface{}{
+		"expires_at": lease.ExpiresAt.UTC().Format(time.RFC3339Nano),
+	}
+	if err := r.client.Update(ctx, "instance_lease", lease.ID, data, nil); err != nil {
+		return fmt.Errorf("failed to renew lease: %w", err)
+	}
+	return nil
+}
+
+// PocketBaseRESTLocalStore implements localstore.LocalStore on the local_queue collection,
+// one record per value or list entry
+type PocketBaseRESTLocalStore struct {
+	client *pbclient.Client
+}
+
+// localQueueRecord is a local_queue record as stored in PocketBase
+type localQueueRecord struct {
+	ID   string `json:"id,omitempty"`
+	Key  string `json:"key"`
+	Seq  int64  `json:"seq"`
+	Data string `json:"data"`
+}
+
+// localQueueSeq orders list entries; microseconds stay exact in a PocketBase number field
+var localQueueSeq struct {
+	sync.Mutex
+	last int64
+}
+
+// nextLocalQueueSeq returns an increasing sequence number, ordered by time across instances
+func nextLocalQueueSeq() int64 {
+	localQueueSeq.Lock()
+	defer localQueueSeq.Unlock()
+	seq := time.Now().UnixMicro()
+	if seq <= localQueueSeq.last {
+		seq = localQueueSeq.last + 1
+	}
+	localQueueSeq.last = seq
+	return seq
+}
+
+// list returns the records under key, oldest first
+func (r *PocketBaseRESTLocalStore) list(ctx context.Context, key string) ([]localQueueRecord, error) {
+	var records []localQueueRecord
+	if err := r.client.List(ctx, "local_queue", fmt.Sprintf("key='%s'", key), "seq", 0, &records); err != nil {
+		return nil, fmt.Errorf("failed to list local queue: %w", err)
+	}
+	return records, nil
+}
+
+// save creates the record, or updates it when it has an ID
+func (r *PocketBaseRESTLocalStore) save(ctx context.Context, rec localQueueRecord) error {
+	var err error
+	if rec.ID == "" {
+		err = r.client.Create(ctx, "local_queue", rec, nil)
+	} else {
+		err = r.client.Update(ctx, "local_queue", rec.ID, rec, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save local queue record: %w", err)
+	}
+	return nil
+}
+
+// delete removes one record
+func (r *PocketBaseRESTLocalStore) delete(ctx context.Context, id string) error {
+	if err := r.client.Delete(ctx, "local_queue", id); err != nil {
+		return fmt.Errorf("failed to delete local queue record: %w", err)
+	}
+	return nil
+}
+
+// Get returns the value under key, or localstore.ErrNotFound
+func (r *PocketBaseRESTLocalStore) Get(ctx context.Context, key string) ([]byte, error) {
+	records, err := r.list(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, localstore.ErrNotFound
+	}
+	return []byte(records[len(records)-1].Data), nil
+}
+
+// Put replaces the value under key, updating its record in place
+func (r *PocketBaseRESTLocalStore) Put(ctx context.Context, key string, value []byte) error {
+	records, err := r.list(ctx, key)
+	if err != nil {
+		return err
+	}
+	rec := localQueueRecord{Key: key, Seq: nextLocalQueueSeq(), Data: string(value)}
+	if len(records) > 0 {
+		rec.ID = records[len(records)-1].ID
+		for _, stale := range records[:len(records)-1] {
+			if err := r.delete(ctx, stale.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return r.save(ctx, rec)
+}
+
+// Append creates one record per entry
+func (r *PocketBaseRESTLocalStore) Append(ctx context.Context, key string, records ...[]byte) error {
+	for _, data := range records {
+		if err := r.save(ctx, localQueueRecord{Key: key, Seq: nextLocalQueueSeq(), Data: string(data)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Records returns the entries under key, oldest first
+func (r *PocketBaseRESTLocalStore) Records(ctx context.Context, key string) ([][]byte, error) {
+	records, err := r.list(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	var data [][]byte
+	for _, rec := range records {
+		data = append(data, []byte(rec.Data))
+	}
+	return data, nil
+}
+
+// Delete removes every record under key
+func (r *PocketBaseRESTLocalStore) Delete(ctx context.Context, key string) error {
+	records, err := r.list(ctx, key)
+	if err != nil {
+		return err
+	}
+	for _, rec := range records {
+		if err := r.delete(ctx, rec.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PocketBaseRESTAuditLogRepository implements AuditLog
+type PocketBaseRESTAuditLogRepository struct {
+	client *pbclient.Client
+}
+
+// Record creates an audit_log record
+func (r *PocketBaseRESTAuditLogRepository) Record(ctx context.Context, entry *models.AuditEntry) error {
+	data := map[string]interface{}{
+		"action":     entry.Action,
+		"target_id":  entry.TargetID,
+		"actor_id":   entry.ActorID,
+		"actor_name": entry.ActorName,
+		"details":    entry.Details,
+		"at":         entry.At.UTC().Format(time.RFC3339),
+	}
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := r.client.Create(ctx, "audit_log", data, &created); err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	entry.ID = created.ID
+	return nil
+}
+
+// PocketBaseRESTPrivacyAuditRepository implements ConsentLog
+type PocketBaseRESTPrivacyAuditRepository struct {
+	client *pbclient.Client
+}
+
+// RecordConsent creates a privacy_audit record
+func (r *PocketBaseRESTPrivacyAuditRepository) RecordConsent(ctx context.Context, employeeID string, consent bool, by int64, at time.Time) error {
+	data := map[string]interface{}{
+		"employee_id": employeeID,
+		"consent":     consent,
+		"changed_by":  by,
+		"changed_at":  at.UTC().Format(time.RFC3339),
+	}
+	if err := r.client.Create(ctx, "privacy_audit", data, nil); err != nil {
+		return fmt.Errorf("failed to record consent change: %w", err)
+	}
+	return nil
+}
+
+// PocketBaseRESTNotificationFailureRepository implements NotificationFailureLog
+type PocketBaseRESTNotificationFailureRepository struct {
+	client *pbclient.Client
+}
+
+// Record creates a notification_failures record. Without migration 026 nothing is
+// stored; the failure is still in the log.
+func (r *PocketBaseRESTNotificationFailureRepository) Record(ctx context.Context, failure *models.NotificationFailure) error {
+	if schema != nil && !schema.Has("notification_failures", "chat_id") {
+		return nil
+	}
+	data := map[string]interface{}{
+		"chat_id":   failure.ChatID,
+		"text":      failure.Text,
+		"error":     failure.Error,
+		"attempts":  failure.Attempts,
+		"failed_at": failure.FailedAt.UTC().Format(time.RFC3339),
+	}
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := r.client.Create(ctx, "notification_failures", data, &created); err != nil {
+		return fmt.Errorf("failed to record notification failure: %w", err)
+	}
+	failure.ID = created.ID
+	return nil
+}
+
+// PocketBaseRESTLateApprovalRepository implements LateApprovalRepository
+type PocketBaseRESTLateApprovalRepository struct {
+	client *pbclient.Client
+}
+
+// lateApprovalRecord is a late_approvals record as stored in PocketBase
+type lateApprovalRecord struct {
+	ID           string `json:"id,omitempty"`
+	EmployeeID   string `json:"employee_id"`
+	Date         string `json:"date"`
+	ExpectedTime string `json:"expected_time"`
+	Reason       string `json:"reason"`
+	Status       string `json:"status"`
+	RequestedAt  string `json:"requested_at"`
+	DecidedBy    int64  `json:"decided_by"`
+	DeciderName  string `json:"decider_name"`
+	DecidedAt    string `json:"decided_at"`
+}
+
+func (rec lateApprovalRecord) toModel() models.LateApproval {
+	return models.LateApproval{
+		ID:           rec.ID,
+		EmployeeID:   rec.EmployeeID,
+		Date:         rec.Date,
+		ExpectedTime: rec.ExpectedTime,
+		Reason:       rec.Reason,
+		Status:       rec.Status,
+		RequestedAt:  parseRecordTime(rec.RequestedAt),
+		DecidedBy:    rec.DecidedBy,
+		DeciderName:  rec.DeciderName,
+		DecidedAt:    parseRecordTime(rec.DecidedAt),
+	}
+}
+
+func lateApprovalRecordOf(a *models.LateApproval) lateApprovalRecord {
+	rec := lateApprovalRecord{
+		EmployeeID:   a.EmployeeID,
+		Date:         a.Date,
+		ExpectedTime: a.ExpectedTime,
+		Reason:       a.Reason,
+		Status:       a.Status,
+		RequestedAt:  a.RequestedAt.UTC().Format(time.RFC3339),
+		DecidedBy:    a.DecidedBy,
+		DeciderName:  a.DeciderName,
+	}
+	if !a.DecidedAt.IsZero() {
+		rec.DecidedAt = a.DecidedAt.UTC().Format(time.RFC3339)
+	}
+	return rec
+}
+
+// Create stores a new request
+func (r *PocketBaseRESTLateApprovalRepository) Create(ctx context.Context, approval *models.LateApproval) error {
+	var saved lateApprovalRecord
+	if err := r.client.Create(ctx, "late_approvals", lateApprovalRecordOf(approval), &saved); err != nil {
+		return fmt.Errorf("failed to create late approval: %w", err)
+	}
+	approval.ID = saved.ID
+	return nil
+}
+
+// Get returns one request by ID, or nil if there is none
+func (r *PocketBaseRESTLateApprovalRepository) Get(ctx context.Context, id string) (*models.LateApproval, error) {
+	var rec lateApprovalRecord
+	err := r.client.GetOne(ctx, "late_approvals", id, &rec)
+	if errors.Is(err, pbclient.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get late approval: %w", err)
+	}
+	approval := rec.toModel()
+	return &approval, nil
+}
+
+// Update writes the status and decision of a request
+func (r *PocketBaseRESTLateApprovalRepository) Update(ctx context.Context, approval *models.LateApproval) error {
+	rec := lateApprovalRecordOf(approval)
+	data := map[string]interface{}{
+		"status":       rec.Status,
+		"decided_by":   rec.DecidedBy,
+		"decider_name": rec.DeciderName,
+		"decided_at":   rec.DecidedAt,
+	}
+	if err := r.client.Update(ctx, "late_approvals", approval.ID, data, nil); err != nil {
+		return fmt.Errorf("failed to update late approval: %w", err)
+	}
+	return nil
+}
+
+// ListByEmployeeDate returns the employee's requests for the day. Without migration 016
+// there are none.
+func (r *PocketBaseRESTLateApprovalRepository) ListByEmployeeDate(ctx context.Context, employeeID, date string) ([]models.LateApproval, error) {
+	filter := fmt.Sprintf("employee_id=%s && date=%s", pbclient.Quote(employeeID), pbclient.Quote(date))
+	return r.list(ctx, filter)
+}
+
+// ListPending returns every request waiting for a decision, oldest day first
+func (r *PocketBaseRESTLateApprovalRepository) ListPending(ctx context.Context) ([]models.LateApproval, error) {
+	return r.list(ctx, fmt.Sprintf("status=%s", pbclient.Quote(models.LateApprovalPending)))
+}
+
+func (r *PocketBaseRESTLateApprovalRepository) list(ctx context.Context, filter string) ([]models.LateApproval, error) {
+	var records []lateApprovalRecord
+	err := r.client.List(ctx, "late_approvals", filter, "date,requested_at", 0, &records)
+	// The collection only exists after migration 016
+	if errors.Is(err, pbclient.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list late approvals: %w", err)
+	}
+	approvals := make([]models.LateApproval, len(records))
+	for i, rec := range records {
+		approvals[i] = rec.toModel()
+	}
+	return approvals, nil
+}
+
+// PocketBaseRESTHolidayRepository implements HolidayRepository
+type PocketBaseRESTHolidayRepository struct {
+	client *pbclient.Client
+}
+
+// holidayRecord is a holidays record as stored in PocketBase
+type holidayRecord struct {
+	ID   string `json:"id"`
+	Date string `json:"date"`
+	Name string `json:"name"`
+}
+
+// GetByDate returns the holiday on the day, or nil if there is none. Without migration 021
+// there are none.
+func (r *PocketBaseRESTHolidayRepository) GetByDate(ctx context.Context, date string) (*models.Holiday, error) {
+	var records []holidayRecord
+	err := r.client.List(ctx, "holidays", fmt.Sprintf("date=%s", pbclient.Quote(date)), "", 1, &records)
+	if errors.Is(err, pbclient.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get holiday: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	rec := records[0]
+	return &models.Holiday{ID: rec.ID, Date: rec.Date, Name: rec.Name}, nil
+}
+
+// PocketBaseRESTDeviceRepository implements DeviceRepository, DeviceAssignment and
+// EmployeeDevices
+type PocketBaseRESTDeviceRepository struct {
+	client *pbclient.Client
+}
+
+// deviceRecord is an employee_devices record as stored in PocketBase
+type deviceRecord struct {
+	ID         string `json:"id"`
+	EmployeeID string `json:"employee_id"`
+	MacAddress string `json:"mac_address"`
+	Label      string `json:"label"`
+	IsPrimary  bool   `json:"is_primary"`
+}
+
+func (rec deviceRecord) toModel() models.EmployeeDevice {
+	return models.EmployeeDevice{
+		ID:         rec.ID,
+		EmployeeID: rec.EmployeeID,
+		MacAddress: rec.MacAddress,
+		Label:      rec.Label,
+		IsPrimary:  rec.IsPrimary,
+	}
+}
+
+// PocketBaseRESTDeviceSightingRepository implements DeviceSightingRepository
+type PocketBaseRESTDeviceSightingRepository struct {
+	client *pbclient.Client
+}
+
+// UpsertSighting creates or updates the devices record of the MAC
+func (r *PocketBaseRESTDeviceSightingRepository) UpsertSighting(ctx context.Context, sighting models.DeviceSighting) error {
+	// Without the field the count would be silently dropped
+	if schema != nil && !schema.Has("devices", "detection_count") {
+		return fmt.Errorf("devices.detection_count is missing; run migration 030 before capturing unknown devices")
+	}
+	mac := strings.ToLower(sighting.MacAddress)
+	var existing []sightingRecord
+	if err := r.client.List(ctx, "devices", fmt.Sprintf("mac_address=%s", pbclient.Quote(mac)), "", 1, &existing); err != nil {
+		return fmt.Errorf("failed to look up device: %w", err)
+	}
+
+	data := map[string]interface{}{
+		"mac_address":     mac,
+		"rssi":            sighting.RSSI,
+		"last_seen":       sighting.SeenAt.UTC().Format(time.RFC3339),
+		"detection_count": sighting.Count,
+	}
+	var err error
+	if len(existing) == 0 {
+		err = r.client.Create(ctx, "devices", data, nil)
+	} else {
+		data["detection_count"] = existing[0].DetectionCount + sighting.Count
+		if existing[0].RSSI != 0 && existing[0].RSSI > sighting.RSSI {
+			data["rssi"] = existing[0].RSSI
+		}
+		err = r.client.Update(ctx, "devices", existing[0].ID, data, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save device sighting: %w", err)
+	}
+	return nil
+}
+
+// sightingRecord is a devices record as stored in PocketBase
+type sightingRecord struct {
+	ID             string `json:"id"`
+	MacAddress     string `json:"mac_address"`
+	RSSI           int    `json:"rssi"`
+	LastSeen       string `json:"last_seen"`
+	DetectionCount int    `json:"detection_count"`
+	IsReviewed     bool   `json:"is_reviewed"`
+	IsWhitelisted  bool   `json:"is_whitelisted"`
+}
+
+func (rec sightingRecord) toModel() models.DeviceSighting {
+	return models.DeviceSighting{
+		MacAddress:  rec.MacAddress,
+		RSSI:        rec.RSSI,
+		SeenAt:      parseRecordTime(rec.LastSeen),
+		Count:       rec.DetectionCount,
+		Reviewed:    rec.IsReviewed,
+		Whitelisted: rec.IsWhitelisted,
+	}
+}
+
+// ListPending returns the devices seen since the time that no admin has reviewed, most
+// often seen first
+func (r *PocketBaseRESTDeviceSightingRepository) ListPending(ctx context.Context, since time.Time) ([]models.DeviceSighting, error) {
+	// Without the field ignored devices would be listed again forever
+	if schema != nil && !schema.Has("devices", "is_reviewed") {
+		return nil, fmt.Errorf("devices.is_reviewed is missing; run migration 031 before reviewing devices")
+	}
+	var records []sightingRecord
+	filter := fmt.Sprintf("last_seen>='%s' && is_reviewed!=true", recordFilterTime(since))
+	if err := r.client.List(ctx, "devices", filter, "-detection_count", 0, &records); err != nil {
+		return nil, fmt.Errorf("failed to list pending devices: %w", err)
+	}
+	sightings := make([]models.DeviceSighting, len(records))
+	for i, rec := range records {
+		sightings[i] = rec.toModel()
+	}
+	return sightings, nil
+}
+
+// Review sets is_reviewed and is_whitelisted on every devices record of the MAC
+func (r *PocketBaseRESTDeviceSightingRepository) Review(ctx context.Context, mac string, whitelisted bool) error {
+	if schema != nil && !schema.Has("devices", "is_reviewed") {
+		return fmt.Errorf("devices.is_reviewed is missing; run migration 031 before reviewing devices")
+	}
+	var records []sightingRecord
+	if err := r.client.List(ctx, "devices", fmt.Sprintf("mac_address=%s", pbclient.Quote(strings.ToLower(mac))), "", 0, &records); err != nil {
+		return fmt.Errorf("failed to look up device: %w", err)
+	}
+	if len(records) == 0 {
+		return ErrDeviceNotFound
+	}
+	data := map[string]interface{}{"is_reviewed": true, "is_whitelisted": whitelisted}
+	for _, rec := range records {
+		if err := r.client.Update(ctx, "devices", rec.ID, data, nil); err != nil {
+			return fmt.Errorf("failed to review device: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListWhitelisted returns the MACs of the devices records with is_whitelisted set
+func (r *PocketBaseRESTDeviceSightingRepository) ListWhitelisted(ctx context.Context) ([]string, error) {
+	var records []sightingRecord
+	if err := r.client.List(ctx, "devices", "is_whitelisted=true", "", 0, &records); err != nil {
+		return nil, fmt.Errorf("failed to list whitelisted devices: %w", err)
+	}
+	macs := make([]string, len(records))
+	for i, rec := range records {
+		macs[i] = strings.ToLower(rec.MacAddress)
+	}
+	return macs, nil
+}
+
+// AssignDevice sets the employee's mac_address when it is empty and adds an
+// employee_devices record otherwise; the extra record needs migration 022
+func (r *PocketBaseRESTDeviceRepository) AssignDevice(ctx context.Context, employee *models.Employee, mac string) error {
+	mac = strings.ToLower(mac)
+	if employee.MacAddress == "" {
+		if err := r.client.Update(ctx, "employees", employee.ID, map[string]interface{}{"mac_address": mac}, nil); err != nil {
+			return fmt.Errorf("failed to assign device: %w", err)
+		}
+		return nil
+	}
+	data := map[string]interface{}{
+		"employee_id": employee.ID,
+		"mac_address": mac,
+		"is_primary":  false,
+	}
+	if err := r.client.Create(ctx, "employee_devices", data, nil); err != nil {
+		return fmt.Errorf("failed to assign device: %w", err)
+	}
+	return nil
+}
+
+// GetEmployeeByDeviceMac returns the active employee owning the device. Without migration
+// 022 no employee has devices.
+func (r *PocketBaseRESTDeviceRepository) GetEmployeeByDeviceMac(ctx context.Context, macAddress string) (*models.Employee, error) {
+	var devices []deviceRecord
+	err := r.client.List(ctx, "employee_devices", fmt.Sprintf("mac_address=%s", pbclient.Quote(strings.ToLower(macAddress))), "", 1, &devices)
+	if errors.Is(err, pbclient.ErrNotFound) {
+		return nil, ErrEmployeeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device: %w", err)
+	}
+	if len(devices) == 0 {
+		return nil, ErrEmployeeNotFound
+	}
+
+	var rec employeeRecord
+	err = r.client.GetOne(ctx, "employees", devices[0].EmployeeID, &rec)
+	if errors.Is(err, pbclient.ErrNotFound) {
+		return nil, ErrEmployeeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get employee of device: %w", err)
+	}
+	if !rec.IsActive {
+		return nil, ErrEmployeeNotFound
+	}
+	emp := rec.toModel()
+	return &emp, nil
+}
+
+// ListDevices returns the employee's employee_devices records by MAC. Without migration
+// 022 no employee has devices.
+func (r *PocketBaseRESTDeviceRepository) ListDevices(ctx context.Context, employeeID string) ([]models.EmployeeDevice, error) {
+	var records []deviceRecord
+	err := r.client.List(ctx, "employee_devices", "employee_id="+pbclient.Quote(employeeID), "mac_address", 0, &records)
+	if errors.Is(err, pbclient.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+	devices := make([]models.EmployeeDevice, len(records))
+	for i, rec := range records {
+		devices[i] = rec.toModel()
+	}
+	return devices, nil
+}
+
+// AddDevice creates an employee_devices record
+func (r *PocketBaseRESTDeviceRepository) AddDevice(ctx context.Context, device *models.EmployeeDevice) error {
+	data := map[string]interface{}{
+		"employee_id": device.EmployeeID,
+		"mac_address": strings.ToLower(device.MacAddress),
+		"label":       device.Label,
+		"is_primary":  device.IsPrimary,
+	}
+	var rec deviceRecord
+	err := r.client.Create(ctx, "employee_devices", data, &rec)
+	if errors.Is(err, pbclient.ErrUniqueViolation) {
+		return ErrDeviceExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add device: %w", err)
+	}
+	device.ID = rec.ID
+	return nil
+}
+
+// RemoveDevice deletes the employee's employee_devices record of the MAC
+func (r *PocketBaseRESTDeviceRepository) RemoveDevice(ctx context.Context, employeeID, mac string) error {
+	var records []deviceRecord
+	filter := fmt.Sprintf("employee_id=%s && mac_address=%s", pbclient.Quote(employeeID), pbclient.Quote(strings.ToLower(mac)))
+	err := r.client.List(ctx, "employee_devices", filter, "", 1, &records)
+	if errors.Is(err, pbclient.ErrNotFound) || err == nil && len(records) == 0 {
+		return ErrDeviceNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up device: %w", err)
+	}
+	if err := r.client.Delete(ctx, "employee_devices", records[0].ID); err != nil {
+		return fmt.Errorf("failed to remove device: %w", err)
+	}
 	return nil
 }
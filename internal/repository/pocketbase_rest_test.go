@@ -0,0 +1,706 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/pbclient/pbtest"
+)
+
+// pbFailures are PocketBase answers that every repository call must report as an error
+var pbFailures = []struct {
+	name   string
+	status int
+	body   string
+	want   error // nil when any error will do
+}{
+	{"unauthorized", http.StatusUnauthorized, `{"status":401,"message":"The request requires valid record authorization token.","data":{}}`, ErrUnauthorized},
+	{"server error", http.StatusInternalServerError, `{"status":500,"message":"Something went wrong while processing your request.","data":{}}`, ErrUnavailable},
+	{"unavailable", http.StatusServiceUnavailable, `<html><body>503 Service Unavailable</body></html>`, ErrUnavailable},
+	{"malformed JSON", http.StatusOK, `{"page":1,"items":[{"id":"rec1",`, nil},
+}
+
+// restCall is one repository call against a fake PocketBase. It returns a summary of the
+// result for comparison.
+type restCall struct {
+	name       string
+	collection string // the collection whose answers pbFailures replace
+	decodes    bool   // whether a malformed body fails the call
+	run        func(ctx context.Context, site Site) (string, error)
+}
+
+// testFailures runs every call against every failure of pbFailures
+func testFailures(t *testing.T, calls []restCall) {
+	for _, call := range calls {
+		for _, f := range pbFailures {
+			if f.status == http.StatusOK && !call.decodes {
+				continue
+			}
+			t.Run(call.name+"/"+f.name, func(t *testing.T) {
+				server := pbtest.NewServer(t)
+				server.Seed(call.collection)
+				server.Respond(call.collection, f.status, f.body)
+
+				got, err := call.run(context.Background(), Site{URL: server.URL})
+				if err == nil || (f.want != nil && !errors.Is(err, f.want)) {
+					t.Errorf("%s = %q, %v, want error %v", call.name, got, err, f.want)
+				}
+			})
+		}
+	}
+}
+
+// restCase is a repository call against seeded records
+type restCase struct {
+	name       string
+	run        func(ctx context.Context, site Site) (string, error)
+	want       string
+	wantErr    error
+	collection string // the collection whose last request is checked, if any
+	wantFilter string
+	wantBody   map[string]any // fields the last create or update must have sent
+}
+
+// testCases runs each case against a fresh server seeded by seed
+func testCases(t *testing.T, seed func(*pbtest.Server), tests []restCase) {
+	SetLocation(time.UTC)
+	t.Cleanup(func() { SetLocation(time.Local) })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := pbtest.NewServer(t)
+			seed(server)
+
+			got, err := tt.run(context.Background(), Site{URL: server.URL})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("error = %v", err)
+			} else if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+
+			if tt.collection == "" {
+				return
+			}
+			requests := server.Requests(tt.collection)
+			if len(requests) == 0 {
+				t.Fatalf("no request to %s", tt.collection)
+			}
+			last := requests[len(requests)-1]
+			if tt.wantFilter != "" && last.Filter != tt.wantFilter {
+				t.Errorf("filter = %q, want %q", last.Filter, tt.wantFilter)
+			}
+			for field, want := range tt.wantBody {
+				if got := last.Body[field]; fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Errorf("sent %s = %v, want %v", field, got, want)
+				}
+			}
+		})
+	}
+}
+
+var restDay = time.Date(2026, 1, 20, 8, 5, 0, 0, time.UTC)
+
+func seedEmployees(s *pbtest.Server) {
+	s.Seed("employees",
+		pbtest.Record{"id": "e1", "name": "Somchai", "employee_code": "N001", "mac_address": "aa:bb:cc:dd:ee:01", "is_active": true, "telegram_chat_id": 1001},
+		pbtest.Record{"id": "e2", "name": "Somsri", "employee_code": "N'02", "mac_address": "aa:bb:cc:dd:ee:02", "is_active": false, "telegram_chat_id": 1002},
+		pbtest.Record{"id": "e3", "name": "Malee", "employee_code": "N003", "mac_address": "aa:bb:cc:dd:ee:03", "is_active": true},
+		pbtest.Record{"id": "e4", "name": "Self-test", "mac_address": "02:00:00:00:00:01", "is_active": true, "is_synthetic": true},
+	)
+	s.Unique("employees", "mac_address")
+	s.Seed("attendance",
+		pbtest.Record{"id": "a1", "employee_id": "e1", "check_in_time": restDay.Format(time.RFC3339), "created_date": "2026-01-20"},
+	)
+}
+
+func TestEmployeeRepository(t *testing.T) {
+	testCases(t, seedEmployees, []restCase{
+		{
+			name: "get by MAC",
+			run: func(ctx context.Context, site Site) (string, error) {
+				emp, err := site.Employees().GetByMacAddress(ctx, "AA:BB:CC:DD:EE:01")
+				if err != nil {
+					return "", err
+				}
+				return emp.ID + " " + emp.Name, nil
+			},
+			want:       "e1 Somchai",
+			collection: "employees",
+			wantFilter: "mac_address='aa:bb:cc:dd:ee:01' && is_active=true",
+		},
+		{
+			name: "get by MAC quotes the filter",
+			run: func(ctx context.Context, site Site) (string, error) {
+				_, err := site.Employees().GetByMacAddress(ctx, "x' || mac_address!='")
+				return "", err
+			},
+			wantErr:    ErrEmployeeNotFound,
+			collection: "employees",
+			wantFilter: `mac_address='x\' || mac_address!=\'' && is_active=true`,
+		},
+		{
+			name: "self-test employee's MAC is stored lower-case",
+			run: func(ctx context.Context, site Site) (string, error) {
+				emp, err := site.SelfTest().EnsureSyntheticEmployee(ctx, "02:00:00:00:00:AB")
+				if err != nil {
+					return "", err
+				}
+				return emp.MacAddress, nil
+			},
+			want:       "02:00:00:00:00:ab",
+			collection: "employees",
+			wantBody:   map[string]any{"mac_address": "02:00:00:00:00:ab", "is_synthetic": true},
+		},
+		{
+			name: "assigned device's MAC is stored lower-case",
+			run: func(ctx context.Context, site Site) (string, error) {
+				return "", site.Devices().AssignDevice(ctx, &models.Employee{ID: "e3"}, "AA:BB:CC:DD:EE:0B")
+			},
+			collection: "employees",
+			wantBody:   map[string]any{"mac_address": "aa:bb:cc:dd:ee:0b"},
+		},
+		{
+			name: "inactive employee's MAC",
+			run: func(ctx context.Context, site Site) (string, error) {
+				_, err := site.Employees().GetByMacAddress(ctx, "aa:bb:cc:dd:ee:02")
+				return "", err
+			},
+			wantErr: ErrEmployeeNotFound,
+		},
+		{
+			name: "get by code with a quote",
+			run: func(ctx context.Context, site Site) (string, error) {
+				emp, err := site.Employees().GetByCode(ctx, "N'02")
+				if err != nil {
+					return "", err
+				}
+				return emp.ID, nil
+			},
+			want:       "e2",
+			collection: "employees",
+			wantFilter: `employee_code='N\'02'`,
+		},
+		{
+			name: "unknown code",
+			run: func(ctx context.Context, site Site) (string, error) {
+				_, err := site.Employees().GetByCode(ctx, "N999")
+				return "", err
+			},
+			wantErr: ErrNotFound,
+		},
+		{
+			name: "search active employees",
+			run: func(ctx context.Context, site Site) (string, error) {
+				employees, total, err := site.Employees().List(ctx, "som", Page{Number: 1, Size: 10})
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%d %v", total, employeeIDs(employees)), nil
+			},
+			want:       "1 [e1]",
+			collection: "employees",
+			wantFilter: "is_active=true && is_synthetic!=true && (name~'som' || employee_code~'som')",
+		},
+		{
+			name: "page of active employees by name",
+			run: func(ctx context.Context, site Site) (string, error) {
+				employees, total, err := site.Employees().PageActive(ctx, Page{Number: 2, Size: 1})
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%d %v", total, employeeIDs(employees)), nil
+			},
+			want: "2 [e1]",
+		},
+		{
+			name: "update profile",
+			run: func(ctx context.Context, site Site) (string, error) {
+				return "", site.Employees().Update(ctx, &models.Employee{ID: "e3", Name: "Malee S.", EmployeeCode: "N003", WorkStartTime: "07:30:00"})
+			},
+			collection: "employees",
+			wantBody:   map[string]any{"name": "Malee S.", "work_start_time": "07:30:00"},
+		},
+		{
+			name: "get by chat",
+			run: func(ctx context.Context, site Site) (string, error) {
+				emp, err := site.Employees().GetByChatID(ctx, 1001)
+				if err != nil {
+					return "", err
+				}
+				return emp.ID + " " + emp.Name, nil
+			},
+			want:       "e1 Somchai",
+			collection: "employees",
+			wantFilter: "telegram_chat_id=1001 && is_active=true",
+		},
+		{
+			name: "inactive employee's chat",
+			run: func(ctx context.Context, site Site) (string, error) {
+				_, err := site.Employees().GetByChatID(ctx, 1002)
+				return "", err
+			},
+			wantErr: ErrEmployeeNotFound,
+		},
+		{
+			name: "owner of an inactive employee's MAC",
+			run: func(ctx context.Context, site Site) (string, error) {
+				emp, err := site.Employees().GetOwnerByMac(ctx, "AA:BB:CC:DD:EE:02")
+				if err != nil {
+					return "", err
+				}
+				return emp.ID, nil
+			},
+			want:       "e2",
+			collection: "employees",
+			wantFilter: "mac_address='aa:bb:cc:dd:ee:02'",
+		},
+		{
+			name: "register",
+			run: func(ctx context.Context, site Site) (string, error) {
+				emp := &models.Employee{MacAddress: "AA:BB:CC:DD:EE:05", TelegramChatID: 1005, Name: "Wichai", EmployeeCode: "N005",
+					IsActive: true, ShowOnBoard: true, PresenceOptOut: true}
+				if err := site.Employees().Register(ctx, emp); err != nil {
+					return "", err
+				}
+				return fmt.Sprint(emp.ID != ""), nil
+			},
+			want:       "true",
+			collection: "employees",
+			wantBody: map[string]any{"mac_address": "aa:bb:cc:dd:ee:05", "telegram_chat_id": 1005, "show_on_board": true,
+				"notify_checkin": true, "presence_tracking_consent": false},
+		},
+		{
+			name: "register a taken MAC",
+			run: func(ctx context.Context, site Site) (string, error) {
+				return "", site.Employees().Register(ctx, &models.Employee{MacAddress: "aa:bb:cc:dd:ee:02", TelegramChatID: 1005, Name: "Wichai"})
+			},
+			wantErr: ErrEmployeeExists,
+		},
+		{
+			name: "update settings",
+			run: func(ctx context.Context, site Site) (string, error) {
+				return "", site.Employees().UpdateSettings(ctx, &models.Employee{ID: "e1", MacAddress: "AA:BB:CC:DD:EE:0A", WorkStartTime: "07:30:00", Language: "en"})
+			},
+			collection: "employees",
+			wantBody: map[string]any{"mac_address": "aa:bb:cc:dd:ee:0a", "work_start_time": "07:30:00", "language": "en",
+				"show_on_board": false, "muted_notifications": "[]", "presence_tracking_consent": true},
+		},
+		{
+			name: "deactivate a deleted employee",
+			run: func(ctx context.Context, site Site) (string, error) {
+				return "", site.Employees().SetActive(ctx, "gone", false)
+			},
+			wantErr: ErrNotFound,
+		},
+		{
+			name: "checked in on the day",
+			run: func(ctx context.Context, site Site) (string, error) {
+				in, err := site.Employees().IsCheckedInOn(ctx, "e1", restDay)
+				return fmt.Sprint(in), err
+			},
+			want:       "true",
+			collection: "attendance",
+			wantFilter: "employee_id='e1' && created_date>='2026-01-20 00:00:00' && created_date<'2026-01-21 00:00:00'",
+		},
+		{
+			name: "not checked in the next day",
+			run: func(ctx context.Context, site Site) (string, error) {
+				in, err := site.Employees().IsCheckedInOn(ctx, "e1", restDay.AddDate(0, 0, 1))
+				return fmt.Sprint(in), err
+			},
+			want: "false",
+		},
+	})
+
+	testFailures(t, []restCall{
+		{"GetByMacAddress", "employees", true, func(ctx context.Context, site Site) (string, error) {
+			_, err := site.Employees().GetByMacAddress(ctx, "aa:bb:cc:dd:ee:01")
+			return "", err
+		}},
+		{"ListActive", "employees", true, func(ctx context.Context, site Site) (string, error) {
+			employees, err := site.Employees().ListActive(ctx)
+			return fmt.Sprint(employeeIDs(employees)), err
+		}},
+		{"SetActive", "employees", false, func(ctx context.Context, site Site) (string, error) {
+			return "", site.Employees().SetActive(ctx, "e1", false)
+		}},
+		{"IsCheckedInOn", "attendance", true, func(ctx context.Context, site Site) (string, error) {
+			in, err := site.Employees().IsCheckedInOn(ctx, "e1", restDay)
+			return fmt.Sprint(in), err
+		}},
+	})
+}
+
+func employeeIDs(employees []models.Employee) []string {
+	var ids []string
+	for _, emp := range employees {
+		ids = append(ids, emp.ID)
+	}
+	return ids
+}
+
+func seedAttendance(s *pbtest.Server) {
+	s.Seed("attendance",
+		pbtest.Record{"id": "a2", "employee_id": "e2", "check_in_time": restDay.Add(time.Hour).Format(time.RFC3339), "created_date": "2026-01-20"},
+		pbtest.Record{"id": "a1", "employee_id": "e1", "check_in_time": restDay.Format(time.RFC3339), "created_date": "2026-01-20"},
+		pbtest.Record{"id": "a0", "employee_id": "e1", "check_in_time": restDay.AddDate(0, 0, -1).Format(time.RFC3339), "created_date": "2026-01-19"},
+	)
+	s.Seed("locked_periods", pbtest.Record{"period": "2025-12", "locked": true})
+}
+
+func TestAttendanceRepository(t *testing.T) {
+	testCases(t, seedAttendance, []restCase{
+		{
+			name: "check in",
+			run: func(ctx context.Context, site Site) (string, error) {
+				day := restDay.AddDate(0, 0, 1)
+				a := &models.Attendance{EmployeeID: "e1", CheckInTime: day, CreatedDate: day, Status: "on_time"}
+				if err := site.Attendance().Create(ctx, a); err != nil {
+					return "", err
+				}
+				return fmt.Sprint(a.ID != ""), nil
+			},
+			want:       "true",
+			collection: "attendance",
+			wantBody:   map[string]any{"employee_id": "e1", "created_date": "2026-01-21", "check_in_time": "2026-01-21T08:05:00Z", "status": "on_time"},
+		},
+		{
+			name: "record of an earlier day",
+			run: func(ctx context.Context, site Site) (string, error) {
+				a, err := site.Attendance().GetByEmployeeOn(ctx, "e1", restDay.AddDate(0, 0, -1))
+				if err != nil || a == nil {
+					return "", err
+				}
+				return a.ID, nil
+			},
+			want:       "a0",
+			collection: "attendance",
+			wantFilter: "employee_id='e1' && created_date>='2026-01-19 00:00:00' && created_date<'2026-01-20 00:00:00'",
+		},
+		{
+			name: "second check-in of the day",
+			run: func(ctx context.Context, site Site) (string, error) {
+				return "", site.Attendance().Create(ctx, &models.Attendance{EmployeeID: "e1", CheckInTime: restDay, CreatedDate: restDay})
+			},
+			wantErr: ErrAttendanceExists,
+		},
+		{
+			name: "check in to a locked period",
+			run: func(ctx context.Context, site Site) (string, error) {
+				day := time.Date(2025, 12, 30, 8, 0, 0, 0, time.UTC)
+				return "", site.Attendance().Create(ctx, &models.Attendance{EmployeeID: "e1", CheckInTime: day, CreatedDate: day})
+			},
+			wantErr: ErrPeriodLocked,
+		},
+		{
+			name: "relink into a locked period",
+			run: func(ctx context.Context, site Site) (string, error) {
+				if err := site.PeriodLocks().Save(ctx, &models.LockedPeriod{Period: "2026-01", Locked: true}); err != nil {
+					return "", err
+				}
+				return "", site.Attendance().Relink(ctx, "a1", "e2")
+			},
+			wantErr: ErrPeriodLocked,
+		},
+		{
+			name: "list the day by check-in time",
+			run: func(ctx context.Context, site Site) (string, error) {
+				records, err := site.Attendance().ListByDate(ctx, restDay)
+				return fmt.Sprint(attendanceIDs(records)), err
+			},
+			want:       "[a1 a2]",
+			collection: "attendance",
+			wantFilter: "created_date>='2026-01-20 00:00:00' && created_date<'2026-01-21 00:00:00'",
+		},
+		{
+			name: "page of an employee's range",
+			run: func(ctx context.Context, site Site) (string, error) {
+				records, total, err := site.Attendance().PageByEmployeeAndRange(ctx, "e1", restDay.AddDate(0, 0, -7), restDay.AddDate(0, 0, 1), Page{Number: 1, Size: 1})
+				return fmt.Sprintf("%d %v", total, attendanceIDs(records)), err
+			},
+			want: "2 [a0]",
+		},
+		{
+			name: "history of an employee's days, newest first",
+			run: func(ctx context.Context, site Site) (string, error) {
+				records, total, err := site.Attendance().ListHistory(ctx, "e1", restDay.AddDate(0, 0, -1), restDay, Page{Number: 1, Size: 10})
+				return fmt.Sprintf("%d %v", total, attendanceIDs(records)), err
+			},
+			want:       "2 [a1 a0]",
+			collection: "attendance",
+			wantFilter: "employee_id='e1' && created_date>='2026-01-19 00:00:00' && created_date<'2026-01-21 00:00:00'",
+		},
+		{
+			name: "get",
+			run: func(ctx context.Context, site Site) (string, error) {
+				a, err := site.Attendance().Get(ctx, "a1")
+				if err != nil {
+					return "", err
+				}
+				return a.EmployeeID + " " + a.CheckInTime.Format(time.RFC3339), nil
+			},
+			want: "e1 2026-01-20T08:05:00Z",
+		},
+		{
+			name: "get a deleted record",
+			run: func(ctx context.Context, site Site) (string, error) {
+				_, err := site.Attendance().Get(ctx, "gone")
+				return "", err
+			},
+			wantErr: ErrNotFound,
+		},
+		{
+			name: "check out",
+			run: func(ctx context.Context, site Site) (string, error) {
+				return "", site.Attendance().UpdateCheckOut(ctx, &models.Attendance{ID: "a1", CreatedDate: restDay, CheckOutTime: restDay.Add(9 * time.Hour), CheckOutSource: "scanner"})
+			},
+			collection: "attendance",
+			wantBody:   map[string]any{"check_out_time": "2026-01-20T17:05:00Z", "check_out_source": "scanner", "needs_review": false},
+		},
+		{
+			name: "check out of a deleted record",
+			run: func(ctx context.Context, site Site) (string, error) {
+				return "", site.Attendance().UpdateCheckOut(ctx, &models.Attendance{ID: "gone", CreatedDate: restDay, CheckOutTime: restDay})
+			},
+			wantErr: ErrNotFound,
+		},
+	})
+
+	testFailures(t, []restCall{
+		{"Create", "attendance", true, func(ctx context.Context, site Site) (string, error) {
+			return "", site.Attendance().Create(ctx, &models.Attendance{EmployeeID: "e1", CheckInTime: restDay, CreatedDate: restDay})
+		}},
+		{"ListByDate", "attendance", true, func(ctx context.Context, site Site) (string, error) {
+			records, err := site.Attendance().ListByDate(ctx, restDay)
+			return fmt.Sprint(attendanceIDs(records)), err
+		}},
+		{"Get", "attendance", true, func(ctx context.Context, site Site) (string, error) {
+			_, err := site.Attendance().Get(ctx, "a1")
+			return "", err
+		}},
+		{"UpdateCheckOut", "attendance", false, func(ctx context.Context, site Site) (string, error) {
+			return "", site.Attendance().UpdateCheckOut(ctx, &models.Attendance{ID: "a1", CheckOutTime: restDay})
+		}},
+		{"locked period check", "locked_periods", true, func(ctx context.Context, site Site) (string, error) {
+			return "", site.Attendance().Create(ctx, &models.Attendance{EmployeeID: "e1", CheckInTime: restDay, CreatedDate: restDay})
+		}},
+	})
+}
+
+func attendanceIDs(records []models.Attendance) []string {
+	var ids []string
+	for _, a := range records {
+		ids = append(ids, a.ID)
+	}
+	return ids
+}
+
+func seedDetections(s *pbtest.Server) {
+	s.Seed("employee_detections",
+		pbtest.Record{"id": "d2", "employee_id": "e1", "mac_address": "aa:bb:cc:dd:ee:01", "scanner_mac": "scanner-2", "rssi": -70, "detected_at": restDay.Add(time.Hour).Format(time.RFC3339)},
+		pbtest.Record{"id": "d1", "employee_id": "e1", "mac_address": "aa:bb:cc:dd:ee:01", "scanner_mac": "scanner-1", "rssi": -50, "detected_at": restDay.Format(time.RFC3339)},
+		pbtest.Record{"id": "d0", "employee_id": "e2", "mac_address": "aa:bb:cc:dd:ee:02", "scanner_mac": "scanner-1", "rssi": -60, "detected_at": restDay.Format(time.RFC3339)},
+	)
+	s.Seed("locked_periods", pbtest.Record{"period": "2025-12", "locked": true})
+}
+
+func TestDetectionRepository(t *testing.T) {
+	testCases(t, seedDetections, []restCase{
+		{
+			name: "save",
+			run: func(ctx context.Context, site Site) (string, error) {
+				return "", site.Detections().Create(ctx, &models.EmployeeDetection{EmployeeID: "e1", MacAddress: "AA:BB:CC:DD:EE:01", ScannerMac: "scanner-1", RSSI: -55, DetectedAt: restDay})
+			},
+			collection: "employee_detections",
+			wantBody:   map[string]any{"employee_id": "e1", "mac_address": "aa:bb:cc:dd:ee:01", "rssi": -55, "detected_at": "2026-01-20T08:05:00Z"},
+		},
+		{
+			name: "save with a taken ID",
+			run: func(ctx context.Context, site Site) (string, error) {
+				return "", site.Detections().Create(ctx, &models.EmployeeDetection{ID: "d1", EmployeeID: "e1", DetectedAt: restDay})
+			},
+			wantErr: ErrBadRequest,
+		},
+		{
+			name: "employee's detections since",
+			run: func(ctx context.Context, site Site) (string, error) {
+				detections, err := site.Detections().ListByEmployeeSince(ctx, "e1", restDay)
+				return fmt.Sprint(detectionIDs(detections)), err
+			},
+			want:       "[d1 d2]",
+			collection: "employee_detections",
+			wantFilter: "employee_id='e1' && detected_at>='2026-01-20 08:05:00.000Z'",
+		},
+		{
+			name: "last of the day",
+			run: func(ctx context.Context, site Site) (string, error) {
+				det, err := site.Detections().GetLastForEmployeeOnDate(ctx, "e1", restDay)
+				if err != nil || det == nil {
+					return "", err
+				}
+				return fmt.Sprintf("%s %s %d", det.ID, det.ScannerMac, det.RSSI), nil
+			},
+			want: "d2 scanner-2 -70",
+		},
+		{
+			name: "none on the day",
+			run: func(ctx context.Context, site Site) (string, error) {
+				det, err := site.Detections().GetLastForEmployeeOnDate(ctx, "e1", restDay.AddDate(0, 0, 1))
+				return fmt.Sprint(det), err
+			},
+			want: "<nil>",
+		},
+		{
+			name: "relink a deleted record",
+			run: func(ctx context.Context, site Site) (string, error) {
+				return "", site.Detections().Relink(ctx, "gone", "e1")
+			},
+			wantErr: ErrNotFound,
+		},
+		{
+			name: "relink into a locked period",
+			run: func(ctx context.Context, site Site) (string, error) {
+				if err := site.PeriodLocks().Save(ctx, &models.LockedPeriod{Period: "2026-01", Locked: true}); err != nil {
+					return "", err
+				}
+				return "", site.Detections().Relink(ctx, "d2", "e2")
+			},
+			wantErr: ErrPeriodLocked,
+		},
+	})
+
+	testFailures(t, []restCall{
+		{"Create", "employee_detections", false, func(ctx context.Context, site Site) (string, error) {
+			return "", site.Detections().Create(ctx, &models.EmployeeDetection{EmployeeID: "e1", DetectedAt: restDay})
+		}},
+		{"ListRecent", "employee_detections", true, func(ctx context.Context, site Site) (string, error) {
+			detections, err := site.Detections().ListRecent(ctx, restDay)
+			return fmt.Sprint(detectionIDs(detections)), err
+		}},
+		{"GetLastForEmployeeOnDate", "employee_detections", true, func(ctx context.Context, site Site) (string, error) {
+			det, err := site.Detections().GetLastForEmployeeOnDate(ctx, "e1", restDay)
+			return fmt.Sprint(det), err
+		}},
+	})
+}
+
+func detectionIDs(detections []models.EmployeeDetection) []string {
+	var ids []string
+	for _, det := range detections {
+		ids = append(ids, det.ID)
+	}
+	return ids
+}
+
+func seedScanners(s *pbtest.Server) {
+	s.Seed("scanners",
+		pbtest.Record{"id": "s1", "scanner_mac": "AA-BB-CC-00-00-01", "name": "Ward 3", "last_seen": restDay.Format(time.RFC3339), "token": HashScannerToken("secret")},
+		pbtest.Record{"id": "s2", "scanner_mac": "aa:bb:cc:00:00:02", "last_seen": restDay.Add(time.Hour).Format(time.RFC3339)},
+	)
+}
+
+func TestScannerRepository(t *testing.T) {
+	testCases(t, seedScanners, []restCase{
+		{
+			name: "get by MAC stored in another spelling",
+			run: func(ctx context.Context, site Site) (string, error) {
+				sc, err := site.Scanners().GetByMac(ctx, "aa:bb:cc:00:00:01")
+				if err != nil {
+					return "", err
+				}
+				return sc.ID + " " + sc.Name, nil
+			},
+			want: "s1 Ward 3",
+		},
+		{
+			name: "unknown scanner",
+			run: func(ctx context.Context, site Site) (string, error) {
+				_, err := site.Scanners().GetByMac(ctx, "aa:bb:cc:00:00:09")
+				return "", err
+			},
+			wantErr: ErrScannerNotFound,
+		},
+		{
+			name: "get by token",
+			run: func(ctx context.Context, site Site) (string, error) {
+				sc, err := site.Scanners().GetByToken(ctx, "secret")
+				if err != nil {
+					return "", err
+				}
+				return sc.ID, nil
+			},
+			want:       "s1",
+			collection: "scanners",
+			wantFilter: "token='" + HashScannerToken("secret") + "'",
+		},
+		{
+			name: "wrong token",
+			run: func(ctx context.Context, site Site) (string, error) {
+				_, err := site.Scanners().GetByToken(ctx, "guess")
+				return "", err
+			},
+			wantErr: ErrScannerNotFound,
+		},
+		{
+			name: "activity of a known scanner rewrites its spelling",
+			run: func(ctx context.Context, site Site) (string, error) {
+				return "", site.Scanners().UpdateActivity(ctx, "AA:BB:CC:00:00:01", models.ScannerHealth{FirmwareVersion: "1.4.0"})
+			},
+			collection: "scanners",
+			wantBody:   map[string]any{"scanner_mac": "aa:bb:cc:00:00:01", "firmware_version": "1.4.0"},
+		},
+		{
+			name: "activity of a new scanner",
+			run: func(ctx context.Context, site Site) (string, error) {
+				if err := site.Scanners().UpdateActivity(ctx, "aa:bb:cc:00:00:03", models.ScannerHealth{}); err != nil {
+					return "", err
+				}
+				scanners, err := site.Scanners().List(ctx)
+				return fmt.Sprint(len(scanners)), err
+			},
+			want: "3",
+		},
+		{
+			name: "list by last seen",
+			run: func(ctx context.Context, site Site) (string, error) {
+				scanners, err := site.Scanners().List(ctx)
+				var ids []string
+				for _, sc := range scanners {
+					ids = append(ids, sc.ID)
+				}
+				return fmt.Sprint(ids), err
+			},
+			want: "[s2 s1]",
+		},
+		{
+			name: "rewrite the MAC of a deleted record",
+			run: func(ctx context.Context, site Site) (string, error) {
+				return "", site.Scanners().SetMac(ctx, "gone", "aa:bb:cc:00:00:01")
+			},
+			wantErr: ErrNotFound,
+		},
+	})
+
+	testFailures(t, []restCall{
+		{"GetByMac", "scanners", true, func(ctx context.Context, site Site) (string, error) {
+			_, err := site.Scanners().GetByMac(ctx, "aa:bb:cc:00:00:01")
+			return "", err
+		}},
+		{"UpdateActivity", "scanners", true, func(ctx context.Context, site Site) (string, error) {
+			return "", site.Scanners().UpdateActivity(ctx, "aa:bb:cc:00:00:01", models.ScannerHealth{})
+		}},
+		{"List", "scanners", true, func(ctx context.Context, site Site) (string, error) {
+			scanners, err := site.Scanners().List(ctx)
+			return fmt.Sprint(len(scanners)), err
+		}},
+	})
+}
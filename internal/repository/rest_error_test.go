@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/pbclient"
+)
+
+func TestRepositoryErrorsWrapAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"items":[]}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":400,"message":"Failed to create record.","data":{"employee_id":{"code":"validation_missing_rel_records","message":"Failed to find all relation records with the provided ids."}}}`))
+		}
+	}))
+	defer srv.Close()
+
+	now := time.Now()
+	err := Site{URL: srv.URL}.Attendance().Create(context.Background(), &models.Attendance{EmployeeID: "gone", CheckInTime: now, CreatedDate: now})
+
+	var apiErr *pbclient.APIError
+	if !errors.As(err, &apiErr) || apiErr.Collection != "attendance" || apiErr.Operation != "create" {
+		t.Fatalf("err = %#v, want an APIError for attendance create", err)
+	}
+	if !errors.Is(err, pbclient.ErrValidationFailed) {
+		t.Errorf("errors.Is(err, pbclient.ErrValidationFailed) = false for %v", err)
+	}
+	if want := "failed to create attendance: 400 employee_id: validation_missing_rel_records"; err.Error() != want {
+		t.Errorf("err = %q, want %q", err, want)
+	}
+}
@@ -0,0 +1,267 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"syscall"
+	"time"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/pbclient"
+)
+
+// RetryPolicy is how often and how patiently a PocketBase call is retried
+type RetryPolicy struct {
+	Attempts int           // calls in total; 1 does not retry
+	Initial  time.Duration // wait before the first retry, doubled before each next one
+	Max      time.Duration // longest wait between two attempts
+}
+
+// DefaultRetryPolicy makes 3 attempts, waiting 200ms and then 400ms, never over 2s
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{Attempts: 3, Initial: 200 * time.Millisecond, Max: 2 * time.Second}
+}
+
+// Retryable reports whether err is a connection error or a 5xx response, which a later
+// attempt may not get. Rejections (4xx) and cancellations by the caller are final.
+func Retryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *pbclient.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Status >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// Do runs op until it succeeds, fails with an error that is not Retryable, or runs out
+// of attempts, in which case the error wraps ErrUnavailable. attempt counts from 1.
+func (p RetryPolicy) Do(ctx context.Context, name string, op func(attempt int) error) error {
+	wait := p.Initial
+	for attempt := 1; ; attempt++ {
+		err := op(attempt)
+		if err == nil || !Retryable(err) {
+			return err
+		}
+		if attempt >= p.Attempts {
+			if errors.Is(err, ErrUnavailable) {
+				return fmt.Errorf("%s failed %d times: %w", name, attempt, err)
+			}
+			return fmt.Errorf("%s failed %d times: %w: %w", name, attempt, ErrUnavailable, err)
+		}
+
+		log.Printf("🔁 %s failed (attempt %d/%d), retrying in %s: %v", name, attempt, p.Attempts, wait, err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%s cancelled while retrying: %w", name, ctx.Err())
+		case <-timer.C:
+		}
+		if wait = 2 * wait; wait > p.Max {
+			wait = p.Max
+		}
+	}
+}
+
+// NewRecordID returns a random PocketBase record ID, 15 lower-case letters and digits
+func NewRecordID() string {
+	const alphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+	b := make([]byte, 15)
+	rand.Read(b)
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+	return string(b)
+}
+
+// storedByEarlierAttempt reports whether a retried create was refused because its
+// record ID already exists, i.e. an attempt whose response was lost did store it
+func storedByEarlierAttempt(attempt int, err error) bool {
+	var apiErr *pbclient.APIError
+	if attempt == 1 || !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, f := range apiErr.Fields {
+		if f.Field == "id" {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryingAttendanceRepository retries the attendance calls of next that fail on a
+// connection error or a 5xx response. Check-ins are created with an ID chosen up front,
+// so a retry after a lost response cannot record the check-in twice.
+type RetryingAttendanceRepository struct {
+	next   VersionableAttendance
+	policy RetryPolicy
+}
+
+// NewRetryingAttendanceRepository retries the calls to next by policy
+func NewRetryingAttendanceRepository(next VersionableAttendance, policy RetryPolicy) *RetryingAttendanceRepository {
+	return &RetryingAttendanceRepository{next: next, policy: policy}
+}
+
+// Create records the check-in, retrying with the same record ID
+func (r *RetryingAttendanceRepository) Create(ctx context.Context, attendance *models.Attendance) error {
+	if attendance.ID == "" {
+		attendance.ID = NewRecordID()
+	}
+	id := attendance.ID
+	return r.policy.Do(ctx, "attendance create", func(attempt int) error {
+		attendance.ID = id
+		err := r.next.Create(ctx, attendance)
+		if storedByEarlierAttempt(attempt, err) {
+			attendance.ID = id
+			return nil
+		}
+		return err
+	})
+}
+
+// UpdateCheckOut writes the check-out, retrying; writing it twice changes nothing
+func (r *RetryingAttendanceRepository) UpdateCheckOut(ctx context.Context, attendance *models.Attendance) error {
+	return r.policy.Do(ctx, "attendance check-out update", func(int) error {
+		return r.next.UpdateCheckOut(ctx, attendance)
+	})
+}
+
+// ListByDate is retried
+func (r *RetryingAttendanceRepository) ListByDate(ctx context.Context, date time.Time) (records []models.Attendance, err error) {
+	err = r.policy.Do(ctx, "attendance list", func(int) error {
+		records, err = r.next.ListByDate(ctx, date)
+		return err
+	})
+	return records, err
+}
+
+// ListSince is retried
+func (r *RetryingAttendanceRepository) ListSince(ctx context.Context, since time.Time) (records []models.Attendance, err error) {
+	err = r.policy.Do(ctx, "attendance list", func(int) error {
+		records, err = r.next.ListSince(ctx, since)
+		return err
+	})
+	return records, err
+}
+
+// ListBetween is retried
+func (r *RetryingAttendanceRepository) ListBetween(ctx context.Context, from, to time.Time) (records []models.Attendance, err error) {
+	err = r.policy.Do(ctx, "attendance list", func(int) error {
+		records, err = r.next.ListBetween(ctx, from, to)
+		return err
+	})
+	return records, err
+}
+
+// ListOpenForDate is retried
+func (r *RetryingAttendanceRepository) ListOpenForDate(ctx context.Context, date time.Time) (records []models.Attendance, err error) {
+	err = r.policy.Do(ctx, "attendance list", func(int) error {
+		records, err = r.next.ListOpenForDate(ctx, date)
+		return err
+	})
+	return records, err
+}
+
+// ListByEmployeeAndRange is retried
+func (r *RetryingAttendanceRepository) ListByEmployeeAndRange(ctx context.Context, employeeID string, from, to time.Time) (records []models.Attendance, err error) {
+	err = r.policy.Do(ctx, "attendance list", func(int) error {
+		records, err = r.next.ListByEmployeeAndRange(ctx, employeeID, from, to)
+		return err
+	})
+	return records, err
+}
+
+// GetTodayByEmployee is retried
+func (r *RetryingAttendanceRepository) GetTodayByEmployee(ctx context.Context, employeeID string) (record *models.Attendance, err error) {
+	err = r.policy.Do(ctx, "attendance get", func(int) error {
+		record, err = r.next.GetTodayByEmployee(ctx, employeeID)
+		return err
+	})
+	return record, err
+}
+
+// GetByEmployeeOn is retried
+func (r *RetryingAttendanceRepository) GetByEmployeeOn(ctx context.Context, employeeID string, day time.Time) (record *models.Attendance, err error) {
+	err = r.policy.Do(ctx, "attendance get", func(int) error {
+		record, err = r.next.GetByEmployeeOn(ctx, employeeID, day)
+		return err
+	})
+	return record, err
+}
+
+// Get is retried
+func (r *RetryingAttendanceRepository) Get(ctx context.Context, id string) (record *models.Attendance, err error) {
+	err = r.policy.Do(ctx, "attendance get", func(int) error {
+		record, err = r.next.Get(ctx, id)
+		return err
+	})
+	return record, err
+}
+
+// RetryableDetections is what RetryingDetectionRepository wraps
+type RetryableDetections interface {
+	EmployeeDetectionRepository
+	DetectionLog
+}
+
+// RetryingDetectionRepository retries the detection calls of next that fail on a
+// connection error or a 5xx response, creating each detection with an ID chosen up front
+type RetryingDetectionRepository struct {
+	next   RetryableDetections
+	policy RetryPolicy
+}
+
+// NewRetryingDetectionRepository retries the calls to next by policy
+func NewRetryingDetectionRepository(next RetryableDetections, policy RetryPolicy) *RetryingDetectionRepository {
+	return &RetryingDetectionRepository{next: next, policy: policy}
+}
+
+// Create saves the detection, retrying with the same record ID
+func (r *RetryingDetectionRepository) Create(ctx context.Context, detection *models.EmployeeDetection) error {
+	if detection.ID == "" {
+		detection.ID = NewRecordID()
+	}
+	return r.policy.Do(ctx, "detection create", func(attempt int) error {
+		err := r.next.Create(ctx, detection)
+		if storedByEarlierAttempt(attempt, err) {
+			return nil
+		}
+		return err
+	})
+}
+
+// ListByEmployeeSince is retried
+func (r *RetryingDetectionRepository) ListByEmployeeSince(ctx context.Context, employeeID string, since time.Time) (detections []models.EmployeeDetection, err error) {
+	err = r.policy.Do(ctx, "detection list", func(int) error {
+		detections, err = r.next.ListByEmployeeSince(ctx, employeeID, since)
+		return err
+	})
+	return detections, err
+}
+
+// GetLastForEmployeeOnDate is retried
+func (r *RetryingDetectionRepository) GetLastForEmployeeOnDate(ctx context.Context, employeeID string, date time.Time) (detection *models.EmployeeDetection, err error) {
+	err = r.policy.Do(ctx, "detection get", func(int) error {
+		detection, err = r.next.GetLastForEmployeeOnDate(ctx, employeeID, date)
+		return err
+	})
+	return detection, err
+}
+
+// ListRecent is retried
+func (r *RetryingDetectionRepository) ListRecent(ctx context.Context, since time.Time) (detections []models.EmployeeDetection, err error) {
+	err = r.policy.Do(ctx, "detection list", func(int) error {
+		detections, err = r.next.ListRecent(ctx, since)
+		return err
+	})
+	return detections, err
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/pbclient"
+)
+
+// testRetryPolicy retries like the default, without the waiting
+var testRetryPolicy = RetryPolicy{Attempts: 3, Initial: time.Millisecond, Max: 2 * time.Millisecond}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection refused", fmt.Errorf("failed to create attendance: %w", &net.OpError{Op: "dial", Err: errors.New("connection refused")}), true},
+		{"server error", fmt.Errorf("failed to create attendance: %w", &pbclient.APIError{Status: 503}), true},
+		{"validation", fmt.Errorf("failed to create attendance: %w", &pbclient.APIError{Status: 400}), false},
+		{"period locked", ErrPeriodLocked, false},
+		{"cancelled", context.Canceled, false},
+		{"success", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Retryable(tt.err); got != tt.want {
+				t.Errorf("Retryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	unavailable := &pbclient.APIError{Status: 502}
+	rejected := &pbclient.APIError{Status: 400}
+
+	tests := []struct {
+		name            string
+		errs            []error // one per attempt, nil once it succeeds
+		wantCalls       int
+		wantUnavailable bool
+		wantErr         bool
+	}{
+		{"succeeds at once", []error{nil}, 1, false, false},
+		{"succeeds on retry", []error{unavailable, unavailable, nil}, 3, false, false},
+		{"gives up", []error{unavailable, unavailable, unavailable}, 3, true, true},
+		{"rejection is final", []error{rejected}, 1, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := testRetryPolicy.Do(context.Background(), "test", func(attempt int) error {
+				calls++
+				return tt.errs[attempt-1]
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if (err != nil) != tt.wantErr || errors.Is(err, ErrUnavailable) != tt.wantUnavailable {
+				t.Errorf("Do() = %v, want error %v, ErrUnavailable %v", err, tt.wantErr, tt.wantUnavailable)
+			}
+		})
+	}
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		policy := RetryPolicy{Attempts: 3, Initial: time.Hour, Max: time.Hour}
+		err := policy.Do(ctx, "test", func(int) error {
+			cancel()
+			return unavailable
+		})
+		if !errors.Is(err, context.Canceled) || errors.Is(err, ErrUnavailable) {
+			t.Errorf("Do() = %v, want context.Canceled", err)
+		}
+	})
+}
+
+// flakyAttendance fails creates with the given errors, storing the record on the
+// attempts listed in stored as a server whose response got lost would
+type flakyAttendance struct {
+	VersionableAttendance
+	errs    []error
+	stored  map[int]bool
+	ids     []string
+	created int
+}
+
+func (f *flakyAttendance) Create(ctx context.Context, attendance *models.Attendance) error {
+	attempt := len(f.ids)
+	f.ids = append(f.ids, attendance.ID)
+	if f.stored[attempt] || f.errs[attempt] == nil {
+		f.created++
+	}
+	return f.errs[attempt]
+}
+
+func TestRetryingAttendanceCreate(t *testing.T) {
+	lost := &pbclient.APIError{Status: 504}
+	duplicateID := &pbclient.APIError{Status: 400, Fields: []pbclient.FieldError{{Field: "id", Code: "validation_pk_invalid"}}}
+
+	tests := []struct {
+		name        string
+		errs        []error
+		stored      map[int]bool
+		wantErr     bool
+		wantCreated int
+	}{
+		{"retried after a blip", []error{lost, nil}, nil, false, 1},
+		{"response of the stored attempt lost", []error{lost, duplicateID}, map[int]bool{0: true}, false, 1},
+		{"duplicate ID on the first attempt is a real error", []error{duplicateID}, nil, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &flakyAttendance{errs: tt.errs, stored: tt.stored}
+			repo := NewRetryingAttendanceRepository(next, testRetryPolicy)
+			att := &models.Attendance{EmployeeID: "e1"}
+
+			err := repo.Create(context.Background(), att)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Create() error = %v, want error %v", err, tt.wantErr)
+			}
+			if next.created != tt.wantCreated {
+				t.Errorf("stored %d times, want %d", next.created, tt.wantCreated)
+			}
+			for _, id := range next.ids {
+				if id == "" || id != next.ids[0] || id != att.ID {
+					t.Errorf("record IDs = %q, attendance ID %q, want one ID throughout", next.ids, att.ID)
+				}
+			}
+		})
+	}
+}
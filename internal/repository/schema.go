@@ -0,0 +1,432 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/pbclient"
+)
+
+// SchemaVersion describes the fields introduced by one schema migration
+type SchemaVersion struct {
+	Version int
+	Name    string
+	Fields  map[string][]string // collection -> fields added by this version
+}
+
+// SchemaVersions lists every known migration in order. Fields from versions after the
+// first are optional: repositories only write them once the live schema has them.
+var SchemaVersions = []SchemaVersion{
+	{
+		Version: 1,
+		Name:    "initial_collections",
+		Fields: map[string][]string{
+			"employees":           {"mac_address", "telegram_chat_id", "name", "work_start_time", "is_active"},
+			"attendance":          {"employee_id", "check_in_time", "scanner_mac", "status", "created_date"},
+			"employee_detections": {"employee_id", "mac_address", "scanner_mac", "rssi", "device_type", "is_itag03", "detected_at"},
+			"scanners":            {"scanner_mac", "last_seen"},
+		},
+	},
+	{
+		Version: 2,
+		Name:    "add_target_device_fields",
+		Fields: map[string][]string{
+			"employee_detections": {"is_target_device", "device_name"},
+		},
+	},
+	{
+		Version: 3,
+		Name:    "add_public_board_fields",
+		Fields: map[string][]string{
+			"employees": {"display_name", "show_on_board"},
+		},
+	},
+	{
+		Version: 4,
+		Name:    "add_checkin_baselines",
+		Fields: map[string][]string{
+			"checkin_baselines": {"employee_id", "samples", "median_minutes", "mad_minutes"},
+		},
+	},
+	{
+		Version: 5,
+		Name:    "add_attendance_source",
+		Fields: map[string][]string{
+			"attendance": {"source"},
+		},
+	},
+	{
+		Version: 6,
+		Name:    "add_checkout_reminders",
+		Fields: map[string][]string{
+			"employees":  {"work_end_time", "muted_notifications"},
+			"attendance": {"check_out_source", "needs_review"},
+		},
+	},
+	{
+		Version: 7,
+		Name:    "add_synthetic_employees",
+		Fields: map[string][]string{
+			"employees": {"is_synthetic"},
+		},
+	},
+	{
+		Version: 8,
+		Name:    "add_scanner_pairing",
+		Fields: map[string][]string{
+			"scanners": {"zone", "paired_at"},
+		},
+	},
+	{
+		Version: 9,
+		Name:    "add_locked_periods",
+		Fields: map[string][]string{
+			"locked_periods": {"period", "locked", "locked_at", "history"},
+		},
+	},
+	{
+		Version: 10,
+		Name:    "add_instance_lease",
+		Fields: map[string][]string{
+			"instance_lease": {"name", "term", "holder", "expires_at"},
+		},
+	},
+	{
+		Version: 11,
+		Name:    "add_presence_consent",
+		Fields: map[string][]string{
+			"employees":     {"presence_tracking_consent"},
+			"privacy_audit": {"employee_id", "consent", "changed_by", "changed_at"},
+		},
+	},
+	{
+		Version: 12,
+		Name:    "add_local_queue",
+		Fields: map[string][]string{
+			"local_queue": {"key", "seq", "data"},
+		},
+	},
+	{
+		Version: 13,
+		Name:    "add_audit_log",
+		Fields: map[string][]string{
+			"audit_log": {"action", "target_id", "actor_id", "actor_name", "details", "at"},
+		},
+	},
+	{
+		Version: 14,
+		Name:    "add_employee_leave",
+		Fields: map[string][]string{
+			"employees": {"leave_until"},
+		},
+	},
+	{
+		Version: 15,
+		Name:    "add_attendance_snapshot",
+		Fields: map[string][]string{
+			"attendance": {"employee_name", "employee_code", "department"},
+		},
+	},
+	{
+		Version: 16,
+		Name:    "add_late_approvals",
+		Fields: map[string][]string{
+			"late_approvals": {"employee_id", "date", "expected_time", "reason", "status", "requested_at",
+				"decided_by", "decider_name", "decided_at"},
+		},
+	},
+	{
+		Version: 17,
+		Name:    "add_scanner_profiles",
+		Fields: map[string][]string{
+			"scanners": {"profile", "overrides"},
+		},
+	},
+	{
+		Version: 18,
+		Name:    "add_guests",
+		Fields: map[string][]string{
+			"employees": {"is_guest", "guest_until"},
+		},
+	},
+	{
+		Version: 19,
+		Name:    "add_scanner_tokens",
+		Fields: map[string][]string{
+			"scanners": {"token"},
+		},
+	},
+	{
+		Version: 20,
+		Name:    "add_grace_periods",
+		Fields: map[string][]string{
+			"employees": {"grace_period_minutes"},
+		},
+	},
+	{
+		Version: 21,
+		Name:    "add_holidays",
+		Fields: map[string][]string{
+			"holidays": {"date", "name"},
+		},
+	},
+	{
+		Version: 22,
+		Name:    "add_employee_devices",
+		Fields: map[string][]string{
+			"employee_devices": {"employee_id", "mac_address", "label", "is_primary"},
+		},
+	},
+	{
+		Version: 23,
+		Name:    "add_ibeacon",
+		Fields: map[string][]string{
+			"employees":           {"ibeacon_id"},
+			"employee_detections": {"ibeacon_uuid", "major", "minor"},
+		},
+	},
+	{
+		Version: 24,
+		Name:    "add_eddystone",
+		Fields: map[string][]string{
+			"employees":           {"eddystone_id"},
+			"employee_detections": {"namespace_id", "instance_id"},
+		},
+	},
+	{
+		Version: 25,
+		Name:    "add_scanner_health",
+		Fields: map[string][]string{
+			"scanners": {"firmware_version", "uptime_seconds", "free_heap"},
+		},
+	},
+	{
+		Version: 26,
+		Name:    "add_notification_failures",
+		Fields: map[string][]string{
+			"notification_failures": {"chat_id", "text", "error", "attempts", "failed_at"},
+		},
+	},
+	{
+		Version: 27,
+		Name:    "add_notification_prefs",
+		Fields: map[string][]string{
+			"employees": {"notify_checkin", "notify_quiet_hours"},
+		},
+	},
+	{
+		Version: 28,
+		Name:    "add_employee_language",
+		Fields: map[string][]string{
+			"employees": {"language"},
+		},
+	},
+	{
+		Version: 29,
+		Name:    "add_scanner_names",
+		Fields: map[string][]string{
+			"scanners": {"name", "location"},
+		},
+	},
+	{
+		Version: 30,
+		Name:    "add_device_sightings",
+		Fields: map[string][]string{
+			"devices": {"detection_count"},
+		},
+	},
+	{
+		Version: 31,
+		Name:    "add_device_review",
+		Fields: map[string][]string{
+			"devices": {"is_reviewed"},
+		},
+	},
+}
+
+// SchemaCapabilities is the set of fields the live PocketBase schema actually has.
+// It is read once at startup and refreshed on demand (SIGHUP, doctor command).
+type SchemaCapabilities struct {
+	client *pbclient.Client
+
+	mu       sync.RWMutex
+	fields   map[string]map[string]bool // collection -> field set; nil until loaded
+	loadedAt time.Time
+	warned   map[string]bool
+}
+
+// NewSchemaCapabilities creates an empty capability set; call Refresh to load it
+func NewSchemaCapabilities(baseURL, authToken string) *SchemaCapabilities {
+	return &SchemaCapabilities{
+		client: pbclient.New(baseURL, authToken, "", siteTransport(baseURL, authToken)),
+		warned: make(map[string]bool),
+	}
+}
+
+// Refresh re-reads the fields of every known collection from PocketBase
+func (c *SchemaCapabilities) Refresh(ctx context.Context) error {
+	fields := make(map[string]map[string]bool)
+	for _, collection := range knownCollections() {
+		names, err := c.fetchFields(ctx, collection)
+		if err != nil {
+			return fmt.Errorf("failed to read schema of %s: %w", collection, err)
+		}
+		set := make(map[string]bool, len(names))
+		for _, n := range names {
+			set[n] = true
+		}
+		fields[collection] = set
+	}
+
+	c.mu.Lock()
+	c.fields = fields
+	c.loadedAt = time.Now()
+	c.warned = make(map[string]bool)
+	c.mu.Unlock()
+
+	log.Printf("🧬 Schema capabilities loaded: version %d", c.Version())
+	return nil
+}
+
+func (c *SchemaCapabilities) fetchFields(ctx context.Context, collection string) ([]string, error) {
+	// PocketBase >= 0.23 uses "fields", older versions "schema"
+	var result struct {
+		Fields []struct {
+			Name string `json:"name"`
+		} `json:"fields"`
+		Schema []struct {
+			Name string `json:"name"`
+		} `json:"schema"`
+	}
+	err := c.client.Schema(ctx, collection, &result)
+	// A collection added by a later migration simply has no fields yet
+	if errors.Is(err, pbclient.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, f := range append(result.Fields, result.Schema...) {
+		names = append(names, f.Name)
+	}
+	return names, nil
+}
+
+// Loaded reports whether the live schema has been read successfully
+func (c *SchemaCapabilities) Loaded() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.fields != nil
+}
+
+// Has reports whether the live schema has the field. Before the first successful
+// refresh every field is assumed present, which matches the pre-check behaviour.
+func (c *SchemaCapabilities) Has(collection, field string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.fields == nil {
+		return true
+	}
+	return c.fields[collection][field]
+}
+
+// Version returns the highest schema version whose fields (and all earlier ones) are present
+func (c *SchemaCapabilities) Version() int {
+	version := 0
+	for _, v := range SchemaVersions {
+		for collection, fields := range v.Fields {
+			for _, f := range fields {
+				if !c.Has(collection, f) {
+					return version
+				}
+			}
+		}
+		version = v.Version
+	}
+	return version
+}
+
+// Missing returns the known fields absent from the live schema, as "collection.field"
+func (c *SchemaCapabilities) Missing() []string {
+	var missing []string
+	for _, v := range SchemaVersions {
+		for collection, fields := range v.Fields {
+			for _, f := range fields {
+				if !c.Has(collection, f) {
+					missing = append(missing, fmt.Sprintf("%s.%s (v%d %s)", collection, f, v.Version, v.Name))
+				}
+			}
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// LoadedAt returns when the schema was last read
+func (c *SchemaCapabilities) LoadedAt() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.loadedAt
+}
+
+// filterOptional removes optional fields the live schema does not have from data,
+// logging a single warning per field until the next refresh
+func (c *SchemaCapabilities) filterOptional(collection string, data map[string]interface{}) {
+	if c == nil {
+		return
+	}
+	for _, field := range optionalFields(collection) {
+		if _, ok := data[field]; !ok || c.Has(collection, field) {
+			continue
+		}
+		delete(data, field)
+
+		key := collection + "." + field
+		c.mu.Lock()
+		if !c.warned[key] {
+			c.warned[key] = true
+			log.Printf("⚠️  Schema has no %s yet (run migrations); not writing it", key)
+		}
+		c.mu.Unlock()
+	}
+}
+
+// knownCollections returns every collection mentioned by SchemaVersions
+func knownCollections() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, v := range SchemaVersions {
+		for collection := range v.Fields {
+			if !seen[collection] {
+				seen[collection] = true
+				names = append(names, collection)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// optionalFields returns fields of a collection introduced after the initial schema
+func optionalFields(collection string) []string {
+	var fields []string
+	for _, v := range SchemaVersions[1:] {
+		fields = append(fields, v.Fields[collection]...)
+	}
+	return fields
+}
+
+// schema is the capability set consulted by repositories when writing; nil disables filtering
+var schema *SchemaCapabilities
+
+// SetSchemaCapabilities makes repositories write optional fields only when the live schema has them
+func SetSchemaCapabilities(c *SchemaCapabilities) {
+	schema = c
+}
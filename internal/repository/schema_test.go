@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/models"
+)
+
+// fakeSchemaServer serves collection schemas and records detection writes
+type fakeSchemaServer struct {
+	mu          sync.Mutex
+	fields      map[string][]string
+	lastWritten map[string]interface{}
+}
+
+func (f *fakeSchemaServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/collections/"):
+		name := strings.TrimPrefix(r.URL.Path, "/api/collections/")
+		var fields []map[string]string
+		for _, n := range f.fields[name] {
+			fields = append(fields, map[string]string{"name": n})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"name": name, "fields": fields})
+
+	case r.Method == http.MethodPost && r.URL.Path == "/api/collections/employee_detections/records":
+		json.NewDecoder(r.Body).Decode(&f.lastWritten)
+		w.Write([]byte(`{"id":"det1"}`))
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func baseSchema() map[string][]string {
+	fields := make(map[string][]string)
+	for collection, names := range SchemaVersions[0].Fields {
+		fields[collection] = append([]string(nil), names...)
+	}
+	return fields
+}
+
+func TestSchemaCapabilities(t *testing.T) {
+	withTarget := baseSchema()
+	withTarget["employee_detections"] = append(withTarget["employee_detections"], "is_target_device", "device_name")
+
+	tests := []struct {
+		name        string
+		fields      map[string][]string
+		wantVersion int
+		wantWritten bool
+	}{
+		{"Before migration", baseSchema(), 1, false},
+		{"After migration", withTarget, 2, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeSchemaServer{fields: tt.fields}
+			server := httptest.NewServer(fake)
+			defer server.Close()
+
+			caps := NewSchemaCapabilities(server.URL, "token")
+			if err := caps.Refresh(context.Background()); err != nil {
+				t.Fatalf("Refresh() error = %v", err)
+			}
+			if got := caps.Version(); got != tt.wantVersion {
+				t.Errorf("Version() = %d, want %d", got, tt.wantVersion)
+			}
+
+			SetSchemaCapabilities(caps)
+			defer SetSchemaCapabilities(nil)
+
+			repo := NewPocketBaseRESTDetectionRepository(server.URL)
+			err := repo.Create(context.Background(), &models.EmployeeDetection{
+				EmployeeID:     "emp1",
+				MacAddress:     "aa:bb:cc:dd:ee:ff",
+				IsTargetDevice: true,
+				DeviceName:     "Somchai",
+				DetectedAt:     time.Now(),
+			})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+
+			_, hasTarget := fake.lastWritten["is_target_device"]
+			_, hasName := fake.lastWritten["device_name"]
+			if hasTarget != tt.wantWritten || hasName != tt.wantWritten {
+				t.Errorf("optional fields written = %v/%v, want %v", hasTarget, hasName, tt.wantWritten)
+			}
+			if fake.lastWritten["mac_address"] != "aa:bb:cc:dd:ee:ff" {
+				t.Errorf("base field mac_address not written: %v", fake.lastWritten)
+			}
+		})
+	}
+}
+
+func TestSchemaCapabilitiesUnloadedAssumesLatest(t *testing.T) {
+	caps := NewSchemaCapabilities("http://127.0.0.1:0", "")
+	if caps.Loaded() {
+		t.Fatal("Loaded() = true before Refresh")
+	}
+	if !caps.Has("employee_detections", "device_name") {
+		t.Error("Has() = false before Refresh, want true")
+	}
+	if got, want := caps.Version(), SchemaVersions[len(SchemaVersions)-1].Version; got != want {
+		t.Errorf("Version() = %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"os"
+
+	"med-pulse-bot/internal/pbclient"
+)
+
+// Site is one PocketBase backend. Tenants either get their own PocketBase (URL and
+// token) or share one and keep their data apart with a collection prefix.
+type Site struct {
+	URL    string
+	Token  string
+	Prefix string // prepended to every collection name, e.g. "clinic_a_"
+}
+
+// DefaultSite is the single-site backend at baseURL using POCKETBASE_TOKEN
+func DefaultSite(baseURL string) Site {
+	return Site{URL: baseURL, Token: os.Getenv("POCKETBASE_TOKEN")}
+}
+
+// client is the PocketBase client of this site. It goes through the default auth
+// manager when that manager serves the site.
+func (s Site) client() *pbclient.Client {
+	return pbclient.New(s.URL, s.Token, s.Prefix, siteTransport(s.URL, s.Token))
+}
+
+// Employees creates an employee repository bound to this site
+func (s Site) Employees() *PocketBaseRESTEmployeeRepository {
+	return &PocketBaseRESTEmployeeRepository{
+		client: s.client(),
+	}
+}
+
+// Attendance creates an attendance repository bound to this site
+func (s Site) Attendance() *PocketBaseRESTAttendanceRepository {
+	return &PocketBaseRESTAttendanceRepository{
+		client: s.client(),
+	}
+}
+
+// Detections creates an employee detection repository bound to this site
+func (s Site) Detections() *PocketBaseRESTDetectionRepository {
+	return &PocketBaseRESTDetectionRepository{
+		client: s.client(),
+	}
+}
+
+// Scanners creates a scanner repository bound to this site
+func (s Site) Scanners() *PocketBaseRESTScannerRepository {
+	return &PocketBaseRESTScannerRepository{
+		client: s.client(),
+	}
+}
+
+// Baselines creates a check-in baseline repository bound to this site
+func (s Site) Baselines() *PocketBaseRESTBaselineRepository {
+	return &PocketBaseRESTBaselineRepository{
+		client: s.client(),
+	}
+}
+
+// SelfTest creates a self-test repository bound to this site
+func (s Site) SelfTest() *PocketBaseRESTSelfTestRepository {
+	return &PocketBaseRESTSelfTestRepository{
+		client: s.client(),
+	}
+}
+
+// PeriodLocks creates a locked payroll period repository bound to this site
+func (s Site) PeriodLocks() *PocketBaseRESTPeriodLockRepository {
+	return &PocketBaseRESTPeriodLockRepository{
+		client: s.client(),
+	}
+}
+
+// Leases creates an instance lease repository bound to this site
+func (s Site) Leases() *PocketBaseRESTLeaseRepository {
+	return &PocketBaseRESTLeaseRepository{
+		client: s.client(),
+	}
+}
+
+// LocalStore creates a local state store on this site's local_queue collection
+func (s Site) LocalStore() *PocketBaseRESTLocalStore {
+	return &PocketBaseRESTLocalStore{
+		client: s.client(),
+	}
+}
+
+// AuditLog creates an audit log repository bound to this site
+func (s Site) AuditLog() *PocketBaseRESTAuditLogRepository {
+	return &PocketBaseRESTAuditLogRepository{
+		client: s.client(),
+	}
+}
+
+// Devices creates an employee device repository bound to this site
+func (s Site) Devices() *PocketBaseRESTDeviceRepository {
+	return &PocketBaseRESTDeviceRepository{
+		client: s.client(),
+	}
+}
+
+// DeviceSightings creates a repository of unknown device sightings bound to this site
+func (s Site) DeviceSightings() *PocketBaseRESTDeviceSightingRepository {
+	return &PocketBaseRESTDeviceSightingRepository{
+		client: s.client(),
+	}
+}
+
+// Holidays creates a holiday repository bound to this site
+func (s Site) Holidays() *PocketBaseRESTHolidayRepository {
+	return &PocketBaseRESTHolidayRepository{
+		client: s.client(),
+	}
+}
+
+// PrivacyAudit creates a consent change audit repository bound to this site
+func (s Site) PrivacyAudit() *PocketBaseRESTPrivacyAuditRepository {
+	return &PocketBaseRESTPrivacyAuditRepository{
+		client: s.client(),
+	}
+}
+
+// LateApprovals creates a late arrival approval repository bound to this site
+func (s Site) LateApprovals() *PocketBaseRESTLateApprovalRepository {
+	return &PocketBaseRESTLateApprovalRepository{
+		client: s.client(),
+	}
+}
+
+// NotificationFailures creates a repository of undelivered bot messages bound to this site
+func (s Site) NotificationFailures() *PocketBaseRESTNotificationFailureRepository {
+	return &PocketBaseRESTNotificationFailureRepository{
+		client: s.client(),
+	}
+}
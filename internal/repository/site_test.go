@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/models"
+)
+
+func TestSiteIsolation(t *testing.T) {
+	var mu sync.Mutex
+	var paths, tokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		tokens = append(tokens, r.Header.Get("Authorization"))
+		mu.Unlock()
+		w.Write([]byte(`{"id":"rec1","items":[],"totalPages":1}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		site      Site
+		wantPath  string
+		wantToken string
+	}{
+		{"default site", Site{URL: server.URL, Token: "root"}, "/api/collections/attendance/records", "root"},
+		{"prefixed tenant", Site{URL: server.URL, Token: "tok-a", Prefix: "clinic_a_"}, "/api/collections/clinic_a_attendance/records", "tok-a"},
+		{"second tenant", Site{URL: server.URL + "/", Token: "tok-b", Prefix: "clinic_b_"}, "/api/collections/clinic_b_attendance/records", "tok-b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, tokens = nil, nil
+			ctx := context.Background()
+
+			if _, err := tt.site.Attendance().ListByDate(ctx, time.Now()); err != nil {
+				t.Fatalf("ListByDate: %v", err)
+			}
+			if err := tt.site.Attendance().Create(ctx, &models.Attendance{EmployeeID: "e1"}); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if _, err := tt.site.Employees().IsCheckedInOn(ctx, "e1", time.Now()); err != nil {
+				t.Fatalf("IsCheckedInOn: %v", err)
+			}
+
+			for i, path := range paths {
+				if path != tt.wantPath {
+					t.Errorf("request %d went to %s, want %s", i, path, tt.wantPath)
+				}
+				if tokens[i] != tt.wantToken {
+					t.Errorf("request %d sent token %q, want %q", i, tokens[i], tt.wantToken)
+				}
+			}
+		})
+	}
+}
+
+func TestAttendancePeriodLock(t *testing.T) {
+	tests := []struct {
+		name       string
+		lockStatus int
+		lockBody   string
+		wantLocked bool
+	}{
+		{"locked period", http.StatusOK, `{"items":[{"id":"l1","period":"2026-01","locked":true}]}`, true},
+		{"unlocked period", http.StatusOK, `{"items":[{"id":"l1","period":"2026-01","locked":false}]}`, false},
+		{"never locked", http.StatusOK, `{"items":[]}`, false},
+		{"collection not migrated", http.StatusNotFound, `{}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var wrote bool
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/api/collections/clinic_a_locked_periods/records" {
+					w.WriteHeader(tt.lockStatus)
+					w.Write([]byte(tt.lockBody))
+					return
+				}
+				wrote = true
+				w.Write([]byte(`{"id":"att1"}`))
+			}))
+			defer server.Close()
+
+			day := time.Date(2026, 1, 20, 8, 0, 0, 0, time.UTC)
+			err := Site{URL: server.URL, Prefix: "clinic_a_"}.Attendance().Create(context.Background(),
+				&models.Attendance{EmployeeID: "e1", CheckInTime: day, CreatedDate: day})
+			if locked := errors.Is(err, ErrPeriodLocked); locked != tt.wantLocked || (err != nil && !locked) {
+				t.Fatalf("Create error = %v, want locked=%v", err, tt.wantLocked)
+			}
+			if wrote == tt.wantLocked {
+				t.Errorf("attendance written = %v, want %v", wrote, !tt.wantLocked)
+			}
+		})
+	}
+}
+
+func TestAttendanceCreateRechecksTheDay(t *testing.T) {
+	tests := []struct {
+		name      string
+		existing  string // ID of the employee's record of the day, if any
+		id        string // ID chosen up front for the new record
+		taken     bool   // the unique index refuses the write, as when another instance wrote first
+		wantErr   error
+		wantWrite bool
+	}{
+		{"first check-in of the day", "", "", false, nil, true},
+		{"checked in by another instance", "att1", "", false, ErrAttendanceExists, false},
+		{"stored by an earlier attempt", "att2", "att2", false, nil, false},
+		{"written by another instance after the check", "", "", true, ErrAlreadyExists, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var wrote bool
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodPost && tt.taken:
+					wrote = true
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(`{"status":400,"message":"Failed to create record.","data":{"created_date":{"code":"validation_not_unique","message":"Value must be unique."},"employee_id":{"code":"validation_not_unique","message":"Value must be unique."}}}`))
+				case r.Method == http.MethodPost:
+					wrote = true
+					w.Write([]byte(`{"id":"att3"}`))
+				case r.URL.Path == "/api/collections/attendance/records" && tt.existing != "":
+					w.Write([]byte(`{"items":[{"id":"` + tt.existing + `"}]}`))
+				default:
+					w.Write([]byte(`{"items":[]}`))
+				}
+			}))
+			defer server.Close()
+
+			day := time.Date(2026, 1, 20, 8, 0, 0, 0, time.UTC)
+			err := Site{URL: server.URL}.Attendance().Create(context.Background(),
+				&models.Attendance{ID: tt.id, EmployeeID: "e1", CheckInTime: day, CreatedDate: day})
+			if !errors.Is(err, tt.wantErr) || (err != nil && tt.wantErr == nil) {
+				t.Fatalf("Create error = %v, want %v", err, tt.wantErr)
+			}
+			if wrote != tt.wantWrite {
+				t.Errorf("attendance written = %v, want %v", wrote, tt.wantWrite)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// AttendanceRepository implements repository.AttendanceRepository and the attendance
+// views (log, browser, today, updater and unlinked) over the attendance table
+type AttendanceRepository struct {
+	db    *sql.DB
+	locks *PeriodLockRepository
+}
+
+const attendanceColumns = `id, employee_id, check_in_time, check_out_time, check_out_source, needs_review,
+	scanner_mac, status, created_date, source, employee_name, employee_code, department`
+
+// attendanceOrder is the order of every listing, oldest first, except the history
+const attendanceOrder = " ORDER BY created_date, check_in_time"
+
+// historyOrder is the order of ListHistory, newest first
+const historyOrder = " ORDER BY created_date DESC, check_in_time DESC"
+
+func scanAttendance(row scanner) (models.Attendance, error) {
+	var a models.Attendance
+	var checkIn, checkOut, createdDate string
+	err := row.Scan(&a.ID, &a.EmployeeID, &checkIn, &checkOut, &a.CheckOutSource, &a.NeedsReview,
+		&a.ScannerMac, &a.Status, &createdDate, &a.Source, &a.EmployeeName, &a.EmployeeCode, &a.Department)
+	if err != nil {
+		return models.Attendance{}, err
+	}
+	a.CheckInTime = parseTime(checkIn)
+	a.CheckOutTime = parseTime(checkOut)
+	a.CreatedDate = parseDay(createdDate)
+	return a, nil
+}
+
+// Create records a check-in; records in a locked period are refused with
+// repository.ErrPeriodLocked, and a second record of the employee's day with
+// repository.ErrAttendanceExists. The lookup and the insert share a transaction, and the
+// unique index on employee_id and created_date backs them up.
+func (r *AttendanceRepository) Create(ctx context.Context, attendance *models.Attendance) error {
+	if err := r.locks.CheckWritable(ctx, attendanceDay(attendance)); err != nil {
+		return err
+	}
+	id := attendance.ID
+	if id == "" {
+		id = repository.NewRecordID()
+	}
+	day := formatDay(attendance.CreatedDate)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create attendance: %w", err)
+	}
+	defer tx.Rollback()
+
+	if attendance.EmployeeID != "" {
+		var existing string
+		err := tx.QueryRowContext(ctx, "SELECT id FROM attendance WHERE employee_id = ? AND created_date = ? LIMIT 1",
+			attendance.EmployeeID, day).Scan(&existing)
+		switch {
+		case err == nil && existing == attendance.ID:
+			return nil // stored by an earlier attempt
+		case err == nil:
+			return repository.ErrAttendanceExists
+		case !errors.Is(err, sql.ErrNoRows):
+			return fmt.Errorf("failed to check for an existing attendance record: %w", err)
+		}
+	}
+
+	checkOutSource, needsReview := "", false
+	if !attendance.CheckOutTime.IsZero() {
+		checkOutSource, needsReview = attendance.CheckOutSource, attendance.NeedsReview
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO attendance ("+attendanceColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, attendance.EmployeeID, formatTime(attendance.CheckInTime), formatTime(attendance.CheckOutTime),
+		checkOutSource, bool01(needsReview), attendance.ScannerMac, attendance.Status, day,
+		attendance.Source, attendance.EmployeeName, attendance.EmployeeCode, attendance.Department)
+	if isUnique(err) {
+		return repository.ErrAttendanceExists
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create attendance: %w", err)
+	}
+	attendance.ID = id
+	return nil
+}
+
+// attendanceDay is the work day an attendance record belongs to
+func attendanceDay(attendance *models.Attendance) time.Time {
+	if !attendance.CreatedDate.IsZero() {
+		return attendance.CreatedDate
+	}
+	return attendance.CheckInTime
+}
+
+// list returns the attendance records matching where, oldest first
+func (r *AttendanceRepository) list(ctx context.Context, where string, args ...any) ([]models.Attendance, error) {
+	attendance, err := r.query(ctx, "WHERE "+where+attendanceOrder, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attendance: %w", err)
+	}
+	return attendance, nil
+}
+
+func (r *AttendanceRepository) query(ctx context.Context, query string, args ...any) ([]models.Attendance, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+attendanceColumns+" FROM attendance "+query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var attendance []models.Attendance
+	for rows.Next() {
+		a, err := scanAttendance(rows)
+		if err != nil {
+			return nil, err
+		}
+		attendance = append(attendance, a)
+	}
+	return attendance, rows.Err()
+}
+
+// page returns one page of the records matching where in order, and how many match
+func (r *AttendanceRepository) page(ctx context.Context, page repository.Page, order, where string, args ...any) ([]models.Attendance, int, error) {
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM attendance WHERE "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to list attendance: %w", err)
+	}
+	attendance, err := r.query(ctx, "WHERE "+where+order+" LIMIT ? OFFSET ?", append(args, page.Size, offset(page))...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list attendance: %w", err)
+	}
+	if attendance == nil {
+		attendance = []models.Attendance{}
+	}
+	return attendance, total, nil
+}
+
+// ListByDate returns all attendance records created on the given day
+func (r *AttendanceRepository) ListByDate(ctx context.Context, date time.Time) ([]models.Attendance, error) {
+	return r.list(ctx, "created_date = ?", formatDay(date))
+}
+
+// ListSince returns all attendance records created on or after the given day, oldest first
+func (r *AttendanceRepository) ListSince(ctx context.Context, since time.Time) ([]models.Attendance, error) {
+	return r.list(ctx, "created_date >= ?", formatDay(since))
+}
+
+// ListBetween returns the attendance records created on or after from and before to, oldest first
+func (r *AttendanceRepository) ListBetween(ctx context.Context, from, to time.Time) ([]models.Attendance, error) {
+	return r.list(ctx, "created_date >= ? AND created_date < ?", formatDay(from), formatDay(to))
+}
+
+// ListOpenForDate returns the attendance records created on the given day without a check-out
+func (r *AttendanceRepository) ListOpenForDate(ctx context.Context, date time.Time) ([]models.Attendance, error) {
+	return r.list(ctx, "created_date = ? AND check_out_time = ''", formatDay(date))
+}
+
+// ListByEmployeeAndRange returns the employee's attendance records created on or after from
+// and before to, oldest first
+func (r *AttendanceRepository) ListByEmployeeAndRange(ctx context.Context, employeeID string, from, to time.Time) ([]models.Attendance, error) {
+	return r.list(ctx, "employee_id = ? AND created_date >= ? AND created_date < ?", employeeID, formatDay(from), formatDay(to))
+}
+
+// PageByDate returns a page of the records created on the given day, and how many there are
+func (r *AttendanceRepository) PageByDate(ctx context.Context, date time.Time, page repository.Page) ([]models.Attendance, int, error) {
+	return r.page(ctx, page, attendanceOrder, "created_date = ?", formatDay(date))
+}
+
+// PageByEmployeeAndRange returns a page of the employee's records created on or after from
+// and before to, and how many there are
+func (r *AttendanceRepository) PageByEmployeeAndRange(ctx context.Context, employeeID string, from, to time.Time, page repository.Page) ([]models.Attendance, int, error) {
+	return r.page(ctx, page, attendanceOrder, "employee_id = ? AND created_date >= ? AND created_date < ?", employeeID, formatDay(from), formatDay(to))
+}
+
+// ListHistory returns a page of the employee's records created on the days first to last,
+// newest first, and how many there are
+func (r *AttendanceRepository) ListHistory(ctx context.Context, employeeID string, first, last time.Time, page repository.Page) ([]models.Attendance, int, error) {
+	return r.page(ctx, page, historyOrder, "employee_id = ? AND created_date >= ? AND created_date <= ?", employeeID, formatDay(first), formatDay(last))
+}
+
+// ListUnlinked returns the attendance records without an employee_id, oldest first
+func (r *AttendanceRepository) ListUnlinked(ctx context.Context) ([]models.Attendance, error) {
+	return r.list(ctx, "employee_id = ''")
+}
+
+// Relink sets the employee_id of one record; records in a locked period are refused with
+// repository.ErrPeriodLocked
+func (r *AttendanceRepository) Relink(ctx context.Context, id, employeeID string) error {
+	attendance, err := r.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to relink attendance %s: %w", id, err)
+	}
+	if err := r.locks.CheckWritable(ctx, attendanceDay(attendance)); err != nil {
+		return err
+	}
+	res, err := r.db.ExecContext(ctx, "UPDATE attendance SET employee_id = ? WHERE id = ?", employeeID, id)
+	if err := updated(res, err, repository.ErrNotFound); err != nil {
+		return fmt.Errorf("failed to relink attendance %s: %w", id, err)
+	}
+	return nil
+}
+
+// GetTodayByEmployee returns the employee's attendance record of today, or nil
+func (r *AttendanceRepository) GetTodayByEmployee(ctx context.Context, employeeID string) (*models.Attendance, error) {
+	return r.GetByEmployeeOn(ctx, employeeID, time.Now())
+}
+
+// GetByEmployeeOn returns the employee's attendance record created on the day, or nil
+func (r *AttendanceRepository) GetByEmployeeOn(ctx context.Context, employeeID string, day time.Time) (*models.Attendance, error) {
+	records, err := r.list(ctx, "employee_id = ? AND created_date = ?", employeeID, formatDay(day))
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	return &records[0], nil
+}
+
+// Get returns one attendance record by ID
+func (r *AttendanceRepository) Get(ctx context.Context, id string) (*models.Attendance, error) {
+	a, err := scanAttendance(r.db.QueryRowContext(ctx, "SELECT "+attendanceColumns+" FROM attendance WHERE id = ?", id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attendance: %w", notFound(err, repository.ErrNotFound))
+	}
+	return &a, nil
+}
+
+// UpdateCheckOut writes the check-out time, source and review flag of a record. Records
+// in a locked period are refused with repository.ErrPeriodLocked.
+func (r *AttendanceRepository) UpdateCheckOut(ctx context.Context, attendance *models.Attendance) error {
+	if err := r.locks.CheckWritable(ctx, attendanceDay(attendance)); err != nil {
+		return err
+	}
+	res, err := r.db.ExecContext(ctx, "UPDATE attendance SET check_out_time = ?, check_out_source = ?, needs_review = ? WHERE id = ?",
+		formatTime(attendance.CheckOutTime), attendance.CheckOutSource, bool01(attendance.NeedsReview), attendance.ID)
+	if err := updated(res, err, repository.ErrNotFound); err != nil {
+		return fmt.Errorf("failed to update attendance: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"med-pulse-bot/internal/logging"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// DetectionRepository implements repository.EmployeeDetectionRepository, DetectionLog and
+// UnlinkedDetections over the employee_detections table
+type DetectionRepository struct {
+	db    *sql.DB
+	locks *PeriodLockRepository
+}
+
+const detectionColumns = `id, employee_id, mac_address, scanner_mac, rssi, device_type, is_itag03,
+	is_target_device, device_name, ibeacon_uuid, major, minor, namespace_id, instance_id, detected_at`
+
+func scanDetection(row scanner) (models.EmployeeDetection, error) {
+	var d models.EmployeeDetection
+	var detectedAt string
+	err := row.Scan(&d.ID, &d.EmployeeID, &d.MacAddress, &d.ScannerMac, &d.RSSI, &d.DeviceType, &d.IsITag03,
+		&d.IsTargetDevice, &d.DeviceName, &d.IBeaconUUID, &d.Major, &d.Minor, &d.NamespaceID, &d.InstanceID, &detectedAt)
+	if err != nil {
+		return models.EmployeeDetection{}, err
+	}
+	d.DetectedAt = parseTime(detectedAt)
+	return d, nil
+}
+
+func (r *DetectionRepository) Create(ctx context.Context, detection *models.EmployeeDetection) error {
+	id := detection.ID
+	if id == "" {
+		id = repository.NewRecordID()
+	}
+	_, err := r.db.ExecContext(ctx, "INSERT INTO employee_detections ("+detectionColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, detection.EmployeeID, strings.ToLower(detection.MacAddress), detection.ScannerMac, detection.RSSI,
+		detection.DeviceType, bool01(detection.IsITag03), bool01(detection.IsTargetDevice), detection.DeviceName,
+		detection.IBeaconUUID, detection.Major, detection.Minor, detection.NamespaceID, detection.InstanceID,
+		formatTime(detection.DetectedAt))
+	if err != nil {
+		return fmt.Errorf("failed to create detection: %w", err)
+	}
+	detection.ID = id
+
+	logging.From(ctx).Debug("💾 Saved detection record", "employee_id", detection.EmployeeID, "rssi", detection.RSSI)
+
+	return nil
+}
+
+// ListByEmployeeSince returns the employee's detections at or after since, oldest first
+func (r *DetectionRepository) ListByEmployeeSince(ctx context.Context, employeeID string, since time.Time) ([]models.EmployeeDetection, error) {
+	return r.list(ctx, "employee_id = ? AND detected_at >= ?", employeeID, formatTime(since))
+}
+
+// ListRecent returns every employee's detections at or after since, oldest first
+func (r *DetectionRepository) ListRecent(ctx context.Context, since time.Time) ([]models.EmployeeDetection, error) {
+	return r.list(ctx, "detected_at >= ?", formatTime(since))
+}
+
+// GetLastForEmployeeOnDate returns the employee's last detection on the given day, or nil
+func (r *DetectionRepository) GetLastForEmployeeOnDate(ctx context.Context, employeeID string, date time.Time) (*models.EmployeeDetection, error) {
+	start := parseDay(formatDay(date))
+	d, err := scanDetection(r.db.QueryRowContext(ctx, "SELECT "+detectionColumns+` FROM employee_detections
+		WHERE employee_id = ? AND detected_at >= ? AND detected_at < ? ORDER BY detected_at DESC LIMIT 1`,
+		employeeID, formatTime(start), formatTime(start.AddDate(0, 0, 1))))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last detection: %w", err)
+	}
+	return &d, nil
+}
+
+// ListUnlinked returns the detections without an employee_id, oldest first
+func (r *DetectionRepository) ListUnlinked(ctx context.Context) ([]models.EmployeeDetection, error) {
+	return r.list(ctx, "employee_id = ''")
+}
+
+// Relink sets the employee_id of one record; detections in a locked period are refused
+// with repository.ErrPeriodLocked
+func (r *DetectionRepository) Relink(ctx context.Context, id, employeeID string) error {
+	var detectedAt string
+	err := r.db.QueryRowContext(ctx, "SELECT detected_at FROM employee_detections WHERE id = ?", id).Scan(&detectedAt)
+	if err != nil {
+		return fmt.Errorf("failed to relink detection %s: %w", id, notFound(err, repository.ErrNotFound))
+	}
+	if err := r.locks.CheckWritable(ctx, parseTime(detectedAt)); err != nil {
+		return err
+	}
+	res, err := r.db.ExecContext(ctx, "UPDATE employee_detections SET employee_id = ? WHERE id = ?", employeeID, id)
+	if err := updated(res, err, repository.ErrNotFound); err != nil {
+		return fmt.Errorf("failed to relink detection %s: %w", id, err)
+	}
+	return nil
+}
+
+// list returns the detections matching where, oldest first
+func (r *DetectionRepository) list(ctx context.Context, where string, args ...any) ([]models.EmployeeDetection, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+detectionColumns+" FROM employee_detections WHERE "+where+" ORDER BY detected_at", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list detections: %w", err)
+	}
+	defer rows.Close()
+	var detections []models.EmployeeDetection
+	for rows.Next() {
+		d, err := scanDetection(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list detections: %w", err)
+		}
+		detections = append(detections, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list detections: %w", err)
+	}
+	return detections, nil
+}
@@ -0,0 +1,89 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// DeviceRepository implements repository.DeviceRepository, DeviceAssignment and
+// EmployeeDevices over the employee_devices table
+type DeviceRepository struct {
+	db        *sql.DB
+	employees *EmployeeRepository
+}
+
+// GetEmployeeByDeviceMac returns the active employee owning the device with the MAC
+func (r *DeviceRepository) GetEmployeeByDeviceMac(ctx context.Context, macAddress string) (*models.Employee, error) {
+	emp, err := r.employees.get(ctx,
+		"is_active = 1 AND id IN (SELECT employee_id FROM employee_devices WHERE mac_address = ?)", strings.ToLower(macAddress))
+	if err != nil && !errors.Is(err, repository.ErrEmployeeNotFound) {
+		return nil, fmt.Errorf("failed to get employee of device: %w", err)
+	}
+	return emp, err
+}
+
+// AssignDevice makes the MAC the employee's mac_address when they have none, an extra
+// device otherwise
+func (r *DeviceRepository) AssignDevice(ctx context.Context, employee *models.Employee, mac string) error {
+	mac = strings.ToLower(mac)
+	var err error
+	if employee.MacAddress == "" {
+		var res sql.Result
+		res, err = r.db.ExecContext(ctx, "UPDATE employees SET mac_address = ? WHERE id = ?", mac, employee.ID)
+		err = updated(res, err, repository.ErrEmployeeNotFound)
+	} else {
+		_, err = r.db.ExecContext(ctx, "INSERT INTO employee_devices (id, employee_id, mac_address) VALUES (?, ?, ?)",
+			repository.NewRecordID(), employee.ID, mac)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to assign device: %w", err)
+	}
+	return nil
+}
+
+// ListDevices returns the employee's extra devices by MAC
+func (r *DeviceRepository) ListDevices(ctx context.Context, employeeID string) ([]models.EmployeeDevice, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT id, employee_id, mac_address, label, is_primary FROM employee_devices WHERE employee_id = ? ORDER BY mac_address", employeeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+	defer rows.Close()
+	var devices []models.EmployeeDevice
+	for rows.Next() {
+		var d models.EmployeeDevice
+		if err := rows.Scan(&d.ID, &d.EmployeeID, &d.MacAddress, &d.Label, &d.IsPrimary); err != nil {
+			return nil, fmt.Errorf("failed to list devices: %w", err)
+		}
+		devices = append(devices, d)
+	}
+	return devices, rows.Err()
+}
+
+// AddDevice inserts the device unless another device already has its MAC
+func (r *DeviceRepository) AddDevice(ctx context.Context, device *models.EmployeeDevice) error {
+	id, mac := repository.NewRecordID(), strings.ToLower(device.MacAddress)
+	res, err := r.db.ExecContext(ctx, `INSERT INTO employee_devices (id, employee_id, mac_address, label, is_primary)
+		SELECT ?, ?, ?, ?, ? WHERE NOT EXISTS (SELECT 1 FROM employee_devices WHERE mac_address = ?)`,
+		id, device.EmployeeID, mac, device.Label, bool01(device.IsPrimary), mac)
+	if err := updated(res, err, repository.ErrDeviceExists); err != nil {
+		return fmt.Errorf("failed to add device: %w", err)
+	}
+	device.ID = id
+	return nil
+}
+
+// RemoveDevice deletes the employee's device with the MAC
+func (r *DeviceRepository) RemoveDevice(ctx context.Context, employeeID, mac string) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM employee_devices WHERE employee_id = ? AND mac_address = ?", employeeID, strings.ToLower(mac))
+	if err := updated(res, err, repository.ErrDeviceNotFound); err != nil {
+		return fmt.Errorf("failed to remove device: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+package sqlite
+
+// The pure-Go driver needs no cgo, so the bot still cross-compiles for the Raspberry Pi
+// with CGO_ENABLED=0
+import _ "modernc.org/sqlite"
+
+// driverName is the database/sql driver registered by modernc.org/sqlite
+const driverName = "sqlite"
@@ -0,0 +1,301 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"med-pulse-bot/internal/beacon"
+	"med-pulse-bot/internal/logging"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// EmployeeRepository implements repository.EmployeeRepository and the employee views
+// (directory, activation, browser and guest registry) over the employees table
+type EmployeeRepository struct {
+	db *sql.DB
+}
+
+const employeeColumns = `id, telegram_chat_id, name, employee_code, mac_address, ibeacon_id, eddystone_id,
+	work_start_time, work_end_time, grace_period_minutes, is_active, department, display_name,
+	show_on_board, is_synthetic, presence_opt_out, language, muted_notifications,
+	notify_checkin, notify_quiet_hours, leave_until, is_guest, guest_until`
+
+func scanEmployee(row scanner) (models.Employee, error) {
+	var e models.Employee
+	var muted, leaveUntil, guestUntil string
+	var notifyCheckIn bool
+	err := row.Scan(&e.ID, &e.TelegramChatID, &e.Name, &e.EmployeeCode, &e.MacAddress, &e.IBeaconID, &e.EddystoneID,
+		&e.WorkStartTime, &e.WorkEndTime, &e.GracePeriod, &e.IsActive, &e.Department, &e.DisplayName,
+		&e.ShowOnBoard, &e.IsSynthetic, &e.PresenceOptOut, &e.Language, &muted,
+		&notifyCheckIn, &e.QuietHours, &leaveUntil, &e.IsGuest, &guestUntil)
+	if err != nil {
+		return models.Employee{}, err
+	}
+	json.Unmarshal([]byte(muted), &e.MutedNotifications)
+	e.CheckInOptOut = !notifyCheckIn
+	e.LeaveUntil = parseDay(leaveUntil)
+	e.GuestUntil = parseDay(guestUntil)
+	return e, nil
+}
+
+// get returns the first employee the query finds, or ErrEmployeeNotFound
+func (r *EmployeeRepository) get(ctx context.Context, where string, args ...any) (*models.Employee, error) {
+	e, err := scanEmployee(r.db.QueryRowContext(ctx, "SELECT "+employeeColumns+" FROM employees WHERE "+where+" LIMIT 1", args...))
+	if err != nil {
+		return nil, notFound(err, repository.ErrEmployeeNotFound)
+	}
+	return &e, nil
+}
+
+// list returns the employees the query finds
+func (r *EmployeeRepository) list(ctx context.Context, query string, args ...any) ([]models.Employee, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+employeeColumns+" FROM employees "+query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var employees []models.Employee
+	for rows.Next() {
+		e, err := scanEmployee(rows)
+		if err != nil {
+			return nil, err
+		}
+		employees = append(employees, e)
+	}
+	return employees, rows.Err()
+}
+
+func (r *EmployeeRepository) GetByMacAddress(ctx context.Context, macAddress string) (*models.Employee, error) {
+	logging.From(ctx).Debug("🔍 Looking up employee by MAC", "lookup_mac", macAddress)
+	emp, err := r.get(ctx, "mac_address = ? AND is_active = 1", strings.ToLower(macAddress))
+	if err != nil && !errors.Is(err, repository.ErrEmployeeNotFound) {
+		logging.From(ctx).Error("❌ Error looking up employee", "lookup_mac", macAddress, "error", err)
+		return nil, fmt.Errorf("failed to get employee: %w", err)
+	}
+	return emp, err
+}
+
+// GetByBeacon looks the employee up by ibeacon_id
+func (r *EmployeeRepository) GetByBeacon(ctx context.Context, uuid string, major, minor int) (*models.Employee, error) {
+	id, err := beacon.IBeaconID(uuid, major, minor)
+	if err != nil {
+		return nil, repository.ErrEmployeeNotFound
+	}
+	return r.getBy(ctx, "ibeacon_id", id)
+}
+
+// GetByEddystone looks the employee up by eddystone_id
+func (r *EmployeeRepository) GetByEddystone(ctx context.Context, namespace, instance string) (*models.Employee, error) {
+	id, err := beacon.EddystoneID(namespace, instance)
+	if err != nil {
+		return nil, repository.ErrEmployeeNotFound
+	}
+	return r.getBy(ctx, "eddystone_id", id)
+}
+
+// getBy returns the active employee whose column holds the beacon identity
+func (r *EmployeeRepository) getBy(ctx context.Context, column, id string) (*models.Employee, error) {
+	emp, err := r.get(ctx, column+" = ? AND is_active = 1", id)
+	if err != nil && !errors.Is(err, repository.ErrEmployeeNotFound) {
+		return nil, fmt.Errorf("failed to get employee by %s: %w", column, err)
+	}
+	return emp, err
+}
+
+// ListActive returns every active employee by name, except the synthetic self-test employee
+func (r *EmployeeRepository) ListActive(ctx context.Context) ([]models.Employee, error) {
+	employees, err := r.list(ctx, "WHERE is_active = 1 AND is_synthetic = 0 ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list employees: %w", err)
+	}
+	return employees, nil
+}
+
+// PageActive returns a page of the active employees by name, and how many there are
+func (r *EmployeeRepository) PageActive(ctx context.Context, page repository.Page) ([]models.Employee, int, error) {
+	return r.List(ctx, "", page)
+}
+
+// List returns a page of the active employees whose name or code contains query, by
+// name, and how many there are
+func (r *EmployeeRepository) List(ctx context.Context, query string, page repository.Page) ([]models.Employee, int, error) {
+	where := "WHERE is_active = 1 AND is_synthetic = 0"
+	var args []any
+	if query = strings.TrimSpace(query); query != "" {
+		// LIKE ignores the case of ASCII letters; Thai has none
+		pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(query) + "%"
+		where += ` AND (name LIKE ? ESCAPE '\' OR employee_code LIKE ? ESCAPE '\')`
+		args = append(args, pattern, pattern)
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM employees "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to list employees: %w", err)
+	}
+	employees, err := r.list(ctx, where+" ORDER BY name LIMIT ? OFFSET ?", append(args, page.Size, offset(page))...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list employees: %w", err)
+	}
+	if employees == nil {
+		employees = []models.Employee{}
+	}
+	return employees, total, nil
+}
+
+// Update writes the employee's profile fields; other fields are left as they are
+func (r *EmployeeRepository) Update(ctx context.Context, employee *models.Employee) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE employees SET name = ?, employee_code = ?, department = ?,
+		display_name = ?, work_start_time = ?, work_end_time = ? WHERE id = ?`,
+		employee.Name, employee.EmployeeCode, employee.Department,
+		employee.DisplayName, employee.WorkStartTime, employee.WorkEndTime, employee.ID)
+	if err := updated(res, err, repository.ErrEmployeeNotFound); err != nil {
+		return fmt.Errorf("failed to update employee: %w", err)
+	}
+	return nil
+}
+
+// UpdateNotificationPrefs writes notify_checkin and notify_quiet_hours
+func (r *EmployeeRepository) UpdateNotificationPrefs(ctx context.Context, employeeID string, prefs models.NotificationPrefs) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE employees SET notify_checkin = ?, notify_quiet_hours = ? WHERE id = ?",
+		bool01(prefs.CheckIn), prefs.QuietHours, employeeID)
+	if err := updated(res, err, repository.ErrEmployeeNotFound); err != nil {
+		return fmt.Errorf("failed to update notification settings: %w", err)
+	}
+	return nil
+}
+
+// GetByCode looks the employee up by employee_code, active or not; an active employee wins
+// over inactive ones with the same code
+func (r *EmployeeRepository) GetByCode(ctx context.Context, code string) (*models.Employee, error) {
+	emp, err := r.get(ctx, "employee_code = ? ORDER BY is_active DESC", code)
+	if err != nil && !errors.Is(err, repository.ErrEmployeeNotFound) {
+		return nil, fmt.Errorf("failed to get employee by code: %w", err)
+	}
+	return emp, err
+}
+
+// SetActive sets the employee's is_active flag; inactive employees no longer check in
+func (r *EmployeeRepository) SetActive(ctx context.Context, id string, active bool) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE employees SET is_active = ? WHERE id = ?", bool01(active), id)
+	if err := updated(res, err, repository.ErrEmployeeNotFound); err != nil {
+		return fmt.Errorf("failed to set employee %s active=%v: %w", id, active, err)
+	}
+	return nil
+}
+
+// CreateGuest creates an active guest record for the tag, off the board and untracked
+func (r *EmployeeRepository) CreateGuest(ctx context.Context, guest *models.Employee) error {
+	g := models.Employee{
+		MacAddress:     guest.MacAddress,
+		Name:           guest.Name,
+		IsActive:       true,
+		IsGuest:        true,
+		GuestUntil:     guest.GuestUntil,
+		PresenceOptOut: true,
+	}
+	if err := r.Create(ctx, &g); err != nil {
+		return fmt.Errorf("failed to create guest: %w", err)
+	}
+	guest.ID = g.ID
+	guest.IsActive, guest.IsGuest = true, true
+	return nil
+}
+
+// Create stores a new employee with every field of the model; an empty ID gets a new one.
+// There is no PocketBase admin UI in front of SQLite, so this is how employees are added.
+func (r *EmployeeRepository) Create(ctx context.Context, employee *models.Employee) error {
+	id := employee.ID
+	if id == "" {
+		id = repository.NewRecordID()
+	}
+	muted, err := json.Marshal(employee.MutedNotifications)
+	if err != nil || employee.MutedNotifications == nil {
+		muted = []byte("[]")
+	}
+	leaveUntil, guestUntil := "", ""
+	if !employee.LeaveUntil.IsZero() {
+		leaveUntil = formatDay(employee.LeaveUntil)
+	}
+	if !employee.GuestUntil.IsZero() {
+		guestUntil = formatDay(employee.GuestUntil)
+	}
+	_, err = r.db.ExecContext(ctx, "INSERT INTO employees ("+employeeColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, employee.TelegramChatID, employee.Name, employee.EmployeeCode, strings.ToLower(employee.MacAddress),
+		employee.IBeaconID, employee.EddystoneID, employee.WorkStartTime, employee.WorkEndTime, employee.GracePeriod,
+		bool01(employee.IsActive), employee.Department, employee.DisplayName, bool01(employee.ShowOnBoard),
+		bool01(employee.IsSynthetic), bool01(employee.PresenceOptOut), employee.Language, string(muted),
+		bool01(!employee.CheckInOptOut), employee.QuietHours, leaveUntil, bool01(employee.IsGuest), guestUntil)
+	if err != nil {
+		return fmt.Errorf("failed to create employee: %w", err)
+	}
+	employee.ID = id
+	return nil
+}
+
+// GetByChatID returns the active employee registered from the Telegram chat
+func (r *EmployeeRepository) GetByChatID(ctx context.Context, chatID int64) (*models.Employee, error) {
+	emp, err := r.get(ctx, "telegram_chat_id = ? AND is_active = 1", chatID)
+	if err != nil && !errors.Is(err, repository.ErrEmployeeNotFound) {
+		return nil, fmt.Errorf("failed to get employee by chat: %w", err)
+	}
+	return emp, err
+}
+
+// GetOwnerByMac returns the employee whose mac_address is the MAC, active or not
+func (r *EmployeeRepository) GetOwnerByMac(ctx context.Context, macAddress string) (*models.Employee, error) {
+	emp, err := r.get(ctx, "mac_address = ?", strings.ToLower(macAddress))
+	if err != nil && !errors.Is(err, repository.ErrEmployeeNotFound) {
+		return nil, fmt.Errorf("failed to get employee by MAC: %w", err)
+	}
+	return emp, err
+}
+
+// Register creates the employee a chat registered unless another employee has the MAC
+func (r *EmployeeRepository) Register(ctx context.Context, employee *models.Employee) error {
+	var n int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM employees WHERE mac_address = ?",
+		strings.ToLower(employee.MacAddress)).Scan(&n)
+	if err != nil {
+		return fmt.Errorf("failed to register employee: %w", err)
+	}
+	if n > 0 {
+		return repository.ErrEmployeeExists
+	}
+	return r.Create(ctx, employee)
+}
+
+// UpdateSettings writes the fields employees change themselves
+func (r *EmployeeRepository) UpdateSettings(ctx context.Context, employee *models.Employee) error {
+	muted, err := json.Marshal(employee.MutedNotifications)
+	if err != nil || employee.MutedNotifications == nil {
+		muted = []byte("[]")
+	}
+	res, err := r.db.ExecContext(ctx, `UPDATE employees SET mac_address = ?, work_start_time = ?, work_end_time = ?,
+		show_on_board = ?, muted_notifications = ?, presence_opt_out = ?, language = ? WHERE id = ?`,
+		strings.ToLower(employee.MacAddress), employee.WorkStartTime, employee.WorkEndTime, bool01(employee.ShowOnBoard),
+		string(muted), bool01(employee.PresenceOptOut), employee.Language, employee.ID)
+	if err := updated(res, err, repository.ErrEmployeeNotFound); err != nil {
+		return fmt.Errorf("failed to update employee settings: %w", err)
+	}
+	return nil
+}
+
+func (r *EmployeeRepository) IsCheckedInOn(ctx context.Context, employeeID string, day time.Time) (bool, error) {
+	logging.From(ctx).Debug("🔍 Checking attendance", "employee_id", employeeID, "day", formatDay(day))
+	var n int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM attendance WHERE employee_id = ? AND created_date = ?",
+		employeeID, formatDay(day)).Scan(&n)
+	if err != nil {
+		logging.From(ctx).Error("❌ Error checking attendance", "employee_id", employeeID, "error", err)
+		return false, fmt.Errorf("failed to check attendance: %w", err)
+	}
+	logging.From(ctx).Debug("Checked attendance", "employee_id", employeeID, "checked_in", n > 0)
+	return n > 0, nil
+}
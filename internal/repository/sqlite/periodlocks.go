@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// PeriodLockRepository implements repository.PeriodLockRepository over the locked_periods
+// table
+type PeriodLockRepository struct {
+	db *sql.DB
+}
+
+const lockedPeriodColumns = "id, period, locked, locked_at, history"
+
+func scanLockedPeriod(row scanner) (models.LockedPeriod, error) {
+	var lock models.LockedPeriod
+	var lockedAt, history string
+	if err := row.Scan(&lock.ID, &lock.Period, &lock.Locked, &lockedAt, &history); err != nil {
+		return models.LockedPeriod{}, err
+	}
+	lock.LockedAt = parseTime(lockedAt)
+	json.Unmarshal([]byte(history), &lock.History)
+	return lock, nil
+}
+
+// Get returns the period's record, or nil if it was never locked
+func (r *PeriodLockRepository) Get(ctx context.Context, period string) (*models.LockedPeriod, error) {
+	lock, err := scanLockedPeriod(r.db.QueryRowContext(ctx, "SELECT "+lockedPeriodColumns+" FROM locked_periods WHERE period = ?", period))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get locked period: %w", err)
+	}
+	return &lock, nil
+}
+
+// Save creates or updates the period's record
+func (r *PeriodLockRepository) Save(ctx context.Context, lock *models.LockedPeriod) error {
+	history, err := json.Marshal(lock.History)
+	if err != nil || lock.History == nil {
+		history = []byte("[]")
+	}
+	id := lock.ID
+	if id == "" {
+		id = repository.NewRecordID()
+		_, err = r.db.ExecContext(ctx, "INSERT INTO locked_periods ("+lockedPeriodColumns+") VALUES (?, ?, ?, ?, ?)",
+			id, lock.Period, bool01(lock.Locked), formatTime(lock.LockedAt), string(history))
+	} else {
+		var res sql.Result
+		res, err = r.db.ExecContext(ctx, "UPDATE locked_periods SET period = ?, locked = ?, locked_at = ?, history = ? WHERE id = ?",
+			lock.Period, bool01(lock.Locked), formatTime(lock.LockedAt), string(history), id)
+		err = updated(res, err, repository.ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save locked period: %w", err)
+	}
+	lock.ID = id
+	return nil
+}
+
+// ListLocked returns the currently locked periods, oldest first
+func (r *PeriodLockRepository) ListLocked(ctx context.Context) ([]models.LockedPeriod, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+lockedPeriodColumns+" FROM locked_periods WHERE locked = 1 ORDER BY period")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list locked periods: %w", err)
+	}
+	defer rows.Close()
+	var locks []models.LockedPeriod
+	for rows.Next() {
+		lock, err := scanLockedPeriod(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list locked periods: %w", err)
+		}
+		locks = append(locks, lock)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list locked periods: %w", err)
+	}
+	return locks, nil
+}
+
+// CheckWritable returns repository.ErrPeriodLocked when the day falls into a locked period
+func (r *PeriodLockRepository) CheckWritable(ctx context.Context, day time.Time) error {
+	if day.IsZero() {
+		return nil
+	}
+	period := day.Format(models.PeriodLayout)
+	lock, err := r.Get(ctx, period)
+	if err != nil {
+		return fmt.Errorf("failed to check period lock: %w", err)
+	}
+	if lock != nil && lock.Locked {
+		return fmt.Errorf("%w: %s", repository.ErrPeriodLocked, period)
+	}
+	return nil
+}
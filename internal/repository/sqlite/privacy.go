@@ -0,0 +1,25 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"med-pulse-bot/internal/repository"
+)
+
+// PrivacyAuditRepository implements repository.ConsentLog over the privacy_audit table
+type PrivacyAuditRepository struct {
+	db *sql.DB
+}
+
+// RecordConsent inserts the consent change
+func (r *PrivacyAuditRepository) RecordConsent(ctx context.Context, employeeID string, consent bool, by int64, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, "INSERT INTO privacy_audit (id, employee_id, consent, changed_by, changed_at) VALUES (?, ?, ?, ?, ?)",
+		repository.NewRecordID(), employeeID, bool01(consent), by, formatTime(at))
+	if err != nil {
+		return fmt.Errorf("failed to record consent change: %w", err)
+	}
+	return nil
+}
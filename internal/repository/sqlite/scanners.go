@@ -0,0 +1,200 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"med-pulse-bot/internal/macaddr"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// ScannerRepository implements repository.ScannerRepository and ScannerRegistry over the
+// scanners table
+type ScannerRepository struct {
+	db *sql.DB
+}
+
+const scannerColumns = `id, scanner_mac, last_seen, name, location, zone, paired_at, profile, overrides,
+	firmware_version, uptime_seconds, free_heap`
+
+func scanScanner(row scanner) (models.Scanner, error) {
+	var sc models.Scanner
+	var lastSeen, pairedAt, overrides string
+	err := row.Scan(&sc.ID, &sc.ScannerMac, &lastSeen, &sc.Name, &sc.Location, &sc.Zone, &pairedAt, &sc.Profile, &overrides,
+		&sc.Health.FirmwareVersion, &sc.Health.UptimeSeconds, &sc.Health.FreeHeap)
+	if err != nil {
+		return models.Scanner{}, err
+	}
+	sc.LastSeen = parseTime(lastSeen)
+	sc.PairedAt = parseTime(pairedAt)
+	json.Unmarshal([]byte(overrides), &sc.Overrides)
+	return sc, nil
+}
+
+// findScanner returns the most recently seen scanner stored under any spelling of the
+// canonical ID, or ErrScannerNotFound
+func findScanner(ctx context.Context, q querier, mac string) (*models.Scanner, error) {
+	spellings := macaddr.Spellings(mac)
+	args := make([]any, len(spellings))
+	for i, s := range spellings {
+		args[i] = s
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+	sc, err := scanScanner(q.QueryRowContext(ctx, "SELECT "+scannerColumns+" FROM scanners WHERE scanner_mac IN ("+
+		placeholders+") ORDER BY last_seen DESC LIMIT 1", args...))
+	if err != nil {
+		return nil, notFound(err, repository.ErrScannerNotFound)
+	}
+	return &sc, nil
+}
+
+// upsert writes the columns to the scanner's record, creating it if needed. A record
+// stored under another spelling of the ID is rewritten to the canonical one.
+func (r *ScannerRepository) upsert(ctx context.Context, scannerMac string, columns []string, values ...any) error {
+	mac, err := macaddr.NormalizeScannerID(scannerMac, true)
+	if err != nil {
+		return fmt.Errorf("invalid scanner_mac %q: %w", scannerMac, err)
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	existing, err := findScanner(ctx, tx, mac)
+	if err != nil && !errors.Is(err, repository.ErrScannerNotFound) {
+		return fmt.Errorf("failed to look up scanner: %w", err)
+	}
+	if existing != nil {
+		set := "scanner_mac = ?, " + strings.Join(columns, " = ?, ") + " = ?"
+		_, err = tx.ExecContext(ctx, "UPDATE scanners SET "+set+" WHERE id = ?", append(append([]any{mac}, values...), existing.ID)...)
+	} else {
+		insert := "INSERT INTO scanners (id, scanner_mac, " + strings.Join(columns, ", ") + ") VALUES (?, ?" + strings.Repeat(", ?", len(columns)) + ")"
+		_, err = tx.ExecContext(ctx, insert, append([]any{repository.NewRecordID(), mac}, values...)...)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateActivity upserts the scanner's last seen time and reported health; zero health
+// fields keep their stored value
+func (r *ScannerRepository) UpdateActivity(ctx context.Context, scannerMac string, health models.ScannerHealth) error {
+	columns := []string{"last_seen"}
+	values := []any{formatTime(time.Now())}
+	if health.FirmwareVersion != "" {
+		columns, values = append(columns, "firmware_version"), append(values, health.FirmwareVersion)
+	}
+	if health.UptimeSeconds != 0 {
+		columns, values = append(columns, "uptime_seconds"), append(values, health.UptimeSeconds)
+	}
+	if health.FreeHeap != 0 {
+		columns, values = append(columns, "free_heap"), append(values, health.FreeHeap)
+	}
+	if err := r.upsert(ctx, scannerMac, columns, values...); err != nil {
+		return fmt.Errorf("failed to update scanner: %w", err)
+	}
+	return nil
+}
+
+// GetByToken returns the scanner whose token column holds the token's hash
+func (r *ScannerRepository) GetByToken(ctx context.Context, token string) (*models.Scanner, error) {
+	if token == "" {
+		return nil, repository.ErrScannerNotFound
+	}
+	sc, err := scanScanner(r.db.QueryRowContext(ctx, "SELECT "+scannerColumns+" FROM scanners WHERE token = ? LIMIT 1",
+		repository.HashScannerToken(token)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrScannerNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up scanner token: %w", err)
+	}
+	return &sc, nil
+}
+
+// GetByMac returns the most recently seen scanner stored under any spelling of the ID
+func (r *ScannerRepository) GetByMac(ctx context.Context, scannerMac string) (*models.Scanner, error) {
+	mac, err := macaddr.NormalizeScannerID(scannerMac, true)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scanner_mac %q: %w", scannerMac, err)
+	}
+	sc, err := findScanner(ctx, r.db, mac)
+	if err != nil && !errors.Is(err, repository.ErrScannerNotFound) {
+		return nil, fmt.Errorf("failed to look up scanner: %w", err)
+	}
+	return sc, err
+}
+
+// Pair creates or updates the scanner record with its zone and pairing time
+func (r *ScannerRepository) Pair(ctx context.Context, scannerMac, zone string, at time.Time) error {
+	if err := r.upsert(ctx, scannerMac, []string{"last_seen", "zone", "paired_at"}, formatTime(at), zone, formatTime(at)); err != nil {
+		return fmt.Errorf("failed to pair scanner: %w", err)
+	}
+	return nil
+}
+
+// SetProfile creates or updates the scanner record with its configuration profile
+func (r *ScannerRepository) SetProfile(ctx context.Context, scannerMac, profile string) error {
+	if err := r.upsert(ctx, scannerMac, []string{"profile"}, profile); err != nil {
+		return fmt.Errorf("failed to set scanner profile: %w", err)
+	}
+	return nil
+}
+
+// SetToken creates or updates the scanner record with the hash of its device token
+func (r *ScannerRepository) SetToken(ctx context.Context, scannerMac, token string) error {
+	hash := ""
+	if token != "" {
+		hash = repository.HashScannerToken(token)
+	}
+	if err := r.upsert(ctx, scannerMac, []string{"token"}, hash); err != nil {
+		return fmt.Errorf("failed to set scanner token: %w", err)
+	}
+	return nil
+}
+
+// Rename creates or updates the scanner record with its friendly name
+func (r *ScannerRepository) Rename(ctx context.Context, scannerMac, name string) error {
+	if err := r.upsert(ctx, scannerMac, []string{"name"}, name); err != nil {
+		return fmt.Errorf("failed to rename scanner: %w", err)
+	}
+	return nil
+}
+
+// SetMac rewrites the scanner_mac of the record with the given ID
+func (r *ScannerRepository) SetMac(ctx context.Context, id, scannerMac string) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE scanners SET scanner_mac = ? WHERE id = ?", scannerMac, id)
+	if err := updated(res, err, repository.ErrScannerNotFound); err != nil {
+		return fmt.Errorf("failed to update scanner %s: %w", id, err)
+	}
+	return nil
+}
+
+// List returns every scanner record, most recently seen first
+func (r *ScannerRepository) List(ctx context.Context) ([]models.Scanner, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+scannerColumns+" FROM scanners ORDER BY last_seen DESC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scanners: %w", err)
+	}
+	defer rows.Close()
+	var scanners []models.Scanner
+	for rows.Next() {
+		sc, err := scanScanner(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list scanners: %w", err)
+		}
+		scanners = append(scanners, sc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list scanners: %w", err)
+	}
+	return scanners, nil
+}
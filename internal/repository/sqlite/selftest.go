@@ -0,0 +1,78 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// SelfTestRepository implements repository.SelfTestRepository
+type SelfTestRepository struct {
+	db        *sql.DB
+	employees *EmployeeRepository
+}
+
+// EnsureSyntheticEmployee returns the synthetic employee with the MAC, creating it if needed
+func (r *SelfTestRepository) EnsureSyntheticEmployee(ctx context.Context, macAddress string) (*models.Employee, error) {
+	mac := strings.ToLower(macAddress)
+	emp, err := r.employees.get(ctx, "mac_address = ?", mac)
+	if err == nil {
+		if !emp.IsSynthetic {
+			return nil, fmt.Errorf("self-test MAC %s belongs to employee %s", mac, emp.ID)
+		}
+		return emp, nil
+	}
+	if !errors.Is(err, repository.ErrEmployeeNotFound) {
+		return nil, fmt.Errorf("failed to look up self-test employee: %w", err)
+	}
+
+	emp = &models.Employee{
+		MacAddress:    mac,
+		Name:          "Self-test (synthetic)",
+		EmployeeCode:  "SELFTEST",
+		WorkStartTime: "23:59:59",
+		IsActive:      true,
+		IsSynthetic:   true,
+	}
+	if err := r.employees.Create(ctx, emp); err != nil {
+		return nil, fmt.Errorf("failed to create self-test employee: %w", err)
+	}
+	log.Printf("🧪 Created synthetic self-test employee %s (MAC %s)", emp.ID, mac)
+	return emp, nil
+}
+
+// CountDetectionsSince counts the employee's detection records at or after since
+func (r *SelfTestRepository) CountDetectionsSince(ctx context.Context, employeeID string, since time.Time) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM employee_detections WHERE employee_id = ? AND detected_at >= ?",
+		employeeID, formatTime(since)).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count self-test detections: %w", err)
+	}
+	return n, nil
+}
+
+// DeleteRecordsBefore deletes the employee's attendance and detection records from before the given time
+func (r *SelfTestRepository) DeleteRecordsBefore(ctx context.Context, employeeID string, before time.Time) (int, error) {
+	deleted := 0
+	for _, table := range []struct{ name, field string }{
+		{"attendance", "check_in_time"},
+		{"employee_detections", "detected_at"},
+	} {
+		res, err := r.db.ExecContext(ctx, "DELETE FROM "+table.name+" WHERE employee_id = ? AND "+table.field+" < ?",
+			employeeID, formatTime(before))
+		if err != nil {
+			return deleted, fmt.Errorf("failed to delete %s records: %w", table.name, err)
+		}
+		n, _ := res.RowsAffected()
+		deleted += int(n)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,307 @@
+// Package sqlite keeps employees, attendance, detections and scanners in a local SQLite
+// file instead of PocketBase, for single-board deployments (STORAGE_BACKEND=sqlite)
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"med-pulse-bot/internal/repository"
+)
+
+// DB is an open SQLite database with the bot's schema
+type DB struct {
+	db *sql.DB
+}
+
+// migrations create the schema, one step per schema version (PRAGMA user_version). New
+// steps are appended; a step is never changed once released.
+var migrations = []string{
+	`CREATE TABLE employees (
+		id                   TEXT PRIMARY KEY,
+		telegram_chat_id     INTEGER NOT NULL DEFAULT 0,
+		name                 TEXT NOT NULL DEFAULT '',
+		employee_code        TEXT NOT NULL DEFAULT '',
+		mac_address          TEXT NOT NULL DEFAULT '',
+		ibeacon_id           TEXT NOT NULL DEFAULT '',
+		eddystone_id         TEXT NOT NULL DEFAULT '',
+		work_start_time      TEXT NOT NULL DEFAULT '',
+		work_end_time        TEXT NOT NULL DEFAULT '',
+		grace_period_minutes INTEGER NOT NULL DEFAULT 0,
+		is_active            INTEGER NOT NULL DEFAULT 1,
+		department           TEXT NOT NULL DEFAULT '',
+		display_name         TEXT NOT NULL DEFAULT '',
+		show_on_board        INTEGER NOT NULL DEFAULT 1,
+		is_synthetic         INTEGER NOT NULL DEFAULT 0,
+		presence_opt_out     INTEGER NOT NULL DEFAULT 0,
+		language             TEXT NOT NULL DEFAULT '',
+		muted_notifications  TEXT NOT NULL DEFAULT '[]',
+		notify_checkin       INTEGER NOT NULL DEFAULT 1,
+		notify_quiet_hours   TEXT NOT NULL DEFAULT '',
+		leave_until          TEXT NOT NULL DEFAULT '',
+		is_guest             INTEGER NOT NULL DEFAULT 0,
+		guest_until          TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX idx_employees_mac ON employees (mac_address);
+	CREATE INDEX idx_employees_code ON employees (employee_code);
+	CREATE INDEX idx_employees_chat ON employees (telegram_chat_id);
+
+	CREATE TABLE employee_devices (
+		id          TEXT PRIMARY KEY,
+		employee_id TEXT NOT NULL,
+		mac_address TEXT NOT NULL,
+		label       TEXT NOT NULL DEFAULT '',
+		is_primary  INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX idx_employee_devices_mac ON employee_devices (mac_address);
+
+	CREATE TABLE attendance (
+		id               TEXT PRIMARY KEY,
+		employee_id      TEXT NOT NULL DEFAULT '',
+		check_in_time    TEXT NOT NULL DEFAULT '',
+		check_out_time   TEXT NOT NULL DEFAULT '',
+		check_out_source TEXT NOT NULL DEFAULT '',
+		needs_review     INTEGER NOT NULL DEFAULT 0,
+		scanner_mac      TEXT NOT NULL DEFAULT '',
+		status           TEXT NOT NULL DEFAULT '',
+		created_date     TEXT NOT NULL,
+		source           TEXT NOT NULL DEFAULT '',
+		employee_name    TEXT NOT NULL DEFAULT '',
+		employee_code    TEXT NOT NULL DEFAULT '',
+		department       TEXT NOT NULL DEFAULT ''
+	);
+	CREATE UNIQUE INDEX idx_attendance_employee_day ON attendance (employee_id, created_date) WHERE employee_id != '';
+	CREATE INDEX idx_attendance_day ON attendance (created_date, check_in_time);
+
+	CREATE TABLE employee_detections (
+		id               TEXT PRIMARY KEY,
+		employee_id      TEXT NOT NULL DEFAULT '',
+		mac_address      TEXT NOT NULL DEFAULT '',
+		scanner_mac      TEXT NOT NULL DEFAULT '',
+		rssi             INTEGER NOT NULL DEFAULT 0,
+		device_type      TEXT NOT NULL DEFAULT '',
+		is_itag03        INTEGER NOT NULL DEFAULT 0,
+		is_target_device INTEGER NOT NULL DEFAULT 0,
+		device_name      TEXT NOT NULL DEFAULT '',
+		ibeacon_uuid     TEXT NOT NULL DEFAULT '',
+		major            INTEGER NOT NULL DEFAULT 0,
+		minor            INTEGER NOT NULL DEFAULT 0,
+		namespace_id     TEXT NOT NULL DEFAULT '',
+		instance_id      TEXT NOT NULL DEFAULT '',
+		detected_at      TEXT NOT NULL
+	);
+	CREATE INDEX idx_detections_employee ON employee_detections (employee_id, detected_at);
+	CREATE INDEX idx_detections_time ON employee_detections (detected_at);
+
+	CREATE TABLE scanners (
+		id               TEXT PRIMARY KEY,
+		scanner_mac      TEXT NOT NULL UNIQUE,
+		last_seen        TEXT NOT NULL DEFAULT '',
+		name             TEXT NOT NULL DEFAULT '',
+		location         TEXT NOT NULL DEFAULT '',
+		zone             TEXT NOT NULL DEFAULT '',
+		paired_at        TEXT NOT NULL DEFAULT '',
+		profile          TEXT NOT NULL DEFAULT '',
+		overrides        TEXT NOT NULL DEFAULT '{}',
+		token            TEXT NOT NULL DEFAULT '',
+		firmware_version TEXT NOT NULL DEFAULT '',
+		uptime_seconds   INTEGER NOT NULL DEFAULT 0,
+		free_heap        INTEGER NOT NULL DEFAULT 0
+	);
+
+	CREATE TABLE locked_periods (
+		id        TEXT PRIMARY KEY,
+		period    TEXT NOT NULL UNIQUE,
+		locked    INTEGER NOT NULL DEFAULT 0,
+		locked_at TEXT NOT NULL DEFAULT '',
+		history   TEXT NOT NULL DEFAULT '[]'
+	);`,
+	`CREATE TABLE privacy_audit (
+		id          TEXT PRIMARY KEY,
+		employee_id TEXT NOT NULL,
+		consent     INTEGER NOT NULL DEFAULT 0,
+		changed_by  INTEGER NOT NULL DEFAULT 0,
+		changed_at  TEXT NOT NULL
+	);
+	CREATE INDEX idx_privacy_audit_employee ON privacy_audit (employee_id, changed_at);`,
+}
+
+// Open opens the database file at path, creating it and its schema on first use
+func Open(ctx context.Context, path string) (*DB, error) {
+	// WAL lets reports read while a check-in is written; busy_timeout waits out the
+	// writer instead of failing with SQLITE_BUSY
+	db, err := sql.Open(driverName, "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	// One connection serializes writes, which SQLite does anyway; a transaction then never
+	// waits for a lock held by another connection of the pool
+	db.SetMaxOpenConns(1)
+
+	d := &DB{db: db}
+	if err := d.migrate(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create the schema of %s: %w", path, err)
+	}
+	return d, nil
+}
+
+// Close closes the database
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+// migrate runs the migrations the database has not had yet
+func (d *DB) migrate(ctx context.Context) error {
+	var version int
+	if err := d.db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	for ; version < len(migrations); version++ {
+		tx, err := d.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, migrations[version]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", version+1, err)
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", version+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Employees returns the employee repository
+func (d *DB) Employees() *EmployeeRepository {
+	return &EmployeeRepository{db: d.db}
+}
+
+// Attendance returns the attendance repository; its writes honour the locked periods
+func (d *DB) Attendance() *AttendanceRepository {
+	return &AttendanceRepository{db: d.db, locks: d.PeriodLocks()}
+}
+
+// Devices returns the repository of the employees' extra devices
+func (d *DB) Devices() *DeviceRepository {
+	return &DeviceRepository{db: d.db, employees: d.Employees()}
+}
+
+// Detections returns the employee detection repository
+func (d *DB) Detections() *DetectionRepository {
+	return &DetectionRepository{db: d.db, locks: d.PeriodLocks()}
+}
+
+// Scanners returns the scanner repository
+func (d *DB) Scanners() *ScannerRepository {
+	return &ScannerRepository{db: d.db}
+}
+
+// PeriodLocks returns the locked payroll period repository
+func (d *DB) PeriodLocks() *PeriodLockRepository {
+	return &PeriodLockRepository{db: d.db}
+}
+
+// PrivacyAudit returns the repository of presence tracking consent changes
+func (d *DB) PrivacyAudit() *PrivacyAuditRepository {
+	return &PrivacyAuditRepository{db: d.db}
+}
+
+// SelfTest returns the self-test repository
+func (d *DB) SelfTest() *SelfTestRepository {
+	return &SelfTestRepository{db: d.db, employees: d.Employees()}
+}
+
+// scanner is a *sql.Row or *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// querier is a *sql.DB or *sql.Tx
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// timeLayout is how times are stored: UTC with a fixed width, so that they sort and
+// compare as text, like PocketBase's date fields
+const timeLayout = "2006-01-02 15:04:05.000Z"
+
+// formatTime formats t for storage; the zero time is stored as ""
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(timeLayout)
+}
+
+// parseTime parses a stored time into the configured timezone, the zero time for ""
+func parseTime(value string) time.Time {
+	t, err := time.Parse(timeLayout, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t.In(repository.Location())
+}
+
+// formatDay formats t's calendar day in the configured timezone, as created_date holds it
+func formatDay(t time.Time) string {
+	return t.In(repository.Location()).Format("2006-01-02")
+}
+
+// parseDay parses a stored calendar day as midnight in the configured timezone
+func parseDay(value string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02", value, repository.Location())
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// isUnique reports whether err is a violation of a UNIQUE constraint or index
+func isUnique(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// notFound turns sql.ErrNoRows into notFound, an error wrapping repository.ErrNotFound
+func notFound(err, notFound error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound
+	}
+	return err
+}
+
+// updated returns notFound when an UPDATE changed no row
+func updated(res sql.Result, err, notFound error) error {
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// offset is the first row of a page; pages start at 1
+func offset(page repository.Page) int {
+	if page.Number < 1 {
+		return 0
+	}
+	return (page.Number - 1) * page.Size
+}
+
+// bool01 stores a bool as SQLite's 0 or 1
+func bool01(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,775 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+var day = time.Date(2026, 1, 20, 8, 5, 0, 0, time.UTC)
+
+// sqlCase is a repository call against a seeded database. It returns a summary of the
+// result for comparison.
+type sqlCase struct {
+	name    string
+	run     func(ctx context.Context, db *DB) (string, error)
+	want    string
+	wantErr error
+}
+
+// open opens a new database in the test's temporary directory
+func open(t *testing.T) *DB {
+	t.Helper()
+	db, err := Open(context.Background(), filepath.Join(t.TempDir(), "med-pulse.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// testCases runs each case against a fresh database seeded by seed
+func testCases(t *testing.T, seed func(ctx context.Context, t *testing.T, db *DB), tests []sqlCase) {
+	repository.SetLocation(time.UTC)
+	t.Cleanup(func() { repository.SetLocation(time.Local) })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := open(t)
+			seed(ctx, t, db)
+
+			got, err := tt.run(ctx, db)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("error = %v", err)
+			} else if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func seedEmployees(ctx context.Context, t *testing.T, db *DB) {
+	for _, e := range []models.Employee{
+		{ID: "e1", Name: "Somchai", EmployeeCode: "N001", MacAddress: "AA:BB:CC:DD:EE:01", IsActive: true, ShowOnBoard: true, TelegramChatID: 1001},
+		{ID: "e2", Name: "Somsri", EmployeeCode: "N_02", MacAddress: "aa:bb:cc:dd:ee:02", TelegramChatID: 1002},
+		{ID: "e3", Name: "Malee", EmployeeCode: "N003", MacAddress: "aa:bb:cc:dd:ee:03", IsActive: true, IBeaconID: "fda50693-a4e2-4fb1-afcf-c6eb07647825:1:2"},
+		{ID: "e4", Name: "Self-test", MacAddress: "02:00:00:00:00:01", IsActive: true, IsSynthetic: true},
+		{ID: "e5", Name: "Somsak", EmployeeCode: "N_02", MacAddress: "aa:bb:cc:dd:ee:05", IsActive: true, MutedNotifications: []string{"checkout"}},
+	} {
+		if err := db.Employees().Create(ctx, &e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Attendance().Create(ctx, &models.Attendance{ID: "a1", EmployeeID: "e1", CheckInTime: day, CreatedDate: day}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func employeeIDs(employees []models.Employee) string {
+	var ids []string
+	for _, e := range employees {
+		ids = append(ids, e.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestEmployeeRepository(t *testing.T) {
+	testCases(t, seedEmployees, []sqlCase{
+		{
+			name: "get by MAC",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				emp, err := db.Employees().GetByMacAddress(ctx, "AA:BB:CC:DD:EE:01")
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%s %s board=%v checkin-opt-out=%v", emp.ID, emp.Name, emp.ShowOnBoard, emp.CheckInOptOut), nil
+			},
+			want: "e1 Somchai board=true checkin-opt-out=false",
+		},
+		{
+			name: "inactive employee's MAC",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				_, err := db.Employees().GetByMacAddress(ctx, "aa:bb:cc:dd:ee:02")
+				return "", err
+			},
+			wantErr: repository.ErrEmployeeNotFound,
+		},
+		{
+			name: "get by iBeacon",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				emp, err := db.Employees().GetByBeacon(ctx, "FDA50693-A4E2-4FB1-AFCF-C6EB07647825", 1, 2)
+				if err != nil {
+					return "", err
+				}
+				return emp.ID, nil
+			},
+			want: "e3",
+		},
+		{
+			name: "active employee wins the code",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				emp, err := db.Employees().GetByCode(ctx, "N_02")
+				if err != nil {
+					return "", err
+				}
+				return emp.ID + " " + strings.Join(emp.MutedNotifications, ","), nil
+			},
+			want: "e5 checkout",
+		},
+		{
+			name: "list active without the self-test employee",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				employees, err := db.Employees().ListActive(ctx)
+				return employeeIDs(employees), err
+			},
+			want: "e3,e1,e5",
+		},
+		{
+			name: "page by name",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				employees, total, err := db.Employees().PageActive(ctx, repository.Page{Number: 2, Size: 2})
+				return fmt.Sprintf("%s of %d", employeeIDs(employees), total), err
+			},
+			want: "e5 of 3",
+		},
+		{
+			name: "search ignores case and escapes LIKE wildcards",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				byName, _, err := db.Employees().List(ctx, "SOM", repository.Page{Number: 1, Size: 10})
+				if err != nil {
+					return "", err
+				}
+				byCode, _, err := db.Employees().List(ctx, "_0", repository.Page{Number: 1, Size: 10})
+				return employeeIDs(byName) + " " + employeeIDs(byCode), err
+			},
+			want: "e1,e5 e5",
+		},
+		{
+			name: "checked in on the day",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				today, err := db.Employees().IsCheckedInOn(ctx, "e1", day.Add(10*time.Hour))
+				if err != nil {
+					return "", err
+				}
+				tomorrow, err := db.Employees().IsCheckedInOn(ctx, "e1", day.AddDate(0, 0, 1))
+				return fmt.Sprint(today, tomorrow), err
+			},
+			want: "true false",
+		},
+		{
+			name: "notification settings",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				if err := db.Employees().UpdateNotificationPrefs(ctx, "e1", models.NotificationPrefs{QuietHours: "22:00-07:00"}); err != nil {
+					return "", err
+				}
+				emp, err := db.Employees().GetByCode(ctx, "N001")
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprint(emp.CheckInOptOut, " ", emp.QuietHours), nil
+			},
+			want: "true 22:00-07:00",
+		},
+		{
+			name: "get by chat",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				emp, err := db.Employees().GetByChatID(ctx, 1001)
+				if err != nil {
+					return "", err
+				}
+				return emp.ID, nil
+			},
+			want: "e1",
+		},
+		{
+			name: "inactive employee's chat",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				_, err := db.Employees().GetByChatID(ctx, 1002)
+				return "", err
+			},
+			wantErr: repository.ErrEmployeeNotFound,
+		},
+		{
+			name: "owner of an inactive employee's MAC",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				emp, err := db.Employees().GetOwnerByMac(ctx, "AA:BB:CC:DD:EE:02")
+				if err != nil {
+					return "", err
+				}
+				return emp.ID, nil
+			},
+			want: "e2",
+		},
+		{
+			name: "register a taken MAC",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				return "", db.Employees().Register(ctx, &models.Employee{Name: "Wichai", MacAddress: "AA:BB:CC:DD:EE:02", TelegramChatID: 1006})
+			},
+			wantErr: repository.ErrEmployeeExists,
+		},
+		{
+			name: "update settings",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				emp, err := db.Employees().GetByChatID(ctx, 1001)
+				if err != nil {
+					return "", err
+				}
+				emp.MacAddress, emp.Language, emp.PresenceOptOut = "AA:BB:CC:DD:EE:0A", models.LanguageEnglish, true
+				if err := db.Employees().UpdateSettings(ctx, emp); err != nil {
+					return "", err
+				}
+				emp, err = db.Employees().GetByChatID(ctx, 1001)
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%s %s opt-out=%v board=%v", emp.MacAddress, emp.Language, emp.PresenceOptOut, emp.ShowOnBoard), nil
+			},
+			want: "aa:bb:cc:dd:ee:0a en opt-out=true board=true",
+		},
+		{
+			name: "update of a missing employee",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				return "", db.Employees().SetActive(ctx, "missing", false)
+			},
+			wantErr: repository.ErrNotFound,
+		},
+		{
+			name: "guest",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				guest := &models.Employee{Name: "Visitor", MacAddress: "AA:BB:CC:DD:EE:09", GuestUntil: day}
+				if err := db.Employees().CreateGuest(ctx, guest); err != nil {
+					return "", err
+				}
+				emp, err := db.Employees().GetByMacAddress(ctx, "aa:bb:cc:dd:ee:09")
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%v %v %s board=%v", emp.ID == guest.ID, emp.IsGuest, emp.GuestUntil.Format("2006-01-02"), emp.ShowOnBoard), nil
+			},
+			want: "true true 2026-01-20 board=false",
+		},
+	})
+}
+
+func seedAttendance(ctx context.Context, t *testing.T, db *DB) {
+	for _, a := range []models.Attendance{
+		{ID: "a2", EmployeeID: "e2", CheckInTime: day.Add(time.Hour), CreatedDate: day},
+		{ID: "a1", EmployeeID: "e1", CheckInTime: day, CreatedDate: day, Status: models.AttendanceStatusLate},
+		{ID: "a3", EmployeeID: "e1", CheckInTime: day.AddDate(0, 0, 1), CreatedDate: day.AddDate(0, 0, 1),
+			CheckOutTime: day.AddDate(0, 0, 1).Add(9 * time.Hour), CheckOutSource: models.CheckOutSourceScanner},
+		{ID: "a4", CheckInTime: day.AddDate(0, 0, 2), CreatedDate: day.AddDate(0, 0, 2)},
+	} {
+		if err := db.Attendance().Create(ctx, &a); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func attendanceIDs(attendance []models.Attendance) string {
+	var ids []string
+	for _, a := range attendance {
+		ids = append(ids, a.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestAttendanceRepository(t *testing.T) {
+	testCases(t, seedAttendance, []sqlCase{
+		{
+			name: "second check-in of the day",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				return "", db.Attendance().Create(ctx, &models.Attendance{EmployeeID: "e1", CheckInTime: day.Add(2 * time.Hour), CreatedDate: day})
+			},
+			wantErr: repository.ErrAttendanceExists,
+		},
+		{
+			name: "retried check-in",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				if err := db.Attendance().Create(ctx, &models.Attendance{ID: "a1", EmployeeID: "e1", CheckInTime: day, CreatedDate: day}); err != nil {
+					return "", err
+				}
+				records, err := db.Attendance().ListByDate(ctx, day)
+				return attendanceIDs(records), err
+			},
+			want: "a1,a2",
+		},
+		{
+			name: "check-ins without an employee never collide",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				a := models.Attendance{CheckInTime: day.AddDate(0, 0, 2), CreatedDate: day.AddDate(0, 0, 2)}
+				if err := db.Attendance().Create(ctx, &a); err != nil {
+					return "", err
+				}
+				records, err := db.Attendance().ListUnlinked(ctx)
+				return fmt.Sprint(len(records), " ", a.ID != ""), err
+			},
+			want: "2 true",
+		},
+		{
+			name: "check-in in a locked period",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				if err := db.PeriodLocks().Save(ctx, &models.LockedPeriod{Period: "2026-02", Locked: true, LockedAt: day}); err != nil {
+					return "", err
+				}
+				feb := time.Date(2026, 2, 2, 8, 0, 0, 0, time.UTC)
+				return "", db.Attendance().Create(ctx, &models.Attendance{EmployeeID: "e1", CheckInTime: feb, CreatedDate: feb})
+			},
+			wantErr: repository.ErrPeriodLocked,
+		},
+		{
+			name: "relink into a locked period",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				if err := db.PeriodLocks().Save(ctx, &models.LockedPeriod{Period: "2026-01", Locked: true, LockedAt: day}); err != nil {
+					return "", err
+				}
+				return "", db.Attendance().Relink(ctx, "a4", "e2")
+			},
+			wantErr: repository.ErrPeriodLocked,
+		},
+		{
+			name: "list since and between",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				since, err := db.Attendance().ListSince(ctx, day.AddDate(0, 0, 1))
+				if err != nil {
+					return "", err
+				}
+				between, err := db.Attendance().ListBetween(ctx, day, day.AddDate(0, 0, 2))
+				return attendanceIDs(since) + " " + attendanceIDs(between), err
+			},
+			want: "a3,a4 a1,a2,a3",
+		},
+		{
+			name: "open records of the day",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				records, err := db.Attendance().ListOpenForDate(ctx, day)
+				return attendanceIDs(records), err
+			},
+			want: "a1,a2",
+		},
+		{
+			name: "history, newest first",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				records, total, err := db.Attendance().ListHistory(ctx, "e1", day, day.AddDate(0, 0, 1), repository.Page{Number: 1, Size: 10})
+				return fmt.Sprintf("%s of %d", attendanceIDs(records), total), err
+			},
+			want: "a3,a1 of 2",
+		},
+		{
+			name: "employee's page",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				records, total, err := db.Attendance().PageByEmployeeAndRange(ctx, "e1", day, day.AddDate(0, 1, 0), repository.Page{Number: 1, Size: 1})
+				return fmt.Sprintf("%s of %d", attendanceIDs(records), total), err
+			},
+			want: "a1 of 2",
+		},
+		{
+			name: "times and status read back",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				a, err := db.Attendance().Get(ctx, "a3")
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%s %s %s %s", a.CheckInTime.Format(time.RFC3339), a.CheckOutTime.Format(time.RFC3339),
+					a.CheckOutSource, a.CreatedDate.Format("2006-01-02")), nil
+			},
+			want: "2026-01-21T08:05:00Z 2026-01-21T17:05:00Z scanner 2026-01-21",
+		},
+		{
+			name: "check-out",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				a := &models.Attendance{ID: "a1", CreatedDate: day, CheckOutTime: day.Add(9 * time.Hour),
+					CheckOutSource: models.CheckOutSourceSelfReported, NeedsReview: true}
+				if err := db.Attendance().UpdateCheckOut(ctx, a); err != nil {
+					return "", err
+				}
+				got, err := db.Attendance().Get(ctx, "a1")
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprint(got.CheckOutTime.Sub(got.CheckInTime), " ", got.NeedsReview, " ", got.Status), nil
+			},
+			want: "9h0m0s true late",
+		},
+		{
+			name: "missing record",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				_, err := db.Attendance().Get(ctx, "missing")
+				return "", err
+			},
+			wantErr: repository.ErrNotFound,
+		},
+	})
+}
+
+func seedDetections(ctx context.Context, t *testing.T, db *DB) {
+	for _, d := range []models.EmployeeDetection{
+		{EmployeeID: "e1", MacAddress: "AA:BB:CC:DD:EE:01", ScannerMac: "scanner-1", RSSI: -60, DetectedAt: day},
+		{EmployeeID: "e1", MacAddress: "aa:bb:cc:dd:ee:01", ScannerMac: "scanner-2", RSSI: -70, DetectedAt: day.Add(8 * time.Hour)},
+		{EmployeeID: "e1", MacAddress: "aa:bb:cc:dd:ee:01", ScannerMac: "scanner-1", RSSI: -65, DetectedAt: day.AddDate(0, 0, 1)},
+		{MacAddress: "aa:bb:cc:dd:ee:09", ScannerMac: "scanner-1", RSSI: -80, DetectedAt: day.Add(time.Hour),
+			IBeaconUUID: "fda50693-a4e2-4fb1-afcf-c6eb07647825", Major: 1, Minor: 2},
+	} {
+		if err := db.Detections().Create(ctx, &d); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDetectionRepository(t *testing.T) {
+	testCases(t, seedDetections, []sqlCase{
+		{
+			name: "employee's detections since",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				detections, err := db.Detections().ListByEmployeeSince(ctx, "e1", day.Add(time.Minute))
+				var rssi []string
+				for _, d := range detections {
+					rssi = append(rssi, fmt.Sprint(d.RSSI))
+				}
+				return strings.Join(rssi, ","), err
+			},
+			want: "-70,-65",
+		},
+		{
+			name: "last of the day",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				d, err := db.Detections().GetLastForEmployeeOnDate(ctx, "e1", day)
+				if err != nil || d == nil {
+					return fmt.Sprint(d), err
+				}
+				return d.ScannerMac + " " + d.DetectedAt.Format(time.RFC3339), nil
+			},
+			want: "scanner-2 2026-01-20T16:05:00Z",
+		},
+		{
+			name: "no detection that day",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				d, err := db.Detections().GetLastForEmployeeOnDate(ctx, "e1", day.AddDate(0, 0, -1))
+				return fmt.Sprint(d), err
+			},
+			want: "<nil>",
+		},
+		{
+			name: "relink an unlinked beacon detection",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				unlinked, err := db.Detections().ListUnlinked(ctx)
+				if err != nil || len(unlinked) != 1 {
+					return fmt.Sprint(len(unlinked)), err
+				}
+				if err := db.Detections().Relink(ctx, unlinked[0].ID, "e3"); err != nil {
+					return "", err
+				}
+				recent, err := db.Detections().ListRecent(ctx, day.Add(time.Hour))
+				if err != nil {
+					return "", err
+				}
+				d := recent[0]
+				return fmt.Sprintf("%s %s %d:%d", d.EmployeeID, d.IBeaconUUID, d.Major, d.Minor), nil
+			},
+			want: "e3 fda50693-a4e2-4fb1-afcf-c6eb07647825 1:2",
+		},
+	})
+}
+
+func seedScanners(ctx context.Context, t *testing.T, db *DB) {
+	// Stored before scanner IDs were normalized
+	if _, err := db.db.ExecContext(ctx, "INSERT INTO scanners (id, scanner_mac, last_seen, zone) VALUES ('s1', 'AA-BB-CC-00-00-01', ?, 'lobby')",
+		formatTime(day)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScannerRepository(t *testing.T) {
+	testCases(t, seedScanners, []sqlCase{
+		{
+			name: "activity rewrites an old spelling and keeps unreported health",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				scanners := db.Scanners()
+				if err := scanners.UpdateActivity(ctx, "aa:bb:cc:00:00:01", models.ScannerHealth{FirmwareVersion: "1.4.0", FreeHeap: 40000}); err != nil {
+					return "", err
+				}
+				if err := scanners.UpdateActivity(ctx, "AA:BB:CC:00:00:01", models.ScannerHealth{UptimeSeconds: 60}); err != nil {
+					return "", err
+				}
+				list, err := scanners.List(ctx)
+				if err != nil {
+					return "", err
+				}
+				var out []string
+				for _, sc := range list {
+					out = append(out, fmt.Sprintf("%s %s %s %s %d %d", sc.ID, sc.ScannerMac, sc.Zone,
+						sc.Health.FirmwareVersion, sc.Health.UptimeSeconds, sc.Health.FreeHeap))
+				}
+				return strings.Join(out, "; "), nil
+			},
+			want: "s1 aa:bb:cc:00:00:01 lobby 1.4.0 60 40000",
+		},
+		{
+			name: "pair a new scanner",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				if err := db.Scanners().Pair(ctx, "esp32-door", "door", day); err != nil {
+					return "", err
+				}
+				sc, err := db.Scanners().GetByMac(ctx, "ESP32-DOOR")
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%s %s %s", sc.ScannerMac, sc.Zone, sc.PairedAt.Format(time.RFC3339)), nil
+			},
+			want: "esp32-door door 2026-01-20T08:05:00Z",
+		},
+		{
+			name: "token",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				if err := db.Scanners().SetToken(ctx, "aa:bb:cc:00:00:01", "secret"); err != nil {
+					return "", err
+				}
+				sc, err := db.Scanners().GetByToken(ctx, "secret")
+				if err != nil {
+					return "", err
+				}
+				return sc.ID, nil
+			},
+			want: "s1",
+		},
+		{
+			name: "revoked token",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				if err := db.Scanners().SetToken(ctx, "aa:bb:cc:00:00:01", "secret"); err != nil {
+					return "", err
+				}
+				if err := db.Scanners().SetToken(ctx, "aa:bb:cc:00:00:01", ""); err != nil {
+					return "", err
+				}
+				_, err := db.Scanners().GetByToken(ctx, "secret")
+				return "", err
+			},
+			wantErr: repository.ErrScannerNotFound,
+		},
+		{
+			name: "unknown scanner",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				_, err := db.Scanners().GetByMac(ctx, "aa:bb:cc:00:00:02")
+				return "", err
+			},
+			wantErr: repository.ErrScannerNotFound,
+		},
+	})
+}
+
+func TestDeviceRepository(t *testing.T) {
+	testCases(t, seedEmployees, []sqlCase{
+		{
+			name: "extra device",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				emp := &models.Employee{ID: "e1", MacAddress: "aa:bb:cc:dd:ee:01"}
+				if err := db.Devices().AssignDevice(ctx, emp, "AA:BB:CC:DD:EE:10"); err != nil {
+					return "", err
+				}
+				owner, err := db.Devices().GetEmployeeByDeviceMac(ctx, "aa:bb:cc:dd:ee:10")
+				if err != nil {
+					return "", err
+				}
+				return owner.ID, nil
+			},
+			want: "e1",
+		},
+		{
+			name: "first device becomes the employee's MAC",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				emp := &models.Employee{ID: "e6", Name: "Manee", IsActive: true}
+				if err := db.Employees().Create(ctx, emp); err != nil {
+					return "", err
+				}
+				if err := db.Devices().AssignDevice(ctx, emp, "AA:BB:CC:DD:EE:06"); err != nil {
+					return "", err
+				}
+				owner, err := db.Employees().GetByMacAddress(ctx, "aa:bb:cc:dd:ee:06")
+				if err != nil {
+					return "", err
+				}
+				return owner.ID, nil
+			},
+			want: "e6",
+		},
+		{
+			name: "device of an inactive employee",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				emp := &models.Employee{ID: "e2", MacAddress: "aa:bb:cc:dd:ee:02"}
+				if err := db.Devices().AssignDevice(ctx, emp, "aa:bb:cc:dd:ee:20"); err != nil {
+					return "", err
+				}
+				_, err := db.Devices().GetEmployeeByDeviceMac(ctx, "aa:bb:cc:dd:ee:20")
+				return "", err
+			},
+			wantErr: repository.ErrEmployeeNotFound,
+		},
+		{
+			name: "added and listed",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				for _, mac := range []string{"AA:BB:CC:DD:EE:12", "aa:bb:cc:dd:ee:11"} {
+					if err := db.Devices().AddDevice(ctx, &models.EmployeeDevice{EmployeeID: "e1", MacAddress: mac, Label: "iTag"}); err != nil {
+						return "", err
+					}
+				}
+				devices, err := db.Devices().ListDevices(ctx, "e1")
+				var macs []string
+				for _, d := range devices {
+					macs = append(macs, d.MacAddress+" "+d.Label)
+				}
+				return strings.Join(macs, ","), err
+			},
+			want: "aa:bb:cc:dd:ee:11 iTag,aa:bb:cc:dd:ee:12 iTag",
+		},
+		{
+			name: "added twice",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				if err := db.Devices().AddDevice(ctx, &models.EmployeeDevice{EmployeeID: "e1", MacAddress: "aa:bb:cc:dd:ee:11"}); err != nil {
+					return "", err
+				}
+				return "", db.Devices().AddDevice(ctx, &models.EmployeeDevice{EmployeeID: "e3", MacAddress: "AA:BB:CC:DD:EE:11"})
+			},
+			wantErr: repository.ErrDeviceExists,
+		},
+		{
+			name: "removed",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				if err := db.Devices().AddDevice(ctx, &models.EmployeeDevice{EmployeeID: "e1", MacAddress: "aa:bb:cc:dd:ee:11"}); err != nil {
+					return "", err
+				}
+				if err := db.Devices().RemoveDevice(ctx, "e1", "AA:BB:CC:DD:EE:11"); err != nil {
+					return "", err
+				}
+				devices, err := db.Devices().ListDevices(ctx, "e1")
+				return fmt.Sprint(len(devices)), err
+			},
+			want: "0",
+		},
+		{
+			name: "removing another employee's device",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				if err := db.Devices().AddDevice(ctx, &models.EmployeeDevice{EmployeeID: "e1", MacAddress: "aa:bb:cc:dd:ee:11"}); err != nil {
+					return "", err
+				}
+				return "", db.Devices().RemoveDevice(ctx, "e3", "aa:bb:cc:dd:ee:11")
+			},
+			wantErr: repository.ErrDeviceNotFound,
+		},
+	})
+}
+
+func TestPrivacyAuditRepository(t *testing.T) {
+	ctx := context.Background()
+	db := open(t)
+	for _, consent := range []bool{false, true} {
+		if err := db.PrivacyAudit().RecordConsent(ctx, "e1", consent, 1001, day); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var n, consented int
+	if err := db.db.QueryRowContext(ctx, "SELECT COUNT(*), SUM(consent) FROM privacy_audit WHERE employee_id = 'e1' AND changed_by = 1001").Scan(&n, &consented); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || consented != 1 {
+		t.Errorf("privacy_audit has %d records, %d consenting; want 2, 1", n, consented)
+	}
+}
+
+func TestSelfTestRepository(t *testing.T) {
+	testCases(t, seedEmployees, []sqlCase{
+		{
+			name: "existing synthetic employee",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				emp, err := db.SelfTest().EnsureSyntheticEmployee(ctx, "02:00:00:00:00:01")
+				if err != nil {
+					return "", err
+				}
+				return emp.ID, nil
+			},
+			want: "e4",
+		},
+		{
+			name: "created once",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				first, err := db.SelfTest().EnsureSyntheticEmployee(ctx, "02:00:00:00:00:09")
+				if err != nil {
+					return "", err
+				}
+				again, err := db.SelfTest().EnsureSyntheticEmployee(ctx, "02:00:00:00:00:09")
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%t %t %t", again.ID == first.ID, again.IsSynthetic, again.ShowOnBoard), nil
+			},
+			want: "true true false",
+		},
+		{
+			name: "real employee's MAC",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				_, err := db.SelfTest().EnsureSyntheticEmployee(ctx, "AA:BB:CC:DD:EE:01")
+				return fmt.Sprint(err), nil
+			},
+			want: "self-test MAC aa:bb:cc:dd:ee:01 belongs to employee e1",
+		},
+		{
+			name: "count and delete old records",
+			run: func(ctx context.Context, db *DB) (string, error) {
+				for _, at := range []time.Time{day, day.Add(time.Hour)} {
+					if err := db.Detections().Create(ctx, &models.EmployeeDetection{EmployeeID: "e4", DetectedAt: at}); err != nil {
+						return "", err
+					}
+				}
+				att := &models.Attendance{EmployeeID: "e4", CheckInTime: day, CreatedDate: day}
+				if err := db.Attendance().Create(ctx, att); err != nil {
+					return "", err
+				}
+				deleted, err := db.SelfTest().DeleteRecordsBefore(ctx, "e4", day.Add(time.Minute))
+				if err != nil {
+					return "", err
+				}
+				left, err := db.SelfTest().CountDetectionsSince(ctx, "e4", day)
+				return fmt.Sprintf("deleted %d, left %d", deleted, left), err
+			},
+			want: "deleted 2, left 1",
+		},
+	})
+}
+
+func TestOpenKeepsData(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "med-pulse.db")
+	db, err := Open(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock := &models.LockedPeriod{Period: "2026-01", Locked: true, LockedAt: day,
+		History: []models.PeriodLockEvent{{Action: models.PeriodActionLock, RequestedBy: 1, ApprovedBy: 2, At: day}}}
+	if err := db.PeriodLocks().Save(ctx, lock); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	db, err = Open(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	locked, err := db.PeriodLocks().ListLocked(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locked) != 1 || locked[0].ID != lock.ID || len(locked[0].History) != 1 || !locked[0].LockedAt.Equal(day) {
+		t.Errorf("locked periods after reopening = %+v, want %+v", locked, lock)
+	}
+}
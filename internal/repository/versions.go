@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/models"
+)
+
+// VersionableAttendance is what VersionedAttendanceRepository wraps
+type VersionableAttendance interface {
+	AttendanceRepository
+	AttendanceLog
+	AttendanceToday
+	AttendanceUpdater
+}
+
+// DataVersions counts the attendance changes of each employee. Caches remember the
+// version their entry was built from and rebuild it once the version has moved on, so
+// a read right after a check-in, manual check-in or check-out correction never gets
+// the data from before it.
+type DataVersions struct {
+	mu       sync.Mutex
+	employee map[string]uint64 // employee ID → changes
+	global   uint64            // changes of any employee
+}
+
+// NewDataVersions creates a version counter where nothing has changed yet
+func NewDataVersions() *DataVersions {
+	return &DataVersions{employee: make(map[string]uint64)}
+}
+
+// Bump records a change of the employee's attendance
+func (v *DataVersions) Bump(employeeID string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.employee[employeeID]++
+	v.global++
+}
+
+// Of returns the version of the employee's attendance
+func (v *DataVersions) Of(employeeID string) uint64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.employee[employeeID]
+}
+
+// Global returns the version of all attendance
+func (v *DataVersions) Global() uint64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.global
+}
+
+// VersionedAttendanceRepository bumps the employee's data version after every
+// attendance record it writes. Writes that do not go through it are not seen.
+type VersionedAttendanceRepository struct {
+	next     VersionableAttendance
+	versions *DataVersions
+}
+
+// NewVersionedAttendanceRepository bumps versions on the writes made through next
+func NewVersionedAttendanceRepository(next VersionableAttendance, versions *DataVersions) *VersionedAttendanceRepository {
+	return &VersionedAttendanceRepository{next: next, versions: versions}
+}
+
+// Create records the check-in and bumps the employee's version
+func (r *VersionedAttendanceRepository) Create(ctx context.Context, attendance *models.Attendance) error {
+	if err := r.next.Create(ctx, attendance); err != nil {
+		return err
+	}
+	r.versions.Bump(attendance.EmployeeID)
+	return nil
+}
+
+// UpdateCheckOut writes the check-out and bumps the employee's version
+func (r *VersionedAttendanceRepository) UpdateCheckOut(ctx context.Context, attendance *models.Attendance) error {
+	if err := r.next.UpdateCheckOut(ctx, attendance); err != nil {
+		return err
+	}
+	r.versions.Bump(attendance.EmployeeID)
+	return nil
+}
+
+// ListByDate is passed through
+func (r *VersionedAttendanceRepository) ListByDate(ctx context.Context, date time.Time) ([]models.Attendance, error) {
+	return r.next.ListByDate(ctx, date)
+}
+
+// ListSince is passed through
+func (r *VersionedAttendanceRepository) ListSince(ctx context.Context, since time.Time) ([]models.Attendance, error) {
+	return r.next.ListSince(ctx, since)
+}
+
+// ListBetween is passed through
+func (r *VersionedAttendanceRepository) ListBetween(ctx context.Context, from, to time.Time) ([]models.Attendance, error) {
+	return r.next.ListBetween(ctx, from, to)
+}
+
+// ListOpenForDate is passed through
+func (r *VersionedAttendanceRepository) ListOpenForDate(ctx context.Context, date time.Time) ([]models.Attendance, error) {
+	return r.next.ListOpenForDate(ctx, date)
+}
+
+// ListByEmployeeAndRange is passed through
+func (r *VersionedAttendanceRepository) ListByEmployeeAndRange(ctx context.Context, employeeID string, from, to time.Time) ([]models.Attendance, error) {
+	return r.next.ListByEmployeeAndRange(ctx, employeeID, from, to)
+}
+
+// GetTodayByEmployee is passed through
+func (r *VersionedAttendanceRepository) GetTodayByEmployee(ctx context.Context, employeeID string) (*models.Attendance, error) {
+	return r.next.GetTodayByEmployee(ctx, employeeID)
+}
+
+// GetByEmployeeOn is passed through
+func (r *VersionedAttendanceRepository) GetByEmployeeOn(ctx context.Context, employeeID string, day time.Time) (*models.Attendance, error) {
+	return r.next.GetByEmployeeOn(ctx, employeeID, day)
+}
+
+// Get is passed through
+func (r *VersionedAttendanceRepository) Get(ctx context.Context, id string) (*models.Attendance, error) {
+	return r.next.Get(ctx, id)
+}
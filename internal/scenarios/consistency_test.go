@@ -0,0 +1,122 @@
+package scenarios
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/handlers"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+	"med-pulse-bot/internal/repository/memory"
+	"med-pulse-bot/internal/services"
+)
+
+// TestReadAfterMutation writes attendance through every path and reads it straight back
+// with the employee cache, the recent detection cache and the board cache all enabled,
+// as in production
+func TestReadAfterMutation(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2026, 2, 2, 8, 0, 0, 0, time.Local))
+	store := memory.NewStore(clk)
+	store.AddEmployee(models.Employee{ID: "e1", Name: "Somchai", EmployeeCode: "E001", MacAddress: "aa:bb:cc:dd:ee:01",
+		TelegramChatID: 1001, WorkStartTime: "08:00:00", IsActive: true, ShowOnBoard: true})
+	store.AddEmployee(models.Employee{ID: "e2", Name: "Suda", EmployeeCode: "E002", MacAddress: "aa:bb:cc:dd:ee:02",
+		TelegramChatID: 1002, WorkStartTime: "08:00:00", IsActive: true, ShowOnBoard: true})
+
+	versions := repository.NewDataVersions()
+	attendance := repository.NewVersionedAttendanceRepository(store.AttendanceRecords(), versions)
+	employees := repository.NewCachedEmployeeRepository(store.Employees(), 5*time.Minute, time.Minute)
+	employees.SetClock(clk)
+	notifier := &recordingNotifier{}
+
+	service := services.NewAttendanceService(employees, attendance, store.DetectionRecords(), store.ScannerRecords(), notifier)
+	service.SetClock(clk)
+	recent := services.NewRecentDetections(services.DefaultRecentDetectionTTL)
+	recent.SetDataVersions(versions)
+	service.SetRecentDetections(recent)
+	h := &harness{clock: clk, store: store, notifier: notifier, handler: handlers.NewDetectionHandler(service)}
+
+	board := services.NewBoardService(employees, attendance)
+	board.SetClock(clk)
+	board.SetDataVersions(versions)
+	manual := services.NewManualCheckIns(employees, attendance, notifier)
+	manual.SetClock(clk)
+
+	present := func(t *testing.T, name string) bool {
+		t.Helper()
+		entries, err := board.Board(ctx)
+		if err != nil {
+			t.Fatalf("Board() error = %v", err)
+		}
+		for _, e := range entries {
+			if e.DisplayName == name {
+				return e.Present
+			}
+		}
+		t.Fatalf("%s is not on the board", name)
+		return false
+	}
+	detect := func(t *testing.T) {
+		t.Helper()
+		if code := h.detect(t, &detectionSpec{ScannerMac: "AA:BB:CC:00:00:01", MacAddress: "aa:bb:cc:dd:ee:01", RSSI: -50}); code != http.StatusOK {
+			t.Fatalf("detect status = %d", code)
+		}
+	}
+
+	t.Run("board is cached", func(t *testing.T) {
+		if present(t, "Suda") || present(t, "Somchai") {
+			t.Fatal("nobody has checked in yet")
+		}
+	})
+
+	t.Run("manual check-in is read back at once", func(t *testing.T) {
+		clk.Advance(10 * time.Second)
+		if _, _, err := manual.CheckIn(ctx, "E002", 8, 0); err != nil {
+			t.Fatalf("CheckIn() error = %v", err)
+		}
+		if att, err := attendance.GetTodayByEmployee(ctx, "e2"); err != nil || att == nil {
+			t.Errorf("today's attendance = %v, %v, want the manual check-in", att, err)
+		}
+		if !present(t, "Suda") {
+			t.Error("board still shows Suda absent")
+		}
+	})
+
+	t.Run("scanner check-in is read back at once", func(t *testing.T) {
+		detect(t)
+		if !present(t, "Somchai") {
+			t.Error("board still shows Somchai absent")
+		}
+	})
+
+	t.Run("repeat is dropped", func(t *testing.T) {
+		clk.Advance(5 * time.Second)
+		detect(t)
+		if got := recent.Suppressed(); got != 1 {
+			t.Errorf("suppressed = %d, want 1", got)
+		}
+	})
+
+	t.Run("correction ends the repeat", func(t *testing.T) {
+		att, err := attendance.GetTodayByEmployee(ctx, "e1")
+		if err != nil || att == nil {
+			t.Fatalf("today's attendance = %v, %v", att, err)
+		}
+		att.CheckOutTime = clk.Now()
+		att.CheckOutSource = models.CheckOutSourceSelfReported
+		if err := attendance.UpdateCheckOut(ctx, att); err != nil {
+			t.Fatalf("UpdateCheckOut() error = %v", err)
+		}
+		clk.Advance(5 * time.Second)
+		detect(t)
+		if got := recent.Suppressed(); got != 1 {
+			t.Errorf("suppressed = %d, want the detection after the correction looked up again", got)
+		}
+		if att, _ := attendance.GetTodayByEmployee(ctx, "e1"); att == nil || att.CheckOutTime.IsZero() {
+			t.Errorf("today's attendance = %+v, want the corrected check-out", att)
+		}
+	})
+}
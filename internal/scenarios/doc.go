@@ -0,0 +1,7 @@
+// Package scenarios holds deterministic end-to-end regression scenarios.
+//
+// Each testdata/*.yaml file describes employees, a timeline of detections at fake-clock
+// timestamps, and the expected attendance rows and notifications. The scenarios run
+// through the real detection handler and AttendanceService on top of the in-memory
+// repositories, so `go test ./...` replays every known past bug on each change.
+package scenarios
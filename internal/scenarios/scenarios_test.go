@@ -0,0 +1,292 @@
+package scenarios
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/handlers"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+	"med-pulse-bot/internal/services"
+)
+
+const timestampLayout = "2006-01-02 15:04:05"
+
+// scenario is the YAML fixture format
+type scenario struct {
+	Name      string           `yaml:"name"`
+	Start     string           `yaml:"start"`
+	Employees []employeeSpec   `yaml:"employees"`
+	Timeline  []timelineStep   `yaml:"timeline"`
+	Expect    expectedOutcomes `yaml:"expect"`
+}
+
+type employeeSpec struct {
+	ID             string `yaml:"id"`
+	Name           string `yaml:"name"`
+	MacAddress     string `yaml:"mac_address"`
+	TelegramChatID int64  `yaml:"telegram_chat_id"`
+	WorkStartTime  string `yaml:"work_start_time"`
+	IsActive       *bool  `yaml:"is_active"`
+}
+
+type timelineStep struct {
+	At         string         `yaml:"at"`
+	Detect     *detectionSpec `yaml:"detect"`
+	WantStatus int            `yaml:"want_status"`
+	Repeat     int            `yaml:"repeat"`
+	Every      string         `yaml:"every"`
+}
+
+type detectionSpec struct {
+	ScannerMac string `yaml:"scanner_mac" json:"scanner_mac"`
+	MacAddress string `yaml:"mac_address" json:"mac_address"`
+	RSSI       int    `yaml:"rssi" json:"rssi"`
+	DeviceType string `yaml:"device_type" json:"device_type,omitempty"`
+}
+
+type expectedOutcomes struct {
+	Attendance    []expectedAttendance  `yaml:"attendance"`
+	Detections    *int                  `yaml:"detections"`
+	Notifications expectedNotifications `yaml:"notifications"`
+}
+
+type expectedAttendance struct {
+	Employee string `yaml:"employee"`
+	Date     string `yaml:"date"`
+	CheckIn  string `yaml:"check_in"`
+	CheckOut string `yaml:"check_out"`
+	Status   string `yaml:"status"`
+}
+
+type expectedNotifications struct {
+	Personal []expectedMessage `yaml:"personal"`
+	Admin    []expectedMessage `yaml:"admin"`
+}
+
+type expectedMessage struct {
+	ChatID   int64  `yaml:"chat_id"`
+	Contains string `yaml:"contains"`
+}
+
+type sentMessage struct {
+	chatID int64
+	text   string
+}
+
+// recordingNotifier captures notifications instead of sending them
+type recordingNotifier struct {
+	personal []sentMessage
+	admin    []sentMessage
+}
+
+func (n *recordingNotifier) SendNotification(message string) {
+	n.admin = append(n.admin, sentMessage{text: message})
+}
+
+func (n *recordingNotifier) SendPersonalNotification(chatID int64, message string) {
+	n.personal = append(n.personal, sentMessage{chatID: chatID, text: message})
+}
+
+// harness wires the real handler and service to in-memory repositories and a fake clock
+type harness struct {
+	clock    *clock.Fake
+	store    *memory.Store
+	notifier *recordingNotifier
+	handler  *handlers.DetectionHandler
+}
+
+func newHarness(start time.Time) *harness {
+	clk := clock.NewFake(start)
+	store := memory.NewStore(clk)
+	notifier := &recordingNotifier{}
+
+	service := services.NewAttendanceService(
+		store.Employees(),
+		store.AttendanceRecords(),
+		store.DetectionRecords(),
+		store.ScannerRecords(),
+		notifier,
+	)
+	service.SetClock(clk)
+
+	// Check-out tracking is on by default in production
+	tracker, err := services.NewCheckOutTracker(services.DefaultCheckOutTrackerConfig(), store.AttendanceRecords(), notifier)
+	if err != nil {
+		panic(err)
+	}
+	service.SetCheckOutTracker(tracker)
+
+	return &harness{
+		clock:    clk,
+		store:    store,
+		notifier: notifier,
+		handler:  handlers.NewDetectionHandler(service),
+	}
+}
+
+func (h *harness) detect(t *testing.T, d *detectionSpec) int {
+	t.Helper()
+	body, err := json.Marshal(d)
+	if err != nil {
+		t.Fatalf("Failed to marshal detection: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/detect", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	h.handler.HandleDetect(rr, req)
+	return rr.Code
+}
+
+func parseTimestamp(t *testing.T, value string) time.Time {
+	t.Helper()
+	ts, err := time.ParseInLocation(timestampLayout, value, time.Local)
+	if err != nil {
+		t.Fatalf("Invalid timestamp %q: %v", value, err)
+	}
+	return ts
+}
+
+func loadScenarios(t *testing.T) map[string]scenario {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join("testdata", "*.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to list scenarios: %v", err)
+	}
+	if len(files) == 0 {
+		t.Fatal("No scenario fixtures found in testdata/")
+	}
+
+	scenarios := make(map[string]scenario, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", file, err)
+		}
+		var sc scenario
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&sc); err != nil {
+			t.Fatalf("Failed to parse %s: %v", file, err)
+		}
+		scenarios[filepath.Base(file)] = sc
+	}
+	return scenarios
+}
+
+func TestScenarios(t *testing.T) {
+	for file, sc := range loadScenarios(t) {
+		sc := sc
+		t.Run(strings.TrimSuffix(file, ".yaml"), func(t *testing.T) {
+			runScenario(t, sc)
+		})
+	}
+}
+
+func runScenario(t *testing.T, sc scenario) {
+	h := newHarness(parseTimestamp(t, sc.Start))
+
+	for _, e := range sc.Employees {
+		active := e.IsActive == nil || *e.IsActive
+		h.store.AddEmployee(models.Employee{
+			ID:             e.ID,
+			Name:           e.Name,
+			MacAddress:     e.MacAddress,
+			TelegramChatID: e.TelegramChatID,
+			WorkStartTime:  e.WorkStartTime,
+			IsActive:       active,
+		})
+	}
+
+	for i, step := range sc.Timeline {
+		h.clock.Set(parseTimestamp(t, step.At))
+
+		repeat := step.Repeat
+		if repeat < 1 {
+			repeat = 1
+		}
+		var every time.Duration
+		if step.Every != "" {
+			d, err := time.ParseDuration(step.Every)
+			if err != nil {
+				t.Fatalf("step %d: invalid every %q: %v", i, step.Every, err)
+			}
+			every = d
+		}
+
+		wantStatus := step.WantStatus
+		if wantStatus == 0 {
+			wantStatus = http.StatusOK
+		}
+
+		for n := 0; n < repeat; n++ {
+			if n > 0 {
+				h.clock.Advance(every)
+			}
+			if step.Detect != nil {
+				if got := h.detect(t, step.Detect); got != wantStatus {
+					t.Fatalf("step %d (%s): status = %d, want %d", i, step.At, got, wantStatus)
+				}
+			}
+		}
+	}
+
+	checkAttendance(t, sc.Expect.Attendance, h.store.Attendance())
+	if sc.Expect.Detections != nil {
+		if got := len(h.store.Detections()); got != *sc.Expect.Detections {
+			t.Errorf("detections = %d, want %d", got, *sc.Expect.Detections)
+		}
+	}
+	checkMessages(t, "personal", sc.Expect.Notifications.Personal, h.notifier.personal, true)
+	checkMessages(t, "admin", sc.Expect.Notifications.Admin, h.notifier.admin, false)
+}
+
+func checkAttendance(t *testing.T, want []expectedAttendance, got []models.Attendance) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("attendance rows = %d, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.EmployeeID != w.Employee {
+			t.Errorf("attendance[%d].employee = %s, want %s", i, g.EmployeeID, w.Employee)
+		}
+		if w.Date != "" && g.CreatedDate.Format("2006-01-02") != w.Date {
+			t.Errorf("attendance[%d].date = %s, want %s", i, g.CreatedDate.Format("2006-01-02"), w.Date)
+		}
+		if w.CheckIn != "" && g.CheckInTime.Format("15:04:05") != w.CheckIn {
+			t.Errorf("attendance[%d].check_in = %s, want %s", i, g.CheckInTime.Format("15:04:05"), w.CheckIn)
+		}
+		if w.CheckOut != "" && (g.CheckOutTime.IsZero() || g.CheckOutTime.Format("15:04:05") != w.CheckOut) {
+			t.Errorf("attendance[%d].check_out = %v, want %s", i, g.CheckOutTime, w.CheckOut)
+		}
+		if g.Status != w.Status {
+			t.Errorf("attendance[%d].status = %s, want %s", i, g.Status, w.Status)
+		}
+	}
+}
+
+func checkMessages(t *testing.T, kind string, want []expectedMessage, got []sentMessage, checkChat bool) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s notifications = %d, want %d: %+v", kind, len(got), len(want), got)
+	}
+	for i, w := range want {
+		if checkChat && got[i].chatID != w.ChatID {
+			t.Errorf("%s[%d].chat_id = %d, want %d", kind, i, got[i].chatID, w.ChatID)
+		}
+		if !strings.Contains(got[i].text, w.Contains) {
+			t.Errorf("%s[%d] = %q, want it to contain %q", kind, i, got[i].text, w.Contains)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// ActivationCallbackPrefix routes the confirm buttons of /activate and /deactivate; the
+// data is "act:on:<employee code>", "act:off:<employee code>" or "act:cancel"
+const ActivationCallbackPrefix = "act"
+
+// EmployeeActivations lets admins switch employees off when they leave and back on. Every
+// change is audited with the admin who confirmed it.
+type EmployeeActivations struct {
+	employees repository.EmployeeActivation
+	audit     repository.AuditLog
+	clock     clock.Clock
+}
+
+// NewEmployeeActivations creates the service; pass the cached employee repository so
+// switching an employee off stops its check-ins right away
+func NewEmployeeActivations(employees repository.EmployeeActivation, audit repository.AuditLog) *EmployeeActivations {
+	return &EmployeeActivations{employees: employees, audit: audit, clock: clock.Real{}}
+}
+
+// SetClock replaces the time source, used by tests
+func (a *EmployeeActivations) SetClock(c clock.Clock) {
+	a.clock = c
+}
+
+// Find returns the employee with the code, active or not, or repository.ErrEmployeeNotFound
+func (a *EmployeeActivations) Find(ctx context.Context, code string) (*models.Employee, error) {
+	return a.employees.GetByCode(ctx, code)
+}
+
+// ActivationData is the callback data of the button that switches the employee on or off
+func ActivationData(code string, active bool) string {
+	if active {
+		return ActivationCallbackPrefix + ":on:" + code
+	}
+	return ActivationCallbackPrefix + ":off:" + code
+}
+
+// HandleCallback applies a confirm or cancel button and returns the text that replaces
+// the prompt
+func (a *EmployeeActivations) HandleCallback(ctx context.Context, data string, actorID int64, actorName string) (string, error) {
+	parts := strings.SplitN(data, ":", 3)
+	if len(parts) == 2 && parts[0] == ActivationCallbackPrefix && parts[1] == "cancel" {
+		return "❌ ยกเลิกแล้ว", nil
+	}
+	if len(parts) != 3 || parts[0] != ActivationCallbackPrefix || (parts[1] != "on" && parts[1] != "off") {
+		return "", fmt.Errorf("invalid activation callback %q", data)
+	}
+	active := parts[1] == "on"
+
+	emp, err := a.employees.GetByCode(ctx, parts[2])
+	if err != nil {
+		return "", err
+	}
+	if emp.IsActive == active {
+		if active {
+			return fmt.Sprintf("ℹ️ %s (%s) ใช้งานอยู่แล้ว", markdown.Escape(emp.Name), markdown.Escape(emp.EmployeeCode)), nil
+		}
+		return fmt.Sprintf("ℹ️ %s (%s) ถูกปิดใช้งานแล้ว", markdown.Escape(emp.Name), markdown.Escape(emp.EmployeeCode)), nil
+	}
+
+	action := models.AuditEmployeeDeactivated
+	if active {
+		action = models.AuditEmployeeReactivated
+	}
+	entry := &models.AuditEntry{
+		Action:    action,
+		TargetID:  emp.ID,
+		ActorID:   actorID,
+		ActorName: actorName,
+		Details:   "employee_code=" + emp.EmployeeCode,
+		At:        a.clock.Now(),
+	}
+	// The entry is recorded first so no change goes unaudited
+	if err := a.audit.Record(ctx, entry); err != nil {
+		return "", fmt.Errorf("failed to audit %s: %w", action, err)
+	}
+	if err := a.employees.SetActive(ctx, emp.ID, active); err != nil {
+		return "", err
+	}
+
+	if active {
+		log.Printf("✅ %s (%s) reactivated by %d", emp.Name, emp.EmployeeCode, actorID)
+		return fmt.Sprintf("✅ เปิดใช้งาน %s (%s) แล้ว\nโดย: %s", markdown.Escape(emp.Name), markdown.Escape(emp.EmployeeCode), markdown.Escape(actorName)), nil
+	}
+	log.Printf("🚫 %s (%s) deactivated by %d", emp.Name, emp.EmployeeCode, actorID)
+	return fmt.Sprintf("🚫 ปิดใช้งาน %s (%s) แล้ว แท็กจะไม่ลงเวลาอีก\nโดย: %s", markdown.Escape(emp.Name), markdown.Escape(emp.EmployeeCode), markdown.Escape(actorName)), nil
+}
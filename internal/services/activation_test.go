@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+func TestEmployeeActivations(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2026, 3, 2, 9, 0, 0, 0, time.Local))
+	store := memory.NewStore(clk)
+	const mac = "aa:bb:cc:dd:ee:01"
+	store.AddEmployee(models.Employee{ID: "e1", Name: "Somchai", EmployeeCode: "N001", MacAddress: mac, IsActive: true})
+	cache := repository.NewCachedEmployeeRepository(store.Employees(), 5*time.Minute, time.Minute)
+	cache.SetClock(clk)
+	activations := NewEmployeeActivations(cache, store.AuditLog())
+	activations.SetClock(clk)
+
+	// Warm the cache, as a detection would
+	if _, err := cache.GetByMacAddress(ctx, mac); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		data       string
+		want       string
+		wantActive bool
+		wantAudit  string
+	}{
+		{"cancel", "act:cancel", "ยกเลิก", true, ""},
+		{"deactivate", ActivationData("N001", false), "ปิดใช้งาน Somchai (N001) แล้ว", false, models.AuditEmployeeDeactivated},
+		{"deactivate twice", ActivationData("N001", false), "ถูกปิดใช้งานแล้ว", false, ""},
+		{"reactivate", ActivationData("N001", true), "เปิดใช้งาน Somchai (N001) แล้ว", true, models.AuditEmployeeReactivated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(store.AuditEntries())
+			got, err := activations.HandleCallback(ctx, tt.data, 42, "Admin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("reply = %q, want it to contain %q", got, tt.want)
+			}
+
+			// The cache must not keep checking a deactivated tag in
+			_, err = cache.GetByMacAddress(ctx, mac)
+			if active := err == nil; active != tt.wantActive {
+				t.Errorf("tag checks in = %v (err %v), want %v", active, err, tt.wantActive)
+			}
+			audit := store.AuditEntries()
+			switch {
+			case tt.wantAudit == "" && len(audit) != before:
+				t.Errorf("audit = %+v, want no new entry", audit[before:])
+			case tt.wantAudit != "" && (len(audit) != before+1 || audit[before].Action != tt.wantAudit || audit[before].ActorID != 42):
+				t.Errorf("audit = %+v, want one %s entry by 42", audit[before:], tt.wantAudit)
+			}
+		})
+	}
+
+	if _, err := activations.HandleCallback(ctx, ActivationData("N999", false), 42, "Admin"); !errors.Is(err, repository.ErrEmployeeNotFound) {
+		t.Errorf("unknown code error = %v, want ErrEmployeeNotFound", err)
+	}
+}
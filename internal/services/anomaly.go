@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+const (
+	// BaselineWindowDays is the number of working days kept in each baseline
+	BaselineWindowDays = 20
+	// baselineMinSamples is how many working days are needed before anything is flagged
+	baselineMinSamples = 5
+	// baselineMinMAD floors the MAD (in minutes) so very punctual employees are not
+	// flagged for a few minutes of drift
+	baselineMinMAD = 5.0
+)
+
+// baselineExcludedStatuses are attendance statuses that do not reflect an arrival at the office
+var baselineExcludedStatuses = map[string]bool{"leave": true, "wfh": true}
+
+// CheckInAnomaly is a check-in far from the employee's usual arrival time
+type CheckInAnomaly struct {
+	EmployeeID string
+	Name       string
+	CheckIn    time.Time
+	Median     float64 // Usual check-in, minutes after midnight
+	Deviation  float64 // Distance from the median in MADs
+}
+
+// CheckInBaselines maintains per-employee rolling check-in baselines (median and MAD
+// over the last BaselineWindowDays working days) and collects unusual check-ins for
+// the admin daily summary
+type CheckInBaselines struct {
+	repo      repository.BaselineRepository
+	threshold float64 // MADs from the median before a check-in counts as unusual
+
+	mu        sync.Mutex
+	anomalies map[string][]CheckInAnomaly // YYYY-MM-DD -> anomalies
+}
+
+// NewCheckInBaselines creates a baseline tracker flagging check-ins more than threshold MADs from the median
+func NewCheckInBaselines(repo repository.BaselineRepository, threshold float64) (*CheckInBaselines, error) {
+	if threshold <= 0 {
+		return nil, fmt.Errorf("anomaly threshold must be positive, got %v", threshold)
+	}
+	return &CheckInBaselines{
+		repo:      repo,
+		threshold: threshold,
+		anomalies: make(map[string][]CheckInAnomaly),
+	}, nil
+}
+
+// Observe compares a check-in with the employee's baseline, remembers it for the daily
+// summary if unusual, then folds it into the baseline
+func (b *CheckInBaselines) Observe(ctx context.Context, employee *models.Employee, attendance *models.Attendance) error {
+	if baselineExcludedStatuses[attendance.Status] {
+		return nil
+	}
+
+	baseline, err := b.repo.Get(ctx, employee.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load baseline: %w", err)
+	}
+	if baseline == nil {
+		baseline = &models.CheckInBaseline{EmployeeID: employee.ID}
+	}
+
+	sample := models.BaselineSample{
+		Date:    attendance.CreatedDate.Format("2006-01-02"),
+		Minutes: minutesOfDay(attendance.CheckInTime),
+	}
+
+	if deviation, ok := baselineDeviation(baseline, sample.Minutes); ok && deviation > b.threshold {
+		log.Printf("🕵️ Unusual check-in for %s at %s (usual %s, %.1f MADs)",
+			employee.Name, attendance.CheckInTime.Format("15:04"), formatMinutes(baseline.Median), deviation)
+		b.mu.Lock()
+		b.anomalies[sample.Date] = append(b.anomalies[sample.Date], CheckInAnomaly{
+			EmployeeID: employee.ID,
+			Name:       employee.Name,
+			CheckIn:    attendance.CheckInTime,
+			Median:     baseline.Median,
+			Deviation:  deviation,
+		})
+		b.mu.Unlock()
+	}
+
+	if !addBaselineSample(baseline, sample) {
+		return nil
+	}
+	return b.repo.Save(ctx, baseline)
+}
+
+// Anomalies returns the unusual check-ins recorded for the day
+func (b *CheckInBaselines) Anomalies(day time.Time) []CheckInAnomaly {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]CheckInAnomaly(nil), b.anomalies[day.Format("2006-01-02")]...)
+}
+
+// SummaryLines renders the day's unusual check-ins for the daily summary and forgets
+// them, together with anything older
+func (b *CheckInBaselines) SummaryLines(ctx context.Context, day time.Time) ([]string, error) {
+	anomalies := b.Anomalies(day)
+
+	b.mu.Lock()
+	key := day.Format("2006-01-02")
+	for date := range b.anomalies {
+		if date <= key {
+			delete(b.anomalies, date)
+		}
+	}
+	b.mu.Unlock()
+
+	lines := make([]string, 0, len(anomalies))
+	for _, a := range anomalies {
+		lines = append(lines, fmt.Sprintf("%s เข้างาน %s (ปกติประมาณ %s)",
+			markdown.Escape(a.Name), a.CheckIn.Format("15:04"), formatMinutes(a.Median)))
+	}
+	return lines, nil
+}
+
+// Rebuild recomputes baselines from attendance history (oldest first) and returns how
+// many employees were updated
+func (b *CheckInBaselines) Rebuild(ctx context.Context, history []models.Attendance) (int, error) {
+	byEmployee := make(map[string][]models.Attendance)
+	var order []string
+	for _, a := range history {
+		if a.Source == models.AttendanceSourceSelfTest {
+			continue
+		}
+		if _, ok := byEmployee[a.EmployeeID]; !ok {
+			order = append(order, a.EmployeeID)
+		}
+		byEmployee[a.EmployeeID] = append(byEmployee[a.EmployeeID], a)
+	}
+
+	updated := 0
+	for _, employeeID := range order {
+		existing, err := b.repo.Get(ctx, employeeID)
+		if err != nil {
+			return updated, fmt.Errorf("failed to load baseline for %s: %w", employeeID, err)
+		}
+
+		baseline := &models.CheckInBaseline{EmployeeID: employeeID}
+		if existing != nil {
+			baseline.ID = existing.ID
+		}
+
+		records := byEmployee[employeeID]
+		sort.SliceStable(records, func(i, j int) bool { return records[i].CheckInTime.Before(records[j].CheckInTime) })
+		for _, a := range records {
+			if baselineExcludedStatuses[a.Status] {
+				continue
+			}
+			addBaselineSample(baseline, models.BaselineSample{
+				Date:    a.CreatedDate.Format("2006-01-02"),
+				Minutes: minutesOfDay(a.CheckInTime),
+			})
+		}
+		if len(baseline.Samples) == 0 {
+			continue
+		}
+
+		if err := b.repo.Save(ctx, baseline); err != nil {
+			return updated, fmt.Errorf("failed to save baseline for %s: %w", employeeID, err)
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// baselineDeviation returns how many MADs minutes is from the baseline median, and
+// false while the baseline has too few samples to judge
+func baselineDeviation(baseline *models.CheckInBaseline, minutes int) (float64, bool) {
+	if len(baseline.Samples) < baselineMinSamples {
+		return 0, false
+	}
+	mad := math.Max(baseline.MAD, baselineMinMAD)
+	return math.Abs(float64(minutes)-baseline.Median) / mad, true
+}
+
+// addBaselineSample adds the first check-in of a day to the rolling window and recomputes
+// median and MAD; it returns false if the day was already counted
+func addBaselineSample(baseline *models.CheckInBaseline, sample models.BaselineSample) bool {
+	for _, s := range baseline.Samples {
+		if s.Date == sample.Date {
+			return false
+		}
+	}
+
+	baseline.Samples = append(baseline.Samples, sample)
+	sort.SliceStable(baseline.Samples, func(i, j int) bool { return baseline.Samples[i].Date < baseline.Samples[j].Date })
+	if n := len(baseline.Samples); n > BaselineWindowDays {
+		baseline.Samples = baseline.Samples[n-BaselineWindowDays:]
+	}
+
+	values := make([]float64, len(baseline.Samples))
+	for i, s := range baseline.Samples {
+		values[i] = float64(s.Minutes)
+	}
+	baseline.Median = median(values)
+
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - baseline.Median)
+	}
+	baseline.MAD = median(deviations)
+	return true
+}
+
+// median returns the median of values (0 for an empty slice) without modifying it
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// minutesOfDay returns minutes after local midnight
+func minutesOfDay(t time.Time) int {
+	return t.Hour()*60 + t.Minute()
+}
+
+// formatMinutes renders minutes after midnight as HH:MM
+func formatMinutes(minutes float64) string {
+	m := int(math.Round(minutes))
+	return fmt.Sprintf("%02d:%02d", m/60, m%60)
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+// checkIn builds an attendance record for day n of February 2026 at hh:mm
+func checkIn(employeeID string, day, hour, minute int, status string) models.Attendance {
+	t := time.Date(2026, 2, day, hour, minute, 0, 0, time.Local)
+	return models.Attendance{EmployeeID: employeeID, CheckInTime: t, CreatedDate: t, Status: status}
+}
+
+func TestAddBaselineSample(t *testing.T) {
+	baseline := &models.CheckInBaseline{EmployeeID: "e1"}
+	for i, m := range []int{470, 472, 468, 475, 500} {
+		addBaselineSample(baseline, models.BaselineSample{Date: time.Date(2026, 1, i+1, 0, 0, 0, 0, time.UTC).Format("2006-01-02"), Minutes: m})
+	}
+
+	if baseline.Median != 472 {
+		t.Errorf("Median = %v, want 472", baseline.Median)
+	}
+	// deviations 2, 0, 4, 3, 28 -> median 3
+	if baseline.MAD != 3 {
+		t.Errorf("MAD = %v, want 3", baseline.MAD)
+	}
+
+	if addBaselineSample(baseline, models.BaselineSample{Date: "2026-01-01", Minutes: 600}) {
+		t.Error("second check-in on the same day must not be added")
+	}
+
+	for i := 0; i < 30; i++ {
+		addBaselineSample(baseline, models.BaselineSample{Date: time.Date(2026, 3, i+1, 0, 0, 0, 0, time.UTC).Format("2006-01-02"), Minutes: 480})
+	}
+	if len(baseline.Samples) != BaselineWindowDays {
+		t.Errorf("samples = %d, want %d", len(baseline.Samples), BaselineWindowDays)
+	}
+	if baseline.Samples[0].Date != "2026-03-11" {
+		t.Errorf("oldest sample = %s, want 2026-03-11", baseline.Samples[0].Date)
+	}
+}
+
+func TestCheckInBaselines_Observe(t *testing.T) {
+	ctx := context.Background()
+	employee := &models.Employee{ID: "e1", Name: "Somchai"}
+
+	tests := []struct {
+		name        string
+		history     []models.Attendance
+		today       models.Attendance
+		wantAnomaly bool
+	}{
+		{
+			name:        "too few samples",
+			history:     []models.Attendance{checkIn("e1", 2, 7, 50, "ontime"), checkIn("e1", 3, 7, 50, "ontime")},
+			today:       checkIn("e1", 4, 10, 30, "late"),
+			wantAnomaly: false,
+		},
+		{
+			name:        "usual time",
+			history:     weekOf(7, 50),
+			today:       checkIn("e1", 20, 7, 58, "ontime"),
+			wantAnomaly: false,
+		},
+		{
+			name:        "much later than usual",
+			history:     weekOf(7, 50),
+			today:       checkIn("e1", 20, 10, 30, "late"),
+			wantAnomaly: true,
+		},
+		{
+			name:        "much earlier than usual",
+			history:     weekOf(7, 50),
+			today:       checkIn("e1", 20, 5, 30, "ontime"),
+			wantAnomaly: true,
+		},
+		{
+			name:        "leave day ignored",
+			history:     weekOf(7, 50),
+			today:       checkIn("e1", 20, 13, 0, "leave"),
+			wantAnomaly: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memory.NewStore(clock.Real{})
+			baselines, err := NewCheckInBaselines(store.Baselines(), 3)
+			if err != nil {
+				t.Fatalf("NewCheckInBaselines() error = %v", err)
+			}
+			for _, a := range tt.history {
+				a := a
+				if err := baselines.Observe(ctx, employee, &a); err != nil {
+					t.Fatalf("Observe() error = %v", err)
+				}
+			}
+
+			if err := baselines.Observe(ctx, employee, &tt.today); err != nil {
+				t.Fatalf("Observe() error = %v", err)
+			}
+
+			got := baselines.Anomalies(tt.today.CreatedDate)
+			if (len(got) > 0) != tt.wantAnomaly {
+				t.Errorf("anomalies = %+v, want anomaly %v", got, tt.wantAnomaly)
+			}
+		})
+	}
+}
+
+// weekOf returns ten working days of check-ins for e1 around hh:mm
+func weekOf(hour, minute int) []models.Attendance {
+	var out []models.Attendance
+	for day := 2; day <= 13; day++ {
+		if wd := time.Date(2026, 2, day, 0, 0, 0, 0, time.Local).Weekday(); wd == time.Saturday || wd == time.Sunday {
+			continue
+		}
+		out = append(out, checkIn("e1", day, hour, minute+day%3, "ontime"))
+	}
+	return out
+}
+
+func TestCheckInBaselines_Rebuild(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore(clock.Real{})
+	baselines, _ := NewCheckInBaselines(store.Baselines(), 3)
+
+	history := append(weekOf(7, 50),
+		checkIn("e2", 2, 9, 0, "ontime"),
+		checkIn("e2", 3, 14, 0, "wfh"),
+		checkIn("e3", 3, 9, 0, "leave"),
+	)
+
+	updated, err := baselines.Rebuild(ctx, history)
+	if err != nil {
+		t.Fatalf("Rebuild() error = %v", err)
+	}
+	if updated != 2 {
+		t.Errorf("updated = %d, want 2", updated)
+	}
+
+	e2, _ := store.Baselines().Get(ctx, "e2")
+	if e2 == nil || len(e2.Samples) != 1 || e2.Median != 540 {
+		t.Errorf("e2 baseline = %+v, want a single 09:00 sample", e2)
+	}
+	if e3, _ := store.Baselines().Get(ctx, "e3"); e3 != nil {
+		t.Errorf("e3 baseline = %+v, want none (leave only)", e3)
+	}
+}
+
+func TestDailySummary_Build(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2026, 2, 20, 23, 30, 0, 0, time.Local)
+	store := memory.NewStore(clock.NewFake(day))
+	store.AddEmployee(models.Employee{ID: "e1", Name: "Somchai", IsActive: true})
+	store.AddEmployee(models.Employee{ID: "e2", Name: "Suda", IsActive: true})
+	store.AddEmployee(models.Employee{ID: "e3", Name: "Anan", IsActive: true})
+
+	baselines, _ := NewCheckInBaselines(store.Baselines(), 3)
+	employee := &models.Employee{ID: "e1", Name: "Somchai"}
+	for _, a := range weekOf(7, 50) {
+		a := a
+		baselines.Observe(ctx, employee, &a)
+	}
+
+	for _, a := range []models.Attendance{checkIn("e1", 20, 10, 30, "late"), checkIn("e2", 20, 7, 55, "ontime")} {
+		a := a
+		store.AttendanceRecords().Create(ctx, &a)
+		if a.EmployeeID == "e1" {
+			baselines.Observe(ctx, employee, &a)
+		}
+	}
+
+	notifier := newRecordingNotifier()
+	summary := NewDailySummary(store.Employees(), store.AttendanceRecords(), notifier)
+	summary.AddSection("เวลาเข้างานผิดปกติ", baselines.SummaryLines)
+	summary.AddSection("empty", func(ctx context.Context, day time.Time) ([]string, error) { return nil, nil })
+
+	if err := summary.Send(ctx, day); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if len(notifier.admin) != 1 {
+		t.Fatalf("admin messages = %d, want 1", len(notifier.admin))
+	}
+
+	msg := notifier.admin[0]
+	for _, want := range []string{"2/3", "เข้าสาย: 1", "เวลาเข้างานผิดปกติ", "Somchai เข้างาน 10:30"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("summary missing %q:\n%s", want, msg)
+		}
+	}
+	if strings.Contains(msg, "empty") {
+		t.Errorf("empty section must be omitted:\n%s", msg)
+	}
+
+	if lines, _ := baselines.SummaryLines(ctx, day); len(lines) != 0 {
+		t.Errorf("anomalies should be cleared after the summary, got %v", lines)
+	}
+}
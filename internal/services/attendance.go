@@ -3,12 +3,17 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/logging"
+	"med-pulse-bot/internal/metrics"
 	"med-pulse-bot/internal/models"
 	"med-pulse-bot/internal/repository"
+	"med-pulse-bot/internal/tenant"
 )
 
 // AttendanceProcessor defines the interface for attendance processing
@@ -16,13 +21,19 @@ type AttendanceProcessor interface {
 	ProcessDetection(ctx context.Context, req *models.DetectionRequest) error
 }
 
-// AttendanceService handles attendance business logic
+// AttendanceService handles attendance business logic by running each detection
+// through the detection pipeline
 type AttendanceService struct {
-	employeeRepo   repository.EmployeeRepository
-	attendanceRepo repository.AttendanceRepository
-	detectionRepo  repository.EmployeeDetectionRepository
-	scannerRepo    repository.ScannerRepository
-	botNotifier    BotNotifier
+	scannerRepo repository.ScannerRepository
+	opts        PipelineOptions
+	pipeline    *Pipeline
+	clock       clock.Clock
+	location    *time.Location // detections are judged on this timezone's wall clock
+	tenantID    string         // metrics label
+
+	writeGate WriteGate        // optional; detections are queued while it is read-only
+	queue     *DetectionQueue  // required with writeGate
+	whitelist *DeviceWhitelist // optional; other devices are dropped unseen
 }
 
 // BotNotifier defines the interface for bot notifications
@@ -31,7 +42,16 @@ type BotNotifier interface {
 	SendPersonalNotification(chatID int64, message string)
 }
 
-// NewAttendanceService creates a new attendance service
+// NopNotifier drops every notification, for a site running without Telegram (MODE=headless)
+type NopNotifier struct{}
+
+// SendNotification does nothing
+func (NopNotifier) SendNotification(message string) {}
+
+// SendPersonalNotification does nothing
+func (NopNotifier) SendPersonalNotification(chatID int64, message string) {}
+
+// NewAttendanceService creates a new attendance service with the default pipeline
 func NewAttendanceService(
 	employeeRepo repository.EmployeeRepository,
 	attendanceRepo repository.AttendanceRepository,
@@ -39,157 +59,326 @@ func NewAttendanceService(
 	scannerRepo repository.ScannerRepository,
 	botNotifier BotNotifier,
 ) *AttendanceService {
-	return &AttendanceService{
-		employeeRepo:   employeeRepo,
-		attendanceRepo: attendanceRepo,
-		detectionRepo:  detectionRepo,
-		scannerRepo:    scannerRepo,
-		botNotifier:    botNotifier,
+	s := &AttendanceService{
+		scannerRepo: scannerRepo,
+		opts: PipelineOptions{
+			Employees:     employeeRepo,
+			Attendance:    attendanceRepo,
+			Detections:    detectionRepo,
+			Notifier:      botNotifier,
+			RSSIThreshold: DefaultRSSIThreshold,
+			GracePeriod:   DefaultGracePeriod,
+			Locks:         NewEmployeeLocks(),
+
+			ScannerRecords: scannerRepo,
+		},
+		clock:    clock.Real{},
+		location: time.Local,
+		tenantID: tenant.DefaultID,
 	}
+	s.pipeline = NewDetectionPipeline(s.opts)
+	return s
 }
 
-// ProcessDetection processes a BLE device detection
-func (s *AttendanceService) ProcessDetection(ctx context.Context, req *models.DetectionRequest) error {
-	// Update scanner activity (optional - comment out if not needed)
-	// if err := s.scannerRepo.UpdateActivity(ctx, req.ScannerMac); err != nil {
-	// 	log.Printf("Warning: failed to update scanner activity: %v", err)
-	// }
+// SetClock replaces the time source, used by tests and scenario fixtures
+func (s *AttendanceService) SetClock(c clock.Clock) {
+	s.clock = c
+}
 
-	// Check if MAC/UUID matches any employee (target device detection)
-	employee, err := s.employeeRepo.GetByMacAddress(ctx, req.MacAddress)
-	if err != nil {
-		// Not a registered employee device - ignore silently
-		return nil
-	}
+// SetLocation sets the timezone whose calendar day and wall clock decide lateness and
+// the attendance date, e.g. the configured TIMEZONE
+func (s *AttendanceService) SetLocation(loc *time.Location) {
+	s.location = loc
+}
 
-	// Device belongs to an employee - mark as target device
-	log.Printf("🎯 TARGET DEVICE detected: Employee=%s, MAC=%s, RSSI=%d",
-		employee.Name, req.MacAddress, req.RSSI)
-	req.IsTargetDevice = true
-	req.DeviceName = employee.Name
-
-	// Check if device is close enough (RSSI threshold for ~10 meters)
-	const rssiThreshold = -70
-	if req.RSSI < rssiThreshold {
-		log.Printf("Device %s too far (RSSI: %d, need: %d or higher)", req.MacAddress, req.RSSI, rssiThreshold)
-		return nil
-	}
+// SetTenant labels this service's metrics with a tenant ID
+func (s *AttendanceService) SetTenant(id string) {
+	s.tenantID = id
+}
 
-	// Check if already checked in today
-	isCheckedIn, err := s.employeeRepo.IsCheckedInToday(ctx, employee.ID)
-	if err != nil {
-		return fmt.Errorf("failed to check attendance status: %w", err)
-	}
+// SetRSSIThreshold sets the weakest signal (dBm) accepted for a check-in
+func (s *AttendanceService) SetRSSIThreshold(dbm int) {
+	s.opts.RSSIThreshold = dbm
+	s.pipeline = NewDetectionPipeline(s.opts)
+}
 
-	// If not checked in, save detection and record attendance
-	if !isCheckedIn {
-		if err := s.saveDetection(ctx, employee.ID, req); err != nil {
-			return fmt.Errorf("failed to save detection: %w", err)
-		}
+// SetGracePeriod sets how long after work start a check-in is still on time for
+// employees without their own grace period, e.g. the site's GRACE_PERIOD_MINUTES
+func (s *AttendanceService) SetGracePeriod(d time.Duration) {
+	s.opts.GracePeriod = d
+	s.pipeline = NewDetectionPipeline(s.opts)
+}
 
-		if err := s.recordAttendance(ctx, employee, req.ScannerMac); err != nil {
-			return fmt.Errorf("failed to record attendance: %w", err)
-		}
-	}
+// SetCheckInBaselines enables unusual check-in time tracking
+func (s *AttendanceService) SetCheckInBaselines(b *CheckInBaselines) {
+	s.opts.Baselines = b
+	s.pipeline = NewDetectionPipeline(s.opts)
+}
 
-	return nil
-}
-
-// saveDetection saves the detection record
-func (s *AttendanceService) saveDetection(ctx context.Context, employeeID string, req *models.DetectionRequest) error {
-	detection := &models.EmployeeDetection{
-		EmployeeID:     employeeID,
-		MacAddress:     req.MacAddress,
-		ScannerMac:     req.ScannerMac,
-		RSSI:           req.RSSI,
-		DeviceType:     req.DeviceType,
-		IsITag03:       req.IsITag03,
-		IsTargetDevice: req.IsTargetDevice,
-		DeviceName:     req.DeviceName,
-		DetectedAt:     time.Now(),
-	}
+// SetDetectionWindow requires several detections within a window before a check-in
+func (s *AttendanceService) SetDetectionWindow(w *DetectionWindow) {
+	s.opts.Smoothing = w
+	s.pipeline = NewDetectionPipeline(s.opts)
+}
 
-	if err := s.detectionRepo.Create(ctx, detection); err != nil {
-		return fmt.Errorf("failed to create detection: %w", err)
-	}
+// SetRSSISmoother compares a moving average of each device's signal against the threshold
+func (s *AttendanceService) SetRSSISmoother(r *RSSISmoother) {
+	s.opts.RSSI = r
+	s.pipeline = NewDetectionPipeline(s.opts)
+}
 
-	if req.IsTargetDevice {
-		log.Printf("💾 Saved TARGET DEVICE detection: Employee=%s, Device=%s, MAC=%s, RSSI=%d",
-			employeeID, req.DeviceName, req.MacAddress, req.RSSI)
-	} else {
-		log.Printf("💾 Saved detection for employee ID %s: MAC=%s, RSSI=%d, Type=%s",
-			employeeID, req.MacAddress, req.RSSI, req.DeviceType)
-	}
+// SetCheckInWindow only lets detections within the window create a check-in; the others
+// are still saved as detections
+func (s *AttendanceService) SetCheckInWindow(w *CheckInWindow) {
+	s.opts.Window = w
+	s.pipeline = NewDetectionPipeline(s.opts)
+}
 
-	return nil
+// SetDevices resolves detections through the employees' registered devices before their
+// own mac_address
+func (s *AttendanceService) SetDevices(devices repository.DeviceRepository) {
+	s.opts.Devices = devices
+	s.pipeline = NewDetectionPipeline(s.opts)
 }
 
-// recordAttendance records attendance and sends notifications
-func (s *AttendanceService) recordAttendance(ctx context.Context, employee *models.Employee, scannerMac string) error {
-	now := time.Now()
-	status := calculateStatus(now, employee.WorkStartTime)
+// SetUnknownDeviceCapture records strong detections of devices no employee owns
+func (s *AttendanceService) SetUnknownDeviceCapture(c *UnknownDeviceCapture) {
+	s.opts.Unknown = c
+	s.pipeline = NewDetectionPipeline(s.opts)
+}
 
-	attendance := &models.Attendance{
-		EmployeeID:  employee.ID,
-		CheckInTime: now,
-		ScannerMac:  scannerMac,
-		Status:      status,
-		CreatedDate: now,
-	}
+// SetWhitelist drops detections of every device the whitelist does not admit before
+// anything about them is logged or stored (WHITELIST_ONLY)
+func (s *AttendanceService) SetWhitelist(w *DeviceWhitelist) {
+	s.whitelist = w
+}
 
-	if err := s.attendanceRepo.Create(ctx, attendance); err != nil {
-		return fmt.Errorf("failed to create attendance record: %w", err)
+// Admit reports whether the detection may be processed; one the whitelist does not admit
+// is only counted, under the "whitelist" stage
+func (s *AttendanceService) Admit(ctx context.Context, req *models.DetectionRequest) bool {
+	if s.whitelist == nil || s.whitelist.Admits(ctx, req) {
+		return true
 	}
+	metrics.Detections.Inc(s.tenantID, "whitelist")
+	return false
+}
 
-	log.Printf("✅ Employee %s checked in at %s (Status: %s)",
-		employee.Name, now.Format("15:04:05"), status)
+// SetDevicePairing lets /pair windows see every detection
+func (s *AttendanceService) SetDevicePairing(p *DevicePairing) {
+	s.opts.NewDevices = p
+	s.pipeline = NewDetectionPipeline(s.opts)
+}
 
-	// Send notification to employee
-	s.sendCheckInNotification(employee, now, scannerMac, status)
+// SetPresenceSampler saves a detection of each checked-in employee every interval, for
+// /whoisin
+func (s *AttendanceService) SetPresenceSampler(p *PresenceSampler) {
+	s.opts.Presence = p
+	s.pipeline = NewDetectionPipeline(s.opts)
+}
 
-	return nil
+// SetWorkCalendar records check-ins on weekends and holidays as offday
+func (s *AttendanceService) SetWorkCalendar(c *WorkCalendar) {
+	s.opts.Calendar = c
+	s.pipeline = NewDetectionPipeline(s.opts)
 }
 
-// sendCheckInNotification sends check-in notification to employee
-func (s *AttendanceService) sendCheckInNotification(employee *models.Employee, checkInTime time.Time, scannerMac, status string) {
-	statusEmoji := "✅"
-	statusText := "เข้างานตรงเวลา"
+// SetMessageTemplates replaces the built-in check-in messages
+func (s *AttendanceService) SetMessageTemplates(t *MessageTemplates) {
+	s.opts.Templates = t
+	s.pipeline = NewDetectionPipeline(s.opts)
+}
 
-	if status == "late" {
-		statusEmoji = "⚠️"
-		statusText = calculateLateStatus(checkInTime, employee.WorkStartTime)
+// SetStationaryTagDetector enables left-behind tag handling for morning check-ins
+func (s *AttendanceService) SetStationaryTagDetector(d *StationaryTagDetector) {
+	s.opts.Stationary = d
+	s.pipeline = NewDetectionPipeline(s.opts)
+}
+
+// SetCheckOutReminder lets the forgotten check-out reminder see every employee detection
+func (s *AttendanceService) SetCheckOutReminder(r *CheckOutReminder) {
+	s.opts.CheckOut = r
+	s.pipeline = NewDetectionPipeline(s.opts)
+}
+
+// SetCheckOutTracker records check-outs from detections of checked-in employees
+func (s *AttendanceService) SetCheckOutTracker(t *CheckOutTracker) {
+	s.opts.Departures = t
+	s.pipeline = NewDetectionPipeline(s.opts)
+}
+
+// SetScannerPairing lets scanner pairing see the first detection of a paired scanner
+func (s *AttendanceService) SetScannerPairing(p *ScannerPairing) {
+	s.opts.Pairing = p
+	s.pipeline = NewDetectionPipeline(s.opts)
+}
+
+// SetRecentDetections drops repeats of settled detections before any repository call
+func (s *AttendanceService) SetRecentDetections(r *RecentDetections) {
+	s.opts.Recent = r
+	s.pipeline = NewDetectionPipeline(s.opts)
+}
+
+// SetLateApprovals makes check-ins within an approved late arrival ontime_approved
+func (s *AttendanceService) SetLateApprovals(l *LateApprovals) {
+	s.opts.Late = l
+	s.pipeline = NewDetectionPipeline(s.opts)
+}
+
+// SetScannerConfigs applies each scanner's effective RSSI threshold instead of the global one
+func (s *AttendanceService) SetScannerConfigs(c *ScannerConfigs) {
+	s.opts.Scanners = c
+	s.pipeline = NewDetectionPipeline(s.opts)
+}
+
+// SetReadOnlyQueue routes detections to q instead of the pipeline while gate is read-only
+func (s *AttendanceService) SetReadOnlyQueue(gate WriteGate, q *DetectionQueue) {
+	s.writeGate = gate
+	s.queue = q
+}
+
+// SetPipeline replaces the detection pipeline with a custom assembly
+func (s *AttendanceService) SetPipeline(p *Pipeline) {
+	s.pipeline = p
+}
+
+// Pipeline returns the detection pipeline in use
+func (s *AttendanceService) Pipeline() *Pipeline {
+	return s.pipeline
+}
+
+// ProcessDetection processes a BLE device detection; in read-only mode, or when
+// PocketBase stays unavailable, it is queued
+func (s *AttendanceService) ProcessDetection(ctx context.Context, req *models.DetectionRequest) error {
+	_, err := s.DetectWithResult(ctx, req)
+	return err
+}
+
+// DetectWithResult processes a detection and reports what happened to it
+func (s *AttendanceService) DetectWithResult(ctx context.Context, req *models.DetectionRequest) (DetectionResult, error) {
+	if !s.Admit(ctx, req) {
+		return DetectionResult{Result: ResultUnknownDevice, Stage: "whitelist"}, nil
+	}
+	now := s.clock.Now()
+	if s.writeGate != nil && s.writeGate.ReadOnly() {
+		if err := s.queue.Enqueue(ctx, req, now); err != nil {
+			return DetectionResult{Result: ResultError}, fmt.Errorf("failed to queue detection: %w", err)
+		}
+		metrics.Detections.Inc(s.tenantID, "queued")
+		metrics.DetectionQueueLength.Add(1, s.tenantID)
+		return DetectionResult{Result: ResultPaused}, nil
 	}
+	dc, err := s.processAt(ctx, req, now)
+	if errors.Is(err, repository.ErrUnavailable) && s.queue != nil {
+		// PocketBase is restarting or unreachable: replay the detection once it is back
+		if qerr := s.queue.Enqueue(context.WithoutCancel(ctx), req, now); qerr == nil {
+			logging.From(ctx).Warn("📥 Queued detection while PocketBase is unavailable", "scanner_mac", req.ScannerMac, "mac", req.MacAddress, "error", err)
+			metrics.Detections.Inc(s.tenantID, "queued")
+			metrics.DetectionQueueLength.Add(1, s.tenantID)
+			res := resultOf(dc, nil)
+			res.Result, res.CheckedIn = ResultPaused, false
+			return res, nil
+		}
+	}
+	return resultOf(dc, err), err
+}
 
-	message := fmt.Sprintf(
-		"%s *สวัสดีตอนเช้า คุณ%s!*\n\n"+
-			"🕐 เวลาเข้างาน: `%s`\n"+
-			"📍 สถานที่: `Scanner %s`\n"+
-			"⏰ สถานะ: *%s*\n\n"+
-			"ขอให้มีความสุขกับการทำงานวันนี้! 😊",
-		statusEmoji, employee.Name, checkInTime.Format("15:04:05"), scannerMac, statusText,
-	)
+// DrainQueue replays queued detections at their original time. It stops, keeping the
+// rest queued, if read-only mode is entered again or PocketBase is unavailable again.
+func (s *AttendanceService) DrainQueue(ctx context.Context) (int, error) {
+	if s.queue == nil {
+		return 0, nil
+	}
+	n, err := s.queue.Drain(ctx, func(ctx context.Context, d QueuedDetection) error {
+		if s.writeGate.ReadOnly() {
+			return ErrDrainStopped
+		}
+		_, err := s.processAt(ctx, &d.Request, d.At)
+		if errors.Is(err, repository.ErrUnavailable) {
+			return fmt.Errorf("%w: %v", ErrDrainStopped, err)
+		}
+		return err
+	})
+	metrics.DetectionQueueLength.Set(float64(s.queue.Len()), s.tenantID)
+	return n, err
+}
+
+// RetryQueued drains the queue every interval until ctx is done, replaying detections
+// queued while PocketBase was unavailable; nothing is drained in read-only mode
+func (s *AttendanceService) RetryQueued(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if s.queue == nil || s.writeGate.ReadOnly() || s.queue.Len() == 0 {
+				continue
+			}
+			n, err := s.DrainQueue(ctx)
+			if err != nil {
+				log.Printf("❌ Retrying queued detections failed: %v", err)
+			} else if n > 0 {
+				log.Printf("📤 Replayed %d detections queued while PocketBase was unavailable", n)
+			}
+		}
+	}()
+}
 
-	s.botNotifier.SendPersonalNotification(employee.TelegramChatID, message)
+// processAt runs a detection seen at the given time through the pipeline
+func (s *AttendanceService) processAt(ctx context.Context, req *models.DetectionRequest, at time.Time) (*DetectionContext, error) {
+	dc := &DetectionContext{Request: req, Now: at.In(s.location)}
+	ctx = logging.With(ctx, "scanner_mac", req.ScannerMac, "mac", req.MacAddress)
+	start := s.clock.Now()
+	err := s.pipeline.Run(ctx, dc)
+	metrics.DetectionLatency.Observe(s.clock.Now().Sub(start).Seconds(), s.tenantID)
+	if s.opts.Recent != nil {
+		s.opts.Recent.observe(dc, err)
+	}
+	logTimeline(ctx, dc)
+	s.recordMetrics(dc)
+	return dc, err
+}
 
-	// Send to admin if late
-	if status == "late" {
-		adminMessage := fmt.Sprintf("⚠️ *พนักงานเข้าสาย*\n👤 ชื่อ: `%s`\n🕐 เวลา: `%s`\n⏰ %s",
-			employee.Name, checkInTime.Format("15:04:05"), statusText)
-		s.botNotifier.SendNotification(adminMessage)
+// recordMetrics counts the detection under the stage that finished it
+func (s *AttendanceService) recordMetrics(dc *DetectionContext) {
+	stage := "none"
+	if n := len(dc.Timeline); n > 0 {
+		stage = dc.Timeline[n-1].Stage
+	}
+	metrics.Detections.Inc(s.tenantID, stage)
+	if dc.Attendance != nil {
+		metrics.CheckIns.Inc(s.tenantID, dc.Attendance.Status)
 	}
 }
 
-// calculateStatus determines if check-in is on time or late
-func calculateStatus(checkInTime time.Time, workStartTime string) string {
+// DefaultGracePeriod is how long after work start a check-in is still on time, unless
+// configured
+const DefaultGracePeriod = 5 * time.Minute
+
+// GracePeriod returns how long after work start the employee still checks in on time:
+// their own grace_period_minutes when set, otherwise site, the grace period of their site
+func GracePeriod(emp *models.Employee, site time.Duration) time.Duration {
+	if emp.GracePeriod > 0 {
+		return time.Duration(emp.GracePeriod) * time.Minute
+	}
+	return site
+}
+
+// calculateStatus determines if check-in is on time or late, within grace of the work start
+// on day, the day the shift started (the day before a night-shift check-in after midnight)
+func calculateStatus(checkInTime, day time.Time, workStartTime string, grace time.Duration) string {
 	workStart, err := time.Parse("15:04:05", workStartTime)
 	if err != nil {
 		return "ontime" // Default to ontime if can't parse
 	}
 
 	todayWorkStart := time.Date(
-		checkInTime.Year(),
-		checkInTime.Month(),
-		checkInTime.Day(),
+		day.Year(),
+		day.Month(),
+		day.Day(),
 		workStart.Hour(),
 		workStart.Minute(),
 		workStart.Second(),
@@ -197,27 +386,24 @@ func calculateStatus(checkInTime time.Time, workStartTime string) string {
 		checkInTime.Location(),
 	)
 
-	// Grace period: 5 minutes
-	gracePeriod := 5 * time.Minute
-
-	if checkInTime.Before(todayWorkStart.Add(gracePeriod)) {
+	if checkInTime.Before(todayWorkStart.Add(grace)) {
 		return "ontime"
 	}
 
 	return "late"
 }
 
-// calculateLateStatus calculates late minutes for display
-func calculateLateStatus(checkInTime time.Time, workStartTime string) string {
+// calculateLateStatus calculates late minutes since the work start on the shift's day for display in lang
+func calculateLateStatus(lang string, checkInTime, day time.Time, workStartTime string) string {
 	workStart, err := time.Parse("15:04:05", workStartTime)
 	if err != nil {
-		return "เข้าสาย"
+		return statusText(lang, "late")
 	}
 
 	todayWorkStart := time.Date(
-		checkInTime.Year(),
-		checkInTime.Month(),
-		checkInTime.Day(),
+		day.Year(),
+		day.Month(),
+		day.Day(),
 		workStart.Hour(),
 		workStart.Minute(),
 		workStart.Second(),
@@ -226,5 +412,5 @@ func calculateLateStatus(checkInTime time.Time, workStartTime string) string {
 	)
 
 	lateMinutes := int(checkInTime.Sub(todayWorkStart).Minutes())
-	return fmt.Sprintf("เข้าสาย %d นาที", lateMinutes)
+	return fmt.Sprintf(statusText(lang, "late_minutes"), lateMinutes)
 }
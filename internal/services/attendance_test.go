@@ -1,58 +1,112 @@
 package services
 
 import (
+	"context"
+	"strings"
 	"testing"
 	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
 )
 
 func TestCalculateStatus(t *testing.T) {
 	tests := []struct {
 		name          string
 		checkInTime   time.Time
+		day           time.Time // the shift's day; the check-in's own day when zero
 		workStartTime string
+		grace         time.Duration
 		want          string
 	}{
 		{
 			name:          "On time - exactly at work start",
 			checkInTime:   time.Date(2026, 2, 1, 8, 0, 0, 0, time.Local),
 			workStartTime: "08:00:00",
+			grace:         DefaultGracePeriod,
 			want:          "ontime",
 		},
 		{
 			name:          "On time - within grace period (5 minutes)",
 			checkInTime:   time.Date(2026, 2, 1, 8, 4, 0, 0, time.Local),
 			workStartTime: "08:00:00",
+			grace:         DefaultGracePeriod,
 			want:          "ontime",
 		},
 		{
 			name:          "Late - 1 minute after grace period",
 			checkInTime:   time.Date(2026, 2, 1, 8, 6, 0, 0, time.Local),
 			workStartTime: "08:00:00",
+			grace:         DefaultGracePeriod,
 			want:          "late",
 		},
 		{
 			name:          "Late - 30 minutes late",
 			checkInTime:   time.Date(2026, 2, 1, 8, 30, 0, 0, time.Local),
 			workStartTime: "08:00:00",
+			grace:         DefaultGracePeriod,
 			want:          "late",
 		},
 		{
 			name:          "On time - before work start",
 			checkInTime:   time.Date(2026, 2, 1, 7, 45, 0, 0, time.Local),
 			workStartTime: "08:00:00",
+			grace:         DefaultGracePeriod,
 			want:          "ontime",
 		},
 		{
 			name:          "Invalid work start time - defaults to ontime",
 			checkInTime:   time.Date(2026, 2, 1, 9, 0, 0, 0, time.Local),
 			workStartTime: "invalid",
+			grace:         DefaultGracePeriod,
+			want:          "ontime",
+		},
+		{
+			name:          "On time - within a 15 minute grace period",
+			checkInTime:   time.Date(2026, 2, 1, 8, 14, 0, 0, time.Local),
+			workStartTime: "08:00:00",
+			grace:         15 * time.Minute,
+			want:          "ontime",
+		},
+		{
+			name:          "Late - exactly at the end of a 15 minute grace period",
+			checkInTime:   time.Date(2026, 2, 1, 8, 15, 0, 0, time.Local),
+			workStartTime: "08:00:00",
+			grace:         15 * time.Minute,
+			want:          "late",
+		},
+		{
+			name:          "Late - 1 second after work start without grace",
+			checkInTime:   time.Date(2026, 2, 1, 8, 0, 1, 0, time.Local),
+			workStartTime: "08:00:00",
+			want:          "late",
+		},
+		{
+			name:          "Late - after midnight of a night shift that started the day before",
+			checkInTime:   time.Date(2026, 2, 2, 0, 30, 0, 0, time.Local),
+			day:           time.Date(2026, 2, 1, 0, 0, 0, 0, time.Local),
+			workStartTime: "22:00:00",
+			grace:         DefaultGracePeriod,
+			want:          "late",
+		},
+		{
+			name:          "On time - before a night shift",
+			checkInTime:   time.Date(2026, 2, 1, 21, 50, 0, 0, time.Local),
+			day:           time.Date(2026, 2, 1, 0, 0, 0, 0, time.Local),
+			workStartTime: "22:00:00",
+			grace:         DefaultGracePeriod,
 			want:          "ontime",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := calculateStatus(tt.checkInTime, tt.workStartTime)
+			day := tt.day
+			if day.IsZero() {
+				day = tt.checkInTime
+			}
+			got := calculateStatus(tt.checkInTime, day, tt.workStartTime, tt.grace)
 			if got != tt.want {
 				t.Errorf("calculateStatus() = %v, want %v", got, tt.want)
 			}
@@ -60,11 +114,61 @@ func TestCalculateStatus(t *testing.T) {
 	}
 }
 
+func TestGracePeriod(t *testing.T) {
+	tests := []struct {
+		name string
+		emp  models.Employee
+		want time.Duration
+	}{
+		{"site grace period", models.Employee{}, 10 * time.Minute},
+		{"own grace period", models.Employee{GracePeriod: 20}, 20 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GracePeriod(&tt.emp, 10*time.Minute); got != tt.want {
+				t.Errorf("GracePeriod() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGracePeriodPerTenant(t *testing.T) {
+	// The same 08:10 check-in at two sites, one with GRACE_PERIOD_MINUTES=15
+	at := time.Date(2026, 2, 2, 8, 10, 0, 0, time.Local)
+	tests := []struct {
+		name  string
+		grace time.Duration
+		want  string
+	}{
+		{"default grace period", DefaultGracePeriod, models.AttendanceStatusLate},
+		{"tenant grace period", 15 * time.Minute, models.AttendanceStatusOnTime},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memory.NewStore(clock.NewFake(at))
+			store.AddEmployee(models.Employee{ID: "e1", Name: "Somchai", MacAddress: "aa:bb:cc:dd:ee:01", WorkStartTime: "08:00:00", IsActive: true})
+			service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), newRecordingNotifier())
+			service.SetClock(clock.NewFake(at))
+			service.SetGracePeriod(tt.grace)
+
+			req := &models.DetectionRequest{ScannerMac: "scanner-1", MacAddress: "aa:bb:cc:dd:ee:01", RSSI: -50}
+			if err := service.ProcessDetection(context.Background(), req); err != nil {
+				t.Fatalf("ProcessDetection() error = %v", err)
+			}
+			records := store.Attendance()
+			if len(records) != 1 || records[0].Status != tt.want {
+				t.Fatalf("attendance = %+v, want one %s", records, tt.want)
+			}
+		})
+	}
+}
+
 func TestCalculateLateStatus(t *testing.T) {
 	tests := []struct {
 		name          string
 		checkInTime   time.Time
 		workStartTime string
+		lang          string
 		want          string
 	}{
 		{
@@ -85,14 +189,286 @@ func TestCalculateLateStatus(t *testing.T) {
 			workStartTime: "invalid",
 			want:          "เข้าสาย",
 		},
+		{
+			name:          "English",
+			checkInTime:   time.Date(2026, 2, 1, 8, 30, 0, 0, time.Local),
+			workStartTime: "08:00:00",
+			lang:          models.LanguageEnglish,
+			want:          "Late by 30 min",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := calculateLateStatus(tt.checkInTime, tt.workStartTime)
+			got := calculateLateStatus(tt.lang, tt.checkInTime, tt.checkInTime, tt.workStartTime)
 			if got != tt.want {
 				t.Errorf("calculateLateStatus() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestNewCheckInWindow(t *testing.T) {
+	tests := []struct {
+		start, end string
+		wantErr    bool
+	}{
+		{"05:00", "12:00", false},
+		{"00:00", "23:59", false},
+		{"5am", "12:00", true},
+		{"05:00", "", true},
+		{"12:00", "05:00", true}, // crosses midnight
+		{"08:00", "08:00", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.start+"-"+tt.end, func(t *testing.T) {
+			w, err := NewCheckInWindow(tt.start, tt.end)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewCheckInWindow() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && w.String() != tt.start+"-"+tt.end {
+				t.Errorf("String() = %q", w.String())
+			}
+		})
+	}
+}
+
+func TestCheckInWindowContains(t *testing.T) {
+	w, _ := NewCheckInWindow("05:00", "12:00")
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"just after midnight", time.Date(2026, 2, 2, 0, 1, 0, 0, time.Local), false},
+		{"a second before start", time.Date(2026, 2, 2, 4, 59, 59, 0, time.Local), false},
+		{"exactly at start", time.Date(2026, 2, 2, 5, 0, 0, 0, time.Local), true},
+		{"a second before end", time.Date(2026, 2, 2, 11, 59, 59, 0, time.Local), true},
+		{"exactly at end", time.Date(2026, 2, 2, 12, 0, 0, 0, time.Local), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := w.Contains(tt.at); got != tt.want {
+				t.Errorf("Contains(%s) = %v, want %v", tt.at.Format("15:04:05"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttendanceCheckInWindow(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 2, 2, 0, 1, 0, 0, time.Local))
+	store := memory.NewStore(clk)
+	store.AddEmployee(models.Employee{ID: "e1", Name: "Somchai", MacAddress: "aa:bb:cc:dd:ee:01", WorkStartTime: "08:00:00", IsActive: true})
+	service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), newRecordingNotifier())
+	service.SetClock(clk)
+	window, _ := NewCheckInWindow("05:00", "12:00")
+	service.SetCheckInWindow(window)
+	detect := func() DetectionResult {
+		res, err := service.DetectWithResult(context.Background(), &models.DetectionRequest{ScannerMac: "scanner-1", MacAddress: "aa:bb:cc:dd:ee:01", RSSI: -50})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	if res := detect(); res.Result != ResultOutsideWindow || res.CheckedIn {
+		t.Errorf("00:01 result = %+v, want %q", res, ResultOutsideWindow)
+	}
+	if got := len(store.Detections()); got != 1 {
+		t.Errorf("detections = %d, want the 00:01 one saved", got)
+	}
+	if got := len(store.Attendance()); got != 0 {
+		t.Fatalf("attendance = %d, want none outside the window", got)
+	}
+
+	clk.Set(time.Date(2026, 2, 2, 7, 50, 0, 0, time.Local))
+	if res := detect(); !res.CheckedIn {
+		t.Errorf("07:50 result = %+v, want a check-in", res)
+	}
+	if att := store.Attendance(); len(att) != 1 || att[0].Status != "ontime" {
+		t.Errorf("attendance = %+v, want one ontime check-in", att)
+	}
+}
+
+func TestAttendanceLocation(t *testing.T) {
+	bangkok := time.FixedZone("Asia/Bangkok", 7*60*60)
+	tests := []struct {
+		name       string
+		at         time.Time // wall clock of a UTC container
+		wantStatus string
+		wantDate   string
+	}{
+		{"08:30 in Bangkok is late", time.Date(2026, 3, 2, 1, 30, 0, 0, time.UTC), models.AttendanceStatusLate, "2026-03-02"},
+		{"07:50 in Bangkok is on time", time.Date(2026, 3, 2, 0, 50, 0, 0, time.UTC), models.AttendanceStatusOnTime, "2026-03-02"},
+		{"before 07:00 in Bangkok is already the next day", time.Date(2026, 3, 1, 23, 30, 0, 0, time.UTC), models.AttendanceStatusOnTime, "2026-03-02"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			clk := clock.NewFake(tt.at)
+			store := memory.NewStore(clk)
+			store.AddEmployee(models.Employee{Name: "Somchai", MacAddress: "aa:bb:cc:dd:ee:01", WorkStartTime: "08:00:00", IsActive: true})
+			service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), newRecordingNotifier())
+			service.SetClock(clk)
+			service.SetLocation(bangkok)
+
+			for i := 0; i < 2; i++ {
+				if err := service.ProcessDetection(ctx, &models.DetectionRequest{ScannerMac: "SC:01", MacAddress: "aa:bb:cc:dd:ee:01", RSSI: -50}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			att := store.Attendance()
+			if len(att) != 1 {
+				t.Fatalf("attendance = %+v, want one check-in", att)
+			}
+			if att[0].Status != tt.wantStatus || att[0].CreatedDate.Format("2006-01-02") != tt.wantDate {
+				t.Errorf("check-in = %s on %s, want %s on %s", att[0].Status, att[0].CreatedDate.Format("2006-01-02"), tt.wantStatus, tt.wantDate)
+			}
+		})
+	}
+}
+
+func TestAttendanceEmployeeDevices(t *testing.T) {
+	store := newPipelineStore()
+	store.AddDevice(models.EmployeeDevice{EmployeeID: "e1", MacAddress: "aa:bb:cc:dd:ee:02", Label: "AirPods"})
+	store.AddDevice(models.EmployeeDevice{EmployeeID: "gone", MacAddress: "aa:bb:cc:dd:ee:03", Label: "iTag"})
+	service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), newRecordingNotifier())
+	service.SetClock(clock.NewFake(pipelineNow))
+	service.SetDevices(store.Devices())
+
+	tests := []struct {
+		name string
+		mac  string
+		want string
+	}{
+		{"unknown device", "aa:bb:cc:dd:ee:99", ResultUnknownDevice},
+		{"device of a missing employee", "aa:bb:cc:dd:ee:03", ResultUnknownDevice},
+		{"registered device", "AA:BB:CC:DD:EE:02", ResultAccepted},
+		{"legacy mac_address of the same employee", "aa:bb:cc:dd:ee:01", ResultDuplicate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := service.DetectWithResult(context.Background(), &models.DetectionRequest{ScannerMac: "scanner-1", MacAddress: tt.mac, RSSI: -50})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Result != tt.want {
+				t.Errorf("result = %q, want %q", res.Result, tt.want)
+			}
+		})
+	}
+	if att := store.Attendance(); len(att) != 1 || att[0].EmployeeID != "e1" {
+		t.Errorf("attendance = %+v, want one check-in of e1", att)
+	}
+}
+
+func TestCheckInNotificationScannerName(t *testing.T) {
+	tests := []struct {
+		name    string
+		scanner *models.Scanner
+		want    string
+	}{
+		{"named scanner", &models.Scanner{ScannerMac: "AA:BB:CC:00:00:01", Name: "ประตูหน้า", Location: "ชั้น 1"}, "📍 สถานที่: `ประตูหน้า (ชั้น 1)`"},
+		{"unnamed scanner", &models.Scanner{ScannerMac: "AA:BB:CC:00:00:01"}, "📍 สถานที่: `Scanner AA:BB:CC:00:00:01`"},
+		{"unknown scanner", nil, "📍 สถานที่: `Scanner aa:bb:cc:00:00:01`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newPipelineStore()
+			if tt.scanner != nil {
+				store.AddScanner(*tt.scanner)
+			}
+			notifier := newRecordingNotifier()
+			service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), notifier)
+			service.SetClock(clock.NewFake(pipelineNow))
+
+			if err := service.ProcessDetection(context.Background(), &models.DetectionRequest{ScannerMac: "aa:bb:cc:00:00:01", MacAddress: "aa:bb:cc:dd:ee:01", RSSI: -50}); err != nil {
+				t.Fatal(err)
+			}
+			if got := notifier.personal[1001]; len(got) != 1 || !strings.Contains(got[0], tt.want) {
+				t.Errorf("sent %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttendanceIBeacon(t *testing.T) {
+	store := newPipelineStore()
+	store.AddEmployee(models.Employee{ID: "e2", Name: "Malee", MacAddress: "aa:bb:cc:dd:ee:02", TelegramChatID: 1002,
+		IBeaconID: "fda50693-a4e2-4fb1-afcf-c6eb07647825:1:42", WorkStartTime: "08:00:00", IsActive: true})
+	service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), newRecordingNotifier())
+	service.SetClock(clock.NewFake(pipelineNow))
+
+	tests := []struct {
+		name  string
+		mac   string
+		uuid  string
+		minor int
+		want  string
+	}{
+		{"random MAC without iBeacon", "5e:11:22:33:44:55", "", 0, ResultUnknownDevice},
+		{"random MAC with another iBeacon", "5e:11:22:33:44:56", "fda50693-a4e2-4fb1-afcf-c6eb07647825", 43, ResultUnknownDevice},
+		{"random MAC with the employee's iBeacon", "5e:11:22:33:44:57", "FDA50693A4E24FB1AFCFC6EB07647825", 42, ResultAccepted},
+		{"MAC is matched before the iBeacon", "aa:bb:cc:dd:ee:01", "fda50693-a4e2-4fb1-afcf-c6eb07647825", 42, ResultAccepted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := service.DetectWithResult(context.Background(), &models.DetectionRequest{ScannerMac: "scanner-1", MacAddress: tt.mac,
+				RSSI: -50, IBeaconUUID: tt.uuid, Major: 1, Minor: tt.minor})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Result != tt.want {
+				t.Errorf("result = %q, want %q", res.Result, tt.want)
+			}
+		})
+	}
+
+	att := store.Attendance()
+	if len(att) != 2 || att[0].EmployeeID != "e2" || att[1].EmployeeID != "e1" {
+		t.Errorf("attendance = %+v, want check-ins of e2 and then e1", att)
+	}
+	if d := store.Detections(); len(d) == 0 || d[0].Minor != 42 || d[0].IBeaconUUID == "" {
+		t.Errorf("detections = %+v, want the iBeacon saved", d)
+	}
+}
+
+func TestAttendanceEddystone(t *testing.T) {
+	store := newPipelineStore()
+	store.AddEmployee(models.Employee{ID: "e2", Name: "Malee", MacAddress: "aa:bb:cc:dd:ee:02", TelegramChatID: 1002,
+		EddystoneID: "edd1ebeac04e5defa017:0123456789ab", WorkStartTime: "08:00:00", IsActive: true})
+	service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), newRecordingNotifier())
+	service.SetClock(clock.NewFake(pipelineNow))
+
+	tests := []struct {
+		name         string
+		mac          string
+		namespace    string
+		instance     string
+		want         string
+		wantIdentity string
+	}{
+		{"random MAC with another instance", "5e:11:22:33:44:56", "edd1ebeac04e5defa017", "0123456789ac", ResultUnknownDevice, ""},
+		{"random MAC with the employee's Eddystone-UID", "5e:11:22:33:44:57", "EDD1EBEAC04E5DEFA017", "0123456789AB", ResultAccepted, IdentityEddystone},
+		{"MAC is matched before the Eddystone-UID", "aa:bb:cc:dd:ee:01", "edd1ebeac04e5defa017", "0123456789ab", ResultAccepted, IdentityMAC},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := service.DetectWithResult(context.Background(), &models.DetectionRequest{ScannerMac: "scanner-1", MacAddress: tt.mac,
+				RSSI: -50, NamespaceID: tt.namespace, InstanceID: tt.instance})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Result != tt.want || res.Identity != tt.wantIdentity {
+				t.Errorf("result = %q by %q, want %q by %q", res.Result, res.Identity, tt.want, tt.wantIdentity)
+			}
+		})
+	}
+
+	if att := store.Attendance(); len(att) != 2 || att[0].EmployeeID != "e2" {
+		t.Errorf("attendance = %+v, want check-ins of e2 and then e1", att)
+	}
+	if d := store.Detections(); len(d) == 0 || d[0].InstanceID == "" {
+		t.Errorf("detections = %+v, want the Eddystone-UID saved", d)
+	}
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// AutoCheckOutStore is the attendance storage the auto check-out reads and updates
+type AutoCheckOutStore interface {
+	repository.AttendanceLog
+	repository.AttendanceUpdater
+}
+
+// AutoCheckOut closes the day's attendance records nobody checked out of, taking the
+// employee's last detection of the day as the check-out. It runs once a day after work
+// (AUTO_CHECKOUT_TIME); check-outs recorded any other way are left alone.
+type AutoCheckOut struct {
+	attendance AutoCheckOutStore
+	detections repository.DetectionLog
+	employees  repository.EmployeeDirectory
+}
+
+// NewAutoCheckOut creates the auto check-out task
+func NewAutoCheckOut(attendance AutoCheckOutStore, detections repository.DetectionLog, employees repository.EmployeeDirectory) *AutoCheckOut {
+	return &AutoCheckOut{attendance: attendance, detections: detections, employees: employees}
+}
+
+// Run checks out the day's open records; as an EndOfDayTask a failing record does not
+// stop the others
+func (a *AutoCheckOut) Run(ctx context.Context, day time.Time) error {
+	open, err := a.attendance.ListOpenForDate(ctx, day)
+	if err != nil {
+		return fmt.Errorf("failed to list open attendance: %w", err)
+	}
+	if len(open) == 0 {
+		return nil
+	}
+	employees, err := a.employees.ListActive(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list employees: %w", err)
+	}
+	byID := make(map[string]models.Employee, len(employees))
+	for _, emp := range employees {
+		byID[emp.ID] = emp
+	}
+
+	closed, failed := 0, 0
+	for i := range open {
+		att := &open[i]
+		// A night shift is still under way at the end of its first calendar day
+		if emp, ok := byID[att.EmployeeID]; ok && emp.Overnight() {
+			continue
+		}
+		last, err := a.detections.GetLastForEmployeeOnDate(ctx, att.EmployeeID, day)
+		if err != nil {
+			log.Printf("❌ Auto check-out of %s failed: %v", att.EmployeeID, err)
+			failed++
+			continue
+		}
+		// Nothing after the check-in itself says when they left
+		if last == nil || !last.DetectedAt.After(att.CheckInTime) {
+			continue
+		}
+
+		att.CheckOutTime = last.DetectedAt
+		att.CheckOutSource = models.CheckOutSourceAuto
+		if err := a.attendance.UpdateCheckOut(ctx, att); err != nil {
+			if errors.Is(err, repository.ErrPeriodLocked) {
+				log.Printf("🔒 Auto check-out of %s not recorded: %v", att.EmployeeID, err)
+				continue
+			}
+			log.Printf("❌ Auto check-out of %s failed: %v", att.EmployeeID, err)
+			failed++
+			continue
+		}
+		closed++
+	}
+	log.Printf("🌆 Auto check-out for %s: %d of %d open records closed", day.Format("2006-01-02"), closed, len(open))
+	if failed > 0 {
+		return fmt.Errorf("%d auto check-outs failed", failed)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+func TestAutoCheckOut_Run(t *testing.T) {
+	day := time.Date(2026, 2, 2, 0, 0, 0, 0, time.Local)
+	at := func(h, m int) time.Time { return day.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute) }
+	store := memory.NewStore(clock.NewFake(at(20, 0)))
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		employee     models.Employee
+		checkOut     time.Time // existing check-out
+		source       string    // of the existing check-out
+		detections   []time.Time
+		wantCheckOut time.Time // zero when the record stays open
+		wantSource   string
+	}{
+		{name: "last detection of the day", detections: []time.Time{at(8, 0), at(17, 42), at(12, 5)}, wantCheckOut: at(17, 42), wantSource: models.CheckOutSourceAuto},
+		{name: "only the check-in detection", detections: []time.Time{at(8, 0)}},
+		{name: "no detections"},
+		{name: "detection on another day", detections: []time.Time{at(8, 0), day.AddDate(0, 0, 1).Add(7 * time.Hour)}},
+		{name: "already checked out by the reminder", checkOut: at(17, 0), source: models.CheckOutSourceSelfReported,
+			detections: []time.Time{at(8, 0), at(18, 0)}, wantCheckOut: at(17, 0), wantSource: models.CheckOutSourceSelfReported},
+		{name: "night shift", employee: models.Employee{WorkStartTime: "22:00:00", WorkEndTime: "06:00:00"}, detections: []time.Time{at(8, 0), at(9, 0)}},
+	}
+	ids := make([]string, len(tests))
+	for i, tt := range tests {
+		emp := tt.employee
+		emp.ID, emp.Name, emp.IsActive = tt.name, tt.name, true
+		store.AddEmployee(emp)
+		att := &models.Attendance{EmployeeID: emp.ID, CheckInTime: at(8, 0), CheckOutTime: tt.checkOut, CheckOutSource: tt.source,
+			Status: models.AttendanceStatusOnTime, CreatedDate: day}
+		if err := store.AttendanceRecords().Create(ctx, att); err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = att.ID
+		for _, d := range tt.detections {
+			if err := store.DetectionRecords().Create(ctx, &models.EmployeeDetection{EmployeeID: emp.ID, ScannerMac: "scanner-1", DetectedAt: d}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := NewAutoCheckOut(store.AttendanceRecords(), store.DetectionRecords(), store.Employees()).Run(ctx, day); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			att, err := store.AttendanceRecords().Get(ctx, ids[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !att.CheckOutTime.Equal(tt.wantCheckOut) || att.CheckOutSource != tt.wantSource {
+				t.Errorf("check-out = %s (%q), want %s (%q)", att.CheckOutTime.Format("15:04"), att.CheckOutSource,
+					tt.wantCheckOut.Format("15:04"), tt.wantSource)
+			}
+		})
+	}
+}
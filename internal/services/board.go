@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// BoardCacheTTL is how long the public board data is reused before it is rebuilt
+const BoardCacheTTL = 30 * time.Second
+
+// BoardEntry is one row of the public status board. It deliberately carries no
+// check-in times, MAC addresses or record IDs.
+type BoardEntry struct {
+	DisplayName string `json:"display_name"`
+	Department  string `json:"department,omitempty"`
+	Present     bool   `json:"present"`
+}
+
+// BoardProvider defines the interface for reading the public status board
+type BoardProvider interface {
+	Board(ctx context.Context) ([]BoardEntry, error)
+}
+
+// BoardService builds the public status board from active employees and today's check-ins
+type BoardService struct {
+	employees  repository.EmployeeDirectory
+	attendance repository.AttendanceLog
+	clock      clock.Clock
+	versions   *repository.DataVersions // optional
+
+	mu            sync.Mutex
+	cached        []BoardEntry
+	cachedAt      time.Time
+	cachedVersion uint64
+}
+
+// NewBoardService creates a new board service
+func NewBoardService(employees repository.EmployeeDirectory, attendance repository.AttendanceLog) *BoardService {
+	return &BoardService{
+		employees:  employees,
+		attendance: attendance,
+		clock:      clock.Real{},
+	}
+}
+
+// SetClock replaces the time source, used by tests
+func (s *BoardService) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// SetDataVersions rebuilds the board as soon as any attendance changed, instead of
+// after BoardCacheTTL
+func (s *BoardService) SetDataVersions(v *repository.DataVersions) {
+	s.versions = v
+}
+
+// Board returns the current board, rebuilding it at most once per BoardCacheTTL
+// unless attendance changed
+func (s *BoardService) Board(ctx context.Context) ([]BoardEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	var version uint64
+	if s.versions != nil {
+		version = s.versions.Global()
+	}
+	if s.cached != nil && now.Sub(s.cachedAt) < BoardCacheTTL && version == s.cachedVersion {
+		return s.cached, nil
+	}
+
+	entries, err := s.build(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	s.cached = entries
+	s.cachedAt = now
+	s.cachedVersion = version
+	return entries, nil
+}
+
+func (s *BoardService) build(ctx context.Context, now time.Time) ([]BoardEntry, error) {
+	employees, err := s.employees.ListActive(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list employees: %w", err)
+	}
+
+	records, err := s.attendance.ListByDate(ctx, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attendance: %w", err)
+	}
+	present := make(map[string]bool, len(records))
+	for _, a := range records {
+		present[a.EmployeeID] = true
+	}
+
+	entries := make([]BoardEntry, 0, len(employees))
+	for _, emp := range employees {
+		if !emp.ShowOnBoard {
+			continue
+		}
+		entries = append(entries, BoardEntry{
+			DisplayName: BoardDisplayName(emp),
+			Department:  emp.Department,
+			Present:     present[emp.ID],
+		})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Department != entries[j].Department {
+			return entries[i].Department < entries[j].Department
+		}
+		return entries[i].DisplayName < entries[j].DisplayName
+	})
+	return entries, nil
+}
+
+// BoardDisplayName returns the name shown on the public board: the display name
+// if set, otherwise the employee's first name
+func BoardDisplayName(emp models.Employee) string {
+	if name := strings.TrimSpace(emp.DisplayName); name != "" {
+		return name
+	}
+	if fields := strings.Fields(emp.Name); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+func newBoardFixture(t *testing.T) (*BoardService, *memory.Store, *clock.Fake) {
+	t.Helper()
+	clk := clock.NewFake(time.Date(2026, 2, 2, 9, 0, 0, 0, time.Local))
+	store := memory.NewStore(clk)
+
+	store.AddEmployee(models.Employee{ID: "e1", Name: "Somchai Jaidee", Department: "ER", IsActive: true, ShowOnBoard: true,
+		MacAddress: "aa:bb:cc:dd:ee:01"})
+	store.AddEmployee(models.Employee{ID: "e2", Name: "Suda Rakdee", DisplayName: "Dr. Suda", Department: "ER", IsActive: true, ShowOnBoard: true})
+	store.AddEmployee(models.Employee{ID: "e3", Name: "Hidden Person", Department: "ER", IsActive: true, ShowOnBoard: false})
+	store.AddEmployee(models.Employee{ID: "e4", Name: "Former Staff", Department: "OPD", IsActive: false, ShowOnBoard: true})
+	store.AddEmployee(models.Employee{ID: "e5", Name: "Anan", Department: "Admin", IsActive: true, ShowOnBoard: true})
+
+	service := NewBoardService(store.Employees(), store.AttendanceRecords())
+	service.SetClock(clk)
+	return service, store, clk
+}
+
+func TestBoardService_Board(t *testing.T) {
+	service, store, clk := newBoardFixture(t)
+	store.AttendanceRecords().Create(context.Background(), &models.Attendance{
+		EmployeeID: "e1", CheckInTime: clk.Now(), CreatedDate: clk.Now(),
+	})
+	// Yesterday's check-in must not count
+	store.AttendanceRecords().Create(context.Background(), &models.Attendance{
+		EmployeeID: "e5", CheckInTime: clk.Now().AddDate(0, 0, -1), CreatedDate: clk.Now().AddDate(0, 0, -1),
+	})
+
+	entries, err := service.Board(context.Background())
+	if err != nil {
+		t.Fatalf("Board() error = %v", err)
+	}
+
+	want := []BoardEntry{
+		{DisplayName: "Anan", Department: "Admin", Present: false},
+		{DisplayName: "Dr. Suda", Department: "ER", Present: false},
+		{DisplayName: "Somchai", Department: "ER", Present: true},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Board() = %+v, want %+v", entries, want)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entry[%d] = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+}
+
+func TestBoardService_Cache(t *testing.T) {
+	service, store, clk := newBoardFixture(t)
+	ctx := context.Background()
+
+	if _, err := service.Board(ctx); err != nil {
+		t.Fatalf("Board() error = %v", err)
+	}
+
+	store.AttendanceRecords().Create(ctx, &models.Attendance{EmployeeID: "e5", CheckInTime: clk.Now(), CreatedDate: clk.Now()})
+
+	tests := []struct {
+		name        string
+		advance     time.Duration
+		wantPresent bool
+	}{
+		{"within TTL serves cached board", BoardCacheTTL - time.Second, false},
+		{"after TTL rebuilds board", time.Second, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk.Advance(tt.advance)
+			entries, err := service.Board(ctx)
+			if err != nil {
+				t.Fatalf("Board() error = %v", err)
+			}
+			if entries[0].DisplayName != "Anan" || entries[0].Present != tt.wantPresent {
+				t.Errorf("entry[0] = %+v, want Anan present=%v", entries[0], tt.wantPresent)
+			}
+		})
+	}
+}
+
+func TestBoardDisplayName(t *testing.T) {
+	tests := []struct {
+		name string
+		emp  models.Employee
+		want string
+	}{
+		{"display name wins", models.Employee{Name: "Somchai Jaidee", DisplayName: "Chai"}, "Chai"},
+		{"first name by default", models.Employee{Name: "Somchai Jaidee"}, "Somchai"},
+		{"blank display name ignored", models.Employee{Name: "Suda", DisplayName: "  "}, "Suda"},
+		{"empty name", models.Employee{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BoardDisplayName(tt.emp); got != tt.want {
+				t.Errorf("BoardDisplayName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
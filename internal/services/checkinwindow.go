@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// CheckInWindow is the time of day during which a detection may create a check-in, so a
+// tag left in a drawer does not check its owner in at 00:01
+type CheckInWindow struct {
+	start, end time.Duration // offsets from midnight; start inclusive, end exclusive
+}
+
+// NewCheckInWindow parses an HH:MM start and end; the window must not cross midnight
+func NewCheckInWindow(start, end string) (*CheckInWindow, error) {
+	from, err := parseClock(start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid check-in window start: %w", err)
+	}
+	until, err := parseClock(end)
+	if err != nil {
+		return nil, fmt.Errorf("invalid check-in window end: %w", err)
+	}
+	if until <= from {
+		return nil, fmt.Errorf("check-in window end %s is not after its start %s", end, start)
+	}
+	return &CheckInWindow{start: from, end: until}, nil
+}
+
+// Contains reports whether t's wall clock is inside the window
+func (w *CheckInWindow) Contains(t time.Time) bool {
+	offset := sinceMidnight(t)
+	return offset >= w.start && offset < w.end
+}
+
+// String renders the window as HH:MM-HH:MM
+func (w *CheckInWindow) String() string {
+	midnight := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	return midnight.Add(w.start).Format("15:04") + "-" + midnight.Add(w.end).Format("15:04")
+}
+
+// CheckInWindowStage stops detections outside the check-in window after they have been
+// saved for presence analytics, so they never create attendance
+type CheckInWindowStage struct {
+	Window *CheckInWindow
+}
+
+func (s CheckInWindowStage) Process(ctx context.Context, dc *DetectionContext) (bool, error) {
+	if !s.Window.Contains(dc.Now) {
+		dc.Notef("%s outside %s", dc.Now.Format("15:04"), s.Window)
+		dc.Reject(ResultOutsideWindow, nil)
+		return false, nil
+	}
+	return true, nil
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// CheckOutTrackerConfig configures check-out tracking
+type CheckOutTrackerConfig struct {
+	After   string        // HH:MM from which a detection of a checked-in employee counts as leaving
+	Until   string        // HH:MM from which detections no longer move the check-out; empty for midnight
+	Refresh time.Duration // least time between the check-in and a check-out, and between two updates
+}
+
+// DefaultCheckOutTrackerConfig returns the built-in timings
+func DefaultCheckOutTrackerConfig() CheckOutTrackerConfig {
+	return CheckOutTrackerConfig{
+		After:   "16:00",
+		Refresh: 5 * time.Minute,
+	}
+}
+
+// CheckOutTrackerStore is the attendance storage the tracker reads and updates
+type CheckOutTrackerStore interface {
+	repository.AttendanceToday
+	repository.AttendanceUpdater
+}
+
+// CheckOutTracker records departures: every detection of a checked-in employee from the
+// check-out time on moves the day's check-out to that detection, so the last one counts
+type CheckOutTracker struct {
+	cfg        CheckOutTrackerConfig
+	after      time.Duration
+	until      time.Duration // 0 when detections move the check-out until midnight
+	attendance CheckOutTrackerStore
+	notifier   BotNotifier
+	templates  *MessageTemplates
+	scanners   repository.ScannerRepository // optional, names the scanner in the message
+}
+
+// NewCheckOutTracker creates a tracker; the times must be HH:MM
+func NewCheckOutTracker(cfg CheckOutTrackerConfig, attendance CheckOutTrackerStore, notifier BotNotifier) (*CheckOutTracker, error) {
+	after, err := parseClock(cfg.After)
+	if err != nil {
+		return nil, fmt.Errorf("invalid check-out time: %w", err)
+	}
+	var until time.Duration
+	if cfg.Until != "" {
+		if until, err = parseClock(cfg.Until); err != nil {
+			return nil, fmt.Errorf("invalid check-out end: %w", err)
+		}
+		if until <= after {
+			return nil, fmt.Errorf("check-out end %s is not after %s", cfg.Until, cfg.After)
+		}
+	}
+	return &CheckOutTracker{cfg: cfg, after: after, until: until, attendance: attendance, notifier: notifier}, nil
+}
+
+// SetTemplates replaces the built-in check-out message
+func (t *CheckOutTracker) SetTemplates(templates *MessageTemplates) {
+	t.templates = templates
+}
+
+// SetScanners names the scanner in check-out messages by its label instead of its MAC
+func (t *CheckOutTracker) SetScanners(scanners repository.ScannerRepository) {
+	t.scanners = scanners
+}
+
+// Observe handles a detection of the employee at the given time. It returns the day's
+// attendance record when the detection counts as a check-out, nil otherwise, and whether
+// the record's check-out was moved to this detection.
+func (t *CheckOutTracker) Observe(ctx context.Context, employee *models.Employee, at time.Time) (*models.Attendance, bool, error) {
+	// The detection's own shift day, not today's: a queued or replayed detection may be
+	// from an earlier day, and a night shift's check-out from the day after its check-in
+	day := employee.ShiftDay(at)
+	if !t.leaving(employee, at.Sub(day)) {
+		return nil, false, nil
+	}
+	att, err := t.attendance.GetByEmployeeOn(ctx, employee.ID, day)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get the day's attendance: %w", err)
+	}
+	if att == nil {
+		return nil, false, nil
+	}
+
+	// A self-reported or imported check-out is the employee's or an admin's word
+	if !att.CheckOutTime.IsZero() && att.CheckOutSource != models.CheckOutSourceScanner {
+		return att, false, nil
+	}
+	last := att.CheckOutTime
+	if last.IsZero() {
+		last = att.CheckInTime
+	}
+	if at.Sub(last) < t.cfg.Refresh {
+		return att, false, nil
+	}
+
+	first := att.CheckOutTime.IsZero()
+	att.CheckOutTime = at
+	att.CheckOutSource = models.CheckOutSourceScanner
+	if err := t.attendance.UpdateCheckOut(ctx, att); err != nil {
+		if errors.Is(err, repository.ErrPeriodLocked) {
+			log.Printf("🔒 Check-out of %s not recorded: %v", employee.Name, err)
+			return att, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to update check-out: %w", err)
+	}
+	log.Printf("👋 Employee %s checked out at %s", employee.Name, at.Format("15:04:05"))
+
+	if first {
+		t.notify(ctx, employee, att)
+	}
+	return att, true, nil
+}
+
+// leaving reports whether a detection the given time after midnight of its shift day counts
+// as leaving. An overnight shift (e.g. 22:00-06:00) is left from its middle on; its end
+// needs no bound, as ShiftDay hands later detections to the next shift.
+func (t *CheckOutTracker) leaving(employee *models.Employee, since time.Duration) bool {
+	if employee.Overnight() {
+		start, _ := time.Parse("15:04:05", employee.WorkStartTime)
+		end, _ := time.Parse("15:04:05", employee.WorkEndTime)
+		length := end.Sub(start) + 24*time.Hour
+		return since >= sinceMidnight(start)+length/2
+	}
+	return since >= t.after && (t.until == 0 || since < t.until)
+}
+
+// notify tells the employee about their first check-out of the day; later detections
+// move the check-out without another message
+func (t *CheckOutTracker) notify(ctx context.Context, employee *models.Employee, att *models.Attendance) {
+	if employee.TelegramChatID == 0 {
+		return
+	}
+	if employee.NotificationMuted(models.NotificationCheckOut) {
+		log.Printf("🔕 %s muted check-out notifications", employee.Name)
+		return
+	}
+	t.notifier.SendPersonalNotification(employee.TelegramChatID, t.templates.Render(employee.Language, TemplateCheckOut, MessageData{
+		Name:            employee.Name,
+		CheckInTime:     att.CheckInTime.Format("15:04:05"),
+		CheckOutTime:    att.CheckOutTime.Format("15:04:05"),
+		Worked:          formatWorked(employee.Language, att.CheckOutTime.Sub(att.CheckInTime)),
+		ScannerLocation: lookUpScanner(ctx, t.scanners, att.ScannerMac).Label(),
+	}))
+}
+
+// formatWorked renders hours worked as "8 ชั่วโมง 30 นาที", or "8h 30m" in English
+func formatWorked(lang string, d time.Duration) string {
+	minutes := int(d.Minutes())
+	if minutes < 0 {
+		minutes = 0
+	}
+	return fmt.Sprintf(statusText(lang, "worked"), minutes/60, minutes%60)
+}
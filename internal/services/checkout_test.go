@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+func TestCheckOutTrackerObserve(t *testing.T) {
+	day := func(h, m int) time.Time { return time.Date(2026, 2, 2, h, m, 0, 0, time.Local) }
+
+	tests := []struct {
+		name         string
+		employee     models.Employee
+		notCheckedIn bool
+		checkOut     time.Time // existing check-out
+		source       string    // of the existing check-out
+		at           time.Time
+		wantCheckOut time.Time // zero when none is recorded
+		wantMoved    bool
+		wantNotified bool
+	}{
+		{
+			name: "before the check-out time",
+			at:   day(15, 59),
+		},
+		{
+			name:         "not checked in",
+			notCheckedIn: true,
+			at:           day(16, 30),
+		},
+		{
+			name:         "first check-out is notified",
+			at:           day(16, 30),
+			wantCheckOut: day(16, 30),
+			wantMoved:    true,
+			wantNotified: true,
+		},
+		{
+			name:         "later detection moves it quietly",
+			checkOut:     day(16, 30),
+			source:       models.CheckOutSourceScanner,
+			at:           day(17, 15),
+			wantCheckOut: day(17, 15),
+			wantMoved:    true,
+		},
+		{
+			name:         "too soon after the last update",
+			checkOut:     day(17, 12),
+			source:       models.CheckOutSourceScanner,
+			at:           day(17, 15),
+			wantCheckOut: day(17, 12),
+		},
+		{
+			name:         "self-reported check-out is kept",
+			checkOut:     day(17, 0),
+			source:       models.CheckOutSourceSelfReported,
+			at:           day(17, 30),
+			wantCheckOut: day(17, 0),
+		},
+		{
+			name: "from the evening on",
+			at:   day(20, 0),
+		},
+		{
+			name:         "muted",
+			employee:     models.Employee{MutedNotifications: []string{"checkout"}},
+			at:           day(16, 30),
+			wantCheckOut: day(16, 30),
+			wantMoved:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := memory.NewStore(clock.NewFake(day(8, 0)))
+			tt.employee.Name, tt.employee.TelegramChatID, tt.employee.IsActive = "Somchai", 42, true
+			emp := store.AddEmployee(tt.employee)
+			if !tt.notCheckedIn {
+				store.AttendanceRecords().Create(ctx, &models.Attendance{EmployeeID: emp.ID, CheckInTime: day(8, 0),
+					CreatedDate: day(8, 0), Status: "ontime", CheckOutTime: tt.checkOut, CheckOutSource: tt.source})
+			}
+
+			cfg := DefaultCheckOutTrackerConfig()
+			cfg.Until = "20:00"
+			notifier := newRecordingNotifier()
+			tracker, err := NewCheckOutTracker(cfg, store.AttendanceRecords(), notifier)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, moved, err := tracker.Observe(ctx, &emp, tt.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if moved != tt.wantMoved {
+				t.Errorf("moved = %v, want %v", moved, tt.wantMoved)
+			}
+			var got time.Time
+			if records := store.Attendance(); len(records) > 0 {
+				got = records[0].CheckOutTime
+			}
+			if !got.Equal(tt.wantCheckOut) {
+				t.Errorf("check-out = %v, want %v", got, tt.wantCheckOut)
+			}
+			if notified := len(notifier.personal[42]) > 0; notified != tt.wantNotified {
+				t.Errorf("notified = %v, want %v", notified, tt.wantNotified)
+			}
+			if tt.wantNotified && !strings.Contains(notifier.personal[42][0], "8 ชั่วโมง 30 นาที") {
+				t.Errorf("notification = %q, want hours worked", notifier.personal[42][0])
+			}
+		})
+	}
+}
+
+func TestCheckOutTrackerReplayedDetection(t *testing.T) {
+	yesterday := func(h, m int) time.Time { return time.Date(2026, 2, 1, h, m, 0, 0, time.Local) }
+	today := time.Date(2026, 2, 2, 9, 0, 0, 0, time.Local)
+	ctx := context.Background()
+	store := memory.NewStore(clock.NewFake(today))
+	emp := store.AddEmployee(models.Employee{Name: "Somchai", IsActive: true})
+	store.AttendanceRecords().Create(ctx, &models.Attendance{EmployeeID: emp.ID, CheckInTime: yesterday(8, 0),
+		CreatedDate: yesterday(8, 0), Status: "ontime"})
+	store.AttendanceRecords().Create(ctx, &models.Attendance{EmployeeID: emp.ID, CheckInTime: today,
+		CreatedDate: today, Status: "ontime"})
+
+	tracker, err := NewCheckOutTracker(DefaultCheckOutTrackerConfig(), store.AttendanceRecords(), newRecordingNotifier())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A detection of yesterday evening replayed from the queue this morning
+	if _, moved, err := tracker.Observe(ctx, &emp, yesterday(17, 30)); err != nil || !moved {
+		t.Fatalf("Observe() = %v, %v, want moved", moved, err)
+	}
+	for _, a := range store.Attendance() {
+		want := time.Time{}
+		if a.CreatedDate.Day() == 1 {
+			want = yesterday(17, 30)
+		}
+		if !a.CheckOutTime.Equal(want) {
+			t.Errorf("check-out of %s = %v, want %v", a.CreatedDate.Format("2006-01-02"), a.CheckOutTime, want)
+		}
+	}
+}
+
+func TestCheckOutTrackerNightShift(t *testing.T) {
+	at := func(day, h, m int) time.Time { return time.Date(2026, 2, day, h, m, 0, 0, time.Local) }
+
+	tests := []struct {
+		name         string
+		at           time.Time
+		wantCheckOut time.Time
+	}{
+		{"still on shift before midnight", at(1, 23, 30), time.Time{}},
+		{"still on shift after midnight", at(2, 1, 30), time.Time{}},
+		{"leaving at the end of the shift", at(2, 5, 30), at(2, 5, 30)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := memory.NewStore(clock.NewFake(tt.at))
+			emp := store.AddEmployee(models.Employee{Name: "Malee", TelegramChatID: 42, IsActive: true,
+				WorkStartTime: "22:00:00", WorkEndTime: "06:00:00"})
+			// The check-in is stored under the day the shift started
+			store.AttendanceRecords().Create(ctx, &models.Attendance{EmployeeID: emp.ID, CheckInTime: at(1, 21, 55),
+				CreatedDate: at(1, 0, 0), Status: "ontime"})
+
+			tracker, err := NewCheckOutTracker(DefaultCheckOutTrackerConfig(), store.AttendanceRecords(), newRecordingNotifier())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := tracker.Observe(ctx, &emp, tt.at); err != nil {
+				t.Fatal(err)
+			}
+			if got := store.Attendance()[0].CheckOutTime; !got.Equal(tt.wantCheckOut) {
+				t.Errorf("check-out = %v, want %v", got, tt.wantCheckOut)
+			}
+		})
+	}
+}
+
+func TestCheckOutMessageNamesTheScanner(t *testing.T) {
+	day := func(h, m int) time.Time { return time.Date(2026, 2, 2, h, m, 0, 0, time.Local) }
+
+	tests := []struct {
+		name    string
+		scanner models.Scanner
+		want    string
+	}{
+		{"named", models.Scanner{ScannerMac: "aa:bb:cc:00:00:01", Name: "Lobby", Location: "Building A"}, "Lobby (Building A)"},
+		{"unnamed", models.Scanner{ScannerMac: "aa:bb:cc:00:00:01"}, "Scanner aa:bb:cc:00:00:01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := memory.NewStore(clock.NewFake(day(8, 0)))
+			store.AddScanner(tt.scanner)
+			emp := store.AddEmployee(models.Employee{Name: "Somchai", TelegramChatID: 42, IsActive: true})
+			store.AttendanceRecords().Create(ctx, &models.Attendance{EmployeeID: emp.ID, CheckInTime: day(8, 0),
+				CreatedDate: day(8, 0), Status: "ontime", ScannerMac: "aa:bb:cc:00:00:01"})
+
+			notifier := newRecordingNotifier()
+			tracker, err := NewCheckOutTracker(DefaultCheckOutTrackerConfig(), store.AttendanceRecords(), notifier)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tracker.SetScanners(store.ScannerRecords())
+			// The built-in check-out message has no location; a custom one may show it
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, TemplateCheckOut+".tmpl"), []byte("📍 {{code .ScannerLocation}}"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			templates, err := LoadMessageTemplates(dir, models.LanguageThai)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tracker.SetTemplates(templates)
+			if _, _, err := tracker.Observe(ctx, &emp, day(16, 30)); err != nil {
+				t.Fatal(err)
+			}
+			if len(notifier.personal[42]) != 1 || !strings.Contains(notifier.personal[42][0], tt.want) {
+				t.Errorf("notification = %q, want the scanner shown as %q", notifier.personal[42], tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCheckOutTrackerValidatesTimes(t *testing.T) {
+	tests := []struct {
+		name  string
+		after string
+		until string
+	}{
+		{"malformed", "4pm", ""},
+		{"end before start", "16:00", "15:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := CheckOutTrackerConfig{After: tt.after, Until: tt.until}
+			if _, err := NewCheckOutTracker(cfg, nil, nil); err == nil {
+				t.Error("want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,328 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/macaddr"
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// PromptNotifier is a notifier that can attach inline reply buttons to personal messages
+type PromptNotifier interface {
+	BotNotifier
+	SendPersonalPrompt(chatID int64, message string, buttons []models.PromptButton)
+}
+
+// CheckOutCallbackPrefix routes the reminder's button presses back to the reminder
+const CheckOutCallbackPrefix = "co"
+
+// CheckOutReminderConfig configures the forgotten check-out reminder
+type CheckOutReminderConfig struct {
+	DefaultEndTime string        // HH:MM:SS for employees without a work_end_time; empty skips them
+	Delay          time.Duration // time after the scheduled end before reminding
+	Snooze         time.Duration // how long "still working" postpones the reminder
+	ExitScanners   []string      // scanner MACs whose detections count as leaving
+}
+
+// DefaultCheckOutReminderConfig returns the built-in timings
+func DefaultCheckOutReminderConfig() CheckOutReminderConfig {
+	return CheckOutReminderConfig{
+		Delay:  30 * time.Minute,
+		Snooze: 2 * time.Hour,
+	}
+}
+
+// CheckOutStore is the attendance storage the reminder reads and updates
+type CheckOutStore interface {
+	repository.AttendanceLog
+	repository.AttendanceUpdater
+}
+
+// reminderState tracks the reminder of one attendance record
+type reminderState struct {
+	sent         bool
+	snoozedUntil time.Time
+	done         bool
+}
+
+// presence is what the reminder saw of an employee's device today
+type presence struct {
+	day      string
+	lastSeen time.Time
+	exited   bool
+}
+
+// CheckOutReminder asks employees who are still checked in after their scheduled end
+// whether they already left, and records their answer as a self-reported check-out
+type CheckOutReminder struct {
+	cfg          CheckOutReminderConfig
+	exitScanners map[string]bool
+	employees    repository.EmployeeDirectory
+	attendance   CheckOutStore
+	notifier     PromptNotifier
+	clock        clock.Clock
+
+	mu        sync.Mutex
+	presence  map[string]*presence      // employee ID → today's detections
+	reminders map[string]*reminderState // attendance ID → reminder
+}
+
+// NewCheckOutReminder creates a reminder; exit scanner MACs must be valid
+func NewCheckOutReminder(cfg CheckOutReminderConfig, employees repository.EmployeeDirectory, attendance CheckOutStore, notifier PromptNotifier) (*CheckOutReminder, error) {
+	if cfg.DefaultEndTime != "" {
+		if _, err := time.Parse("15:04:05", cfg.DefaultEndTime); err != nil {
+			return nil, fmt.Errorf("invalid work end time %q: want HH:MM:SS", cfg.DefaultEndTime)
+		}
+	}
+	exitScanners := make(map[string]bool)
+	for _, mac := range cfg.ExitScanners {
+		normalized, err := macaddr.Normalize(mac)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exit scanner %q: %w", mac, err)
+		}
+		exitScanners[normalized] = true
+	}
+
+	return &CheckOutReminder{
+		cfg:          cfg,
+		exitScanners: exitScanners,
+		employees:    employees,
+		attendance:   attendance,
+		notifier:     notifier,
+		clock:        clock.Real{},
+		presence:     make(map[string]*presence),
+		reminders:    make(map[string]*reminderState),
+	}, nil
+}
+
+// SetClock replaces the time source, used by tests
+func (r *CheckOutReminder) SetClock(c clock.Clock) {
+	r.clock = c
+}
+
+// Observe records a detection of an employee's device
+func (r *CheckOutReminder) Observe(employee *models.Employee, scannerMac string, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	day := at.Format("2006-01-02")
+	p, ok := r.presence[employee.ID]
+	if !ok || p.day != day {
+		p = &presence{day: day}
+		r.presence[employee.ID] = p
+	}
+	if at.After(p.lastSeen) {
+		p.lastSeen = at
+	}
+	if mac, err := macaddr.Normalize(scannerMac); err == nil && r.exitScanners[mac] {
+		p.exited = true
+	}
+}
+
+// Start runs Check every interval until ctx is cancelled
+func (r *CheckOutReminder) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.Check(ctx); err != nil {
+					log.Printf("❌ Check-out reminder failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Check sends the reminders that are due: today's check-ins without a check-out or
+// exit detection, Delay past the employee's scheduled end and not snoozed
+func (r *CheckOutReminder) Check(ctx context.Context) error {
+	now := r.clock.Now()
+	records, err := r.attendance.ListByDate(ctx, now)
+	if err != nil {
+		return fmt.Errorf("failed to list attendance: %w", err)
+	}
+	employees, err := r.employees.ListActive(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list employees: %w", err)
+	}
+	byID := make(map[string]models.Employee, len(employees))
+	for _, emp := range employees {
+		byID[emp.ID] = emp
+	}
+	r.forgetExcept(records)
+
+	for _, att := range records {
+		emp, ok := byID[att.EmployeeID]
+		if !ok || !att.CheckOutTime.IsZero() || emp.TelegramChatID == 0 ||
+			emp.NotificationMuted(models.NotificationCheckOutReminder) {
+			continue
+		}
+		end, ok := r.scheduledEnd(emp, att.CheckInTime)
+		if !ok || now.Before(end.Add(r.cfg.Delay)) {
+			continue
+		}
+		if !r.due(att, now) {
+			continue
+		}
+
+		left := r.leftAt(emp.ID, att.CheckInTime, end)
+		message := fmt.Sprintf("🏠 %s\n\n"+
+			"เวลาเลิกงาน: `%s`\nออกจากที่ทำงานแล้วหรือยัง?", markdown.Bold("คุณ"+emp.Name+" ยังไม่ได้บันทึกเวลาออกงาน"), end.Format("15:04"))
+		r.notifier.SendPersonalPrompt(emp.TelegramChatID, message, []models.PromptButton{
+			{Label: "ออกแล้วเมื่อ " + left.Format("15:04"), Data: fmt.Sprintf("%s:left:%s:%s", CheckOutCallbackPrefix, att.ID, left.Format("1504"))},
+			{Label: "ยังทำงานอยู่", Data: fmt.Sprintf("%s:snooze:%s", CheckOutCallbackPrefix, att.ID)},
+		})
+		log.Printf("🏠 Sent check-out reminder to %s", emp.Name)
+	}
+	return nil
+}
+
+// forgetExcept drops reminder state of records from earlier days
+func (r *CheckOutReminder) forgetExcept(records []models.Attendance) {
+	keep := make(map[string]bool, len(records))
+	for _, att := range records {
+		keep[att.ID] = true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id := range r.reminders {
+		if !keep[id] {
+			delete(r.reminders, id)
+		}
+	}
+}
+
+// due reports whether the record should be reminded now, marking it as sent if so
+func (r *CheckOutReminder) due(att models.Attendance, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if p, ok := r.presence[att.EmployeeID]; ok && p.exited && p.day == att.CheckInTime.Format("2006-01-02") {
+		return false
+	}
+	state, ok := r.reminders[att.ID]
+	if !ok {
+		state = &reminderState{}
+		r.reminders[att.ID] = state
+	}
+	if state.done || now.Before(state.snoozedUntil) || (state.sent && state.snoozedUntil.IsZero()) {
+		return false
+	}
+	state.sent = true
+	state.snoozedUntil = time.Time{}
+	return true
+}
+
+// scheduledEnd returns the employee's end of work on the check-in day
+func (r *CheckOutReminder) scheduledEnd(emp models.Employee, checkIn time.Time) (time.Time, bool) {
+	value := emp.WorkEndTime
+	if value == "" {
+		value = r.cfg.DefaultEndTime
+	}
+	t, err := time.Parse("15:04:05", value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Date(checkIn.Year(), checkIn.Month(), checkIn.Day(),
+		t.Hour(), t.Minute(), t.Second(), 0, checkIn.Location()), true
+}
+
+// leftAt guesses when the employee left: the last detection of the day, else the scheduled end
+func (r *CheckOutReminder) leftAt(employeeID string, checkIn, end time.Time) time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if p, ok := r.presence[employeeID]; ok && p.day == checkIn.Format("2006-01-02") && p.lastSeen.After(checkIn) {
+		return p.lastSeen
+	}
+	return end
+}
+
+// HandleCallback answers a reminder button press from chatID and returns the reply text
+func (r *CheckOutReminder) HandleCallback(ctx context.Context, chatID int64, data string) (string, error) {
+	parts := strings.Split(data, ":")
+	if len(parts) < 3 || parts[0] != CheckOutCallbackPrefix {
+		return "", fmt.Errorf("invalid check-out callback %q", data)
+	}
+	action, attendanceID := parts[1], parts[2]
+
+	att, err := r.attendance.Get(ctx, attendanceID)
+	if err != nil {
+		return "", err
+	}
+	if err := r.checkOwner(ctx, att, chatID); err != nil {
+		return "", err
+	}
+	if !att.CheckOutTime.IsZero() {
+		return fmt.Sprintf("✅ บันทึกเวลาออกงานไว้แล้ว `%s`", att.CheckOutTime.Format("15:04")), nil
+	}
+
+	switch action {
+	case "left":
+		if len(parts) != 4 {
+			return "", fmt.Errorf("invalid check-out callback %q", data)
+		}
+		t, err := time.Parse("1504", parts[3])
+		if err != nil {
+			return "", fmt.Errorf("invalid check-out time %q", parts[3])
+		}
+		in := att.CheckInTime
+		att.CheckOutTime = time.Date(in.Year(), in.Month(), in.Day(), t.Hour(), t.Minute(), 0, 0, in.Location())
+		att.CheckOutSource = models.CheckOutSourceSelfReported
+		att.NeedsReview = true
+		if err := r.attendance.UpdateCheckOut(ctx, att); err != nil {
+			return "", fmt.Errorf("failed to record check-out: %w", err)
+		}
+		r.setState(attendanceID, func(s *reminderState) { s.done = true })
+		log.Printf("🏠 Self-reported check-out at %s for attendance %s (needs review)", att.CheckOutTime.Format("15:04"), attendanceID)
+		return fmt.Sprintf("✅ บันทึกเวลาออกงาน `%s` แล้ว\nรอผู้ดูแลตรวจสอบ\n\n📊 วันนี้: เข้า `%s` ออก `%s`",
+			att.CheckOutTime.Format("15:04"), att.CheckInTime.Format("15:04"), att.CheckOutTime.Format("15:04")), nil
+
+	case "snooze":
+		until := r.clock.Now().Add(r.cfg.Snooze)
+		r.setState(attendanceID, func(s *reminderState) { s.snoozedUntil = until })
+		return fmt.Sprintf("⏰ จะเตือนอีกครั้งเวลา `%s`", until.Format("15:04")), nil
+	}
+	return "", fmt.Errorf("invalid check-out callback %q", data)
+}
+
+// checkOwner makes sure the button was pressed by the employee the record belongs to
+func (r *CheckOutReminder) checkOwner(ctx context.Context, att *models.Attendance, chatID int64) error {
+	employees, err := r.employees.ListActive(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list employees: %w", err)
+	}
+	for _, emp := range employees {
+		if emp.ID == att.EmployeeID && emp.TelegramChatID == chatID {
+			return nil
+		}
+	}
+	return errors.New("attendance record belongs to another employee")
+}
+
+// setState updates the reminder state of an attendance record
+func (r *CheckOutReminder) setState(attendanceID string, update func(*reminderState)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, ok := r.reminders[attendanceID]
+	if !ok {
+		state = &reminderState{sent: true}
+		r.reminders[attendanceID] = state
+	}
+	update(state)
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+type recordingPrompter struct {
+	*recordingNotifier
+	prompts map[int64][][]models.PromptButton
+}
+
+func newRecordingPrompter() *recordingPrompter {
+	return &recordingPrompter{recordingNotifier: newRecordingNotifier(), prompts: make(map[int64][][]models.PromptButton)}
+}
+
+func (n *recordingPrompter) SendPersonalPrompt(chatID int64, message string, buttons []models.PromptButton) {
+	n.prompts[chatID] = append(n.prompts[chatID], buttons)
+}
+
+// SendPrompt records admin prompts under chat 0
+func (n *recordingPrompter) SendPrompt(message string, buttons []models.PromptButton) {
+	n.admin = append(n.admin, message)
+	n.prompts[0] = append(n.prompts[0], buttons)
+}
+
+const exitScanner = "AA:BB:CC:00:00:09"
+
+func TestCheckOutReminderCheck(t *testing.T) {
+	day := func(h, m int) time.Time { return time.Date(2026, 2, 2, h, m, 0, 0, time.Local) }
+
+	tests := []struct {
+		name       string
+		employee   models.Employee
+		checkedOut bool
+		detections []string // scanners, seen at 17:10
+		now        time.Time
+		wantPrompt bool
+	}{
+		{
+			name:       "due after scheduled end plus delay",
+			employee:   models.Employee{WorkEndTime: "17:00:00"},
+			now:        day(17, 30),
+			wantPrompt: true,
+		},
+		{
+			name:     "not yet due",
+			employee: models.Employee{WorkEndTime: "17:00:00"},
+			now:      day(17, 29),
+		},
+		{
+			name:       "default end time for employees without a schedule",
+			employee:   models.Employee{},
+			now:        day(18, 30),
+			wantPrompt: true,
+		},
+		{
+			name:       "already checked out",
+			employee:   models.Employee{WorkEndTime: "17:00:00"},
+			checkedOut: true,
+			now:        day(17, 30),
+		},
+		{
+			name:       "seen at exit",
+			employee:   models.Employee{WorkEndTime: "17:00:00"},
+			detections: []string{"AA:BB:CC:00:00:01", "aa-bb-cc-00-00-09"},
+			now:        day(17, 30),
+		},
+		{
+			name:     "muted",
+			employee: models.Employee{WorkEndTime: "17:00:00", MutedNotifications: []string{"checkout_reminder"}},
+			now:      day(17, 30),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(day(8, 0))
+			store := memory.NewStore(clk)
+			tt.employee.Name, tt.employee.TelegramChatID, tt.employee.IsActive = "Somchai", 42, true
+			emp := store.AddEmployee(tt.employee)
+			att := &models.Attendance{EmployeeID: emp.ID, CheckInTime: day(8, 0), CreatedDate: day(8, 0), Status: "ontime"}
+			if tt.checkedOut {
+				att.CheckOutTime = day(17, 5)
+			}
+			store.AttendanceRecords().Create(context.Background(), att)
+
+			cfg := DefaultCheckOutReminderConfig()
+			cfg.DefaultEndTime = "18:00:00"
+			cfg.ExitScanners = []string{exitScanner}
+			notifier := newRecordingPrompter()
+			reminder, err := NewCheckOutReminder(cfg, store.Employees(), store.AttendanceRecords(), notifier)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reminder.SetClock(clk)
+			for _, scanner := range tt.detections {
+				reminder.Observe(&emp, scanner, day(17, 10))
+			}
+
+			clk.Set(tt.now)
+			if err := reminder.Check(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if got := len(notifier.prompts[42]) > 0; got != tt.wantPrompt {
+				t.Errorf("prompted = %v, want %v", got, tt.wantPrompt)
+			}
+		})
+	}
+}
+
+func TestCheckOutReminderCallbacks(t *testing.T) {
+	ctx := context.Background()
+	day := func(h, m int) time.Time { return time.Date(2026, 2, 2, h, m, 0, 0, time.Local) }
+
+	setup := func(t *testing.T) (*CheckOutReminder, *clock.Fake, *memory.Store, *recordingPrompter, string) {
+		clk := clock.NewFake(day(8, 0))
+		store := memory.NewStore(clk)
+		emp := store.AddEmployee(models.Employee{Name: "Somchai", TelegramChatID: 42, WorkEndTime: "17:00:00", IsActive: true})
+		att := &models.Attendance{EmployeeID: emp.ID, CheckInTime: day(8, 0), CreatedDate: day(8, 0), Status: "ontime"}
+		store.AttendanceRecords().Create(ctx, att)
+
+		notifier := newRecordingPrompter()
+		reminder, err := NewCheckOutReminder(DefaultCheckOutReminderConfig(), store.Employees(), store.AttendanceRecords(), notifier)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reminder.SetClock(clk)
+		reminder.Observe(&emp, "AA:BB:CC:00:00:01", day(16, 45))
+		clk.Set(day(17, 30))
+		if err := reminder.Check(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if len(notifier.prompts[42]) != 1 {
+			t.Fatalf("got %d prompts, want 1", len(notifier.prompts[42]))
+		}
+		return reminder, clk, store, notifier, att.ID
+	}
+
+	t.Run("left records a self-reported check-out at the last detection", func(t *testing.T) {
+		reminder, _, store, notifier, attID := setup(t)
+		left := notifier.prompts[42][0][0]
+		if left.Data != "co:left:"+attID+":1645" || !strings.Contains(left.Label, "16:45") {
+			t.Fatalf("left button = %+v", left)
+		}
+
+		if _, err := reminder.HandleCallback(ctx, 42, left.Data); err != nil {
+			t.Fatal(err)
+		}
+		att := store.Attendance()[0]
+		if !att.CheckOutTime.Equal(day(16, 45)) || att.CheckOutSource != models.CheckOutSourceSelfReported || !att.NeedsReview {
+			t.Errorf("attendance = %+v", att)
+		}
+
+		if _, err := reminder.HandleCallback(ctx, 42, left.Data); err != nil {
+			t.Errorf("second press: %v", err)
+		}
+	})
+
+	t.Run("other chats cannot answer", func(t *testing.T) {
+		reminder, _, store, notifier, _ := setup(t)
+		if _, err := reminder.HandleCallback(ctx, 7, notifier.prompts[42][0][0].Data); err == nil {
+			t.Error("expected an error for another employee's record")
+		}
+		if !store.Attendance()[0].CheckOutTime.IsZero() {
+			t.Error("check-out recorded from another chat")
+		}
+	})
+
+	t.Run("still working snoozes the reminder", func(t *testing.T) {
+		reminder, clk, _, notifier, _ := setup(t)
+		if _, err := reminder.HandleCallback(ctx, 42, notifier.prompts[42][0][1].Data); err != nil {
+			t.Fatal(err)
+		}
+
+		clk.Set(day(19, 29))
+		reminder.Check(ctx)
+		if len(notifier.prompts[42]) != 1 {
+			t.Fatalf("reminded again %d times during snooze", len(notifier.prompts[42])-1)
+		}
+		clk.Set(day(19, 30))
+		reminder.Check(ctx)
+		reminder.Check(ctx)
+		if len(notifier.prompts[42]) != 2 {
+			t.Errorf("got %d prompts after snooze, want 2", len(notifier.prompts[42]))
+		}
+	})
+}
@@ -0,0 +1,353 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/macaddr"
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// DepartmentCallbackPrefix routes the Accept buttons of the department digest
+const DepartmentCallbackPrefix = "dept"
+
+// maxDigestProposals is the most proposals offered in one digest; the rest wait a week
+const maxDigestProposals = 20
+
+// AdminPromptNotifier sends the admin chat messages with inline reply buttons
+type AdminPromptNotifier interface {
+	BotNotifier
+	SendPrompt(message string, buttons []models.PromptButton)
+}
+
+// DepartmentEmployees finds employees without a department and stores accepted ones
+type DepartmentEmployees interface {
+	repository.EmployeeRepository
+	repository.EmployeeDirectory
+}
+
+// DepartmentInferenceConfig configures department proposals for employees without one
+type DepartmentInferenceConfig struct {
+	Zones         map[string]string // scanner zone → department
+	Window        time.Duration     // trailing window of detections looked at
+	MinDetections int               // fewest detections in mapped zones before proposing
+	MinShare      float64           // share of those detections the proposed department needs
+	DigestDay     time.Weekday      // the end-of-day run on this weekday sends the digest
+}
+
+// DefaultDepartmentInferenceConfig looks at two weeks of detections and sends the digest on Mondays
+func DefaultDepartmentInferenceConfig() DepartmentInferenceConfig {
+	return DepartmentInferenceConfig{
+		Window:        14 * 24 * time.Hour,
+		MinDetections: 20,
+		MinShare:      0.6,
+		DigestDay:     time.Monday,
+	}
+}
+
+// ParseDepartmentZones parses a zone to department table: "er:Emergency,opd:Outpatient".
+// Zones are matched case-insensitively.
+func ParseDepartmentZones(spec string) (map[string]string, error) {
+	zones := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		zone, dept, ok := strings.Cut(pair, ":")
+		zone, dept = strings.ToLower(strings.TrimSpace(zone)), strings.TrimSpace(dept)
+		if !ok || zone == "" || dept == "" {
+			return nil, fmt.Errorf("invalid zone mapping %q, want zone:department", pair)
+		}
+		zones[zone] = dept
+	}
+	return zones, nil
+}
+
+// DepartmentProposal is a department inferred for an employee without one
+type DepartmentProposal struct {
+	Employee   models.Employee
+	Department string
+	Detections int // detections in the department's zones
+	Total      int // detections in any mapped zone
+}
+
+// DepartmentInference proposes departments for employees who have none, from the zones of
+// the scanners that detect them most. Proposals are only ever applied by an admin
+// pressing Accept, and each acceptance is written to the audit log.
+type DepartmentInference struct {
+	cfg         DepartmentInferenceConfig
+	departments []string // distinct departments, sorted; buttons refer to them by index
+	employees   DepartmentEmployees
+	detections  repository.DetectionLog
+	scanners    repository.ScannerRegistry
+	audit       repository.AuditLog
+	notifier    AdminPromptNotifier
+	configs     *ScannerConfigs // nil uses the zones chosen when pairing
+	clock       clock.Clock
+}
+
+// NewDepartmentInference creates the inference job
+func NewDepartmentInference(cfg DepartmentInferenceConfig, employees DepartmentEmployees, detections repository.DetectionLog,
+	scanners repository.ScannerRegistry, audit repository.AuditLog, notifier AdminPromptNotifier) (*DepartmentInference, error) {
+	if len(cfg.Zones) == 0 {
+		return nil, errors.New("department inference needs at least one zone mapping")
+	}
+	if cfg.Window <= 0 || cfg.MinDetections < 1 || cfg.MinShare <= 0 || cfg.MinShare > 1 {
+		return nil, fmt.Errorf("invalid department inference config %+v", cfg)
+	}
+
+	seen := make(map[string]bool)
+	var departments []string
+	for _, dept := range cfg.Zones {
+		if !seen[dept] {
+			seen[dept] = true
+			departments = append(departments, dept)
+		}
+	}
+	sort.Strings(departments)
+
+	return &DepartmentInference{
+		cfg:         cfg,
+		departments: departments,
+		employees:   employees,
+		detections:  detections,
+		scanners:    scanners,
+		audit:       audit,
+		notifier:    notifier,
+		clock:       clock.Real{},
+	}, nil
+}
+
+// SetClock replaces the time source, used by tests
+func (d *DepartmentInference) SetClock(c clock.Clock) {
+	d.clock = c
+}
+
+// SetScannerConfigs takes scanner zones from their effective configuration, so zones set
+// by a profile or an override count too
+func (d *DepartmentInference) SetScannerConfigs(c *ScannerConfigs) {
+	d.configs = c
+}
+
+// Propose computes the current proposals for every active employee without a department,
+// ordered by name
+func (d *DepartmentInference) Propose(ctx context.Context) ([]DepartmentProposal, error) {
+	employees, err := d.employees.ListActive(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list employees: %w", err)
+	}
+	zones, err := d.scannerZones(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var proposals []DepartmentProposal
+	for _, emp := range employees {
+		if emp.Department != "" || emp.IsGuest {
+			continue
+		}
+		p, err := d.propose(ctx, emp, zones)
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			proposals = append(proposals, *p)
+		}
+	}
+	sort.Slice(proposals, func(i, j int) bool { return proposals[i].Employee.Name < proposals[j].Employee.Name })
+	return proposals, nil
+}
+
+// SendDigest sends the admin the week's proposals with an Accept button each. It runs
+// as an end-of-day task and only sends on the configured weekday.
+func (d *DepartmentInference) SendDigest(ctx context.Context, day time.Time) error {
+	if day.Weekday() != d.cfg.DigestDay {
+		return nil
+	}
+	proposals, err := d.Propose(ctx)
+	if err != nil {
+		return err
+	}
+	if len(proposals) == 0 {
+		log.Printf("🏢 No department proposals this week")
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteString("🏢 *เสนอแผนกสำหรับพนักงานที่ยังไม่มีแผนก*\n")
+	fmt.Fprintf(&b, "จากการตรวจพบในช่วง %d วันที่ผ่านมา\n\n", int(d.cfg.Window.Hours()/24))
+	var buttons []models.PromptButton
+	for i, p := range proposals {
+		if i == maxDigestProposals {
+			fmt.Fprintf(&b, "\n…และอีก %d คน (เสนอในสัปดาห์ถัดไป)\n", len(proposals)-maxDigestProposals)
+			break
+		}
+		fmt.Fprintf(&b, "%d. %s → %s (%d/%d ครั้ง)\n", i+1, markdown.Escape(p.Employee.Name), markdown.Bold(p.Department), p.Detections, p.Total)
+		buttons = append(buttons, models.PromptButton{
+			Label: fmt.Sprintf("✅ %s → %s", p.Employee.Name, p.Department),
+			Data:  fmt.Sprintf("%s:%s:%d", DepartmentCallbackPrefix, p.Employee.ID, d.departmentIndex(p.Department)),
+		})
+	}
+	b.WriteString("\nกด ✅ เพื่อยอมรับ ระบบจะไม่บันทึกแผนกเองโดยอัตโนมัติ")
+
+	d.notifier.SendPrompt(b.String(), buttons)
+	log.Printf("🏢 Sent %d department proposals", len(buttons))
+	return nil
+}
+
+// Accept applies the proposal of an Accept button for the admin, after checking it
+// against fresh data: a proposal that changed since the digest is not applied.
+func (d *DepartmentInference) Accept(ctx context.Context, data string, actorID int64, actorName string) (string, error) {
+	parts := strings.Split(data, ":")
+	if len(parts) != 3 || parts[0] != DepartmentCallbackPrefix {
+		return "", fmt.Errorf("invalid department callback %q", data)
+	}
+	idx, err := strconv.Atoi(parts[2])
+	if err != nil || idx < 0 || idx >= len(d.departments) {
+		return "", fmt.Errorf("invalid department in callback %q", data)
+	}
+	offered := d.departments[idx]
+
+	emp, err := d.activeEmployee(ctx, parts[1])
+	if err != nil {
+		return "", err
+	}
+	if emp.Department != "" {
+		return fmt.Sprintf("ℹ️ %s อยู่แผนก %s แล้ว", markdown.Escape(emp.Name), markdown.Bold(emp.Department)), nil
+	}
+
+	zones, err := d.scannerZones(ctx)
+	if err != nil {
+		return "", err
+	}
+	p, err := d.propose(ctx, *emp, zones)
+	if err != nil {
+		return "", err
+	}
+	if p == nil {
+		return fmt.Sprintf("⚠️ ข้อมูลล่าสุดไม่พอจะเสนอแผนกให้ %s แล้ว ไม่ได้บันทึก", markdown.Escape(emp.Name)), nil
+	}
+	if p.Department != offered {
+		return fmt.Sprintf("⚠️ ข้อเสนอของ %s เปลี่ยนเป็น %s (%d/%d ครั้ง) ไม่ได้บันทึก\nรอสรุปสัปดาห์ถัดไป",
+			markdown.Escape(emp.Name), markdown.Bold(p.Department), p.Detections, p.Total), nil
+	}
+
+	// Recorded first so no accepted mapping goes unaudited
+	entry := &models.AuditEntry{
+		Action:    models.AuditDepartmentAccepted,
+		TargetID:  emp.ID,
+		ActorID:   actorID,
+		ActorName: actorName,
+		Details:   fmt.Sprintf("department=%s detections=%d/%d", p.Department, p.Detections, p.Total),
+		At:        d.clock.Now(),
+	}
+	if err := d.audit.Record(ctx, entry); err != nil {
+		return "", fmt.Errorf("failed to audit department change: %w", err)
+	}
+	emp.Department = p.Department
+	if err := d.employees.Update(ctx, emp); err != nil {
+		return "", err
+	}
+
+	log.Printf("🏢 %s assigned to department %s (accepted by %d)", emp.Name, p.Department, actorID)
+	return fmt.Sprintf("✅ บันทึก %s → %s แล้ว\nยอมรับโดย: %s", markdown.Escape(emp.Name), markdown.Bold(p.Department), markdown.Escape(actorName)), nil
+}
+
+// propose returns the employee's proposal, or nil without enough detections in mapped
+// zones or without a clear majority
+func (d *DepartmentInference) propose(ctx context.Context, emp models.Employee, zones map[string]string) (*DepartmentProposal, error) {
+	detections, err := d.detections.ListByEmployeeSince(ctx, emp.ID, d.clock.Now().Add(-d.cfg.Window))
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	total := 0
+	for _, det := range detections {
+		dept, ok := d.cfg.Zones[zones[scannerKey(det.ScannerMac)]]
+		if !ok {
+			continue
+		}
+		counts[dept]++
+		total++
+	}
+	if total < d.cfg.MinDetections {
+		return nil, nil
+	}
+
+	best, bestCount := "", 0
+	for _, dept := range d.departments {
+		if counts[dept] > bestCount {
+			best, bestCount = dept, counts[dept]
+		}
+	}
+	if float64(bestCount) < d.cfg.MinShare*float64(total) {
+		return nil, nil
+	}
+	return &DepartmentProposal{Employee: emp, Department: best, Detections: bestCount, Total: total}, nil
+}
+
+// scannerZones maps every scanner to its lower-cased zone
+func (d *DepartmentInference) scannerZones(ctx context.Context) (map[string]string, error) {
+	if d.configs != nil {
+		configs, err := d.configs.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		zones := make(map[string]string, len(configs))
+		for _, cfg := range configs {
+			if cfg.Zone != "" {
+				zones[scannerKey(cfg.ScannerMac)] = strings.ToLower(strings.TrimSpace(cfg.Zone))
+			}
+		}
+		return zones, nil
+	}
+	scanners, err := d.scanners.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scanners: %w", err)
+	}
+	zones := make(map[string]string, len(scanners))
+	for _, sc := range scanners {
+		if sc.Zone != "" {
+			zones[scannerKey(sc.ScannerMac)] = strings.ToLower(strings.TrimSpace(sc.Zone))
+		}
+	}
+	return zones, nil
+}
+
+// activeEmployee finds an active employee by ID
+func (d *DepartmentInference) activeEmployee(ctx context.Context, id string) (*models.Employee, error) {
+	employees, err := d.employees.ListActive(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list employees: %w", err)
+	}
+	for _, emp := range employees {
+		if emp.ID == id {
+			return &emp, nil
+		}
+	}
+	return nil, fmt.Errorf("employee %s is not active", id)
+}
+
+// departmentIndex is the button index of a department
+func (d *DepartmentInference) departmentIndex(dept string) int {
+	return sort.SearchStrings(d.departments, dept)
+}
+
+// scannerKey is the canonical form of a scanner ID, so detections and scanner records
+// written in different spellings match
+func scannerKey(id string) string {
+	if mac, err := macaddr.NormalizeScannerID(id, true); err == nil {
+		return mac
+	}
+	return strings.ToLower(strings.TrimSpace(id))
+}
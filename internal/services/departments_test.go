@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+func TestParseDepartmentZones(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"pairs", "ER:Emergency, opd : Outpatient", map[string]string{"er": "Emergency", "opd": "Outpatient"}, false},
+		{"thai department", "ward3:อายุรกรรม", map[string]string{"ward3": "อายุรกรรม"}, false},
+		{"trailing comma", "er:Emergency,", map[string]string{"er": "Emergency"}, false},
+		{"missing department", "er:", nil, true},
+		{"no separator", "er", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDepartmentZones(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("zones = %v, want %v", got, tt.want)
+			}
+			for zone, dept := range tt.want {
+				if got[zone] != dept {
+					t.Errorf("zones[%s] = %q, want %q", zone, got[zone], dept)
+				}
+			}
+		})
+	}
+}
+
+func TestDepartmentInference(t *testing.T) {
+	ctx := context.Background()
+	monday := time.Date(2026, 2, 2, 18, 0, 0, 0, time.Local)
+	clk := clock.NewFake(monday)
+	store := memory.NewStore(clk)
+	store.AddScanner(models.Scanner{ScannerMac: "AA:BB:CC:00:00:01", Zone: "ER"})
+	store.AddScanner(models.Scanner{ScannerMac: "AA:BB:CC:00:00:02", Zone: "OPD"})
+	store.AddScanner(models.Scanner{ScannerMac: "AA:BB:CC:00:00:03", Zone: "Lobby"})
+
+	detect := func(emp models.Employee, scanner string, n int, at time.Time) {
+		for i := 0; i < n; i++ {
+			store.DetectionRecords().Create(ctx, &models.EmployeeDetection{
+				EmployeeID: emp.ID, ScannerMac: scanner, DetectedAt: at.Add(time.Duration(i) * time.Minute),
+			})
+		}
+	}
+	lastWeek := monday.AddDate(0, 0, -5)
+
+	somchai := store.AddEmployee(models.Employee{Name: "Somchai", IsActive: true})
+	detect(somchai, "aa:bb:cc:00:00:01", 25, lastWeek) // spelled unlike the scanner record
+	detect(somchai, "AA:BB:CC:00:00:02", 5, lastWeek)
+	detect(somchai, "AA:BB:CC:00:00:03", 30, lastWeek) // unmapped zone, not counted
+
+	malee := store.AddEmployee(models.Employee{Name: "Malee", IsActive: true})
+	detect(malee, "AA:BB:CC:00:00:01", 12, lastWeek)
+	detect(malee, "AA:BB:CC:00:00:02", 12, lastWeek) // no clear majority
+
+	anan := store.AddEmployee(models.Employee{Name: "Anan", Department: "Pharmacy", IsActive: true})
+	detect(anan, "AA:BB:CC:00:00:01", 30, lastWeek)
+
+	niran := store.AddEmployee(models.Employee{Name: "Niran", IsActive: true})
+	detect(niran, "AA:BB:CC:00:00:01", 10, lastWeek)                  // too few
+	detect(niran, "AA:BB:CC:00:00:01", 30, monday.AddDate(0, 0, -30)) // outside the window
+
+	cfg := DefaultDepartmentInferenceConfig()
+	cfg.Zones, _ = ParseDepartmentZones("er:Emergency,opd:Outpatient")
+	notifier := newRecordingPrompter()
+	inference, err := NewDepartmentInference(cfg, store.Employees(), store.DetectionRecords(), store.ScannerRecords(), store.AuditLog(), notifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inference.SetClock(clk)
+
+	department := func(id string) string {
+		employees, _ := store.Employees().ListActive(ctx)
+		for _, emp := range employees {
+			if emp.ID == id {
+				return emp.Department
+			}
+		}
+		return "?"
+	}
+
+	t.Run("digest only on its weekday", func(t *testing.T) {
+		if err := inference.SendDigest(ctx, monday.AddDate(0, 0, -1)); err != nil {
+			t.Fatal(err)
+		}
+		if len(notifier.prompts[0]) != 0 {
+			t.Fatalf("sent a digest on Sunday")
+		}
+	})
+
+	var accept string
+	t.Run("digest proposes without applying", func(t *testing.T) {
+		if err := inference.SendDigest(ctx, monday); err != nil {
+			t.Fatal(err)
+		}
+		if len(notifier.prompts[0]) != 1 {
+			t.Fatalf("digests = %d, want 1", len(notifier.prompts[0]))
+		}
+		buttons := notifier.prompts[0][0]
+		if len(buttons) != 1 || buttons[0].Data != "dept:"+somchai.ID+":0" {
+			t.Fatalf("buttons = %+v, want Somchai → Emergency only", buttons)
+		}
+		if !strings.Contains(notifier.admin[0], "Somchai → *Emergency* (25/30") {
+			t.Errorf("digest = %q", notifier.admin[0])
+		}
+		if department(somchai.ID) != "" || len(store.AuditEntries()) != 0 {
+			t.Error("proposal applied without acceptance")
+		}
+		accept = buttons[0].Data
+	})
+
+	t.Run("stale proposal is refused", func(t *testing.T) {
+		detect(malee, "AA:BB:CC:00:00:01", 30, monday.Add(-time.Hour)) // Malee is now mostly in the ER
+		reply, err := inference.Accept(ctx, "dept:"+malee.ID+":1", 7, "Admin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(reply, "Emergency") || department(malee.ID) != "" {
+			t.Errorf("reply = %q, department = %q; want the fresh proposal shown and nothing applied", reply, department(malee.ID))
+		}
+	})
+
+	t.Run("accept applies and audits", func(t *testing.T) {
+		if _, err := inference.Accept(ctx, accept, 7, "Admin"); err != nil {
+			t.Fatal(err)
+		}
+		if got := department(somchai.ID); got != "Emergency" {
+			t.Errorf("department = %q, want Emergency", got)
+		}
+		audit := store.AuditEntries()
+		if len(audit) != 1 || audit[0].Action != models.AuditDepartmentAccepted || audit[0].TargetID != somchai.ID || audit[0].ActorID != 7 {
+			t.Errorf("audit = %+v", audit)
+		}
+	})
+
+	t.Run("second press changes nothing", func(t *testing.T) {
+		reply, err := inference.Accept(ctx, accept, 8, "Other admin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(reply, "แล้ว") || len(store.AuditEntries()) != 1 {
+			t.Errorf("reply = %q, audit entries = %d", reply, len(store.AuditEntries()))
+		}
+	})
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/logging"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// DevicePairingCallbackPrefix routes the Confirm/Retry buttons of a /pair proposal; the
+// data is "dp:ok:<MAC>" or "dp:retry"
+const DevicePairingCallbackPrefix = "dp"
+
+// DefaultDevicePairingWindow is how long /pair listens for the employee's device
+const DefaultDevicePairingWindow = 60 * time.Second
+
+// devicePairingProposalTTL is how long a proposed MAC stays reserved for its chat
+const devicePairingProposalTTL = 5 * time.Minute
+
+// ErrNoPairingProposal means the chat has no proposal for the MAC, or it expired
+var ErrNoPairingProposal = errors.New("no pairing proposal for this device")
+
+// pairingCandidate is the strongest detection of one device during a window
+type pairingCandidate struct {
+	mac        string
+	rssi       int
+	scannerMac string
+}
+
+// pairingSession is one chat's /pair: an open window collecting candidates, then a
+// proposal waiting for Confirm or Retry
+type pairingSession struct {
+	expires    time.Time
+	candidates map[string]pairingCandidate // MAC → strongest detection
+	timer      *time.Timer
+
+	proposed      string // MAC proposed to the chat, reserved from other sessions
+	proposedUntil time.Time
+}
+
+// DevicePairing finds the MAC of an employee's new tag: while a chat's window is open it
+// keeps the strongest detection of every device, and when the window closes it proposes
+// the strongest one no employee owns. A proposed MAC is reserved for its chat, so two
+// people pairing near the same scanner are never offered the same device.
+type DevicePairing struct {
+	employees repository.EmployeeRepository
+	devices   repository.DeviceRepository // optional
+	notifier  PromptNotifier
+	window    time.Duration
+	clock     clock.Clock
+
+	mu       sync.Mutex
+	sessions map[int64]*pairingSession // chat → pairing
+}
+
+// NewDevicePairing creates the /pair service listening for window after each request
+func NewDevicePairing(employees repository.EmployeeRepository, notifier PromptNotifier, window time.Duration) (*DevicePairing, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("device pairing window must be positive, got %s", window)
+	}
+	return &DevicePairing{
+		employees: employees,
+		notifier:  notifier,
+		window:    window,
+		clock:     clock.Real{},
+		sessions:  make(map[int64]*pairingSession),
+	}, nil
+}
+
+// SetClock replaces the time source, used by tests
+func (p *DevicePairing) SetClock(c clock.Clock) {
+	p.clock = c
+}
+
+// SetDevices skips devices registered to an employee besides their mac_address
+func (p *DevicePairing) SetDevices(devices repository.DeviceRepository) {
+	p.devices = devices
+}
+
+// Start opens a pairing window for the chat, replacing any pairing it had, and returns
+// when the window closes
+func (p *DevicePairing) Start(chatID int64) time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if old, ok := p.sessions[chatID]; ok && old.timer != nil {
+		old.timer.Stop()
+	}
+	now := p.clock.Now()
+	// Forget proposals nobody answered
+	for id, s := range p.sessions {
+		if s.candidates == nil && !now.Before(s.proposedUntil) {
+			delete(p.sessions, id)
+		}
+	}
+	expires := now.Add(p.window)
+	p.sessions[chatID] = &pairingSession{
+		expires:    expires,
+		candidates: make(map[string]pairingCandidate),
+		timer:      time.AfterFunc(p.window, func() { p.close(context.Background(), chatID) }),
+	}
+	log.Printf("📲 Device pairing window opened")
+	return expires
+}
+
+// Observe offers a detection to every open window
+func (p *DevicePairing) Observe(mac string, rssi int, scannerMac string, at time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, s := range p.sessions {
+		if s.candidates == nil || at.After(s.expires) {
+			continue
+		}
+		if c, ok := s.candidates[mac]; !ok || rssi > c.rssi {
+			s.candidates[mac] = pairingCandidate{mac: mac, rssi: rssi, scannerMac: scannerMac}
+		}
+	}
+}
+
+// close ends the chat's window and sends it the proposal, or why there is none
+func (p *DevicePairing) close(ctx context.Context, chatID int64) {
+	p.mu.Lock()
+	s, ok := p.sessions[chatID]
+	if !ok || s.candidates == nil {
+		p.mu.Unlock()
+		return
+	}
+	candidates := make([]pairingCandidate, 0, len(s.candidates))
+	for _, c := range s.candidates {
+		candidates = append(candidates, c)
+	}
+	s.candidates, s.timer = nil, nil
+	p.mu.Unlock()
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].rssi != candidates[j].rssi {
+			return candidates[i].rssi > candidates[j].rssi
+		}
+		return candidates[i].mac < candidates[j].mac
+	})
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	conflict := false
+	for _, c := range candidates {
+		if p.registered(ctx, c.mac) {
+			continue
+		}
+		proposed, reserved := p.reserve(chatID, c.mac)
+		if !proposed {
+			// The chat started over or another pairing took its place
+			return
+		}
+		if reserved {
+			conflict = true
+			continue
+		}
+		log.Printf("📲 Proposed device %s", logging.MaskMAC(c.mac))
+		p.notifier.SendPersonalPrompt(chatID, fmt.Sprintf(
+			"📲 *พบอุปกรณ์ใหม่*\n\nMAC: `%s`\nสัญญาณ: %d dBm ที่ Scanner `%s`\n\nใช่อุปกรณ์ของคุณหรือไม่?", c.mac, c.rssi, c.scannerMac),
+			[]models.PromptButton{
+				{Label: "✅ ยืนยัน", Data: DevicePairingCallbackPrefix + ":ok:" + c.mac},
+				{Label: "🔄 ลองใหม่", Data: DevicePairingCallbackPrefix + ":retry"},
+			})
+		return
+	}
+
+	p.mu.Lock()
+	if p.sessions[chatID] == s {
+		delete(p.sessions, chatID)
+	}
+	p.mu.Unlock()
+	if conflict {
+		p.notifier.SendPersonalNotification(chatID, "⚠️ อุปกรณ์ที่พบกำลังถูกจับคู่โดยผู้อื่นใกล้ Scanner เดียวกัน\nรอให้อีกคนจับคู่เสร็จแล้วลองใหม่ด้วย /pair")
+		return
+	}
+	p.notifier.SendPersonalNotification(chatID, "❌ ไม่พบอุปกรณ์ใหม่\nถือแท็กไว้ใกล้ Scanner แล้วลองใหม่ด้วย /pair")
+}
+
+// reserve proposes the MAC to the chat unless another chat holds it. proposed is false
+// when the chat's window was replaced meanwhile.
+func (p *DevicePairing) reserve(chatID int64, mac string) (proposed, reserved bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.clock.Now()
+	s, ok := p.sessions[chatID]
+	if !ok || s.candidates != nil {
+		return false, false
+	}
+	for other, o := range p.sessions {
+		if other != chatID && o.proposed == mac && now.Before(o.proposedUntil) {
+			return true, true
+		}
+	}
+	s.proposed, s.proposedUntil = mac, now.Add(devicePairingProposalTTL)
+	return true, false
+}
+
+// registered reports whether an employee already owns the MAC; failed lookups count as
+// owned so the proposal is never someone else's device
+func (p *DevicePairing) registered(ctx context.Context, mac string) bool {
+	if p.devices != nil {
+		_, err := p.devices.GetEmployeeByDeviceMac(ctx, mac)
+		if !errors.Is(err, repository.ErrEmployeeNotFound) {
+			return true
+		}
+	}
+	_, err := p.employees.GetByMacAddress(ctx, mac)
+	return !errors.Is(err, repository.ErrEmployeeNotFound)
+}
+
+// Confirm takes the MAC proposed to the chat, ending its pairing
+func (p *DevicePairing) Confirm(chatID int64, mac string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s, ok := p.sessions[chatID]
+	if !ok || s.proposed != mac || !p.clock.Now().Before(s.proposedUntil) {
+		return ErrNoPairingProposal
+	}
+	delete(p.sessions, chatID)
+	log.Printf("📲 Device %s confirmed", logging.MaskMAC(mac))
+	return nil
+}
+
+// Cancel drops the chat's pairing, releasing its proposal
+func (p *DevicePairing) Cancel(chatID int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if s, ok := p.sessions[chatID]; ok {
+		if s.timer != nil {
+			s.timer.Stop()
+		}
+		delete(p.sessions, chatID)
+	}
+}
+
+// DevicePairingStage offers every detection to the open /pair windows. It runs before
+// repeats are dropped, so a tag that has been advertising all along is still heard.
+type DevicePairingStage struct {
+	Pairing *DevicePairing
+}
+
+func (s DevicePairingStage) Process(ctx context.Context, dc *DetectionContext) (bool, error) {
+	s.Pairing.Observe(dc.Request.MacAddress, dc.Request.RSSI, dc.Request.ScannerMac, dc.Now)
+	return true, nil
+}
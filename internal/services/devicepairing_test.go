@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+)
+
+func TestDevicePairing(t *testing.T) {
+	const (
+		registered = "aa:bb:cc:dd:ee:01" // Somchai's tag in newPipelineStore
+		tagA       = "aa:bb:cc:dd:ee:0a"
+		tagB       = "aa:bb:cc:dd:ee:0b"
+	)
+	type detection struct {
+		mac  string
+		rssi int
+	}
+	tests := []struct {
+		name       string
+		detections []detection
+		pairers    []int64 // chats whose windows close in this order
+		want       []string
+	}{
+		{"strongest new device", []detection{{registered, -30}, {tagA, -60}, {tagB, -45}, {tagA, -40}}, []int64{1},
+			[]string{"dp:ok:" + tagA}},
+		{"nothing heard", []detection{{registered, -30}}, []int64{1},
+			[]string{"ไม่พบอุปกรณ์ใหม่"}},
+		{"two people pairing get distinct devices", []detection{{tagA, -40}, {tagB, -50}}, []int64{1, 2},
+			[]string{"dp:ok:" + tagA, "dp:ok:" + tagB}},
+		{"a third person gets a conflict", []detection{{tagA, -40}, {tagB, -50}}, []int64{1, 2, 3},
+			[]string{"dp:ok:" + tagA, "dp:ok:" + tagB, "กำลังถูกจับคู่โดยผู้อื่น"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newPipelineStore()
+			notifier := newRecordingPrompter()
+			// A window that never closes on its own; the test closes it
+			p, err := NewDevicePairing(store.Employees(), notifier, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.SetClock(clock.NewFake(pipelineNow))
+			for _, chatID := range tt.pairers {
+				p.Start(chatID)
+				t.Cleanup(func() { p.Cancel(chatID) })
+			}
+			for _, d := range tt.detections {
+				p.Observe(d.mac, d.rssi, "scanner-1", pipelineNow.Add(time.Second))
+			}
+
+			for i, chatID := range tt.pairers {
+				p.close(context.Background(), chatID)
+				got := strings.Join(notifier.personal[chatID], "\n")
+				for _, buttons := range notifier.prompts[chatID] {
+					for _, b := range buttons {
+						got += "\n" + b.Data
+					}
+				}
+				if !strings.Contains(got, tt.want[i]) {
+					t.Errorf("chat %d got %q, want it to contain %q", chatID, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDevicePairingConfirm(t *testing.T) {
+	store := newPipelineStore()
+	clk := clock.NewFake(pipelineNow)
+	p, err := NewDevicePairing(store.Employees(), newRecordingPrompter(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetClock(clk)
+	p.Start(1)
+	t.Cleanup(func() { p.Cancel(1) })
+
+	// Detections reach the window through the pipeline, repeats included
+	service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), newRecordingNotifier())
+	service.SetClock(clk)
+	service.SetRecentDetections(NewRecentDetections(time.Minute))
+	service.SetDevicePairing(p)
+	for i := 0; i < 2; i++ {
+		if err := service.ProcessDetection(context.Background(), &models.DetectionRequest{ScannerMac: "scanner-1", MacAddress: "AA:BB:CC:DD:EE:0A", RSSI: -50 + i*10}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p.close(context.Background(), 1)
+
+	if err := p.Confirm(1, "aa:bb:cc:dd:ee:0b"); !errors.Is(err, ErrNoPairingProposal) {
+		t.Errorf("Confirm(other MAC) = %v, want ErrNoPairingProposal", err)
+	}
+	if err := p.Confirm(2, "aa:bb:cc:dd:ee:0a"); !errors.Is(err, ErrNoPairingProposal) {
+		t.Errorf("Confirm(other chat) = %v, want ErrNoPairingProposal", err)
+	}
+	clk.Advance(devicePairingProposalTTL)
+	if err := p.Confirm(1, "aa:bb:cc:dd:ee:0a"); !errors.Is(err, ErrNoPairingProposal) {
+		t.Errorf("Confirm(expired) = %v, want ErrNoPairingProposal", err)
+	}
+	clk.Set(pipelineNow)
+	if err := p.Confirm(1, "aa:bb:cc:dd:ee:0a"); err != nil {
+		t.Errorf("Confirm() = %v, want the proposal taken", err)
+	}
+	if err := p.Confirm(1, "aa:bb:cc:dd:ee:0a"); !errors.Is(err, ErrNoPairingProposal) {
+		t.Errorf("second Confirm() = %v, want ErrNoPairingProposal", err)
+	}
+}
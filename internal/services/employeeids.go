@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"med-pulse-bot/internal/macaddr"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// RelinkOutcome is what happened to one record whose employee_id was lost
+type RelinkOutcome string
+
+// Relink outcomes
+const (
+	Relinked      RelinkOutcome = "relinked"
+	WouldRelink   RelinkOutcome = "would_relink" // dry run
+	RelinkUnknown RelinkOutcome = "unresolved"   // no employee could be found for it
+	RelinkFailed  RelinkOutcome = "failed"
+)
+
+// RelinkResult is the outcome of one attendance or detection record
+type RelinkResult struct {
+	Collection string // attendance or employee_detections
+	ID         string
+	EmployeeID string
+	Outcome    RelinkOutcome
+	Detail     string // how the employee was found, or why not
+}
+
+// RelinkReport lists what happened to every record without an employee_id
+type RelinkReport struct {
+	DryRun  bool
+	Results []RelinkResult
+}
+
+// Count returns the number of records with the given outcome
+func (r *RelinkReport) Count(outcome RelinkOutcome) int {
+	n := 0
+	for _, res := range r.Results {
+		if res.Outcome == outcome {
+			n++
+		}
+	}
+	return n
+}
+
+// Write prints one line per record followed by totals
+func (r *RelinkReport) Write(w io.Writer) {
+	for _, res := range r.Results {
+		fmt.Fprintf(w, "%-20s %-15s → %-15s %-12s %s\n", res.Collection, res.ID, res.EmployeeID, res.Outcome, res.Detail)
+	}
+
+	relinked := Relinked
+	if r.DryRun {
+		relinked = WouldRelink
+	}
+	fmt.Fprintf(w, "\n%d records: %d %s, %d unresolved, %d failed\n",
+		len(r.Results), r.Count(relinked), relinked, r.Count(RelinkUnknown), r.Count(RelinkFailed))
+}
+
+// RelinkEmployeeStore finds employees by their device MAC and by employee code
+type RelinkEmployeeStore interface {
+	repository.EmployeeRepository
+	repository.EmployeeActivation
+}
+
+// EmployeeIDRepair links attendance and detection records back to their employee after
+// employee_id was stored in a number field, which turned every record ID into 0
+type EmployeeIDRepair struct {
+	attendance repository.UnlinkedAttendance
+	detections repository.UnlinkedDetections
+	employees  RelinkEmployeeStore
+	devices    repository.DeviceRepository // optional
+}
+
+// NewEmployeeIDRepair creates a repair of the records of attendance and detections
+func NewEmployeeIDRepair(attendance repository.UnlinkedAttendance, detections repository.UnlinkedDetections, employees RelinkEmployeeStore) *EmployeeIDRepair {
+	return &EmployeeIDRepair{attendance: attendance, detections: detections, employees: employees}
+}
+
+// SetDevices also finds employees through their registered devices besides mac_address
+func (r *EmployeeIDRepair) SetDevices(devices repository.DeviceRepository) {
+	r.devices = devices
+}
+
+// Run relinks every record without an employee_id. A detection belongs to the employee
+// owning its MAC. A check-in belongs to the employee whose code it recorded at check-in or
+// else to the one employee owning the detections saved with it, at the same scanner and
+// second. Only active employees are found by MAC. With dryRun nothing is written.
+func (r *EmployeeIDRepair) Run(ctx context.Context, dryRun bool) (*RelinkReport, error) {
+	detections, err := r.detections.ListUnlinked(ctx)
+	if err != nil {
+		return nil, err
+	}
+	attendance, err := r.attendance.ListUnlinked(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RelinkReport{DryRun: dryRun}
+	owners := make(map[string]string)                           // MAC → employee ID, "" when nobody owns it
+	byScannerTime := make(map[string][]string, len(detections)) // MACs detected at a scanner and second
+	for _, det := range detections {
+		mac, _ := macaddr.Normalize(det.MacAddress)
+		key := scannerTimeKey(det.ScannerMac, det.DetectedAt)
+		byScannerTime[key] = append(byScannerTime[key], mac)
+
+		employeeID, err := r.owner(ctx, owners, mac)
+		res := RelinkResult{Collection: "employee_detections", ID: det.ID, EmployeeID: employeeID, Detail: "mac " + det.MacAddress}
+		report.Results = append(report.Results, r.relink(ctx, r.detections, res, err, dryRun))
+	}
+
+	for _, a := range attendance {
+		res := RelinkResult{Collection: "attendance", ID: a.ID}
+		var err error
+		switch macs, ok := byScannerTime[scannerTimeKey(a.ScannerMac, a.CheckInTime)]; {
+		case a.EmployeeCode != "":
+			res.Detail = "code " + a.EmployeeCode
+			var emp *models.Employee
+			if emp, err = r.employees.GetByCode(ctx, a.EmployeeCode); err == nil {
+				res.EmployeeID = emp.ID
+			} else if errors.Is(err, repository.ErrNotFound) {
+				err = nil
+			}
+		case ok:
+			res.Detail = "detection mac " + strings.Join(macs, ", ")
+			res.EmployeeID, err = r.soleOwner(ctx, owners, macs)
+		default:
+			res.Detail = "no employee code or detection"
+		}
+		report.Results = append(report.Results, r.relink(ctx, r.attendance, res, err, dryRun))
+	}
+	return report, nil
+}
+
+// relinker is the Relink shared by attendance and detections
+type relinker interface {
+	Relink(ctx context.Context, id, employeeID string) error
+}
+
+// relink writes the employee found for res, unless err says the search failed
+func (r *EmployeeIDRepair) relink(ctx context.Context, records relinker, res RelinkResult, err error, dryRun bool) RelinkResult {
+	switch {
+	case err != nil:
+		res.Outcome, res.Detail = RelinkFailed, err.Error()
+	case res.EmployeeID == "":
+		res.Outcome = RelinkUnknown
+	case dryRun:
+		res.Outcome = WouldRelink
+	default:
+		res.Outcome = Relinked
+		if err := records.Relink(ctx, res.ID, res.EmployeeID); err != nil {
+			res.Outcome, res.Detail = RelinkFailed, err.Error()
+		}
+	}
+	return res
+}
+
+// owner returns the ID of the active employee owning the MAC, or "" when nobody does,
+// remembering the answer in owners
+func (r *EmployeeIDRepair) owner(ctx context.Context, owners map[string]string, mac string) (string, error) {
+	if mac == "" {
+		return "", nil
+	}
+	if id, ok := owners[mac]; ok {
+		return id, nil
+	}
+	emp, err := r.employees.GetByMacAddress(ctx, mac)
+	if errors.Is(err, repository.ErrNotFound) && r.devices != nil {
+		emp, err = r.devices.GetEmployeeByDeviceMac(ctx, mac)
+	}
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		owners[mac] = ""
+		return "", nil
+	case err != nil:
+		return "", err
+	}
+	owners[mac] = emp.ID
+	return emp.ID, nil
+}
+
+// soleOwner returns the one employee owning the MACs detected with a check-in, or "" when
+// none or several do
+func (r *EmployeeIDRepair) soleOwner(ctx context.Context, owners map[string]string, macs []string) (string, error) {
+	found := ""
+	for _, mac := range macs {
+		id, err := r.owner(ctx, owners, mac)
+		switch {
+		case err != nil:
+			return "", err
+		case id == "" || id == found:
+		case found != "":
+			return "", nil // two employees at the scanner that second
+		default:
+			found = id
+		}
+	}
+	return found, nil
+}
+
+// scannerTimeKey identifies the detections saved with a check-in, which share its scanner
+// and time; PocketBase keeps both to the second
+func scannerTimeKey(scanner string, at time.Time) string {
+	return fmt.Sprintf("%s|%d", scanner, at.Unix())
+}
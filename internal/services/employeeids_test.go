@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+func TestEmployeeIDRepair(t *testing.T) {
+	store := memory.NewStore(clock.NewFake(pipelineNow))
+	store.AddEmployee(models.Employee{ID: "e1", EmployeeCode: "N001", MacAddress: "aa:bb:cc:dd:ee:01", IsActive: true})
+	store.AddEmployee(models.Employee{ID: "e2", EmployeeCode: "N002", MacAddress: "aa:bb:cc:dd:ee:02"}) // left since
+	store.AddEmployee(models.Employee{ID: "e3", EmployeeCode: "N003", MacAddress: "aa:bb:cc:dd:ee:04", IsActive: true})
+	store.AddDevice(models.EmployeeDevice{EmployeeID: "e1", MacAddress: "aa:bb:cc:dd:ee:03"})
+
+	ctx := context.Background()
+	for _, det := range []models.EmployeeDetection{
+		{MacAddress: "AA:BB:CC:DD:EE:01", ScannerMac: "scanner-1", DetectedAt: pipelineNow},
+		{MacAddress: "aa:bb:cc:dd:ee:03", ScannerMac: "scanner-2", DetectedAt: pipelineNow.Add(1)},
+		{MacAddress: "aa:bb:cc:dd:ee:99", ScannerMac: "scanner-1", DetectedAt: pipelineNow.Add(2)}, // same second
+		{MacAddress: "aa:bb:cc:dd:ee:04", ScannerMac: "scanner-2", DetectedAt: pipelineNow},        // with e1's device
+		{EmployeeID: "e1", MacAddress: "aa:bb:cc:dd:ee:01", ScannerMac: "scanner-1", DetectedAt: pipelineNow.Add(3)},
+	} {
+		store.DetectionRecords().Create(ctx, &det)
+	}
+	for _, a := range []models.Attendance{
+		{ID: "snapshot", EmployeeCode: "N002", CheckInTime: pipelineNow, CreatedDate: pipelineNow},
+		{ID: "detected", ScannerMac: "scanner-1", CheckInTime: pipelineNow, CreatedDate: pipelineNow},
+		{ID: "manual", CheckInTime: pipelineNow, CreatedDate: pipelineNow},
+		{ID: "ambiguous", ScannerMac: "scanner-2", CheckInTime: pipelineNow, CreatedDate: pipelineNow},
+	} {
+		store.AddAttendance(a)
+	}
+
+	repair := NewEmployeeIDRepair(store.AttendanceRecords(), store.DetectionRecords(), store.Employees())
+	repair.SetDevices(store.Devices())
+
+	dry, err := repair.Run(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dry.Count(WouldRelink) != 5 || dry.Count(RelinkUnknown) != 3 {
+		t.Errorf("dry run = %+v, want 5 to relink and 3 unresolved", dry.Results)
+	}
+	if unlinked, _ := store.AttendanceRecords().ListUnlinked(ctx); len(unlinked) != 4 {
+		t.Fatalf("dry run relinked attendance: %+v", unlinked)
+	}
+
+	report, err := repair.Run(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Count(Relinked) != 5 || report.Count(RelinkFailed) != 0 {
+		t.Errorf("report = %+v, want 5 relinked", report.Results)
+	}
+	want := map[string]string{"snapshot": "e2", "detected": "e1", "manual": "", "ambiguous": ""}
+	for _, a := range store.Attendance() {
+		if a.EmployeeID != want[a.ID] {
+			t.Errorf("attendance %s employee_id = %q, want %q", a.ID, a.EmployeeID, want[a.ID])
+		}
+	}
+	var macs []string
+	for _, det := range store.Detections() {
+		if det.EmployeeID == "" {
+			macs = append(macs, det.MacAddress)
+		}
+	}
+	if len(macs) != 1 || macs[0] != "aa:bb:cc:dd:ee:99" {
+		t.Errorf("detections left unlinked = %v, want only the unknown device", macs)
+	}
+}
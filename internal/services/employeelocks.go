@@ -0,0 +1,45 @@
+package services
+
+import "sync"
+
+// EmployeeLocks serializes the check-ins of each employee, so two scanners seeing the
+// same employee at once cannot both find them not checked in yet. Detections of
+// different employees do not wait for each other.
+type EmployeeLocks struct {
+	mu    sync.Mutex
+	locks map[string]*employeeLock
+}
+
+// employeeLock is the lock of one employee and how many detections hold or wait for it
+type employeeLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// NewEmployeeLocks creates the locks of all employees
+func NewEmployeeLocks() *EmployeeLocks {
+	return &EmployeeLocks{locks: make(map[string]*employeeLock)}
+}
+
+// Lock waits until no other detection holds the employee's lock and returns the function
+// releasing it. Locks nobody waits for are forgotten.
+func (l *EmployeeLocks) Lock(employeeID string) (unlock func()) {
+	l.mu.Lock()
+	el := l.locks[employeeID]
+	if el == nil {
+		el = &employeeLock{}
+		l.locks[employeeID] = el
+	}
+	el.refs++
+	l.mu.Unlock()
+
+	el.mu.Lock()
+	return func() {
+		el.mu.Unlock()
+		l.mu.Lock()
+		if el.refs--; el.refs == 0 {
+			delete(l.locks, employeeID)
+		}
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// racyAttendance stores check-ins without checking for an existing one, like PocketBase
+// between two instances' checks and writes
+type racyAttendance struct {
+	repository.AttendanceRepository
+	mu      sync.Mutex
+	created []models.Attendance
+}
+
+func (r *racyAttendance) Create(ctx context.Context, attendance *models.Attendance) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.created = append(r.created, *attendance)
+	return nil
+}
+
+func (r *racyAttendance) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.created)
+}
+
+// racyEmployees answers whether an employee checked in from racyAttendance, yielding
+// before it does so that simultaneous detections all get to look first
+type racyEmployees struct {
+	repository.EmployeeRepository
+	attendance *racyAttendance
+}
+
+func (e racyEmployees) IsCheckedInOn(ctx context.Context, employeeID string, day time.Time) (bool, error) {
+	checkedIn := e.attendance.count() > 0
+	runtime.Gosched()
+	return checkedIn, nil
+}
+
+func TestConcurrentDetectionsCheckInOnce(t *testing.T) {
+	store := newPipelineStore()
+	attendance := &racyAttendance{AttendanceRepository: store.AttendanceRecords()}
+	employees := racyEmployees{EmployeeRepository: store.Employees(), attendance: attendance}
+	service := NewAttendanceService(employees, attendance, store.DetectionRecords(), store.ScannerRecords(), newRecordingNotifier())
+	service.SetClock(clock.NewFake(pipelineNow))
+
+	const detections = 20
+	results := make(chan string, detections)
+	var wg sync.WaitGroup
+	for i := range detections {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := &models.DetectionRequest{ScannerMac: fmt.Sprintf("scanner-%d", i%2), MacAddress: "aa:bb:cc:dd:ee:01", RSSI: -50}
+			res, err := service.DetectWithResult(context.Background(), req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			results <- res.Result
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	if n := attendance.count(); n != 1 {
+		t.Fatalf("attendance records created = %d, want 1", n)
+	}
+	counts := map[string]int{}
+	for r := range results {
+		counts[r]++
+	}
+	if counts[ResultDuplicate] != detections-1 {
+		t.Errorf("results = %v, want %d duplicates", counts, detections-1)
+	}
+}
+
+func TestAttendanceStageExistingRecord(t *testing.T) {
+	store := newPipelineStore()
+	ctx := context.Background()
+	// Another instance recorded the check-in after this one's dedupe stage looked
+	store.AttendanceRecords().Create(ctx, &models.Attendance{EmployeeID: "e1", CheckInTime: pipelineNow, CreatedDate: pipelineNow})
+
+	dc := newDetectionContext("aa:bb:cc:dd:ee:01", -50)
+	dc.Employee = &models.Employee{ID: "e1", Name: "Somchai", WorkStartTime: "08:00:00"}
+	cont, err := AttendanceStage{Attendance: store.AttendanceRecords()}.Process(ctx, dc)
+	if err != nil || cont {
+		t.Fatalf("Process() = %v, %v, want false, nil", cont, err)
+	}
+	if dc.Result != ResultDuplicate || dc.Attendance != nil || len(store.Attendance()) != 1 {
+		t.Errorf("result %q, attendance %+v, %d records, want a duplicate and one record", dc.Result, dc.Attendance, len(store.Attendance()))
+	}
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// EndOfDayTask is a unit of work run once per day by EndOfDayJob
+type EndOfDayTask func(ctx context.Context, day time.Time) error
+
+type namedTask struct {
+	name string
+	run  EndOfDayTask
+}
+
+// EndOfDayJob runs registered tasks once a day at a fixed local time
+type EndOfDayJob struct {
+	at    time.Duration // offset from midnight
+	tasks []namedTask
+	now   func() time.Time
+}
+
+// NewEndOfDayJob creates a job that fires daily at the given HH:MM time
+func NewEndOfDayJob(at string) (*EndOfDayJob, error) {
+	offset, err := parseClock(at)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end-of-day time: %w", err)
+	}
+	return &EndOfDayJob{at: offset, now: time.Now}, nil
+}
+
+// Register adds a task; tasks run sequentially in registration order
+func (j *EndOfDayJob) Register(name string, task EndOfDayTask) {
+	j.tasks = append(j.tasks, namedTask{name: name, run: task})
+}
+
+// Start runs the job loop in a goroutine until ctx is cancelled
+func (j *EndOfDayJob) Start(ctx context.Context) {
+	go func() {
+		for {
+			next := j.nextRun(j.now())
+			timer := time.NewTimer(next.Sub(j.now()))
+
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				log.Println("🌙 End-of-day job stopped")
+				return
+			case <-timer.C:
+				j.RunOnce(ctx, next)
+			}
+		}
+	}()
+}
+
+// RunOnce executes all tasks for the given day; a failing task does not stop the others
+func (j *EndOfDayJob) RunOnce(ctx context.Context, day time.Time) {
+	log.Printf("🌙 Running end-of-day job for %s (%d tasks)", day.Format("2006-01-02"), len(j.tasks))
+	for _, t := range j.tasks {
+		if err := t.run(ctx, day); err != nil {
+			log.Printf("❌ End-of-day task %s failed: %v", t.name, err)
+		}
+	}
+}
+
+// nextRun returns the next time the job should fire strictly after now
+func (j *EndOfDayJob) nextRun(now time.Time) time.Time {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	next := midnight.Add(j.at)
+	if !next.After(now) {
+		next = midnight.AddDate(0, 0, 1).Add(j.at)
+	}
+	return next
+}
+
+// parseClock parses an HH:MM time of day into an offset from midnight
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// sinceMidnight returns the offset of t from its local midnight
+func sinceMidnight(t time.Time) time.Duration {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return t.Sub(midnight)
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// ExportRow is one check-in of an attendance export, naming the employee as they were
+// when it was recorded
+type ExportRow struct {
+	Date         string // YYYY-MM-DD
+	EmployeeCode string
+	EmployeeName string
+	Department   string
+	CheckIn      string // HH:MM
+	CheckOut     string // HH:MM, empty when not checked out
+	Status       string
+	Source       string
+	LateMinutes  int  // minutes after the work start of a late check-in, 0 otherwise
+	Locked       bool // the period is locked, so the row is final
+}
+
+// exportHeader is the CSV header matching ExportRow
+var exportHeader = []string{"date", "employee_code", "employee_name", "department", "check_in", "check_out", "status", "source", "late_minutes", "locked"}
+
+// AttendanceExport renders the check-ins of a payroll period. Regenerating the export of
+// a past period gives the same result after an employee is renamed or moved.
+type AttendanceExport struct {
+	employees     repository.EmployeeDirectory
+	attendance    repository.AttendanceLog
+	periodLocks   repository.PeriodLockRepository
+	loc           *time.Location
+	includeGuests bool
+}
+
+// NewAttendanceExport creates an export with times shown in loc, marking the rows of
+// periods locked in periodLocks
+func NewAttendanceExport(employees repository.EmployeeDirectory, attendance repository.AttendanceLog, periodLocks repository.PeriodLockRepository, loc *time.Location) *AttendanceExport {
+	return &AttendanceExport{employees: employees, attendance: attendance, periodLocks: periodLocks, loc: loc}
+}
+
+// SetIncludeGuests adds the check-ins of guest tags, left out by default
+func (e *AttendanceExport) SetIncludeGuests(on bool) {
+	e.includeGuests = on
+}
+
+// Rows returns the check-ins of period ("2026-01"), oldest first, without self-test
+// records or, unless included, guest check-ins. Names, codes and departments come from the snapshot on each record; only
+// records from before the snapshot fields fall back to the employee's current values. Late
+// minutes are counted from the employee's current work start.
+func (e *AttendanceExport) Rows(ctx context.Context, period string) ([]ExportRow, error) {
+	start, err := time.ParseInLocation(models.PeriodLayout, period, e.loc)
+	if err != nil {
+		return nil, fmt.Errorf("period must be YYYY-MM, got %q", period)
+	}
+	lock, err := e.periodLocks.Get(ctx, period)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the lock of %s: %w", period, err)
+	}
+	locked := lock != nil && lock.Locked
+	records, err := e.attendance.ListBetween(ctx, start, start.AddDate(0, 1, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	var current map[string]models.Employee // loaded only when a record has no snapshot or is late
+	var rows []ExportRow
+	for _, a := range records {
+		if a.Source == models.AttendanceSourceSelfTest || (a.Status == models.AttendanceStatusGuest && !e.includeGuests) {
+			continue
+		}
+		noSnapshot := a.EmployeeName == "" && a.EmployeeCode == ""
+		if current == nil && (noSnapshot || a.Status == models.AttendanceStatusLate) {
+			if current, err = e.currentEmployees(ctx); err != nil {
+				return nil, err
+			}
+		}
+		if noSnapshot {
+			if emp, ok := current[a.EmployeeID]; ok {
+				a.SnapshotEmployee(&emp)
+			} else {
+				a.EmployeeCode = a.EmployeeID
+			}
+		}
+
+		row := ExportRow{
+			Date:         a.CreatedDate.In(e.loc).Format("2006-01-02"),
+			EmployeeCode: a.EmployeeCode,
+			EmployeeName: a.EmployeeName,
+			Department:   a.Department,
+			CheckIn:      a.CheckInTime.In(e.loc).Format("15:04"),
+			Status:       a.Status,
+			Source:       a.Source,
+			Locked:       locked,
+		}
+		if !a.CheckOutTime.IsZero() {
+			row.CheckOut = a.CheckOutTime.In(e.loc).Format("15:04")
+		}
+		if emp, ok := current[a.EmployeeID]; ok && a.Status == models.AttendanceStatusLate {
+			created := a.CreatedDate.In(e.loc)
+			day := time.Date(created.Year(), created.Month(), created.Day(), 0, 0, 0, 0, e.loc)
+			row.LateMinutes = lateMinutes(a.CheckInTime.In(e.loc), day, emp.WorkStartTime)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// WriteCSV writes the rows of period as CSV with a header line
+func (e *AttendanceExport) WriteCSV(ctx context.Context, w io.Writer, period string) (int, error) {
+	rows, err := e.Rows(ctx, period)
+	if err != nil {
+		return 0, err
+	}
+	out := csv.NewWriter(w)
+	out.Write(exportHeader)
+	for _, r := range rows {
+		late := ""
+		if r.LateMinutes > 0 {
+			late = strconv.Itoa(r.LateMinutes)
+		}
+		out.Write([]string{r.Date, r.EmployeeCode, r.EmployeeName, r.Department, r.CheckIn, r.CheckOut, r.Status, r.Source, late,
+			strconv.FormatBool(r.Locked)})
+	}
+	out.Flush()
+	return len(rows), out.Error()
+}
+
+// currentEmployees indexes the active employees by ID
+func (e *AttendanceExport) currentEmployees(ctx context.Context) (map[string]models.Employee, error) {
+	employees, err := e.employees.ListActive(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list employees: %w", err)
+	}
+	byID := make(map[string]models.Employee, len(employees))
+	for _, emp := range employees {
+		byID[emp.ID] = emp
+	}
+	return byID, nil
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+func TestAttendanceExportSurvivesRenames(t *testing.T) {
+	ctx := context.Background()
+	january := time.Date(2026, 1, 5, 7, 55, 0, 0, time.Local)
+	clk := clock.NewFake(january)
+	store := memory.NewStore(clk)
+	emp := store.AddEmployee(models.Employee{Name: "Somchai Jaidee", EmployeeCode: "E001", Department: "ER",
+		MacAddress: "aa:bb:cc:dd:ee:01", WorkStartTime: "08:00:00", IsActive: true})
+	service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), newRecordingNotifier())
+	service.SetClock(clk)
+	checkIn := func(t *testing.T) {
+		t.Helper()
+		if err := service.ProcessDetection(ctx, &models.DetectionRequest{ScannerMac: "scanner-1", MacAddress: emp.MacAddress, RSSI: -60}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkIn(t)
+	// A record from before the snapshot fields existed
+	old := store.AddEmployee(models.Employee{Name: "Malee", EmployeeCode: "E002", Department: "OPD", IsActive: true})
+	at := january.AddDate(0, 0, 1)
+	store.AttendanceRecords().Create(ctx, &models.Attendance{EmployeeID: old.ID, CheckInTime: at, CreatedDate: at, Status: "ontime", Source: models.AttendanceSourceScanner})
+
+	export := NewAttendanceExport(store.Employees(), store.AttendanceRecords(), store.PeriodLocks(), time.Local)
+	generate := func(t *testing.T, period string) string {
+		t.Helper()
+		var b bytes.Buffer
+		if _, err := export.WriteCSV(ctx, &b, period); err != nil {
+			t.Fatal(err)
+		}
+		return b.String()
+	}
+	before := generate(t, "2026-01")
+	if want := "2026-01-05,E001,Somchai Jaidee,ER,07:55,,ontime,scanner,,false\n"; !strings.Contains(before, want) {
+		t.Fatalf("January export =\n%s\nwant a row %q", before, want)
+	}
+
+	// HR corrects the name and moves the employee in February
+	emp.Name, emp.Department = "Somchai Jaidii", "ICU"
+	if err := store.Employees().Update(ctx, &emp); err != nil {
+		t.Fatal(err)
+	}
+	clk.Set(time.Date(2026, 2, 2, 7, 50, 0, 0, time.Local))
+	checkIn(t)
+
+	t.Run("past month is unchanged", func(t *testing.T) {
+		if after := generate(t, "2026-01"); after != before {
+			t.Errorf("January export changed after the rename:\nbefore\n%s\nafter\n%s", before, after)
+		}
+	})
+
+	t.Run("new check-ins use the new name", func(t *testing.T) {
+		if got, want := generate(t, "2026-02"), "2026-02-02,E001,Somchai Jaidii,ICU,07:50,,ontime,scanner,,false\n"; !strings.HasSuffix(got, want) {
+			t.Errorf("February export =\n%s\nwant a row %q", got, want)
+		}
+	})
+
+	t.Run("records without a snapshot use the current employee", func(t *testing.T) {
+		if want := "2026-01-06,E002,Malee,OPD,07:55,,ontime,scanner,,false\n"; !strings.Contains(before, want) {
+			t.Errorf("January export =\n%s\nwant a row %q", before, want)
+		}
+	})
+
+	t.Run("late minutes", func(t *testing.T) {
+		late := time.Date(2026, 1, 7, 8, 23, 0, 0, time.Local)
+		store.AttendanceRecords().Create(ctx, &models.Attendance{EmployeeID: emp.ID, EmployeeName: "Somchai Jaidee", EmployeeCode: "E001",
+			Department: "ER", CheckInTime: late, CreatedDate: late, Status: "late", Source: models.AttendanceSourceScanner})
+		if got, want := generate(t, "2026-01"), "2026-01-07,E001,Somchai Jaidee,ER,08:23,,late,scanner,23,false\n"; !strings.Contains(got, want) {
+			t.Errorf("January export =\n%s\nwant a row %q", got, want)
+		}
+	})
+
+	t.Run("locked period", func(t *testing.T) {
+		if err := store.PeriodLocks().Save(ctx, &models.LockedPeriod{Period: "2026-01", Locked: true, LockedAt: clk.Now()}); err != nil {
+			t.Fatal(err)
+		}
+		got := generate(t, "2026-01")
+		if want := "2026-01-05,E001,Somchai Jaidee,ER,07:55,,ontime,scanner,,true\n"; !strings.Contains(got, want) {
+			t.Errorf("January export =\n%s\nwant a row %q", got, want)
+		}
+		if got, want := generate(t, "2026-02"), ",scanner,,false\n"; !strings.HasSuffix(got, want) {
+			t.Errorf("February export =\n%s\nwant the row unlocked", got)
+		}
+	})
+
+	t.Run("invalid period", func(t *testing.T) {
+		if _, err := export.Rows(ctx, "January"); err == nil {
+			t.Error("Rows() accepted an invalid period")
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/logging"
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// ErrGuestUntilPast means a guest tag was registered with a last day before today
+var ErrGuestUntilPast = errors.New("guest tag expiry is in the past")
+
+// ErrTagInUse means the tag's MAC already belongs to an active employee or guest
+var ErrTagInUse = errors.New("tag is already registered")
+
+// GuestEmployees lists, creates and switches off guest records
+type GuestEmployees interface {
+	repository.EmployeeDirectory
+	repository.EmployeeActivation
+	repository.GuestRegistry
+}
+
+// Guests lends tags to visitors and contractors until a date. Guests check in like
+// employees but are never late, are reported apart from staff, and are deactivated at
+// the end of their last day with a reminder to collect the tag.
+type Guests struct {
+	employees GuestEmployees
+	audit     repository.AuditLog
+	notifier  BotNotifier
+	clock     clock.Clock
+}
+
+// NewGuests creates the guest registry and its expiry task
+func NewGuests(employees GuestEmployees, audit repository.AuditLog, notifier BotNotifier) *Guests {
+	return &Guests{employees: employees, audit: audit, notifier: notifier, clock: clock.Real{}}
+}
+
+// SetClock replaces the time source, used by tests
+func (g *Guests) SetClock(c clock.Clock) {
+	g.clock = c
+}
+
+// Register creates an active guest for the tag, valid through the until day. The
+// registration is audited with the admin who made it.
+func (g *Guests) Register(ctx context.Context, mac, name string, until time.Time, actorID int64, actorName string) (*models.Employee, error) {
+	now := g.clock.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if until.Before(today) {
+		return nil, fmt.Errorf("%w: %s", ErrGuestUntilPast, until.Format("2006-01-02"))
+	}
+	active, err := g.employees.ListActive(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list employees: %w", err)
+	}
+	for _, emp := range active {
+		if strings.EqualFold(emp.MacAddress, mac) {
+			return nil, fmt.Errorf("%w to %s", ErrTagInUse, emp.Name)
+		}
+	}
+
+	guest := &models.Employee{Name: name, MacAddress: mac, GuestUntil: until}
+	if err := g.employees.CreateGuest(ctx, guest); err != nil {
+		return nil, err
+	}
+	entry := &models.AuditEntry{
+		Action:    models.AuditGuestRegistered,
+		TargetID:  guest.ID,
+		ActorID:   actorID,
+		ActorName: actorName,
+		Details:   fmt.Sprintf("mac=%s guest_until=%s", mac, until.Format("2006-01-02")),
+		At:        now,
+	}
+	if err := g.audit.Record(ctx, entry); err != nil {
+		log.Printf("Warning: failed to audit guest registration of %s: %v", name, err)
+	}
+	log.Printf("🏷️ Guest %s registered with tag %s until %s by %d", name, logging.MaskMAC(mac), until.Format("2006-01-02"), actorID)
+	return guest, nil
+}
+
+// Expire deactivates guests whose last day is the day or earlier and reminds the admin
+// chat to collect their tags; it is an EndOfDayTask
+func (g *Guests) Expire(ctx context.Context, day time.Time) error {
+	active, err := g.employees.ListActive(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list employees: %w", err)
+	}
+	next := day.AddDate(0, 0, 1)
+	var expired []models.Employee
+	for _, emp := range active {
+		if !emp.GuestExpired(next) {
+			continue
+		}
+		entry := &models.AuditEntry{
+			Action:    models.AuditEmployeeDeactivated,
+			TargetID:  emp.ID,
+			ActorName: "system",
+			Details:   "guest_until=" + emp.GuestUntil.Format("2006-01-02"),
+			At:        g.clock.Now(),
+		}
+		if err := g.audit.Record(ctx, entry); err != nil {
+			return fmt.Errorf("failed to audit guest expiry: %w", err)
+		}
+		if err := g.employees.SetActive(ctx, emp.ID, false); err != nil {
+			return err
+		}
+		log.Printf("🏷️ Guest %s expired after %s, tag %s deactivated", emp.Name, emp.GuestUntil.Format("2006-01-02"), logging.MaskMAC(emp.MacAddress))
+		expired = append(expired, emp)
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteString("🏷️ *แท็กผู้มาติดต่อหมดอายุ*\nปิดใช้งานแล้ว กรุณาเก็บแท็กคืน:\n")
+	for _, emp := range expired {
+		fmt.Fprintf(&b, "• %s %s (ถึง %s)\n", markdown.Escape(emp.Name), markdown.Code(emp.MacAddress), emp.GuestUntil.Format("02/01/2006"))
+	}
+	g.notifier.SendNotification(b.String())
+	return nil
+}
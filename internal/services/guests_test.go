@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+func TestGuestsRegister(t *testing.T) {
+	ctx := context.Background()
+	monday := time.Date(2026, 3, 2, 9, 0, 0, 0, time.Local)
+	clk := clock.NewFake(monday)
+	store := memory.NewStore(clk)
+	store.AddEmployee(models.Employee{Name: "Somchai", MacAddress: "aa:bb:cc:dd:ee:01", IsActive: true})
+	guests := NewGuests(store.Employees(), store.AuditLog(), newRecordingNotifier())
+	guests.SetClock(clk)
+
+	tests := []struct {
+		name    string
+		mac     string
+		until   time.Time
+		wantErr error
+	}{
+		{"until a past day", "aa:bb:cc:dd:ee:09", monday.AddDate(0, 0, -1), ErrGuestUntilPast},
+		{"tag of an employee", "AA:BB:CC:DD:EE:01", monday.AddDate(0, 0, 4), ErrTagInUse},
+		{"until today", "aa:bb:cc:dd:ee:08", time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local), nil},
+		{"for a week", "aa:bb:cc:dd:ee:09", time.Date(2026, 3, 6, 0, 0, 0, 0, time.Local), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guest, err := guests.Register(ctx, tt.mac, "ช่างแอร์", tt.until, 42, "Admin")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Register() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (!guest.IsGuest || !guest.IsActive || guest.ID == "") {
+				t.Errorf("guest = %+v", guest)
+			}
+		})
+	}
+
+	audit := store.AuditEntries()
+	if len(audit) != 2 || audit[1].Action != models.AuditGuestRegistered || audit[1].ActorID != 42 {
+		t.Errorf("audit = %+v, want both registrations", audit)
+	}
+}
+
+func TestGuestCheckIns(t *testing.T) {
+	ctx := context.Background()
+	morning := time.Date(2026, 3, 2, 9, 30, 0, 0, time.Local)
+	clk := clock.NewFake(morning)
+	store := memory.NewStore(clk)
+	store.AddEmployee(models.Employee{Name: "Somchai", MacAddress: "aa:bb:cc:dd:ee:01", WorkStartTime: "08:00:00", IsActive: true})
+	notifier := newRecordingNotifier()
+	guests := NewGuests(store.Employees(), store.AuditLog(), notifier)
+	guests.SetClock(clk)
+	guest, err := guests.Register(ctx, "aa:bb:cc:dd:ee:09", "ช่างแอร์", time.Date(2026, 3, 3, 0, 0, 0, 0, time.Local), 42, "Admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), notifier)
+	service.SetClock(clk)
+	detect := func(mac string) DetectionResult {
+		t.Helper()
+		res, err := service.DetectWithResult(ctx, &models.DetectionRequest{ScannerMac: "SC:01", MacAddress: mac, RSSI: -50})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	t.Run("guest is never late", func(t *testing.T) {
+		detect("aa:bb:cc:dd:ee:01")
+		detect("aa:bb:cc:dd:ee:09")
+		statuses := make(map[string]string)
+		for _, a := range store.Attendance() {
+			statuses[a.EmployeeID] = a.Status
+		}
+		if got := statuses[guest.ID]; got != models.AttendanceStatusGuest {
+			t.Errorf("guest status = %q, want %q", got, models.AttendanceStatusGuest)
+		}
+		if len(notifier.admin) != 1 {
+			t.Errorf("admin notifications = %q, want only the employee's late alert", notifier.admin)
+		}
+	})
+
+	t.Run("summary counts guests apart", func(t *testing.T) {
+		summary, err := NewDailySummary(store.Employees(), store.AttendanceRecords(), notifier).Build(ctx, morning)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{"เข้างาน: 1/1 คน", "เข้าสาย: 1 คน", "ผู้มาติดต่อ (แท็กชั่วคราว): 1 คน"} {
+			if !strings.Contains(summary, want) {
+				t.Errorf("summary missing %q:\n%s", want, summary)
+			}
+		}
+	})
+
+	t.Run("export leaves guests out by default", func(t *testing.T) {
+		export := NewAttendanceExport(store.Employees(), store.AttendanceRecords(), store.PeriodLocks(), time.Local)
+		rows, err := export.Rows(ctx, "2026-03")
+		if err != nil || len(rows) != 1 || rows[0].EmployeeName != "Somchai" {
+			t.Errorf("Rows() = %+v, %v, want the employee only", rows, err)
+		}
+		export.SetIncludeGuests(true)
+		if rows, err := export.Rows(ctx, "2026-03"); err != nil || len(rows) != 2 {
+			t.Errorf("Rows() with guests = %+v, %v", rows, err)
+		}
+	})
+
+	t.Run("expires after the last day", func(t *testing.T) {
+		if err := guests.Expire(ctx, morning); err != nil {
+			t.Fatal(err)
+		}
+		if emps, _ := store.Employees().ListActive(ctx); len(emps) != 2 {
+			t.Fatalf("active after the first day = %d, want 2", len(emps))
+		}
+
+		// A detection after the last day before the end-of-day run is ignored like an inactive tag
+		clk.Set(morning.AddDate(0, 0, 2))
+		if res := detect("aa:bb:cc:dd:ee:09"); res.Result != ResultUnknownDevice {
+			t.Errorf("expired guest detection = %s, want %s", res.Result, ResultUnknownDevice)
+		}
+
+		sent := len(notifier.admin)
+		if err := guests.Expire(ctx, morning.AddDate(0, 0, 1)); err != nil {
+			t.Fatal(err)
+		}
+		emps, _ := store.Employees().ListActive(ctx)
+		if len(emps) != 1 || emps[0].IsGuest {
+			t.Errorf("active after the last day = %+v, want the employee only", emps)
+		}
+		if len(notifier.admin) != sent+1 || !strings.Contains(notifier.admin[sent], "aa:bb:cc:dd:ee:09") {
+			t.Errorf("admin notifications = %q, want a reminder to collect the tag", notifier.admin[sent:])
+		}
+	})
+}
@@ -0,0 +1,431 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/localstore"
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// InactivityCallbackPrefix routes the Deactivate and Keep buttons of the inactivity digest
+const InactivityCallbackPrefix = "idle"
+
+// inactivityStateKey is the local store value holding the policy's per-employee counters
+const inactivityStateKey = "inactivity_state.json"
+
+// InactivityEmployees lists active employees and switches them off
+type InactivityEmployees interface {
+	repository.EmployeeDirectory
+	repository.EmployeeActivation
+}
+
+// InactivityPolicyConfig configures the inactivity policy
+type InactivityPolicyConfig struct {
+	FlagAfter       time.Duration // time without detections or check-ins before the admin is asked
+	DeactivateAfter time.Duration // time after which an employee the admin did not answer for is deactivated
+	Grace           time.Duration // least time between the first digest and an automatic deactivation
+	DigestDay       time.Weekday  // the end-of-day run on this weekday sends the digest
+}
+
+// DefaultInactivityPolicyConfig asks after 30 days and deactivates after 90, with the
+// digest on Mondays
+func DefaultInactivityPolicyConfig() InactivityPolicyConfig {
+	return InactivityPolicyConfig{
+		FlagAfter:       30 * 24 * time.Hour,
+		DeactivateAfter: 90 * 24 * time.Hour,
+		Grace:           7 * 24 * time.Hour,
+		DigestDay:       time.Monday,
+	}
+}
+
+// inactivityCounters is the policy's state for one employee
+type inactivityCounters struct {
+	FlaggedAt   time.Time `json:"flagged_at,omitzero"` // first offered in a digest
+	ResetAt     time.Time `json:"reset_at,omitzero"`   // kept or reactivated; idle time counts from here
+	Deactivated bool      `json:"deactivated,omitempty"`
+}
+
+// InactiveEmployee is an active employee not seen for at least FlagAfter
+type InactiveEmployee struct {
+	Employee models.Employee
+	LastSeen time.Time // zero when not seen within DeactivateAfter
+}
+
+// InactivityPolicy flags employees without detections or check-ins for a while, asks
+// the admin in a weekly digest whether to deactivate them, and deactivates those still
+// unanswered once the stricter threshold passes. Every deactivation is audited.
+type InactivityPolicy struct {
+	cfg        InactivityPolicyConfig
+	employees  InactivityEmployees
+	attendance repository.AttendanceLog
+	detections repository.DetectionLog
+	audit      repository.AuditLog
+	store      localstore.LocalStore
+	notifier   AdminPromptNotifier
+	clock      clock.Clock
+
+	mu sync.Mutex // serializes runs and button presses over the stored counters
+}
+
+// NewInactivityPolicy creates the policy job
+func NewInactivityPolicy(cfg InactivityPolicyConfig, employees InactivityEmployees, attendance repository.AttendanceLog,
+	detections repository.DetectionLog, audit repository.AuditLog, store localstore.LocalStore, notifier AdminPromptNotifier) (*InactivityPolicy, error) {
+	if cfg.FlagAfter <= 0 || cfg.DeactivateAfter < cfg.FlagAfter || cfg.Grace < 0 {
+		return nil, fmt.Errorf("invalid inactivity policy config %+v: want 0 < flag <= deactivate", cfg)
+	}
+	return &InactivityPolicy{
+		cfg:        cfg,
+		employees:  employees,
+		attendance: attendance,
+		detections: detections,
+		audit:      audit,
+		store:      store,
+		notifier:   notifier,
+		clock:      clock.Real{},
+	}, nil
+}
+
+// SetClock replaces the time source, used by tests
+func (p *InactivityPolicy) SetClock(c clock.Clock) {
+	p.clock = c
+}
+
+// Run is the end-of-day task: it clears the counters of reactivated employees,
+// deactivates those past DeactivateAfter the admin left unanswered, and on the digest
+// day asks about the rest of the inactive employees.
+func (p *InactivityPolicy) Run(ctx context.Context, day time.Time) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.clock.Now()
+	state, err := p.load(ctx)
+	if err != nil {
+		return err
+	}
+	employees, err := p.employees.ListActive(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list employees: %w", err)
+	}
+
+	active := make(map[string]bool, len(employees))
+	for _, emp := range employees {
+		active[emp.ID] = true
+	}
+	for id, c := range state {
+		switch {
+		case !active[id] && !c.Deactivated:
+			// Switched off, by us or by hand; only reactivation matters from now on
+			state[id] = &inactivityCounters{Deactivated: true}
+		case active[id] && c.Deactivated:
+			state[id] = &inactivityCounters{ResetAt: now}
+			log.Printf("♻️  Employee %s was reactivated, inactivity counters cleared", id)
+		}
+	}
+
+	inactive, err := p.inactive(ctx, employees, state, now)
+	if err != nil {
+		return err
+	}
+	var pending []InactiveEmployee
+	for _, ie := range inactive {
+		c := state[ie.Employee.ID]
+		unanswered := c != nil && !c.FlaggedAt.IsZero() && now.Sub(c.FlaggedAt) >= p.cfg.Grace
+		if unanswered && p.idle(ie, now) >= p.cfg.DeactivateAfter {
+			if err := p.autoDeactivate(ctx, ie, state, now); err != nil {
+				return err
+			}
+			continue
+		}
+		pending = append(pending, ie)
+	}
+
+	if day.Weekday() == p.cfg.DigestDay && len(pending) > 0 {
+		for _, ie := range p.sendDigest(pending, now) {
+			c := counters(state, ie.Employee.ID)
+			if c.FlaggedAt.IsZero() {
+				c.FlaggedAt = now
+			}
+		}
+	}
+	return p.save(ctx, state)
+}
+
+// Inactive returns the active employees not seen for at least FlagAfter, longest idle first
+func (p *InactivityPolicy) Inactive(ctx context.Context) ([]InactiveEmployee, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, err := p.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	employees, err := p.employees.ListActive(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list employees: %w", err)
+	}
+	return p.inactive(ctx, employees, state, p.clock.Now())
+}
+
+// HandleCallback applies a Deactivate or Keep button for the admin, after checking the
+// employee against fresh data: one seen again since the digest is not deactivated.
+func (p *InactivityPolicy) HandleCallback(ctx context.Context, data string, actorID int64, actorName string) (string, error) {
+	parts := strings.Split(data, ":")
+	if len(parts) != 3 || parts[0] != InactivityCallbackPrefix || (parts[1] != "off" && parts[1] != "keep") {
+		return "", fmt.Errorf("invalid inactivity callback %q", data)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.clock.Now()
+	state, err := p.load(ctx)
+	if err != nil {
+		return "", err
+	}
+	employees, err := p.employees.ListActive(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list employees: %w", err)
+	}
+	var emp *models.Employee
+	for i := range employees {
+		if employees[i].ID == parts[2] {
+			emp = &employees[i]
+		}
+	}
+	if emp == nil {
+		return fmt.Sprintf("ℹ️ พนักงาน %s ถูกปิดใช้งานแล้ว", parts[2]), nil
+	}
+
+	if parts[1] == "keep" {
+		entry := &models.AuditEntry{
+			Action:    models.AuditEmployeeKept,
+			TargetID:  emp.ID,
+			ActorID:   actorID,
+			ActorName: actorName,
+			At:        now,
+		}
+		if err := p.audit.Record(ctx, entry); err != nil {
+			return "", fmt.Errorf("failed to audit keeping employee: %w", err)
+		}
+		state[emp.ID] = &inactivityCounters{ResetAt: now}
+		if err := p.save(ctx, state); err != nil {
+			return "", err
+		}
+		log.Printf("💤 %s kept active by %d", emp.Name, actorID)
+		return fmt.Sprintf("✅ เก็บ %s ไว้แล้ว\nจะถามอีกครั้งถ้าไม่พบอีก %d วัน\nโดย: %s", markdown.Escape(emp.Name), days(p.cfg.FlagAfter), markdown.Escape(actorName)), nil
+	}
+
+	inactive, err := p.inactive(ctx, []models.Employee{*emp}, state, now)
+	if err != nil {
+		return "", err
+	}
+	if len(inactive) == 0 {
+		return fmt.Sprintf("⚠️ %s กลับมาแล้วหรืออยู่ระหว่างลา ไม่ได้ปิดใช้งาน", markdown.Escape(emp.Name)), nil
+	}
+	details := fmt.Sprintf("idle_days=%s", idleDays(inactive[0], p.cfg, now))
+	if err := p.deactivate(ctx, *emp, actorID, actorName, details, state); err != nil {
+		return "", err
+	}
+	if err := p.save(ctx, state); err != nil {
+		return "", err
+	}
+	log.Printf("💤 %s deactivated by %d", emp.Name, actorID)
+	return fmt.Sprintf("🚫 ปิดใช้งาน %s แล้ว\nโดย: %s", markdown.Escape(emp.Name), markdown.Escape(actorName)), nil
+}
+
+// inactive returns the employees not on leave and not seen for at least FlagAfter,
+// longest idle first. Check-ins are read once; detections only for employees
+// without a recent check-in.
+func (p *InactivityPolicy) inactive(ctx context.Context, employees []models.Employee, state map[string]*inactivityCounters, now time.Time) ([]InactiveEmployee, error) {
+	since := now.Add(-p.cfg.DeactivateAfter)
+	records, err := p.attendance.ListSince(ctx, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attendance: %w", err)
+	}
+	lastCheckIn := make(map[string]time.Time)
+	for _, a := range records {
+		if a.Status != "leave" && a.CheckInTime.After(lastCheckIn[a.EmployeeID]) {
+			lastCheckIn[a.EmployeeID] = a.CheckInTime
+		}
+	}
+
+	var inactive []InactiveEmployee
+	for _, emp := range employees {
+		// Guests are switched off when their tag expires
+		if emp.IsGuest || emp.OnLeave(now) {
+			continue
+		}
+		last := lastCheckIn[emp.ID]
+		if c := state[emp.ID]; c != nil && c.ResetAt.After(last) {
+			last = c.ResetAt
+		}
+		if !emp.LeaveUntil.IsZero() {
+			if back := emp.LeaveUntil.AddDate(0, 0, 1); back.After(last) {
+				last = back
+			}
+		}
+		if now.Sub(last) < p.cfg.FlagAfter {
+			continue
+		}
+
+		detections, err := p.detections.ListByEmployeeSince(ctx, emp.ID, laterOf(since, last))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list detections of %s: %w", emp.ID, err)
+		}
+		if n := len(detections); n > 0 && detections[n-1].DetectedAt.After(last) {
+			last = detections[n-1].DetectedAt
+		}
+		if now.Sub(last) < p.cfg.FlagAfter {
+			continue
+		}
+		if last.Before(since) {
+			last = time.Time{}
+		}
+		inactive = append(inactive, InactiveEmployee{Employee: emp, LastSeen: last})
+	}
+	sort.SliceStable(inactive, func(i, j int) bool { return inactive[i].LastSeen.Before(inactive[j].LastSeen) })
+	return inactive, nil
+}
+
+// sendDigest asks the admin about the inactive employees and returns those offered
+func (p *InactivityPolicy) sendDigest(inactive []InactiveEmployee, now time.Time) []InactiveEmployee {
+	var b strings.Builder
+	b.WriteString("💤 *พนักงานที่ไม่พบในระบบเป็นเวลานาน*\n")
+	fmt.Fprintf(&b, "ไม่มีการตรวจพบหรือเข้างานอย่างน้อย %d วัน\n\n", days(p.cfg.FlagAfter))
+	var buttons []models.PromptButton
+	offered := inactive
+	for i, ie := range inactive {
+		if i == maxDigestProposals {
+			fmt.Fprintf(&b, "\n…และอีก %d คน (ถามในสัปดาห์ถัดไป)\n", len(inactive)-maxDigestProposals)
+			offered = inactive[:maxDigestProposals]
+			break
+		}
+		fmt.Fprintf(&b, "%d. %s (%s) ไม่พบ %s วัน\n", i+1, markdown.Escape(ie.Employee.Name), markdown.Escape(ie.Employee.EmployeeCode), idleDays(ie, p.cfg, now))
+		buttons = append(buttons,
+			models.PromptButton{
+				Label: "🚫 ปิดใช้งาน " + ie.Employee.Name,
+				Data:  fmt.Sprintf("%s:off:%s", InactivityCallbackPrefix, ie.Employee.ID),
+			},
+			models.PromptButton{
+				Label: "✅ เก็บไว้ " + ie.Employee.Name,
+				Data:  fmt.Sprintf("%s:keep:%s", InactivityCallbackPrefix, ie.Employee.ID),
+			})
+	}
+	fmt.Fprintf(&b, "\nถ้าไม่ตอบ ระบบจะปิดใช้งานเองเมื่อไม่พบครบ %d วัน", days(p.cfg.DeactivateAfter))
+
+	p.notifier.SendPrompt(b.String(), buttons)
+	log.Printf("💤 Asked about %d inactive employees", len(offered))
+	return offered
+}
+
+// autoDeactivate deactivates an employee the admin did not answer for and tells the admin
+func (p *InactivityPolicy) autoDeactivate(ctx context.Context, ie InactiveEmployee, state map[string]*inactivityCounters, now time.Time) error {
+	details := fmt.Sprintf("idle_days=%s flagged_at=%s", idleDays(ie, p.cfg, now), state[ie.Employee.ID].FlaggedAt.Format("2006-01-02"))
+	if err := p.deactivate(ctx, ie.Employee, 0, "system", details, state); err != nil {
+		return err
+	}
+	log.Printf("💤 %s deactivated automatically after %s idle days", ie.Employee.Name, idleDays(ie, p.cfg, now))
+	p.notifier.SendNotification(fmt.Sprintf("🚫 *ปิดใช้งานอัตโนมัติ*: %s (%s)\nไม่พบ %s วันและไม่มีการตอบในสรุปประจำสัปดาห์",
+		markdown.Escape(ie.Employee.Name), markdown.Escape(ie.Employee.EmployeeCode), idleDays(ie, p.cfg, now)))
+	return nil
+}
+
+// deactivate audits and applies a deactivation; the entry is recorded first so none goes unaudited
+func (p *InactivityPolicy) deactivate(ctx context.Context, emp models.Employee, actorID int64, actorName, details string, state map[string]*inactivityCounters) error {
+	entry := &models.AuditEntry{
+		Action:    models.AuditEmployeeDeactivated,
+		TargetID:  emp.ID,
+		ActorID:   actorID,
+		ActorName: actorName,
+		Details:   details,
+		At:        p.clock.Now(),
+	}
+	if err := p.audit.Record(ctx, entry); err != nil {
+		return fmt.Errorf("failed to audit deactivation: %w", err)
+	}
+	if err := p.employees.SetActive(ctx, emp.ID, false); err != nil {
+		return err
+	}
+	state[emp.ID] = &inactivityCounters{Deactivated: true}
+	return nil
+}
+
+// idle is how long the employee has not been seen; at least DeactivateAfter when not seen at all
+func (p *InactivityPolicy) idle(ie InactiveEmployee, now time.Time) time.Duration {
+	if ie.LastSeen.IsZero() {
+		return p.cfg.DeactivateAfter
+	}
+	return now.Sub(ie.LastSeen)
+}
+
+// load reads the counters; a missing value is an empty state
+func (p *InactivityPolicy) load(ctx context.Context) (map[string]*inactivityCounters, error) {
+	state := make(map[string]*inactivityCounters)
+	data, err := p.store.Get(ctx, inactivityStateKey)
+	if errors.Is(err, localstore.ErrNotFound) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inactivity state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse inactivity state: %w", err)
+	}
+	return state, nil
+}
+
+// save writes the counters, dropping employees with nothing to remember
+func (p *InactivityPolicy) save(ctx context.Context, state map[string]*inactivityCounters) error {
+	for id, c := range state {
+		if *c == (inactivityCounters{}) {
+			delete(state, id)
+		}
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := p.store.Put(ctx, inactivityStateKey, data); err != nil {
+		return fmt.Errorf("failed to save inactivity state: %w", err)
+	}
+	return nil
+}
+
+// counters returns the employee's counters, creating them if needed
+func counters(state map[string]*inactivityCounters, id string) *inactivityCounters {
+	if state[id] == nil {
+		state[id] = &inactivityCounters{}
+	}
+	return state[id]
+}
+
+// idleDays is the number of days the employee has not been seen, "N+" when not at all
+func idleDays(ie InactiveEmployee, cfg InactivityPolicyConfig, now time.Time) string {
+	if ie.LastSeen.IsZero() {
+		return fmt.Sprintf("%d+", days(cfg.DeactivateAfter))
+	}
+	return fmt.Sprint(days(now.Sub(ie.LastSeen)))
+}
+
+// days is a duration in whole days
+func days(d time.Duration) int {
+	return int(d.Hours() / 24)
+}
+
+func laterOf(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/localstore"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+func TestInactivityPolicy(t *testing.T) {
+	ctx := context.Background()
+	monday := time.Date(2026, 3, 2, 23, 30, 0, 0, time.Local)
+	clk := clock.NewFake(monday)
+	store := memory.NewStore(clk)
+
+	checkIn := func(emp models.Employee, daysAgo int) {
+		at := clk.Now().AddDate(0, 0, -daysAgo)
+		store.AttendanceRecords().Create(ctx, &models.Attendance{EmployeeID: emp.ID, CheckInTime: at, CreatedDate: at, Status: "on_time"})
+	}
+	detect := func(emp models.Employee, daysAgo int) {
+		store.DetectionRecords().Create(ctx, &models.EmployeeDetection{EmployeeID: emp.ID, DetectedAt: clk.Now().AddDate(0, 0, -daysAgo)})
+	}
+
+	somchai := store.AddEmployee(models.Employee{Name: "Somchai", EmployeeCode: "E1", IsActive: true})
+	checkIn(somchai, 3)
+	malee := store.AddEmployee(models.Employee{Name: "Malee", EmployeeCode: "E2", IsActive: true})
+	detect(malee, 10) // seen without checking in
+	contractor := store.AddEmployee(models.Employee{Name: "Contractor", EmployeeCode: "C1", IsActive: true})
+	checkIn(contractor, 40)
+	ghost := store.AddEmployee(models.Employee{Name: "Ghost", EmployeeCode: "C2", IsActive: true})
+	nid := store.AddEmployee(models.Employee{Name: "Nid", EmployeeCode: "E3", IsActive: true})
+	checkIn(nid, 35)
+	prasit := store.AddEmployee(models.Employee{Name: "Prasit", EmployeeCode: "E4", IsActive: true})
+	checkIn(prasit, 60)
+	store.AddEmployee(models.Employee{Name: "OnLeave", EmployeeCode: "E5", IsActive: true, LeaveUntil: monday.AddDate(0, 0, 30)})
+	store.AddEmployee(models.Employee{Name: "Back", EmployeeCode: "E6", IsActive: true, LeaveUntil: monday.AddDate(0, 0, -10)})
+
+	notifier := newRecordingPrompter()
+	policy, err := NewInactivityPolicy(DefaultInactivityPolicyConfig(), store.Employees(), store.AttendanceRecords(),
+		store.DetectionRecords(), store.AuditLog(), localstore.NewMemoryStore(), notifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy.SetClock(clk)
+
+	isActive := func(id string) bool {
+		employees, _ := store.Employees().ListActive(ctx)
+		for _, emp := range employees {
+			if emp.ID == id {
+				return true
+			}
+		}
+		return false
+	}
+	run := func(t *testing.T) {
+		t.Helper()
+		if err := policy.Run(ctx, clk.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("digest only on its weekday", func(t *testing.T) {
+		clk.Set(monday.AddDate(0, 0, -1))
+		run(t)
+		clk.Set(monday)
+		if len(notifier.prompts[0]) != 0 {
+			t.Fatal("sent a digest on Sunday")
+		}
+	})
+
+	t.Run("digest lists the inactive without deactivating", func(t *testing.T) {
+		run(t)
+		if len(notifier.prompts[0]) != 1 {
+			t.Fatalf("digests = %d, want 1", len(notifier.prompts[0]))
+		}
+		var listed []string
+		for _, b := range notifier.prompts[0][0] {
+			if strings.HasPrefix(b.Data, "idle:off:") {
+				listed = append(listed, strings.TrimPrefix(b.Data, "idle:off:"))
+			}
+		}
+		want := []string{ghost.ID, prasit.ID, contractor.ID, nid.ID} // longest idle first
+		if strings.Join(listed, ",") != strings.Join(want, ",") {
+			t.Fatalf("listed = %v, want %v", listed, want)
+		}
+		if digest := notifier.admin[0]; !strings.Contains(digest, "Ghost (C2) ไม่พบ 90+ วัน") || !strings.Contains(digest, "Contractor (C1) ไม่พบ 40 วัน") {
+			t.Errorf("digest = %q", digest)
+		}
+		if !isActive(ghost.ID) || len(store.AuditEntries()) != 0 {
+			t.Error("the digest changed something")
+		}
+	})
+
+	t.Run("buttons", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			data       string
+			before     func()
+			wantReply  string
+			wantActive bool
+			wantAudit  string
+		}{
+			{"keep", "idle:keep:" + contractor.ID, nil, "เก็บ Contractor ไว้แล้ว", true, models.AuditEmployeeKept},
+			{"deactivate", "idle:off:" + prasit.ID, nil, "ปิดใช้งาน Prasit แล้ว", false, models.AuditEmployeeDeactivated},
+			{"second press changes nothing", "idle:off:" + prasit.ID, nil, "ถูกปิดใช้งานแล้ว", false, ""},
+			{"seen again since the digest", "idle:off:" + nid.ID, func() { detect(nid, 0) }, "กลับมาแล้ว", true, ""},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if tt.before != nil {
+					tt.before()
+				}
+				audited := len(store.AuditEntries())
+				reply, err := policy.HandleCallback(ctx, tt.data, 42, "Admin")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(reply, tt.wantReply) {
+					t.Errorf("reply = %q, want %q", reply, tt.wantReply)
+				}
+				id := tt.data[strings.LastIndex(tt.data, ":")+1:]
+				if isActive(id) != tt.wantActive {
+					t.Errorf("active = %v, want %v", !tt.wantActive, tt.wantActive)
+				}
+				entries := store.AuditEntries()[audited:]
+				if tt.wantAudit == "" {
+					if len(entries) != 0 {
+						t.Errorf("audited %+v", entries)
+					}
+					return
+				}
+				if len(entries) != 1 || entries[0].Action != tt.wantAudit || entries[0].TargetID != id || entries[0].ActorID != 42 {
+					t.Errorf("audit = %+v, want one %s by 42", entries, tt.wantAudit)
+				}
+			})
+		}
+	})
+
+	t.Run("unanswered employee is deactivated after the grace period", func(t *testing.T) {
+		clk.Set(monday.AddDate(0, 0, 1))
+		run(t)
+		if !isActive(ghost.ID) {
+			t.Fatal("deactivated before the admin had a week to answer")
+		}
+
+		clk.Set(monday.AddDate(0, 0, 7))
+		audited := len(store.AuditEntries())
+		run(t)
+		if isActive(ghost.ID) {
+			t.Fatal("Ghost still active after the grace period")
+		}
+		entries := store.AuditEntries()[audited:]
+		if len(entries) != 1 || entries[0].Action != models.AuditEmployeeDeactivated || entries[0].ActorID != 0 || entries[0].ActorName != "system" {
+			t.Errorf("audit = %+v, want one deactivation by the system", entries)
+		}
+		if len(notifier.admin) < 2 || !strings.Contains(notifier.admin[1], "ปิดใช้งานอัตโนมัติ*: Ghost") {
+			t.Errorf("admin messages = %q, want the automatic deactivation", notifier.admin)
+		}
+		// Contractor was kept a week ago and is not asked about again yet
+		if len(notifier.prompts[0]) != 1 {
+			t.Errorf("digests = %d, want no new one", len(notifier.prompts[0]))
+		}
+		if !isActive(contractor.ID) {
+			t.Error("the kept contractor was deactivated")
+		}
+	})
+
+	t.Run("reactivation clears the counters", func(t *testing.T) {
+		store.Employees().SetActive(ctx, ghost.ID, true)
+		clk.Set(monday.AddDate(0, 0, 8))
+		run(t)
+
+		clk.Set(monday.AddDate(0, 0, 14))
+		run(t)
+		if !isActive(ghost.ID) {
+			t.Fatal("the reactivated employee was deactivated again")
+		}
+		if n := len(notifier.prompts[0]); n != 1 {
+			t.Errorf("digests = %d, want none while Ghost is newly reactivated; last = %q", n, notifier.admin[len(notifier.admin)-1])
+		}
+	})
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// LateApprovalCallbackPrefix routes the approve and reject buttons of late arrival requests
+const LateApprovalCallbackPrefix = "late"
+
+// lateApprovalMaxDaysAhead is how far ahead a late arrival can be requested
+const lateApprovalMaxDaysAhead = 60
+
+// Late approval request errors
+var (
+	ErrLateApprovalDate      = errors.New("date must be YYYY-MM-DD, today or within 60 days")
+	ErrLateApprovalTime      = errors.New("expected time must be HH:MM, after the work start")
+	ErrLateApprovalReason    = errors.New("a reason is required")
+	ErrLateApprovalDuplicate = errors.New("a late arrival for this day was already requested")
+)
+
+// LateApprovals lets employees ask in advance to arrive late on one day, e.g. after a
+// hospital appointment. An admin approves or rejects each request from the admin chat;
+// on that day a check-in by the approved time is ontime_approved instead of late and
+// raises no late alert. Requests nobody decided expire when their day has passed.
+type LateApprovals struct {
+	approvals repository.LateApprovalRepository
+	employees repository.EmployeeDirectory
+	notifier  AdminPromptNotifier
+	grace     time.Duration
+	clock     clock.Clock
+}
+
+// NewLateApprovals creates the late arrival approval service
+func NewLateApprovals(approvals repository.LateApprovalRepository, employees repository.EmployeeDirectory, notifier AdminPromptNotifier) *LateApprovals {
+	return &LateApprovals{approvals: approvals, employees: employees, notifier: notifier, grace: DefaultGracePeriod, clock: clock.Real{}}
+}
+
+// SetClock replaces the time source, used by tests
+func (l *LateApprovals) SetClock(c clock.Clock) {
+	l.clock = c
+}
+
+// SetGracePeriod sets the site's grace period, which a requested arrival must exceed
+func (l *LateApprovals) SetGracePeriod(d time.Duration) {
+	l.grace = d
+}
+
+// Request records the employee's request to arrive by expected (HH:MM) on date
+// (YYYY-MM-DD) and asks the admin chat to decide
+func (l *LateApprovals) Request(ctx context.Context, employeeID, date, expected, reason string) (*models.LateApproval, error) {
+	now := l.clock.Now()
+	day, err := time.ParseInLocation("2006-01-02", date, now.Location())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if err != nil || day.Before(today) || day.After(today.AddDate(0, 0, lateApprovalMaxDaysAhead)) {
+		return nil, ErrLateApprovalDate
+	}
+	emp, err := l.activeEmployee(ctx, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	at, err := time.Parse("15:04", expected)
+	if err != nil {
+		return nil, ErrLateApprovalTime
+	}
+	arrival := day.Add(time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute)
+	if emp.ShiftDay(arrival).Before(day) {
+		// After midnight of a night shift that started on day
+		arrival = arrival.AddDate(0, 0, 1)
+	}
+	if calculateStatus(arrival, day, emp.WorkStartTime, GracePeriod(emp, l.grace)) != models.AttendanceStatusLate {
+		return nil, ErrLateApprovalTime
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrLateApprovalReason
+	}
+
+	existing, err := l.approvals.ListByEmployeeDate(ctx, emp.ID, date)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range existing {
+		if a.Status == models.LateApprovalPending || a.Status == models.LateApprovalApproved {
+			return nil, ErrLateApprovalDuplicate
+		}
+	}
+
+	approval := &models.LateApproval{
+		EmployeeID:   emp.ID,
+		Date:         date,
+		ExpectedTime: at.Format("15:04"),
+		Reason:       reason,
+		Status:       models.LateApprovalPending,
+		RequestedAt:  now,
+	}
+	if err := l.approvals.Create(ctx, approval); err != nil {
+		return nil, err
+	}
+
+	log.Printf("🕘 %s requested to arrive by %s on %s", emp.Name, approval.ExpectedTime, date)
+	l.notifier.SendPrompt(fmt.Sprintf("🕘 *ขอเข้างานสายล่วงหน้า*\n👤 %s (%s)\n📅 %s ภายใน `%s`\n📝 %s",
+		markdown.Escape(emp.Name), markdown.Code(emp.EmployeeCode), day.Format("02/01/2006"), approval.ExpectedTime, markdown.Escape(reason)),
+		[]models.PromptButton{
+			{Label: "✅ อนุมัติ", Data: LateApprovalCallbackPrefix + ":approve:" + approval.ID},
+			{Label: "❌ ไม่อนุมัติ", Data: LateApprovalCallbackPrefix + ":reject:" + approval.ID},
+		})
+	return approval, nil
+}
+
+// Decide applies an approve or reject button press of an admin and tells the employee.
+// It returns the text that replaces the prompt.
+func (l *LateApprovals) Decide(ctx context.Context, data string, actorID int64, actorName string) (string, error) {
+	parts := strings.Split(data, ":")
+	if len(parts) != 3 || parts[0] != LateApprovalCallbackPrefix || (parts[1] != "approve" && parts[1] != "reject") {
+		return "", fmt.Errorf("invalid late approval callback %q", data)
+	}
+	approval, err := l.approvals.Get(ctx, parts[2])
+	if err != nil {
+		return "", err
+	}
+	if approval == nil {
+		return "", fmt.Errorf("late approval %s not found", parts[2])
+	}
+	if approval.Status != models.LateApprovalPending {
+		return fmt.Sprintf("ℹ️ คำขอนี้%sแล้ว", lateApprovalStatusText(approval.Status)), nil
+	}
+
+	now := l.clock.Now()
+	approval.Status = models.LateApprovalRejected
+	if parts[1] == "approve" {
+		approval.Status = models.LateApprovalApproved
+	}
+	if approval.Date < now.Format("2006-01-02") {
+		approval.Status = models.LateApprovalExpired
+	} else {
+		approval.DecidedBy, approval.DeciderName = actorID, actorName
+	}
+	approval.DecidedAt = now
+	if err := l.approvals.Update(ctx, approval); err != nil {
+		return "", err
+	}
+
+	emp, err := l.activeEmployee(ctx, approval.EmployeeID)
+	if err != nil {
+		log.Printf("Warning: late approval %s decided for an employee no longer active: %v", approval.ID, err)
+		emp = &models.Employee{Name: approval.EmployeeID}
+	}
+	log.Printf("🕘 Late arrival of %s on %s %s by %d", emp.Name, approval.Date, approval.Status, actorID)
+	if approval.Status == models.LateApprovalExpired {
+		return fmt.Sprintf("⌛ คำขอของ %s วันที่ %s หมดอายุแล้ว", markdown.Escape(emp.Name), approval.Date), nil
+	}
+
+	if emp.TelegramChatID != 0 {
+		l.notifier.SendPersonalNotification(emp.TelegramChatID, fmt.Sprintf("🕘 คำขอเข้างานภายใน `%s` วันที่ %s *%s*",
+			approval.ExpectedTime, approval.Date, lateApprovalStatusText(approval.Status)))
+	}
+	return fmt.Sprintf("🕘 %s วันที่ %s ภายใน `%s`: *%s*\nโดย: %s",
+		markdown.Escape(emp.Name), approval.Date, approval.ExpectedTime, lateApprovalStatusText(approval.Status), markdown.Escape(actorName)), nil
+}
+
+// ApprovedFor returns the employee's approved late arrival for the day, or nil
+func (l *LateApprovals) ApprovedFor(ctx context.Context, employeeID string, day time.Time) (*models.LateApproval, error) {
+	approvals, err := l.approvals.ListByEmployeeDate(ctx, employeeID, day.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range approvals {
+		if a.Status == models.LateApprovalApproved {
+			return &a, nil
+		}
+	}
+	return nil, nil
+}
+
+// Status is the check-in status of the employee at the given time, with grace the
+// site's grace period: a late check-in within the day's approved time, with the same
+// grace period, is ontime_approved.
+// When approvals cannot be read the check-in stays late. A nil LateApprovals only
+// compares with the work start.
+func (l *LateApprovals) Status(ctx context.Context, emp *models.Employee, at time.Time, grace time.Duration) string {
+	grace = GracePeriod(emp, grace)
+	day := emp.ShiftDay(at)
+	status := calculateStatus(at, day, emp.WorkStartTime, grace)
+	if l == nil || emp.IsSynthetic || status != models.AttendanceStatusLate {
+		return status
+	}
+	approval, err := l.ApprovedFor(ctx, emp.ID, day)
+	if err != nil {
+		log.Printf("Warning: failed to read late approvals of %s: %v", emp.Name, err)
+		return status
+	}
+	if approval != nil && calculateStatus(at, day, approval.ExpectedTime+":00", grace) != models.AttendanceStatusLate {
+		return models.AttendanceStatusOnTimeApproved
+	}
+	return status
+}
+
+// Expire marks requests nobody decided by the end of their day as expired; it is an EndOfDayTask
+func (l *LateApprovals) Expire(ctx context.Context, day time.Time) error {
+	pending, err := l.approvals.ListPending(ctx)
+	if err != nil {
+		return err
+	}
+	now, last := l.clock.Now(), day.Format("2006-01-02")
+	expired := 0
+	for _, a := range pending {
+		if a.Date > last {
+			continue
+		}
+		a.Status, a.DecidedAt = models.LateApprovalExpired, now
+		if err := l.approvals.Update(ctx, &a); err != nil {
+			return err
+		}
+		expired++
+	}
+	if expired > 0 {
+		log.Printf("🕘 %d late arrival requests expired undecided", expired)
+	}
+	return nil
+}
+
+// activeEmployee finds an active employee by ID
+func (l *LateApprovals) activeEmployee(ctx context.Context, id string) (*models.Employee, error) {
+	employees, err := l.employees.ListActive(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list employees: %w", err)
+	}
+	for _, emp := range employees {
+		if emp.ID == id {
+			return &emp, nil
+		}
+	}
+	return nil, fmt.Errorf("employee %s is not active", id)
+}
+
+// lateApprovalStatusText is how a request's status is shown
+func lateApprovalStatusText(status string) string {
+	switch status {
+	case models.LateApprovalApproved:
+		return "อนุมัติ"
+	case models.LateApprovalRejected:
+		return "ไม่อนุมัติ"
+	case models.LateApprovalExpired:
+		return "หมดอายุ"
+	}
+	return "รออนุมัติ"
+}
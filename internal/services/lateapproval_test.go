@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+func TestLateApprovalRequest(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		employeeID string
+		date       string
+		expected   string
+		reason     string
+		existing   string // status of an earlier request for the day, empty for none
+		wantErr    error
+	}{
+		{name: "tomorrow", employeeID: "e1", date: "2026-02-03", expected: "10:30", reason: "ไปพบแพทย์"},
+		{name: "today", employeeID: "e1", date: "2026-02-02", expected: "9:00", reason: "ไปพบแพทย์"},
+		{name: "after a rejected request", employeeID: "e1", date: "2026-02-03", expected: "10:30", reason: "ไปพบแพทย์", existing: models.LateApprovalRejected},
+		{name: "yesterday", employeeID: "e1", date: "2026-02-01", expected: "10:30", reason: "ไปพบแพทย์", wantErr: ErrLateApprovalDate},
+		{name: "too far ahead", employeeID: "e1", date: "2026-05-01", expected: "10:30", reason: "ไปพบแพทย์", wantErr: ErrLateApprovalDate},
+		{name: "not a date", employeeID: "e1", date: "03/02/2026", expected: "10:30", reason: "ไปพบแพทย์", wantErr: ErrLateApprovalDate},
+		{name: "within the grace period", employeeID: "e1", date: "2026-02-03", expected: "08:04", reason: "ไปพบแพทย์", wantErr: ErrLateApprovalTime},
+		{name: "not a time", employeeID: "e1", date: "2026-02-03", expected: "late", reason: "ไปพบแพทย์", wantErr: ErrLateApprovalTime},
+		{name: "no reason", employeeID: "e1", date: "2026-02-03", expected: "10:30", reason: " ", wantErr: ErrLateApprovalReason},
+		{name: "already pending", employeeID: "e1", date: "2026-02-03", expected: "10:30", reason: "ไปพบแพทย์", existing: models.LateApprovalPending, wantErr: ErrLateApprovalDuplicate},
+		{name: "already approved", employeeID: "e1", date: "2026-02-03", expected: "10:30", reason: "ไปพบแพทย์", existing: models.LateApprovalApproved, wantErr: ErrLateApprovalDuplicate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newPipelineStore()
+			if tt.existing != "" {
+				store.LateApprovals().Create(ctx, &models.LateApproval{EmployeeID: "e1", Date: tt.date, ExpectedTime: "09:00", Status: tt.existing})
+			}
+			notifier := newRecordingPrompter()
+			approvals := NewLateApprovals(store.LateApprovals(), store.Employees(), notifier)
+			approvals.SetClock(clock.NewFake(pipelineNow))
+
+			approval, err := approvals.Request(ctx, tt.employeeID, tt.date, tt.expected, tt.reason)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Request() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(notifier.admin) != 0 {
+					t.Errorf("admin prompts = %q, want none", notifier.admin)
+				}
+				return
+			}
+			if approval.Status != models.LateApprovalPending || approval.ID == "" {
+				t.Errorf("approval = %+v, want a stored pending request", approval)
+			}
+			buttons := notifier.prompts[0]
+			if len(buttons) != 1 || buttons[0][0].Data != "late:approve:"+approval.ID || buttons[0][1].Data != "late:reject:"+approval.ID {
+				t.Errorf("admin buttons = %+v", buttons)
+			}
+		})
+	}
+}
+
+func TestLateApprovalDecide(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		date       string
+		status     string
+		action     string
+		wantStatus string
+		wantNotice bool
+	}{
+		{"approve", "2026-02-03", models.LateApprovalPending, "approve", models.LateApprovalApproved, true},
+		{"reject", "2026-02-03", models.LateApprovalPending, "reject", models.LateApprovalRejected, true},
+		{"day has passed", "2026-02-01", models.LateApprovalPending, "approve", models.LateApprovalExpired, false},
+		{"already decided", "2026-02-03", models.LateApprovalRejected, "approve", models.LateApprovalRejected, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newPipelineStore()
+			approval := &models.LateApproval{EmployeeID: "e1", Date: tt.date, ExpectedTime: "10:30", Status: tt.status}
+			store.LateApprovals().Create(ctx, approval)
+			notifier := newRecordingPrompter()
+			approvals := NewLateApprovals(store.LateApprovals(), store.Employees(), notifier)
+			approvals.SetClock(clock.NewFake(pipelineNow))
+
+			reply, err := approvals.Decide(ctx, "late:"+tt.action+":"+approval.ID, 42, "Admin")
+			if err != nil || reply == "" {
+				t.Fatalf("Decide() = %q, %v", reply, err)
+			}
+			got, _ := store.LateApprovals().Get(ctx, approval.ID)
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", got.Status, tt.wantStatus)
+			}
+			if notified := len(notifier.personal[1001]) == 1; notified != tt.wantNotice {
+				t.Errorf("employee notified = %v, want %v", notified, tt.wantNotice)
+			}
+		})
+	}
+
+	t.Run("unknown request", func(t *testing.T) {
+		store := newPipelineStore()
+		approvals := NewLateApprovals(store.LateApprovals(), store.Employees(), newRecordingPrompter())
+		if _, err := approvals.Decide(ctx, "late:approve:missing", 42, "Admin"); err == nil {
+			t.Error("Decide() of an unknown request succeeded")
+		}
+	})
+}
+
+func TestLateApprovalCheckIn(t *testing.T) {
+	ctx := context.Background()
+	at := func(h, m int) time.Time { return time.Date(2026, 2, 2, h, m, 0, 0, time.Local) }
+
+	tests := []struct {
+		name       string
+		approval   string // status of a 10:30 request for the day, empty for none
+		date       string
+		checkIn    time.Time
+		wantStatus string
+		wantAdmin  int
+	}{
+		{"no request", "", "2026-02-02", at(10, 0), models.AttendanceStatusLate, 1},
+		{"approved, in time", models.LateApprovalApproved, "2026-02-02", at(10, 0), models.AttendanceStatusOnTimeApproved, 0},
+		{"approved, within grace", models.LateApprovalApproved, "2026-02-02", at(10, 34), models.AttendanceStatusOnTimeApproved, 0},
+		{"approved, later still", models.LateApprovalApproved, "2026-02-02", at(10, 40), models.AttendanceStatusLate, 1},
+		{"approved for another day", models.LateApprovalApproved, "2026-02-03", at(10, 0), models.AttendanceStatusLate, 1},
+		{"pending", models.LateApprovalPending, "2026-02-02", at(10, 0), models.AttendanceStatusLate, 1},
+		{"on time anyway", models.LateApprovalApproved, "2026-02-02", at(8, 0), models.AttendanceStatusOnTime, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newPipelineStore()
+			if tt.approval != "" {
+				store.LateApprovals().Create(ctx, &models.LateApproval{EmployeeID: "e1", Date: tt.date, ExpectedTime: "10:30", Status: tt.approval})
+			}
+			notifier := newRecordingPrompter()
+			approvals := NewLateApprovals(store.LateApprovals(), store.Employees(), notifier)
+			service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), notifier)
+			service.SetClock(clock.NewFake(tt.checkIn))
+			service.SetLateApprovals(approvals)
+
+			req := &models.DetectionRequest{ScannerMac: "scanner-1", MacAddress: "aa:bb:cc:dd:ee:01", RSSI: -50}
+			if err := service.ProcessDetection(ctx, req); err != nil {
+				t.Fatalf("ProcessDetection() error = %v", err)
+			}
+			records := store.Attendance()
+			if len(records) != 1 || records[0].Status != tt.wantStatus {
+				t.Fatalf("attendance = %+v, want one %s", records, tt.wantStatus)
+			}
+			if len(notifier.admin) != tt.wantAdmin {
+				t.Errorf("admin alerts = %q, want %d", notifier.admin, tt.wantAdmin)
+			}
+		})
+	}
+}
+
+func TestLateApprovalExpire(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore(clock.NewFake(pipelineNow))
+	repo := store.LateApprovals()
+	for _, a := range []models.LateApproval{
+		{EmployeeID: "e1", Date: "2026-02-01", ExpectedTime: "10:00", Status: models.LateApprovalPending},
+		{EmployeeID: "e1", Date: "2026-02-02", ExpectedTime: "10:00", Status: models.LateApprovalPending},
+		{EmployeeID: "e1", Date: "2026-02-03", ExpectedTime: "10:00", Status: models.LateApprovalPending},
+		{EmployeeID: "e2", Date: "2026-02-02", ExpectedTime: "10:00", Status: models.LateApprovalApproved},
+	} {
+		repo.Create(ctx, &a)
+	}
+	approvals := NewLateApprovals(repo, store.Employees(), newRecordingPrompter())
+	approvals.SetClock(clock.NewFake(pipelineNow))
+
+	if err := approvals.Expire(ctx, pipelineNow); err != nil {
+		t.Fatalf("Expire() error = %v", err)
+	}
+	pending, _ := repo.ListPending(ctx)
+	if len(pending) != 1 || pending[0].Date != "2026-02-03" {
+		t.Errorf("pending = %+v, want only tomorrow's request", pending)
+	}
+	if approved, _ := approvals.ApprovedFor(ctx, "e2", pipelineNow); approved == nil {
+		t.Error("an approved request expired")
+	}
+}
+
+func TestDailySummaryCountsApprovedLateArrivals(t *testing.T) {
+	ctx := context.Background()
+	store := newPipelineStore()
+	for _, status := range []string{models.AttendanceStatusOnTimeApproved, models.AttendanceStatusLate} {
+		store.AttendanceRecords().Create(ctx, &models.Attendance{EmployeeID: status, CheckInTime: pipelineNow, CreatedDate: pipelineNow, Status: status})
+	}
+
+	msg, err := NewDailySummary(store.Employees(), store.AttendanceRecords(), newRecordingNotifier()).Build(ctx, pipelineNow)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	for _, want := range []string{"เข้าสาย: 1", "เข้าสายที่อนุมัติล่วงหน้า: 1"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("summary missing %q:\n%s", want, msg)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// LegacyRow is one line of the fingerprint machine export: employee code, date, in, out
+type LegacyRow struct {
+	Line         int
+	EmployeeCode string
+	Date         string
+	In           string
+	Out          string // may be empty
+}
+
+// ParseLegacyCSV reads a fingerprint machine export. A header line is skipped, and
+// rows are returned as-is; dates and times are only validated by the importer.
+func ParseLegacyCSV(r io.Reader) ([]LegacyRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var rows []LegacyRow
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if line == 1 && len(record) > 0 {
+			record[0] = strings.TrimPrefix(record[0], "\uFEFF")
+			if len(record) > 1 {
+				if _, err := parseLegacyDate(record[1], time.UTC); err != nil {
+					continue // header
+				}
+			}
+		}
+
+		row := LegacyRow{Line: line}
+		fields := []*string{&row.EmployeeCode, &row.Date, &row.In, &row.Out}
+		for i := 0; i < len(record) && i < len(fields); i++ {
+			*fields[i] = strings.TrimSpace(record[i])
+		}
+		if row.EmployeeCode == "" && row.Date == "" && row.In == "" {
+			continue // blank line
+		}
+		rows = append(rows, row)
+	}
+}
+
+// legacyDateLayouts are the date notations seen in fingerprint exports
+var legacyDateLayouts = []string{"2006-01-02", "02/01/2006", "2/1/2006", "02-01-2006"}
+
+// parseLegacyDate parses a date in loc; Buddhist Era years (e.g. 2569) are converted
+func parseLegacyDate(value string, loc *time.Location) (time.Time, error) {
+	for _, layout := range legacyDateLayouts {
+		t, err := time.ParseInLocation(layout, value, loc)
+		if err != nil {
+			continue
+		}
+		if t.Year() > 2400 {
+			t = t.AddDate(-543, 0, 0)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// parseLegacyClock returns the time of day on date
+func parseLegacyClock(date time.Time, value string) (time.Time, error) {
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), t.Second(), 0, date.Location()), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
+
+// ImportOutcome is what the importer did with one row
+type ImportOutcome string
+
+// Import outcomes
+const (
+	ImportCreated         ImportOutcome = "imported"
+	ImportWouldCreate     ImportOutcome = "would_import" // dry run
+	ImportCollision       ImportOutcome = "collision"
+	ImportUnknownEmployee ImportOutcome = "unknown_employee"
+	ImportInvalid         ImportOutcome = "invalid"
+	ImportFailed          ImportOutcome = "failed"
+	ImportLocked          ImportOutcome = "locked" // the day is in a locked payroll period
+)
+
+// ImportResult is the outcome of one row
+type ImportResult struct {
+	Row     LegacyRow
+	Outcome ImportOutcome
+	Detail  string
+}
+
+// ImportReport reconciles every input row with what happened to it
+type ImportReport struct {
+	DryRun  bool
+	Results []ImportResult
+}
+
+// Count returns the number of rows with the given outcome
+func (r *ImportReport) Count(outcome ImportOutcome) int {
+	n := 0
+	for _, res := range r.Results {
+		if res.Outcome == outcome {
+			n++
+		}
+	}
+	return n
+}
+
+// Unknown returns the rows whose employee code matched no employee
+func (r *ImportReport) Unknown() []LegacyRow {
+	var rows []LegacyRow
+	for _, res := range r.Results {
+		if res.Outcome == ImportUnknownEmployee {
+			rows = append(rows, res.Row)
+		}
+	}
+	return rows
+}
+
+// Write prints the per-row reconciliation followed by totals
+func (r *ImportReport) Write(w io.Writer) {
+	for _, res := range r.Results {
+		fmt.Fprintf(w, "line %-5d %-8s %-10s %-16s %s\n",
+			res.Row.Line, res.Row.EmployeeCode, res.Row.Date, res.Outcome, res.Detail)
+	}
+
+	created := ImportCreated
+	if r.DryRun {
+		created = ImportWouldCreate
+	}
+	fmt.Fprintf(w, "\n%d rows: %d %s, %d collisions, %d unknown employee, %d invalid, %d locked, %d failed\n",
+		len(r.Results), r.Count(created), created, r.Count(ImportCollision),
+		r.Count(ImportUnknownEmployee), r.Count(ImportInvalid), r.Count(ImportLocked), r.Count(ImportFailed))
+}
+
+// ImportAttendanceStore is the attendance storage the legacy importer reads and writes
+type ImportAttendanceStore interface {
+	repository.AttendanceRepository
+	repository.AttendanceLog
+}
+
+// LegacyImporter imports fingerprint machine attendance history
+type LegacyImporter struct {
+	employees  repository.EmployeeDirectory
+	attendance ImportAttendanceStore
+	loc        *time.Location
+	grace      time.Duration
+}
+
+// NewLegacyImporter creates an importer interpreting times in loc
+func NewLegacyImporter(employees repository.EmployeeDirectory, attendance ImportAttendanceStore, loc *time.Location) *LegacyImporter {
+	return &LegacyImporter{employees: employees, attendance: attendance, loc: loc, grace: DefaultGracePeriod}
+}
+
+// SetGracePeriod sets the grace period of employees without their own, e.g. the
+// configured GRACE_PERIOD_MINUTES
+func (im *LegacyImporter) SetGracePeriod(d time.Duration) {
+	im.grace = d
+}
+
+// Import maps rows to employees by code and creates attendance records with source=import.
+// Rows for a day the employee already has a record for are skipped as collisions. With
+// dryRun nothing is written.
+func (im *LegacyImporter) Import(ctx context.Context, rows []LegacyRow, dryRun bool) (*ImportReport, error) {
+	employees, err := im.employees.ListActive(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list employees: %w", err)
+	}
+	byCode := make(map[string]models.Employee)
+	for _, emp := range employees {
+		if emp.EmployeeCode != "" {
+			byCode[strings.ToLower(emp.EmployeeCode)] = emp
+		}
+	}
+
+	report := &ImportReport{DryRun: dryRun}
+	type pending struct {
+		row        LegacyRow
+		attendance *models.Attendance
+	}
+	var valid []pending
+	var earliest time.Time
+	for _, row := range rows {
+		emp, ok := byCode[strings.ToLower(row.EmployeeCode)]
+		if !ok {
+			report.Results = append(report.Results, ImportResult{Row: row, Outcome: ImportUnknownEmployee})
+			continue
+		}
+		attendance, err := im.toAttendance(row, emp)
+		if err != nil {
+			report.Results = append(report.Results, ImportResult{Row: row, Outcome: ImportInvalid, Detail: err.Error()})
+			continue
+		}
+		valid = append(valid, pending{row: row, attendance: attendance})
+		if earliest.IsZero() || attendance.CreatedDate.Before(earliest) {
+			earliest = attendance.CreatedDate
+		}
+	}
+
+	taken := make(map[string]string) // employee|date -> what holds it
+	if len(valid) > 0 {
+		existing, err := im.attendance.ListSince(ctx, earliest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list existing attendance: %w", err)
+		}
+		for _, a := range existing {
+			taken[a.EmployeeID+"|"+a.CreatedDate.Format("2006-01-02")] = "existing record " + a.ID
+		}
+	}
+
+	for _, p := range valid {
+		key := p.attendance.EmployeeID + "|" + p.attendance.CreatedDate.Format("2006-01-02")
+		if holder, ok := taken[key]; ok {
+			report.Results = append(report.Results, ImportResult{Row: p.row, Outcome: ImportCollision, Detail: holder})
+			continue
+		}
+		taken[key] = fmt.Sprintf("line %d of this file", p.row.Line)
+
+		if dryRun {
+			report.Results = append(report.Results, ImportResult{Row: p.row, Outcome: ImportWouldCreate, Detail: p.attendance.Status})
+			continue
+		}
+		if err := im.attendance.Create(ctx, p.attendance); err != nil {
+			outcome := ImportFailed
+			switch {
+			case errors.Is(err, repository.ErrPeriodLocked):
+				outcome = ImportLocked
+			case errors.Is(err, repository.ErrAttendanceExists):
+				outcome = ImportCollision
+			}
+			log.Printf("❌ Import of line %d failed: %v", p.row.Line, err)
+			report.Results = append(report.Results, ImportResult{Row: p.row, Outcome: outcome, Detail: err.Error()})
+			continue
+		}
+		report.Results = append(report.Results, ImportResult{Row: p.row, Outcome: ImportCreated, Detail: p.attendance.Status})
+	}
+
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		return report.Results[i].Row.Line < report.Results[j].Row.Line
+	})
+	return report, nil
+}
+
+// toAttendance converts a row for emp into an attendance record
+func (im *LegacyImporter) toAttendance(row LegacyRow, emp models.Employee) (*models.Attendance, error) {
+	date, err := parseLegacyDate(row.Date, im.loc)
+	if err != nil {
+		return nil, err
+	}
+	if row.In == "" {
+		return nil, errors.New("missing in time")
+	}
+	in, err := parseLegacyClock(date, row.In)
+	if err != nil {
+		return nil, err
+	}
+
+	attendance := &models.Attendance{
+		EmployeeID:  emp.ID,
+		CheckInTime: in,
+		Status:      calculateStatus(in, date, emp.WorkStartTime, GracePeriod(&emp, im.grace)),
+		CreatedDate: date,
+		Source:      models.AttendanceSourceImport,
+	}
+	attendance.SnapshotEmployee(&emp)
+	if row.Out != "" {
+		out, err := parseLegacyClock(date, row.Out)
+		if err != nil {
+			return nil, err
+		}
+		if out.Before(in) {
+			return nil, fmt.Errorf("out %s before in %s", row.Out, row.In)
+		}
+		attendance.CheckOutTime = out
+		attendance.CheckOutSource = models.CheckOutSourceImport
+	}
+	return attendance, nil
+}
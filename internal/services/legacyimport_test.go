@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+const legacyExport = "\uFEFFcode,date,in,out\n" +
+	"E001,2026-02-02,07:55,17:01\n" +
+	"E001,03/02/2026,08:20:30,\n" +
+	"E002,2026-02-02,08:00,17:00\n" +
+	"E999,2026-02-02,08:00,17:00\n" +
+	"E001,2026-02-02,08:10,17:00\n" +
+	"E001,04/02/2569,08:00,07:00\n" +
+	"e001,05/02/2569,08:00,\n" +
+	"\n"
+
+func TestParseLegacyCSV(t *testing.T) {
+	rows, err := ParseLegacyCSV(strings.NewReader(legacyExport))
+	if err != nil {
+		t.Fatalf("ParseLegacyCSV: %v", err)
+	}
+	if len(rows) != 7 {
+		t.Fatalf("got %d rows, want 7 (header and blank line skipped)", len(rows))
+	}
+	if got := rows[0]; got.Line != 2 || got.EmployeeCode != "E001" || got.Out != "17:01" {
+		t.Errorf("first row = %+v", got)
+	}
+}
+
+func TestLegacyImporter(t *testing.T) {
+	bangkok := time.FixedZone("ICT", 7*3600)
+	rows, err := ParseLegacyCSV(strings.NewReader(legacyExport))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		dryRun      bool
+		wantOutcome map[int]ImportOutcome // by line
+		wantCreated int
+	}{
+		{
+			name:   "dry run writes nothing",
+			dryRun: true,
+			wantOutcome: map[int]ImportOutcome{
+				2: ImportWouldCreate, 3: ImportWouldCreate, 4: ImportCollision, 5: ImportUnknownEmployee,
+				6: ImportCollision, 7: ImportInvalid, 8: ImportWouldCreate,
+			},
+		},
+		{
+			name:   "import",
+			dryRun: false,
+			wantOutcome: map[int]ImportOutcome{
+				2: ImportCreated, 3: ImportCreated, 4: ImportCollision, 5: ImportUnknownEmployee,
+				6: ImportCollision, 7: ImportInvalid, 8: ImportCreated,
+			},
+			wantCreated: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memory.NewStore(clock.NewFake(time.Date(2026, 3, 1, 9, 0, 0, 0, bangkok)))
+			e1 := store.AddEmployee(models.Employee{EmployeeCode: "E001", Name: "Somchai", WorkStartTime: "08:00:00", IsActive: true})
+			e2 := store.AddEmployee(models.Employee{EmployeeCode: "E002", Name: "Suda", WorkStartTime: "08:00:00", IsActive: true})
+			existing := time.Date(2026, 2, 2, 8, 1, 0, 0, bangkok)
+			store.AttendanceRecords().Create(context.Background(), &models.Attendance{
+				EmployeeID: e2.ID, CheckInTime: existing, CreatedDate: existing, Status: "ontime", Source: models.AttendanceSourceScanner,
+			})
+
+			importer := NewLegacyImporter(store.Employees(), store.AttendanceRecords(), bangkok)
+			report, err := importer.Import(context.Background(), rows, tt.dryRun)
+			if err != nil {
+				t.Fatalf("Import: %v", err)
+			}
+
+			for _, res := range report.Results {
+				if want := tt.wantOutcome[res.Row.Line]; res.Outcome != want {
+					t.Errorf("line %d: outcome %s (%s), want %s", res.Row.Line, res.Outcome, res.Detail, want)
+				}
+			}
+			if unknown := report.Unknown(); len(unknown) != 1 || unknown[0].EmployeeCode != "E999" {
+				t.Errorf("Unknown = %+v", unknown)
+			}
+
+			var imported []models.Attendance
+			for _, a := range store.Attendance() {
+				if a.Source == models.AttendanceSourceImport {
+					imported = append(imported, a)
+				}
+			}
+			if len(imported) != tt.wantCreated {
+				t.Fatalf("imported %d records, want %d", len(imported), tt.wantCreated)
+			}
+			if tt.wantCreated == 0 {
+				return
+			}
+
+			first := imported[0]
+			if first.EmployeeID != e1.ID || !first.CheckInTime.Equal(time.Date(2026, 2, 2, 7, 55, 0, 0, bangkok)) {
+				t.Errorf("first import = %+v", first)
+			}
+			if !first.CheckOutTime.Equal(time.Date(2026, 2, 2, 17, 1, 0, 0, bangkok)) || first.Status != "ontime" {
+				t.Errorf("first import out/status = %v %s", first.CheckOutTime, first.Status)
+			}
+			if imported[1].Status != "late" || !imported[1].CheckOutTime.IsZero() {
+				t.Errorf("second import = %+v, want late without check-out", imported[1])
+			}
+			if got := imported[2].CreatedDate.Format("2006-01-02"); got != "2026-02-05" {
+				t.Errorf("Buddhist Era date imported as %s, want 2026-02-05", got)
+			}
+		})
+	}
+}
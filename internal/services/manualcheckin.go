@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// Manual check-in errors
+var (
+	ErrEmployeeNotFound = errors.New("no active employee with this code")
+	ErrAlreadyCheckedIn = errors.New("the employee already checked in today")
+	ErrCheckInInFuture  = errors.New("the check-in time is in the future")
+	ErrChatNotEmployee  = errors.New("no active employee is registered to this chat")
+	ErrNotCheckedIn     = errors.New("the employee has not checked in today")
+)
+
+// ManualCheckInStore is the attendance storage manual check-ins and check-outs write to
+type ManualCheckInStore interface {
+	repository.AttendanceRepository
+	repository.AttendanceLog
+	repository.AttendanceUpdater
+}
+
+// ManualCheckInEmployees finds employees by code and tells whether they checked in today
+type ManualCheckInEmployees interface {
+	repository.EmployeeRepository
+	repository.EmployeeDirectory
+}
+
+// ManualCheckIns records check-ins an admin enters for employees whose tag was not
+// detected, and the ones employees enter themselves with /checkin and /checkout, with
+// source=manual and the usual on-time/late status
+type ManualCheckIns struct {
+	employees  ManualCheckInEmployees
+	attendance ManualCheckInStore
+	notifier   BotNotifier
+	late       *LateApprovals
+	grace      time.Duration
+	clock      clock.Clock
+}
+
+// NewManualCheckIns creates the manual check-in service
+func NewManualCheckIns(employees ManualCheckInEmployees, attendance ManualCheckInStore, notifier BotNotifier) *ManualCheckIns {
+	return &ManualCheckIns{employees: employees, attendance: attendance, notifier: notifier, grace: DefaultGracePeriod, clock: clock.Real{}}
+}
+
+// SetClock replaces the time source, used by tests
+func (m *ManualCheckIns) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
+// SetLateApprovals makes manual check-ins within an approved late arrival ontime_approved
+func (m *ManualCheckIns) SetLateApprovals(l *LateApprovals) {
+	m.late = l
+}
+
+// SetGracePeriod sets the site's grace period for employees without their own
+func (m *ManualCheckIns) SetGracePeriod(d time.Duration) {
+	m.grace = d
+}
+
+// Find returns the active employee with the code, matched case-insensitively; the
+// self-test employee is never found
+func (m *ManualCheckIns) Find(ctx context.Context, employeeCode string) (*models.Employee, error) {
+	code := strings.TrimSpace(employeeCode)
+	if code == "" {
+		return nil, ErrEmployeeNotFound
+	}
+	employees, err := m.employees.ListActive(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list employees: %w", err)
+	}
+	for _, emp := range employees {
+		if strings.EqualFold(emp.EmployeeCode, code) {
+			return &emp, nil
+		}
+	}
+	return nil, ErrEmployeeNotFound
+}
+
+// CheckIn records the employee's check-in today at hour:minute and tells the employee
+func (m *ManualCheckIns) CheckIn(ctx context.Context, employeeCode string, hour, minute int) (*models.Employee, *models.Attendance, error) {
+	emp, err := m.Find(ctx, employeeCode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := m.clock.Now()
+	at := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+	if at.After(now) {
+		return nil, nil, ErrCheckInInFuture
+	}
+	attendance, err := m.record(ctx, emp, at)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Printf("✍️ Manual check-in for %s at %s (Status: %s)", emp.Name, at.Format("15:04"), attendance.Status)
+
+	if emp.TelegramChatID != 0 && !emp.NotificationMuted(models.NotificationCheckIn) {
+		status := "เข้างานตรงเวลา"
+		if attendance.Status == models.AttendanceStatusOnTimeApproved {
+			status = onTimeApprovedText
+		}
+		if attendance.Status == "late" {
+			status = calculateLateStatus(models.LanguageThai, at, attendance.CreatedDate, emp.WorkStartTime)
+		}
+		m.notifier.SendPersonalNotification(emp.TelegramChatID, fmt.Sprintf(
+			"✍️ *ผู้ดูแลบันทึกเวลาเข้างานให้คุณ*\n\n🕐 เวลาเข้างาน: `%s`\n⏰ สถานะ: *%s*", at.Format("15:04"), status))
+	}
+	return emp, attendance, nil
+}
+
+// SelfCheckIn records a check-in now for the employee registered to the chat, e.g. when
+// their tag's battery is dead, and tells the admins
+func (m *ManualCheckIns) SelfCheckIn(ctx context.Context, chatID int64) (*models.Employee, *models.Attendance, error) {
+	emp, err := m.byChat(ctx, chatID)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := m.clock.Now()
+	attendance, err := m.record(ctx, emp, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Printf("✍️ Self check-in for %s at %s (Status: %s)", emp.Name, now.Format("15:04"), attendance.Status)
+	m.notifyAdmins(emp, "/checkin", now)
+	return emp, attendance, nil
+}
+
+// SelfCheckOut records now as the check-out of the current shift of the employee
+// registered to the chat, and tells the admins
+func (m *ManualCheckIns) SelfCheckOut(ctx context.Context, chatID int64) (*models.Employee, *models.Attendance, error) {
+	emp, err := m.byChat(ctx, chatID)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := m.clock.Now()
+	records, err := m.attendance.ListByDate(ctx, emp.ShiftDay(now))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read today's attendance: %w", err)
+	}
+	var attendance *models.Attendance
+	for i := range records {
+		if records[i].EmployeeID == emp.ID {
+			attendance = &records[i]
+			break
+		}
+	}
+	if attendance == nil {
+		return nil, nil, ErrNotCheckedIn
+	}
+
+	attendance.CheckOutTime = now
+	attendance.CheckOutSource = models.CheckOutSourceManual
+	if err := m.attendance.UpdateCheckOut(ctx, attendance); err != nil {
+		return nil, nil, fmt.Errorf("failed to record check-out: %w", err)
+	}
+	log.Printf("✍️ Self check-out for %s at %s", emp.Name, now.Format("15:04"))
+	m.notifyAdmins(emp, "/checkout", now)
+	return emp, attendance, nil
+}
+
+// record creates the employee's manual check-in at the given time, unless they already
+// checked in for that shift
+func (m *ManualCheckIns) record(ctx context.Context, emp *models.Employee, at time.Time) (*models.Attendance, error) {
+	checkedIn, err := m.employees.IsCheckedInOn(ctx, emp.ID, emp.ShiftDay(at))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check today's attendance: %w", err)
+	}
+	if checkedIn {
+		return nil, ErrAlreadyCheckedIn
+	}
+
+	attendance := &models.Attendance{
+		EmployeeID:  emp.ID,
+		CheckInTime: at,
+		Status:      m.late.Status(ctx, emp, at, m.grace),
+		CreatedDate: emp.ShiftDay(at),
+		Source:      models.AttendanceSourceManual,
+	}
+	attendance.SnapshotEmployee(emp)
+	if err := m.attendance.Create(ctx, attendance); errors.Is(err, repository.ErrAttendanceExists) {
+		return nil, ErrAlreadyCheckedIn
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to record attendance: %w", err)
+	}
+	return attendance, nil
+}
+
+// byChat returns the active employee registered to the chat
+func (m *ManualCheckIns) byChat(ctx context.Context, chatID int64) (*models.Employee, error) {
+	employees, err := m.employees.ListActive(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list employees: %w", err)
+	}
+	for _, emp := range employees {
+		if emp.TelegramChatID == chatID && !emp.IsGuest {
+			return &emp, nil
+		}
+	}
+	return nil, ErrChatNotEmployee
+}
+
+// notifyAdmins tells the admin chat that an employee entered their own time
+func (m *ManualCheckIns) notifyAdmins(emp *models.Employee, command string, at time.Time) {
+	m.notifier.SendNotification(fmt.Sprintf("✍️ *บันทึกเวลาด้วยตนเอง*\n\nพนักงาน: %s (%s)\nคำสั่ง: `%s`\nเวลา: `%s`",
+		markdown.Escape(emp.Name), markdown.Escape(emp.EmployeeCode), command, at.Format("02/01/2006 15:04")))
+}
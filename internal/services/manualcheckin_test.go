@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+func TestManualCheckIn(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 2, 2, 10, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name         string
+		code         string
+		hour, minute int
+		checkedIn    bool
+		wantErr      error
+		wantStatus   string
+	}{
+		{name: "on time", code: "E001", hour: 8, minute: 0, wantStatus: "ontime"},
+		{name: "late, code in other case", code: "e001", hour: 8, minute: 30, wantStatus: "late"},
+		{name: "unknown code", code: "E999", hour: 8, wantErr: ErrEmployeeNotFound},
+		{name: "synthetic employee", code: "SELFTEST", hour: 8, wantErr: ErrEmployeeNotFound},
+		{name: "in the future", code: "E001", hour: 10, minute: 5, wantErr: ErrCheckInInFuture},
+		{name: "already checked in", code: "E001", hour: 8, checkedIn: true, wantErr: ErrAlreadyCheckedIn},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memory.NewStore(clock.NewFake(now))
+			emp := store.AddEmployee(models.Employee{Name: "Somchai", EmployeeCode: "E001", TelegramChatID: 1001,
+				WorkStartTime: "08:00:00", IsActive: true})
+			store.AddEmployee(models.Employee{Name: "Self-test", EmployeeCode: "SELFTEST", IsActive: true, IsSynthetic: true})
+			if tt.checkedIn {
+				store.AttendanceRecords().Create(ctx, &models.Attendance{EmployeeID: emp.ID, CheckInTime: now, CreatedDate: now})
+			}
+			notifier := newRecordingNotifier()
+			checkIns := NewManualCheckIns(store.Employees(), store.AttendanceRecords(), notifier)
+			checkIns.SetClock(clock.NewFake(now))
+
+			_, att, err := checkIns.CheckIn(ctx, tt.code, tt.hour, tt.minute)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CheckIn error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if att.Status != tt.wantStatus || att.Source != models.AttendanceSourceManual ||
+				att.CheckInTime.Hour() != tt.hour || att.CheckInTime.Minute() != tt.minute {
+				t.Errorf("got %+v, want %02d:%02d %s from manual", att, tt.hour, tt.minute, tt.wantStatus)
+			}
+			if n := len(store.Attendance()); n != 1 {
+				t.Errorf("got %d attendance records, want 1", n)
+			}
+			if len(notifier.personal[1001]) != 1 {
+				t.Errorf("employee messages = %q, want one", notifier.personal[1001])
+			}
+		})
+	}
+}
+
+func TestSelfCheckInAndOut(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2026, 2, 2, 7, 50, 0, 0, time.Local))
+	store := memory.NewStore(clk)
+	store.AddEmployee(models.Employee{Name: "Somchai", EmployeeCode: "E001", TelegramChatID: 1001,
+		WorkStartTime: "08:00:00", IsActive: true})
+	notifier := newRecordingNotifier()
+	checkIns := NewManualCheckIns(store.Employees(), store.AttendanceRecords(), notifier)
+	checkIns.SetClock(clk)
+
+	steps := []struct {
+		name    string
+		at      string // HH:MM on 2026-02-02
+		chatID  int64
+		out     bool
+		wantErr error
+	}{
+		{"check-out before checking in", "07:50", 1001, true, ErrNotCheckedIn},
+		{"unregistered chat", "07:50", 2002, false, ErrChatNotEmployee},
+		{"check-in", "08:20", 1001, false, nil},
+		{"second check-in", "08:30", 1001, false, ErrAlreadyCheckedIn},
+		{"check-out", "17:30", 1001, true, nil},
+	}
+	for _, st := range steps {
+		t.Run(st.name, func(t *testing.T) {
+			at, _ := time.ParseInLocation("2006-01-02 15:04", "2026-02-02 "+st.at, time.Local)
+			clk.Set(at)
+			var err error
+			if st.out {
+				_, _, err = checkIns.SelfCheckOut(ctx, st.chatID)
+			} else {
+				_, _, err = checkIns.SelfCheckIn(ctx, st.chatID)
+			}
+			if !errors.Is(err, st.wantErr) {
+				t.Fatalf("error = %v, want %v", err, st.wantErr)
+			}
+		})
+	}
+
+	att := store.Attendance()
+	if len(att) != 1 {
+		t.Fatalf("attendance = %+v, want one record", att)
+	}
+	if a := att[0]; a.Status != models.AttendanceStatusLate || a.Source != models.AttendanceSourceManual ||
+		a.CheckInTime.Format("15:04") != "08:20" || a.CheckOutTime.Format("15:04") != "17:30" || a.CheckOutSource != models.CheckOutSourceManual {
+		t.Errorf("attendance = %+v, want a late manual 08:20-17:30 record", a)
+	}
+	if len(notifier.admin) != 2 {
+		t.Errorf("admin notifications = %q, want one per entry", notifier.admin)
+	}
+}
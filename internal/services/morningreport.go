@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// MorningReportLimit is the most characters of one morning report message. Telegram
+// takes 4096 UTF-16 units; the margin covers the emoji, which take two.
+const MorningReportLimit = 3500
+
+// noDepartment is the heading of employees without a department
+const noDepartment = "ไม่ระบุแผนก"
+
+// MorningReport sends the admin chat who checked in on time, who is late and who has not
+// been seen yet, by department, once a morning (MORNING_REPORT_TIME)
+type MorningReport struct {
+	employees  repository.EmployeeDirectory
+	attendance repository.AttendanceLog
+	calendar   *WorkCalendar
+	notifier   BotNotifier
+	grace      time.Duration
+	limit      int
+}
+
+// NewMorningReport creates the report; with a calendar it is skipped on weekends and holidays
+func NewMorningReport(employees repository.EmployeeDirectory, attendance repository.AttendanceLog, calendar *WorkCalendar, notifier BotNotifier) *MorningReport {
+	return &MorningReport{employees: employees, attendance: attendance, calendar: calendar, notifier: notifier, grace: DefaultGracePeriod, limit: MorningReportLimit}
+}
+
+// SetGracePeriod sets the site's grace period, after which an employee not seen yet is listed
+func (r *MorningReport) SetGracePeriod(d time.Duration) {
+	r.grace = d
+}
+
+// Send builds the report as of at and sends it to the admin chat, in as many messages as
+// it takes; it is an EndOfDayTask
+func (r *MorningReport) Send(ctx context.Context, at time.Time) error {
+	messages, err := r.Build(ctx, at)
+	if err != nil {
+		return err
+	}
+	for _, message := range messages {
+		r.notifier.SendNotification(message)
+	}
+	return nil
+}
+
+// morningGroup is one department of the morning report
+type morningGroup struct {
+	onTime, late, missing []string
+}
+
+// Build renders the report as of at, split into messages of at most MorningReportLimit
+// characters. It returns no messages on a day off.
+func (r *MorningReport) Build(ctx context.Context, at time.Time) ([]string, error) {
+	if r.calendar != nil {
+		working, err := r.calendar.IsWorkingDay(ctx, at)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the work calendar: %w", err)
+		}
+		if !working {
+			log.Printf("☀️ Morning report skipped: %s is not a working day", at.Format("2006-01-02"))
+			return nil, nil
+		}
+	}
+	employees, err := r.employees.ListActive(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list employees: %w", err)
+	}
+	records, err := r.attendance.ListByDate(ctx, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attendance: %w", err)
+	}
+	first := make(map[string]models.Attendance)
+	for _, a := range records {
+		if _, ok := first[a.EmployeeID]; ok || a.Source == models.AttendanceSourceSelfTest || a.Status == models.AttendanceStatusGuest {
+			continue
+		}
+		first[a.EmployeeID] = a
+	}
+
+	groups := make(map[string]*morningGroup)
+	onTime, late, missing, onLeave := 0, 0, 0, 0
+	sort.Slice(employees, func(i, j int) bool { return employees[i].Name < employees[j].Name })
+	for i := range employees {
+		emp := &employees[i]
+		if emp.IsGuest || emp.IsSynthetic {
+			continue
+		}
+		dept := emp.Department
+		if dept == "" {
+			dept = noDepartment
+		}
+		g := groups[dept]
+		if g == nil {
+			g = &morningGroup{}
+			groups[dept] = g
+		}
+
+		a, ok := first[emp.ID]
+		switch {
+		case ok && a.Status == models.AttendanceStatusLate:
+			checkIn := a.CheckInTime.In(at.Location())
+			created := a.CreatedDate.In(at.Location())
+			day := time.Date(created.Year(), created.Month(), created.Day(), 0, 0, 0, 0, at.Location())
+			g.late = append(g.late, fmt.Sprintf("⚠️ %s %s (สาย %d นาที)", markdown.Escape(emp.Name), checkIn.Format("15:04"), lateMinutes(checkIn, day, emp.WorkStartTime)))
+			late++
+		case ok:
+			g.onTime = append(g.onTime, fmt.Sprintf("✅ %s %s", markdown.Escape(emp.Name), a.CheckInTime.In(at.Location()).Format("15:04")))
+			onTime++
+		case emp.OnLeave(at):
+			onLeave++
+		case shiftStarted(emp, at, r.grace):
+			g.missing = append(g.missing, "❓ "+markdown.Escape(emp.Name))
+			missing++
+		}
+	}
+
+	header := fmt.Sprintf("☀️ *รายงานการเข้างานเช้า* %s\n✅ ตรงเวลา: %d คน\n⚠️ สาย: %d คน\n❓ ยังไม่พบ: %d คน\n",
+		at.Format("02/01/2006 15:04"), onTime, late, missing)
+	if onLeave > 0 {
+		header += fmt.Sprintf("🌴 ลา: %d คน\n", onLeave)
+	}
+
+	depts := make([]string, 0, len(groups))
+	for dept, g := range groups {
+		if len(g.onTime)+len(g.late)+len(g.missing) > 0 {
+			depts = append(depts, dept)
+		}
+	}
+	sort.Slice(depts, func(i, j int) bool {
+		if (depts[i] == noDepartment) != (depts[j] == noDepartment) {
+			return depts[j] == noDepartment
+		}
+		return depts[i] < depts[j]
+	})
+
+	var messages []string
+	var b strings.Builder
+	b.WriteString(header)
+	length := utf8.RuneCountInString(header)
+	// add appends a line, starting a new message first if the line and the next extra
+	// characters would not fit
+	add := func(line, dept string, extra int) {
+		n := utf8.RuneCountInString(line) + 1
+		if length+n+extra > r.limit {
+			messages = append(messages, strings.TrimRight(b.String(), "\n"))
+			b.Reset()
+			length = 0
+			line = strings.TrimPrefix(line, "\n")
+			if dept != "" {
+				// A department cut in two is named again at the top of the next message
+				cont := markdown.Bold(dept) + " (ต่อ)\n"
+				b.WriteString(cont)
+				length = utf8.RuneCountInString(cont)
+			}
+		}
+		b.WriteString(line + "\n")
+		length += n
+	}
+	for _, dept := range depts {
+		g := groups[dept]
+		lines := append(append(append([]string{}, g.onTime...), g.late...), g.missing...)
+		// Keep the heading with the department's first line
+		add("\n"+markdown.Bold(dept), "", utf8.RuneCountInString(lines[0])+1)
+		for _, line := range lines {
+			add(line, dept, 0)
+		}
+	}
+	return append(messages, strings.TrimRight(b.String(), "\n")), nil
+}
+
+// shiftStarted reports whether the employee's grace period, or else the site's grace, is
+// over at at, so not being seen yet means something; employees without a work start count
+// as started
+func shiftStarted(emp *models.Employee, at time.Time, grace time.Duration) bool {
+	start, err := time.Parse("15:04:05", emp.WorkStartTime)
+	if err != nil {
+		return true
+	}
+	begin := time.Date(at.Year(), at.Month(), at.Day(), start.Hour(), start.Minute(), start.Second(), 0, at.Location())
+	return !at.Before(begin.Add(GracePeriod(emp, grace)))
+}
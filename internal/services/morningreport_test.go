@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+func TestMorningReport(t *testing.T) {
+	monday := time.Date(2026, 3, 2, 10, 0, 0, 0, time.Local)
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, 3, 2, hour, minute, 0, 0, time.Local)
+	}
+	store := memory.NewStore(clock.NewFake(monday))
+	employees := []models.Employee{
+		{ID: "e1", Name: "Somchai", Department: "ICU", WorkStartTime: "08:00:00"},
+		{ID: "e2", Name: "Somsri", Department: "ICU", WorkStartTime: "08:00:00"},
+		{ID: "e3", Name: "Somsak", Department: "ER", WorkStartTime: "08:00:00"},
+		{ID: "e4", Name: "Malee", WorkStartTime: "08:00:00"},
+		{ID: "e5", Name: "Night", Department: "ER", WorkStartTime: "22:00:00", WorkEndTime: "06:00:00"},
+		{ID: "e6", Name: "Away", Department: "ER", WorkStartTime: "08:00:00", LeaveUntil: at(0, 0).AddDate(0, 0, 3)},
+		{ID: "e7", Name: "Visitor", IsGuest: true},
+		{ID: "e8", Name: "Probe", IsSynthetic: true},
+	}
+	for _, emp := range employees {
+		emp.IsActive = true
+		store.AddEmployee(emp)
+	}
+	for _, a := range []models.Attendance{
+		{EmployeeID: "e1", CheckInTime: at(7, 52), Status: models.AttendanceStatusOnTime},
+		{EmployeeID: "e2", CheckInTime: at(8, 23), Status: models.AttendanceStatusLate},
+		{EmployeeID: "e3", CheckInTime: at(3, 0), Status: models.AttendanceStatusOnTime, Source: models.AttendanceSourceSelfTest},
+		{EmployeeID: "e7", CheckInTime: at(9, 0), Status: models.AttendanceStatusGuest},
+	} {
+		a.CreatedDate = at(0, 0)
+		if err := store.AttendanceRecords().Create(context.Background(), &a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	calendar, err := NewWorkCalendar("Mon,Tue,Wed,Thu,Fri", store.Holidays())
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifier := newRecordingNotifier()
+	report := NewMorningReport(store.Employees(), store.AttendanceRecords(), calendar, notifier)
+
+	if err := report.Send(context.Background(), monday); err != nil {
+		t.Fatal(err)
+	}
+	if len(notifier.admin) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(notifier.admin))
+	}
+	got := notifier.admin[0]
+	for _, want := range []string{
+		"✅ ตรงเวลา: 1 คน", "⚠️ สาย: 1 คน", "❓ ยังไม่พบ: 2 คน", "🌴 ลา: 1 คน",
+		"*ER*\n❓ Somsak", "*ICU*\n✅ Somchai 07:52\n⚠️ Somsri 08:23 (สาย 23 นาที)", "*ไม่ระบุแผนก*\n❓ Malee",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report = %q, want it to contain %q", got, want)
+		}
+	}
+	for _, notWant := range []string{"Night", "Away", "Visitor", "Probe"} {
+		if strings.Contains(got, notWant) {
+			t.Errorf("report = %q, want no %q", got, notWant)
+		}
+	}
+	if strings.Index(got, "*ICU*") > strings.Index(got, "*ไม่ระบุแผนก*") {
+		t.Errorf("report = %q, want employees without a department last", got)
+	}
+
+	// Weekends are skipped
+	if err := report.Send(context.Background(), monday.AddDate(0, 0, -2)); err != nil {
+		t.Fatal(err)
+	}
+	if len(notifier.admin) != 1 {
+		t.Errorf("sent %d messages by Saturday, want none", len(notifier.admin)-1)
+	}
+}
+
+func TestMorningReportSplits(t *testing.T) {
+	monday := time.Date(2026, 3, 2, 10, 0, 0, 0, time.Local)
+	store := memory.NewStore(clock.NewFake(monday))
+	for i := 1; i <= 120; i++ {
+		store.AddEmployee(models.Employee{ID: fmt.Sprintf("e%d", i), Name: fmt.Sprintf("พนักงาน_%03d", i),
+			Department: fmt.Sprintf("แผนก %d", i%3), WorkStartTime: "08:00:00", IsActive: true})
+	}
+	report := NewMorningReport(store.Employees(), store.AttendanceRecords(), nil, newRecordingNotifier())
+	report.limit = 500
+
+	messages, err := report.Build(context.Background(), monday)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) < 3 {
+		t.Fatalf("built %d messages, want the report split", len(messages))
+	}
+	all := strings.Join(messages, "\n")
+	for i, m := range messages {
+		if n := utf8.RuneCountInString(m); n > 500 {
+			t.Errorf("message %d has %d characters, want at most 500", i+1, n)
+		}
+		if err := markdown.Check(m); err != nil {
+			t.Errorf("message %d does not parse: %v", i+1, err)
+		}
+		if i > 0 && !strings.HasPrefix(m, "*แผนก") {
+			t.Errorf("message %d starts %q, want a department heading", i+1, m[:min(len(m), 40)])
+		}
+	}
+	for i := 1; i <= 120; i++ {
+		if name := fmt.Sprintf("❓ พนักงาน\\_%03d", i); strings.Count(all, name) != 1 {
+			t.Errorf("%s appears %d times, want once", name, strings.Count(all, name))
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"med-pulse-bot/internal/models"
+)
+
+// Event types of notifications, sent to webhooks as event_type
+const (
+	EventAdmin    = "admin"    // an admin chat message with no more specific type
+	EventPersonal = "personal" // a personal message with no more specific type
+	EventCheckIn  = "check_in"
+	EventLate     = "late" // the admin alert of a late check-in
+)
+
+// Notification is one message and the event it reports
+type Notification struct {
+	EventType string
+	Message   string // Telegram Markdown
+}
+
+// Notifier delivers notifications over one channel: the Telegram bot, a webhook, ...
+type Notifier interface {
+	NotifyEmployee(ctx context.Context, employee *models.Employee, msg Notification) error
+	NotifyAdmin(ctx context.Context, msg Notification) error
+}
+
+// notifyEmployee sends the employee msg, with its event type when the notifier takes one
+func notifyEmployee(ctx context.Context, notifier BotNotifier, employee *models.Employee, msg Notification) {
+	n, ok := notifier.(Notifier)
+	if !ok {
+		notifier.SendPersonalNotification(employee.TelegramChatID, msg.Message)
+		return
+	}
+	if err := n.NotifyEmployee(ctx, employee, msg); err != nil {
+		log.Printf("⚠️  Failed to deliver %s notification to %s: %v", msg.EventType, employee.Name, err)
+	}
+}
+
+// notifyAdmin sends the admins msg, with its event type when the notifier takes one
+func notifyAdmin(ctx context.Context, notifier BotNotifier, msg Notification) {
+	n, ok := notifier.(Notifier)
+	if !ok {
+		notifier.SendNotification(msg.Message)
+		return
+	}
+	if err := n.NotifyAdmin(ctx, msg); err != nil {
+		log.Printf("⚠️  Failed to deliver %s admin notification: %v", msg.EventType, err)
+	}
+}
+
+// BotChannel delivers notifications through a BotNotifier, i.e. the Telegram bot
+type BotChannel struct {
+	Bot BotNotifier
+}
+
+// NotifyEmployee sends msg to the employee's Telegram chat
+func (c BotChannel) NotifyEmployee(ctx context.Context, employee *models.Employee, msg Notification) error {
+	c.Bot.SendPersonalNotification(employee.TelegramChatID, msg.Message)
+	return nil
+}
+
+// NotifyAdmin sends msg to the admin chats
+func (c BotChannel) NotifyAdmin(ctx context.Context, msg Notification) error {
+	c.Bot.SendNotification(msg.Message)
+	return nil
+}
+
+// SendPersonalPrompt sends a personal prompt; without prompt support in the bot it is
+// sent as a plain personal notification
+func (c BotChannel) SendPersonalPrompt(chatID int64, message string, buttons []models.PromptButton) {
+	if p, ok := c.Bot.(PromptNotifier); ok {
+		p.SendPersonalPrompt(chatID, message, buttons)
+		return
+	}
+	c.Bot.SendPersonalNotification(chatID, message)
+}
+
+// SendPrompt sends an admin prompt; without prompt support in the bot it is sent as a
+// plain admin notification
+func (c BotChannel) SendPrompt(message string, buttons []models.PromptButton) {
+	if p, ok := c.Bot.(AdminPromptNotifier); ok {
+		p.SendPrompt(message, buttons)
+		return
+	}
+	c.Bot.SendNotification(message)
+}
+
+// MultiNotifier fans every notification out to several channels. Each channel gets it
+// whatever the others do; the failures are returned together.
+type MultiNotifier struct {
+	channels []Notifier
+}
+
+// NewMultiNotifier creates a notifier delivering to every channel, in the given order
+func NewMultiNotifier(channels ...Notifier) *MultiNotifier {
+	return &MultiNotifier{channels: channels}
+}
+
+// NotifyEmployee sends msg to the employee over every channel
+func (m *MultiNotifier) NotifyEmployee(ctx context.Context, employee *models.Employee, msg Notification) error {
+	var errs []error
+	for _, c := range m.channels {
+		if err := c.NotifyEmployee(ctx, employee, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%T: %w", c, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NotifyAdmin sends msg to the admins over every channel
+func (m *MultiNotifier) NotifyAdmin(ctx context.Context, msg Notification) error {
+	var errs []error
+	for _, c := range m.channels {
+		if err := c.NotifyAdmin(ctx, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%T: %w", c, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SendNotification sends an admin message over every channel
+func (m *MultiNotifier) SendNotification(message string) {
+	notifyAdmin(context.Background(), m, Notification{EventType: EventAdmin, Message: message})
+}
+
+// SendPersonalNotification sends a personal message over every channel
+func (m *MultiNotifier) SendPersonalNotification(chatID int64, message string) {
+	notifyEmployee(context.Background(), m, &models.Employee{TelegramChatID: chatID},
+		Notification{EventType: EventPersonal, Message: message})
+}
+
+// SendPersonalPrompt sends a personal prompt to the channels with reply buttons and a
+// plain personal message to the others
+func (m *MultiNotifier) SendPersonalPrompt(chatID int64, message string, buttons []models.PromptButton) {
+	employee := &models.Employee{TelegramChatID: chatID}
+	for _, c := range m.channels {
+		if p, ok := c.(PromptNotifier); ok {
+			p.SendPersonalPrompt(chatID, message, buttons)
+			continue
+		}
+		if err := c.NotifyEmployee(context.Background(), employee, Notification{EventType: EventPersonal, Message: message}); err != nil {
+			log.Printf("⚠️  Failed to deliver personal prompt over %T: %v", c, err)
+		}
+	}
+}
+
+// SendPrompt sends an admin prompt to the channels with reply buttons and a plain admin
+// message to the others
+func (m *MultiNotifier) SendPrompt(message string, buttons []models.PromptButton) {
+	for _, c := range m.channels {
+		if p, ok := c.(AdminPromptNotifier); ok {
+			p.SendPrompt(message, buttons)
+			continue
+		}
+		if err := c.NotifyAdmin(context.Background(), Notification{EventType: EventAdmin, Message: message}); err != nil {
+			log.Printf("⚠️  Failed to deliver admin prompt over %T: %v", c, err)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/models"
+)
+
+// channelRecorder is a Notifier channel remembering what it was given, or failing every call
+type channelRecorder struct {
+	fail   bool
+	events []string // "recipient:event_type"
+}
+
+func (c *channelRecorder) NotifyEmployee(ctx context.Context, employee *models.Employee, msg Notification) error {
+	if c.fail {
+		return errors.New("channel down")
+	}
+	c.events = append(c.events, employee.Name+":"+msg.EventType)
+	return nil
+}
+
+func (c *channelRecorder) NotifyAdmin(ctx context.Context, msg Notification) error {
+	if c.fail {
+		return errors.New("channel down")
+	}
+	c.events = append(c.events, "admin:"+msg.EventType)
+	return nil
+}
+
+func TestMultiNotifierFanOut(t *testing.T) {
+	tests := []struct {
+		name    string
+		fail    []bool
+		wantErr bool
+	}{
+		{"all deliver", []bool{false, false}, false},
+		{"first fails", []bool{true, false}, true},
+		{"last fails", []bool{false, true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var channels []Notifier
+			var recorders []*channelRecorder
+			for _, fail := range tt.fail {
+				r := &channelRecorder{fail: fail}
+				channels, recorders = append(channels, r), append(recorders, r)
+			}
+			m := NewMultiNotifier(channels...)
+
+			errEmp := m.NotifyEmployee(context.Background(), &models.Employee{Name: "Somchai"}, Notification{EventType: EventCheckIn})
+			errAdmin := m.NotifyAdmin(context.Background(), Notification{EventType: EventLate})
+			if (errEmp != nil) != tt.wantErr || (errAdmin != nil) != tt.wantErr {
+				t.Errorf("errors = %v, %v, wantErr %v", errEmp, errAdmin, tt.wantErr)
+			}
+			for i, r := range recorders {
+				if r.fail {
+					continue
+				}
+				if len(r.events) != 2 || r.events[0] != "Somchai:check_in" || r.events[1] != "admin:late" {
+					t.Errorf("channel %d got %v, want [Somchai:check_in admin:late]", i, r.events)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		send   func(w *WebhookNotifier) error
+		want   webhookPayload
+		wantOK bool
+	}{
+		{
+			name:   "admin",
+			status: http.StatusOK,
+			send: func(w *WebhookNotifier) error {
+				return w.NotifyAdmin(context.Background(), Notification{EventType: EventLate, Message: "late"})
+			},
+			want:   webhookPayload{Recipient: "admin", Message: "late", EventType: "late"},
+			wantOK: true,
+		},
+		{
+			name:   "employee code",
+			status: http.StatusNoContent,
+			send: func(w *WebhookNotifier) error {
+				return w.NotifyEmployee(context.Background(), &models.Employee{ID: "emp1", EmployeeCode: "N-042"},
+					Notification{EventType: EventCheckIn, Message: "hi"})
+			},
+			want:   webhookPayload{Recipient: "N-042", Message: "hi", EventType: "check_in"},
+			wantOK: true,
+		},
+		{
+			name:   "record ID without a code",
+			status: http.StatusOK,
+			send: func(w *WebhookNotifier) error {
+				return w.NotifyEmployee(context.Background(), &models.Employee{ID: "emp1"},
+					Notification{EventType: EventCheckIn, Message: "hi"})
+			},
+			want:   webhookPayload{Recipient: "emp1", Message: "hi", EventType: "check_in"},
+			wantOK: true,
+		},
+		{
+			name:   "chat ID only",
+			status: http.StatusOK,
+			send: func(w *WebhookNotifier) error {
+				return w.NotifyEmployee(context.Background(), &models.Employee{TelegramChatID: 1001},
+					Notification{EventType: EventPersonal, Message: "hi"})
+			},
+			want:   webhookPayload{Recipient: "1001", Message: "hi", EventType: "personal"},
+			wantOK: true,
+		},
+		{
+			name:   "endpoint error",
+			status: http.StatusBadGateway,
+			send: func(w *WebhookNotifier) error {
+				return w.NotifyAdmin(context.Background(), Notification{EventType: EventAdmin, Message: "x"})
+			},
+			want:   webhookPayload{Recipient: "admin", Message: "x", EventType: "admin"},
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got webhookPayload
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("got %s with Content-Type %q", r.Method, r.Header.Get("Content-Type"))
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("failed to decode payload: %v", err)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			err := tt.send(NewWebhookNotifier(srv.URL))
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("error = %v, wantOK %v", err, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("payload = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookFailureDoesNotBlockTelegram(t *testing.T) {
+	var mu sync.Mutex
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	bot := newRecordingNotifier()
+	m := NewMultiNotifier(NewWebhookNotifier(srv.URL), BotChannel{Bot: bot})
+	emp := &models.Employee{Name: "Somchai", TelegramChatID: 1001, WorkStartTime: "08:00:00"}
+	at := time.Date(2026, 2, 2, 8, 20, 0, 0, time.Local)
+	sendCheckInNotification(context.Background(), m, nil, emp,
+		&models.Attendance{CheckInTime: at, CreatedDate: at, Status: models.AttendanceStatusLate}, &models.Scanner{})
+
+	if len(bot.personal[1001]) != 1 || len(bot.admin) != 1 {
+		t.Errorf("bot sent %d personal and %d admin messages, want 1 and 1", len(bot.personal[1001]), len(bot.admin))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 2 {
+		t.Errorf("webhook called %d times, want 2", calls)
+	}
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/localstore"
+	"med-pulse-bot/internal/metrics"
+)
+
+// opsSnapshotKey is the local store entry holding the operational totals of recent days
+const opsSnapshotKey = "ops_snapshots.json"
+
+// opsRetentionDays is how many days of operational totals are kept
+const opsRetentionDays = 14
+
+// OpsTotals are the operational numbers of one day. PocketBase and notification
+// figures are shared by every site of the process.
+type OpsTotals struct {
+	Detections           float64  `json:"detections"` // finished by the pipeline, queued ones once
+	Suppressed           float64  `json:"suppressed"` // stopped as duplicates or repeats
+	PocketBaseRequests   float64  `json:"pocketbase_requests"`
+	PocketBaseErrors     float64  `json:"pocketbase_errors"`
+	NotificationFailures float64  `json:"notification_failures"`
+	Latency              []uint64 `json:"latency"` // pipeline runs per metrics.LatencyBuckets bucket
+	QueuePeak            float64  `json:"queue_peak"`
+}
+
+// OpsRecorder turns the process-lifetime counters of the metrics registry into daily
+// totals: it snapshots them at each midnight and saves the day so far to the local
+// store periodically, so a restart loses at most one interval.
+type OpsRecorder struct {
+	tenantID string
+	store    localstore.LocalStore
+	clock    clock.Clock
+
+	mu      sync.Mutex
+	day     string    // date being counted, empty before the first Record
+	base    OpsTotals // counter values when this process started counting day
+	carried OpsTotals // counted for day by earlier processes
+	peak    float64   // highest queue length seen by this process during day
+}
+
+// NewOpsRecorder creates a recorder of the site's operational numbers kept in store
+func NewOpsRecorder(tenantID string, store localstore.LocalStore) *OpsRecorder {
+	return &OpsRecorder{tenantID: tenantID, store: store, clock: clock.Real{}}
+}
+
+// SetClock replaces the time source, used by tests
+func (r *OpsRecorder) SetClock(c clock.Clock) {
+	r.clock = c
+}
+
+// Start records every interval and at each midnight in a goroutine until ctx is
+// cancelled, then records once more
+func (r *OpsRecorder) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		for {
+			if err := r.Record(ctx); err != nil {
+				log.Printf("❌ Saving operational totals failed: %v", err)
+			}
+			now := r.clock.Now()
+			midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+			timer := time.NewTimer(min(interval, midnight.Sub(now)))
+
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				if err := r.Record(context.Background()); err != nil {
+					log.Printf("❌ Saving operational totals failed: %v", err)
+				}
+				return
+			case <-timer.C:
+			}
+		}
+	}()
+}
+
+// Record adds the counts since the previous call to the current day and saves it. The
+// first call after midnight closes the previous day at the counter values it finds.
+func (r *OpsRecorder) Record(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	today := r.clock.Now().Format("2006-01-02")
+	current := readOpsCounters(r.tenantID)
+	r.peak = max(r.peak, metrics.DetectionQueueLength.TakePeak(r.tenantID))
+
+	days, err := r.load(ctx)
+	if err != nil {
+		return err
+	}
+	switch r.day {
+	case today:
+	case "":
+		// Started, or restarted, during the day: keep what was saved before
+		r.day, r.base, r.carried, r.peak = today, current, days[today], max(r.peak, days[today].QueuePeak)
+	default:
+		days[r.day] = r.totals(current)
+		r.day, r.base, r.carried = today, current, OpsTotals{}
+		r.peak = metrics.DetectionQueueLength.Value(r.tenantID)
+	}
+	days[today] = r.totals(current)
+
+	cutoff := r.clock.Now().AddDate(0, 0, -opsRetentionDays).Format("2006-01-02")
+	for day := range days {
+		if day < cutoff {
+			delete(days, day)
+		}
+	}
+	data, err := json.Marshal(days)
+	if err != nil {
+		return err
+	}
+	return r.store.Put(ctx, opsSnapshotKey, data)
+}
+
+// Totals returns the operational numbers recorded for a day; false means nothing was
+// recorded that day
+func (r *OpsRecorder) Totals(ctx context.Context, day time.Time) (OpsTotals, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	days, err := r.load(ctx)
+	if err != nil {
+		return OpsTotals{}, false, err
+	}
+	totals, ok := days[day.Format("2006-01-02")]
+	return totals, ok, nil
+}
+
+// SummaryLines is a SummarySection with the numbers of the day before day, complete
+// since midnight
+func (r *OpsRecorder) SummaryLines(ctx context.Context, day time.Time) ([]string, error) {
+	previous := day.AddDate(0, 0, -1)
+	t, ok, err := r.Totals(ctx, previous)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	errorRate := 0.0
+	if t.PocketBaseRequests > 0 {
+		errorRate = 100 * t.PocketBaseErrors / t.PocketBaseRequests
+	}
+	p95 := "-"
+	if v, ok := latencyQuantile(t.Latency, 0.95); ok {
+		if math.IsInf(v, 1) {
+			p95 = fmt.Sprintf("> %gs", metrics.LatencyBuckets[len(metrics.LatencyBuckets)-1])
+		} else {
+			p95 = time.Duration(v * float64(time.Second)).Round(time.Millisecond).String()
+		}
+	}
+	return []string{
+		fmt.Sprintf("วันที่ %s", previous.Format("02/01/2006")),
+		fmt.Sprintf("ประมวลผลการตรวจจับ: %.0f ครั้ง (ตัดซ้ำ %.0f)", t.Detections, t.Suppressed),
+		fmt.Sprintf("PocketBase ผิดพลาด: %.1f%% (%.0f/%.0f)", errorRate, t.PocketBaseErrors, t.PocketBaseRequests),
+		fmt.Sprintf("ส่งข้อความไม่สำเร็จ: %.0f", t.NotificationFailures),
+		fmt.Sprintf("เวลาประมวลผล p95: %s", p95),
+		fmt.Sprintf("คิวสูงสุด: %.0f", t.QueuePeak),
+	}, nil
+}
+
+// totals is what has been counted for r.day given the current counter values
+func (r *OpsRecorder) totals(current OpsTotals) OpsTotals {
+	t := OpsTotals{
+		Detections:           r.carried.Detections + current.Detections - r.base.Detections,
+		Suppressed:           r.carried.Suppressed + current.Suppressed - r.base.Suppressed,
+		PocketBaseRequests:   r.carried.PocketBaseRequests + current.PocketBaseRequests - r.base.PocketBaseRequests,
+		PocketBaseErrors:     r.carried.PocketBaseErrors + current.PocketBaseErrors - r.base.PocketBaseErrors,
+		NotificationFailures: r.carried.NotificationFailures + current.NotificationFailures - r.base.NotificationFailures,
+		Latency:              make([]uint64, len(current.Latency)),
+		QueuePeak:            r.peak,
+	}
+	for i := range t.Latency {
+		t.Latency[i] = current.Latency[i] - r.base.Latency[i]
+		if len(r.carried.Latency) == len(t.Latency) {
+			t.Latency[i] += r.carried.Latency[i]
+		}
+	}
+	return t
+}
+
+// load reads the saved days; a missing or corrupt entry starts empty
+func (r *OpsRecorder) load(ctx context.Context) (map[string]OpsTotals, error) {
+	days := make(map[string]OpsTotals)
+	data, err := r.store.Get(ctx, opsSnapshotKey)
+	if errors.Is(err, localstore.ErrNotFound) {
+		return days, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read operational totals: %w", err)
+	}
+	if err := json.Unmarshal(data, &days); err != nil {
+		log.Printf("⚠️  Corrupt operational totals, starting empty: %v", err)
+		return make(map[string]OpsTotals), nil
+	}
+	return days, nil
+}
+
+// readOpsCounters reads the process-lifetime counters behind OpsTotals
+func readOpsCounters(tenantID string) OpsTotals {
+	return OpsTotals{
+		Detections:           metrics.Detections.Total(tenantID) - metrics.Detections.Value(tenantID, "queued"),
+		Suppressed:           metrics.Detections.Value(tenantID, "dedupe") + metrics.Detections.Value(tenantID, "recent"),
+		PocketBaseRequests:   metrics.PocketBaseRequests.Total(),
+		PocketBaseErrors:     metrics.PocketBaseRequests.Value("error"),
+		NotificationFailures: metrics.NotificationFailures.Total(),
+		Latency:              metrics.DetectionLatency.Counts(tenantID),
+	}
+}
+
+// latencyQuantile estimates the q quantile, in seconds, of the observations counted per
+// metrics.LatencyBuckets bucket, interpolating within the bucket it falls in. It is
+// +Inf when it lies above every bound and false when there are no observations.
+func latencyQuantile(counts []uint64, q float64) (float64, bool) {
+	bounds := metrics.LatencyBuckets
+	if len(counts) != len(bounds)+1 {
+		return 0, false
+	}
+	var total uint64
+	for _, c := range counts {
+		total += c
+	}
+	if total == 0 {
+		return 0, false
+	}
+
+	rank := q * float64(total)
+	var below uint64
+	for i, c := range counts {
+		if c > 0 && float64(below+c) >= rank {
+			if i == len(bounds) {
+				return math.Inf(1), true
+			}
+			lower := 0.0
+			if i > 0 {
+				lower = bounds[i-1]
+			}
+			return lower + (bounds[i]-lower)*(rank-float64(below))/float64(c), true
+		}
+		below += c
+	}
+	return math.Inf(1), true
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/localstore"
+	"med-pulse-bot/internal/metrics"
+)
+
+func TestOpsRecorder(t *testing.T) {
+	ctx := context.Background()
+	const tenant = "ops-test"
+	day1 := time.Date(2026, 3, 2, 10, 0, 0, 0, time.Local)
+	clk := clock.NewFake(day1)
+	store := localstore.NewMemoryStore()
+	newRecorder := func() *OpsRecorder {
+		r := NewOpsRecorder(tenant, store)
+		r.SetClock(clk)
+		return r
+	}
+	record := func(t *testing.T, r *OpsRecorder, at time.Time) {
+		t.Helper()
+		clk.Set(at)
+		if err := r.Record(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	totals := func(t *testing.T, day time.Time) OpsTotals {
+		t.Helper()
+		got, ok, err := newRecorder().Totals(ctx, day)
+		if err != nil || !ok {
+			t.Fatalf("Totals(%s) = %v, %v", day.Format("2006-01-02"), ok, err)
+		}
+		return got
+	}
+
+	// Counts from before the recorder started belong to no day
+	metrics.Detections.Inc(tenant, "notification")
+	first := newRecorder()
+	record(t, first, day1)
+
+	for range 3 {
+		metrics.Detections.Inc(tenant, "notification")
+	}
+	metrics.Detections.Add(2, tenant, "dedupe")
+	metrics.Detections.Inc(tenant, "queued")
+	metrics.PocketBaseRequests.Add(9, "ok")
+	metrics.PocketBaseRequests.Inc("error")
+	metrics.NotificationFailures.Inc()
+	for range 19 {
+		metrics.DetectionLatency.Observe(0.02, tenant)
+	}
+	metrics.DetectionLatency.Observe(3, tenant)
+	metrics.DetectionQueueLength.Add(1, tenant)
+	metrics.DetectionQueueLength.Add(1, tenant)
+	metrics.DetectionQueueLength.Set(0, tenant)
+	record(t, first, day1.Add(2*time.Hour))
+
+	t.Run("day so far", func(t *testing.T) {
+		got := totals(t, day1)
+		if got.Detections != 5 || got.Suppressed != 2 || got.PocketBaseRequests != 10 || got.PocketBaseErrors != 1 ||
+			got.NotificationFailures != 1 || got.QueuePeak != 2 {
+			t.Errorf("totals = %+v", got)
+		}
+	})
+
+	// A restart keeps what was saved and counts on from there
+	second := newRecorder()
+	record(t, second, day1.Add(3*time.Hour))
+	metrics.Detections.Inc(tenant, "notification")
+	record(t, second, day1.AddDate(0, 0, 1).Add(-10*time.Hour+time.Second)) // 00:00:01
+	metrics.Detections.Inc(tenant, "notification")
+	record(t, second, day1.AddDate(0, 0, 1).Add(-9*time.Hour))
+
+	t.Run("midnight closes the day", func(t *testing.T) {
+		if got := totals(t, day1); got.Detections != 6 || got.QueuePeak != 2 {
+			t.Errorf("first day = %+v, want 6 detections and a queue peak of 2 across the restart", got)
+		}
+		if got := totals(t, day1.AddDate(0, 0, 1)); got.Detections != 1 || got.Suppressed != 0 || got.QueuePeak != 0 {
+			t.Errorf("second day = %+v, want only its own detection", got)
+		}
+	})
+
+	t.Run("summary reports the previous day", func(t *testing.T) {
+		lines, err := second.SummaryLines(ctx, day1.AddDate(0, 0, 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{
+			"วันที่ 02/03/2026",
+			"ประมวลผลการตรวจจับ: 6 ครั้ง (ตัดซ้ำ 2)",
+			"PocketBase ผิดพลาด: 10.0% (1/10)",
+			"ส่งข้อความไม่สำเร็จ: 1",
+			"เวลาประมวลผล p95: 25ms",
+			"คิวสูงสุด: 2",
+		}
+		if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+			t.Errorf("lines =\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+		}
+
+		if lines, _ := second.SummaryLines(ctx, day1); len(lines) != 0 {
+			t.Errorf("lines for a day without data = %q", lines)
+		}
+	})
+}
+
+func TestLatencyQuantile(t *testing.T) {
+	counts := func(byBucket map[int]uint64) []uint64 {
+		c := make([]uint64, len(metrics.LatencyBuckets)+1)
+		for i, n := range byBucket {
+			c[i] = n
+		}
+		return c
+	}
+	tests := []struct {
+		name   string
+		counts []uint64
+		want   float64
+		wantOK bool
+	}{
+		{"no observations", counts(nil), 0, false},
+		{"mismatched buckets", []uint64{1, 2}, 0, false},
+		{"first bucket", counts(map[int]uint64{0: 10}), 0.00475, true},
+		{"interpolated", counts(map[int]uint64{2: 19, 9: 1}), 0.025, true},
+		{"above every bound", counts(map[int]uint64{0: 1, len(metrics.LatencyBuckets): 19}), math.Inf(1), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := latencyQuantile(tt.counts, 0.95)
+			if ok != tt.wantOK || math.Abs(got-tt.want) > 1e-9 && !math.IsInf(tt.want, 1) || math.IsInf(tt.want, 1) != math.IsInf(got, 1) {
+				t.Errorf("latencyQuantile() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
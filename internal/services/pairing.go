@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/macaddr"
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/repository"
+)
+
+// Pairing code errors reported back to the scanner
+var (
+	ErrPairingCodeInvalid = errors.New("pairing_code_invalid")
+	ErrPairingCodeExpired = errors.New("pairing_code_expired")
+	ErrPairingCodeUsed    = errors.New("pairing_code_used")
+)
+
+// maxZoneLength keeps zones short enough for Telegram button data (64 bytes)
+const maxZoneLength = 48
+
+// pairingCode is an issued code and what it pairs the scanner into
+type pairingCode struct {
+	zone    string
+	chatID  int64 // admin chat that asked for the code
+	expires time.Time
+	used    bool
+}
+
+// pairedScanner is a scanner waiting for its first detection after pairing
+type pairedScanner struct {
+	mac    string // as sent by the scanner
+	zone   string
+	chatID int64
+}
+
+// ScannerPairing issues short-lived codes that a new scanner sends on its first
+// heartbeat, records the scanner with the chosen zone and confirms to the admin once
+// the first real detection from it arrives
+type ScannerPairing struct {
+	scanners repository.ScannerRegistry
+	notifier BotNotifier
+	ttl      time.Duration
+	clock    clock.Clock
+
+	mu       sync.Mutex
+	codes    map[string]*pairingCode
+	awaiting map[string]pairedScanner // normalized scanner MAC → pairing
+}
+
+// NewScannerPairing creates a pairing service whose codes are valid for ttl
+func NewScannerPairing(scanners repository.ScannerRegistry, notifier BotNotifier, ttl time.Duration) (*ScannerPairing, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("pairing code lifetime must be positive, got %s", ttl)
+	}
+	return &ScannerPairing{
+		scanners: scanners,
+		notifier: notifier,
+		ttl:      ttl,
+		clock:    clock.Real{},
+		codes:    make(map[string]*pairingCode),
+		awaiting: make(map[string]pairedScanner),
+	}, nil
+}
+
+// SetClock replaces the time source, used by tests
+func (p *ScannerPairing) SetClock(c clock.Clock) {
+	p.clock = c
+}
+
+// NewCode issues a 6-digit pairing code for the zone, requested from chatID
+func (p *ScannerPairing) NewCode(zone string, chatID int64) (string, time.Time, error) {
+	zone = strings.TrimSpace(zone)
+	if zone == "" || len(zone) > maxZoneLength {
+		return "", time.Time{}, fmt.Errorf("zone must be 1-%d bytes", maxZoneLength)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.clock.Now()
+	p.prune(now)
+
+	for {
+		n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		code := fmt.Sprintf("%06d", n.Int64())
+		if _, taken := p.codes[code]; taken {
+			continue
+		}
+		expires := now.Add(p.ttl)
+		p.codes[code] = &pairingCode{zone: zone, chatID: chatID, expires: expires}
+		log.Printf("📡 Issued scanner pairing code for zone %q (expires %s)", zone, expires.Format("15:04"))
+		return code, expires, nil
+	}
+}
+
+// Redeem pairs the scanner with the code's zone and returns the zone. Unknown, expired
+// and already used codes are rejected.
+func (p *ScannerPairing) Redeem(ctx context.Context, code, scannerMac string) (string, error) {
+	mac, err := macaddr.NormalizeScannerID(scannerMac, true)
+	if err != nil {
+		return "", fmt.Errorf("invalid scanner_mac: %w", err)
+	}
+
+	p.mu.Lock()
+	now := p.clock.Now()
+	c, ok := p.codes[strings.TrimSpace(code)]
+	switch {
+	case !ok:
+		err = ErrPairingCodeInvalid
+	case c.used:
+		err = ErrPairingCodeUsed
+	case now.After(c.expires):
+		err = ErrPairingCodeExpired
+	default:
+		// Claimed before writing so a concurrent heartbeat cannot reuse it
+		c.used = true
+	}
+	p.mu.Unlock()
+	if err != nil {
+		log.Printf("🚫 Scanner %s sent a rejected pairing code: %v", scannerMac, err)
+		return "", err
+	}
+
+	if err := p.scanners.Pair(ctx, mac, c.zone, now); err != nil {
+		p.mu.Lock()
+		c.used = false
+		p.mu.Unlock()
+		return "", err
+	}
+
+	p.mu.Lock()
+	p.awaiting[mac] = pairedScanner{mac: scannerMac, zone: c.zone, chatID: c.chatID}
+	p.mu.Unlock()
+
+	log.Printf("📡 Scanner %s paired into zone %q", scannerMac, c.zone)
+	p.notifier.SendPersonalNotification(c.chatID, fmt.Sprintf(
+		"📡 *Scanner จับคู่แล้ว*\n\nScanner: %s\nโซน: %s\n\n⏳ รอการตรวจจับครั้งแรก...", markdown.Code(scannerMac), markdown.Bold(c.zone)))
+	return c.zone, nil
+}
+
+// Observe confirms to the admin when a freshly paired scanner sends its first detection
+func (p *ScannerPairing) Observe(scannerMac string, at time.Time) {
+	mac, err := macaddr.NormalizeScannerID(scannerMac, true)
+	if err != nil {
+		return
+	}
+
+	p.mu.Lock()
+	paired, ok := p.awaiting[mac]
+	delete(p.awaiting, mac)
+	p.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	log.Printf("📡 First detection from paired scanner %s", paired.mac)
+	p.notifier.SendPersonalNotification(paired.chatID, fmt.Sprintf(
+		"✅ *Scanner พร้อมใช้งาน*\n\nScanner: %s\nโซน: %s\nตรวจจับครั้งแรก: `%s`", markdown.Code(paired.mac), markdown.Bold(paired.zone), at.Format("15:04:05")))
+}
+
+// Zones lists the zones already assigned to scanners, for choosing one in the bot
+func (p *ScannerPairing) Zones(ctx context.Context) ([]string, error) {
+	scanners, err := p.scanners.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var zones []string
+	for _, sc := range scanners {
+		if sc.Zone != "" && !seen[sc.Zone] {
+			seen[sc.Zone] = true
+			zones = append(zones, sc.Zone)
+		}
+	}
+	sort.Strings(zones)
+	return zones, nil
+}
+
+// prune forgets codes long past their expiry; used and expired codes are kept for a
+// while so a retry is told why it was rejected. Callers hold mu.
+func (p *ScannerPairing) prune(now time.Time) {
+	for code, c := range p.codes {
+		if now.After(c.expires.Add(24 * time.Hour)) {
+			delete(p.codes, code)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+func TestScannerPairingRedeem(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, 2, 2, 9, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name    string
+		redeem  func(p *ScannerPairing, clk *clock.Fake, code string) error
+		wantErr error
+	}{
+		{
+			name: "valid code",
+			redeem: func(p *ScannerPairing, clk *clock.Fake, code string) error {
+				_, err := p.Redeem(ctx, code, "AA:BB:CC:00:00:10")
+				return err
+			},
+		},
+		{
+			name: "unknown code",
+			redeem: func(p *ScannerPairing, clk *clock.Fake, code string) error {
+				_, err := p.Redeem(ctx, "000000x", "AA:BB:CC:00:00:10")
+				return err
+			},
+			wantErr: ErrPairingCodeInvalid,
+		},
+		{
+			name: "expired code",
+			redeem: func(p *ScannerPairing, clk *clock.Fake, code string) error {
+				clk.Advance(10*time.Minute + time.Second)
+				_, err := p.Redeem(ctx, code, "AA:BB:CC:00:00:10")
+				return err
+			},
+			wantErr: ErrPairingCodeExpired,
+		},
+		{
+			name: "reused code",
+			redeem: func(p *ScannerPairing, clk *clock.Fake, code string) error {
+				if _, err := p.Redeem(ctx, code, "AA:BB:CC:00:00:10"); err != nil {
+					return err
+				}
+				_, err := p.Redeem(ctx, code, "AA:BB:CC:00:00:11")
+				return err
+			},
+			wantErr: ErrPairingCodeUsed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(start)
+			store := memory.NewStore(clk)
+			pairing, err := NewScannerPairing(store.ScannerRecords(), newRecordingNotifier(), 10*time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pairing.SetClock(clk)
+			code, _, err := pairing.NewCode("ทางเข้า", 7)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = tt.redeem(pairing, clk, code)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == ErrPairingCodeUsed && len(store.Scanners()) != 1 {
+				t.Errorf("got %d scanners, want only the first one paired", len(store.Scanners()))
+			}
+		})
+	}
+}
+
+func TestScannerPairingConfirmsFirstDetection(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2026, 2, 2, 9, 0, 0, 0, time.Local))
+	store := memory.NewStore(clk)
+	notifier := newRecordingNotifier()
+
+	// Pre-provisioned with the MAC in another case
+	store.ScannerRecords().UpdateActivity(ctx, "aa:bb:cc:00:00:10", models.ScannerHealth{})
+
+	pairing, err := NewScannerPairing(store.ScannerRecords(), notifier, 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pairing.SetClock(clk)
+	service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), notifier)
+	service.SetClock(clk)
+	service.SetScannerPairing(pairing)
+
+	code, _, _ := pairing.NewCode("ทางเข้า", 7)
+	if _, err := pairing.Redeem(ctx, code, "AA:BB:CC:00:00:10"); err != nil {
+		t.Fatal(err)
+	}
+	scanners := store.Scanners()
+	if len(scanners) != 1 || scanners[0].Zone != "ทางเข้า" || scanners[0].PairedAt.IsZero() {
+		t.Fatalf("scanners = %+v, want the pre-provisioned record paired", scanners)
+	}
+	if len(notifier.personal[7]) != 1 {
+		t.Fatalf("got %d messages after pairing, want 1", len(notifier.personal[7]))
+	}
+
+	// Any device counts, even one that is not an employee's
+	for i := 0; i < 2; i++ {
+		service.ProcessDetection(ctx, &models.DetectionRequest{ScannerMac: "AA-BB-CC-00-00-10", MacAddress: "11:22:33:44:55:66", RSSI: -60})
+	}
+	if got := notifier.personal[7]; len(got) != 2 || !strings.Contains(got[1], "พร้อมใช้งาน") {
+		t.Errorf("messages = %q, want one live confirmation", got)
+	}
+
+	zones, err := pairing.Zones(ctx)
+	if err != nil || len(zones) != 1 || zones[0] != "ทางเข้า" {
+		t.Errorf("Zones() = %v, %v", zones, err)
+	}
+}
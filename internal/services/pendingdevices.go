@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/logging"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// PendingDeviceCallbackPrefix routes the buttons of /pending_devices; the data is
+// "pd:assign:<MAC>" or "pd:ignore:<MAC>"
+const PendingDeviceCallbackPrefix = "pd"
+
+// pendingDeviceAge is how recently a device must have been seen to be listed
+const pendingDeviceAge = 7 * 24 * time.Hour
+
+// maxPendingDevices caps the list so its buttons fit one message
+const maxPendingDevices = 10
+
+// ErrDeviceAssigned means another employee already owns the MAC
+var ErrDeviceAssigned = errors.New("device is already assigned to an employee")
+
+// PendingDevices lets admins review the unknown devices the scanners keep hearing: assign
+// one to an employee, or ignore it so it is not listed again
+type PendingDevices struct {
+	sightings repository.DeviceSightingRepository
+	employees repository.CachableEmployees
+	devices   repository.DeviceRepository // optional
+	assign    repository.DeviceAssignment
+	clock     clock.Clock
+}
+
+// NewPendingDevices creates the review service; pass the cached employee repository so
+// the owner lookups of a listing do not each reach PocketBase
+func NewPendingDevices(sightings repository.DeviceSightingRepository, employees repository.CachableEmployees, assign repository.DeviceAssignment) *PendingDevices {
+	return &PendingDevices{sightings: sightings, employees: employees, assign: assign, clock: clock.Real{}}
+}
+
+// SetClock replaces the time source, used by tests
+func (p *PendingDevices) SetClock(c clock.Clock) {
+	p.clock = c
+}
+
+// SetDevices treats devices registered to an employee besides their mac_address as owned
+func (p *PendingDevices) SetDevices(devices repository.DeviceRepository) {
+	p.devices = devices
+}
+
+// List returns the unreviewed devices seen in the last week that no employee owns, each
+// MAC once with its records merged, most often seen first
+func (p *PendingDevices) List(ctx context.Context) ([]models.DeviceSighting, error) {
+	sightings, err := p.sightings.ListPending(ctx, p.clock.Now().Add(-pendingDeviceAge))
+	if err != nil {
+		return nil, err
+	}
+
+	// A race between two scanners can leave a MAC with two records, in either case
+	byMAC := make(map[string]*models.DeviceSighting, len(sightings))
+	var pending []models.DeviceSighting
+	for _, s := range sightings {
+		s.MacAddress = strings.ToLower(s.MacAddress)
+		if seen, ok := byMAC[s.MacAddress]; ok {
+			seen.Count += s.Count
+			seen.RSSI = max(seen.RSSI, s.RSSI)
+			if s.SeenAt.After(seen.SeenAt) {
+				seen.SeenAt = s.SeenAt
+			}
+			continue
+		}
+		byMAC[s.MacAddress] = &s
+	}
+	for _, s := range byMAC {
+		// Someone may have registered the device since it was recorded
+		owner, err := p.owner(ctx, s.MacAddress)
+		if err != nil {
+			return nil, err
+		}
+		if owner == nil {
+			pending = append(pending, *s)
+		}
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		if pending[i].Count != pending[j].Count {
+			return pending[i].Count > pending[j].Count
+		}
+		return pending[i].MacAddress < pending[j].MacAddress
+	})
+	if len(pending) > maxPendingDevices {
+		pending = pending[:maxPendingDevices]
+	}
+	return pending, nil
+}
+
+// owner returns the active employee owning the MAC, nil if nobody does
+func (p *PendingDevices) owner(ctx context.Context, mac string) (*models.Employee, error) {
+	if p.devices != nil {
+		emp, err := p.devices.GetEmployeeByDeviceMac(ctx, mac)
+		if err == nil {
+			return emp, nil
+		}
+		if !errors.Is(err, repository.ErrEmployeeNotFound) {
+			return nil, err
+		}
+	}
+	emp, err := p.employees.GetByMacAddress(ctx, mac)
+	if errors.Is(err, repository.ErrEmployeeNotFound) {
+		return nil, nil
+	}
+	return emp, err
+}
+
+// Assign gives the device to the employee with the code and whitelists it. It returns
+// repository.ErrEmployeeNotFound for an unknown or inactive code and ErrDeviceAssigned
+// when another employee owns the MAC.
+func (p *PendingDevices) Assign(ctx context.Context, mac, code string) (*models.Employee, error) {
+	emp, err := p.employees.GetByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if !emp.IsActive {
+		return nil, repository.ErrEmployeeNotFound
+	}
+	owner, err := p.owner(ctx, mac)
+	if err != nil {
+		return nil, err
+	}
+	if owner != nil && owner.ID != emp.ID {
+		return nil, ErrDeviceAssigned
+	}
+	if owner == nil {
+		if err := p.assign.AssignDevice(ctx, emp, mac); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.sightings.Review(ctx, mac, true); err != nil {
+		return nil, fmt.Errorf("device assigned but not marked reviewed: %w", err)
+	}
+	log.Printf("📱 Pending device %s assigned to %s (%s)", logging.MaskMAC(mac), emp.Name, emp.EmployeeCode)
+	return emp, nil
+}
+
+// Ignore stops listing the device without whitelisting it
+func (p *PendingDevices) Ignore(ctx context.Context, mac string) error {
+	if err := p.sightings.Review(ctx, mac, false); err != nil {
+		return err
+	}
+	log.Printf("🙈 Pending device %s ignored", logging.MaskMAC(mac))
+	return nil
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+// duplicatedSightings lists every pending device twice, once in upper case, as two records
+// of one MAC would be
+type duplicatedSightings struct {
+	*memory.DeviceSightingRepository
+}
+
+func (d duplicatedSightings) ListPending(ctx context.Context, since time.Time) ([]models.DeviceSighting, error) {
+	sightings, err := d.DeviceSightingRepository.ListPending(ctx, since)
+	for _, s := range sightings {
+		dup := s
+		dup.MacAddress, dup.RSSI, dup.Count = strings.ToUpper(s.MacAddress), s.RSSI-10, 1
+		sightings = append(sightings, dup)
+	}
+	return sightings, err
+}
+
+func newPendingDevicesStore(t *testing.T) *memory.Store {
+	t.Helper()
+	store := newPipelineStore()
+	store.AddEmployee(models.Employee{ID: "e2", Name: "Suda", EmployeeCode: "N002", IsActive: true})
+	store.AddEmployee(models.Employee{ID: "e3", Name: "Anan", EmployeeCode: "N003", MacAddress: "aa:bb:cc:dd:ee:03", IsActive: true})
+	store.AddEmployee(models.Employee{ID: "e4", Name: "Left", EmployeeCode: "N004", IsActive: false})
+	log := store.DeviceSightingLog()
+	for _, s := range []models.DeviceSighting{
+		{MacAddress: "aa:bb:cc:dd:ee:0a", RSSI: -55, SeenAt: pipelineNow.Add(-time.Hour), Count: 3},
+		{MacAddress: "aa:bb:cc:dd:ee:0b", RSSI: -50, SeenAt: pipelineNow.Add(-time.Hour), Count: 9},
+		{MacAddress: "aa:bb:cc:dd:ee:01", RSSI: -40, SeenAt: pipelineNow.Add(-time.Hour), Count: 5}, // registered since
+		{MacAddress: "aa:bb:cc:dd:ee:0c", RSSI: -45, SeenAt: pipelineNow.Add(-8 * 24 * time.Hour), Count: 7},
+	} {
+		if err := log.UpsertSighting(context.Background(), s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+func TestPendingDevicesList(t *testing.T) {
+	store := newPendingDevicesStore(t)
+	pending := NewPendingDevices(duplicatedSightings{store.DeviceSightingLog()}, store.Employees(), store.Devices())
+	pending.SetClock(clock.NewFake(pipelineNow))
+	if err := pending.Ignore(context.Background(), "AA:BB:CC:DD:EE:0A"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeviceSightingLog().UpsertSighting(context.Background(), models.DeviceSighting{MacAddress: "aa:bb:cc:dd:ee:0d", RSSI: -58, SeenAt: pipelineNow, Count: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := pending.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, s := range got {
+		lines = append(lines, fmt.Sprintf("%s %d x%d", s.MacAddress, s.RSSI, s.Count))
+	}
+	want := []string{"aa:bb:cc:dd:ee:0b -50 x10", "aa:bb:cc:dd:ee:0d -58 x2"}
+	if fmt.Sprint(lines) != fmt.Sprint(want) {
+		t.Errorf("List() = %v, want %v", lines, want)
+	}
+}
+
+func TestPendingDevicesAssign(t *testing.T) {
+	tests := []struct {
+		name       string
+		mac        string
+		code       string
+		wantErr    error
+		wantOwner  string // employee ID the MAC resolves to afterwards
+		wantDevice bool   // assigned as an extra device
+	}{
+		{"employee without a device gets it as their MAC", "aa:bb:cc:dd:ee:0b", "N002", nil, "e2", false},
+		{"employee with a device gets an extra one", "aa:bb:cc:dd:ee:0b", "N003", nil, "e3", true},
+		{"unknown code", "aa:bb:cc:dd:ee:0b", "N999", repository.ErrEmployeeNotFound, "", false},
+		{"inactive employee", "aa:bb:cc:dd:ee:0b", "N004", repository.ErrEmployeeNotFound, "", false},
+		{"device owned by someone else", "aa:bb:cc:dd:ee:01", "N002", ErrDeviceAssigned, "e1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newPendingDevicesStore(t)
+			pending := NewPendingDevices(store.DeviceSightingLog(), store.Employees(), store.Devices())
+			pending.SetDevices(store.Devices())
+			pending.SetClock(clock.NewFake(pipelineNow))
+
+			emp, err := pending.Assign(context.Background(), tt.mac, tt.code)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Assign() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && emp.ID != tt.wantOwner {
+				t.Errorf("Assign() = %s, want %s", emp.ID, tt.wantOwner)
+			}
+
+			owner := ""
+			if emp, err := store.Devices().GetEmployeeByDeviceMac(context.Background(), tt.mac); err == nil {
+				owner = emp.ID
+				if !tt.wantDevice {
+					t.Errorf("%s was added as an extra device of %s", tt.mac, owner)
+				}
+			} else if emp, err := store.Employees().GetByMacAddress(context.Background(), tt.mac); err == nil {
+				owner = emp.ID
+			}
+			if owner != tt.wantOwner {
+				t.Errorf("%s belongs to %q, want %q", tt.mac, owner, tt.wantOwner)
+			}
+
+			reviewed := true
+			for _, s := range store.DeviceSightings() {
+				if s.MacAddress == tt.mac {
+					reviewed = s.Reviewed && s.Whitelisted
+				}
+			}
+			if reviewed != (tt.wantErr == nil) {
+				t.Errorf("%s whitelisted = %v, want %v", tt.mac, reviewed, tt.wantErr == nil)
+			}
+		})
+	}
+}
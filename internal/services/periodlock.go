@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// PeriodLockCallbackPrefix routes the approval buttons of lock and unlock requests
+const PeriodLockCallbackPrefix = "plock"
+
+// periodLockRequestTTL is how long a lock or unlock request waits for its second admin
+const periodLockRequestTTL = 24 * time.Hour
+
+// Period lock request errors
+var (
+	ErrPeriodLockSameApprover = errors.New("the request must be approved by a different admin")
+	ErrPeriodLockNotFound     = errors.New("the request is unknown or has expired")
+)
+
+// PeriodLocking locks payroll periods so their attendance can no longer change. Every
+// lock and unlock needs two admins: one requests it, a different one approves it. The
+// approved change is stored with its history and announced in the admin chat.
+type PeriodLocking struct {
+	locks    repository.PeriodLockRepository
+	notifier BotNotifier
+	clock    clock.Clock
+
+	mu      sync.Mutex
+	pending map[string]*models.PeriodLockRequest
+}
+
+// NewPeriodLocking creates the period locking service
+func NewPeriodLocking(locks repository.PeriodLockRepository, notifier BotNotifier) *PeriodLocking {
+	return &PeriodLocking{
+		locks:    locks,
+		notifier: notifier,
+		clock:    clock.Real{},
+		pending:  make(map[string]*models.PeriodLockRequest),
+	}
+}
+
+// SetClock replaces the time source, used by tests
+func (p *PeriodLocking) SetClock(c clock.Clock) {
+	p.clock = c
+}
+
+// Request records a lock or unlock of period ("2026-01") for a second admin to approve
+func (p *PeriodLocking) Request(ctx context.Context, action, period string, by models.PeriodLockActor) (*models.PeriodLockRequest, error) {
+	if action != models.PeriodActionLock && action != models.PeriodActionUnlock {
+		return nil, fmt.Errorf("unknown action %q", action)
+	}
+	if _, err := time.Parse(models.PeriodLayout, period); err != nil {
+		return nil, fmt.Errorf("period must be YYYY-MM, got %q", period)
+	}
+
+	lock, err := p.locks.Get(ctx, period)
+	if err != nil {
+		return nil, err
+	}
+	locked := lock != nil && lock.Locked
+	switch {
+	case action == models.PeriodActionLock && locked:
+		return nil, fmt.Errorf("period %s is already locked", period)
+	case action == models.PeriodActionUnlock && !locked:
+		return nil, fmt.Errorf("period %s is not locked", period)
+	}
+
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	now := p.clock.Now()
+	req := &models.PeriodLockRequest{
+		ID:        hex.EncodeToString(id),
+		Action:    action,
+		Period:    period,
+		Requester: by,
+		Expires:   now.Add(periodLockRequestTTL),
+	}
+
+	p.mu.Lock()
+	p.prune(now)
+	p.pending[req.ID] = req
+	p.mu.Unlock()
+
+	log.Printf("🔐 %s of period %s requested by %d, awaiting a second admin", action, period, by.UserID)
+	return req, nil
+}
+
+// Approve applies a pending request. The approver must differ from the requester.
+func (p *PeriodLocking) Approve(ctx context.Context, id string, by models.PeriodLockActor) (*models.LockedPeriod, error) {
+	now := p.clock.Now()
+	p.mu.Lock()
+	p.prune(now)
+	req, ok := p.pending[id]
+	switch {
+	case !ok:
+		p.mu.Unlock()
+		return nil, ErrPeriodLockNotFound
+	case req.Requester.UserID == by.UserID:
+		p.mu.Unlock()
+		return nil, ErrPeriodLockSameApprover
+	}
+	// Claimed before writing so a second press cannot apply it twice
+	delete(p.pending, id)
+	p.mu.Unlock()
+
+	lock, err := p.apply(ctx, req, by, now)
+	if err != nil {
+		p.mu.Lock()
+		p.pending[id] = req
+		p.mu.Unlock()
+		return nil, err
+	}
+
+	log.Printf("🔐 Period %s %sed: requested by %d, approved by %d", req.Period, req.Action, req.Requester.UserID, by.UserID)
+	verb := "ล็อก"
+	if req.Action == models.PeriodActionUnlock {
+		verb = "ปลดล็อก"
+	}
+	p.notifier.SendNotification(fmt.Sprintf("🔐 *%sงวด %s แล้ว*\n\nขอโดย: %s\nอนุมัติโดย: %s\nเวลา: `%s`",
+		verb, req.Period, markdown.Escape(actorName(req.Requester)), markdown.Escape(actorName(by)), now.Format("02/01/2006 15:04")))
+	return lock, nil
+}
+
+// Cancel drops a pending request; either admin may cancel it
+func (p *PeriodLocking) Cancel(id string, by models.PeriodLockActor) (*models.PeriodLockRequest, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	req, ok := p.pending[id]
+	if !ok {
+		return nil, ErrPeriodLockNotFound
+	}
+	delete(p.pending, id)
+	log.Printf("🔐 %s of period %s cancelled by %d", req.Action, req.Period, by.UserID)
+	return req, nil
+}
+
+// Locked returns the currently locked periods, oldest first
+func (p *PeriodLocking) Locked(ctx context.Context) ([]models.LockedPeriod, error) {
+	return p.locks.ListLocked(ctx)
+}
+
+// apply stores the approved change with its audit entry
+func (p *PeriodLocking) apply(ctx context.Context, req *models.PeriodLockRequest, by models.PeriodLockActor, now time.Time) (*models.LockedPeriod, error) {
+	lock, err := p.locks.Get(ctx, req.Period)
+	if err != nil {
+		return nil, err
+	}
+	if lock == nil {
+		lock = &models.LockedPeriod{Period: req.Period}
+	}
+	if lock.Locked == (req.Action == models.PeriodActionLock) {
+		return nil, fmt.Errorf("period %s is already %sed", req.Period, req.Action)
+	}
+
+	lock.Locked = req.Action == models.PeriodActionLock
+	lock.LockedAt = time.Time{}
+	if lock.Locked {
+		lock.LockedAt = now
+	}
+	lock.History = append(lock.History, models.PeriodLockEvent{
+		Action:        req.Action,
+		RequestedBy:   req.Requester.UserID,
+		RequesterName: req.Requester.Name,
+		ApprovedBy:    by.UserID,
+		ApproverName:  by.Name,
+		At:            now,
+	})
+	if err := p.locks.Save(ctx, lock); err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+// prune forgets expired requests; callers hold mu
+func (p *PeriodLocking) prune(now time.Time) {
+	for id, req := range p.pending {
+		if now.After(req.Expires) {
+			delete(p.pending, id)
+		}
+	}
+}
+
+// actorName is how an admin is named in the admin chat
+func actorName(a models.PeriodLockActor) string {
+	if a.Name != "" {
+		return a.Name
+	}
+	return fmt.Sprintf("`%d`", a.UserID)
+}
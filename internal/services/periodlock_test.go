@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+func TestPeriodLocking(t *testing.T) {
+	ctx := context.Background()
+	alice := models.PeriodLockActor{UserID: 1, Name: "Alice"}
+	bob := models.PeriodLockActor{UserID: 2, Name: "Bob"}
+	jan := time.Date(2026, 1, 15, 8, 0, 0, 0, time.UTC)
+	feb := time.Date(2026, 2, 3, 8, 0, 0, 0, time.UTC)
+
+	setup := func() (*memory.Store, *clock.Fake, *recordingNotifier, *PeriodLocking, models.Attendance) {
+		clk := clock.NewFake(time.Date(2026, 2, 5, 10, 0, 0, 0, time.UTC))
+		store := memory.NewStore(clk)
+		existing := models.Attendance{EmployeeID: "e1", CheckInTime: jan, CreatedDate: jan, Status: "ontime"}
+		store.AttendanceRecords().Create(ctx, &existing)
+		notifier := newRecordingNotifier()
+		locking := NewPeriodLocking(store.PeriodLocks(), notifier)
+		locking.SetClock(clk)
+		return store, clk, notifier, locking, existing
+	}
+
+	lock := func(t *testing.T, locking *PeriodLocking, action string) {
+		t.Helper()
+		req, err := locking.Request(ctx, action, "2026-01", alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := locking.Approve(ctx, req.ID, alice); !errors.Is(err, ErrPeriodLockSameApprover) {
+			t.Fatalf("self-approval error = %v, want ErrPeriodLockSameApprover", err)
+		}
+		if _, err := locking.Approve(ctx, req.ID, bob); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := locking.Approve(ctx, req.ID, bob); !errors.Is(err, ErrPeriodLockNotFound) {
+			t.Fatalf("second approval error = %v, want ErrPeriodLockNotFound", err)
+		}
+	}
+
+	t.Run("locked period refuses every attendance write", func(t *testing.T) {
+		store, _, notifier, locking, existing := setup()
+		lock(t, locking, models.PeriodActionLock)
+
+		if len(notifier.admin) != 1 {
+			t.Errorf("admin messages = %q, want one audit notice", notifier.admin)
+		}
+		attendance := store.AttendanceRecords()
+		if err := attendance.Create(ctx, &models.Attendance{EmployeeID: "e2", CheckInTime: jan, CreatedDate: jan}); !errors.Is(err, repository.ErrPeriodLocked) {
+			t.Errorf("Create in January = %v, want ErrPeriodLocked", err)
+		}
+		existing.CheckOutTime = jan.Add(9 * time.Hour)
+		if err := attendance.UpdateCheckOut(ctx, &existing); !errors.Is(err, repository.ErrPeriodLocked) {
+			t.Errorf("UpdateCheckOut in January = %v, want ErrPeriodLocked", err)
+		}
+		if err := attendance.Create(ctx, &models.Attendance{EmployeeID: "e2", CheckInTime: feb, CreatedDate: feb}); err != nil {
+			t.Errorf("Create in February: %v", err)
+		}
+
+		store.AddEmployee(models.Employee{EmployeeCode: "E001", Name: "Somchai", WorkStartTime: "08:00:00", IsActive: true})
+		report, err := NewLegacyImporter(store.Employees(), attendance, time.UTC).Import(ctx, []LegacyRow{
+			{Line: 2, EmployeeCode: "E001", Date: "2026-01-20", In: "08:00"},
+		}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := report.Results[0].Outcome; got != ImportLocked {
+			t.Errorf("import outcome = %s, want %s", got, ImportLocked)
+		}
+
+		if _, err := locking.Request(ctx, models.PeriodActionLock, "2026-01", alice); err == nil {
+			t.Error("locking an already locked period should fail")
+		}
+	})
+
+	t.Run("unlock is approved and audited", func(t *testing.T) {
+		store, _, _, locking, existing := setup()
+		lock(t, locking, models.PeriodActionLock)
+		lock(t, locking, models.PeriodActionUnlock)
+
+		existing.CheckOutTime = jan.Add(9 * time.Hour)
+		if err := store.AttendanceRecords().UpdateCheckOut(ctx, &existing); err != nil {
+			t.Errorf("UpdateCheckOut after unlock: %v", err)
+		}
+		record, _ := store.PeriodLocks().Get(ctx, "2026-01")
+		if record == nil || record.Locked || len(record.History) != 2 || record.History[1].Action != models.PeriodActionUnlock ||
+			record.History[1].RequestedBy != alice.UserID || record.History[1].ApprovedBy != bob.UserID {
+			t.Errorf("record = %+v, want unlocked with lock and unlock in its history", record)
+		}
+	})
+
+	t.Run("requests expire and can be cancelled", func(t *testing.T) {
+		_, clk, _, locking, _ := setup()
+		req, err := locking.Request(ctx, models.PeriodActionLock, "2026-01", alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		clk.Advance(25 * time.Hour)
+		if _, err := locking.Approve(ctx, req.ID, bob); !errors.Is(err, ErrPeriodLockNotFound) {
+			t.Errorf("expired approval error = %v, want ErrPeriodLockNotFound", err)
+		}
+
+		req, _ = locking.Request(ctx, models.PeriodActionLock, "2026-01", alice)
+		if _, err := locking.Cancel(req.ID, bob); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := locking.Approve(ctx, req.ID, bob); !errors.Is(err, ErrPeriodLockNotFound) {
+			t.Errorf("cancelled approval error = %v, want ErrPeriodLockNotFound", err)
+		}
+		if _, err := locking.Request(ctx, models.PeriodActionLock, "January", alice); err == nil {
+			t.Error("expected an error for a malformed period")
+		}
+	})
+}
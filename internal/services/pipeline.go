@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"med-pulse-bot/internal/logging"
+	"med-pulse-bot/internal/models"
+)
+
+// DetectionContext carries one detection through the pipeline. Stages read the
+// request and fill in what they learn for the stages after them.
+type DetectionContext struct {
+	Request    *models.DetectionRequest
+	Now        time.Time
+	Employee   *models.Employee   // set by the employee match stage
+	Identity   string             // IdentityMAC, IdentityIBeacon or IdentityEddystone; set with Employee
+	Attendance *models.Attendance // set by the attendance stage
+
+	// Result and Threshold are set by a stage that rejects the detection, for the scanner
+	Result    string
+	Threshold *int
+
+	// Timeline records the outcome of every stage that ran, in order
+	Timeline []StageOutcome
+	note     string
+	unlock   func() // releases the employee's check-in lock at the end of the run
+}
+
+// StageOutcome is what one stage decided about a detection
+type StageOutcome struct {
+	Stage    string
+	Continue bool
+	Note     string
+	Err      error
+}
+
+// Notef attaches a short explanation to the outcome of the current stage
+func (dc *DetectionContext) Notef(format string, args ...interface{}) {
+	dc.note = fmt.Sprintf(format, args...)
+}
+
+// Reject records why the detection was turned away and the limit that applied, if any
+func (dc *DetectionContext) Reject(result string, threshold *int) {
+	dc.Result = result
+	dc.Threshold = threshold
+}
+
+// Stage is one step of detection processing. Returning false stops the pipeline
+// without an error (the detection is deliberately ignored).
+type Stage interface {
+	Process(ctx context.Context, dc *DetectionContext) (bool, error)
+}
+
+// StageFunc adapts a function to the Stage interface
+type StageFunc func(ctx context.Context, dc *DetectionContext) (bool, error)
+
+// Process calls f
+func (f StageFunc) Process(ctx context.Context, dc *DetectionContext) (bool, error) {
+	return f(ctx, dc)
+}
+
+type namedStage struct {
+	name  string
+	stage Stage
+}
+
+// Pipeline runs named stages in order until one stops or fails
+type Pipeline struct {
+	stages []namedStage
+}
+
+// NewPipeline creates an empty pipeline
+func NewPipeline() *Pipeline {
+	return &Pipeline{}
+}
+
+// Use appends a stage
+func (p *Pipeline) Use(name string, stage Stage) *Pipeline {
+	p.stages = append(p.stages, namedStage{name: name, stage: stage})
+	return p
+}
+
+// Stages returns the stage names in execution order
+func (p *Pipeline) Stages() []string {
+	names := make([]string, len(p.stages))
+	for i, s := range p.stages {
+		names[i] = s.name
+	}
+	return names
+}
+
+// Run processes the detection, recording every stage outcome in dc.Timeline
+func (p *Pipeline) Run(ctx context.Context, dc *DetectionContext) error {
+	defer dc.releaseLock()
+	for _, s := range p.stages {
+		dc.note = ""
+		cont, err := s.stage.Process(ctx, dc)
+		dc.Timeline = append(dc.Timeline, StageOutcome{Stage: s.name, Continue: cont && err == nil, Note: dc.note, Err: err})
+		if err != nil {
+			return err
+		}
+		if !cont {
+			break
+		}
+	}
+	return nil
+}
+
+// releaseLock releases the employee's check-in lock if a stage took it
+func (dc *DetectionContext) releaseLock() {
+	if dc.unlock != nil {
+		dc.unlock()
+		dc.unlock = nil
+	}
+}
+
+// String renders the timeline compactly for logs, e.g. "match ✓ → proximity ✗ (rssi -75 < -70)"
+func (dc *DetectionContext) String() string {
+	parts := make([]string, len(dc.Timeline))
+	for i, o := range dc.Timeline {
+		mark := "✓"
+		switch {
+		case o.Err != nil:
+			mark = "❌"
+		case !o.Continue:
+			mark = "✗"
+		}
+		part := o.Stage + " " + mark
+		if o.Err != nil {
+			part += " (" + o.Err.Error() + ")"
+		} else if o.Note != "" {
+			part += " (" + o.Note + ")"
+		}
+		parts[i] = part
+	}
+	return strings.Join(parts, " → ")
+}
+
+// logTimeline logs the timeline of detections that reached an employee
+func logTimeline(ctx context.Context, dc *DetectionContext) {
+	if dc.Employee == nil {
+		return
+	}
+	logging.From(ctx).Info("🧭 Detection timeline", "employee", dc.Employee.Name, "timeline", dc.String())
+}
@@ -0,0 +1,406 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+var pipelineNow = time.Date(2026, 2, 2, 8, 0, 0, 0, time.Local)
+
+func newPipelineStore() *memory.Store {
+	store := memory.NewStore(clock.NewFake(pipelineNow))
+	store.AddEmployee(models.Employee{
+		ID: "e1", Name: "Somchai", MacAddress: "aa:bb:cc:dd:ee:01",
+		TelegramChatID: 1001, WorkStartTime: "08:00:00", IsActive: true,
+	})
+	return store
+}
+
+func newDetectionContext(mac string, rssi int) *DetectionContext {
+	return &DetectionContext{
+		Request: &models.DetectionRequest{ScannerMac: "scanner-1", MacAddress: mac, RSSI: rssi},
+		Now:     pipelineNow,
+	}
+}
+
+func TestPipeline_Run(t *testing.T) {
+	stage := func(cont bool, err error) Stage {
+		return StageFunc(func(ctx context.Context, dc *DetectionContext) (bool, error) {
+			dc.Notef("note")
+			return cont, err
+		})
+	}
+	boom := errors.New("boom")
+
+	tests := []struct {
+		name      string
+		stages    []Stage
+		wantErr   error
+		wantTrace string
+	}{
+		{"all continue", []Stage{stage(true, nil), stage(true, nil)}, nil, "a ✓ (note) → b ✓ (note)"},
+		{"stop halts", []Stage{stage(false, nil), stage(true, nil)}, nil, "a ✗ (note)"},
+		{"error halts", []Stage{stage(true, boom), stage(true, nil)}, boom, "a ❌ (boom)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPipeline().Use("a", tt.stages[0]).Use("b", tt.stages[1])
+			dc := newDetectionContext("aa:bb:cc:dd:ee:01", -50)
+
+			if err := p.Run(context.Background(), dc); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Run() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := dc.String(); got != tt.wantTrace {
+				t.Errorf("timeline = %q, want %q", got, tt.wantTrace)
+			}
+		})
+	}
+}
+
+func TestNewDetectionPipeline_Stages(t *testing.T) {
+	store := newPipelineStore()
+	base := PipelineOptions{
+		Employees:     store.Employees(),
+		Attendance:    store.AttendanceRecords(),
+		Detections:    store.DetectionRecords(),
+		Notifier:      newRecordingNotifier(),
+		RSSIThreshold: DefaultRSSIThreshold,
+	}
+
+	got := NewDetectionPipeline(base).Stages()
+	want := []string{"normalize", "employee_match", "proximity", "dedupe", "detection_log", "attendance", "notification"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("default stages = %v, want %v", got, want)
+	}
+
+	withOptional := base
+	withOptional.Stationary, _ = NewStationaryTagDetector(DefaultStationaryTagConfig(), newRecordingNotifier())
+	withOptional.Baselines, _ = NewCheckInBaselines(store.Baselines(), 3)
+	withOptional.Recent = NewRecentDetections(DefaultRecentDetectionTTL)
+	withOptional.Window, _ = NewCheckInWindow("05:00", "12:00")
+	got = NewDetectionPipeline(withOptional).Stages()
+	want = []string{"normalize", "recent", "employee_match", "stationary_observe", "proximity", "dedupe",
+		"stationary_confirm", "detection_log", "checkin_window", "attendance", "baseline", "notification"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stages with optional = %v, want %v", got, want)
+	}
+}
+
+func TestNormalizeStage(t *testing.T) {
+	tests := []struct {
+		mac      string
+		wantCont bool
+		wantMAC  string
+	}{
+		{"AA-BB-CC-DD-EE-01", true, "aa:bb:cc:dd:ee:01"},
+		{"aabb.ccdd.ee01", true, "aa:bb:cc:dd:ee:01"},
+		{"not-a-mac", false, "not-a-mac"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mac, func(t *testing.T) {
+			dc := newDetectionContext(tt.mac, -50)
+			cont, err := NormalizeStage{}.Process(context.Background(), dc)
+			if err != nil || cont != tt.wantCont {
+				t.Fatalf("Process() = %v, %v, want %v, nil", cont, err, tt.wantCont)
+			}
+			if dc.Request.MacAddress != tt.wantMAC {
+				t.Errorf("mac = %s, want %s", dc.Request.MacAddress, tt.wantMAC)
+			}
+		})
+	}
+}
+
+func TestEmployeeMatchStage(t *testing.T) {
+	stage := EmployeeMatchStage{Employees: newPipelineStore().Employees()}
+
+	t.Run("registered device", func(t *testing.T) {
+		dc := newDetectionContext("aa:bb:cc:dd:ee:01", -50)
+		cont, err := stage.Process(context.Background(), dc)
+		if err != nil || !cont {
+			t.Fatalf("Process() = %v, %v, want true, nil", cont, err)
+		}
+		if dc.Employee == nil || dc.Employee.ID != "e1" {
+			t.Errorf("employee = %+v, want e1", dc.Employee)
+		}
+		if !dc.Request.IsTargetDevice || dc.Request.DeviceName != "Somchai" {
+			t.Errorf("request not marked as target device: %+v", dc.Request)
+		}
+	})
+
+	t.Run("unknown device", func(t *testing.T) {
+		dc := newDetectionContext("11:22:33:44:55:66", -50)
+		cont, err := stage.Process(context.Background(), dc)
+		if err != nil || cont {
+			t.Fatalf("Process() = %v, %v, want false, nil", cont, err)
+		}
+	})
+
+	t.Run("failed lookup", func(t *testing.T) {
+		for _, lookupErr := range []error{repository.ErrUnavailable, repository.ErrUnauthorized} {
+			stage := EmployeeMatchStage{Employees: failingEmployees{newPipelineStore().Employees(), lookupErr}}
+			dc := newDetectionContext("aa:bb:cc:dd:ee:01", -50)
+			cont, err := stage.Process(context.Background(), dc)
+			if cont || !errors.Is(err, lookupErr) || dc.Result == ResultUnknownDevice {
+				t.Errorf("Process() = %v, %v with result %q, want the %v passed on", cont, err, dc.Result, lookupErr)
+			}
+		}
+	})
+}
+
+// failingEmployees fails every MAC lookup with err, as PocketBase does when it is down
+// or refuses the token
+type failingEmployees struct {
+	repository.EmployeeRepository
+	err error
+}
+
+func (f failingEmployees) GetByMacAddress(ctx context.Context, mac string) (*models.Employee, error) {
+	return nil, fmt.Errorf("failed to get employee: %w", f.err)
+}
+
+func TestProximityStage(t *testing.T) {
+	tests := []struct {
+		rssi     int
+		wantCont bool
+	}{
+		{-50, true},
+		{-70, true},
+		{-71, false},
+	}
+
+	for _, tt := range tests {
+		dc := newDetectionContext("aa:bb:cc:dd:ee:01", tt.rssi)
+		cont, _ := ProximityStage{Threshold: DefaultRSSIThreshold}.Process(context.Background(), dc)
+		if cont != tt.wantCont {
+			t.Errorf("rssi %d: continue = %v, want %v", tt.rssi, cont, tt.wantCont)
+		}
+	}
+}
+
+func TestDedupeStage(t *testing.T) {
+	store := newPipelineStore()
+	stage := DedupeStage{Employees: store.Employees()}
+	ctx := context.Background()
+
+	dc := newDetectionContext("aa:bb:cc:dd:ee:01", -50)
+	dc.Employee = &models.Employee{ID: "e1"}
+	if cont, err := stage.Process(ctx, dc); err != nil || !cont {
+		t.Fatalf("first check-in: Process() = %v, %v, want true, nil", cont, err)
+	}
+
+	store.AttendanceRecords().Create(ctx, &models.Attendance{EmployeeID: "e1", CheckInTime: pipelineNow, CreatedDate: pipelineNow})
+	if cont, err := stage.Process(ctx, dc); err != nil || cont {
+		t.Fatalf("already checked in: Process() = %v, %v, want false, nil", cont, err)
+	}
+}
+
+func TestStationaryConfirmStage(t *testing.T) {
+	detector, _ := NewStationaryTagDetector(DefaultStationaryTagConfig(), newRecordingNotifier())
+	dc := newDetectionContext("aa:bb:cc:dd:ee:01", -50)
+	dc.Employee = &models.Employee{ID: "e1", Name: "Somchai"}
+
+	// Without an overnight flag the check-in goes ahead
+	cont, err := StationaryConfirmStage{Detector: detector}.Process(context.Background(), dc)
+	if err != nil || !cont {
+		t.Fatalf("Process() = %v, %v, want true, nil", cont, err)
+	}
+}
+
+func TestDetectionLogAndAttendanceStages(t *testing.T) {
+	store := newPipelineStore()
+	ctx := context.Background()
+
+	dc := newDetectionContext("aa:bb:cc:dd:ee:01", -60)
+	dc.Now = pipelineNow.Add(10 * time.Minute)
+	dc.Employee = &models.Employee{ID: "e1", Name: "Somchai", WorkStartTime: "08:00:00"}
+
+	if cont, err := (DetectionLogStage{Detections: store.DetectionRecords()}).Process(ctx, dc); err != nil || !cont {
+		t.Fatalf("DetectionLogStage = %v, %v", cont, err)
+	}
+	if cont, err := (AttendanceStage{Attendance: store.AttendanceRecords()}).Process(ctx, dc); err != nil || !cont {
+		t.Fatalf("AttendanceStage = %v, %v", cont, err)
+	}
+
+	if got := store.Detections(); len(got) != 1 || got[0].RSSI != -60 || !got[0].DetectedAt.Equal(dc.Now) {
+		t.Errorf("detections = %+v", got)
+	}
+	if dc.Attendance == nil || dc.Attendance.Status != "late" || len(store.Attendance()) != 1 {
+		t.Errorf("attendance = %+v, want one late record", dc.Attendance)
+	}
+}
+
+func TestNotificationStage(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    string
+		wantAdmin int
+	}{
+		{"on time notifies employee only", "ontime", 0},
+		{"late also alerts admin", "late", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier := newRecordingNotifier()
+			dc := newDetectionContext("aa:bb:cc:dd:ee:01", -60)
+			dc.Employee = &models.Employee{ID: "e1", Name: "Somchai", TelegramChatID: 1001, WorkStartTime: "08:00:00"}
+			dc.Attendance = &models.Attendance{EmployeeID: "e1", CheckInTime: pipelineNow.Add(30 * time.Minute), ScannerMac: "scanner-1", Status: tt.status}
+
+			NotificationStage{Notifier: notifier}.Process(context.Background(), dc)
+
+			if len(notifier.personal[1001]) != 1 || !strings.Contains(notifier.personal[1001][0], "Scanner scanner-1") {
+				t.Errorf("personal = %v", notifier.personal)
+			}
+			if len(notifier.admin) != tt.wantAdmin {
+				t.Errorf("admin messages = %d, want %d", len(notifier.admin), tt.wantAdmin)
+			}
+		})
+	}
+}
+
+func TestAttendanceService_Timeline(t *testing.T) {
+	store := newPipelineStore()
+	service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), newRecordingNotifier())
+	service.SetClock(clock.NewFake(pipelineNow))
+
+	dc := &DetectionContext{Request: &models.DetectionRequest{ScannerMac: "scanner-1", MacAddress: "AA:BB:CC:DD:EE:01", RSSI: -75}, Now: pipelineNow}
+	if err := service.Pipeline().Run(context.Background(), dc); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if want := "normalize ✓ → employee_match ✓ → proximity ✗ (rssi -75 < -70)"; dc.String() != want {
+		t.Errorf("timeline = %q, want %q", dc.String(), want)
+	}
+}
+
+func TestAttendanceService_SetRSSIThreshold(t *testing.T) {
+	tests := []struct {
+		name        string
+		threshold   int // 0 keeps the default
+		wantCheckIn bool
+	}{
+		{"default drops -75", 0, false},
+		{"thick walls accept -75", -80, true},
+		{"stricter drops -75", -60, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newPipelineStore()
+			service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), newRecordingNotifier())
+			service.SetClock(clock.NewFake(pipelineNow))
+			if tt.threshold != 0 {
+				service.SetRSSIThreshold(tt.threshold)
+			}
+
+			req := &models.DetectionRequest{ScannerMac: "scanner-1", MacAddress: "aa:bb:cc:dd:ee:01", RSSI: -75}
+			if err := service.ProcessDetection(context.Background(), req); err != nil {
+				t.Fatalf("ProcessDetection() error = %v", err)
+			}
+			if got := len(store.Attendance()) == 1; got != tt.wantCheckIn {
+				t.Errorf("checked in = %v, want %v", got, tt.wantCheckIn)
+			}
+		})
+	}
+}
+
+func TestPresenceOptOutKeepsCheckInOnly(t *testing.T) {
+	store := memory.NewStore(clock.NewFake(pipelineNow))
+	store.AddEmployee(models.Employee{
+		ID: "e1", Name: "Somchai", MacAddress: "aa:bb:cc:dd:ee:01",
+		TelegramChatID: 1001, WorkStartTime: "08:00:00", IsActive: true, PresenceOptOut: true,
+	})
+	service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), newRecordingNotifier())
+	service.SetClock(clock.NewFake(pipelineNow))
+	stationary, _ := NewStationaryTagDetector(DefaultStationaryTagConfig(), newRecordingNotifier())
+	service.SetStationaryTagDetector(stationary)
+
+	req := &models.DetectionRequest{ScannerMac: "scanner-1", MacAddress: "aa:bb:cc:dd:ee:01", RSSI: -50}
+	if err := service.ProcessDetection(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := store.Attendance(); len(got) != 1 {
+		t.Errorf("attendance = %+v, want the check-in", got)
+	}
+	if got := store.Detections(); len(got) != 0 {
+		t.Errorf("detections = %+v, want none without consent", got)
+	}
+}
+
+func TestCheckInNotificationEscapesNames(t *testing.T) {
+	emp := &models.Employee{Name: "*Som_chai* [night`s] 2*2_", TelegramChatID: 1001, WorkStartTime: "08:00:00"}
+	tests := []struct {
+		status    string
+		wantAdmin int
+	}{
+		{models.AttendanceStatusOnTime, 0},
+		{models.AttendanceStatusOnTimeApproved, 0},
+		{models.AttendanceStatusOffDay, 0},
+		{models.AttendanceStatusLate, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			notifier := newRecordingNotifier()
+			sendCheckInNotification(context.Background(), notifier, nil, emp, &models.Attendance{
+				ScannerMac: "scanner_1", CheckInTime: pipelineNow.Add(20 * time.Minute),
+				CreatedDate: pipelineNow, Status: tt.status,
+			}, &models.Scanner{ScannerMac: "scanner_1", Name: "Lobby_1"})
+			if len(notifier.admin) != tt.wantAdmin {
+				t.Errorf("sent %d admin messages, want %d", len(notifier.admin), tt.wantAdmin)
+			}
+			for _, m := range append(notifier.personal[1001], notifier.admin...) {
+				if err := markdown.Check(m); err != nil {
+					t.Errorf("%q does not parse: %v", m, err)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckInNotificationPreferences(t *testing.T) {
+	tests := []struct {
+		name         string
+		optOut       bool
+		quietHours   string
+		checkInAt    string
+		status       string
+		wantPersonal int
+		wantAdmin    int
+	}{
+		{"default", false, "", "07:55", models.AttendanceStatusOnTime, 1, 0},
+		{"turned off", true, "", "07:55", models.AttendanceStatusOnTime, 0, 0},
+		{"turned off, late still reaches the admins", true, "", "08:20", models.AttendanceStatusLate, 0, 1},
+		{"outside quiet hours", false, "22:00-07:00", "07:55", models.AttendanceStatusOnTime, 1, 0},
+		{"quiet hours past midnight", false, "22:00-07:00", "06:30", models.AttendanceStatusOnTime, 0, 0},
+		{"quiet hours in the day", false, "12:00-13:00", "12:30", models.AttendanceStatusLate, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emp := &models.Employee{Name: "Somchai", TelegramChatID: 1001, WorkStartTime: "08:00:00",
+				CheckInOptOut: tt.optOut, QuietHours: tt.quietHours}
+			at, _ := time.ParseInLocation("15:04", tt.checkInAt, time.Local)
+			at = time.Date(2026, 2, 2, at.Hour(), at.Minute(), 0, 0, time.Local)
+			notifier := newRecordingNotifier()
+			sendCheckInNotification(context.Background(), notifier, nil, emp, &models.Attendance{CheckInTime: at, CreatedDate: pipelineNow, Status: tt.status}, &models.Scanner{})
+			if got := len(notifier.personal[1001]); got != tt.wantPersonal {
+				t.Errorf("sent %d personal messages, want %d", got, tt.wantPersonal)
+			}
+			if got := len(notifier.admin); got != tt.wantAdmin {
+				t.Errorf("sent %d admin messages, want %d", got, tt.wantAdmin)
+			}
+		})
+	}
+}
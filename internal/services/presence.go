@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// DefaultPresenceWindow is how far back /whoisin looks for detections
+const DefaultPresenceWindow = 15 * time.Minute
+
+// PresenceSampler decides which detections of employees who already checked in are still
+// saved: one per employee every interval, so recent detections show who is in without
+// logging every advertisement of every tag
+type PresenceSampler struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	last map[string]time.Time // employee ID → last saved detection
+}
+
+// NewPresenceSampler saves a detection of each checked-in employee at most once per interval
+func NewPresenceSampler(interval time.Duration) *PresenceSampler {
+	return &PresenceSampler{interval: interval, last: make(map[string]time.Time)}
+}
+
+// Due reports whether a detection of the employee at now should be saved, and if so
+// counts it as saved
+func (p *PresenceSampler) Due(employeeID string, now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if last, ok := p.last[employeeID]; ok && now.Sub(last) < p.interval && !now.Before(last) {
+		return false
+	}
+	p.last[employeeID] = now
+	// Forget employees not seen for a while so the map stays as small as the office
+	for id, at := range p.last {
+		if now.Sub(at) > 24*time.Hour {
+			delete(p.last, id)
+		}
+	}
+	return true
+}
+
+// mark records a detection of the employee saved at now outside the sampler, e.g. the
+// one that checked them in
+func (p *PresenceSampler) mark(employeeID string, now time.Time) {
+	p.mu.Lock()
+	p.last[employeeID] = now
+	p.mu.Unlock()
+}
+
+// PresentEmployee is an employee detected recently, with the scanner that heard them best
+type PresentEmployee struct {
+	Employee   models.Employee
+	LastSeen   time.Time
+	ScannerMac string // scanner with the strongest signal within the window
+	RSSI       int
+}
+
+// Presence tells who is in the office from the detections of the last few minutes
+type Presence struct {
+	employees  repository.EmployeeDirectory
+	detections repository.DetectionLog
+	clock      clock.Clock
+}
+
+// NewPresence creates the presence report
+func NewPresence(employees repository.EmployeeDirectory, detections repository.DetectionLog) *Presence {
+	return &Presence{employees: employees, detections: detections, clock: clock.Real{}}
+}
+
+// SetClock replaces the time source, used by tests
+func (p *Presence) SetClock(c clock.Clock) {
+	p.clock = c
+}
+
+// WhoIsIn returns the active employees detected within the window, most recently seen
+// first. Detections of deactivated employees are left out.
+func (p *Presence) WhoIsIn(ctx context.Context, window time.Duration) ([]PresentEmployee, error) {
+	detections, err := p.detections.ListRecent(ctx, p.clock.Now().Add(-window))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recent detections: %w", err)
+	}
+	if len(detections) == 0 {
+		return nil, nil
+	}
+	employees, err := p.employees.ListActive(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list employees: %w", err)
+	}
+	byID := make(map[string]models.Employee, len(employees))
+	for _, emp := range employees {
+		byID[emp.ID] = emp
+	}
+
+	present := make(map[string]*PresentEmployee)
+	for _, d := range detections {
+		emp, ok := byID[d.EmployeeID]
+		if !ok {
+			continue
+		}
+		entry, ok := present[d.EmployeeID]
+		if !ok {
+			present[d.EmployeeID] = &PresentEmployee{Employee: emp, LastSeen: d.DetectedAt, ScannerMac: d.ScannerMac, RSSI: d.RSSI}
+			continue
+		}
+		if d.DetectedAt.After(entry.LastSeen) {
+			entry.LastSeen = d.DetectedAt
+		}
+		if d.RSSI > entry.RSSI {
+			entry.ScannerMac, entry.RSSI = d.ScannerMac, d.RSSI
+		}
+	}
+
+	out := make([]PresentEmployee, 0, len(present))
+	for _, entry := range present {
+		out = append(out, *entry)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].LastSeen.Equal(out[j].LastSeen) {
+			return out[i].LastSeen.After(out[j].LastSeen)
+		}
+		return out[i].Employee.Name < out[j].Employee.Name
+	})
+	return out, nil
+}
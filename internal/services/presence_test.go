@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+)
+
+func TestPresence_WhoIsIn(t *testing.T) {
+	clk := clock.NewFake(pipelineNow)
+	store := newPipelineStore()
+	store.AddEmployee(models.Employee{ID: "e2", Name: "Malee", MacAddress: "aa:bb:cc:dd:ee:02", TelegramChatID: 1002,
+		WorkStartTime: "08:00:00", IsActive: true})
+	store.AddEmployee(models.Employee{ID: "e3", Name: "Anong", MacAddress: "aa:bb:cc:dd:ee:03", TelegramChatID: 1003,
+		WorkStartTime: "08:00:00", IsActive: true, PresenceOptOut: true})
+	service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), newRecordingNotifier())
+	service.SetClock(clk)
+	service.SetPresenceSampler(NewPresenceSampler(5 * time.Minute))
+	presence := NewPresence(store.Employees(), store.DetectionRecords())
+	presence.SetClock(clk)
+
+	steps := []struct {
+		at      time.Duration // since pipelineNow
+		scanner string
+		mac     string
+		rssi    int
+	}{
+		{0, "scanner-1", "aa:bb:cc:dd:ee:01", -60},               // check-in, saved
+		{0, "scanner-1", "aa:bb:cc:dd:ee:03", -50},               // check-in of the opted-out employee, not saved
+		{2 * time.Minute, "scanner-2", "aa:bb:cc:dd:ee:01", -40}, // within the interval, not saved
+		{3 * time.Minute, "scanner-1", "aa:bb:cc:dd:ee:02", -55}, // check-in, saved
+		{6 * time.Minute, "scanner-2", "aa:bb:cc:dd:ee:01", -45}, // presence sample, saved
+		{7 * time.Minute, "scanner-1", "aa:bb:cc:dd:ee:03", -50}, // opted out, not saved
+	}
+	for _, st := range steps {
+		clk.Set(pipelineNow.Add(st.at))
+		if _, err := service.DetectWithResult(context.Background(), &models.DetectionRequest{ScannerMac: st.scanner, MacAddress: st.mac, RSSI: st.rssi}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(store.Detections()); n != 3 {
+		t.Errorf("saved %d detections, want 3", n)
+	}
+
+	clk.Set(pipelineNow.Add(10 * time.Minute))
+	present, err := presence.WhoIsIn(context.Background(), DefaultPresenceWindow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(present) != 2 {
+		t.Fatalf("present = %+v, want Somchai and Malee", present)
+	}
+	if p := present[0]; p.Employee.ID != "e1" || !p.LastSeen.Equal(pipelineNow.Add(6*time.Minute)) || p.ScannerMac != "scanner-2" || p.RSSI != -45 {
+		t.Errorf("first = %+v, want e1 last seen 08:06 strongest at scanner-2", p)
+	}
+	if present[1].Employee.ID != "e2" {
+		t.Errorf("second = %+v, want e2", present[1])
+	}
+
+	clk.Set(pipelineNow.Add(time.Hour))
+	if present, err := presence.WhoIsIn(context.Background(), DefaultPresenceWindow); err != nil || len(present) != 0 {
+		t.Errorf("an hour later present = %+v, %v, want nobody", present, err)
+	}
+}
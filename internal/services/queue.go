@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/localstore"
+	"med-pulse-bot/internal/logging"
+	"med-pulse-bot/internal/macaddr"
+	"med-pulse-bot/internal/models"
+)
+
+// WriteGate reports whether writes to PocketBase are suspended for maintenance
+type WriteGate interface {
+	ReadOnly() bool
+}
+
+// QueuedDetection is a detection held back while writes were suspended
+type QueuedDetection struct {
+	At      time.Time               `json:"at"`
+	Request models.DetectionRequest `json:"request"`
+}
+
+// detectionQueueKey is the local store list holding queued detections
+const detectionQueueKey = "detection_queue.jsonl"
+
+// drainRateWindow is the trailing window over which a queue's drain rate is reported
+const drainRateWindow = time.Hour
+
+// DetectionQueue keeps detections in the local store until they can be processed
+type DetectionQueue struct {
+	store localstore.LocalStore
+	mu    sync.Mutex
+
+	drainMu sync.Mutex
+	drained []drainEvent // drains within drainRateWindow, oldest first
+}
+
+// drainEvent is one drain that processed entries
+type drainEvent struct {
+	at time.Time
+	n  int
+}
+
+// NewDetectionQueue creates a queue kept in store
+func NewDetectionQueue(store localstore.LocalStore) *DetectionQueue {
+	return &DetectionQueue{store: store}
+}
+
+// Enqueue appends a detection seen at the given time
+func (q *DetectionQueue) Enqueue(ctx context.Context, req *models.DetectionRequest, at time.Time) error {
+	line, err := json.Marshal(QueuedDetection{At: at, Request: *req})
+	if err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.store.Append(ctx, detectionQueueKey, line)
+}
+
+// Len returns the number of queued detections
+func (q *DetectionQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	entries, _ := q.read(context.Background())
+	return len(entries)
+}
+
+// ErrDrainStopped is returned by a drain callback to stop draining and keep the rest queued
+var ErrDrainStopped = errors.New("drain stopped")
+
+// Drain processes queued detections oldest first. Entries whose processing fails stay
+// queued for the next drain, as does everything left when ctx is done or the callback
+// returns ErrDrainStopped.
+func (q *DetectionQueue) Drain(ctx context.Context, process func(ctx context.Context, d QueuedDetection) error) (int, error) {
+	q.mu.Lock()
+	entries, err := q.read(ctx)
+	if err == nil && len(entries) > 0 {
+		err = q.store.Delete(ctx, detectionQueueKey)
+	}
+	q.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	var failed [][]byte
+	processed := 0
+	for i, d := range entries {
+		err := ctx.Err()
+		if err == nil {
+			err = process(ctx, d)
+		}
+		if err != nil && (ctx.Err() != nil || errors.Is(err, ErrDrainStopped)) {
+			for _, rest := range entries[i:] {
+				line, _ := json.Marshal(rest)
+				failed = append(failed, line)
+			}
+			break
+		}
+		if err != nil {
+			log.Printf("⚠️  Queued detection of %s from %s failed, keeping it: %v", logging.MaskMAC(d.Request.MacAddress), d.At.Format(time.RFC3339), err)
+			line, _ := json.Marshal(d)
+			failed = append(failed, line)
+			continue
+		}
+		processed++
+	}
+
+	if len(failed) > 0 {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		// The drain context may be done by now; the entries must not be lost with it
+		if err := q.store.Append(context.WithoutCancel(ctx), detectionQueueKey, failed...); err != nil {
+			return processed, fmt.Errorf("failed to requeue %d detections: %w", len(failed), err)
+		}
+	}
+	q.recordDrain(time.Now(), processed)
+	return processed, nil
+}
+
+// recordDrain remembers a drain for the drain rate and forgets those out of the window
+func (q *DetectionQueue) recordDrain(now time.Time, n int) {
+	q.drainMu.Lock()
+	defer q.drainMu.Unlock()
+	if n > 0 {
+		q.drained = append(q.drained, drainEvent{at: now, n: n})
+	}
+	for len(q.drained) > 0 && now.Sub(q.drained[0].at) > drainRateWindow {
+		q.drained = q.drained[1:]
+	}
+}
+
+// Stats reports the queue's length, oldest entry and drain rate
+func (q *DetectionQueue) Stats(ctx context.Context, now time.Time) (QueueStats, error) {
+	q.mu.Lock()
+	entries, err := q.read(ctx)
+	q.mu.Unlock()
+	if err != nil {
+		return QueueStats{}, err
+	}
+
+	stats := QueueStats{Len: len(entries)}
+	for _, d := range entries {
+		if stats.Oldest.IsZero() || d.At.Before(stats.Oldest) {
+			stats.Oldest = d.At
+		}
+	}
+	q.recordDrain(now, 0)
+	q.drainMu.Lock()
+	for _, e := range q.drained {
+		stats.DrainedLastHour += e.n
+	}
+	q.drainMu.Unlock()
+	return stats, nil
+}
+
+// Trim drops queued detections until at most max remain. Only presence detections are
+// dropped, oldest first: the first and the last detection of a device on a day may
+// record a check-in or a check-out, so they are kept even if max cannot be reached.
+// It returns how many were dropped.
+func (q *DetectionQueue) Trim(ctx context.Context, max int) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	entries, err := q.read(ctx)
+	if err != nil || len(entries) <= max {
+		return 0, err
+	}
+
+	critical := attendanceCandidates(entries)
+	excess := len(entries) - max
+	var kept [][]byte
+	dropped := 0
+	for i, d := range entries {
+		if dropped < excess && !critical[i] {
+			dropped++
+			continue
+		}
+		line, err := json.Marshal(d)
+		if err != nil {
+			return 0, err
+		}
+		kept = append(kept, line)
+	}
+	if dropped == 0 {
+		return 0, nil
+	}
+
+	// Entries are read oldest first, so dropping from the front drops the oldest
+	if err := q.store.Delete(ctx, detectionQueueKey); err != nil {
+		return 0, fmt.Errorf("failed to trim detection queue: %w", err)
+	}
+	if err := q.store.Append(context.WithoutCancel(ctx), detectionQueueKey, kept...); err != nil {
+		return 0, fmt.Errorf("failed to rewrite trimmed detection queue: %w", err)
+	}
+	return dropped, nil
+}
+
+// attendanceCandidates marks the entries that may record attendance: the first and the
+// last detection of each device on each day
+func attendanceCandidates(entries []QueuedDetection) map[int]bool {
+	first := make(map[string]int)
+	last := make(map[string]int)
+	for i, d := range entries {
+		mac, err := macaddr.Normalize(d.Request.MacAddress)
+		if err != nil {
+			mac = d.Request.MacAddress
+		}
+		key := mac + "|" + d.At.Format("2006-01-02")
+		if j, ok := first[key]; !ok || d.At.Before(entries[j].At) {
+			first[key] = i
+		}
+		if j, ok := last[key]; !ok || !d.At.Before(entries[j].At) {
+			last[key] = i
+		}
+	}
+	critical := make(map[int]bool, 2*len(first))
+	for _, i := range first {
+		critical[i] = true
+	}
+	for _, i := range last {
+		critical[i] = true
+	}
+	return critical
+}
+
+// read loads every entry; unreadable entries are logged and skipped. Callers hold mu.
+func (q *DetectionQueue) read(ctx context.Context) ([]QueuedDetection, error) {
+	records, err := q.store.Records(ctx, detectionQueueKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read detection queue: %w", err)
+	}
+
+	var entries []QueuedDetection
+	for _, rec := range records {
+		var d QueuedDetection
+		if err := json.Unmarshal(rec, &d); err != nil {
+			log.Printf("⚠️  Skipping corrupt queued detection: %v", err)
+			continue
+		}
+		entries = append(entries, d)
+	}
+	return entries, nil
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/localstore"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+type fakeGate struct{ readOnly bool }
+
+func (g *fakeGate) ReadOnly() bool { return g.readOnly }
+
+func TestReadOnlyQueuesDetections(t *testing.T) {
+	ctx := context.Background()
+	morning := time.Date(2026, 2, 2, 7, 55, 0, 0, time.Local)
+	clk := clock.NewFake(morning)
+	store := memory.NewStore(clk)
+	store.AddEmployee(models.Employee{Name: "Somchai", MacAddress: "AA:BB:CC:DD:EE:01", WorkStartTime: "08:00:00", IsActive: true})
+
+	service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), newRecordingNotifier())
+	service.SetClock(clk)
+	gate := &fakeGate{readOnly: true}
+	queue := NewDetectionQueue(localstore.NewFileStore(t.TempDir()))
+	service.SetReadOnlyQueue(gate, queue)
+
+	req := &models.DetectionRequest{ScannerMac: "SC:01", MacAddress: "aa:bb:cc:dd:ee:01", RSSI: -50}
+	if err := service.ProcessDetection(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(store.Attendance()) + len(store.Detections()); n != 0 {
+		t.Fatalf("wrote %d records in read-only mode", n)
+	}
+	if queue.Len() != 1 {
+		t.Fatalf("queue length = %d, want 1", queue.Len())
+	}
+
+	t.Run("drain stops while still read-only", func(t *testing.T) {
+		if n, err := service.DrainQueue(ctx); err != nil || n != 0 {
+			t.Fatalf("DrainQueue = %d, %v", n, err)
+		}
+		if queue.Len() != 1 {
+			t.Errorf("queue length = %d, want 1", queue.Len())
+		}
+	})
+
+	t.Run("drain replays at the original time", func(t *testing.T) {
+		gate.readOnly = false
+		clk.Set(morning.Add(2 * time.Hour)) // maintenance ran past work start
+		if n, err := service.DrainQueue(ctx); err != nil || n != 1 {
+			t.Fatalf("DrainQueue = %d, %v", n, err)
+		}
+		att := store.Attendance()
+		if len(att) != 1 || !att[0].CheckInTime.Equal(morning) || att[0].Status != "ontime" {
+			t.Errorf("attendance = %+v", att)
+		}
+		if queue.Len() != 0 {
+			t.Errorf("queue length = %d, want 0", queue.Len())
+		}
+	})
+}
+
+// downAttendance fails check-ins as the retrying repository does once PocketBase
+// stayed unreachable on every attempt
+type downAttendance struct {
+	repository.AttendanceRepository
+	down bool
+}
+
+func (a *downAttendance) Create(ctx context.Context, attendance *models.Attendance) error {
+	if a.down {
+		return fmt.Errorf("attendance create failed 3 times: %w", repository.ErrUnavailable)
+	}
+	return a.AttendanceRepository.Create(ctx, attendance)
+}
+
+func TestUnavailableQueuesDetections(t *testing.T) {
+	ctx := context.Background()
+	morning := time.Date(2026, 2, 2, 7, 55, 0, 0, time.Local)
+	clk := clock.NewFake(morning)
+	store := memory.NewStore(clk)
+	store.AddEmployee(models.Employee{Name: "Somchai", MacAddress: "AA:BB:CC:DD:EE:01", WorkStartTime: "08:00:00", IsActive: true})
+
+	attendance := &downAttendance{AttendanceRepository: store.AttendanceRecords(), down: true}
+	service := NewAttendanceService(store.Employees(), attendance, store.DetectionRecords(), store.ScannerRecords(), newRecordingNotifier())
+	service.SetClock(clk)
+	queue := NewDetectionQueue(localstore.NewFileStore(t.TempDir()))
+	service.SetReadOnlyQueue(&fakeGate{}, queue)
+
+	req := &models.DetectionRequest{ScannerMac: "SC:01", MacAddress: "aa:bb:cc:dd:ee:01", RSSI: -50}
+	res, err := service.DetectWithResult(ctx, req)
+	if err != nil || res.Result != ResultPaused {
+		t.Fatalf("DetectWithResult = %+v, %v, want %s", res, err, ResultPaused)
+	}
+	clk.Set(morning.Add(time.Minute)) // still down a minute later
+	if res, err := service.DetectWithResult(ctx, req); err != nil || res.Result != ResultPaused {
+		t.Fatalf("DetectWithResult = %+v, %v, want %s", res, err, ResultPaused)
+	}
+	if queue.Len() != 2 {
+		t.Fatalf("queue length = %d, want 2", queue.Len())
+	}
+
+	t.Run("drain stops while still unavailable", func(t *testing.T) {
+		if n, err := service.DrainQueue(ctx); err != nil || n != 0 {
+			t.Fatalf("DrainQueue = %d, %v", n, err)
+		}
+		if queue.Len() != 2 {
+			t.Errorf("queue length = %d, want 2", queue.Len())
+		}
+	})
+
+	t.Run("drain replays once PocketBase is back", func(t *testing.T) {
+		attendance.down = false
+		clk.Set(morning.Add(10 * time.Minute))
+		if n, err := service.DrainQueue(ctx); err != nil || n != 2 {
+			t.Fatalf("DrainQueue = %d, %v", n, err)
+		}
+		// The second replay finds the first one's check-in and does not add another
+		att := store.Attendance()
+		if len(att) != 1 || !att[0].CheckInTime.Equal(morning) || att[0].Status != "ontime" {
+			t.Errorf("attendance = %+v", att)
+		}
+	})
+}
+
+func TestUnavailableLookupQueuesDetection(t *testing.T) {
+	ctx := context.Background()
+	store := newPipelineStore()
+	employees := failingEmployees{store.Employees(), repository.ErrUnavailable}
+	service := NewAttendanceService(employees, store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), newRecordingNotifier())
+	service.SetClock(clock.NewFake(pipelineNow))
+	queue := NewDetectionQueue(localstore.NewFileStore(t.TempDir()))
+	service.SetReadOnlyQueue(&fakeGate{}, queue)
+
+	// Without the queue the failure reaches the caller instead of passing for an unknown phone
+	bare := NewAttendanceService(employees, store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), newRecordingNotifier())
+	bare.SetClock(clock.NewFake(pipelineNow))
+	req := &models.DetectionRequest{ScannerMac: "scanner-1", MacAddress: "aa:bb:cc:dd:ee:01", RSSI: -50}
+	if err := bare.ProcessDetection(ctx, req); !errors.Is(err, repository.ErrUnavailable) {
+		t.Errorf("ProcessDetection() error = %v, want ErrUnavailable", err)
+	}
+
+	res, err := service.DetectWithResult(ctx, req)
+	if err != nil || res.Result != ResultPaused || queue.Len() != 1 {
+		t.Errorf("DetectWithResult = %+v, %v with %d queued, want %s and 1 queued", res, err, queue.Len(), ResultPaused)
+	}
+}
+
+func TestDetectionQueueKeepsFailures(t *testing.T) {
+	ctx := context.Background()
+	queue := NewDetectionQueue(localstore.NewFileStore(t.TempDir()))
+	for _, mac := range []string{"01", "02", "03"} {
+		queue.Enqueue(ctx, &models.DetectionRequest{MacAddress: mac}, time.Now())
+	}
+
+	var seen []string
+	n, err := queue.Drain(ctx, func(ctx context.Context, d QueuedDetection) error {
+		seen = append(seen, d.Request.MacAddress)
+		if d.Request.MacAddress == "02" {
+			return errors.New("pocketbase down")
+		}
+		return nil
+	})
+	if err != nil || n != 2 || len(seen) != 3 {
+		t.Fatalf("Drain = %d, %v after %v", n, err, seen)
+	}
+	if queue.Len() != 1 {
+		t.Errorf("queue length = %d, want the failed entry kept", queue.Len())
+	}
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/metrics"
+	"med-pulse-bot/internal/tenant"
+)
+
+// QueueStats is a snapshot of a local queue, shown by /queues
+type QueueStats struct {
+	Name            string
+	Len             int
+	Oldest          time.Time // time of the oldest entry; zero when empty
+	DrainedLastHour int       // entries drained within the last hour
+}
+
+// WatchedQueue is a local queue the watchdog keeps in bounds, e.g. a DetectionQueue
+type WatchedQueue interface {
+	Stats(ctx context.Context, now time.Time) (QueueStats, error)
+	// Trim drops entries that are safe to lose, oldest first, until at most max remain
+	Trim(ctx context.Context, max int) (int, error)
+}
+
+// QueueWatchdogConfig sets the bounds of the watched queues
+type QueueWatchdogConfig struct {
+	HighWater int // length at which a queue is reported as not draining
+	HardCap   int // length above which entries safe to lose are dropped; 0 never drops
+}
+
+// QueueWatchdog watches local queues that should drain on their own. A queue at the high
+// water mark is logged and flagged in medpulse_queue_over_high_water (the
+// detection_queue_depth alert tells the admin chat); above the hard cap it drops entries
+// safe to lose and tells the admin chat how many.
+type QueueWatchdog struct {
+	cfg      QueueWatchdogConfig
+	notifier BotNotifier
+	clock    clock.Clock
+	tenantID string
+
+	names  []string
+	queues map[string]WatchedQueue
+
+	mu   sync.Mutex
+	over map[string]bool // queue name → at the high water mark at the last check
+}
+
+// NewQueueWatchdog creates a watchdog; add queues with Watch
+func NewQueueWatchdog(cfg QueueWatchdogConfig, notifier BotNotifier) (*QueueWatchdog, error) {
+	if cfg.HighWater < 1 || cfg.HardCap < 0 {
+		return nil, fmt.Errorf("invalid queue watchdog config %+v", cfg)
+	}
+	if cfg.HardCap > 0 && cfg.HardCap < cfg.HighWater {
+		return nil, errors.New("queue hard cap must not be below the high water mark")
+	}
+	return &QueueWatchdog{
+		cfg:      cfg,
+		notifier: notifier,
+		clock:    clock.Real{},
+		tenantID: tenant.DefaultID,
+		queues:   make(map[string]WatchedQueue),
+		over:     make(map[string]bool),
+	}, nil
+}
+
+// SetClock replaces the time source, used by tests
+func (w *QueueWatchdog) SetClock(c clock.Clock) {
+	w.clock = c
+}
+
+// SetTenant labels this watchdog's metrics with a tenant ID
+func (w *QueueWatchdog) SetTenant(id string) {
+	w.tenantID = id
+}
+
+// Watch adds a queue under a name used in logs, metrics and /queues
+func (w *QueueWatchdog) Watch(name string, q WatchedQueue) {
+	w.names = append(w.names, name)
+	w.queues[name] = q
+}
+
+// Start checks the queues every interval until ctx is done
+func (w *QueueWatchdog) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				w.Check(ctx)
+			}
+		}
+	}()
+}
+
+// Check compares every queue with the high water mark and trims those above the hard cap
+func (w *QueueWatchdog) Check(ctx context.Context) {
+	now := w.clock.Now()
+	for _, name := range w.names {
+		q := w.queues[name]
+		stats, err := q.Stats(ctx, now)
+		if err != nil {
+			log.Printf("❌ Queue watchdog could not read %s [%s]: %v", name, w.tenantID, err)
+			continue
+		}
+		w.checkHighWater(name, stats, now)
+
+		if w.cfg.HardCap == 0 || stats.Len <= w.cfg.HardCap {
+			continue
+		}
+		dropped, err := q.Trim(ctx, w.cfg.HardCap)
+		if err != nil {
+			log.Printf("❌ Queue watchdog could not trim %s [%s]: %v", name, w.tenantID, err)
+			continue
+		}
+		metrics.QueueDropped.Add(float64(dropped), w.tenantID, name)
+		log.Printf("🗑️ Queue %s [%s] over its cap of %d with %d entries: dropped the %d oldest presence entries, kept every possible check-in and check-out",
+			name, w.tenantID, w.cfg.HardCap, stats.Len, dropped)
+		if dropped > 0 {
+			w.notifier.SendNotification(fmt.Sprintf("🗑️ *คิว %s เกินขีดจำกัด*\n\nมี %d รายการ (สูงสุด %d) ลบการตรวจจับที่ไม่กระทบการลงเวลาที่เก่าที่สุด %d รายการ",
+				name, stats.Len, w.cfg.HardCap, dropped))
+		}
+	}
+}
+
+// checkHighWater logs a queue crossing the high water mark in either direction
+func (w *QueueWatchdog) checkHighWater(name string, stats QueueStats, now time.Time) {
+	over := stats.Len >= w.cfg.HighWater
+	w.mu.Lock()
+	changed := w.over[name] != over
+	w.over[name] = over
+	w.mu.Unlock()
+
+	if over {
+		metrics.QueueOverHighWater.Set(1, w.tenantID, name)
+	} else {
+		metrics.QueueOverHighWater.Set(0, w.tenantID, name)
+	}
+	if !changed {
+		return
+	}
+	if over {
+		log.Printf("⚠️  Queue %s [%s] reached %d entries (high water %d), oldest from %s, %d drained in the last hour",
+			name, w.tenantID, stats.Len, w.cfg.HighWater, now.Sub(stats.Oldest).Round(time.Second), stats.DrainedLastHour)
+		return
+	}
+	log.Printf("✅ Queue %s [%s] back under its high water mark with %d entries", name, w.tenantID, stats.Len)
+}
+
+// Stats returns a snapshot of every watched queue, in the order they were added
+func (w *QueueWatchdog) Stats(ctx context.Context) ([]QueueStats, error) {
+	now := w.clock.Now()
+	all := make([]QueueStats, 0, len(w.names))
+	for _, name := range w.names {
+		stats, err := w.queues[name].Stats(ctx, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read queue %s: %w", name, err)
+		}
+		stats.Name = name
+		all = append(all, stats)
+	}
+	return all, nil
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/localstore"
+	"med-pulse-bot/internal/metrics"
+	"med-pulse-bot/internal/models"
+)
+
+func TestDetectionQueueTrim(t *testing.T) {
+	ctx := context.Background()
+	queue := NewDetectionQueue(localstore.NewFileStore(t.TempDir()))
+	day := time.Date(2026, 2, 2, 7, 0, 0, 0, time.Local)
+
+	// Somchai seen every minute; Somsri seen once
+	for i := 0; i < 6; i++ {
+		req := &models.DetectionRequest{ScannerMac: "SC:01", MacAddress: "aa:bb:cc:dd:ee:01", RSSI: -50}
+		if err := queue.Enqueue(ctx, req, day.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := &models.DetectionRequest{ScannerMac: "SC:01", MacAddress: "AA-BB-CC-DD-EE-02", RSSI: -50}
+	if err := queue.Enqueue(ctx, req, day.Add(30*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		max         int
+		wantDropped int
+		wantLen     int
+	}{
+		{"under the cap", 10, 0, 7},
+		{"drops the oldest presence first", 5, 2, 5},
+		{"keeps check-ins and check-outs over the cap", 1, 2, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dropped, err := queue.Trim(ctx, tt.max)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dropped != tt.wantDropped || queue.Len() != tt.wantLen {
+				t.Errorf("Trim(%d) dropped %d leaving %d, want %d leaving %d", tt.max, dropped, queue.Len(), tt.wantDropped, tt.wantLen)
+			}
+		})
+	}
+
+	var kept []time.Time
+	if _, err := queue.Drain(ctx, func(_ context.Context, d QueuedDetection) error {
+		kept = append(kept, d.At)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Time{day, day.Add(5 * time.Minute), day.Add(30 * time.Second)} // in queue order
+	if len(kept) != len(want) {
+		t.Fatalf("kept %v, want %v", kept, want)
+	}
+	for i := range want {
+		if !kept[i].Equal(want[i]) {
+			t.Errorf("kept[%d] = %v, want %v", i, kept[i], want[i])
+		}
+	}
+}
+
+func TestQueueWatchdogCheck(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, 2, 2, 7, 0, 0, 0, time.Local)
+	queue := NewDetectionQueue(localstore.NewFileStore(t.TempDir()))
+	for i := 0; i < 8; i++ {
+		req := &models.DetectionRequest{ScannerMac: "SC:01", MacAddress: "aa:bb:cc:dd:ee:01", RSSI: -50}
+		if err := queue.Enqueue(ctx, req, start.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	notifier := newRecordingNotifier()
+	watchdog, err := NewQueueWatchdog(QueueWatchdogConfig{HighWater: 3, HardCap: 5}, notifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	watchdog.SetClock(clock.NewFake(start.Add(10 * time.Minute)))
+	watchdog.SetTenant("watchdog-test")
+	watchdog.Watch("detection_queue", queue)
+
+	watchdog.Check(ctx)
+	if queue.Len() != 5 {
+		t.Errorf("queue length = %d, want the hard cap of 5", queue.Len())
+	}
+	if got := metrics.QueueDropped.Value("watchdog-test", "detection_queue"); got != 3 {
+		t.Errorf("dropped metric = %v, want 3", got)
+	}
+	if len(notifier.admin) != 1 {
+		t.Errorf("admin notifications = %q, want one about the drop", notifier.admin)
+	}
+
+	stats, err := watchdog.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0].Name != "detection_queue" || stats[0].Len != 5 || !stats[0].Oldest.Equal(start) {
+		t.Errorf("Stats() = %+v", stats)
+	}
+
+	t.Run("under the cap drops nothing", func(t *testing.T) {
+		watchdog.Check(ctx)
+		if queue.Len() != 5 || len(notifier.admin) != 1 {
+			t.Errorf("queue length = %d, notifications = %d", queue.Len(), len(notifier.admin))
+		}
+	})
+}
+
+func TestNewQueueWatchdogValidates(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     QueueWatchdogConfig
+		wantErr bool
+	}{
+		{"high water and cap", QueueWatchdogConfig{HighWater: 100, HardCap: 1000}, false},
+		{"no cap", QueueWatchdogConfig{HighWater: 100}, false},
+		{"no high water", QueueWatchdogConfig{HardCap: 1000}, true},
+		{"cap below high water", QueueWatchdogConfig{HighWater: 100, HardCap: 10}, true},
+		{"negative cap", QueueWatchdogConfig{HighWater: 100, HardCap: -1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewQueueWatchdog(tt.cfg, newRecordingNotifier()); (err != nil) != tt.wantErr {
+				t.Errorf("NewQueueWatchdog(%+v) error = %v, wantErr %v", tt.cfg, err, tt.wantErr)
+			}
+		})
+	}
+}
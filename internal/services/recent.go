@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"med-pulse-bot/internal/repository"
+)
+
+// DefaultRecentDetectionTTL is how long a settled detection of a device at a scanner
+// stops repeats of it
+const DefaultRecentDetectionTTL = 60 * time.Second
+
+// recentKey is a device as seen by one scanner
+type recentKey struct {
+	scanner string
+	mac     string
+}
+
+// recentEntry is the last settled detection of a device, with the data version of the
+// employee it belonged to
+type recentEntry struct {
+	at         time.Time
+	employeeID string // empty for unknown devices
+	version    uint64
+}
+
+// RecentDetections drops the detections a tag repeats every few seconds once the
+// pipeline has settled what its detection means (unknown device, checked in, already
+// checked in), so repeats are not looked up in PocketBase again. Detections still
+// waiting for a check-in (too far, smoothing) are never remembered, and an entry
+// only stops detections on the day it was made, so the first detection of a day
+// always goes through. With data versions an entry stops nothing once the employee's
+// attendance changed, e.g. after a manual check-in or a check-out correction.
+type RecentDetections struct {
+	ttl      time.Duration
+	versions *repository.DataVersions // optional
+
+	mu        sync.Mutex
+	seen      map[recentKey]recentEntry // last settled detection
+	lastSweep time.Time
+
+	suppressed atomic.Int64
+}
+
+// NewRecentDetections creates a cache stopping repeats for ttl
+func NewRecentDetections(ttl time.Duration) *RecentDetections {
+	return &RecentDetections{ttl: ttl, seen: make(map[recentKey]recentEntry)}
+}
+
+// SetDataVersions lets attendance changes end the entries of the employee's devices
+func (r *RecentDetections) SetDataVersions(v *repository.DataVersions) {
+	r.versions = v
+}
+
+// Suppress reports whether a detection of mac by scanner at now repeats a settled one,
+// counting it when it does
+func (r *RecentDetections) Suppress(scanner, mac string, now time.Time) bool {
+	r.mu.Lock()
+	entry, ok := r.seen[recentKey{scanner, mac}]
+	r.mu.Unlock()
+	at := entry.at
+	if !ok || now.Sub(at) >= r.ttl || now.Before(at) || !sameDay(at, now) {
+		return false
+	}
+	if entry.employeeID != "" && r.version(entry.employeeID) != entry.version {
+		return false
+	}
+	r.suppressed.Add(1)
+	return true
+}
+
+// Remember records a settled detection of mac by scanner at now; employeeID is empty
+// for a device no employee owns
+func (r *RecentDetections) Remember(scanner, mac, employeeID string, now time.Time) {
+	entry := recentEntry{at: now, employeeID: employeeID}
+	if employeeID != "" {
+		entry.version = r.version(employeeID)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seen[recentKey{scanner, mac}] = entry
+
+	// Devices that stop being seen are dropped once they can no longer stop anything
+	if now.Sub(r.lastSweep) < r.ttl {
+		return
+	}
+	for k, e := range r.seen {
+		if now.Sub(e.at) >= r.ttl {
+			delete(r.seen, k)
+		}
+	}
+	r.lastSweep = now
+}
+
+// version returns the employee's data version, 0 without data versions
+func (r *RecentDetections) version(employeeID string) uint64 {
+	if r.versions == nil {
+		return 0
+	}
+	return r.versions.Of(employeeID)
+}
+
+// Suppressed returns how many detections have been dropped
+func (r *RecentDetections) Suppressed() int64 {
+	return r.suppressed.Load()
+}
+
+// Len returns the number of remembered devices
+func (r *RecentDetections) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.seen)
+}
+
+// observe remembers the detection if the pipeline settled it
+func (r *RecentDetections) observe(dc *DetectionContext, err error) {
+	if err != nil || len(dc.Timeline) == 0 {
+		return
+	}
+	// A dropped repeat must not extend the entry, or a tag seen all day is never looked up
+	last := dc.Timeline[len(dc.Timeline)-1].Stage
+	if last == "recent" {
+		return
+	}
+	// The self-test must run through the whole pipeline every time
+	if dc.Employee != nil && dc.Employee.IsSynthetic {
+		return
+	}
+	switch {
+	case dc.Result == ResultUnknownDevice, dc.Result == ResultDuplicate, dc.Attendance != nil,
+		last == "checkout":
+		employeeID := ""
+		if dc.Employee != nil {
+			employeeID = dc.Employee.ID
+		}
+		r.Remember(dc.Request.ScannerMac, dc.Request.MacAddress, employeeID, dc.Now)
+	}
+}
+
+// sameDay reports whether a and b fall on the same calendar day in b's location
+func sameDay(a, b time.Time) bool {
+	a = a.In(b.Location())
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
+
+// RecentDetectionStage drops repeats of a settled detection before anything is looked up
+type RecentDetectionStage struct {
+	Recent *RecentDetections
+}
+
+func (s RecentDetectionStage) Process(ctx context.Context, dc *DetectionContext) (bool, error) {
+	if s.Recent.Suppress(dc.Request.ScannerMac, dc.Request.MacAddress, dc.Now) {
+		dc.Notef("repeat within %s", s.Recent.ttl)
+		dc.Reject(ResultDuplicate, nil)
+		return false, nil
+	}
+	return true, nil
+}
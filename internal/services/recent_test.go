@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// countingEmployees counts device lookups
+type countingEmployees struct {
+	repository.EmployeeRepository
+	mu      sync.Mutex
+	lookups int
+}
+
+func (c *countingEmployees) GetByMacAddress(ctx context.Context, mac string) (*models.Employee, error) {
+	c.mu.Lock()
+	c.lookups++
+	c.mu.Unlock()
+	return c.EmployeeRepository.GetByMacAddress(ctx, mac)
+}
+
+func TestRecentDetections(t *testing.T) {
+	store := newPipelineStore()
+	store.AddEmployee(models.Employee{ID: "e2", Name: "Malee", MacAddress: "aa:bb:cc:dd:ee:02", WorkStartTime: "08:00:00", IsActive: true})
+	employees := &countingEmployees{EmployeeRepository: store.Employees()}
+	clk := clock.NewFake(pipelineNow)
+	service := NewAttendanceService(employees, store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), newRecordingNotifier())
+	service.SetClock(clk)
+	recent := NewRecentDetections(DefaultRecentDetectionTTL)
+	service.SetRecentDetections(recent)
+
+	steps := []struct {
+		name        string
+		after       time.Duration // since the previous step
+		mac         string
+		rssi        int
+		wantStage   string
+		wantLookups int // total so far
+	}{
+		{"first detection checks in", 0, "aa:bb:cc:dd:ee:01", -60, "notification", 1},
+		{"repeat is dropped before lookup", 3 * time.Second, "AA:BB:CC:DD:EE:01", -60, "recent", 1},
+		{"repeat within the ttl of the settled one", 50 * time.Second, "aa:bb:cc:dd:ee:01", -60, "recent", 1},
+		{"looked up again after the ttl", 10 * time.Second, "aa:bb:cc:dd:ee:01", -60, "dedupe", 2},
+		{"too far is not remembered", time.Minute, "aa:bb:cc:dd:ee:02", -80, "proximity", 3},
+		{"closer right after checks in", 2 * time.Second, "aa:bb:cc:dd:ee:02", -60, "notification", 4},
+		{"unknown device is remembered", 0, "aa:bb:cc:dd:ee:99", -60, "employee_match", 5},
+		{"unknown device repeat", 3 * time.Second, "aa:bb:cc:dd:ee:99", -60, "recent", 5},
+	}
+	for _, st := range steps {
+		t.Run(st.name, func(t *testing.T) {
+			clk.Advance(st.after)
+			res, err := service.DetectWithResult(context.Background(), &models.DetectionRequest{ScannerMac: "scanner-1", MacAddress: st.mac, RSSI: st.rssi})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Stage != st.wantStage {
+				t.Errorf("stage = %q, want %q", res.Stage, st.wantStage)
+			}
+			if employees.lookups != st.wantLookups {
+				t.Errorf("lookups = %d, want %d", employees.lookups, st.wantLookups)
+			}
+		})
+	}
+	if got := recent.Suppressed(); got != 3 {
+		t.Errorf("Suppressed() = %d, want 3", got)
+	}
+	if got := len(store.Attendance()); got != 2 {
+		t.Errorf("check-ins = %d, want 2", got)
+	}
+}
+
+func TestRecentDetections_Suppress(t *testing.T) {
+	settled := time.Date(2026, 2, 2, 23, 59, 50, 0, time.Local)
+	tests := []struct {
+		name    string
+		scanner string
+		at      time.Time
+		bump    string // employee whose attendance changes after the detection
+		want    bool
+	}{
+		{"same scanner within ttl", "scanner-1", settled.Add(5 * time.Second), "", true},
+		{"other scanner", "scanner-2", settled.Add(5 * time.Second), "", false},
+		{"first detection after midnight", "scanner-1", settled.Add(15 * time.Second), "", false},
+		{"after ttl", "scanner-1", settled.Add(time.Minute), "", false},
+		{"clock went back", "scanner-1", settled.Add(-time.Second), "", false},
+		{"attendance changed", "scanner-1", settled.Add(5 * time.Second), "e1", false},
+		{"other employee's attendance changed", "scanner-1", settled.Add(5 * time.Second), "e2", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			versions := repository.NewDataVersions()
+			r := NewRecentDetections(time.Minute)
+			r.SetDataVersions(versions)
+			r.Remember("scanner-1", "aa:bb:cc:dd:ee:01", "e1", settled)
+			if tt.bump != "" {
+				versions.Bump(tt.bump)
+			}
+			if got := r.Suppress(tt.scanner, "aa:bb:cc:dd:ee:01", tt.at); got != tt.want {
+				t.Errorf("Suppress() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecentDetections_Concurrent(t *testing.T) {
+	r := NewRecentDetections(time.Minute)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				at := pipelineNow.Add(time.Duration(j) * time.Second)
+				if !r.Suppress("scanner-1", "aa:bb:cc:dd:ee:01", at) {
+					r.Remember("scanner-1", "aa:bb:cc:dd:ee:01", "e1", at)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if r.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", r.Len())
+	}
+}
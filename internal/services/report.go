@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// MonthlySummary is one employee's attendance totals for a month
+type MonthlySummary struct {
+	Month       time.Time // first day of the month
+	WorkingDays int       // working days of the month up to today
+	DaysPresent int       // working days with a check-in
+	DaysLate    int
+	LateMinutes int // minutes after work start, summed over the late days
+	OffDays     int // weekends and holidays with a check-in
+
+	// AverageCheckIn is the mean check-in time as a duration since the start of the shift's
+	// day; for a night shift it can pass 24h. Zero when there were no check-ins.
+	AverageCheckIn time.Duration
+}
+
+// AttendanceReport computes attendance totals of one employee
+type AttendanceReport struct {
+	attendance repository.AttendanceLog
+	calendar   *WorkCalendar
+	loc        *time.Location
+	clock      clock.Clock
+}
+
+// NewAttendanceReport creates the report with days and times taken in loc; a nil calendar
+// makes every day a working day
+func NewAttendanceReport(attendance repository.AttendanceLog, calendar *WorkCalendar, loc *time.Location) *AttendanceReport {
+	return &AttendanceReport{attendance: attendance, calendar: calendar, loc: loc, clock: clock.Real{}}
+}
+
+// SetClock replaces the time source, used by tests
+func (r *AttendanceReport) SetClock(c clock.Clock) {
+	r.clock = c
+}
+
+// Monthly totals the employee's attendance in the month of the given day. Days after today
+// are not counted as working days; self-test and guest check-ins are left out.
+func (r *AttendanceReport) Monthly(ctx context.Context, emp *models.Employee, month time.Time) (*MonthlySummary, error) {
+	month = month.In(r.loc)
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, r.loc)
+	end := start.AddDate(0, 1, 0)
+	records, err := r.attendance.ListByEmployeeAndRange(ctx, emp.ID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attendance: %w", err)
+	}
+
+	summary := &MonthlySummary{Month: start}
+	now := r.clock.Now().In(r.loc)
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, r.loc)
+	for day := start; day.Before(end) && day.Before(tomorrow); day = day.AddDate(0, 0, 1) {
+		working, err := r.isWorkingDay(ctx, day)
+		if err != nil {
+			return nil, err
+		}
+		if working {
+			summary.WorkingDays++
+		}
+	}
+
+	// The first check-in of each day counts; a second record of a day is a correction
+	seen := make(map[string]bool)
+	var total time.Duration
+	for _, a := range records {
+		if a.Source == models.AttendanceSourceSelfTest || a.Status == models.AttendanceStatusGuest {
+			continue
+		}
+		created := a.CreatedDate.In(r.loc)
+		key := created.Format("2006-01-02")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if a.Status == models.AttendanceStatusOffDay {
+			summary.OffDays++
+			continue
+		}
+
+		summary.DaysPresent++
+		day := time.Date(created.Year(), created.Month(), created.Day(), 0, 0, 0, 0, r.loc)
+		total += a.CheckInTime.In(r.loc).Sub(day)
+		if a.Status == models.AttendanceStatusLate {
+			summary.DaysLate++
+			summary.LateMinutes += lateMinutes(a.CheckInTime.In(r.loc), day, emp.WorkStartTime)
+		}
+	}
+	if summary.DaysPresent > 0 {
+		summary.AverageCheckIn = (total / time.Duration(summary.DaysPresent)).Round(time.Minute)
+	}
+	return summary, nil
+}
+
+func (r *AttendanceReport) isWorkingDay(ctx context.Context, day time.Time) (bool, error) {
+	if r.calendar == nil {
+		return true, nil
+	}
+	working, err := r.calendar.IsWorkingDay(ctx, day)
+	if err != nil {
+		return false, fmt.Errorf("failed to read the work calendar: %w", err)
+	}
+	return working, nil
+}
+
+// lateMinutes is how many whole minutes after the work start on day the check-in was,
+// as in the late check-in alert; zero when the work start is unknown or not yet passed
+func lateMinutes(checkIn, day time.Time, workStartTime string) int {
+	workStart, err := time.Parse("15:04:05", workStartTime)
+	if err != nil {
+		return 0
+	}
+	start := time.Date(day.Year(), day.Month(), day.Day(), workStart.Hour(), workStart.Minute(), workStart.Second(), 0, checkIn.Location())
+	return max(int(checkIn.Sub(start).Minutes()), 0)
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+func TestAttendanceReportMonthly(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 4, day, hour, minute, 0, 0, time.Local)
+	}
+	record := func(employeeID string, created, checkIn time.Time, status string) models.Attendance {
+		return models.Attendance{EmployeeID: employeeID, CreatedDate: created, CheckInTime: checkIn, Status: status, Source: models.AttendanceSourceScanner}
+	}
+	selfTest := record("e1", at(7, 0, 0), at(7, 3, 0), models.AttendanceStatusOnTime)
+	selfTest.Source = models.AttendanceSourceSelfTest
+
+	tests := []struct {
+		name    string
+		emp     models.Employee
+		records []models.Attendance
+		month   time.Time
+		want    MonthlySummary
+	}{
+		{
+			name: "day shift",
+			emp:  models.Employee{ID: "e1", WorkStartTime: "08:00:00"},
+			records: []models.Attendance{
+				record("e1", time.Date(2026, 3, 31, 0, 0, 0, 0, time.Local), time.Date(2026, 3, 31, 9, 0, 0, 0, time.Local), models.AttendanceStatusLate),
+				record("e1", at(1, 0, 0), at(1, 7, 50), models.AttendanceStatusOnTime),
+				record("e1", at(2, 0, 0), at(2, 8, 20), models.AttendanceStatusLate),
+				record("e1", at(3, 0, 0), at(3, 8, 7), models.AttendanceStatusLate),
+				record("e1", at(3, 0, 0), at(3, 9, 0), models.AttendanceStatusLate), // a second record of the day
+				record("e1", at(4, 0, 0), at(4, 10, 0), models.AttendanceStatusOffDay),
+				selfTest,
+				record("e1", at(8, 0, 0), at(8, 8, 0), models.AttendanceStatusGuest),
+				record("e2", at(9, 0, 0), at(9, 11, 0), models.AttendanceStatusLate),
+			},
+			month: at(15, 0, 0),
+			// Apr 1-10 has 8 weekdays; Apr 6 is a holiday
+			want: MonthlySummary{WorkingDays: 7, DaysPresent: 3, DaysLate: 2, LateMinutes: 27, OffDays: 1,
+				AverageCheckIn: 8*time.Hour + 6*time.Minute}, // 07:50, 08:20 and 08:07 average 08:05:40
+		},
+		{
+			name: "night shift averages across midnight",
+			emp:  models.Employee{ID: "e1", WorkStartTime: "22:00:00", WorkEndTime: "06:00:00"},
+			records: []models.Attendance{
+				record("e1", at(1, 0, 0), at(1, 23, 50), models.AttendanceStatusOnTime),
+				record("e1", at(2, 0, 0), at(3, 0, 10), models.AttendanceStatusLate),
+			},
+			month: at(1, 0, 0),
+			want:  MonthlySummary{WorkingDays: 7, DaysPresent: 2, DaysLate: 1, LateMinutes: 130, AverageCheckIn: 24 * time.Hour},
+		},
+		{
+			name:  "future month",
+			emp:   models.Employee{ID: "e1", WorkStartTime: "08:00:00"},
+			month: time.Date(2026, 5, 1, 0, 0, 0, 0, time.Local),
+			want:  MonthlySummary{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memory.NewStore(clock.NewFake(at(10, 18, 0)))
+			store.AddHoliday("2026-04-06", "วันจักรี")
+			for _, a := range tt.records {
+				store.AddAttendance(a)
+			}
+			calendar, err := NewWorkCalendar("Mon,Tue,Wed,Thu,Fri", store.Holidays())
+			if err != nil {
+				t.Fatal(err)
+			}
+			report := NewAttendanceReport(store.AttendanceRecords(), calendar, time.Local)
+			report.SetClock(clock.NewFake(at(10, 18, 0)))
+
+			got, err := report.Monthly(context.Background(), &tt.emp, tt.month)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got.Month = time.Time{}
+			if *got != tt.want {
+				t.Errorf("Monthly() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+
+	"med-pulse-bot/internal/models"
+)
+
+// Detection results reported back to scanners
+const (
+	ResultAccepted      = "accepted"       // taken in; the stage tells how far it got
+	ResultTooFar        = "too_far"        // signal weaker than the RSSI threshold
+	ResultUnknownDevice = "unknown_device" // no employee owns the device
+	ResultDuplicate     = "duplicate"      // employee already checked in today
+	ResultOutsideWindow = "outside_window" // saved, but outside the check-in window
+	ResultPaused        = "paused"         // writes suspended or PocketBase unavailable, queued for later
+	ResultQueued        = "queued"         // taken in, processed in the background (DETECT_WORKERS)
+	ResultBusy          = "busy"           // the background queue is full, retry later
+	ResultRateLimited   = "rate_limited"   // scanner sent too many detections
+	ResultError         = "error"          // processing failed
+)
+
+// DetectionResult tells the scanner what happened to a detection. It must never carry
+// personal data: the firmware prints it to a serial log anyone on site can read.
+type DetectionResult struct {
+	Result    string `json:"result"`
+	Matched   bool   `json:"matched"`             // the device belongs to an employee
+	Identity  string `json:"identity,omitempty"`  // what it was matched by: mac, ibeacon or eddystone
+	CheckedIn bool   `json:"checked_in"`          // this detection created today's check-in
+	Stage     string `json:"stage,omitempty"`     // stage that decided, e.g. "smoothing"
+	Threshold *int   `json:"threshold,omitempty"` // the limit that applied, e.g. -70 dBm
+}
+
+// DetectionResultProcessor is an attendance processor that reports the result to the scanner
+type DetectionResultProcessor interface {
+	AttendanceProcessor
+	DetectWithResult(ctx context.Context, req *models.DetectionRequest) (DetectionResult, error)
+}
+
+// DetectionFilter is an attendance processor that can refuse a detection before the
+// caller logs anything about it
+type DetectionFilter interface {
+	Admit(ctx context.Context, req *models.DetectionRequest) bool
+}
+
+// resultOf summarizes a pipeline run for the scanner
+func resultOf(dc *DetectionContext, err error) DetectionResult {
+	res := DetectionResult{Result: ResultAccepted, Matched: dc.Employee != nil, Identity: dc.Identity, CheckedIn: dc.Attendance != nil}
+	if n := len(dc.Timeline); n > 0 {
+		res.Stage = dc.Timeline[n-1].Stage
+	}
+	switch {
+	case err != nil:
+		res.Result, res.CheckedIn = ResultError, false
+	case dc.Result != "":
+		res.Result, res.Threshold = dc.Result, dc.Threshold
+	}
+	return res
+}
+
+var (
+	_ DetectionResultProcessor = (*AttendanceService)(nil)
+	_ DetectionFilter          = (*AttendanceService)(nil)
+)
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// RSSISmoother keeps an exponential moving average of each device's signal at each
+// scanner, so the ±10 dB jitter between packets does not flap a check-in around the
+// threshold. Devices not heard for the idle time are forgotten and start over.
+type RSSISmoother struct {
+	alpha float64 // weight of the newest sample, 0 < alpha <= 1
+	idle  time.Duration
+
+	mu        sync.Mutex
+	devices   map[rssiKey]smoothedRSSI
+	lastSweep time.Time
+}
+
+type rssiKey struct {
+	scanner, mac string
+}
+
+type smoothedRSSI struct {
+	avg  float64
+	seen time.Time
+}
+
+// NewRSSISmoother creates a smoother giving the newest sample weight alpha
+func NewRSSISmoother(alpha float64, idle time.Duration) (*RSSISmoother, error) {
+	if alpha <= 0 || alpha > 1 {
+		return nil, fmt.Errorf("RSSI smoothing factor must be in (0, 1], got %g", alpha)
+	}
+	if idle <= 0 {
+		return nil, fmt.Errorf("RSSI smoothing idle time must be positive, got %s", idle)
+	}
+	return &RSSISmoother{alpha: alpha, idle: idle, devices: make(map[rssiKey]smoothedRSSI)}, nil
+}
+
+// Observe adds a sample and returns the device's smoothed signal, rounded to a dBm
+func (s *RSSISmoother) Observe(scanner, mac string, rssi int, at time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if at.Sub(s.lastSweep) >= s.idle {
+		s.sweep(at)
+	}
+	key := rssiKey{scanner, mac}
+	d, ok := s.devices[key]
+	if !ok || at.Sub(d.seen) >= s.idle {
+		d.avg = float64(rssi)
+	} else {
+		d.avg += s.alpha * (float64(rssi) - d.avg)
+	}
+	d.seen = at
+	s.devices[key] = d
+	return int(math.Round(d.avg))
+}
+
+// Len returns the number of devices being smoothed
+func (s *RSSISmoother) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.devices)
+}
+
+// sweep forgets devices idle at now; the caller holds s.mu
+func (s *RSSISmoother) sweep(now time.Time) {
+	for key, d := range s.devices {
+		if now.Sub(d.seen) >= s.idle {
+			delete(s.devices, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+)
+
+func TestRSSISmoother_Observe(t *testing.T) {
+	s, err := NewRSSISmoother(0.5, 5*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		at      time.Duration
+		scanner string
+		rssi    int
+		want    int
+	}{
+		{0, "scanner-1", -60, -60}, // the first reading is taken as is
+		{10 * time.Second, "scanner-1", -80, -70},
+		{20 * time.Second, "scanner-1", -60, -65},
+		{20 * time.Second, "scanner-2", -90, -90}, // another scanner has its own average
+		{6 * time.Minute, "scanner-1", -80, -80},  // idle long enough to start over
+	}
+	for _, st := range steps {
+		if got := s.Observe(st.scanner, "aa:bb:cc:dd:ee:01", st.rssi, pipelineNow.Add(st.at)); got != st.want {
+			t.Errorf("Observe(%s, %d) at +%s = %d, want %d", st.scanner, st.rssi, st.at, got, st.want)
+		}
+	}
+}
+
+func TestRSSISmoother_EvictsIdleDevices(t *testing.T) {
+	s, _ := NewRSSISmoother(0.3, 5*time.Minute)
+	for i, mac := range []string{"aa:00:00:00:00:01", "aa:00:00:00:00:02", "aa:00:00:00:00:03"} {
+		s.Observe("scanner-1", mac, -70, pipelineNow.Add(time.Duration(i)*time.Minute))
+	}
+	if got := s.Len(); got != 3 {
+		t.Fatalf("Len() = %d, want 3", got)
+	}
+
+	// At +6m the first device (0m) and second (1m) are idle; the third (2m) is not
+	s.Observe("scanner-1", "aa:00:00:00:00:04", -70, pipelineNow.Add(6*time.Minute+30*time.Second))
+	if got := s.Len(); got != 2 {
+		t.Errorf("Len() after the sweep = %d, want 2", got)
+	}
+}
+
+func TestNewRSSISmoother_Validates(t *testing.T) {
+	for _, alpha := range []float64{0, -0.1, 1.5} {
+		if _, err := NewRSSISmoother(alpha, time.Minute); err == nil {
+			t.Errorf("NewRSSISmoother(%g) succeeded", alpha)
+		}
+	}
+	if _, err := NewRSSISmoother(0.3, 0); err == nil {
+		t.Error("NewRSSISmoother() with no idle time succeeded")
+	}
+}
+
+func TestAttendanceService_SmoothedRSSI(t *testing.T) {
+	store := newPipelineStore()
+	clk := clock.NewFake(pipelineNow)
+	service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), newRecordingNotifier())
+	service.SetClock(clk)
+	smoother, _ := NewRSSISmoother(0.5, 5*time.Minute)
+	service.SetRSSISmoother(smoother)
+
+	// A far reading keeps the average below -70 after a single close spike
+	detect := func(rssi int) DetectionResult {
+		clk.Advance(5 * time.Second)
+		res, err := service.DetectWithResult(context.Background(), &models.DetectionRequest{ScannerMac: "scanner-1", MacAddress: "aa:bb:cc:dd:ee:01", RSSI: rssi})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	if res := detect(-85); res.Result != ResultTooFar {
+		t.Fatalf("first result = %q, want %q", res.Result, ResultTooFar)
+	}
+	if res := detect(-60); res.Result != ResultTooFar {
+		t.Fatalf("spike result = %q, want %q (average -73)", res.Result, ResultTooFar)
+	}
+	if res := detect(-60); !res.CheckedIn {
+		t.Fatalf("result = %+v, want a check-in (average -66)", res)
+	}
+
+	detections := store.Detections()
+	if len(detections) != 1 || detections[0].RSSI != -60 {
+		t.Errorf("detections = %+v, want one with the raw RSSI -60", detections)
+	}
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"med-pulse-bot/internal/macaddr"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// ScannerIDOutcome is what happened to one scanner record during normalization
+type ScannerIDOutcome string
+
+// Scanner ID normalization outcomes
+const (
+	ScannerIDCanonical      ScannerIDOutcome = "canonical"
+	ScannerIDNormalized     ScannerIDOutcome = "normalized"
+	ScannerIDWouldNormalize ScannerIDOutcome = "would_normalize" // dry run
+	ScannerIDCollision      ScannerIDOutcome = "collision"       // another record is the same scanner
+	ScannerIDInvalid        ScannerIDOutcome = "invalid"         // not a MAC and free-form IDs are not allowed
+	ScannerIDFailed         ScannerIDOutcome = "failed"
+)
+
+// ScannerIDResult is the outcome of one scanner record
+type ScannerIDResult struct {
+	Scanner   models.Scanner
+	Canonical string
+	Outcome   ScannerIDOutcome
+	Detail    string
+}
+
+// ScannerIDReport lists what happened to every scanner record
+type ScannerIDReport struct {
+	DryRun  bool
+	Results []ScannerIDResult
+}
+
+// Count returns the number of records with the given outcome
+func (r *ScannerIDReport) Count(outcome ScannerIDOutcome) int {
+	n := 0
+	for _, res := range r.Results {
+		if res.Outcome == outcome {
+			n++
+		}
+	}
+	return n
+}
+
+// Write prints one line per record followed by totals
+func (r *ScannerIDReport) Write(w io.Writer) {
+	for _, res := range r.Results {
+		fmt.Fprintf(w, "%-15s %-20s → %-20s %-16s %s\n",
+			res.Scanner.ID, res.Scanner.ScannerMac, res.Canonical, res.Outcome, res.Detail)
+	}
+
+	normalized := ScannerIDNormalized
+	if r.DryRun {
+		normalized = ScannerIDWouldNormalize
+	}
+	fmt.Fprintf(w, "\n%d scanners: %d canonical, %d %s, %d collisions, %d invalid, %d failed\n",
+		len(r.Results), r.Count(ScannerIDCanonical), r.Count(normalized), normalized,
+		r.Count(ScannerIDCollision), r.Count(ScannerIDInvalid), r.Count(ScannerIDFailed))
+}
+
+// NormalizeScannerIDs rewrites every scanner record to the canonical form of its ID.
+// Records that turn out to be the same scanner are reported as collisions and left for
+// an admin to merge: the record already in canonical form, or else the most recently
+// seen one, is normalized and the others keep their ID. With dryRun nothing is written.
+func NormalizeScannerIDs(ctx context.Context, scanners repository.ScannerRegistry, allowFreeform, dryRun bool) (*ScannerIDReport, error) {
+	records, err := scanners.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ScannerIDReport{DryRun: dryRun}
+	groups := make(map[string][]models.Scanner)
+	var order []string
+	for _, sc := range records {
+		id, err := macaddr.NormalizeScannerID(sc.ScannerMac, allowFreeform)
+		if err != nil {
+			report.Results = append(report.Results, ScannerIDResult{Scanner: sc, Outcome: ScannerIDInvalid, Detail: err.Error()})
+			continue
+		}
+		if _, ok := groups[id]; !ok {
+			order = append(order, id)
+		}
+		groups[id] = append(groups[id], sc)
+	}
+
+	for _, id := range order {
+		group := groups[id]
+		sort.SliceStable(group, func(i, j int) bool {
+			if (group[i].ScannerMac == id) != (group[j].ScannerMac == id) {
+				return group[i].ScannerMac == id
+			}
+			return group[i].LastSeen.After(group[j].LastSeen)
+		})
+
+		keeper := group[0]
+		res := ScannerIDResult{Scanner: keeper, Canonical: id, Outcome: ScannerIDCanonical}
+		switch {
+		case keeper.ScannerMac == id:
+		case dryRun:
+			res.Outcome = ScannerIDWouldNormalize
+		default:
+			res.Outcome = ScannerIDNormalized
+			if err := scanners.SetMac(ctx, keeper.ID, id); err != nil {
+				res.Outcome, res.Detail = ScannerIDFailed, err.Error()
+			}
+		}
+		report.Results = append(report.Results, res)
+
+		for _, dup := range group[1:] {
+			report.Results = append(report.Results, ScannerIDResult{
+				Scanner: dup, Canonical: id, Outcome: ScannerIDCollision,
+				Detail: fmt.Sprintf("same scanner as %s; merge or delete by hand", keeper.ID),
+			})
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+func TestNormalizeScannerIDs(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 2, 2, 9, 0, 0, 0, time.Local)
+
+	seed := func() *memory.Store {
+		store := memory.NewStore(nil)
+		store.AddScanner(models.Scanner{ID: "lobby-upper", ScannerMac: "AA:BB:CC:00:00:01", LastSeen: now.Add(-time.Hour)})
+		store.AddScanner(models.Scanner{ID: "lobby-bare", ScannerMac: "aabbcc000001", LastSeen: now})
+		store.AddScanner(models.Scanner{ID: "ward", ScannerMac: "aa:bb:cc:00:00:02", LastSeen: now})
+		store.AddScanner(models.Scanner{ID: "named", ScannerMac: "ESP32-Lobby", LastSeen: now})
+		return store
+	}
+
+	tests := []struct {
+		name     string
+		freeform bool
+		dryRun   bool
+		want     map[string]ScannerIDOutcome
+		wantMacs []string
+	}{
+		{
+			name: "normalize most recent, report collision",
+			want: map[string]ScannerIDOutcome{
+				"lobby-bare": ScannerIDNormalized, "lobby-upper": ScannerIDCollision,
+				"ward": ScannerIDCanonical, "named": ScannerIDInvalid,
+			},
+			wantMacs: []string{"AA:BB:CC:00:00:01", "ESP32-Lobby", "aa:bb:cc:00:00:01", "aa:bb:cc:00:00:02"},
+		},
+		{
+			name:     "free-form IDs allowed",
+			freeform: true,
+			want: map[string]ScannerIDOutcome{
+				"lobby-bare": ScannerIDNormalized, "lobby-upper": ScannerIDCollision,
+				"ward": ScannerIDCanonical, "named": ScannerIDNormalized,
+			},
+			wantMacs: []string{"AA:BB:CC:00:00:01", "aa:bb:cc:00:00:01", "aa:bb:cc:00:00:02", "esp32-lobby"},
+		},
+		{
+			name:   "dry run",
+			dryRun: true,
+			want: map[string]ScannerIDOutcome{
+				"lobby-bare": ScannerIDWouldNormalize, "lobby-upper": ScannerIDCollision,
+				"ward": ScannerIDCanonical, "named": ScannerIDInvalid,
+			},
+			wantMacs: []string{"AA:BB:CC:00:00:01", "ESP32-Lobby", "aa:bb:cc:00:00:02", "aabbcc000001"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := seed()
+			report, err := NormalizeScannerIDs(ctx, store.ScannerRecords(), tt.freeform, tt.dryRun)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(report.Results) != len(tt.want) {
+				t.Fatalf("got %d results, want %d", len(report.Results), len(tt.want))
+			}
+			for _, res := range report.Results {
+				if res.Outcome != tt.want[res.Scanner.ID] {
+					t.Errorf("%s: outcome %s, want %s", res.Scanner.ID, res.Outcome, tt.want[res.Scanner.ID])
+				}
+			}
+
+			var macs []string
+			for _, sc := range store.Scanners() {
+				macs = append(macs, sc.ScannerMac)
+			}
+			sort.Strings(macs)
+			if strings.Join(macs, ",") != strings.Join(tt.wantMacs, ",") {
+				t.Errorf("stored scanner IDs %v, want %v", macs, tt.wantMacs)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// scannerListTTL is how long the scanner records behind the effective configs are reused
+const scannerListTTL = time.Minute
+
+// ErrUnknownScannerProfile means a scanner was assigned a profile that is not defined
+var ErrUnknownScannerProfile = errors.New("unknown scanner profile")
+
+// ScannerConfig is the effective configuration of one scanner, served to the scanner
+// and used by the detection pipeline
+type ScannerConfig struct {
+	ScannerMac            string `json:"scanner_mac"`
+	Profile               string `json:"profile,omitempty"`
+	RSSIThreshold         int    `json:"rssi_threshold"`
+	Zone                  string `json:"zone,omitempty"`
+	ReportIntervalSeconds int    `json:"report_interval_seconds,omitempty"`
+	MinFirmware           string `json:"min_firmware,omitempty"`
+}
+
+// MergeScannerSettings layers settings from lowest to highest precedence: every field
+// set at a level wins over the levels before it
+func MergeScannerSettings(levels ...models.ScannerSettings) models.ScannerSettings {
+	var merged models.ScannerSettings
+	for _, l := range levels {
+		if l.RSSIThreshold != nil {
+			merged.RSSIThreshold = l.RSSIThreshold
+		}
+		if l.Zone != nil {
+			merged.Zone = l.Zone
+		}
+		if l.ReportIntervalSeconds != nil {
+			merged.ReportIntervalSeconds = l.ReportIntervalSeconds
+		}
+		if l.MinFirmware != nil {
+			merged.MinFirmware = l.MinFirmware
+		}
+	}
+	return merged
+}
+
+// ScannerProfiles are the named configuration profiles scanners follow, over the
+// defaults every scanner starts from
+type ScannerProfiles struct {
+	Defaults models.ScannerSettings            `yaml:"defaults"`
+	Profiles map[string]models.ScannerSettings `yaml:"profiles"`
+}
+
+// LoadScannerProfiles reads a profiles YAML file. Its defaults win over base, which
+// holds the settings from the environment. An empty path gives base and no profiles.
+func LoadScannerProfiles(path string, base models.ScannerSettings) (*ScannerProfiles, error) {
+	p := &ScannerProfiles{Defaults: base}
+	if path == "" {
+		return p, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scanner profiles file: %w", err)
+	}
+	var file ScannerProfiles
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse scanner profiles file %s: %w", path, err)
+	}
+	for name := range file.Profiles {
+		if name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("invalid scanner profile name %q", name)
+		}
+	}
+	p.Defaults = MergeScannerSettings(base, file.Defaults)
+	p.Profiles = file.Profiles
+	return p, nil
+}
+
+// Names returns the profile names, sorted
+func (p *ScannerProfiles) Names() []string {
+	names := make([]string, 0, len(p.Profiles))
+	for name := range p.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Effective merges defaults → profile → the scanner's overrides. The zone chosen when
+// pairing counts as an override unless the overrides set one themselves. A profile
+// that is no longer defined is skipped.
+func (p *ScannerProfiles) Effective(sc models.Scanner) ScannerConfig {
+	scanner := sc.Overrides
+	if scanner.Zone == nil && sc.Zone != "" {
+		zone := sc.Zone
+		scanner.Zone = &zone
+	}
+	s := MergeScannerSettings(p.Defaults, p.Profiles[sc.Profile], scanner)
+
+	cfg := ScannerConfig{ScannerMac: sc.ScannerMac, Profile: sc.Profile, RSSIThreshold: DefaultRSSIThreshold}
+	if s.RSSIThreshold != nil {
+		cfg.RSSIThreshold = *s.RSSIThreshold
+	}
+	if s.Zone != nil {
+		cfg.Zone = *s.Zone
+	}
+	if s.ReportIntervalSeconds != nil {
+		cfg.ReportIntervalSeconds = *s.ReportIntervalSeconds
+	}
+	if s.MinFirmware != nil {
+		cfg.MinFirmware = *s.MinFirmware
+	}
+	return cfg
+}
+
+// ScannerConfigs resolves the effective configuration of scanners from their records.
+// The records are listed at most once a minute; assigning a profile refreshes them.
+type ScannerConfigs struct {
+	profiles *ScannerProfiles
+	scanners repository.ScannerRegistry
+	clock    clock.Clock
+
+	mu       sync.Mutex
+	byMac    map[string]models.Scanner
+	loadedAt time.Time
+}
+
+// NewScannerConfigs resolves configs of the scanners in the registry with the profiles
+func NewScannerConfigs(profiles *ScannerProfiles, scanners repository.ScannerRegistry) *ScannerConfigs {
+	return &ScannerConfigs{profiles: profiles, scanners: scanners, clock: clock.Real{}}
+}
+
+// SetClock replaces the time source, used by tests
+func (c *ScannerConfigs) SetClock(clk clock.Clock) {
+	c.clock = clk
+}
+
+// Profiles returns the profile names, sorted
+func (c *ScannerConfigs) Profiles() []string {
+	return c.profiles.Names()
+}
+
+// Config returns the scanner's effective configuration. A scanner without a record
+// gets the defaults.
+func (c *ScannerConfigs) Config(ctx context.Context, scannerMac string) (ScannerConfig, error) {
+	byMac, err := c.records(ctx)
+	if err != nil {
+		return ScannerConfig{}, err
+	}
+	sc, ok := byMac[scannerKey(scannerMac)]
+	if !ok {
+		sc = models.Scanner{ScannerMac: scannerMac}
+	}
+	return c.profiles.Effective(sc), nil
+}
+
+// RSSIThreshold returns the scanner's check-in threshold, the default threshold when
+// its records cannot be read
+func (c *ScannerConfigs) RSSIThreshold(ctx context.Context, scannerMac string) int {
+	cfg, err := c.Config(ctx, scannerMac)
+	if err != nil {
+		log.Printf("⚠️  Scanner config of %s unavailable, using the default threshold: %v", scannerMac, err)
+		return c.profiles.Effective(models.Scanner{}).RSSIThreshold
+	}
+	return cfg.RSSIThreshold
+}
+
+// List returns the effective configuration of every known scanner
+func (c *ScannerConfigs) List(ctx context.Context) ([]ScannerConfig, error) {
+	byMac, err := c.records(ctx)
+	if err != nil {
+		return nil, err
+	}
+	configs := make([]ScannerConfig, 0, len(byMac))
+	for _, sc := range byMac {
+		configs = append(configs, c.profiles.Effective(sc))
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].ScannerMac < configs[j].ScannerMac })
+	return configs, nil
+}
+
+// SetProfile assigns a defined profile to the scanner; an empty profile returns it to
+// the defaults
+func (c *ScannerConfigs) SetProfile(ctx context.Context, scannerMac, profile string) (ScannerConfig, error) {
+	if _, ok := c.profiles.Profiles[profile]; profile != "" && !ok {
+		return ScannerConfig{}, fmt.Errorf("%w %q", ErrUnknownScannerProfile, profile)
+	}
+	if err := c.scanners.SetProfile(ctx, scannerMac, profile); err != nil {
+		return ScannerConfig{}, err
+	}
+	c.mu.Lock()
+	c.byMac = nil
+	c.mu.Unlock()
+	return c.Config(ctx, scannerMac)
+}
+
+// records returns the scanner records keyed by canonical ID, listing them when stale
+func (c *ScannerConfigs) records(ctx context.Context) (map[string]models.Scanner, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.clock.Now()
+	if c.byMac != nil && now.Sub(c.loadedAt) < scannerListTTL {
+		return c.byMac, nil
+	}
+
+	scanners, err := c.scanners.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scanners: %w", err)
+	}
+	c.byMac = make(map[string]models.Scanner, len(scanners))
+	for _, sc := range scanners {
+		// Most recently seen first: a stale record under another spelling does not win
+		if _, ok := c.byMac[scannerKey(sc.ScannerMac)]; !ok {
+			c.byMac[scannerKey(sc.ScannerMac)] = sc
+		}
+	}
+	c.loadedAt = now
+	return c.byMac, nil
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+func intp(v int) *int       { return &v }
+func strp(v string) *string { return &v }
+
+func TestScannerProfilesEffective(t *testing.T) {
+	profiles := &ScannerProfiles{
+		Defaults: models.ScannerSettings{RSSIThreshold: intp(-70), ReportIntervalSeconds: intp(5), MinFirmware: strp("1.0.0")},
+		Profiles: map[string]models.ScannerSettings{
+			"entrance": {RSSIThreshold: intp(-75), Zone: strp("entrance"), ReportIntervalSeconds: intp(2)},
+			"ward":     {RSSIThreshold: intp(-65)},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		scanner models.Scanner
+		want    ScannerConfig
+	}{
+		{
+			name:    "defaults only",
+			scanner: models.Scanner{ScannerMac: "SC:01"},
+			want:    ScannerConfig{ScannerMac: "SC:01", RSSIThreshold: -70, ReportIntervalSeconds: 5, MinFirmware: "1.0.0"},
+		},
+		{
+			name:    "profile wins over defaults",
+			scanner: models.Scanner{ScannerMac: "SC:01", Profile: "entrance"},
+			want:    ScannerConfig{ScannerMac: "SC:01", Profile: "entrance", RSSIThreshold: -75, Zone: "entrance", ReportIntervalSeconds: 2, MinFirmware: "1.0.0"},
+		},
+		{
+			name: "overrides win over the profile",
+			scanner: models.Scanner{ScannerMac: "SC:01", Profile: "entrance",
+				Overrides: models.ScannerSettings{RSSIThreshold: intp(-80), MinFirmware: strp("1.2.0")}},
+			want: ScannerConfig{ScannerMac: "SC:01", Profile: "entrance", RSSIThreshold: -80, Zone: "entrance", ReportIntervalSeconds: 2, MinFirmware: "1.2.0"},
+		},
+		{
+			name:    "paired zone wins over the profile zone",
+			scanner: models.Scanner{ScannerMac: "SC:01", Profile: "entrance", Zone: "er"},
+			want:    ScannerConfig{ScannerMac: "SC:01", Profile: "entrance", RSSIThreshold: -75, Zone: "er", ReportIntervalSeconds: 2, MinFirmware: "1.0.0"},
+		},
+		{
+			name: "override zone wins over the paired zone",
+			scanner: models.Scanner{ScannerMac: "SC:01", Zone: "er",
+				Overrides: models.ScannerSettings{Zone: strp("opd")}},
+			want: ScannerConfig{ScannerMac: "SC:01", RSSIThreshold: -70, Zone: "opd", ReportIntervalSeconds: 5, MinFirmware: "1.0.0"},
+		},
+		{
+			name:    "undefined profile falls back to the defaults",
+			scanner: models.Scanner{ScannerMac: "SC:01", Profile: "removed"},
+			want:    ScannerConfig{ScannerMac: "SC:01", Profile: "removed", RSSIThreshold: -70, ReportIntervalSeconds: 5, MinFirmware: "1.0.0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := profiles.Effective(tt.scanner); got != tt.want {
+				t.Errorf("Effective() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadScannerProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.yaml")
+	data := "defaults:\n  report_interval_seconds: 5\nprofiles:\n  outdoor:\n    rssi_threshold: -80\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, err := LoadScannerProfiles(path, models.ScannerSettings{RSSIThreshold: intp(-72)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := profiles.Effective(models.Scanner{}); got.RSSIThreshold != -72 || got.ReportIntervalSeconds != 5 {
+		t.Errorf("defaults = %+v, want the environment threshold and the file interval", got)
+	}
+	if got := profiles.Effective(models.Scanner{Profile: "outdoor"}); got.RSSIThreshold != -80 {
+		t.Errorf("outdoor threshold = %d, want -80", got.RSSIThreshold)
+	}
+
+	if _, err := LoadScannerProfiles(filepath.Join(t.TempDir(), "missing.yaml"), models.ScannerSettings{}); err == nil {
+		t.Error("missing file loaded without error")
+	}
+}
+
+func TestScannerConfigsThresholdPerScanner(t *testing.T) {
+	ctx := context.Background()
+	morning := time.Date(2026, 2, 2, 7, 55, 0, 0, time.Local)
+	clk := clock.NewFake(morning)
+	store := memory.NewStore(clk)
+	store.AddEmployee(models.Employee{Name: "Somchai", MacAddress: "AA:BB:CC:DD:EE:01", WorkStartTime: "08:00:00", IsActive: true})
+
+	profiles := &ScannerProfiles{
+		Defaults: models.ScannerSettings{RSSIThreshold: intp(-70)},
+		Profiles: map[string]models.ScannerSettings{"outdoor": {RSSIThreshold: intp(-80)}},
+	}
+	configs := NewScannerConfigs(profiles, store.ScannerRecords())
+	configs.SetClock(clk)
+
+	service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), newRecordingNotifier())
+	service.SetClock(clk)
+	service.SetScannerConfigs(configs)
+
+	req := &models.DetectionRequest{ScannerMac: "AA:00:00:00:00:01", MacAddress: "aa:bb:cc:dd:ee:01", RSSI: -75}
+	if res, err := service.DetectWithResult(ctx, req); err != nil || res.Result != ResultTooFar {
+		t.Fatalf("before the profile: %+v, %v, want %s", res, err, ResultTooFar)
+	}
+
+	if _, err := configs.SetProfile(ctx, "aa-00-00-00-00-01", "indoor"); !errors.Is(err, ErrUnknownScannerProfile) {
+		t.Fatalf("SetProfile(indoor) = %v, want ErrUnknownScannerProfile", err)
+	}
+	cfg, err := configs.SetProfile(ctx, "aa-00-00-00-00-01", "outdoor")
+	if err != nil || cfg.RSSIThreshold != -80 {
+		t.Fatalf("SetProfile(outdoor) = %+v, %v", cfg, err)
+	}
+	if res, err := service.DetectWithResult(ctx, req); err != nil || res.Result != ResultAccepted {
+		t.Fatalf("after the profile: %+v, %v, want %s", res, err, ResultAccepted)
+	}
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/macaddr"
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/metrics"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+	"med-pulse-bot/internal/tenant"
+)
+
+// SelfTestConfig configures the periodic pipeline self-test
+type SelfTestConfig struct {
+	MacAddress string        // reserved device MAC of the synthetic employee
+	ScannerMac string        // scanner the synthetic detections claim to come from
+	Deadline   time.Duration // how long the detection record may take to appear
+	Detections int           // detections injected per run, enough to pass smoothing
+}
+
+// DefaultSelfTestConfig returns locally administered MACs no real device uses
+func DefaultSelfTestConfig() SelfTestConfig {
+	return SelfTestConfig{
+		MacAddress: "02:00:00:5e:1f:01",
+		ScannerMac: "02:00:00:5e:1f:00",
+		Deadline:   30 * time.Second,
+		Detections: 1,
+	}
+}
+
+// selfTestRSSI is strong enough to pass any sensible proximity threshold
+const selfTestRSSI = -40
+
+// SelfTest injects a synthetic employee's detection through the real pipeline and
+// alerts the admin chat when its detection record does not show up in time
+type SelfTest struct {
+	cfg          SelfTestConfig
+	processor    DetectionResultProcessor
+	store        repository.SelfTestRepository
+	notifier     BotNotifier
+	tenantID     string
+	clock        clock.Clock
+	pollInterval time.Duration
+
+	mu       sync.Mutex
+	employee *models.Employee
+	failing  bool
+}
+
+// NewSelfTest creates a self-test; the MACs must be valid
+func NewSelfTest(cfg SelfTestConfig, processor DetectionResultProcessor, store repository.SelfTestRepository, notifier BotNotifier) (*SelfTest, error) {
+	if _, err := macaddr.Normalize(cfg.MacAddress); err != nil {
+		return nil, fmt.Errorf("invalid self-test MAC %q: %w", cfg.MacAddress, err)
+	}
+	if _, err := macaddr.Normalize(cfg.ScannerMac); err != nil {
+		return nil, fmt.Errorf("invalid self-test scanner MAC %q: %w", cfg.ScannerMac, err)
+	}
+	if cfg.Deadline <= 0 {
+		return nil, fmt.Errorf("self-test deadline must be positive, got %s", cfg.Deadline)
+	}
+	if cfg.Detections < 1 {
+		cfg.Detections = 1
+	}
+	return &SelfTest{
+		cfg:          cfg,
+		processor:    processor,
+		store:        store,
+		notifier:     notifier,
+		tenantID:     tenant.DefaultID,
+		clock:        clock.Real{},
+		pollInterval: time.Second,
+	}, nil
+}
+
+// SetClock replaces the time source, used by tests
+func (t *SelfTest) SetClock(c clock.Clock) {
+	t.clock = c
+}
+
+// SetTenant sets the tenant the self-test metrics are recorded under
+func (t *SelfTest) SetTenant(id string) {
+	t.tenantID = id
+}
+
+// Start runs the self-test every interval until ctx is cancelled
+func (t *SelfTest) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := t.Run(ctx); err != nil {
+					log.Printf("❌ Self-test failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// errSelfTestPaused means the run was skipped because writes are suspended
+var errSelfTestPaused = errors.New("writes suspended")
+
+// Run performs one self-test, updating the health metric and alerting on failure
+func (t *SelfTest) Run(ctx context.Context) error {
+	err := t.run(ctx)
+	if errors.Is(err, errSelfTestPaused) {
+		log.Printf("🧪 Self-test skipped: %v", err)
+		return nil
+	}
+	t.report(err)
+	return err
+}
+
+func (t *SelfTest) run(ctx context.Context) error {
+	emp, err := t.store.EnsureSyntheticEmployee(ctx, t.cfg.MacAddress)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.employee = emp
+	t.mu.Unlock()
+
+	// Detection records are stored with second precision
+	start := t.clock.Now().Truncate(time.Second)
+
+	// Earlier runs' check-in would stop this one at dedupe
+	if _, err := t.store.DeleteRecordsBefore(ctx, emp.ID, start); err != nil {
+		return fmt.Errorf("failed to clear earlier self-test records: %w", err)
+	}
+
+	for i := 0; i < t.cfg.Detections; i++ {
+		res, err := t.processor.DetectWithResult(ctx, &models.DetectionRequest{
+			ScannerMac: t.cfg.ScannerMac,
+			MacAddress: emp.MacAddress,
+			RSSI:       selfTestRSSI,
+			DeviceType: "selftest",
+		})
+		if err != nil {
+			return fmt.Errorf("synthetic detection failed at %s: %w", res.Stage, err)
+		}
+		switch res.Result {
+		case ResultPaused:
+			return errSelfTestPaused
+		case ResultAccepted:
+		default:
+			return fmt.Errorf("synthetic detection rejected: %s at %s", res.Result, res.Stage)
+		}
+	}
+
+	deadline := time.NewTimer(t.cfg.Deadline)
+	defer deadline.Stop()
+	poll := time.NewTicker(t.pollInterval)
+	defer poll.Stop()
+	for {
+		n, err := t.store.CountDetectionsSince(ctx, emp.ID, start)
+		if err == nil && n > 0 {
+			return nil
+		}
+		if err != nil {
+			log.Printf("⚠️  Self-test could not read detections: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline.C:
+			return fmt.Errorf("no detection record within %s", t.cfg.Deadline)
+		case <-poll.C:
+		}
+	}
+}
+
+// report updates the health metric and tells the admin chat when the result changes
+func (t *SelfTest) report(err error) {
+	t.mu.Lock()
+	wasFailing := t.failing
+	t.failing = err != nil
+	t.mu.Unlock()
+
+	if err == nil {
+		metrics.SelfTestHealthy.Set(1, t.tenantID)
+		if wasFailing {
+			log.Println("🧪 Self-test recovered")
+			t.notifier.SendNotification("✅ *Self-test กลับมาทำงานปกติ*\n\nระบบบันทึกการตรวจจับได้ตามปกติแล้ว")
+		}
+		return
+	}
+
+	metrics.SelfTestHealthy.Set(0, t.tenantID)
+	metrics.SelfTestFailures.Inc(t.tenantID)
+	if !wasFailing {
+		t.notifier.SendNotification(fmt.Sprintf("🚨 *Self-test ล้มเหลว*\n\n"+
+			"การตรวจจับทดสอบไม่ถูกบันทึกลง PocketBase\nสาเหตุ: %s\n\n"+
+			"การเข้างานจริงอาจไม่ถูกบันทึก กรุณาตรวจสอบระบบ", markdown.Code(err.Error())))
+	}
+}
+
+// Cleanup deletes every synthetic record from before the given time; registered with
+// the end-of-day job so failed runs leave nothing behind
+func (t *SelfTest) Cleanup(ctx context.Context, before time.Time) error {
+	t.mu.Lock()
+	emp := t.employee
+	t.mu.Unlock()
+	if emp == nil {
+		var err error
+		if emp, err = t.store.EnsureSyntheticEmployee(ctx, t.cfg.MacAddress); err != nil {
+			return err
+		}
+	}
+
+	deleted, err := t.store.DeleteRecordsBefore(ctx, emp.ID, before)
+	if err != nil {
+		return fmt.Errorf("failed to delete self-test records: %w", err)
+	}
+	if deleted > 0 {
+		log.Printf("🧹 Deleted %d synthetic self-test records", deleted)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/metrics"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+// blackholeProcessor accepts detections without recording anything, like a broken deployment
+type blackholeProcessor struct{}
+
+func (blackholeProcessor) ProcessDetection(ctx context.Context, req *models.DetectionRequest) error {
+	return nil
+}
+
+func (blackholeProcessor) DetectWithResult(ctx context.Context, req *models.DetectionRequest) (DetectionResult, error) {
+	return DetectionResult{Result: ResultAccepted, Stage: "notification"}, nil
+}
+
+func TestSelfTest(t *testing.T) {
+	ctx := context.Background()
+	day := func(h, m int) time.Time { return time.Date(2026, 2, 2, h, m, 0, 0, time.Local) }
+
+	setup := func(t *testing.T, tenantID string) (*memory.Store, *clock.Fake, *recordingNotifier, *AttendanceService, *SelfTest) {
+		clk := clock.NewFake(day(9, 0))
+		store := memory.NewStore(clk)
+		store.AddEmployee(models.Employee{Name: "Somchai", TelegramChatID: 42, MacAddress: "aa:bb:cc:dd:ee:01", WorkStartTime: "08:30:00", IsActive: true})
+		notifier := newRecordingNotifier()
+		service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), notifier)
+		service.SetClock(clk)
+
+		cfg := DefaultSelfTestConfig()
+		cfg.Deadline = 20 * time.Millisecond
+		selfTest, err := NewSelfTest(cfg, service, store.SelfTest(), notifier)
+		if err != nil {
+			t.Fatal(err)
+		}
+		selfTest.SetClock(clk)
+		selfTest.SetTenant(tenantID)
+		selfTest.pollInterval = 5 * time.Millisecond
+		return store, clk, notifier, service, selfTest
+	}
+
+	t.Run("repeated runs pass without notifying or reporting", func(t *testing.T) {
+		store, clk, notifier, _, selfTest := setup(t, "selftest-pass")
+		for _, at := range []time.Time{day(9, 0), day(9, 15)} {
+			clk.Set(at)
+			if err := selfTest.Run(ctx); err != nil {
+				t.Fatalf("run at %s: %v", at.Format("15:04"), err)
+			}
+		}
+
+		if len(notifier.admin) != 0 || len(notifier.personal) != 0 {
+			t.Errorf("notified: admin %q, personal %v", notifier.admin, notifier.personal)
+		}
+		if got := metrics.SelfTestHealthy.Value("selftest-pass"); got != 1 {
+			t.Errorf("healthy = %v, want 1", got)
+		}
+		if n := len(store.Attendance()); n != 1 || store.Attendance()[0].Source != models.AttendanceSourceSelfTest {
+			t.Errorf("attendance = %+v, want only the latest synthetic record", store.Attendance())
+		}
+
+		employees, _ := store.Employees().ListActive(ctx)
+		if len(employees) != 1 {
+			t.Errorf("ListActive returned %d employees, want the synthetic one hidden", len(employees))
+		}
+		summary, err := NewDailySummary(store.Employees(), store.AttendanceRecords(), notifier).Build(ctx, day(9, 15))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(summary, "0/1") {
+			t.Errorf("summary counts the synthetic check-in:\n%s", summary)
+		}
+	})
+
+	t.Run("missing detection record alerts once until recovery", func(t *testing.T) {
+		store, clk, notifier, service, selfTest := setup(t, "selftest-fail")
+		selfTest.processor = blackholeProcessor{}
+
+		for i := 0; i < 2; i++ {
+			if err := selfTest.Run(ctx); err == nil {
+				t.Fatal("expected a failure")
+			}
+		}
+		if len(notifier.admin) != 1 || !strings.Contains(notifier.admin[0], "Self-test") {
+			t.Fatalf("admin messages = %q, want a single alert", notifier.admin)
+		}
+		if got := metrics.SelfTestHealthy.Value("selftest-fail"); got != 0 {
+			t.Errorf("healthy = %v, want 0", got)
+		}
+		if got := metrics.SelfTestFailures.Value("selftest-fail"); got != 2 {
+			t.Errorf("failures = %v, want 2", got)
+		}
+
+		selfTest.processor = service
+		clk.Advance(15 * time.Minute)
+		if err := selfTest.Run(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if len(notifier.admin) != 2 || metrics.SelfTestHealthy.Value("selftest-fail") != 1 {
+			t.Errorf("admin messages = %q, want a recovery notice", notifier.admin)
+		}
+
+		clk.Advance(time.Minute)
+		if err := selfTest.Cleanup(ctx, clk.Now()); err != nil {
+			t.Fatal(err)
+		}
+		if len(store.Attendance()) != 0 || len(store.Detections()) != 0 {
+			t.Errorf("cleanup left %d attendance and %d detections", len(store.Attendance()), len(store.Detections()))
+		}
+	})
+
+	t.Run("refuses a MAC owned by a real employee", func(t *testing.T) {
+		store, _, _, _, selfTest := setup(t, "selftest-taken")
+		store.AddEmployee(models.Employee{Name: "Malee", MacAddress: DefaultSelfTestConfig().MacAddress, IsActive: true})
+		if err := selfTest.Run(ctx); err == nil {
+			t.Error("expected an error for a MAC owned by a real employee")
+		}
+		if len(store.Attendance()) != 0 {
+			t.Error("checked in the real employee")
+		}
+	})
+}
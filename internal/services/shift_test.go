@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+func TestEmployee_ShiftDay(t *testing.T) {
+	day := time.Date(2026, 2, 2, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		name       string
+		start, end string
+		at         time.Duration // since midnight of day
+		want       time.Time
+	}{
+		{"day shift", "08:00:00", "17:00:00", 1 * time.Hour, day},
+		{"no end time", "08:00:00", "", 23 * time.Hour, day},
+		{"night shift before midnight", "22:00:00", "06:00:00", 22*time.Hour + 5*time.Minute, day},
+		{"night shift after midnight", "22:00:00", "06:00:00", 1*time.Hour + 30*time.Minute, day.AddDate(0, 0, -1)},
+		{"night shift just after the end", "22:00:00", "06:00:00", 7 * time.Hour, day.AddDate(0, 0, -1)},
+		{"night shift early arrival", "22:00:00", "06:00:00", 20 * time.Hour, day}, // past the 14:00 midpoint of the gap
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emp := models.Employee{WorkStartTime: tt.start, WorkEndTime: tt.end}
+			if got := emp.ShiftDay(day.Add(tt.at)); !got.Equal(tt.want) {
+				t.Errorf("ShiftDay(%s) = %s, want %s", day.Add(tt.at).Format("15:04"), got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestAttendanceNightShift(t *testing.T) {
+	evening := time.Date(2026, 2, 1, 22, 3, 0, 0, time.Local)
+	clk := clock.NewFake(evening)
+	store := memory.NewStore(clk)
+	store.AddEmployee(models.Employee{ID: "e1", Name: "Somchai", MacAddress: "aa:bb:cc:dd:ee:01",
+		TelegramChatID: 1001, WorkStartTime: "22:00:00", WorkEndTime: "06:00:00", IsActive: true})
+	service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), newRecordingNotifier())
+	service.SetClock(clk)
+
+	detect := func() DetectionResult {
+		res, err := service.DetectWithResult(context.Background(), &models.DetectionRequest{ScannerMac: "scanner-1", MacAddress: "aa:bb:cc:dd:ee:01", RSSI: -50})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	if res := detect(); !res.CheckedIn {
+		t.Fatalf("evening result = %+v, want a check-in", res)
+	}
+	// Past midnight the employee is still on the shift that started the evening before
+	clk.Advance(3*time.Hour + 27*time.Minute)
+	if res := detect(); res.Result != ResultDuplicate {
+		t.Fatalf("01:30 result = %q, want %q", res.Result, ResultDuplicate)
+	}
+
+	att := store.Attendance()
+	if len(att) != 1 {
+		t.Fatalf("attendance = %+v, want a single check-in", att)
+	}
+	if got := att[0].CreatedDate.Format("2006-01-02"); got != "2026-02-01" {
+		t.Errorf("created_date = %s, want the shift's start day 2026-02-01", got)
+	}
+	if att[0].Status != models.AttendanceStatusOnTime {
+		t.Errorf("status = %q, want %q", att[0].Status, models.AttendanceStatusOnTime)
+	}
+}
+
+func TestAttendanceNightShiftLateAfterMidnight(t *testing.T) {
+	night := time.Date(2026, 2, 2, 0, 30, 0, 0, time.Local)
+	store := memory.NewStore(clock.NewFake(night))
+	store.AddEmployee(models.Employee{ID: "e1", Name: "Somchai", MacAddress: "aa:bb:cc:dd:ee:01",
+		TelegramChatID: 1001, WorkStartTime: "22:00:00", WorkEndTime: "06:00:00", IsActive: true})
+	service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), newRecordingNotifier())
+	service.SetClock(clock.NewFake(night))
+
+	if err := service.ProcessDetection(context.Background(), &models.DetectionRequest{ScannerMac: "scanner-1", MacAddress: "aa:bb:cc:dd:ee:01", RSSI: -50}); err != nil {
+		t.Fatal(err)
+	}
+	att := store.Attendance()
+	if len(att) != 1 || att[0].Status != models.AttendanceStatusLate || att[0].CreatedDate.Format("2006-01-02") != "2026-02-01" {
+		t.Fatalf("attendance = %+v, want one late check-in for 2026-02-01", att)
+	}
+}
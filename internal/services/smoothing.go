@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/localstore"
+)
+
+// smoothingSnapshotVersion is bumped whenever the snapshot format changes
+const smoothingSnapshotVersion = 1
+
+// windowSample is one detection kept in the smoothing window
+type windowSample struct {
+	At   time.Time `json:"at"`
+	RSSI int       `json:"rssi"`
+}
+
+// DetectionWindow requires a device to be seen a minimum number of times within a
+// sliding window before it may check in, so a single stray detection does not count
+type DetectionWindow struct {
+	minDetections int
+	window        time.Duration
+
+	mu      sync.Mutex
+	devices map[string][]windowSample // MAC -> samples, oldest first
+}
+
+// NewDetectionWindow creates a window requiring minDetections within window
+func NewDetectionWindow(minDetections int, window time.Duration) (*DetectionWindow, error) {
+	if minDetections < 1 {
+		return nil, fmt.Errorf("smoothing needs at least 1 detection, got %d", minDetections)
+	}
+	if window <= 0 {
+		return nil, fmt.Errorf("smoothing window must be positive, got %s", window)
+	}
+	return &DetectionWindow{
+		minDetections: minDetections,
+		window:        window,
+		devices:       make(map[string][]windowSample),
+	}, nil
+}
+
+// Add records a detection and returns how many detections of the device are in the window
+func (w *DetectionWindow) Add(mac string, rssi int, at time.Time) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	samples := append(w.prune(w.devices[mac], at), windowSample{At: at, RSSI: rssi})
+	w.devices[mac] = samples
+	return len(samples)
+}
+
+// Ready reports whether count detections satisfy the window
+func (w *DetectionWindow) Ready(count int) bool {
+	return count >= w.minDetections
+}
+
+// Len returns the number of devices with samples in the window at now
+func (w *DetectionWindow) Len(now time.Time) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := 0
+	for _, samples := range w.devices {
+		if len(w.prune(samples, now)) > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// prune drops samples older than the window; the caller holds w.mu
+func (w *DetectionWindow) prune(samples []windowSample, now time.Time) []windowSample {
+	cutoff := now.Add(-w.window)
+	i := 0
+	for i < len(samples) && !samples[i].At.After(cutoff) {
+		i++
+	}
+	return samples[i:]
+}
+
+// smoothingSnapshotKey is the local store value holding the window across restarts
+const smoothingSnapshotKey = "smoothing_window.json"
+
+// smoothingSnapshot is the stored format of the window
+type smoothingSnapshot struct {
+	Version int                       `json:"version"`
+	SavedAt time.Time                 `json:"saved_at"`
+	Devices map[string][]windowSample `json:"devices"`
+}
+
+// SaveSnapshot writes the current window to store, replacing the previous snapshot
+func (w *DetectionWindow) SaveSnapshot(ctx context.Context, store localstore.LocalStore, now time.Time) error {
+	w.mu.Lock()
+	snapshot := smoothingSnapshot{Version: smoothingSnapshotVersion, SavedAt: now, Devices: make(map[string][]windowSample)}
+	for mac, samples := range w.devices {
+		if samples = w.prune(samples, now); len(samples) > 0 {
+			snapshot.Devices[mac] = samples
+		}
+	}
+	w.mu.Unlock()
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	if err := store.Put(ctx, smoothingSnapshotKey, data); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	log.Printf("💾 Saved smoothing window for %d devices", len(snapshot.Devices))
+	return nil
+}
+
+// LoadSnapshot restores the window from store. A missing, stale, unreadable or
+// unknown-version snapshot leaves the window empty; only the number of restored
+// devices is returned.
+func (w *DetectionWindow) LoadSnapshot(ctx context.Context, store localstore.LocalStore, now time.Time) int {
+	data, err := store.Get(ctx, smoothingSnapshotKey)
+	if errors.Is(err, localstore.ErrNotFound) {
+		return 0
+	}
+	if err != nil {
+		log.Printf("⚠️  Cannot read smoothing snapshot, starting empty: %v", err)
+		return 0
+	}
+
+	var snapshot smoothingSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		log.Printf("⚠️  Corrupt smoothing snapshot, starting empty: %v", err)
+		return 0
+	}
+	if snapshot.Version != smoothingSnapshotVersion {
+		log.Printf("⚠️  Smoothing snapshot has version %d (want %d), starting empty",
+			snapshot.Version, smoothingSnapshotVersion)
+		return 0
+	}
+	if age := now.Sub(snapshot.SavedAt); age < 0 || age >= w.window {
+		log.Printf("Smoothing snapshot is %s old, discarding", age.Round(time.Second))
+		return 0
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	restored := 0
+	for mac, samples := range snapshot.Devices {
+		if samples = w.prune(samples, now); len(samples) > 0 {
+			w.devices[mac] = samples
+			restored++
+		}
+	}
+	log.Printf("♻️  Restored smoothing window for %d devices", restored)
+	return restored
+}
+
+// SmoothingStage holds back a check-in until the device has been seen often enough within the window
+type SmoothingStage struct {
+	Window *DetectionWindow
+}
+
+func (s SmoothingStage) Process(ctx context.Context, dc *DetectionContext) (bool, error) {
+	count := s.Window.Add(dc.Request.MacAddress, dc.Request.RSSI, dc.Now)
+	if !s.Window.Ready(count) {
+		dc.Notef("%d/%d detections in window", count, s.Window.minDetections)
+		return false, nil
+	}
+	return true, nil
+}
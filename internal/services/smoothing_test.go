@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/localstore"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+// smoothedService builds a service requiring 3 detections within 2 minutes, restoring
+// the window from snapshot like a fresh process would
+func smoothedService(t *testing.T, store *memory.Store, clk *clock.Fake, snapshot localstore.LocalStore) (*AttendanceService, *DetectionWindow) {
+	t.Helper()
+	window, err := NewDetectionWindow(3, 2*time.Minute)
+	if err != nil {
+		t.Fatalf("NewDetectionWindow() error = %v", err)
+	}
+	window.LoadSnapshot(context.Background(), snapshot, clk.Now())
+
+	service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), newRecordingNotifier())
+	service.SetClock(clk)
+	service.SetDetectionWindow(window)
+	return service, window
+}
+
+func detectAt(t *testing.T, service *AttendanceService, clk *clock.Fake, at time.Duration) {
+	t.Helper()
+	clk.Set(pipelineNow.Add(at))
+	req := &models.DetectionRequest{ScannerMac: "scanner-1", MacAddress: "aa:bb:cc:dd:ee:01", RSSI: -60}
+	if err := service.ProcessDetection(context.Background(), req); err != nil {
+		t.Fatalf("ProcessDetection() error = %v", err)
+	}
+}
+
+func TestDetectionWindow_Add(t *testing.T) {
+	w, _ := NewDetectionWindow(3, 2*time.Minute)
+	steps := []struct {
+		at   time.Duration
+		want int
+	}{
+		{0, 1},
+		{30 * time.Second, 2},
+		{2 * time.Minute, 2}, // the first sample is exactly one window old
+		{5 * time.Minute, 1},
+	}
+	for _, s := range steps {
+		if got := w.Add("aa", -60, pipelineNow.Add(s.at)); got != s.want {
+			t.Errorf("Add at +%s = %d, want %d", s.at, got, s.want)
+		}
+	}
+}
+
+func TestDetectionWindow_RestartMidWindow(t *testing.T) {
+	tests := []struct {
+		name        string
+		downtime    time.Duration
+		corrupt     bool
+		wantCheckIn bool
+	}{
+		{"snapshot reloaded after quick restart", 20 * time.Second, false, true},
+		{"stale snapshot discarded", 3 * time.Minute, false, false},
+		{"corrupt snapshot starts empty", 20 * time.Second, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot := localstore.NewFileStore(t.TempDir())
+			store := newPipelineStore()
+			clk := clock.NewFake(pipelineNow)
+
+			service, window := smoothedService(t, store, clk, snapshot)
+			detectAt(t, service, clk, 0)
+			detectAt(t, service, clk, 20*time.Second)
+			if len(store.Attendance()) != 0 {
+				t.Fatal("checked in before the window was satisfied")
+			}
+
+			// Graceful shutdown, then start a new process after the downtime
+			if err := window.SaveSnapshot(context.Background(), snapshot, clk.Now()); err != nil {
+				t.Fatalf("SaveSnapshot() error = %v", err)
+			}
+			if tt.corrupt {
+				snapshot.Put(context.Background(), smoothingSnapshotKey, []byte(`{"version":1,"devices":`))
+			}
+			clk.Advance(tt.downtime)
+			restarted, _ := smoothedService(t, store, clk, snapshot)
+
+			detectAt(t, restarted, clk, 20*time.Second+tt.downtime+10*time.Second)
+			if got := len(store.Attendance()) == 1; got != tt.wantCheckIn {
+				t.Errorf("checked in = %v, want %v", got, tt.wantCheckIn)
+			}
+		})
+	}
+}
+
+func TestDetectionWindow_LoadSnapshot(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		content string
+		want    int
+	}{
+		{"missing snapshot", "", 0},
+		{"unknown version", `{"version":99,"saved_at":"2026-02-02T08:00:00Z","devices":{"aa":[{"at":"2026-02-02T08:00:00Z","rssi":-60}]}}`, 0},
+		{"garbage", `not json`, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := localstore.NewMemoryStore()
+			if tt.content != "" {
+				store.Put(ctx, smoothingSnapshotKey, []byte(tt.content))
+			}
+			w, _ := NewDetectionWindow(3, 2*time.Minute)
+			if got := w.LoadSnapshot(ctx, store, time.Date(2026, 2, 2, 8, 0, 30, 0, time.UTC)); got != tt.want {
+				t.Errorf("LoadSnapshot() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("stale entries dropped", func(t *testing.T) {
+		now := time.Date(2026, 2, 2, 8, 1, 0, 0, time.UTC)
+		src, _ := NewDetectionWindow(3, 2*time.Minute)
+		src.Add("old", -60, now.Add(-3*time.Minute))
+		src.Add("new", -60, now.Add(-30*time.Second))
+		store := localstore.NewMemoryStore()
+		if err := src.SaveSnapshot(ctx, store, now.Add(-time.Minute)); err != nil {
+			t.Fatalf("SaveSnapshot() error = %v", err)
+		}
+
+		w, _ := NewDetectionWindow(3, 2*time.Minute)
+		if got := w.LoadSnapshot(ctx, store, now); got != 1 {
+			t.Errorf("LoadSnapshot() = %d, want 1", got)
+		}
+	})
+}
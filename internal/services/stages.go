@@ -0,0 +1,560 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"med-pulse-bot/internal/logging"
+	"med-pulse-bot/internal/macaddr"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// DefaultRSSIThreshold is the weakest signal (dBm) accepted for a check-in, roughly 10 meters
+const DefaultRSSIThreshold = -70
+
+// PipelineOptions selects and configures the stages of the detection pipeline
+type PipelineOptions struct {
+	Employees     repository.EmployeeRepository
+	Attendance    repository.AttendanceRepository
+	Detections    repository.EmployeeDetectionRepository
+	Notifier      BotNotifier
+	RSSIThreshold int
+	GracePeriod   time.Duration // for employees without their own
+
+	Smoothing  *DetectionWindow            // optional
+	Stationary *StationaryTagDetector      // optional
+	Baselines  *CheckInBaselines           // optional
+	CheckOut   *CheckOutReminder           // optional
+	Departures *CheckOutTracker            // optional
+	Pairing    *ScannerPairing             // optional
+	Recent     *RecentDetections           // optional
+	Late       *LateApprovals              // optional
+	Scanners   *ScannerConfigs             // optional
+	RSSI       *RSSISmoother               // optional
+	Window     *CheckInWindow              // optional
+	Calendar   *WorkCalendar               // optional
+	Devices    repository.DeviceRepository // optional
+	Unknown    *UnknownDeviceCapture       // optional
+	NewDevices *DevicePairing              // optional
+	Presence   *PresenceSampler            // optional
+	Templates  *MessageTemplates           // optional, nil uses the built-in messages
+	Locks      *EmployeeLocks              // optional, serializes each employee's check-ins
+
+	ScannerRecords repository.ScannerRepository // optional, names the scanner in notifications
+}
+
+// NewDetectionPipeline assembles the detection pipeline; optional stages are only
+// included when configured
+func NewDetectionPipeline(opts PipelineOptions) *Pipeline {
+	p := NewPipeline()
+	if opts.Pairing != nil {
+		p.Use("scanner_pairing", ScannerPairingStage{Pairing: opts.Pairing})
+	}
+	p.Use("normalize", NormalizeStage{})
+	if opts.NewDevices != nil {
+		p.Use("device_pairing", DevicePairingStage{Pairing: opts.NewDevices})
+	}
+	if opts.Recent != nil {
+		p.Use("recent", RecentDetectionStage{Recent: opts.Recent})
+	}
+	p.Use("employee_match", EmployeeMatchStage{Employees: opts.Employees, Devices: opts.Devices, Unknown: opts.Unknown})
+	if opts.CheckOut != nil {
+		p.Use("checkout_observe", CheckOutObserveStage{Reminder: opts.CheckOut})
+	}
+	if opts.Stationary != nil {
+		p.Use("stationary_observe", StationaryObserveStage{Detector: opts.Stationary})
+	}
+	p.Use("proximity", ProximityStage{Threshold: opts.RSSIThreshold, Configs: opts.Scanners, Smoother: opts.RSSI})
+	if opts.Departures != nil {
+		p.Use("checkout", CheckOutStage{Tracker: opts.Departures})
+	}
+	if opts.Smoothing != nil {
+		p.Use("smoothing", SmoothingStage{Window: opts.Smoothing})
+	}
+	p.Use("dedupe", DedupeStage{Employees: opts.Employees, Detections: opts.Detections, Presence: opts.Presence, Locks: opts.Locks})
+	if opts.Stationary != nil {
+		p.Use("stationary_confirm", StationaryConfirmStage{Detector: opts.Stationary})
+	}
+	p.Use("detection_log", DetectionLogStage{Detections: opts.Detections, Presence: opts.Presence})
+	if opts.Window != nil {
+		p.Use("checkin_window", CheckInWindowStage{Window: opts.Window})
+	}
+	p.Use("attendance", AttendanceStage{Attendance: opts.Attendance, Grace: opts.GracePeriod, LateApprovals: opts.Late, Calendar: opts.Calendar})
+	if opts.Baselines != nil {
+		p.Use("baseline", BaselineStage{Baselines: opts.Baselines})
+	}
+	return p.Use("notification", NotificationStage{Notifier: opts.Notifier, Templates: opts.Templates, Scanners: opts.ScannerRecords})
+}
+
+// ScannerPairingStage reports every detection, employee device or not, to scanner pairing
+type ScannerPairingStage struct {
+	Pairing *ScannerPairing
+}
+
+func (s ScannerPairingStage) Process(ctx context.Context, dc *DetectionContext) (bool, error) {
+	s.Pairing.Observe(dc.Request.ScannerMac, dc.Now)
+	return true, nil
+}
+
+// NormalizeStage canonicalizes the device MAC address; unparseable addresses are ignored
+type NormalizeStage struct{}
+
+func (NormalizeStage) Process(ctx context.Context, dc *DetectionContext) (bool, error) {
+	mac, err := macaddr.Normalize(dc.Request.MacAddress)
+	if err != nil {
+		dc.Notef("invalid mac_address")
+		dc.Reject(ResultUnknownDevice, nil)
+		return false, nil
+	}
+	dc.Request.MacAddress = mac
+	return true, nil
+}
+
+// Identities a detection can be matched to an employee by
+const (
+	IdentityMAC       = "mac"       // a registered device or the employee's mac_address
+	IdentityIBeacon   = "ibeacon"   // the iBeacon of a phone whose MAC is randomized
+	IdentityEddystone = "eddystone" // an Eddystone-UID tag
+)
+
+// EmployeeMatchStage looks up the employee owning the device, among the registered devices
+// first, then the employees' own mac_address and then, for phones whose MAC is randomized
+// and Eddystone tags, by the beacon identity the detection carries; unknown devices, and
+// guest tags past their last day, are ignored. Unknown devices are recorded for pairing
+// when Unknown is set.
+type EmployeeMatchStage struct {
+	Employees repository.EmployeeRepository
+	Devices   repository.DeviceRepository // optional
+	Unknown   *UnknownDeviceCapture       // optional
+}
+
+func (s EmployeeMatchStage) Process(ctx context.Context, dc *DetectionContext) (bool, error) {
+	req := dc.Request
+	employee, identity, err := s.match(ctx, req)
+	if errors.Is(err, repository.ErrNotFound) {
+		// Not a registered employee device - ignore silently
+		dc.Notef("not an employee device")
+		if s.Unknown != nil {
+			s.Unknown.Observe(ctx, req.MacAddress, req.RSSI, dc.Now)
+		}
+		dc.Reject(ResultUnknownDevice, nil)
+		return false, nil
+	}
+	if err != nil {
+		// An outage must not pass for an unknown phone: the caller sees it, and an
+		// unavailable PocketBase gets the detection queued
+		return false, fmt.Errorf("failed to look up the device's employee: %w", err)
+	}
+	if employee.GuestExpired(dc.Now) {
+		// Deactivated at the end of its last day; until then treated as inactive
+		dc.Notef("guest tag expired")
+		dc.Reject(ResultUnknownDevice, nil)
+		return false, nil
+	}
+
+	logging.From(ctx).Debug("🎯 Employee device detected", "employee", employee.Name, "employee_code", employee.EmployeeCode,
+		"identity", identity, "rssi", req.RSSI)
+	req.IsTargetDevice = true
+	req.DeviceName = employee.Name
+	dc.Employee = employee
+	dc.Identity = identity
+	if identity != IdentityMAC {
+		dc.Notef("matched by %s", identity)
+	}
+	return true, nil
+}
+
+// match tries each identity the detection carries in turn and returns the employee and
+// the identity that matched
+func (s EmployeeMatchStage) match(ctx context.Context, req *models.DetectionRequest) (*models.Employee, string, error) {
+	if employee := s.byDevice(ctx, req.MacAddress); employee != nil {
+		return employee, IdentityMAC, nil
+	}
+	employee, err := s.Employees.GetByMacAddress(ctx, req.MacAddress)
+	if !errors.Is(err, repository.ErrEmployeeNotFound) {
+		return employee, IdentityMAC, err
+	}
+	if req.IBeaconUUID != "" {
+		employee, err = s.Employees.GetByBeacon(ctx, req.IBeaconUUID, req.Major, req.Minor)
+		if !errors.Is(err, repository.ErrEmployeeNotFound) {
+			return employee, IdentityIBeacon, err
+		}
+	}
+	if req.NamespaceID != "" {
+		employee, err = s.Employees.GetByEddystone(ctx, req.NamespaceID, req.InstanceID)
+		if !errors.Is(err, repository.ErrEmployeeNotFound) {
+			return employee, IdentityEddystone, err
+		}
+	}
+	return nil, "", repository.ErrEmployeeNotFound
+}
+
+// byDevice returns the employee owning a registered device with the MAC, or nil if there
+// is none; failed lookups fall back to mac_address
+func (s EmployeeMatchStage) byDevice(ctx context.Context, mac string) *models.Employee {
+	if s.Devices == nil {
+		return nil
+	}
+	employee, err := s.Devices.GetEmployeeByDeviceMac(ctx, mac)
+	if err != nil {
+		if !errors.Is(err, repository.ErrEmployeeNotFound) {
+			logging.From(ctx).Warn("Failed to look up device, trying the employee MAC", "error", err)
+		}
+		return nil
+	}
+	return employee
+}
+
+// StationaryObserveStage feeds every employee detection to the left-behind tag analysis
+type StationaryObserveStage struct {
+	Detector *StationaryTagDetector
+}
+
+func (s StationaryObserveStage) Process(ctx context.Context, dc *DetectionContext) (bool, error) {
+	// The self-test tag is seen all evening and must not look left behind
+	if dc.Employee.IsSynthetic || dc.Employee.PresenceOptOut {
+		return true, nil
+	}
+	s.Detector.Observe(dc.Employee, dc.Request.ScannerMac, dc.Request.RSSI, dc.Now)
+	return true, nil
+}
+
+// CheckOutObserveStage feeds every employee detection to the forgotten check-out reminder
+type CheckOutObserveStage struct {
+	Reminder *CheckOutReminder
+}
+
+func (s CheckOutObserveStage) Process(ctx context.Context, dc *DetectionContext) (bool, error) {
+	// Without consent the reminder falls back to the scheduled end instead of the last sighting
+	if dc.Employee.PresenceOptOut {
+		return true, nil
+	}
+	s.Reminder.Observe(dc.Employee, dc.Request.ScannerMac, dc.Now)
+	return true, nil
+}
+
+// CheckOutStage records the check-out of employees already checked in today once it is
+// late enough; earlier detections go on to the check-in stages
+type CheckOutStage struct {
+	Tracker *CheckOutTracker
+}
+
+func (s CheckOutStage) Process(ctx context.Context, dc *DetectionContext) (bool, error) {
+	if dc.Employee.IsSynthetic {
+		return true, nil
+	}
+	att, moved, err := s.Tracker.Observe(ctx, dc.Employee, dc.Now)
+	if err != nil {
+		return false, fmt.Errorf("failed to record check-out: %w", err)
+	}
+	if att == nil {
+		return true, nil
+	}
+	if !moved {
+		dc.Notef("check-out unchanged")
+		dc.Reject(ResultDuplicate, nil)
+		return false, nil
+	}
+	dc.Notef("checked out")
+	return false, nil
+}
+
+// ProximityStage ignores devices whose signal is weaker than the threshold, or than the
+// scanner's own threshold when scanner configs are set. With a smoother the moving average
+// is compared instead of the reading; the raw reading is still what gets stored.
+type ProximityStage struct {
+	Threshold int
+	Configs   *ScannerConfigs // optional
+	Smoother  *RSSISmoother   // optional
+}
+
+func (s ProximityStage) Process(ctx context.Context, dc *DetectionContext) (bool, error) {
+	threshold := s.Threshold
+	if s.Configs != nil {
+		threshold = s.Configs.RSSIThreshold(ctx, dc.Request.ScannerMac)
+	}
+	rssi := dc.Request.RSSI
+	if s.Smoother != nil {
+		rssi = s.Smoother.Observe(dc.Request.ScannerMac, dc.Request.MacAddress, rssi, dc.Now)
+	}
+	if rssi < threshold {
+		dc.Reject(ResultTooFar, &threshold)
+		logging.From(ctx).Debug("Device too far", "rssi", rssi, "raw_rssi", dc.Request.RSSI, "threshold", threshold)
+		if rssi != dc.Request.RSSI {
+			dc.Notef("rssi %d (raw %d) < %d", rssi, dc.Request.RSSI, threshold)
+		} else {
+			dc.Notef("rssi %d < %d", rssi, threshold)
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+// DedupeStage stops detections of employees who already checked in for the current
+// shift, which for a night shift may have started the day before. With Presence, the
+// sampled ones are still saved so recent detections show who is in. With Locks, the
+// employee's lock is held from the check until the pipeline ends, so a simultaneous
+// detection of the same employee only checks once this one's check-in is recorded.
+type DedupeStage struct {
+	Employees  repository.EmployeeRepository
+	Detections repository.EmployeeDetectionRepository
+	Presence   *PresenceSampler // optional
+	Locks      *EmployeeLocks   // optional
+}
+
+func (s DedupeStage) Process(ctx context.Context, dc *DetectionContext) (bool, error) {
+	if s.Locks != nil && dc.unlock == nil {
+		dc.unlock = s.Locks.Lock(dc.Employee.ID)
+	}
+	isCheckedIn, err := s.Employees.IsCheckedInOn(ctx, dc.Employee.ID, dc.Employee.ShiftDay(dc.Now))
+	if err != nil {
+		return false, fmt.Errorf("failed to check attendance status: %w", err)
+	}
+	if isCheckedIn {
+		dc.Notef("already checked in today")
+		dc.Reject(ResultDuplicate, nil)
+		if s.Presence != nil && !dc.Employee.PresenceOptOut && s.Presence.Due(dc.Employee.ID, dc.Now) {
+			if err := saveDetection(ctx, s.Detections, dc); err != nil {
+				return false, err
+			}
+			dc.Notef("presence sample saved")
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+// StationaryConfirmStage holds back check-ins of tags that looked left behind overnight
+type StationaryConfirmStage struct {
+	Detector *StationaryTagDetector
+}
+
+func (s StationaryConfirmStage) Process(ctx context.Context, dc *DetectionContext) (bool, error) {
+	if dc.Employee.PresenceOptOut {
+		return true, nil
+	}
+	if !s.Detector.ConfirmCheckIn(dc.Employee.ID, dc.Request.ScannerMac, dc.Request.RSSI, dc.Now) {
+		logging.From(ctx).Info("🏷️ Possible stationary tag, waiting for stronger confirmation before check-in",
+			"employee", dc.Employee.Name)
+		dc.Notef("possible stationary tag")
+		return false, nil
+	}
+	return true, nil
+}
+
+// DetectionLogStage saves the detection that triggers the check-in, unless the employee
+// declined presence tracking
+type DetectionLogStage struct {
+	Detections repository.EmployeeDetectionRepository
+	Presence   *PresenceSampler // optional; the next presence sample waits its interval
+}
+
+func (s DetectionLogStage) Process(ctx context.Context, dc *DetectionContext) (bool, error) {
+	if dc.Employee.PresenceOptOut {
+		dc.Notef("no presence consent, not logged")
+		return true, nil
+	}
+	if err := saveDetection(ctx, s.Detections, dc); err != nil {
+		return false, err
+	}
+	if s.Presence != nil {
+		s.Presence.mark(dc.Employee.ID, dc.Now)
+	}
+	return true, nil
+}
+
+// saveDetection saves the matched detection as an employee_detections record
+func saveDetection(ctx context.Context, detections repository.EmployeeDetectionRepository, dc *DetectionContext) error {
+	req := dc.Request
+	detection := &models.EmployeeDetection{
+		EmployeeID:     dc.Employee.ID,
+		MacAddress:     req.MacAddress,
+		ScannerMac:     req.ScannerMac,
+		RSSI:           req.RSSI,
+		DeviceType:     req.DeviceType,
+		IsITag03:       req.IsITag03,
+		IsTargetDevice: req.IsTargetDevice,
+		DeviceName:     req.DeviceName,
+		IBeaconUUID:    req.IBeaconUUID,
+		Major:          req.Major,
+		Minor:          req.Minor,
+		NamespaceID:    req.NamespaceID,
+		InstanceID:     req.InstanceID,
+		DetectedAt:     dc.Now,
+	}
+
+	if err := detections.Create(ctx, detection); err != nil {
+		return fmt.Errorf("failed to save detection: %w", err)
+	}
+
+	logging.From(ctx).Debug("💾 Saved detection", "employee_id", dc.Employee.ID, "rssi", req.RSSI, "device_type", req.DeviceType)
+	return nil
+}
+
+// AttendanceStage records the check-in with its on-time/late status; with LateApprovals
+// a check-in within the day's approved late arrival is ontime_approved, and with a
+// Calendar one on a weekend or holiday is offday. Guests are never on time or late.
+type AttendanceStage struct {
+	Attendance    repository.AttendanceRepository
+	Grace         time.Duration  // the site's grace period
+	LateApprovals *LateApprovals // optional
+	Calendar      *WorkCalendar  // optional
+}
+
+func (s AttendanceStage) Process(ctx context.Context, dc *DetectionContext) (bool, error) {
+	status := models.AttendanceStatusGuest
+	if !dc.Employee.IsGuest {
+		status = s.LateApprovals.Status(ctx, dc.Employee, dc.Now, s.Grace)
+	}
+	day := dc.Employee.ShiftDay(dc.Now)
+	if s.Calendar != nil && !dc.Employee.IsGuest {
+		working, err := s.Calendar.IsWorkingDay(ctx, day)
+		if err != nil {
+			// A holiday that cannot be read is taken as a working day, like before holidays
+			logging.From(ctx).Warn("Failed to read the work calendar", "error", err)
+		}
+		if !working {
+			status = models.AttendanceStatusOffDay
+		}
+	}
+
+	attendance := &models.Attendance{
+		EmployeeID:  dc.Employee.ID,
+		CheckInTime: dc.Now,
+		ScannerMac:  dc.Request.ScannerMac,
+		Status:      status,
+		CreatedDate: day,
+		Source:      models.AttendanceSourceScanner,
+	}
+	attendance.SnapshotEmployee(dc.Employee)
+	if dc.Employee.IsSynthetic {
+		attendance.Source = models.AttendanceSourceSelfTest
+	}
+
+	if err := s.Attendance.Create(ctx, attendance); errors.Is(err, repository.ErrAttendanceExists) {
+		// Another instance recorded the check-in after the dedupe stage looked
+		dc.Notef("already checked in today")
+		dc.Reject(ResultDuplicate, nil)
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to record attendance: %w", err)
+	}
+
+	logging.From(ctx).Info("✅ Employee checked in",
+		"employee", dc.Employee.Name, "at", dc.Now.Format("15:04:05"), "status", status)
+	dc.Attendance = attendance
+	dc.Notef("%s", status)
+	return true, nil
+}
+
+// BaselineStage folds the check-in into the employee's check-in time baseline; failures are only logged
+type BaselineStage struct {
+	Baselines *CheckInBaselines
+}
+
+func (s BaselineStage) Process(ctx context.Context, dc *DetectionContext) (bool, error) {
+	if dc.Employee.IsSynthetic || dc.Employee.IsGuest || dc.Attendance.Status == models.AttendanceStatusOffDay {
+		return true, nil
+	}
+	if err := s.Baselines.Observe(ctx, dc.Employee, dc.Attendance); err != nil {
+		logging.From(ctx).Warn("Failed to update check-in baseline", "employee", dc.Employee.Name, "error", err)
+		dc.Notef("baseline not updated")
+	}
+	return true, nil
+}
+
+// NotificationStage tells the employee about the check-in, and the admin chat if late
+type NotificationStage struct {
+	Notifier  BotNotifier
+	Templates *MessageTemplates
+	Scanners  repository.ScannerRepository // optional
+}
+
+func (s NotificationStage) Process(ctx context.Context, dc *DetectionContext) (bool, error) {
+	if dc.Employee.IsSynthetic {
+		dc.Notef("synthetic, not notified")
+		return true, nil
+	}
+	if dc.Employee.IsGuest {
+		dc.Notef("guest, not notified")
+		return true, nil
+	}
+	sendCheckInNotification(ctx, s.Notifier, s.Templates, dc.Employee, dc.Attendance, lookUpScanner(ctx, s.Scanners, dc.Attendance.ScannerMac))
+	return true, nil
+}
+
+// lookUpScanner returns the record of the scanner with the MAC, for its friendly name;
+// without one (or without scanners) notifications show the MAC
+func lookUpScanner(ctx context.Context, scanners repository.ScannerRepository, mac string) *models.Scanner {
+	fallback := &models.Scanner{ScannerMac: mac}
+	if scanners == nil || mac == "" {
+		return fallback
+	}
+	sc, err := scanners.GetByMac(ctx, mac)
+	if err != nil {
+		if !errors.Is(err, repository.ErrScannerNotFound) {
+			logging.From(ctx).Warn("Failed to look up the scanner name", "scanner", mac, "error", err)
+		}
+		return fallback
+	}
+	return sc
+}
+
+// onTimeApprovedText is the status shown for a check-in within an approved late arrival
+const onTimeApprovedText = "เข้างานตามเวลาที่ได้รับอนุมัติ"
+
+// offDayText is the status shown for a check-in on a weekend or holiday
+const offDayText = "มาทำงานในวันหยุด"
+
+// sendCheckInNotification sends check-in notification to employee
+func sendCheckInNotification(ctx context.Context, notifier BotNotifier, templates *MessageTemplates, employee *models.Employee, attendance *models.Attendance, scanner *models.Scanner) {
+	checkInTime := attendance.CheckInTime
+	tmpl, data := checkInMessageData(employee.Language, employee, attendance, scanner)
+	message := templates.Render(employee.Language, tmpl, data)
+
+	switch {
+	case employee.NotificationMuted(models.NotificationCheckIn):
+		log.Printf("🔕 %s muted check-in notifications", employee.Name)
+	case employee.InQuietHours(checkInTime):
+		log.Printf("🌙 %s checked in during their quiet hours (%s), not notified", employee.Name, employee.QuietHours)
+	default:
+		notifyEmployee(ctx, notifier, employee, Notification{EventType: EventCheckIn, Message: message})
+	}
+
+	// Send to admin if late, whatever the employee's own settings
+	if attendance.Status == "late" {
+		lang := templates.AdminLanguage()
+		_, data := checkInMessageData(lang, employee, attendance, scanner)
+		notifyAdmin(ctx, notifier, Notification{EventType: EventLate, Message: templates.Render(lang, TemplateAdminLate, data)})
+	}
+}
+
+// checkInMessageData picks the check-in message for the attendance status and fills in
+// its values in lang
+func checkInMessageData(lang string, employee *models.Employee, attendance *models.Attendance, scanner *models.Scanner) (string, MessageData) {
+	data := MessageData{
+		Name:            employee.Name,
+		EmployeeCode:    employee.EmployeeCode,
+		Department:      employee.Department,
+		CheckInTime:     attendance.CheckInTime.Format("15:04:05"),
+		ScannerLocation: scanner.Label(),
+		Status:          statusText(lang, "on_time"),
+		StatusEmoji:     "✅",
+	}
+	switch attendance.Status {
+	case models.AttendanceStatusOnTimeApproved:
+		data.Status = statusText(lang, "on_time_approved")
+	case models.AttendanceStatusOffDay:
+		data.StatusEmoji = "🏖️"
+		data.Status = statusText(lang, "off_day")
+	case models.AttendanceStatusLate:
+		data.StatusEmoji = "⚠️"
+		data.Status = calculateLateStatus(lang, attendance.CheckInTime, attendance.CreatedDate, employee.WorkStartTime)
+		return TemplateCheckInLate, data
+	}
+	return TemplateCheckInOnTime, data
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/models"
+)
+
+// StationaryTagConfig configures the left-behind tag analysis
+type StationaryTagConfig struct {
+	EveningStart      string        // HH:MM after which detections count towards the analysis
+	MinSpan           time.Duration // minimum continuous detection span to flag a tag
+	MaxGap            time.Duration // largest gap between detections still considered continuous
+	MaxRSSISpread     int           // max-min RSSI (dB) for the signal to count as stable
+	ConfirmDetections int           // consecutive detections required the next morning
+}
+
+// DefaultStationaryTagConfig returns the built-in thresholds
+func DefaultStationaryTagConfig() StationaryTagConfig {
+	return StationaryTagConfig{
+		EveningStart:      "20:00",
+		MinSpan:           2 * time.Hour,
+		MaxGap:            15 * time.Minute,
+		MaxRSSISpread:     10,
+		ConfirmDetections: 3,
+	}
+}
+
+// eveningTrack is the current continuous run of evening detections for one employee
+type eveningTrack struct {
+	employee  models.Employee
+	day       string
+	spanStart time.Time
+	last      time.Time
+	minRSSI   int
+	maxRSSI   int
+	scanners  map[string]bool
+}
+
+// stationaryFlag marks an employee whose tag looked left behind overnight
+type stationaryFlag struct {
+	until         time.Time // flag is ignored after this time
+	scanners      map[string]bool
+	minRSSI       int // overnight signal band
+	maxRSSI       int
+	confirmations int
+	lastSeen      time.Time
+}
+
+// StationaryTagDetector flags tags that were detected continuously with a stable
+// signal late in the evening, and requires stronger confirmation before the next
+// morning's check-in for those employees
+type StationaryTagDetector struct {
+	cfg          StationaryTagConfig
+	eveningStart time.Duration
+	notifier     BotNotifier
+
+	mu      sync.Mutex
+	evening map[string]*eveningTrack
+	flagged map[string]*stationaryFlag
+}
+
+// NewStationaryTagDetector creates a detector; the notifier receives the "left at office" message
+func NewStationaryTagDetector(cfg StationaryTagConfig, notifier BotNotifier) (*StationaryTagDetector, error) {
+	eveningStart, err := parseClock(cfg.EveningStart)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stationary tag evening start: %w", err)
+	}
+	if cfg.ConfirmDetections < 1 {
+		cfg.ConfirmDetections = 1
+	}
+
+	return &StationaryTagDetector{
+		cfg:          cfg,
+		eveningStart: eveningStart,
+		notifier:     notifier,
+		evening:      make(map[string]*eveningTrack),
+		flagged:      make(map[string]*stationaryFlag),
+	}, nil
+}
+
+// Observe records a detection of an employee's device; only evening detections are kept
+func (d *StationaryTagDetector) Observe(employee *models.Employee, scannerMac string, rssi int, at time.Time) {
+	if sinceMidnight(at) < d.eveningStart {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	day := at.Format("2006-01-02")
+	track, ok := d.evening[employee.ID]
+	if !ok || track.day != day || at.Sub(track.last) > d.cfg.MaxGap {
+		// Start a new continuous run
+		d.evening[employee.ID] = &eveningTrack{
+			employee:  *employee,
+			day:       day,
+			spanStart: at,
+			last:      at,
+			minRSSI:   rssi,
+			maxRSSI:   rssi,
+			scanners:  map[string]bool{scannerMac: true},
+		}
+		return
+	}
+
+	track.last = at
+	track.scanners[scannerMac] = true
+	if rssi < track.minRSSI {
+		track.minRSSI = rssi
+	}
+	if rssi > track.maxRSSI {
+		track.maxRSSI = rssi
+	}
+}
+
+// Analyze is the end-of-day task: it flags employees whose tag has been detected
+// continuously with a stable signal up to now and notifies them
+func (d *StationaryTagDetector) Analyze(ctx context.Context, now time.Time) error {
+	d.mu.Lock()
+	var toNotify []models.Employee
+	day := now.Format("2006-01-02")
+	for id, track := range d.evening {
+		if track.day == day &&
+			track.last.Sub(track.spanStart) >= d.cfg.MinSpan &&
+			now.Sub(track.last) <= d.cfg.MaxGap &&
+			track.maxRSSI-track.minRSSI <= d.cfg.MaxRSSISpread {
+
+			// The flag covers the whole next day
+			tomorrow := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, 1)
+			d.flagged[id] = &stationaryFlag{
+				until:    tomorrow.AddDate(0, 0, 1),
+				scanners: track.scanners,
+				minRSSI:  track.minRSSI,
+				maxRSSI:  track.maxRSSI,
+			}
+			toNotify = append(toNotify, track.employee)
+		}
+	}
+	d.evening = make(map[string]*eveningTrack)
+	d.mu.Unlock()
+
+	for _, emp := range toNotify {
+		log.Printf("🏷️ Possible stationary tag for employee %s, next check-in needs %d confirmations",
+			emp.Name, d.cfg.ConfirmDetections)
+		if d.notifier != nil && emp.TelegramChatID != 0 {
+			d.notifier.SendPersonalNotification(emp.TelegramChatID, fmt.Sprintf(
+				"🏷️ %s\n\n"+
+					"ระบบตรวจพบแท็กของคุณอยู่กับที่ต่อเนื่องหลังเวลา `%s`\n"+
+					"พรุ่งนี้ระบบจะยืนยันการเข้างานเพิ่มเติมก่อนบันทึกเวลา",
+				markdown.Bold("คุณ"+emp.Name+" ลืมแท็กไว้ที่สำนักงานหรือไม่?"), d.cfg.EveningStart))
+		}
+	}
+	return nil
+}
+
+// ConfirmCheckIn reports whether a check-in may be created for this detection.
+// Unflagged employees are always confirmed. Flagged employees need a detection from a
+// scanner that did not see the tag overnight, or enough consecutive detections whose
+// RSSI falls outside the overnight band (i.e. the tag has actually moved).
+func (d *StationaryTagDetector) ConfirmCheckIn(employeeID, scannerMac string, rssi int, at time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	flag, ok := d.flagged[employeeID]
+	if !ok {
+		return true
+	}
+	if !at.Before(flag.until) {
+		delete(d.flagged, employeeID)
+		return true
+	}
+
+	if !flag.scanners[scannerMac] {
+		delete(d.flagged, employeeID)
+		return true
+	}
+
+	inBand := rssi >= flag.minRSSI-d.cfg.MaxRSSISpread && rssi <= flag.maxRSSI+d.cfg.MaxRSSISpread
+	if inBand || (flag.confirmations > 0 && at.Sub(flag.lastSeen) > d.cfg.MaxGap) {
+		flag.confirmations = 0
+	}
+	if inBand {
+		return false
+	}
+	flag.confirmations++
+	flag.lastSeen = at
+
+	if flag.confirmations >= d.cfg.ConfirmDetections {
+		delete(d.flagged, employeeID)
+		return true
+	}
+	return false
+}
+
+// IsFlagged reports whether the employee currently has a possible stationary tag
+func (d *StationaryTagDetector) IsFlagged(employeeID string, at time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	flag, ok := d.flagged[employeeID]
+	return ok && at.Before(flag.until)
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/models"
+)
+
+type recordingNotifier struct {
+	admin    []string
+	personal map[int64][]string
+}
+
+func newRecordingNotifier() *recordingNotifier {
+	return &recordingNotifier{personal: make(map[int64][]string)}
+}
+
+func (n *recordingNotifier) SendNotification(message string) {
+	n.admin = append(n.admin, message)
+}
+
+func (n *recordingNotifier) SendPersonalNotification(chatID int64, message string) {
+	n.personal[chatID] = append(n.personal[chatID], message)
+}
+
+// observeEvery feeds detections every interval from start (inclusive) to end (inclusive)
+func observeEvery(d *StationaryTagDetector, emp *models.Employee, scanner string, rssi func(i int) int,
+	start, end time.Time, interval time.Duration) {
+	for i, at := 0, start; !at.After(end); i, at = i+1, at.Add(interval) {
+		d.Observe(emp, scanner, rssi(i), at)
+	}
+}
+
+func TestStationaryTagAnalyze(t *testing.T) {
+	day := func(h, m int) time.Time { return time.Date(2026, 2, 2, h, m, 0, 0, time.Local) }
+	steady := func(i int) int { return -62 - i%3 }
+	jumpy := func(i int) int { return -50 - (i%2)*30 }
+
+	tests := []struct {
+		name        string
+		observe     func(d *StationaryTagDetector, emp *models.Employee)
+		wantFlagged bool
+	}{
+		{
+			name: "Continuous stable detections all evening",
+			observe: func(d *StationaryTagDetector, emp *models.Employee) {
+				observeEvery(d, emp, "scanner-a", steady, day(20, 0), day(23, 25), 5*time.Minute)
+			},
+			wantFlagged: true,
+		},
+		{
+			name: "Detections before evening start are ignored",
+			observe: func(d *StationaryTagDetector, emp *models.Employee) {
+				observeEvery(d, emp, "scanner-a", steady, day(17, 0), day(19, 55), 5*time.Minute)
+			},
+			wantFlagged: false,
+		},
+		{
+			name: "Span shorter than minimum",
+			observe: func(d *StationaryTagDetector, emp *models.Employee) {
+				observeEvery(d, emp, "scanner-a", steady, day(22, 0), day(23, 25), 5*time.Minute)
+			},
+			wantFlagged: false,
+		},
+		{
+			name: "Gap breaks continuity",
+			observe: func(d *StationaryTagDetector, emp *models.Employee) {
+				observeEvery(d, emp, "scanner-a", steady, day(20, 0), day(21, 30), 5*time.Minute)
+				observeEvery(d, emp, "scanner-a", steady, day(22, 30), day(23, 25), 5*time.Minute)
+			},
+			wantFlagged: false,
+		},
+		{
+			name: "Unstable RSSI means the tag is moving",
+			observe: func(d *StationaryTagDetector, emp *models.Employee) {
+				observeEvery(d, emp, "scanner-a", jumpy, day(20, 0), day(23, 25), 5*time.Minute)
+			},
+			wantFlagged: false,
+		},
+		{
+			name: "Detections stopped well before the job ran",
+			observe: func(d *StationaryTagDetector, emp *models.Employee) {
+				observeEvery(d, emp, "scanner-a", steady, day(20, 0), day(22, 45), 5*time.Minute)
+			},
+			wantFlagged: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier := newRecordingNotifier()
+			d, err := NewStationaryTagDetector(DefaultStationaryTagConfig(), notifier)
+			if err != nil {
+				t.Fatalf("NewStationaryTagDetector() error = %v", err)
+			}
+			emp := &models.Employee{ID: "emp1", Name: "Somchai", TelegramChatID: 42}
+
+			tt.observe(d, emp)
+			if err := d.Analyze(context.Background(), day(23, 30)); err != nil {
+				t.Fatalf("Analyze() error = %v", err)
+			}
+
+			nextMorning := day(7, 0).AddDate(0, 0, 1)
+			if got := d.IsFlagged(emp.ID, nextMorning); got != tt.wantFlagged {
+				t.Errorf("IsFlagged() = %v, want %v", got, tt.wantFlagged)
+			}
+			if got := len(notifier.personal[42]) == 1; got != tt.wantFlagged {
+				t.Errorf("employee notified = %v, want %v", got, tt.wantFlagged)
+			}
+		})
+	}
+}
+
+func TestStationaryTagConfirmCheckIn(t *testing.T) {
+	evening := func(h, m int) time.Time { return time.Date(2026, 2, 2, h, m, 0, 0, time.Local) }
+	morning := func(h, m int) time.Time { return time.Date(2026, 2, 3, h, m, 0, 0, time.Local) }
+
+	type detection struct {
+		scanner string
+		rssi    int
+		at      time.Time
+	}
+
+	tests := []struct {
+		name       string
+		detections []detection
+		want       []bool
+	}{
+		{
+			name:       "Different scanner confirms immediately",
+			detections: []detection{{"scanner-b", -63, morning(7, 55)}},
+			want:       []bool{true},
+		},
+		{
+			name: "Tag still sitting on the desk never confirms",
+			detections: []detection{
+				{"scanner-a", -63, morning(0, 1)},
+				{"scanner-a", -62, morning(0, 2)},
+				{"scanner-a", -64, morning(0, 3)},
+				{"scanner-a", -63, morning(0, 4)},
+			},
+			want: []bool{false, false, false, false},
+		},
+		{
+			name: "Moving tag confirms after consecutive detections",
+			detections: []detection{
+				{"scanner-a", -45, morning(7, 50)},
+				{"scanner-a", -40, morning(7, 51)},
+				{"scanner-a", -42, morning(7, 52)},
+			},
+			want: []bool{false, false, true},
+		},
+		{
+			name: "Stationary reading resets the confirmation count",
+			detections: []detection{
+				{"scanner-a", -45, morning(7, 50)},
+				{"scanner-a", -40, morning(7, 51)},
+				{"scanner-a", -63, morning(7, 52)},
+				{"scanner-a", -42, morning(7, 53)},
+			},
+			want: []bool{false, false, false, false},
+		},
+		{
+			name:       "Flag expires after the following day",
+			detections: []detection{{"scanner-a", -63, morning(7, 0).AddDate(0, 0, 1)}},
+			want:       []bool{true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := NewStationaryTagDetector(DefaultStationaryTagConfig(), nil)
+			if err != nil {
+				t.Fatalf("NewStationaryTagDetector() error = %v", err)
+			}
+			emp := &models.Employee{ID: "emp1", Name: "Somchai"}
+			observeEvery(d, emp, "scanner-a", func(int) int { return -63 }, evening(20, 0), evening(23, 25), 5*time.Minute)
+			d.Analyze(context.Background(), evening(23, 30))
+
+			for i, det := range tt.detections {
+				if got := d.ConfirmCheckIn(emp.ID, det.scanner, det.rssi, det.at); got != tt.want[i] {
+					t.Errorf("detection %d: ConfirmCheckIn() = %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+
+	t.Run("Unflagged employee is always confirmed", func(t *testing.T) {
+		d, _ := NewStationaryTagDetector(DefaultStationaryTagConfig(), nil)
+		if !d.ConfirmCheckIn("someone", "scanner-a", -60, morning(8, 0)) {
+			t.Error("ConfirmCheckIn() = false, want true")
+		}
+	})
+}
+
+func TestEndOfDayJobNextRun(t *testing.T) {
+	job, err := NewEndOfDayJob("23:30")
+	if err != nil {
+		t.Fatalf("NewEndOfDayJob() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"Earlier the same day", time.Date(2026, 2, 2, 9, 0, 0, 0, time.Local), time.Date(2026, 2, 2, 23, 30, 0, 0, time.Local)},
+		{"Exactly at run time", time.Date(2026, 2, 2, 23, 30, 0, 0, time.Local), time.Date(2026, 2, 3, 23, 30, 0, 0, time.Local)},
+		{"After run time", time.Date(2026, 2, 2, 23, 45, 0, 0, time.Local), time.Date(2026, 2, 3, 23, 30, 0, 0, time.Local)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := job.nextRun(tt.now); !got.Equal(tt.want) {
+				t.Errorf("nextRun() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewEndOfDayJob("25:00"); err == nil {
+		t.Error("NewEndOfDayJob(25:00) error = nil, want error")
+	}
+}
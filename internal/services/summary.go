@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// SummarySection contributes lines to the admin daily summary; no lines means the section is omitted
+type SummarySection func(ctx context.Context, day time.Time) ([]string, error)
+
+type namedSection struct {
+	title string
+	lines SummarySection
+}
+
+// DailySummary sends the admin chat an end-of-day attendance summary
+type DailySummary struct {
+	employees  repository.EmployeeDirectory
+	attendance repository.AttendanceLog
+	notifier   BotNotifier
+	sections   []namedSection
+}
+
+// NewDailySummary creates a new daily summary
+func NewDailySummary(employees repository.EmployeeDirectory, attendance repository.AttendanceLog, notifier BotNotifier) *DailySummary {
+	return &DailySummary{employees: employees, attendance: attendance, notifier: notifier}
+}
+
+// AddSection appends a titled section to the summary
+func (d *DailySummary) AddSection(title string, lines SummarySection) {
+	d.sections = append(d.sections, namedSection{title: title, lines: lines})
+}
+
+// Send builds the summary for the day and sends it to the admin chat; it is an EndOfDayTask
+func (d *DailySummary) Send(ctx context.Context, day time.Time) error {
+	message, err := d.Build(ctx, day)
+	if err != nil {
+		return err
+	}
+	d.notifier.SendNotification(message)
+	return nil
+}
+
+// Build renders the summary message for the day
+func (d *DailySummary) Build(ctx context.Context, day time.Time) (string, error) {
+	employees, err := d.employees.ListActive(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list employees: %w", err)
+	}
+	records, err := d.attendance.ListByDate(ctx, day)
+	if err != nil {
+		return "", fmt.Errorf("failed to list attendance: %w", err)
+	}
+
+	staff := 0
+	for _, emp := range employees {
+		if !emp.IsGuest {
+			staff++
+		}
+	}
+
+	// Guests are counted on their own line, never as present or late staff
+	present := make(map[string]bool)
+	guests := make(map[string]bool)
+	late, approved := 0, 0
+	for _, a := range records {
+		if present[a.EmployeeID] || guests[a.EmployeeID] || a.Source == models.AttendanceSourceSelfTest {
+			continue
+		}
+		if a.Status == models.AttendanceStatusGuest {
+			guests[a.EmployeeID] = true
+			continue
+		}
+		present[a.EmployeeID] = true
+		switch a.Status {
+		case "late":
+			late++
+		case models.AttendanceStatusOnTimeApproved:
+			approved++
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "📋 *สรุปการเข้างานประจำวัน* %s\n\n", day.Format("02/01/2006"))
+	fmt.Fprintf(&b, "✅ เข้างาน: %d/%d คน\n", len(present), staff)
+	fmt.Fprintf(&b, "⚠️ เข้าสาย: %d คน\n", late)
+	if approved > 0 {
+		fmt.Fprintf(&b, "🕘 เข้าสายที่อนุมัติล่วงหน้า: %d คน\n", approved)
+	}
+	if len(guests) > 0 {
+		fmt.Fprintf(&b, "🏷️ ผู้มาติดต่อ (แท็กชั่วคราว): %d คน\n", len(guests))
+	}
+
+	for _, section := range d.sections {
+		lines, err := section.lines(ctx, day)
+		if err != nil {
+			log.Printf("Warning: daily summary section %q failed: %v", section.title, err)
+			continue
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n*%s*\n", section.title)
+		for _, line := range lines {
+			fmt.Fprintf(&b, "• %s\n", line)
+		}
+	}
+
+	return b.String(), nil
+}
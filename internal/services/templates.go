@@ -0,0 +1,246 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"med-pulse-bot/internal/markdown"
+	"med-pulse-bot/internal/models"
+)
+
+// Message template names; a templates directory overrides the Thai one with <name>.tmpl
+// and the English one with en/<name>.tmpl
+const (
+	TemplateCheckInOnTime = "checkin_ontime" // also on-time with an approved late arrival, and off days
+	TemplateCheckInLate   = "checkin_late"
+	TemplateCheckOut      = "checkout"
+	TemplateAdminLate     = "admin_late"
+)
+
+// MessageData is what a message template sees. The values are raw: templates pass the
+// ones from PocketBase through escape, bold or code, as the built-in ones do.
+type MessageData struct {
+	Name            string // employee name
+	EmployeeCode    string // empty if the employee has none
+	Department      string // empty if the employee has none
+	CheckInTime     string // HH:MM:SS
+	CheckOutTime    string // HH:MM:SS, check-out only
+	Worked          string // e.g. "8 ชั่วโมง 30 นาที", check-out only
+	ScannerLocation string
+	Status          string // e.g. "เข้างานตรงเวลา" or "เข้าสาย 12 นาที"
+	StatusEmoji     string // ✅, ⚠️ or 🏖️
+}
+
+// defaultMessageTemplates are the built-in messages by language
+var defaultMessageTemplates = map[string]map[string]string{
+	models.LanguageThai:    thaiMessageTemplates,
+	models.LanguageEnglish: englishMessageTemplates,
+}
+
+var thaiMessageTemplates = map[string]string{
+	TemplateCheckInOnTime: "{{.StatusEmoji}} {{bold (print \"สวัสดีตอนเช้า คุณ\" .Name \"!\")}}\n\n" +
+		"🕐 เวลาเข้างาน: {{code .CheckInTime}}\n" +
+		"📍 สถานที่: {{code .ScannerLocation}}\n" +
+		"⏰ สถานะ: {{bold .Status}}\n\n" +
+		"ขอให้มีความสุขกับการทำงานวันนี้! 😊",
+	TemplateCheckInLate: "{{.StatusEmoji}} {{bold (print \"สวัสดีตอนเช้า คุณ\" .Name \"!\")}}\n\n" +
+		"🕐 เวลาเข้างาน: {{code .CheckInTime}}\n" +
+		"📍 สถานที่: {{code .ScannerLocation}}\n" +
+		"⏰ สถานะ: {{bold .Status}}\n\n" +
+		"ขอให้มีความสุขกับการทำงานวันนี้! 😊",
+	TemplateCheckOut: "👋 {{bold (print \"บันทึกเวลาออกงาน คุณ\" .Name)}}\n\n" +
+		"🕐 เวลาเข้างาน: {{code .CheckInTime}}\n" +
+		"🏁 เวลาออกงาน: {{code .CheckOutTime}}\n" +
+		"⏱️ รวมเวลาทำงาน: {{bold .Worked}}\n\n" +
+		"หากยังไม่ได้ออกงาน เวลาออกงานจะปรับตามการตรวจพบครั้งล่าสุด",
+	TemplateAdminLate: "⚠️ *พนักงานเข้าสาย*\n" +
+		"👤 ชื่อ: {{code .Name}}\n" +
+		"{{if .EmployeeCode}}🪪 รหัส: {{code .EmployeeCode}}\n{{end}}" +
+		"{{if .Department}}🏢 แผนก: {{escape .Department}}\n{{end}}" +
+		"🕐 เวลา: {{code .CheckInTime}}\n" +
+		"⏰ {{escape .Status}}",
+}
+
+var englishMessageTemplates = map[string]string{
+	TemplateCheckInOnTime: "{{.StatusEmoji}} {{bold (print \"Good morning, \" .Name \"!\")}}\n\n" +
+		"🕐 Check-in: {{code .CheckInTime}}\n" +
+		"📍 Location: {{code .ScannerLocation}}\n" +
+		"⏰ Status: {{bold .Status}}\n\n" +
+		"Have a good day at work! 😊",
+	TemplateCheckInLate: "{{.StatusEmoji}} {{bold (print \"Good morning, \" .Name \"!\")}}\n\n" +
+		"🕐 Check-in: {{code .CheckInTime}}\n" +
+		"📍 Location: {{code .ScannerLocation}}\n" +
+		"⏰ Status: {{bold .Status}}\n\n" +
+		"Have a good day at work! 😊",
+	TemplateCheckOut: "👋 {{bold (print \"Check-out recorded, \" .Name)}}\n\n" +
+		"🕐 Check-in: {{code .CheckInTime}}\n" +
+		"🏁 Check-out: {{code .CheckOutTime}}\n" +
+		"⏱️ Worked: {{bold .Worked}}\n\n" +
+		"If you have not left yet, the check-out moves to the last time you are detected",
+	TemplateAdminLate: "⚠️ *Late check-in*\n" +
+		"👤 Name: {{code .Name}}\n" +
+		"{{if .EmployeeCode}}🪪 Code: {{code .EmployeeCode}}\n{{end}}" +
+		"{{if .Department}}🏢 Department: {{escape .Department}}\n{{end}}" +
+		"🕐 Time: {{code .CheckInTime}}\n" +
+		"⏰ {{escape .Status}}",
+}
+
+// statusTexts are the .Status and .Worked values by language
+var statusTexts = map[string]map[string]string{
+	models.LanguageThai: {
+		"on_time":          "เข้างานตรงเวลา",
+		"on_time_approved": onTimeApprovedText,
+		"off_day":          offDayText,
+		"late":             "เข้าสาย",
+		"late_minutes":     "เข้าสาย %d นาที",
+		"worked":           "%d ชั่วโมง %d นาที",
+	},
+	models.LanguageEnglish: {
+		"on_time":          "On time",
+		"on_time_approved": "On time (approved late arrival)",
+		"off_day":          "Working on a day off",
+		"late":             "Late",
+		"late_minutes":     "Late by %d min",
+		"worked":           "%dh %dm",
+	},
+}
+
+// statusText returns the text called key in lang, in Thai if lang has none
+func statusText(lang, key string) string {
+	if text, ok := statusTexts[lang][key]; ok {
+		return text
+	}
+	return statusTexts[models.LanguageThai][key]
+}
+
+// templateFuncs format values for Telegram Markdown
+var templateFuncs = template.FuncMap{
+	"escape": markdown.Escape,
+	"bold":   markdown.Bold,
+	"code":   markdown.Code,
+}
+
+// sampleMessageData has every Markdown character in the values a template could print
+// unescaped, so LoadMessageTemplates catches a template that does
+var sampleMessageData = MessageData{
+	Name:            "Som_chai *Test*",
+	EmployeeCode:    "N_001",
+	Department:      "Ward_3 *ICU*",
+	CheckInTime:     "08:05:00",
+	CheckOutTime:    "17:10:00",
+	Worked:          "9 ชั่วโมง 5 นาที",
+	ScannerLocation: "Scanner aa:bb:cc:dd:ee:ff [lobby_1]",
+	Status:          "สาย 5 นาที",
+	StatusEmoji:     "⚠️",
+}
+
+// MessageTemplates renders the notification messages. A nil *MessageTemplates renders
+// the built-in ones, with the admin chat's in Thai.
+type MessageTemplates struct {
+	templates     map[string]map[string]*template.Template // language → name → template
+	adminLanguage string
+}
+
+var builtinMessageTemplates = mustParseMessageTemplates(defaultMessageTemplates)
+
+// LoadMessageTemplates reads <name>.tmpl (Thai) and en/<name>.tmpl (English) for each
+// message from dir, keeping the built-in wording for the files that do not exist; an
+// empty dir keeps all of them. A template that does not parse, uses an unknown field or
+// renders invalid Markdown is an error. Messages to the admin chat are in adminLanguage.
+func LoadMessageTemplates(dir, adminLanguage string) (*MessageTemplates, error) {
+	if !models.ValidLanguage(adminLanguage) {
+		return nil, fmt.Errorf("unsupported admin language %q", adminLanguage)
+	}
+	sources := make(map[string]map[string]string, len(defaultMessageTemplates))
+	for lang, defaults := range defaultMessageTemplates {
+		sources[lang] = make(map[string]string, len(defaults))
+		langDir := dir
+		if lang != models.LanguageThai {
+			langDir = filepath.Join(dir, lang)
+		}
+		for name, text := range defaults {
+			sources[lang][name] = text
+			if dir == "" {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(langDir, name+".tmpl"))
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			sources[lang][name] = string(data)
+		}
+	}
+	t, err := parseMessageTemplates(sources)
+	if err != nil {
+		return nil, err
+	}
+	t.adminLanguage = adminLanguage
+	return t, nil
+}
+
+func parseMessageTemplates(sources map[string]map[string]string) (*MessageTemplates, error) {
+	t := &MessageTemplates{templates: make(map[string]map[string]*template.Template, len(sources)), adminLanguage: models.LanguageThai}
+	for lang, texts := range sources {
+		t.templates[lang] = make(map[string]*template.Template, len(texts))
+		for name, text := range texts {
+			tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+			if err != nil {
+				return nil, fmt.Errorf("%s template %s: %w", lang, name, err)
+			}
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, sampleMessageData); err != nil {
+				return nil, fmt.Errorf("%s template %s: %w", lang, name, err)
+			}
+			if err := markdown.Check(buf.String()); err != nil {
+				return nil, fmt.Errorf("%s template %s renders invalid Markdown (escape the values with escape, bold or code): %w", lang, name, err)
+			}
+			t.templates[lang][name] = tmpl
+		}
+	}
+	return t, nil
+}
+
+func mustParseMessageTemplates(sources map[string]map[string]string) *MessageTemplates {
+	t, err := parseMessageTemplates(sources)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// AdminLanguage is the language of the messages to the admin chat
+func (t *MessageTemplates) AdminLanguage() string {
+	if t == nil {
+		return models.LanguageThai
+	}
+	return t.adminLanguage
+}
+
+// Render returns the message called name in lang for data. An unsupported lang is
+// Thai; a custom template that fails falls back to the built-in wording.
+func (t *MessageTemplates) Render(lang, name string, data MessageData) string {
+	if t == nil {
+		t = builtinMessageTemplates
+	}
+	if !models.ValidLanguage(lang) {
+		lang = models.LanguageThai
+	}
+	var buf bytes.Buffer
+	err := t.templates[lang][name].Execute(&buf, data)
+	if err == nil {
+		return buf.String()
+	}
+	log.Printf("⚠️ Template %s/%s failed, using the built-in message: %v", lang, name, err)
+	buf.Reset()
+	_ = builtinMessageTemplates.templates[lang][name].Execute(&buf, data) // cannot fail on a MessageData
+	return buf.String()
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/models"
+)
+
+func TestLoadMessageTemplates(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{"no files keeps the built-in messages", nil, ""},
+		{"custom late message", map[string]string{"checkin_late.tmpl": "⏰ {{bold .Name}} {{escape .Status}} @ {{code .ScannerLocation}}"}, ""},
+		{"parse error", map[string]string{"checkout.tmpl": "{{bold .Name"}, "template checkout"},
+		{"unknown field", map[string]string{"admin_late.tmpl": "{{.Nmae}}"}, "can't evaluate field Nmae"},
+		{"unescaped name", map[string]string{"checkin_ontime.tmpl": "สวัสดี {{.Name}}"}, "renders invalid Markdown"},
+		{"custom English message", map[string]string{"en/checkout.tmpl": "👋 {{bold .Name}} worked {{.Worked}}"}, ""},
+		{"English parse error", map[string]string{"en/checkout.tmpl": "{{bold .Name"}, "en template checkout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, text := range tt.files {
+				if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, err := LoadMessageTemplates(dir, models.LanguageThai)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("LoadMessageTemplates() error = %v, want none", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("LoadMessageTemplates() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckInNotificationTemplates(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "checkin_late.tmpl"), []byte("⏰ {{bold .Name}} {{.CheckInTime}} {{escape .Status}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	templates, err := LoadMessageTemplates(dir, models.LanguageThai)
+	if err != nil {
+		t.Fatal(err)
+	}
+	emp := &models.Employee{Name: "Som_chai", TelegramChatID: 1001, WorkStartTime: "08:00:00"}
+
+	tests := []struct {
+		status       string
+		wantPersonal string
+	}{
+		{models.AttendanceStatusOnTime, "✅ *สวัสดีตอนเช้า คุณSom_chai!*\n\n🕐 เวลาเข้างาน: `07:55:00`"},
+		{models.AttendanceStatusLate, "⏰ *Som_chai* 08:20:00 "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			at := time.Date(2026, 2, 2, 7, 55, 0, 0, time.Local)
+			if tt.status == models.AttendanceStatusLate {
+				at = at.Add(25 * time.Minute)
+			}
+			notifier := newRecordingNotifier()
+			sendCheckInNotification(context.Background(), notifier, templates, emp, &models.Attendance{CheckInTime: at, CreatedDate: pipelineNow, Status: tt.status}, &models.Scanner{})
+			if got := notifier.personal[1001]; len(got) != 1 || !strings.HasPrefix(got[0], tt.wantPersonal) {
+				t.Errorf("sent %q, want it to start with %q", got, tt.wantPersonal)
+			}
+			for _, m := range notifier.admin {
+				if !strings.HasPrefix(m, "⚠️ *พนักงานเข้าสาย*\n👤 ชื่อ: `Som_chai`") {
+					t.Errorf("admin message %q, want the built-in one", m)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckInNotificationLanguages(t *testing.T) {
+	tests := []struct {
+		name      string
+		language  string
+		admin     string
+		wantEmp   []string
+		wantAdmin []string
+	}{
+		{"Thai employee", "", models.LanguageThai,
+			[]string{"*สวัสดีตอนเช้า คุณSomchai!*", "⏰ สถานะ: *เข้าสาย 20 นาที*"},
+			[]string{"*พนักงานเข้าสาย*", "🪪 รหัส: `N001`", "🏢 แผนก: Ward\\_3", "เข้าสาย 20 นาที"}},
+		{"English employee, Thai admins", models.LanguageEnglish, models.LanguageThai,
+			[]string{"*Good morning, Somchai!*", "⏰ Status: *Late by 20 min*"}, []string{"*พนักงานเข้าสาย*", "เข้าสาย 20 นาที"}},
+		{"English admins", "", models.LanguageEnglish,
+			[]string{"⏰ สถานะ: *เข้าสาย 20 นาที*"}, []string{"*Late check-in*", "🪪 Code: `N001`", "🏢 Department: Ward\\_3", "Late by 20 min"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templates, err := LoadMessageTemplates("", tt.admin)
+			if err != nil {
+				t.Fatal(err)
+			}
+			emp := &models.Employee{Name: "Somchai", EmployeeCode: "N001", Department: "Ward_3", TelegramChatID: 1001,
+				WorkStartTime: "08:00:00", Language: tt.language}
+			at := time.Date(2026, 2, 2, 8, 20, 0, 0, time.Local)
+			notifier := newRecordingNotifier()
+			sendCheckInNotification(context.Background(), notifier, templates, emp, &models.Attendance{CheckInTime: at, CreatedDate: at, Status: models.AttendanceStatusLate}, &models.Scanner{})
+			if len(notifier.personal[1001]) != 1 || len(notifier.admin) != 1 {
+				t.Fatalf("sent %d personal and %d admin messages, want one each", len(notifier.personal[1001]), len(notifier.admin))
+			}
+			for _, want := range tt.wantEmp {
+				if !strings.Contains(notifier.personal[1001][0], want) {
+					t.Errorf("employee message %q, want it to contain %q", notifier.personal[1001][0], want)
+				}
+			}
+			for _, want := range tt.wantAdmin {
+				if !strings.Contains(notifier.admin[0], want) {
+					t.Errorf("admin message %q, want it to contain %q", notifier.admin[0], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/logging"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// UnknownDeviceCapture records strong detections of devices no employee owns in the
+// devices collection, so an admin can later pair them. Each device is written at most
+// once per interval; the detections in between are added to its count on the next write,
+// along with their strongest RSSI.
+type UnknownDeviceCapture struct {
+	devices  repository.DeviceSightingRepository
+	minRSSI  int
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*pendingSighting // MAC → detections since the last write
+}
+
+type pendingSighting struct {
+	written time.Time
+	count   int
+	rssi    int // strongest since the last write
+}
+
+// NewUnknownDeviceCapture records unknown devices heard stronger than minRSSI (dBm),
+// writing each at most once per interval
+func NewUnknownDeviceCapture(devices repository.DeviceSightingRepository, minRSSI int, interval time.Duration) *UnknownDeviceCapture {
+	return &UnknownDeviceCapture{
+		devices:  devices,
+		minRSSI:  minRSSI,
+		interval: interval,
+		pending:  make(map[string]*pendingSighting),
+	}
+}
+
+// Observe counts a detection of the unknown device at now and writes the sighting when
+// it is due. Weak signals, e.g. people passing outside, are ignored.
+func (c *UnknownDeviceCapture) Observe(ctx context.Context, mac string, rssi int, now time.Time) {
+	if rssi <= c.minRSSI {
+		return
+	}
+	c.mu.Lock()
+	p, ok := c.pending[mac]
+	if !ok {
+		p = &pendingSighting{}
+		c.pending[mac] = p
+	}
+	if p.count == 0 || rssi > p.rssi {
+		p.rssi = rssi
+	}
+	p.count++
+	if !p.written.IsZero() && now.Sub(p.written) < c.interval && !now.Before(p.written) {
+		c.mu.Unlock()
+		return
+	}
+	sighting := models.DeviceSighting{MacAddress: mac, RSSI: p.rssi, SeenAt: now, Count: p.count}
+	p.written, p.count = now, 0
+	// Forget devices with nothing left to write so passers-by do not pile up
+	for m, other := range c.pending {
+		if other.count == 0 && now.Sub(other.written) >= c.interval {
+			delete(c.pending, m)
+		}
+	}
+	c.mu.Unlock()
+
+	if err := c.devices.UpsertSighting(ctx, sighting); err != nil {
+		logging.From(ctx).Warn("Failed to record unknown device", "error", err)
+		// Keep the detections for the next write
+		c.mu.Lock()
+		if p, ok := c.pending[mac]; ok {
+			p.count += sighting.Count
+			p.rssi = max(p.rssi, sighting.RSSI)
+		} else {
+			c.pending[mac] = &pendingSighting{written: now, count: sighting.Count, rssi: sighting.RSSI}
+		}
+		c.mu.Unlock()
+	}
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+func TestUnknownDeviceCapture(t *testing.T) {
+	const mac = "aa:bb:cc:dd:ee:99"
+	type detection struct {
+		after time.Duration // since pipelineNow
+		rssi  int
+	}
+	tests := []struct {
+		name       string
+		detections []detection
+		want       string // the devices record, empty if none
+	}{
+		{"weak signal is ignored", []detection{{0, -75}, {time.Minute, -60}}, ""},
+		{"first strong detection is written", []detection{{0, -55}}, "-55@08:00 x1"},
+		{"repeats within the interval wait", []detection{{0, -55}, {time.Minute, -50}, {2 * time.Minute, -52}}, "-55@08:00 x1"},
+		{"next write adds the waiting detections", []detection{{0, -55}, {time.Minute, -50}, {2 * time.Minute, -70}, {5 * time.Minute, -52}}, "-50@08:05 x3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memory.NewStore(clock.NewFake(pipelineNow))
+			capture := NewUnknownDeviceCapture(store.DeviceSightingLog(), -60, 5*time.Minute)
+			for _, d := range tt.detections {
+				capture.Observe(context.Background(), mac, d.rssi, pipelineNow.Add(d.after))
+			}
+			got := ""
+			for _, s := range store.DeviceSightings() {
+				got = fmt.Sprintf("%d@%s x%d", s.RSSI, s.SeenAt.Format("15:04"), s.Count)
+			}
+			if got != tt.want {
+				t.Errorf("devices record = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttendanceCapturesUnknownDevices(t *testing.T) {
+	store := newPipelineStore()
+	service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), newRecordingNotifier())
+	service.SetClock(clock.NewFake(pipelineNow))
+	service.SetUnknownDeviceCapture(NewUnknownDeviceCapture(store.DeviceSightingLog(), -60, 5*time.Minute))
+
+	for _, mac := range []string{"AA:BB:CC:DD:EE:99", "aa:bb:cc:dd:ee:01"} {
+		if err := service.ProcessDetection(context.Background(), &models.DetectionRequest{ScannerMac: "scanner-1", MacAddress: mac, RSSI: -50}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sightings := store.DeviceSightings()
+	if len(sightings) != 1 || sightings[0].MacAddress != "aa:bb:cc:dd:ee:99" || sightings[0].Count != 1 {
+		t.Errorf("devices = %+v, want the unknown device only", sightings)
+	}
+}
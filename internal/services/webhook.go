@@ -0,0 +1,270 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/metrics"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+const (
+	// webhookTimeout bounds one webhook call
+	webhookTimeout = 5 * time.Second
+	// webhookQueueSize is how many notifications may wait for the webhook; beyond it they
+	// are dead-lettered rather than blocking the caller
+	webhookQueueSize = 256
+	// webhookAttempts is how often one notification is POSTed before it is dead-lettered
+	webhookAttempts = 5
+	// webhookDeadLetterTimeout bounds recording a dead letter
+	webhookDeadLetterTimeout = 3 * time.Second
+)
+
+// webhookRetryDelay is the wait before the second attempt, doubled before each next one
+var webhookRetryDelay = time.Second
+
+// webhookSleep waits d or until ctx is done; tests replace it to skip the waits
+var webhookSleep = func(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// WebhookAdminRecipient is the recipient of admin notifications in webhook payloads
+const WebhookAdminRecipient = "admin"
+
+// errWebhookQueueFull dead-letters a notification that found the queue full
+var errWebhookQueueFull = errors.New("webhook queue full")
+
+// NotificationFailureRecorder counts undelivered notifications for the
+// notification_failures alert
+type NotificationFailureRecorder interface {
+	RecordNotificationFailure(err error)
+}
+
+// webhookPayload is the JSON body POSTed for every notification
+type webhookPayload struct {
+	Recipient string `json:"recipient"`
+	Message   string `json:"message"`
+	EventType string `json:"event_type"`
+}
+
+// webhookStatusError is a webhook answer other than 2xx
+type webhookStatusError struct {
+	code int
+}
+
+func (e *webhookStatusError) Error() string {
+	return fmt.Sprintf("webhook returned status %d", e.code)
+}
+
+// WebhookNotifier POSTs every notification as JSON to a URL. Between Start and Flush
+// notifications are queued and POSTed in the background one at a time, with retries,
+// like the Telegram outbox, so a slow or failing endpoint never holds up the detection
+// that sent them. Outside of them each is POSTed once, right away.
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+
+	deadLetters repository.NotificationFailureLog // optional
+	failures    NotificationFailureRecorder       // optional
+
+	mu     sync.RWMutex
+	queue  chan webhookPayload // nil outside Start and Flush
+	ctx    context.Context     // cancelled when the flush runs out of time
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewWebhookNotifier creates a notifier POSTing to url
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{url: url, client: &http.Client{Timeout: webhookTimeout}}
+}
+
+// SetDeadLetters keeps the notifications that were never delivered in l and counts them
+// in failures; either may be nil
+func (w *WebhookNotifier) SetDeadLetters(l repository.NotificationFailureLog, failures NotificationFailureRecorder) {
+	w.deadLetters, w.failures = l, failures
+}
+
+// Start queues notifications and POSTs them in the background until Flush
+func (w *WebhookNotifier) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.mu.Lock()
+	w.queue = make(chan webhookPayload, webhookQueueSize)
+	w.ctx, w.cancel, w.done = ctx, cancel, make(chan struct{})
+	go w.run(w.queue, w.done)
+	w.mu.Unlock()
+}
+
+// Flush stops queueing and waits for the queued notifications to be delivered. Those
+// still waiting when ctx is done are dead-lettered.
+func (w *WebhookNotifier) Flush(ctx context.Context) error {
+	w.mu.Lock()
+	queue, done, cancel := w.queue, w.done, w.cancel
+	w.queue = nil
+	if queue != nil {
+		close(queue)
+	}
+	w.mu.Unlock()
+	if queue == nil {
+		return nil
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		cancel()
+		<-done
+		return fmt.Errorf("webhook notifications left undelivered: %w", ctx.Err())
+	}
+}
+
+// NotifyEmployee sends msg addressed to the employee's code, or its record ID or Telegram
+// chat ID when it has none
+func (w *WebhookNotifier) NotifyEmployee(ctx context.Context, employee *models.Employee, msg Notification) error {
+	recipient := employee.EmployeeCode
+	if recipient == "" {
+		recipient = employee.ID
+	}
+	if recipient == "" {
+		recipient = strconv.FormatInt(employee.TelegramChatID, 10)
+	}
+	return w.send(ctx, webhookPayload{Recipient: recipient, Message: msg.Message, EventType: msg.EventType})
+}
+
+// NotifyAdmin sends msg addressed to WebhookAdminRecipient
+func (w *WebhookNotifier) NotifyAdmin(ctx context.Context, msg Notification) error {
+	return w.send(ctx, webhookPayload{Recipient: WebhookAdminRecipient, Message: msg.Message, EventType: msg.EventType})
+}
+
+// SendNotification sends an admin message
+func (w *WebhookNotifier) SendNotification(message string) {
+	notifyAdmin(context.Background(), w, Notification{EventType: EventAdmin, Message: message})
+}
+
+// SendPersonalNotification sends a personal message addressed to the Telegram chat ID
+func (w *WebhookNotifier) SendPersonalNotification(chatID int64, message string) {
+	notifyEmployee(context.Background(), w, &models.Employee{TelegramChatID: chatID},
+		Notification{EventType: EventPersonal, Message: message})
+}
+
+// send queues payload, or POSTs it right away when the notifier was not started. A full
+// queue dead-letters it.
+func (w *WebhookNotifier) send(ctx context.Context, payload webhookPayload) error {
+	w.mu.RLock()
+	queue := w.queue
+	queued := false
+	if queue != nil {
+		select {
+		case queue <- payload:
+			queued = true
+		default:
+		}
+	}
+	w.mu.RUnlock()
+
+	switch {
+	case queued:
+		return nil
+	case queue != nil:
+		w.deadLetter(payload, 0, errWebhookQueueFull)
+		return errWebhookQueueFull
+	}
+	return w.post(ctx, payload)
+}
+
+func (w *WebhookNotifier) run(queue <-chan webhookPayload, done chan<- struct{}) {
+	defer close(done)
+	for payload := range queue {
+		if err := w.ctx.Err(); err != nil {
+			w.deadLetter(payload, 0, fmt.Errorf("shutting down: %w", err))
+			continue
+		}
+		w.deliverWithRetry(w.ctx, payload)
+	}
+}
+
+// deliverWithRetry POSTs payload until the webhook accepts it, rejects it for good (a 4xx
+// other than 429) or the attempts run out, and dead-letters it in the last two cases
+func (w *WebhookNotifier) deliverWithRetry(ctx context.Context, payload webhookPayload) {
+	for attempt := 1; ; attempt++ {
+		err := w.post(ctx, payload)
+		if err == nil {
+			return
+		}
+		var status *webhookStatusError
+		final := errors.As(err, &status) && status.code >= 400 && status.code < 500 && status.code != http.StatusTooManyRequests
+		if final || attempt == webhookAttempts {
+			w.deadLetter(payload, attempt, err)
+			return
+		}
+		wait := webhookRetryDelay << (attempt - 1)
+		log.Printf("⏳ Webhook delivery failed (attempt %d/%d), retrying in %s: %v", attempt, webhookAttempts, wait, err)
+		if serr := webhookSleep(ctx, wait); serr != nil {
+			w.deadLetter(payload, attempt, fmt.Errorf("%w; shutting down: %w", err, serr))
+			return
+		}
+	}
+}
+
+// post POSTs payload once
+func (w *WebhookNotifier) post(ctx context.Context, payload webhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &webhookStatusError{code: resp.StatusCode}
+	}
+	return nil
+}
+
+// deadLetter gives up on payload: it is logged, counted for the notification_failures
+// alert and kept in the dead letter log if one is set
+func (w *WebhookNotifier) deadLetter(payload webhookPayload, attempts int, err error) {
+	// The recipient and the text are personal, so they are only logged at debug
+	log.Printf("💀 Gave up POSTing a %s notification to the webhook after %d attempts: %v", payload.EventType, attempts, err)
+	slog.Debug("Undelivered webhook notification", "recipient", payload.Recipient, "text", payload.Message)
+	metrics.NotificationFailures.Inc()
+	if w.failures != nil {
+		w.failures.RecordNotificationFailure(err)
+	}
+	if w.deadLetters == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), webhookDeadLetterTimeout)
+	defer cancel()
+	failure := &models.NotificationFailure{
+		Text: payload.Message, Error: "webhook: " + err.Error(), Attempts: attempts, FailedAt: time.Now(),
+	}
+	if rerr := w.deadLetters.Record(ctx, failure); rerr != nil {
+		log.Printf("Failed to record the undelivered webhook notification: %v", rerr)
+	}
+}
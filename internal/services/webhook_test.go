@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/repository/memory"
+)
+
+// failureCounter counts the undelivered notifications it is told about
+type failureCounter struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (c *failureCounter) RecordNotificationFailure(err error) {
+	c.mu.Lock()
+	c.n++
+	c.mu.Unlock()
+}
+
+func TestWebhookQueuedDelivery(t *testing.T) {
+	saved := webhookSleep
+	webhookSleep = func(ctx context.Context, d time.Duration) error { return ctx.Err() }
+	t.Cleanup(func() { webhookSleep = saved })
+
+	tests := []struct {
+		name           string
+		statuses       []int // answers in turn, then 200
+		wantCalls      int
+		wantDeadLetter bool
+	}{
+		{"delivered", nil, 1, false},
+		{"retried until accepted", []int{http.StatusBadGateway, http.StatusTooManyRequests}, 3, false},
+		{"rejected for good", []int{http.StatusBadRequest}, 1, true},
+		{"attempts run out", []int{500, 500, 500, 500, 500}, webhookAttempts, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var calls int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				status := http.StatusOK
+				if calls < len(tt.statuses) {
+					status = tt.statuses[calls]
+				}
+				calls++
+				w.WriteHeader(status)
+			}))
+			defer srv.Close()
+
+			store := memory.NewStore(nil)
+			failures := &failureCounter{}
+			w := NewWebhookNotifier(srv.URL)
+			w.SetDeadLetters(store.NotificationFailureLog(), failures)
+			w.Start()
+			w.SendNotification("late")
+			if err := w.Flush(context.Background()); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+
+			if calls != tt.wantCalls {
+				t.Errorf("webhook called %d times, want %d", calls, tt.wantCalls)
+			}
+			dead := store.NotificationFailures()
+			if (len(dead) == 1) != tt.wantDeadLetter || (failures.n == 1) != tt.wantDeadLetter {
+				t.Errorf("dead letters = %+v, failures = %d, want dead-lettered %v", dead, failures.n, tt.wantDeadLetter)
+			}
+		})
+	}
+}
+
+func TestWebhookSlowEndpointDoesNotBlockSender(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+
+	w := NewWebhookNotifier(srv.URL)
+	w.Start()
+	start := time.Now()
+	w.SendNotification("late")
+	w.SendNotification("late again")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("sending took %s with a hanging webhook", elapsed)
+	}
+	close(release)
+	if err := w.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/logging"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// DeviceWhitelist decides which detections may be processed at all under WHITELIST_ONLY:
+// those of devices an employee owns and of devices an admin whitelisted. Its lookups run
+// with logging discarded, so a device that is not admitted leaves no trace in the logs.
+type DeviceWhitelist struct {
+	match     EmployeeMatchStage
+	whitelist repository.DeviceSightingRepository
+	ttl       time.Duration
+	clock     clock.Clock
+
+	mu     sync.Mutex
+	macs   map[string]bool // whitelisted devices
+	loaded time.Time
+}
+
+// NewDeviceWhitelist admits the devices of employees, looked up in employees (pass the
+// cached repository), and the whitelisted devices, reread every ttl
+func NewDeviceWhitelist(employees repository.EmployeeRepository, whitelist repository.DeviceSightingRepository, ttl time.Duration) *DeviceWhitelist {
+	return &DeviceWhitelist{
+		match:     EmployeeMatchStage{Employees: employees},
+		whitelist: whitelist,
+		ttl:       ttl,
+		clock:     clock.Real{},
+	}
+}
+
+// SetClock replaces the time source, used by tests
+func (w *DeviceWhitelist) SetClock(c clock.Clock) {
+	w.clock = c
+}
+
+// SetDevices also admits devices registered to an employee besides their mac_address
+func (w *DeviceWhitelist) SetDevices(devices repository.DeviceRepository) {
+	w.match.Devices = devices
+}
+
+// Admits reports whether the detection's device is an employee's or whitelisted. A failed
+// lookup does not admit it: nothing about a device is kept before it is known to be allowed.
+func (w *DeviceWhitelist) Admits(ctx context.Context, req *models.DetectionRequest) bool {
+	if w.whitelisted(ctx, req.MacAddress) {
+		return true
+	}
+	_, _, err := w.match.match(logging.Discard(ctx), req)
+	if err != nil && !errors.Is(err, repository.ErrEmployeeNotFound) {
+		// The error may quote the lookup, so only its occurrence is logged
+		logging.From(ctx).Warn("Whitelist lookup failed, dropping the detection")
+	}
+	return err == nil
+}
+
+// whitelisted reports whether admins whitelisted the MAC, rereading the list once it is
+// older than ttl; a failed reread keeps the list already loaded
+func (w *DeviceWhitelist) whitelisted(ctx context.Context, mac string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.clock.Now()
+	if w.macs == nil || now.Sub(w.loaded) >= w.ttl {
+		macs, err := w.whitelist.ListWhitelisted(ctx)
+		if err != nil {
+			logging.From(ctx).Warn("Failed to read the device whitelist", "error", err)
+			if w.macs == nil {
+				w.macs = map[string]bool{}
+			}
+		} else {
+			w.macs = make(map[string]bool, len(macs))
+			for _, m := range macs {
+				w.macs[m] = true
+			}
+		}
+		w.loaded = now
+	}
+	return w.macs[mac]
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository"
+)
+
+// countingDetections counts the detection records created
+type countingDetections struct {
+	repository.EmployeeDetectionRepository
+	creates int
+}
+
+func (c *countingDetections) Create(ctx context.Context, detection *models.EmployeeDetection) error {
+	c.creates++
+	return c.EmployeeDetectionRepository.Create(ctx, detection)
+}
+
+func TestDeviceWhitelist(t *testing.T) {
+	tests := []struct {
+		name        string
+		mac         string
+		wantAdmit   bool
+		wantCreates int
+	}{
+		{"employee tag", "aa:bb:cc:dd:ee:01", true, 1},
+		{"extra device of an employee", "aa:bb:cc:dd:ee:02", true, 1},
+		{"whitelisted device", "aa:bb:cc:dd:ee:0a", true, 0},
+		{"reviewed but ignored device", "aa:bb:cc:dd:ee:0b", false, 0},
+		{"unknown device", "aa:bb:cc:dd:ee:99", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newPipelineStore()
+			store.AddDevice(models.EmployeeDevice{EmployeeID: "e1", MacAddress: "aa:bb:cc:dd:ee:02"})
+			sightings := store.DeviceSightingLog()
+			for mac, whitelisted := range map[string]bool{"aa:bb:cc:dd:ee:0a": true, "aa:bb:cc:dd:ee:0b": false} {
+				sightings.UpsertSighting(context.Background(), models.DeviceSighting{MacAddress: mac, RSSI: -50, SeenAt: pipelineNow, Count: 1})
+				sightings.Review(context.Background(), mac, whitelisted)
+			}
+			detections := &countingDetections{EmployeeDetectionRepository: store.DetectionRecords()}
+			service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), detections, store.ScannerRecords(), newRecordingNotifier())
+			service.SetClock(clock.NewFake(pipelineNow))
+			service.SetDevices(store.Devices())
+			service.SetUnknownDeviceCapture(NewUnknownDeviceCapture(sightings, -60, time.Minute))
+			whitelist := NewDeviceWhitelist(store.Employees(), sightings, time.Minute)
+			whitelist.SetDevices(store.Devices())
+			service.SetWhitelist(whitelist)
+
+			req := &models.DetectionRequest{ScannerMac: "scanner-1", MacAddress: tt.mac, RSSI: -50}
+			if got := service.Admit(context.Background(), req); got != tt.wantAdmit {
+				t.Errorf("Admit() = %v, want %v", got, tt.wantAdmit)
+			}
+			res, err := service.DetectWithResult(context.Background(), req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dropped := res.Stage == "whitelist"; dropped == tt.wantAdmit {
+				t.Errorf("result = %+v, want dropped = %v", res, !tt.wantAdmit)
+			}
+			if detections.creates != tt.wantCreates {
+				t.Errorf("detection records created = %d, want %d", detections.creates, tt.wantCreates)
+			}
+			for _, s := range store.DeviceSightings() {
+				if !tt.wantAdmit && s.MacAddress == tt.mac && s.Count > 1 {
+					t.Errorf("sighting of %s recorded again: %+v", tt.mac, s)
+				}
+			}
+		})
+	}
+}
+
+func TestDeviceWhitelistRereads(t *testing.T) {
+	store := newPipelineStore()
+	sightings := store.DeviceSightingLog()
+	clk := clock.NewFake(pipelineNow)
+	whitelist := NewDeviceWhitelist(store.Employees(), sightings, time.Minute)
+	whitelist.SetClock(clk)
+	req := &models.DetectionRequest{ScannerMac: "scanner-1", MacAddress: "aa:bb:cc:dd:ee:0a", RSSI: -50}
+
+	if whitelist.Admits(context.Background(), req) {
+		t.Fatal("Admits() = true before the device was whitelisted")
+	}
+	sightings.UpsertSighting(context.Background(), models.DeviceSighting{MacAddress: req.MacAddress, RSSI: -50, SeenAt: pipelineNow, Count: 1})
+	sightings.Review(context.Background(), req.MacAddress, true)
+	if whitelist.Admits(context.Background(), req) {
+		t.Error("Admits() = true before the list was reread")
+	}
+	clk.Advance(time.Minute)
+	if !whitelist.Admits(context.Background(), req) {
+		t.Error("Admits() = false after the list was reread")
+	}
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"med-pulse-bot/internal/repository"
+)
+
+// weekdayNames maps the WORK_DAYS abbreviations to weekdays
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// WorkCalendar tells working days from weekends and holidays. Check-ins on other days are
+// recorded as offday: never late, and the admin chat is not alerted.
+type WorkCalendar struct {
+	days     map[time.Weekday]bool // nil when every weekday is a working day
+	holidays repository.HolidayRepository
+}
+
+// NewWorkCalendar parses a comma-separated list of working weekdays such as
+// "Mon,Tue,Wed,Thu,Fri"; an empty list makes every weekday a working day
+func NewWorkCalendar(workDays string, holidays repository.HolidayRepository) (*WorkCalendar, error) {
+	c := &WorkCalendar{holidays: holidays}
+	if strings.TrimSpace(workDays) == "" {
+		return c, nil
+	}
+	c.days = make(map[time.Weekday]bool)
+	for _, name := range strings.Split(workDays, ",") {
+		day, ok := weekdayNames[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("invalid work day %q: want Mon, Tue, Wed, Thu, Fri, Sat or Sun", strings.TrimSpace(name))
+		}
+		c.days[day] = true
+	}
+	return c, nil
+}
+
+// IsWorkingDay reports whether date's calendar day is a working weekday and not a holiday
+func (c *WorkCalendar) IsWorkingDay(ctx context.Context, date time.Time) (bool, error) {
+	if c.days != nil && !c.days[date.Weekday()] {
+		return false, nil
+	}
+	if c.holidays == nil {
+		return true, nil
+	}
+	holiday, err := c.holidays.GetByDate(ctx, date.Format("2006-01-02"))
+	if err != nil {
+		return true, err
+	}
+	return holiday == nil, nil
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/repository/memory"
+)
+
+func TestWorkCalendar_IsWorkingDay(t *testing.T) {
+	store := memory.NewStore(nil)
+	store.AddHoliday("2026-04-13", "สงกรานต์")
+
+	tests := []struct {
+		name     string
+		workDays string
+		date     time.Time
+		want     bool
+	}{
+		{"weekday", "Mon,Tue,Wed,Thu,Fri", time.Date(2026, 4, 10, 9, 0, 0, 0, time.Local), true},
+		{"saturday", "Mon,Tue,Wed,Thu,Fri", time.Date(2026, 4, 11, 9, 0, 0, 0, time.Local), false},
+		{"holiday on a weekday", "Mon,Tue,Wed,Thu,Fri", time.Date(2026, 4, 13, 9, 0, 0, 0, time.Local), false},
+		{"every day without WORK_DAYS", "", time.Date(2026, 4, 11, 9, 0, 0, 0, time.Local), true},
+		{"holiday without WORK_DAYS", "", time.Date(2026, 4, 13, 9, 0, 0, 0, time.Local), false},
+		{"spaces and case", " sat , SUN ", time.Date(2026, 4, 12, 9, 0, 0, 0, time.Local), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewWorkCalendar(tt.workDays, store.Holidays())
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := c.IsWorkingDay(context.Background(), tt.date)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("IsWorkingDay(%s) = %v, want %v", tt.date.Format("Mon 2006-01-02"), got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewWorkCalendar("Mon,Funday", nil); err == nil {
+		t.Error("NewWorkCalendar() with an unknown day succeeded")
+	}
+}
+
+func TestAttendanceOffDay(t *testing.T) {
+	saturday := time.Date(2026, 4, 11, 9, 30, 0, 0, time.Local)
+	store := memory.NewStore(clock.NewFake(saturday))
+	store.AddEmployee(models.Employee{ID: "e1", Name: "Somchai", MacAddress: "aa:bb:cc:dd:ee:01",
+		TelegramChatID: 1001, WorkStartTime: "08:00:00", IsActive: true})
+	notifier := newRecordingNotifier()
+	service := NewAttendanceService(store.Employees(), store.AttendanceRecords(), store.DetectionRecords(), store.ScannerRecords(), notifier)
+	service.SetClock(clock.NewFake(saturday))
+	calendar, _ := NewWorkCalendar("Mon,Tue,Wed,Thu,Fri", store.Holidays())
+	service.SetWorkCalendar(calendar)
+
+	if err := service.ProcessDetection(context.Background(), &models.DetectionRequest{ScannerMac: "scanner-1", MacAddress: "aa:bb:cc:dd:ee:01", RSSI: -50}); err != nil {
+		t.Fatal(err)
+	}
+
+	if att := store.Attendance(); len(att) != 1 || att[0].Status != models.AttendanceStatusOffDay {
+		t.Fatalf("attendance = %+v, want one offday check-in", att)
+	}
+	if len(notifier.admin) != 0 {
+		t.Errorf("admin notifications = %q, want none for an off-day check-in", notifier.admin)
+	}
+	if msgs := notifier.personal[1001]; len(msgs) != 1 || strings.Contains(msgs[0], "เข้าสาย") {
+		t.Errorf("personal notifications = %q, want one that is not about being late", msgs)
+	}
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"med-pulse-bot/internal/logging"
+	"med-pulse-bot/internal/metrics"
+	"med-pulse-bot/internal/models"
+)
+
+// DetectionWorkers processes detections in the background, so a scanner is answered as
+// soon as its detection is queued instead of after the PocketBase round-trips. The queue
+// is bounded: when it is full, Enqueue refuses the detection and the scanner retries.
+type DetectionWorkers struct {
+	service  AttendanceProcessor
+	workers  int
+	tenantID string // metrics label
+
+	mu     sync.RWMutex
+	queue  chan queuedWork
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// queuedWork is a detection waiting for a worker with the context it arrived with
+type queuedWork struct {
+	ctx context.Context
+	req models.DetectionRequest
+}
+
+// NewDetectionWorkers creates a pool of workers calling service for up to queueSize
+// waiting detections; Start launches the workers
+func NewDetectionWorkers(service AttendanceProcessor, workers, queueSize int, tenantID string) *DetectionWorkers {
+	return &DetectionWorkers{
+		service:  service,
+		workers:  max(workers, 1),
+		tenantID: tenantID,
+		queue:    make(chan queuedWork, max(queueSize, 1)),
+	}
+}
+
+// Start launches the workers; they run until Stop
+func (p *DetectionWorkers) Start(context.Context) error {
+	for range p.workers {
+		p.wg.Add(1)
+		go p.work()
+	}
+	return nil
+}
+
+// Enqueue queues a detection without waiting and reports whether there was room. The
+// detection keeps ctx's values but not its cancellation, as the request that brought it
+// ends before it is processed.
+func (p *DetectionWorkers) Enqueue(ctx context.Context, req *models.DetectionRequest) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return false
+	}
+	select {
+	case p.queue <- queuedWork{ctx: context.WithoutCancel(ctx), req: *req}:
+		return true
+	default:
+		metrics.DetectionsDropped.Inc(p.tenantID)
+		return false
+	}
+}
+
+// Len is the number of detections waiting for a worker
+func (p *DetectionWorkers) Len() int {
+	return len(p.queue)
+}
+
+// Stop refuses new detections and waits until the queued ones are processed or ctx is
+// done, in which case the rest are given up
+func (p *DetectionWorkers) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		log.Printf("⚠️  Gave up %d queued detections [%s] on shutdown", len(p.queue), p.tenantID)
+		return ctx.Err()
+	}
+}
+
+// work processes queued detections until the queue is closed and empty
+func (p *DetectionWorkers) work() {
+	defer p.wg.Done()
+	for w := range p.queue {
+		if err := p.service.ProcessDetection(w.ctx, &w.req); err != nil {
+			logging.From(w.ctx).Error("Error processing detection", "scanner_mac", w.req.ScannerMac, "mac", w.req.MacAddress, "error", err)
+		}
+	}
+}
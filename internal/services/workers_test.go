@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"testing"
+
+	"med-pulse-bot/internal/metrics"
+	"med-pulse-bot/internal/models"
+)
+
+// blockingProcessor records detections, each waiting until release is closed
+type blockingProcessor struct {
+	release chan struct{}
+	mu      sync.Mutex
+	macs    []string
+}
+
+func (b *blockingProcessor) ProcessDetection(ctx context.Context, req *models.DetectionRequest) error {
+	<-b.release
+	b.mu.Lock()
+	b.macs = append(b.macs, req.MacAddress)
+	b.mu.Unlock()
+	return nil
+}
+
+func TestDetectionWorkers(t *testing.T) {
+	processor := &blockingProcessor{release: make(chan struct{})}
+	workers := NewDetectionWorkers(processor, 1, 2, "workers-test")
+	workers.Start(context.Background())
+	dropped := metrics.DetectionsDropped.Value("workers-test")
+
+	// The worker holds the first detection; two more fill the queue and the fourth is refused
+	enqueue := func(mac string) bool {
+		return workers.Enqueue(context.Background(), &models.DetectionRequest{ScannerMac: "scanner-1", MacAddress: mac})
+	}
+	if !enqueue("aa:bb:cc:dd:ee:01") {
+		t.Fatal("first detection refused")
+	}
+	for workers.Len() > 0 {
+		runtime.Gosched() // until the worker takes it
+	}
+	for _, mac := range []string{"aa:bb:cc:dd:ee:02", "aa:bb:cc:dd:ee:03"} {
+		if !enqueue(mac) {
+			t.Fatalf("%s refused with room in the queue", mac)
+		}
+	}
+	if enqueue("aa:bb:cc:dd:ee:04") {
+		t.Error("detection queued beyond the queue size")
+	}
+	if got := metrics.DetectionsDropped.Value("workers-test") - dropped; got != 1 {
+		t.Errorf("dropped detections counted = %v, want 1", got)
+	}
+
+	// Stopping finishes what was queued and refuses the rest
+	close(processor.release)
+	if err := workers.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(processor.macs) != 3 {
+		t.Errorf("processed %v, want the three queued detections", processor.macs)
+	}
+	if enqueue("aa:bb:cc:dd:ee:05") {
+		t.Error("detection queued after Stop")
+	}
+}
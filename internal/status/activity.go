@@ -0,0 +1,67 @@
+package status
+
+import (
+	"sort"
+	"time"
+
+	"med-pulse-bot/internal/macaddr"
+)
+
+// maxNotificationFailures caps how many failed sends are remembered
+const maxNotificationFailures = 1000
+
+// Scanner is when a scanner was last heard from since startup
+type Scanner struct {
+	Mac      string    `json:"scanner_mac"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// ScannerSeen notes a detection or heartbeat from the scanner
+func (s *SystemStatus) ScannerSeen(scannerMac string) {
+	mac, err := macaddr.Normalize(scannerMac)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scanners[mac] = s.clock.Now()
+}
+
+// Scanners returns every scanner heard from since startup, sorted by MAC
+func (s *SystemStatus) Scanners() []Scanner {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]Scanner, 0, len(s.scanners))
+	for mac, at := range s.scanners {
+		list = append(list, Scanner{Mac: mac, LastSeen: at})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Mac < list[j].Mac })
+	return list
+}
+
+// RecordNotificationFailure notes a Telegram message that could not be sent
+func (s *SystemStatus) RecordNotificationFailure(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifyFailures = append(s.notifyFailures, s.clock.Now())
+	if len(s.notifyFailures) > maxNotificationFailures {
+		s.notifyFailures = s.notifyFailures[len(s.notifyFailures)-maxNotificationFailures:]
+	}
+	if err != nil {
+		s.notifyLastErr = err.Error()
+	}
+}
+
+// NotificationFailures returns how many sends failed within window and, if any did,
+// the last error
+func (s *SystemStatus) NotificationFailures(window time.Duration) (int, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cutoff := s.clock.Now().Add(-window)
+	i := sort.Search(len(s.notifyFailures), func(i int) bool { return s.notifyFailures[i].After(cutoff) })
+	n := len(s.notifyFailures) - i
+	if n == 0 {
+		return 0, ""
+	}
+	return n, s.notifyLastErr
+}
@@ -0,0 +1,279 @@
+// Package status tracks whether the PocketBase backends are reachable, whether writes
+// are suspended for maintenance, when scanners were last heard from and whether Telegram
+// sends fail, so the bot, /readyz, /api/alerts and dashboards report the same state
+package status
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+)
+
+// DefaultFailureThreshold is the number of consecutive failed calls that marks a backend down
+const DefaultFailureThreshold = 3
+
+// DefaultProbeInterval is how often StartProbe checks each backend
+const DefaultProbeInterval = 15 * time.Second
+
+// Backend is the health of one PocketBase server
+type Backend struct {
+	Host    string    `json:"host"`
+	Healthy bool      `json:"healthy"`
+	Reason  string    `json:"reason,omitempty"`
+	Since   time.Time `json:"since"` // when the current state began
+	LastOK  time.Time `json:"last_ok,omitempty"`
+}
+
+type backendState struct {
+	Backend
+	failures int
+}
+
+// SystemStatus records the outcome of every PocketBase call. A backend is down after
+// threshold consecutive failures and up again after the first success.
+type SystemStatus struct {
+	mu        sync.Mutex
+	threshold int
+	clock     clock.Clock
+	backends  map[string]*backendState
+
+	readOnly      bool
+	readOnlySince time.Time
+	onWritable    []func()
+	onRecovered   map[string][]func() // host → callbacks run when it recovers
+
+	scanners       map[string]time.Time // normalized scanner MAC → last heard from
+	notifyFailures []time.Time          // recent failed Telegram sends, oldest first
+	notifyLastErr  string
+}
+
+// NewSystemStatus creates a status tracker; threshold < 1 uses DefaultFailureThreshold
+func NewSystemStatus(threshold int) *SystemStatus {
+	if threshold < 1 {
+		threshold = DefaultFailureThreshold
+	}
+	return &SystemStatus{
+		threshold: threshold,
+		clock:     clock.Real{},
+		backends:  make(map[string]*backendState),
+		scanners:  make(map[string]time.Time),
+	}
+}
+
+// SetClock replaces the time source, used by tests
+func (s *SystemStatus) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// Record notes the outcome of a call to host; a nil err is a success. A success on a
+// host marked down runs its OnRecovered callbacks.
+func (s *SystemStatus) Record(host string, err error) {
+	s.mu.Lock()
+	now := s.clock.Now()
+	b := s.state(host, now)
+	if err == nil {
+		b.failures = 0
+		b.LastOK = now
+		if b.Healthy {
+			s.mu.Unlock()
+			return
+		}
+		log.Printf("✅ PocketBase %s recovered after %s", host, now.Sub(b.Since).Round(time.Second))
+		b.Healthy, b.Reason, b.Since = true, "", now
+		callbacks := s.onRecovered[host]
+		s.mu.Unlock()
+		for _, fn := range callbacks {
+			fn()
+		}
+		return
+	}
+	defer s.mu.Unlock()
+
+	b.failures++
+	if b.Healthy && b.failures >= s.threshold {
+		log.Printf("🚨 PocketBase %s marked down after %d failures: %v", host, b.failures, err)
+		b.Healthy, b.Reason, b.Since = false, err.Error(), now
+	}
+}
+
+// state returns the tracked state of host, starting healthy; callers hold mu
+func (s *SystemStatus) state(host string, now time.Time) *backendState {
+	b, ok := s.backends[host]
+	if !ok {
+		b = &backendState{Backend: Backend{Host: host, Healthy: true, Since: now}}
+		s.backends[host] = b
+	}
+	return b
+}
+
+// Backend returns the health of the server behind baseURL; unseen servers are healthy
+func (s *SystemStatus) Backend(baseURL string) Backend {
+	host := hostOf(baseURL)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b, ok := s.backends[host]; ok {
+		return b.Backend
+	}
+	return Backend{Host: host, Healthy: true}
+}
+
+// Backends returns every tracked server, sorted by host
+func (s *SystemStatus) Backends() []Backend {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]Backend, 0, len(s.backends))
+	for _, b := range s.backends {
+		list = append(list, b.Backend)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Host < list[j].Host })
+	return list
+}
+
+// FailureThreshold returns the number of consecutive failed calls that marks a backend down
+func (s *SystemStatus) FailureThreshold() int {
+	return s.threshold
+}
+
+// Degraded reports whether any tracked server is down
+func (s *SystemStatus) Degraded() bool {
+	for _, b := range s.Backends() {
+		if !b.Healthy {
+			return true
+		}
+	}
+	return false
+}
+
+// SetReadOnly enters or leaves maintenance read-only mode and reports whether the mode
+// changed. Leaving it runs the OnWritable callbacks.
+func (s *SystemStatus) SetReadOnly(on bool) bool {
+	s.mu.Lock()
+	if s.readOnly == on {
+		s.mu.Unlock()
+		return false
+	}
+	s.readOnly, s.readOnlySince = on, s.clock.Now()
+	callbacks := s.onWritable
+	s.mu.Unlock()
+
+	if on {
+		log.Println("🛠️ Read-only mode on: writes are suspended")
+		return true
+	}
+	log.Println("🛠️ Read-only mode off: writes resumed")
+	for _, fn := range callbacks {
+		fn()
+	}
+	return true
+}
+
+// ReadOnly reports whether writes are suspended for maintenance
+func (s *SystemStatus) ReadOnly() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readOnly
+}
+
+// ReadOnlySince returns when read-only mode was last switched on or off
+func (s *SystemStatus) ReadOnlySince() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readOnlySince
+}
+
+// OnWritable registers fn to run whenever read-only mode is left, e.g. to drain queues
+func (s *SystemStatus) OnWritable(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onWritable = append(s.onWritable, fn)
+}
+
+// OnRecovered registers fn to run whenever the server behind baseURL recovers after
+// being marked down, e.g. to replay writes queued during the outage. fn runs on the
+// goroutine of the call that succeeded, so it should not block.
+func (s *SystemStatus) OnRecovered(baseURL string, fn func()) {
+	host := hostOf(baseURL)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.onRecovered == nil {
+		s.onRecovered = make(map[string][]func())
+	}
+	s.onRecovered[host] = append(s.onRecovered[host], fn)
+}
+
+// Transport wraps next so every PocketBase call is recorded. Transport errors and 5xx
+// responses are failures; any other response means the server is reachable.
+// Calls cancelled by the caller are not counted.
+func (s *SystemStatus) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := next.RoundTrip(req)
+		switch {
+		case err != nil && req.Context().Err() != nil:
+			// the caller gave up; says nothing about the backend
+		case err != nil:
+			s.Record(req.URL.Host, err)
+		case resp.StatusCode >= http.StatusInternalServerError:
+			s.Record(req.URL.Host, fmt.Errorf("HTTP %s", resp.Status))
+		default:
+			s.Record(req.URL.Host, nil)
+		}
+		return resp, err
+	})
+}
+
+// StartProbe checks /api/health of each base URL every interval until ctx is done, so a
+// backend recovers (or goes down) even when nothing else is calling it. rt should be a
+// transport returned by Transport.
+func (s *SystemStatus) StartProbe(ctx context.Context, rt http.RoundTripper, baseURLs []string, interval time.Duration) {
+	client := &http.Client{Timeout: 5 * time.Second, Transport: rt}
+	probe := func() {
+		for _, base := range baseURLs {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(base, "/")+"/api/health", nil)
+			if err != nil {
+				continue
+			}
+			if resp, err := client.Do(req); err == nil {
+				resp.Body.Close()
+			}
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		probe()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				probe()
+			}
+		}
+	}()
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// hostOf extracts host[:port] from a base URL
+func hostOf(baseURL string) string {
+	if u, err := url.Parse(baseURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return baseURL
+}
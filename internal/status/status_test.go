@@ -0,0 +1,167 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"med-pulse-bot/internal/clock"
+)
+
+func TestSystemStatusTransitions(t *testing.T) {
+	errDown := errors.New("connection refused")
+	tests := []struct {
+		name          string
+		outcomes      []error
+		wantHealthy   bool
+		wantReason    string
+		wantRecovered int
+	}{
+		{"no calls", nil, true, "", 0},
+		{"single failure", []error{errDown}, true, "", 0},
+		{"threshold reached", []error{errDown, errDown, errDown}, false, "connection refused", 0},
+		{"success resets count", []error{errDown, errDown, nil, errDown, errDown}, true, "", 0},
+		{"recovers on success", []error{errDown, errDown, errDown, errDown, nil, nil}, true, "", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSystemStatus(3)
+			s.SetClock(clock.NewFake(time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)))
+			recovered := 0
+			s.OnRecovered("http://pb:8090", func() { recovered++ })
+			s.OnRecovered("http://other:8090", func() { t.Error("other backend's callback ran") })
+			for _, err := range tt.outcomes {
+				s.Record("pb:8090", err)
+			}
+
+			b := s.Backend("http://pb:8090")
+			if b.Healthy != tt.wantHealthy || b.Reason != tt.wantReason {
+				t.Errorf("Backend = %+v, want healthy=%v reason=%q", b, tt.wantHealthy, tt.wantReason)
+			}
+			if s.Degraded() == tt.wantHealthy {
+				t.Errorf("Degraded = %v, want %v", s.Degraded(), !tt.wantHealthy)
+			}
+			if recovered != tt.wantRecovered {
+				t.Errorf("OnRecovered ran %d times, want %d", recovered, tt.wantRecovered)
+			}
+		})
+	}
+}
+
+func TestTransport(t *testing.T) {
+	code := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		code        int
+		wantHealthy bool
+	}{
+		{"server errors mark down", http.StatusServiceUnavailable, false},
+		{"client errors mean reachable", http.StatusNotFound, true},
+		{"ok", http.StatusOK, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSystemStatus(2)
+			client := &http.Client{Transport: s.Transport(nil)}
+			code = tt.code
+			for i := 0; i < 2; i++ {
+				resp, err := client.Get(server.URL)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp.Body.Close()
+			}
+			if got := s.Backend(server.URL).Healthy; got != tt.wantHealthy {
+				t.Errorf("Healthy = %v, want %v", got, tt.wantHealthy)
+			}
+		})
+	}
+
+	t.Run("cancelled calls are not counted", func(t *testing.T) {
+		s := NewSystemStatus(1)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		if _, err := (&http.Client{Transport: s.Transport(nil)}).Do(req); err == nil {
+			t.Fatal("expected error for cancelled request")
+		}
+		if s.Degraded() {
+			t.Error("cancelled call marked the backend down")
+		}
+	})
+}
+
+func TestReadOnly(t *testing.T) {
+	s := NewSystemStatus(3)
+	drained := 0
+	s.OnWritable(func() { drained++ })
+
+	steps := []struct {
+		on          bool
+		wantChanged bool
+		wantDrained int
+	}{
+		{on: false, wantChanged: false, wantDrained: 0},
+		{on: true, wantChanged: true, wantDrained: 0},
+		{on: true, wantChanged: false, wantDrained: 0},
+		{on: false, wantChanged: true, wantDrained: 1},
+	}
+	for i, step := range steps {
+		if changed := s.SetReadOnly(step.on); changed != step.wantChanged {
+			t.Errorf("step %d: SetReadOnly(%v) changed = %v, want %v", i, step.on, changed, step.wantChanged)
+		}
+		if s.ReadOnly() != step.on {
+			t.Errorf("step %d: ReadOnly = %v, want %v", i, s.ReadOnly(), step.on)
+		}
+		if drained != step.wantDrained {
+			t.Errorf("step %d: OnWritable ran %d times, want %d", i, drained, step.wantDrained)
+		}
+	}
+}
+
+func TestActivity(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC))
+	s := NewSystemStatus(3)
+	s.SetClock(clk)
+
+	s.ScannerSeen("AA-BB-CC-DD-EE-01")
+	s.ScannerSeen("not a mac")
+	clk.Advance(time.Minute)
+	s.ScannerSeen("aa:bb:cc:dd:ee:01")
+	if got := s.Scanners(); len(got) != 1 || got[0].Mac != "aa:bb:cc:dd:ee:01" || !got[0].LastSeen.Equal(clk.Now()) {
+		t.Errorf("Scanners = %+v, want one normalized scanner seen now", got)
+	}
+
+	s.RecordNotificationFailure(errors.New("chat not found"))
+	clk.Advance(10 * time.Minute)
+	s.RecordNotificationFailure(errors.New("too many requests"))
+
+	tests := []struct {
+		name    string
+		window  time.Duration
+		want    int
+		wantErr string
+	}{
+		{"both failures in window", 15 * time.Minute, 2, "too many requests"},
+		{"only the recent failure", 5 * time.Minute, 1, "too many requests"},
+		{"none after the window", 0, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, lastErr := s.NotificationFailures(tt.window)
+			if n != tt.want || lastErr != tt.wantErr {
+				t.Errorf("NotificationFailures(%s) = %d, %q, want %d, %q", tt.window, n, lastErr, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+// Package tenant maps API keys and chats to tenants so one deployment can serve
+// several sites with isolated data
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultID labels the tenant-less single-site deployment in logs and metrics
+const DefaultID = "default"
+
+// Tenant is one site served by this deployment
+type Tenant struct {
+	ID               string            `yaml:"id"`
+	Name             string            `yaml:"name"`
+	APIKeys          []string          `yaml:"api_keys"`          // X-API-Key values sent by this site's scanners
+	PocketBaseURL    string            `yaml:"pocketbase_url"`    // Empty uses POCKETBASE_URL
+	PocketBaseToken  string            `yaml:"pocketbase_token"`  // Empty uses POCKETBASE_TOKEN
+	CollectionPrefix string            `yaml:"collection_prefix"` // Keeps tenants apart on a shared PocketBase
+	AdminChatID      int64             `yaml:"admin_chat_id"`     // Chat receiving this tenant's admin notifications
+	JoinCode         string            `yaml:"join_code"`         // Employees send /site <code> to bind their chat; empty allows only the admin chat
+	Overrides        map[string]string `yaml:"overrides"`         // Config overrides keyed by environment variable name
+}
+
+// Registry holds the configured tenants. A nil Registry means single-site mode.
+type Registry struct {
+	tenants  []*Tenant
+	byID     map[string]*Tenant
+	byAPIKey map[string]*Tenant
+	byChat   map[int64]*Tenant
+	byCode   map[string]*Tenant
+}
+
+// LoadFile reads a tenants YAML file; ${VAR} references are expanded from the
+// environment so tokens can stay out of the file. An empty path returns nil.
+func LoadFile(path, defaultURL, defaultToken string) (*Registry, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenants file: %w", err)
+	}
+
+	var file struct {
+		Tenants []*Tenant `yaml:"tenants"`
+	}
+	if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(data))), &file); err != nil {
+		return nil, fmt.Errorf("failed to parse tenants file %s: %w", path, err)
+	}
+	for _, t := range file.Tenants {
+		if t.PocketBaseURL == "" {
+			t.PocketBaseURL = defaultURL
+		}
+		if t.PocketBaseToken == "" {
+			t.PocketBaseToken = defaultToken
+		}
+	}
+	return NewRegistry(file.Tenants)
+}
+
+// NewRegistry validates tenants and indexes them. Two tenants may not share an ID,
+// an API key, an admin chat, a join code, or the same PocketBase URL and collection prefix.
+func NewRegistry(tenants []*Tenant) (*Registry, error) {
+	if len(tenants) == 0 {
+		return nil, fmt.Errorf("no tenants configured")
+	}
+
+	r := &Registry{
+		byID:     make(map[string]*Tenant),
+		byAPIKey: make(map[string]*Tenant),
+		byChat:   make(map[int64]*Tenant),
+		byCode:   make(map[string]*Tenant),
+	}
+	storage := make(map[string]string)
+	for _, t := range tenants {
+		if t.ID == "" || t.ID == DefaultID {
+			return nil, fmt.Errorf("invalid tenant id %q", t.ID)
+		}
+		if _, ok := r.byID[t.ID]; ok {
+			return nil, fmt.Errorf("duplicate tenant id %q", t.ID)
+		}
+		if len(t.APIKeys) == 0 {
+			return nil, fmt.Errorf("tenant %q has no api_keys", t.ID)
+		}
+		for _, key := range t.APIKeys {
+			if other, ok := r.byAPIKey[key]; ok {
+				return nil, fmt.Errorf("tenant %q reuses an API key of tenant %q", t.ID, other.ID)
+			}
+			r.byAPIKey[key] = t
+		}
+		if t.AdminChatID != 0 {
+			if other, ok := r.byChat[t.AdminChatID]; ok {
+				return nil, fmt.Errorf("tenant %q shares admin chat %d with tenant %q", t.ID, t.AdminChatID, other.ID)
+			}
+			r.byChat[t.AdminChatID] = t
+		}
+		if t.JoinCode != "" {
+			if other, ok := r.byCode[t.JoinCode]; ok {
+				return nil, fmt.Errorf("tenant %q reuses the join code of tenant %q", t.ID, other.ID)
+			}
+			r.byCode[t.JoinCode] = t
+		}
+		where := strings.TrimRight(t.PocketBaseURL, "/") + "|" + t.CollectionPrefix
+		if other, ok := storage[where]; ok {
+			return nil, fmt.Errorf("tenant %q shares PocketBase and collection prefix with tenant %q", t.ID, other)
+		}
+		storage[where] = t.ID
+
+		r.byID[t.ID] = t
+		r.tenants = append(r.tenants, t)
+	}
+	return r, nil
+}
+
+// All returns the tenants in file order
+func (r *Registry) All() []*Tenant {
+	if r == nil {
+		return nil
+	}
+	return r.tenants
+}
+
+// Get returns the tenant with the given ID
+func (r *Registry) Get(id string) (*Tenant, bool) {
+	if r == nil {
+		return nil, false
+	}
+	t, ok := r.byID[id]
+	return t, ok
+}
+
+// ByAPIKey returns the tenant owning an API key
+func (r *Registry) ByAPIKey(key string) (*Tenant, bool) {
+	if r == nil || key == "" {
+		return nil, false
+	}
+	t, ok := r.byAPIKey[key]
+	return t, ok
+}
+
+// ByAdminChat returns the tenant whose admin chat is chatID
+func (r *Registry) ByAdminChat(chatID int64) (*Tenant, bool) {
+	if r == nil {
+		return nil, false
+	}
+	t, ok := r.byChat[chatID]
+	return t, ok
+}
+
+// ByJoinCode returns the tenant an employee chat may bind to with code
+func (r *Registry) ByJoinCode(code string) (*Tenant, bool) {
+	if r == nil || code == "" {
+		return nil, false
+	}
+	t, ok := r.byCode[code]
+	return t, ok
+}
+
+type contextKey struct{}
+
+// WithTenant returns a context carrying the resolved tenant
+func WithTenant(ctx context.Context, t *Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the tenant resolved for a request, or nil in single-site mode
+func FromContext(ctx context.Context) *Tenant {
+	t, _ := ctx.Value(contextKey{}).(*Tenant)
+	return t
+}
+
+// IDFromContext returns the tenant ID for labelling, DefaultID in single-site mode
+func IDFromContext(ctx context.Context) string {
+	if t := FromContext(ctx); t != nil {
+		return t.ID
+	}
+	return DefaultID
+}
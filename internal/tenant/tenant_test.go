@@ -0,0 +1,162 @@
+package tenant
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewRegistryValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		tenants []*Tenant
+		wantErr string
+	}{
+		{
+			name: "separate prefixes on one PocketBase",
+			tenants: []*Tenant{
+				{ID: "a", APIKeys: []string{"ka"}, PocketBaseURL: "http://pb", CollectionPrefix: "a_"},
+				{ID: "b", APIKeys: []string{"kb"}, PocketBaseURL: "http://pb", CollectionPrefix: "b_"},
+			},
+		},
+		{
+			name: "separate PocketBase servers",
+			tenants: []*Tenant{
+				{ID: "a", APIKeys: []string{"ka"}, PocketBaseURL: "http://pb-a"},
+				{ID: "b", APIKeys: []string{"kb"}, PocketBaseURL: "http://pb-b"},
+			},
+		},
+		{
+			name:    "no tenants",
+			wantErr: "no tenants",
+		},
+		{
+			name:    "reserved id",
+			tenants: []*Tenant{{ID: DefaultID, APIKeys: []string{"k"}}},
+			wantErr: "invalid tenant id",
+		},
+		{
+			name:    "missing api keys",
+			tenants: []*Tenant{{ID: "a"}},
+			wantErr: "no api_keys",
+		},
+		{
+			name: "duplicate id",
+			tenants: []*Tenant{
+				{ID: "a", APIKeys: []string{"k1"}, CollectionPrefix: "x_"},
+				{ID: "a", APIKeys: []string{"k2"}, CollectionPrefix: "y_"},
+			},
+			wantErr: "duplicate tenant id",
+		},
+		{
+			name: "shared api key",
+			tenants: []*Tenant{
+				{ID: "a", APIKeys: []string{"k"}, CollectionPrefix: "a_"},
+				{ID: "b", APIKeys: []string{"k"}, CollectionPrefix: "b_"},
+			},
+			wantErr: "reuses an API key",
+		},
+		{
+			name: "shared admin chat",
+			tenants: []*Tenant{
+				{ID: "a", APIKeys: []string{"ka"}, CollectionPrefix: "a_", AdminChatID: 7},
+				{ID: "b", APIKeys: []string{"kb"}, CollectionPrefix: "b_", AdminChatID: 7},
+			},
+			wantErr: "shares admin chat",
+		},
+		{
+			name: "shared join code",
+			tenants: []*Tenant{
+				{ID: "a", APIKeys: []string{"ka"}, CollectionPrefix: "a_", JoinCode: "welcome"},
+				{ID: "b", APIKeys: []string{"kb"}, CollectionPrefix: "b_", JoinCode: "welcome"},
+			},
+			wantErr: "reuses the join code",
+		},
+		{
+			name: "shared storage",
+			tenants: []*Tenant{
+				{ID: "a", APIKeys: []string{"ka"}, PocketBaseURL: "http://pb"},
+				{ID: "b", APIKeys: []string{"kb"}, PocketBaseURL: "http://pb/"},
+			},
+			wantErr: "shares PocketBase",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRegistry(tt.tenants)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	t.Setenv("CLINIC_A_TOKEN", "secret-a")
+	path := filepath.Join(t.TempDir(), "tenants.yaml")
+	data := `tenants:
+  - id: clinic-a
+    api_keys: [key-a]
+    pocketbase_token: ${CLINIC_A_TOKEN}
+    collection_prefix: a_
+    admin_chat_id: -100
+    join_code: a-staff
+    overrides:
+      END_OF_DAY_TIME: "22:00"
+  - id: clinic-b
+    api_keys: [key-b]
+    pocketbase_url: http://pb-b
+`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	reg, err := LoadFile(path, "http://pb", "root")
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+
+	a, ok := reg.ByAPIKey("key-a")
+	if !ok || a.ID != "clinic-a" {
+		t.Fatalf("ByAPIKey(key-a) = %v, %v", a, ok)
+	}
+	if a.PocketBaseURL != "http://pb" || a.PocketBaseToken != "secret-a" || a.Overrides["END_OF_DAY_TIME"] != "22:00" {
+		t.Errorf("clinic-a = %+v", a)
+	}
+	if b, _ := reg.Get("clinic-b"); b.PocketBaseToken != "root" || b.PocketBaseURL != "http://pb-b" {
+		t.Errorf("clinic-b = %+v", b)
+	}
+	if got, ok := reg.ByAdminChat(-100); !ok || got != a {
+		t.Errorf("ByAdminChat(-100) = %v, %v", got, ok)
+	}
+	if got, ok := reg.ByJoinCode("a-staff"); !ok || got != a {
+		t.Errorf("ByJoinCode(a-staff) = %v, %v", got, ok)
+	}
+	if _, ok := reg.ByAPIKey("unknown"); ok {
+		t.Error("unknown API key resolved to a tenant")
+	}
+
+	if reg, err := LoadFile("", "http://pb", "root"); reg != nil || err != nil {
+		t.Errorf("LoadFile(\"\") = %v, %v, want single-site mode", reg, err)
+	}
+}
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	if got := IDFromContext(ctx); got != DefaultID {
+		t.Errorf("IDFromContext without tenant = %q, want %q", got, DefaultID)
+	}
+	ctx = WithTenant(ctx, &Tenant{ID: "clinic-a"})
+	if got := IDFromContext(ctx); got != "clinic-a" {
+		t.Errorf("IDFromContext = %q, want clinic-a", got)
+	}
+}
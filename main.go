@@ -2,18 +2,34 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 
 	"med-pulse-bot/bot"
 	"med-pulse-bot/config"
+	"med-pulse-bot/internal/alerts"
+	"med-pulse-bot/internal/faults"
 	"med-pulse-bot/internal/handlers"
+	"med-pulse-bot/internal/leader"
+	"med-pulse-bot/internal/lifecycle"
+	"med-pulse-bot/internal/localstore"
+	"med-pulse-bot/internal/logging"
+	"med-pulse-bot/internal/metrics"
+	"med-pulse-bot/internal/models"
+	"med-pulse-bot/internal/pbclient"
 	"med-pulse-bot/internal/repository"
 	"med-pulse-bot/internal/services"
+	"med-pulse-bot/internal/status"
+	"med-pulse-bot/internal/tenant"
 )
 
 func main() {
@@ -22,36 +38,230 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	if err := logging.Setup(os.Stderr, cfg.LogLevel, cfg.LogFormat); err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	pbclient.SetLogBodies(cfg.LogHTTPBodies)
+	pbclient.SetTimeout(cfg.PocketBaseTimeout)
 	log.Println("Config loaded successfully")
 
+	// Attendance days (created_date), late checks and the bot's dates follow TIMEZONE, not
+	// the container's zone
+	loc, err := cfg.Location()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	repository.SetLocation(loc)
+	bot.SetLocation(loc)
+
+	// CLI subcommands (e.g. `app doctor`) run instead of the server
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(cfg, os.Args[1], os.Args[2:]))
+	}
+
 	// Create application context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Setup signal handling for graceful shutdown
+	// Fault injection is only ever wired in when explicitly enabled
+	var injector *faults.Injector
+	pbTransport := http.DefaultTransport
+	if cfg.EnableFaultInjection {
+		injector = faults.NewInjector()
+		pbTransport = faults.NewTransport(http.DefaultTransport, injector, faults.ComponentPocketBase)
+		log.Println("⚠️  FAULT INJECTION ENABLED - /debug/faults is exposed, never use this in production")
+	}
+
+	// Every PocketBase call feeds the shared system status behind /readyz and the bot banner
+	systemStatus := status.NewSystemStatus(status.DefaultFailureThreshold)
+	pbTransport = systemStatus.Transport(pbTransport)
+	repository.SetTransport(pbTransport)
+	pbAuth := initPocketBaseAuth(cfg)
+	if cfg.ReadOnly {
+		systemStatus.SetReadOnly(true)
+	}
+
+	// Read the live schema once so repositories only write fields that exist
+	usesPocketBase := cfg.StorageBackend == config.StoragePocketBase
+	schemaCaps := repository.NewSchemaCapabilities(cfg.PocketBaseURL, cfg.PocketBaseToken)
+	if usesPocketBase {
+		if err := schemaCaps.Refresh(ctx); err != nil {
+			log.Printf("Warning: schema capability check failed, assuming latest schema: %v", err)
+		}
+	}
+	repository.SetSchemaCapabilities(schemaCaps)
+
+	// Setup signal handling: SIGHUP refreshes the schema, SIGINT/SIGTERM shut down
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 	go func() {
-		<-sigChan
-		log.Println("Shutdown signal received, initiating graceful shutdown...")
-		cancel()
+		for sig := range sigChan {
+			if sig == syscall.SIGHUP {
+				if !usesPocketBase {
+					continue
+				}
+				log.Println("SIGHUP received, refreshing schema capabilities...")
+				if err := schemaCaps.Refresh(ctx); err != nil {
+					log.Printf("Warning: schema refresh failed: %v", err)
+				}
+				continue
+			}
+			log.Println("Shutdown signal received, initiating graceful shutdown...")
+			cancel()
+			return
+		}
 	}()
 
+	// Tenants are optional; without TENANTS_FILE this is a single-site deployment
+	tenants, err := tenant.LoadFile(cfg.TenantsFile, cfg.PocketBaseURL, cfg.PocketBaseToken)
+	if err != nil {
+		log.Fatalf("Failed to load tenants: %v", err)
+	}
+
 	// Initialize application dependencies
-	handler := initApplication(cfg)
+	// With several instances on one PocketBase only the lease holder runs singletons
+	elector, err := initElector(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize leader election: %v", err)
+	}
+
+	// Without tenants the records live in the default PocketBase, or in SQLite on a single board
+	store := pocketBaseStorage(repository.DefaultSite(cfg.PocketBaseURL))
+	if !usesPocketBase {
+		db, err := openSQLite(ctx, cfg)
+		if err != nil {
+			log.Fatalf("Failed to initialize storage: %v", err)
+		}
+		defer db.Close()
+		store = sqliteStorage(db)
+	}
+
+	if cfg.Headless() {
+		log.Println("🔇 Headless mode: no Telegram bot, detections are recorded without Telegram notifications")
+	} else {
+		log.Println("🤖 Telegram mode: the bot answers commands and sends notifications")
+	}
+	// One webhook channel is shared by every site, so a single queue is flushed on shutdown
+	var webhook *services.WebhookNotifier
+	if channels, _ := cfg.NotifierChannels(); slices.Contains(channels, config.NotifierWebhook) {
+		log.Printf("🪝 Notifications are also POSTed to the webhook at %s", cfg.NotifyWebhookURL)
+		webhook = services.NewWebhookNotifier(cfg.NotifyWebhookURL)
+		webhook.SetDeadLetters(deadLetterLog(cfg), systemStatus)
+	}
+	application, err := initApplication(ctx, cfg, tenants, store, webhook, injector, systemStatus, elector)
+	if err != nil {
+		log.Fatalf("Failed to initialize application: %v", err)
+	}
+
+	// Long-running components start in dependency order and stop in reverse
+	components := lifecycle.NewManager()
+	// Notifications are queued and retried in the background; the outbox starts first and
+	// stops last, so what the rest send while stopping is still delivered
+	if !cfg.Headless() {
+		components.Register(lifecycle.Component{
+			Name: "telegram_outbox",
+			Start: func(context.Context) error {
+				bot.StartOutbox()
+				return nil
+			},
+			Stop:        bot.FlushOutbox,
+			StopTimeout: outboxFlushTimeout,
+		})
+	}
+	if webhook != nil {
+		components.Register(lifecycle.Component{
+			Name: "webhook_outbox",
+			Start: func(context.Context) error {
+				webhook.Start()
+				return nil
+			},
+			Stop:        webhook.Flush,
+			StopTimeout: outboxFlushTimeout,
+		})
+	}
+	registerComponents(components, application, elector)
+
+	// Probe every backend so recovery is noticed even when nothing else calls it
+	probeURLs := backendURLs(cfg, tenants)
+	components.Register(lifecycle.Background("pocketbase_probe", nil, func(ctx context.Context) {
+		systemStatus.StartProbe(ctx, pbTransport, probeURLs, status.DefaultProbeInterval)
+	}))
+
+	// Initialize Telegram Bot; polling stops before the schedulers its callbacks use.
+	// Headless sites were given no-op notifiers and have no bot to poll.
+	if !cfg.Headless() {
+		if err := initBot(cfg, tenants, pbTransport, pbAuth, systemStatus); err != nil {
+			log.Printf("Warning: Failed to init Telegram Bot: %v", err)
+		} else {
+			components.Register(lifecycle.Background("telegram_bot", application.schedulerNames(), func(ctx context.Context) {
+				elector.Run(ctx, "telegram_polling", bot.StartPolling)
+			}))
+		}
+	}
 
-	// Initialize Telegram Bot
-	if err := initBot(cfg); err != nil {
-		log.Printf("Warning: Failed to init Telegram Bot: %v", err)
+	// Built-in alerts are served at /api/alerts and sent to the admin chat as they change
+	alertEvaluator := initAlerts(cfg, application, systemStatus)
+	if cfg.AlertInterval > 0 {
+		components.Register(lifecycle.Background("alerts", []string{"leader_election"}, func(ctx context.Context) {
+			elector.Run(ctx, "alert_notifications", func(ctx context.Context) {
+				alertEvaluator.Start(ctx, cfg.AlertInterval, notifierFor(cfg, nil, webhook))
+			})
+		}))
 	}
 
 	// Setup HTTP server
+	healthHandler := handlers.NewHealthHandler(injector)
+	healthHandler.SetSystemStatus(systemStatus)
+	healthHandler.SetLifecycle(components)
+	if elector != nil {
+		healthHandler.SetElector(elector)
+	}
+	healthHandler.SetPocketBaseProbe(probeURLs, pbTransport)
+	if !cfg.Headless() {
+		healthHandler.SetTelegram(bot.Initialized)
+	}
+
 	mux := http.NewServeMux()
-	mux.HandleFunc("/api/detect", handler.HandleDetect)
+	mux.HandleFunc("/api/detect", application.detect)
+	mux.HandleFunc("/api/v2/detect", application.detectV2)
+	mux.HandleFunc("/api/heartbeat", application.heartbeat)
+	mux.HandleFunc("/api/scanner/heartbeat", application.heartbeat)
+	mux.HandleFunc("/api/scanner/config", application.scannerConfig)
+	mux.Handle("/metrics", metrics.Handler())
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
+	mux.HandleFunc("/healthz", healthHandler.HandleLive)
+	mux.HandleFunc("/readyz", healthHandler.HandleReady)
+	mux.HandleFunc("/api/alerts", handlers.NewAlertsHandler(alertEvaluator).HandleAlerts)
+	for _, s := range application.sites {
+		if s.cfg.PublicBoardCIDRs == "" {
+			continue
+		}
+		boardHandler, err := initBoard(s)
+		if err != nil {
+			log.Fatalf("Failed to initialize public board: %v", err)
+		}
+		mux.HandleFunc(s.boardPath(), boardHandler.HandleBoard)
+		log.Printf("Public board %s enabled for %s", s.boardPath(), s.cfg.PublicBoardCIDRs)
+	}
+	dashboard := handlers.NewDashboardHandler(cfg.AllowedOrigins, loc)
+	for _, s := range application.sites {
+		dashboard.AddSite(s.cfg.DashboardAPIToken, s.store.employees, s.store.attendance)
+	}
+	if dashboard.Enabled() {
+		mux.HandleFunc("/api/attendance", dashboard.HandleAttendance)
+		mux.HandleFunc("/api/employees", dashboard.HandleEmployees)
+		log.Printf("Dashboard API enabled for origins %q", cfg.AllowedOrigins)
+	}
+	switch {
+	case injector == nil:
+	case cfg.FaultInjectionToken == "":
+		log.Println("⚠️  ENABLE_FAULT_INJECTION is set but FAULT_INJECTION_TOKEN is not; /debug/faults is not exposed")
+	default:
+		mux.HandleFunc("/debug/faults", handlers.NewFaultHandler(injector, cfg.FaultInjectionToken).HandleFaults)
+	}
 
 	server := &http.Server{
 		Addr:         ":8080",
@@ -61,53 +271,377 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
-	// Start server in a goroutine
-	go func() {
-		log.Println("Server starting on :8080")
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed: %v", err)
-		}
-	}()
+	// The server starts last and stops first, so no detection arrives while the rest shut down
+	var serverDeps []string
+	for _, st := range components.Status() {
+		serverDeps = append(serverDeps, st.Name)
+	}
+	components.Register(lifecycle.Component{
+		Name:      "http_server",
+		DependsOn: serverDeps,
+		Start: func(context.Context) error {
+			ln, err := net.Listen("tcp", server.Addr)
+			if err != nil {
+				return err
+			}
+			log.Println("Server starting on :8080")
+			go func() {
+				if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
+					log.Fatalf("Server failed: %v", err)
+				}
+			}()
+			return nil
+		},
+		Stop: server.Shutdown,
+	})
+
+	if err := components.Start(ctx); err != nil {
+		log.Fatalf("Failed to start: %v", err)
+	}
 
 	// Wait for shutdown signal
 	<-ctx.Done()
 
-	// Graceful shutdown
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer shutdownCancel()
-
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		log.Printf("Server shutdown error: %v", err)
+	if err := components.Stop(context.Background()); err != nil {
+		log.Printf("Shutdown error: %v", err)
 	}
 
 	log.Println("Server stopped gracefully")
 }
 
+// registerComponents registers the leader election and the background work and saved
+// state of every site
+func registerComponents(components *lifecycle.Manager, application *app, elector *leader.Elector) {
+	// Hand the lease over on shutdown so a standby takes over without waiting for it to expire
+	var stopElection context.CancelFunc
+	components.Register(lifecycle.Component{
+		Name: "leader_election",
+		Start: func(ctx context.Context) error {
+			ctx, stopElection = context.WithCancel(context.WithoutCancel(ctx))
+			if elector != nil {
+				elector.Start(ctx)
+			}
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			stopElection()
+			elector.Resign(ctx)
+			return nil
+		},
+	})
+
+	for _, s := range application.sites {
+		components.Register(lifecycle.Background("detection_queue["+s.tenantID+"]", []string{"leader_election"}, func(ctx context.Context) {
+			elector.Run(ctx, "detection_queue_drain["+s.tenantID+"]", s.drain)
+		}))
+		components.Register(lifecycle.Background("schedulers["+s.tenantID+"]", []string{"leader_election"}, func(ctx context.Context) {
+			for _, j := range s.jobs {
+				elector.Run(ctx, j.name, j.start)
+			}
+		}))
+
+		// Keep half-accumulated smoothing windows across a restart, saved once detections stop
+		if s.smoothing != nil {
+			components.Register(lifecycle.Component{
+				Name: "smoothing[" + s.tenantID + "]",
+				Stop: func(ctx context.Context) error {
+					return s.smoothing.SaveSnapshot(ctx, s.local, time.Now())
+				},
+			})
+		}
+
+		// Detections already answered with 202 are processed before the rest shuts down,
+		// and before the smoothing window they feed is saved
+		if s.workers != nil {
+			var deps []string
+			if s.smoothing != nil {
+				deps = append(deps, "smoothing["+s.tenantID+"]")
+			}
+			components.Register(lifecycle.Component{
+				Name:        "detection_workers[" + s.tenantID + "]",
+				DependsOn:   deps,
+				Start:       s.workers.Start,
+				Stop:        s.workers.Stop,
+				StopTimeout: time.Minute,
+			})
+		}
+	}
+}
+
+// schedulerNames lists the scheduler components of every site
+func (a *app) schedulerNames() []string {
+	var names []string
+	for _, s := range a.sites {
+		names = append(names, "schedulers["+s.tenantID+"]")
+	}
+	return names
+}
+
+// initAlerts builds the built-in alert rules over the shared system status and each
+// site's detection queue
+func initAlerts(cfg *config.Config, application *app, systemStatus *status.SystemStatus) *alerts.Evaluator {
+	queues := make(map[string]alerts.QueueLen, len(application.sites))
+	for _, s := range application.sites {
+		queues[s.tenantID] = s.queue
+	}
+	return alerts.NewEvaluator(
+		alerts.PocketBaseDownRule(systemStatus),
+		alerts.ScannerOfflineRule(systemStatus, cfg.ScannerOfflineAfter),
+		alerts.DetectionQueueDepthRule(queues, cfg.AlertQueueDepth),
+		alerts.NotificationFailuresRule(systemStatus, cfg.AlertNotificationFailures, cfg.AlertNotificationWindow),
+	)
+}
+
+// initPocketBaseAuth creates the token manager of the default PocketBase; repositories
+// created afterwards use it. With admin credentials it re-authenticates when the token expires.
+func initPocketBaseAuth(cfg *config.Config) *repository.AuthManager {
+	auth := repository.NewAuthManager(cfg.PocketBaseURL, cfg.PocketBaseToken, cfg.PocketBaseAdminEmail, cfg.PocketBaseAdminPassword)
+	if cfg.PocketBaseAdminEmail != "" && cfg.PocketBaseAdminPassword != "" {
+		log.Printf("🔑 PocketBase token is refreshed automatically for %s", cfg.PocketBaseAdminEmail)
+	}
+	repository.SetDefaultAuth(auth)
+	return auth
+}
+
+// initElector creates the leader elector when LEADER_ELECTION is set; nil runs every
+// singleton on this instance
+func initElector(cfg *config.Config) (*leader.Elector, error) {
+	if !cfg.LeaderElection {
+		return nil, nil
+	}
+	instanceID := cfg.InstanceID
+	if instanceID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("INSTANCE_ID is not set and the hostname is unknown: %w", err)
+		}
+		instanceID = hostname
+	}
+	log.Printf("👑 Leader election enabled as instance %s (lease TTL %s)", instanceID, cfg.LeaderLeaseTTL)
+	return leader.NewElector(repository.DefaultSite(cfg.PocketBaseURL).Leases(), instanceID, cfg.LeaderLeaseTTL)
+}
+
+// outboxFlushTimeout is how long shutdown waits for queued notifications
+const outboxFlushTimeout = 10 * time.Second
+
 // initBot initializes the Telegram bot
-func initBot(cfg *config.Config) error {
-	if err := bot.Init(cfg.TelegramBotToken, cfg.AuthorizedChatID); err != nil {
+func initBot(cfg *config.Config, tenants *tenant.Registry, pbTransport http.RoundTripper, pbAuth *repository.AuthManager, systemStatus *status.SystemStatus) error {
+	adminChats, err := cfg.AdminChatIDs()
+	if err != nil {
+		return err
+	}
+	if err := bot.Init(cfg.TelegramBotToken, adminChats); err != nil {
 		return err
 	}
+	if err := bot.SetDefaultLanguage(cfg.AdminLanguage); err != nil {
+		return fmt.Errorf("invalid ADMIN_LANGUAGE: %w", err)
+	}
+	if tenants != nil {
+		if err := bot.SetTenants(tenants, filepath.Join(cfg.DataDir, "chat_tenants.json")); err != nil {
+			return err
+		}
+	}
 
-	// Set PocketBase URL and token for bot
-	bot.SetPocketBaseURL(cfg.PocketBaseURL)
-	bot.SetPocketBaseToken(cfg.PocketBaseToken)
-	bot.StartPolling()
+	// Set PocketBase URL and auth for bot
+	bot.SetSystemStatus(systemStatus)
+	bot.SetScannerOfflineAfter(cfg.ScannerOfflineAfter)
+	bot.SetDeadLetterLog(deadLetterLog(cfg))
 
 	log.Println("Telegram Bot Initialized")
 	return nil
 }
 
-// initApplication initializes all application dependencies
-func initApplication(cfg *config.Config) *handlers.DetectionHandler {
-	// Initialize repositories with PocketBase REST API
-	employeeRepo := repository.NewPocketBaseRESTEmployeeRepository(cfg.PocketBaseURL)
-	attendanceRepo := repository.NewPocketBaseRESTAttendanceRepository(cfg.PocketBaseURL)
-	detectionRepo := repository.NewPocketBaseRESTDetectionRepository(cfg.PocketBaseURL)
-	scannerRepo := repository.NewPocketBaseRESTScannerRepository(cfg.PocketBaseURL)
+// backendURLs lists the distinct PocketBase servers in use; none with SQLite storage
+func backendURLs(cfg *config.Config, tenants *tenant.Registry) []string {
+	if cfg.StorageBackend == config.StorageSQLite {
+		return nil
+	}
+	urls := []string{cfg.PocketBaseURL}
+	seen := map[string]bool{cfg.PocketBaseURL: true}
+	for _, t := range tenants.All() {
+		if !seen[t.PocketBaseURL] {
+			seen[t.PocketBaseURL] = true
+			urls = append(urls, t.PocketBaseURL)
+		}
+	}
+	return urls
+}
+
+// initBoard creates the public status board handler of a site
+func initBoard(s *siteApp) (*handlers.BoardHandler, error) {
+	boardService := services.NewBoardService(s.store.employees, s.store.attendance)
+	boardService.SetDataVersions(s.versions)
+	return handlers.NewBoardHandler(boardService, s.cfg.PublicBoardCIDRs, s.cfg.PublicBoardRateLimit)
+}
+
+// app holds the components main needs after initialization
+type app struct {
+	detect        http.HandlerFunc
+	detectV2      http.HandlerFunc
+	heartbeat     http.HandlerFunc
+	scannerConfig http.HandlerFunc
+	sites         []*siteApp // one per tenant, or the single default site
+}
+
+// siteApp is the service stack of one tenant
+type siteApp struct {
+	tenantID  string
+	cfg       *config.Config
+	store     *storage
+	detection *handlers.DetectionHandler
+	workers   *services.DetectionWorkers // nil unless DETECT_WORKERS is set
+	heartbeat *handlers.HeartbeatHandler
+	scanners  *handlers.ScannerConfigHandler
+	smoothing *services.DetectionWindow // nil unless smoothing is enabled
+	versions  *repository.DataVersions
+	queue     *services.DetectionQueue
+	local     localstore.LocalStore
+	drain     func(ctx context.Context) // drains queued detections once the site is writable
+	jobs      []job                     // scheduled singletons, started through the elector
+}
+
+// job is a singleton background task of a site
+type job struct {
+	name  string
+	start func(ctx context.Context)
+}
+
+// boardPath is where the site's public board is served
+func (s *siteApp) boardPath() string {
+	if s.tenantID == tenant.DefaultID {
+		return "/public/board"
+	}
+	return "/public/board/" + s.tenantID
+}
+
+// tenantConfig applies a tenant's overrides; its local state lives under DATA_DIR/tenants/<id>
+func tenantConfig(cfg *config.Config, t *tenant.Tenant) *config.Config {
+	tcfg := cfg.WithOverrides(t.Overrides)
+	if _, ok := t.Overrides["DATA_DIR"]; !ok {
+		tcfg.DataDir = filepath.Join(cfg.DataDir, "tenants", t.ID)
+	}
+	tcfg.PocketBaseURL = t.PocketBaseURL
+	tcfg.PocketBaseToken = t.PocketBaseToken
+	return tcfg
+}
+
+// newLocalStore opens the backend chosen by LOCAL_STORE for the site's detection queue
+// and smoothing snapshot
+func newLocalStore(cfg *config.Config, site repository.Site) (localstore.LocalStore, error) {
+	switch cfg.LocalStore {
+	case "file":
+		return localstore.NewFileStore(cfg.DataDir), nil
+	case "memory":
+		log.Println("⚠️  LOCAL_STORE=memory: queued detections and the smoothing window are lost on restart")
+		return localstore.NewMemoryStore(), nil
+	case "pocketbase":
+		return site.LocalStore(), nil
+	default:
+		return nil, fmt.Errorf("invalid LOCAL_STORE %q: want file, memory or pocketbase", cfg.LocalStore)
+	}
+}
+
+// initApplication initializes all application dependencies and starts background jobs.
+// Each tenant gets its own repositories, notifier and jobs so no query can cross tenants;
+// without tenants the single site keeps its records in store. Every site shares webhook, the
+// webhook channel (nil when inactive).
+func initApplication(ctx context.Context, cfg *config.Config, tenants *tenant.Registry, store *storage, webhook *services.WebhookNotifier, injector *faults.Injector, systemStatus *status.SystemStatus, elector *leader.Elector) (*app, error) {
+	if tenants == nil {
+		s, err := initSite(ctx, tenant.DefaultID, cfg, store, notifierFor(cfg, nil, webhook), injector, systemStatus, elector)
+		if err != nil {
+			return nil, err
+		}
+		return &app{
+			detect:        s.detection.HandleDetect,
+			detectV2:      s.detection.HandleDetectV2,
+			heartbeat:     s.heartbeat.HandleHeartbeat,
+			scannerConfig: s.scanners.HandleScannerConfig,
+			sites:         []*siteApp{s},
+		}, nil
+	}
+
+	application := &app{}
+	detectionHandlers := make(map[string]*handlers.DetectionHandler)
+	heartbeatHandlers := make(map[string]*handlers.HeartbeatHandler)
+	configHandlers := make(map[string]*handlers.ScannerConfigHandler)
+	for _, t := range tenants.All() {
+		site := repository.Site{URL: t.PocketBaseURL, Token: t.PocketBaseToken, Prefix: t.CollectionPrefix}
+		s, err := initSite(ctx, t.ID, tenantConfig(cfg, t), pocketBaseStorage(site), notifierFor(cfg, t, webhook), injector, systemStatus, elector)
+		if err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", t.ID, err)
+		}
+		detectionHandlers[t.ID] = s.detection
+		heartbeatHandlers[t.ID] = s.heartbeat
+		configHandlers[t.ID] = s.scanners
+		application.sites = append(application.sites, s)
+		log.Printf("🏥 Tenant %s ready (%s, prefix %q)", t.ID, t.PocketBaseURL, t.CollectionPrefix)
+	}
+	router := handlers.NewTenantRouter(tenants, detectionHandlers)
+	router.SetHeartbeatHandlers(heartbeatHandlers)
+	router.SetScannerConfigHandlers(configHandlers)
+	application.detect = router.HandleDetect
+	application.detectV2 = router.HandleDetectV2
+	application.heartbeat = router.HandleHeartbeat
+	application.scannerConfig = router.HandleScannerConfig
+	return application, nil
+}
+
+// notifierFor is where a site's notifications go: the channels of NOTIFIERS, i.e. the
+// Telegram bot (to the admin chat of the tenant t when there is one, never in headless
+// mode) and webhook, nil when the webhook channel is not active, or nowhere without any
+func notifierFor(cfg *config.Config, t *tenant.Tenant, webhook *services.WebhookNotifier) services.BotNotifier {
+	names, _ := cfg.NotifierChannels() // validated by LoadConfig
+	var channels []services.Notifier
+	var single services.BotNotifier
+	for _, name := range names {
+		switch {
+		case name == config.NotifierTelegram && cfg.Headless():
+			continue
+		case name == config.NotifierTelegram && t != nil:
+			single = bot.NewTenantNotifier(t.AdminChatID)
+			channels = append(channels, services.BotChannel{Bot: single})
+		case name == config.NotifierTelegram:
+			single = bot.NewNotifier()
+			channels = append(channels, services.BotChannel{Bot: single})
+		case name == config.NotifierWebhook && webhook != nil:
+			single = webhook
+			channels = append(channels, webhook)
+		}
+	}
+	switch len(channels) {
+	case 0:
+		return services.NopNotifier{}
+	case 1:
+		return single
+	}
+	return services.NewMultiNotifier(channels...)
+}
+
+// initSite builds the detection service stack and end-of-day jobs of one site
+func initSite(ctx context.Context, tenantID string, cfg *config.Config, store *storage, notifier services.BotNotifier, injector *faults.Injector, systemStatus *status.SystemStatus, elector *leader.Elector) (*siteApp, error) {
+	var employeeRepo repository.CachableEmployees = store.employees
+	var deviceRepo repository.DeviceRepository = store.devices
+	if cfg.EmployeeCacheTTL > 0 {
+		cache := repository.NewCachedEmployeeRepository(employeeRepo, cfg.EmployeeCacheTTL, cfg.EmployeeCacheMissTTL)
+		cache.SetDevices(deviceRepo)
+		bot.SetEmployeeCache(tenantID, cache)
+		employeeRepo, deviceRepo = cache, cache
+	}
+	// Calls failing on a PocketBase restart or a network blip are retried before giving up
+	retry := repository.DefaultRetryPolicy()
+	retry.Attempts = cfg.PocketBaseRetryAttempts
+
+	// Attendance writes bump the employee's data version, so caches never serve data from before them
+	versions := repository.NewDataVersions()
+	attendanceRepo := repository.NewVersionedAttendanceRepository(repository.NewRetryingAttendanceRepository(store.attendance, retry), versions)
+	detectionRepo := repository.NewRetryingDetectionRepository(store.detections, retry)
+	scannerRepo := store.scanners
 
 	// Create bot notifier wrapper
-	botNotifier := bot.NewNotifier()
+	botNotifier := notifier
+	if injector != nil {
+		botNotifier = faults.NewNotifier(botNotifier, injector)
+	}
 
 	// Initialize services
 	attendanceService := services.NewAttendanceService(
@@ -117,9 +651,398 @@ func initApplication(cfg *config.Config) *handlers.DetectionHandler {
 		scannerRepo,
 		botNotifier,
 	)
+	attendanceService.SetTenant(tenantID)
+	attendanceService.SetDevices(deviceRepo)
+	loc, err := cfg.Location()
+	if err != nil {
+		return nil, err
+	}
+	attendanceService.SetLocation(loc)
+	attendanceService.SetRSSIThreshold(cfg.RSSIThreshold)
+	log.Printf("📶 RSSI check-in threshold [%s]: %d dBm", tenantID, cfg.RSSIThreshold)
+	// A tenant's GRACE_PERIOD_MINUTES applies to its own employees without their own
+	grace := time.Duration(cfg.GracePeriodMinutes) * time.Minute
+	attendanceService.SetGracePeriod(grace)
+	bot.SetGracePeriod(tenantID, grace)
+
+	// Each scanner follows defaults → its profile → its own overrides, RSSI_THRESHOLD being the base default
+	threshold := cfg.RSSIThreshold
+	scannerProfiles, err := services.LoadScannerProfiles(cfg.ScannerProfilesFile, models.ScannerSettings{RSSIThreshold: &threshold})
+	if err != nil {
+		return nil, err
+	}
+	if names := scannerProfiles.Names(); len(names) > 0 {
+		log.Printf("📡 Scanner profiles [%s]: %s", tenantID, strings.Join(names, ", "))
+	}
+	scannerConfigs := services.NewScannerConfigs(scannerProfiles, scannerRepo)
+	attendanceService.SetScannerConfigs(scannerConfigs)
+	bot.SetScannerProfiles(tenantID, scannerConfigs)
+
+	local, err := newLocalStore(cfg, store.site)
+	if err != nil {
+		return nil, err
+	}
+
+	// In read-only mode, or while PocketBase stays unavailable, detections wait in a local queue
+	queue := services.NewDetectionQueue(local)
+	attendanceService.SetReadOnlyQueue(systemStatus, queue)
+	drain := func(ctx context.Context) {
+		n, err := attendanceService.DrainQueue(ctx)
+		if err != nil {
+			log.Printf("❌ Draining detection queue [%s] failed: %v", tenantID, err)
+		} else if n > 0 {
+			log.Printf("📤 Drained %d queued detections [%s]", n, tenantID)
+		}
+	}
+	systemStatus.OnWritable(func() {
+		if elector.IsLeader() {
+			go drain(ctx)
+		}
+	})
+	// Replay detections queued during an outage as soon as this site's PocketBase is back
+	if store.site.URL != "" {
+		systemStatus.OnRecovered(store.site.URL, func() {
+			if elector.IsLeader() && !systemStatus.ReadOnly() {
+				go drain(ctx)
+			}
+		})
+	}
+	drainLeftover := func(ctx context.Context) {
+		if !systemStatus.ReadOnly() {
+			go drain(ctx) // left over from before a restart or a previous leader
+		}
+	}
+	var jobs []job
+
+	// Detections queued because PocketBase stayed unavailable are also retried every minute,
+	// in case the recovery was not observed (e.g. the backend was never marked down)
+	jobs = append(jobs, job{"detection_queue_retry[" + tenantID + "]", func(ctx context.Context) {
+		attendanceService.RetryQueued(ctx, time.Minute)
+	}})
+
+	// Queues that stop draining are reported and, above the hard cap, trimmed of presence detections
+	watchdog, err := services.NewQueueWatchdog(services.QueueWatchdogConfig{HighWater: cfg.AlertQueueDepth, HardCap: cfg.QueueHardCap}, botNotifier)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ALERT_QUEUE_DEPTH/QUEUE_HARD_CAP: %w", err)
+	}
+	watchdog.SetTenant(tenantID)
+	watchdog.Watch("detection_queue", queue)
+	bot.SetQueueWatchdog(tenantID, watchdog)
+	jobs = append(jobs, job{"queue_watchdog[" + tenantID + "]", func(ctx context.Context) {
+		watchdog.Start(ctx, time.Minute)
+	}})
+
+	// Require several detections within a window before check-in, surviving restarts
+	var smoothing *services.DetectionWindow
+	if cfg.SmoothingMinDetections > 1 {
+		window, err := services.NewDetectionWindow(cfg.SmoothingMinDetections, cfg.SmoothingWindow)
+		if err != nil {
+			return nil, err
+		}
+		window.LoadSnapshot(ctx, local, time.Now())
+		attendanceService.SetDetectionWindow(window)
+		smoothing = window
+	}
+
+	// Compare a moving average of each device's signal against the threshold
+	if cfg.RSSISmoothingAlpha > 0 {
+		smoother, err := services.NewRSSISmoother(cfg.RSSISmoothingAlpha, cfg.RSSISmoothingIdle)
+		if err != nil {
+			return nil, err
+		}
+		attendanceService.SetRSSISmoother(smoother)
+	}
+
+	// Check-ins on weekends and holidays are recorded as offday, never late
+	calendar, err := services.NewWorkCalendar(cfg.WorkDays, store.holidays)
+	if err != nil {
+		return nil, err
+	}
+	attendanceService.SetWorkCalendar(calendar)
+	bot.SetMonthlyReport(tenantID, services.NewAttendanceReport(attendanceRepo, calendar, loc))
+	bot.SetAttendanceExport(tenantID, services.NewAttendanceExport(employeeRepo, attendanceRepo, store.periodLocks, loc))
+	bot.SetRepositories(tenantID, bot.Repositories{Employees: store.employees, Attendance: store.attendance,
+		Devices: store.devices, Consents: store.privacyAudit, Scanners: store.scanners, Backend: store.site.URL})
+	bot.SetEmployeeList(tenantID, store.employees)
+	bot.SetEmployeeActivation(tenantID, services.NewEmployeeActivations(employeeRepo, store.auditLog))
+	bot.SetNotificationPrefs(tenantID, employeeRepo)
+
+	// Detections outside the check-in window are saved but never check anyone in
+	if cfg.CheckInWindowStart != "" || cfg.CheckInWindowEnd != "" {
+		window, err := services.NewCheckInWindow(cfg.CheckInWindowStart, cfg.CheckInWindowEnd)
+		if err != nil {
+			return nil, err
+		}
+		attendanceService.SetCheckInWindow(window)
+		log.Printf("🕔 Check-ins allowed %s", window)
+	}
+
+	// Drop the detections a tag repeats every few seconds once they have been settled
+	if cfg.RecentDetectionTTL > 0 {
+		recent := services.NewRecentDetections(cfg.RecentDetectionTTL)
+		recent.SetDataVersions(versions)
+		attendanceService.SetRecentDetections(recent)
+	}
+
+	// Employees already checked in keep leaving a detection now and then, for /whoisin
+	if cfg.PresenceLogInterval > 0 {
+		attendanceService.SetPresenceSampler(services.NewPresenceSampler(cfg.PresenceLogInterval))
+	}
+	bot.SetPresence(tenantID, services.NewPresence(employeeRepo, detectionRepo))
+
+	// Off by default: recording every strong unknown device is a privacy decision
+	if cfg.UnknownDeviceCapture {
+		attendanceService.SetUnknownDeviceCapture(services.NewUnknownDeviceCapture(store.deviceSightings, cfg.UnknownDeviceMinRSSI, cfg.UnknownDeviceSampleInterval))
+		pendingDevices := services.NewPendingDevices(store.deviceSightings, employeeRepo, store.devices)
+		pendingDevices.SetDevices(deviceRepo)
+		bot.SetPendingDevices(tenantID, pendingDevices)
+	}
+
+	// Privacy-sensitive sites never process, log or store other people's devices
+	if cfg.WhitelistOnly {
+		whitelist := services.NewDeviceWhitelist(employeeRepo, store.deviceSightings, cfg.EmployeeCacheTTL)
+		whitelist.SetDevices(deviceRepo)
+		attendanceService.SetWhitelist(whitelist)
+		if cfg.UnknownDeviceCapture {
+			log.Printf("⚠️  WHITELIST_ONLY drops unknown devices before UNKNOWN_DEVICE_CAPTURE sees them [%s]", tenantID)
+		}
+		log.Printf("🔒 Whitelist-only mode [%s]: detections of other devices are dropped unlogged", tenantID)
+	}
+
+	// Left-behind tag analysis runs as part of the end-of-day job
+	stationaryCfg := services.DefaultStationaryTagConfig()
+	stationaryCfg.EveningStart = cfg.StationaryTagEveningStart
+	stationaryCfg.ConfirmDetections = cfg.StationaryTagConfirmations
+	stationary, err := services.NewStationaryTagDetector(stationaryCfg, botNotifier)
+	if err != nil {
+		return nil, err
+	}
+	attendanceService.SetStationaryTagDetector(stationary)
+
+	// Unusual check-in times are noted in the admin daily summary
+	baselines, err := services.NewCheckInBaselines(store.baselines, cfg.AnomalyMADThreshold)
+	if err != nil {
+		return nil, err
+	}
+	attendanceService.SetCheckInBaselines(baselines)
+
+	// Notification wording from TEMPLATES_DIR; a broken template stops the startup
+	templates, err := services.LoadMessageTemplates(cfg.TemplatesDir, cfg.AdminLanguage)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TEMPLATES_DIR or ADMIN_LANGUAGE: %w", err)
+	}
+	attendanceService.SetMessageTemplates(templates)
+
+	// Detections of checked-in employees late in the day move their check-out
+	if cfg.CheckOutTrackingEnabled {
+		trackerCfg := services.DefaultCheckOutTrackerConfig()
+		trackerCfg.After = cfg.CheckOutAfter
+		trackerCfg.Until = cfg.StationaryTagEveningStart
+		tracker, err := services.NewCheckOutTracker(trackerCfg, attendanceRepo, botNotifier)
+		if err != nil {
+			return nil, err
+		}
+		tracker.SetTemplates(templates)
+		tracker.SetScanners(scannerRepo)
+		attendanceService.SetCheckOutTracker(tracker)
+	}
+
+	// Employees still checked in after their scheduled end are asked whether they left
+	if prompter, ok := botNotifier.(services.PromptNotifier); ok && cfg.CheckOutReminderEnabled {
+		reminderCfg := services.DefaultCheckOutReminderConfig()
+		reminderCfg.DefaultEndTime = cfg.WorkEndTime
+		reminderCfg.Delay = cfg.CheckOutReminderDelay
+		reminderCfg.Snooze = cfg.CheckOutReminderSnooze
+		reminderCfg.ExitScanners = cfg.ExitScanners()
+		reminder, err := services.NewCheckOutReminder(reminderCfg, employeeRepo, attendanceRepo, prompter)
+		if err != nil {
+			return nil, err
+		}
+		attendanceService.SetCheckOutReminder(reminder)
+		bot.HandleCallbacks(tenantID, services.CheckOutCallbackPrefix, reminder.HandleCallback)
+		jobs = append(jobs, job{"checkout_reminder[" + tenantID + "]", func(ctx context.Context) {
+			reminder.Start(ctx, time.Minute)
+		}})
+	}
+
+	// New scanners are paired from the bot with a short-lived code sent on their first heartbeat
+	pairing, err := services.NewScannerPairing(scannerRepo, botNotifier, cfg.PairingCodeTTL)
+	if err != nil {
+		return nil, err
+	}
+	attendanceService.SetScannerPairing(pairing)
+	bot.SetScannerPairing(tenantID, pairing)
+
+	// Employees find their tag's MAC with /pair instead of typing it
+	if prompter, ok := botNotifier.(services.PromptNotifier); ok {
+		devicePairing, err := services.NewDevicePairing(employeeRepo, prompter, cfg.DevicePairingWindow)
+		if err != nil {
+			return nil, err
+		}
+		devicePairing.SetDevices(deviceRepo)
+		attendanceService.SetDevicePairing(devicePairing)
+		bot.SetDevicePairing(tenantID, devicePairing)
+	}
+
+	// Payroll periods are locked from the bot once a second admin approves
+	bot.SetPeriodLocking(tenantID, services.NewPeriodLocking(store.periodLocks, botNotifier))
+
+	// Employees ask to arrive late on one day; check-ins by the approved time are ontime_approved
+	var lateApprovals *services.LateApprovals
+	if prompter, ok := botNotifier.(services.AdminPromptNotifier); ok {
+		lateApprovals = services.NewLateApprovals(store.lateApprovals, employeeRepo, prompter)
+		lateApprovals.SetGracePeriod(grace)
+		attendanceService.SetLateApprovals(lateApprovals)
+		bot.SetLateApprovals(tenantID, lateApprovals)
+	}
+
+	// Admins check in employees whose tag was not detected, picking the time in the bot
+	manualCheckIns := services.NewManualCheckIns(employeeRepo, attendanceRepo, botNotifier)
+	manualCheckIns.SetLateApprovals(lateApprovals)
+	manualCheckIns.SetGracePeriod(grace)
+	bot.SetManualCheckIns(tenantID, manualCheckIns)
+
+	endOfDay, err := services.NewEndOfDayJob(cfg.EndOfDayTime)
+	if err != nil {
+		return nil, err
+	}
+	endOfDay.Register("stationary_tags", stationary.Analyze)
+	if lateApprovals != nil {
+		endOfDay.Register("late_approval_expiry", lateApprovals.Expire)
+	}
+
+	// Guest tags check in until their last day, then are switched off with a reminder to collect them
+	guests := services.NewGuests(store.employees, store.auditLog, botNotifier)
+	endOfDay.Register("guest_expiry", guests.Expire)
+	bot.SetGuests(tenantID, guests)
+	if cfg.DailySummaryEnabled {
+		summary := services.NewDailySummary(employeeRepo, attendanceRepo, botNotifier)
+		summary.AddSection("🕵️ เวลาเข้างานผิดปกติ", baselines.SummaryLines)
+
+		// Yesterday's operational numbers, from counters snapshotted at midnight and saved every minute
+		ops := services.NewOpsRecorder(tenantID, local)
+		summary.AddSection("📈 การทำงานของระบบเมื่อวาน", ops.SummaryLines)
+		jobs = append(jobs, job{"ops_recorder[" + tenantID + "]", func(ctx context.Context) {
+			ops.Start(ctx, time.Minute)
+		}})
+		endOfDay.Register("daily_summary", summary.Send)
+	}
+
+	// Mornings of working days the admin chat hears who is in, late or not seen yet
+	if cfg.MorningReportTime != "" && cfg.MorningReportTime != "off" {
+		morning, err := services.NewEndOfDayJob(cfg.MorningReportTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MORNING_REPORT_TIME: %w", err)
+		}
+		report := services.NewMorningReport(employeeRepo, attendanceRepo, calendar, botNotifier)
+		report.SetGracePeriod(grace)
+		morning.Register("morning_report", report.Send)
+		jobs = append(jobs, job{"morning_report[" + tenantID + "]", morning.Start})
+	}
+
+	// Employees without a department get a weekly proposal from the zones they are seen in
+	if prompter, ok := botNotifier.(services.AdminPromptNotifier); ok && cfg.DepartmentZones != "" {
+		zones, err := services.ParseDepartmentZones(cfg.DepartmentZones)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DEPARTMENT_ZONES: %w", err)
+		}
+		deptCfg := services.DefaultDepartmentInferenceConfig()
+		deptCfg.Zones = zones
+		deptCfg.Window = cfg.DepartmentInferenceWindow
+		inference, err := services.NewDepartmentInference(deptCfg, employeeRepo, detectionRepo, scannerRepo, store.auditLog, prompter)
+		if err != nil {
+			return nil, err
+		}
+		inference.SetScannerConfigs(scannerConfigs)
+		endOfDay.Register("department_digest", inference.SendDigest)
+		bot.SetDepartmentInference(tenantID, inference)
+	}
+
+	// Employees not seen for a while are offered for deactivation, and switched off if nobody answers
+	if prompter, ok := botNotifier.(services.AdminPromptNotifier); ok && cfg.InactivityPolicyEnabled {
+		idleCfg := services.DefaultInactivityPolicyConfig()
+		idleCfg.FlagAfter = time.Duration(cfg.InactivityFlagDays) * 24 * time.Hour
+		idleCfg.DeactivateAfter = time.Duration(cfg.InactivityDeactivateDays) * 24 * time.Hour
+		policy, err := services.NewInactivityPolicy(idleCfg, employeeRepo, attendanceRepo, detectionRepo, store.auditLog, local, prompter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid INACTIVITY_FLAG_DAYS/INACTIVITY_DEACTIVATE_DAYS: %w", err)
+		}
+		endOfDay.Register("inactivity_policy", policy.Run)
+		bot.SetInactivityPolicy(tenantID, policy)
+	}
+
+	// A synthetic employee's detection goes through the real pipeline to catch broken deployments
+	if cfg.SelfTestInterval > 0 {
+		selfTestCfg := services.DefaultSelfTestConfig()
+		if cfg.SelfTestMAC != "" {
+			selfTestCfg.MacAddress = cfg.SelfTestMAC
+		}
+		selfTestCfg.Deadline = cfg.SelfTestDeadline
+		selfTestCfg.Detections = cfg.SmoothingMinDetections
+		selfTest, err := services.NewSelfTest(selfTestCfg, attendanceService, store.selfTest, botNotifier)
+		if err != nil {
+			return nil, err
+		}
+		selfTest.SetTenant(tenantID)
+		endOfDay.Register("selftest_retention", selfTest.Cleanup)
+		jobs = append(jobs, job{"selftest[" + tenantID + "]", func(ctx context.Context) {
+			selfTest.Start(ctx, cfg.SelfTestInterval)
+		}})
+	}
+	jobs = append(jobs, job{"end_of_day[" + tenantID + "]", endOfDay.Start})
+
+	// Records nobody checked out of are closed at the day's last detection
+	if cfg.AutoCheckOutTime != "" {
+		autoCheckOut, err := services.NewEndOfDayJob(cfg.AutoCheckOutTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AUTO_CHECKOUT_TIME: %w", err)
+		}
+		autoCheckOut.Register("auto_checkout", services.NewAutoCheckOut(attendanceRepo, detectionRepo, employeeRepo).Run)
+		jobs = append(jobs, job{"auto_checkout[" + tenantID + "]", autoCheckOut.Start})
+	}
+	log.Printf("🧭 Detection pipeline [%s]: %s", tenantID, strings.Join(attendanceService.Pipeline().Stages(), " → "))
 
 	// Initialize handlers
+	profiles, err := handlers.NewPayloadProfiles(cfg.PayloadProfiles, cfg.PayloadProfileKeys)
+	if err != nil {
+		return nil, err
+	}
 	detectionHandler := handlers.NewDetectionHandler(attendanceService)
+	detectionHandler.SetPayloadProfiles(profiles)
+	detectionHandler.SetRateLimit(cfg.DetectRateLimit)
+	detectionHandler.SetMaxBodyBytes(int64(cfg.DetectMaxBodyBytes))
+	// Answer scanners before PocketBase is done with their detections
+	var workers *services.DetectionWorkers
+	if cfg.DetectWorkers > 0 {
+		workers = services.NewDetectionWorkers(attendanceService, cfg.DetectWorkers, cfg.DetectQueueSize, tenantID)
+		detectionHandler.SetWorkers(workers)
+		log.Printf("🧵 Detections processed by %d workers, up to %d queued [%s]", cfg.DetectWorkers, cfg.DetectQueueSize, tenantID)
+	}
+	detectionHandler.SetScannerTracker(systemStatus)
+	detectionHandler.SetFreeformScannerIDs(cfg.FreeformScannerIDs)
+	detectionHandler.SetScannerAuth(handlers.NewScannerAuth(scannerRepo, cfg.RequireScannerTokens))
+	bot.SetScannerTokens(tenantID, scannerRepo)
+	bot.SetScannerNames(tenantID, scannerRepo)
+	heartbeatHandler := handlers.NewHeartbeatHandler(scannerRepo, pairing)
+	heartbeatHandler.SetWriteGate(systemStatus)
+	heartbeatHandler.SetScannerTracker(systemStatus)
+	heartbeatHandler.SetFreeformScannerIDs(cfg.FreeformScannerIDs)
+	scannerConfigHandler := handlers.NewScannerConfigHandler(scannerConfigs)
+	scannerConfigHandler.SetFreeformScannerIDs(cfg.FreeformScannerIDs)
 
-	return detectionHandler
+	return &siteApp{
+		tenantID:  tenantID,
+		cfg:       cfg,
+		store:     store,
+		detection: detectionHandler,
+		workers:   workers,
+		heartbeat: heartbeatHandler,
+		scanners:  scannerConfigHandler,
+		smoothing: smoothing,
+		versions:  versions,
+		local:     local,
+		queue:     queue,
+		drain:     drainLeftover,
+		jobs:      jobs,
+	}, nil
 }
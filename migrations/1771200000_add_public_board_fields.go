@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("employees")
+		if err != nil {
+			return err
+		}
+
+		// Add display_name field (empty means first name)
+		collection.Fields.Add(&core.TextField{
+			Id:   "emp_display",
+			Name: "display_name",
+			Max:  64,
+		})
+
+		// Add show_on_board field
+		collection.Fields.Add(&core.BoolField{
+			Id:   "emp_board",
+			Name: "show_on_board",
+		})
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		// The board is opt-out, so existing employees start out visible
+		records, err := app.FindAllRecords("employees")
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			record.Set("show_on_board", true)
+			if err := app.Save(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("employees")
+		if err != nil {
+			return err
+		}
+
+		collection.Fields.RemoveById("emp_display")
+		collection.Fields.RemoveById("emp_board")
+
+		return app.Save(collection)
+	})
+}
@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		collection := core.NewBaseCollection("checkin_baselines")
+
+		collection.Fields.Add(&core.TextField{
+			Id:       "bsl_emp_id",
+			Name:     "employee_id",
+			Required: true,
+		})
+
+		// Last 20 working days as [{"date":"YYYY-MM-DD","minutes":470}, ...]
+		collection.Fields.Add(&core.JSONField{
+			Id:   "bsl_samples",
+			Name: "samples",
+		})
+
+		collection.Fields.Add(&core.NumberField{
+			Id:   "bsl_median",
+			Name: "median_minutes",
+		})
+
+		collection.Fields.Add(&core.NumberField{
+			Id:   "bsl_mad",
+			Name: "mad_minutes",
+		})
+
+		collection.AddIndex("idx_bsl_emp", true, "employee_id", "")
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("checkin_baselines")
+		if err != nil {
+			return err
+		}
+
+		return app.Delete(collection)
+	})
+}
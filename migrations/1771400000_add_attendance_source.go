@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("attendance")
+		if err != nil {
+			return err
+		}
+
+		// Add source field (scanner, import)
+		collection.Fields.Add(&core.TextField{
+			Id:   "att_source",
+			Name: "source",
+			Max:  32,
+		})
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		// Every record so far came from a scanner
+		records, err := app.FindAllRecords("attendance")
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			record.Set("source", "scanner")
+			if err := app.Save(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("attendance")
+		if err != nil {
+			return err
+		}
+
+		collection.Fields.RemoveById("att_source")
+
+		return app.Save(collection)
+	})
+}
@@ -0,0 +1,73 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		employees, err := app.FindCollectionByNameOrId("employees")
+		if err != nil {
+			return err
+		}
+
+		// Add work_end_time field (HH:MM:SS like work_start_time, empty means no schedule)
+		employees.Fields.Add(&core.TextField{
+			Id:      "emp_work_end",
+			Name:    "work_end_time",
+			Pattern: `^([0-1]?[0-9]|2[0-3]):[0-5][0-9]:[0-5][0-9]$`,
+		})
+
+		// Add muted_notifications field (list of notification categories)
+		employees.Fields.Add(&core.JSONField{
+			Id:   "emp_muted",
+			Name: "muted_notifications",
+		})
+
+		if err := app.Save(employees); err != nil {
+			return err
+		}
+
+		attendance, err := app.FindCollectionByNameOrId("attendance")
+		if err != nil {
+			return err
+		}
+
+		// Add check_out_source field (scanner, import, self_reported)
+		attendance.Fields.Add(&core.TextField{
+			Id:   "att_out_source",
+			Name: "check_out_source",
+			Max:  32,
+		})
+
+		// Add needs_review field for self-reported check-outs
+		attendance.Fields.Add(&core.BoolField{
+			Id:   "att_review",
+			Name: "needs_review",
+		})
+
+		return app.Save(attendance)
+	}, func(app core.App) error {
+		employees, err := app.FindCollectionByNameOrId("employees")
+		if err != nil {
+			return err
+		}
+
+		employees.Fields.RemoveById("emp_work_end")
+		employees.Fields.RemoveById("emp_muted")
+
+		if err := app.Save(employees); err != nil {
+			return err
+		}
+
+		attendance, err := app.FindCollectionByNameOrId("attendance")
+		if err != nil {
+			return err
+		}
+
+		attendance.Fields.RemoveById("att_out_source")
+		attendance.Fields.RemoveById("att_review")
+
+		return app.Save(attendance)
+	})
+}
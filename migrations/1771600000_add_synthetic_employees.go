@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		employees, err := app.FindCollectionByNameOrId("employees")
+		if err != nil {
+			return err
+		}
+
+		// Add is_synthetic field flagging the self-test employee
+		employees.Fields.Add(&core.BoolField{
+			Id:   "emp_synthetic",
+			Name: "is_synthetic",
+		})
+
+		return app.Save(employees)
+	}, func(app core.App) error {
+		employees, err := app.FindCollectionByNameOrId("employees")
+		if err != nil {
+			return err
+		}
+
+		employees.Fields.RemoveById("emp_synthetic")
+
+		return app.Save(employees)
+	})
+}
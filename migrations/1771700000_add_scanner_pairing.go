@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		scanners, err := app.FindCollectionByNameOrId("scanners")
+		if err != nil {
+			return err
+		}
+
+		// Add zone field chosen when the scanner is paired from the bot
+		scanners.Fields.Add(&core.TextField{
+			Id:   "scn_zone",
+			Name: "zone",
+			Max:  64,
+		})
+
+		// Add paired_at field recording when the pairing code was redeemed
+		scanners.Fields.Add(&core.DateField{
+			Id:   "scn_paired_at",
+			Name: "paired_at",
+		})
+
+		return app.Save(scanners)
+	}, func(app core.App) error {
+		scanners, err := app.FindCollectionByNameOrId("scanners")
+		if err != nil {
+			return err
+		}
+
+		scanners.Fields.RemoveById("scn_zone")
+		scanners.Fields.RemoveById("scn_paired_at")
+
+		return app.Save(scanners)
+	})
+}
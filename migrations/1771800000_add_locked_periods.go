@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		collection := core.NewBaseCollection("locked_periods")
+
+		// Payroll month as YYYY-MM
+		collection.Fields.Add(&core.TextField{
+			Id:       "lck_period",
+			Name:     "period",
+			Required: true,
+			Max:      7,
+		})
+
+		collection.Fields.Add(&core.BoolField{
+			Id:   "lck_locked",
+			Name: "locked",
+		})
+
+		collection.Fields.Add(&core.DateField{
+			Id:   "lck_locked_at",
+			Name: "locked_at",
+		})
+
+		// Approved locks and unlocks as [{"action":"lock","requested_by":1,"approved_by":2,"at":"..."}, ...]
+		collection.Fields.Add(&core.JSONField{
+			Id:   "lck_history",
+			Name: "history",
+		})
+
+		collection.AddIndex("idx_lck_period", true, "period", "")
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("locked_periods")
+		if err != nil {
+			return err
+		}
+
+		return app.Delete(collection)
+	})
+}
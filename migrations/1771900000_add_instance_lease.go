@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		collection := core.NewBaseCollection("instance_lease")
+
+		collection.Fields.Add(&core.TextField{
+			Id:       "lease_name",
+			Name:     "name",
+			Required: true,
+		})
+
+		// One record per term; the unique index lets only one instance take a term
+		collection.Fields.Add(&core.NumberField{
+			Id:       "lease_term",
+			Name:     "term",
+			Required: true,
+			OnlyInt:  true,
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:       "lease_holder",
+			Name:     "holder",
+			Required: true,
+		})
+
+		collection.Fields.Add(&core.DateField{
+			Id:       "lease_expires_at",
+			Name:     "expires_at",
+			Required: true,
+		})
+
+		collection.AddIndex("idx_lease_term", true, "name, term", "")
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("instance_lease")
+		if err != nil {
+			return err
+		}
+
+		return app.Delete(collection)
+	})
+}
@@ -0,0 +1,83 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		employees, err := app.FindCollectionByNameOrId("employees")
+		if err != nil {
+			return err
+		}
+
+		// Presence tracking consent; without it only check-ins and check-outs are kept
+		employees.Fields.Add(&core.BoolField{
+			Id:   "emp_presence_consent",
+			Name: "presence_tracking_consent",
+		})
+
+		if err := app.Save(employees); err != nil {
+			return err
+		}
+
+		// Existing employees keep being tracked as before
+		records, err := app.FindAllRecords("employees")
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			record.Set("presence_tracking_consent", true)
+			if err := app.Save(record); err != nil {
+				return err
+			}
+		}
+
+		// One record per consent change
+		audit := core.NewBaseCollection("privacy_audit")
+
+		audit.Fields.Add(&core.TextField{
+			Id:       "pva_emp_id",
+			Name:     "employee_id",
+			Required: true,
+		})
+
+		audit.Fields.Add(&core.BoolField{
+			Id:   "pva_consent",
+			Name: "consent",
+		})
+
+		// Telegram chat ID of whoever made the change
+		audit.Fields.Add(&core.NumberField{
+			Id:   "pva_changed_by",
+			Name: "changed_by",
+		})
+
+		audit.Fields.Add(&core.DateField{
+			Id:       "pva_changed_at",
+			Name:     "changed_at",
+			Required: true,
+		})
+
+		audit.AddIndex("idx_pva_emp", false, "employee_id", "")
+
+		return app.Save(audit)
+	}, func(app core.App) error {
+		audit, err := app.FindCollectionByNameOrId("privacy_audit")
+		if err != nil {
+			return err
+		}
+		if err := app.Delete(audit); err != nil {
+			return err
+		}
+
+		employees, err := app.FindCollectionByNameOrId("employees")
+		if err != nil {
+			return err
+		}
+
+		employees.Fields.RemoveById("emp_presence_consent")
+
+		return app.Save(employees)
+	})
+}
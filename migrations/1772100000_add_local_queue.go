@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		collection := core.NewBaseCollection("local_queue")
+
+		collection.Fields.Add(&core.TextField{
+			Id:       "lq_key",
+			Name:     "key",
+			Required: true,
+		})
+
+		// Orders the entries of a list; microseconds since the epoch
+		collection.Fields.Add(&core.NumberField{
+			Id:      "lq_seq",
+			Name:    "seq",
+			OnlyInt: true,
+		})
+
+		// Smoothing snapshots outgrow the default text limit
+		collection.Fields.Add(&core.TextField{
+			Id:   "lq_data",
+			Name: "data",
+			Max:  1 << 20,
+		})
+
+		collection.AddIndex("idx_lq_key_seq", false, "key, seq", "")
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("local_queue")
+		if err != nil {
+			return err
+		}
+
+		return app.Delete(collection)
+	})
+}
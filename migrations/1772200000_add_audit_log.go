@@ -0,0 +1,56 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		collection := core.NewBaseCollection("audit_log")
+
+		collection.Fields.Add(&core.TextField{
+			Id:       "aud_action",
+			Name:     "action",
+			Required: true,
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "aud_target_id",
+			Name: "target_id",
+		})
+
+		// Telegram user ID; 0 for changes made by the system
+		collection.Fields.Add(&core.NumberField{
+			Id:      "aud_actor_id",
+			Name:    "actor_id",
+			OnlyInt: true,
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "aud_actor_name",
+			Name: "actor_name",
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "aud_details",
+			Name: "details",
+		})
+
+		collection.Fields.Add(&core.DateField{
+			Id:       "aud_at",
+			Name:     "at",
+			Required: true,
+		})
+
+		collection.AddIndex("idx_aud_target", false, "target_id", "")
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("audit_log")
+		if err != nil {
+			return err
+		}
+
+		return app.Delete(collection)
+	})
+}
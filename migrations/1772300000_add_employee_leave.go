@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		employees, err := app.FindCollectionByNameOrId("employees")
+		if err != nil {
+			return err
+		}
+
+		// Last day of approved long leave; the inactivity policy skips employees on leave
+		employees.Fields.Add(&core.DateField{
+			Id:   "emp_leave_until",
+			Name: "leave_until",
+		})
+
+		return app.Save(employees)
+	}, func(app core.App) error {
+		employees, err := app.FindCollectionByNameOrId("employees")
+		if err != nil {
+			return err
+		}
+
+		employees.Fields.RemoveById("emp_leave_until")
+
+		return app.Save(employees)
+	})
+}
@@ -0,0 +1,61 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("attendance")
+		if err != nil {
+			return err
+		}
+
+		// The employee as they were at check-in, so renaming them does not change past reports
+		collection.Fields.Add(&core.TextField{Id: "att_employee_name", Name: "employee_name", Max: 255})
+		collection.Fields.Add(&core.TextField{Id: "att_employee_code", Name: "employee_code", Max: 64})
+		collection.Fields.Add(&core.TextField{Id: "att_department", Name: "department", Max: 255})
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		// Existing records get the employee's current values, the best guess available
+		employees, err := app.FindAllRecords("employees")
+		if err != nil {
+			return err
+		}
+		byID := make(map[string]*core.Record, len(employees))
+		for _, e := range employees {
+			byID[e.Id] = e
+		}
+		records, err := app.FindAllRecords("attendance")
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			emp, ok := byID[record.GetString("employee_id")]
+			if !ok {
+				continue
+			}
+			record.Set("employee_name", emp.GetString("name"))
+			record.Set("employee_code", emp.GetString("employee_code"))
+			record.Set("department", emp.GetString("department"))
+			if err := app.Save(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("attendance")
+		if err != nil {
+			return err
+		}
+
+		collection.Fields.RemoveById("att_employee_name")
+		collection.Fields.RemoveById("att_employee_code")
+		collection.Fields.RemoveById("att_department")
+
+		return app.Save(collection)
+	})
+}
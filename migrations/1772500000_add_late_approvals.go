@@ -0,0 +1,76 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		collection := core.NewBaseCollection("late_approvals")
+
+		collection.Fields.Add(&core.TextField{
+			Id:       "lat_employee_id",
+			Name:     "employee_id",
+			Required: true,
+		})
+
+		// YYYY-MM-DD of the one day the approval covers
+		collection.Fields.Add(&core.TextField{
+			Id:       "lat_date",
+			Name:     "date",
+			Required: true,
+		})
+
+		// HH:MM the employee expects to check in by
+		collection.Fields.Add(&core.TextField{
+			Id:       "lat_expected_time",
+			Name:     "expected_time",
+			Required: true,
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "lat_reason",
+			Name: "reason",
+		})
+
+		// pending, approved, rejected or expired
+		collection.Fields.Add(&core.TextField{
+			Id:       "lat_status",
+			Name:     "status",
+			Required: true,
+		})
+
+		collection.Fields.Add(&core.DateField{
+			Id:   "lat_requested_at",
+			Name: "requested_at",
+		})
+
+		// Telegram user ID of the admin who decided
+		collection.Fields.Add(&core.NumberField{
+			Id:      "lat_decided_by",
+			Name:    "decided_by",
+			OnlyInt: true,
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "lat_decider_name",
+			Name: "decider_name",
+		})
+
+		collection.Fields.Add(&core.DateField{
+			Id:   "lat_decided_at",
+			Name: "decided_at",
+		})
+
+		collection.AddIndex("idx_lat_employee_date", false, "employee_id, date", "")
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("late_approvals")
+		if err != nil {
+			return err
+		}
+
+		return app.Delete(collection)
+	})
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		scanners, err := app.FindCollectionByNameOrId("scanners")
+		if err != nil {
+			return err
+		}
+
+		// Add profile field naming the configuration profile the scanner follows
+		scanners.Fields.Add(&core.TextField{
+			Id:   "scn_profile",
+			Name: "profile",
+			Max:  64,
+		})
+
+		// Add overrides field with settings that win over the profile for this scanner only
+		scanners.Fields.Add(&core.JSONField{
+			Id:   "scn_overrides",
+			Name: "overrides",
+		})
+
+		return app.Save(scanners)
+	}, func(app core.App) error {
+		scanners, err := app.FindCollectionByNameOrId("scanners")
+		if err != nil {
+			return err
+		}
+
+		scanners.Fields.RemoveById("scn_profile")
+		scanners.Fields.RemoveById("scn_overrides")
+
+		return app.Save(scanners)
+	})
+}
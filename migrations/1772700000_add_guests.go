@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		employees, err := app.FindCollectionByNameOrId("employees")
+		if err != nil {
+			return err
+		}
+
+		// Add is_guest field flagging a time-boxed visitor or contractor tag
+		employees.Fields.Add(&core.BoolField{
+			Id:   "emp_guest",
+			Name: "is_guest",
+		})
+
+		// Last day a guest's tag is valid; the record is deactivated after it
+		employees.Fields.Add(&core.DateField{
+			Id:   "emp_guest_until",
+			Name: "guest_until",
+		})
+
+		return app.Save(employees)
+	}, func(app core.App) error {
+		employees, err := app.FindCollectionByNameOrId("employees")
+		if err != nil {
+			return err
+		}
+
+		employees.Fields.RemoveById("emp_guest")
+		employees.Fields.RemoveById("emp_guest_until")
+
+		return app.Save(employees)
+	})
+}
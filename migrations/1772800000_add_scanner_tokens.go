@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		scanners, err := app.FindCollectionByNameOrId("scanners")
+		if err != nil {
+			return err
+		}
+
+		// Add token field with the SHA-256 of the scanner's own device token; hidden from the API
+		scanners.Fields.Add(&core.TextField{
+			Id:     "scn_token",
+			Name:   "token",
+			Max:    64,
+			Hidden: true,
+		})
+
+		return app.Save(scanners)
+	}, func(app core.App) error {
+		scanners, err := app.FindCollectionByNameOrId("scanners")
+		if err != nil {
+			return err
+		}
+
+		scanners.Fields.RemoveById("scn_token")
+
+		return app.Save(scanners)
+	})
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		employees, err := app.FindCollectionByNameOrId("employees")
+		if err != nil {
+			return err
+		}
+
+		// Minutes after work_start_time an employee still checks in on time; 0 uses GRACE_PERIOD_MINUTES
+		employees.Fields.Add(&core.NumberField{
+			Id:      "emp_grace_period",
+			Name:    "grace_period_minutes",
+			OnlyInt: true,
+		})
+
+		return app.Save(employees)
+	}, func(app core.App) error {
+		employees, err := app.FindCollectionByNameOrId("employees")
+		if err != nil {
+			return err
+		}
+
+		employees.Fields.RemoveById("emp_grace_period")
+
+		return app.Save(employees)
+	})
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		collection := core.NewBaseCollection("holidays")
+
+		// YYYY-MM-DD of the holiday, judged in TIMEZONE
+		collection.Fields.Add(&core.TextField{
+			Id:       "hol_date",
+			Name:     "date",
+			Required: true,
+			Pattern:  `^\d{4}-\d{2}-\d{2}$`,
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "hol_name",
+			Name: "name",
+		})
+
+		collection.AddIndex("idx_hol_date", true, "date", "")
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("holidays")
+		if err != nil {
+			return err
+		}
+
+		return app.Delete(collection)
+	})
+}
@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		collection := core.NewBaseCollection("employee_devices")
+
+		// ID of the employees record owning the device
+		collection.Fields.Add(&core.TextField{
+			Id:       "dev_employee_id",
+			Name:     "employee_id",
+			Required: true,
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:       "dev_mac_address",
+			Name:     "mac_address",
+			Required: true,
+			Pattern:  `^([0-9A-Fa-f]{2}[:-]){5}([0-9A-Fa-f]{2})$`,
+		})
+
+		// e.g. "iTag" or "AirPods"
+		collection.Fields.Add(&core.TextField{
+			Id:   "dev_label",
+			Name: "label",
+		})
+
+		collection.Fields.Add(&core.BoolField{
+			Id:   "dev_is_primary",
+			Name: "is_primary",
+		})
+
+		collection.AddIndex("idx_dev_mac_address", true, "mac_address", "")
+		collection.AddIndex("idx_dev_employee_id", false, "employee_id", "")
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("employee_devices")
+		if err != nil {
+			return err
+		}
+
+		return app.Delete(collection)
+	})
+}
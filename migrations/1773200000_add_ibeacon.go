@@ -0,0 +1,71 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		employees, err := app.FindCollectionByNameOrId("employees")
+		if err != nil {
+			return err
+		}
+
+		// "<uuid>:<major>:<minor>" of an iBeacon the employee carries, for iPhones whose MAC is randomized
+		employees.Fields.Add(&core.TextField{
+			Id:      "emp_ibeacon_id",
+			Name:    "ibeacon_id",
+			Pattern: `^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}:\d{1,5}:\d{1,5}$`,
+		})
+		employees.AddIndex("idx_emp_ibeacon_id", true, "ibeacon_id", "ibeacon_id != ''")
+
+		if err := app.Save(employees); err != nil {
+			return err
+		}
+
+		detections, err := app.FindCollectionByNameOrId("employee_detections")
+		if err != nil {
+			return err
+		}
+
+		// iBeacon the scanner reported with the detection, if any
+		detections.Fields.Add(&core.TextField{
+			Id:   "det_ibeacon_uuid",
+			Name: "ibeacon_uuid",
+		})
+		detections.Fields.Add(&core.NumberField{
+			Id:      "det_major",
+			Name:    "major",
+			OnlyInt: true,
+		})
+		detections.Fields.Add(&core.NumberField{
+			Id:      "det_minor",
+			Name:    "minor",
+			OnlyInt: true,
+		})
+
+		return app.Save(detections)
+	}, func(app core.App) error {
+		employees, err := app.FindCollectionByNameOrId("employees")
+		if err != nil {
+			return err
+		}
+
+		employees.RemoveIndex("idx_emp_ibeacon_id")
+		employees.Fields.RemoveById("emp_ibeacon_id")
+		if err := app.Save(employees); err != nil {
+			return err
+		}
+
+		detections, err := app.FindCollectionByNameOrId("employee_detections")
+		if err != nil {
+			return err
+		}
+
+		detections.Fields.RemoveById("det_ibeacon_uuid")
+		detections.Fields.RemoveById("det_major")
+		detections.Fields.RemoveById("det_minor")
+
+		return app.Save(detections)
+	})
+}
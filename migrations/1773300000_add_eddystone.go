@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		employees, err := app.FindCollectionByNameOrId("employees")
+		if err != nil {
+			return err
+		}
+
+		// "<namespace>:<instance>" of an Eddystone-UID tag the employee carries
+		employees.Fields.Add(&core.TextField{
+			Id:      "emp_eddystone_id",
+			Name:    "eddystone_id",
+			Pattern: `^[0-9a-f]{20}:[0-9a-f]{12}$`,
+		})
+		employees.AddIndex("idx_emp_eddystone_id", true, "eddystone_id", "eddystone_id != ''")
+
+		if err := app.Save(employees); err != nil {
+			return err
+		}
+
+		detections, err := app.FindCollectionByNameOrId("employee_detections")
+		if err != nil {
+			return err
+		}
+
+		// Eddystone-UID the scanner reported with the detection, if any
+		detections.Fields.Add(&core.TextField{
+			Id:   "det_namespace_id",
+			Name: "namespace_id",
+		})
+		detections.Fields.Add(&core.TextField{
+			Id:   "det_instance_id",
+			Name: "instance_id",
+		})
+
+		return app.Save(detections)
+	}, func(app core.App) error {
+		employees, err := app.FindCollectionByNameOrId("employees")
+		if err != nil {
+			return err
+		}
+
+		employees.RemoveIndex("idx_emp_eddystone_id")
+		employees.Fields.RemoveById("emp_eddystone_id")
+		if err := app.Save(employees); err != nil {
+			return err
+		}
+
+		detections, err := app.FindCollectionByNameOrId("employee_detections")
+		if err != nil {
+			return err
+		}
+
+		detections.Fields.RemoveById("det_namespace_id")
+		detections.Fields.RemoveById("det_instance_id")
+
+		return app.Save(detections)
+	})
+}
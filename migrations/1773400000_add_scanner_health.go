@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		scanners, err := app.FindCollectionByNameOrId("scanners")
+		if err != nil {
+			return err
+		}
+
+		// Health the scanner reports in its heartbeat
+		scanners.Fields.Add(&core.TextField{
+			Id:   "scn_firmware_version",
+			Name: "firmware_version",
+			Max:  32,
+		})
+		scanners.Fields.Add(&core.NumberField{
+			Id:      "scn_uptime_seconds",
+			Name:    "uptime_seconds",
+			OnlyInt: true,
+		})
+		scanners.Fields.Add(&core.NumberField{
+			Id:      "scn_free_heap",
+			Name:    "free_heap",
+			OnlyInt: true,
+		})
+
+		return app.Save(scanners)
+	}, func(app core.App) error {
+		scanners, err := app.FindCollectionByNameOrId("scanners")
+		if err != nil {
+			return err
+		}
+
+		scanners.Fields.RemoveById("scn_firmware_version")
+		scanners.Fields.RemoveById("scn_uptime_seconds")
+		scanners.Fields.RemoveById("scn_free_heap")
+
+		return app.Save(scanners)
+	})
+}
@@ -0,0 +1,52 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		collection := core.NewBaseCollection("notification_failures")
+
+		collection.Fields.Add(&core.NumberField{
+			Id:       "ntf_chat_id",
+			Name:     "chat_id",
+			Required: true,
+			OnlyInt:  true,
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "ntf_text",
+			Name: "text",
+		})
+
+		// Telegram's answer to the last attempt, or why delivery stopped
+		collection.Fields.Add(&core.TextField{
+			Id:   "ntf_error",
+			Name: "error",
+		})
+
+		collection.Fields.Add(&core.NumberField{
+			Id:      "ntf_attempts",
+			Name:    "attempts",
+			OnlyInt: true,
+		})
+
+		collection.Fields.Add(&core.DateField{
+			Id:       "ntf_failed_at",
+			Name:     "failed_at",
+			Required: true,
+		})
+
+		collection.AddIndex("idx_ntf_failed_at", false, "failed_at", "")
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("notification_failures")
+		if err != nil {
+			return err
+		}
+
+		return app.Delete(collection)
+	})
+}
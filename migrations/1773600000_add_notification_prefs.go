@@ -0,0 +1,60 @@
+package migrations
+
+import (
+	"slices"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("employees")
+		if err != nil {
+			return err
+		}
+
+		// Personal check-in messages; admin late alerts are sent regardless
+		collection.Fields.Add(&core.BoolField{
+			Id:   "emp_notify_checkin",
+			Name: "notify_checkin",
+		})
+
+		// "HH:MM-HH:MM" in which no personal check-in message is sent
+		collection.Fields.Add(&core.TextField{
+			Id:      "emp_notify_quiet_hours",
+			Name:    "notify_quiet_hours",
+			Max:     11,
+			Pattern: `^([01][0-9]|2[0-3]):[0-5][0-9]-([01][0-9]|2[0-3]):[0-5][0-9]$`,
+		})
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		// Check-in messages stay on, except for employees who muted the checkin category,
+		// which notify_checkin replaces
+		records, err := app.FindAllRecords("employees")
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			muted := record.GetStringSlice("muted_notifications")
+			record.Set("notify_checkin", !slices.Contains(muted, "checkin"))
+			record.Set("muted_notifications", slices.DeleteFunc(muted, func(c string) bool { return c == "checkin" }))
+			if err := app.Save(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("employees")
+		if err != nil {
+			return err
+		}
+
+		collection.Fields.RemoveById("emp_notify_checkin")
+		collection.Fields.RemoveById("emp_notify_quiet_hours")
+
+		return app.Save(collection)
+	})
+}
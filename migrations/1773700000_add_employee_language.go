@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("employees")
+		if err != nil {
+			return err
+		}
+
+		// Language of the employee's bot replies and notifications; empty is Thai
+		collection.Fields.Add(&core.TextField{
+			Id:      "emp_language",
+			Name:    "language",
+			Max:     2,
+			Pattern: `^(th|en)$`,
+		})
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("employees")
+		if err != nil {
+			return err
+		}
+
+		collection.Fields.RemoveById("emp_language")
+
+		return app.Save(collection)
+	})
+}
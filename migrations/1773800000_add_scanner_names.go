@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		scanners, err := app.FindCollectionByNameOrId("scanners")
+		if err != nil {
+			return err
+		}
+
+		// Friendly name and location employees see instead of the MAC
+		scanners.Fields.Add(&core.TextField{
+			Id:   "scn_name",
+			Name: "name",
+			Max:  64,
+		})
+		scanners.Fields.Add(&core.TextField{
+			Id:   "scn_location",
+			Name: "location",
+			Max:  128,
+		})
+
+		return app.Save(scanners)
+	}, func(app core.App) error {
+		scanners, err := app.FindCollectionByNameOrId("scanners")
+		if err != nil {
+			return err
+		}
+
+		scanners.Fields.RemoveById("scn_name")
+		scanners.Fields.RemoveById("scn_location")
+
+		return app.Save(scanners)
+	})
+}
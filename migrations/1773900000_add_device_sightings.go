@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		devices, err := app.FindCollectionByNameOrId("devices")
+		if err != nil {
+			return err
+		}
+
+		// Detections of an unknown device, written by UNKNOWN_DEVICE_CAPTURE
+		devices.Fields.Add(&core.NumberField{
+			Id:      "dev_count",
+			Name:    "detection_count",
+			OnlyInt: true,
+		})
+
+		return app.Save(devices)
+	}, func(app core.App) error {
+		devices, err := app.FindCollectionByNameOrId("devices")
+		if err != nil {
+			return err
+		}
+
+		devices.Fields.RemoveById("dev_count")
+
+		return app.Save(devices)
+	})
+}
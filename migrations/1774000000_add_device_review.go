@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		devices, err := app.FindCollectionByNameOrId("devices")
+		if err != nil {
+			return err
+		}
+
+		// Set once an admin assigns or ignores the device in /pending_devices
+		devices.Fields.Add(&core.BoolField{
+			Id:   "dev_reviewed",
+			Name: "is_reviewed",
+		})
+
+		return app.Save(devices)
+	}, func(app core.App) error {
+		devices, err := app.FindCollectionByNameOrId("devices")
+		if err != nil {
+			return err
+		}
+
+		devices.Fields.RemoveById("dev_reviewed")
+
+		return app.Save(devices)
+	})
+}
@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// maxReportedDuplicates caps how many repeated days the migration error names
+const maxReportedDuplicates = 10
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("attendance")
+		if err != nil {
+			return err
+		}
+
+		// The index cannot be built over duplicates already stored. Records without an
+		// employee (empty, or the 0 the old number field stored for every ID) are not an
+		// employee's day and are left out of the index; any other repeated day stops the
+		// migration until an admin removes the extra records.
+		records, err := app.FindAllRecords("attendance")
+		if err != nil {
+			return err
+		}
+		counts := make(map[string]int, len(records))
+		var repeated []string
+		for _, record := range records {
+			employeeID := record.GetString("employee_id")
+			if employeeID == "" || employeeID == "0" {
+				continue
+			}
+			key := employeeID + " on " + record.GetString("created_date")
+			counts[key]++
+			if counts[key] == 2 {
+				repeated = append(repeated, key)
+			}
+		}
+		if len(repeated) > 0 {
+			shown := repeated
+			if len(shown) > maxReportedDuplicates {
+				shown = shown[:maxReportedDuplicates]
+			}
+			return fmt.Errorf("cannot add the unique index idx_attendance_employee_day: %d employee days have more than one attendance record (employee_id on created_date: %s); delete the extra records and restart",
+				len(repeated), strings.Join(shown, ", "))
+		}
+
+		// One check-in per employee and day, however many instances write it
+		collection.AddIndex("idx_attendance_employee_day", true, "employee_id, created_date", "employee_id NOT IN ('', '0')")
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("attendance")
+		if err != nil {
+			return err
+		}
+
+		collection.RemoveIndex("idx_attendance_employee_day")
+
+		return app.Save(collection)
+	})
+}
@@ -0,0 +1,74 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		// employee_id holds the ID of an employees record, but these two were created as
+		// number fields, which stored every ID as 0. The values are worthless, so the field
+		// is replaced; `repair-employee-ids` links the records to their employee again.
+		for _, c := range []struct{ collection, fieldID string }{
+			{"attendance", "att_employee_ref"},
+			{"employee_detections", "det_employee_ref"},
+		} {
+			collection, err := app.FindCollectionByNameOrId(c.collection)
+			if err != nil {
+				return err
+			}
+			old := collection.Fields.GetByName("employee_id")
+			if old != nil && old.Type() == core.FieldTypeText {
+				continue
+			}
+			if old != nil {
+				collection.Fields.RemoveById(old.GetId())
+			}
+
+			// ID of the employees record; not required, as old records stay empty until relinked
+			collection.Fields.Add(&core.TextField{
+				Id:   c.fieldID,
+				Name: "employee_id",
+			})
+
+			if c.collection == "attendance" {
+				// With no employee_id left there are no duplicates in the way of the index of
+				// migration 032; records waiting to be relinked all share the empty one
+				collection.RemoveIndex("idx_attendance_employee_day")
+				collection.AddIndex("idx_attendance_employee_day", true, "employee_id, created_date", "employee_id != ''")
+			}
+
+			if err := app.Save(collection); err != nil {
+				return err
+			}
+			log.Printf("🔧 %s.employee_id is now a text field; run repair-employee-ids to relink its records", c.collection)
+		}
+		return nil
+	}, func(app core.App) error {
+		// Lossy: the number field cannot hold record IDs, so rolling back drops every
+		// relinked employee_id. It is not required, so records stay valid without one.
+		for _, c := range []struct{ collection, fieldID, oldID string }{
+			{"attendance", "att_employee_ref", "att_emp_id"},
+			{"employee_detections", "det_employee_ref", "det_emp_id"},
+		} {
+			collection, err := app.FindCollectionByNameOrId(c.collection)
+			if err != nil {
+				return err
+			}
+			if collection.Fields.GetById(c.fieldID) == nil {
+				continue
+			}
+			collection.Fields.RemoveById(c.fieldID)
+			collection.Fields.Add(&core.NumberField{
+				Id:   c.oldID,
+				Name: "employee_id",
+			})
+			if err := app.Save(collection); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
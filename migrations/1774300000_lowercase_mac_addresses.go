@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+
+	"med-pulse-bot/internal/macaddr"
+)
+
+func init() {
+	core.AppMigrations.Register(func(app core.App) error {
+		// MACs are looked up in their canonical lower-case form, so records stored in
+		// upper case or another notation before MACs were normalized no longer match.
+		// Scanner IDs are handled by `normalize-scanners`.
+		for _, name := range []string{"employees", "employee_devices", "devices"} {
+			records, err := app.FindAllRecords(name)
+			if err != nil {
+				return err
+			}
+			taken := make(map[string]bool, len(records))
+			for _, record := range records {
+				taken[record.GetString("mac_address")] = true
+			}
+			normalized := 0
+			for _, record := range records {
+				stored := record.GetString("mac_address")
+				mac, err := macaddr.Normalize(stored)
+				if err != nil || mac == stored {
+					continue
+				}
+				// Another record already holds the canonical form; merging is left to an admin
+				if taken[mac] {
+					log.Printf("⚠️  %s %s: %s is also stored as %s, left unchanged", name, record.Id, stored, mac)
+					continue
+				}
+				record.Set("mac_address", mac)
+				if err := app.Save(record); err != nil {
+					return err
+				}
+				taken[mac] = true
+				normalized++
+			}
+			if normalized > 0 {
+				log.Printf("🔧 Normalized %d %s.mac_address values to lower case", normalized, name)
+			}
+		}
+		return nil
+	}, func(app core.App) error {
+		// The original spellings are gone; the normalized MACs stay
+		return nil
+	})
+}
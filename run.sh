@@ -3,4 +3,4 @@ export GOPATH=$(pwd)/.go
 export GOCACHE=$(pwd)/.cache
 export GOTMPDIR=$(pwd)/.tmp
 
-go run main.go
+go run .
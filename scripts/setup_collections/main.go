@@ -68,6 +68,8 @@ func main() {
 		{"attendance", createAttendanceCollection},
 		{"employee_detections", createDetectionsCollection},
 		{"devices", createDevicesCollection},
+		{"holidays", createHolidaysCollection},
+		{"employee_devices", createEmployeeDevicesCollection},
 	}
 
 	for _, col := range collections {
@@ -213,6 +215,125 @@ func updateCollectionFields(baseURL, token, name string, fields []map[string]int
 	return nil
 }
 
+// replaceNumberField replaces a number field of an existing collection with field, e.g.
+// employee_id, which older versions of this script created as a number and so stored
+// every record ID as 0. The old values are dropped with the field, and so are the indexes
+// using it; records are left for repair-employee-ids to relink.
+func replaceNumberField(baseURL, token, collection string, field map[string]interface{}) error {
+	getURL := fmt.Sprintf("%s/api/collections/%s", baseURL, collection)
+	req, _ := http.NewRequest("GET", getURL, nil)
+	req.Header.Set("Authorization", token)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to get collection: %v", err)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	var existing struct {
+		Fields  []map[string]interface{} `json:"fields"`
+		Indexes []string                 `json:"indexes"`
+	}
+	if err := json.Unmarshal(body, &existing); err != nil {
+		return fmt.Errorf("failed to parse collection: %v", err)
+	}
+
+	name := field["name"].(string)
+	fields := make([]map[string]interface{}, 0, len(existing.Fields))
+	replaced := false
+	for _, f := range existing.Fields {
+		if f["name"] == name && f["type"] == "number" {
+			fields = append(fields, field)
+			replaced = true
+			continue
+		}
+		fields = append(fields, f)
+	}
+	if !replaced {
+		return nil
+	}
+	indexes := []string{}
+	for _, idx := range existing.Indexes {
+		if !bytes.Contains([]byte(idx), []byte(name)) {
+			indexes = append(indexes, idx)
+		}
+	}
+
+	jsonData, _ := json.Marshal(map[string]interface{}{"fields": fields, "indexes": indexes})
+	req, _ = http.NewRequest("PATCH", getURL, bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", token)
+
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to update: %v", err)
+	}
+
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("replacing %s failed: %s - %s", name, resp.Status, string(body))
+	}
+
+	fmt.Printf("   Replaced number field %s with a %s field; run repair-employee-ids to relink records\n", name, field["type"])
+	return nil
+}
+
+func ensureIndex(baseURL, token, collection, name, index string) error {
+	// Get existing collection to check its indexes
+	getURL := fmt.Sprintf("%s/api/collections/%s", baseURL, collection)
+	req, _ := http.NewRequest("GET", getURL, nil)
+	req.Header.Set("Authorization", token)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to get collection: %v", err)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	var existing struct {
+		Indexes []string `json:"indexes"`
+	}
+	if err := json.Unmarshal(body, &existing); err != nil {
+		return fmt.Errorf("failed to parse collection: %v", err)
+	}
+	for _, idx := range existing.Indexes {
+		if bytes.Contains([]byte(idx), []byte("`"+name+"`")) {
+			fmt.Printf("   Index %s already exists\n", name)
+			return nil
+		}
+	}
+
+	updateURL := fmt.Sprintf("%s/api/collections/%s", baseURL, collection)
+	jsonData, _ := json.Marshal(map[string]interface{}{
+		"indexes": append(existing.Indexes, index),
+	})
+	req, _ = http.NewRequest("PATCH", updateURL, bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", token)
+
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to update: %v", err)
+	}
+
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	// Existing duplicates keep a unique index from being built
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("adding index %s failed, remove duplicate records first: %s - %s", name, resp.Status, string(body))
+	}
+
+	fmt.Printf("   Added index %s\n", name)
+	return nil
+}
+
 func createTextField(name string, required bool) map[string]interface{} {
 	return map[string]interface{}{
 		"name":        name,
@@ -296,6 +417,12 @@ func createScannersCollection(baseURL, token string) error {
 	fields := []map[string]interface{}{
 		createTextFieldWithPattern("scanner_mac", true, "^([0-9A-Fa-f]{2}[:-]){5}([0-9A-Fa-f]{2})$"),
 		createDateField("last_seen", true),
+		createTextField("token", false), // SHA-256 of the scanner's device token
+		createTextField("firmware_version", false),
+		createNumberField("uptime_seconds", false),
+		createNumberField("free_heap", false), // bytes
+		createTextField("name", false),        // friendly name shown to employees; empty shows the MAC
+		createTextField("location", false),    // shown after the name, e.g. the building and floor
 	}
 	return createCollection(baseURL, token, "scanners", fields)
 }
@@ -308,34 +435,59 @@ func createEmployeesCollection(baseURL, token string) error {
 		createTextField("employee_code", false),
 		createTextField("department", false),
 		createTextFieldWithPattern("work_start_time", false, "^([0-1]?[0-9]|2[0-3]):[0-5][0-9]:[0-5][0-9]$"),
+		createNumberField("grace_period_minutes", false), // 0 uses GRACE_PERIOD_MINUTES
+		createTextFieldWithPattern("ibeacon_id", false, "^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}:\\d{1,5}:\\d{1,5}$"), // uuid:major:minor
+		createTextFieldWithPattern("eddystone_id", false, "^[0-9a-f]{20}:[0-9a-f]{12}$"),                                                    // namespace:instance
 		createBoolField("is_active", false),
+		createBoolField("notify_checkin", false), // personal check-in messages; admin late alerts are sent regardless
+		createTextFieldWithPattern("notify_quiet_hours", false, "^([01][0-9]|2[0-3]):[0-5][0-9]-([01][0-9]|2[0-3]):[0-5][0-9]$"),
+		createTextFieldWithPattern("language", false, "^(th|en)$"), // bot replies and notifications; empty is Thai
 	}
 	return createCollection(baseURL, token, "employees", fields)
 }
 
 func createAttendanceCollection(baseURL, token string) error {
 	fields := []map[string]interface{}{
-		createNumberField("employee_id", true),
+		createTextField("employee_id", true), // ID of the employees record
 		createDateField("check_in_time", true),
 		createDateField("check_out_time", false),
 		createTextField("scanner_mac", false),
 		createTextField("status", true),
 		createDateField("created_date", true),
+		createTextField("source", false),           // scanner, import, manual or selftest
+		createTextField("check_out_source", false), // scanner, self_reported, auto or manual
+	}
+	if err := createCollection(baseURL, token, "attendance", fields); err != nil {
+		return err
 	}
-	return createCollection(baseURL, token, "attendance", fields)
+	if err := replaceNumberField(baseURL, token, "attendance", createTextField("employee_id", false)); err != nil {
+		return err
+	}
+	// One check-in per employee and day, however many instances write it; records not
+	// relinked yet by repair-employee-ids share the empty employee_id
+	return ensureIndex(baseURL, token, "attendance", "idx_attendance_employee_day",
+		"CREATE UNIQUE INDEX `idx_attendance_employee_day` ON `attendance` (`employee_id`, `created_date`) WHERE `employee_id` != ''")
 }
 
 func createDetectionsCollection(baseURL, token string) error {
 	fields := []map[string]interface{}{
-		createNumberField("employee_id", true),
+		createTextField("employee_id", true), // ID of the employees record
 		createTextField("mac_address", true),
 		createTextField("scanner_mac", true),
 		createNumberField("rssi", true),
 		createTextField("device_type", false),
 		createBoolField("is_itag03", false),
+		createTextField("ibeacon_uuid", false),
+		createNumberField("major", false),
+		createNumberField("minor", false),
+		createTextField("namespace_id", false),
+		createTextField("instance_id", false),
 		createDateField("detected_at", true),
 	}
-	return createCollection(baseURL, token, "employee_detections", fields)
+	if err := createCollection(baseURL, token, "employee_detections", fields); err != nil {
+		return err
+	}
+	return replaceNumberField(baseURL, token, "employee_detections", createTextField("employee_id", false))
 }
 
 func createDevicesCollection(baseURL, token string) error {
@@ -345,10 +497,30 @@ func createDevicesCollection(baseURL, token string) error {
 		createBoolField("is_whitelisted", false),
 		createNumberField("rssi", false),
 		createDateField("last_seen", false),
+		createNumberField("detection_count", false),
+		createBoolField("is_reviewed", false),
 	}
 	return createCollection(baseURL, token, "devices", fields)
 }
 
+func createHolidaysCollection(baseURL, token string) error {
+	fields := []map[string]interface{}{
+		createTextFieldWithPattern("date", true, "^\\d{4}-\\d{2}-\\d{2}$"), // YYYY-MM-DD
+		createTextField("name", false),
+	}
+	return createCollection(baseURL, token, "holidays", fields)
+}
+
+func createEmployeeDevicesCollection(baseURL, token string) error {
+	fields := []map[string]interface{}{
+		createTextField("employee_id", true), // ID of the owning employees record
+		createTextFieldWithPattern("mac_address", true, "^([0-9A-Fa-f]{2}[:-]){5}([0-9A-Fa-f]{2})$"),
+		createTextField("label", false),
+		createBoolField("is_primary", false),
+	}
+	return createCollection(baseURL, token, "employee_devices", fields)
+}
+
 func checkHealth(baseURL string) error {
 	resp, err := httpClient.Get(baseURL + "/api/health")
 	if err != nil {
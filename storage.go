@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"med-pulse-bot/config"
+	"med-pulse-bot/internal/repository"
+	"med-pulse-bot/internal/repository/memory"
+	"med-pulse-bot/internal/repository/sqlite"
+)
+
+// siteEmployees is everything a site does with its employees
+type siteEmployees interface {
+	repository.CachableEmployees
+	repository.EmployeeBrowser
+	repository.GuestRegistry
+	repository.EmployeeAccounts
+}
+
+// siteAttendance is everything a site does with its attendance records
+type siteAttendance interface {
+	repository.VersionableAttendance
+	repository.AttendanceBrowser
+	repository.AttendanceHistory
+}
+
+// siteScanners is everything a site does with its scanner records
+type siteScanners interface {
+	repository.ScannerRepository
+	repository.ScannerRegistry
+}
+
+// siteDevices resolves employees through their extra devices, assigns new ones and lets
+// employees manage their own
+type siteDevices interface {
+	repository.DeviceRepository
+	repository.DeviceAssignment
+	repository.EmployeeDevices
+}
+
+// storage holds the repositories of one site, kept in PocketBase or in a local SQLite file
+type storage struct {
+	site            repository.Site // the PocketBase site; zero with SQLite
+	employees       siteEmployees
+	attendance      siteAttendance
+	detections      repository.RetryableDetections
+	scanners        siteScanners
+	periodLocks     repository.PeriodLockRepository
+	selfTest        repository.SelfTestRepository
+	devices         siteDevices
+	deviceSightings repository.DeviceSightingRepository
+	holidays        repository.HolidayRepository
+	auditLog        repository.AuditLog
+	baselines       repository.BaselineRepository
+	lateApprovals   repository.LateApprovalRepository
+	privacyAudit    repository.ConsentLog
+}
+
+// pocketBaseStorage keeps every record of the site in its PocketBase
+func pocketBaseStorage(site repository.Site) *storage {
+	return &storage{
+		site:            site,
+		employees:       site.Employees(),
+		attendance:      site.Attendance(),
+		detections:      site.Detections(),
+		scanners:        site.Scanners(),
+		periodLocks:     site.PeriodLocks(),
+		selfTest:        site.SelfTest(),
+		devices:         site.Devices(),
+		deviceSightings: site.DeviceSightings(),
+		holidays:        site.Holidays(),
+		auditLog:        site.AuditLog(),
+		baselines:       site.Baselines(),
+		lateApprovals:   site.LateApprovals(),
+		privacyAudit:    site.PrivacyAudit(),
+	}
+}
+
+// sqliteStorage keeps employees, attendance, detections, scanners and consent changes in
+// the SQLite file. The records with no SQLite table yet (holidays, the audit log, device
+// sightings, baselines and late approvals) live in memory until the bot restarts, like
+// the notifications of deadLetterLog.
+func sqliteStorage(db *sqlite.DB) *storage {
+	log.Println("⚠️  STORAGE_BACKEND=sqlite: holidays, the audit log, unknown devices, check-in baselines, " +
+		"late approvals and undelivered notifications are kept in memory and lost on restart")
+	extras := memory.NewStore(nil)
+	return &storage{
+		employees:       db.Employees(),
+		attendance:      db.Attendance(),
+		detections:      db.Detections(),
+		scanners:        db.Scanners(),
+		periodLocks:     db.PeriodLocks(),
+		selfTest:        db.SelfTest(),
+		devices:         db.Devices(),
+		deviceSightings: extras.DeviceSightingLog(),
+		holidays:        extras.Holidays(),
+		auditLog:        extras.AuditLog(),
+		baselines:       extras.Baselines(),
+		lateApprovals:   extras.LateApprovals(),
+		privacyAudit:    db.PrivacyAudit(),
+	}
+}
+
+// deadLetterLog is where the bot keeps the messages it could not deliver
+func deadLetterLog(cfg *config.Config) repository.NotificationFailureLog {
+	if cfg.StorageBackend == config.StorageSQLite {
+		return memory.NewStore(nil).NotificationFailureLog()
+	}
+	return repository.Site{URL: cfg.PocketBaseURL, Token: cfg.PocketBaseToken}.NotificationFailures()
+}
+
+// openSQLite opens the database STORAGE_BACKEND=sqlite keeps the records in
+func openSQLite(ctx context.Context, cfg *config.Config) (*sqlite.DB, error) {
+	path := cfg.SQLiteFile()
+	db, err := sqlite.Open(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite storage: %w", err)
+	}
+	log.Printf("💾 Storing records in SQLite at %s", path)
+	return db, nil
+}